@@ -3,51 +3,138 @@ package main
 import (
 	"context"
 	"fmt"
-	"log/slog"
+	"maps"
 	"os"
-	"os/signal"
-	"syscall"
+	"slices"
 	"time"
 
 	"github.com/Fancu1/phoenix-rss/internal/ai-service/client"
 	"github.com/Fancu1/phoenix-rss/internal/ai-service/core"
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/repository"
 	"github.com/Fancu1/phoenix-rss/internal/ai-service/worker"
 	"github.com/Fancu1/phoenix-rss/internal/config"
-	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/internal/events"
+	"github.com/Fancu1/phoenix-rss/internal/summaryquality"
+	"github.com/Fancu1/phoenix-rss/pkg/app"
+	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
 )
 
 func main() {
-	if err := logger.InitFromEnv(); err != nil {
-		fmt.Printf("Failed to initialize logger: %v\n", err)
+	a, err := app.New("ai-service")
+	if err != nil {
+		fmt.Printf("Failed to initialize service: %v\n", err)
 		os.Exit(1)
 	}
-	defer logger.Close()
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		fmt.Printf("Failed to load config: %v\n", err)
-		os.Exit(1)
+		a.Fatal("failed to load config", "error", err)
 	}
 
-	log := logger.New(slog.LevelDebug)
+	log := a.Logger()
+	if err := a.SetupTracing(cfg.Tracing.Params()); err != nil {
+		a.Fatal("failed to set up tracing", "error", err)
+	}
 
 	requestTimeout, err := time.ParseDuration(cfg.AIService.RequestTimeout)
 	if err != nil {
-		log.Error("failed to parse request timeout", "timeout", cfg.AIService.RequestTimeout, "error", err)
-		os.Exit(1)
+		a.Fatal("failed to parse request timeout", "timeout", cfg.AIService.RequestTimeout, "error", err)
+	}
+
+	backoffInitial, backoffMax, retryDelay, retryMaxDelay, err := cfg.AIService.RetryDurations()
+	if err != nil {
+		a.Fatal("invalid retry config", "error", err)
 	}
 
 	// Create LLM client
-	llmClient := client.NewLLMClient(
+	baseClient := client.NewLLMClient(
 		cfg.AIService.LLMBaseURL,
 		cfg.AIService.LLMAPIKey,
 		cfg.AIService.LLMModel,
 		requestTimeout,
 		log,
 	)
+	baseClient.SetRetry(client.RetryPolicy{
+		MaxAttempts:    cfg.AIService.LLMRetryMaxAttempts,
+		BackoffInitial: backoffInitial,
+		BackoffMax:     backoffMax,
+	})
+	baseClient.SetMaxConcurrency(cfg.AIService.LLMMaxConcurrency)
+	llmClient := baseClient.WithMaxTokens(cfg.AIService.SummaryMaxTokens)
 
 	// Create processing service
 	processingService := core.NewProcessingService(llmClient, log)
+	processingService.UseExpandedTokenLimit(cfg.AIService.ExpandedMaxTokens)
+	processingService.UseSummaryLanguages(cfg.Summaries.Languages)
+	processingService.UseReaderLanguages(cfg.Summaries.MaxReaderLanguages)
+
+	// Record every LLM request in the usage ledger
+	db := repository.InitDB(&cfg.Database)
+	sqlDB, err := db.DB()
+	if err != nil {
+		a.Fatal("failed to open database", "error", err)
+	}
+	a.Closer("database", sqlDB)
+	credentials := repository.NewCredentialRepository(db)
+	processingService.UseUsageLedger(credentials)
+	modelPrices, err := cfg.AIService.ParsedModelPrices()
+	if err != nil {
+		a.Fatal("invalid model prices", "error", err)
+	}
+	prices := make(map[string]core.ModelPrice, len(modelPrices))
+	for model, price := range modelPrices {
+		prices[model] = core.ModelPrice{PromptPerMillion: price.Prompt, CompletionPerMillion: price.Completion}
+	}
+	processingService.UsePricing(prices)
+	if cfg.AIService.SummaryCacheEnabled {
+		processingService.UseSummaryCache(repository.NewSummaryCacheRepository(db))
+	}
+
+	// Choose between the enabled prompt variants by readers' ratings
+	available, err := client.LoadSummaryPrompts(cfg.AIService.PromptDir)
+	if err != nil {
+		a.Fatal("failed to load summary prompts", "dir", cfg.AIService.PromptDir, "error", err)
+	}
+	prompts := make([]client.SummaryPrompt, 0, len(cfg.AIService.SummaryPrompts))
+	for _, name := range cfg.AIService.SummaryPrompts {
+		prompt, ok := available[name]
+		if !ok {
+			a.Fatal("unknown summary prompt", "prompt", name, "available", slices.Sorted(maps.Keys(available)))
+		}
+		prompt.MaxLength = cfg.AIService.SummaryMaxLength
+		prompts = append(prompts, prompt)
+	}
+	processingService.UsePromptSelector(core.NewPromptSelector(prompts, summaryquality.NewStore(db), core.PromptSelectorConfig{
+		Exploration: cfg.AIService.PromptExploration,
+		MinRatings:  cfg.AIService.PromptMinRatings,
+	}, log))
+
+	var routing *events.Routing
+	if cfg.Kafka.Routing.Enabled {
+		routing, err = events.NewRouting(cfg.Kafka.Routing.Topic, cfg.Kafka.Routing.Strict, cfg.Kafka.Routing.EventTypes)
+		if err != nil {
+			a.Fatal("invalid kafka routing config", "error", err)
+		}
+	}
+	articlesNewTopic := routing.TopicFor(events.EventArticlePersisted, cfg.Kafka.AIProcessing.ArticlesNewTopic)
+	articlesProcessedTopic := routing.TopicFor(events.EventArticleProcessed, cfg.Kafka.AIProcessing.ArticlesProcessedTopic)
+
+	compression, err := events.ParseCompression(cfg.Kafka.Compression)
+	if err != nil {
+		a.Fatal("invalid kafka compression", "value", cfg.Kafka.Compression, "error", err)
+	}
+	var sizeReportInterval time.Duration
+	if cfg.Kafka.SizeReportInterval != "" {
+		sizeReportInterval, err = time.ParseDuration(cfg.Kafka.SizeReportInterval)
+		if err != nil {
+			a.Fatal("failed to parse kafka size report interval", "value", cfg.Kafka.SizeReportInterval, "error", err)
+		}
+	}
+	producerOptions := events.ProducerOptions{Compression: compression}
+	if sizeReportInterval > 0 {
+		producerOptions.Sizes = events.NewMessageSizes()
+	}
 
 	// Create and start article processor
 	articleProcessor := worker.NewArticleProcessor(
@@ -55,47 +142,84 @@ func main() {
 		processingService,
 		cfg.Kafka.Brokers,
 		cfg.Kafka.AIProcessing.AIServiceGroupID,
-		cfg.Kafka.AIProcessing.ArticlesNewTopic,
-		cfg.Kafka.AIProcessing.ArticlesProcessedTopic,
+		articlesNewTopic,
+		articlesProcessedTopic,
 	)
+	articleProcessor.SetProducerOptions(producerOptions)
+	retryTopic := cfg.Kafka.AIProcessing.ArticlesRetryTopic
+	articleProcessor.SetRetry(worker.RetryConfig{
+		Topic:      retryTopic,
+		MaxRetries: cfg.AIService.MaxRetries,
+		Delay:      retryDelay,
+		MaxDelay:   retryMaxDelay,
+	})
+	articleProcessor.SetBudget(core.NewBudget(models.LLMBudget{
+		DailyCostUSD: cfg.AIService.DailyBudgetUSD,
+		DailyTokens:  cfg.AIService.DailyTokenBudget,
+	}, credentials, log))
+	batchWindow, err := time.ParseDuration(cfg.AIService.BatchWindow)
+	if err != nil {
+		a.Fatal("failed to parse batch window", "value", cfg.AIService.BatchWindow, "error", err)
+	}
+	articleProcessor.SetBatching(worker.BatchConfig{
+		Size:        cfg.AIService.BatchSize,
+		Window:      batchWindow,
+		Concurrency: cfg.AIService.BatchConcurrency,
+	})
+	processedEventRetention, err := cfg.AIService.ProcessedEventRetentionDuration()
+	if err != nil {
+		a.Fatal("invalid processed event retention", "error", err)
+	}
+	if processedEventRetention > 0 {
+		processedEvents := core.NewProcessedEvents(repository.NewProcessedEventRepository(db), core.DefaultProcessedEventClaimTimeout, log)
+		articleProcessor.SetProcessedEvents(processedEvents)
+		a.Go("processed event purge", func(ctx context.Context) error {
+			processedEvents.RunPurge(ctx, processedEventRetention)
+			return nil
+		})
+	}
+	if registry := cfg.Kafka.SchemaRegistry; registry.URL != "" {
+		schemas := events.NewSchemaPolicy(events.NewSchemaRegistry(registry.URL), registry.Topics, registry.RequiredTopics)
+		articleProcessor.SetSchemaCodecs(
+			schemas.Codec(articlesNewTopic, &article_eventspb.ArticlePersistedEvent{}),
+			schemas.Codec(articlesProcessedTopic, &article_eventspb.ArticleProcessedEvent{}),
+		)
+		log.Info("schema registry enabled", "url", registry.URL, "topics", registry.Topics, "required_topics", registry.RequiredTopics)
+	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	if producerOptions.Sizes != nil {
+		a.Go("kafka message size report", func(ctx context.Context) error {
+			producerOptions.Sizes.Run(ctx, log, sizeReportInterval, compression)
+			return nil
+		})
+	}
 
-	// Handle graceful shutdown
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	// the processor closes its reader and writer once its loop has returned
+	a.Add("article processor shutdown", nil, articleProcessor.Stop)
+	a.Go("article processor", articleProcessor.Start)
+	if retryTopic != "" && cfg.AIService.MaxRetries > 0 {
+		retryRelay := worker.NewRetryRelay(log, cfg.Kafka.Brokers, cfg.Kafka.AIProcessing.AIServiceRetryGroupID, retryTopic, articlesNewTopic, producerOptions)
+		a.Go("article retry relay", retryRelay.Start)
+	}
 
 	log.Info("starting AI service",
 		"llm_model", cfg.AIService.LLMModel,
 		"request_timeout", cfg.AIService.RequestTimeout,
-		"articles_new_topic", cfg.Kafka.AIProcessing.ArticlesNewTopic,
-		"articles_processed_topic", cfg.Kafka.AIProcessing.ArticlesProcessedTopic,
+		"summary_max_tokens", cfg.AIService.SummaryMaxTokens,
+		"summary_cache", cfg.AIService.SummaryCacheEnabled,
+		"summary_prompts", cfg.AIService.SummaryPrompts,
+		"prompt_dir", cfg.AIService.PromptDir,
+		"max_reader_languages", cfg.Summaries.MaxReaderLanguages,
+		"daily_budget_usd", cfg.AIService.DailyBudgetUSD,
+		"daily_token_budget", cfg.AIService.DailyTokenBudget,
+		"llm_max_concurrency", cfg.AIService.LLMMaxConcurrency,
+		"llm_retry_max_attempts", cfg.AIService.LLMRetryMaxAttempts,
+		"max_retries", cfg.AIService.MaxRetries,
+		"articles_retry_topic", retryTopic,
+		"articles_new_topic", articlesNewTopic,
+		"articles_processed_topic", articlesProcessedTopic,
 	)
-
-	// Start article processor
-	go func() {
-		if err := articleProcessor.Start(ctx); err != nil && err != context.Canceled {
-			log.Error("article processor failed", "error", err)
-			cancel()
-		}
-	}()
-
-	// Wait for shutdown signal
-	select {
-	case sig := <-signalChan:
-		log.Info("received shutdown signal", "signal", sig)
-		cancel()
-	case <-ctx.Done():
-	}
-
-	// Graceful shutdown
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer shutdownCancel()
-
-	if err := articleProcessor.Stop(shutdownCtx); err != nil {
-		log.Error("failed to stop article processor gracefully", "error", err)
+	if err := a.Run(context.Background()); err != nil {
+		os.Exit(1)
 	}
-
-	log.Info("AI service shutdown completed")
 }
@@ -4,11 +4,13 @@ import (
 	"context"
 	"embed"
 	"fmt"
-	"log/slog"
 	"os"
 	"time"
+	// folder digests follow the wall clock of IANA time zones, which the image lacks
+	_ "time/tzdata"
 
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	gormlogger "gorm.io/gorm/logger"
@@ -16,47 +18,85 @@ import (
 	"github.com/Fancu1/phoenix-rss/internal/api-service/core"
 	"github.com/Fancu1/phoenix-rss/internal/api-service/server"
 	"github.com/Fancu1/phoenix-rss/internal/config"
-	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/internal/events"
+	feedcore "github.com/Fancu1/phoenix-rss/internal/feed-service/core"
+	"github.com/Fancu1/phoenix-rss/pkg/app"
+	"github.com/Fancu1/phoenix-rss/pkg/grpctls"
+	"github.com/Fancu1/phoenix-rss/pkg/serviceauth"
+	"github.com/Fancu1/phoenix-rss/pkg/tracing"
 )
 
 //go:embed all:dist
 var staticFiles embed.FS
 
 func main() {
-	if err := logger.InitFromEnv(); err != nil {
-		fmt.Printf("Failed to initialize logger: %v\n", err)
+	a, err := app.New("api-service")
+	if err != nil {
+		fmt.Printf("Failed to initialize service: %v\n", err)
 		os.Exit(1)
 	}
-	defer logger.Close()
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		fmt.Printf("Failed to load config: %v\n", err)
-		os.Exit(1)
+		a.Fatal("failed to load config", "error", err)
 	}
 
-	appLogger := logger.New(slog.LevelDebug)
+	appLogger := a.Logger()
+	if err := a.SetupTracing(cfg.Tracing.Params()); err != nil {
+		a.Fatal("failed to set up tracing", "error", err)
+	}
+
+	var dialOptions []grpc.DialOption
+	if cfg.Auth.ServiceToken != "" {
+		dialOptions = append(dialOptions, serviceauth.NewSigner("api-service", cfg.Auth.ServiceToken).DialOption())
+	} else {
+		appLogger.Warn("AUTH_SERVICE_TOKEN is not set, calls to the feed and user services are unauthenticated")
+	}
 
-	feedSvc, err := core.NewFeedServiceClient(cfg.FeedService.Address)
+	feedCreds, err := grpctls.ClientCredentials(cfg.FeedService.TLS.Params())
 	if err != nil {
-		appLogger.Error("failed to connect to feed service", "address", cfg.FeedService.Address, "error", err)
-		os.Exit(1)
+		a.Fatal("failed to set up feed service TLS", "error", err)
+	}
+	userCreds, err := grpctls.ClientCredentials(cfg.UserService.TLS.Params())
+	if err != nil {
+		a.Fatal("failed to set up user service TLS", "error", err)
 	}
-	defer feedSvc.Close()
 
-	articleSvc, err := core.NewArticleServiceClient(cfg.FeedService.Address)
+	// retries and breakers run before the signer so that every attempt is signed afresh
+	clientsCfg := cfg.Server.GRPCClients
+	backoffInitial, backoffMax, openTimeout, err := clientsCfg.Durations()
 	if err != nil {
-		appLogger.Error("failed to connect to feed service for articles", "address", cfg.FeedService.Address, "error", err)
-		os.Exit(1)
+		a.Fatal("invalid gRPC client settings", "error", err)
 	}
-	defer articleSvc.Close()
+	resilienceCfg := core.ResilienceConfig{
+		MaxAttempts:      clientsCfg.RetryMaxAttempts,
+		BackoffInitial:   backoffInitial,
+		BackoffMax:       backoffMax,
+		FailureThreshold: clientsCfg.BreakerFailureThreshold,
+		OpenTimeout:      openTimeout,
+	}
+	feedResilience := core.NewResilience("feed-service", resilienceCfg, appLogger)
+	userResilience := core.NewResilience("user-service", resilienceCfg, appLogger)
+	feedDialOptions := append([]grpc.DialOption{grpc.WithTransportCredentials(feedCreds), feedResilience.DialOption()}, dialOptions...)
+	userDialOptions := append([]grpc.DialOption{grpc.WithTransportCredentials(userCreds), userResilience.DialOption()}, dialOptions...)
 
-	userSvc, err := core.NewUserServiceClient(cfg.UserService.Address)
+	feedSvc, err := core.NewFeedServiceClient(cfg.FeedService.Address, feedDialOptions...)
 	if err != nil {
-		appLogger.Error("failed to connect to user service", "address", cfg.UserService.Address, "error", err)
-		os.Exit(1)
+		a.Fatal("failed to connect to feed service", "address", cfg.FeedService.Address, "error", err)
+	}
+	a.Closer("feed service client", feedSvc)
+
+	articleSvc, err := core.NewArticleServiceClient(cfg.FeedService.Address, feedDialOptions...)
+	if err != nil {
+		a.Fatal("failed to connect to feed service for articles", "address", cfg.FeedService.Address, "error", err)
+	}
+	a.Closer("article service client", articleSvc)
+
+	userSvc, err := core.NewUserServiceClient(cfg.UserService.Address, userDialOptions...)
+	if err != nil {
+		a.Fatal("failed to connect to user service", "address", cfg.UserService.Address, "error", err)
 	}
-	defer userSvc.Close()
+	a.Closer("user service client", userSvc)
 
 	redisClient := redis.NewClient(&redis.Options{Addr: cfg.Redis.Address})
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -64,7 +104,7 @@ func main() {
 		appLogger.Warn("redis ping failed, token cache will be best-effort", "address", cfg.Redis.Address, "error", err)
 	}
 	cancel()
-	defer redisClient.Close()
+	a.Closer("redis", redisClient)
 
 	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		cfg.Database.Host, cfg.Database.Port, cfg.Database.User,
@@ -73,20 +113,78 @@ func main() {
 		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
 	})
 	if err != nil {
-		appLogger.Error("failed to connect to database", "error", err)
-		os.Exit(1)
+		a.Fatal("failed to connect to database", "error", err)
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		a.Fatal("failed to enable database tracing", "error", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		a.Fatal("failed to open database", "error", err)
+	}
+	a.Closer("database", sqlDB)
+
+	if demo := cfg.Server.Demo; demo.Enabled {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := core.SeedDemo(ctx, userSvc, feedSvc, core.DemoAccount{
+			Username: demo.Username,
+			Password: demo.Password,
+			Feeds:    demo.Feeds,
+		}, appLogger)
+		cancel()
+		if err != nil {
+			a.Fatal("failed to set up the demo user", "error", err)
+		}
 	}
-	sqlDB, _ := db.DB()
-	defer sqlDB.Close()
 
 	srv, err := server.New(cfg, db, feedSvc, articleSvc, userSvc, redisClient, staticFiles)
 	if err != nil {
-		appLogger.Error("failed to create server", "error", err)
-		os.Exit(1)
+		a.Fatal("failed to create server", "error", err)
+	}
+
+	srv.SetGRPCClients(feedResilience, userResilience)
+
+	// opening an older article queues an update check, published here since the REST
+	// article view reads the database directly
+	onReadMinAge, checkedWithin, cooldown, err := cfg.FeedService.ArticleUpdate.OnRead.Durations()
+	if err != nil {
+		a.Fatal("invalid on-read article check settings", "error", err)
+	}
+	if onReadMinAge > 0 {
+		var routing *events.Routing
+		if cfg.Kafka.Routing.Enabled {
+			routing, err = events.NewRouting(cfg.Kafka.Routing.Topic, cfg.Kafka.Routing.Strict, cfg.Kafka.Routing.EventTypes)
+			if err != nil {
+				a.Fatal("invalid kafka routing config", "error", err)
+			}
+		}
+		compression, err := events.ParseCompression(cfg.Kafka.Compression)
+		if err != nil {
+			a.Fatal("invalid kafka compression", "value", cfg.Kafka.Compression, "error", err)
+		}
+		articleCheckProducer := events.NewKafkaArticleCheckProducer(appLogger, events.KafkaConfig{
+			Brokers: cfg.Kafka.Brokers,
+			Topic:   routing.TopicFor(events.EventArticleCheck, cfg.Kafka.ArticleCheck.Topic),
+		})
+		articleCheckProducer.SetProducerOptions(events.ProducerOptions{Compression: compression})
+		a.Closer("article check producer", articleCheckProducer)
+		srv.SetReadRechecker(feedcore.NewReadRechecker(articleCheckProducer, feedcore.NewRedisReadRecheckStore(redisClient), feedcore.ReadRecheckConfig{
+			MinAge:        onReadMinAge,
+			CheckedWithin: checkedWithin,
+			Cooldown:      cooldown,
+		}))
+		appLogger.Info("on-read article checks enabled", "min_age", onReadMinAge.String(), "checked_within", checkedWithin.String(), "cooldown", cooldown.String())
+	}
+
+	if digests := srv.Digests(); digests != nil {
+		a.Go("folder digests", digests.Start)
+	}
+
+	for _, httpServer := range srv.HTTPServers() {
+		a.ServeHTTP("HTTP server "+httpServer.Addr, httpServer)
 	}
 
-	if err := srv.Start(); err != nil {
-		appLogger.Error("failed to start server", "error", err)
+	if err := a.Run(context.Background()); err != nil {
 		os.Exit(1)
 	}
 }
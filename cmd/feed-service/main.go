@@ -3,51 +3,87 @@ package main
 import (
 	"context"
 	"fmt"
-	"log/slog"
-	"net"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
-	"golang.org/x/sync/errgroup"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/health"
-	"google.golang.org/grpc/health/grpc_health_v1"
 
+	"github.com/Fancu1/phoenix-rss/internal/archive"
 	"github.com/Fancu1/phoenix-rss/internal/config"
 	"github.com/Fancu1/phoenix-rss/internal/events"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/core"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/handler"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/worker"
+	"github.com/Fancu1/phoenix-rss/internal/policy"
+	"github.com/Fancu1/phoenix-rss/pkg/app"
+	"github.com/Fancu1/phoenix-rss/pkg/dbtx"
+	"github.com/Fancu1/phoenix-rss/pkg/grpctls"
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
 	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/secrets"
+	"github.com/Fancu1/phoenix-rss/pkg/serviceauth"
+	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
 	feedpb "github.com/Fancu1/phoenix-rss/protos/gen/go/feed"
 )
 
 func main() {
-	if err := logger.InitFromEnv(); err != nil {
-		fmt.Printf("Failed to initialize logger: %v\n", err)
+	a, err := app.New("feed-service")
+	if err != nil {
+		fmt.Printf("Failed to initialize service: %v\n", err)
 		os.Exit(1)
 	}
-	defer logger.Close()
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		fmt.Printf("Failed to load config: %v\n", err)
-		os.Exit(1)
+		a.Fatal("failed to load config", "error", err)
 	}
 
-	log := logger.New(slog.LevelDebug)
+	log := a.Logger()
+	if err := a.SetupTracing(cfg.Tracing.Params()); err != nil {
+		a.Fatal("failed to set up tracing", "error", err)
+	}
 
 	db := repository.InitDB(&cfg.Database)
+	sqlDB, err := db.DB()
+	if err != nil {
+		a.Fatal("failed to open database", "error", err)
+	}
+	a.Closer("database", sqlDB)
+	var routing *events.Routing
+	if cfg.Kafka.Routing.Enabled {
+		routing, err = events.NewRouting(cfg.Kafka.Routing.Topic, cfg.Kafka.Routing.Strict, cfg.Kafka.Routing.EventTypes)
+		if err != nil {
+			a.Fatal("invalid kafka routing config", "error", err)
+		}
+	}
+
+	compression, err := events.ParseCompression(cfg.Kafka.Compression)
+	if err != nil {
+		a.Fatal("invalid kafka compression", "value", cfg.Kafka.Compression, "error", err)
+	}
+	var sizeReportInterval time.Duration
+	if cfg.Kafka.SizeReportInterval != "" {
+		sizeReportInterval, err = time.ParseDuration(cfg.Kafka.SizeReportInterval)
+		if err != nil {
+			a.Fatal("failed to parse kafka size report interval", "value", cfg.Kafka.SizeReportInterval, "error", err)
+		}
+	}
+	producerOptions := events.ProducerOptions{Compression: compression}
+	if sizeReportInterval > 0 {
+		producerOptions.Sizes = events.NewMessageSizes()
+	}
 
 	feedRepo := repository.NewFeedRepository(db)
 	articleRepo := repository.NewArticleRepository(db)
 
-	aiEventProducer := events.NewKafkaArticleEventProducer(log, cfg.Kafka.Brokers, cfg.Kafka.AIProcessing.ArticlesNewTopic)
-	defer aiEventProducer.Close()
+	aiEventProducer := events.NewKafkaArticleEventProducer(log, cfg.Kafka.Brokers,
+		routing.TopicFor(events.EventArticlePersisted, cfg.Kafka.AIProcessing.ArticlesNewTopic))
+	aiEventProducer.SetProducerOptions(producerOptions)
+	a.Closer("article persisted producer", aiEventProducer)
 
 	aiEventConsumer := events.NewKafkaArticleEventConsumer(
 		log,
@@ -56,47 +92,118 @@ func main() {
 		cfg.Kafka.AIProcessing.ArticlesProcessedTopic,
 	)
 
+	// the processed event codec also serves the routed consumer, so it follows the routing
+	var processedCodec *events.ProtoCodec
+	if registry := cfg.Kafka.SchemaRegistry; registry.URL != "" {
+		schemas := events.NewSchemaPolicy(events.NewSchemaRegistry(registry.URL), registry.Topics, registry.RequiredTopics)
+		aiEventProducer.SetSchemaCodec(schemas.Codec(
+			routing.TopicFor(events.EventArticlePersisted, cfg.Kafka.AIProcessing.ArticlesNewTopic),
+			&article_eventspb.ArticlePersistedEvent{}))
+		processedCodec = schemas.Codec(
+			routing.TopicFor(events.EventArticleProcessed, cfg.Kafka.AIProcessing.ArticlesProcessedTopic),
+			&article_eventspb.ArticleProcessedEvent{})
+		aiEventConsumer.SetSchemaCodec(processedCodec)
+		log.Info("schema registry enabled", "url", registry.URL, "topics", registry.Topics, "required_topics", registry.RequiredTopics)
+	}
+
 	// Initialize Kafka producer for feed.fetch events (needed by FeedService for async subscription)
 	feedFetchProducer := events.NewKafkaProducer(log, events.KafkaConfig{
 		Brokers: cfg.Kafka.Brokers,
-		Topic:   cfg.Kafka.FeedFetch.Topic,
+		Topic:   routing.TopicFor(events.EventFeedFetch, cfg.Kafka.FeedFetch.Topic),
 		GroupID: cfg.Kafka.FeedFetch.FeedServiceGroupID,
 	})
-	defer feedFetchProducer.Close()
+	feedFetchProducer.SetProducerOptions(producerOptions)
+	a.Closer("feed fetch producer", feedFetchProducer)
+
+	// events are stored with the rows they describe and relayed to Kafka, so a broker
+	// outage delays them instead of losing them
+	outboxInterval, outboxRetention, err := cfg.FeedService.Outbox.Durations()
+	if err != nil {
+		a.Fatal("invalid outbox settings", "error", err)
+	}
+	uow := dbtx.NewUnitOfWork(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	outbox := core.NewEventOutbox(outboxRepo)
+	outboxRelay := core.NewOutboxRelay(outboxRepo, feedFetchProducer, aiEventProducer, cfg.FeedService.Outbox.BatchSize, log)
 
 	// FeedService now supports async subscription via Kafka producer
 	feedService := core.NewFeedService(feedRepo, log, feedFetchProducer)
+	feedService.SetUnitOfWork(uow)
+	feedService.SetOutbox(outbox)
 	articleService := core.NewArticleService(feedRepo, articleRepo, aiEventProducer, log)
+	articleService.SetOutbox(outbox, uow)
+
+	httpClients := core.NewHTTPClientFactory(core.FetchIdentity{
+		UserAgent: cfg.Fetch.UserAgent,
+		From:      cfg.Fetch.From,
+		InfoURL:   cfg.Fetch.InfoURL,
+	})
+	httpClients.SetMetrics(httpclient.LogMetrics{Logger: log})
+	if cfg.Fetch.BlockPrivateNetworks {
+		httpClients.SetGuard(httpclient.BlockPrivateNetworks)
+	}
+	if hostRate := cfg.Fetch.HostRate; hostRate.RequestsPerSecond > 0 {
+		httpClients.SetHostRateLimiter(core.NewHostRateLimiter(hostRate.RequestsPerSecond, hostRate.Burst))
+		log.Info("per-host rate limit enabled", "requests_per_second", hostRate.RequestsPerSecond, "burst", hostRate.Burst)
+	}
+	feedService.SetHTTPClientFactory(httpClients)
+	articleService.SetHTTPClientFactory(httpClients)
+
+	// custom fetch headers on subscriptions are encrypted with the credentials key
+	credentialCipher, err := secrets.NewCipher(cfg.Auth.CredentialsKey)
+	if err != nil {
+		a.Fatal("failed to initialize credentials cipher", "error", err)
+	}
+	feedService.SetSecretEncrypter(credentialCipher)
+	feedService.SetDeletedFeedRetention(models.FeedRetention(cfg.FeedService.DeletedFeedRetention))
+	feedService.SetFailureThreshold(cfg.FeedService.Health.FailureThreshold)
+
+	policies, err := policy.Load(cfg)
+	if err != nil {
+		a.Fatal("failed to load policies", "file", cfg.Policy.File, "error", err)
+	}
+	feedService.SetPolicy(policies)
+	defaultPolicy := policies.Defaults()
+	log.Info("policies loaded", "file", cfg.Policy.File, "rules", len(policies.Document().Rules), "max_subscriptions", defaultPolicy.MaxSubscriptions)
+	articleService.SetImportPolicy(policies)
+	articleService.SetSecretDecrypter(credentialCipher)
+	if cfg.FeedService.Snapshots.Keep > 0 {
+		articleService.SetSnapshots(repository.NewSnapshotRepository(db), cfg.FeedService.Snapshots.Keep, cfg.FeedService.Snapshots.MaxBytes)
+	}
 
 	updateTimeout, err := time.ParseDuration(cfg.FeedService.ArticleUpdate.HTTPTimeout)
 	if err != nil {
-		log.Error("invalid article update http timeout", "value", cfg.FeedService.ArticleUpdate.HTTPTimeout, "error", err)
-		os.Exit(1)
+		a.Fatal("invalid article update http timeout", "value", cfg.FeedService.ArticleUpdate.HTTPTimeout, "error", err)
 	}
 	backoffInitial, err := time.ParseDuration(cfg.FeedService.ArticleUpdate.HTTPRetryBackoffInitial)
 	if err != nil {
-		log.Error("invalid article update backoff initial", "value", cfg.FeedService.ArticleUpdate.HTTPRetryBackoffInitial, "error", err)
-		os.Exit(1)
+		a.Fatal("invalid article update backoff initial", "value", cfg.FeedService.ArticleUpdate.HTTPRetryBackoffInitial, "error", err)
 	}
 	backoffMax, err := time.ParseDuration(cfg.FeedService.ArticleUpdate.HTTPRetryBackoffMax)
 	if err != nil {
-		log.Error("invalid article update backoff max", "value", cfg.FeedService.ArticleUpdate.HTTPRetryBackoffMax, "error", err)
-		os.Exit(1)
+		a.Fatal("invalid article update backoff max", "value", cfg.FeedService.ArticleUpdate.HTTPRetryBackoffMax, "error", err)
 	}
 	robotsTTL, err := time.ParseDuration(cfg.FeedService.ArticleUpdate.RobotsCacheTTL)
 	if err != nil {
-		log.Error("invalid robots cache ttl", "value", cfg.FeedService.ArticleUpdate.RobotsCacheTTL, "error", err)
-		os.Exit(1)
+		a.Fatal("invalid robots cache ttl", "value", cfg.FeedService.ArticleUpdate.RobotsCacheTTL, "error", err)
 	}
 
-	httpClient := &http.Client{Timeout: updateTimeout}
-	robotsClient := core.NewRobotsClient(httpClient, robotsTTL, log)
-	articleChecker := core.NewArticleUpdateChecker(articleRepo, log, httpClient, robotsClient, core.ArticleUpdateConfig{
-		UserAgent:       cfg.FeedService.ArticleUpdate.HTTPUserAgent,
-		MaxAttempts:     cfg.FeedService.ArticleUpdate.HTTPRetryMaxAttempts,
-		BackoffInitial:  backoffInitial,
-		BackoffMax:      backoffMax,
-		Jitter:          cfg.FeedService.ArticleUpdate.HTTPRetryJitter,
+	robotsClient := core.NewRobotsClient(httpClients.Client(httpclient.Options{
+		Name:    "robots",
+		Timeout: updateTimeout,
+	}), robotsTTL, log)
+	articleClient := httpClients.Client(httpclient.Options{
+		Name:    "article_update",
+		Timeout: updateTimeout,
+		Retry: httpclient.RetryPolicy{
+			MaxAttempts:    cfg.FeedService.ArticleUpdate.HTTPRetryMaxAttempts,
+			BackoffInitial: backoffInitial,
+			BackoffMax:     backoffMax,
+			Jitter:         cfg.FeedService.ArticleUpdate.HTTPRetryJitter,
+		},
+	})
+	articleChecker := core.NewArticleUpdateChecker(articleRepo, log, articleClient, robotsClient, core.ArticleUpdateConfig{
+		UserAgent:       httpClients.UserAgent(),
 		MaxContentBytes: cfg.FeedService.ArticleUpdate.MaxContentBytes,
 		RespectRobots:   cfg.FeedService.ArticleUpdate.RespectRobots,
 	})
@@ -107,10 +214,73 @@ func main() {
 		Topic:   cfg.Kafka.ArticleCheck.Topic,
 		GroupID: cfg.Kafka.ArticleCheck.FeedServiceGroupID,
 	}, articleUpdateWorker.HandleArticleCheck)
-	defer articleCheckConsumer.Stop(context.Background())
 
-	// FeedFetcher now handles metadata updates for pending feeds
 	feedFetcher := worker.NewFeedFetcher(log, articleService, feedRepo)
+	feedFetcher.SetFailureThreshold(cfg.FeedService.Health.FailureThreshold)
+	if alerts := cfg.FeedService.Alerts; alerts.WebhookURL != "" {
+		failureRateWindow, err := time.ParseDuration(alerts.FailureRateWindow)
+		if err != nil {
+			a.Fatal("invalid alerts failure rate window", "value", alerts.FailureRateWindow, "error", err)
+		}
+		alertCooldown, err := time.ParseDuration(alerts.Cooldown)
+		if err != nil {
+			a.Fatal("invalid alerts cooldown", "value", alerts.Cooldown, "error", err)
+		}
+		feedFetcher.SetAlerter(core.NewOperatorAlerter(core.NewWebhookAlertSink(alerts.WebhookURL, alerts.Format), core.OperatorAlertConfig{
+			PopularFeedSubscribers: alerts.PopularFeedSubscribers,
+			FailureRate:            alerts.FailureRate,
+			FailureRateWindow:      failureRateWindow,
+			FailureRateMinFetches:  alerts.FailureRateMinFetches,
+			Cooldown:               alertCooldown,
+		}, log))
+		log.Info("operator alerts enabled", "format", alerts.Format, "popular_feed_subscribers", alerts.PopularFeedSubscribers, "failure_rate", alerts.FailureRate)
+	}
+
+	hostPause := cfg.FeedService.ArticleUpdate.HostPause
+	onReadMinAge, checkedWithin, cooldown, err := cfg.FeedService.ArticleUpdate.OnRead.Durations()
+	if err != nil {
+		a.Fatal("invalid on-read article check settings", "error", err)
+	}
+	// the api-service caches feed lists in Redis, which go stale when a fetch retitles a feed
+	redisClient := redis.NewClient(&redis.Options{Addr: cfg.Redis.Address})
+	a.Closer("redis", redisClient)
+	articleService.SetFeedListCache(core.NewFeedListCache(redisClient))
+
+	crawlBudgeted := defaultPolicy.CrawlDailyRequests > 0 || policies.Overrides(policy.SettingCrawlDailyRequests)
+	if crawlBudgeted || hostPause.FailureThreshold > 0 || onReadMinAge > 0 {
+		if err := redisClient.Ping(context.Background()).Err(); err != nil {
+			log.Warn("redis ping failed, crawl budget and host pauses will be best-effort", "address", cfg.Redis.Address, "error", err)
+		}
+		if crawlBudgeted {
+			budget := core.NewCrawlBudget(core.NewRedisCrawlBudgetStore(redisClient), defaultPolicy.CrawlDailyRequests)
+			budget.SetLimits(core.NewPolicyCrawlLimits(policies, feedRepo))
+			feedFetcher.SetCrawlBudget(budget)
+			articleChecker.SetCrawlBudget(budget)
+			log.Info("crawl budget enabled", "daily_requests", defaultPolicy.CrawlDailyRequests, "per_feed_rules", policies.Overrides(policy.SettingCrawlDailyRequests))
+		}
+		if hostPause.FailureThreshold > 0 {
+			pause, err := time.ParseDuration(hostPause.Pause)
+			if err != nil || pause <= 0 {
+				a.Fatal("invalid article update host pause", "value", hostPause.Pause, "error", err)
+			}
+			articleChecker.SetHostBreaker(core.NewHostBreaker(core.NewRedisHostBreakerStore(redisClient), hostPause.FailureThreshold, pause))
+			log.Info("article check host pauses enabled", "failure_threshold", hostPause.FailureThreshold, "pause", pause.String())
+		}
+		if onReadMinAge > 0 {
+			articleCheckProducer := events.NewKafkaArticleCheckProducer(log, events.KafkaConfig{
+				Brokers: cfg.Kafka.Brokers,
+				Topic:   routing.TopicFor(events.EventArticleCheck, cfg.Kafka.ArticleCheck.Topic),
+			})
+			articleCheckProducer.SetProducerOptions(producerOptions)
+			a.Closer("article check producer", articleCheckProducer)
+			articleService.SetReadRechecker(core.NewReadRechecker(articleCheckProducer, core.NewRedisReadRecheckStore(redisClient), core.ReadRecheckConfig{
+				MinAge:        onReadMinAge,
+				CheckedWithin: checkedWithin,
+				Cooldown:      cooldown,
+			}))
+			log.Info("on-read article checks enabled", "min_age", onReadMinAge.String(), "checked_within", checkedWithin.String(), "cooldown", cooldown.String())
+		}
+	}
 
 	feedFetchConsumer := events.NewKafkaConsumer(log, events.KafkaConfig{
 		Brokers: cfg.Kafka.Brokers,
@@ -119,100 +289,129 @@ func main() {
 	}, feedFetcher.HandleFeedFetch)
 
 	aiResultHandler := worker.NewAIResultHandler(log, articleService, aiEventConsumer)
+	aiResultsBatchWait, err := time.ParseDuration(cfg.FeedService.AIResults.BatchWait)
+	if err != nil {
+		a.Fatal("invalid AI results batch wait", "value", cfg.FeedService.AIResults.BatchWait, "error", err)
+	}
+	aiResultHandler.SetBatching(events.BatchOptions{MaxSize: cfg.FeedService.AIResults.BatchSize, MaxWait: aiResultsBatchWait})
 
-	grpcHandler := handler.NewFeedServiceHandler(log, feedService, articleService, feedFetchProducer)
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
-
-	g, ctx := errgroup.WithContext(ctx)
-
-	g.Go(func() error {
-		return startGRPCServer(ctx, grpcHandler, cfg.FeedService.Port, log)
-	})
-
-	g.Go(func() error {
-		log.Info("starting Kafka consumer")
-		return feedFetchConsumer.Start(ctx)
-	})
+	deadFeedThreshold, err := time.ParseDuration(cfg.FeedService.DeadFeed.Threshold)
+	if err != nil {
+		a.Fatal("invalid dead feed threshold", "value", cfg.FeedService.DeadFeed.Threshold, "error", err)
+	}
+	deadFeedInterval, err := time.ParseDuration(cfg.FeedService.DeadFeed.CheckInterval)
+	if err != nil {
+		a.Fatal("invalid dead feed check interval", "value", cfg.FeedService.DeadFeed.CheckInterval, "error", err)
+	}
+	deadFeedDetector := worker.NewDeadFeedDetector(log, feedRepo, deadFeedThreshold, deadFeedInterval)
 
-	g.Go(func() error {
-		log.Info("starting AI event handler")
-		return aiResultHandler.Start(ctx)
-	})
+	trashGrace := defaultPolicy.TrashRetention
+	trashPurgeInterval, err := time.ParseDuration(cfg.FeedService.ArticleTrash.PurgeInterval)
+	if err != nil {
+		a.Fatal("invalid article trash purge interval", "value", cfg.FeedService.ArticleTrash.PurgeInterval, "error", err)
+	}
+	articleService.SetTrashGracePeriod(trashGrace)
+	articleService.SetSummaryLanguage(cfg.Summaries.DefaultLanguage())
+	trashPurger := worker.NewArticleTrashPurger(log, articleRepo, trashGrace, trashPurgeInterval)
 
-	g.Go(func() error {
-		log.Info("starting article check consumer")
-		return articleCheckConsumer.Start(ctx)
-	})
+	exportObjects, err := archive.OpenStorage(cfg)
+	if err != nil {
+		a.Fatal("failed to open export storage", "storage", cfg.Exports.Storage, "error", err)
+	}
+	exportInterval, err := time.ParseDuration(cfg.Exports.PollInterval)
+	if err != nil {
+		a.Fatal("invalid export poll interval", "value", cfg.Exports.PollInterval, "error", err)
+	}
+	exportStaleAfter, err := time.ParseDuration(cfg.Exports.StaleAfter)
+	if err != nil {
+		a.Fatal("invalid export stale after", "value", cfg.Exports.StaleAfter, "error", err)
+	}
+	exporter := archive.NewExporter(archive.NewStore(db), exportObjects, cfg.Exports.PageSize, exportStaleAfter, log)
+	exportWorker := worker.NewArticleExportWorker(log, exporter, exportInterval)
 
-	g.Go(func() error {
-		select {
-		case sig := <-signalChan:
-			log.Info("received shutdown signal", "signal", sig)
-			cancel()
-			return nil
-		case <-ctx.Done():
-			return ctx.Err()
+	// event types moved to the shared topic are consumed by a single routed consumer
+	dispatcher := events.NewDispatcher(log, cfg.Kafka.Routing.Strict)
+	if routing.Routes(events.EventFeedFetch) {
+		dispatcher.Register(events.EventFeedFetch, events.FeedFetchHandler(feedFetcher.HandleFeedFetch))
+	}
+	if routing.Routes(events.EventArticleCheck) {
+		dispatcher.Register(events.EventArticleCheck, events.ArticleCheckHandler(articleUpdateWorker.HandleArticleCheck))
+	}
+	if routing.Routes(events.EventArticleProcessed) {
+		if err := processedCodec.Check(context.Background()); err != nil {
+			a.Fatal("article processed schema is incompatible with the registry", "error", err)
 		}
-	})
-
-	if err := g.Wait(); err != nil && err != context.Canceled {
-		log.Error("Feed Service error", "error", err)
-		os.Exit(1)
+		dispatcher.Register(events.EventArticleProcessed, events.ArticleProcessedHandler(processedCodec, aiResultHandler.HandleArticleProcessed))
 	}
 
-	log.Info("Feed Service shutdown completed")
-}
+	grpcHandler := handler.NewFeedServiceHandler(log, feedService, articleService, outbox)
 
-func startGRPCServer(ctx context.Context, handler *handler.FeedServiceHandler, port int, log *slog.Logger) error {
-	address := fmt.Sprintf(":%d", port)
-	lis, err := net.Listen("tcp", address)
+	serverCreds, err := grpctls.ServerCredentials(cfg.FeedService.TLS.Params())
 	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %w", address, err)
+		a.Fatal("failed to set up gRPC TLS", "error", err)
+	}
+	interceptors := []grpc.UnaryServerInterceptor{logger.RequestIDServerInterceptor()}
+	if cfg.Auth.ServiceToken != "" {
+		skew, err := cfg.Auth.ServiceTokenSkew()
+		if err != nil {
+			a.Fatal("invalid service token max skew", "error", err)
+		}
+		interceptors = append(interceptors, serviceauth.NewVerifier(cfg.Auth.ServiceToken, skew).ServerInterceptor())
+	} else {
+		log.Warn("AUTH_SERVICE_TOKEN is not set, the gRPC server accepts calls from any peer")
 	}
 
-	grpcServer := grpc.NewServer()
-	feedpb.RegisterFeedServiceServer(grpcServer, handler)
-
-	// register gRPC health check service
-	healthServer := health.NewServer()
-	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
-	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
-
-	log.Info("starting gRPC server", "address", address)
-
-	serverErr := make(chan error, 1)
-	go func() {
-		serverErr <- grpcServer.Serve(lis)
-	}()
-
-	select {
-	case err := <-serverErr:
-		return fmt.Errorf("gRPC server error: %w", err)
-	case <-ctx.Done():
-		log.Info("gracefully stopping gRPC server")
-
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
+	if producerOptions.Sizes != nil {
+		a.Go("kafka message size report", func(ctx context.Context) error {
+			producerOptions.Sizes.Run(ctx, log, sizeReportInterval, compression)
+			return nil
+		})
+	}
 
-		shutdownComplete := make(chan struct{})
-		go func() {
-			grpcServer.GracefulStop()
-			close(shutdownComplete)
-		}()
+	// at shutdown the consumers stop taking events first, then close their readers, and
+	// the gRPC server drains its calls
+	drainPeriod, err := time.ParseDuration(cfg.FeedService.DrainPeriod)
+	if err != nil {
+		a.Fatal("invalid drain period", "value", cfg.FeedService.DrainPeriod, "error", err)
+	}
+	a.SetDrainPeriod(drainPeriod)
+	if !routing.Routes(events.EventFeedFetch) {
+		a.Intake("feed fetch consumer", feedFetchConsumer.Start, feedFetchConsumer.Stop)
+	}
+	if !routing.Routes(events.EventArticleProcessed) {
+		a.Intake("AI result handler", aiResultHandler.Start, nil)
+	}
+	if !routing.Routes(events.EventArticleCheck) {
+		a.Intake("article check consumer", articleCheckConsumer.Start, articleCheckConsumer.Stop)
+	}
+	if dispatcher.HasHandlers() {
+		routedConsumer := events.NewKafkaRoutedConsumer(log, events.KafkaConfig{
+			Brokers: cfg.Kafka.Brokers,
+			Topic:   routing.Topic,
+			GroupID: cfg.Kafka.Routing.FeedServiceGroupID,
+		}, dispatcher)
+		log.Info("routing Kafka events", "event_types", cfg.Kafka.Routing.EventTypes)
+		a.Intake("routed Kafka consumer", routedConsumer.Start, routedConsumer.Stop)
+	}
 
-		select {
-		case <-shutdownComplete:
-			log.Info("gRPC server stopped gracefully")
-		case <-shutdownCtx.Done():
-			log.Warn("gRPC server shutdown timeout, forcing stop")
-			grpcServer.Stop()
-		}
+	log.Info("starting background workers",
+		"dead_feed_threshold", deadFeedThreshold, "dead_feed_interval", deadFeedInterval,
+		"trash_grace_period", trashGrace, "trash_purge_interval", trashPurgeInterval,
+		"export_storage", cfg.Exports.Storage, "export_interval", exportInterval)
+	a.Go("dead feed detector", deadFeedDetector.Start)
+	a.Go("article trash purger", trashPurger.Start)
+	a.Go("article export worker", exportWorker.Start)
+	a.Go("outbox relay", worker.NewOutboxRelayWorker(log, outboxRelay, outboxInterval, outboxRetention).Start)
+
+	grpcServer := grpc.NewServer(
+		grpc.Creds(serverCreds),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(interceptors...),
+	)
+	feedpb.RegisterFeedServiceServer(grpcServer, grpcHandler)
+	a.ServeGRPC("gRPC server", grpcServer, fmt.Sprintf(":%d", cfg.FeedService.Port))
 
-		return nil
+	if err := a.Run(context.Background()); err != nil {
+		os.Exit(1)
 	}
 }
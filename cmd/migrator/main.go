@@ -1,17 +1,23 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 
 	"github.com/Fancu1/phoenix-rss/internal/config"
+	"github.com/Fancu1/phoenix-rss/internal/migrations"
 )
 
 func main() {
@@ -50,9 +56,15 @@ func run() error {
 		err = m.Up()
 		if errors.Is(err, migrate.ErrNoChange) {
 			fmt.Println("no change")
-			return nil
+		} else if err != nil {
+			return err
 		}
-		return err
+		// Go migrations (batched backfills, dual-write shims) run once the schema is in place
+		return runOnline(cfg)
+	case "online":
+		return runOnline(cfg)
+	case "online-status":
+		return printOnlineStatus(cfg)
 	case "down":
 		err = m.Down()
 		if errors.Is(err, migrate.ErrNoChange) {
@@ -77,6 +89,58 @@ func run() error {
 	}
 }
 
+func openRunner(cfg *config.Config) (*migrations.Runner, error) {
+	db := cfg.Database
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+		db.Host, db.User, db.Password, db.DBName, db.Port, db.SSLMode)
+
+	conn, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Warn)})
+	if err != nil {
+		return nil, fmt.Errorf("connect database: %w", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	return migrations.NewRunner(conn, logger), nil
+}
+
+func runOnline(cfg *config.Config) error {
+	runner, err := openRunner(cfg)
+	if err != nil {
+		return err
+	}
+
+	applied, err := runner.Apply(context.Background(), migrations.All)
+	if err != nil {
+		return fmt.Errorf("online migrations: %w", err)
+	}
+	fmt.Printf("online migrations applied: %d\n", applied)
+	return nil
+}
+
+func printOnlineStatus(cfg *config.Config) error {
+	runner, err := openRunner(cfg)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := runner.Status(context.Background(), migrations.All)
+	if err != nil {
+		return fmt.Errorf("online migrations: %w", err)
+	}
+	if len(statuses) == 0 {
+		fmt.Println("no online migrations registered")
+		return nil
+	}
+	for _, status := range statuses {
+		state := "pending"
+		if status.CompletedAt != nil {
+			state = "done " + status.CompletedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%-40s %-26s %s\n", status.ID, state, status.Description)
+	}
+	return nil
+}
+
 func buildPostgresURL(cfg *config.Config) string {
 	db := cfg.Database
 	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
@@ -87,7 +151,9 @@ func buildPostgresURL(cfg *config.Config) string {
 func usage() {
 	fmt.Println("usage: migrator <command>")
 	fmt.Println("commands:")
-	fmt.Println("  up         apply all pending migrations")
-	fmt.Println("  down       rollback all migrations")
-	fmt.Println("  version    print current version")
+	fmt.Println("  up             apply all pending SQL migrations, then pending online migrations")
+	fmt.Println("  online         apply pending online migrations (batched backfills, dual-write shims)")
+	fmt.Println("  online-status  list online migrations and whether they completed")
+	fmt.Println("  down           rollback all migrations")
+	fmt.Println("  version        print current version")
 }
@@ -20,10 +20,12 @@ func newAICmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "ai",
 		Short: "AI processing management",
-		Long:  `Manage AI processing for articles.`,
+		Long:  `Manage AI processing for articles and review LLM usage.`,
 	}
 
 	cmd.AddCommand(newAIProcessCmd())
+	cmd.AddCommand(newAIExpandCmd())
+	cmd.AddCommand(newAIUsageCmd())
 
 	return cmd
 }
@@ -57,6 +59,75 @@ func newAIProcessCmd() *cobra.Command {
 	return cmd
 }
 
+func newAIExpandCmd() *cobra.Command {
+	var feedID uint
+
+	cmd := &cobra.Command{
+		Use:   "expand",
+		Short: "Regenerate truncated summaries with the expanded token limit",
+		Long:  `Send articles whose summary was cut off at the LLM token limit back to AI processing with the expanded limit.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAIExpand(feedID)
+		},
+	}
+
+	cmd.Flags().UintVarP(&feedID, "feed-id", "f", 0, "Only regenerate articles in this feed")
+
+	return cmd
+}
+
+func runAIExpand(feedID uint) error {
+	ctx := context.Background()
+
+	query := db.WithContext(ctx).Where("summary_truncated = ?", true)
+	if feedID != 0 {
+		query = query.Where("feed_id = ?", feedID)
+	}
+
+	var articles []models.Article
+	if err := query.Order("published_at DESC").Find(&articles).Error; err != nil {
+		return fmt.Errorf("failed to get articles: %w", err)
+	}
+
+	if len(articles) == 0 {
+		fmt.Println("No truncated summaries found.")
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Println("=== AI Expand Request ===")
+	fmt.Println()
+	fmt.Printf("Truncated:    %d articles\n", len(articles))
+	fmt.Println()
+
+	displayCount := len(articles)
+	if displayCount > 10 {
+		displayCount = 10
+	}
+	for i := 0; i < displayCount; i++ {
+		fmt.Printf("  #%-4d %s\n", articles[i].ID, truncateString(articles[i].Title, 60))
+	}
+	if len(articles) > 10 {
+		fmt.Printf("  ... and %d more\n", len(articles)-10)
+	}
+
+	fmt.Println()
+	fmt.Print("Type 'yes' to continue: ")
+
+	if !confirmAction() {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	if err := sendToAIQueue(ctx, articles, true); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("Done! %d articles sent for regeneration.\n", len(articles))
+	return nil
+}
+
 func runAIProcessArticle(articleID uint) error {
 	ctx := context.Background()
 
@@ -90,7 +161,7 @@ func runAIProcessArticle(articleID uint) error {
 	}
 
 	// Send to AI processing queue
-	if err := sendToAIQueue(ctx, []models.Article{article}); err != nil {
+	if err := sendToAIQueue(ctx, []models.Article{article}, false); err != nil {
 		return err
 	}
 
@@ -164,7 +235,7 @@ func runAIProcessFeed(feedID uint) error {
 	}
 
 	// Send to AI processing queue
-	if err := sendToAIQueue(ctx, articles); err != nil {
+	if err := sendToAIQueue(ctx, articles, false); err != nil {
 		return err
 	}
 
@@ -173,17 +244,38 @@ func runAIProcessFeed(feedID uint) error {
 	return nil
 }
 
-func sendToAIQueue(ctx context.Context, articles []models.Article) error {
+// sendToAIQueue publishes the articles for AI processing. With expand set the AI service
+// uses its expanded token limit.
+func sendToAIQueue(ctx context.Context, articles []models.Article, expand bool) error {
 	// Load config for Kafka
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	topic := cfg.Kafka.AIProcessing.ArticlesNewTopic
+	if cfg.Kafka.Routing.Enabled {
+		routing, err := events.NewRouting(cfg.Kafka.Routing.Topic, cfg.Kafka.Routing.Strict, cfg.Kafka.Routing.EventTypes)
+		if err != nil {
+			return fmt.Errorf("invalid kafka routing config: %w", err)
+		}
+		topic = routing.TopicFor(events.EventArticlePersisted, topic)
+	}
+
+	compression, err := events.ParseCompression(cfg.Kafka.Compression)
+	if err != nil {
+		return fmt.Errorf("invalid kafka compression: %w", err)
+	}
+
 	// Create producer
 	log := logger.New(0) // quiet logger
-	producer := events.NewKafkaArticleEventProducer(log, cfg.Kafka.Brokers, cfg.Kafka.AIProcessing.ArticlesNewTopic)
+	producer := events.NewKafkaArticleEventProducer(log, cfg.Kafka.Brokers, topic)
+	producer.SetProducerOptions(events.ProducerOptions{Compression: compression})
 	defer producer.Close()
+	if registry := cfg.Kafka.SchemaRegistry; registry.URL != "" {
+		schemas := events.NewSchemaPolicy(events.NewSchemaRegistry(registry.URL), registry.Topics, registry.RequiredTopics)
+		producer.SetSchemaCodec(schemas.Codec(topic, &article_eventspb.ArticlePersistedEvent{}))
+	}
 
 	fmt.Println()
 	fmt.Printf("Processing %d articles...\n", len(articles))
@@ -197,6 +289,7 @@ func sendToAIQueue(ctx context.Context, articles []models.Article) error {
 			Url:         article.URL,
 			Description: article.Description,
 			PublishedAt: article.PublishedAt.Unix(),
+			Expand:      expand,
 		}
 
 		if err := producer.PublishArticlePersisted(ctx, event); err != nil {
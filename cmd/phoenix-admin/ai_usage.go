@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/config"
+)
+
+func newAIUsageCmd() *cobra.Command {
+	var days int
+
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Show LLM token usage and estimated cost per day",
+		Long:  `Show the LLM requests, tokens and estimated cost of each day by model and key source, and today's instance key usage against the daily budget.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if days < 1 {
+				return fmt.Errorf("--days must be at least 1")
+			}
+			return runAIUsage(days)
+		},
+	}
+
+	cmd.Flags().IntVarP(&days, "days", "d", 7, "Number of days to show, today included")
+
+	return cmd
+}
+
+func runAIUsage(days int) error {
+	ctx := context.Background()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	budget := models.LLMBudget{DailyCostUSD: cfg.AIService.DailyBudgetUSD, DailyTokens: cfg.AIService.DailyTokenBudget}
+
+	today := models.UsageDay(time.Now())
+	var rows []models.LLMUsageDaily
+	if err := db.WithContext(ctx).
+		Where("day >= ?", today.AddDate(0, 0, 1-days)).
+		Order("day DESC, byok ASC, cost_usd DESC, model ASC").
+		Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to get LLM usage: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Printf("%-10s | %-24s | %-8s | %8s | %12s | %12s | %10s\n", "Day", "Model", "Key", "Requests", "Prompt", "Completion", "Cost (USD)")
+	fmt.Println(strings.Repeat("-", 104))
+
+	var total, instanceToday models.LLMUsageTotals
+	for _, row := range rows {
+		key := "instance"
+		if row.BYOK {
+			key = "byok"
+		} else if row.Day.Equal(today) {
+			instanceToday.Add(row)
+		}
+		total.Add(row)
+		fmt.Printf("%-10s | %-24s | %-8s | %8d | %12d | %12d | %10.4f\n",
+			row.Day.Format("2006-01-02"), truncateString(row.Model, 24), key,
+			row.Requests, row.PromptTokens, row.CompletionTokens, row.CostUSD)
+	}
+	if len(rows) == 0 {
+		fmt.Println("No LLM usage recorded.")
+	}
+
+	fmt.Println()
+	fmt.Printf("Total:        %d requests, %d tokens, $%.4f\n", total.Requests, total.TotalTokens, total.CostUSD)
+	fmt.Printf("Today:        %d tokens, $%.4f with the instance key\n", instanceToday.TotalTokens, instanceToday.CostUSD)
+	switch {
+	case !budget.Enabled():
+		fmt.Println("Budget:       none")
+	case budget.Exceeded(instanceToday.CostUSD, instanceToday.TotalTokens):
+		fmt.Printf("Budget:       %s, exceeded, processing paused until tomorrow (UTC)\n", formatLLMBudget(budget))
+	default:
+		fmt.Printf("Budget:       %s\n", formatLLMBudget(budget))
+	}
+	fmt.Println()
+
+	return nil
+}
+
+// formatLLMBudget lists the limits of a budget, e.g. "$5.00/day, 1000000 tokens/day"
+func formatLLMBudget(budget models.LLMBudget) string {
+	var limits []string
+	if budget.DailyCostUSD > 0 {
+		limits = append(limits, fmt.Sprintf("$%.2f/day", budget.DailyCostUSD))
+	}
+	if budget.DailyTokens > 0 {
+		limits = append(limits, fmt.Sprintf("%d tokens/day", budget.DailyTokens))
+	}
+	return strings.Join(limits, ", ")
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/api-service/handler"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+// readerDeviceID names the reader in the read-state sync, so its changes can be told apart
+const readerDeviceID = "phoenix-admin-read"
+
+// apiClient talks to the REST API as a regular user. Unlike the other commands it needs
+// no database access, so it also works from a machine that only reaches the API.
+type apiClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+
+	// states caches the user's read and starred states, kept current through the sync API
+	states map[uint]models.UserArticleState
+	cursor string
+}
+
+func newAPIClient(baseURL, token string) *apiClient {
+	return &apiClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		client: httpclient.New(httpclient.Options{
+			Name:         "phoenix-admin-read",
+			Timeout:      30 * time.Second,
+			UserAgent:    "phoenix-admin read",
+			MaxBodyBytes: 32 << 20,
+		}),
+		states: make(map[uint]models.UserArticleState),
+	}
+}
+
+// login exchanges the credentials for a token
+func (c *apiClient) login(ctx context.Context, username, password string) error {
+	var resp handler.AuthResponse
+	err := c.do(ctx, http.MethodPost, "/users/login", handler.LoginRequest{Username: username, Password: password}, &resp)
+	if err != nil {
+		return fmt.Errorf("login as %s: %w", username, err)
+	}
+	c.token = resp.Token
+	return nil
+}
+
+func (c *apiClient) feeds(ctx context.Context) ([]models.UserFeed, error) {
+	var feeds []models.UserFeed
+	if err := c.do(ctx, http.MethodGet, "/feeds", nil, &feeds); err != nil {
+		return nil, fmt.Errorf("list feeds: %w", err)
+	}
+	return feeds, nil
+}
+
+// articles returns a page of a feed's articles, newest first
+func (c *apiClient) articles(ctx context.Context, feedID uint, page, pageSize int) (*handler.ArticleListResponse, error) {
+	query := url.Values{"page": {strconv.Itoa(page)}, "page_size": {strconv.Itoa(pageSize)}}
+	var resp handler.ArticleListResponse
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/feeds/%d/articles?%s", feedID, query.Encode()), nil, &resp); err != nil {
+		return nil, fmt.Errorf("list articles of feed %d: %w", feedID, err)
+	}
+	return &resp, nil
+}
+
+func (c *apiClient) article(ctx context.Context, articleID uint) (*handler.ArticleDetail, error) {
+	var resp handler.ArticleDetail
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/articles/%d", articleID), nil, &resp); err != nil {
+		return nil, fmt.Errorf("get article %d: %w", articleID, err)
+	}
+	return &resp, nil
+}
+
+// nextUnread returns the unread article after the given one, or nil when none is left.
+// With markRead the article given in after is marked read in the same request.
+func (c *apiClient) nextUnread(ctx context.Context, after uint, markRead bool) (*models.Article, error) {
+	query := url.Values{}
+	if after != 0 {
+		query.Set("after", strconv.FormatUint(uint64(after), 10))
+		query.Set("mark_read", strconv.FormatBool(markRead))
+	}
+	var article *models.Article
+	if err := c.do(ctx, http.MethodGet, "/articles/next-unread?"+query.Encode(), nil, &article); err != nil {
+		return nil, fmt.Errorf("find next unread article: %w", err)
+	}
+	if markRead && after != 0 {
+		c.remember(models.UserArticleState{ArticleID: after, Read: true, Starred: c.states[after].Starred})
+	}
+	return article, nil
+}
+
+// syncStates pulls the states changed since the last sync
+func (c *apiClient) syncStates(ctx context.Context) error {
+	for {
+		var page handler.ArticleStatesPage
+		path := "/sync/article-states?" + url.Values{"cursor": {c.cursor}}.Encode()
+		if err := c.do(ctx, http.MethodGet, path, nil, &page); err != nil {
+			return fmt.Errorf("sync article states: %w", err)
+		}
+		c.apply(page)
+		if !page.HasMore {
+			return nil
+		}
+	}
+}
+
+// setState marks an article read or unread (models.StateFieldRead), starred or unstarred
+// (models.StateFieldStarred)
+func (c *apiClient) setState(ctx context.Context, articleID uint, field string, value bool) error {
+	now := time.Now().UTC()
+	req := handler.PushArticleStatesRequest{
+		DeviceID: readerDeviceID,
+		Cursor:   c.cursor,
+		Changes:  []handler.ArticleStateChange{{ArticleID: articleID, Field: field, Value: value, ChangedAt: &now}},
+	}
+	var resp struct {
+		handler.ArticleStatesPage
+		Rejected []uint `json:"rejected"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/sync/article-states", req, &resp); err != nil {
+		return fmt.Errorf("set %s of article %d: %w", field, articleID, err)
+	}
+	if len(resp.Rejected) > 0 {
+		return fmt.Errorf("article %d was not found or is not in your feeds", articleID)
+	}
+	c.apply(resp.ArticleStatesPage)
+	return nil
+}
+
+func (c *apiClient) apply(page handler.ArticleStatesPage) {
+	for _, state := range page.States {
+		c.remember(state)
+	}
+	if page.Cursor != "" {
+		c.cursor = page.Cursor
+	}
+}
+
+func (c *apiClient) remember(state models.UserArticleState) {
+	c.states[state.ArticleID] = state
+}
+
+// isRead tells whether the article is read, from the synced states
+func (c *apiClient) isRead(article *models.Article) bool {
+	return c.states[article.ID].Read
+}
+
+func (c *apiClient) isStarred(article *models.Article) bool {
+	return c.states[article.ID].Starred
+}
+
+// do sends a request with the token and decodes the JSON response into out. A 204
+// leaves out untouched; API errors come back with their code and message.
+func (c *apiClient) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var apiErr ierr.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Message == "" {
+			return fmt.Errorf("API returned HTTP %d", resp.StatusCode)
+		}
+		return errors.New(apiErr.Message)
+	}
+	if resp.StatusCode == http.StatusNoContent || out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
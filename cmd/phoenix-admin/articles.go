@@ -77,7 +77,8 @@ func runArticlesList(limit int, feedID uint) error {
 	query := db.WithContext(ctx).
 		Table("articles").
 		Select("articles.*, feeds.title as feed_title").
-		Joins("LEFT JOIN feeds ON articles.feed_id = feeds.id")
+		Joins("LEFT JOIN feeds ON articles.feed_id = feeds.id").
+		Where("articles.deleted_at IS NULL")
 
 	countQuery := db.WithContext(ctx).Model(&models.Article{})
 
@@ -134,7 +135,7 @@ func runArticlesShow(articleID uint) error {
 		Table("articles").
 		Select("articles.*, feeds.title as feed_title").
 		Joins("LEFT JOIN feeds ON articles.feed_id = feeds.id").
-		Where("articles.id = ?", articleID).
+		Where("articles.id = ? AND articles.deleted_at IS NULL", articleID).
 		First(&article).Error
 
 	if err != nil {
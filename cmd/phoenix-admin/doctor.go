@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Fancu1/phoenix-rss/internal/doctor"
+)
+
+func newDoctorCmd() *cobra.Command {
+	var fix bool
+	var stuckAfter time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check and repair data integrity",
+		Long: `Scan for inconsistencies: subscriptions, articles, snapshots, notifications and LLM
+credentials whose feed or user no longer exists, feeds stored twice under equivalent URLs,
+and articles stuck in AI processing. With --fix each kind of problem is repaired in its own
+transaction. Without it the command fails when anything was found, so it can run from cron.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor(fix, stuckAfter)
+		},
+	}
+
+	cmd.Flags().BoolVar(&fix, "fix", false, "Repair what was found")
+	cmd.Flags().DurationVar(&stuckAfter, "stuck-after", doctor.DefaultStuckAfter, "How long an article may stay in AI processing before it counts as stuck")
+
+	return cmd
+}
+
+func runDoctor(fix bool, stuckAfter time.Duration) error {
+	findings, runErr := doctor.New(db, stuckAfter).Run(context.Background(), fix)
+
+	var found, unfixed int64
+	fmt.Println()
+	fmt.Printf("%-30s | %8s | %8s | %s\n", "Check", "Found", "Fixed", "Description")
+	fmt.Println(strings.Repeat("-", 100))
+	for _, finding := range findings {
+		fmt.Printf("%-30s | %8d | %8d | %s\n", finding.Check, finding.Count, finding.Fixed, finding.Description)
+		if finding.Count > 0 {
+			fmt.Printf("%-30s   e.g. %s\n", "", strings.Join(finding.Samples, ", "))
+			if !fix {
+				fmt.Printf("%-30s   --fix will %s\n", "", finding.Repair)
+			}
+		}
+		found += finding.Count
+		unfixed += finding.Count - finding.Fixed
+	}
+	fmt.Println()
+
+	if runErr != nil {
+		return runErr
+	}
+	if unfixed > 0 && !fix {
+		return fmt.Errorf("%d problems found, run with --fix to repair them", unfixed)
+	}
+	if unfixed > 0 {
+		return fmt.Errorf("%d problems were left unrepaired, they changed during the run", unfixed)
+	}
+	if found > 0 {
+		fmt.Printf("Repaired %d problems.\n", found)
+	} else {
+		fmt.Println("No problems found.")
+	}
+	return nil
+}
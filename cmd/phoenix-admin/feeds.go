@@ -3,23 +3,40 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/spf13/cobra"
 
+	"github.com/Fancu1/phoenix-rss/internal/config"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/core"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
+	"github.com/Fancu1/phoenix-rss/internal/policy"
+	"github.com/Fancu1/phoenix-rss/pkg/dbtx"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/secrets"
 )
 
 func newFeedsCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "feeds",
 		Short: "Manage feeds",
-		Long:  `List and view feed information.`,
+		Long:  `List, find and view feeds, and apply bulk actions to them.`,
 	}
 
 	cmd.AddCommand(newFeedsListCmd())
+	cmd.AddCommand(newFeedsFindCmd())
+	cmd.AddCommand(newFeedsBulkCmd())
 	cmd.AddCommand(newFeedsShowCmd())
+	cmd.AddCommand(newFeedsUnarchiveCmd())
+	cmd.AddCommand(newFeedsSnapshotCmd())
+	cmd.AddCommand(newFeedsDeleteCmd())
+	cmd.AddCommand(newFeedsPurgeOrphansCmd())
+	cmd.AddCommand(newFeedsBackfillCmd())
 
 	return cmd
 }
@@ -55,6 +72,88 @@ func newFeedsShowCmd() *cobra.Command {
 	return cmd
 }
 
+func newFeedsUnarchiveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unarchive [feed_id]",
+		Short: "Unarchive a dead feed",
+		Long:  `Reactivate a feed that was archived after returning 404/410, so the scheduler fetches it again.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %w", err)
+			}
+			return runFeedsUnarchive(uint(feedID))
+		},
+	}
+
+	return cmd
+}
+
+func newFeedsSnapshotCmd() *cobra.Command {
+	var snapshotID uint
+
+	cmd := &cobra.Command{
+		Use:   "snapshot [feed_id]",
+		Short: "Show raw feed responses",
+		Long: `List the raw responses stored for a feed, or print one of them with --id.
+Snapshots are only stored when FEED_SERVICE_SNAPSHOTS_KEEP is above 0.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %w", err)
+			}
+			return runFeedsSnapshot(uint(feedID), snapshotID)
+		},
+	}
+
+	cmd.Flags().UintVar(&snapshotID, "id", 0, "Print the raw body of this snapshot")
+
+	return cmd
+}
+
+func newFeedsDeleteCmd() *cobra.Command {
+	var retention string
+
+	cmd := &cobra.Command{
+		Use:   "delete [feed_id]",
+		Short: "Delete a feed for every subscriber",
+		Long: `Notify and unsubscribe every subscriber, then archive the feed with its articles
+(--retention archive) or delete it together with its articles (--retention purge).
+Everything happens in one transaction.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %w", err)
+			}
+			parsed, err := models.ParseFeedRetention(retention)
+			if err != nil {
+				return err
+			}
+			return runFeedsDelete(uint(feedID), parsed)
+		},
+	}
+
+	cmd.Flags().StringVar(&retention, "retention", string(models.FeedRetentionArchive), "What happens to the articles: archive or purge")
+
+	return cmd
+}
+
+func newFeedsPurgeOrphansCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "purge-orphans",
+		Short: "Remove rows left behind by deleted feeds",
+		Long:  `Delete subscriptions, articles and snapshots whose feed no longer exists, e.g. after a feed row was removed by hand.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFeedsPurgeOrphans()
+		},
+	}
+
+	return cmd
+}
+
 func runFeedsList() error {
 	ctx := context.Background()
 
@@ -68,8 +167,8 @@ func runFeedsList() error {
 	err := db.WithContext(ctx).
 		Table("feeds").
 		Select(`feeds.*, 
-			(SELECT COUNT(*) FROM articles WHERE articles.feed_id = feeds.id) as article_count,
-			(SELECT COUNT(*) FROM articles WHERE articles.feed_id = feeds.id AND processed_at IS NOT NULL) as processed_count`).
+			(SELECT COUNT(*) FROM articles WHERE articles.feed_id = feeds.id AND articles.deleted_at IS NULL) as article_count,
+			(SELECT COUNT(*) FROM articles WHERE articles.feed_id = feeds.id AND articles.deleted_at IS NULL AND processed_at IS NOT NULL) as processed_count`).
 		Order("feeds.id").
 		Find(&feeds).Error
 
@@ -119,8 +218,18 @@ func runFeedsShow(feedID uint) error {
 	fmt.Printf("URL:         %s\n", feed.URL)
 	fmt.Printf("Description: %s\n", truncateString(feed.Description, 60))
 	fmt.Printf("Status:      %s\n", feed.Status)
+	fmt.Printf("Fetch tier:  %s\n", feed.FetchTier)
+	if feed.GoneSince != nil {
+		fmt.Printf("Gone since:  %s\n", feed.GoneSince.Format("2006-01-02 15:04:05"))
+	}
+	if feed.ArchivedAt != nil {
+		fmt.Printf("Archived:    %s\n", feed.ArchivedAt.Format("2006-01-02 15:04:05"))
+	}
 	fmt.Printf("Created:     %s\n", feed.CreatedAt.Format("2006-01-02 15:04:05"))
 	fmt.Printf("Updated:     %s\n", feed.UpdatedAt.Format("2006-01-02 15:04:05"))
+	if feed.ImportedSince != nil {
+		fmt.Printf("Held back:   items before %s (see feeds backfill)\n", feed.ImportedSince.Format("2006-01-02 15:04:05"))
+	}
 
 	// Print article stats
 	fmt.Println()
@@ -134,7 +243,234 @@ func runFeedsShow(feedID uint) error {
 		fmt.Printf("Progress:    %.1f%%\n", percentage)
 	}
 
+	printCrawlBudget(ctx, &feed)
+
 	fmt.Println()
 	return nil
 }
 
+// printCrawlBudget shows what the feed used of today's crawl budget, when its policy gives
+// it one
+func printCrawlBudget(ctx context.Context, feed *models.Feed) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return
+	}
+	policies, err := policy.Load(cfg)
+	if err != nil {
+		return
+	}
+	decision := policies.Evaluate(policy.FeedSubject(feed))
+	if decision.Policy.CrawlDailyRequests <= 0 {
+		return
+	}
+
+	redisClient := redis.NewClient(&redis.Options{Addr: cfg.Redis.Address})
+	defer redisClient.Close()
+	budget := core.NewCrawlBudget(core.NewRedisCrawlBudgetStore(redisClient), decision.Policy.CrawlDailyRequests)
+
+	fmt.Println()
+	fmt.Println("--- Crawl budget ---")
+	if len(decision.Rules) > 0 {
+		fmt.Printf("Policy rules: %s\n", strings.Join(decision.Rules, ", "))
+	}
+	usage, err := budget.Usage(ctx, feed.ID)
+	if err != nil {
+		fmt.Printf("Unavailable: %v\n", err)
+		return
+	}
+	fmt.Printf("Used today:  %d of %d (%s UTC)\n", usage.Used, usage.Limit, usage.Day)
+	for _, kind := range core.CrawlRequestKinds {
+		fmt.Printf("  %-14s %d\n", kind, usage.ByKind[kind])
+	}
+	if usage.Refused > 0 {
+		fmt.Printf("Refused:     %d\n", usage.Refused)
+	}
+}
+
+func newFeedsBackfillCmd() *cobra.Command {
+	var since string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "backfill [feed_id]",
+		Short: "Import the items the import limits held back",
+		Long: `Fetch the feed and import, newest first, the items that the per-fetch cap or the
+initial import cutoff left out (FEED_SERVICE_IMPORT_*, or the feed's policy). Use
+--since to stop at a publication date and --limit to import a batch at a time; run it
+again for the rest. New articles go through the outbox, so the feed-service sends them
+to the AI service.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %w", err)
+			}
+			var sinceTime time.Time
+			if since != "" {
+				if sinceTime, err = parseSince(since); err != nil {
+					return err
+				}
+			}
+			if limit < 0 {
+				return fmt.Errorf("--limit must not be negative")
+			}
+			return runFeedsBackfill(uint(feedID), sinceTime, limit)
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "", "Only import items published after this date (2006-01-02) or duration ago (e.g. 2160h)")
+	cmd.Flags().IntVar(&limit, "limit", 0, "Import at most this many items (0 for all)")
+
+	return cmd
+}
+
+// parseSince reads a date, or a duration back from now
+func parseSince(value string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: want a date like 2006-01-02 or a duration like 720h", value)
+}
+
+func runFeedsUnarchive(feedID uint) error {
+	ctx := context.Background()
+
+	var feed models.Feed
+	if err := db.WithContext(ctx).First(&feed, feedID).Error; err != nil {
+		return fmt.Errorf("feed not found: %w", err)
+	}
+
+	feedRepo := repository.NewFeedRepository(db)
+	restored, err := feedRepo.RestoreFeed(ctx, feedID, fmt.Sprintf("Feed %q has been unarchived by an administrator.", feed.Title))
+	if err != nil {
+		return fmt.Errorf("failed to unarchive feed: %w", err)
+	}
+	if !restored {
+		return fmt.Errorf("feed #%d is not archived (status: %s)", feedID, feed.Status)
+	}
+
+	fmt.Printf("Feed #%d unarchived; it will be fetched on the next scheduler run.\n", feedID)
+	return nil
+}
+
+func runFeedsDelete(feedID uint, retention models.FeedRetention) error {
+	ctx := context.Background()
+
+	var feed models.Feed
+	if err := db.WithContext(ctx).First(&feed, feedID).Error; err != nil {
+		return fmt.Errorf("feed not found: %w", err)
+	}
+
+	feedRepo := repository.NewFeedRepository(db)
+	message := fmt.Sprintf("Feed %q was removed by an administrator and you have been unsubscribed.", feed.Title)
+	deletion, err := feedRepo.DeleteFeed(ctx, feedID, retention, message, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to delete feed: %w", err)
+	}
+
+	fmt.Printf("Feed #%d deleted (%s): %d subscribers unsubscribed, %d articles purged.\n",
+		feedID, retention, len(deletion.UserIDs), deletion.ArticlesPurged)
+	fmt.Println("Cached feed lists in the API service expire within 15 minutes.")
+	return nil
+}
+
+func runFeedsBackfill(feedID uint, since time.Time, limit int) error {
+	ctx := context.Background()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	log := logger.New(0) // quiet logger
+	feedRepo := repository.NewFeedRepository(db)
+	service := core.NewArticleService(feedRepo, repository.NewArticleRepository(db), nil, log)
+	service.SetOutbox(core.NewEventOutbox(repository.NewOutboxRepository(db)), dbtx.NewUnitOfWork(db))
+	service.SetHTTPClientFactory(core.NewHTTPClientFactory(core.FetchIdentity{
+		UserAgent: cfg.Fetch.UserAgent,
+		From:      cfg.Fetch.From,
+		InfoURL:   cfg.Fetch.InfoURL,
+	}))
+	if cipher, err := secrets.NewCipher(cfg.Auth.CredentialsKey); err == nil {
+		service.SetSecretDecrypter(cipher)
+	}
+
+	articles, err := service.BackfillArticles(ctx, feedID, since, limit)
+	if err != nil {
+		return fmt.Errorf("failed to backfill feed: %w", err)
+	}
+
+	feed, err := feedRepo.GetByID(ctx, feedID)
+	if err != nil {
+		return fmt.Errorf("failed to reload feed: %w", err)
+	}
+	fmt.Printf("Feed #%d: imported %d held back items.\n", feedID, len(articles))
+	if feed.ImportedSince != nil {
+		fmt.Printf("Items published before %s are still held back.\n", feed.ImportedSince.Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
+
+func runFeedsPurgeOrphans() error {
+	purge, err := repository.NewFeedRepository(db).PurgeOrphans(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to purge orphans: %w", err)
+	}
+
+	fmt.Printf("Removed %d subscriptions, %d articles and %d snapshots of missing feeds.\n",
+		purge.Subscriptions, purge.Articles, purge.Snapshots)
+	return nil
+}
+
+func runFeedsSnapshot(feedID, snapshotID uint) error {
+	ctx := context.Background()
+	snapshotRepo := repository.NewSnapshotRepository(db)
+
+	if snapshotID != 0 {
+		snapshot, err := snapshotRepo.Get(ctx, feedID, snapshotID)
+		if err != nil {
+			return fmt.Errorf("failed to get snapshot: %w", err)
+		}
+		if snapshot == nil {
+			return fmt.Errorf("snapshot #%d of feed #%d not found", snapshotID, feedID)
+		}
+		raw, err := snapshot.Raw()
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(raw)
+		return err
+	}
+
+	snapshots, err := snapshotRepo.ListByFeed(ctx, feedID)
+	if err != nil {
+		return fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	// Print header
+	fmt.Println()
+	fmt.Printf("%-6s | %-19s | %-6s | %-25s | %-10s | %s\n", "ID", "Fetched", "Status", "Content-Type", "Size", "Parse error")
+	fmt.Println(strings.Repeat("-", 100))
+
+	for _, snap := range snapshots {
+		size := strconv.FormatInt(snap.Size, 10)
+		if snap.Truncated {
+			size += "+"
+		}
+		parseError := "-"
+		if snap.ParseError != nil {
+			parseError = truncateString(*snap.ParseError, 30)
+		}
+		fmt.Printf("%-6d | %-19s | %-6d | %-25s | %-10s | %s\n",
+			snap.ID, snap.FetchedAt.Format("2006-01-02 15:04:05"), snap.StatusCode,
+			truncateString(snap.ContentType, 25), size, parseError)
+	}
+
+	fmt.Println()
+	fmt.Printf("Total: %d snapshots (print one with --id)\n", len(snapshots))
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Fancu1/phoenix-rss/internal/config"
+	"github.com/Fancu1/phoenix-rss/internal/events"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/core"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// feedFilterFlags select feeds by status, last fetch error, time since the last fetch,
+// tier or ID
+type feedFilterFlags struct {
+	status        string
+	errorContains string
+	notFetchedFor time.Duration
+	tier          string
+	ids           []uint
+}
+
+func (f *feedFilterFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.status, "status", "", "Only feeds with this status: active, error, archived or suspended")
+	cmd.Flags().StringVar(&f.errorContains, "error", "", "Only feeds whose last fetch error contains this text, e.g. 503 or \"no such host\"")
+	cmd.Flags().DurationVar(&f.notFetchedFor, "not-fetched-for", 0, "Only feeds never fetched or last fetched longer ago than this, e.g. 72h")
+	cmd.Flags().StringVar(&f.tier, "tier", "", "Only feeds in this fetch tier: high, normal or low")
+	cmd.Flags().UintSliceVar(&f.ids, "id", nil, "Only these feeds (repeatable or comma separated)")
+}
+
+func (f *feedFilterFlags) filter() (repository.FeedListFilter, error) {
+	filter := repository.FeedListFilter{ErrorContains: strings.TrimSpace(f.errorContains), IDs: f.ids}
+	var err error
+	if f.status != "" {
+		if filter.Status, err = models.ParseFeedStatus(f.status); err != nil {
+			return filter, err
+		}
+	}
+	if f.tier != "" {
+		if filter.Tier, err = models.ParseFeedTier(f.tier); err != nil {
+			return filter, err
+		}
+	}
+	if f.notFetchedFor > 0 {
+		since := time.Now().UTC().Add(-f.notFetchedFor)
+		filter.NotFetchedSince = &since
+	}
+	return filter, nil
+}
+
+func newFeedsFindCmd() *cobra.Command {
+	var (
+		flags feedFilterFlags
+		limit int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "find",
+		Short: "Find feeds by status, error, fetch age and tier",
+		Long: `List the feeds matching every given filter, in ID order, e.g. the feeds failing
+with 503 for three days:
+
+  phoenix-admin feeds find --status error --error 503 --not-fetched-for 72h`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter, err := flags.filter()
+			if err != nil {
+				return err
+			}
+			return runFeedsFind(filter, limit)
+		},
+	}
+
+	flags.register(cmd)
+	cmd.Flags().IntVar(&limit, "limit", 100, "Show at most this many feeds")
+
+	return cmd
+}
+
+func newFeedsBulkCmd() *cobra.Command {
+	var (
+		flags  feedFilterFlags
+		toTier string
+		dryRun bool
+		yes    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bulk [reactivate|suspend|refetch|set_tier]",
+		Short: "Apply an action to every feed matching filters",
+		Long: `Apply an action to every feed matching the filters, in batches:
+
+  reactivate  set feeds back to active, unarchive them and queue a fetch
+  suspend     stop fetching feeds until they are reactivated
+  refetch     queue a fetch of every feed now (suspended feeds are skipped)
+  set_tier    move feeds to the fetch tier given with --to
+
+At least one filter is required. The matching feeds are counted and the action is
+confirmed before anything changes, unless --yes is given.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			action, err := models.ParseFeedBulkAction(strings.ReplaceAll(args[0], "-", "_"))
+			if err != nil {
+				return err
+			}
+			update := models.FeedBulkUpdate{Action: action}
+			if action == models.FeedBulkSetTier {
+				if update.Tier, err = models.ParseFeedTier(toTier); err != nil {
+					return fmt.Errorf("--to: %w", err)
+				}
+			}
+			filter, err := flags.filter()
+			if err != nil {
+				return err
+			}
+			return runFeedsBulk(filter, update, dryRun, yes)
+		},
+	}
+
+	flags.register(cmd)
+	cmd.Flags().StringVar(&toTier, "to", "", "Target fetch tier of set_tier: high, normal or low")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only count the matching feeds")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Do not ask for confirmation")
+
+	return cmd
+}
+
+func runFeedsFind(filter repository.FeedListFilter, limit int) error {
+	ctx := context.Background()
+	service := core.NewFeedService(repository.NewFeedRepository(db), logger.New(0), nil)
+
+	total, err := service.CountFeeds(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to count feeds: %w", err)
+	}
+	feeds, _, err := service.ListFeedsPage(ctx, filter, max(limit, 1), "")
+	if err != nil {
+		return fmt.Errorf("failed to list feeds: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Printf("%-6s | %-9s | %-6s | %-4s | %-16s | %-30s | %s\n", "ID", "Status", "Tier", "Subs", "Last fetched", "Last error", "URL")
+	fmt.Println(strings.Repeat("-", 120))
+
+	for _, f := range feeds {
+		lastFetched := "never"
+		if f.LastFetchedAt != nil {
+			lastFetched = f.LastFetchedAt.Format("2006-01-02 15:04")
+		}
+		lastError := "-"
+		if f.LastFetchError != nil {
+			lastError = truncateString(*f.LastFetchError, 30)
+		}
+		fmt.Printf("%-6d | %-9s | %-6s | %-4d | %-16s | %-30s | %s\n",
+			f.ID, f.Status, f.FetchTier, f.SubscriberCount, lastFetched, lastError, f.URL)
+	}
+
+	fmt.Println()
+	fmt.Printf("Showing %d of %d matching feeds\n", len(feeds), total)
+
+	return nil
+}
+
+func runFeedsBulk(filter repository.FeedListFilter, update models.FeedBulkUpdate, dryRun, yes bool) error {
+	ctx := context.Background()
+	feedRepo := repository.NewFeedRepository(db)
+
+	counting := update
+	counting.DryRun = true
+	preview, err := core.NewFeedService(feedRepo, logger.New(0), nil).BulkUpdateFeeds(ctx, filter, counting)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d feeds match.\n", preview.Matched)
+	if dryRun || preview.Matched == 0 {
+		return nil
+	}
+	if !yes {
+		fmt.Printf("Apply %s to %d feeds? Type 'yes' to continue: ", update.Action, preview.Matched)
+		if !confirmAction() {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	var producer events.Producer
+	if update.Action == models.FeedBulkReactivate || update.Action == models.FeedBulkRefetch {
+		kafkaProducer, err := newFeedFetchProducer()
+		if err != nil {
+			return err
+		}
+		defer kafkaProducer.Close()
+		producer = kafkaProducer
+	}
+
+	result, err := core.NewFeedService(feedRepo, logger.New(0), producer).BulkUpdateFeeds(ctx, filter, update)
+	if result != nil {
+		fmt.Printf("%s: %d feeds matched, %d updated, %d fetches queued.\n",
+			update.Action, result.Matched, result.Updated, result.FetchesQueued)
+	}
+	if err != nil {
+		return fmt.Errorf("bulk %s stopped: %w", update.Action, err)
+	}
+	return nil
+}
+
+// newFeedFetchProducer publishes feed.fetch events to the topic the feed service consumes
+func newFeedFetchProducer() (*events.KafkaProducer, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	topic := cfg.Kafka.FeedFetch.Topic
+	if cfg.Kafka.Routing.Enabled {
+		routing, err := events.NewRouting(cfg.Kafka.Routing.Topic, cfg.Kafka.Routing.Strict, cfg.Kafka.Routing.EventTypes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kafka routing config: %w", err)
+		}
+		topic = routing.TopicFor(events.EventFeedFetch, topic)
+	}
+
+	compression, err := events.ParseCompression(cfg.Kafka.Compression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kafka compression: %w", err)
+	}
+
+	producer := events.NewKafkaProducer(logger.New(0), events.KafkaConfig{Brokers: cfg.Kafka.Brokers, Topic: topic})
+	producer.SetProducerOptions(events.ProducerOptions{Compression: compression})
+	return producer, nil
+}
@@ -44,6 +44,12 @@ func main() {
 	rootCmd.AddCommand(newAICmd())
 	rootCmd.AddCommand(newFeedsCmd())
 	rootCmd.AddCommand(newStatsCmd())
+	rootCmd.AddCommand(newReportCmd())
+	rootCmd.AddCommand(newDoctorCmd())
+	rootCmd.AddCommand(newUsersCmd())
+	rootCmd.AddCommand(newReadCmd())
+	rootCmd.AddCommand(newBackupCmd())
+	rootCmd.AddCommand(newRestoreCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
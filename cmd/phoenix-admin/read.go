@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/htmlconv"
+)
+
+// readWidth is the column articles are wrapped at
+const readWidth = 80
+
+type readOptions struct {
+	apiURL   string
+	username string
+	token    string
+}
+
+func newReadCmd() *cobra.Command {
+	var opts readOptions
+
+	cmd := &cobra.Command{
+		Use:   "read",
+		Short: "Read articles in the terminal",
+		Long: `Read your feeds in the terminal, through the REST API as a regular user.
+
+Without a subcommand an interactive reader starts: list feeds and their unread articles,
+open articles as text, step through unread articles and mark them read or starred.
+The subcommands do the same one step at a time, for scripts.
+
+Sign in with --token (or PHOENIX_TOKEN), or with --username (or PHOENIX_USERNAME) and
+the password from PHOENIX_PASSWORD or a prompt. Note that the prompt echoes the password.`,
+		// the reader only talks to the API, so the database connection is skipped
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := bufio.NewReader(os.Stdin)
+			client, err := connect(cmd.Context(), opts, in)
+			if err != nil {
+				return err
+			}
+			return runReader(cmd.Context(), client, in, os.Stdout)
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&opts.apiURL, "api", envOr("PHOENIX_API_URL", "http://localhost:8080/api/v1"), "API base URL (PHOENIX_API_URL)")
+	cmd.PersistentFlags().StringVarP(&opts.username, "username", "u", os.Getenv("PHOENIX_USERNAME"), "Username to sign in as (PHOENIX_USERNAME)")
+	cmd.PersistentFlags().StringVar(&opts.token, "token", os.Getenv("PHOENIX_TOKEN"), "API token instead of a username (PHOENIX_TOKEN)")
+
+	cmd.AddCommand(newReadFeedsCmd(&opts))
+	cmd.AddCommand(newReadListCmd(&opts))
+	cmd.AddCommand(newReadShowCmd(&opts))
+	cmd.AddCommand(newReadStateCmd(&opts, "mark-read", "Mark articles read", models.StateFieldRead, "unread", "Mark them unread instead"))
+	cmd.AddCommand(newReadStateCmd(&opts, "star", "Star articles", models.StateFieldStarred, "remove", "Remove the star instead"))
+
+	return cmd
+}
+
+func newReadFeedsCmd(opts *readOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "feeds",
+		Short: "List your feeds",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := connect(cmd.Context(), *opts, bufio.NewReader(os.Stdin))
+			if err != nil {
+				return err
+			}
+			return printFeeds(cmd.Context(), client, os.Stdout)
+		},
+	}
+}
+
+func newReadListCmd(opts *readOptions) *cobra.Command {
+	var all bool
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "list [feed_id]",
+		Short: "List the unread articles of a feed",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			feedID, err := parseReadID(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid feed ID: %w", err)
+			}
+			client, err := connect(cmd.Context(), *opts, bufio.NewReader(os.Stdin))
+			if err != nil {
+				return err
+			}
+			return printArticles(cmd.Context(), client, os.Stdout, feedID, limit, all)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&all, "all", "a", false, "Include read articles")
+	cmd.Flags().IntVarP(&limit, "limit", "l", 20, "Number of articles to look at")
+
+	return cmd
+}
+
+func newReadShowCmd(opts *readOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show [article_id]",
+		Short: "Show an article as text",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			articleID, err := parseReadID(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid article ID: %w", err)
+			}
+			client, err := connect(cmd.Context(), *opts, bufio.NewReader(os.Stdin))
+			if err != nil {
+				return err
+			}
+			return printArticle(cmd.Context(), client, os.Stdout, articleID)
+		},
+	}
+}
+
+// newReadStateCmd builds the commands that set a read or starred state, with a flag that
+// clears it instead
+func newReadStateCmd(opts *readOptions, use, short, field, clearFlag, clearUsage string) *cobra.Command {
+	var clear bool
+
+	cmd := &cobra.Command{
+		Use:   use + " [article_id...]",
+		Short: short,
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids := make([]uint, len(args))
+			for i, arg := range args {
+				id, err := parseReadID(arg)
+				if err != nil {
+					return fmt.Errorf("invalid article ID %q: %w", arg, err)
+				}
+				ids[i] = id
+			}
+			client, err := connect(cmd.Context(), *opts, bufio.NewReader(os.Stdin))
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if err := client.setState(cmd.Context(), id, field, !clear); err != nil {
+					return err
+				}
+			}
+			fmt.Printf("Updated %d articles.\n", len(ids))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&clear, clearFlag, false, clearUsage)
+
+	return cmd
+}
+
+// connect signs in and loads the read states
+func connect(ctx context.Context, opts readOptions, in *bufio.Reader) (*apiClient, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	client := newAPIClient(opts.apiURL, opts.token)
+	if opts.token == "" {
+		if opts.username == "" {
+			return nil, fmt.Errorf("sign in with --token or --username")
+		}
+		password := os.Getenv("PHOENIX_PASSWORD")
+		if password == "" {
+			fmt.Fprintf(os.Stderr, "Password for %s: ", opts.username)
+			line, err := in.ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("read password: %w", err)
+			}
+			password = strings.TrimRight(line, "\r\n")
+		}
+		if err := client.login(ctx, opts.username, password); err != nil {
+			return nil, err
+		}
+	}
+	if err := client.syncStates(ctx); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// runReader is the interactive reader: one command per line, with the article opened last
+// as the current one
+func runReader(ctx context.Context, client *apiClient, in *bufio.Reader, out io.Writer) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	fmt.Fprintln(out, "Phoenix RSS reader. Type h for help, Enter for the next unread article.")
+	if err := printFeeds(ctx, client, out); err != nil {
+		return err
+	}
+
+	var current uint
+	for {
+		fmt.Fprint(out, "\nphoenix> ")
+		line, err := in.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Fprintln(out)
+				return nil
+			}
+			return err
+		}
+
+		fields := strings.Fields(line)
+		command, arg := "n", ""
+		if len(fields) > 0 {
+			command = fields[0]
+		}
+		if len(fields) > 1 {
+			arg = fields[1]
+		}
+		// commands on an article default to the current one
+		target := current
+		if arg != "" && command != "l" && command != "list" {
+			if target, err = parseReadID(arg); err != nil {
+				fmt.Fprintf(out, "invalid article ID %q\n", arg)
+				continue
+			}
+		}
+
+		switch command {
+		case "q", "quit", "exit":
+			return nil
+		case "h", "help", "?":
+			printReaderHelp(out)
+		case "f", "feeds":
+			err = printFeeds(ctx, client, out)
+		case "l", "list":
+			feedID, parseErr := parseReadID(arg)
+			if parseErr != nil {
+				fmt.Fprintln(out, "usage: l <feed_id> [all]")
+				continue
+			}
+			err = printArticles(ctx, client, out, feedID, 20, len(fields) > 2 && fields[2] == "all")
+		case "o", "open":
+			if err = printArticle(ctx, client, out, target); err == nil {
+				current = target
+			}
+		case "n", "next":
+			var next *models.Article
+			next, err = client.nextUnread(ctx, current, current != 0)
+			if err == nil && next == nil {
+				fmt.Fprintln(out, "No unread articles left.")
+				current = 0
+			} else if err == nil {
+				current = next.ID
+				err = printArticle(ctx, client, out, current)
+			}
+		case "r", "read", "u", "unread", "s", "star", "unstar":
+			if target == 0 {
+				fmt.Fprintln(out, "Open an article first, or give its ID.")
+				continue
+			}
+			field, value := models.StateFieldRead, command == "r" || command == "read"
+			if command == "s" || command == "star" || command == "unstar" {
+				field, value = models.StateFieldStarred, command != "unstar"
+			}
+			if err = client.setState(ctx, target, field, value); err == nil {
+				fmt.Fprintf(out, "Article %d: %s\n", target, stateLabel(client.states[target]))
+			}
+		default:
+			fmt.Fprintf(out, "unknown command %q, type h for help\n", command)
+		}
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+		}
+	}
+}
+
+func printReaderHelp(out io.Writer) {
+	fmt.Fprint(out, `Commands:
+  f, feeds            list your feeds
+  l, list ID [all]    list the unread (or all) articles of feed ID
+  o, open ID          open article ID
+  n, next, Enter      mark the current article read and open the next unread one
+  r, read [ID]        mark the current article (or ID) read
+  u, unread [ID]      mark it unread
+  s, star [ID]        star it
+  unstar [ID]         remove its star
+  q, quit             leave the reader
+`)
+}
+
+func printFeeds(ctx context.Context, client *apiClient, out io.Writer) error {
+	feeds, err := client.feeds(ctx)
+	if err != nil {
+		return err
+	}
+	if len(feeds) == 0 {
+		fmt.Fprintln(out, "You have no feeds.")
+		return nil
+	}
+
+	fmt.Fprintf(out, "\n%-6s | %-50s | %s\n", "ID", "Title", "Status")
+	fmt.Fprintln(out, strings.Repeat("-", 70))
+	for _, feed := range feeds {
+		title := feed.Title
+		if feed.CustomTitle != nil && *feed.CustomTitle != "" {
+			title = *feed.CustomTitle
+		}
+		fmt.Fprintf(out, "%-6d | %-50s | %s\n", feed.ID, truncateString(title, 50), feed.Status)
+	}
+	return nil
+}
+
+func printArticles(ctx context.Context, client *apiClient, out io.Writer, feedID uint, limit int, all bool) error {
+	page, err := client.articles(ctx, feedID, 1, limit)
+	if err != nil {
+		return err
+	}
+
+	shown := 0
+	for _, article := range page.Items {
+		if !all && client.isRead(article) {
+			continue
+		}
+		if shown == 0 {
+			fmt.Fprintf(out, "\n%-8s | %-3s | %-10s | %s\n", "ID", "", "Published", "Title")
+			fmt.Fprintln(out, strings.Repeat("-", 80))
+		}
+		marks := ""
+		if !client.isRead(article) {
+			marks += "*"
+		}
+		if client.isStarred(article) {
+			marks += "+"
+		}
+		fmt.Fprintf(out, "%-8d | %-3s | %-10s | %s\n", article.ID, marks, article.PublishedAt.Format("2006-01-02"), truncateString(article.Title, 50))
+		shown++
+	}
+
+	switch {
+	case shown == 0 && all:
+		fmt.Fprintln(out, "This feed has no articles yet.")
+	case shown == 0:
+		fmt.Fprintf(out, "No unread articles among the latest %d.\n", len(page.Items))
+	default:
+		fmt.Fprintf(out, "\n* unread  + starred  (%d of %d articles in the feed)\n", shown, page.Pagination.Total)
+	}
+	return nil
+}
+
+func printArticle(ctx context.Context, client *apiClient, out io.Writer, articleID uint) error {
+	detail, err := client.article(ctx, articleID)
+	if err != nil {
+		return err
+	}
+	article := detail.Article
+
+	fmt.Fprintf(out, "\n%s\n", wrapText(article.Title, readWidth))
+	feedTitle := ""
+	if detail.ArticleNavigation != nil {
+		feedTitle = detail.Feed.Title + " · "
+	}
+	fmt.Fprintf(out, "%s%s · %s\n", feedTitle, article.PublishedAt.Format("2006-01-02 15:04"), stateLabel(client.states[article.ID]))
+	fmt.Fprintln(out, article.URL)
+
+	if article.Summary != nil && *article.Summary != "" {
+		fmt.Fprintf(out, "\nSummary: %s\n", wrapText(*article.Summary, readWidth))
+	}
+
+	body := article.Content
+	if strings.TrimSpace(body) == "" {
+		body = article.Description
+	}
+	text, err := htmlconv.ToText(body)
+	if err != nil {
+		return fmt.Errorf("render article %d: %w", articleID, err)
+	}
+	fmt.Fprintln(out, strings.Repeat("─", readWidth))
+	fmt.Fprint(out, wrapText(text, readWidth))
+	if detail.ArticleNavigation != nil && detail.NextArticleID != nil {
+		fmt.Fprintf(out, "\n(next in feed: %d)\n", *detail.NextArticleID)
+	}
+	return nil
+}
+
+func stateLabel(state models.UserArticleState) string {
+	label := "unread"
+	if state.Read {
+		label = "read"
+	}
+	if state.Starred {
+		label += ", starred"
+	}
+	return label
+}
+
+// wrapText breaks lines longer than width at spaces, keeping each line's indentation
+func wrapText(text string, width int) string {
+	lines := strings.Split(text, "\n")
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+		column := 0
+		for j, word := range strings.Fields(line) {
+			switch {
+			case j == 0:
+				b.WriteString(indent)
+				column = len([]rune(indent))
+			case column+1+len([]rune(word)) > width:
+				b.WriteString("\n" + indent)
+				column = len([]rune(indent))
+			default:
+				b.WriteByte(' ')
+				column++
+			}
+			b.WriteString(word)
+			column += len([]rune(word))
+		}
+	}
+	return b.String()
+}
+
+func parseReadID(value string) (uint, error) {
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("must be a positive number")
+	}
+	return uint(id), nil
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
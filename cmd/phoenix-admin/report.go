@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Fancu1/phoenix-rss/internal/config"
+	"github.com/Fancu1/phoenix-rss/internal/reports"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/mailer"
+)
+
+func newReportCmd() *cobra.Command {
+	var send bool
+
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Show the weekly operator report",
+		Long: `Compile the operator report for the last seven days and print it.
+With --send the report is also emailed to the configured recipients and stored, like the scheduled run.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReport(send)
+		},
+	}
+
+	cmd.Flags().BoolVar(&send, "send", false, "Email and store the report")
+
+	return cmd
+}
+
+func runReport(send bool) error {
+	ctx := context.Background()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	reportCfg := cfg.SchedulerService.OperatorReport
+	collector := reports.NewCollector(db, reportCfg.TokenPricePerMillion)
+
+	if !send {
+		end := time.Now().UTC().Truncate(time.Hour)
+		report, err := collector.Collect(ctx, end.Add(-reports.ReportPeriod), end)
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		fmt.Print(report.Text())
+		return nil
+	}
+
+	log := logger.New(0) // quiet logger
+	m := mailer.New(mailer.Config{
+		Host:     cfg.Email.SMTPHost,
+		Port:     cfg.Email.SMTPPort,
+		Username: cfg.Email.SMTPUsername,
+		Password: cfg.Email.SMTPPassword,
+		From:     cfg.Email.From,
+	}, log)
+
+	report, err := reports.NewJob(db, collector, m, reportCfg.Recipients, log).Run(ctx)
+	if report != nil {
+		fmt.Println()
+		fmt.Print(report.Text())
+		fmt.Println()
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(reportCfg.Recipients) == 0 {
+		fmt.Println("Report stored; no recipients configured, nothing emailed.")
+	} else {
+		fmt.Printf("Report stored and sent to %d recipients.\n", len(reportCfg.Recipients))
+	}
+	return nil
+}
@@ -3,26 +3,32 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/summaryquality"
 )
 
 func newStatsCmd() *cobra.Command {
+	var ratingsSince time.Duration
+
 	cmd := &cobra.Command{
 		Use:   "stats",
 		Short: "Show statistics",
-		Long:  `Display overall statistics for feeds and articles.`,
+		Long:  `Display overall statistics for feeds and articles, and the quality of AI summaries per model and prompt as rated by readers.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runStats()
+			return runStats(ratingsSince)
 		},
 	}
 
+	cmd.Flags().DurationVar(&ratingsSince, "ratings-since", 30*24*time.Hour, "Count summary ratings given within this period (0 counts all)")
+
 	return cmd
 }
 
-func runStats() error {
+func runStats(ratingsSince time.Duration) error {
 	ctx := context.Background()
 
 	// Count feeds
@@ -37,19 +43,41 @@ func runStats() error {
 		return fmt.Errorf("failed to count articles: %w", err)
 	}
 
-	// Count processed articles
-	var processedCount int64
-	if err := db.WithContext(ctx).Model(&models.Article{}).Where("processed_at IS NOT NULL").Count(&processedCount).Error; err != nil {
-		return fmt.Errorf("failed to count processed articles: %w", err)
+	// Count articles per AI processing status
+	var statusRows []struct {
+		ProcessingStatus models.ProcessingStatus
+		Count            int64
+	}
+	if err := db.WithContext(ctx).Model(&models.Article{}).
+		Select("processing_status, COUNT(*) AS count").
+		Group("processing_status").
+		Scan(&statusRows).Error; err != nil {
+		return fmt.Errorf("failed to count articles by processing status: %w", err)
+	}
+	byStatus := make(map[models.ProcessingStatus]int64, len(statusRows))
+	for _, row := range statusRows {
+		byStatus[row.ProcessingStatus] = row.Count
 	}
 
-	pendingCount := articleCount - processedCount
+	// Break failures down by error class
+	var failureRows []struct {
+		ProcessingError string
+		Count           int64
+	}
+	if err := db.WithContext(ctx).Model(&models.Article{}).
+		Select("COALESCE(processing_error, 'unknown') AS processing_error, COUNT(*) AS count").
+		Where("processing_status = ?", models.ProcessingFailed).
+		Group("processing_error").
+		Order("count DESC").
+		Scan(&failureRows).Error; err != nil {
+		return fmt.Errorf("failed to count failed articles: %w", err)
+	}
 
-	// Calculate percentages
-	var processedPercent, pendingPercent float64
-	if articleCount > 0 {
-		processedPercent = float64(processedCount) / float64(articleCount) * 100
-		pendingPercent = float64(pendingCount) / float64(articleCount) * 100
+	percent := func(n int64) float64 {
+		if articleCount == 0 {
+			return 0
+		}
+		return float64(n) / float64(articleCount) * 100
 	}
 
 	// Print statistics
@@ -60,8 +88,47 @@ func runStats() error {
 	fmt.Printf("Articles:  %d\n", articleCount)
 	fmt.Println()
 	fmt.Println("AI Processing:")
-	fmt.Printf("  ✓ Processed:  %d (%.1f%%)\n", processedCount, processedPercent)
-	fmt.Printf("  ⏳ Pending:   %d (%.1f%%)\n", pendingCount, pendingPercent)
+	fmt.Printf("  ✓ Succeeded:  %d (%.1f%%)\n", byStatus[models.ProcessingSucceeded], percent(byStatus[models.ProcessingSucceeded]))
+	fmt.Printf("  ⏳ Processing: %d (%.1f%%)\n", byStatus[models.ProcessingInProgress], percent(byStatus[models.ProcessingInProgress]))
+	fmt.Printf("  ⏳ Pending:    %d (%.1f%%)\n", byStatus[models.ProcessingPending], percent(byStatus[models.ProcessingPending]))
+	fmt.Printf("  ✗ Failed:     %d (%.1f%%)\n", byStatus[models.ProcessingFailed], percent(byStatus[models.ProcessingFailed]))
+	for _, row := range failureRows {
+		fmt.Printf("      %-14s %d\n", row.ProcessingError, row.Count)
+	}
+	fmt.Println()
+
+	return printSummaryQuality(ctx, ratingsSince)
+}
+
+// printSummaryQuality reports readers' ratings per model and prompt variant, with the
+// score the ai-service ranks prompt variants by
+func printSummaryQuality(ctx context.Context, since time.Duration) error {
+	var from time.Time
+	if since > 0 {
+		from = time.Now().UTC().Add(-since)
+	}
+	stats, err := summaryquality.NewStore(db).Stats(ctx, from)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Summary Quality:")
+	if len(stats) == 0 {
+		fmt.Println("  No ratings yet")
+		fmt.Println()
+		return nil
+	}
+	fmt.Printf("  %-24s %-14s %8s %8s %14s %7s\n", "MODEL", "PROMPT", "RATINGS", "USEFUL", "HALLUCINATION", "SCORE")
+	for _, variant := range stats {
+		prompt := variant.Prompt
+		if prompt == "" {
+			prompt = "(unknown)"
+		}
+		fmt.Printf("  %-24s %-14s %8d %7.1f%% %13.1f%% %7.3f\n",
+			truncateString(variant.Model, 24), truncateString(prompt, 14), variant.Ratings,
+			variant.UsefulRate()*100, variant.HallucinationRate()*100,
+			summaryquality.DefaultEvaluator.Score(variant))
+	}
 	fmt.Println()
 
 	return nil
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/config"
+	usermodels "github.com/Fancu1/phoenix-rss/internal/user-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/password"
+)
+
+func newUsersCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "users",
+		Short: "Manage users",
+		Long:  `Inspect user accounts and grant roles.`,
+	}
+
+	cmd.AddCommand(newUsersRehashStatusCmd())
+	cmd.AddCommand(newUsersSetRoleCmd())
+
+	return cmd
+}
+
+func newUsersRehashStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rehash-status",
+		Short: "Report password hashes due for an upgrade",
+		Long: `Group the stored password hashes by algorithm and parameters, and count those that
+differ from the configured password hashing. They are upgraded when their user next logs in.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUsersRehashStatus()
+		},
+	}
+
+	return cmd
+}
+
+func newUsersSetRoleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set-role [username] [role]",
+		Short: "Make a user an administrator or a regular user",
+		Long: `Set the role of a user to "admin" or "user". Administrators may use the /api/v1/admin
+endpoints with their own token; use this to appoint the first one.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUsersSetRole(args[0], args[1])
+		},
+	}
+
+	return cmd
+}
+
+func runUsersSetRole(username, role string) error {
+	role, err := usermodels.ParseRole(role)
+	if err != nil {
+		return err
+	}
+
+	result := db.WithContext(context.Background()).Model(&usermodels.User{}).
+		Where("username = ?", username).
+		Update("role", role)
+	if result.Error != nil {
+		return fmt.Errorf("failed to set role: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user %q not found", username)
+	}
+
+	fmt.Printf("User %q now has the %s role.\n", username, role)
+	return nil
+}
+
+func runUsersRehashStatus() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	hasher, err := password.NewHasher(cfg.Auth.PasswordHashing.Params())
+	if err != nil {
+		return fmt.Errorf("invalid password hashing config: %w", err)
+	}
+
+	byScheme := make(map[string]int64)
+	var total, outdated int64
+	var users []usermodels.User
+	result := db.WithContext(context.Background()).
+		Select("id", "password_hash").
+		FindInBatches(&users, 500, func(tx *gorm.DB, batch int) error {
+			for _, user := range users {
+				byScheme[password.Describe(user.PasswordHash)]++
+				total++
+				if hasher.NeedsRehash(user.PasswordHash) {
+					outdated++
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to read password hashes: %w", result.Error)
+	}
+
+	schemes := make([]string, 0, len(byScheme))
+	for scheme := range byScheme {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+
+	fmt.Printf("\nConfigured algorithm: %s\n\n", cfg.Auth.PasswordHashing.Algorithm)
+	fmt.Printf("%-32s | %8s\n", "Hash", "Users")
+	fmt.Println(strings.Repeat("-", 43))
+	for _, scheme := range schemes {
+		fmt.Printf("%-32s | %8d\n", scheme, byScheme[scheme])
+	}
+	fmt.Println()
+	fmt.Printf("%d of %d users have a hash due for an upgrade at their next login.\n", outdated, total)
+	if byScheme[password.AlgorithmUnknown] > 0 {
+		fmt.Printf("%d users have a hash in an unknown format and cannot log in.\n", byScheme[password.AlgorithmUnknown])
+	}
+	return nil
+}
@@ -3,83 +3,136 @@ package main
 import (
 	"context"
 	"fmt"
-	"log/slog"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"gorm.io/gorm"
 
 	"github.com/Fancu1/phoenix-rss/internal/config"
+	"github.com/Fancu1/phoenix-rss/internal/engagement"
 	"github.com/Fancu1/phoenix-rss/internal/events"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
+	"github.com/Fancu1/phoenix-rss/internal/policy"
+	"github.com/Fancu1/phoenix-rss/internal/readstate"
+	"github.com/Fancu1/phoenix-rss/internal/reports"
 	"github.com/Fancu1/phoenix-rss/internal/scheduler-service/client"
 	"github.com/Fancu1/phoenix-rss/internal/scheduler-service/service"
+	"github.com/Fancu1/phoenix-rss/pkg/app"
+	"github.com/Fancu1/phoenix-rss/pkg/grpctls"
 	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/mailer"
+	"github.com/Fancu1/phoenix-rss/pkg/serviceauth"
 )
 
 func main() {
-	if err := logger.InitFromEnv(); err != nil {
-		fmt.Printf("Failed to initialize logger: %v\n", err)
+	a, err := app.New("scheduler-service")
+	if err != nil {
+		fmt.Printf("Failed to initialize service: %v\n", err)
 		os.Exit(1)
 	}
-	defer logger.Close()
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		fmt.Printf("Failed to load config: %v\n", err)
-		os.Exit(1)
+		a.Fatal("failed to load config", "error", err)
 	}
 
-	log := logger.New(slog.LevelDebug)
+	log := a.Logger()
+	if err := a.SetupTracing(cfg.Tracing.Params()); err != nil {
+		a.Fatal("failed to set up tracing", "error", err)
+	}
 
 	// Create gRPC connection to feed service
-	conn, err := grpc.NewClient(
-		cfg.FeedService.Address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	feedCreds, err := grpctls.ClientCredentials(cfg.FeedService.TLS.Params())
 	if err != nil {
-		log.Error("failed to connect to feed service", "address", cfg.FeedService.Address, "error", err)
-		os.Exit(1)
+		a.Fatal("failed to set up feed service TLS", "error", err)
+	}
+	dialOptions := []grpc.DialOption{
+		grpc.WithTransportCredentials(feedCreds),
+		grpc.WithUnaryInterceptor(logger.RequestIDClientInterceptor()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 	}
-	defer conn.Close()
+	if cfg.Auth.ServiceToken != "" {
+		dialOptions = append(dialOptions, serviceauth.NewSigner("scheduler-service", cfg.Auth.ServiceToken).DialOption())
+	} else {
+		log.Warn("AUTH_SERVICE_TOKEN is not set, calls to the feed service are unauthenticated")
+	}
+	conn, err := grpc.NewClient(cfg.FeedService.Address, dialOptions...)
+	if err != nil {
+		a.Fatal("failed to connect to feed service", "address", cfg.FeedService.Address, "error", err)
+	}
+	a.Closer("feed service connection", conn)
 
 	// Create feed service client
 	feedClient := client.NewFeedServiceClient(conn, log)
 
+	var routing *events.Routing
+	if cfg.Kafka.Routing.Enabled {
+		routing, err = events.NewRouting(cfg.Kafka.Routing.Topic, cfg.Kafka.Routing.Strict, cfg.Kafka.Routing.EventTypes)
+		if err != nil {
+			a.Fatal("invalid kafka routing config", "error", err)
+		}
+	}
+
+	compression, err := events.ParseCompression(cfg.Kafka.Compression)
+	if err != nil {
+		a.Fatal("invalid kafka compression", "value", cfg.Kafka.Compression, "error", err)
+	}
+	var sizeReportInterval time.Duration
+	if cfg.Kafka.SizeReportInterval != "" {
+		sizeReportInterval, err = time.ParseDuration(cfg.Kafka.SizeReportInterval)
+		if err != nil {
+			a.Fatal("failed to parse kafka size report interval", "value", cfg.Kafka.SizeReportInterval, "error", err)
+		}
+	}
+	producerOptions := events.ProducerOptions{Compression: compression}
+	if sizeReportInterval > 0 {
+		producerOptions.Sizes = events.NewMessageSizes()
+	}
+
 	// Create Kafka producer
 	producer := events.NewKafkaProducer(log, events.KafkaConfig{
 		Brokers: cfg.Kafka.Brokers,
-		Topic:   cfg.Kafka.FeedFetch.Topic,
+		Topic:   routing.TopicFor(events.EventFeedFetch, cfg.Kafka.FeedFetch.Topic),
 		GroupID: cfg.Kafka.FeedFetch.FeedServiceGroupID, // Use same topic and group for scheduler
 	})
-	defer producer.Close()
+	producer.SetProducerOptions(producerOptions)
+	a.Closer("feed fetch producer", producer)
 
 	articleCheckProducer := events.NewKafkaArticleCheckProducer(log, events.KafkaConfig{
 		Brokers: cfg.Kafka.Brokers,
-		Topic:   cfg.Kafka.ArticleCheck.Topic,
+		Topic:   routing.TopicFor(events.EventArticleCheck, cfg.Kafka.ArticleCheck.Topic),
 	})
-	defer articleCheckProducer.Close()
+	articleCheckProducer.SetProducerOptions(producerOptions)
+	a.Closer("article check producer", articleCheckProducer)
 
 	// Parse batch delay duration
 	batchDelay, err := time.ParseDuration(cfg.SchedulerService.BatchDelay)
 	if err != nil {
-		log.Error("failed to parse batch delay", "batch_delay", cfg.SchedulerService.BatchDelay, "error", err)
-		os.Exit(1)
+		a.Fatal("failed to parse batch delay", "batch_delay", cfg.SchedulerService.BatchDelay, "error", err)
 	}
 
-	minCheckInterval, err := time.ParseDuration(cfg.SchedulerService.ArticleCheck.MinCheckInterval)
+	minFetchInterval, err := time.ParseDuration(cfg.SchedulerService.MinFetchInterval)
 	if err != nil {
-		log.Error("failed to parse article check min interval", "value", cfg.SchedulerService.ArticleCheck.MinCheckInterval, "error", err)
-		os.Exit(1)
+		a.Fatal("failed to parse min fetch interval", "value", cfg.SchedulerService.MinFetchInterval, "error", err)
+	}
+
+	lowTierFetchInterval, err := time.ParseDuration(cfg.SchedulerService.LowTierFetchInterval)
+	if err != nil {
+		a.Fatal("failed to parse low tier fetch interval", "value", cfg.SchedulerService.LowTierFetchInterval, "error", err)
 	}
 
-	articleWindow := time.Duration(cfg.SchedulerService.ArticleCheck.WindowDays) * 24 * time.Hour
+	// the article check window and interval apply to the whole instance
+	policies, err := policy.Load(cfg)
+	if err != nil {
+		a.Fatal("failed to load policies", "file", cfg.Policy.File, "error", err)
+	}
+	articleWindow := policies.Defaults().ArticleCheckWindow
+	minCheckInterval := policies.Defaults().ArticleCheckInterval
 	articlePageSize := cfg.SchedulerService.ArticleCheck.PageSize
 	if articlePageSize <= 0 {
-		log.Error("invalid article check page size", "value", articlePageSize)
-		os.Exit(1)
+		a.Fatal("invalid article check page size", "value", articlePageSize)
 	}
 
 	// Create and start scheduler
@@ -97,13 +150,78 @@ func main() {
 		minCheckInterval,
 		articlePageSize,
 	)
+	scheduler.SetFeedPaging(cfg.SchedulerService.FeedPageSize, minFetchInterval)
+	scheduler.SetLowTierInterval(lowTierFetchInterval)
+	scheduler.SetArticleCheckMaxPageSize(cfg.SchedulerService.ArticleCheck.MaxPageSize)
+	if catchUpCfg := cfg.SchedulerService.CatchUp; catchUpCfg.Enabled {
+		catchUpGap, err := time.ParseDuration(catchUpCfg.Gap)
+		if err != nil {
+			a.Fatal("failed to parse catch-up gap", "value", catchUpCfg.Gap, "error", err)
+		}
+		catchUpWindow, err := time.ParseDuration(catchUpCfg.Window)
+		if err != nil {
+			a.Fatal("failed to parse catch-up window", "value", catchUpCfg.Window, "error", err)
+		}
+		scheduler.SetCatchUp(catchUpGap, catchUpWindow)
+	}
+
+	// The operator report, engagement and compaction jobs work straight on the database
+	var db *gorm.DB
+	if cfg.SchedulerService.OperatorReport.Enabled || cfg.SchedulerService.Engagement.Enabled || cfg.SchedulerService.StateCompaction.Enabled {
+		db = repository.InitDB(&cfg.Database)
+		sqlDB, err := db.DB()
+		if err != nil {
+			a.Fatal("failed to open database", "error", err)
+		}
+		a.Closer("database", sqlDB)
+	}
+
+	if reportCfg := cfg.SchedulerService.OperatorReport; reportCfg.Enabled {
+		reportMailer := mailer.New(mailer.Config{
+			Host:     cfg.Email.SMTPHost,
+			Port:     cfg.Email.SMTPPort,
+			Username: cfg.Email.SMTPUsername,
+			Password: cfg.Email.SMTPPassword,
+			From:     cfg.Email.From,
+		}, log)
+		reportJob := reports.NewJob(db, reports.NewCollector(db, reportCfg.TokenPricePerMillion), reportMailer, reportCfg.Recipients, log)
+		scheduler.AddJob("operator report", reportCfg.Cron, func(ctx context.Context) {
+			if _, err := reportJob.Run(ctx); err != nil {
+				log.Error("operator report failed", "error", err)
+			}
+		})
+	}
+
+	if engagementCfg := cfg.SchedulerService.Engagement; engagementCfg.Enabled {
+		engagementJob := engagement.NewJob(db, log)
+		scheduler.AddJob("subscription engagement", engagementCfg.Cron, func(ctx context.Context) {
+			if _, err := engagementJob.Run(ctx); err != nil {
+				log.Error("subscription engagement failed", "error", err)
+			}
+		})
+	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	if compactionCfg := cfg.SchedulerService.StateCompaction; compactionCfg.Enabled {
+		retention, err := time.ParseDuration(compactionCfg.Retention)
+		if err != nil || retention <= 0 {
+			a.Fatal("failed to parse state compaction retention", "value", compactionCfg.Retention, "error", err)
+		}
+		compactionJob := readstate.NewCompactionJob(readstate.NewStore(db), retention, log)
+		scheduler.AddJob("article state compaction", compactionCfg.Cron, func(ctx context.Context) {
+			if _, err := compactionJob.Run(ctx); err != nil {
+				log.Error("article state compaction failed", "error", err)
+			}
+		})
+	}
+
+	if producerOptions.Sizes != nil {
+		a.Go("kafka message size report", func(ctx context.Context) error {
+			producerOptions.Sizes.Run(ctx, log, sizeReportInterval, compression)
+			return nil
+		})
+	}
 
-	// Handle graceful shutdown
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	a.Add("scheduler", scheduler.Start, scheduler.Stop)
 
 	log.Info("starting scheduler service",
 		"schedule", cfg.SchedulerService.Schedule,
@@ -111,28 +229,7 @@ func main() {
 		"batch_delay", cfg.SchedulerService.BatchDelay,
 		"max_concurrent", cfg.SchedulerService.MaxConcurrent,
 	)
-
-	// Start scheduler
-	if err := scheduler.Start(ctx); err != nil {
-		log.Error("failed to start scheduler", "error", err)
+	if err := a.Run(context.Background()); err != nil {
 		os.Exit(1)
 	}
-
-	// Wait for shutdown signal
-	select {
-	case sig := <-signalChan:
-		log.Info("received shutdown signal", "signal", sig)
-		cancel()
-	case <-ctx.Done():
-	}
-
-	// Graceful shutdown
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer shutdownCancel()
-
-	if err := scheduler.Stop(shutdownCtx); err != nil {
-		log.Error("failed to stop scheduler gracefully", "error", err)
-	}
-
-	log.Info("scheduler service shutdown completed")
 }
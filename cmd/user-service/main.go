@@ -1,73 +1,131 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log/slog"
-	"net"
 	"os"
+	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/health"
-	"google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/Fancu1/phoenix-rss/internal/config"
 	"github.com/Fancu1/phoenix-rss/internal/user-service/core"
 	"github.com/Fancu1/phoenix-rss/internal/user-service/handler"
 	userRepo "github.com/Fancu1/phoenix-rss/internal/user-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/app"
+	"github.com/Fancu1/phoenix-rss/pkg/dbtx"
+	"github.com/Fancu1/phoenix-rss/pkg/grpctls"
 	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/mailer"
+	"github.com/Fancu1/phoenix-rss/pkg/password"
+	"github.com/Fancu1/phoenix-rss/pkg/secrets"
+	"github.com/Fancu1/phoenix-rss/pkg/serviceauth"
 	userpb "github.com/Fancu1/phoenix-rss/protos/gen/go/user"
 )
 
 func main() {
-	if err := logger.InitFromEnv(); err != nil {
-		fmt.Printf("Failed to initialize logger: %v\n", err)
+	a, err := app.New("user-service")
+	if err != nil {
+		fmt.Printf("Failed to initialize service: %v\n", err)
 		os.Exit(1)
 	}
-	defer logger.Close()
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		fmt.Printf("Failed to load config: %v\n", err)
-		os.Exit(1)
+		a.Fatal("failed to load config", "error", err)
 	}
 
-	log := logger.New(slog.LevelDebug)
+	log := a.Logger()
+	if err := a.SetupTracing(cfg.Tracing.Params()); err != nil {
+		a.Fatal("failed to set up tracing", "error", err)
+	}
 
 	// initialize database connection
 	db := userRepo.InitDB(&cfg.Database)
+	sqlDB, err := db.DB()
+	if err != nil {
+		a.Fatal("failed to open database", "error", err)
+	}
+	a.Closer("database", sqlDB)
 
 	// initialize user repository and service
 	userRepository := userRepo.NewUserRepository(db)
-	userSvc := core.NewUserService(userRepository, cfg.Auth.JWTSecret)
+	userSvc := core.NewUserService(userRepository, userRepo.NewSessionRepository(db), cfg.Auth.JWTSecret)
+	passwordHasher, err := password.NewHasher(cfg.Auth.PasswordHashing.Params())
+	if err != nil {
+		a.Fatal("failed to initialize password hashing", "error", err)
+	}
+	userSvc.SetPasswordHasher(passwordHasher)
+	userSvc.SetUnitOfWork(dbtx.NewUnitOfWork(db))
+	accessTTL, sessionTTL, err := cfg.Auth.TokenTTLs()
+	if err != nil {
+		a.Fatal("invalid token ttls", "error", err)
+	}
+	userSvc.SetTokenTTLs(accessTTL, sessionTTL)
+	impersonationTTL, err := time.ParseDuration(cfg.Auth.ImpersonationTTL)
+	if err != nil {
+		a.Fatal("invalid impersonation ttl", "value", cfg.Auth.ImpersonationTTL, "error", err)
+	}
+	userSvc.SetImpersonationTTL(impersonationTTL)
+	resetTTL, err := time.ParseDuration(cfg.Auth.PasswordReset.TTL)
+	if err != nil {
+		a.Fatal("invalid password reset ttl", "value", cfg.Auth.PasswordReset.TTL, "error", err)
+	}
+	resetMailer := mailer.New(mailer.Config{
+		Host:     cfg.Email.SMTPHost,
+		Port:     cfg.Email.SMTPPort,
+		Username: cfg.Email.SMTPUsername,
+		Password: cfg.Email.SMTPPassword,
+		From:     cfg.Email.From,
+	}, log)
+	userSvc.SetPasswordResets(userRepo.NewPasswordResetRepository(db), resetMailer, core.PasswordResetOptions{
+		TTL: resetTTL,
+		URL: cfg.Auth.PasswordReset.URL,
+	})
+
+	// initialize per-user LLM credential storage (bring-your-own-key)
+	credentialCipher, err := secrets.NewCipher(cfg.Auth.CredentialsKey)
+	if err != nil {
+		a.Fatal("failed to initialize credentials cipher", "error", err)
+	}
+	credentialSvc := core.NewCredentialService(userRepo.NewCredentialRepository(db), credentialCipher)
 
 	// create gRPC handler
-	grpcHandler := handler.NewUserServiceHandler(userSvc)
+	grpcHandler := handler.NewUserServiceHandler(userSvc, credentialSvc)
+	grpcHandler.SetPreferenceService(core.NewPreferenceService(userRepo.NewPreferenceRepository(db)))
 
-	// create gRPC server
-	grpcServer := grpc.NewServer()
+	// create gRPC server, refusing unsigned calls once a service token is set
+	serverCreds, err := grpctls.ServerCredentials(cfg.UserService.TLS.Params())
+	if err != nil {
+		a.Fatal("failed to set up gRPC TLS", "error", err)
+	}
+	interceptors := []grpc.UnaryServerInterceptor{logger.RequestIDServerInterceptor()}
+	if cfg.Auth.ServiceToken != "" {
+		skew, err := cfg.Auth.ServiceTokenSkew()
+		if err != nil {
+			a.Fatal("invalid service token max skew", "error", err)
+		}
+		interceptors = append(interceptors, serviceauth.NewVerifier(cfg.Auth.ServiceToken, skew).ServerInterceptor())
+	} else {
+		log.Warn("AUTH_SERVICE_TOKEN is not set, the gRPC server accepts calls from any peer")
+	}
+	grpcServer := grpc.NewServer(
+		grpc.Creds(serverCreds),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(interceptors...),
+	)
 	userpb.RegisterUserServiceServer(grpcServer, grpcHandler)
 
-	// register gRPC health check service
-	healthServer := health.NewServer()
-	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
-	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
-
 	// start listening on the specified port
 	port := "50051" // default port for user service
 	if userServicePort := os.Getenv("USER_SERVICE_PORT"); userServicePort != "" {
 		port = userServicePort
 	}
-
-	lis, err := net.Listen("tcp", ":"+port)
-	if err != nil {
-		log.Error("failed to listen", "port", port, "error", err)
-		os.Exit(1)
-	}
+	a.ServeGRPC("gRPC server", grpcServer, ":"+port)
 
 	log.Info("User Service starting", "port", port)
-
-	if err := grpcServer.Serve(lis); err != nil {
-		log.Error("failed to serve gRPC server", "error", err)
+	if err := a.Run(context.Background()); err != nil {
 		os.Exit(1)
 	}
 }
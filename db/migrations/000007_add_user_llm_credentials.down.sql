@@ -0,0 +1,3 @@
+-- Remove per-user LLM credentials and usage ledger
+DROP TABLE IF EXISTS llm_usage;
+DROP TABLE IF EXISTS user_llm_credentials;
@@ -0,0 +1,25 @@
+-- Per-user LLM credentials (bring-your-own-key). The API key is stored encrypted
+-- with AUTH_CREDENTIALS_KEY; only the last few characters are kept in clear for display.
+CREATE TABLE IF NOT EXISTS user_llm_credentials (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    base_url TEXT NOT NULL DEFAULT '',
+    model VARCHAR(255) NOT NULL DEFAULT '',
+    api_key_ciphertext TEXT NOT NULL,
+    api_key_hint VARCHAR(16) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- LLM usage ledger. user_id is NULL when the instance-wide key was used.
+CREATE TABLE IF NOT EXISTS llm_usage (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    article_id INTEGER REFERENCES articles(id) ON DELETE SET NULL,
+    model VARCHAR(255) NOT NULL DEFAULT '',
+    byok BOOLEAN NOT NULL DEFAULT FALSE,
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    total_tokens INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_llm_usage_user_created ON llm_usage (user_id, created_at);
@@ -0,0 +1,7 @@
+-- Remove notifications and feed archiving columns
+DROP TABLE IF EXISTS notifications;
+DROP INDEX IF EXISTS idx_feeds_gone_since;
+UPDATE feeds SET status = 'error' WHERE status = 'archived';
+ALTER TABLE feeds
+    DROP COLUMN IF EXISTS archived_at,
+    DROP COLUMN IF EXISTS gone_since;
@@ -0,0 +1,19 @@
+-- Track feeds whose source has disappeared (HTTP 404/410) so they can be archived
+-- instead of being fetched forever. 'archived' feeds are excluded from scheduling.
+ALTER TABLE feeds
+    ADD COLUMN IF NOT EXISTS gone_since TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_feeds_gone_since ON feeds (gone_since) WHERE gone_since IS NOT NULL;
+
+-- User-facing notifications (e.g. a subscribed feed was archived or came back)
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feed_id INTEGER REFERENCES feeds(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications (user_id, created_at DESC);
@@ -0,0 +1,2 @@
+-- Remove the summary truncation flag from articles table
+ALTER TABLE articles DROP COLUMN IF EXISTS summary_truncated;
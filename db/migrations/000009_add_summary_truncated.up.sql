@@ -0,0 +1,3 @@
+-- Flag summaries the LLM cut off at its token limit
+ALTER TABLE articles
+    ADD COLUMN IF NOT EXISTS summary_truncated BOOLEAN NOT NULL DEFAULT false;
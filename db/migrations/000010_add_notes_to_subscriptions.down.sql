@@ -0,0 +1,2 @@
+-- Remove notes column from subscriptions table
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS notes;
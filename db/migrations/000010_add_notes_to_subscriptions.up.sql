@@ -0,0 +1,2 @@
+-- Free-form user note on a subscription (why they follow it, what to watch for)
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS notes TEXT;
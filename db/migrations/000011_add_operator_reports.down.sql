@@ -0,0 +1,7 @@
+-- Remove operator report history and feed fetch error tracking
+DROP TABLE IF EXISTS operator_reports;
+
+DROP INDEX IF EXISTS idx_feeds_last_fetch_error_at;
+
+ALTER TABLE feeds DROP COLUMN IF EXISTS last_fetch_error_at;
+ALTER TABLE feeds DROP COLUMN IF EXISTS last_fetch_error;
@@ -0,0 +1,18 @@
+-- Last fetch error per feed, used by the operator report to list failing feeds and top errors
+ALTER TABLE feeds
+    ADD COLUMN IF NOT EXISTS last_fetch_error TEXT,
+    ADD COLUMN IF NOT EXISTS last_fetch_error_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_feeds_last_fetch_error_at ON feeds (last_fetch_error_at);
+
+-- History of the weekly operator reports; report holds the full JSON artifact
+CREATE TABLE IF NOT EXISTS operator_reports (
+    id BIGSERIAL PRIMARY KEY,
+    period_start TIMESTAMPTZ NOT NULL,
+    period_end TIMESTAMPTZ NOT NULL,
+    report JSONB NOT NULL,
+    recipients TEXT NOT NULL DEFAULT '',
+    emailed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_operator_reports_period_end ON operator_reports (period_end);
@@ -0,0 +1,2 @@
+-- Remove custom fetch headers from subscriptions table
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS fetch_headers_ciphertext;
@@ -0,0 +1,2 @@
+-- Custom headers sent when fetching a private feed, encrypted with the credentials key
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS fetch_headers_ciphertext TEXT;
@@ -0,0 +1,2 @@
+-- Remove raw feed response snapshots
+DROP TABLE IF EXISTS feed_snapshots;
@@ -0,0 +1,14 @@
+-- Last raw responses per feed, kept for debugging when enabled (feed_service.snapshots.keep).
+-- body is gzip-compressed and capped; older rows are pruned on every insert.
+CREATE TABLE IF NOT EXISTS feed_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    feed_id INTEGER NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    status_code INTEGER NOT NULL,
+    content_type TEXT NOT NULL DEFAULT '',
+    body BYTEA NOT NULL,
+    size BIGINT NOT NULL,
+    truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    parse_error TEXT,
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_feed_snapshots_feed_id_fetched_at ON feed_snapshots (feed_id, fetched_at DESC);
@@ -0,0 +1,4 @@
+-- Remove feed fetch time tracking
+DROP INDEX IF EXISTS idx_feeds_last_fetched_at;
+
+ALTER TABLE feeds DROP COLUMN IF EXISTS last_fetched_at;
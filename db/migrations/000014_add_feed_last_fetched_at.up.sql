@@ -0,0 +1,4 @@
+-- When each feed was last fetched, so the scheduler can page through only the feeds that are due
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS last_fetched_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_feeds_last_fetched_at ON feeds (last_fetched_at);
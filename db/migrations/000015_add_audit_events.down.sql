@@ -0,0 +1,2 @@
+-- Remove the audit trail
+DROP TABLE IF EXISTS audit_events;
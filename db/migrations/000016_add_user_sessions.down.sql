@@ -0,0 +1,2 @@
+-- Remove login sessions
+DROP TABLE IF EXISTS user_sessions;
@@ -0,0 +1,2 @@
+-- Remove subscription engagement
+DROP TABLE IF EXISTS subscription_engagement;
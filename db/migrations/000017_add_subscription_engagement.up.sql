@@ -0,0 +1,10 @@
+-- Articles read vs delivered per subscription over the last 90 days, recomputed nightly
+-- by the scheduler to suggest feeds a user never reads.
+CREATE TABLE IF NOT EXISTS subscription_engagement (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feed_id INTEGER NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    delivered BIGINT NOT NULL DEFAULT 0,
+    read_count BIGINT NOT NULL DEFAULT 0,
+    computed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, feed_id)
+);
@@ -0,0 +1,2 @@
+-- Remove the AI summary cache
+DROP TABLE IF EXISTS ai_summary_cache;
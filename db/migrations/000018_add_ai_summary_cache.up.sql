@@ -0,0 +1,12 @@
+-- AI summaries keyed by a hash of the normalized article title and content, reused for
+-- copies of an article that arrive through other feeds instead of calling the LLM again
+CREATE TABLE IF NOT EXISTS ai_summary_cache (
+    content_hash VARCHAR(64) PRIMARY KEY,
+    summary TEXT NOT NULL DEFAULT '',
+    summary_truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    processing_model VARCHAR(255) NOT NULL DEFAULT '',
+    hits BIGINT NOT NULL DEFAULT 0,
+    last_hit_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
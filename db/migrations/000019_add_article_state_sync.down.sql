@@ -0,0 +1,3 @@
+-- Remove per-user article state sync
+DROP TABLE IF EXISTS user_article_states;
+DROP TABLE IF EXISTS article_state_events;
@@ -0,0 +1,29 @@
+-- Per-user read and starred state for sync clients. Every change is appended to
+-- article_state_events; user_article_states is the compacted result, merged last writer
+-- wins per article and field on the client's timestamp. Events past the retention are
+-- pruned by the scheduler once they are folded into the states.
+CREATE TABLE IF NOT EXISTS article_state_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    field VARCHAR(16) NOT NULL,
+    value BOOLEAN NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    device_id VARCHAR(128) NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_article_state_events_user_id_id ON article_state_events (user_id, id);
+CREATE INDEX IF NOT EXISTS idx_article_state_events_received_at ON article_state_events (received_at);
+
+CREATE TABLE IF NOT EXISTS user_article_states (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    read BOOLEAN NOT NULL DEFAULT false,
+    read_changed_at TIMESTAMPTZ,
+    starred BOOLEAN NOT NULL DEFAULT false,
+    starred_changed_at TIMESTAMPTZ,
+    -- seq is the ID of the last event that changed the row, the cursor of incremental sync
+    seq BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, article_id)
+);
+CREATE INDEX IF NOT EXISTS idx_user_article_states_user_id_seq ON user_article_states (user_id, seq);
@@ -0,0 +1,3 @@
+-- Media type of the article page as last fetched by the update checker; NULL until checked
+ALTER TABLE articles
+    ADD COLUMN IF NOT EXISTS content_type VARCHAR(100) NULL;
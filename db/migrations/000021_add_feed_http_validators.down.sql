@@ -0,0 +1,3 @@
+ALTER TABLE feeds
+    DROP COLUMN IF EXISTS http_last_modified,
+    DROP COLUMN IF EXISTS http_etag;
@@ -0,0 +1,4 @@
+-- Validators of the last feed response, sent back as If-None-Match / If-Modified-Since
+ALTER TABLE feeds
+    ADD COLUMN IF NOT EXISTS http_etag TEXT NULL,
+    ADD COLUMN IF NOT EXISTS http_last_modified TEXT NULL;
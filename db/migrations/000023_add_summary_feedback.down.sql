@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS summary_feedback;
+
+ALTER TABLE ai_summary_cache
+    DROP COLUMN IF EXISTS processing_prompt;
+ALTER TABLE articles
+    DROP COLUMN IF EXISTS processing_prompt;
@@ -0,0 +1,22 @@
+-- The prompt variant an article was summarized with, next to processing_model
+ALTER TABLE articles
+    ADD COLUMN IF NOT EXISTS processing_prompt VARCHAR(64) NULL;
+ALTER TABLE ai_summary_cache
+    ADD COLUMN IF NOT EXISTS processing_prompt VARCHAR(64) NOT NULL DEFAULT '';
+
+-- Readers' ratings of AI summaries, one per user and article. The model and prompt are
+-- copied from the article when it is rated, so a later regeneration does not move the
+-- rating to another variant.
+CREATE TABLE IF NOT EXISTS summary_feedback (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    processing_model VARCHAR(255) NOT NULL DEFAULT '',
+    processing_prompt VARCHAR(64) NOT NULL DEFAULT '',
+    useful BOOLEAN NOT NULL,
+    hallucination BOOLEAN NOT NULL DEFAULT false,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, article_id)
+);
+CREATE INDEX IF NOT EXISTS idx_summary_feedback_variant ON summary_feedback (processing_model, processing_prompt, updated_at);
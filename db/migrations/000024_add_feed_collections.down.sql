@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS feed_collection_feeds;
+DROP TABLE IF EXISTS feed_collections;
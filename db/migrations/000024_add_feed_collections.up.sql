@@ -0,0 +1,21 @@
+-- Operator-curated collections of feeds that users subscribe to in one go. Feeds are
+-- listed by URL, so a collection may name feeds this instance has not fetched yet.
+-- Collections with subscribe_new_users set are the instance's default feeds.
+CREATE TABLE IF NOT EXISTS feed_collections (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    subscribe_new_users BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS feed_collection_feeds (
+    collection_id INTEGER NOT NULL REFERENCES feed_collections(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (collection_id, position)
+);
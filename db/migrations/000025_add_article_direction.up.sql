@@ -0,0 +1,4 @@
+-- Text direction to render the article content in (ltr or rtl); articles stored before
+-- it was detected are left to right until their content is next updated
+ALTER TABLE articles
+    ADD COLUMN IF NOT EXISTS direction VARCHAR(3) NOT NULL DEFAULT 'ltr';
@@ -0,0 +1,10 @@
+-- Per-user reading preferences (default sort, show read, list content, theme), kept
+-- server-side so they follow the user across devices. Users without a row have the defaults.
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    default_sort VARCHAR(16) NOT NULL DEFAULT 'recent',
+    show_read BOOLEAN NOT NULL DEFAULT TRUE,
+    list_content VARCHAR(16) NOT NULL DEFAULT 'excerpt',
+    theme VARCHAR(16) NOT NULL DEFAULT 'system',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
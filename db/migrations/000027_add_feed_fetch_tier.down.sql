@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_feeds_status_fetch_tier;
+
+ALTER TABLE feeds
+    DROP COLUMN IF EXISTS fetch_tier;
+
+UPDATE feeds SET status = 'active' WHERE status = 'suspended';
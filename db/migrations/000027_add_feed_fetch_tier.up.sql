@@ -0,0 +1,7 @@
+-- Fetch tier of a feed: high feeds are fetched on every scheduler run, low feeds only
+-- once the low tier interval has passed. Suspended feeds (status 'suspended') are not
+-- fetched at all.
+ALTER TABLE feeds
+    ADD COLUMN IF NOT EXISTS fetch_tier VARCHAR(16) NOT NULL DEFAULT 'normal';
+
+CREATE INDEX IF NOT EXISTS idx_feeds_status_fetch_tier ON feeds (status, fetch_tier);
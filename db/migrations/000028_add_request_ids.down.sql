@@ -0,0 +1,6 @@
+ALTER TABLE feeds
+    DROP COLUMN IF EXISTS last_fetch_request_id;
+
+ALTER TABLE articles
+    DROP COLUMN IF EXISTS processing_request_id,
+    DROP COLUMN IF EXISTS request_id;
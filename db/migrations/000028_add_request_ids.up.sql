@@ -0,0 +1,9 @@
+-- ID of the request that caused each asynchronous write, so a user action can be traced
+-- from the API through Kafka to the rows it produced. Rows written before IDs were
+-- propagated keep NULL.
+ALTER TABLE articles
+    ADD COLUMN IF NOT EXISTS request_id VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS processing_request_id VARCHAR(64);
+
+ALTER TABLE feeds
+    ADD COLUMN IF NOT EXISTS last_fetch_request_id VARCHAR(64);
@@ -0,0 +1,4 @@
+-- Length of the current streak of failed fetches; a feed is set to error and no longer
+-- scheduled once it reaches the configured threshold. A successful fetch resets it.
+ALTER TABLE feeds
+    ADD COLUMN IF NOT EXISTS consecutive_failures INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,17 @@
+-- Summary variants of an article, one per model and language, so an article can carry
+-- e.g. an English and a Chinese summary or the summaries of two models side by side.
+-- articles.summary stays the default variant for older clients; the existing summaries
+-- are copied in by the online migration 0004_article_summaries.
+CREATE TABLE IF NOT EXISTS article_summaries (
+    id BIGSERIAL PRIMARY KEY,
+    article_id INTEGER NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    model VARCHAR(255) NOT NULL DEFAULT '',
+    language VARCHAR(16) NOT NULL,
+    summary TEXT NOT NULL,
+    truncated BOOLEAN NOT NULL DEFAULT false,
+    prompt VARCHAR(64),
+    request_id VARCHAR(64),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (article_id, model, language)
+);
@@ -0,0 +1,13 @@
+-- Folders a user files subscriptions in. Folders nest through parent_id; the names of a
+-- folder's children are unique, top-level folders having parent 0 for the index.
+-- subscriptions.folder_id is added by the online migration 0005_subscription_folders.
+CREATE TABLE IF NOT EXISTS folders (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parent_id INTEGER REFERENCES folders(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_folders_user_parent_name ON folders (user_id, COALESCE(parent_id, 0), name);
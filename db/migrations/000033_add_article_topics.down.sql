@@ -0,0 +1,2 @@
+ALTER TABLE ai_summary_cache DROP COLUMN IF EXISTS topics;
+ALTER TABLE articles DROP COLUMN IF EXISTS topics;
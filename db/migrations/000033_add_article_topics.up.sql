@@ -0,0 +1,4 @@
+-- Topics the LLM lists along with an article's default summary, as a JSON array of
+-- lowercase strings, and with the cached summaries they are reused with
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS topics JSONB;
+ALTER TABLE ai_summary_cache ADD COLUMN IF NOT EXISTS topics TEXT NOT NULL DEFAULT '';
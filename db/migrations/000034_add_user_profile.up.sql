@@ -0,0 +1,5 @@
+-- Profile fields users manage themselves. Emails are stored lower-cased, so the unique
+-- index also rules out addresses differing only in case; users without one keep NULL.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(254);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name VARCHAR(100) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email) WHERE email IS NOT NULL;
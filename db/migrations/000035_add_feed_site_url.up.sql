@@ -0,0 +1,2 @@
+-- The website a feed belongs to, taken from the feed's own link on every fetch
+ALTER TABLE feeds ADD COLUMN IF NOT EXISTS site_url TEXT NOT NULL DEFAULT '';
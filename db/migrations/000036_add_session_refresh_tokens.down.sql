@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_user_sessions_previous_refresh_hash;
+DROP INDEX IF EXISTS idx_user_sessions_refresh_token_hash;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS previous_refresh_hash;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS refresh_token_hash;
@@ -0,0 +1,6 @@
+-- A session's refresh token, stored as its SHA-256. Each refresh replaces it and keeps the
+-- one it replaced, so a replayed old token is recognized and ends the session.
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS refresh_token_hash VARCHAR(64);
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS previous_refresh_hash VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_sessions_refresh_token_hash ON user_sessions (refresh_token_hash);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_previous_refresh_hash ON user_sessions (previous_refresh_hash);
@@ -0,0 +1,3 @@
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS summary_language;
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS items_per_page;
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS default_view;
@@ -0,0 +1,5 @@
+-- Preferences for the default article view, page size and summary language. The table
+-- has a row per user who changed a preference, so the columns are added with defaults.
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS default_view VARCHAR(16) NOT NULL DEFAULT 'all';
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS items_per_page INTEGER NOT NULL DEFAULT 50;
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS summary_language VARCHAR(16) NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Role of each user; administrators may use the /admin endpoints with their own token.
+-- Existing users become regular users.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user';
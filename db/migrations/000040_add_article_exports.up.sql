@@ -0,0 +1,19 @@
+-- Archives of every article of a feed, written in the background by the feed-service and
+-- kept in object storage under object_key. An export requested with the admin token has
+-- no user.
+CREATE TABLE IF NOT EXISTS article_exports (
+    id SERIAL PRIMARY KEY,
+    feed_id INTEGER NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    format VARCHAR(16) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'queued',
+    object_key TEXT NOT NULL DEFAULT '',
+    articles BIGINT NOT NULL DEFAULT 0,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_article_exports_status ON article_exports (status, id);
+CREATE INDEX IF NOT EXISTS idx_article_exports_user_id ON article_exports (user_id, created_at);
@@ -0,0 +1,15 @@
+-- Events the feed-service has to publish to Kafka, written in the transaction of the rows
+-- they describe. A relay publishes them in id order and sets published_at; a relay
+-- replica holds the rows it is publishing until locked_until.
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(32) NOT NULL,
+    payload BYTEA NOT NULL,
+    request_id VARCHAR(64),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    locked_by VARCHAR(64),
+    locked_until TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMPTZ
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS ai_processed_events;
@@ -0,0 +1,14 @@
+-- ArticlePersistedEvents the ai-service has claimed or processed, by article and the
+-- request that queued it. A consumer group rebalance can deliver an event again; its
+-- row tells the ai-service not to summarize the article a second time. Rows are deleted
+-- after the retention.
+CREATE TABLE IF NOT EXISTS ai_processed_events (
+    article_id BIGINT NOT NULL,
+    request_id VARCHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'processing',
+    claimed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    PRIMARY KEY (article_id, request_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_processed_events_claimed_at ON ai_processed_events (claimed_at);
@@ -0,0 +1,4 @@
+-- Set while the feed has items without a usable publication date, which the feed-service
+-- then dates from their place in the feed. Cleared by a fetch where every item has one.
+ALTER TABLE feeds
+    ADD COLUMN IF NOT EXISTS dates_unreliable BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+ALTER TABLE feeds
+    DROP COLUMN IF EXISTS imported_since;
@@ -0,0 +1,4 @@
+-- Items published before this date were left out of an import by the per-fetch limit or
+-- the initial cutoff. Fetches skip them until phoenix-admin feeds backfill imports them.
+ALTER TABLE feeds
+    ADD COLUMN IF NOT EXISTS imported_since TIMESTAMPTZ;
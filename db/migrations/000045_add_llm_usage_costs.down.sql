@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS llm_usage_daily;
+
+ALTER TABLE llm_usage
+    DROP COLUMN IF EXISTS cost_usd;
@@ -0,0 +1,25 @@
+-- The estimated cost of each LLM request, from the configured model prices; 0 for
+-- models without one and for requests recorded before prices existed.
+ALTER TABLE llm_usage
+    ADD COLUMN IF NOT EXISTS cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- LLM usage per UTC day, model and key source, kept up to date with the ledger. The
+-- ai-service checks its daily budget against today's instance-key rows.
+CREATE TABLE IF NOT EXISTS llm_usage_daily (
+    day DATE NOT NULL,
+    model VARCHAR(255) NOT NULL DEFAULT '',
+    byok BOOLEAN NOT NULL DEFAULT FALSE,
+    requests BIGINT NOT NULL DEFAULT 0,
+    prompt_tokens BIGINT NOT NULL DEFAULT 0,
+    completion_tokens BIGINT NOT NULL DEFAULT 0,
+    total_tokens BIGINT NOT NULL DEFAULT 0,
+    cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, model, byok)
+);
+
+INSERT INTO llm_usage_daily (day, model, byok, requests, prompt_tokens, completion_tokens, total_tokens, cost_usd)
+SELECT (created_at AT TIME ZONE 'UTC')::date, model, byok, COUNT(*), SUM(prompt_tokens),
+       SUM(completion_tokens), SUM(total_tokens), SUM(cost_usd)
+FROM llm_usage
+GROUP BY 1, 2, 3
+ON CONFLICT (day, model, byok) DO NOTHING;
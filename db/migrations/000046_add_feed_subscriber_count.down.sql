@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS subscriptions_count_subscribers ON subscriptions;
+DROP FUNCTION IF EXISTS feeds_count_subscribers();
+ALTER TABLE feeds
+    DROP COLUMN IF EXISTS subscriber_count;
@@ -0,0 +1,27 @@
+-- The number of users subscribed to a feed, kept by a trigger on subscriptions so every
+-- path that subscribes or unsubscribes, including cascades from deleted users and feeds,
+-- keeps it in step. The scheduler and the article checks read it to put popular feeds
+-- first; phoenix-admin doctor recounts it if it ever drifts.
+ALTER TABLE feeds
+    ADD COLUMN IF NOT EXISTS subscriber_count INTEGER NOT NULL DEFAULT 0;
+
+UPDATE feeds SET subscriber_count = (
+    SELECT COUNT(*) FROM subscriptions WHERE subscriptions.feed_id = feeds.id
+);
+
+CREATE OR REPLACE FUNCTION feeds_count_subscribers() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('DELETE', 'UPDATE') THEN
+        UPDATE feeds SET subscriber_count = subscriber_count - 1 WHERE id = OLD.feed_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE feeds SET subscriber_count = subscriber_count + 1 WHERE id = NEW.feed_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS subscriptions_count_subscribers ON subscriptions;
+CREATE TRIGGER subscriptions_count_subscribers
+    AFTER INSERT OR DELETE OR UPDATE OF feed_id ON subscriptions
+    FOR EACH ROW EXECUTE FUNCTION feeds_count_subscribers();
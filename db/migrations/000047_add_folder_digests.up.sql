@@ -0,0 +1,18 @@
+-- Digests of the unread articles of a folder, written by the api-service on a schedule
+-- the user sets and delivered as a notification. A digest is sent daily, or weekly on
+-- weekday (0 is Sunday), at hour in the IANA timezone; next_run_at is when it is next
+-- due, in UTC.
+CREATE TABLE IF NOT EXISTS folder_digests (
+    folder_id INTEGER PRIMARY KEY REFERENCES folders(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    frequency VARCHAR(16) NOT NULL,
+    hour SMALLINT NOT NULL,
+    weekday SMALLINT,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    next_run_at TIMESTAMPTZ NOT NULL,
+    last_sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_folder_digests_user_id ON folder_digests (user_id);
+CREATE INDEX IF NOT EXISTS idx_folder_digests_next_run_at ON folder_digests (next_run_at);
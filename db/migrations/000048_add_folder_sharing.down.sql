@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_folders_share_slug;
+ALTER TABLE folders DROP COLUMN IF EXISTS share_slug;
@@ -0,0 +1,5 @@
+-- A folder with a share_slug has a public read-only page at /api/v1/shared/:slug listing
+-- the recent articles of its feeds and those of the folders below it. NULL keeps the
+-- folder private.
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS share_slug VARCHAR(100);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_folders_share_slug ON folders (share_slug);
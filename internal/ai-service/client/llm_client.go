@@ -10,6 +10,12 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
+	"github.com/Fancu1/phoenix-rss/pkg/tracing"
 )
 
 // LLMClient provide interface to Large Language Model APIs
@@ -18,8 +24,15 @@ type LLMClient struct {
 	apiKey     string
 	model      string
 	timeout    time.Duration
+	maxTokens  int
+	prompt     SummaryPrompt
+	retry      RetryPolicy
 	httpClient *http.Client
-	logger     *slog.Logger
+	// userHTTPClient serves the base URLs users configure, and refuses private networks
+	userHTTPClient *http.Client
+	// throttle is shared by the copies of the client
+	throttle *throttle
+	logger   *slog.Logger
 }
 
 // LLMRequest represent the request payload for LLM API
@@ -27,6 +40,19 @@ type LLMRequest struct {
 	Model          string         `json:"model"`
 	Messages       []Message      `json:"messages"`
 	ResponseFormat ResponseFormat `json:"response_format"`
+	MaxTokens      int            `json:"max_tokens,omitempty"`
+}
+
+// APIError is returned when the LLM API answers with a non-200 status, after retries
+type APIError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is the wait the API asked for in its Retry-After header, 0 without one
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("LLM API request failed with status %d: %s", e.StatusCode, e.Body)
 }
 
 // Message represent a single message in the conversation
@@ -48,8 +74,9 @@ type LLMResponse struct {
 
 // Choice represent a single response choice
 type Choice struct {
-	Index   int     `json:"index"`
-	Message Message `json:"message"`
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
 }
 
 // Usage represent token usage information
@@ -62,6 +89,20 @@ type Usage struct {
 // ProcessingResult contains the result of article processing
 type ProcessingResult struct {
 	Summary string
+	// Topics are the article's topics the model listed after the summary, if any
+	Topics []string
+	Usage  Usage
+	// Truncated is set when the model stopped at the token limit rather than finishing
+	Truncated bool
+}
+
+// Credentials override the instance LLM settings, e.g. for a user's own API key.
+// Empty BaseURL or Model fall back to the client's configured values. A BaseURL is only
+// reached on a public address.
+type Credentials struct {
+	BaseURL string
+	APIKey  string
+	Model   string
 }
 
 // LLMClientInterface define the interface for LLM clients
@@ -70,6 +111,9 @@ type LLMClientInterface interface {
 	GetModel() string
 }
 
+// maxLLMResponseBytes caps completion responses, far above any sane summary
+const maxLLMResponseBytes = 4 << 20
+
 // NewLLMClient create a new LLM client instance
 func NewLLMClient(baseURL, apiKey, model string, timeout time.Duration, logger *slog.Logger) *LLMClient {
 	return &LLMClient{
@@ -77,18 +121,162 @@ func NewLLMClient(baseURL, apiKey, model string, timeout time.Duration, logger *
 		apiKey:  apiKey,
 		model:   model,
 		timeout: timeout,
-		httpClient: &http.Client{
-			Timeout: timeout,
-		},
-		logger: logger,
+		prompt:  DefaultSummaryPrompt,
+		retry:   RetryPolicy{}.normalized(),
+		httpClient: httpclient.New(httpclient.Options{
+			Name:         "llm",
+			Timeout:      timeout,
+			MaxBodyBytes: maxLLMResponseBytes,
+			Metrics:      httpclient.LogMetrics{Logger: logger},
+		}),
+		userHTTPClient: httpclient.New(httpclient.Options{
+			Name:         "llm_user",
+			Timeout:      timeout,
+			MaxBodyBytes: maxLLMResponseBytes,
+			Metrics:      httpclient.LogMetrics{Logger: logger},
+			Guard:        httpclient.BlockPrivateNetworks,
+		}),
+		throttle: newThrottle(0),
+		logger:   logger,
+	}
+}
+
+// SetRetry retries the requests that failed with a rate limit, a server error or in
+// transit as the policy allows
+func (c *LLMClient) SetRetry(policy RetryPolicy) {
+	c.retry = policy.normalized()
+}
+
+// SetMaxConcurrency caps the requests in flight to the LLM API across the client and its
+// copies; 0 is no cap. Set it before making copies.
+func (c *LLMClient) SetMaxConcurrency(n int) {
+	c.throttle = newThrottle(n)
+}
+
+// WithCredentials returns a copy of the client that authenticates with the given credentials.
+// The HTTP clients are shared, so the copy is cheap to create per request.
+func (c *LLMClient) WithCredentials(creds Credentials) *LLMClient {
+	scoped := *c
+	if creds.BaseURL != "" {
+		scoped.baseURL = strings.TrimRight(creds.BaseURL, "/")
+		if c.userHTTPClient != nil {
+			scoped.httpClient = c.userHTTPClient
+		}
+	}
+	if creds.APIKey != "" {
+		scoped.apiKey = creds.APIKey
+	}
+	if creds.Model != "" {
+		scoped.model = creds.Model
 	}
+	return &scoped
+}
+
+// WithMaxTokens returns a copy of the client that caps completions at n tokens (0 means no cap)
+func (c *LLMClient) WithMaxTokens(n int) LLMClientInterface {
+	scoped := *c
+	scoped.maxTokens = n
+	return &scoped
+}
+
+// WithPrompt returns a copy of the client that summarizes with the given prompt variant
+func (c *LLMClient) WithPrompt(prompt SummaryPrompt) LLMClientInterface {
+	scoped := *c
+	scoped.prompt = prompt
+	return &scoped
 }
 
 // ProcessArticle process article content using LLM and returns summary and tags
-func (c *LLMClient) ProcessArticle(ctx context.Context, title, content string) (*ProcessingResult, error) {
+func (c *LLMClient) ProcessArticle(ctx context.Context, title, content string) (result *ProcessingResult, err error) {
+	ctx, span := tracing.Start(ctx, "llm.chat_completion",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("gen_ai.request.model", c.model),
+			attribute.Int("gen_ai.request.max_tokens", c.maxTokens),
+			attribute.String("phoenix.prompt", c.prompt.Name),
+		))
+	defer func() {
+		if result != nil {
+			span.SetAttributes(
+				attribute.Int("gen_ai.usage.input_tokens", result.Usage.PromptTokens),
+				attribute.Int("gen_ai.usage.output_tokens", result.Usage.CompletionTokens),
+				attribute.Bool("phoenix.summary_truncated", result.Truncated),
+			)
+		}
+		tracing.End(span, err)
+	}()
+
 	// create prompt for article processing
 	prompt := c.createArticleProcessingPrompt(title, content)
 
+	llmResp, err := c.chat(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	responseText := llmResp.Choices[0].Message.Content
+
+	c.logger.Debug("received response from LLM API", "response_length", len(responseText))
+
+	// parse the response to extract summary and tags
+	result, err = c.parseProcessingResult(responseText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse LLM response: %w", err)
+	}
+	result.Usage = llmResp.Usage
+	result.Truncated = isTruncated(llmResp.Choices[0].FinishReason)
+	if result.Truncated {
+		c.logger.Warn("LLM response was truncated at the token limit", "model", c.model, "max_tokens", c.maxTokens)
+	}
+
+	return result, nil
+}
+
+// Completion is the model's answer to a free-form prompt
+type Completion struct {
+	Text  string
+	Usage Usage
+	// Truncated is set when the model stopped at the token limit rather than finishing
+	Truncated bool
+}
+
+// Complete sends prompt as the only user message and returns the model's answer as is.
+// A maxTokens above 0 caps the answer instead of the client's limit.
+func (c *LLMClient) Complete(ctx context.Context, prompt string, maxTokens int) (completion *Completion, err error) {
+	if maxTokens > 0 {
+		scoped := *c
+		scoped.maxTokens = maxTokens
+		c = &scoped
+	}
+	ctx, span := tracing.Start(ctx, "llm.chat_completion",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("gen_ai.request.model", c.model),
+			attribute.Int("gen_ai.request.max_tokens", c.maxTokens),
+		))
+	defer func() {
+		if completion != nil {
+			span.SetAttributes(
+				attribute.Int("gen_ai.usage.input_tokens", completion.Usage.PromptTokens),
+				attribute.Int("gen_ai.usage.output_tokens", completion.Usage.CompletionTokens),
+			)
+		}
+		tracing.End(span, err)
+	}()
+
+	llmResp, err := c.chat(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return &Completion{
+		Text:      llmResp.Choices[0].Message.Content,
+		Usage:     llmResp.Usage,
+		Truncated: isTruncated(llmResp.Choices[0].FinishReason),
+	}, nil
+}
+
+// chat sends prompt to the chat completions API. The response has a first choice with
+// content.
+func (c *LLMClient) chat(ctx context.Context, prompt string) (*LLMResponse, error) {
 	req := LLMRequest{
 		Model: c.model,
 		Messages: []Message{
@@ -100,6 +288,7 @@ func (c *LLMClient) ProcessArticle(ctx context.Context, title, content string) (
 		ResponseFormat: ResponseFormat{
 			Type: "text",
 		},
+		MaxTokens: c.maxTokens,
 	}
 
 	reqBody, err := json.Marshal(req)
@@ -107,8 +296,49 @@ func (c *LLMClient) ProcessArticle(ctx context.Context, title, content string) (
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	backoff := c.retry.BackoffInitial
+	for attempt := 1; ; attempt++ {
+		llmResp, err := c.send(ctx, reqBody)
+		if err == nil || attempt >= c.retry.MaxAttempts || !IsRetryable(err) || ctx.Err() != nil {
+			return llmResp, err
+		}
+
+		wait := backoff
+		if retryAfter := RetryAfter(err); retryAfter > 0 {
+			if retryAfter > c.retry.BackoffMax {
+				// too long to hold the article up for, the caller decides when to try again
+				return nil, err
+			}
+			wait = retryAfter
+		}
+		c.logger.Warn("LLM API request failed, retrying",
+			"model", c.model,
+			"attempt", attempt,
+			"max_attempts", c.retry.MaxAttempts,
+			"wait", wait,
+			"error", err,
+		)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		}
+		backoff = min(backoff*2, c.retry.BackoffMax)
+	}
+}
+
+// send makes one request to the chat completions API, once the throttle lets it through
+func (c *LLMClient) send(ctx context.Context, reqBody []byte) (*LLMResponse, error) {
+	release, err := c.throttle.acquire(ctx, c.apiKey, c.retry.BackoffMax)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	// TODO: request url should be configurable
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/chat/completions", bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/chat/completions", bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
@@ -116,7 +346,7 @@ func (c *LLMClient) ProcessArticle(ctx context.Context, title, content string) (
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
 
-	c.logger.Debug("sending request to LLM API", "url", httpReq.URL.String(), "model", c.model)
+	c.logger.Debug("sending request to LLM API", "url", httpReq.URL.String(), "model", c.model, "prompt", c.prompt.Name, "max_tokens", c.maxTokens)
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
@@ -130,8 +360,16 @@ func (c *LLMClient) ProcessArticle(ctx context.Context, title, content string) (
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		c.logger.Error("LLM API request failed", "status", resp.StatusCode, "body", string(body))
-		return nil, fmt.Errorf("LLM API request failed with status %d: %s", resp.StatusCode, string(body))
+		apiErr := &APIError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && apiErr.RetryAfter > 0 {
+			c.throttle.coolDown(c.apiKey, apiErr.RetryAfter)
+		}
+		c.logger.Error("LLM API request failed", "status", resp.StatusCode, "retry_after", apiErr.RetryAfter, "body", string(body))
+		return nil, apiErr
 	}
 
 	var llmResp LLMResponse
@@ -142,40 +380,33 @@ func (c *LLMClient) ProcessArticle(ctx context.Context, title, content string) (
 	if len(llmResp.Choices) == 0 {
 		return nil, fmt.Errorf("no choices in LLM response")
 	}
-
-	responseText := llmResp.Choices[0].Message.Content
-	if responseText == "" {
+	if llmResp.Choices[0].Message.Content == "" {
 		return nil, fmt.Errorf("empty response from LLM")
 	}
+	return &llmResp, nil
+}
 
-	c.logger.Debug("received response from LLM API", "response_length", len(responseText))
-
-	// parse the response to extract summary and tags
-	result, err := c.parseProcessingResult(responseText)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse LLM response: %w", err)
+// isTruncated reports whether the finish reason means the output hit the token limit.
+// OpenAI-compatible APIs use "length"; Anthropic-style gateways pass through "max_tokens".
+func isTruncated(finishReason string) bool {
+	switch finishReason {
+	case "length", "max_tokens":
+		return true
+	default:
+		return false
 	}
-
-	return result, nil
 }
 
 // createArticleProcessingPrompt create a prompt for article processing
 func (c *LLMClient) createArticleProcessingPrompt(title, content string) string {
-	prompt := fmt.Sprintf(`Please provide a concise summary of the following article in 2-3 sentences. Focus on the main topics, key insights, and most important information. Use simple chinese to respond.
-
-Article Title: %s
-
-Article Content: %s
-
-Please respond with only the summary text, no additional formatting or JSON structure needed.`, title, content)
-
-	return prompt
+	return c.prompt.Render(title, content)
 }
 
-// parseProcessingResult parse the LLM response to extract summary
+// parseProcessingResult parse the LLM response to extract summary and topics
 func (c *LLMClient) parseProcessingResult(responseText string) (*ProcessingResult, error) {
 	// clean up the response text
-	summary := strings.TrimSpace(responseText)
+	summary, topics := splitTopics(responseText)
+	summary = strings.TrimSpace(summary)
 
 	// ensure the summary is not empty
 	if summary == "" {
@@ -197,6 +428,7 @@ func (c *LLMClient) parseProcessingResult(responseText string) (*ProcessingResul
 
 	return &ProcessingResult{
 		Summary: summary,
+		Topics:  topics,
 	}, nil
 }
 
@@ -3,12 +3,20 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
 )
 
 func TestLLMClient_ProcessArticle(t *testing.T) {
@@ -144,6 +152,199 @@ func TestLLMClient_ProcessArticle(t *testing.T) {
 	}
 }
 
+func TestLLMClient_WithCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer user-key" {
+			t.Errorf("Expected user API key, got %s", auth)
+		}
+
+		var req LLMRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		if req.Model != "user-model" {
+			t.Errorf("Expected model: user-model, got %s", req.Model)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"choices": [{"message": {"content": "Summary"}}], "usage": {"prompt_tokens": 12, "completion_tokens": 3, "total_tokens": 15}}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	base := NewLLMClient("http://instance.invalid", "instance-key", "instance-model", time.Second*5, logger)
+
+	creds := Credentials{BaseURL: server.URL + "/", APIKey: "user-key", Model: "user-model"}
+	_, err := base.WithCredentials(creds).ProcessArticle(context.Background(), "Title", "Content")
+	if !errors.Is(err, httpclient.ErrBlockedAddress) {
+		t.Fatalf("Expected a user base URL on loopback to be refused, got %v", err)
+	}
+
+	// the test server only listens on loopback
+	base.userHTTPClient = server.Client()
+	scoped := base.WithCredentials(creds)
+
+	result, err := scoped.ProcessArticle(context.Background(), "Title", "Content")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Usage.TotalTokens != 15 || result.Usage.PromptTokens != 12 {
+		t.Errorf("Expected usage to be reported, got %+v", result.Usage)
+	}
+	if scoped.GetModel() != "user-model" {
+		t.Errorf("Expected scoped model user-model, got %s", scoped.GetModel())
+	}
+	if base.GetModel() != "instance-model" || base.apiKey != "instance-key" {
+		t.Errorf("Base client must not be modified by WithCredentials")
+	}
+}
+
+func TestLLMClient_Complete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req LLMRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		if req.MaxTokens != 900 {
+			t.Errorf("Expected max tokens 900, got %d", req.MaxTokens)
+		}
+		if len(req.Messages) != 1 || req.Messages[0].Content != "Group these headlines" {
+			t.Errorf("Expected the prompt as the only message, got %+v", req.Messages)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"choices": [{"message": {"content": "{\"topics\": []}"}, "finish_reason": "length"}], "usage": {"prompt_tokens": 40, "completion_tokens": 900, "total_tokens": 940}}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	client := NewLLMClient(server.URL, "test-key", "test-model", time.Second*5, logger)
+
+	completion, err := client.Complete(context.Background(), "Group these headlines", 900)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if completion.Text != `{"topics": []}` {
+		t.Errorf("Expected the answer as is, got %q", completion.Text)
+	}
+	if !completion.Truncated || completion.Usage.TotalTokens != 940 {
+		t.Errorf("Expected truncation and usage to be reported, got %+v", completion)
+	}
+	if client.maxTokens != 0 {
+		t.Errorf("Complete must not change the client's token limit")
+	}
+}
+
+func TestLLMClient_Retry(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	var calls atomic.Int32
+	statuses := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[min(int(calls.Add(1))-1, len(statuses)-1)]
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"choices": [{"message": {"content": "Summary"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewLLMClient(server.URL, "test-key", "test-model", time.Second*5, logger)
+	client.SetRetry(RetryPolicy{MaxAttempts: 3, BackoffInitial: time.Millisecond, BackoffMax: 10 * time.Millisecond})
+	result, err := client.ProcessArticle(context.Background(), "Title", "Content")
+	if err != nil || result.Summary != "Summary" || calls.Load() != 3 {
+		t.Fatalf("expected success on the third attempt, got %v after %d calls", err, calls.Load())
+	}
+
+	// attempts run out on a failure that persists
+	calls.Store(0)
+	statuses = []int{http.StatusBadGateway}
+	_, err = client.ProcessArticle(context.Background(), "Title", "Content")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway || calls.Load() != 3 {
+		t.Fatalf("expected the last 502 after 3 attempts, got %v after %d calls", err, calls.Load())
+	}
+
+	// other client errors are not retried
+	calls.Store(0)
+	statuses = []int{http.StatusUnauthorized}
+	if _, err = client.ProcessArticle(context.Background(), "Title", "Content"); err == nil || calls.Load() != 1 {
+		t.Fatalf("expected a 401 not to be retried, got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestLLMClient_RetryAfter(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewLLMClient(server.URL, "test-key", "test-model", time.Second*5, logger)
+	client.SetRetry(RetryPolicy{MaxAttempts: 3, BackoffInitial: time.Millisecond, BackoffMax: time.Second})
+
+	// a Retry-After past the backoff cap is handed to the caller instead of waited out
+	_, err := client.ProcessArticle(context.Background(), "Title", "Content")
+	if !IsRetryable(err) || RetryAfter(err) != 2*time.Minute || calls.Load() != 1 {
+		t.Fatalf("expected a retryable error asking for 2m after one call, got %v after %d calls", err, calls.Load())
+	}
+
+	// requests with the rate limited key are held back without being sent
+	scoped := client.WithCredentials(Credentials{})
+	_, err = scoped.ProcessArticle(context.Background(), "Title", "Content")
+	if wait := RetryAfter(err); wait <= time.Minute || calls.Load() != 1 {
+		t.Fatalf("expected the cooldown to be shared, got %v after %d calls", err, calls.Load())
+	}
+	other := client.WithCredentials(Credentials{APIKey: "user-key"})
+	if _, err = other.ProcessArticle(context.Background(), "Title", "Content"); calls.Load() != 2 {
+		t.Fatalf("expected another key to be sent, got %v after %d calls", err, calls.Load())
+	}
+
+	if got := parseRetryAfter("Wed, 21 Oct 2015 07:28:30 GMT", time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)); got != 30*time.Second {
+		t.Errorf("expected an HTTP date Retry-After of 30s, got %v", got)
+	}
+}
+
+func TestLLMClient_MaxConcurrency(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{"choices": [{"message": {"content": "Summary"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewLLMClient(server.URL, "test-key", "test-model", time.Second*5, logger)
+	client.SetMaxConcurrency(2)
+	scoped := client.WithMaxTokens(100)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := scoped.ProcessArticle(context.Background(), "Title", "Content"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if peak.Load() != 2 {
+		t.Errorf("expected at most 2 requests in flight, saw %d", peak.Load())
+	}
+}
+
 func TestLLMClient_GetModel(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 	client := NewLLMClient("http://example.com", "test-key", "test-model", time.Second, logger)
@@ -171,6 +372,104 @@ func TestLLMClient_CreateArticleProcessingPrompt(t *testing.T) {
 	}
 }
 
+func TestLLMClient_WithPrompt(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	base := NewLLMClient("http://example.com", "test-key", "test-model", time.Second, logger)
+
+	keyPoints, ok := SummaryPromptByName("key_points")
+	if !ok {
+		t.Fatal("Expected the key_points prompt to be built in")
+	}
+	scoped := base.WithPrompt(keyPoints).(*LLMClient)
+
+	if got := scoped.createArticleProcessingPrompt("Title", "Content"); got != keyPoints.Render("Title", "Content") {
+		t.Errorf("Expected the key_points prompt, got %q", got)
+	}
+	if got := base.createArticleProcessingPrompt("Title", "Content"); got != DefaultSummaryPrompt.Render("Title", "Content") {
+		t.Errorf("Expected the base client to keep the default prompt, got %q", got)
+	}
+}
+
+func TestSummaryPrompt_WithLanguage(t *testing.T) {
+	if got := DefaultSummaryPrompt.Render("Title", "Content"); !strings.Contains(got, "Use simple chinese to respond") {
+		t.Errorf("Expected the default prompt to ask for Chinese, got %q", got)
+	}
+	english := DefaultSummaryPrompt.WithLanguage("en").Render("Title", "Content")
+	if !strings.Contains(english, "Use English to respond") || !strings.Contains(english, "Article Title: Title") || !strings.Contains(english, "Article Content: Content") {
+		t.Errorf("Expected an English prompt for the article, got %q", english)
+	}
+	if got := SummaryLanguageName("pt-BR"); got != "pt-BR" {
+		t.Errorf("Expected an unknown language code to be passed on, got %q", got)
+	}
+}
+
+func TestSummaryPrompt_TemplateVariables(t *testing.T) {
+	prompt, err := NewSummaryPrompt("short", "{{.Title}} | {{.Content}} | {{.Language}} ({{.LanguageCode}}) | {{.MaxLength}}")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	prompt.MaxLength = 280
+	if got := prompt.WithLanguage("fr").Render("Title", "Content"); !strings.HasPrefix(got, "Title | Content | French (fr) | 280") {
+		t.Errorf("Expected the template variables to be filled in, got %q", got)
+	}
+
+	if got := DefaultSummaryPrompt.Render("Title", "Content"); strings.Contains(got, "characters long") {
+		t.Errorf("Expected no length limit without a max length, got %q", got)
+	}
+	limited := DefaultSummaryPrompt
+	limited.MaxLength = 300
+	if got := limited.Render("Title", "Content"); !strings.Contains(got, "at most 300 characters long") {
+		t.Errorf("Expected the max length in the default prompt, got %q", got)
+	}
+
+	for name, text := range map[string]string{
+		"unparsable":      "{{.Title",
+		"unknown field":   "{{.Body}}",
+		"ignores content": "Summarize {{.Title}} in {{.Language}}",
+	} {
+		if _, err := NewSummaryPrompt(name, text); err == nil {
+			t.Errorf("Expected the %s template to be rejected", name)
+		}
+	}
+}
+
+func TestLoadSummaryPrompts(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "tweet.tmpl"), []byte("Summarize {{.Content}} in {{.Language}}."), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "key_points.tmpl"), []byte("Key points of {{.Content}}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a template"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	prompts, err := LoadSummaryPrompts(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(prompts) != 3 {
+		t.Fatalf("Expected the built-in variants and tweet, got %d prompts", len(prompts))
+	}
+	if got := prompts["tweet"].WithLanguage("en").Render("Title", "Content"); !strings.HasPrefix(got, "Summarize Content in English.") {
+		t.Errorf("Expected the tweet template, got %q", got)
+	}
+	if got := prompts["key_points"].Render("Title", "Content"); !strings.HasPrefix(got, "Key points of Content") {
+		t.Errorf("Expected the file to replace the built-in key_points variant, got %q", got)
+	}
+	if prompts["default"].Template != DefaultSummaryPrompt.Template {
+		t.Error("Expected the built-in default variant to be kept")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.tmpl"), []byte("{{.Content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSummaryPrompts(dir); err == nil {
+		t.Error("Expected a broken template to fail loading")
+	}
+}
+
 func TestLLMClient_ParseProcessingResult(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 	client := NewLLMClient("http://example.com", "test-key", "test-model", time.Second, logger)
@@ -236,3 +535,82 @@ func TestLLMClient_ParseProcessingResult(t *testing.T) {
 		})
 	}
 }
+
+func TestLLMClient_ParseProcessingResultTopics(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	client := NewLLMClient("http://example.com", "test-key", "test-model", time.Second, logger)
+
+	result, err := client.parseProcessingResult("A summary.\nSecond line.\n\nTOPICS: Go, #Databases, go, , Postgres., a, b, c")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Summary != "A summary.\nSecond line." {
+		t.Errorf("Expected the topics line to be taken off the summary, got %q", result.Summary)
+	}
+	if want := []string{"go", "databases", "postgres", "a", "b"}; !slices.Equal(result.Topics, want) {
+		t.Errorf("Expected topics %v, got %v", want, result.Topics)
+	}
+
+	result, err = client.parseProcessingResult("摘要。\nTopics: 数据库、人工智能")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := []string{"数据库", "人工智能"}; result.Summary != "摘要。" || !slices.Equal(result.Topics, want) {
+		t.Errorf("Expected the summary and topics %v, got %q and %v", want, result.Summary, result.Topics)
+	}
+
+	result, err = client.parseProcessingResult("Topics are covered here.")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Summary != "Topics are covered here." || result.Topics != nil {
+		t.Errorf("Expected a summary without topics, got %q and %v", result.Summary, result.Topics)
+	}
+
+	if !strings.Contains(DefaultSummaryPrompt.Render("Title", "Content"), `starting with "Topics:"`) {
+		t.Errorf("Expected the prompt to ask for topics")
+	}
+}
+
+func TestLLMClient_TruncatedSummary(t *testing.T) {
+	finishReason := "length"
+	var sentMaxTokens int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req LLMRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		sentMaxTokens = req.MaxTokens
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"choices": [{"message": {"content": "The article explains"}, "finish_reason": "` + finishReason + `"}]}`))
+	}))
+	defer server.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	base := NewLLMClient(server.URL, "test-key", "test-model", time.Second*5, logger)
+	limited := base.WithMaxTokens(256)
+
+	result, err := limited.ProcessArticle(context.Background(), "Title", "Content")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sentMaxTokens != 256 {
+		t.Errorf("Expected max_tokens 256 to be sent, got %d", sentMaxTokens)
+	}
+	if !result.Truncated {
+		t.Error("Expected finish_reason length to mark the summary as truncated")
+	}
+
+	finishReason = "stop"
+	result, err = base.ProcessArticle(context.Background(), "Title", "Content")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sentMaxTokens != 0 {
+		t.Errorf("Expected no max_tokens from the base client, got %d", sentMaxTokens)
+	}
+	if result.Truncated {
+		t.Error("Expected finish_reason stop not to mark the summary as truncated")
+	}
+}
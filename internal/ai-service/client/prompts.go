@@ -0,0 +1,227 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// DefaultSummaryLanguage is the language summaries are written in when none is asked for
+const DefaultSummaryLanguage = "zh"
+
+// summaryLanguageNames are how prompts name the languages of summaries; other codes are
+// passed to the model as they are
+var summaryLanguageNames = map[string]string{
+	"zh": "simple chinese",
+	"en": "English",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"fr": "French",
+	"de": "German",
+	"es": "Spanish",
+}
+
+// SummaryLanguageName names the language with the given code in a prompt
+func SummaryLanguageName(code string) string {
+	if name, ok := summaryLanguageNames[strings.ToLower(code)]; ok {
+		return name
+	}
+	return code
+}
+
+// SummaryPrompt is a variant of the summarization prompt. Summaries record the name of
+// the variant they were made with, so readers' ratings can be compared across variants.
+type SummaryPrompt struct {
+	Name string
+	// Template is a text/template executed with a PromptData
+	Template string
+	// Language is the code of the language to summarize in; empty is DefaultSummaryLanguage
+	Language string
+	// MaxLength is the length in characters the summary is asked to stay within; 0 asks
+	// for none
+	MaxLength int
+}
+
+// PromptData are the variables of a prompt template
+type PromptData struct {
+	Title   string
+	Content string
+	// Language names the language to respond in, e.g. "English"; LanguageCode is its code
+	Language     string
+	LanguageCode string
+	MaxLength    int
+}
+
+// WithLanguage returns a copy of the prompt that asks for a summary in the language
+func (p SummaryPrompt) WithLanguage(language string) SummaryPrompt {
+	p.Language = language
+	return p
+}
+
+// Render fills the template with an article and asks for its topics after the summary.
+// A template that fails to execute is replaced by DefaultSummaryPrompt; NewSummaryPrompt
+// catches such templates before they are used.
+func (p SummaryPrompt) Render(title, content string) string {
+	language := p.Language
+	if language == "" {
+		language = DefaultSummaryLanguage
+	}
+	data := PromptData{
+		Title:        title,
+		Content:      content,
+		Language:     SummaryLanguageName(language),
+		LanguageCode: language,
+		MaxLength:    p.MaxLength,
+	}
+	prompt, err := p.execute(data)
+	if err != nil {
+		prompt, _ = DefaultSummaryPrompt.execute(data)
+	}
+	return prompt + fmt.Sprintf(topicsInstruction, MaxTopics, data.Language)
+}
+
+func (p SummaryPrompt) execute(data PromptData) (string, error) {
+	tmpl, err := template.New(p.Name).Option("missingkey=error").Parse(p.Template)
+	if err != nil {
+		return "", err
+	}
+	var prompt strings.Builder
+	if err := tmpl.Execute(&prompt, data); err != nil {
+		return "", err
+	}
+	return prompt.String(), nil
+}
+
+// NewSummaryPrompt makes a variant from a template, checking that it renders and uses the
+// article's content
+func NewSummaryPrompt(name, text string) (SummaryPrompt, error) {
+	prompt := SummaryPrompt{Name: name, Template: text}
+	rendered, err := prompt.execute(PromptData{Title: "{title}", Content: "{content}", Language: "English", LanguageCode: "en", MaxLength: 1})
+	if err != nil {
+		return SummaryPrompt{}, fmt.Errorf("invalid summary prompt %q: %w", name, err)
+	}
+	if !strings.Contains(rendered, "{content}") {
+		return SummaryPrompt{}, fmt.Errorf("summary prompt %q does not use .Content", name)
+	}
+	return prompt, nil
+}
+
+// MaxTopics caps the topics kept of one summary
+const MaxTopics = 5
+
+// maxTopicLength caps the characters of one topic; longer ones are dropped
+const maxTopicLength = 50
+
+// topicsPrefix starts the line of the response that lists the article's topics
+const topicsPrefix = "topics:"
+
+// topicsInstruction is appended to every prompt; it takes the number of topics and the
+// name of the language to write them in
+const topicsInstruction = `
+
+After that, add one last line starting with "Topics:" that lists at most %d short topics of the article in %s, separated by commas.`
+
+// splitTopics takes the topics line off the end of a response. Topics are lowercased
+// and deduplicated, and at most MaxTopics are kept.
+func splitTopics(response string) (string, []string) {
+	lines := strings.Split(strings.TrimSpace(response), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	if len(last) < len(topicsPrefix) || !strings.EqualFold(last[:len(topicsPrefix)], topicsPrefix) {
+		return response, nil
+	}
+
+	var topics []string
+	for _, topic := range strings.FieldsFunc(last[len(topicsPrefix):], func(r rune) bool { return r == ',' || r == '，' || r == '、' }) {
+		topic = strings.ToLower(strings.Trim(strings.TrimSpace(topic), "#.。*"))
+		if topic == "" || utf8.RuneCountInString(topic) > maxTopicLength || slices.Contains(topics, topic) {
+			continue
+		}
+		topics = append(topics, topic)
+		if len(topics) == MaxTopics {
+			break
+		}
+	}
+	return strings.Join(lines[:len(lines)-1], "\n"), topics
+}
+
+// DefaultSummaryPrompt is the prompt used when no variant is chosen
+var DefaultSummaryPrompt = SummaryPrompt{
+	Name: "default",
+	Template: `Please provide a concise summary of the following article in 2-3 sentences{{if .MaxLength}}, at most {{.MaxLength}} characters long{{end}}. Focus on the main topics, key insights, and most important information. Use {{.Language}} to respond.
+
+Article Title: {{.Title}}
+
+Article Content: {{.Content}}
+
+Please respond with only the summary text, no additional formatting or JSON structure needed.`,
+}
+
+// summaryPrompts are the built-in variants operators can enable side by side
+var summaryPrompts = map[string]SummaryPrompt{
+	DefaultSummaryPrompt.Name: DefaultSummaryPrompt,
+	"key_points": {
+		Name: "key_points",
+		Template: `Summarize the key points of the following article as 3 short bullet points in {{.Language}}{{if .MaxLength}}, at most {{.MaxLength}} characters in all{{end}}. Only state what the article says; do not add facts, opinions or conclusions of your own.
+
+Article Title: {{.Title}}
+
+Article Content: {{.Content}}
+
+Please respond with only the bullet points, no introduction or closing remarks.`,
+	},
+}
+
+// SummaryPromptByName returns the built-in variant with the given name
+func SummaryPromptByName(name string) (SummaryPrompt, bool) {
+	prompt, ok := summaryPrompts[name]
+	return prompt, ok
+}
+
+// SummaryPromptNames lists the built-in variants in alphabetical order
+func SummaryPromptNames() []string {
+	names := make([]string, 0, len(summaryPrompts))
+	for name := range summaryPrompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// promptFileExt is the extension of the prompt template files LoadSummaryPrompts reads
+const promptFileExt = ".tmpl"
+
+// LoadSummaryPrompts returns the built-in variants along with one variant per template
+// file in dir, named after the file without its .tmpl extension. A file named after a
+// built-in variant replaces it. An empty dir only returns the built-in variants.
+func LoadSummaryPrompts(dir string) (map[string]SummaryPrompt, error) {
+	prompts := make(map[string]SummaryPrompt, len(summaryPrompts))
+	for name, prompt := range summaryPrompts {
+		prompts[name] = prompt
+	}
+	if dir == "" {
+		return prompts, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+promptFileExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		text, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read summary prompt: %w", err)
+		}
+		name := strings.TrimSuffix(filepath.Base(file), promptFileExt)
+		prompt, err := NewSummaryPrompt(name, string(text))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		prompts[name] = prompt
+	}
+	return prompts, nil
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
+)
+
+// RetryPolicy retries requests the LLM API answered with 429 or 5xx, or that failed in
+// transit, backing off exponentially. A Retry-After sent with the answer replaces the
+// backoff; one longer than BackoffMax is not waited out, the error carries it instead.
+type RetryPolicy struct {
+	MaxAttempts    int // 0 or 1 disables retries
+	BackoffInitial time.Duration
+	BackoffMax     time.Duration
+}
+
+func (p RetryPolicy) normalized() RetryPolicy {
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = 1
+	}
+	if p.BackoffInitial <= 0 {
+		p.BackoffInitial = time.Second
+	}
+	if p.BackoffMax < p.BackoffInitial {
+		p.BackoffMax = 30 * time.Second
+	}
+	return p
+}
+
+// IsRetryable reports whether a request that failed with err may succeed when sent
+// again later: the API was rate limiting or failing, or the request did not get through
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return httpclient.IsRetryableStatus(apiErr.StatusCode)
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, httpclient.ErrBodyTooLarge)
+	}
+	return false
+}
+
+// RetryAfter is how long the API asked to wait before sending err's request again, 0
+// when it did not say
+func RetryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// parseRetryAfter reads a Retry-After header, in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
+
+// throttle caps the requests in flight to the LLM API and holds back the requests made
+// with an API key the API rate limited until the wait it asked for is over. Copies of a
+// client share it, so the cap covers every request of the process.
+type throttle struct {
+	slots chan struct{} // nil for no cap
+
+	mu       sync.Mutex
+	cooldown map[string]time.Time // by API key
+}
+
+func newThrottle(maxConcurrency int) *throttle {
+	t := &throttle{cooldown: make(map[string]time.Time)}
+	if maxConcurrency > 0 {
+		t.slots = make(chan struct{}, maxConcurrency)
+	}
+	return t
+}
+
+// acquire waits out the cooldown of apiKey, then for a free slot. A cooldown longer than
+// maxWait is not waited out but failed with as a 429. The returned func frees the slot.
+func (t *throttle) acquire(ctx context.Context, apiKey string, maxWait time.Duration) (func(), error) {
+	t.mu.Lock()
+	until := t.cooldown[apiKey]
+	t.mu.Unlock()
+	if wait := time.Until(until); wait > maxWait {
+		return nil, &APIError{StatusCode: http.StatusTooManyRequests, Body: "rate limited, not sent", RetryAfter: wait}
+	} else if wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+
+	if t.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case t.slots <- struct{}{}:
+		return func() { <-t.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// coolDown holds back the requests made with apiKey for d
+func (t *throttle) coolDown(apiKey string, d time.Duration) {
+	until := time.Now().Add(d)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until.After(t.cooldown[apiKey]) {
+		t.cooldown[apiKey] = until
+	}
+	// forget the keys whose wait is over, so the map only holds keys rate limited now
+	for key, keyUntil := range t.cooldown {
+		if time.Until(keyUntil) <= 0 {
+			delete(t.cooldown, key)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/client"
+	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
+)
+
+// Error classes reported with a failed ArticleProcessedEvent, so the UI can say why a
+// summary is unavailable without exposing provider error messages
+const (
+	ErrorClassInvalidInput = "invalid_input"
+	ErrorClassRateLimited  = "rate_limited"
+	ErrorClassUnauthorized = "unauthorized"
+	ErrorClassTimeout      = "timeout"
+	ErrorClassLLM          = "llm_error"
+)
+
+// ErrInvalidArticle is returned for events that can never be processed
+var ErrInvalidArticle = errors.New("invalid article")
+
+// ClassifyError maps a processing error to one of the error classes
+func ClassifyError(err error) string {
+	if errors.Is(err, ErrInvalidArticle) {
+		return ErrorClassInvalidInput
+	}
+
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests:
+			return ErrorClassRateLimited
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ErrorClassUnauthorized
+		case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+			return ErrorClassTimeout
+		}
+		return ErrorClassLLM
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrorClassTimeout
+	}
+	return ErrorClassLLM
+}
+
+// FailedEvent returns the event that tells the feed service processing of an article gave up
+func FailedEvent(articleID uint64, err error) *article_eventspb.ArticleProcessedEvent {
+	return &article_eventspb.ArticleProcessedEvent{
+		ArticleId:  articleID,
+		Failed:     true,
+		ErrorClass: ClassifyError(err),
+	}
+}
+
+// Retryable reports whether processing that failed with err may succeed later, as when
+// the LLM API was rate limiting or unavailable
+func Retryable(err error) bool {
+	return !errors.Is(err, ErrInvalidArticle) && client.IsRetryable(err)
+}
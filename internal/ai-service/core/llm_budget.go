@@ -0,0 +1,106 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/client"
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+)
+
+// ModelPrice is what a model costs in USD per million prompt and completion tokens
+type ModelPrice struct {
+	PromptPerMillion     float64
+	CompletionPerMillion float64
+}
+
+// Cost estimates the cost of one request
+func (p ModelPrice) Cost(usage client.Usage) float64 {
+	return (float64(usage.PromptTokens)*p.PromptPerMillion + float64(usage.CompletionTokens)*p.CompletionPerMillion) / 1e6
+}
+
+// UsePricing records the estimated cost of each request with its usage, from the price
+// of its model. Requests to models without a price are recorded at no cost.
+func (s *ProcessingService) UsePricing(prices map[string]ModelPrice) {
+	s.prices = prices
+}
+
+// UsageReader reads back the usage the ledger recorded, such as
+// repository.CredentialRepository
+type UsageReader interface {
+	InstanceUsageOn(ctx context.Context, t time.Time) (costUSD float64, tokens int64, err error)
+}
+
+// DefaultBudgetRecheck is how often a paused processor checks whether the budget allows
+// it to go on, so the pause ends soon after the usage of other replicas is corrected or
+// the day rolls over
+const DefaultBudgetRecheck = time.Minute
+
+// Budget pauses processing once the instance key used up the day's budget, until the
+// next UTC day. Usage is read from the daily aggregate, so every replica sees the same
+// spend. A request in flight when the budget runs out still completes, so the spend can
+// go over by up to a batch. When the usage cannot be read processing goes on. A nil
+// *Budget never pauses.
+type Budget struct {
+	limits  models.LLMBudget
+	usage   UsageReader
+	recheck time.Duration
+	logger  *slog.Logger
+	now     func() time.Time
+}
+
+// NewBudget returns nil, a budget that never pauses, when limits is not enabled
+func NewBudget(limits models.LLMBudget, usage UsageReader, logger *slog.Logger) *Budget {
+	if !limits.Enabled() {
+		return nil
+	}
+	return &Budget{
+		limits:  limits,
+		usage:   usage,
+		recheck: DefaultBudgetRecheck,
+		logger:  logger,
+		now:     func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Exceeded reports whether the instance key is over today's budget
+func (b *Budget) Exceeded(ctx context.Context) bool {
+	if b == nil {
+		return false
+	}
+	cost, tokens, err := b.usage.InstanceUsageOn(ctx, b.now())
+	if err != nil {
+		b.logger.Warn("failed to read LLM usage, not enforcing the budget", "error", err)
+		return false
+	}
+	return b.limits.Exceeded(cost, tokens)
+}
+
+// Wait returns right away while the budget allows processing, and otherwise once it
+// does again. It only fails once ctx is done.
+func (b *Budget) Wait(ctx context.Context) error {
+	if !b.Exceeded(ctx) {
+		return ctx.Err()
+	}
+
+	tomorrow := models.UsageDay(b.now()).AddDate(0, 0, 1)
+	b.logger.Warn("daily LLM budget exceeded, pausing processing",
+		"daily_cost_usd", b.limits.DailyCostUSD,
+		"daily_tokens", b.limits.DailyTokens,
+		"resumes_by", tomorrow,
+	)
+	for {
+		timer := time.NewTimer(min(b.recheck, max(tomorrow.Sub(b.now()), 0)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if !b.Exceeded(ctx) {
+			b.logger.Info("LLM budget allows processing again, resuming")
+			return nil
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/client"
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
+)
+
+// scriptedUsage returns its readings in turn, repeating the last
+type scriptedUsage struct {
+	mu       sync.Mutex
+	readings []float64
+	err      error
+	reads    int
+}
+
+func (s *scriptedUsage) InstanceUsageOn(ctx context.Context, t time.Time) (float64, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cost := s.readings[min(s.reads, len(s.readings)-1)]
+	s.reads++
+	return cost, 0, s.err
+}
+
+func TestModelPrice_Cost(t *testing.T) {
+	price := ModelPrice{PromptPerMillion: 0.15, CompletionPerMillion: 0.60}
+	cost := price.Cost(client.Usage{PromptTokens: 2000, CompletionTokens: 500, TotalTokens: 2500})
+	if math.Abs(cost-0.0006) > 1e-12 {
+		t.Errorf("expected a cost of $0.0006, got %v", cost)
+	}
+}
+
+func TestProcessingService_RecordsCost(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	event := &article_eventspb.ArticlePersistedEvent{ArticleId: 7, FeedId: 3, Title: "Title", Content: "Content"}
+	result := &client.ProcessingResult{Summary: "Summary", Usage: client.Usage{PromptTokens: 1_000_000, CompletionTokens: 100_000, TotalTokens: 1_100_000}}
+
+	store := &fakeUsageLedger{}
+	service := NewProcessingService(&MockLLMClient{result: result, model: "priced-model"}, logger)
+	service.UseUsageLedger(store)
+	service.UsePricing(map[string]ModelPrice{"priced-model": {PromptPerMillion: 1, CompletionPerMillion: 4}})
+
+	if _, err := service.ProcessArticle(context.Background(), event); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(store.usage) != 1 || math.Abs(store.usage[0].CostUSD-1.4) > 1e-9 {
+		t.Fatalf("expected one usage record costing $1.40, got %+v", store.usage)
+	}
+
+	service.UsePricing(nil)
+	if _, err := service.ProcessArticle(context.Background(), event); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if store.usage[1].CostUSD != 0 || store.usage[1].TotalTokens != 1_100_000 {
+		t.Errorf("expected a model without a price to be recorded at no cost, got %+v", store.usage[1])
+	}
+}
+
+func TestBudget_Wait(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	limits := models.LLMBudget{DailyCostUSD: 5}
+
+	if NewBudget(models.LLMBudget{}, &scriptedUsage{}, logger) != nil {
+		t.Fatal("expected no budget without limits")
+	}
+	var none *Budget
+	if err := none.Wait(context.Background()); err != nil {
+		t.Fatalf("a nil budget must not pause, got %v", err)
+	}
+
+	// under the budget processing goes on right away
+	usage := &scriptedUsage{readings: []float64{4.99}}
+	budget := NewBudget(limits, usage, logger)
+	if err := budget.Wait(context.Background()); err != nil || usage.reads != 1 {
+		t.Fatalf("expected no pause under the budget, got %v after %d reads", err, usage.reads)
+	}
+
+	// over it, processing waits until the usage allows it again
+	usage = &scriptedUsage{readings: []float64{5, 5.2, 0}}
+	budget = NewBudget(limits, usage, logger)
+	budget.recheck = time.Millisecond
+	if err := budget.Wait(context.Background()); err != nil || usage.reads != 3 {
+		t.Fatalf("expected to resume once the usage dropped, got %v after %d reads", err, usage.reads)
+	}
+
+	// a paused processor still stops on shutdown
+	usage = &scriptedUsage{readings: []float64{10}}
+	budget = NewBudget(limits, usage, logger)
+	budget.recheck = time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := budget.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to end with the context, got %v", err)
+	}
+
+	// the pause ends with the day even before the next recheck
+	usage = &scriptedUsage{readings: []float64{10, 0}}
+	budget = NewBudget(limits, usage, logger)
+	budget.recheck = time.Hour
+	budget.now = func() time.Time {
+		return time.Now().UTC().Truncate(24 * time.Hour).Add(24*time.Hour - time.Millisecond)
+	}
+	if err := budget.Wait(context.Background()); err != nil || usage.reads != 2 {
+		t.Fatalf("expected to recheck at midnight, got %v after %d reads", err, usage.reads)
+	}
+
+	// usage that cannot be read does not stop processing
+	usage = &scriptedUsage{readings: []float64{10}, err: errors.New("db down")}
+	if budget := NewBudget(limits, usage, logger); budget.Exceeded(context.Background()) {
+		t.Error("expected a failed usage read not to enforce the budget")
+	}
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ProcessedEventStore records the ArticlePersistedEvents that were processed, such as
+// repository.ProcessedEventRepository
+type ProcessedEventStore interface {
+	Claim(ctx context.Context, articleID uint64, requestID string, now, staleBefore time.Time) (bool, error)
+	Complete(ctx context.Context, articleID uint64, requestID string, at time.Time) error
+	Release(ctx context.Context, articleID uint64, requestID string) error
+	DeleteClaimedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// DefaultProcessedEventClaimTimeout is how long an event claimed by a consumer that
+// stopped while processing it stays claimed
+const DefaultProcessedEventClaimTimeout = 10 * time.Minute
+
+// ProcessedEvents keeps the ai-service from summarizing an article twice for one
+// ArticlePersistedEvent, as when a consumer group rebalance delivers it again. Events
+// are told apart by article and request ID, so a requeue for a regeneration, which comes
+// with a new request, is processed. Events without a request ID are always processed.
+// When the store fails the event is processed anyway: a second summary costs tokens, a
+// lost one leaves the article without. A nil *ProcessedEvents processes every event.
+type ProcessedEvents struct {
+	store        ProcessedEventStore
+	claimTimeout time.Duration
+	logger       *slog.Logger
+	now          func() time.Time
+}
+
+func NewProcessedEvents(store ProcessedEventStore, claimTimeout time.Duration, logger *slog.Logger) *ProcessedEvents {
+	if claimTimeout <= 0 {
+		claimTimeout = DefaultProcessedEventClaimTimeout
+	}
+	return &ProcessedEvents{
+		store:        store,
+		claimTimeout: claimTimeout,
+		logger:       logger,
+		now:          func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Claim reports whether the event should be processed. It is not when it was processed
+// already, or is being processed by another consumer.
+func (p *ProcessedEvents) Claim(ctx context.Context, articleID uint64, requestID string) bool {
+	if p == nil || requestID == "" {
+		return true
+	}
+	now := p.now()
+	claimed, err := p.store.Claim(ctx, articleID, requestID, now, now.Add(-p.claimTimeout))
+	if err != nil {
+		p.logger.Warn("failed to claim article persisted event, processing it anyway", "article_id", articleID, "error", err)
+		return true
+	}
+	return claimed
+}
+
+// Complete records that the claimed event was processed and its result published
+func (p *ProcessedEvents) Complete(ctx context.Context, articleID uint64, requestID string) {
+	if p == nil || requestID == "" {
+		return
+	}
+	if err := p.store.Complete(ctx, articleID, requestID, p.now()); err != nil {
+		p.logger.Warn("failed to record processed article persisted event", "article_id", articleID, "error", err)
+	}
+}
+
+// Release gives up the claim of an event that could not be processed, so it is processed
+// when it is delivered again
+func (p *ProcessedEvents) Release(ctx context.Context, articleID uint64, requestID string) {
+	if p == nil || requestID == "" {
+		return
+	}
+	if err := p.store.Release(ctx, articleID, requestID); err != nil {
+		p.logger.Warn("failed to release article persisted event", "article_id", articleID, "error", err)
+	}
+}
+
+// Purge forgets the events claimed before the time and returns how many it forgot
+func (p *ProcessedEvents) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if p == nil {
+		return 0, nil
+	}
+	return p.store.DeleteClaimedBefore(ctx, before)
+}
+
+// RunPurge forgets the events claimed longer ago than the retention once an hour, until
+// the context is cancelled
+func (p *ProcessedEvents) RunPurge(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if deleted, err := p.Purge(ctx, p.now().Add(-retention)); err != nil && ctx.Err() == nil {
+			p.logger.Error("failed to purge processed article persisted events", "error", err)
+		} else if deleted > 0 {
+			p.logger.Info("purged processed article persisted events", "deleted", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
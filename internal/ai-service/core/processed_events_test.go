@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/repository"
+)
+
+func newTestProcessedEvents(t *testing.T) (*ProcessedEvents, *time.Time) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.ProcessedEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	processed := NewProcessedEvents(repository.NewProcessedEventRepository(db), time.Minute, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	processed.now = func() time.Time { return now }
+	return processed, &now
+}
+
+func TestProcessedEvents_SkipsEventsDeliveredAgain(t *testing.T) {
+	processed, now := newTestProcessedEvents(t)
+	ctx := context.Background()
+
+	if !processed.Claim(ctx, 1, "req-1") {
+		t.Fatal("expected the first delivery to be claimed")
+	}
+	if processed.Claim(ctx, 1, "req-1") {
+		t.Error("expected a delivery while the event is processed to be skipped")
+	}
+	processed.Complete(ctx, 1, "req-1")
+
+	*now = now.Add(time.Hour)
+	if processed.Claim(ctx, 1, "req-1") {
+		t.Error("expected a processed event to be skipped")
+	}
+	if !processed.Claim(ctx, 1, "req-2") {
+		t.Error("expected a requeue of the article with a new request to be processed")
+	}
+	if !processed.Claim(ctx, 1, "") || !processed.Claim(ctx, 1, "") {
+		t.Error("expected events without a request ID to be processed every time")
+	}
+
+	// a released claim, or one left by a consumer that stopped, is claimed again
+	processed.Release(ctx, 1, "req-2")
+	if !processed.Claim(ctx, 1, "req-2") {
+		t.Error("expected a released event to be claimed again")
+	}
+	*now = now.Add(2 * time.Minute)
+	if !processed.Claim(ctx, 1, "req-2") {
+		t.Error("expected a stale claim to be claimed again")
+	}
+
+	deleted, err := processed.Purge(ctx, now.Add(-30*time.Minute))
+	if err != nil || deleted != 1 {
+		t.Fatalf("expected the processed event to be purged, got %d and %v", deleted, err)
+	}
+	if !processed.Claim(ctx, 1, "req-1") {
+		t.Error("expected a purged event to be processed")
+	}
+}
+
+type failingProcessedEventStore struct {
+	ProcessedEventStore
+}
+
+func (failingProcessedEventStore) Claim(ctx context.Context, articleID uint64, requestID string, now, staleBefore time.Time) (bool, error) {
+	return false, errors.New("database unavailable")
+}
+
+func TestProcessedEvents_ProcessesEventsWhenTheStoreFails(t *testing.T) {
+	processed := NewProcessedEvents(failingProcessedEventStore{}, 0, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	if !processed.Claim(context.Background(), 1, "req-1") {
+		t.Error("expected the event to be processed when the store fails")
+	}
+
+	var disabled *ProcessedEvents
+	if !disabled.Claim(context.Background(), 1, "req-1") {
+		t.Error("expected a nil ProcessedEvents to process every event")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"time"
 
 	"github.com/Fancu1/phoenix-rss/internal/ai-service/client"
@@ -13,7 +14,21 @@ import (
 // ProcessingService handle article processing using AI
 type ProcessingService struct {
 	llmClient client.LLMClientInterface
-	logger    *slog.Logger
+	usage     UsageLedger
+	// summaryCache, when set, lets articles with the same content share a summary
+	summaryCache SummaryCache
+	// prompts, when set, chooses the prompt variant of each summary
+	prompts *PromptSelector
+	// expandedMaxTokens is used for events that ask to regenerate a truncated summary
+	expandedMaxTokens int
+	// languages are the languages every article is summarized in, the default first
+	languages []string
+	// maxReaderLanguages caps the languages of an event's subscribers an article is also
+	// summarized in
+	maxReaderLanguages int
+	// prices estimate the cost of each request recorded in the usage ledger
+	prices map[string]ModelPrice
+	logger *slog.Logger
 }
 
 // NewProcessingService create a new processing service instance
@@ -24,26 +39,126 @@ func NewProcessingService(llmClient client.LLMClientInterface, logger *slog.Logg
 	}
 }
 
-// ProcessArticle process an article and returns the processed event
+// UseExpandedTokenLimit sets the token limit used when regenerating a truncated summary
+func (s *ProcessingService) UseExpandedTokenLimit(maxTokens int) {
+	s.expandedMaxTokens = maxTokens
+}
+
+// UsePromptSelector summarizes with the prompt variants of the selector instead of the
+// client's default prompt
+func (s *ProcessingService) UsePromptSelector(selector *PromptSelector) {
+	s.prompts = selector
+}
+
+// UseSummaryLanguages summarizes every article in each of the languages. The first is
+// the default language, the one ProcessArticle summarizes in.
+func (s *ProcessingService) UseSummaryLanguages(languages []string) {
+	s.languages = languages
+}
+
+// UseReaderLanguages also summarizes each article in up to limit of the languages its
+// feed's subscribers prefer, as listed on the event, most wanted first
+func (s *ProcessingService) UseReaderLanguages(limit int) {
+	s.maxReaderLanguages = limit
+}
+
+// extraLanguages are the languages besides the default one an article is summarized in:
+// the configured ones, then the readers' ones up to the cap
+func (s *ProcessingService) extraLanguages(event *article_eventspb.ArticlePersistedEvent) []string {
+	languages := slices.Clone(s.languages[min(1, len(s.languages)):])
+	readers := 0
+	for _, language := range event.Languages {
+		if readers == s.maxReaderLanguages {
+			break
+		}
+		if language == "" || language == s.defaultLanguage() || slices.Contains(languages, language) {
+			continue
+		}
+		languages = append(languages, language)
+		readers++
+	}
+	return languages
+}
+
+// defaultLanguage is the language of the summary every article must get
+func (s *ProcessingService) defaultLanguage() string {
+	if len(s.languages) == 0 {
+		return client.DefaultSummaryLanguage
+	}
+	return s.languages[0]
+}
+
+// ProcessArticleLanguages summarizes an article in each configured language, and in the
+// languages its readers prefer, and returns one processed event per language, the default
+// first. Only a failure of the default
+// language fails the article; the other languages are skipped with a warning, so a reader
+// asking for them sees the default summary instead.
+func (s *ProcessingService) ProcessArticleLanguages(ctx context.Context, event *article_eventspb.ArticlePersistedEvent) ([]*article_eventspb.ArticleProcessedEvent, error) {
+	processed, err := s.ProcessArticle(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []*article_eventspb.ArticleProcessedEvent{processed}
+	for _, language := range s.extraLanguages(event) {
+		translated, err := s.processLanguage(ctx, event, language)
+		if err != nil {
+			if ctx.Err() != nil {
+				return results, ctx.Err()
+			}
+			s.logger.Warn("failed to summarize article in an extra language",
+				"article_id", event.ArticleId,
+				"language", language,
+				"error", err,
+			)
+			continue
+		}
+		results = append(results, translated)
+	}
+	return results, nil
+}
+
+// ProcessArticle summarizes an article in the default language and returns the processed
+// event
 func (s *ProcessingService) ProcessArticle(ctx context.Context, event *article_eventspb.ArticlePersistedEvent) (*article_eventspb.ArticleProcessedEvent, error) {
+	return s.processLanguage(ctx, event, s.defaultLanguage())
+}
+
+// processLanguage summarizes an article in one language
+func (s *ProcessingService) processLanguage(ctx context.Context, event *article_eventspb.ArticlePersistedEvent, language string) (*article_eventspb.ArticleProcessedEvent, error) {
 	s.logger.Info("processing article",
 		"article_id", event.ArticleId,
 		"feed_id", event.FeedId,
 		"title", event.Title,
+		"language", language,
+		"expand", event.Expand,
 	)
 
 	startTime := time.Now()
 
 	if event.ArticleId == 0 {
-		return nil, fmt.Errorf("invalid article ID: %d", event.ArticleId)
+		return nil, fmt.Errorf("%w: article ID %d", ErrInvalidArticle, event.ArticleId)
 	}
 
 	if event.Title == "" && event.Content == "" {
-		return nil, fmt.Errorf("both title and content are empty for article %d", event.ArticleId)
+		return nil, fmt.Errorf("%w: both title and content are empty for article %d", ErrInvalidArticle, event.ArticleId)
+	}
+
+	hash := summaryCacheKey(contentHash(event.Title, event.Content), language)
+	if cached := s.cachedResult(ctx, event, hash, language); cached != nil {
+		return cached, nil
 	}
 
 	// Process article content with LLM
-	result, err := s.llmClient.ProcessArticle(ctx, event.Title, event.Content)
+	llmClient := s.llmClient
+	if event.Expand {
+		llmClient = s.withExpandedLimit(llmClient)
+	}
+	llmClient, promptName, err := s.withPrompt(ctx, llmClient, language)
+	if err != nil {
+		return nil, err
+	}
+	result, err := llmClient.ProcessArticle(ctx, event.Title, event.Content)
 	if err != nil {
 		s.logger.Error("failed to process article with LLM",
 			"article_id", event.ArticleId,
@@ -56,20 +171,67 @@ func (s *ProcessingService) ProcessArticle(ctx context.Context, event *article_e
 
 	// Create processed event
 	processedEvent := &article_eventspb.ArticleProcessedEvent{
-		ArticleId:       event.ArticleId,
-		Summary:         result.Summary,
-		ProcessingModel: s.llmClient.GetModel(),
+		ArticleId:        event.ArticleId,
+		Summary:          result.Summary,
+		ProcessingModel:  llmClient.GetModel(),
+		ProcessingPrompt: promptName,
+		SummaryTruncated: result.Truncated,
+		Language:         language,
+		Topics:           result.Topics,
 	}
 
+	s.recordUsage(ctx, event.ArticleId, llmClient.GetModel(), result.Usage)
+	s.cacheResult(ctx, hash, processedEvent)
+
 	s.logger.Info("article processing completed",
 		"article_id", event.ArticleId,
 		"summary_length", len(result.Summary),
+		"summary_truncated", result.Truncated,
+		"topics", len(result.Topics),
+		"prompt", promptName,
+		"language", language,
 		"processing_duration", duration,
+		"total_tokens", result.Usage.TotalTokens,
+		"cost_usd", s.prices[llmClient.GetModel()].Cost(result.Usage),
 	)
 
 	return processedEvent, nil
 }
 
+// withExpandedLimit raises the completion token limit for a regeneration request
+func (s *ProcessingService) withExpandedLimit(llmClient client.LLMClientInterface) client.LLMClientInterface {
+	if s.expandedMaxTokens <= 0 {
+		return llmClient
+	}
+	limiter, ok := llmClient.(tokenLimiter)
+	if !ok {
+		s.logger.Warn("LLM client does not support token limits, regenerating with the default limit")
+		return llmClient
+	}
+	return limiter.WithMaxTokens(s.expandedMaxTokens)
+}
+
+// withPrompt applies the prompt variant chosen for the client's model in the language and
+// returns its name. Without a selector the client keeps its default prompt. A client that
+// cannot change its prompt only summarizes in the default language.
+func (s *ProcessingService) withPrompt(ctx context.Context, llmClient client.LLMClientInterface, language string) (client.LLMClientInterface, string, error) {
+	scoper, ok := llmClient.(promptScoper)
+	if !ok {
+		if language != client.DefaultSummaryLanguage {
+			return nil, "", fmt.Errorf("LLM client does not support prompt variants, cannot summarize in %q", language)
+		}
+		if s.prompts != nil {
+			s.logger.Warn("LLM client does not support prompt variants, summarizing with the default prompt")
+		}
+		return llmClient, client.DefaultSummaryPrompt.Name, nil
+	}
+	prompt := client.DefaultSummaryPrompt
+	if s.prompts != nil {
+		prompt = s.prompts.Select(ctx, llmClient.GetModel())
+	}
+	return scoper.WithPrompt(prompt.WithLanguage(language)), prompt.Name, nil
+}
+
 // ProcessBatch processes multiple articles in batch
 func (s *ProcessingService) ProcessBatch(ctx context.Context, articles []*article_eventspb.ArticlePersistedEvent) ([]*article_eventspb.ArticleProcessedEvent, error) {
 	if len(articles) == 0 {
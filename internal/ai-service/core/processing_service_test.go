@@ -3,12 +3,15 @@ package core
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/Fancu1/phoenix-rss/internal/ai-service/client"
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/models"
 	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
 )
 
@@ -250,3 +253,256 @@ func TestProcessingService_ProcessBatch(t *testing.T) {
 		}
 	})
 }
+
+type fakeUsageLedger struct {
+	usage []*models.LLMUsage
+}
+
+func (f *fakeUsageLedger) RecordUsage(ctx context.Context, usage *models.LLMUsage) error {
+	f.usage = append(f.usage, usage)
+	return nil
+}
+
+func TestProcessingService_RecordsUsage(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	event := &article_eventspb.ArticlePersistedEvent{ArticleId: 7, FeedId: 3, Title: "Title", Content: "Content"}
+	result := &client.ProcessingResult{Summary: "Summary", Usage: client.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}}
+
+	ledger := &fakeUsageLedger{}
+	service := NewProcessingService(&MockLLMClient{result: result, model: "instance-model"}, logger)
+	service.UseUsageLedger(ledger)
+
+	processed, err := service.ProcessArticle(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if processed.ProcessingModel != "instance-model" {
+		t.Errorf("Expected the shared summary to be written with the instance model, got %s", processed.ProcessingModel)
+	}
+	if len(ledger.usage) != 1 {
+		t.Fatalf("Expected 1 usage record, got %d", len(ledger.usage))
+	}
+	usage := ledger.usage[0]
+	if usage.UserID != nil || usage.BYOK || usage.TotalTokens != 15 || *usage.ArticleID != 7 {
+		t.Errorf("Unexpected usage record: %+v", usage)
+	}
+}
+
+// limitingLLMClient reports as truncated unless it was given the expanded limit
+type limitingLLMClient struct {
+	MockLLMClient
+	maxTokens int
+}
+
+func (m *limitingLLMClient) ProcessArticle(ctx context.Context, title, content string) (*client.ProcessingResult, error) {
+	return &client.ProcessingResult{Summary: "Summary", Truncated: m.maxTokens < 1000}, nil
+}
+
+func (m *limitingLLMClient) WithMaxTokens(n int) client.LLMClientInterface {
+	return &limitingLLMClient{MockLLMClient: m.MockLLMClient, maxTokens: n}
+}
+
+func TestProcessingService_ExpandedRegeneration(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	service := NewProcessingService(&limitingLLMClient{MockLLMClient: MockLLMClient{model: "test-model"}, maxTokens: 512}, logger)
+	service.UseExpandedTokenLimit(2048)
+
+	event := &article_eventspb.ArticlePersistedEvent{ArticleId: 7, FeedId: 3, Title: "Title", Content: "Content"}
+	processed, err := service.ProcessArticle(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !processed.SummaryTruncated {
+		t.Error("Expected summary at the default limit to be flagged as truncated")
+	}
+
+	event.Expand = true
+	processed, err = service.ProcessArticle(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if processed.SummaryTruncated {
+		t.Error("Expected expanded regeneration to use the longer limit")
+	}
+}
+
+// countingLLMClient counts the articles it summarizes
+type countingLLMClient struct {
+	MockLLMClient
+	calls int
+}
+
+func (m *countingLLMClient) ProcessArticle(ctx context.Context, title, content string) (*client.ProcessingResult, error) {
+	m.calls++
+	return &client.ProcessingResult{Summary: fmt.Sprintf("Summary %d", m.calls), Topics: []string{"go", "releases"}}, nil
+}
+
+type memorySummaryCache map[string]*models.CachedSummary
+
+func (c memorySummaryCache) Get(ctx context.Context, contentHash string) (*models.CachedSummary, error) {
+	return c[contentHash], nil
+}
+
+func (c memorySummaryCache) Put(ctx context.Context, summary *models.CachedSummary) error {
+	c[summary.ContentHash] = summary
+	return nil
+}
+
+func TestProcessingService_SummaryCache(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	llm := &countingLLMClient{MockLLMClient: MockLLMClient{model: "test-model"}}
+	service := NewProcessingService(llm, logger)
+	cache := memorySummaryCache{}
+	service.UseSummaryCache(cache)
+	ctx := context.Background()
+
+	first, err := service.ProcessArticle(ctx, &article_eventspb.ArticlePersistedEvent{
+		ArticleId: 1, FeedId: 1, Title: "Go 1.30 released", Content: "<p>The Go team is happy to announce Go 1.30.</p>",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// the same article through another feed, with other markup and spacing
+	second, err := service.ProcessArticle(ctx, &article_eventspb.ArticlePersistedEvent{
+		ArticleId: 2, FeedId: 9, Title: "Go 1.30 Released", Content: "<div>The Go team is  happy\nto announce <b>Go 1.30</b>.</div>",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if llm.calls != 1 {
+		t.Errorf("LLM calls = %d, want the copy served from the cache", llm.calls)
+	}
+	if second.ArticleId != 2 || second.Summary != first.Summary || second.ProcessingModel != "test-model" {
+		t.Errorf("cached result = %+v, want the first summary for article 2", second)
+	}
+	if !slices.Equal(second.Topics, []string{"go", "releases"}) || !slices.Equal(first.Topics, second.Topics) {
+		t.Errorf("cached topics = %v, want the topics of the first summary", second.Topics)
+	}
+
+	if _, err := service.ProcessArticle(ctx, &article_eventspb.ArticlePersistedEvent{
+		ArticleId: 3, FeedId: 1, Title: "Go 1.30 released", Content: "<p>Something else entirely.</p>",
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if llm.calls != 2 {
+		t.Errorf("LLM calls = %d, want different content summarized", llm.calls)
+	}
+
+	// a regeneration bypasses the cache and replaces its entry
+	regenerated, err := service.ProcessArticle(ctx, &article_eventspb.ArticlePersistedEvent{
+		ArticleId: 2, FeedId: 9, Title: "Go 1.30 Released", Content: "<p>The Go team is happy to announce Go 1.30.</p>", Expand: true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if llm.calls != 3 || cache[contentHash("Go 1.30 released", "The Go team is happy to announce Go 1.30.")].Summary != regenerated.Summary {
+		t.Errorf("regeneration did not refresh the cache: calls = %d", llm.calls)
+	}
+}
+
+// languageLLMClient summarizes in the language of the prompt it was scoped to
+type languageLLMClient struct {
+	MockLLMClient
+	language string
+	failIn   string
+}
+
+func (m *languageLLMClient) ProcessArticle(ctx context.Context, title, content string) (*client.ProcessingResult, error) {
+	if m.language == m.failIn {
+		return nil, errors.New("mock LLM error")
+	}
+	return &client.ProcessingResult{Summary: "Summary in " + m.language}, nil
+}
+
+func (m *languageLLMClient) WithPrompt(prompt client.SummaryPrompt) client.LLMClientInterface {
+	return &languageLLMClient{MockLLMClient: m.MockLLMClient, language: prompt.Language, failIn: m.failIn}
+}
+
+func TestProcessingService_ProcessArticleLanguages(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	llm := &languageLLMClient{MockLLMClient: MockLLMClient{model: "m"}, failIn: "fr"}
+	service := NewProcessingService(llm, logger)
+	service.UseSummaryLanguages([]string{"en", "fr", "zh"})
+	event := &article_eventspb.ArticlePersistedEvent{ArticleId: 1, FeedId: 1, Title: "Title", Content: "Content"}
+
+	processed, err := service.ProcessArticleLanguages(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(processed) != 2 {
+		t.Fatalf("expected the default and the zh summaries, got %d", len(processed))
+	}
+	if processed[0].Language != "en" || processed[0].Summary != "Summary in en" {
+		t.Errorf("expected the default language first, got %q in %q", processed[0].Summary, processed[0].Language)
+	}
+	if processed[1].Language != "zh" || processed[1].Summary != "Summary in zh" {
+		t.Errorf("expected the zh summary after the failed fr one, got %q in %q", processed[1].Summary, processed[1].Language)
+	}
+
+	// the default language failing fails the article
+	llm.failIn = "en"
+	if _, err := service.ProcessArticleLanguages(context.Background(), event); err == nil {
+		t.Error("expected a failure of the default language to fail the article")
+	}
+}
+
+func TestProcessingService_ReaderLanguages(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	llm := &languageLLMClient{MockLLMClient: MockLLMClient{model: "m"}}
+	service := NewProcessingService(llm, logger)
+	service.UseSummaryLanguages([]string{"en", "zh"})
+	event := &article_eventspb.ArticlePersistedEvent{ArticleId: 1, FeedId: 1, Title: "Title", Content: "Content", Languages: []string{"de", "en", "zh", "ja", "fr"}}
+
+	languages := func() []string {
+		processed, err := service.ProcessArticleLanguages(context.Background(), event)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var languages []string
+		for _, result := range processed {
+			languages = append(languages, result.Language)
+		}
+		return languages
+	}
+
+	if got := languages(); !slices.Equal(got, []string{"en", "zh"}) {
+		t.Errorf("Expected only the configured languages without reader languages, got %v", got)
+	}
+	service.UseReaderLanguages(2)
+	if got := languages(); !slices.Equal(got, []string{"en", "zh", "de", "ja"}) {
+		t.Errorf("Expected the two most wanted reader languages after the configured ones, got %v", got)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	service := NewProcessingService(&MockLLMClient{model: "test-model"}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	_, invalidErr := service.ProcessArticle(context.Background(), &article_eventspb.ArticlePersistedEvent{ArticleId: 1})
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"empty article", invalidErr, ErrorClassInvalidInput},
+		{"rate limited", fmt.Errorf("LLM processing failed: %w", &client.APIError{StatusCode: 429}), ErrorClassRateLimited},
+		{"bad key", &client.APIError{StatusCode: 401}, ErrorClassUnauthorized},
+		{"gateway timeout", &client.APIError{StatusCode: 504}, ErrorClassTimeout},
+		{"server error", &client.APIError{StatusCode: 500}, ErrorClassLLM},
+		{"deadline", fmt.Errorf("failed to send request: %w", context.DeadlineExceeded), ErrorClassTimeout},
+		{"unparsable response", errors.New("no choices in LLM response"), ErrorClassLLM},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.want {
+				t.Errorf("ClassifyError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	event := FailedEvent(7, invalidErr)
+	if !event.Failed || event.ArticleId != 7 || event.ErrorClass != ErrorClassInvalidInput {
+		t.Errorf("unexpected failed event: %+v", event)
+	}
+}
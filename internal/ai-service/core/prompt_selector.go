@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/client"
+	"github.com/Fancu1/phoenix-rss/internal/summaryquality"
+)
+
+// Ratings older than promptStatsWindow do not count towards a variant's score, so a
+// change of model behaviour shows within a month; the stats are reloaded every
+// promptStatsRefresh
+const (
+	promptStatsWindow  = 30 * 24 * time.Hour
+	promptStatsRefresh = 10 * time.Minute
+)
+
+// QualitySource returns readers' ratings of the summary variants since a time
+type QualitySource interface {
+	Stats(ctx context.Context, since time.Time) ([]summaryquality.VariantStats, error)
+}
+
+// promptScoper is implemented by LLM clients whose summarization prompt can be changed
+type promptScoper interface {
+	WithPrompt(prompt client.SummaryPrompt) client.LLMClientInterface
+}
+
+// PromptSelectorConfig tunes how a PromptSelector trades trying variants against using
+// the best one
+type PromptSelectorConfig struct {
+	// Exploration is the share of summaries made with a random variant, so that every
+	// variant keeps collecting ratings
+	Exploration float64
+	// MinRatings is how many ratings a variant needs before its score counts
+	MinRatings int
+	// Evaluator scores the variants; nil uses summaryquality.DefaultEvaluator
+	Evaluator summaryquality.Evaluator
+}
+
+// PromptSelector chooses the prompt variant of each summary from readers' ratings. Most
+// summaries use the variant that scores best for the model; the first variant is used
+// until another one has enough ratings to beat it.
+type PromptSelector struct {
+	prompts []client.SummaryPrompt
+	source  QualitySource
+	config  PromptSelectorConfig
+	logger  *slog.Logger
+
+	mu       sync.Mutex
+	stats    map[string]summaryquality.VariantStats // by model and prompt, see variantKey
+	loadedAt time.Time
+
+	now    func() time.Time
+	random func() float64
+	pick   func(n int) int
+}
+
+func NewPromptSelector(prompts []client.SummaryPrompt, source QualitySource, config PromptSelectorConfig, logger *slog.Logger) *PromptSelector {
+	if config.Evaluator == nil {
+		config.Evaluator = summaryquality.DefaultEvaluator
+	}
+	return &PromptSelector{
+		prompts: prompts,
+		source:  source,
+		config:  config,
+		logger:  logger,
+		now:     time.Now,
+		random:  rand.Float64,
+		pick:    rand.IntN,
+	}
+}
+
+// Select returns the prompt variant for the next summary made with model
+func (p *PromptSelector) Select(ctx context.Context, model string) client.SummaryPrompt {
+	if len(p.prompts) == 1 {
+		return p.prompts[0]
+	}
+	if p.random() < p.config.Exploration {
+		return p.prompts[p.pick(len(p.prompts))]
+	}
+
+	stats := p.currentStats(ctx)
+	best, bestScore := p.prompts[0], 0.0
+	found := false
+	for _, prompt := range p.prompts {
+		variant, ok := stats[variantKey(model, prompt.Name)]
+		if !ok || variant.Ratings < int64(p.config.MinRatings) {
+			continue
+		}
+		if score := p.config.Evaluator.Score(variant); !found || score > bestScore {
+			best, bestScore, found = prompt, score, true
+		}
+	}
+	return best
+}
+
+// currentStats returns the ratings, reloading them when they are stale. A failed reload
+// keeps the previous ratings and is retried after the next refresh interval.
+func (p *PromptSelector) currentStats(ctx context.Context) map[string]summaryquality.VariantStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	if !p.loadedAt.IsZero() && now.Sub(p.loadedAt) < promptStatsRefresh {
+		return p.stats
+	}
+	p.loadedAt = now
+
+	stats, err := p.source.Stats(ctx, now.Add(-promptStatsWindow))
+	if err != nil {
+		p.logger.Warn("failed to load summary ratings, keeping the previous ones", "error", err)
+		return p.stats
+	}
+	p.stats = make(map[string]summaryquality.VariantStats, len(stats))
+	for _, variant := range stats {
+		p.stats[variantKey(variant.Model, variant.Prompt)] = variant
+	}
+	return p.stats
+}
+
+func variantKey(model, prompt string) string {
+	return model + "\x00" + prompt
+}
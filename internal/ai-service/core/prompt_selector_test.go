@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/client"
+	"github.com/Fancu1/phoenix-rss/internal/summaryquality"
+	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
+)
+
+type staticQualitySource struct {
+	stats []summaryquality.VariantStats
+	err   error
+	loads int
+}
+
+func (s *staticQualitySource) Stats(ctx context.Context, since time.Time) ([]summaryquality.VariantStats, error) {
+	s.loads++
+	return s.stats, s.err
+}
+
+var (
+	conciseTestPrompt = client.SummaryPrompt{Name: "concise", Template: "concise %s %s %s"}
+	bulletsTestPrompt = client.SummaryPrompt{Name: "bullets", Template: "bullets %s %s %s"}
+)
+
+func TestPromptSelector_Select(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	source := &staticQualitySource{stats: []summaryquality.VariantStats{
+		{Model: "m", Prompt: "concise", Ratings: 50, Useful: 30},
+		{Model: "m", Prompt: "bullets", Ratings: 50, Useful: 45},
+		{Model: "other", Prompt: "bullets", Ratings: 50, Useful: 5},
+		{Model: "other", Prompt: "concise", Ratings: 3, Useful: 3},
+	}}
+	selector := NewPromptSelector([]client.SummaryPrompt{conciseTestPrompt, bulletsTestPrompt}, source, PromptSelectorConfig{Exploration: 0.1, MinRatings: 20}, logger)
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	selector.now = func() time.Time { return now }
+	roll := 0.5
+	selector.random = func() float64 { return roll }
+	selector.pick = func(n int) int { return n - 1 }
+	ctx := context.Background()
+
+	if got := selector.Select(ctx, "m"); got.Name != "bullets" {
+		t.Errorf("expected the better rated variant, got %s", got.Name)
+	}
+	if got := selector.Select(ctx, "other"); got.Name != "bullets" {
+		t.Errorf("expected the only variant with enough ratings, got %s", got.Name)
+	}
+	if got := selector.Select(ctx, "unrated"); got.Name != "concise" {
+		t.Errorf("expected the first variant for an unrated model, got %s", got.Name)
+	}
+
+	roll = 0.05
+	selector.pick = func(n int) int { return 0 }
+	if got := selector.Select(ctx, "m"); got.Name != "concise" {
+		t.Errorf("expected exploration to pick a random variant, got %s", got.Name)
+	}
+	roll = 0.5
+
+	if source.loads != 1 {
+		t.Errorf("expected ratings to be loaded once within the refresh interval, got %d loads", source.loads)
+	}
+	source.err = errors.New("database down")
+	now = now.Add(promptStatsRefresh)
+	if got := selector.Select(ctx, "m"); got.Name != "bullets" {
+		t.Errorf("expected the previous ratings to be kept on a failed reload, got %s", got.Name)
+	}
+	if source.loads != 2 {
+		t.Errorf("expected a reload after the refresh interval, got %d loads", source.loads)
+	}
+}
+
+// promptRecordingLLMClient remembers the prompt it was scoped to
+type promptRecordingLLMClient struct {
+	MockLLMClient
+	prompt string
+}
+
+func (m *promptRecordingLLMClient) ProcessArticle(ctx context.Context, title, content string) (*client.ProcessingResult, error) {
+	return &client.ProcessingResult{Summary: "Summary by " + m.prompt}, nil
+}
+
+func (m *promptRecordingLLMClient) WithPrompt(prompt client.SummaryPrompt) client.LLMClientInterface {
+	return &promptRecordingLLMClient{MockLLMClient: m.MockLLMClient, prompt: prompt.Name}
+}
+
+func TestProcessingService_RecordsPrompt(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	service := NewProcessingService(&promptRecordingLLMClient{MockLLMClient: MockLLMClient{model: "m"}}, logger)
+	event := &article_eventspb.ArticlePersistedEvent{ArticleId: 1, FeedId: 1, Title: "Title", Content: "Content"}
+
+	processed, err := service.ProcessArticle(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if processed.ProcessingPrompt != client.DefaultSummaryPrompt.Name {
+		t.Errorf("expected the default prompt without a selector, got %q", processed.ProcessingPrompt)
+	}
+
+	source := &staticQualitySource{stats: []summaryquality.VariantStats{{Model: "m", Prompt: "bullets", Ratings: 30, Useful: 30}}}
+	selector := NewPromptSelector([]client.SummaryPrompt{conciseTestPrompt, bulletsTestPrompt}, source, PromptSelectorConfig{MinRatings: 10}, logger)
+	service.UsePromptSelector(selector)
+
+	processed, err = service.ProcessArticle(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if processed.ProcessingPrompt != "bullets" || processed.Summary != "Summary by bullets" {
+		t.Errorf("expected a summary made with the selected prompt, got %q by %q", processed.Summary, processed.ProcessingPrompt)
+	}
+}
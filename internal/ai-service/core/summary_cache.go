@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/client"
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
+)
+
+// SummaryCache stores AI results by content hash
+type SummaryCache interface {
+	// Get returns nil when nothing is cached for the hash
+	Get(ctx context.Context, contentHash string) (*models.CachedSummary, error)
+	Put(ctx context.Context, summary *models.CachedSummary) error
+}
+
+// UseSummaryCache reuses the summary of an article with the same normalized title and
+// content, e.g. one syndicated through several feeds, instead of calling the LLM again
+func (s *ProcessingService) UseSummaryCache(cache SummaryCache) {
+	s.summaryCache = cache
+}
+
+// cachedResult returns the processed event for a cached summary of the article in the
+// language, or nil. Regenerations always go to the LLM.
+func (s *ProcessingService) cachedResult(ctx context.Context, event *article_eventspb.ArticlePersistedEvent, contentHash, language string) *article_eventspb.ArticleProcessedEvent {
+	if s.summaryCache == nil || event.Expand {
+		return nil
+	}
+	cached, err := s.summaryCache.Get(ctx, contentHash)
+	if err != nil {
+		s.logger.Warn("summary cache lookup failed", "article_id", event.ArticleId, "error", err)
+	}
+	if cached == nil {
+		return nil
+	}
+
+	s.logger.Info("reusing cached summary",
+		"article_id", event.ArticleId,
+		"feed_id", event.FeedId,
+		"content_hash", contentHash,
+		"model", cached.ProcessingModel,
+		"language", language,
+	)
+	return &article_eventspb.ArticleProcessedEvent{
+		ArticleId:        event.ArticleId,
+		Summary:          cached.Summary,
+		ProcessingModel:  cached.ProcessingModel,
+		ProcessingPrompt: cached.ProcessingPrompt,
+		SummaryTruncated: cached.SummaryTruncated,
+		Language:         language,
+		Topics:           cached.TopicList(),
+	}
+}
+
+// cacheResult stores a fresh summary; failures are logged and never fail processing
+func (s *ProcessingService) cacheResult(ctx context.Context, contentHash string, processed *article_eventspb.ArticleProcessedEvent) {
+	if s.summaryCache == nil || processed.Summary == "" {
+		return
+	}
+	err := s.summaryCache.Put(ctx, &models.CachedSummary{
+		ContentHash:      contentHash,
+		Summary:          processed.Summary,
+		SummaryTruncated: processed.SummaryTruncated,
+		ProcessingModel:  processed.ProcessingModel,
+		ProcessingPrompt: processed.ProcessingPrompt,
+		Topics:           strings.Join(processed.Topics, ","),
+	})
+	if err != nil {
+		s.logger.Warn("failed to cache summary", "article_id", processed.ArticleId, "error", err)
+	}
+}
+
+// contentHash identifies an article by its text: markup, case and whitespace do not
+// change the hash, so copies of it from different feeds share a summary
+func contentHash(title, content string) string {
+	sum := sha256.New()
+	io.WriteString(sum, normalizeText(title))
+	io.WriteString(sum, "\n")
+	io.WriteString(sum, normalizeText(content))
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// summaryCacheKey keys the summary of content in a language. Summaries in the default
+// language keep the plain content hash they were cached under before other languages.
+func summaryCacheKey(contentHash, language string) string {
+	if language == client.DefaultSummaryLanguage {
+		return contentHash
+	}
+	sum := sha256.Sum256([]byte(contentHash + "\n" + language))
+	return hex.EncodeToString(sum[:])
+}
+
+// inlineTags do not separate words, unlike block elements and line breaks
+var inlineTags = map[string]bool{
+	"a": true, "abbr": true, "b": true, "cite": true, "code": true, "em": true, "i": true, "mark": true,
+	"q": true, "s": true, "small": true, "span": true, "strong": true, "sub": true, "sup": true, "u": true,
+}
+
+// normalizeText keeps the text of HTML, lowercased, with whitespace collapsed
+func normalizeText(markup string) string {
+	var text strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(markup))
+	skip := 0
+	for {
+		token := tokenizer.Next()
+		switch token {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(strings.ToLower(text.String())), " ")
+		case html.TextToken:
+			if skip == 0 {
+				text.Write(tokenizer.Text())
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			switch {
+			case tag == "script" || tag == "style":
+				if token == html.StartTagToken {
+					skip++
+				} else if token == html.EndTagToken && skip > 0 {
+					skip--
+				}
+			case !inlineTags[tag]:
+				text.WriteByte(' ')
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"context"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/client"
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+)
+
+// UsageLedger records the LLM requests made for accounting
+type UsageLedger interface {
+	RecordUsage(ctx context.Context, usage *models.LLMUsage) error
+}
+
+// tokenLimiter is implemented by LLM clients whose completion token limit can be changed
+type tokenLimiter interface {
+	WithMaxTokens(n int) client.LLMClientInterface
+}
+
+// UseUsageLedger records every LLM request in the ledger. Summaries are shared by every
+// subscriber of a feed, so they are always written with the instance key; a user's own key
+// only serves what that user alone sees.
+func (s *ProcessingService) UseUsageLedger(ledger UsageLedger) {
+	s.usage = ledger
+}
+
+// recordUsage writes a usage ledger entry; failures are logged and never fail processing.
+// Summaries are written with the instance key for every subscriber of the feed, so the
+// entry belongs to no user and is not byok; briefings record their own, for the reader.
+func (s *ProcessingService) recordUsage(ctx context.Context, articleID uint64, model string, usage client.Usage) {
+	if s.usage == nil {
+		return
+	}
+
+	article := uint(articleID)
+	entry := &models.LLMUsage{
+		ArticleID:        &article,
+		Model:            model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		CostUSD:          s.prices[model].Cost(usage),
+	}
+	if err := s.usage.RecordUsage(ctx, entry); err != nil {
+		s.logger.Warn("failed to record LLM usage", "article_id", articleID, "error", err)
+	}
+}
@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+// UserLLMCredential is the read-side view of a user's encrypted bring-your-own-key credential
+type UserLLMCredential struct {
+	UserID           uint
+	BaseURL          string
+	Model            string
+	APIKeyCiphertext string `gorm:"column:api_key_ciphertext"`
+}
+
+func (UserLLMCredential) TableName() string {
+	return "user_llm_credentials"
+}
+
+// LLMUsage is a single entry in the LLM usage ledger.
+// UserID is nil when the instance-wide key was used.
+type LLMUsage struct {
+	ID               uint64 `gorm:"primaryKey"`
+	UserID           *uint
+	ArticleID        *uint
+	Model            string
+	BYOK             bool `gorm:"column:byok"`
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	// CostUSD is estimated from the configured price of the model, 0 without one
+	CostUSD   float64 `gorm:"column:cost_usd"`
+	CreatedAt time.Time
+}
+
+func (LLMUsage) TableName() string {
+	return "llm_usage"
+}
+
+// LLMUsageDaily aggregates the usage ledger per UTC day, model and key source
+type LLMUsageDaily struct {
+	Day              time.Time `gorm:"primaryKey;type:date" json:"day"`
+	Model            string    `gorm:"primaryKey" json:"model"`
+	BYOK             bool      `gorm:"primaryKey;column:byok" json:"byok"`
+	Requests         int64     `json:"requests"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	TotalTokens      int64     `json:"total_tokens"`
+	CostUSD          float64   `gorm:"column:cost_usd" json:"cost_usd"`
+}
+
+func (LLMUsageDaily) TableName() string {
+	return "llm_usage_daily"
+}
+
+// LLMUsageTotals sums daily usage rows
+type LLMUsageTotals struct {
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// Add counts a day's row in the totals
+func (t *LLMUsageTotals) Add(day LLMUsageDaily) {
+	t.Requests += day.Requests
+	t.PromptTokens += day.PromptTokens
+	t.CompletionTokens += day.CompletionTokens
+	t.TotalTokens += day.TotalTokens
+	t.CostUSD += day.CostUSD
+}
+
+// LLMBudget caps what the instance key may spend in a UTC day. Requests made with a
+// subscriber's own key are paid by them and do not count. A zero limit is no limit.
+type LLMBudget struct {
+	DailyCostUSD float64 `json:"daily_cost_usd"`
+	DailyTokens  int64   `json:"daily_tokens"`
+}
+
+// Enabled reports whether the budget limits anything
+func (b LLMBudget) Enabled() bool {
+	return b.DailyCostUSD > 0 || b.DailyTokens > 0
+}
+
+// Exceeded reports whether the instance key usage of a day is over the budget
+func (b LLMBudget) Exceeded(costUSD float64, tokens int64) bool {
+	return (b.DailyCostUSD > 0 && costUSD >= b.DailyCostUSD) || (b.DailyTokens > 0 && tokens >= b.DailyTokens)
+}
+
+// UsageDay is the UTC day usage at t is counted on
+func UsageDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Statuses of a ProcessedEvent
+const (
+	ProcessedEventProcessing = "processing"
+	ProcessedEventDone       = "done"
+)
+
+// ProcessedEvent records that an ArticlePersistedEvent was claimed for processing, and
+// once its result is published that it was processed
+type ProcessedEvent struct {
+	ArticleID   uint64 `gorm:"primaryKey;autoIncrement:false"`
+	RequestID   string `gorm:"primaryKey;size:64"`
+	Status      string `gorm:"size:16"`
+	ClaimedAt   time.Time
+	CompletedAt *time.Time
+}
+
+func (ProcessedEvent) TableName() string {
+	return "ai_processed_events"
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// CachedSummary is an AI result keyed by the normalized content it was made from, so
+// the same article syndicated through several feeds is summarized once
+type CachedSummary struct {
+	ContentHash      string `gorm:"primaryKey;size:64"`
+	Summary          string
+	SummaryTruncated bool
+	ProcessingModel  string
+	ProcessingPrompt string
+	// Topics are the topics listed with the summary, joined by commas
+	Topics string
+	// Hits counts the articles that reused the summary instead of calling the LLM
+	Hits      int64
+	LastHitAt *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (CachedSummary) TableName() string {
+	return "ai_summary_cache"
+}
+
+// TopicList splits Topics
+func (c CachedSummary) TopicList() []string {
+	if c.Topics == "" {
+		return nil
+	}
+	return strings.Split(c.Topics, ",")
+}
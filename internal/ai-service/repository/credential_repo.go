@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+)
+
+// CredentialRepository records LLM usage
+type CredentialRepository struct {
+	db *gorm.DB
+}
+
+func NewCredentialRepository(db *gorm.DB) *CredentialRepository {
+	return &CredentialRepository{db: db}
+}
+
+// RecordUsage appends an entry to the usage ledger and adds it to the day's aggregate
+func (r *CredentialRepository) RecordUsage(ctx context.Context, usage *models.LLMUsage) error {
+	if usage.CreatedAt.IsZero() {
+		usage.CreatedAt = time.Now().UTC()
+	}
+	daily := &models.LLMUsageDaily{
+		Day:              models.UsageDay(usage.CreatedAt),
+		Model:            usage.Model,
+		BYOK:             usage.BYOK,
+		Requests:         1,
+		PromptTokens:     int64(usage.PromptTokens),
+		CompletionTokens: int64(usage.CompletionTokens),
+		TotalTokens:      int64(usage.TotalTokens),
+		CostUSD:          usage.CostUSD,
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(usage).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "day"}, {Name: "model"}, {Name: "byok"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"requests":          gorm.Expr("llm_usage_daily.requests + excluded.requests"),
+				"prompt_tokens":     gorm.Expr("llm_usage_daily.prompt_tokens + excluded.prompt_tokens"),
+				"completion_tokens": gorm.Expr("llm_usage_daily.completion_tokens + excluded.completion_tokens"),
+				"total_tokens":      gorm.Expr("llm_usage_daily.total_tokens + excluded.total_tokens"),
+				"cost_usd":          gorm.Expr("llm_usage_daily.cost_usd + excluded.cost_usd"),
+			}),
+		}).Create(daily).Error
+	})
+}
+
+// InstanceUsageOn returns the estimated cost and the tokens the instance key used on the
+// UTC day of t
+func (r *CredentialRepository) InstanceUsageOn(ctx context.Context, t time.Time) (float64, int64, error) {
+	var totals struct {
+		CostUSD     float64
+		TotalTokens int64
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.LLMUsageDaily{}).
+		Select("COALESCE(SUM(cost_usd), 0) AS cost_usd, COALESCE(SUM(total_tokens), 0) AS total_tokens").
+		Where("day = ? AND byok = ?", models.UsageDay(t), false).
+		Scan(&totals).Error
+	return totals.CostUSD, totals.TotalTokens, err
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+)
+
+// ProcessedEventRepository records the ArticlePersistedEvents the ai-service processed
+type ProcessedEventRepository struct {
+	db *gorm.DB
+}
+
+func NewProcessedEventRepository(db *gorm.DB) *ProcessedEventRepository {
+	return &ProcessedEventRepository{db: db}
+}
+
+// Claim records that the event of the article and request is being processed, and
+// reports whether it was claimed. An event processed already is not claimed again, nor
+// one claimed since staleBefore; an older claim was left by a consumer that stopped.
+func (r *ProcessedEventRepository) Claim(ctx context.Context, articleID uint64, requestID string, now, staleBefore time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "article_id"}, {Name: "request_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"claimed_at": now}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "ai_processed_events.status = ? AND ai_processed_events.claimed_at < ?", Vars: []interface{}{models.ProcessedEventProcessing, staleBefore}},
+		}},
+	}).Create(&models.ProcessedEvent{
+		ArticleID: articleID,
+		RequestID: requestID,
+		Status:    models.ProcessedEventProcessing,
+		ClaimedAt: now,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Complete records that the event was processed
+func (r *ProcessedEventRepository) Complete(ctx context.Context, articleID uint64, requestID string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.ProcessedEvent{}).
+		Where("article_id = ? AND request_id = ?", articleID, requestID).
+		UpdateColumns(map[string]interface{}{
+			"status":       models.ProcessedEventDone,
+			"completed_at": at,
+		}).Error
+}
+
+// Release drops the claim of an event that was not processed, so a delivery of it
+// again is processed
+func (r *ProcessedEventRepository) Release(ctx context.Context, articleID uint64, requestID string) error {
+	return r.db.WithContext(ctx).
+		Where("article_id = ? AND request_id = ? AND status = ?", articleID, requestID, models.ProcessedEventProcessing).
+		Delete(&models.ProcessedEvent{}).Error
+}
+
+// DeleteClaimedBefore deletes the records of events claimed before the time and returns
+// how many it deleted
+func (r *ProcessedEventRepository) DeleteClaimedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("claimed_at < ?", before).Delete(&models.ProcessedEvent{})
+	return result.RowsAffected, result.Error
+}
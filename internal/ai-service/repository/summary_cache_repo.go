@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+)
+
+// SummaryCacheRepository stores AI summaries by content hash
+type SummaryCacheRepository struct {
+	db *gorm.DB
+}
+
+func NewSummaryCacheRepository(db *gorm.DB) *SummaryCacheRepository {
+	return &SummaryCacheRepository{db: db}
+}
+
+// Get returns the cached summary of the content hash and counts the hit, or nil when
+// there is none. A failure to count the hit is returned along with the summary.
+func (r *SummaryCacheRepository) Get(ctx context.Context, contentHash string) (*models.CachedSummary, error) {
+	var cached models.CachedSummary
+	err := r.db.WithContext(ctx).Where("content_hash = ?", contentHash).Take(&cached).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.WithContext(ctx).Model(&models.CachedSummary{}).
+		Where("content_hash = ?", contentHash).
+		UpdateColumns(map[string]interface{}{
+			"hits":        gorm.Expr("hits + 1"),
+			"last_hit_at": time.Now().UTC(),
+		}).Error
+	return &cached, err
+}
+
+// Put stores a summary, replacing the one cached for the same content
+func (r *SummaryCacheRepository) Put(ctx context.Context, summary *models.CachedSummary) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "content_hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"summary", "summary_truncated", "processing_model", "processing_prompt", "topics", "updated_at"}),
+	}).Create(summary).Error
+}
@@ -2,15 +2,18 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/segmentio/kafka-go"
-	"google.golang.org/protobuf/proto"
 
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/client"
 	"github.com/Fancu1/phoenix-rss/internal/ai-service/core"
+	"github.com/Fancu1/phoenix-rss/internal/events"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/tracing"
 	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
 )
 
@@ -20,10 +23,27 @@ type ArticleProcessor struct {
 	processingService *core.ProcessingService
 	consumer          *kafka.Reader
 	producer          *kafka.Writer
+	retryProducer     *kafka.Writer
 	brokers           []string
 	groupID           string
 	inputTopic        string
 	outputTopic       string
+	inputCodec        *events.ProtoCodec
+	outputCodec       *events.ProtoCodec
+	producerOptions   events.ProducerOptions
+	processedEvents   *core.ProcessedEvents
+	budget            *core.Budget
+	batching          BatchConfig
+	retry             RetryConfig
+}
+
+// BatchConfig groups the events the processor reads close together: it waits up to
+// Window after the first event of a batch for up to Size events, and processes up to
+// Concurrency of them at a time. A Size of 1 processes events one by one.
+type BatchConfig struct {
+	Size        int
+	Window      time.Duration
+	Concurrency int
 }
 
 // NewArticleProcessor creates a new article processor instance
@@ -42,11 +62,52 @@ func NewArticleProcessor(
 		groupID:           groupID,
 		inputTopic:        inputTopic,
 		outputTopic:       outputTopic,
+		batching:          BatchConfig{Size: 1, Concurrency: 1},
 	}
 }
 
+// SetSchemaCodecs checks consumed events and registers published ones with the schema
+// registry; either codec may be nil for a topic that does not use it
+func (p *ArticleProcessor) SetSchemaCodecs(input, output *events.ProtoCodec) {
+	p.inputCodec = input
+	p.outputCodec = output
+}
+
+// SetProducerOptions compresses the processed events and counts their sizes as configured
+func (p *ArticleProcessor) SetProducerOptions(opts events.ProducerOptions) {
+	p.producerOptions = opts
+}
+
+// SetProcessedEvents skips the events processed already, as when a rebalance delivers
+// them again
+func (p *ArticleProcessor) SetProcessedEvents(processed *core.ProcessedEvents) {
+	p.processedEvents = processed
+}
+
+// SetBudget stops reading events while the day's LLM budget is used up. The events wait
+// in Kafka and are processed once the budget allows it again.
+func (p *ArticleProcessor) SetBudget(budget *core.Budget) {
+	p.budget = budget
+}
+
+// SetRetry sends the articles that failed with a temporary LLM error to the retry topic
+// instead of reporting them failed, until their retries run out
+func (p *ArticleProcessor) SetRetry(cfg RetryConfig) {
+	p.retry = cfg
+}
+
+// SetBatching processes the events in batches, so articles published together are
+// summarized in parallel instead of one after the other
+func (p *ArticleProcessor) SetBatching(cfg BatchConfig) {
+	p.batching = BatchConfig{Size: max(cfg.Size, 1), Window: cfg.Window, Concurrency: max(cfg.Concurrency, 1)}
+}
+
 // Start begins processing article events from Kafka
 func (p *ArticleProcessor) Start(ctx context.Context) error {
+	if err := p.inputCodec.Check(ctx); err != nil {
+		return err
+	}
+
 	p.consumer = kafka.NewReader(kafka.ReaderConfig{
 		Brokers:        p.brokers,
 		Topic:          p.inputTopic,
@@ -62,6 +123,16 @@ func (p *ArticleProcessor) Start(ctx context.Context) error {
 		Balancer:     &kafka.LeastBytes{},
 		RequiredAcks: kafka.RequireOne,
 		Async:        false,
+		Compression:  p.producerOptions.Compression,
+	}
+	if p.retry.enabled() {
+		p.retryProducer = &kafka.Writer{
+			Addr:         kafka.TCP(p.brokers...),
+			Topic:        p.retry.Topic,
+			Balancer:     &kafka.LeastBytes{},
+			RequiredAcks: kafka.RequireOne,
+			Compression:  p.producerOptions.Compression,
+		}
 	}
 
 	p.logger.Info("starting AI article processor",
@@ -69,6 +140,11 @@ func (p *ArticleProcessor) Start(ctx context.Context) error {
 		"output_topic", p.outputTopic,
 		"group_id", p.groupID,
 		"brokers", p.brokers,
+		"batch_size", p.batching.Size,
+		"batch_window", p.batching.Window.String(),
+		"batch_concurrency", p.batching.Concurrency,
+		"retry_topic", p.retry.Topic,
+		"max_retries", p.retry.MaxRetries,
 	)
 
 	defer func() {
@@ -78,6 +154,9 @@ func (p *ArticleProcessor) Start(ctx context.Context) error {
 		if p.producer != nil {
 			p.producer.Close()
 		}
+		if p.retryProducer != nil {
+			p.retryProducer.Close()
+		}
 	}()
 
 	for {
@@ -88,28 +167,89 @@ func (p *ArticleProcessor) Start(ctx context.Context) error {
 		default:
 		}
 
-		message, err := p.consumer.FetchMessage(ctx)
+		if err := p.budget.Wait(ctx); err != nil {
+			return err
+		}
+
+		// a batch cut short by shutdown is left uncommitted, to be read again
+		batch, err := collectBatch(ctx, p.consumer.FetchMessage, p.batching, p.logger)
+		if err != nil {
+			return err
+		}
+
+		p.processBatch(ctx, batch)
+
+		// Commit the batch
+		if err := p.consumer.CommitMessages(ctx, batch...); err != nil {
+			p.logger.Error("failed to commit messages", "error", err, "count", len(batch))
+		}
+	}
+}
+
+// collectBatch reads a message, waiting as long as it takes, then up to cfg.Size-1 more
+// that arrive within cfg.Window. It only fails once ctx is done.
+func collectBatch(ctx context.Context, fetch func(ctx context.Context) (kafka.Message, error), cfg BatchConfig, logger *slog.Logger) ([]kafka.Message, error) {
+	var batch []kafka.Message
+	for len(batch) == 0 {
+		message, err := fetch(ctx)
 		if err != nil {
 			if ctx.Err() != nil {
-				return ctx.Err()
+				return nil, ctx.Err()
 			}
-			p.logger.Error("failed to fetch message", "error", err)
+			logger.Error("failed to fetch message", "error", err)
 			continue
 		}
+		batch = append(batch, message)
+	}
+	if cfg.Size <= 1 || cfg.Window <= 0 {
+		return batch, nil
+	}
 
-		if err := p.processMessage(ctx, message); err != nil {
-			p.logger.Error("failed to process message",
-				"error", err,
-				"offset", message.Offset,
-				"partition", message.Partition,
-			)
+	windowCtx, cancel := context.WithTimeout(ctx, cfg.Window)
+	defer cancel()
+	for len(batch) < cfg.Size {
+		message, err := fetch(windowCtx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if windowCtx.Err() != nil {
+				break
+			}
+			logger.Error("failed to fetch message", "error", err)
+			continue
 		}
+		batch = append(batch, message)
+	}
+	return batch, nil
+}
 
-		// Commit the message
-		if err := p.consumer.CommitMessages(ctx, message); err != nil {
-			p.logger.Error("failed to commit message", "error", err)
-		}
+// processBatch processes the messages, up to the batch concurrency at a time
+func (p *ArticleProcessor) processBatch(ctx context.Context, batch []kafka.Message) {
+	if len(batch) > 1 {
+		p.logger.Debug("processing message batch", "count", len(batch))
 	}
+
+	sem := make(chan struct{}, p.batching.Concurrency)
+	var wg sync.WaitGroup
+	for _, message := range batch {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := p.processMessage(ctx, message); err != nil {
+				p.logger.Error("failed to process message",
+					"error", err,
+					"offset", message.Offset,
+					"partition", message.Partition,
+				)
+			}
+		}()
+	}
+	wg.Wait()
 }
 
 // Stop stops the article processor
@@ -128,91 +268,164 @@ func (p *ArticleProcessor) Stop(ctx context.Context) error {
 			p.logger.Error("failed to close producer", "error", err)
 		}
 	}
+	if p.retryProducer != nil {
+		if err := p.retryProducer.Close(); err != nil {
+			p.logger.Error("failed to close retry producer", "error", err)
+		}
+	}
 
 	return nil
 }
 
 // processMessage processes a single Kafka message
-func (p *ArticleProcessor) processMessage(ctx context.Context, message kafka.Message) error {
+func (p *ArticleProcessor) processMessage(ctx context.Context, message kafka.Message) (err error) {
 	p.logger.Debug("processing message",
 		"offset", message.Offset,
 		"partition", message.Partition,
 		"key", string(message.Key),
 	)
 
+	// On a shared topic other event types pass by; only article_persisted is ours
+	if eventType, ok := events.EventTypeOf(message); ok && eventType != events.EventArticlePersisted {
+		p.logger.Debug("skipping message of another event type", "event_type", eventType)
+		return nil
+	}
+
 	// Parse the message as ArticlePersistedEvent
 	var event article_eventspb.ArticlePersistedEvent
-	if err := p.unmarshalEvent(message.Value, &event); err != nil {
+	if err := p.inputCodec.Decode(ctx, message.Value, &event); err != nil {
 		return fmt.Errorf("failed to unmarshal event: %w", err)
 	}
 
+	// a request ID made up below would differ for each delivery of the event
+	deliveryID := event.RequestId
+	if deliveryID == "" {
+		deliveryID = events.RequestIDOf(message)
+	}
+
+	ctx, span := events.StartConsumeSpan(events.ContextWithRequestID(ctx, message, event.RequestId), message)
+	defer func() { tracing.End(span, err) }()
+	event.RequestId, _ = logger.GetRequestID(ctx)
+
 	p.logger.Info("received article persisted event",
 		"article_id", event.ArticleId,
 		"feed_id", event.FeedId,
 		"title", event.Title,
+		"request_id", event.RequestId,
 	)
 
-	// Process the article
-	processedEvent, err := p.processingService.ProcessArticle(ctx, &event)
+	if !p.processedEvents.Claim(ctx, event.ArticleId, deliveryID) {
+		p.logger.Info("skipping article persisted event processed already",
+			"article_id", event.ArticleId,
+			"request_id", event.RequestId,
+		)
+		return nil
+	}
+	processed := false
+	defer func() {
+		// the context may be done, so record the outcome regardless
+		recordCtx := context.WithoutCancel(ctx)
+		if processed {
+			p.processedEvents.Complete(recordCtx, event.ArticleId, deliveryID)
+		} else {
+			p.processedEvents.Release(recordCtx, event.ArticleId, deliveryID)
+		}
+	}()
+
+	// Process the article in every summary language
+	processedEvents, err := p.processingService.ProcessArticleLanguages(ctx, &event)
 	if err != nil {
-		return fmt.Errorf("failed to process article: %w", err)
+		if ctx.Err() != nil {
+			return fmt.Errorf("failed to process article: %w", err)
+		}
+		// A temporary failure is tried again later. The event is left unclaimed, so the
+		// retry is processed.
+		if scheduled, retryErr := p.scheduleRetry(ctx, message, err); scheduled {
+			return retryErr
+		}
+		// Tell the feed service so the article shows "summary unavailable" instead of
+		// waiting for a summary that never comes
+		failed := core.FailedEvent(event.ArticleId, err)
+		failed.RequestId = event.RequestId
+		if pubErr := p.publishProcessedEvent(ctx, failed); pubErr != nil {
+			return fmt.Errorf("failed to process article: %w (publishing the failure: %v)", err, pubErr)
+		}
+		processed = true
+		return fmt.Errorf("failed to process article (%s): %w", failed.ErrorClass, err)
 	}
 
-	// Publish the processed event
-	if err := p.publishProcessedEvent(ctx, processedEvent); err != nil {
-		return fmt.Errorf("failed to publish processed event: %w", err)
+	// Publish one processed event per language
+	for _, processedEvent := range processedEvents {
+		processedEvent.RequestId = event.RequestId
+		if err := p.publishProcessedEvent(ctx, processedEvent); err != nil {
+			return fmt.Errorf("failed to publish processed event: %w", err)
+		}
 	}
 
+	processed = true
+
 	p.logger.Info("successfully processed and published article",
 		"article_id", event.ArticleId,
-		"summary_length", len(processedEvent.Summary),
+		"languages", len(processedEvents),
+		"summary_length", len(processedEvents[0].Summary),
 	)
 
 	return nil
 }
 
-// unmarshalEvent unmarshals the event based on the message format
-func (p *ArticleProcessor) unmarshalEvent(data []byte, event *article_eventspb.ArticlePersistedEvent) error {
-	if err := proto.Unmarshal(data, event); err == nil {
-		return nil
+// scheduleRetry sends a message that failed with err to the retry topic, when err is
+// temporary and the message has retries left. It reports whether it did, with the error
+// to log for the failed attempt.
+func (p *ArticleProcessor) scheduleRetry(ctx context.Context, message kafka.Message, err error) (bool, error) {
+	if !p.retry.enabled() || !core.Retryable(err) {
+		return false, nil
 	}
-
-	if err := json.Unmarshal(data, event); err != nil {
-		return fmt.Errorf("failed to unmarshal as both protobuf and JSON: %w", err)
+	attempt := retryAttemptOf(message) + 1
+	if attempt > p.retry.MaxRetries {
+		p.logger.Warn("article retries exhausted, reporting it failed", "key", string(message.Key), "retries", p.retry.MaxRetries)
+		return false, nil
 	}
 
-	return nil
+	delay := p.retry.delay(attempt, client.RetryAfter(err))
+	retry := retryMessage(message, attempt, time.Now().Add(delay))
+	if pubErr := p.retryProducer.WriteMessages(ctx, retry); pubErr != nil {
+		p.logger.Error("failed to schedule article retry, reporting it failed", "error", pubErr)
+		return false, nil
+	}
+	p.producerOptions.Sizes.Observe(p.retry.Topic, retry)
+	return true, fmt.Errorf("failed to process article, retry %d of %d in %s: %w", attempt, p.retry.MaxRetries, delay, err)
 }
 
 // publishProcessedEvent publishes the processed event to Kafka
-func (p *ArticleProcessor) publishProcessedEvent(ctx context.Context, event *article_eventspb.ArticleProcessedEvent) error {
-	data, err := proto.Marshal(event)
+func (p *ArticleProcessor) publishProcessedEvent(ctx context.Context, event *article_eventspb.ArticleProcessedEvent) (err error) {
+	ctx, span := events.StartPublishSpan(ctx, p.outputTopic, events.EventArticleProcessed)
+	defer func() { tracing.End(span, err) }()
+
+	data, err := p.outputCodec.Encode(ctx, event)
 	if err != nil {
-		data, err = json.Marshal(event)
-		if err != nil {
-			return fmt.Errorf("failed to marshal processed event: %w", err)
-		}
+		return fmt.Errorf("failed to marshal processed event: %w", err)
 	}
 
 	message := kafka.Message{
 		Key:   []byte(fmt.Sprintf("article_%d", event.ArticleId)),
 		Value: data,
-		Headers: []kafka.Header{
+		Headers: events.WithContextHeaders(ctx, event.RequestId, []kafka.Header{
 			{
-				Key:   "event_type",
-				Value: []byte("article_processed"),
+				Key:   events.EventTypeHeader,
+				Value: []byte(events.EventArticleProcessed),
 			},
 			{
 				Key:   "source",
 				Value: []byte("ai-service"),
 			},
-		},
+		}),
 		Time: time.Now(),
 	}
 
 	if err := p.producer.WriteMessages(ctx, message); err != nil {
 		return fmt.Errorf("failed to write message to Kafka: %w", err)
 	}
+	p.producerOptions.Sizes.Observe(p.outputTopic, message)
 
 	p.logger.Debug("published processed event",
 		"article_id", event.ArticleId,
@@ -0,0 +1,99 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// channelFetcher hands out the messages sent on its channel, like kafka.Reader.FetchMessage
+type channelFetcher chan kafka.Message
+
+func (c channelFetcher) fetch(ctx context.Context) (kafka.Message, error) {
+	select {
+	case message := <-c:
+		return message, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func TestCollectBatch(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	messages := make(channelFetcher, 10)
+	for i := 0; i < 5; i++ {
+		messages <- kafka.Message{Offset: int64(i)}
+	}
+
+	batch, err := collectBatch(context.Background(), messages.fetch, BatchConfig{Size: 3, Window: time.Second}, logger)
+	if err != nil || len(batch) != 3 || batch[2].Offset != 2 {
+		t.Fatalf("expected a full batch of the first 3 messages, got %v and %v", batch, err)
+	}
+
+	start := time.Now()
+	batch, err = collectBatch(context.Background(), messages.fetch, BatchConfig{Size: 3, Window: 50 * time.Millisecond}, logger)
+	if err != nil || len(batch) != 2 {
+		t.Fatalf("expected the 2 messages left once the window passed, got %v and %v", batch, err)
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("expected to wait for the window, returned after %s", waited)
+	}
+
+	messages <- kafka.Message{Offset: 5}
+	messages <- kafka.Message{Offset: 6}
+	batch, err = collectBatch(context.Background(), messages.fetch, BatchConfig{Size: 1}, logger)
+	if err != nil || len(batch) != 1 {
+		t.Fatalf("expected a size of 1 to read messages one by one, got %v and %v", batch, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	batch, err = collectBatch(ctx, messages.fetch, BatchConfig{Size: 3, Window: time.Second}, logger)
+	if !errors.Is(err, context.Canceled) || batch != nil {
+		t.Errorf("expected a batch cut short by shutdown to be dropped, got %v and %v", batch, err)
+	}
+}
+
+func TestRetryConfig_Delay(t *testing.T) {
+	cfg := RetryConfig{Topic: "articles.retry", MaxRetries: 5, Delay: time.Minute, MaxDelay: 10 * time.Minute}
+	for retry, want := range map[int]time.Duration{1: time.Minute, 2: 2 * time.Minute, 4: 8 * time.Minute, 5: 10 * time.Minute} {
+		if got := cfg.delay(retry, 0); got != want {
+			t.Errorf("retry %d: expected a delay of %v, got %v", retry, want, got)
+		}
+	}
+	if got := cfg.delay(1, time.Hour); got != time.Hour {
+		t.Errorf("expected a longer Retry-After to win, got %v", got)
+	}
+}
+
+func TestRetryMessage(t *testing.T) {
+	original := kafka.Message{
+		Key:     []byte("article_7"),
+		Value:   []byte("event"),
+		Headers: []kafka.Header{{Key: "event_type", Value: []byte("article_persisted")}, {Key: "request_id", Value: []byte("req-1")}},
+	}
+	if retryAttemptOf(original) != 0 || !retryDueOf(original).IsZero() {
+		t.Fatal("expected a first delivery to have no retry headers")
+	}
+
+	due := time.UnixMilli(time.Now().Add(time.Minute).UnixMilli())
+	first := retryMessage(original, 1, due)
+	second := retryMessage(first, 2, due.Add(time.Minute))
+	if retryAttemptOf(first) != 1 || !retryDueOf(first).Equal(due) {
+		t.Fatalf("expected retry 1 due at %v, got %d at %v", due, retryAttemptOf(first), retryDueOf(first))
+	}
+	if retryAttemptOf(second) != 2 || len(second.Headers) != 4 {
+		t.Fatalf("expected the retry headers to be replaced, got %v", second.Headers)
+	}
+	if string(second.Key) != "article_7" || string(second.Value) != "event" || string(second.Headers[1].Value) != "req-1" {
+		t.Errorf("expected the event to keep its key, value and headers, got %+v", second)
+	}
+}
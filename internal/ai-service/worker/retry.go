@@ -0,0 +1,201 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/Fancu1/phoenix-rss/internal/events"
+)
+
+// Headers of the article persisted events sent to the retry topic: how many times the
+// article was retried, and the Unix time in milliseconds it is due again
+const (
+	RetryAttemptHeader = "retry_attempt"
+	RetryDueHeader     = "retry_due"
+)
+
+// RetryConfig sends the articles whose processing failed with a temporary LLM error,
+// such as a rate limit or an outage, to Topic to be processed again after Delay, doubled
+// for each retry up to MaxDelay, up to MaxRetries times. A Retry-After the LLM API sent
+// replaces a shorter delay. An empty Topic or MaxRetries of 0 reports such failures at
+// once.
+type RetryConfig struct {
+	Topic      string
+	MaxRetries int
+	Delay      time.Duration
+	MaxDelay   time.Duration
+}
+
+func (c RetryConfig) enabled() bool {
+	return c.Topic != "" && c.MaxRetries > 0
+}
+
+// delay is how long the retry-th retry waits, at least retryAfter
+func (c RetryConfig) delay(retry int, retryAfter time.Duration) time.Duration {
+	delay := c.Delay
+	for i := 1; i < retry && delay < c.MaxDelay; i++ {
+		delay *= 2
+	}
+	return max(min(delay, c.MaxDelay), retryAfter)
+}
+
+// retryAttemptOf returns the retries a message already went through, 0 for a first
+// delivery
+func retryAttemptOf(message kafka.Message) int {
+	for _, header := range message.Headers {
+		if header.Key == RetryAttemptHeader {
+			attempt, _ := strconv.Atoi(string(header.Value))
+			return attempt
+		}
+	}
+	return 0
+}
+
+// retryDueOf returns when a retried message is due, the zero time when it does not say
+func retryDueOf(message kafka.Message) time.Time {
+	for _, header := range message.Headers {
+		if header.Key == RetryDueHeader {
+			if millis, err := strconv.ParseInt(string(header.Value), 10, 64); err == nil {
+				return time.UnixMilli(millis)
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// retryMessage is message sent for its attempt-th retry, due at due. It keeps the key,
+// value and headers, so the event comes back as it was first published.
+func retryMessage(message kafka.Message, attempt int, due time.Time) kafka.Message {
+	headers := make([]kafka.Header, 0, len(message.Headers)+2)
+	for _, header := range message.Headers {
+		if header.Key != RetryAttemptHeader && header.Key != RetryDueHeader {
+			headers = append(headers, header)
+		}
+	}
+	headers = append(headers,
+		kafka.Header{Key: RetryAttemptHeader, Value: []byte(strconv.Itoa(attempt))},
+		kafka.Header{Key: RetryDueHeader, Value: []byte(strconv.FormatInt(due.UnixMilli(), 10))},
+	)
+	return kafka.Message{
+		Key:     message.Key,
+		Value:   message.Value,
+		Headers: headers,
+		Time:    time.Now(),
+	}
+}
+
+// relayRetryInterval is how long the relay waits before writing a message again after
+// Kafka refused it
+const relayRetryInterval = 5 * time.Second
+
+// RetryRelay moves the articles waiting in the retry topic back to the articles topic
+// once they are due. Messages are relayed in order, so one due later holds up those
+// behind it in its partition by up to the longest retry delay.
+type RetryRelay struct {
+	logger      *slog.Logger
+	brokers     []string
+	groupID     string
+	retryTopic  string
+	outputTopic string
+	options     events.ProducerOptions
+	consumer    *kafka.Reader
+	producer    *kafka.Writer
+}
+
+// NewRetryRelay creates a relay from retryTopic to outputTopic
+func NewRetryRelay(logger *slog.Logger, brokers []string, groupID, retryTopic, outputTopic string, options events.ProducerOptions) *RetryRelay {
+	return &RetryRelay{
+		logger:      logger,
+		brokers:     brokers,
+		groupID:     groupID,
+		retryTopic:  retryTopic,
+		outputTopic: outputTopic,
+		options:     options,
+	}
+}
+
+// Start relays messages until ctx is done
+func (r *RetryRelay) Start(ctx context.Context) error {
+	r.consumer = kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        r.brokers,
+		Topic:          r.retryTopic,
+		GroupID:        r.groupID,
+		MinBytes:       1,
+		MaxBytes:       10e6, // 10MB
+		CommitInterval: time.Second,
+	})
+	r.producer = &kafka.Writer{
+		Addr:         kafka.TCP(r.brokers...),
+		Topic:        r.outputTopic,
+		Balancer:     &kafka.LeastBytes{},
+		RequiredAcks: kafka.RequireOne,
+		Compression:  r.options.Compression,
+	}
+	defer func() {
+		r.consumer.Close()
+		r.producer.Close()
+	}()
+
+	r.logger.Info("starting article retry relay", "retry_topic", r.retryTopic, "output_topic", r.outputTopic, "group_id", r.groupID)
+
+	for {
+		message, err := r.consumer.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			r.logger.Error("failed to fetch retry message", "error", err)
+			continue
+		}
+
+		if wait := time.Until(retryDueOf(message)); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				// left uncommitted, to be relayed after the restart
+				timer.Stop()
+				return ctx.Err()
+			}
+		}
+
+		// a message skipped here would be committed with the next one, so it is written
+		// until it goes through
+		for {
+			err := r.relay(ctx, message)
+			if err == nil {
+				break
+			}
+			r.logger.Error("failed to relay retry message", "error", err, "offset", message.Offset, "partition", message.Partition)
+			select {
+			case <-time.After(relayRetryInterval):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if err := r.consumer.CommitMessages(ctx, message); err != nil {
+			r.logger.Error("failed to commit retry message", "error", err, "offset", message.Offset)
+		}
+	}
+}
+
+// relay writes message back to the output topic
+func (r *RetryRelay) relay(ctx context.Context, message kafka.Message) error {
+	relayed := kafka.Message{Key: message.Key, Value: message.Value, Headers: message.Headers, Time: time.Now()}
+	if err := r.producer.WriteMessages(ctx, relayed); err != nil {
+		return fmt.Errorf("failed to write message to Kafka: %w", err)
+	}
+	r.options.Sizes.Observe(r.outputTopic, relayed)
+
+	r.logger.Info("relayed article for retry",
+		"key", string(message.Key),
+		"retry_attempt", retryAttemptOf(message),
+		"request_id", events.RequestIDOf(message),
+	)
+	return nil
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/htmlconv"
+)
+
+// Article export formats
+const (
+	ExportFormatMarkdown = "markdown"
+	ExportFormatOrg      = "org"
+)
+
+// ArticleExport is an article together with the context written into the export's
+// front matter
+type ArticleExport struct {
+	Article   *models.Article
+	FeedTitle string
+	Tags      []string
+}
+
+// ExportArticle renders an article as a standalone note: Markdown with YAML front matter,
+// or Org with file keywords. The body is converted from the sanitized HTML content, falling
+// back to the plain-text description when there is none.
+func ExportArticle(export ArticleExport, format string) ([]byte, error) {
+	var convert func(string) (string, error)
+	var header string
+	switch format {
+	case ExportFormatMarkdown:
+		convert, header = htmlconv.ToMarkdown, markdownFrontMatter(export)
+	case ExportFormatOrg:
+		convert, header = htmlconv.ToOrg, orgKeywords(export)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+
+	body := strings.TrimSpace(export.Article.Description) + "\n"
+	if strings.TrimSpace(export.Article.Content) != "" {
+		converted, err := convert(export.Article.Content)
+		if err != nil {
+			return nil, fmt.Errorf("convert article %d: %w", export.Article.ID, err)
+		}
+		body = converted
+	}
+
+	return []byte(header + "\n" + body), nil
+}
+
+// ArticlePlainText renders an article as bare prose for screen readers, speech synthesis
+// and language models: the title, then the body converted from the sanitized HTML
+// content with its links footnoted, or the plain-text description when there is none
+func ArticlePlainText(article *models.Article) ([]byte, error) {
+	body := strings.TrimSpace(article.Description)
+	if strings.TrimSpace(article.Content) != "" {
+		converted, err := htmlconv.ToPlainText(article.Content)
+		if err != nil {
+			return nil, fmt.Errorf("convert article %d: %w", article.ID, err)
+		}
+		body = strings.TrimSpace(converted)
+	}
+
+	text := oneLine(article.Title)
+	if body != "" {
+		text += "\n\n" + body
+	}
+	return []byte(text + "\n"), nil
+}
+
+func markdownFrontMatter(export ArticleExport) string {
+	article := export.Article
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "title: %s\n", yamlString(article.Title))
+	fmt.Fprintf(&b, "url: %s\n", yamlString(article.URL))
+	fmt.Fprintf(&b, "date: %s\n", article.PublishedAt.UTC().Format("2006-01-02T15:04:05Z07:00"))
+	if export.FeedTitle != "" {
+		fmt.Fprintf(&b, "feed: %s\n", yamlString(export.FeedTitle))
+	}
+	if len(export.Tags) > 0 {
+		quoted := make([]string, len(export.Tags))
+		for i, tag := range export.Tags {
+			quoted[i] = yamlString(tag)
+		}
+		fmt.Fprintf(&b, "tags: [%s]\n", strings.Join(quoted, ", "))
+	}
+	if summary := articleSummary(article); summary != "" {
+		fmt.Fprintf(&b, "summary: %s\n", yamlString(summary))
+	}
+	b.WriteString("---\n")
+	return b.String()
+}
+
+func orgKeywords(export ArticleExport) string {
+	article := export.Article
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#+TITLE: %s\n", oneLine(article.Title))
+	fmt.Fprintf(&b, "#+URL: %s\n", article.URL)
+	fmt.Fprintf(&b, "#+DATE: %s\n", article.PublishedAt.UTC().Format("[2006-01-02 Mon 15:04]"))
+	if export.FeedTitle != "" {
+		fmt.Fprintf(&b, "#+FEED: %s\n", oneLine(export.FeedTitle))
+	}
+	if len(export.Tags) > 0 {
+		tags := make([]string, len(export.Tags))
+		for i, tag := range export.Tags {
+			// org tags cannot contain spaces
+			tags[i] = strings.Join(strings.Fields(tag), "_")
+		}
+		fmt.Fprintf(&b, "#+FILETAGS: :%s:\n", strings.Join(tags, ":"))
+	}
+	if summary := articleSummary(article); summary != "" {
+		fmt.Fprintf(&b, "#+DESCRIPTION: %s\n", oneLine(summary))
+	}
+	return b.String()
+}
+
+func articleSummary(article *models.Article) string {
+	if article.Summary == nil {
+		return ""
+	}
+	return strings.TrimSpace(*article.Summary)
+}
+
+// yamlString quotes a value as a YAML double-quoted scalar, which accepts the same
+// escapes as a Go string literal
+func yamlString(value string) string {
+	return strconv.Quote(value)
+}
+
+func oneLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
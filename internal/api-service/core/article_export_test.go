@@ -0,0 +1,110 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+func exportFixture() ArticleExport {
+	summary := "A short\nsummary."
+	return ArticleExport{
+		Article: &models.Article{
+			ID:          7,
+			Title:       `Say "hello"`,
+			URL:         "https://example.com/hello",
+			Content:     "<p>Hello <em>world</em>.</p>",
+			PublishedAt: time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC),
+			Summary:     &summary,
+		},
+		FeedTitle: "Example Blog",
+		Tags:      []string{"go", "release notes"},
+	}
+}
+
+func TestExportArticle_Markdown(t *testing.T) {
+	got, err := ExportArticle(exportFixture(), ExportFormatMarkdown)
+	if err != nil {
+		t.Fatalf("ExportArticle() error = %v", err)
+	}
+
+	want := `---
+title: "Say \"hello\""
+url: "https://example.com/hello"
+date: 2024-03-05T09:30:00Z
+feed: "Example Blog"
+tags: ["go", "release notes"]
+summary: "A short\nsummary."
+---
+
+Hello _world_.
+`
+	if string(got) != want {
+		t.Errorf("ExportArticle() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExportArticle_Org(t *testing.T) {
+	got, err := ExportArticle(exportFixture(), ExportFormatOrg)
+	if err != nil {
+		t.Fatalf("ExportArticle() error = %v", err)
+	}
+
+	want := `#+TITLE: Say "hello"
+#+URL: https://example.com/hello
+#+DATE: [2024-03-05 Tue 09:30]
+#+FEED: Example Blog
+#+FILETAGS: :go:release_notes:
+#+DESCRIPTION: A short summary.
+
+Hello /world/.
+`
+	if string(got) != want {
+		t.Errorf("ExportArticle() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExportArticle_FallsBackToDescription(t *testing.T) {
+	export := ArticleExport{Article: &models.Article{Title: "t", Description: "Plain text only"}}
+	got, err := ExportArticle(export, ExportFormatMarkdown)
+	if err != nil {
+		t.Fatalf("ExportArticle() error = %v", err)
+	}
+	if !strings.HasSuffix(string(got), "---\n\nPlain text only\n") {
+		t.Errorf("ExportArticle() = %q, want the description as body", got)
+	}
+	if strings.Contains(string(got), "summary:") || strings.Contains(string(got), "tags:") {
+		t.Errorf("ExportArticle() = %q, want empty fields left out", got)
+	}
+}
+
+func TestExportArticle_UnknownFormat(t *testing.T) {
+	if _, err := ExportArticle(exportFixture(), "pdf"); err == nil {
+		t.Error("ExportArticle() error = nil, want unsupported format error")
+	}
+}
+
+func TestArticlePlainText(t *testing.T) {
+	article := &models.Article{
+		Title:   "Say \"hello\"\n again",
+		Content: `<p>Hello <em>world</em>.</p><p>See <a href="https://example.com/more">more</a>.</p>`,
+	}
+	got, err := ArticlePlainText(article)
+	if err != nil {
+		t.Fatalf("ArticlePlainText() error = %v", err)
+	}
+	want := "Say \"hello\" again\n\nHello world.\n\nSee more [1].\n\nLinks:\n[1] https://example.com/more\n"
+	if string(got) != want {
+		t.Errorf("ArticlePlainText() = %q, want %q", got, want)
+	}
+
+	got, err = ArticlePlainText(&models.Article{Title: "t", Description: "Plain text only"})
+	if err != nil {
+		t.Fatalf("ArticlePlainText() error = %v", err)
+	}
+	if string(got) != "t\n\nPlain text only\n" {
+		t.Errorf("ArticlePlainText() = %q, want the description as body", got)
+	}
+}
@@ -3,15 +3,32 @@ package core
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
 	feedpb "github.com/Fancu1/phoenix-rss/protos/gen/go/feed"
 )
 
 type ArticleServiceInterface interface {
 	TriggerFetch(ctx context.Context, userID, feedID uint) error
+	RegenerateSummary(ctx context.Context, userID, articleID uint) error
+	DeleteArticle(ctx context.Context, userID, articleID uint) error
+	RestoreArticle(ctx context.Context, userID, articleID uint) error
+	NextUnreadArticle(ctx context.Context, userID uint, query NextUnreadQuery) (uint, error)
+	SearchArticles(ctx context.Context, userID uint, query string, offset, limit int) ([]*models.Article, int64, error)
+	SetArticleRead(ctx context.Context, userID, articleID uint, read bool) (*models.Article, error)
+	MarkFeedRead(ctx context.Context, userID, feedID uint, before time.Time) (int64, error)
+	SetArticleStarred(ctx context.Context, userID, articleID uint, starred bool) (*models.Article, error)
+	ListStarredArticles(ctx context.Context, userID uint, offset, limit int) ([]*models.Article, int64, error)
+	ListUserArticles(ctx context.Context, userID uint, filter TimelineFilter, pageSize int, pageToken string) ([]*models.Article, string, int64, error)
+	AddArticleTags(ctx context.Context, userID, articleID uint, tags []string) (*models.Article, error)
+	RemoveArticleTags(ctx context.Context, userID, articleID uint, tags []string) (*models.Article, error)
+	ListTags(ctx context.Context, userID uint) ([]models.TagCount, error)
 }
 
 type ArticleServiceClient struct {
@@ -19,8 +36,12 @@ type ArticleServiceClient struct {
 	conn   *grpc.ClientConn
 }
 
-func NewArticleServiceClient(address string) (*ArticleServiceClient, error) {
-	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewArticleServiceClient(address string, opts ...grpc.DialOption) (*ArticleServiceClient, error) {
+	conn, err := grpc.NewClient(address, append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(logger.RequestIDClientInterceptor()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}, opts...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Feed Service at %s: %w", address, err)
 	}
@@ -45,3 +66,291 @@ func (c *ArticleServiceClient) TriggerFetch(ctx context.Context, userID, feedID
 	}
 	return nil
 }
+
+// RegenerateSummary asks the feed service to requeue a truncated summary with the expanded token limit
+func (c *ArticleServiceClient) RegenerateSummary(ctx context.Context, userID, articleID uint) error {
+	_, err := c.client.RegenerateSummary(ctx, &feedpb.RegenerateSummaryRequest{
+		UserId:    uint64(userID),
+		ArticleId: uint64(articleID),
+	})
+	if err != nil {
+		return MapGRPCError(err)
+	}
+	return nil
+}
+
+func (c *ArticleServiceClient) DeleteArticle(ctx context.Context, userID, articleID uint) error {
+	_, err := c.client.DeleteArticle(ctx, &feedpb.DeleteArticleRequest{
+		UserId:    uint64(userID),
+		ArticleId: uint64(articleID),
+	})
+	if err != nil {
+		return MapGRPCError(err)
+	}
+	return nil
+}
+
+func (c *ArticleServiceClient) RestoreArticle(ctx context.Context, userID, articleID uint) error {
+	_, err := c.client.RestoreArticle(ctx, &feedpb.RestoreArticleRequest{
+		UserId:    uint64(userID),
+		ArticleId: uint64(articleID),
+	})
+	if err != nil {
+		return MapGRPCError(err)
+	}
+	return nil
+}
+
+// NextUnreadQuery selects the next unread article for keyboard navigation
+type NextUnreadQuery struct {
+	AfterID  uint   // current article; 0 starts from the newest one
+	Scope    string // "feed", "folder" or "all"
+	FeedID   uint   // feed for the feed scope; defaults to the feed of AfterID
+	FolderID uint   // folder for the folder scope, its subfolders included
+	MarkRead bool   // mark AfterID read in the same transaction
+}
+
+// NextUnreadArticle returns the ID of the next unread article, or 0 when none is left
+func (c *ArticleServiceClient) NextUnreadArticle(ctx context.Context, userID uint, query NextUnreadQuery) (uint, error) {
+	resp, err := c.client.NextUnreadArticle(ctx, &feedpb.NextUnreadArticleRequest{
+		UserId:         uint64(userID),
+		AfterArticleId: uint64(query.AfterID),
+		Scope:          query.Scope,
+		FeedId:         uint64(query.FeedID),
+		FolderId:       uint64(query.FolderID),
+		MarkRead:       query.MarkRead,
+	})
+	if err != nil {
+		return 0, MapGRPCError(err)
+	}
+	if resp.Article == nil {
+		return 0, nil
+	}
+	return uint(resp.Article.Id), nil
+}
+
+// SearchArticles returns a page of the user's articles matching query, best match first,
+// and the number of matches
+func (c *ArticleServiceClient) SearchArticles(ctx context.Context, userID uint, query string, offset, limit int) ([]*models.Article, int64, error) {
+	resp, err := c.client.SearchArticles(ctx, &feedpb.SearchArticlesRequest{
+		UserId: uint64(userID),
+		Query:  query,
+		Offset: uint32(offset),
+		Limit:  uint32(limit),
+	})
+	if err != nil {
+		return nil, 0, MapGRPCError(err)
+	}
+
+	articles := make([]*models.Article, len(resp.Articles))
+	for i, pbArticle := range resp.Articles {
+		article, err := convertPbToArticle(pbArticle)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to convert article %d: %w", pbArticle.Id, err)
+		}
+		articles[i] = article
+	}
+	return articles, resp.Total, nil
+}
+
+// SetArticleRead marks an article read or unread for the user and returns it
+func (c *ArticleServiceClient) SetArticleRead(ctx context.Context, userID, articleID uint, read bool) (*models.Article, error) {
+	resp, err := c.client.SetArticleRead(ctx, &feedpb.SetArticleReadRequest{
+		UserId:    uint64(userID),
+		ArticleId: uint64(articleID),
+		Read:      read,
+	})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+	return convertPbToArticle(resp.Article)
+}
+
+// MarkFeedRead marks the feed's articles published at or before a non-zero before read for
+// the user and returns how many were unread
+func (c *ArticleServiceClient) MarkFeedRead(ctx context.Context, userID, feedID uint, before time.Time) (int64, error) {
+	req := &feedpb.MarkFeedReadRequest{UserId: uint64(userID), FeedId: uint64(feedID)}
+	if !before.IsZero() {
+		req.Before = before.UTC().Format(time.RFC3339)
+	}
+	resp, err := c.client.MarkFeedRead(ctx, req)
+	if err != nil {
+		return 0, MapGRPCError(err)
+	}
+	return resp.Marked, nil
+}
+
+// SetArticleStarred stars or unstars an article for the user and returns it
+func (c *ArticleServiceClient) SetArticleStarred(ctx context.Context, userID, articleID uint, starred bool) (*models.Article, error) {
+	resp, err := c.client.SetArticleStarred(ctx, &feedpb.SetArticleStarredRequest{
+		UserId:    uint64(userID),
+		ArticleId: uint64(articleID),
+		Starred:   starred,
+	})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+	return convertPbToArticle(resp.Article)
+}
+
+// ListStarredArticles returns a page of the user's starred articles, most recently
+// starred first, and the number of starred articles
+func (c *ArticleServiceClient) ListStarredArticles(ctx context.Context, userID uint, offset, limit int) ([]*models.Article, int64, error) {
+	resp, err := c.client.ListStarredArticles(ctx, &feedpb.ListStarredArticlesRequest{
+		UserId: uint64(userID),
+		Offset: uint32(offset),
+		Limit:  uint32(limit),
+	})
+	if err != nil {
+		return nil, 0, MapGRPCError(err)
+	}
+
+	articles := make([]*models.Article, len(resp.Articles))
+	for i, pbArticle := range resp.Articles {
+		article, err := convertPbToArticle(pbArticle)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to convert article %d: %w", pbArticle.Id, err)
+		}
+		articles[i] = article
+	}
+	return articles, resp.Total, nil
+}
+
+// TimelineFilter narrows ListUserArticles; zero fields do not filter
+type TimelineFilter struct {
+	Tag      string // name of a tag of the user
+	Unread   bool   // only articles the user has not read
+	Starred  bool   // only articles the user starred
+	FolderID uint   // only articles of the feeds the user filed in this folder
+}
+
+// ListUserArticles returns a page of the newest articles across the user's subscribed
+// feeds matching filter, the token of the next page, empty on the last one, and the
+// number of matching articles
+func (c *ArticleServiceClient) ListUserArticles(ctx context.Context, userID uint, filter TimelineFilter, pageSize int, pageToken string) ([]*models.Article, string, int64, error) {
+	resp, err := c.client.ListUserArticles(ctx, &feedpb.ListUserArticlesRequest{
+		UserId:    uint64(userID),
+		PageSize:  uint32(pageSize),
+		PageToken: pageToken,
+		Tag:       filter.Tag,
+		Unread:    filter.Unread,
+		Starred:   filter.Starred,
+		FolderId:  uint64(filter.FolderID),
+	})
+	if err != nil {
+		return nil, "", 0, MapGRPCError(err)
+	}
+
+	articles := make([]*models.Article, len(resp.Articles))
+	for i, pbArticle := range resp.Articles {
+		article, err := convertPbToArticle(pbArticle)
+		if err != nil {
+			return nil, "", 0, fmt.Errorf("failed to convert article %d: %w", pbArticle.Id, err)
+		}
+		articles[i] = article
+	}
+	return articles, resp.NextPageToken, resp.Total, nil
+}
+
+// AddArticleTags puts tags on an article for the user and returns it with its tags
+func (c *ArticleServiceClient) AddArticleTags(ctx context.Context, userID, articleID uint, tags []string) (*models.Article, error) {
+	resp, err := c.client.AddArticleTags(ctx, &feedpb.ArticleTagsRequest{
+		UserId:    uint64(userID),
+		ArticleId: uint64(articleID),
+		Tags:      tags,
+	})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+	return convertPbToArticle(resp.Article)
+}
+
+// RemoveArticleTags takes tags off an article for the user and returns it with the tags left
+func (c *ArticleServiceClient) RemoveArticleTags(ctx context.Context, userID, articleID uint, tags []string) (*models.Article, error) {
+	resp, err := c.client.RemoveArticleTags(ctx, &feedpb.ArticleTagsRequest{
+		UserId:    uint64(userID),
+		ArticleId: uint64(articleID),
+		Tags:      tags,
+	})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+	return convertPbToArticle(resp.Article)
+}
+
+// ListTags returns the user's tags by name with their article counts
+func (c *ArticleServiceClient) ListTags(ctx context.Context, userID uint) ([]models.TagCount, error) {
+	resp, err := c.client.ListTags(ctx, &feedpb.ListTagsRequest{UserId: uint64(userID)})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+
+	tags := make([]models.TagCount, len(resp.Tags))
+	for i, tag := range resp.Tags {
+		tags[i] = models.TagCount{Name: tag.Name, ArticleCount: tag.ArticleCount}
+	}
+	return tags, nil
+}
+
+func convertPbToArticle(pb *feedpb.Article) (*models.Article, error) {
+	article := &models.Article{
+		ID:               uint(pb.Id),
+		FeedID:           uint(pb.FeedId),
+		Title:            pb.Title,
+		URL:              pb.Url,
+		Description:      pb.Description,
+		Content:          pb.Content,
+		Read:             pb.Read,
+		Starred:          pb.Starred,
+		SummaryTruncated: pb.SummaryTruncated,
+		ProcessingStatus: models.ProcessingStatus(pb.ProcessingStatus),
+		Summary:          optionalString(pb.Summary),
+		ProcessingModel:  optionalString(pb.ProcessingModel),
+		ProcessingPrompt: optionalString(pb.ProcessingPrompt),
+		ProcessingError:  optionalString(pb.ProcessingError),
+		HTTPETag:         optionalString(pb.HttpEtag),
+		HTTPLastModified: optionalString(pb.HttpLastModified),
+		ContentType:      optionalString(pb.ContentType),
+		Direction:        pb.Direction,
+		Tags:             pb.Tags,
+		Topics:           pb.Topics,
+	}
+
+	var err error
+	if article.CreatedAt, err = time.Parse(time.RFC3339, pb.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %w", err)
+	}
+	if article.UpdatedAt, err = time.Parse(time.RFC3339, pb.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to parse updated_at: %w", err)
+	}
+	if article.PublishedAt, err = time.Parse(time.RFC3339, pb.PublishedAt); err != nil {
+		return nil, fmt.Errorf("failed to parse published_at: %w", err)
+	}
+	if article.ProcessedAt, err = optionalTime(pb.ProcessedAt); err != nil {
+		return nil, fmt.Errorf("failed to parse processed_at: %w", err)
+	}
+	if article.LastCheckedAt, err = optionalTime(pb.LastCheckedAt); err != nil {
+		return nil, fmt.Errorf("failed to parse last_checked_at: %w", err)
+	}
+	return article, nil
+}
+
+// optionalString maps the empty string proto3 sends for an unset field back to nil
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// optionalTime parses an RFC 3339 timestamp, nil when empty
+func optionalTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
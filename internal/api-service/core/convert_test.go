@@ -0,0 +1,93 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Fancu1/phoenix-rss/pkg/prototest"
+	feedpb "github.com/Fancu1/phoenix-rss/protos/gen/go/feed"
+	userpb "github.com/Fancu1/phoenix-rss/protos/gen/go/user"
+)
+
+// The golden files under testdata change whenever a field is added to a .proto file or a
+// conversion maps it differently; review the diff and rerun with
+// PROTOTEST_UPDATE=1.
+
+func TestUserProto_WireRoundTrip(t *testing.T) {
+	prototest.RequireRoundTrip(t, prototest.MessageTypes(userpb.File_user_proto), 20)
+}
+
+func TestConvertPbToFeed_ReadsEveryField(t *testing.T) {
+	var pb feedpb.Feed
+	prototest.Fill(&pb)
+	pb.CreatedAt = prototest.BaseTime.Format(time.RFC3339)
+	pb.UpdatedAt = prototest.BaseTime.Add(time.Hour).Format(time.RFC3339)
+	pb.LastFetchedAt = prototest.BaseTime.Add(2 * time.Hour).Format(time.RFC3339)
+
+	client := &FeedServiceClient{}
+	convert := func(msg proto.Message) any {
+		feed, err := client.convertPbToFeed(msg.(*feedpb.Feed))
+		return [2]any{feed, err}
+	}
+	// ListAllFeeds lists every feed for the admin API, where the subscription fields of a
+	// feed and the subscriber figures have no place in models.Feed
+	prototest.RequireAllRead(t, &pb, convert, "custom_title", "notes", "owner_user_id", "subscriber_count", "has_fetch_headers")
+
+	feed, err := client.convertPbToFeed(&pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prototest.RequireGolden(t, "testdata/feed.golden.json", feed)
+}
+
+func TestConvertPbToFeedHealth_ReadsEveryField(t *testing.T) {
+	var pb feedpb.FeedHealth
+	prototest.Fill(&pb)
+	pb.LastFetchedAt = prototest.BaseTime.Format(time.RFC3339)
+	pb.LastFetchErrorAt = prototest.BaseTime.Add(time.Hour).Format(time.RFC3339)
+
+	convert := func(msg proto.Message) any {
+		health, err := convertPbToFeedHealth(msg.(*feedpb.FeedHealth))
+		return [2]any{health, err}
+	}
+	prototest.RequireAllRead(t, &pb, convert)
+
+	health, err := convertPbToFeedHealth(&pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prototest.RequireGolden(t, "testdata/feed_health.golden.json", health)
+}
+
+func TestConvertPbToArticle_ReadsEveryField(t *testing.T) {
+	var pb feedpb.Article
+	prototest.Fill(&pb)
+	for i, field := range []*string{&pb.CreatedAt, &pb.UpdatedAt, &pb.PublishedAt, &pb.ProcessedAt, &pb.LastCheckedAt} {
+		*field = prototest.BaseTime.Add(time.Duration(i) * time.Hour).Format(time.RFC3339)
+	}
+
+	convert := func(msg proto.Message) any {
+		article, err := convertPbToArticle(msg.(*feedpb.Article))
+		return [2]any{article, err}
+	}
+	prototest.RequireAllRead(t, &pb, convert)
+
+	article, err := convertPbToArticle(&pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prototest.RequireGolden(t, "testdata/article.golden.json", article)
+}
+
+func TestConvertPbToLLMCredential_ReadsEveryField(t *testing.T) {
+	var pb userpb.LLMCredential
+	prototest.Fill(&pb)
+
+	convert := func(msg proto.Message) any {
+		return convertPbToLLMCredential(7, msg.(*userpb.LLMCredential))
+	}
+	prototest.RequireAllRead(t, &pb, convert)
+	prototest.RequireGolden(t, "testdata/llm_credential.golden.json", convertPbToLLMCredential(7, &pb))
+}
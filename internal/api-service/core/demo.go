@@ -0,0 +1,74 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+// DemoAccount is the user visitors of a read-only demo instance sign in as
+type DemoAccount struct {
+	Username string
+	Password string
+	Feeds    []string
+}
+
+// SeedDemo makes sure the demo user exists and is subscribed to the demo feeds. A feed
+// that cannot be subscribed to is logged and skipped, so one dead feed does not keep the
+// demo from starting.
+func SeedDemo(ctx context.Context, users UserServiceInterface, feeds FeedServiceInterface, account DemoAccount, log *slog.Logger) error {
+	userID, err := demoUserID(ctx, users, account)
+	if err != nil {
+		return err
+	}
+
+	subscribed := 0
+	for _, url := range account.Feeds {
+		if _, err := feeds.SubscribeToFeed(ctx, userID, url); err != nil {
+			if status.Code(err) != codes.AlreadyExists {
+				log.Warn("failed to subscribe demo user to feed", "url", url, "error", err)
+			}
+			continue
+		}
+		subscribed++
+	}
+	log.Info("demo user ready", "username", account.Username, "user_id", userID, "feeds", len(account.Feeds), "new_subscriptions", subscribed)
+	return nil
+}
+
+// demoUserID registers the demo user, or finds the ID of the existing one
+func demoUserID(ctx context.Context, users UserServiceInterface, account DemoAccount) (uint, error) {
+	user, err := users.Register(account.Username, account.Password)
+	if err == nil {
+		return user.ID, nil
+	}
+	if !ierr.IsAlreadyExists(err) {
+		return 0, fmt.Errorf("register demo user: %w", err)
+	}
+
+	// Only a login reveals the ID of an existing user; its session is closed right away
+	tokens, err := users.Login(account.Username, account.Password, models.SessionClient{UserAgent: "phoenix-rss demo setup"})
+	if err != nil {
+		return 0, fmt.Errorf("demo user '%s' exists with another password: %w", account.Username, err)
+	}
+	token := tokens.AccessToken
+	user, err = users.GetUserFromToken(token)
+	if err != nil {
+		return 0, fmt.Errorf("look up demo user: %w", err)
+	}
+	if parsed, err := users.ValidateToken(token); err == nil {
+		if claims, ok := parsed.Claims.(jwt.MapClaims); ok {
+			if sessionID, _ := claims["sid"].(string); sessionID != "" {
+				_ = users.RevokeSession(ctx, user.ID, sessionID)
+			}
+		}
+	}
+	return user.ID, nil
+}
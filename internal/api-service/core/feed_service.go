@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
 	feedpb "github.com/Fancu1/phoenix-rss/protos/gen/go/feed"
 )
 
@@ -19,10 +21,32 @@ type BatchSubscribeResult struct {
 	Feed    *models.Feed
 }
 
+// AdminFeedFilter selects feeds for administration; the zero value matches every feed
+type AdminFeedFilter struct {
+	Status models.FeedStatus
+	// ErrorContains matches the last fetch error, ignoring case, e.g. "503" or "timeout"
+	ErrorContains string
+	// NotFetchedSince keeps feeds never fetched or last fetched before this time
+	NotFetchedSince *time.Time
+	Tier            models.FeedTier
+	FeedIDs         []uint
+}
+
+// AdminFeed is a feed as administrators list it
+type AdminFeed struct {
+	*models.Feed
+}
+
 type FeedServiceInterface interface {
 	ListAllFeeds(ctx context.Context) ([]*models.Feed, error)
 	SubscribeToFeed(ctx context.Context, userID uint, url string) (*models.Feed, error)
 	BatchSubscribeToFeeds(ctx context.Context, userID uint, urls []string) (results []BatchSubscribeResult, imported, failed int, err error)
+	ExistsByURLs(ctx context.Context, urls []string) (map[string]bool, error)
+	UpdateSubscription(ctx context.Context, userID, feedID uint, update models.SubscriptionUpdate) error
+	GetFeedHealth(ctx context.Context, userID, feedID uint) (*models.FeedHealth, error)
+	DeleteFeed(ctx context.Context, feedID uint, retention models.FeedRetention) (*models.FeedDeletion, error)
+	ListAdminFeeds(ctx context.Context, filter AdminFeedFilter, pageSize int, pageToken string) (feeds []*AdminFeed, nextPageToken string, total int64, err error)
+	BulkUpdateFeeds(ctx context.Context, filter AdminFeedFilter, update models.FeedBulkUpdate) (*models.FeedBulkResult, error)
 }
 
 type FeedServiceClient struct {
@@ -30,8 +54,12 @@ type FeedServiceClient struct {
 	conn   *grpc.ClientConn
 }
 
-func NewFeedServiceClient(address string) (*FeedServiceClient, error) {
-	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewFeedServiceClient(address string, opts ...grpc.DialOption) (*FeedServiceClient, error) {
+	conn, err := grpc.NewClient(address, append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(logger.RequestIDClientInterceptor()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}, opts...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Feed Service at %s: %w", address, err)
 	}
@@ -103,6 +131,145 @@ func (c *FeedServiceClient) BatchSubscribeToFeeds(ctx context.Context, userID ui
 	return results, int(resp.Imported), int(resp.Failed), nil
 }
 
+// ExistsByURLs tells which of the URLs a feed already exists for on the instance, in one
+// call to the feed service
+func (c *FeedServiceClient) ExistsByURLs(ctx context.Context, urls []string) (map[string]bool, error) {
+	exists := make(map[string]bool)
+	if len(urls) == 0 {
+		return exists, nil
+	}
+	resp, err := c.client.ExistsByURLs(ctx, &feedpb.ExistsByURLsRequest{Urls: urls})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+	for _, url := range resp.ExistingUrls {
+		exists[url] = true
+	}
+	return exists, nil
+}
+
+// UpdateSubscription changes subscription settings through the feed service, which owns
+// the key that encrypts custom fetch headers
+func (c *FeedServiceClient) UpdateSubscription(ctx context.Context, userID, feedID uint, update models.SubscriptionUpdate) error {
+	req := &feedpb.UpdateSubscriptionRequest{
+		UserId:      uint64(userID),
+		FeedId:      uint64(feedID),
+		CustomTitle: update.CustomTitle,
+		Notes:       update.Notes,
+	}
+	if update.FetchHeaders != nil {
+		req.FetchHeaders = &feedpb.FetchHeaders{Headers: update.FetchHeaders}
+	}
+
+	if _, err := c.client.UpdateSubscription(ctx, req); err != nil {
+		return MapGRPCError(err)
+	}
+	return nil
+}
+
+// GetFeedHealth reports the fetch status of a feed the user subscribes to
+func (c *FeedServiceClient) GetFeedHealth(ctx context.Context, userID, feedID uint) (*models.FeedHealth, error) {
+	resp, err := c.client.GetFeedHealth(ctx, &feedpb.GetFeedHealthRequest{UserId: uint64(userID), FeedId: uint64(feedID)})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+	return convertPbToFeedHealth(resp.Health)
+}
+
+// DeleteFeed removes a feed for every subscriber through the feed service. An empty
+// retention uses the feed service's configured default.
+func (c *FeedServiceClient) DeleteFeed(ctx context.Context, feedID uint, retention models.FeedRetention) (*models.FeedDeletion, error) {
+	resp, err := c.client.DeleteFeed(ctx, &feedpb.DeleteFeedRequest{
+		FeedId:    uint64(feedID),
+		Retention: string(retention),
+	})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+
+	deletion := &models.FeedDeletion{
+		FeedID:         feedID,
+		Retention:      models.FeedRetention(resp.Retention),
+		UserIDs:        make([]uint, len(resp.UnsubscribedUserIds)),
+		ArticlesPurged: resp.ArticlesPurged,
+	}
+	for i, userID := range resp.UnsubscribedUserIds {
+		deletion.UserIDs[i] = uint(userID)
+	}
+	return deletion, nil
+}
+
+// ListAdminFeeds returns one page of the feeds matching filter, in ID order, with the
+// number of matching feeds and the token of the next page ("" on the last page)
+func (c *FeedServiceClient) ListAdminFeeds(ctx context.Context, filter AdminFeedFilter, pageSize int, pageToken string) ([]*AdminFeed, string, int64, error) {
+	req := &feedpb.ListAllFeedsRequest{
+		PageSize:        uint32(pageSize),
+		PageToken:       pageToken,
+		Status:          string(filter.Status),
+		ErrorContains:   filter.ErrorContains,
+		NotFetchedSince: formatOptionalTime(filter.NotFetchedSince),
+		FetchTier:       string(filter.Tier),
+		FeedIds:         feedIDsToPb(filter.FeedIDs),
+		IncludeTotal:    true,
+	}
+	resp, err := c.client.ListAllFeeds(ctx, req)
+	if err != nil {
+		return nil, "", 0, MapGRPCError(err)
+	}
+
+	feeds := make([]*AdminFeed, len(resp.Feeds))
+	for i, pbFeed := range resp.Feeds {
+		feed, err := c.convertPbToFeed(pbFeed)
+		if err != nil {
+			return nil, "", 0, fmt.Errorf("failed to convert feed %d: %w", pbFeed.Id, err)
+		}
+		feeds[i] = &AdminFeed{Feed: feed}
+	}
+	return feeds, resp.NextPageToken, int64(resp.Total), nil
+}
+
+// BulkUpdateFeeds applies an administrator's action to every feed matching filter
+func (c *FeedServiceClient) BulkUpdateFeeds(ctx context.Context, filter AdminFeedFilter, update models.FeedBulkUpdate) (*models.FeedBulkResult, error) {
+	resp, err := c.client.BulkUpdateFeeds(ctx, &feedpb.BulkUpdateFeedsRequest{
+		Action:          string(update.Action),
+		Status:          string(filter.Status),
+		ErrorContains:   filter.ErrorContains,
+		NotFetchedSince: formatOptionalTime(filter.NotFetchedSince),
+		FetchTier:       string(filter.Tier),
+		FeedIds:         feedIDsToPb(filter.FeedIDs),
+		TargetTier:      string(update.Tier),
+		DryRun:          update.DryRun,
+	})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+	return &models.FeedBulkResult{
+		Action:        update.Action,
+		DryRun:        update.DryRun,
+		Matched:       int64(resp.Matched),
+		Updated:       int64(resp.Updated),
+		FetchesQueued: int64(resp.FetchesQueued),
+	}, nil
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func feedIDsToPb(feedIDs []uint) []uint64 {
+	if len(feedIDs) == 0 {
+		return nil
+	}
+	ids := make([]uint64, len(feedIDs))
+	for i, feedID := range feedIDs {
+		ids[i] = uint64(feedID)
+	}
+	return ids
+}
+
 func (c *FeedServiceClient) convertPbToFeed(pbFeed *feedpb.Feed) (*models.Feed, error) {
 	createdAt, err := time.Parse(time.RFC3339, pbFeed.CreatedAt)
 	if err != nil {
@@ -114,13 +281,46 @@ func (c *FeedServiceClient) convertPbToFeed(pbFeed *feedpb.Feed) (*models.Feed,
 		return nil, fmt.Errorf("failed to parse updated_at: %w", err)
 	}
 
-	return &models.Feed{
-		ID:          uint(pbFeed.Id),
-		Title:       pbFeed.Title,
-		URL:         pbFeed.Url,
-		Description: pbFeed.Description,
-		Status:      models.FeedStatus(pbFeed.Status),
-		CreatedAt:   createdAt,
-		UpdatedAt:   updatedAt,
-	}, nil
+	feed := &models.Feed{
+		ID:                  uint(pbFeed.Id),
+		Title:               pbFeed.Title,
+		URL:                 pbFeed.Url,
+		Description:         pbFeed.Description,
+		Status:              models.FeedStatus(pbFeed.Status),
+		CreatedAt:           createdAt,
+		UpdatedAt:           updatedAt,
+		FetchTier:           models.FeedTier(pbFeed.FetchTier),
+		LastFetchError:      optionalString(pbFeed.LastFetchError),
+		ConsecutiveFailures: int(pbFeed.ConsecutiveFailures),
+		SiteURL:             pbFeed.SiteUrl,
+		DatesUnreliable:     pbFeed.DatesUnreliable,
+		SubscriberCount:     int64(pbFeed.SubscriberCount),
+	}
+	if pbFeed.LastFetchedAt != "" {
+		lastFetchedAt, err := time.Parse(time.RFC3339, pbFeed.LastFetchedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse last_fetched_at: %w", err)
+		}
+		feed.LastFetchedAt = &lastFetchedAt
+	}
+	return feed, nil
+}
+
+func convertPbToFeedHealth(pb *feedpb.FeedHealth) (*models.FeedHealth, error) {
+	health := &models.FeedHealth{
+		FeedID:              uint(pb.FeedId),
+		Status:              models.FeedStatus(pb.Status),
+		LastError:           optionalString(pb.LastFetchError),
+		ConsecutiveFailures: int(pb.ConsecutiveFailures),
+		FailureThreshold:    int(pb.FailureThreshold),
+		DatesUnreliable:     pb.DatesUnreliable,
+	}
+	var err error
+	if health.LastFetchedAt, err = optionalTime(pb.LastFetchedAt); err != nil {
+		return nil, fmt.Errorf("failed to parse last_fetched_at: %w", err)
+	}
+	if health.LastErrorAt, err = optionalTime(pb.LastFetchErrorAt); err != nil {
+		return nil, fmt.Errorf("failed to parse last_fetch_error_at: %w", err)
+	}
+	return health, nil
 }
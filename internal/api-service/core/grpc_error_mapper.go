@@ -19,6 +19,9 @@ func MapGRPCError(err error) error {
 
 	switch st.Code() {
 	case codes.InvalidArgument:
+		if st.Message() == ierr.ErrInvalidResetToken.Message {
+			return ierr.ErrInvalidResetToken
+		}
 		return ierr.NewValidationError(st.Message())
 	case codes.Unauthenticated:
 		// check the message to determine specific error type
@@ -34,6 +37,9 @@ func MapGRPCError(err error) error {
 		}
 		return ierr.ErrUnauthorized.WithCause(fmt.Errorf(msg))
 	case codes.AlreadyExists:
+		if st.Message() == ierr.ErrEmailTaken.Message {
+			return ierr.ErrEmailTaken
+		}
 		return ierr.ErrUserExists.WithCause(fmt.Errorf(st.Message()))
 	case codes.NotFound:
 		switch st.Message() {
@@ -43,16 +49,39 @@ func MapGRPCError(err error) error {
 			return ierr.ErrFeedNotFound
 		case "User not found":
 			return ierr.ErrUserNotFound
+		case "LLM credential not found":
+			return ierr.ErrCredentialNotFound
+		case "Session not found":
+			return ierr.ErrSessionNotFound
+		case ierr.ErrFolderNotFound.Message:
+			return ierr.ErrFolderNotFound
 		default:
 			return ierr.ErrInternalServer.WithCause(fmt.Errorf(st.Message()))
 		}
 	case codes.PermissionDenied:
-		if st.Message() == "Not subscribed to this feed" {
+		switch st.Message() {
+		case "Not subscribed to this feed":
 			return ierr.ErrNotSubscribed
+		case ierr.ErrWrongPassword.Message:
+			return ierr.ErrWrongPassword
 		}
 		return ierr.ErrUnauthorized.WithCause(fmt.Errorf(st.Message()))
+	case codes.ResourceExhausted:
+		if st.Message() == ierr.ErrSubscriptionLimit.Message {
+			return ierr.ErrSubscriptionLimit
+		}
+		return ierr.ErrInternalServer.WithCause(fmt.Errorf(st.Message()))
+	case codes.FailedPrecondition:
+		for _, upstream := range ierr.UpstreamErrors {
+			if st.Message() == upstream.Message {
+				return upstream
+			}
+		}
+		return ierr.ErrInternalServer.WithCause(fmt.Errorf(st.Message()))
 	case codes.Internal:
 		return ierr.ErrInternalServer.WithCause(fmt.Errorf(st.Message()))
+	case codes.Unavailable:
+		return ierr.ErrServiceUnavailable.WithCause(fmt.Errorf(st.Message()))
 	default:
 		return ierr.ErrInternalServer.WithCause(fmt.Errorf("gRPC error: %v", err))
 	}
@@ -0,0 +1,33 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+func TestMapGRPCError_UpstreamErrors(t *testing.T) {
+	for _, upstream := range ierr.UpstreamErrors {
+		err := MapGRPCError(status.Error(codes.FailedPrecondition, upstream.Message))
+		if !errors.Is(err, upstream) {
+			t.Errorf("expected %q to map back to code %d, got %v", upstream.Message, upstream.Code, err)
+		}
+	}
+
+	var appErr *ierr.AppError
+	err := MapGRPCError(status.Error(codes.FailedPrecondition, "something else"))
+	if !errors.As(err, &appErr) || appErr.Code != ierr.ErrInternalServer.Code {
+		t.Errorf("expected an unknown precondition to map to an internal error, got %v", err)
+	}
+}
+
+func TestMapGRPCError_FolderNotFound(t *testing.T) {
+	err := MapGRPCError(status.Error(codes.NotFound, ierr.ErrFolderNotFound.Message))
+	if !errors.Is(err, ierr.ErrFolderNotFound) {
+		t.Errorf("expected a missing folder to map back to code %d, got %v", ierr.ErrFolderNotFound.Code, err)
+	}
+}
@@ -0,0 +1,236 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+// lockoutMemory is how long past lockouts count towards the length of the next one
+const lockoutMemory = 24 * time.Hour
+
+const (
+	loginFailuresKeyPattern = "login:%s:%s:failures"
+	loginLockKeyPattern     = "login:%s:%s:lock"
+	loginLockoutsKeyPattern = "login:%s:%s:lockouts"
+)
+
+// LoginAttemptStore keeps the counters and locks of the LoginGuard
+type LoginAttemptStore interface {
+	// Increment adds one to the counter at key, which expires ttl after its first increment
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	Get(ctx context.Context, key string) (int64, error)
+	// Lock sets key for ttl; Remaining tells how long it still has, 0 once it expired
+	Lock(ctx context.Context, key string, ttl time.Duration) error
+	Remaining(ctx context.Context, key string) (time.Duration, error)
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// LoginChallenge verifies the answer to a challenge, such as a CAPTCHA, that a client
+// is asked to solve after repeated failed logins
+type LoginChallenge interface {
+	Verify(ctx context.Context, response, remoteIP string) (bool, error)
+}
+
+// LoginGuardConfig sets when failed logins lock an account or a client IP out
+type LoginGuardConfig struct {
+	// MaxAccountFailures and MaxIPFailures are the failures within FailureWindow that
+	// start a lockout of the username or the IP
+	MaxAccountFailures int
+	MaxIPFailures      int
+	FailureWindow      time.Duration
+	// ChallengeAfter is the account failures after which a challenge must be solved,
+	// when a LoginChallenge is set; 0 disables it
+	ChallengeAfter int
+	// BaseLockout doubles with every lockout within a day, up to MaxLockout
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+// LoginLockedError is returned for attempts during a lockout
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("login locked for another %s", e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return ierr.ErrLoginLocked
+}
+
+// LoginGuard tracks failed logins per username and per client IP and locks them out for
+// a while when they pile up. Each new lockout within a day lasts twice as long.
+type LoginGuard struct {
+	store     LoginAttemptStore
+	config    LoginGuardConfig
+	challenge LoginChallenge
+}
+
+func NewLoginGuard(store LoginAttemptStore, config LoginGuardConfig) *LoginGuard {
+	return &LoginGuard{store: store, config: config}
+}
+
+// SetChallenge asks clients to solve a challenge after config.ChallengeAfter failures
+func (g *LoginGuard) SetChallenge(challenge LoginChallenge) {
+	g.challenge = challenge
+}
+
+// Check refuses an attempt while the username or the IP is locked out, with a
+// *LoginLockedError, or when a required challenge was not solved, with
+// ierr.ErrChallengeRequired
+func (g *LoginGuard) Check(ctx context.Context, username, ip, challengeResponse string) error {
+	username = normalizeLoginUsername(username)
+	for _, subject := range [][2]string{{"user", username}, {"ip", ip}} {
+		remaining, err := g.store.Remaining(ctx, fmt.Sprintf(loginLockKeyPattern, subject[0], subject[1]))
+		if err != nil {
+			return fmt.Errorf("check login lock: %w", err)
+		}
+		if remaining > 0 {
+			return &LoginLockedError{RetryAfter: remaining}
+		}
+	}
+
+	if g.challenge == nil || g.config.ChallengeAfter <= 0 {
+		return nil
+	}
+	failures, err := g.store.Get(ctx, fmt.Sprintf(loginFailuresKeyPattern, "user", username))
+	if err != nil {
+		return fmt.Errorf("check login failures: %w", err)
+	}
+	if failures < int64(g.config.ChallengeAfter) {
+		return nil
+	}
+	if challengeResponse == "" {
+		return fmt.Errorf("%d failed logins for '%s': %w", failures, username, ierr.ErrChallengeRequired)
+	}
+	solved, err := g.challenge.Verify(ctx, challengeResponse, ip)
+	if err != nil {
+		return fmt.Errorf("verify login challenge: %w", ierr.ErrChallengeRequired.WithCause(err))
+	}
+	if !solved {
+		return fmt.Errorf("wrong challenge response for '%s': %w", username, ierr.ErrChallengeRequired)
+	}
+	return nil
+}
+
+// ChallengeRequired tells whether the next attempt for username must solve a challenge
+func (g *LoginGuard) ChallengeRequired(ctx context.Context, username string) bool {
+	if g.challenge == nil || g.config.ChallengeAfter <= 0 {
+		return false
+	}
+	failures, err := g.store.Get(ctx, fmt.Sprintf(loginFailuresKeyPattern, "user", normalizeLoginUsername(username)))
+	return err == nil && failures >= int64(g.config.ChallengeAfter)
+}
+
+// RecordFailure counts a failed login and returns the lockout it started, if any
+func (g *LoginGuard) RecordFailure(ctx context.Context, username, ip string) (time.Duration, error) {
+	var lockout time.Duration
+	for _, subject := range []struct {
+		kind, id string
+		max      int
+	}{
+		{"user", normalizeLoginUsername(username), g.config.MaxAccountFailures},
+		{"ip", ip, g.config.MaxIPFailures},
+	} {
+		if subject.max <= 0 || subject.id == "" {
+			continue
+		}
+		failuresKey := fmt.Sprintf(loginFailuresKeyPattern, subject.kind, subject.id)
+		failures, err := g.store.Increment(ctx, failuresKey, g.config.FailureWindow)
+		if err != nil {
+			return lockout, fmt.Errorf("count failed login: %w", err)
+		}
+		if failures < int64(subject.max) {
+			continue
+		}
+
+		lockouts, err := g.store.Increment(ctx, fmt.Sprintf(loginLockoutsKeyPattern, subject.kind, subject.id), lockoutMemory)
+		if err != nil {
+			return lockout, fmt.Errorf("count lockout: %w", err)
+		}
+		duration := g.lockoutDuration(lockouts)
+		if err := g.store.Lock(ctx, fmt.Sprintf(loginLockKeyPattern, subject.kind, subject.id), duration); err != nil {
+			return lockout, fmt.Errorf("lock login: %w", err)
+		}
+		// the failures start over once the lockout is served
+		if err := g.store.Delete(ctx, failuresKey); err != nil {
+			return lockout, fmt.Errorf("reset failed logins: %w", err)
+		}
+		lockout = max(lockout, duration)
+	}
+	return lockout, nil
+}
+
+// RecordSuccess forgets the username's failures and past lockouts. The IP's are kept, so
+// logging into one account does not clear the way for guessing at others.
+func (g *LoginGuard) RecordSuccess(ctx context.Context, username string) error {
+	username = normalizeLoginUsername(username)
+	return g.store.Delete(ctx,
+		fmt.Sprintf(loginFailuresKeyPattern, "user", username),
+		fmt.Sprintf(loginLockoutsKeyPattern, "user", username))
+}
+
+// lockoutDuration is the length of the nth lockout within lockoutMemory
+func (g *LoginGuard) lockoutDuration(n int64) time.Duration {
+	duration := g.config.BaseLockout
+	for i := int64(1); i < n && duration < g.config.MaxLockout; i++ {
+		duration *= 2
+	}
+	return min(duration, g.config.MaxLockout)
+}
+
+func normalizeLoginUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// RedisLoginAttemptStore keeps login attempts in Redis, shared by all api-service replicas
+type RedisLoginAttemptStore struct {
+	client redis.Cmdable
+}
+
+func NewRedisLoginAttemptStore(client redis.Cmdable) *RedisLoginAttemptStore {
+	return &RedisLoginAttemptStore{client: client}
+}
+
+func (s *RedisLoginAttemptStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	pipe := s.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (s *RedisLoginAttemptStore) Get(ctx context.Context, key string) (int64, error) {
+	value, err := s.client.Get(ctx, key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return value, err
+}
+
+func (s *RedisLoginAttemptStore) Lock(ctx context.Context, key string, ttl time.Duration) error {
+	return s.client.Set(ctx, key, 1, ttl).Err()
+}
+
+func (s *RedisLoginAttemptStore) Remaining(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := s.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// PTTL is negative for missing keys and keys without expiry
+	return max(ttl, 0), nil
+}
+
+func (s *RedisLoginAttemptStore) Delete(ctx context.Context, keys ...string) error {
+	return s.client.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+// memoryAttemptStore is a LoginAttemptStore on a fake clock
+type memoryAttemptStore struct {
+	now     time.Time
+	values  map[string]int64
+	expires map[string]time.Time
+}
+
+func newMemoryAttemptStore() *memoryAttemptStore {
+	return &memoryAttemptStore{now: time.Unix(0, 0), values: map[string]int64{}, expires: map[string]time.Time{}}
+}
+
+func (s *memoryAttemptStore) expire(key string) {
+	if at, ok := s.expires[key]; ok && !s.now.Before(at) {
+		delete(s.values, key)
+		delete(s.expires, key)
+	}
+}
+
+func (s *memoryAttemptStore) Increment(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	s.expire(key)
+	s.values[key]++
+	if _, ok := s.expires[key]; !ok {
+		s.expires[key] = s.now.Add(ttl)
+	}
+	return s.values[key], nil
+}
+
+func (s *memoryAttemptStore) Get(_ context.Context, key string) (int64, error) {
+	s.expire(key)
+	return s.values[key], nil
+}
+
+func (s *memoryAttemptStore) Lock(_ context.Context, key string, ttl time.Duration) error {
+	s.values[key] = 1
+	s.expires[key] = s.now.Add(ttl)
+	return nil
+}
+
+func (s *memoryAttemptStore) Remaining(_ context.Context, key string) (time.Duration, error) {
+	s.expire(key)
+	if _, ok := s.values[key]; !ok {
+		return 0, nil
+	}
+	return s.expires[key].Sub(s.now), nil
+}
+
+func (s *memoryAttemptStore) Delete(_ context.Context, keys ...string) error {
+	for _, key := range keys {
+		delete(s.values, key)
+		delete(s.expires, key)
+	}
+	return nil
+}
+
+type fixedChallenge string
+
+func (c fixedChallenge) Verify(_ context.Context, response, _ string) (bool, error) {
+	return response == string(c), nil
+}
+
+var testGuardConfig = LoginGuardConfig{
+	MaxAccountFailures: 3,
+	MaxIPFailures:      10,
+	FailureWindow:      15 * time.Minute,
+	BaseLockout:        time.Minute,
+	MaxLockout:         3 * time.Minute,
+}
+
+func failLogins(t *testing.T, guard *LoginGuard, username, ip string, n int) time.Duration {
+	t.Helper()
+	var lockout time.Duration
+	for i := 0; i < n; i++ {
+		var err error
+		if lockout, err = guard.RecordFailure(context.Background(), username, ip); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+	}
+	return lockout
+}
+
+func TestLoginGuard_ExponentialLockout(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryAttemptStore()
+	guard := NewLoginGuard(store, testGuardConfig)
+
+	if lockout := failLogins(t, guard, "dave", "10.0.0.1", 2); lockout != 0 {
+		t.Fatalf("locked out after 2 failures for %s", lockout)
+	}
+	if err := guard.Check(ctx, "dave", "10.0.0.1", ""); err != nil {
+		t.Fatalf("Check() before the limit = %v", err)
+	}
+
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		lockout := failLogins(t, guard, " Alice ", "10.0.0.2", testGuardConfig.MaxAccountFailures)
+		if lockout != want {
+			t.Fatalf("lockout = %s, want %s", lockout, want)
+		}
+		var locked *LoginLockedError
+		err := guard.Check(ctx, "alice", "10.0.0.3", "")
+		if !errors.As(err, &locked) || locked.RetryAfter != want || !errors.Is(err, ierr.ErrLoginLocked) {
+			t.Fatalf("Check() during lockout = %v, want a lock of %s", err, want)
+		}
+		store.now = store.now.Add(want)
+		if err := guard.Check(ctx, "alice", "10.0.0.3", ""); err != nil {
+			t.Fatalf("Check() after the lockout = %v", err)
+		}
+	}
+
+	if err := guard.RecordSuccess(ctx, "alice"); err != nil {
+		t.Fatalf("RecordSuccess() error = %v", err)
+	}
+	if lockout := failLogins(t, guard, "alice", "10.0.0.2", testGuardConfig.MaxAccountFailures); lockout != time.Minute {
+		t.Errorf("lockout after a successful login = %s, want the base lockout again", lockout)
+	}
+}
+
+func TestLoginGuard_IPLockout(t *testing.T) {
+	ctx := context.Background()
+	guard := NewLoginGuard(newMemoryAttemptStore(), testGuardConfig)
+
+	// spreading the guesses over many usernames still locks the IP out
+	for i := 0; i < testGuardConfig.MaxIPFailures; i++ {
+		failLogins(t, guard, string(rune('a'+i)), "10.0.0.9", 1)
+	}
+	if err := guard.Check(ctx, "zed", "10.0.0.9", ""); !errors.Is(err, ierr.ErrLoginLocked) {
+		t.Errorf("Check() from the guessing IP = %v, want locked", err)
+	}
+	if err := guard.Check(ctx, "zed", "10.0.0.10", ""); err != nil {
+		t.Errorf("Check() from another IP = %v", err)
+	}
+}
+
+func TestLoginGuard_Challenge(t *testing.T) {
+	ctx := context.Background()
+	config := testGuardConfig
+	config.ChallengeAfter = 2
+	guard := NewLoginGuard(newMemoryAttemptStore(), config)
+
+	failLogins(t, guard, "bob", "10.0.0.1", 2)
+	if err := guard.Check(ctx, "bob", "10.0.0.1", ""); err != nil {
+		t.Fatalf("Check() without a challenge configured = %v", err)
+	}
+
+	guard.SetChallenge(fixedChallenge("solved"))
+	if !guard.ChallengeRequired(ctx, "bob") {
+		t.Error("ChallengeRequired() = false after 2 failures")
+	}
+	if err := guard.Check(ctx, "bob", "10.0.0.1", ""); !errors.Is(err, ierr.ErrChallengeRequired) {
+		t.Errorf("Check() without a response = %v, want a challenge", err)
+	}
+	if err := guard.Check(ctx, "bob", "10.0.0.1", "guess"); !errors.Is(err, ierr.ErrChallengeRequired) {
+		t.Errorf("Check() with a wrong response = %v, want a challenge", err)
+	}
+	if err := guard.Check(ctx, "bob", "10.0.0.1", "solved"); err != nil {
+		t.Errorf("Check() with the right response = %v", err)
+	}
+	if guard.ChallengeRequired(ctx, "carol") {
+		t.Error("ChallengeRequired() = true for a user without failures")
+	}
+}
@@ -3,18 +3,29 @@ package core
 import (
 	"encoding/xml"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
 )
 
+// PhoenixNamespace is the XML namespace of the extension attributes that carry Phoenix RSS
+// subscription settings plain OPML has no place for. Other readers ignore them.
+const PhoenixNamespace = "https://github.com/Fancu1/phoenix-rss/opml"
+
+// phoenixPrefix is the namespace prefix used in generated documents
+const phoenixPrefix = "phoenix"
+
 // OPML represents the root element of an OPML document.
 type OPML struct {
 	XMLName xml.Name `xml:"opml"`
 	Version string   `xml:"version,attr"`
-	Head    OPMLHead `xml:"head"`
-	Body    OPMLBody `xml:"body"`
+	// PhoenixNS declares the extension namespace on export
+	PhoenixNS string   `xml:"xmlns:phoenix,attr,omitempty"`
+	Head      OPMLHead `xml:"head"`
+	Body      OPMLBody `xml:"body"`
 }
 
 // OPMLHead contains metadata about the OPML document.
@@ -36,13 +47,81 @@ type OPMLOutline struct {
 	Type     string        `xml:"type,attr,omitempty"`
 	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
 	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
-	Outlines []OPMLOutline `xml:"outline,omitempty"` // Nested outlines for folders
+	Comment  string        `xml:"comment,attr,omitempty"` // Subscription notes
+	Outlines []OPMLOutline `xml:"outline,omitempty"`      // Nested outlines for folders
+	// Extensions holds the phoenix:* attributes, and any other attribute on import
+	Extensions []xml.Attr `xml:",any,attr"`
 }
 
-// OPMLFeedItem represents a parsed feed from OPML for import preview.
+// extension returns the value of a phoenix:* attribute. Documents that use the prefix
+// without declaring the namespace are accepted too.
+func (o OPMLOutline) extension(name string) (string, bool) {
+	for _, attr := range o.Extensions {
+		if attr.Name.Local == name && (attr.Name.Space == PhoenixNamespace || attr.Name.Space == phoenixPrefix) {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// countExtension returns a phoenix:* attribute holding a count, nil when it is missing or
+// not a non-negative integer
+func (o OPMLOutline) countExtension(name string) *int64 {
+	value, ok := o.extension(name)
+	if !ok {
+		return nil
+	}
+	count, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || count < 0 {
+		return nil
+	}
+	return &count
+}
+
+func (o *OPMLOutline) setExtension(name, value string) {
+	o.Extensions = append(o.Extensions, xml.Attr{Name: xml.Name{Local: phoenixPrefix + ":" + name}, Value: value})
+}
+
+// OPMLFeedItem represents a parsed feed from OPML for import preview. CustomTitle and
+// Notes are applied to the new subscription on import, which is filed in Folder, the
+// names of the outlines it is nested in. UnreadHint and TotalHint carry the read-state
+// counts a Phoenix RSS export may include. The preview sets ExistsGlobally on feeds the
+// instance already has, which import without waiting for a first fetch.
 type OPMLFeedItem struct {
-	Title string `json:"title"`
-	URL   string `json:"url"`
+	Title          string   `json:"title"`
+	URL            string   `json:"url"`
+	ExistsGlobally bool     `json:"exists_globally"`
+	Folder         []string `json:"folder,omitempty"`
+	CustomTitle    *string  `json:"custom_title,omitempty"`
+	Notes          *string  `json:"notes,omitempty"`
+	UnreadHint     *int64   `json:"unread_hint,omitempty"`
+	TotalHint      *int64   `json:"total_hint,omitempty"`
+}
+
+// FolderPath returns the folder names of the item, checked and trimmed, nil when it is
+// not in a folder
+func (item OPMLFeedItem) FolderPath() ([]string, error) {
+	if len(item.Folder) > models.MaxFolderDepth {
+		return nil, fmt.Errorf("folders nest at most %d levels deep", models.MaxFolderDepth)
+	}
+	var path []string
+	for _, name := range item.Folder {
+		name, err := models.NormalizeFolderName(name)
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, name)
+	}
+	return path, nil
+}
+
+// SubscriptionUpdate returns the settings to apply after subscribing, nil when there are none
+func (item OPMLFeedItem) SubscriptionUpdate() *models.SubscriptionUpdate {
+	update := models.SubscriptionUpdate{CustomTitle: item.CustomTitle, Notes: item.Notes}
+	if update.IsEmpty() {
+		return nil
+	}
+	return &update
 }
 
 // OPMLParseResult contains the result of parsing an OPML file.
@@ -51,13 +130,21 @@ type OPMLParseResult struct {
 	Total int            `json:"total"`
 }
 
+// OPMLReadCounts is the read state of one feed's articles, exported as hints
+type OPMLReadCounts struct {
+	Unread int64
+	Total  int64
+}
+
 // OPMLImportResult contains the result of importing feeds from OPML.
 type OPMLImportResult struct {
-	Imported   int      `json:"imported"`
-	Skipped    int      `json:"skipped"`
-	Failed     int      `json:"failed"`
-	SkippedIDs []string `json:"skipped_urls,omitempty"`
-	FailedIDs  []string `json:"failed_urls,omitempty"`
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+	Failed   int `json:"failed"`
+	// ReadStateApplied counts the imported feeds whose unread hint was applied
+	ReadStateApplied int      `json:"read_state_applied"`
+	SkippedIDs       []string `json:"skipped_urls,omitempty"`
+	FailedIDs        []string `json:"failed_urls,omitempty"`
 }
 
 // OPMLService handles OPML parsing and generation.
@@ -70,33 +157,53 @@ func NewOPMLService() *OPMLService {
 
 // GenerateOPML creates an OPML document from a list of feeds.
 // Uses custom_title if set, otherwise falls back to the original feed title.
+// Feeds filed in a folder are nested in outlines named after the folder and its parents.
+// Subscription notes are exported in the outline's comment attribute, and a custom title
+// is also kept in phoenix:customTitle so an import can tell it from the feed's own title.
 func (s *OPMLService) GenerateOPML(feeds []*models.UserFeed, username string) ([]byte, error) {
+	return s.GenerateOPMLWithCounts(feeds, username, nil)
+}
+
+// GenerateOPMLWithCounts is GenerateOPML that also annotates each outline with the feed's
+// read-state counts, from counts by feed ID, in phoenix:unread and phoenix:total. Feeds
+// missing from counts have no articles yet and are annotated with zeros; a nil map
+// leaves the annotations out.
+func (s *OPMLService) GenerateOPMLWithCounts(feeds []*models.UserFeed, username string, counts map[uint]OPMLReadCounts) ([]byte, error) {
 	opml := OPML{
-		Version: "2.0",
+		Version:   "2.0",
+		PhoenixNS: PhoenixNamespace,
 		Head: OPMLHead{
 			Title:       "Phoenix RSS Subscriptions",
 			DateCreated: time.Now().Format(time.RFC1123),
 			OwnerName:   username,
 		},
-		Body: OPMLBody{
-			Outlines: make([]OPMLOutline, 0, len(feeds)),
-		},
 	}
 
+	root := &opmlFolder{}
 	for _, feed := range feeds {
 		// Use custom title if set, otherwise use original title
 		title := feed.Title
-		if feed.CustomTitle != nil && *feed.CustomTitle != "" {
-			title = *feed.CustomTitle
-		}
 		outline := OPMLOutline{
-			Text:   title,
-			Title:  title,
 			Type:   "rss",
 			XMLURL: feed.URL,
 		}
-		opml.Body.Outlines = append(opml.Body.Outlines, outline)
+		if feed.CustomTitle != nil && *feed.CustomTitle != "" {
+			title = *feed.CustomTitle
+			outline.setExtension("customTitle", title)
+		}
+		outline.Text = title
+		outline.Title = title
+		if feed.Notes != nil {
+			outline.Comment = *feed.Notes
+		}
+		if counts != nil {
+			count := counts[feed.ID]
+			outline.setExtension("unread", strconv.FormatInt(count.Unread, 10))
+			outline.setExtension("total", strconv.FormatInt(count.Total, 10))
+		}
+		root.add(feed.FolderPath, outline)
 	}
+	opml.Body.Outlines = root.outlines()
 
 	// Generate XML with proper formatting
 	output, err := xml.MarshalIndent(opml, "", "  ")
@@ -119,7 +226,7 @@ func (s *OPMLService) ParseOPML(data []byte) (*OPMLParseResult, error) {
 	}
 
 	feeds := make([]OPMLFeedItem, 0)
-	s.extractFeeds(opml.Body.Outlines, &feeds)
+	s.extractFeeds(opml.Body.Outlines, nil, &feeds)
 
 	return &OPMLParseResult{
 		Feeds: feeds,
@@ -128,8 +235,9 @@ func (s *OPMLService) ParseOPML(data []byte) (*OPMLParseResult, error) {
 }
 
 // extractFeeds recursively extracts feed items from OPML outlines.
-// This handles both flat and nested (categorized) OPML structures.
-func (s *OPMLService) extractFeeds(outlines []OPMLOutline, feeds *[]OPMLFeedItem) {
+// This handles both flat and nested (categorized) OPML structures; folder is the path of
+// the outlines enclosing these ones.
+func (s *OPMLService) extractFeeds(outlines []OPMLOutline, folder []string, feeds *[]OPMLFeedItem) {
 	for _, outline := range outlines {
 		// Check if this is a feed (has xmlUrl) or a folder
 		if outline.XMLURL != "" {
@@ -142,32 +250,85 @@ func (s *OPMLService) extractFeeds(outlines []OPMLOutline, feeds *[]OPMLFeedItem
 			if url == "" {
 				continue
 			}
-			*feeds = append(*feeds, OPMLFeedItem{
-				Title: title,
-				URL:   url,
-			})
+			item := OPMLFeedItem{
+				Title:  title,
+				URL:    url,
+				Folder: folder,
+			}
+			if customTitle, ok := outline.extension("customTitle"); ok && customTitle != "" {
+				item.CustomTitle = &customTitle
+			}
+			if notes := outline.Comment; notes != "" {
+				item.Notes = &notes
+			}
+			item.UnreadHint = outline.countExtension("unread")
+			item.TotalHint = outline.countExtension("total")
+			*feeds = append(*feeds, item)
 		}
 
-		// Recursively process nested outlines (folders/categories)
+		// Recursively process nested outlines (folders/categories). Outlines without a
+		// name add no level.
 		if len(outline.Outlines) > 0 {
-			s.extractFeeds(outline.Outlines, feeds)
+			name := strings.TrimSpace(outline.Title)
+			if name == "" {
+				name = strings.TrimSpace(outline.Text)
+			}
+			path := folder
+			if name != "" {
+				path = append(slices.Clip(folder), name)
+			}
+			s.extractFeeds(outline.Outlines, path, feeds)
 		}
 	}
 }
 
+// opmlFolder collects the outlines of one folder while a document is generated
+type opmlFolder struct {
+	name     string
+	feeds    []OPMLOutline
+	children []*opmlFolder
+}
+
+// add files outline under the folder at path, below f
+func (f *opmlFolder) add(path []string, outline OPMLOutline) {
+	folder := f
+	for _, name := range path {
+		i := slices.IndexFunc(folder.children, func(child *opmlFolder) bool { return child.name == name })
+		if i < 0 {
+			folder.children = append(folder.children, &opmlFolder{name: name})
+			i = len(folder.children) - 1
+		}
+		folder = folder.children[i]
+	}
+	folder.feeds = append(folder.feeds, outline)
+}
+
+// outlines returns the outlines of f: its folders in the order first seen, then its feeds
+func (f *opmlFolder) outlines() []OPMLOutline {
+	outlines := make([]OPMLOutline, 0, len(f.children)+len(f.feeds))
+	for _, child := range f.children {
+		outlines = append(outlines, OPMLOutline{
+			Text:     child.name,
+			Title:    child.name,
+			Outlines: child.outlines(),
+		})
+	}
+	return append(outlines, f.feeds...)
+}
+
 // FilterDuplicates removes feeds that already exist in the user's subscriptions.
 func (s *OPMLService) FilterDuplicates(parsedFeeds []OPMLFeedItem, existingFeeds []*models.UserFeed) (toImport []OPMLFeedItem, duplicates []OPMLFeedItem) {
 	existingURLs := make(map[string]bool)
 	for _, feed := range existingFeeds {
 		// Normalize URL for comparison
-		existingURLs[normalizeURL(feed.URL)] = true
+		existingURLs[NormalizeFeedURL(feed.URL)] = true
 	}
 
 	toImport = make([]OPMLFeedItem, 0)
 	duplicates = make([]OPMLFeedItem, 0)
 
 	for _, feed := range parsedFeeds {
-		normalizedURL := normalizeURL(feed.URL)
+		normalizedURL := NormalizeFeedURL(feed.URL)
 		if existingURLs[normalizedURL] {
 			duplicates = append(duplicates, feed)
 		} else {
@@ -180,11 +341,17 @@ func (s *OPMLService) FilterDuplicates(parsedFeeds []OPMLFeedItem, existingFeeds
 	return toImport, duplicates
 }
 
-// normalizeURL normalizes a URL for comparison purposes.
-func normalizeURL(url string) string {
+// MarkExisting sets ExistsGlobally on the items whose URL is in exists
+func (s *OPMLService) MarkExisting(items []OPMLFeedItem, exists map[string]bool) {
+	for i := range items {
+		items[i].ExistsGlobally = exists[items[i].URL]
+	}
+}
+
+// NormalizeFeedURL normalizes a feed URL for comparison purposes.
+func NormalizeFeedURL(url string) string {
 	url = strings.TrimSpace(url)
 	url = strings.ToLower(url)
 	url = strings.TrimSuffix(url, "/")
 	return url
 }
-
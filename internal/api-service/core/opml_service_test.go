@@ -1,6 +1,7 @@
 package core
 
 import (
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -23,7 +24,7 @@ func TestOPMLService_GenerateOPML(t *testing.T) {
 			username: "testuser",
 			want: []string{
 				`<?xml version="1.0" encoding="UTF-8"?>`,
-				`<opml version="2.0">`,
+				`<opml version="2.0" xmlns:phoenix="https://github.com/Fancu1/phoenix-rss/opml">`,
 				`<title>Phoenix RSS Subscriptions</title>`,
 				`<ownerName>testuser</ownerName>`,
 				`</opml>`,
@@ -68,6 +69,16 @@ func TestOPMLService_GenerateOPML(t *testing.T) {
 				`&#34;`,
 			},
 		},
+		{
+			name: "feed with notes",
+			feeds: []*models.UserFeed{
+				{Feed: models.Feed{ID: 1, Title: "Changelog", URL: "https://example.com/feed.xml"}, Notes: strPtr("watch for breaking changes")},
+			},
+			username: "testuser",
+			want: []string{
+				`comment="watch for breaking changes"`,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -131,9 +142,9 @@ func TestOPMLService_ParseOPML(t *testing.T) {
 </opml>`,
 			wantCount: 3,
 			wantFeeds: []OPMLFeedItem{
-				{Title: "TechCrunch", URL: "https://techcrunch.com/feed/"},
-				{Title: "Ars Technica", URL: "https://arstechnica.com/feed/"},
-				{Title: "BBC", URL: "https://bbc.com/feed/"},
+				{Title: "TechCrunch", URL: "https://techcrunch.com/feed/", Folder: []string{"Tech"}},
+				{Title: "Ars Technica", URL: "https://arstechnica.com/feed/", Folder: []string{"Tech"}},
+				{Title: "BBC", URL: "https://bbc.com/feed/", Folder: []string{"News"}},
 			},
 			wantErr: false,
 		},
@@ -215,6 +226,9 @@ func TestOPMLService_ParseOPML(t *testing.T) {
 				if got.URL != want.URL {
 					t.Errorf("ParseOPML() feed[%d].URL = %q, want %q", i, got.URL, want.URL)
 				}
+				if !slices.Equal(got.Folder, want.Folder) {
+					t.Errorf("ParseOPML() feed[%d].Folder = %q, want %q", i, got.Folder, want.Folder)
+				}
 			}
 		})
 	}
@@ -312,6 +326,19 @@ func TestOPMLService_FilterDuplicates(t *testing.T) {
 	}
 }
 
+func TestOPMLService_MarkExisting(t *testing.T) {
+	service := NewOPMLService()
+	items := []OPMLFeedItem{
+		{Title: "Known", URL: "https://example.com/feed.xml"},
+		{Title: "New", URL: "https://example.org/feed.xml"},
+	}
+
+	service.MarkExisting(items, map[string]bool{"https://example.com/feed.xml": true})
+	if !items[0].ExistsGlobally || items[1].ExistsGlobally {
+		t.Errorf("MarkExisting() = %+v, want only the known feed marked", items)
+	}
+}
+
 func TestOPMLService_ParseRealFeedlyExport(t *testing.T) {
 	service := NewOPMLService()
 
@@ -411,3 +438,147 @@ func TestOPMLService_RoundTrip(t *testing.T) {
 	}
 }
 
+func TestOPMLService_RoundTripFolders(t *testing.T) {
+	service := NewOPMLService()
+
+	feeds := []*models.UserFeed{
+		{Feed: models.Feed{ID: 1, Title: "Unfiled", URL: "https://example.com/feed.xml"}},
+		{Feed: models.Feed{ID: 2, Title: "Go Blog", URL: "https://go.dev/blog/feed.atom"}, FolderPath: []string{"Tech", "Go"}},
+		{Feed: models.Feed{ID: 3, Title: "Ars", URL: "https://arstechnica.com/feed/"}, FolderPath: []string{"Tech"}},
+		{Feed: models.Feed{ID: 4, Title: "Rust Blog", URL: "https://blog.rust-lang.org/feed.xml"}, FolderPath: []string{"Tech", "Rust"}},
+	}
+
+	opmlData, err := service.GenerateOPML(feeds, "testuser")
+	if err != nil {
+		t.Fatalf("GenerateOPML() error = %v", err)
+	}
+	if strings.Count(string(opmlData), `text="Tech"`) != 1 {
+		t.Errorf("GenerateOPML() should write the Tech folder once:\n%s", opmlData)
+	}
+
+	result, err := service.ParseOPML(opmlData)
+	if err != nil {
+		t.Fatalf("ParseOPML() error = %v", err)
+	}
+	folders := make(map[string][]string, len(result.Feeds))
+	for _, item := range result.Feeds {
+		folders[item.URL] = item.Folder
+	}
+	for _, feed := range feeds {
+		if got, ok := folders[feed.URL]; !ok || !slices.Equal(got, feed.FolderPath) {
+			t.Errorf("Round-trip %s folder = %q, want %q", feed.URL, got, feed.FolderPath)
+		}
+	}
+}
+
+func TestOPMLService_RoundTripSettings(t *testing.T) {
+	service := NewOPMLService()
+
+	feeds := []*models.UserFeed{
+		{Feed: models.Feed{ID: 1, Title: "Upstream Title", URL: "https://example.com/feed.xml"}, CustomTitle: strPtr("My Name"), Notes: strPtr("read weekly")},
+		{Feed: models.Feed{ID: 2, Title: "Plain", URL: "https://example.org/feed.xml"}},
+	}
+
+	opmlData, err := service.GenerateOPML(feeds, "testuser")
+	if err != nil {
+		t.Fatalf("GenerateOPML() error = %v", err)
+	}
+	if !strings.Contains(string(opmlData), `phoenix:customTitle="My Name"`) {
+		t.Errorf("GenerateOPML() output missing phoenix:customTitle\nGot: %s", opmlData)
+	}
+
+	result, err := service.ParseOPML(opmlData)
+	if err != nil {
+		t.Fatalf("ParseOPML() error = %v", err)
+	}
+	if len(result.Feeds) != 2 {
+		t.Fatalf("ParseOPML() got %d feeds, want 2", len(result.Feeds))
+	}
+
+	first := result.Feeds[0]
+	if first.CustomTitle == nil || *first.CustomTitle != "My Name" {
+		t.Errorf("feed[0].CustomTitle = %v, want %q", first.CustomTitle, "My Name")
+	}
+	if first.Notes == nil || *first.Notes != "read weekly" {
+		t.Errorf("feed[0].Notes = %v, want %q", first.Notes, "read weekly")
+	}
+	if update := first.SubscriptionUpdate(); update == nil || update.CustomTitle == nil {
+		t.Errorf("feed[0].SubscriptionUpdate() = %v, want the custom title", update)
+	}
+	if update := result.Feeds[1].SubscriptionUpdate(); update != nil {
+		t.Errorf("feed[1].SubscriptionUpdate() = %+v, want nil", update)
+	}
+}
+
+func TestOPMLService_ParseUndeclaredPhoenixPrefix(t *testing.T) {
+	service := NewOPMLService()
+
+	opmlData := `<?xml version="1.0"?>
+<opml version="2.0"><body>
+  <outline text="News" xmlUrl="https://example.com/feed.xml" phoenix:customTitle="Morning News" />
+</body></opml>`
+
+	result, err := service.ParseOPML([]byte(opmlData))
+	if err != nil {
+		t.Fatalf("ParseOPML() error = %v", err)
+	}
+	if got := result.Feeds[0].CustomTitle; got == nil || *got != "Morning News" {
+		t.Errorf("CustomTitle = %v, want %q", got, "Morning News")
+	}
+}
+
+func TestOPMLService_RoundTripReadCounts(t *testing.T) {
+	service := NewOPMLService()
+
+	feeds := []*models.UserFeed{
+		{Feed: models.Feed{ID: 1, Title: "Busy", URL: "https://example.com/feed.xml"}},
+		{Feed: models.Feed{ID: 2, Title: "Empty", URL: "https://example.org/feed.xml"}},
+	}
+
+	plain, err := service.GenerateOPML(feeds, "testuser")
+	if err != nil {
+		t.Fatalf("GenerateOPML() error = %v", err)
+	}
+	if strings.Contains(string(plain), "phoenix:unread") {
+		t.Errorf("GenerateOPML() output has counts nobody asked for\nGot: %s", plain)
+	}
+
+	opmlData, err := service.GenerateOPMLWithCounts(feeds, "testuser", map[uint]OPMLReadCounts{1: {Unread: 3, Total: 40}})
+	if err != nil {
+		t.Fatalf("GenerateOPMLWithCounts() error = %v", err)
+	}
+	if !strings.Contains(string(opmlData), `phoenix:unread="3" phoenix:total="40"`) {
+		t.Errorf("GenerateOPMLWithCounts() output missing the counts\nGot: %s", opmlData)
+	}
+
+	result, err := service.ParseOPML(opmlData)
+	if err != nil {
+		t.Fatalf("ParseOPML() error = %v", err)
+	}
+	first, second := result.Feeds[0], result.Feeds[1]
+	if first.UnreadHint == nil || *first.UnreadHint != 3 || first.TotalHint == nil || *first.TotalHint != 40 {
+		t.Errorf("feed[0] hints = %v/%v, want 3/40", first.UnreadHint, first.TotalHint)
+	}
+	if second.UnreadHint == nil || *second.UnreadHint != 0 || second.TotalHint == nil || *second.TotalHint != 0 {
+		t.Errorf("feed[1] hints = %v/%v, want 0/0", second.UnreadHint, second.TotalHint)
+	}
+}
+
+func TestOPMLService_ParseInvalidReadCounts(t *testing.T) {
+	service := NewOPMLService()
+
+	opmlData := `<?xml version="1.0"?>
+<opml version="2.0"><body>
+  <outline text="News" xmlUrl="https://example.com/feed.xml" phoenix:unread="-1" phoenix:total="many" />
+</body></opml>`
+
+	result, err := service.ParseOPML([]byte(opmlData))
+	if err != nil {
+		t.Fatalf("ParseOPML() error = %v", err)
+	}
+	if item := result.Feeds[0]; item.UnreadHint != nil || item.TotalHint != nil {
+		t.Errorf("hints = %v/%v, want them ignored", item.UnreadHint, item.TotalHint)
+	}
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,206 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// idempotentPrefixes start the names of the RPCs that read, or set a value, and so can be
+// sent again without changing the outcome
+var idempotentPrefixes = []string{"Get", "List", "Exists", "Check", "Validate", "Search", "Next", "Set"}
+
+// IdempotentMethod reports whether a call to the full gRPC method name (e.g.
+// "/feed.FeedService/ListArticles") can be retried
+func IdempotentMethod(fullMethod string) bool {
+	name := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	for _, prefix := range idempotentPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResilienceConfig tunes the retries and circuit breaker of the calls to one service
+type ResilienceConfig struct {
+	// MaxAttempts counts the first attempt of idempotent calls; 1 disables retries
+	MaxAttempts    int
+	BackoffInitial time.Duration
+	BackoffMax     time.Duration
+	// FailureThreshold consecutive failures open the breaker; 0 disables it
+	FailureThreshold int
+	// OpenTimeout is how long the breaker fails calls fast before letting a probe through
+	OpenTimeout time.Duration
+}
+
+// BreakerState is the state of a circuit breaker
+type BreakerState string
+
+const (
+	// BreakerClosed lets every call through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen fails calls without sending them
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets one probe through, whose outcome closes or reopens the breaker
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// ClientStats describes the calls made to one service since the process started
+type ClientStats struct {
+	Target              string       `json:"target"`
+	State               BreakerState `json:"state"`
+	StateSince          time.Time    `json:"state_since"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	Calls               int64        `json:"calls"`
+	Failures            int64        `json:"failures"`
+	Retries             int64        `json:"retries"`
+	// Rejected counts the calls the open breaker failed without sending
+	Rejected int64 `json:"rejected"`
+	// Opened counts how often the breaker opened
+	Opened int64 `json:"opened"`
+}
+
+// Resilience retries the idempotent calls to a service that fail with Unavailable, as when
+// it restarts, and stops sending calls while the service keeps failing. It is installed
+// on a connection with DialOption.
+type Resilience struct {
+	cfg    ResilienceConfig
+	logger *slog.Logger
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+
+	mu      sync.Mutex
+	stats   ClientStats
+	probing bool
+}
+
+func NewResilience(target string, cfg ResilienceConfig, logger *slog.Logger) *Resilience {
+	cfg.MaxAttempts = max(cfg.MaxAttempts, 1)
+	return &Resilience{
+		cfg:    cfg,
+		logger: logger.With("target", target),
+		now:    time.Now,
+		sleep:  sleepContext,
+		stats:  ClientStats{Target: target, State: BreakerClosed, StateSince: time.Now()},
+	}
+}
+
+// DialOption installs the retries and breaker on the calls made over a connection
+func (r *Resilience) DialOption() grpc.DialOption {
+	return grpc.WithChainUnaryInterceptor(r.Interceptor())
+}
+
+// Interceptor retries and guards unary calls. Calls the open breaker refuses fail with
+// Unavailable.
+func (r *Resilience) Interceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		attempts := 1
+		if IdempotentMethod(method) {
+			attempts = r.cfg.MaxAttempts
+		}
+
+		backoff := r.cfg.BackoffInitial
+		for attempt := 1; ; attempt++ {
+			if err := r.allow(); err != nil {
+				return err
+			}
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			r.record(ctx, err)
+			if err == nil || attempt >= attempts || status.Code(err) != codes.Unavailable || ctx.Err() != nil {
+				return err
+			}
+
+			r.mu.Lock()
+			r.stats.Retries++
+			r.mu.Unlock()
+			// wait between half and all of the backoff so callers do not retry in step
+			wait := backoff/2 + rand.N(backoff/2+1)
+			r.logger.Debug("retrying gRPC call", "method", method, "attempt", attempt+1, "wait", wait.String(), "error", err)
+			if err := r.sleep(ctx, wait); err != nil {
+				return status.FromContextError(err).Err()
+			}
+			backoff = min(backoff*2, r.cfg.BackoffMax)
+		}
+	}
+}
+
+// Stats returns the breaker state and call counters
+func (r *Resilience) Stats() ClientStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// allow refuses the call while the breaker is open, and lets a single probe through once
+// the open timeout has passed
+func (r *Resilience) allow() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stats.State == BreakerOpen && r.now().Sub(r.stats.StateSince) >= r.cfg.OpenTimeout {
+		r.transition(BreakerHalfOpen)
+	}
+	if r.stats.State == BreakerOpen || (r.stats.State == BreakerHalfOpen && r.probing) {
+		r.stats.Rejected++
+		return status.Errorf(codes.Unavailable, "%s is unavailable, circuit breaker open", r.stats.Target)
+	}
+	if r.stats.State == BreakerHalfOpen {
+		r.probing = true
+	}
+	r.stats.Calls++
+	return nil
+}
+
+// record updates the breaker with the outcome of a call. Only failures that tell the
+// service is unreachable or stuck count: an error it answered with shows it is up.
+func (r *Resilience) record(ctx context.Context, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	probe := r.probing
+	r.probing = false
+
+	code := status.Code(err)
+	switch {
+	case ctx.Err() != nil && (code == codes.Canceled || code == codes.DeadlineExceeded):
+		// the caller gave up; this says nothing about the service
+	case code == codes.Unavailable || code == codes.DeadlineExceeded:
+		r.stats.Failures++
+		r.stats.ConsecutiveFailures++
+		threshold := r.cfg.FailureThreshold
+		if threshold > 0 && (probe || (r.stats.State == BreakerClosed && r.stats.ConsecutiveFailures >= threshold)) {
+			r.stats.Opened++
+			r.transition(BreakerOpen)
+			r.logger.Warn("circuit breaker opened", "consecutive_failures", r.stats.ConsecutiveFailures, "open_timeout", r.cfg.OpenTimeout.String(), "error", err)
+		}
+	default:
+		r.stats.ConsecutiveFailures = 0
+		if r.stats.State != BreakerClosed {
+			r.transition(BreakerClosed)
+			r.logger.Info("circuit breaker closed")
+		}
+	}
+}
+
+func (r *Resilience) transition(state BreakerState) {
+	r.stats.State = state
+	r.stats.StateSince = r.now()
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+const (
+	readMethod  = "/feed.FeedService/ListArticles"
+	writeMethod = "/feed.FeedService/SubscribeToFeed"
+)
+
+// scriptedInvoker answers calls with errs in turn, then with success
+type scriptedInvoker struct {
+	errs  []error
+	calls int
+}
+
+func (s *scriptedInvoker) invoke(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+	s.calls++
+	if len(s.errs) == 0 {
+		return nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+func newTestResilience(cfg ResilienceConfig) (*Resilience, *time.Time, *[]time.Duration) {
+	now := time.Unix(0, 0)
+	var waits []time.Duration
+	r := NewResilience("feed-service", cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	r.now = func() time.Time { return now }
+	r.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return r, &now, &waits
+}
+
+func call(r *Resilience, invoker *scriptedInvoker, method string) error {
+	return r.Interceptor()(context.Background(), method, nil, nil, nil, invoker.invoke)
+}
+
+var errUnavailable = status.Error(codes.Unavailable, "connection refused")
+
+func TestIdempotentMethod(t *testing.T) {
+	for method, want := range map[string]bool{
+		"/feed.FeedService/ListArticles":      true,
+		"/feed.FeedService/GetFeedHealth":     true,
+		"/feed.FeedService/SetArticleRead":    true,
+		"/user.UserService/ValidateToken":     true,
+		"/feed.FeedService/SubscribeToFeed":   false,
+		"/feed.FeedService/DeleteFeed":        false,
+		"/user.UserService/Register":          false,
+		"/feed.FeedService/RegenerateSummary": false,
+	} {
+		if got := IdempotentMethod(method); got != want {
+			t.Errorf("IdempotentMethod(%s) = %v, want %v", method, got, want)
+		}
+	}
+}
+
+func TestResilience_RetriesIdempotentCalls(t *testing.T) {
+	r, _, waits := newTestResilience(ResilienceConfig{MaxAttempts: 4, BackoffInitial: 100 * time.Millisecond, BackoffMax: 150 * time.Millisecond})
+
+	invoker := &scriptedInvoker{errs: []error{errUnavailable, errUnavailable, errUnavailable}}
+	if err := call(r, invoker, readMethod); err != nil {
+		t.Fatalf("expected the fourth attempt to succeed, got %v", err)
+	}
+	if invoker.calls != 4 || len(*waits) != 3 {
+		t.Fatalf("expected 4 attempts and 3 waits, got %d and %v", invoker.calls, *waits)
+	}
+	for i, limit := range []time.Duration{100, 150, 150} {
+		if wait := (*waits)[i]; wait < limit*time.Millisecond/2 || wait > limit*time.Millisecond {
+			t.Errorf("wait %d = %s, expected between half and all of %dms", i, wait, limit)
+		}
+	}
+
+	invoker = &scriptedInvoker{errs: []error{errUnavailable}}
+	if err := call(r, invoker, writeMethod); status.Code(err) != codes.Unavailable || invoker.calls != 1 {
+		t.Errorf("non-idempotent calls must not be retried, got %d calls and %v", invoker.calls, err)
+	}
+
+	invoker = &scriptedInvoker{errs: []error{status.Error(codes.NotFound, "Feed not found")}}
+	if err := call(r, invoker, readMethod); status.Code(err) != codes.NotFound || invoker.calls != 1 {
+		t.Errorf("answers from the service must not be retried, got %d calls and %v", invoker.calls, err)
+	}
+
+	if stats := r.Stats(); stats.Retries != 3 || stats.Calls != 6 || stats.Failures != 4 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestResilience_CircuitBreaker(t *testing.T) {
+	r, now, _ := newTestResilience(ResilienceConfig{MaxAttempts: 1, FailureThreshold: 3, OpenTimeout: 10 * time.Second})
+
+	for i := 0; i < 3; i++ {
+		call(r, &scriptedInvoker{errs: []error{errUnavailable}}, readMethod)
+	}
+	if stats := r.Stats(); stats.State != BreakerOpen || stats.Opened != 1 {
+		t.Fatalf("expected the breaker to open after 3 failures, got %+v", stats)
+	}
+
+	invoker := &scriptedInvoker{}
+	err := call(r, invoker, readMethod)
+	if status.Code(err) != codes.Unavailable || invoker.calls != 0 {
+		t.Fatalf("an open breaker must fail calls without sending them, got %d calls and %v", invoker.calls, err)
+	}
+	var appErr *ierr.AppError
+	if !errors.As(MapGRPCError(err), &appErr) || appErr.Code != ierr.ErrServiceUnavailable.Code {
+		t.Errorf("expected the rejection to map to a 503, got %v", MapGRPCError(err))
+	}
+
+	// after the timeout a failed probe reopens the breaker
+	*now = now.Add(10 * time.Second)
+	call(r, &scriptedInvoker{errs: []error{errUnavailable}}, readMethod)
+	if stats := r.Stats(); stats.State != BreakerOpen || stats.Opened != 2 {
+		t.Fatalf("expected a failed probe to reopen the breaker, got %+v", stats)
+	}
+
+	// and a successful one closes it
+	*now = now.Add(10 * time.Second)
+	if err := call(r, &scriptedInvoker{}, writeMethod); err != nil {
+		t.Fatalf("expected the probe through, got %v", err)
+	}
+	stats := r.Stats()
+	if stats.State != BreakerClosed || stats.ConsecutiveFailures != 0 || stats.Rejected != 1 {
+		t.Errorf("expected the breaker closed, got %+v", stats)
+	}
+}
+
+func TestResilience_CallerCancellationIsNotAFailure(t *testing.T) {
+	r, _, _ := newTestResilience(ResilienceConfig{MaxAttempts: 3, FailureThreshold: 1, OpenTimeout: time.Second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	invoker := &scriptedInvoker{errs: []error{status.Error(codes.Canceled, "context canceled")}}
+	r.Interceptor()(ctx, readMethod, nil, nil, nil, invoker.invoke)
+
+	if stats := r.Stats(); stats.State != BreakerClosed || stats.Failures != 0 || invoker.calls != 1 {
+		t.Errorf("expected a cancelled call to leave the breaker closed, got %+v after %d calls", stats, invoker.calls)
+	}
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// SettingsExportVersion is bumped when the settings export format changes incompatibly
+const SettingsExportVersion = 1
+
+// SettingsExport is the full-fidelity JSON export of a user's subscriptions and their
+// settings. Custom fetch headers are secrets and are left out.
+type SettingsExport struct {
+	Version       int                    `json:"version"`
+	ExportedAt    time.Time              `json:"exported_at"`
+	Subscriptions []SubscriptionSettings `json:"subscriptions"`
+}
+
+// SubscriptionSettings is one subscription in a SettingsExport
+type SubscriptionSettings struct {
+	URL         string  `json:"url"`
+	Title       string  `json:"title,omitempty"` // the feed's own title, informational
+	CustomTitle *string `json:"custom_title,omitempty"`
+	Notes       *string `json:"notes,omitempty"`
+	// Folder names the folder the subscription is filed in and its parents, top-level first
+	Folder []string `json:"folder,omitempty"`
+}
+
+// FolderPath returns the folder names, checked and trimmed, nil when the subscription is
+// not in a folder
+func (s SubscriptionSettings) FolderPath() ([]string, error) {
+	return OPMLFeedItem{Folder: s.Folder}.FolderPath()
+}
+
+// Update returns the subscription update that restores these settings exactly; settings
+// missing from the export are cleared. The folder is resolved to an ID by the importer.
+func (s SubscriptionSettings) Update() models.SubscriptionUpdate {
+	cleared := ""
+	update := models.SubscriptionUpdate{CustomTitle: &cleared, Notes: &cleared}
+	if s.CustomTitle != nil {
+		update.CustomTitle = s.CustomTitle
+	}
+	if s.Notes != nil {
+		update.Notes = s.Notes
+	}
+	return update
+}
+
+// SettingsImportResult reports what an import of a SettingsExport did
+type SettingsImportResult struct {
+	Subscribed int      `json:"subscribed"`
+	Updated    int      `json:"updated"`
+	Failed     int      `json:"failed"`
+	FailedURLs []string `json:"failed_urls,omitempty"`
+}
+
+// NewSettingsExport builds the export of the given subscriptions
+func NewSettingsExport(feeds []*models.UserFeed) *SettingsExport {
+	export := &SettingsExport{
+		Version:       SettingsExportVersion,
+		ExportedAt:    time.Now().UTC(),
+		Subscriptions: make([]SubscriptionSettings, 0, len(feeds)),
+	}
+	for _, feed := range feeds {
+		export.Subscriptions = append(export.Subscriptions, SubscriptionSettings{
+			URL:         feed.URL,
+			Title:       feed.Title,
+			CustomTitle: feed.CustomTitle,
+			Notes:       feed.Notes,
+			Folder:      feed.FolderPath,
+		})
+	}
+	return export
+}
+
+// ParseSettingsExport decodes and checks a settings export. Entries for the same feed URL
+// are collapsed, the last one winning.
+func ParseSettingsExport(data []byte) (*SettingsExport, error) {
+	var export SettingsExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("invalid settings export: %w", err)
+	}
+	if export.Version != SettingsExportVersion {
+		return nil, fmt.Errorf("unsupported settings export version %d", export.Version)
+	}
+
+	seen := make(map[string]int, len(export.Subscriptions))
+	subscriptions := make([]SubscriptionSettings, 0, len(export.Subscriptions))
+	for i, sub := range export.Subscriptions {
+		sub.URL = strings.TrimSpace(sub.URL)
+		if sub.URL == "" {
+			return nil, fmt.Errorf("subscription %d has no url", i)
+		}
+		if err := sub.Update().Validate(); err != nil {
+			return nil, fmt.Errorf("subscription %s: %w", sub.URL, err)
+		}
+		if _, err := sub.FolderPath(); err != nil {
+			return nil, fmt.Errorf("subscription %s: %w", sub.URL, err)
+		}
+
+		key := NormalizeFeedURL(sub.URL)
+		if idx, ok := seen[key]; ok {
+			subscriptions[idx] = sub
+			continue
+		}
+		seen[key] = len(subscriptions)
+		subscriptions = append(subscriptions, sub)
+	}
+	export.Subscriptions = subscriptions
+	return &export, nil
+}
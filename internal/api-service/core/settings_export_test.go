@@ -0,0 +1,81 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+func TestSettingsExport_RoundTrip(t *testing.T) {
+	feeds := []*models.UserFeed{
+		{Feed: models.Feed{ID: 1, Title: "Upstream", URL: "https://example.com/feed.xml"}, CustomTitle: strPtr("Mine"), Notes: strPtr("weekly"), FolderPath: []string{"Tech", "Go"}},
+		{Feed: models.Feed{ID: 2, Title: "Plain", URL: "https://example.org/feed.xml"}},
+	}
+
+	data, err := json.Marshal(NewSettingsExport(feeds))
+	if err != nil {
+		t.Fatalf("marshal export: %v", err)
+	}
+
+	export, err := ParseSettingsExport(data)
+	if err != nil {
+		t.Fatalf("ParseSettingsExport() error = %v", err)
+	}
+	if len(export.Subscriptions) != 2 {
+		t.Fatalf("got %d subscriptions, want 2", len(export.Subscriptions))
+	}
+
+	update := export.Subscriptions[0].Update()
+	if *update.CustomTitle != "Mine" || *update.Notes != "weekly" {
+		t.Errorf("Update() = %q / %q, want the exported settings", *update.CustomTitle, *update.Notes)
+	}
+	if path, err := export.Subscriptions[0].FolderPath(); err != nil || strings.Join(path, "/") != "Tech/Go" {
+		t.Errorf("FolderPath() = %q, %v, want Tech/Go", path, err)
+	}
+
+	// settings missing from the export are cleared, so the import restores them exactly
+	update = export.Subscriptions[1].Update()
+	if update.CustomTitle == nil || *update.CustomTitle != "" || update.Notes == nil || *update.Notes != "" {
+		t.Errorf("Update() for a subscription without settings should clear them, got %+v", update)
+	}
+}
+
+func TestParseSettingsExport_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"not json", `<opml/>`, "invalid settings export"},
+		{"unknown version", `{"version": 2, "subscriptions": []}`, "unsupported settings export version 2"},
+		{"missing url", `{"version": 1, "subscriptions": [{"title": "x"}]}`, "has no url"},
+		{"notes too long", `{"version": 1, "subscriptions": [{"url": "https://example.com", "notes": "` + strings.Repeat("n", models.MaxSubscriptionNotesLength+1) + `"}]}`, "notes must be at most"},
+		{"blank folder", `{"version": 1, "subscriptions": [{"url": "https://example.com", "folder": ["Tech", " "]}]}`, "folder name must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSettingsExport([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseSettingsExport() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSettingsExport_CollapsesDuplicateURLs(t *testing.T) {
+	data := `{"version": 1, "subscriptions": [
+		{"url": "https://example.com/feed.xml", "custom_title": "First"},
+		{"url": "https://EXAMPLE.com/feed.xml/", "custom_title": "Second"}
+	]}`
+
+	export, err := ParseSettingsExport([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseSettingsExport() error = %v", err)
+	}
+	if len(export.Subscriptions) != 1 || *export.Subscriptions[0].CustomTitle != "Second" {
+		t.Errorf("got %+v, want one subscription titled Second", export.Subscriptions)
+	}
+}
@@ -3,22 +3,43 @@ package core
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
 	userpb "github.com/Fancu1/phoenix-rss/protos/gen/go/user"
 )
 
 // UserServiceInterface define the contract for user service operations
 type UserServiceInterface interface {
 	Register(username, password string) (*models.User, error)
-	Login(username, password string) (string, error)
+	Login(username, password string, client models.SessionClient) (*models.AuthTokens, error)
+	RefreshToken(ctx context.Context, refreshToken string, client models.SessionClient) (*models.AuthTokens, error)
 	ValidateToken(tokenString string) (*jwt.Token, error)
 	GetUserFromToken(tokenString string) (*models.User, error)
+	SetLLMCredential(ctx context.Context, userID uint, baseURL, model, apiKey string) (*models.LLMCredential, error)
+	GetLLMCredential(ctx context.Context, userID uint) (*models.LLMCredential, error)
+	DeleteLLMCredential(ctx context.Context, userID uint) error
+	GetLLMUsage(ctx context.Context, userID uint, since time.Time) ([]models.LLMUsageSummary, error)
+	ListSessions(ctx context.Context, userID uint) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userID uint, sessionID string) error
+	RevokeOtherSessions(ctx context.Context, userID uint, currentSessionID string) (int64, error)
+	GetPreferences(ctx context.Context, userID uint) (*models.UserPreferences, error)
+	UpdatePreferences(ctx context.Context, userID uint, update models.PreferencesUpdate) (*models.UserPreferences, error)
+	GetProfile(ctx context.Context, userID uint) (*models.User, error)
+	UpdateProfile(ctx context.Context, userID uint, update models.ProfileUpdate) (*models.User, error)
+	ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword, currentSessionID string) (int64, error)
+	RequestPasswordReset(ctx context.Context, email, ip string) error
+	ResetPassword(ctx context.Context, token, newPassword string) (*models.User, int64, error)
+	ListUsers(ctx context.Context, afterID uint, limit int) ([]models.User, int64, error)
+	SetUserRole(ctx context.Context, userID uint, role string) (*models.User, error)
+	Impersonate(ctx context.Context, userID, impersonatorID uint, impersonator string) (*models.User, *models.AuthTokens, error)
 }
 
 // UserServiceClient implement UserServiceInterface using gRPC
@@ -28,8 +49,12 @@ type UserServiceClient struct {
 }
 
 // NewUserServiceClient create a new gRPC client for the user service
-func NewUserServiceClient(address string) (*UserServiceClient, error) {
-	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewUserServiceClient(address string, opts ...grpc.DialOption) (*UserServiceClient, error) {
+	conn, err := grpc.NewClient(address, append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(logger.RequestIDClientInterceptor()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}, opts...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to user service at %s: %w", address, err)
 	}
@@ -71,21 +96,47 @@ func (c *UserServiceClient) Register(username, password string) (*models.User, e
 	}, nil
 }
 
-func (c *UserServiceClient) Login(username, password string) (string, error) {
+func (c *UserServiceClient) Login(username, password string, client models.SessionClient) (*models.AuthTokens, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	req := &userpb.LoginRequest{
-		Username: username,
-		Password: password,
+		Username:  username,
+		Password:  password,
+		UserAgent: client.UserAgent,
+		Ip:        client.IP,
 	}
 
 	resp, err := c.client.Login(ctx, req)
 	if err != nil {
-		return "", MapGRPCError(err)
+		return nil, MapGRPCError(err)
 	}
 
-	return resp.Token, nil
+	return &models.AuthTokens{
+		AccessToken:  resp.Token,
+		ExpiresAt:    time.Unix(resp.ExpiresAt, 0).UTC(),
+		RefreshToken: resp.RefreshToken,
+	}, nil
+}
+
+func (c *UserServiceClient) RefreshToken(ctx context.Context, refreshToken string, client models.SessionClient) (*models.AuthTokens, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	resp, err := c.client.RefreshToken(ctx, &userpb.RefreshTokenRequest{
+		RefreshToken: refreshToken,
+		UserAgent:    client.UserAgent,
+		Ip:           client.IP,
+	})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+
+	return &models.AuthTokens{
+		AccessToken:  resp.Token,
+		ExpiresAt:    time.Unix(resp.ExpiresAt, 0).UTC(),
+		RefreshToken: resp.RefreshToken,
+	}, nil
 }
 
 func (c *UserServiceClient) ValidateToken(tokenString string) (*jwt.Token, error) {
@@ -144,3 +195,269 @@ func (c *UserServiceClient) GetUserFromToken(tokenString string) (*models.User,
 		Username: resp.User.Username,
 	}, nil
 }
+
+func (c *UserServiceClient) SetLLMCredential(ctx context.Context, userID uint, baseURL, model, apiKey string) (*models.LLMCredential, error) {
+	resp, err := c.client.SetLLMCredential(ctx, &userpb.SetLLMCredentialRequest{
+		UserId:  uint64(userID),
+		BaseUrl: baseURL,
+		Model:   model,
+		ApiKey:  apiKey,
+	})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+
+	return convertPbToLLMCredential(userID, resp.Credential), nil
+}
+
+func (c *UserServiceClient) GetLLMCredential(ctx context.Context, userID uint) (*models.LLMCredential, error) {
+	resp, err := c.client.GetLLMCredential(ctx, &userpb.GetLLMCredentialRequest{UserId: uint64(userID)})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+
+	return convertPbToLLMCredential(userID, resp.Credential), nil
+}
+
+func (c *UserServiceClient) DeleteLLMCredential(ctx context.Context, userID uint) error {
+	_, err := c.client.DeleteLLMCredential(ctx, &userpb.DeleteLLMCredentialRequest{UserId: uint64(userID)})
+	if err != nil {
+		return MapGRPCError(err)
+	}
+	return nil
+}
+
+func (c *UserServiceClient) GetLLMUsage(ctx context.Context, userID uint, since time.Time) ([]models.LLMUsageSummary, error) {
+	req := &userpb.GetLLMUsageRequest{UserId: uint64(userID)}
+	if !since.IsZero() {
+		req.Since = since.Unix()
+	}
+
+	resp, err := c.client.GetLLMUsage(ctx, req)
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+
+	usage := make([]models.LLMUsageSummary, 0, len(resp.Usage))
+	for _, u := range resp.Usage {
+		usage = append(usage, models.LLMUsageSummary{
+			Model:            u.Model,
+			BYOK:             u.Byok,
+			Requests:         u.Requests,
+			PromptTokens:     u.PromptTokens,
+			CompletionTokens: u.CompletionTokens,
+			TotalTokens:      u.TotalTokens,
+		})
+	}
+	return usage, nil
+}
+
+func (c *UserServiceClient) ListSessions(ctx context.Context, userID uint) ([]models.Session, error) {
+	resp, err := c.client.ListSessions(ctx, &userpb.ListSessionsRequest{UserId: uint64(userID)})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+
+	sessions := make([]models.Session, 0, len(resp.Sessions))
+	for _, s := range resp.Sessions {
+		sessions = append(sessions, models.Session{
+			ID:         s.Id,
+			UserID:     userID,
+			UserAgent:  s.UserAgent,
+			IP:         s.Ip,
+			CreatedAt:  time.Unix(s.CreatedAt, 0).UTC(),
+			LastSeenAt: time.Unix(s.LastSeenAt, 0).UTC(),
+			ExpiresAt:  time.Unix(s.ExpiresAt, 0).UTC(),
+		})
+	}
+	return sessions, nil
+}
+
+func (c *UserServiceClient) RevokeSession(ctx context.Context, userID uint, sessionID string) error {
+	_, err := c.client.RevokeSession(ctx, &userpb.RevokeSessionRequest{UserId: uint64(userID), SessionId: sessionID})
+	if err != nil {
+		return MapGRPCError(err)
+	}
+	return nil
+}
+
+func (c *UserServiceClient) RevokeOtherSessions(ctx context.Context, userID uint, currentSessionID string) (int64, error) {
+	resp, err := c.client.RevokeOtherSessions(ctx, &userpb.RevokeOtherSessionsRequest{
+		UserId:           uint64(userID),
+		CurrentSessionId: currentSessionID,
+	})
+	if err != nil {
+		return 0, MapGRPCError(err)
+	}
+	return resp.Revoked, nil
+}
+
+func convertPbToLLMCredential(userID uint, pb *userpb.LLMCredential) *models.LLMCredential {
+	if pb == nil {
+		return nil
+	}
+	return &models.LLMCredential{
+		UserID:     userID,
+		BaseURL:    pb.BaseUrl,
+		Model:      pb.Model,
+		APIKeyHint: pb.ApiKeyHint,
+		UpdatedAt:  time.Unix(pb.UpdatedAt, 0).UTC(),
+	}
+}
+
+func (c *UserServiceClient) GetPreferences(ctx context.Context, userID uint) (*models.UserPreferences, error) {
+	resp, err := c.client.GetPreferences(ctx, &userpb.GetPreferencesRequest{UserId: uint64(userID)})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+	return convertPbToPreferences(userID, resp.Preferences), nil
+}
+
+func (c *UserServiceClient) UpdatePreferences(ctx context.Context, userID uint, update models.PreferencesUpdate) (*models.UserPreferences, error) {
+	req := &userpb.UpdatePreferencesRequest{
+		UserId:          uint64(userID),
+		DefaultSort:     update.DefaultSort,
+		ShowRead:        update.ShowRead,
+		ListContent:     update.ListContent,
+		Theme:           update.Theme,
+		DefaultView:     update.DefaultView,
+		SummaryLanguage: update.SummaryLanguage,
+	}
+	if update.ItemsPerPage != nil {
+		// clamped so that an out-of-range value is refused rather than wrapped around
+		itemsPerPage := int32(min(max(*update.ItemsPerPage, math.MinInt32), math.MaxInt32))
+		req.ItemsPerPage = &itemsPerPage
+	}
+	resp, err := c.client.UpdatePreferences(ctx, req)
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+	return convertPbToPreferences(userID, resp.Preferences), nil
+}
+
+func convertPbToPreferences(userID uint, pb *userpb.Preferences) *models.UserPreferences {
+	preferences := &models.UserPreferences{
+		UserID:          userID,
+		DefaultSort:     pb.GetDefaultSort(),
+		ShowRead:        pb.GetShowRead(),
+		ListContent:     pb.GetListContent(),
+		Theme:           pb.GetTheme(),
+		DefaultView:     pb.GetDefaultView(),
+		ItemsPerPage:    int(pb.GetItemsPerPage()),
+		SummaryLanguage: pb.GetSummaryLanguage(),
+	}
+	if pb.GetUpdatedAt() != 0 {
+		preferences.UpdatedAt = time.Unix(pb.GetUpdatedAt(), 0).UTC()
+	}
+	return preferences
+}
+
+func (c *UserServiceClient) GetProfile(ctx context.Context, userID uint) (*models.User, error) {
+	resp, err := c.client.GetProfile(ctx, &userpb.GetProfileRequest{UserId: uint64(userID)})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+	return convertPbToProfile(resp.User), nil
+}
+
+func (c *UserServiceClient) UpdateProfile(ctx context.Context, userID uint, update models.ProfileUpdate) (*models.User, error) {
+	resp, err := c.client.UpdateProfile(ctx, &userpb.UpdateProfileRequest{
+		UserId:      uint64(userID),
+		Username:    update.Username,
+		Email:       update.Email,
+		DisplayName: update.DisplayName,
+	})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+	return convertPbToProfile(resp.User), nil
+}
+
+// ChangePassword replaces the user's password and returns how many other sessions were
+// signed out
+func (c *UserServiceClient) ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword, currentSessionID string) (int64, error) {
+	resp, err := c.client.ChangePassword(ctx, &userpb.ChangePasswordRequest{
+		UserId:           uint64(userID),
+		CurrentPassword:  currentPassword,
+		NewPassword:      newPassword,
+		CurrentSessionId: currentSessionID,
+	})
+	if err != nil {
+		return 0, MapGRPCError(err)
+	}
+	return resp.RevokedSessions, nil
+}
+
+// RequestPasswordReset mails a reset token to the user with the email, if there is one
+func (c *UserServiceClient) RequestPasswordReset(ctx context.Context, email, ip string) error {
+	_, err := c.client.RequestPasswordReset(ctx, &userpb.RequestPasswordResetRequest{Email: email, Ip: ip})
+	if err != nil {
+		return MapGRPCError(err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and returns the user and how many
+// of their sessions were signed out
+func (c *UserServiceClient) ResetPassword(ctx context.Context, token, newPassword string) (*models.User, int64, error) {
+	resp, err := c.client.ResetPassword(ctx, &userpb.ResetPasswordRequest{Token: token, NewPassword: newPassword})
+	if err != nil {
+		return nil, 0, MapGRPCError(err)
+	}
+	return convertPbToProfile(resp.User), resp.RevokedSessions, nil
+}
+
+// ListUsers returns up to limit users with an ID above afterID, in ID order, and how many
+// users there are
+func (c *UserServiceClient) ListUsers(ctx context.Context, afterID uint, limit int) ([]models.User, int64, error) {
+	resp, err := c.client.ListUsers(ctx, &userpb.ListUsersRequest{AfterId: uint64(afterID), Limit: int32(limit)})
+	if err != nil {
+		return nil, 0, MapGRPCError(err)
+	}
+	users := make([]models.User, len(resp.Users))
+	for i, pb := range resp.Users {
+		users[i] = *convertPbToProfile(pb)
+	}
+	return users, resp.Total, nil
+}
+
+func (c *UserServiceClient) SetUserRole(ctx context.Context, userID uint, role string) (*models.User, error) {
+	resp, err := c.client.SetUserRole(ctx, &userpb.SetUserRoleRequest{UserId: uint64(userID), Role: role})
+	if err != nil {
+		return nil, MapGRPCError(err)
+	}
+	return convertPbToProfile(resp.User), nil
+}
+
+// Impersonate returns a short-lived, read-only token for the administrator to see the API
+// as the user does
+func (c *UserServiceClient) Impersonate(ctx context.Context, userID, impersonatorID uint, impersonator string) (*models.User, *models.AuthTokens, error) {
+	resp, err := c.client.Impersonate(ctx, &userpb.ImpersonateRequest{
+		UserId:         uint64(userID),
+		ImpersonatorId: uint64(impersonatorID),
+		Impersonator:   impersonator,
+	})
+	if err != nil {
+		return nil, nil, MapGRPCError(err)
+	}
+	return convertPbToProfile(resp.User), &models.AuthTokens{
+		AccessToken: resp.Token,
+		ExpiresAt:   time.Unix(resp.ExpiresAt, 0).UTC(),
+	}, nil
+}
+
+func convertPbToProfile(pb *userpb.User) *models.User {
+	user := &models.User{
+		ID:          uint(pb.GetId()),
+		Username:    pb.GetUsername(),
+		DisplayName: pb.GetDisplayName(),
+		Role:        pb.GetRole(),
+	}
+	if email := pb.GetEmail(); email != "" {
+		user.Email = &email
+	}
+	if pb.GetCreatedAt() != 0 {
+		user.CreatedAt = time.Unix(pb.GetCreatedAt(), 0).UTC()
+	}
+	return user
+}
@@ -0,0 +1,505 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	aimodels "github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/api-service/core"
+	"github.com/Fancu1/phoenix-rss/internal/api-service/repository"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/policy"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// AdminHandler serves the operator endpoints behind RequireAdmin
+type AdminHandler struct {
+	snapshotRepo *repository.SnapshotRepository
+	feedService  core.FeedServiceInterface
+	cache        redis.Cmdable
+	routeMetrics *RouteMetrics
+	grpcClients  []*core.Resilience
+	policies     *policy.Engine
+	policyRepo   *repository.PolicyRepository
+	llmUsage     *repository.LLMUsageRepository
+	llmBudget    aimodels.LLMBudget
+}
+
+func NewAdminHandler(snapshotRepo *repository.SnapshotRepository, feedService core.FeedServiceInterface, cache redis.Cmdable) *AdminHandler {
+	return &AdminHandler{snapshotRepo: snapshotRepo, feedService: feedService, cache: cache}
+}
+
+// SetRouteMetrics serves the statistics collected by metrics from ListRouteMetrics
+func (h *AdminHandler) SetRouteMetrics(metrics *RouteMetrics) {
+	h.routeMetrics = metrics
+}
+
+// SetGRPCClients serves the breaker state and call counters of clients from
+// ListGRPCClientMetrics
+func (h *AdminHandler) SetGRPCClients(clients ...*core.Resilience) {
+	h.grpcClients = clients
+}
+
+// SetPolicies serves the policy engine from ListPolicies and EvaluatePolicy, which load
+// the users and feeds evaluated from repo
+func (h *AdminHandler) SetPolicies(engine *policy.Engine, repo *repository.PolicyRepository) {
+	h.policies = engine
+	h.policyRepo = repo
+}
+
+// SetLLMUsage serves the ai-service's daily LLM usage from repo, and its budget, from
+// GetLLMUsage
+func (h *AdminHandler) SetLLMUsage(repo *repository.LLMUsageRepository, budget aimodels.LLMBudget) {
+	h.llmUsage = repo
+	h.llmBudget = budget
+}
+
+// feedDeletionQuery picks what happens to the articles of a deleted feed
+type feedDeletionQuery struct {
+	Retention string `form:"retention"`
+
+	retention models.FeedRetention
+}
+
+func (q *feedDeletionQuery) validate() []string {
+	if q.Retention == "" {
+		return nil
+	}
+	retention, err := models.ParseFeedRetention(q.Retention)
+	if err != nil {
+		return []string{fmt.Sprintf("retention must be one of %s, %s", models.FeedRetentionArchive, models.FeedRetentionPurge)}
+	}
+	q.retention = retention
+	return nil
+}
+
+// DeleteFeed removes a feed for every subscriber. The retention query parameter picks
+// whether its articles are archived with it or purged; without it the feed service default
+// applies. The feed list cache of every former subscriber is dropped.
+func (h *AdminHandler) DeleteFeed(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	feedID, err := strconv.ParseUint(c.Param("feed_id"), 10, 32)
+	if err != nil || feedID == 0 {
+		c.Error(ierr.ErrInvalidFeedID)
+		return
+	}
+
+	var query feedDeletionQuery
+	if err := bindQuery(c, &query); err != nil {
+		c.Error(err)
+		return
+	}
+
+	deletion, err := h.feedService.DeleteFeed(ctx, uint(feedID), query.retention)
+	if err != nil {
+		log.Error("failed to delete feed", "feed_id", feedID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	if h.cache != nil && len(deletion.UserIDs) > 0 {
+		keys := make([]string, len(deletion.UserIDs))
+		for i, userID := range deletion.UserIDs {
+			keys[i] = fmt.Sprintf(userFeedsCacheKeyPattern, userID)
+		}
+		if err := h.cache.Del(ctx, keys...).Err(); err != nil && err != redis.Nil {
+			log.Warn("failed to invalidate user feeds caches", "feed_id", feedID, "error", err.Error())
+		}
+	}
+
+	log.Info("admin deleted feed", "feed_id", feedID, "retention", deletion.Retention, "unsubscribed_users", len(deletion.UserIDs))
+	c.JSON(http.StatusOK, deletion)
+}
+
+const (
+	defaultAdminFeedLimit = 100
+	maxAdminFeedLimit     = 1000
+)
+
+// adminFeedFilterParams select feeds by status, last fetch error, time since the last
+// fetch, tier or ID
+type adminFeedFilterParams struct {
+	Status        string `json:"status" form:"status"`
+	Error         string `json:"error" form:"error"`
+	NotFetchedFor string `json:"not_fetched_for" form:"not_fetched_for"` // e.g. "72h"
+	Tier          string `json:"tier" form:"tier"`
+	FeedIDs       []uint `json:"feed_ids" form:"-"`
+}
+
+func (p adminFeedFilterParams) filter() (core.AdminFeedFilter, error) {
+	filter, problems := p.parse()
+	if len(problems) > 0 {
+		return filter, ierr.NewValidationError(strings.Join(problems, "; "))
+	}
+	return filter, nil
+}
+
+// parse builds the filter, reporting every parameter it cannot use
+func (p adminFeedFilterParams) parse() (core.AdminFeedFilter, []string) {
+	filter := core.AdminFeedFilter{ErrorContains: p.Error, FeedIDs: p.FeedIDs}
+	var problems []string
+	var err error
+	if p.Status != "" {
+		if filter.Status, err = models.ParseFeedStatus(p.Status); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if p.Tier != "" {
+		if filter.Tier, err = models.ParseFeedTier(p.Tier); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if p.NotFetchedFor != "" {
+		age, err := time.ParseDuration(p.NotFetchedFor)
+		if err != nil || age <= 0 {
+			problems = append(problems, fmt.Sprintf("invalid not_fetched_for duration %q", p.NotFetchedFor))
+		} else {
+			since := time.Now().UTC().Add(-age)
+			filter.NotFetchedSince = &since
+		}
+	}
+	return filter, problems
+}
+
+// adminFeedListQuery is a filtered page of the instance's feeds
+type adminFeedListQuery struct {
+	adminFeedFilterParams
+	Limit int `form:"limit"`
+
+	filter core.AdminFeedFilter
+}
+
+func (q *adminFeedListQuery) validate() []string {
+	filter, problems := q.parse()
+	q.filter = filter
+	return append(problems, checkRange("limit", q.Limit, 1, maxAdminFeedLimit)...)
+}
+
+// ListFeeds pages through every feed of the instance, optionally filtered by status,
+// last fetch error, time since the last fetch and tier. It always answers with the list
+// envelope.
+func (h *AdminHandler) ListFeeds(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	query := adminFeedListQuery{Limit: defaultAdminFeedLimit}
+	if err := bindQuery(c, &query); err != nil {
+		c.Error(err)
+		return
+	}
+	pageToken, err := parseTokenCursor(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	feeds, nextToken, total, err := h.feedService.ListAdminFeeds(ctx, query.filter, query.Limit, pageToken)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to list feeds", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	writeListEnvelope(c, ListEnvelope[*core.AdminFeed]{
+		Items:      feeds,
+		NextCursor: encodeTokenCursor(nextToken),
+		Total:      total,
+	})
+}
+
+// bulkUpdateFeedsRequest is the body of BulkUpdateFeeds
+type bulkUpdateFeedsRequest struct {
+	Action string                `json:"action" binding:"required"`
+	Filter adminFeedFilterParams `json:"filter"`
+	Tier   string                `json:"tier"` // target tier of set_tier
+	DryRun bool                  `json:"dry_run"`
+}
+
+// BulkUpdateFeeds reactivates, suspends, refetches or re-tiers every feed matching the
+// filter. A filter is required; dry_run only counts the feeds the action would apply to.
+func (h *AdminHandler) BulkUpdateFeeds(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	var req bulkUpdateFeedsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+	action, err := models.ParseFeedBulkAction(req.Action)
+	if err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+	update := models.FeedBulkUpdate{Action: action, DryRun: req.DryRun}
+	if action == models.FeedBulkSetTier {
+		if update.Tier, err = models.ParseFeedTier(req.Tier); err != nil {
+			c.Error(ierr.NewValidationError(err.Error()))
+			return
+		}
+	}
+	filter, err := req.Filter.filter()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	result, err := h.feedService.BulkUpdateFeeds(ctx, filter, update)
+	if err != nil {
+		log.Error("failed to apply bulk feed action", "action", action, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	log.Info("admin applied bulk feed action",
+		"action", action,
+		"dry_run", result.DryRun,
+		"matched", result.Matched,
+		"updated", result.Updated,
+		"fetches_queued", result.FetchesQueued,
+	)
+	c.JSON(http.StatusOK, result)
+}
+
+// FetchFeed queues a fetch of any feed right away, whoever subscribes to it. Suspended
+// feeds are not fetched; the result then reports no fetch queued.
+func (h *AdminHandler) FetchFeed(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	feedID, err := strconv.ParseUint(c.Param("feed_id"), 10, 32)
+	if err != nil || feedID == 0 {
+		c.Error(ierr.ErrInvalidFeedID)
+		return
+	}
+
+	filter := core.AdminFeedFilter{FeedIDs: []uint{uint(feedID)}}
+	result, err := h.feedService.BulkUpdateFeeds(ctx, filter, models.FeedBulkUpdate{Action: models.FeedBulkRefetch})
+	if err != nil {
+		log.Error("failed to queue feed fetch", "feed_id", feedID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	if result.Matched == 0 {
+		c.Error(ierr.ErrFeedNotFound)
+		return
+	}
+
+	log.Info("admin queued feed fetch", "feed_id", feedID, "fetches_queued", result.FetchesQueued)
+	c.JSON(http.StatusAccepted, result)
+}
+
+// ListFeedSnapshots lists the raw responses stored for a feed, newest first
+func (h *AdminHandler) ListFeedSnapshots(c *gin.Context) {
+	feedID, err := strconv.ParseUint(c.Param("feed_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.ErrInvalidFeedID)
+		return
+	}
+
+	snapshots, err := h.snapshotRepo.ListByFeed(c.Request.Context(), uint(feedID))
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshots)
+}
+
+// GetFeedSnapshot downloads the decompressed body of one snapshot, as the server sent it
+func (h *AdminHandler) GetFeedSnapshot(c *gin.Context) {
+	feedID, err := strconv.ParseUint(c.Param("feed_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.ErrInvalidFeedID)
+		return
+	}
+	snapshotID, err := strconv.ParseUint(c.Param("snapshot_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.NewValidationError("invalid snapshot ID"))
+		return
+	}
+
+	snapshot, err := h.snapshotRepo.Get(c.Request.Context(), uint(feedID), uint(snapshotID))
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	if snapshot == nil {
+		c.Error(fmt.Errorf("snapshot %d of feed %d: %w", snapshotID, feedID, ierr.ErrSnapshotNotFound))
+		return
+	}
+
+	raw, err := snapshot.Raw()
+	if err != nil {
+		c.Error(ierr.NewInternalError(err))
+		return
+	}
+
+	// served as a download so a captured HTML error page is never rendered by the browser
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=feed-%d-snapshot-%d", feedID, snapshotID))
+	c.Header("X-Snapshot-Status", strconv.Itoa(snapshot.StatusCode))
+	c.Header("X-Snapshot-Truncated", strconv.FormatBool(snapshot.Truncated))
+	c.Data(http.StatusOK, "application/octet-stream", raw)
+}
+
+// ListRouteMetrics returns the per-route request statistics
+func (h *AdminHandler) ListRouteMetrics(c *gin.Context) {
+	if h.routeMetrics == nil {
+		c.JSON(http.StatusOK, []RouteStats{})
+		return
+	}
+	c.JSON(http.StatusOK, h.routeMetrics.Snapshot())
+}
+
+// ListGRPCClientMetrics returns the circuit breaker state and call counters of the
+// feed-service and user-service clients
+func (h *AdminHandler) ListGRPCClientMetrics(c *gin.Context) {
+	stats := make([]core.ClientStats, 0, len(h.grpcClients))
+	for _, client := range h.grpcClients {
+		stats = append(stats, client.Stats())
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// adminLLMUsageResponse is what GetLLMUsage returns
+type adminLLMUsageResponse struct {
+	Since time.Time                `json:"since"`
+	Days  []aimodels.LLMUsageDaily `json:"days"`
+	Total aimodels.LLMUsageTotals  `json:"total"`
+	// Today is what the instance key used today, the usage the budget counts
+	Today          aimodels.LLMUsageTotals `json:"today"`
+	Budget         aimodels.LLMBudget      `json:"budget"`
+	BudgetExceeded bool                    `json:"budget_exceeded"`
+}
+
+// GetLLMUsage reports the LLM usage and estimated cost of every user and the instance key
+// per day over the last `days` days, and today's usage against the daily budget
+func (h *AdminHandler) GetLLMUsage(c *gin.Context) {
+	query := llmUsageQuery{Days: defaultLLMUsageDays}
+	if err := bindQuery(c, &query); err != nil {
+		c.Error(err)
+		return
+	}
+	now := time.Now().UTC()
+	since := aimodels.UsageDay(now).AddDate(0, 0, 1-query.Days)
+
+	days, err := h.llmUsage.ListDaily(c.Request.Context(), since)
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+
+	resp := adminLLMUsageResponse{Since: since, Days: days, Budget: h.llmBudget}
+	today := aimodels.UsageDay(now)
+	for _, day := range days {
+		resp.Total.Add(day)
+		if !day.BYOK && day.Day.Equal(today) {
+			resp.Today.Add(day)
+		}
+	}
+	resp.BudgetExceeded = h.llmBudget.Exceeded(resp.Today.CostUSD, resp.Today.TotalTokens)
+	c.JSON(http.StatusOK, resp)
+}
+
+// policiesResponse is what ListPolicies returns
+type policiesResponse struct {
+	// Base is the policy configured in the environment
+	Base policy.Policy `json:"base"`
+	// Defaults is the base with the document's defaults applied, the policy of subjects no
+	// rule matches
+	Defaults policy.Policy   `json:"defaults"`
+	Document policy.Document `json:"document"`
+}
+
+// ListPolicies shows the configured limits, the defaults the policy document makes of
+// them and the document's rules
+func (h *AdminHandler) ListPolicies(c *gin.Context) {
+	c.JSON(http.StatusOK, policiesResponse{
+		Base:     h.policies.Base(),
+		Defaults: h.policies.Defaults(),
+		Document: h.policies.Document(),
+	})
+}
+
+// evaluatePolicyRequest is the body of EvaluatePolicy
+type evaluatePolicyRequest struct {
+	UserID uint `json:"user_id"`
+	FeedID uint `json:"feed_id"`
+	// Document is evaluated instead of the loaded one, to try a change before deploying it
+	Document *policy.Document `json:"document"`
+}
+
+// policyEvaluation is a decision with the user's subscription usage
+type policyEvaluation struct {
+	policy.Decision
+	Subscriptions *int64 `json:"subscriptions,omitempty"`
+	// SubscriptionsLeft is how many more feeds the user may subscribe to, absent without
+	// a quota
+	SubscriptionsLeft *int64 `json:"subscriptions_left,omitempty"`
+}
+
+// EvaluatePolicy is a dry run of the policy of a user, a feed or both, with the loaded
+// document or one given in the request. Nothing is changed.
+func (h *AdminHandler) EvaluatePolicy(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	var req evaluatePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+	if req.UserID == 0 && req.FeedID == 0 {
+		c.Error(ierr.NewValidationError("user_id or feed_id is required"))
+		return
+	}
+
+	engine := h.policies
+	if req.Document != nil {
+		var err error
+		if engine, err = policy.NewEngine(h.policies.Base(), *req.Document); err != nil {
+			c.Error(ierr.NewValidationError(err.Error()))
+			return
+		}
+	}
+
+	subject := policy.Subject{UserID: req.UserID}
+	if req.FeedID != 0 {
+		feed, err := h.policyRepo.Feed(ctx, req.FeedID)
+		if err != nil {
+			log.Error("failed to load feed", "feed_id", req.FeedID, "error", err.Error())
+			c.Error(ierr.NewDatabaseError(err))
+			return
+		}
+		if feed == nil {
+			c.Error(ierr.ErrFeedNotFound)
+			return
+		}
+		subject = policy.FeedSubject(feed)
+		subject.UserID = req.UserID
+	}
+
+	evaluation := policyEvaluation{Decision: engine.Evaluate(subject)}
+	if req.UserID != 0 {
+		count, err := h.policyRepo.CountSubscriptions(ctx, req.UserID)
+		if err != nil {
+			log.Error("failed to count subscriptions", "user_id", req.UserID, "error", err.Error())
+			c.Error(ierr.NewDatabaseError(err))
+			return
+		}
+		evaluation.Subscriptions = &count
+		if limit := int64(evaluation.Policy.MaxSubscriptions); limit > 0 {
+			left := max(limit-count, 0)
+			evaluation.SubscriptionsLeft = &left
+		}
+	}
+	c.JSON(http.StatusOK, evaluation)
+}
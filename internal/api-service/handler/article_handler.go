@@ -1,20 +1,47 @@
 package handler
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 
 	"github.com/Fancu1/phoenix-rss/internal/api-service/core"
 	"github.com/Fancu1/phoenix-rss/internal/api-service/repository"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/summaries"
 	"github.com/Fancu1/phoenix-rss/pkg/ierr"
 	"github.com/Fancu1/phoenix-rss/pkg/logger"
 )
 
+// defaultSearchLimit and maxSearchLimit bound pages of search results; the feed service
+// caps them at the same maximum
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// defaultTimelineLimit and maxTimelineLimit bound pages of the timeline, also capped by
+// the feed service
+const (
+	defaultTimelineLimit = 50
+	maxTimelineLimit     = 200
+)
+
+// defaultStarredLimit and maxStarredLimit bound pages of starred articles, also capped by
+// the feed service
+const (
+	defaultStarredLimit = 20
+	maxStarredLimit     = 100
+)
+
 // PaginationMeta contains pagination metadata for list responses
 type PaginationMeta struct {
 	Page       int   `json:"page"`
@@ -29,20 +56,135 @@ type ArticleListResponse struct {
 	Pagination PaginationMeta    `json:"pagination"`
 }
 
+// TrashedArticle is an article in the trash
+type TrashedArticle struct {
+	*models.Article
+	DeletedAt       time.Time `json:"deleted_at"`
+	RestorableUntil time.Time `json:"restorable_until"`
+}
+
+// ArticleDetail is an article with its feed and its neighbours in the feed's list
+type ArticleDetail struct {
+	*models.Article
+	*models.ArticleNavigation
+}
+
+// articleSortQuery is the order a feed's articles are listed in
+type articleSortQuery struct {
+	Sort string `form:"sort"`
+
+	order repository.ArticleSort
+}
+
+func (q *articleSortQuery) validate() []string {
+	order, err := repository.ParseArticleSort(q.Sort)
+	if err != nil {
+		return []string{fmt.Sprintf("sort must be one of %s, %s", repository.SortRecent, repository.SortSmart)}
+	}
+	q.order = order
+	return nil
+}
+
+// articlePageQuery is a page of a feed's articles
+type articlePageQuery struct {
+	articleSortQuery
+	Page     int `form:"page"`
+	PageSize int `form:"page_size"`
+}
+
+func (q *articlePageQuery) validate() []string {
+	problems := q.articleSortQuery.validate()
+	if q.Page < 1 {
+		problems = append(problems, "page must be at least 1")
+	}
+	return append(problems, checkRange("page_size", q.PageSize, 1, repository.MaxPageSize)...)
+}
+
+// timelineQuery filters and bounds a page of the timeline
+type timelineQuery struct {
+	Limit    int    `form:"limit"`
+	Tag      string `form:"tag"`
+	Unread   bool   `form:"unread"`
+	Starred  bool   `form:"starred"`
+	FolderID uint   `form:"folder_id"`
+}
+
+func (q *timelineQuery) validate() []string {
+	return checkRange("limit", q.Limit, 1, maxTimelineLimit)
+}
+
+// nextUnreadQuery selects where j/k navigation goes next
+type nextUnreadQuery struct {
+	Scope    string `form:"scope" binding:"oneof=feed folder all"`
+	After    uint   `form:"after"`
+	FeedID   uint   `form:"feed_id"`
+	FolderID uint   `form:"folder_id"`
+	MarkRead bool   `form:"mark_read"`
+}
+
+// searchQuery is a full-text search over the caller's articles
+type searchQuery struct {
+	Q string `form:"q"`
+}
+
+func (q *searchQuery) validate() []string {
+	q.Q = strings.TrimSpace(q.Q)
+	if q.Q == "" {
+		return []string{"q is required"}
+	}
+	return nil
+}
+
+// exportQuery is the note format an article is exported in
+type exportQuery struct {
+	Format string `form:"format" binding:"oneof=markdown org"`
+}
+
+// markFeedReadQuery spares the articles published after Before, when set
+type markFeedReadQuery struct {
+	Before time.Time `form:"before"`
+}
+
 type ArticleHandler struct {
 	service          core.ArticleServiceInterface
 	subscriptionRepo *repository.SubscriptionRepository
 	articleRepo      *repository.ArticleRepository
+	trashGrace       time.Duration
+	cache            redis.Cmdable // nil when plain text is not cached
+	opener           ArticleOpener // nil when on-read checks are disabled
+}
+
+// ArticleOpener is told of each article a user opens
+type ArticleOpener interface {
+	ArticleOpened(ctx context.Context, article *models.Article)
 }
 
-func NewArticleHandler(service core.ArticleServiceInterface, subscriptionRepo *repository.SubscriptionRepository, articleRepo *repository.ArticleRepository) *ArticleHandler {
+func NewArticleHandler(service core.ArticleServiceInterface, subscriptionRepo *repository.SubscriptionRepository, articleRepo *repository.ArticleRepository, trashGrace time.Duration) *ArticleHandler {
 	return &ArticleHandler{
 		service:          service,
 		subscriptionRepo: subscriptionRepo,
 		articleRepo:      articleRepo,
+		trashGrace:       trashGrace,
 	}
 }
 
+// SetCache caches the plain-text rendering of articles
+func (h *ArticleHandler) SetCache(cache redis.Cmdable) {
+	h.cache = cache
+}
+
+// SetReadRechecker queues update checks of older articles as users open them
+func (h *ArticleHandler) SetReadRechecker(opener ArticleOpener) {
+	h.opener = opener
+}
+
+// plainTextCacheKeyPattern holds an article's plain text, keyed by its ID and updated_at
+// so an updated article is converted again
+const (
+	plainTextCacheKeyPattern = "article:%d:plain:%d"
+	plainTextCacheTTL        = 24 * time.Hour
+)
+
 func (h *ArticleHandler) TriggerFetch(c *gin.Context) {
 	ctx := c.Request.Context()
 	log := logger.FromContext(ctx)
@@ -84,9 +226,12 @@ func (h *ArticleHandler) ListArticles(c *gin.Context) {
 		return
 	}
 
-	// Parse pagination parameters from query string
-	page := parseIntQueryParam(c, "page", 1)
-	pageSize := parseIntQueryParam(c, "page_size", repository.DefaultPageSize)
+	query := articlePageQuery{Page: 1, PageSize: repository.DefaultPageSize}
+	if err := bindQuery(c, &query); err != nil {
+		c.Error(err)
+		return
+	}
+	sort := query.order
 
 	subscribed, err := h.subscriptionRepo.IsUserSubscribed(ctx, userID, uint(feedID))
 	if err != nil {
@@ -99,43 +244,62 @@ func (h *ArticleHandler) ListArticles(c *gin.Context) {
 		return
 	}
 
-	articles, total, err := h.articleRepo.ListByFeedIDPaginated(ctx, uint(feedID), page, pageSize)
-	if err != nil {
-		log.Error("failed to list articles", "feed_id", feedID, "page", page, "error", err.Error())
-		c.Error(ierr.NewDatabaseError(err))
+	if wantsEnvelope(c) {
+		window, err := parseListWindow(c, repository.DefaultPageSize, repository.MaxPageSize)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		articles, total, err := h.articleRepo.ListByFeedIDRange(ctx, userID, uint(feedID), sort, window.Offset, window.Limit)
+		if err != nil {
+			log.Error("failed to list articles", "feed_id", feedID, "offset", window.Offset, "error", err.Error())
+			c.Error(ierr.NewDatabaseError(err))
+			return
+		}
+		if err := h.applySummaries(c, articles...); err != nil {
+			c.Error(err)
+			return
+		}
+		writeListEnvelope(c, newListEnvelope(articles, window, total, false))
 		return
 	}
 
-	// Normalize page/pageSize in response (repo may have adjusted invalid values)
-	if page < 1 {
-		page = 1
+	articles, total, err := h.articleRepo.ListByFeedIDPaginated(ctx, userID, uint(feedID), sort, query.Page, query.PageSize)
+	if err != nil {
+		log.Error("failed to list articles", "feed_id", feedID, "page", query.Page, "error", err.Error())
+		c.Error(ierr.NewDatabaseError(err))
+		return
 	}
-	if pageSize < 1 || pageSize > repository.MaxPageSize {
-		pageSize = repository.DefaultPageSize
+	if err := h.applySummaries(c, articles...); err != nil {
+		c.Error(err)
+		return
 	}
 
 	c.JSON(http.StatusOK, ArticleListResponse{
 		Items: articles,
 		Pagination: PaginationMeta{
-			Page:       page,
-			PageSize:   pageSize,
+			Page:       query.Page,
+			PageSize:   query.PageSize,
 			Total:      total,
-			TotalPages: calculateTotalPages(total, pageSize),
+			TotalPages: calculateTotalPages(total, query.PageSize),
 		},
 	})
 }
 
-// parseIntQueryParam extracts an integer query parameter with a fallback default
-func parseIntQueryParam(c *gin.Context, key string, defaultVal int) int {
-	valStr := c.Query(key)
-	if valStr == "" {
-		return defaultVal
+// applySummaries loads the summary variants of the articles and shows the one asked for
+// with the summary_language and summary_model query parameters. Articles without such a
+// variant keep their default summary.
+func (h *ArticleHandler) applySummaries(c *gin.Context, articles ...*models.Article) error {
+	ctx := c.Request.Context()
+	pref := summaries.Preference{
+		Language: strings.ToLower(strings.TrimSpace(c.Query("summary_language"))),
+		Model:    strings.TrimSpace(c.Query("summary_model")),
 	}
-	val, err := strconv.Atoi(valStr)
-	if err != nil {
-		return defaultVal
+	if err := h.articleRepo.ApplySummaries(ctx, pref, articles...); err != nil {
+		logger.FromContext(ctx).Error("failed to load article summaries", "count", len(articles), "error", err.Error())
+		return ierr.NewDatabaseError(err)
 	}
-	return val
+	return nil
 }
 
 // calculateTotalPages computes the number of pages needed for a given total and page size
@@ -165,35 +329,676 @@ func (h *ArticleHandler) GetArticle(c *gin.Context) {
 		c.Error(ierr.NewValidationError("invalid article ID"))
 		return
 	}
+	// previous and next follow the order the client lists the feed in
+	var query articleSortQuery
+	if err := bindQuery(c, &query); err != nil {
+		c.Error(err)
+		return
+	}
 
-	feedID, err := h.articleRepo.GetFeedID(ctx, uint(articleID))
+	detail, err := h.articleRepo.Detail(ctx, userID, uint(articleID), query.order)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.Error(ierr.ErrArticleNotFound)
 			return
 		}
-		log.Error("failed to get article feed_id", "article_id", articleID, "error", err.Error())
+		log.Error("failed to get article", "article_id", articleID, "error", err.Error())
 		c.Error(ierr.NewDatabaseError(err))
 		return
 	}
+	if !detail.Subscribed {
+		c.Error(ierr.ErrNotSubscribed)
+		return
+	}
+	article := detail.Article
+	if err := h.applySummaries(c, article); err != nil {
+		c.Error(err)
+		return
+	}
+	if h.opener != nil {
+		h.opener.ArticleOpened(ctx, article)
+	}
+
+	c.JSON(http.StatusOK, ArticleDetail{Article: article, ArticleNavigation: detail.Navigation})
+}
+
+// RegenerateSummary queues a truncated article summary for regeneration with a longer limit
+func (h *ArticleHandler) RegenerateSummary(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
 
-	subscribed, err := h.subscriptionRepo.IsUserSubscribed(ctx, userID, feedID)
+	articleID, err := strconv.ParseUint(c.Param("article_id"), 10, 32)
 	if err != nil {
-		log.Error("failed to check subscription", "user_id", userID, "feed_id", feedID, "error", err.Error())
+		c.Error(ierr.NewValidationError("invalid article ID"))
+		return
+	}
+
+	if err := h.service.RegenerateSummary(ctx, userID, uint(articleID)); err != nil {
+		log.Error("failed to regenerate summary", "user_id", userID, "article_id", articleID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Summary regeneration accepted"})
+}
+
+// DeleteArticle moves an article to the trash for every subscriber of its feed
+func (h *ArticleHandler) DeleteArticle(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	articleID, err := strconv.ParseUint(c.Param("article_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.NewValidationError("invalid article ID"))
+		return
+	}
+
+	if err := h.service.DeleteArticle(ctx, userID, uint(articleID)); err != nil {
+		log.Error("failed to delete article", "user_id", userID, "article_id", articleID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Article moved to trash",
+		"restorable_until": time.Now().UTC().Add(h.trashGrace),
+	})
+}
+
+// MarkArticleRead marks an article read for the caller only
+func (h *ArticleHandler) MarkArticleRead(c *gin.Context) {
+	h.setArticleRead(c, true)
+}
+
+// MarkArticleUnread marks an article unread for the caller only
+func (h *ArticleHandler) MarkArticleUnread(c *gin.Context) {
+	h.setArticleRead(c, false)
+}
+
+func (h *ArticleHandler) setArticleRead(c *gin.Context, read bool) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	articleID, err := strconv.ParseUint(c.Param("article_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.NewValidationError("invalid article ID"))
+		return
+	}
+
+	article, err := h.service.SetArticleRead(ctx, userID, uint(articleID), read)
+	if err != nil {
+		log.Error("failed to set article read state", "user_id", userID, "article_id", articleID, "read", read, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, article)
+}
+
+// StarArticle stars an article for the caller only
+func (h *ArticleHandler) StarArticle(c *gin.Context) {
+	h.setArticleStarred(c, true)
+}
+
+// UnstarArticle unstars an article for the caller only
+func (h *ArticleHandler) UnstarArticle(c *gin.Context) {
+	h.setArticleStarred(c, false)
+}
+
+func (h *ArticleHandler) setArticleStarred(c *gin.Context, starred bool) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	articleID, err := strconv.ParseUint(c.Param("article_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.NewValidationError("invalid article ID"))
+		return
+	}
+
+	article, err := h.service.SetArticleStarred(ctx, userID, uint(articleID), starred)
+	if err != nil {
+		log.Error("failed to set article starred state", "user_id", userID, "article_id", articleID, "starred", starred, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, article)
+}
+
+// ArticleTagsRequest names the tags to put on an article
+type ArticleTagsRequest struct {
+	Tags []string `json:"tags" binding:"required"`
+}
+
+// AddArticleTags puts the caller's tags on an article, creating the tags they do not have yet
+func (h *ArticleHandler) AddArticleTags(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	articleID, err := strconv.ParseUint(c.Param("article_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.NewValidationError("invalid article ID"))
+		return
+	}
+
+	var req ArticleTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+
+	article, err := h.service.AddArticleTags(ctx, userID, uint(articleID), req.Tags)
+	if err != nil {
+		log.Error("failed to tag article", "user_id", userID, "article_id", articleID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, article)
+}
+
+// RemoveArticleTag takes one of the caller's tags off an article
+func (h *ArticleHandler) RemoveArticleTag(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	articleID, err := strconv.ParseUint(c.Param("article_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.NewValidationError("invalid article ID"))
+		return
+	}
+
+	article, err := h.service.RemoveArticleTags(ctx, userID, uint(articleID), []string{c.Param("tag")})
+	if err != nil {
+		log.Error("failed to untag article", "user_id", userID, "article_id", articleID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, article)
+}
+
+// ListTags returns the caller's tags by name with the number of articles each is on
+func (h *ArticleHandler) ListTags(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	tags, err := h.service.ListTags(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to list tags", "user_id", userID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// ListStarred returns the caller's starred articles across their subscribed feeds, most
+// recently starred first
+func (h *ArticleHandler) ListStarred(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	window, err := parseListWindow(c, defaultStarredLimit, maxStarredLimit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	articles, total, err := h.service.ListStarredArticles(ctx, userID, window.Offset, window.Limit)
+	if err != nil {
+		log.Error("failed to list starred articles", "user_id", userID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	if err := h.applySummaries(c, articles...); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !wantsEnvelope(c) {
+		if articles == nil {
+			articles = []*models.Article{}
+		}
+		c.JSON(http.StatusOK, articles)
+		return
+	}
+	writeListEnvelope(c, newListEnvelope(articles, window, total, false))
+}
+
+// ListTimeline returns the newest articles across the caller's subscribed feeds, newest
+// published first. The tag, unread, starred and folder_id query parameters narrow it to
+// the caller's tagged, unread or starred articles or the feeds of one folder. It always answers with the list envelope, whose next_cursor pages on without articles
+// published in between shifting the pages.
+func (h *ArticleHandler) ListTimeline(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	query := timelineQuery{Limit: defaultTimelineLimit}
+	if err := bindQuery(c, &query); err != nil {
+		c.Error(err)
+		return
+	}
+	pageToken, err := parseTokenCursor(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	filter := core.TimelineFilter{Tag: query.Tag, Unread: query.Unread, Starred: query.Starred, FolderID: query.FolderID}
+	articles, nextToken, total, err := h.service.ListUserArticles(ctx, userID, filter, query.Limit, pageToken)
+	if err != nil {
+		log.Error("failed to list timeline", "user_id", userID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	if err := h.applySummaries(c, articles...); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if articles == nil {
+		articles = []*models.Article{}
+	}
+	writeListEnvelope(c, ListEnvelope[*models.Article]{
+		Items:      articles,
+		NextCursor: encodeTokenCursor(nextToken),
+		Total:      total,
+	})
+}
+
+// MarkFeedRead marks every article of a subscribed feed read for the caller. The optional
+// before query parameter (RFC 3339) spares articles published after it, e.g. after the
+// client last loaded the list.
+func (h *ArticleHandler) MarkFeedRead(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	feedID, err := strconv.ParseUint(c.Param("feed_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.ErrInvalidFeedID)
+		return
+	}
+
+	var query markFeedReadQuery
+	if err := bindQuery(c, &query); err != nil {
+		c.Error(err)
+		return
+	}
+
+	marked, err := h.service.MarkFeedRead(ctx, userID, uint(feedID), query.Before)
+	if err != nil {
+		log.Error("failed to mark feed read", "user_id", userID, "feed_id", feedID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
+
+// RestoreArticle takes an article out of the trash within the grace period
+func (h *ArticleHandler) RestoreArticle(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	articleID, err := strconv.ParseUint(c.Param("article_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.NewValidationError("invalid article ID"))
+		return
+	}
+
+	if err := h.service.RestoreArticle(ctx, userID, uint(articleID)); err != nil {
+		log.Error("failed to restore article", "user_id", userID, "article_id", articleID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	article, err := h.articleRepo.GetByID(ctx, userID, uint(articleID))
+	if err != nil {
+		log.Error("failed to get restored article", "article_id", articleID, "error", err.Error())
 		c.Error(ierr.NewDatabaseError(err))
 		return
 	}
-	if !subscribed {
-		c.Error(ierr.ErrNotSubscribed)
+
+	c.JSON(http.StatusOK, article)
+}
+
+// ListTrash returns the restorable articles deleted from the user's subscribed feeds
+func (h *ArticleHandler) ListTrash(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
 		return
 	}
 
-	article, err := h.articleRepo.GetByID(ctx, uint(articleID))
+	window, err := parseListWindow(c, defaultListLimit, maxListLimit)
 	if err != nil {
-		log.Error("failed to get article", "article_id", articleID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	articles, total, err := h.articleRepo.ListDeletedForUser(ctx, userID, time.Now().UTC().Add(-h.trashGrace), window.Offset, window.Limit)
+	if err != nil {
+		log.Error("failed to list trashed articles", "user_id", userID, "error", err.Error())
 		c.Error(ierr.NewDatabaseError(err))
 		return
 	}
 
+	items := make([]TrashedArticle, len(articles))
+	for i, article := range articles {
+		items[i] = TrashedArticle{
+			Article:         article,
+			DeletedAt:       article.DeletedAt.Time,
+			RestorableUntil: article.DeletedAt.Time.Add(h.trashGrace),
+		}
+	}
+
+	if !wantsEnvelope(c) {
+		c.JSON(http.StatusOK, items)
+		return
+	}
+	writeListEnvelope(c, newListEnvelope(items, window, total, false))
+}
+
+// NextUnread returns the next unread article for j/k navigation. With mark_read=true the
+// article given in after is marked read in the same step. It responds 204 when nothing
+// unread is left in the scope.
+func (h *ArticleHandler) NextUnread(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	params := nextUnreadQuery{Scope: "all"}
+	if err := bindQuery(c, &params); err != nil {
+		c.Error(err)
+		return
+	}
+	query := core.NextUnreadQuery{
+		Scope:    params.Scope,
+		AfterID:  params.After,
+		FeedID:   params.FeedID,
+		FolderID: params.FolderID,
+		MarkRead: params.MarkRead,
+	}
+	if query.MarkRead && IsReadOnly(c) {
+		c.Error(readOnlyError(c))
+		return
+	}
+
+	nextID, err := h.service.NextUnreadArticle(ctx, userID, query)
+	if err != nil {
+		log.Error("failed to find next unread article", "user_id", userID, "after_id", query.AfterID, "scope", query.Scope, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	if nextID == 0 {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	article, err := h.articleRepo.GetByID(ctx, userID, nextID)
+	if err != nil {
+		log.Error("failed to get next unread article", "article_id", nextID, "error", err.Error())
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	if err := h.applySummaries(c, article); err != nil {
+		c.Error(err)
+		return
+	}
+
 	c.JSON(http.StatusOK, article)
 }
+
+// SearchArticles runs a full-text search over the articles of the user's subscribed feeds.
+// The q parameter takes web search syntax: quoted phrases, OR and -excluded words.
+func (h *ArticleHandler) SearchArticles(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	var search searchQuery
+	if err := bindQuery(c, &search); err != nil {
+		c.Error(err)
+		return
+	}
+	query := search.Q
+
+	window, err := parseListWindow(c, defaultSearchLimit, maxSearchLimit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	articles, total, err := h.service.SearchArticles(ctx, userID, query, window.Offset, window.Limit)
+	if err != nil {
+		log.Error("failed to search articles", "user_id", userID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	if err := h.applySummaries(c, articles...); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !wantsEnvelope(c) {
+		if articles == nil {
+			articles = []*models.Article{}
+		}
+		c.JSON(http.StatusOK, articles)
+		return
+	}
+	writeListEnvelope(c, newListEnvelope(articles, window, total, false))
+}
+
+// ExportArticle returns a single article as a Markdown or Org note for note-taking tools
+func (h *ArticleHandler) ExportArticle(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	query := exportQuery{Format: core.ExportFormatMarkdown}
+	if err := bindQuery(c, &query); err != nil {
+		c.Error(err)
+		return
+	}
+	format := query.Format
+	contentType, extension := "text/markdown; charset=utf-8", "md"
+	if format == core.ExportFormatOrg {
+		contentType, extension = "text/org; charset=utf-8", "org"
+	}
+
+	article, subscription, ok := h.subscribedArticle(c)
+	if !ok {
+		return
+	}
+
+	feedTitle := subscription.Feed.Title
+	if subscription.CustomTitle != nil {
+		feedTitle = *subscription.CustomTitle
+	}
+
+	data, err := core.ExportArticle(core.ArticleExport{Article: article, FeedTitle: feedTitle}, format)
+	if err != nil {
+		log.Error("failed to export article", "article_id", article.ID, "format", format, "error", err.Error())
+		c.Error(ierr.NewInternalError(errors.New("failed to export article")))
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=article-%d.%s", article.ID, extension))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// PlainArticle returns an article as normalized plain text, paragraphs separated by blank
+// lines and links footnoted, for screen readers, speech synthesis and LLM pipelines. The
+// text is cached per version of the article, and clients can revalidate with its ETag.
+func (h *ArticleHandler) PlainArticle(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	article, _, ok := h.subscribedArticle(c)
+	if !ok {
+		return
+	}
+
+	version := article.UpdatedAt.UTC().UnixNano()
+	etag := fmt.Sprintf(`"plain-%d-%d"`, article.ID, version)
+	// the API marks its responses uncacheable; this one may be kept if revalidated
+	c.Header("Cache-Control", "private, no-cache")
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", article.UpdatedAt.UTC().Format(http.TimeFormat))
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" && strings.Contains(ifNoneMatch, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	cacheKey := fmt.Sprintf(plainTextCacheKeyPattern, article.ID, version)
+	if h.cache != nil {
+		if cached, err := h.cache.Get(ctx, cacheKey).Bytes(); err == nil {
+			c.Data(http.StatusOK, "text/plain; charset=utf-8", cached)
+			return
+		} else if err != redis.Nil {
+			log.Warn("failed to read plain text cache", "article_id", article.ID, "error", err.Error())
+		}
+	}
+
+	data, err := core.ArticlePlainText(article)
+	if err != nil {
+		log.Error("failed to convert article to plain text", "article_id", article.ID, "error", err.Error())
+		c.Error(ierr.NewInternalError(errors.New("failed to convert article to plain text")))
+		return
+	}
+
+	if h.cache != nil {
+		if err := h.cache.Set(ctx, cacheKey, data, plainTextCacheTTL).Err(); err != nil {
+			log.Warn("failed to cache plain text", "article_id", article.ID, "error", err.Error())
+		}
+	}
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", data)
+}
+
+// subscribedArticle loads the article named by the article_id parameter, which must be in
+// a feed the user is subscribed to, along with that subscription. Errors are added to c.
+func (h *ArticleHandler) subscribedArticle(c *gin.Context) (*models.Article, *models.Subscription, bool) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return nil, nil, false
+	}
+
+	articleID, err := strconv.ParseUint(c.Param("article_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.NewValidationError("invalid article ID"))
+		return nil, nil, false
+	}
+
+	feedID, err := h.articleRepo.GetFeedID(ctx, uint(articleID))
+	if err != nil {
+		log.Error("failed to get article feed_id", "article_id", articleID, "error", err.Error())
+		c.Error(ierr.NewDatabaseError(err))
+		return nil, nil, false
+	}
+	if feedID == 0 {
+		c.Error(ierr.ErrArticleNotFound)
+		return nil, nil, false
+	}
+
+	subscription, err := h.subscriptionRepo.GetWithFeed(ctx, userID, feedID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Error(ierr.ErrNotSubscribed)
+			return nil, nil, false
+		}
+		log.Error("failed to get subscription", "user_id", userID, "feed_id", feedID, "error", err.Error())
+		c.Error(ierr.NewDatabaseError(err))
+		return nil, nil, false
+	}
+
+	article, err := h.articleRepo.GetByID(ctx, userID, uint(articleID))
+	if err != nil {
+		log.Error("failed to get article", "article_id", articleID, "error", err.Error())
+		c.Error(ierr.NewDatabaseError(err))
+		return nil, nil, false
+	}
+	return article, subscription, true
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/api-service/repository"
+	"github.com/Fancu1/phoenix-rss/internal/events"
+	feedcore "github.com/Fancu1/phoenix-rss/internal/feed-service/core"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+// claimOnce lets each article be claimed once
+type claimOnce map[uint]bool
+
+func (s claimOnce) Claim(_ context.Context, articleID uint, _ time.Duration) (bool, error) {
+	if s[articleID] {
+		return false, nil
+	}
+	s[articleID] = true
+	return true, nil
+}
+
+// checkEvents passes the published article checks on to the test
+type checkEvents chan events.ArticleCheckEvent
+
+func (p checkEvents) PublishArticleCheck(_ context.Context, event events.ArticleCheckEvent) error {
+	p <- event
+	return nil
+}
+
+func setupArticleHandler(t *testing.T) (*ArticleHandler, *gorm.DB) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_fk=1", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Feed{}, &models.Article{}, &models.Subscription{},
+		&models.UserArticleState{}, &models.Tag{}, &models.ArticleTag{}, &models.ArticleSummary{}))
+	return NewArticleHandler(nil, repository.NewSubscriptionRepository(db), repository.NewArticleRepository(db), 0), db
+}
+
+func TestArticleHandler_GetArticleQueuesOnReadCheck(t *testing.T) {
+	h, db := setupArticleHandler(t)
+	published := checkEvents(make(chan events.ArticleCheckEvent, 1))
+	h.SetReadRechecker(feedcore.NewReadRechecker(published, claimOnce{}, feedcore.ReadRecheckConfig{
+		MinAge:        48 * time.Hour,
+		CheckedWithin: 12 * time.Hour,
+		Cooldown:      time.Hour,
+	}))
+
+	feed := &models.Feed{Title: "Feed", URL: "https://example.com/feed.xml"}
+	require.NoError(t, db.Create(feed).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feed.ID}).Error)
+	etag := `"v1"`
+	old := &models.Article{FeedID: feed.ID, Title: "old", URL: "https://example.com/old",
+		PublishedAt: time.Now().Add(-30 * 24 * time.Hour), HTTPETag: &etag}
+	fresh := &models.Article{FeedID: feed.ID, Title: "fresh", URL: "https://example.com/fresh",
+		PublishedAt: time.Now().Add(-time.Hour)}
+	require.NoError(t, db.Create(old).Error)
+	require.NoError(t, db.Create(fresh).Error)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(ierr.ErrorHandlerMiddleware())
+	engine.GET("/articles/:article_id", func(c *gin.Context) { c.Set("userID", uint(1)) }, h.GetArticle)
+	open := func(articleID uint) int {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/articles/%d", articleID), nil))
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, open(old.ID))
+	select {
+	case event := <-published:
+		assert.Equal(t, old.ID, event.ArticleID)
+		assert.Equal(t, feed.ID, event.FeedID)
+		assert.Equal(t, old.URL, event.URL)
+		assert.Equal(t, feedcore.ReasonOnRead, event.Reason)
+		assert.Equal(t, etag, event.PrevETag)
+	case <-time.After(5 * time.Second):
+		t.Fatal("opening an old article queued no check")
+	}
+
+	// the cooldown holds off a second check, and recent articles are not checked
+	require.Equal(t, http.StatusOK, open(old.ID))
+	require.Equal(t, http.StatusOK, open(fresh.ID))
+	select {
+	case event := <-published:
+		t.Fatalf("unexpected check of article %d", event.ArticleID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Fancu1/phoenix-rss/internal/briefing"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+const (
+	// maxBriefingWindow bounds how far back a briefing reaches
+	maxBriefingWindow = 7 * 24 * time.Hour
+	// maxBriefingLanguageLength matches the summary language codes readers may prefer
+	maxBriefingLanguageLength = 16
+)
+
+// briefingQuery picks the time a briefing starts at and its language
+type briefingQuery struct {
+	Since    time.Time `form:"since"`
+	Language string    `form:"summary_language"`
+	Refresh  bool      `form:"refresh"`
+}
+
+func (q *briefingQuery) validate() []string {
+	var problems []string
+	now := time.Now()
+	switch {
+	case q.Since.IsZero():
+		problems = append(problems, "since is required")
+	case q.Since.After(now):
+		problems = append(problems, "since must not be in the future")
+	case now.Sub(q.Since) > maxBriefingWindow:
+		problems = append(problems, "since must be within the last 7 days")
+	}
+
+	q.Language = strings.ToLower(strings.TrimSpace(q.Language))
+	if len(q.Language) > maxBriefingLanguageLength || strings.IndexFunc(q.Language, func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-'
+	}) >= 0 {
+		problems = append(problems, "summary_language must be a language code such as zh or pt-br")
+	}
+	return problems
+}
+
+// BriefingHandler serves "catch me up" briefings
+type BriefingHandler struct {
+	service *briefing.Service
+}
+
+func NewBriefingHandler(service *briefing.Service) *BriefingHandler {
+	return &BriefingHandler{service: service}
+}
+
+// CreateBriefing groups the headlines the user has not read since the given time into
+// topics with their key stories, written by the LLM
+func (h *BriefingHandler) CreateBriefing(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	var query briefingQuery
+	if err := bindQuery(c, &query); err != nil {
+		c.Error(err)
+		return
+	}
+
+	result, err := h.service.Generate(ctx, briefing.Request{
+		UserID:   userID,
+		Since:    query.Since,
+		Language: query.Language,
+		Refresh:  query.Refresh,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to generate briefing", "user_id", userID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
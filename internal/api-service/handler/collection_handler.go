@@ -0,0 +1,342 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Fancu1/phoenix-rss/internal/api-service/core"
+	"github.com/Fancu1/phoenix-rss/internal/api-service/repository"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// maxCollectionFeeds caps the feeds of one collection, so subscribing to it stays a
+// single batch
+const maxCollectionFeeds = 500
+
+var collectionSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// CollectionHandler serves the curated feed collections: discovery and one-click
+// subscription for users, and their management for operators
+type CollectionHandler struct {
+	collectionRepo *repository.CollectionRepository
+	feedService    core.FeedServiceInterface
+	cache          redis.Cmdable
+}
+
+func NewCollectionHandler(collectionRepo *repository.CollectionRepository, feedService core.FeedServiceInterface, cache redis.Cmdable) *CollectionHandler {
+	return &CollectionHandler{collectionRepo: collectionRepo, feedService: feedService, cache: cache}
+}
+
+// CollectionRequest creates or replaces a collection. Feeds keep the order given.
+type CollectionRequest struct {
+	Slug              string                  `json:"slug" binding:"required"`
+	Name              string                  `json:"name" binding:"required,max=255"`
+	Description       string                  `json:"description"`
+	SubscribeNewUsers bool                    `json:"subscribe_new_users"`
+	Feeds             []CollectionFeedRequest `json:"feeds" binding:"required,min=1,dive"`
+}
+
+type CollectionFeedRequest struct {
+	URL         string `json:"url" binding:"required,url"`
+	Title       string `json:"title" binding:"max=255"`
+	Description string `json:"description"`
+}
+
+// CollectionSubscribeResult reports a subscription to the feeds of collections
+type CollectionSubscribeResult struct {
+	Subscribed        int      `json:"subscribed"`
+	AlreadySubscribed int      `json:"already_subscribed"`
+	Failed            int      `json:"failed"`
+	FailedURLs        []string `json:"failed_urls"`
+}
+
+// ListCollections returns every collection, marking the feeds the caller already follows
+func (h *CollectionHandler) ListCollections(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	collections, err := h.collectionRepo.List(ctx)
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	if err := h.collectionRepo.MarkSubscribed(ctx, userID, collections...); err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, collections)
+}
+
+// GetCollection returns one collection, marking the feeds the caller already follows
+func (h *CollectionHandler) GetCollection(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	collection, err := h.loadCollection(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if err := h.collectionRepo.MarkSubscribed(ctx, userID, collection); err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+// SubscribeToCollection subscribes the caller to every feed of a collection they do not
+// follow yet
+func (h *CollectionHandler) SubscribeToCollection(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	collection, err := h.loadCollection(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	result, err := subscribeToCollections(ctx, h.feedService, userID, collection)
+	if err != nil {
+		log.Error("failed to subscribe to collection", "user_id", userID, "collection_id", collection.ID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	if result.Subscribed > 0 && h.cache != nil {
+		cacheKey := fmt.Sprintf(userFeedsCacheKeyPattern, userID)
+		if err := h.cache.Del(ctx, cacheKey).Err(); err != nil && err != redis.Nil {
+			log.Warn("failed to invalidate user feeds cache", "user_id", userID, "error", err.Error())
+		}
+	}
+
+	log.Info("user subscribed to collection", "user_id", userID, "collection_id", collection.ID, "subscribed", result.Subscribed, "failed", result.Failed)
+	c.JSON(http.StatusOK, result)
+}
+
+// AdminListCollections returns every collection for management
+func (h *CollectionHandler) AdminListCollections(c *gin.Context) {
+	collections, err := h.collectionRepo.List(c.Request.Context())
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	c.JSON(http.StatusOK, collections)
+}
+
+// CreateCollection adds a collection
+func (h *CollectionHandler) CreateCollection(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	collection, err := h.bindCollection(c, 0)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if err := h.collectionRepo.Create(ctx, collection); err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+
+	logger.FromContext(ctx).Info("admin created collection", "collection_id", collection.ID, "slug", collection.Slug, "feeds", len(collection.Feeds))
+	c.JSON(http.StatusCreated, collection)
+}
+
+// UpdateCollection replaces a collection's details and feeds
+func (h *CollectionHandler) UpdateCollection(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	collectionID, err := parseCollectionID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	collection, err := h.bindCollection(c, collectionID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	updated, err := h.collectionRepo.Update(ctx, collection)
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	if !updated {
+		c.Error(fmt.Errorf("collection %d: %w", collectionID, ierr.ErrCollectionNotFound))
+		return
+	}
+
+	// reload for the timestamps the update did not touch
+	stored, err := h.collectionRepo.Get(ctx, collectionID)
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+
+	logger.FromContext(ctx).Info("admin updated collection", "collection_id", collectionID, "slug", collection.Slug, "feeds", len(collection.Feeds))
+	c.JSON(http.StatusOK, stored)
+}
+
+// DeleteCollection removes a collection. Subscriptions made through it are kept.
+func (h *CollectionHandler) DeleteCollection(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	collectionID, err := parseCollectionID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	deleted, err := h.collectionRepo.Delete(ctx, collectionID)
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	if !deleted {
+		c.Error(fmt.Errorf("collection %d: %w", collectionID, ierr.ErrCollectionNotFound))
+		return
+	}
+
+	logger.FromContext(ctx).Info("admin deleted collection", "collection_id", collectionID)
+	c.JSON(http.StatusOK, gin.H{"message": "Collection deleted"})
+}
+
+func parseCollectionID(c *gin.Context) (uint, error) {
+	collectionID, err := strconv.ParseUint(c.Param("collection_id"), 10, 32)
+	if err != nil || collectionID == 0 {
+		return 0, ierr.NewValidationError("invalid collection ID")
+	}
+	return uint(collectionID), nil
+}
+
+func (h *CollectionHandler) loadCollection(c *gin.Context) (*models.Collection, error) {
+	collectionID, err := parseCollectionID(c)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := h.collectionRepo.Get(c.Request.Context(), collectionID)
+	if err != nil {
+		return nil, ierr.NewDatabaseError(err)
+	}
+	if collection == nil {
+		return nil, fmt.Errorf("collection %d: %w", collectionID, ierr.ErrCollectionNotFound)
+	}
+	return collection, nil
+}
+
+// bindCollection validates a CollectionRequest into the collection with the given ID,
+// 0 for a new one
+func (h *CollectionHandler) bindCollection(c *gin.Context, collectionID uint) (*models.Collection, error) {
+	var req CollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return nil, ierr.NewValidationError(err.Error())
+	}
+
+	req.Slug = strings.TrimSpace(req.Slug)
+	if len(req.Slug) > 100 || !collectionSlugPattern.MatchString(req.Slug) {
+		return nil, ierr.NewValidationError("slug must be at most 100 lowercase letters, digits and single hyphens")
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, ierr.NewValidationError("name must not be empty")
+	}
+	if len(req.Feeds) > maxCollectionFeeds {
+		return nil, ierr.NewValidationError(fmt.Sprintf("at most %d feeds are allowed", maxCollectionFeeds))
+	}
+
+	collection := &models.Collection{
+		ID:                collectionID,
+		Slug:              req.Slug,
+		Name:              req.Name,
+		Description:       strings.TrimSpace(req.Description),
+		SubscribeNewUsers: req.SubscribeNewUsers,
+		Feeds:             make([]models.CollectionFeed, len(req.Feeds)),
+	}
+	seen := make(map[string]bool, len(req.Feeds))
+	for i, feed := range req.Feeds {
+		url := strings.TrimSpace(feed.URL)
+		if seen[url] {
+			return nil, ierr.NewValidationError(fmt.Sprintf("feed %s is listed twice", url))
+		}
+		seen[url] = true
+		collection.Feeds[i] = models.CollectionFeed{
+			URL:         url,
+			Title:       strings.TrimSpace(feed.Title),
+			Description: strings.TrimSpace(feed.Description),
+		}
+	}
+
+	taken, err := h.collectionRepo.SlugTaken(c.Request.Context(), collection.Slug, collectionID)
+	if err != nil {
+		return nil, ierr.NewDatabaseError(err)
+	}
+	if taken {
+		return nil, fmt.Errorf("collection slug %q: %w", collection.Slug, ierr.ErrCollectionExists)
+	}
+	return collection, nil
+}
+
+// subscribeToCollections subscribes the user to the feeds of the collections in one
+// batch, each feed once
+func subscribeToCollections(ctx context.Context, feedService core.FeedServiceInterface, userID uint, collections ...*models.Collection) (*CollectionSubscribeResult, error) {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, collection := range collections {
+		for _, url := range collection.URLs() {
+			if !seen[url] {
+				seen[url] = true
+				urls = append(urls, url)
+			}
+		}
+	}
+
+	result := &CollectionSubscribeResult{FailedURLs: make([]string, 0)}
+	if len(urls) == 0 {
+		return result, nil
+	}
+
+	results, imported, _, err := feedService.BatchSubscribeToFeeds(ctx, userID, urls)
+	if err != nil {
+		return nil, err
+	}
+	result.Subscribed = imported
+	for _, r := range results {
+		switch {
+		case r.Success:
+		case r.Error == "already subscribed":
+			result.AlreadySubscribed++
+		default:
+			result.Failed++
+			result.FailedURLs = append(result.FailedURLs, r.URL)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+// readOnlyKey marks requests served by a read-only demo instance or made with an
+// impersonation token
+const readOnlyKey = "readOnly"
+
+// DemoModeMiddleware serves the API read-only for a public demo. Requests other than
+// GET, HEAD and OPTIONS are refused with ierr.ErrReadOnlyDemo unless their route is one
+// of writable, such as login. Successful reads may be reused by clients for cacheTTL.
+func DemoModeMiddleware(cacheTTL time.Duration, writable ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(writable))
+	for _, route := range writable {
+		allowed[route] = true
+	}
+	cacheControl := fmt.Sprintf("private, max-age=%d", int(cacheTTL.Seconds()))
+
+	return func(c *gin.Context) {
+		c.Set(readOnlyKey, true)
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead:
+			if cacheTTL > 0 {
+				c.Writer = &successCacheWriter{ResponseWriter: c.Writer, cacheControl: cacheControl}
+			}
+		case http.MethodOptions:
+		default:
+			if !allowed[c.Request.Method+" "+c.FullPath()] {
+				ierr.AbortWithError(c, ierr.ErrReadOnlyDemo)
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// IsReadOnly tells whether the request is served by a read-only demo instance or made
+// with an impersonation token, for the few reads that can also write, such as
+// next-unread with mark_read
+func IsReadOnly(c *gin.Context) bool {
+	return c.GetBool(readOnlyKey)
+}
+
+// readOnlyError is the error refusing a write to a read-only request
+func readOnlyError(c *gin.Context) error {
+	if impersonator, ok := GetImpersonatorFromContext(c); ok {
+		return ierr.ErrImpersonationReadOnly.WithCause(fmt.Errorf("%s impersonating user %d", impersonator, c.GetUint("userID")))
+	}
+	return ierr.ErrReadOnlyDemo
+}
+
+// successCacheWriter lets clients cache successful responses only, so an error is not
+// served again from their cache
+type successCacheWriter struct {
+	gin.ResponseWriter
+	cacheControl string
+}
+
+func (w *successCacheWriter) WriteHeader(code int) {
+	if code >= 200 && code < 300 {
+		w.Header().Set("Cache-Control", w.cacheControl)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
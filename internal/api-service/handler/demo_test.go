@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+func newDemoEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(ierr.ErrorHandlerMiddleware())
+	api := engine.Group("/api/v1")
+	api.Use(APIHeadersMiddleware())
+	api.Use(DemoModeMiddleware(time.Hour, "POST /api/v1/users/login"))
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"read_only": IsReadOnly(c)}) }
+	api.GET("/feeds", ok)
+	api.GET("/feeds/:feed_id", func(c *gin.Context) { c.Error(ierr.ErrFeedNotFound) })
+	api.POST("/feeds", ok)
+	api.DELETE("/feeds/:feed_id", ok)
+	api.POST("/users/login", ok)
+	api.POST("/users/register", ok)
+	return engine
+}
+
+func TestDemoModeMiddleware(t *testing.T) {
+	engine := newDemoEngine()
+
+	for _, tc := range []struct {
+		method, path string
+		status       int
+		cacheControl string
+	}{
+		{http.MethodGet, "/api/v1/feeds", http.StatusOK, "private, max-age=3600"},
+		{http.MethodGet, "/api/v1/feeds/7", http.StatusNotFound, "no-cache, no-store, must-revalidate"},
+		{http.MethodPost, "/api/v1/feeds", http.StatusForbidden, "no-cache, no-store, must-revalidate"},
+		{http.MethodDelete, "/api/v1/feeds/7", http.StatusForbidden, "no-cache, no-store, must-revalidate"},
+		{http.MethodPost, "/api/v1/users/register", http.StatusForbidden, "no-cache, no-store, must-revalidate"},
+		{http.MethodPost, "/api/v1/users/login", http.StatusOK, "no-cache, no-store, must-revalidate"},
+	} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Equal(t, tc.status, w.Code, "%s %s", tc.method, tc.path)
+		assert.Equal(t, tc.cacheControl, w.Header().Get("Cache-Control"), "%s %s", tc.method, tc.path)
+		if tc.status == http.StatusForbidden {
+			assert.Contains(t, w.Body.String(), ierr.ErrReadOnlyDemo.Message)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/api-service/repository"
+	"github.com/Fancu1/phoenix-rss/internal/archive"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/objectstore"
+)
+
+// archiveExportQuery picks the format of a new feed archive
+type archiveExportQuery struct {
+	Format string `form:"format"`
+	format archive.Format
+}
+
+func (q *archiveExportQuery) validate() []string {
+	format, err := archive.ParseFormat(q.Format)
+	if err != nil {
+		return []string{fmt.Sprintf("format must be one of %s, %s", archive.FormatJSONL, archive.FormatWARC)}
+	}
+	q.format = format
+	return nil
+}
+
+// ExportHandler queues archives of every article of a feed and serves them once the
+// feed-service has written them
+type ExportHandler struct {
+	exports          *archive.Store
+	objects          objectstore.Store
+	subscriptionRepo *repository.SubscriptionRepository
+}
+
+func NewExportHandler(exports *archive.Store, objects objectstore.Store, subscriptionRepo *repository.SubscriptionRepository) *ExportHandler {
+	return &ExportHandler{exports: exports, objects: objects, subscriptionRepo: subscriptionRepo}
+}
+
+// CreateExport queues an export of a feed the user is subscribed to
+func (h *ExportHandler) CreateExport(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	feedID, err := strconv.ParseUint(c.Param("feed_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.ErrInvalidFeedID)
+		return
+	}
+
+	subscribed, err := h.subscriptionRepo.IsUserSubscribed(ctx, userID, uint(feedID))
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	if !subscribed {
+		c.Error(ierr.ErrNotSubscribed)
+		return
+	}
+
+	h.create(c, uint(feedID), &userID)
+}
+
+// GetExport returns one of the user's exports
+func (h *ExportHandler) GetExport(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	if export, ok := h.load(c, &userID); ok {
+		c.JSON(http.StatusOK, export)
+	}
+}
+
+// DownloadExport streams the archive of one of the user's finished exports
+func (h *ExportHandler) DownloadExport(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+	if export, ok := h.load(c, &userID); ok {
+		h.download(c, export)
+	}
+}
+
+// AdminCreateExport queues an export of any feed. It is made for the administrator
+// calling with their own token, or for no one with the admin token.
+func (h *ExportHandler) AdminCreateExport(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	feedID, err := strconv.ParseUint(c.Param("feed_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.ErrInvalidFeedID)
+		return
+	}
+	found, err := h.exports.FeedExists(ctx, uint(feedID))
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	if !found {
+		c.Error(ierr.ErrFeedNotFound)
+		return
+	}
+
+	var requester *uint
+	if userID, exists := GetUserIDFromContext(c); exists {
+		requester = &userID
+	}
+	h.create(c, uint(feedID), requester)
+}
+
+// AdminGetExport returns any export
+func (h *ExportHandler) AdminGetExport(c *gin.Context) {
+	if export, ok := h.load(c, nil); ok {
+		c.JSON(http.StatusOK, export)
+	}
+}
+
+// AdminDownloadExport streams the archive of any finished export
+func (h *ExportHandler) AdminDownloadExport(c *gin.Context) {
+	if export, ok := h.load(c, nil); ok {
+		h.download(c, export)
+	}
+}
+
+// create queues the export, or returns the one the requester already has queued or
+// running for the feed in that format
+func (h *ExportHandler) create(c *gin.Context, feedID uint, requester *uint) {
+	ctx := c.Request.Context()
+
+	var query archiveExportQuery
+	if err := bindQuery(c, &query); err != nil {
+		c.Error(err)
+		return
+	}
+
+	export, created, err := h.exports.Create(ctx, feedID, requester, query.format)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to queue export", "feed_id", feedID, "error", err.Error())
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	if created {
+		logger.FromContext(ctx).Info("queued article export", "export_id", export.ID, "feed_id", feedID, "format", export.Format)
+	}
+	c.JSON(http.StatusAccepted, export)
+}
+
+// load reads the export of the path, reporting exports of other users than owner as not
+// found; a nil owner may see every export
+func (h *ExportHandler) load(c *gin.Context, owner *uint) (*archive.Export, bool) {
+	exportID, err := strconv.ParseUint(c.Param("export_id"), 10, 32)
+	if err != nil || exportID == 0 {
+		c.Error(ierr.NewValidationError("invalid export ID"))
+		return nil, false
+	}
+
+	export, err := h.exports.Get(c.Request.Context(), uint(exportID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Error(ierr.ErrExportNotFound)
+		return nil, false
+	}
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return nil, false
+	}
+	if owner != nil && (export.UserID == nil || *export.UserID != *owner) {
+		c.Error(ierr.ErrExportNotFound)
+		return nil, false
+	}
+	return export, true
+}
+
+func (h *ExportHandler) download(c *gin.Context, export *archive.Export) {
+	ctx := c.Request.Context()
+	if export.Status != archive.StatusSucceeded {
+		c.Error(ierr.ErrExportNotReady.WithCause(fmt.Errorf("export %d is %s", export.ID, export.Status)))
+		return
+	}
+
+	reader, err := h.objects.Get(ctx, export.ObjectKey)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to open export archive", "export_id", export.ID, "key", export.ObjectKey, "error", err.Error())
+		if errors.Is(err, objectstore.ErrNotFound) {
+			c.Error(ierr.ErrExportNotFound.WithCause(err))
+			return
+		}
+		c.Error(ierr.NewInternalError(err))
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, export.Bytes, "application/gzip", reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%s", export.FileName()),
+	})
+}
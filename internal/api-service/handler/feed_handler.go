@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,22 +22,36 @@ import (
 type FeedHandler struct {
 	feedService      core.FeedServiceInterface
 	subscriptionRepo *repository.SubscriptionRepository
+	folderRepo       *repository.FolderRepository
 	cache            redis.Cmdable
+	cacheTTL         time.Duration
 }
 
-func NewFeedHandler(feedService core.FeedServiceInterface, subscriptionRepo *repository.SubscriptionRepository, cache redis.Cmdable) *FeedHandler {
+func NewFeedHandler(feedService core.FeedServiceInterface, subscriptionRepo *repository.SubscriptionRepository, folderRepo *repository.FolderRepository, cache redis.Cmdable) *FeedHandler {
 	return &FeedHandler{
 		feedService:      feedService,
 		subscriptionRepo: subscriptionRepo,
+		folderRepo:       folderRepo,
 		cache:            cache,
+		cacheTTL:         userFeedsCacheTTL,
 	}
 }
 
+// SetCacheTTL changes how long a user's feed list stays cached
+func (h *FeedHandler) SetCacheTTL(ttl time.Duration) {
+	h.cacheTTL = ttl
+}
+
 const (
-	userFeedsCacheKeyPattern = "user:%d:feeds"
+	userFeedsCacheKeyPattern = models.UserFeedsCacheKeyPattern
 	userFeedsCacheTTL        = 15 * time.Minute
 )
 
+// feedListQuery narrows the caller's feeds to those matching the search query Q
+type feedListQuery struct {
+	Q string `form:"q"`
+}
+
 type AddFeedRequest struct {
 	URL string `json:"url" binding:"required,url"`
 }
@@ -86,20 +101,55 @@ func (h *FeedHandler) ListFeeds(c *gin.Context) {
 		return
 	}
 
-	if cachedFeeds, ok := h.getCachedUserFeeds(ctx, userID); ok {
-		c.JSON(http.StatusOK, cachedFeeds)
+	var query feedListQuery
+	if err := bindQuery(c, &query); err != nil {
+		c.Error(err)
 		return
 	}
 
-	feeds, err := h.subscriptionRepo.ListUserFeeds(ctx, userID)
-	if err != nil {
-		log.Error("failed to list user feeds", "user_id", userID, "error", err.Error())
-		c.Error(ierr.NewDatabaseError(err))
+	var window listWindow
+	envelope := wantsEnvelope(c)
+	if envelope {
+		var err error
+		if window, err = parseListWindow(c, defaultListLimit, maxListLimit); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	feeds, cached := h.getCachedUserFeeds(ctx, userID)
+	if !cached {
+		var err error
+		feeds, err = h.subscriptionRepo.ListUserFeeds(ctx, userID)
+		if err != nil {
+			log.Error("failed to list user feeds", "user_id", userID, "error", err.Error())
+			c.Error(ierr.NewDatabaseError(err))
+			return
+		}
+		h.setCachedUserFeeds(ctx, userID, feeds)
+	}
+
+	feeds = filterUserFeeds(feeds, query.Q)
+	if !envelope {
+		c.JSON(http.StatusOK, feeds)
 		return
 	}
+	writeListEnvelope(c, newListEnvelope(windowOf(feeds, window), window, int64(len(feeds)), cached))
+}
+
+// filterUserFeeds keeps the feeds whose title, custom title, URL or notes match the search query
+func filterUserFeeds(feeds []*models.UserFeed, query string) []*models.UserFeed {
+	if strings.TrimSpace(query) == "" {
+		return feeds
+	}
 
-	h.setCachedUserFeeds(ctx, userID, feeds)
-	c.JSON(http.StatusOK, feeds)
+	matched := make([]*models.UserFeed, 0, len(feeds))
+	for _, feed := range feeds {
+		if feed.Matches(query) {
+			matched = append(matched, feed)
+		}
+	}
+	return matched
 }
 
 func (h *FeedHandler) UnsubscribeFeed(c *gin.Context) {
@@ -139,8 +189,192 @@ func (h *FeedHandler) UnsubscribeFeed(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "successfully unsubscribed from feed"})
 }
 
+// maxBatchUnsubscribe caps the feeds of one batch unsubscribe request
+const maxBatchUnsubscribe = 500
+
+type UnsubscribeFeedsRequest struct {
+	FeedIDs []uint `json:"feed_ids" binding:"required,min=1"`
+}
+
+// UnsubscribeFeeds removes several subscriptions at once. Feeds the user is not
+// subscribed to are reported rather than failing the request.
+func (h *FeedHandler) UnsubscribeFeeds(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	var req UnsubscribeFeedsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+	if len(req.FeedIDs) > maxBatchUnsubscribe {
+		c.Error(ierr.NewValidationError(fmt.Sprintf("at most %d feed_ids are allowed", maxBatchUnsubscribe)))
+		return
+	}
+
+	unsubscribed, err := h.subscriptionRepo.DeleteMany(ctx, userID, req.FeedIDs)
+	if err != nil {
+		log.Error("failed to delete subscriptions", "user_id", userID, "feeds", len(req.FeedIDs), "error", err.Error())
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+
+	deleted := make(map[uint]bool, len(unsubscribed))
+	for _, feedID := range unsubscribed {
+		deleted[feedID] = true
+	}
+	notSubscribed := make([]uint, 0)
+	for _, feedID := range req.FeedIDs {
+		if !deleted[feedID] {
+			notSubscribed = append(notSubscribed, feedID)
+			deleted[feedID] = true // report duplicates once
+		}
+	}
+
+	if len(unsubscribed) > 0 {
+		h.invalidateUserFeedsCache(ctx, userID)
+	}
+	log.Info("batch unsubscribed", "user_id", userID, "unsubscribed", len(unsubscribed), "not_subscribed", len(notSubscribed))
+	c.JSON(http.StatusOK, gin.H{"unsubscribed": unsubscribed, "not_subscribed": notSubscribed})
+}
+
+// cleanupMinDelivered is how many unread deliveries make a feed a cleanup suggestion
+const cleanupMinDelivered = 10
+
+// CleanupSuggestions lists the caller's feeds that delivered articles over the last 90
+// days without any being read. feed_ids unsubscribes them all through UnsubscribeFeeds.
+func (h *FeedHandler) CleanupSuggestions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	suggestions, err := h.subscriptionRepo.ListCleanupSuggestions(ctx, userID, cleanupMinDelivered)
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+
+	feedIDs := make([]uint, len(suggestions))
+	for i, suggestion := range suggestions {
+		feedIDs[i] = suggestion.FeedID
+	}
+	if suggestions == nil {
+		suggestions = []models.CleanupSuggestion{}
+	}
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions, "feed_ids": feedIDs})
+}
+
+// GetFeedHealth reports when a subscribed feed was last fetched, its last error and how
+// many fetches in a row have failed
+func (h *FeedHandler) GetFeedHealth(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	feedID, err := strconv.ParseUint(c.Param("feed_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.ErrInvalidFeedID)
+		return
+	}
+
+	health, err := h.feedService.GetFeedHealth(ctx, userID, uint(feedID))
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to get feed health", "user_id", userID, "feed_id", feedID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, health)
+}
+
+// UpdateFeedRequest changes subscription settings. Omitted fields are left unchanged;
+// null or an empty string clears a setting, and a null folder_id unfiles the feed.
 type UpdateFeedRequest struct {
-	CustomTitle *string `json:"custom_title"`
+	CustomTitle  optionalString  `json:"custom_title"`
+	Notes        optionalString  `json:"notes"`
+	FolderID     optionalID      `json:"folder_id"`
+	FetchHeaders optionalHeaders `json:"fetch_headers"`
+}
+
+// optionalString tells an omitted JSON field apart from an explicit null
+type optionalString struct {
+	Set   bool
+	Value *string
+}
+
+func (o *optionalString) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
+
+// ptr returns the update value: nil when omitted, "" when cleared
+func (o optionalString) ptr() *string {
+	if !o.Set {
+		return nil
+	}
+	if o.Value == nil {
+		empty := ""
+		return &empty
+	}
+	return o.Value
+}
+
+// optionalID tells an omitted JSON ID apart from an explicit null
+type optionalID struct {
+	Set   bool
+	Value *uint
+}
+
+func (o *optionalID) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
+
+// ptr returns the update value: nil when omitted, 0 when cleared
+func (o optionalID) ptr() *uint {
+	if !o.Set {
+		return nil
+	}
+	if o.Value == nil {
+		var none uint
+		return &none
+	}
+	return o.Value
+}
+
+// optionalHeaders tells an omitted fetch_headers object apart from null or {}, which clear them
+type optionalHeaders struct {
+	Set   bool
+	Value map[string]string
+}
+
+func (o *optionalHeaders) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Value)
+}
+
+// headers returns the update value: nil when omitted, an empty map when cleared
+func (o optionalHeaders) headers() map[string]string {
+	if !o.Set {
+		return nil
+	}
+	if o.Value == nil {
+		return map[string]string{}
+	}
+	return o.Value
 }
 
 func (h *FeedHandler) UpdateFeed(c *gin.Context) {
@@ -165,6 +399,32 @@ func (h *FeedHandler) UpdateFeed(c *gin.Context) {
 		return
 	}
 
+	update := models.SubscriptionUpdate{
+		CustomTitle:  req.CustomTitle.ptr(),
+		Notes:        req.Notes.ptr(),
+		FolderID:     req.FolderID.ptr(),
+		FetchHeaders: req.FetchHeaders.headers(),
+	}
+	if update.IsEmpty() {
+		c.Error(ierr.NewValidationError("nothing to update: set custom_title, notes, folder_id and/or fetch_headers"))
+		return
+	}
+	if err := update.Validate(); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+	if update.FolderID != nil && *update.FolderID != 0 {
+		folder, err := h.folderRepo.Get(ctx, userID, *update.FolderID)
+		if err != nil {
+			c.Error(ierr.NewDatabaseError(err))
+			return
+		}
+		if folder == nil {
+			c.Error(fmt.Errorf("folder %d: %w", *update.FolderID, ierr.ErrFolderNotFound))
+			return
+		}
+	}
+
 	subscribed, err := h.subscriptionRepo.IsUserSubscribed(ctx, userID, uint(feedID))
 	if err != nil {
 		log.Error("failed to check subscription", "user_id", userID, "feed_id", feedID, "error", err.Error())
@@ -176,8 +436,23 @@ func (h *FeedHandler) UpdateFeed(c *gin.Context) {
 		return
 	}
 
-	if err := h.subscriptionRepo.UpdateCustomTitle(ctx, userID, uint(feedID), req.CustomTitle); err != nil {
-		log.Error("failed to update custom title", "user_id", userID, "feed_id", feedID, "error", err.Error())
+	if update.FetchHeaders != nil {
+		// fetch headers are encrypted by the feed service, so these updates go through it
+		if err := h.feedService.UpdateSubscription(ctx, userID, uint(feedID), update); err != nil {
+			log.Error("failed to update subscription", "user_id", userID, "feed_id", feedID, "error", err.Error())
+			c.Error(err)
+			return
+		}
+		// folders are kept by the API service alone
+		if update.FolderID != nil {
+			if err := h.subscriptionRepo.Update(ctx, userID, uint(feedID), models.SubscriptionUpdate{FolderID: update.FolderID}); err != nil {
+				log.Error("failed to file subscription", "user_id", userID, "feed_id", feedID, "error", err.Error())
+				c.Error(ierr.NewDatabaseError(err))
+				return
+			}
+		}
+	} else if err := h.subscriptionRepo.Update(ctx, userID, uint(feedID), update); err != nil {
+		log.Error("failed to update subscription", "user_id", userID, "feed_id", feedID, "error", err.Error())
 		c.Error(ierr.NewDatabaseError(err))
 		return
 	}
@@ -190,28 +465,7 @@ func (h *FeedHandler) UpdateFeed(c *gin.Context) {
 	}
 
 	h.invalidateUserFeedsCache(ctx, userID)
-	c.JSON(http.StatusOK, &models.UserFeed{
-		Feed:        sub.Feed,
-		CustomTitle: sub.CustomTitle,
-	})
-}
-
-// Keep the old method for backward compatibility (will be deprecated)
-func (h *FeedHandler) ListAllFeeds(c *gin.Context) {
-	// Get contextual logger for this request
-	log := logger.FromContext(c.Request.Context())
-
-	log.Info("listing all feeds (deprecated endpoint)")
-
-	feeds, err := h.feedService.ListAllFeeds(c.Request.Context())
-	if err != nil {
-		log.Error("failed to list all feeds", "error", err.Error())
-		c.Error(err)
-		return
-	}
-
-	log.Info("successfully retrieved all feeds", "feed_count", len(feeds))
-	c.JSON(http.StatusOK, feeds)
+	c.JSON(http.StatusOK, sub.UserFeed())
 }
 
 func (h *FeedHandler) cacheKeyForUserFeeds(userID uint) string {
@@ -253,7 +507,7 @@ func (h *FeedHandler) setCachedUserFeeds(ctx context.Context, userID uint, feeds
 		return
 	}
 
-	if err := h.cache.Set(ctx, cacheKey, payload, userFeedsCacheTTL).Err(); err != nil {
+	if err := h.cache.Set(ctx, cacheKey, payload, h.cacheTTL).Err(); err != nil {
 		logger.FromContext(ctx).Warn("failed to store user feeds cache", "user_id", userID, "error", err.Error())
 	}
 }
@@ -0,0 +1,548 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Fancu1/phoenix-rss/internal/api-service/repository"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// sharedFolderCacheKeyPattern keys the cached public page of a shared folder by slug
+const sharedFolderCacheKeyPattern = "shared_folder:%s"
+
+// FolderHandler serves the folders users file their subscriptions in, and the public
+// pages of those they share. Subscriptions are filed through PATCH /feeds/:feed_id.
+type FolderHandler struct {
+	folderRepo *repository.FolderRepository
+	cache      redis.Cmdable
+	sharedTTL  time.Duration
+}
+
+func NewFolderHandler(folderRepo *repository.FolderRepository, cache redis.Cmdable) *FolderHandler {
+	return &FolderHandler{folderRepo: folderRepo, cache: cache}
+}
+
+// SetSharedCacheTTL caches the public pages of shared folders for ttl, and lets clients
+// and proxies keep them as long; 0, the default, serves every request from the database
+func (h *FolderHandler) SetSharedCacheTTL(ttl time.Duration) {
+	h.sharedTTL = ttl
+}
+
+// CreateFolderRequest creates a folder, top-level unless a parent is given
+type CreateFolderRequest struct {
+	Name     string `json:"name" binding:"required"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// UpdateFolderRequest renames and/or moves a folder. Omitted fields are left unchanged;
+// a null parent_id moves the folder to the top level.
+type UpdateFolderRequest struct {
+	Name     *string    `json:"name"`
+	ParentID optionalID `json:"parent_id"`
+}
+
+// ListFolders returns the caller's folders by name
+func (h *FolderHandler) ListFolders(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	folders, err := h.folderRepo.List(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	c.JSON(http.StatusOK, folders)
+}
+
+// CreateFolder adds a folder
+func (h *FolderHandler) CreateFolder(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	var req CreateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+
+	folder := &models.Folder{UserID: userID, ParentID: req.ParentID}
+	if err := h.place(ctx, folder, req.Name); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := h.folderRepo.Create(ctx, folder); err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+
+	logger.FromContext(ctx).Info("user created folder", "user_id", userID, "folder_id", folder.ID)
+	c.JSON(http.StatusCreated, folder)
+}
+
+// UpdateFolder renames a folder or moves it under another one
+func (h *FolderHandler) UpdateFolder(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	folder, err := h.loadFolder(c, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req UpdateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+	if req.Name == nil && !req.ParentID.Set {
+		c.Error(ierr.NewValidationError("nothing to update: set name and/or parent_id"))
+		return
+	}
+
+	name := folder.Name
+	if req.Name != nil {
+		name = *req.Name
+	}
+	if req.ParentID.Set {
+		folder.ParentID = req.ParentID.Value
+	}
+	if err := h.place(ctx, folder, name); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := h.folderRepo.Update(ctx, folder); err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+
+	h.invalidateUserFeedsCache(ctx, userID)
+	h.invalidateSharedFolderCache(ctx, folder.ShareSlug)
+	c.JSON(http.StatusOK, folder)
+}
+
+// DeleteFolder removes a folder and the folders below it. Their subscriptions are kept
+// and left unfiled.
+func (h *FolderHandler) DeleteFolder(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	folderID, err := parseFolderID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// the shared pages of the folders going away must not outlive them in the cache
+	folders, err := h.folderRepo.List(ctx, userID)
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	deleted, err := h.folderRepo.Delete(ctx, userID, folderID)
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	if !deleted {
+		c.Error(fmt.Errorf("folder %d: %w", folderID, ierr.ErrFolderNotFound))
+		return
+	}
+
+	h.invalidateUserFeedsCache(ctx, userID)
+	subtree := models.FolderSubtree(folders, folderID)
+	for _, folder := range folders {
+		if slices.Contains(subtree, folder.ID) {
+			h.invalidateSharedFolderCache(ctx, folder.ShareSlug)
+		}
+	}
+	logger.FromContext(ctx).Info("user deleted folder", "user_id", userID, "folder_id", folderID)
+	c.JSON(http.StatusOK, gin.H{"message": "Folder deleted"})
+}
+
+// FolderDigestRequest schedules the digest of a folder: daily, or weekly on a weekday
+// from 0 (Sunday) to 6, at an hour of an IANA timezone, UTC when none is given
+type FolderDigestRequest struct {
+	Frequency string `json:"frequency" binding:"required"`
+	Hour      *int   `json:"hour" binding:"required"`
+	Weekday   *int   `json:"weekday"`
+	Timezone  string `json:"timezone"`
+}
+
+// GetFolderDigest returns the digest schedule of a folder
+func (h *FolderHandler) GetFolderDigest(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	folder, err := h.loadFolder(c, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	digest, err := h.folderRepo.GetDigest(c.Request.Context(), userID, folder.ID)
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	if digest == nil {
+		c.Error(fmt.Errorf("folder %d: %w", folder.ID, ierr.ErrDigestNotFound))
+		return
+	}
+	c.JSON(http.StatusOK, digest)
+}
+
+// SetFolderDigest schedules a digest of the unread articles of a folder and the folders
+// below it, or changes its schedule. The digest is written by the LLM and delivered as a
+// notification.
+func (h *FolderHandler) SetFolderDigest(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	folder, err := h.loadFolder(c, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req FolderDigestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+	digest := &models.FolderDigest{
+		FolderID:  folder.ID,
+		UserID:    userID,
+		Frequency: strings.ToLower(strings.TrimSpace(req.Frequency)),
+		Hour:      *req.Hour,
+		Weekday:   req.Weekday,
+		Timezone:  strings.TrimSpace(req.Timezone),
+	}
+	if digest.Timezone == "" {
+		digest.Timezone = "UTC"
+	}
+	if err := digest.Validate(); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+	digest.NextRunAt = digest.NextRun(time.Now())
+
+	if err := h.folderRepo.SaveDigest(ctx, digest); err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	saved, err := h.folderRepo.GetDigest(ctx, userID, folder.ID)
+	if err != nil || saved == nil {
+		c.Error(ierr.NewDatabaseError(fmt.Errorf("failed to read back digest of folder %d: %w", folder.ID, err)))
+		return
+	}
+
+	logger.FromContext(ctx).Info("user scheduled folder digest", "user_id", userID, "folder_id", folder.ID, "frequency", saved.Frequency, "next_run_at", saved.NextRunAt)
+	c.JSON(http.StatusOK, saved)
+}
+
+// DeleteFolderDigest stops the digest of a folder
+func (h *FolderHandler) DeleteFolderDigest(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	folderID, err := parseFolderID(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	deleted, err := h.folderRepo.DeleteDigest(ctx, userID, folderID)
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	if !deleted {
+		c.Error(fmt.Errorf("folder %d: %w", folderID, ierr.ErrDigestNotFound))
+		return
+	}
+
+	logger.FromContext(ctx).Info("user stopped folder digest", "user_id", userID, "folder_id", folderID)
+	c.JSON(http.StatusOK, gin.H{"message": "Folder digest stopped"})
+}
+
+// ShareFolderRequest shares a folder under a slug of lowercase letters, digits and single
+// hyphens, such as "go-weekly"
+type ShareFolderRequest struct {
+	Slug string `json:"slug" binding:"required"`
+}
+
+// ShareFolder publishes the recent articles of a folder and the folders below it on a
+// public read-only page at /shared/:slug, or moves the page to a new slug
+func (h *FolderHandler) ShareFolder(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	folder, err := h.loadFolder(c, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req ShareFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+	slug, err := models.NormalizeShareSlug(req.Slug)
+	if err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+	taken, err := h.folderRepo.ShareSlugTaken(ctx, slug, folder.ID)
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	if taken {
+		c.Error(fmt.Errorf("share slug %q: %w", slug, ierr.ErrShareSlugTaken))
+		return
+	}
+
+	previous := folder.ShareSlug
+	if err := h.folderRepo.SetShareSlug(ctx, folder, &slug); err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	h.invalidateSharedFolderCache(ctx, previous)
+
+	logger.FromContext(ctx).Info("user shared folder", "user_id", userID, "folder_id", folder.ID, "slug", slug)
+	c.JSON(http.StatusOK, folder)
+}
+
+// UnshareFolder takes the public page of a folder down
+func (h *FolderHandler) UnshareFolder(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	folder, err := h.loadFolder(c, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if folder.ShareSlug == nil {
+		c.Error(fmt.Errorf("folder %d: %w", folder.ID, ierr.ErrSharedFolderNotFound))
+		return
+	}
+
+	previous := folder.ShareSlug
+	if err := h.folderRepo.SetShareSlug(ctx, folder, nil); err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	h.invalidateSharedFolderCache(ctx, previous)
+
+	logger.FromContext(ctx).Info("user stopped sharing folder", "user_id", userID, "folder_id", folder.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Folder is no longer shared"})
+}
+
+// GetSharedFolder serves the public page of a shared folder without authentication: its
+// name and the titles, excerpts and links of its most recent articles, without their
+// content or anything about the user sharing it
+func (h *FolderHandler) GetSharedFolder(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	slug, err := models.NormalizeShareSlug(c.Param("slug"))
+	if err != nil {
+		c.Error(fmt.Errorf("share slug %q: %w", c.Param("slug"), ierr.ErrSharedFolderNotFound))
+		return
+	}
+
+	cacheKey := fmt.Sprintf(sharedFolderCacheKeyPattern, slug)
+	if h.cache != nil && h.sharedTTL > 0 {
+		if cached, err := h.cache.Get(ctx, cacheKey).Bytes(); err == nil {
+			h.serveSharedFolder(c, cached)
+			return
+		} else if err != redis.Nil {
+			log.Warn("failed to read shared folder cache", "slug", slug, "error", err.Error())
+		}
+	}
+
+	folder, err := h.folderRepo.GetShared(ctx, slug)
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	if folder == nil {
+		c.Error(fmt.Errorf("share slug %q: %w", slug, ierr.ErrSharedFolderNotFound))
+		return
+	}
+	articles, err := h.folderRepo.SharedArticles(ctx, folder, models.MaxSharedArticles)
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+
+	page, err := json.Marshal(models.SharedFolder{Slug: slug, Name: folder.Name, Articles: articles})
+	if err != nil {
+		c.Error(ierr.NewInternalError(fmt.Errorf("encode shared folder %q: %w", slug, err)))
+		return
+	}
+	if h.cache != nil && h.sharedTTL > 0 {
+		if err := h.cache.Set(ctx, cacheKey, page, h.sharedTTL).Err(); err != nil {
+			log.Warn("failed to cache shared folder", "slug", slug, "error", err.Error())
+		}
+	}
+	h.serveSharedFolder(c, page)
+}
+
+// serveSharedFolder writes the page of a shared folder, which anyone may cache for as
+// long as it stays in ours
+func (h *FolderHandler) serveSharedFolder(c *gin.Context, page []byte) {
+	if h.sharedTTL > 0 {
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.sharedTTL.Seconds())))
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", page)
+}
+
+// place validates the name of a new or changed folder and where it goes: the parent must
+// be a folder of the user outside the folder's own subtree, the nesting within
+// models.MaxFolderDepth and the name free under the parent
+func (h *FolderHandler) place(ctx context.Context, folder *models.Folder, name string) error {
+	name, err := models.NormalizeFolderName(name)
+	if err != nil {
+		return ierr.NewValidationError(err.Error())
+	}
+	folder.Name = name
+
+	folders, err := h.folderRepo.List(ctx, folder.UserID)
+	if err != nil {
+		return ierr.NewDatabaseError(err)
+	}
+	paths := models.FolderPaths(folders)
+
+	depth := 1
+	if folder.ParentID != nil {
+		parentPath, ok := paths[*folder.ParentID]
+		if !ok {
+			return fmt.Errorf("parent folder %d: %w", *folder.ParentID, ierr.ErrFolderNotFound)
+		}
+		depth += len(parentPath)
+	}
+	if folder.ID != 0 {
+		subtree := models.FolderSubtree(folders, folder.ID)
+		if folder.ParentID != nil && slices.Contains(subtree, *folder.ParentID) {
+			return ierr.NewValidationError("a folder cannot be moved into itself or a folder below it")
+		}
+		// the folders below move along, so the deepest of them must still fit
+		own, deepest := len(paths[folder.ID]), depth
+		for _, id := range subtree {
+			deepest = max(deepest, depth+len(paths[id])-own)
+		}
+		depth = deepest
+	}
+	if depth > models.MaxFolderDepth {
+		return ierr.NewValidationError(fmt.Sprintf("folders nest at most %d levels deep", models.MaxFolderDepth))
+	}
+
+	taken, err := h.folderRepo.NameTaken(ctx, folder.UserID, folder.ParentID, folder.Name, folder.ID)
+	if err != nil {
+		return ierr.NewDatabaseError(err)
+	}
+	if taken {
+		return ierr.ErrFolderExists
+	}
+	return nil
+}
+
+func (h *FolderHandler) loadFolder(c *gin.Context, userID uint) (*models.Folder, error) {
+	folderID, err := parseFolderID(c)
+	if err != nil {
+		return nil, err
+	}
+	folder, err := h.folderRepo.Get(c.Request.Context(), userID, folderID)
+	if err != nil {
+		return nil, ierr.NewDatabaseError(err)
+	}
+	if folder == nil {
+		return nil, fmt.Errorf("folder %d: %w", folderID, ierr.ErrFolderNotFound)
+	}
+	return folder, nil
+}
+
+func (h *FolderHandler) invalidateUserFeedsCache(ctx context.Context, userID uint) {
+	if h.cache == nil {
+		return
+	}
+
+	cacheKey := fmt.Sprintf(userFeedsCacheKeyPattern, userID)
+	if err := h.cache.Del(ctx, cacheKey).Err(); err != nil && err != redis.Nil {
+		logger.FromContext(ctx).Warn("failed to invalidate user feeds cache", "user_id", userID, "error", err.Error())
+	}
+}
+
+func (h *FolderHandler) invalidateSharedFolderCache(ctx context.Context, slug *string) {
+	if h.cache == nil || slug == nil {
+		return
+	}
+
+	cacheKey := fmt.Sprintf(sharedFolderCacheKeyPattern, *slug)
+	if err := h.cache.Del(ctx, cacheKey).Err(); err != nil && err != redis.Nil {
+		logger.FromContext(ctx).Warn("failed to invalidate shared folder cache", "slug", *slug, "error", err.Error())
+	}
+}
+
+func parseFolderID(c *gin.Context) (uint, error) {
+	folderID, err := strconv.ParseUint(c.Param("folder_id"), 10, 32)
+	if err != nil || folderID == 0 {
+		return 0, ierr.NewValidationError("invalid folder ID")
+	}
+	return uint(folderID), nil
+}
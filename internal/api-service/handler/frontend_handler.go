@@ -13,10 +13,25 @@ import (
 type StaticFrontendHandler struct {
 	indexHTML  []byte
 	fileServer http.Handler
+	csp        string
 }
 
-// NewStaticFrontendHandler create a new handler for the embedded frontend.
-func NewStaticFrontendHandler(staticFS fs.FS) (*StaticFrontendHandler, error) {
+// FrontendContentSecurityPolicy returns the default policy for frontend pages. SvelteKit
+// bootstraps with an inline script, and article images may come from any host. apiOrigin
+// is allowed for fetches when the API lives on another origin.
+func FrontendContentSecurityPolicy(apiOrigin string) string {
+	connectSrc := "'self'"
+	if apiOrigin != "" {
+		connectSrc += " " + apiOrigin
+	}
+	return "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; " +
+		"img-src 'self' data: http: https:; font-src 'self' data:; connect-src " + connectSrc + "; " +
+		"object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'"
+}
+
+// NewStaticFrontendHandler create a new handler for the embedded frontend, sending csp
+// as the Content-Security-Policy of every page and asset.
+func NewStaticFrontendHandler(staticFS fs.FS, csp string) (*StaticFrontendHandler, error) {
 	// Read index.html into memory once at startup.
 	indexHTML, err := fs.ReadFile(staticFS, "dist/index.html")
 	if err != nil {
@@ -32,6 +47,7 @@ func NewStaticFrontendHandler(staticFS fs.FS) (*StaticFrontendHandler, error) {
 	return &StaticFrontendHandler{
 		indexHTML:  indexHTML,
 		fileServer: http.FileServer(http.FS(subFS)),
+		csp:        csp,
 	}, nil
 }
 
@@ -58,9 +74,16 @@ func (h *StaticFrontendHandler) RegisterRoutes(engine *gin.Engine) {
 	})
 }
 
-// cacheControlMiddleware apply appropriate Cache-Control headers to frontend assets.
+// cacheControlMiddleware apply appropriate Cache-Control and CSP headers to frontend assets.
+// API paths are left to APIHeadersMiddleware.
 func (h *StaticFrontendHandler) cacheControlMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			c.Next()
+			return
+		}
+		c.Header("Content-Security-Policy", h.csp)
+
 		// Check if the request is for an immutable asset.
 		if strings.HasPrefix(c.Request.URL.Path, "/_app/immutable/") || strings.HasPrefix(c.Request.URL.Path, "/assets/") {
 			c.Header("Cache-Control", "public, max-age=31536000, immutable")
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiContentSecurityPolicy locks down API responses, which are never rendered as pages
+const apiContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+
+// NewEngine returns a gin engine with the default logger and recovery middleware. The
+// client IP, which login protection and the audit trail key on, is taken from the
+// X-Forwarded-For and X-Real-IP headers only on requests from one of trustedProxies, IPs
+// or CIDRs; with none it is the address of the connection, so clients cannot forge it.
+func NewEngine(trustedProxies []string) (*gin.Engine, error) {
+	engine := gin.Default()
+	if err := engine.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	return engine, nil
+}
+
+// APIHeadersMiddleware marks API responses as uncacheable and sends a restrictive CSP.
+func APIHeadersMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
+		c.Header("Content-Security-Policy", apiContentSecurityPolicy)
+		c.Next()
+	}
+}
+
+// CORSMiddleware lets browsers on the allowed origins call the API, for frontends served
+// from another origin. Preflight requests are answered directly.
+func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[strings.TrimRight(origin, "/")] = true
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || !allowed[origin] {
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Access-Control-Allow-Credentials", "true")
+		c.Header("Access-Control-Expose-Headers", "Content-Disposition, X-Request-ID, X-Impersonated-By")
+		c.Header("Vary", "Origin")
+
+		if c.Request.Method == http.MethodOptions {
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "Authorization, Content-Type, Accept, X-Request-ID")
+			c.Header("Access-Control-Max-Age", "600")
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/internal/api-service/core"
+	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+func newCORSEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(CORSMiddleware([]string{"https://app.example.com/"}))
+	api := engine.Group("/api/v1")
+	api.Use(APIHeadersMiddleware())
+	api.GET("/feeds", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+	return engine
+}
+
+func TestCORSMiddleware_Preflight(t *testing.T) {
+	engine := newCORSEngine()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/feeds", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	engine.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+}
+
+func TestCORSMiddleware_SimpleRequest(t *testing.T) {
+	engine := newCORSEngine()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/feeds", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	engine.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, apiContentSecurityPolicy, w.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "no-cache, no-store, must-revalidate", w.Header().Get("Cache-Control"))
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/feeds", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	engine.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestFrontendContentSecurityPolicy(t *testing.T) {
+	assert.Contains(t, FrontendContentSecurityPolicy(""), "connect-src 'self';")
+	assert.Contains(t, FrontendContentSecurityPolicy("https://api.example.com"), "connect-src 'self' https://api.example.com;")
+}
+
+// failingLogins turns down every login
+type failingLogins struct {
+	core.UserServiceInterface
+}
+
+func (failingLogins) Login(string, string, models.SessionClient) (*models.AuthTokens, error) {
+	return nil, ierr.ErrInvalidCredentials
+}
+
+// lockoutKeys records the keys the login guard counts failures under
+type lockoutKeys struct {
+	incremented []string
+}
+
+func (s *lockoutKeys) Increment(_ context.Context, key string, _ time.Duration) (int64, error) {
+	s.incremented = append(s.incremented, key)
+	return 1, nil
+}
+
+func (s *lockoutKeys) Get(context.Context, string) (int64, error) { return 0, nil }
+
+func (s *lockoutKeys) Lock(context.Context, string, time.Duration) error { return nil }
+
+func (s *lockoutKeys) Remaining(context.Context, string) (time.Duration, error) { return 0, nil }
+
+func (s *lockoutKeys) Delete(context.Context, ...string) error { return nil }
+
+func TestNewEngine_ForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	login := func(t *testing.T, trustedProxies []string, forwardedFor string) string {
+		t.Helper()
+		engine, err := NewEngine(trustedProxies)
+		require.NoError(t, err)
+		store := &lockoutKeys{}
+		users := NewUserHandler(failingLogins{})
+		users.SetLoginGuard(core.NewLoginGuard(store, core.LoginGuardConfig{MaxIPFailures: 5, FailureWindow: time.Minute, BaseLockout: time.Minute, MaxLockout: time.Hour}))
+		engine.Use(ierr.ErrorHandlerMiddleware())
+		engine.POST("/login", users.Login)
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username": "reader", "password": "guess"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.RemoteAddr = "203.0.113.7:4711"
+		engine.ServeHTTP(w, req)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		require.Len(t, store.incremented, 1)
+		return store.incremented[0]
+	}
+
+	// by default a forged header cannot move failures to another IP's lockout
+	assert.Equal(t, "login:ip:203.0.113.7:failures", login(t, nil, "198.51.100.1"))
+	assert.Equal(t, "login:ip:203.0.113.7:failures", login(t, nil, "198.51.100.2"))
+
+	// behind a trusted proxy the header names the client
+	assert.Equal(t, "login:ip:198.51.100.1:failures", login(t, []string{"203.0.113.0/24"}, "198.51.100.1"))
+
+	_, err := NewEngine([]string{"not-an-ip"})
+	assert.Error(t, err)
+}
@@ -1,23 +1,36 @@
 package handler
 
 import (
+	"context"
+	"crypto/subtle"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
 	"github.com/Fancu1/phoenix-rss/pkg/ierr"
 	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/tracing"
 )
 
+// maxRequestIDLength bounds client-supplied request IDs, which are stored with the rows
+// written by the asynchronous work a request starts
+const maxRequestIDLength = 64
+
 // RequestIDMiddleware propagates or generates a request ID for distributed tracing.
 func RequestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestID := c.GetHeader("X-Request-ID")
-		if requestID == "" {
+		if requestID == "" || len(requestID) > maxRequestIDLength {
 			requestID = uuid.New().String()[:8]
 		}
 
@@ -29,6 +42,37 @@ func RequestIDMiddleware() gin.HandlerFunc {
 	}
 }
 
+// TracingMiddleware starts the server span of each request, continuing the trace of a
+// caller that sent a traceparent header. The gRPC calls and events the request causes
+// become its children. It runs after RequestIDMiddleware to tag the span with the ID.
+func TracingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracing.Start(ctx, c.Request.Method, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		span.SetName(c.Request.Method + " " + route)
+		span.SetAttributes(
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", c.Writer.Status()),
+		)
+		if requestID, ok := GetRequestIDFromContext(c); ok {
+			span.SetAttributes(attribute.String("phoenix.request_id", requestID))
+		}
+		if c.Writer.Status() >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("status %d", c.Writer.Status()))
+		}
+	}
+}
+
 // GetRequestIDFromContext retrieves the request ID from context.
 func GetRequestIDFromContext(c *gin.Context) (string, bool) {
 	if v, ok := c.Get("request_id"); ok {
@@ -37,9 +81,21 @@ func GetRequestIDFromContext(c *gin.Context) (string, bool) {
 	return "", false
 }
 
+// SessionChecker tells whether the session a token was issued for is still active.
+type SessionChecker interface {
+	CheckSession(ctx context.Context, userID uint, sessionID string) (bool, error)
+}
+
+// RoleChecker returns the stored role of a user, empty when the user does not exist.
+type RoleChecker interface {
+	UserRole(ctx context.Context, userID uint) (string, error)
+}
+
 // AuthMiddleware validates JWT tokens locally using shared secret.
 type AuthMiddleware struct {
 	jwtSecret []byte
+	sessions  SessionChecker
+	roles     RoleChecker
 }
 
 // NewAuthMiddleware creates an AuthMiddleware with the given secret.
@@ -47,70 +103,216 @@ func NewAuthMiddleware(jwtSecret string) *AuthMiddleware {
 	return &AuthMiddleware{jwtSecret: []byte(jwtSecret)}
 }
 
+// SetSessionChecker rejects tokens whose session was revoked. Tokens issued before
+// sessions existed carry no session and stay valid until they expire.
+func (m *AuthMiddleware) SetSessionChecker(sessions SessionChecker) {
+	m.sessions = sessions
+}
+
+// SetRoleChecker lets RequireAdmin admit users with the admin role. The role is read on
+// every request rather than from the token, so a demotion applies right away.
+func (m *AuthMiddleware) SetRoleChecker(roles RoleChecker) {
+	m.roles = roles
+}
+
 // RequireAuth enforces JWT authentication and populates user context.
 func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.Error(ierr.ErrUnauthorized.WithCause(fmt.Errorf("authorization header required")))
-			c.Abort()
+		if m.authenticate(c) {
+			c.Next()
+		}
+	}
+}
+
+// RequireAdmin lets through requests carrying the configured admin token in the
+// X-Admin-Token header, and authenticated users with the admin role. An empty adminToken
+// admits no token, leaving only administrators.
+func (m *AuthMiddleware) RequireAdmin(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("X-Admin-Token") != "" {
+			if adminToken == "" {
+				c.Error(ierr.ErrForbidden.WithCause(fmt.Errorf("admin token not configured")))
+				c.Abort()
+				return
+			}
+			RequireAdminToken(adminToken)(c)
 			return
 		}
 
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.Error(ierr.ErrUnauthorized.WithCause(fmt.Errorf("invalid authorization header format")))
+		if !m.authenticate(c) {
+			return
+		}
+		userID := c.GetUint("userID")
+		if impersonator, ok := GetImpersonatorFromContext(c); ok {
+			c.Error(ierr.ErrForbidden.WithCause(fmt.Errorf("%s impersonating user %d cannot use admin routes", impersonator, userID)))
 			c.Abort()
 			return
 		}
-
-		token, err := jwt.Parse(parts[1], func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-			}
-			return m.jwtSecret, nil
-		})
-		if err != nil {
-			c.Error(ierr.ErrInvalidToken.WithCause(err))
+		if m.roles == nil {
+			c.Error(ierr.ErrForbidden.WithCause(fmt.Errorf("user %d: admin roles not checked", userID)))
 			c.Abort()
 			return
 		}
-		if !token.Valid {
-			c.Error(ierr.ErrInvalidToken.WithCause(fmt.Errorf("token validation failed")))
+		role, err := m.roles.UserRole(c.Request.Context(), userID)
+		if err != nil {
+			c.Error(ierr.NewDatabaseError(fmt.Errorf("failed to check role of user %d: %w", userID, err)))
 			c.Abort()
 			return
 		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.Error(ierr.ErrInvalidToken.WithCause(fmt.Errorf("invalid token claims")))
+		if role != models.RoleAdmin {
+			c.Error(ierr.ErrForbidden.WithCause(fmt.Errorf("user %d is not an admin", userID)))
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
+
+// authenticate validates the bearer token and populates the user context. It aborts the
+// request and reports false when the token is missing or invalid.
+func (m *AuthMiddleware) authenticate(c *gin.Context) bool {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		c.Error(ierr.ErrUnauthorized.WithCause(fmt.Errorf("authorization header required")))
+		c.Abort()
+		return false
+	}
+
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		c.Error(ierr.ErrUnauthorized.WithCause(fmt.Errorf("invalid authorization header format")))
+		c.Abort()
+		return false
+	}
+
+	token, err := jwt.Parse(parts[1], func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return m.jwtSecret, nil
+	})
+	if err != nil {
+		c.Error(ierr.ErrInvalidToken.WithCause(err))
+		c.Abort()
+		return false
+	}
+	if !token.Valid {
+		c.Error(ierr.ErrInvalidToken.WithCause(fmt.Errorf("token validation failed")))
+		c.Abort()
+		return false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		c.Error(ierr.ErrInvalidToken.WithCause(fmt.Errorf("invalid token claims")))
+		c.Abort()
+		return false
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		c.Error(ierr.ErrInvalidToken.WithCause(fmt.Errorf("missing user_id claim")))
+		c.Abort()
+		return false
+	}
+
+	username, ok := claims["username"].(string)
+	if !ok {
+		c.Error(ierr.ErrInvalidToken.WithCause(fmt.Errorf("missing username claim")))
+		c.Abort()
+		return false
+	}
+
+	user := &models.User{ID: uint(userID), Username: username}
+
+	if sessionID, _ := claims["sid"].(string); sessionID != "" {
+		if m.sessions != nil {
+			active, err := m.sessions.CheckSession(c.Request.Context(), user.ID, sessionID)
+			if err != nil && !active {
+				c.Error(ierr.NewDatabaseError(fmt.Errorf("failed to check session: %w", err)))
+				c.Abort()
+				return false
+			}
+			if err != nil {
+				logger.FromContext(c.Request.Context()).Warn("failed to record session activity", "error", err.Error())
+			}
+			if !active {
+				c.Error(ierr.ErrInvalidToken.WithCause(fmt.Errorf("session revoked or expired")))
+				c.Abort()
+				return false
+			}
+		}
+		c.Set("sessionID", sessionID)
+	}
+
+	c.Set("userID", user.ID)
+	c.Set("user", user)
+	c.Request = c.Request.WithContext(logger.WithUserID(c.Request.Context(), user.ID))
+
+	if impersonator, _ := claims["imp_by"].(string); impersonator != "" {
+		return impersonate(c, impersonator)
+	}
+	return true
+}
+
+// impersonatorKey holds the administrator a request is made by with an impersonation token
+const impersonatorKey = "impersonator"
+
+// impersonate flags a request made with an impersonation token: its response names the
+// administrator in the X-Impersonated-By header, and only reads are let through. The
+// request is also marked read-only, for the few reads that can also write.
+func impersonate(c *gin.Context, impersonator string) bool {
+	c.Set(impersonatorKey, impersonator)
+	c.Set(readOnlyKey, true)
+	c.Header("X-Impersonated-By", impersonator)
+	logger.FromContext(c.Request.Context()).Info("impersonated request", "impersonated_by", impersonator, "method", c.Request.Method, "path", c.Request.URL.Path)
+
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	c.Error(ierr.ErrImpersonationReadOnly.WithCause(fmt.Errorf("%s impersonating user %d", impersonator, c.GetUint("userID"))))
+	c.Abort()
+	return false
+}
+
+// GetImpersonatorFromContext retrieves the administrator impersonating the authenticated
+// user, absent for the user's own tokens.
+func GetImpersonatorFromContext(c *gin.Context) (string, bool) {
+	if v, ok := c.Get(impersonatorKey); ok {
+		return v.(string), true
+	}
+	return "", false
+}
 
-		userID, ok := claims["user_id"].(float64)
-		if !ok {
-			c.Error(ierr.ErrInvalidToken.WithCause(fmt.Errorf("missing user_id claim")))
+// RequireAdminToken lets through only requests carrying the configured admin token in the
+// X-Admin-Token header.
+func RequireAdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		given := c.GetHeader("X-Admin-Token")
+		if given == "" {
+			c.Error(ierr.ErrUnauthorized.WithCause(fmt.Errorf("admin token required")))
 			c.Abort()
 			return
 		}
-
-		username, ok := claims["username"].(string)
-		if !ok {
-			c.Error(ierr.ErrInvalidToken.WithCause(fmt.Errorf("missing username claim")))
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.Error(ierr.ErrForbidden.WithCause(fmt.Errorf("invalid admin token")))
 			c.Abort()
 			return
 		}
-
-		user := &models.User{ID: uint(userID), Username: username}
-		c.Set("userID", user.ID)
-		c.Set("user", user)
-		c.Request = c.Request.WithContext(logger.WithUserID(c.Request.Context(), user.ID))
-
 		c.Next()
 	}
 }
 
+// GetSessionIDFromContext retrieves the session of the authenticating token, absent for
+// tokens issued before sessions existed.
+func GetSessionIDFromContext(c *gin.Context) (string, bool) {
+	if v, ok := c.Get("sessionID"); ok {
+		return v.(string), true
+	}
+	return "", false
+}
+
 // GetUserIDFromContext retrieves the authenticated user ID from context.
 func GetUserIDFromContext(c *gin.Context) (uint, bool) {
 	if v, ok := c.Get("userID"); ok {
@@ -1,16 +1,24 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 
+	"github.com/Fancu1/phoenix-rss/internal/api-service/core"
 	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
 )
 
 const testJWTSecret = "test-secret-key"
@@ -147,6 +155,102 @@ func TestAuthMiddleware_InvalidAuthHeaderFormat(t *testing.T) {
 	}
 }
 
+func TestRequireAdminToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		header  string
+		aborted bool
+	}{
+		{"missing", "", true},
+		{"wrong", "not-the-token", true},
+		{"valid", "admin-secret", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			req, _ := http.NewRequest(http.MethodGet, "/admin/feeds/1/snapshots", nil)
+			if tc.header != "" {
+				req.Header.Set("X-Admin-Token", tc.header)
+			}
+			ctx.Request = req
+
+			RequireAdminToken("admin-secret")(ctx)
+
+			require.Equal(t, tc.aborted, ctx.IsAborted())
+		})
+	}
+}
+
+type fakeRoleChecker map[uint]string
+
+func (f fakeRoleChecker) UserRole(_ context.Context, userID uint) (string, error) {
+	return f[userID], nil
+}
+
+func TestRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	middleware := NewAuthMiddleware(testJWTSecret)
+	middleware.SetRoleChecker(fakeRoleChecker{1: models.RoleAdmin, 2: models.RoleUser})
+	adminToken := generateTestToken(t, 1, "operator", time.Now().Add(time.Hour))
+	userToken := generateTestToken(t, 2, "reader", time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name       string
+		configured string
+		header     string
+		bearer     string
+		wantErr    *ierr.AppError
+	}{
+		{name: "admin token", configured: "admin-secret", header: "admin-secret"},
+		{name: "wrong admin token", configured: "admin-secret", header: "not-the-token", wantErr: ierr.ErrForbidden},
+		{name: "admin token not configured", header: "admin-secret", wantErr: ierr.ErrForbidden},
+		{name: "admin user", bearer: adminToken},
+		{name: "regular user", configured: "admin-secret", bearer: userToken, wantErr: ierr.ErrForbidden},
+		{name: "anonymous", configured: "admin-secret", wantErr: ierr.ErrUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			req, _ := http.NewRequest(http.MethodGet, "/admin/users", nil)
+			if tc.header != "" {
+				req.Header.Set("X-Admin-Token", tc.header)
+			}
+			if tc.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.bearer)
+			}
+			ctx.Request = req
+
+			middleware.RequireAdmin(tc.configured)(ctx)
+
+			if tc.wantErr == nil {
+				require.False(t, ctx.IsAborted())
+				return
+			}
+			require.True(t, ctx.IsAborted())
+			require.Len(t, ctx.Errors, 1)
+			var appErr *ierr.AppError
+			require.ErrorAs(t, ctx.Errors[0].Err, &appErr)
+			require.Equal(t, tc.wantErr.Code, appErr.Code)
+		})
+	}
+
+	// without a role checker no user is an administrator
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	req, _ := http.NewRequest(http.MethodGet, "/admin/users", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	ctx.Request = req
+	NewAuthMiddleware(testJWTSecret).RequireAdmin("")(ctx)
+	require.True(t, ctx.IsAborted())
+}
+
 func TestAuthMiddleware_MissingClaims(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -224,4 +328,207 @@ func TestRequestIDMiddleware(t *testing.T) {
 		require.Equal(t, existingID, id)
 		require.Equal(t, existingID, w.Header().Get("X-Request-ID"))
 	})
+
+	t.Run("replaces an overlong request ID", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Request-ID", strings.Repeat("x", maxRequestIDLength+1))
+		ctx.Request = req
+
+		RequestIDMiddleware()(ctx)
+
+		id, ok := GetRequestIDFromContext(ctx)
+		require.True(t, ok)
+		require.Len(t, id, 8)
+	})
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	router := gin.New()
+	router.Use(RequestIDMiddleware(), TracingMiddleware())
+	router.GET("/api/v1/feeds/:feed_id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/feeds/7", nil)
+	req.Header.Set("traceparent", traceparent)
+	req.Header.Set("X-Request-ID", "upstream1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	require.Equal(t, "GET /api/v1/feeds/:feed_id", span.Name())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+
+	attrs := map[string]string{}
+	for _, kv := range span.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	require.Equal(t, "200", attrs["http.response.status_code"])
+	require.Equal(t, "upstream1", attrs["phoenix.request_id"])
+}
+
+type fakeSessionChecker map[string]bool
+
+func (f fakeSessionChecker) CheckSession(_ context.Context, _ uint, sessionID string) (bool, error) {
+	return f[sessionID], nil
+}
+
+func TestAuthMiddleware_Sessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	middleware := NewAuthMiddleware(testJWTSecret)
+	middleware.SetSessionChecker(fakeSessionChecker{"active": true, "revoked": false})
+
+	run := func(sessionID string) *gin.Context {
+		claims := jwt.MapClaims{"user_id": float64(7), "username": "sessions", "exp": time.Now().Add(time.Hour).Unix()}
+		if sessionID != "" {
+			claims["sid"] = sessionID
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+		require.NoError(t, err)
+
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/feeds", nil)
+		ctx.Request.Header.Set("Authorization", "Bearer "+token)
+		middleware.RequireAuth()(ctx)
+		return ctx
+	}
+
+	ctx := run("active")
+	require.False(t, ctx.IsAborted())
+	sessionID, ok := GetSessionIDFromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, "active", sessionID)
+
+	ctx = run("revoked")
+	require.True(t, ctx.IsAborted())
+	var appErr *ierr.AppError
+	require.ErrorAs(t, ctx.Errors.Last().Err, &appErr)
+	require.Equal(t, ierr.ErrInvalidToken.Code, appErr.Code)
+
+	// tokens from before sessions existed stay valid until they expire
+	ctx = run("")
+	require.False(t, ctx.IsAborted())
+	_, ok = GetSessionIDFromContext(ctx)
+	require.False(t, ok)
+}
+
+func TestAuthMiddleware_Impersonation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	middleware := NewAuthMiddleware(testJWTSecret)
+	middleware.SetRoleChecker(fakeRoleChecker{1: models.RoleAdmin, 2: models.RoleUser})
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":   float64(2),
+		"username":  "reader",
+		"imp_by":    "operator",
+		"imp_by_id": float64(1),
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testJWTSecret))
+	require.NoError(t, err)
+
+	run := func(method string, handler gin.HandlerFunc) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request, _ = http.NewRequest(method, "/feeds", nil)
+		ctx.Request.Header.Set("Authorization", "Bearer "+token)
+		handler(ctx)
+		return ctx, w
+	}
+
+	ctx, w := run(http.MethodGet, middleware.RequireAuth())
+	require.False(t, ctx.IsAborted())
+	require.Equal(t, "operator", w.Header().Get("X-Impersonated-By"))
+	impersonator, ok := GetImpersonatorFromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, "operator", impersonator)
+	require.Equal(t, uint(2), ctx.GetUint("userID"))
+
+	// impersonation tokens are read-only
+	ctx, w = run(http.MethodPost, middleware.RequireAuth())
+	require.True(t, ctx.IsAborted())
+	require.Equal(t, "operator", w.Header().Get("X-Impersonated-By"))
+	var appErr *ierr.AppError
+	require.ErrorAs(t, ctx.Errors.Last().Err, &appErr)
+	require.Equal(t, ierr.ErrImpersonationReadOnly.Code, appErr.Code)
+
+	// and do not open the admin routes
+	ctx, _ = run(http.MethodGet, middleware.RequireAdmin(""))
+	require.True(t, ctx.IsAborted())
+	require.ErrorAs(t, ctx.Errors.Last().Err, &appErr)
+	require.Equal(t, ierr.ErrForbidden.Code, appErr.Code)
+
+	// the user's own tokens are not flagged
+	w = httptest.NewRecorder()
+	ctx, _ = gin.CreateTestContext(w)
+	ctx.Request, _ = http.NewRequest(http.MethodPost, "/feeds", nil)
+	ctx.Request.Header.Set("Authorization", "Bearer "+generateTestToken(t, 2, "reader", time.Now().Add(time.Hour)))
+	middleware.RequireAuth()(ctx)
+	require.False(t, ctx.IsAborted())
+	require.Empty(t, w.Header().Get("X-Impersonated-By"))
+	_, ok = GetImpersonatorFromContext(ctx)
+	require.False(t, ok)
+}
+
+// nextUnreadService records the next-unread lookups reaching the article service
+type nextUnreadService struct {
+	core.ArticleServiceInterface
+	queries []core.NextUnreadQuery
+}
+
+func (s *nextUnreadService) NextUnreadArticle(_ context.Context, _ uint, query core.NextUnreadQuery) (uint, error) {
+	s.queries = append(s.queries, query)
+	return 0, nil
+}
+
+func TestAuthMiddleware_ImpersonationNextUnread(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	middleware := NewAuthMiddleware(testJWTSecret)
+	service := &nextUnreadService{}
+	articles := NewArticleHandler(service, nil, nil, time.Hour)
+	engine := gin.New()
+	engine.Use(ierr.ErrorHandlerMiddleware())
+	engine.GET("/articles/next-unread", middleware.RequireAuth(), articles.NextUnread)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":   float64(2),
+		"username":  "reader",
+		"imp_by":    "operator",
+		"imp_by_id": float64(1),
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testJWTSecret))
+	require.NoError(t, err)
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	// marking the article read would change the impersonated user's data
+	w := get("/articles/next-unread?mark_read=true")
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Contains(t, w.Body.String(), ierr.ErrImpersonationReadOnly.Message)
+	require.Empty(t, service.queries)
+
+	// looking it up is a read
+	w = get("/articles/next-unread")
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Len(t, service.queries, 1)
+	require.False(t, service.queries[0].MarkRead)
 }
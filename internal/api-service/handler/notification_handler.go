@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Fancu1/phoenix-rss/internal/api-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+const defaultNotificationLimit = 50
+
+// notificationQuery selects which of the user's notifications are listed
+type notificationQuery struct {
+	Unread bool `form:"unread"`
+	Limit  int  `form:"limit"`
+}
+
+type NotificationHandler struct {
+	notificationRepo *repository.NotificationRepository
+}
+
+func NewNotificationHandler(notificationRepo *repository.NotificationRepository) *NotificationHandler {
+	return &NotificationHandler{notificationRepo: notificationRepo}
+}
+
+// ListNotifications returns the user's recent notifications (e.g. archived feeds)
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	query := notificationQuery{Limit: defaultNotificationLimit}
+	if err := bindQuery(c, &query); err != nil {
+		c.Error(err)
+		return
+	}
+	unreadOnly := query.Unread
+
+	if wantsEnvelope(c) {
+		window, err := parseListWindow(c, defaultNotificationLimit, repository.MaxNotifications)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		notifications, total, err := h.notificationRepo.ListByUserRange(c.Request.Context(), userID, unreadOnly, window.Offset, window.Limit)
+		if err != nil {
+			c.Error(ierr.NewDatabaseError(err))
+			return
+		}
+		writeListEnvelope(c, newListEnvelope(notifications, window, total, false))
+		return
+	}
+
+	if problems := checkRange("limit", query.Limit, 1, repository.MaxNotifications); problems != nil {
+		c.Error(invalidQuery(problems...))
+		return
+	}
+	notifications, err := h.notificationRepo.ListByUser(c.Request.Context(), userID, unreadOnly, query.Limit)
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+// MarkNotificationRead marks a single notification as read
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	notificationID, err := strconv.ParseUint(c.Param("notification_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.NewValidationError("invalid notification ID"))
+		return
+	}
+
+	updated, err := h.notificationRepo.MarkRead(c.Request.Context(), userID, uint(notificationID))
+	if err != nil {
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	if !updated {
+		c.Error(fmt.Errorf("notification %d for user %d: %w", notificationID, userID, ierr.ErrNotificationNotFound))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
@@ -6,13 +6,17 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/Fancu1/phoenix-rss/internal/api-service/core"
 	"github.com/Fancu1/phoenix-rss/internal/api-service/repository"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
 	"github.com/Fancu1/phoenix-rss/pkg/ierr"
 	"github.com/Fancu1/phoenix-rss/pkg/logger"
 )
@@ -24,19 +28,25 @@ const (
 type OPMLHandler struct {
 	feedService      core.FeedServiceInterface
 	subscriptionRepo *repository.SubscriptionRepository
+	articleRepo      *repository.ArticleRepository
+	folderRepo       *repository.FolderRepository
 	opmlService      *core.OPMLService
 	cache            redis.Cmdable
 }
 
-func NewOPMLHandler(feedService core.FeedServiceInterface, subscriptionRepo *repository.SubscriptionRepository, cache redis.Cmdable) *OPMLHandler {
+func NewOPMLHandler(feedService core.FeedServiceInterface, subscriptionRepo *repository.SubscriptionRepository, articleRepo *repository.ArticleRepository, folderRepo *repository.FolderRepository, cache redis.Cmdable) *OPMLHandler {
 	return &OPMLHandler{
 		feedService:      feedService,
 		subscriptionRepo: subscriptionRepo,
+		articleRepo:      articleRepo,
+		folderRepo:       folderRepo,
 		opmlService:      core.NewOPMLService(),
 		cache:            cache,
 	}
 }
 
+// ExportOPML returns the user's subscriptions as OPML. With counts=true every outline is
+// annotated with its feed's unread and total article counts.
 func (h *OPMLHandler) ExportOPML(c *gin.Context) {
 	ctx := c.Request.Context()
 	log := logger.FromContext(ctx)
@@ -47,6 +57,16 @@ func (h *OPMLHandler) ExportOPML(c *gin.Context) {
 		return
 	}
 
+	var withCounts bool
+	if value := c.Query("counts"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.Error(ierr.NewValidationError("counts must be true or false"))
+			return
+		}
+		withCounts = parsed
+	}
+
 	feeds, err := h.subscriptionRepo.ListUserFeeds(ctx, userID)
 	if err != nil {
 		log.Error("failed to list user feeds for export", "user_id", userID, "error", err.Error())
@@ -54,8 +74,26 @@ func (h *OPMLHandler) ExportOPML(c *gin.Context) {
 		return
 	}
 
+	var counts map[uint]core.OPMLReadCounts
+	if withCounts {
+		feedIDs := make([]uint, len(feeds))
+		for i, feed := range feeds {
+			feedIDs[i] = feed.ID
+		}
+		readCounts, err := h.articleRepo.CountReadStateByFeeds(ctx, userID, feedIDs)
+		if err != nil {
+			log.Error("failed to count articles for export", "user_id", userID, "error", err.Error())
+			c.Error(ierr.NewDatabaseError(err))
+			return
+		}
+		counts = make(map[uint]core.OPMLReadCounts, len(readCounts))
+		for _, count := range readCounts {
+			counts[count.FeedID] = core.OPMLReadCounts{Unread: count.Unread, Total: count.Total}
+		}
+	}
+
 	username := fmt.Sprintf("user_%d", userID)
-	opmlData, err := h.opmlService.GenerateOPML(feeds, username)
+	opmlData, err := h.opmlService.GenerateOPMLWithCounts(feeds, username, counts)
 	if err != nil {
 		log.Error("failed to generate OPML", "user_id", userID, "error", err.Error())
 		c.Error(ierr.NewInternalError(errors.New("failed to generate OPML export")))
@@ -116,14 +154,40 @@ func (h *OPMLHandler) PreviewOPML(c *gin.Context) {
 		return
 	}
 
-	existingFeeds, err := h.subscriptionRepo.ListUserFeeds(ctx, userID)
-	if err != nil {
-		log.Error("failed to list existing feeds", "user_id", userID, "error", err.Error())
-		c.Error(ierr.NewDatabaseError(err))
+	// the user's own subscriptions and the feeds known instance-wide are looked up at once
+	urls := make([]string, len(parseResult.Feeds))
+	for i, item := range parseResult.Feeds {
+		urls[i] = item.URL
+	}
+	var existingFeeds []*models.UserFeed
+	var existsGlobally map[string]bool
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		feeds, err := h.subscriptionRepo.ListUserFeeds(gctx, userID)
+		if err != nil {
+			log.Error("failed to list existing feeds", "user_id", userID, "error", err.Error())
+			return ierr.NewDatabaseError(err)
+		}
+		existingFeeds = feeds
+		return nil
+	})
+	g.Go(func() error {
+		exists, err := h.feedService.ExistsByURLs(gctx, urls)
+		if err != nil {
+			log.Error("failed to look up feeds for preview", "user_id", userID, "error", err.Error())
+			return err
+		}
+		existsGlobally = exists
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		c.Error(err)
 		return
 	}
 
 	toImport, duplicates := h.opmlService.FilterDuplicates(parseResult.Feeds, existingFeeds)
+	h.opmlService.MarkExisting(toImport, existsGlobally)
+	h.opmlService.MarkExisting(duplicates, existsGlobally)
 
 	c.JSON(http.StatusOK, PreviewImportRequest{
 		ToImport:   toImport,
@@ -187,6 +251,45 @@ func (h *OPMLHandler) ImportOPML(c *gin.Context) {
 		}
 	}
 
+	// Apply the settings, folders and unread hints carried in the OPML to the new
+	// subscriptions. A hint only takes effect on feeds that already have articles here;
+	// the articles of a feed new to this instance are fetched later and start out unread.
+	items := make(map[string]core.OPMLFeedItem, len(req.Feeds))
+	for _, item := range req.Feeds {
+		items[item.URL] = item
+	}
+	folders := h.newFolderResolver(userID)
+	for _, r := range results {
+		if !r.Success || r.Feed == nil {
+			continue
+		}
+		item := items[r.URL]
+		update := item.SubscriptionUpdate()
+		if path, err := item.FolderPath(); err != nil {
+			log.Warn("skipping invalid imported folder", "user_id", userID, "feed_id", r.Feed.ID, "error", err.Error())
+		} else if folderID, err := folders.resolve(ctx, path); err != nil {
+			log.Warn("failed to create imported folder", "user_id", userID, "feed_id", r.Feed.ID, "error", err.Error())
+		} else if folderID != 0 {
+			if update == nil {
+				update = &models.SubscriptionUpdate{}
+			}
+			update.FolderID = &folderID
+		}
+		if update != nil {
+			if err := h.subscriptionRepo.Update(ctx, userID, r.Feed.ID, *update); err != nil {
+				log.Warn("failed to apply imported subscription settings", "user_id", userID, "feed_id", r.Feed.ID, "error", err.Error())
+			}
+		}
+		if item.UnreadHint != nil {
+			marked, err := h.articleRepo.KeepNewestUnread(ctx, userID, r.Feed.ID, int(*item.UnreadHint))
+			if err != nil {
+				log.Warn("failed to apply imported unread hint", "user_id", userID, "feed_id", r.Feed.ID, "error", err.Error())
+			} else if marked > 0 {
+				result.ReadStateApplied++
+			}
+		}
+	}
+
 	if imported > 0 {
 		h.invalidateUserFeedsCache(ctx, userID)
 	}
@@ -194,6 +297,153 @@ func (h *OPMLHandler) ImportOPML(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// ExportSettings returns the user's subscriptions and their settings as JSON, for a
+// lossless move between Phoenix RSS instances
+func (h *OPMLHandler) ExportSettings(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	feeds, err := h.subscriptionRepo.ListUserFeeds(ctx, userID)
+	if err != nil {
+		log.Error("failed to list user feeds for settings export", "user_id", userID, "error", err.Error())
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+
+	filename := fmt.Sprintf("phoenix-rss-settings-%s.json", time.Now().Format("2006-01-02"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.JSON(http.StatusOK, core.NewSettingsExport(feeds))
+}
+
+// ImportSettings restores a settings export: missing feeds are subscribed to, and every
+// listed subscription gets exactly the exported settings
+func (h *OPMLHandler) ImportSettings(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxOPMLFileSize+1))
+	if err != nil {
+		c.Error(ierr.NewValidationError("failed to read request body"))
+		return
+	}
+	if len(data) > maxOPMLFileSize {
+		c.Error(ierr.NewValidationError("file size exceeds maximum allowed (10MB)"))
+		return
+	}
+
+	export, err := core.ParseSettingsExport(data)
+	if err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+
+	existingFeeds, err := h.subscriptionRepo.ListUserFeeds(ctx, userID)
+	if err != nil {
+		log.Error("failed to list existing feeds", "user_id", userID, "error", err.Error())
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	feedIDs := make(map[string]uint, len(existingFeeds))
+	for _, feed := range existingFeeds {
+		feedIDs[core.NormalizeFeedURL(feed.URL)] = feed.ID
+	}
+
+	var missing []string
+	for _, sub := range export.Subscriptions {
+		if _, ok := feedIDs[core.NormalizeFeedURL(sub.URL)]; !ok {
+			missing = append(missing, sub.URL)
+		}
+	}
+
+	result := core.SettingsImportResult{FailedURLs: make([]string, 0)}
+	folders := h.newFolderResolver(userID)
+	if len(missing) > 0 {
+		results, imported, _, err := h.feedService.BatchSubscribeToFeeds(ctx, userID, missing)
+		if err != nil {
+			log.Error("batch subscribe failed", "user_id", userID, "error", err.Error())
+			c.Error(err)
+			return
+		}
+		result.Subscribed = imported
+		for _, r := range results {
+			if r.Feed != nil {
+				feedIDs[core.NormalizeFeedURL(r.URL)] = r.Feed.ID
+			}
+		}
+	}
+
+	for _, sub := range export.Subscriptions {
+		feedID, ok := feedIDs[core.NormalizeFeedURL(sub.URL)]
+		if !ok {
+			result.Failed++
+			result.FailedURLs = append(result.FailedURLs, sub.URL)
+			continue
+		}
+		update := sub.Update()
+		path, err := sub.FolderPath()
+		if err == nil {
+			var folderID uint
+			if folderID, err = folders.resolve(ctx, path); err == nil {
+				update.FolderID = &folderID
+			}
+		}
+		if err != nil {
+			log.Warn("failed to restore subscription folder", "user_id", userID, "feed_id", feedID, "error", err.Error())
+		}
+		if err := h.subscriptionRepo.Update(ctx, userID, feedID, update); err != nil {
+			log.Warn("failed to restore subscription settings", "user_id", userID, "feed_id", feedID, "error", err.Error())
+			result.Failed++
+			result.FailedURLs = append(result.FailedURLs, sub.URL)
+			continue
+		}
+		result.Updated++
+	}
+
+	h.invalidateUserFeedsCache(ctx, userID)
+	c.JSON(http.StatusOK, result)
+}
+
+// folderResolver finds or creates the folders at the paths of an import, remembering them
+// so feeds sharing a folder look it up once
+type folderResolver struct {
+	folderRepo *repository.FolderRepository
+	userID     uint
+	ids        map[string]uint
+}
+
+func (h *OPMLHandler) newFolderResolver(userID uint) *folderResolver {
+	return &folderResolver{folderRepo: h.folderRepo, userID: userID, ids: make(map[string]uint)}
+}
+
+// resolve returns the ID of the folder at path, 0 for an empty path
+func (f *folderResolver) resolve(ctx context.Context, path []string) (uint, error) {
+	if len(path) == 0 {
+		return 0, nil
+	}
+	key := strings.Join(path, "\x00")
+	if id, ok := f.ids[key]; ok {
+		return id, nil
+	}
+	id, err := f.folderRepo.EnsurePath(ctx, f.userID, path)
+	if err != nil {
+		return 0, err
+	}
+	f.ids[key] = id
+	return id, nil
+}
+
 func (h *OPMLHandler) invalidateUserFeedsCache(ctx context.Context, userID uint) {
 	if h.cache == nil {
 		return
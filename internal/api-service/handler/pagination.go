@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListEnvelopeMediaType requests the list envelope through the Accept header
+const ListEnvelopeMediaType = "application/vnd.phoenix-rss.list+json"
+
+const (
+	// defaultListLimit and maxListLimit bound envelope pages of lists served from memory
+	defaultListLimit = 50
+	maxListLimit     = 200
+
+	cursorPrefix = "o:"
+	// tokenCursorPrefix marks cursors wrapping a page token of a list paged by position,
+	// such as the timeline, rather than by offset
+	tokenCursorPrefix = "t:"
+)
+
+// ListEnvelope wraps a list response with paging metadata. Clients opt in with
+// ?envelope=true or an Accept header containing ListEnvelopeMediaType; otherwise list
+// endpoints keep their plain responses.
+type ListEnvelope[T any] struct {
+	Items []T `json:"items"`
+	// NextCursor fetches the following page; empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int64  `json:"total"`
+	// TotalApproximate is set when the total may be stale, e.g. served from cache
+	TotalApproximate bool `json:"total_approximate"`
+}
+
+// listWindow is the part of a list selected by the cursor and limit query parameters
+type listWindow struct {
+	Offset int
+	Limit  int
+}
+
+// wantsEnvelope reports whether the client asked for the list envelope
+func wantsEnvelope(c *gin.Context) bool {
+	if v := c.Query("envelope"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		return err == nil && enabled
+	}
+	return acceptsEnvelope(c)
+}
+
+func acceptsEnvelope(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), ListEnvelopeMediaType)
+}
+
+// listWindowQuery holds the query parameters of a list window
+type listWindowQuery struct {
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit"`
+}
+
+// parseListWindow reads cursor and limit, which must be between 1 and maxLimit
+func parseListWindow(c *gin.Context, defaultLimit, maxLimit int) (listWindow, error) {
+	query := listWindowQuery{Limit: defaultLimit}
+	if err := bindQuery(c, &query); err != nil {
+		return listWindow{}, err
+	}
+
+	problems := checkRange("limit", query.Limit, 1, maxLimit)
+	window := listWindow{Limit: query.Limit}
+	if query.Cursor != "" {
+		offset, err := decodeCursor(query.Cursor)
+		if err != nil {
+			problems = append(problems, "cursor is invalid")
+		}
+		window.Offset = offset
+	}
+	if len(problems) > 0 {
+		return listWindow{}, invalidQuery(problems...)
+	}
+	return window, nil
+}
+
+// encodeCursor makes an opaque cursor for the item at offset
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	value, ok := strings.CutPrefix(string(raw), cursorPrefix)
+	if !ok {
+		return 0, strconv.ErrSyntax
+	}
+	offset, err := strconv.Atoi(value)
+	if err != nil || offset < 0 {
+		return 0, strconv.ErrSyntax
+	}
+	return offset, nil
+}
+
+// parseTokenCursor returns the page token held by the cursor query parameter, empty for
+// the first page
+func parseTokenCursor(c *gin.Context) (string, error) {
+	cursor := c.Query("cursor")
+	if cursor == "" {
+		return "", nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", invalidQuery("cursor is invalid")
+	}
+	token, ok := strings.CutPrefix(string(raw), tokenCursorPrefix)
+	if !ok || token == "" {
+		return "", invalidQuery("cursor is invalid")
+	}
+	return token, nil
+}
+
+// encodeTokenCursor makes an opaque cursor for a page token; empty stays empty
+func encodeTokenCursor(token string) string {
+	if token == "" {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(tokenCursorPrefix + token))
+}
+
+// newListEnvelope builds the envelope for one window of a list with total items
+func newListEnvelope[T any](items []T, window listWindow, total int64, approximate bool) ListEnvelope[T] {
+	if items == nil {
+		items = []T{}
+	}
+	envelope := ListEnvelope[T]{Items: items, Total: total, TotalApproximate: approximate}
+	if next := window.Offset + len(items); len(items) > 0 && int64(next) < total {
+		envelope.NextCursor = encodeCursor(next)
+	}
+	return envelope
+}
+
+// windowOf returns the window of a list held in memory
+func windowOf[T any](items []T, window listWindow) []T {
+	if window.Offset >= len(items) {
+		return []T{}
+	}
+	end := min(window.Offset+window.Limit, len(items))
+	return items[window.Offset:end]
+}
+
+// writeListEnvelope responds with the envelope, echoing the envelope media type when it
+// was requested through the Accept header
+func writeListEnvelope[T any](c *gin.Context, envelope ListEnvelope[T]) {
+	c.Header("Vary", "Accept")
+	if acceptsEnvelope(c) {
+		c.Header("Content-Type", ListEnvelopeMediaType+"; charset=utf-8")
+	}
+	c.JSON(http.StatusOK, envelope)
+}
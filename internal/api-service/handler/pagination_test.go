@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+func newPaginationContext(target, accept string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+	if accept != "" {
+		c.Request.Header.Set("Accept", accept)
+	}
+	return c, w
+}
+
+func TestWantsEnvelope(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		accept string
+		want   bool
+	}{
+		{"plain", "/feeds", "application/json", false},
+		{"query flag", "/feeds?envelope=true", "", true},
+		{"accept header", "/feeds", ListEnvelopeMediaType + ", application/json;q=0.9", true},
+		{"query overrides header", "/feeds?envelope=false", ListEnvelopeMediaType, false},
+		{"invalid flag", "/feeds?envelope=maybe", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newPaginationContext(tt.target, tt.accept)
+			assert.Equal(t, tt.want, wantsEnvelope(c))
+		})
+	}
+}
+
+func TestListEnvelope_Paging(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	c, _ := newPaginationContext("/feeds?limit=2", "")
+	window, err := parseListWindow(c, 10, 100)
+	require.NoError(t, err)
+
+	var seen []int
+	for {
+		envelope := newListEnvelope(windowOf(items, window), window, int64(len(items)), false)
+		assert.Equal(t, int64(5), envelope.Total)
+		seen = append(seen, envelope.Items...)
+		if envelope.NextCursor == "" {
+			break
+		}
+
+		c, _ := newPaginationContext("/feeds?limit=2&cursor="+envelope.NextCursor, "")
+		window, err = parseListWindow(c, 10, 100)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, items, seen)
+}
+
+func TestParseListWindow(t *testing.T) {
+	c, _ := newPaginationContext("/feeds", "")
+	window, err := parseListWindow(c, 10, 100)
+	require.NoError(t, err)
+	assert.Equal(t, listWindow{Offset: 0, Limit: 10}, window, "limit defaults")
+
+	for _, limit := range []string{"0", "500", "ten"} {
+		c, _ := newPaginationContext("/feeds?limit="+limit, "")
+		_, err := parseListWindow(c, 10, 100)
+		var appErr *ierr.AppError
+		require.ErrorAs(t, err, &appErr, "limit %q", limit)
+		assert.Equal(t, http.StatusUnprocessableEntity, appErr.HTTPStatus, "limit %q", limit)
+	}
+
+	for _, cursor := range []string{"not-base64!", encodeCursor(-1), "eDox"} {
+		c, _ := newPaginationContext("/feeds?cursor="+cursor, "")
+		_, err := parseListWindow(c, 10, 100)
+		assert.True(t, ierr.IsValidationError(err), "cursor %q", cursor)
+	}
+}
+
+func TestWriteListEnvelope(t *testing.T) {
+	c, w := newPaginationContext("/feeds", ListEnvelopeMediaType)
+	writeListEnvelope(c, newListEnvelope[string](nil, listWindow{Limit: 10}, 0, true))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), ListEnvelopeMediaType)
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, []any{}, body["items"])
+	assert.NotContains(t, body, "next_cursor")
+	assert.Equal(t, true, body["total_approximate"])
+}
+
+func TestTokenCursor(t *testing.T) {
+	c, _ := newPaginationContext("/articles", "")
+	token, err := parseTokenCursor(c)
+	require.NoError(t, err)
+	assert.Empty(t, token)
+	assert.Empty(t, encodeTokenCursor(""), "no cursor after the last page")
+
+	cursor := encodeTokenCursor("MjAyNi0xMC0wMVQxMjowMDowMFp8NDI=")
+	c, _ = newPaginationContext("/articles?cursor="+cursor, "")
+	token, err = parseTokenCursor(c)
+	require.NoError(t, err)
+	assert.Equal(t, "MjAyNi0xMC0wMVQxMjowMDowMFp8NDI=", token)
+
+	for _, cursor := range []string{"not-base64!", encodeCursor(20)} {
+		c, _ := newPaginationContext("/articles?cursor="+cursor, "")
+		_, err := parseTokenCursor(c)
+		assert.True(t, ierr.IsValidationError(err), "cursor %q", cursor)
+	}
+}
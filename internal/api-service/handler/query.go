@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+// queryValidator is implemented by query structs with checks beyond their binding tags,
+// such as bounds kept in constants. validate may normalize the values it checks.
+type queryValidator interface {
+	validate() []string
+}
+
+// bindQuery binds the query string into dst, a pointer to a struct of form-tagged fields
+// holding its defaults. Absent parameters keep their default. Malformed values, values
+// breaking a binding rule and the problems dst's validate method finds are all reported
+// in one 422 error.
+func bindQuery(c *gin.Context, dst any) error {
+	problems := malformedQuery(c.Request.URL.Query(), reflect.TypeOf(dst).Elem())
+	if len(problems) == 0 {
+		if err := c.ShouldBindQuery(dst); err != nil {
+			problems = bindingProblems(reflect.TypeOf(dst).Elem(), err)
+		} else if v, ok := dst.(queryValidator); ok {
+			problems = v.validate()
+		}
+	}
+	if len(problems) > 0 {
+		return invalidQuery(problems...)
+	}
+	return nil
+}
+
+// invalidQuery is the error for query parameters with the problems
+func invalidQuery(problems ...string) error {
+	return ierr.NewInvalidQueryError("invalid query parameters: " + strings.Join(problems, "; "))
+}
+
+// checkRange reports a parameter outside [lo, hi]
+func checkRange(name string, value, lo, hi int) []string {
+	if value < lo || value > hi {
+		return []string{fmt.Sprintf("%s must be between %d and %d", name, lo, hi)}
+	}
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// malformedQuery reports the parameters whose values cannot be parsed into the type of
+// their field; binding would fail on them without naming the parameter
+func malformedQuery(values url.Values, t reflect.Type) []string {
+	var problems []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			problems = append(problems, malformedQuery(values, field.Type)...)
+			continue
+		}
+		name := queryName(field)
+		if !field.IsExported() || name == "" {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr || fieldType.Kind() == reflect.Slice {
+			fieldType = fieldType.Elem()
+		}
+		for _, value := range values[name] {
+			// binding takes an empty value as the zero value
+			if value == "" {
+				continue
+			}
+			if problem := malformedValue(name, value, fieldType); problem != "" {
+				problems = append(problems, problem)
+				break
+			}
+		}
+	}
+	return problems
+}
+
+func malformedValue(name, value string, t reflect.Type) string {
+	if t == timeType {
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return name + " must be an RFC 3339 time"
+		}
+		return ""
+	}
+
+	var err error
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		_, err = strconv.ParseInt(value, 10, t.Bits())
+		if err != nil {
+			return name + " must be an integer"
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, err = strconv.ParseUint(value, 10, t.Bits())
+		if err != nil {
+			return name + " must be a non-negative integer"
+		}
+	case reflect.Float32, reflect.Float64:
+		_, err = strconv.ParseFloat(value, t.Bits())
+		if err != nil {
+			return name + " must be a number"
+		}
+	case reflect.Bool:
+		_, err = strconv.ParseBool(value)
+		if err != nil {
+			return name + " must be true or false"
+		}
+	}
+	return ""
+}
+
+// bindingProblems describes the binding rules the parameters broke
+func bindingProblems(t reflect.Type, err error) []string {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return []string{err.Error()}
+	}
+
+	problems := make([]string, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		name := fieldErr.Field()
+		if field, ok := t.FieldByName(fieldErr.StructField()); ok && queryName(field) != "" {
+			name = queryName(field)
+		}
+		problems = append(problems, describeRule(name, fieldErr))
+	}
+	return problems
+}
+
+func describeRule(name string, fieldErr validator.FieldError) string {
+	numeric := fieldErr.Kind() != reflect.String && fieldErr.Kind() != reflect.Slice
+	switch fieldErr.Tag() {
+	case "required":
+		return name + " is required"
+	case "min", "gte":
+		if numeric {
+			return fmt.Sprintf("%s must be at least %s", name, fieldErr.Param())
+		}
+		return fmt.Sprintf("%s must have at least %s characters", name, fieldErr.Param())
+	case "max", "lte":
+		if numeric {
+			return fmt.Sprintf("%s must be at most %s", name, fieldErr.Param())
+		}
+		return fmt.Sprintf("%s must have at most %s characters", name, fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", name, strings.Join(strings.Fields(fieldErr.Param()), ", "))
+	default:
+		return name + " is invalid"
+	}
+}
+
+// queryName is the query parameter a field binds, empty for untagged or ignored fields
+func queryName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
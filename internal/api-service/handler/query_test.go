@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/internal/api-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+func requireInvalidQuery(t *testing.T, err error) string {
+	t.Helper()
+	var appErr *ierr.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusUnprocessableEntity, appErr.HTTPStatus)
+	assert.True(t, ierr.IsValidationError(err))
+	return appErr.Message
+}
+
+func TestBindQuery_Defaults(t *testing.T) {
+	c, _ := newPaginationContext("/feeds/1/articles", "")
+	query := articlePageQuery{Page: 1, PageSize: repository.DefaultPageSize}
+	require.NoError(t, bindQuery(c, &query))
+
+	assert.Equal(t, 1, query.Page)
+	assert.Equal(t, repository.DefaultPageSize, query.PageSize)
+	assert.Equal(t, repository.SortRecent, query.order)
+}
+
+func TestBindQuery_Values(t *testing.T) {
+	c, _ := newPaginationContext("/feeds/1/articles?page=3&page_size=50&sort=SMART", "")
+	query := articlePageQuery{Page: 1, PageSize: repository.DefaultPageSize}
+	require.NoError(t, bindQuery(c, &query))
+	assert.Equal(t, articlePageQuery{articleSortQuery: articleSortQuery{Sort: "SMART", order: repository.SortSmart}, Page: 3, PageSize: 50}, query)
+
+	c, _ = newPaginationContext("/articles/next-unread?after=12&mark_read=true", "")
+	next := nextUnreadQuery{Scope: "all"}
+	require.NoError(t, bindQuery(c, &next))
+	assert.Equal(t, nextUnreadQuery{Scope: "all", After: 12, MarkRead: true}, next)
+
+	c, _ = newPaginationContext("/feeds/1/read?before=2026-10-01T12:00:00Z", "")
+	var before markFeedReadQuery
+	require.NoError(t, bindQuery(c, &before))
+	assert.True(t, before.Before.Equal(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestBindQuery_Problems(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		query  any
+		want   []string
+	}{
+		{
+			name:   "every problem reported",
+			target: "/feeds/1/articles?page=0&page_size=1000&sort=oldest",
+			query:  &articlePageQuery{Page: 1, PageSize: repository.DefaultPageSize},
+			want:   []string{"sort must be one of recent, smart", "page must be at least 1", "page_size must be between 1 and 50"},
+		},
+		{
+			name:   "malformed numbers",
+			target: "/feeds/1/articles?page=two&page_size=1.5",
+			query:  &articlePageQuery{Page: 1, PageSize: repository.DefaultPageSize},
+			want:   []string{"page must be an integer", "page_size must be an integer"},
+		},
+		{
+			name:   "malformed bool and negative ID",
+			target: "/articles/next-unread?after=-1&mark_read=maybe",
+			query:  &nextUnreadQuery{Scope: "all"},
+			want:   []string{"after must be a non-negative integer", "mark_read must be true or false"},
+		},
+		{
+			name:   "binding rule",
+			target: "/articles/next-unread?scope=everything",
+			query:  &nextUnreadQuery{Scope: "all"},
+			want:   []string{"scope must be one of feed, folder, all"},
+		},
+		{
+			name:   "malformed time",
+			target: "/feeds/1/read?before=yesterday",
+			query:  &markFeedReadQuery{},
+			want:   []string{"before must be an RFC 3339 time"},
+		},
+		{
+			name:   "required after trimming",
+			target: "/articles/search?q=%20%20",
+			query:  &searchQuery{},
+			want:   []string{"q is required"},
+		},
+		{
+			name:   "briefing window and language",
+			target: "/briefing?since=2020-01-01T00:00:00Z&summary_language=de_DE",
+			query:  &briefingQuery{},
+			want:   []string{"since must be within the last 7 days", "summary_language must be a language code"},
+		},
+		{
+			name:   "briefing without since",
+			target: "/briefing",
+			query:  &briefingQuery{},
+			want:   []string{"since is required"},
+		},
+		{
+			name:   "export format",
+			target: "/feeds/1/exports?format=pdf",
+			query:  &archiveExportQuery{},
+			want:   []string{"format must be one of jsonl, warc"},
+		},
+		{
+			name:   "embedded filter",
+			target: "/admin/feeds?status=sleeping&not_fetched_for=-1h&limit=0",
+			query:  &adminFeedListQuery{Limit: defaultAdminFeedLimit},
+			want:   []string{"status", `invalid not_fetched_for duration "-1h"`, "limit must be between 1 and 1000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newPaginationContext(tt.target, "")
+			message := requireInvalidQuery(t, bindQuery(c, tt.query))
+			for _, want := range tt.want {
+				assert.Contains(t, message, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+const rateLimitKeyPattern = "ratelimit:%s:%s"
+
+// RequestCounter counts requests in fixed windows, shared by all api-service replicas
+type RequestCounter interface {
+	// Increment adds one to the counter at key, which expires ttl after its first increment
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// RateLimitMiddleware lets each client IP make limit requests per window to the routes
+// it guards, counted under name, and refuses the rest with ierr.ErrRateLimited. Requests
+// go through when the counter cannot be reached.
+func RateLimitMiddleware(counter RequestCounter, name string, limit int, window time.Duration) gin.HandlerFunc {
+	retryAfter := strconv.Itoa(retryAfterSeconds(window))
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		count, err := counter.Increment(ctx, fmt.Sprintf(rateLimitKeyPattern, name, c.ClientIP()), window)
+		if err != nil {
+			logger.FromContext(ctx).Warn("failed to count request for rate limit", "limit", name, "error", err.Error())
+			c.Next()
+			return
+		}
+		if count > int64(limit) {
+			c.Header("Retry-After", retryAfter)
+			ierr.AbortWithError(c, ierr.ErrRateLimited)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+// requestCounts counts requests per key, failing while down is set
+type requestCounts struct {
+	counts map[string]int64
+	down   bool
+}
+
+func (s *requestCounts) Increment(_ context.Context, key string, _ time.Duration) (int64, error) {
+	if s.down {
+		return 0, errors.New("connection refused")
+	}
+	s.counts[key]++
+	return s.counts[key], nil
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	counter := &requestCounts{counts: make(map[string]int64)}
+	engine := gin.New()
+	engine.Use(ierr.ErrorHandlerMiddleware())
+	engine.GET("/shared/:slug", RateLimitMiddleware(counter, "shared_folders", 2, time.Minute), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	get := func(remoteAddr string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/shared/go-weekly", nil)
+		req.RemoteAddr = remoteAddr
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, get("203.0.113.7:4711").Code)
+	assert.Equal(t, http.StatusOK, get("203.0.113.7:4712").Code)
+	w := get("203.0.113.7:4713")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), ierr.ErrRateLimited.Message)
+	assert.Equal(t, int64(3), counter.counts["ratelimit:shared_folders:203.0.113.7"])
+
+	// other clients have their own count
+	assert.Equal(t, http.StatusOK, get("198.51.100.1:4711").Code)
+
+	// a counter that cannot be reached lets requests through
+	counter.down = true
+	assert.Equal(t, http.StatusOK, get("203.0.113.7:4714").Code)
+}
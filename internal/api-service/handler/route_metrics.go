@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// unmatchedRoute groups the requests that matched no route, whatever their method, so
+// probes for random paths cannot grow the metrics without bound
+const unmatchedRoute = "(unmatched)"
+
+// RouteStats aggregates the requests served by one method and route template
+type RouteStats struct {
+	Method       string  `json:"method"`
+	Route        string  `json:"route"`
+	Requests     int64   `json:"requests"`
+	ClientErrors int64   `json:"client_errors"`
+	ServerErrors int64   `json:"server_errors"`
+	Slow         int64   `json:"slow"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	MaxLatencyMs int64   `json:"max_latency_ms"`
+	BytesOut     int64   `json:"bytes_out"`
+
+	totalLatency time.Duration
+	maxLatency   time.Duration
+}
+
+// RouteMetrics keeps per-route request statistics in memory since the process started. It
+// is fed by the access log middleware and served to operators by the admin endpoints.
+type RouteMetrics struct {
+	mu     sync.Mutex
+	routes map[[2]string]*RouteStats
+}
+
+func NewRouteMetrics() *RouteMetrics {
+	return &RouteMetrics{routes: make(map[[2]string]*RouteStats)}
+}
+
+// ObserveAccess implements logger.AccessMetrics
+func (m *RouteMetrics) ObserveAccess(r logger.AccessRecord) {
+	method, route := r.Method, r.Route
+	if route == "" {
+		method, route = "*", unmatchedRoute
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := [2]string{method, route}
+	stats, ok := m.routes[key]
+	if !ok {
+		stats = &RouteStats{Method: method, Route: route}
+		m.routes[key] = stats
+	}
+	stats.Requests++
+	switch {
+	case r.Status >= 500:
+		stats.ServerErrors++
+	case r.Status >= 400:
+		stats.ClientErrors++
+	}
+	if r.Slow {
+		stats.Slow++
+	}
+	stats.totalLatency += r.Latency
+	stats.maxLatency = max(stats.maxLatency, r.Latency)
+	stats.BytesOut += r.BytesOut
+}
+
+// Snapshot returns the statistics of every route seen so far, ordered by route and method
+func (m *RouteMetrics) Snapshot() []RouteStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]RouteStats, 0, len(m.routes))
+	for _, stats := range m.routes {
+		snapshot := *stats
+		snapshot.AvgLatencyMs = float64(stats.totalLatency.Microseconds()) / 1000 / float64(stats.Requests)
+		snapshot.MaxLatencyMs = stats.maxLatency.Milliseconds()
+		out = append(out, snapshot)
+	}
+	slices.SortFunc(out, func(a, b RouteStats) int {
+		return cmp.Or(cmp.Compare(a.Route, b.Route), cmp.Compare(a.Method, b.Method))
+	})
+	return out
+}
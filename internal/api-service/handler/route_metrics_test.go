@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+type recordingAccessMetrics struct {
+	records []logger.AccessRecord
+}
+
+func (r *recordingAccessMetrics) ObserveAccess(record logger.AccessRecord) {
+	r.records = append(r.records, record)
+}
+
+func TestAccessLogMiddleware_RecordsRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	recorder := &recordingAccessMetrics{}
+	metrics := NewRouteMetrics()
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+	router.Use(logger.AccessLogMiddleware(logger.AccessLogOptions{SlowThreshold: 20 * time.Millisecond, Metrics: recorder}))
+	router.Use(logger.AccessLogMiddleware(logger.AccessLogOptions{SlowThreshold: 20 * time.Millisecond, Metrics: metrics}))
+	router.GET("/feeds/:feed_id", func(c *gin.Context) {
+		c.Request = c.Request.WithContext(logger.WithUserID(c.Request.Context(), 7))
+		if c.Param("feed_id") == "slow" {
+			time.Sleep(30 * time.Millisecond)
+		}
+		c.String(http.StatusOK, "hello")
+	})
+
+	for _, path := range []string{"/feeds/1", "/feeds/slow", "/nowhere", "/elsewhere"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Request-ID", "req-1")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	require.Len(t, recorder.records, 4)
+	first := recorder.records[0]
+	assert.Equal(t, "/feeds/:feed_id", first.Route)
+	assert.Equal(t, "/feeds/1", first.Path)
+	assert.Equal(t, http.StatusOK, first.Status)
+	assert.EqualValues(t, 7, first.UserID)
+	assert.Equal(t, "req-1", first.RequestID)
+	assert.EqualValues(t, 5, first.BytesOut)
+	assert.False(t, first.Slow)
+	assert.True(t, recorder.records[1].Slow)
+	assert.Empty(t, recorder.records[2].Route)
+
+	stats := metrics.Snapshot()
+	require.Len(t, stats, 2)
+	assert.Equal(t, RouteStats{Method: "*", Route: unmatchedRoute, Requests: 2, ClientErrors: 2}, withoutLatency(stats[0]))
+	assert.Equal(t, RouteStats{Method: http.MethodGet, Route: "/feeds/:feed_id", Requests: 2, Slow: 1, BytesOut: 10}, withoutLatency(stats[1]))
+	assert.GreaterOrEqual(t, stats[1].MaxLatencyMs, int64(30))
+}
+
+func withoutLatency(stats RouteStats) RouteStats {
+	stats.AvgLatencyMs, stats.MaxLatencyMs = 0, 0
+	stats.totalLatency, stats.maxLatency = 0, 0
+	return stats
+}
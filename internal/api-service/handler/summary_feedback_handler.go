@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Fancu1/phoenix-rss/internal/summaryquality"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// SummaryFeedbackHandler takes readers' ratings of AI summaries
+type SummaryFeedbackHandler struct {
+	store *summaryquality.Store
+}
+
+func NewSummaryFeedbackHandler(store *summaryquality.Store) *SummaryFeedbackHandler {
+	return &SummaryFeedbackHandler{store: store}
+}
+
+// SummaryFeedbackRequest rates the current summary of an article
+type SummaryFeedbackRequest struct {
+	Useful *bool `json:"useful" binding:"required"`
+	// Hallucination flags a summary stating something the article does not say
+	Hallucination bool   `json:"hallucination"`
+	Comment       string `json:"comment"`
+}
+
+// RateSummary records the caller's rating of an article's summary, replacing their
+// earlier rating of it
+func (h *SummaryFeedbackHandler) RateSummary(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	articleID, err := strconv.ParseUint(c.Param("article_id"), 10, 32)
+	if err != nil {
+		c.Error(ierr.NewValidationError("invalid article ID"))
+		return
+	}
+
+	var req SummaryFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+
+	feedback, err := h.store.Rate(ctx, userID, uint(articleID), summaryquality.Rating{
+		Useful:        *req.Useful,
+		Hallucination: req.Hallucination,
+		Comment:       req.Comment,
+	})
+	if err != nil {
+		log.Error("failed to rate summary", "user_id", userID, "article_id", articleID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, feedback)
+}
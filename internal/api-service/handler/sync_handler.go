@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/readstate"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+const (
+	defaultSyncPageSize = 500
+	maxSyncPageSize     = 1000
+	maxDeviceIDLength   = 128
+)
+
+// syncPageQuery bounds a page of changed article states
+type syncPageQuery struct {
+	Limit int `form:"limit"`
+}
+
+func (q *syncPageQuery) validate() []string {
+	return checkRange("limit", q.Limit, 1, maxSyncPageSize)
+}
+
+// SyncHandler serves the read-state sync of offline-capable clients
+type SyncHandler struct {
+	store *readstate.Store
+}
+
+func NewSyncHandler(store *readstate.Store) *SyncHandler {
+	return &SyncHandler{store: store}
+}
+
+// ArticleStateChange is one read or starred change made on a client
+type ArticleStateChange struct {
+	ArticleID uint   `json:"article_id" binding:"required"`
+	Field     string `json:"field" binding:"required"`
+	Value     bool   `json:"value"`
+	// ChangedAt is the client's time of the change; it decides conflicts
+	ChangedAt *time.Time `json:"changed_at"`
+}
+
+type PushArticleStatesRequest struct {
+	DeviceID string               `json:"device_id"`
+	Cursor   string               `json:"cursor"`
+	Changes  []ArticleStateChange `json:"changes"`
+}
+
+// ArticleStatesPage is the states changed since a cursor
+type ArticleStatesPage struct {
+	States  []models.UserArticleState `json:"states"`
+	Cursor  string                    `json:"cursor"`
+	HasMore bool                      `json:"has_more"`
+}
+
+// PullArticleStates returns the caller's article states changed after the cursor. A
+// client without a cursor gets every state, and keeps the returned cursor for next time.
+func (h *SyncHandler) PullArticleStates(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	cursor, err := readstate.ParseCursor(c.Query("cursor"))
+	if err != nil {
+		c.Error(invalidQuery("cursor is invalid"))
+		return
+	}
+	page, err := h.changes(c, userID, cursor)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// PushArticleStates merges the changes a client made, possibly offline, into the caller's
+// article states and answers with everything changed after the client's cursor, its own
+// changes included, so it ends up with the merged result.
+func (h *SyncHandler) PushArticleStates(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	var req PushArticleStatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+	if len(req.Changes) > readstate.MaxChanges {
+		c.Error(ierr.NewValidationError(fmt.Sprintf("at most %d changes are allowed per push", readstate.MaxChanges)))
+		return
+	}
+	if len(req.DeviceID) > maxDeviceIDLength {
+		c.Error(ierr.NewValidationError(fmt.Sprintf("device_id must be at most %d characters", maxDeviceIDLength)))
+		return
+	}
+	cursor, err := readstate.ParseCursor(req.Cursor)
+	if err != nil {
+		c.Error(ierr.NewValidationError("invalid cursor"))
+		return
+	}
+
+	changes := make([]readstate.Change, len(req.Changes))
+	for i, change := range req.Changes {
+		if !readstate.ValidField(change.Field) {
+			c.Error(ierr.NewValidationError(fmt.Sprintf("unknown field %q: must be %s or %s", change.Field, models.StateFieldRead, models.StateFieldStarred)))
+			return
+		}
+		changes[i] = readstate.Change{ArticleID: change.ArticleID, Field: change.Field, Value: change.Value}
+		if change.ChangedAt != nil {
+			changes[i].ChangedAt = *change.ChangedAt
+		}
+	}
+
+	result, err := h.store.Push(ctx, userID, req.DeviceID, changes)
+	if err != nil {
+		log.Error("failed to push article states", "user_id", userID, "changes", len(changes), "error", err.Error())
+		c.Error(ierr.NewDatabaseError(err))
+		return
+	}
+	page, err := h.changes(c, userID, cursor)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	rejected := result.Rejected
+	if rejected == nil {
+		rejected = []uint{}
+	}
+	log.Info("article states pushed", "user_id", userID, "device_id", req.DeviceID, "changes", len(changes), "applied", result.Applied, "rejected", len(rejected))
+	c.JSON(http.StatusOK, gin.H{
+		"applied":  result.Applied,
+		"rejected": rejected,
+		"states":   page.States,
+		"cursor":   page.Cursor,
+		"has_more": page.HasMore,
+	})
+}
+
+func (h *SyncHandler) changes(c *gin.Context, userID uint, cursor readstate.Cursor) (ArticleStatesPage, error) {
+	query := syncPageQuery{Limit: defaultSyncPageSize}
+	if err := bindQuery(c, &query); err != nil {
+		return ArticleStatesPage{}, err
+	}
+	states, next, more, err := h.store.Changes(c.Request.Context(), userID, cursor, query.Limit)
+	if err != nil {
+		return ArticleStatesPage{}, ierr.NewDatabaseError(err)
+	}
+	if states == nil {
+		states = []models.UserArticleState{}
+	}
+	return ArticleStatesPage{States: states, Cursor: next.String(), HasMore: more}, nil
+}
@@ -1,17 +1,31 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/Fancu1/phoenix-rss/internal/api-service/core"
+	"github.com/Fancu1/phoenix-rss/internal/api-service/repository"
+	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
 	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
 )
 
 type UserHandler struct {
-	userService core.UserServiceInterface
+	userService    core.UserServiceInterface
+	loginGuard     *core.LoginGuard
+	auditRepo      *repository.AuditRepository
+	collectionRepo *repository.CollectionRepository
+	feedService    core.FeedServiceInterface
 }
 
 func NewUserHandler(userService core.UserServiceInterface) *UserHandler {
@@ -20,6 +34,23 @@ func NewUserHandler(userService core.UserServiceInterface) *UserHandler {
 	}
 }
 
+// SetLoginGuard enables brute-force protection of the login endpoint
+func (h *UserHandler) SetLoginGuard(guard *core.LoginGuard) {
+	h.loginGuard = guard
+}
+
+// SetAuditLog stores login events in the audit trail; without it they are only logged
+func (h *UserHandler) SetAuditLog(auditRepo *repository.AuditRepository) {
+	h.auditRepo = auditRepo
+}
+
+// SetDefaultFeeds subscribes new users to the feeds of the collections marked for new
+// users when they register
+func (h *UserHandler) SetDefaultFeeds(collectionRepo *repository.CollectionRepository, feedService core.FeedServiceInterface) {
+	h.collectionRepo = collectionRepo
+	h.feedService = feedService
+}
+
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
 	Password string `json:"password" binding:"required,min=6"`
@@ -28,16 +59,36 @@ type RegisterRequest struct {
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// ChallengeResponse answers the challenge asked for after repeated failures
+	ChallengeResponse string `json:"challenge_response"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 type AuthResponse struct {
 	Token string `json:"token"`
-	User  struct {
+	// ExpiresAt is when Token expires; RefreshToken gets the next one
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token"`
+	User         struct {
 		ID       uint   `json:"id"`
 		Username string `json:"username"`
 	} `json:"user"`
 }
 
+func newAuthResponse(tokens *models.AuthTokens, user *models.User) AuthResponse {
+	response := AuthResponse{
+		Token:        tokens.AccessToken,
+		ExpiresAt:    tokens.ExpiresAt,
+		RefreshToken: tokens.RefreshToken,
+	}
+	response.User.ID = user.ID
+	response.User.Username = user.Username
+	return response
+}
+
 func (h *UserHandler) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -51,8 +102,8 @@ func (h *UserHandler) Register(c *gin.Context) {
 		c.Error(ierr.NewValidationError("username must be at least 3 characters"))
 		return
 	}
-	if len(req.Password) < 6 {
-		c.Error(ierr.NewValidationError("password must be at least 6 characters"))
+	if len(req.Password) < models.MinPasswordLength {
+		c.Error(ierr.NewValidationError(fmt.Sprintf("password must be at least %d characters", models.MinPasswordLength)))
 		return
 	}
 
@@ -63,46 +114,812 @@ func (h *UserHandler) Register(c *gin.Context) {
 	}
 
 	// Generate token for immediate login
-	token, err := h.userService.Login(req.Username, req.Password)
+	tokens, err := h.userService.Login(req.Username, req.Password, sessionClient(c))
 	if err != nil {
 		c.Error(ierr.NewInternalError(err))
 		return
 	}
 
-	response := AuthResponse{
-		Token: token,
+	h.subscribeDefaultFeeds(c.Request.Context(), user.ID)
+
+	c.JSON(http.StatusCreated, newAuthResponse(tokens, user))
+}
+
+// subscribeDefaultFeeds subscribes a new user to the instance's default feeds. A failure
+// does not fail the registration, the user can still subscribe to the collections later.
+func (h *UserHandler) subscribeDefaultFeeds(ctx context.Context, userID uint) {
+	if h.collectionRepo == nil {
+		return
 	}
-	response.User.ID = user.ID
-	response.User.Username = user.Username
+	log := logger.FromContext(ctx)
 
-	c.JSON(http.StatusCreated, response)
+	collections, err := h.collectionRepo.ListForNewUsers(ctx)
+	if err != nil {
+		log.Warn("failed to load default feed collections", "user_id", userID, "error", err.Error())
+		return
+	}
+	if len(collections) == 0 {
+		return
+	}
+
+	result, err := subscribeToCollections(ctx, h.feedService, userID, collections...)
+	if err != nil {
+		log.Warn("failed to subscribe new user to default feeds", "user_id", userID, "error", err.Error())
+		return
+	}
+	log.Info("subscribed new user to default feeds", "user_id", userID, "subscribed", result.Subscribed, "failed", result.Failed)
 }
 
+// Login exchanges credentials for a token. With a login guard set, repeated failures lock
+// the username or the client IP out for a while, and may require a challenge first.
 func (h *UserHandler) Login(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.Error(ierr.NewValidationError(err.Error()))
 		return
 	}
+	ip := c.ClientIP()
+
+	if h.loginGuard != nil {
+		if err := h.loginGuard.Check(ctx, req.Username, ip, req.ChallengeResponse); err != nil {
+			var locked *core.LoginLockedError
+			switch {
+			case errors.As(err, &locked):
+				h.recordAudit(c, models.AuditLoginBlocked, nil, req.Username, map[string]any{"retry_after_seconds": retryAfterSeconds(locked.RetryAfter)})
+				c.Header("Retry-After", strconv.Itoa(retryAfterSeconds(locked.RetryAfter)))
+				c.Error(err)
+				return
+			case errors.Is(err, ierr.ErrChallengeRequired):
+				if req.ChallengeResponse != "" {
+					h.recordAudit(c, models.AuditLoginChallengeFailed, nil, req.Username, nil)
+				}
+				c.Error(err)
+				return
+			default:
+				// the guard's store is unavailable: let the attempt through rather than lock everyone out
+				log.Warn("login guard check failed", "error", err.Error())
+			}
+		}
+	}
 
-	token, err := h.userService.Login(req.Username, req.Password)
+	tokens, err := h.userService.Login(req.Username, req.Password, sessionClient(c))
 	if err != nil {
+		if errors.Is(err, ierr.ErrInvalidCredentials) {
+			h.recordLoginFailure(c, req.Username, ip)
+			if h.loginGuard != nil && h.loginGuard.ChallengeRequired(ctx, req.Username) {
+				// tell the client to show the challenge on its next attempt
+				err = fmt.Errorf("%w: %w", ierr.ErrChallengeRequired, err)
+			}
+		}
 		c.Error(err)
 		return
 	}
 
 	// Get user details for response
-	user, err := h.userService.GetUserFromToken(token)
+	user, err := h.userService.GetUserFromToken(tokens.AccessToken)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	response := AuthResponse{
-		Token: token,
+	if h.loginGuard != nil {
+		if err := h.loginGuard.RecordSuccess(ctx, req.Username); err != nil {
+			log.Warn("failed to reset failed logins", "user_id", user.ID, "error", err.Error())
+		}
+	}
+	h.recordAudit(c, models.AuditLoginSucceeded, &user.ID, user.Username, nil)
+
+	c.JSON(http.StatusOK, newAuthResponse(tokens, user))
+}
+
+// Refresh exchanges a refresh token for a new access token and refresh token. The old
+// refresh token stops working; presenting it again signs its session out.
+func (h *UserHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+
+	tokens, err := h.userService.RefreshToken(c.Request.Context(), req.RefreshToken, sessionClient(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := h.userService.GetUserFromToken(tokens.AccessToken)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newAuthResponse(tokens, user))
+}
+
+// Logout signs the session of the calling token out, so neither its access token nor
+// its refresh token works any more
+func (h *UserHandler) Logout(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	// tokens issued before sessions existed have nothing to sign out
+	if sessionID, _ := GetSessionIDFromContext(c); sessionID != "" {
+		err := h.userService.RevokeSession(c.Request.Context(), userID, sessionID)
+		if err != nil && !errors.Is(err, ierr.ErrSessionNotFound) {
+			c.Error(err)
+			return
+		}
+		h.recordAudit(c, models.AuditLogout, &userID, contextUsername(c), map[string]any{"session_id": sessionID})
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// sessionClient describes the caller for the session a login opens
+func sessionClient(c *gin.Context) models.SessionClient {
+	return models.SessionClient{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+}
+
+// recordLoginFailure counts a failed login and audits it, and the lockout it started
+func (h *UserHandler) recordLoginFailure(c *gin.Context, username, ip string) {
+	h.recordAudit(c, models.AuditLoginFailed, nil, username, nil)
+	if h.loginGuard == nil {
+		return
+	}
+	lockout, err := h.loginGuard.RecordFailure(c.Request.Context(), username, ip)
+	if err != nil {
+		logger.FromContext(c.Request.Context()).Warn("failed to count failed login", "error", err.Error())
+	}
+	if lockout > 0 {
+		h.recordAudit(c, models.AuditLoginLocked, nil, username, map[string]any{"lockout_seconds": retryAfterSeconds(lockout)})
+	}
+}
+
+// maxAuditUsernameLength matches the users table, attempts may name anything
+const maxAuditUsernameLength = 50
+
+// recordAudit logs a security event and appends it to the audit trail. A failure to store
+// it is logged and does not fail the request.
+func (h *UserHandler) recordAudit(c *gin.Context, event string, userID *uint, username string, details map[string]any) {
+	if err := h.writeAudit(c, event, userID, username, details); err != nil {
+		logger.FromContext(c.Request.Context()).Error("failed to record audit event", "event", event, "error", err.Error())
+	}
+}
+
+// writeAudit logs a security event and appends it to the audit trail, for the requests
+// that must not go ahead unless it is stored
+func (h *UserHandler) writeAudit(c *gin.Context, event string, userID *uint, username string, details map[string]any) error {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+	requestID, _ := logger.GetRequestID(ctx)
+
+	if runes := []rune(username); len(runes) > maxAuditUsernameLength {
+		username = string(runes[:maxAuditUsernameLength])
+	}
+	entry := &models.AuditEvent{Event: event, UserID: userID, Username: username, IP: c.ClientIP(), RequestID: requestID}
+	if len(details) > 0 {
+		if data, err := json.Marshal(details); err == nil {
+			entry.Details = string(data)
+		}
+	}
+	log.Info("audit event", "event", event, "username", username, "ip", entry.IP, "details", entry.Details)
+
+	if h.auditRepo == nil {
+		return nil
+	}
+	return h.auditRepo.Record(ctx, entry)
+}
+
+// retryAfterSeconds rounds a wait up to whole seconds for the Retry-After header
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+const (
+	defaultLLMUsageDays = 30
+	maxLLMUsageDays     = 366
+)
+
+// llmUsageQuery is the number of days LLM usage is reported for
+type llmUsageQuery struct {
+	Days int `form:"days"`
+}
+
+func (q *llmUsageQuery) validate() []string {
+	return checkRange("days", q.Days, 1, maxLLMUsageDays)
+}
+
+type SetLLMCredentialRequest struct {
+	BaseURL string `json:"base_url"`
+	Model   string `json:"model"`
+	APIKey  string `json:"api_key" binding:"required"`
+}
+
+type LLMUsageResponse struct {
+	Since time.Time                `json:"since"`
+	Usage []models.LLMUsageSummary `json:"usage"`
+}
+
+// GetLLMCredential returns the caller's bring-your-own-key settings (never the key itself)
+func (h *UserHandler) GetLLMCredential(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	credential, err := h.userService.GetLLMCredential(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, credential)
+}
+
+// SetLLMCredential stores or replaces the caller's own LLM API key
+func (h *UserHandler) SetLLMCredential(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	var req SetLLMCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+
+	credential, err := h.userService.SetLLMCredential(ctx, userID, req.BaseURL, req.Model, req.APIKey)
+	if err != nil {
+		log.Error("failed to set LLM credential", "user_id", userID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	log.Info("user LLM credential updated", "user_id", userID, "model", credential.Model)
+	c.JSON(http.StatusOK, credential)
+}
+
+// DeleteLLMCredential removes the caller's key; processing falls back to the instance key
+func (h *UserHandler) DeleteLLMCredential(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	if err := h.userService.DeleteLLMCredential(c.Request.Context(), userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "LLM credential removed"})
+}
+
+// GetLLMUsage reports the caller's LLM token usage over the last `days` days
+func (h *UserHandler) GetLLMUsage(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	query := llmUsageQuery{Days: defaultLLMUsageDays}
+	if err := bindQuery(c, &query); err != nil {
+		c.Error(err)
+		return
+	}
+	since := time.Now().UTC().AddDate(0, 0, -query.Days)
+
+	usage, err := h.userService.GetLLMUsage(c.Request.Context(), userID, since)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, LLMUsageResponse{Since: since, Usage: usage})
+}
+
+// ListSessions returns the caller's active sessions, marking the one making the request
+func (h *UserHandler) ListSessions(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	sessions, err := h.userService.ListSessions(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	currentID, _ := GetSessionIDFromContext(c)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// RevokeSession signs one of the caller's sessions out; its token stops working at once
+func (h *UserHandler) RevokeSession(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	sessionID := c.Param("session_id")
+	if err := h.userService.RevokeSession(c.Request.Context(), userID, sessionID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.recordAudit(c, models.AuditSessionRevoked, &userID, contextUsername(c), map[string]any{"session_id": sessionID})
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// RevokeOtherSessions signs out every session of the caller but the one making the request
+func (h *UserHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	currentID, _ := GetSessionIDFromContext(c)
+	revoked, err := h.userService.RevokeOtherSessions(c.Request.Context(), userID, currentID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.recordAudit(c, models.AuditSessionsRevoked, &userID, contextUsername(c), map[string]any{"revoked": revoked})
+	c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
+
+// UpdatePreferencesRequest changes the preferences that are present and keeps the others
+type UpdatePreferencesRequest struct {
+	DefaultSort     *string `json:"default_sort"`
+	ShowRead        *bool   `json:"show_read"`
+	ListContent     *string `json:"list_content"`
+	Theme           *string `json:"theme"`
+	DefaultView     *string `json:"default_view"`
+	ItemsPerPage    *int    `json:"items_per_page"`
+	SummaryLanguage *string `json:"summary_language"`
+}
+
+// GetPreferences returns the caller's reading preferences, the defaults until they change
+// one
+func (h *UserHandler) GetPreferences(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	preferences, err := h.userService.GetPreferences(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+// UpdatePreferences changes the caller's reading preferences present in the body
+func (h *UserHandler) UpdatePreferences(c *gin.Context) {
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	var req UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+
+	preferences, err := h.userService.UpdatePreferences(ctx, userID, models.PreferencesUpdate{
+		DefaultSort:     req.DefaultSort,
+		ShowRead:        req.ShowRead,
+		ListContent:     req.ListContent,
+		Theme:           req.Theme,
+		DefaultView:     req.DefaultView,
+		ItemsPerPage:    req.ItemsPerPage,
+		SummaryLanguage: req.SummaryLanguage,
+	})
+	if err != nil {
+		log.Error("failed to update preferences", "user_id", userID, "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+// ProfileResponse is the caller's account as they see it
+type ProfileResponse struct {
+	ID          uint      `json:"id"`
+	Username    string    `json:"username"`
+	Email       *string   `json:"email"`
+	DisplayName string    `json:"display_name"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// ImpersonatedBy is the administrator viewing the profile with an impersonation token
+	ImpersonatedBy string `json:"impersonated_by,omitempty"`
+}
+
+// UpdateProfileRequest changes the profile fields that are present; an empty email
+// removes it
+type UpdateProfileRequest struct {
+	Username    *string `json:"username"`
+	Email       *string `json:"email"`
+	DisplayName *string `json:"display_name"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// GetProfile returns the caller's account and profile
+func (h *UserHandler) GetProfile(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	user, err := h.userService.GetProfile(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := toProfileResponse(user)
+	response.ImpersonatedBy, _ = GetImpersonatorFromContext(c)
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateProfile changes the caller's username, email and/or display name
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+	var fields []string
+	if req.Username != nil {
+		fields = append(fields, "username")
+	}
+	if req.Email != nil {
+		fields = append(fields, "email")
+	}
+	if req.DisplayName != nil {
+		fields = append(fields, "display_name")
+	}
+	if len(fields) == 0 {
+		c.Error(ierr.NewValidationError("nothing to update: set username, email and/or display_name"))
+		return
+	}
+
+	user, err := h.userService.UpdateProfile(c.Request.Context(), userID, models.ProfileUpdate{
+		Username:    req.Username,
+		Email:       req.Email,
+		DisplayName: req.DisplayName,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	details := map[string]any{"fields": fields}
+	if previous := contextUsername(c); req.Username != nil && previous != user.Username {
+		details["previous_username"] = previous
+	}
+	h.recordAudit(c, models.AuditProfileUpdated, &userID, user.Username, details)
+	c.JSON(http.StatusOK, toProfileResponse(user))
+}
+
+// ChangePassword replaces the caller's password once the current one checks out. Every
+// other session is signed out; the one making the request stays signed in.
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	userID, exists := GetUserIDFromContext(c)
+	if !exists {
+		c.Error(ierr.ErrUnauthorized)
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+
+	currentID, _ := GetSessionIDFromContext(c)
+	revoked, err := h.userService.ChangePassword(c.Request.Context(), userID, req.CurrentPassword, req.NewPassword, currentID)
+	if err != nil {
+		if errors.Is(err, ierr.ErrWrongPassword) {
+			h.recordAudit(c, models.AuditPasswordChangeFailed, &userID, contextUsername(c), nil)
+		}
+		c.Error(err)
+		return
+	}
+
+	h.recordAudit(c, models.AuditPasswordChanged, &userID, contextUsername(c), map[string]any{"revoked_sessions": revoked})
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed", "revoked_sessions": revoked})
+}
+
+// RequestPasswordReset mails a reset token to the account with the email. It answers the
+// same whether or not there is such an account.
+func (h *UserHandler) RequestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+
+	if err := h.userService.RequestPasswordReset(c.Request.Context(), req.Email, c.ClientIP()); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If an account uses this email, a password reset email is on its way"})
+}
+
+// ResetPassword sets a new password with the token from a reset email and signs out every
+// session of the account
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+
+	user, revoked, err := h.userService.ResetPassword(c.Request.Context(), req.Token, req.NewPassword)
+	if err != nil {
+		if errors.Is(err, ierr.ErrInvalidResetToken) {
+			h.recordAudit(c, models.AuditPasswordResetFailed, nil, "", nil)
+		}
+		c.Error(err)
+		return
 	}
+
+	h.recordAudit(c, models.AuditPasswordReset, &user.ID, user.Username, map[string]any{"revoked_sessions": revoked})
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset", "revoked_sessions": revoked})
+}
+
+const (
+	defaultAdminUserLimit = 100
+	maxAdminUserLimit     = 1000
+)
+
+// adminUserListQuery is a page of the instance's users
+type adminUserListQuery struct {
+	Limit int `form:"limit"`
+}
+
+func (q *adminUserListQuery) validate() []string {
+	return checkRange("limit", q.Limit, 1, maxAdminUserLimit)
+}
+
+// ListUsers pages through every user of the instance in ID order, for administrators. It
+// always answers with the list envelope.
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	query := adminUserListQuery{Limit: defaultAdminUserLimit}
+	if err := bindQuery(c, &query); err != nil {
+		c.Error(err)
+		return
+	}
+	pageToken, err := parseTokenCursor(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	var afterID uint64
+	if pageToken != "" {
+		if afterID, err = strconv.ParseUint(pageToken, 10, 32); err != nil {
+			c.Error(invalidQuery("cursor is invalid"))
+			return
+		}
+	}
+
+	users, total, err := h.userService.ListUsers(ctx, uint(afterID), query.Limit)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to list users", "error", err.Error())
+		c.Error(err)
+		return
+	}
+
+	items := make([]ProfileResponse, len(users))
+	for i := range users {
+		items[i] = toProfileResponse(&users[i])
+	}
+	var nextToken string
+	if len(users) == query.Limit {
+		nextToken = strconv.FormatUint(uint64(users[len(users)-1].ID), 10)
+	}
+	writeListEnvelope(c, ListEnvelope[ProfileResponse]{
+		Items:      items,
+		NextCursor: encodeTokenCursor(nextToken),
+		Total:      total,
+	})
+}
+
+// SetUserRoleRequest is the body of SetUserRole
+type SetUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user admin"`
+}
+
+// SetUserRole makes a user an administrator or a regular user. The change applies to the
+// user's next admin request, without signing in again.
+func (h *UserHandler) SetUserRole(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil || userID == 0 {
+		c.Error(ierr.NewValidationError("invalid user ID"))
+		return
+	}
+
+	var req SetUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+
+	user, err := h.userService.SetUserRole(c.Request.Context(), uint(userID), req.Role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	changedBy := contextUsername(c)
+	if changedBy == "" {
+		changedBy = "admin token"
+	}
+	h.recordAudit(c, models.AuditRoleChanged, &user.ID, user.Username, map[string]any{"role": user.Role, "changed_by": changedBy})
+	c.JSON(http.StatusOK, toProfileResponse(user))
+}
+
+func toProfileResponse(user *models.User) ProfileResponse {
+	return ProfileResponse{
+		ID:          user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		DisplayName: user.DisplayName,
+		Role:        user.Role,
+		CreatedAt:   user.CreatedAt,
+	}
+}
+
+// contextUsername is the authenticated username, for the audit trail
+func contextUsername(c *gin.Context) string {
+	if v, ok := c.Get("user"); ok {
+		return v.(*models.User).Username
+	}
+	return ""
+}
+
+// maxImpersonationReasonLength bounds the reason stored in the audit trail
+const maxImpersonationReasonLength = 500
+
+// ImpersonateRequest is the body of ImpersonateUser
+type ImpersonateRequest struct {
+	// Reason is recorded in the audit trail, e.g. the support ticket being debugged
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ImpersonationResponse carries the token to use the API as the user
+type ImpersonationResponse struct {
+	Token          string    `json:"token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	ImpersonatedBy string    `json:"impersonated_by"`
+	User           struct {
+		ID       uint   `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+}
+
+// ImpersonateUser issues a short-lived, read-only token for an administrator to see the
+// user's feeds and articles as they do. It takes an administrator signed in as
+// themselves, not the admin token. Who impersonated whom and why is recorded in the
+// audit trail before the token is issued, and no token is issued if it cannot be.
+func (h *UserHandler) ImpersonateUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil || userID == 0 {
+		c.Error(ierr.NewValidationError("invalid user ID"))
+		return
+	}
+
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(ierr.NewValidationError(err.Error()))
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		c.Error(ierr.NewValidationError("reason is required"))
+		return
+	}
+	if len([]rune(req.Reason)) > maxImpersonationReasonLength {
+		c.Error(ierr.NewValidationError(fmt.Sprintf("reason must be at most %d characters", maxImpersonationReasonLength)))
+		return
+	}
+
+	adminID, ok := GetUserIDFromContext(c)
+	if !ok {
+		c.Error(ierr.ErrForbidden.WithCause(fmt.Errorf("impersonation requires signing in as an administrator, not the admin token")))
+		return
+	}
+	admin := contextUsername(c)
+	if uint(userID) == adminID {
+		c.Error(ierr.NewValidationError("cannot impersonate yourself"))
+		return
+	}
+
+	// the user service checks these again when it signs the token, they are only checked
+	// here so that a refused attempt does not leave a started impersonation in the trail
+	target, err := h.userService.GetProfile(c.Request.Context(), uint(userID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if target.Role == models.RoleAdmin {
+		c.Error(ierr.ErrForbidden.WithCause(fmt.Errorf("user %d is an administrator and cannot be impersonated", target.ID)))
+		return
+	}
+
+	if err := h.writeAudit(c, models.AuditImpersonationStarted, &target.ID, target.Username, map[string]any{
+		"impersonated_by":    admin,
+		"impersonated_by_id": adminID,
+		"reason":             req.Reason,
+	}); err != nil {
+		c.Error(ierr.NewDatabaseError(fmt.Errorf("failed to record impersonation of user %d: %w", target.ID, err)))
+		return
+	}
+
+	user, tokens, err := h.userService.Impersonate(c.Request.Context(), uint(userID), adminID, admin)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response := ImpersonationResponse{Token: tokens.AccessToken, ExpiresAt: tokens.ExpiresAt, ImpersonatedBy: admin}
 	response.User.ID = user.ID
 	response.User.Username = user.Username
-
 	c.JSON(http.StatusOK, response)
 }
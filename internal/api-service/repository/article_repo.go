@@ -2,10 +2,17 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/Fancu1/phoenix-rss/internal/articletags"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/readstate"
+	"github.com/Fancu1/phoenix-rss/internal/summaries"
 )
 
 const (
@@ -15,12 +22,65 @@ const (
 	MaxPageSize = 50
 )
 
+// ArticleSort is the order of an article listing
+type ArticleSort string
+
+const (
+	// SortRecent lists the newest articles first
+	SortRecent ArticleSort = "recent"
+	// SortSmart ranks articles by a score mixing recency, unread status, feed affinity
+	// and starring, see SmartSortWeights
+	SortSmart ArticleSort = "smart"
+)
+
+// ParseArticleSort parses the sort query parameter; empty means SortRecent
+func ParseArticleSort(value string) (ArticleSort, error) {
+	switch sort := ArticleSort(strings.ToLower(strings.TrimSpace(value))); sort {
+	case "":
+		return SortRecent, nil
+	case SortRecent, SortSmart:
+		return sort, nil
+	default:
+		return "", fmt.Errorf("unknown sort %q: must be %s or %s", value, SortRecent, SortSmart)
+	}
+}
+
+// SmartSortWeights tunes the SortSmart score, computed in SQL as the sum of
+//   - Recency * h / (h + age in hours), h being RecencyHalfLife in hours, so an article
+//     RecencyHalfLife old scores half of a brand new one
+//   - Unread for articles the user has not read
+//   - Affinity * the share of the feed's articles the user has read
+//   - Starred for articles the user starred
+type SmartSortWeights struct {
+	Recency         float64
+	Unread          float64
+	Affinity        float64
+	Starred         float64
+	RecencyHalfLife time.Duration
+}
+
+// DefaultSmartSortWeights favour fresh unread articles from feeds that get read
+var DefaultSmartSortWeights = SmartSortWeights{
+	Recency:         1,
+	Unread:          0.5,
+	Affinity:        0.3,
+	Starred:         0.4,
+	RecencyHalfLife: 24 * time.Hour,
+}
+
 type ArticleRepository struct {
-	db *gorm.DB
+	db           *gorm.DB
+	smartWeights SmartSortWeights
+	now          func() time.Time
 }
 
 func NewArticleRepository(db *gorm.DB) *ArticleRepository {
-	return &ArticleRepository{db: db}
+	return &ArticleRepository{db: db, smartWeights: DefaultSmartSortWeights, now: time.Now}
+}
+
+// SetSmartSortWeights replaces the weights of the SortSmart score
+func (r *ArticleRepository) SetSmartSortWeights(weights SmartSortWeights) {
+	r.smartWeights = weights
 }
 
 func (r *ArticleRepository) ListByFeedID(ctx context.Context, feedID uint) ([]*models.Article, error) {
@@ -32,12 +92,12 @@ func (r *ArticleRepository) ListByFeedID(ctx context.Context, feedID uint) ([]*m
 	return articles, err
 }
 
-// ListByFeedIDPaginated returns paginated articles for a feed.
-// Results are ordered by published_at DESC (newest first).
-// Page numbers start from 1. Invalid inputs are normalized to defaults.
+// ListByFeedIDPaginated returns paginated articles for a feed in the given order, with
+// the user's read state. Page numbers start from 1. Invalid inputs are normalized to defaults.
 func (r *ArticleRepository) ListByFeedIDPaginated(
 	ctx context.Context,
-	feedID uint,
+	userID, feedID uint,
+	sort ArticleSort,
 	page, pageSize int,
 ) ([]*models.Article, int64, error) {
 	// Normalize inputs to prevent invalid queries
@@ -48,8 +108,17 @@ func (r *ArticleRepository) ListByFeedIDPaginated(
 		pageSize = DefaultPageSize
 	}
 
-	offset := (page - 1) * pageSize
+	return r.ListByFeedIDRange(ctx, userID, feedID, sort, (page-1)*pageSize, pageSize)
+}
 
+// ListByFeedIDRange returns up to limit articles for a feed starting at offset in the
+// given order, with the user's read state, along with the feed's article count
+func (r *ArticleRepository) ListByFeedIDRange(
+	ctx context.Context,
+	userID, feedID uint,
+	sort ArticleSort,
+	offset, limit int,
+) ([]*models.Article, int64, error) {
 	// Count total articles first (uses idx_articles_feed_id)
 	var total int64
 	if err := r.db.WithContext(ctx).
@@ -59,21 +128,70 @@ func (r *ArticleRepository) ListByFeedIDPaginated(
 		return nil, 0, err
 	}
 
-	// Fetch paginated articles (uses idx_articles_feed_published)
+	// Fetch paginated articles (recent order uses idx_articles_feed_published)
 	var articles []*models.Article
-	if err := r.db.WithContext(ctx).
+	if err := r.order(r.db.WithContext(ctx), sort, userID).
 		Where("feed_id = ?", feedID).
-		Order("published_at DESC").
 		Offset(offset).
-		Limit(pageSize).
+		Limit(limit).
 		Find(&articles).Error; err != nil {
 		return nil, 0, err
 	}
+	if err := r.ApplyReadState(ctx, userID, articles...); err != nil {
+		return nil, 0, err
+	}
 
 	return articles, total, nil
 }
 
-func (r *ArticleRepository) GetByID(ctx context.Context, articleID uint) (*models.Article, error) {
+// order applies sort for the user to an articles query, newest first breaking ties
+func (r *ArticleRepository) order(query *gorm.DB, sort ArticleSort, userID uint) *gorm.DB {
+	const newestFirst = "articles.published_at DESC, articles.id DESC"
+	if sort != SortSmart {
+		return query.Order(newestFirst)
+	}
+	// one expression: GORM drops an expression ORDER BY merged with plain columns
+	return query.Clauses(clause.OrderBy{Expression: clause.Expr{
+		SQL:  r.smartScoreSQL() + " DESC, " + newestFirst,
+		Vars: r.smartScoreVars(userID),
+	}})
+}
+
+// smartScoreVars are the parameters of smartScoreSQL for the user
+func (r *ArticleRepository) smartScoreVars(userID uint) []any {
+	return []any{r.now().UTC(), userID, userID, userID}
+}
+
+// smartScoreSQL is the SortSmart score of an article row for a user; it takes the
+// parameters from smartScoreVars
+func (r *ArticleRepository) smartScoreSQL() string {
+	w := r.smartWeights
+	halfLife := w.RecencyHalfLife.Hours()
+	if halfLife <= 0 {
+		halfLife = DefaultSmartSortWeights.RecencyHalfLife.Hours()
+	}
+
+	// age in hours, clamped at 0 for articles dated in the future
+	ageHours := "MAX((julianday(?) - julianday(articles.published_at)) * 24.0, 0)"
+	if r.db.Dialector.Name() == "postgres" {
+		ageHours = "GREATEST(EXTRACT(EPOCH FROM (CAST(? AS timestamptz) - articles.published_at)) / 3600.0, 0)"
+	}
+	affinity := "COALESCE((SELECT AVG(CASE WHEN fs.read THEN 1.0 ELSE 0.0 END) FROM articles fa" +
+		" LEFT JOIN user_article_states fs ON fs.article_id = fa.id AND fs.user_id = ?" +
+		" WHERE fa.feed_id = articles.feed_id AND fa.deleted_at IS NULL), 0)"
+
+	return fmt.Sprintf("(%g * %g / (%g + %s)"+
+		" + %g * (CASE WHEN "+readstate.UnreadCondition+" THEN 1 ELSE 0 END)"+
+		" + %g * %s"+
+		" + %g * (CASE WHEN "+readstate.StarredCondition+" THEN 1 ELSE 0 END))",
+		w.Recency, halfLife, halfLife, ageHours,
+		w.Unread,
+		w.Affinity, affinity,
+		w.Starred)
+}
+
+// GetByID returns an article with the user's read state
+func (r *ArticleRepository) GetByID(ctx context.Context, userID, articleID uint) (*models.Article, error) {
 	var article models.Article
 	err := r.db.WithContext(ctx).
 		Where("id = ?", articleID).
@@ -81,9 +199,26 @@ func (r *ArticleRepository) GetByID(ctx context.Context, articleID uint) (*model
 	if err != nil {
 		return nil, err
 	}
+	if err := r.ApplyReadState(ctx, userID, &article); err != nil {
+		return nil, err
+	}
 	return &article, nil
 }
 
+// ApplyReadState sets Read, Starred and Tags on the articles to the user's own state
+func (r *ArticleRepository) ApplyReadState(ctx context.Context, userID uint, articles ...*models.Article) error {
+	if err := readstate.NewStore(r.db).ApplyReadState(ctx, userID, articles); err != nil {
+		return err
+	}
+	return articletags.NewStore(r.db).Apply(ctx, userID, articles)
+}
+
+// ApplySummaries loads the summary variants of the articles and shows the one the reader
+// prefers in their summary fields
+func (r *ArticleRepository) ApplySummaries(ctx context.Context, pref summaries.Preference, articles ...*models.Article) error {
+	return summaries.NewStore(r.db).Apply(ctx, pref, articles)
+}
+
 func (r *ArticleRepository) GetFeedID(ctx context.Context, articleID uint) (uint, error) {
 	var feedID uint
 	err := r.db.WithContext(ctx).
@@ -94,4 +229,167 @@ func (r *ArticleRepository) GetFeedID(ctx context.Context, articleID uint) (uint
 	return feedID, err
 }
 
+// articleDetailRow is a row of the Detail query
+type articleDetailRow struct {
+	models.Article
+	UserRead        bool
+	UserStarred     bool
+	PrevID          *uint
+	NextID          *uint
+	FeedTitle       string
+	FeedURL         string
+	FeedCustomTitle *string
+	Subscribed      bool
+}
+
+// ArticleDetail is an article as a user opens it
+type ArticleDetail struct {
+	// Article carries the user's read state and tags when Subscribed
+	Article    *models.Article
+	Navigation *models.ArticleNavigation
+	// Subscribed tells whether the user subscribes to the article's feed
+	Subscribed bool
+}
+
+// Detail returns an article with the user's read state, its feed titled as the user
+// named it, and the articles listed right before and after it in its feed in the given
+// order, all in one query; only the user's tags are loaded apart, and only when the user
+// subscribes to the feed. It returns gorm.ErrRecordNotFound for a missing or deleted
+// article.
+func (r *ArticleRepository) Detail(ctx context.Context, userID, articleID uint, sort ArticleSort) (*ArticleDetail, error) {
+	order := "articles.published_at DESC, articles.id DESC"
+	var orderVars []any
+	if sort == SortSmart {
+		order = r.smartScoreSQL() + " DESC, " + order
+		orderVars = r.smartScoreVars(userID)
+	}
 
+	vars := append([]any{articleID}, orderVars...)
+	vars = append(vars, userID, userID, articleID)
+	var row articleDetailRow
+	result := r.db.WithContext(ctx).Raw(`SELECT articles.*, COALESCE(uas.read, false) AS user_read,
+		COALESCE(uas.starred, false) AS user_starred, nav.prev_id, nav.next_id,
+		feeds.title AS feed_title, feeds.url AS feed_url, subscriptions.custom_title AS feed_custom_title,
+		subscriptions.user_id IS NOT NULL AS subscribed
+	FROM (
+		SELECT articles.id, LAG(articles.id) OVER w AS prev_id, LEAD(articles.id) OVER w AS next_id
+		FROM articles
+		WHERE articles.feed_id = (SELECT feed_id FROM articles WHERE id = ?) AND articles.deleted_at IS NULL
+		WINDOW w AS (ORDER BY `+order+`)
+	) nav
+	JOIN articles ON articles.id = nav.id
+	JOIN feeds ON feeds.id = articles.feed_id
+	LEFT JOIN user_article_states uas ON uas.user_id = ? AND uas.article_id = articles.id
+	LEFT JOIN subscriptions ON subscriptions.feed_id = articles.feed_id AND subscriptions.user_id = ?
+	WHERE nav.id = ?`, vars...).Scan(&row)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	article := row.Article
+	article.Read = row.UserRead
+	article.Starred = row.UserStarred
+	if row.Subscribed {
+		if err := articletags.NewStore(r.db).Apply(ctx, userID, []*models.Article{&article}); err != nil {
+			return nil, err
+		}
+	}
+	title := row.FeedTitle
+	if row.FeedCustomTitle != nil && *row.FeedCustomTitle != "" {
+		title = *row.FeedCustomTitle
+	}
+	return &ArticleDetail{
+		Article: &article,
+		Navigation: &models.ArticleNavigation{
+			Feed: models.ArticleFeedInfo{
+				ID:      article.FeedID,
+				Title:   title,
+				URL:     row.FeedURL,
+				IconURL: models.FeedIconURL(row.FeedURL),
+			},
+			PreviousArticleID: row.PrevID,
+			NextArticleID:     row.NextID,
+		},
+		Subscribed: row.Subscribed,
+	}, nil
+}
+
+// ListDeletedForUser returns trashed articles from the user's subscribed feeds that were
+// deleted at or after deletedSince, most recently deleted first, with their total count
+func (r *ArticleRepository) ListDeletedForUser(ctx context.Context, userID uint, deletedSince time.Time, offset, limit int) ([]*models.Article, int64, error) {
+	subscribedFeeds := r.db.Table("subscriptions").Select("feed_id").Where("user_id = ?", userID)
+	query := r.db.WithContext(ctx).
+		Unscoped().
+		Model(&models.Article{}).
+		Where("deleted_at IS NOT NULL AND deleted_at >= ?", deletedSince).
+		Where("feed_id IN (?)", subscribedFeeds)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	articles := make([]*models.Article, 0)
+	if err := query.Order("deleted_at DESC, id DESC").Offset(offset).Limit(limit).Find(&articles).Error; err != nil {
+		return nil, 0, err
+	}
+	return articles, total, nil
+}
+
+// FeedReadCounts is the number of live articles of a feed, and how many a user has not read
+type FeedReadCounts struct {
+	FeedID uint
+	Unread int64
+	Total  int64
+}
+
+// CountReadStateByFeeds returns the user's read counts of each given feed that has articles
+func (r *ArticleRepository) CountReadStateByFeeds(ctx context.Context, userID uint, feedIDs []uint) ([]FeedReadCounts, error) {
+	counts := make([]FeedReadCounts, 0, len(feedIDs))
+	if len(feedIDs) == 0 {
+		return counts, nil
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.Article{}).
+		Select("feed_id, SUM(CASE WHEN "+readstate.UnreadCondition+" THEN 1 ELSE 0 END) AS unread, COUNT(*) AS total", userID).
+		Where("feed_id IN ?", feedIDs).
+		Group("feed_id").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// KeepNewestUnread marks read for the user every article of the feed but the keep newest
+// ones, as an imported unread hint asks for, and returns how many were marked
+func (r *ArticleRepository) KeepNewestUnread(ctx context.Context, userID, feedID uint, keep int) (int64, error) {
+	query := r.db.WithContext(ctx).
+		Model(&models.Article{}).
+		Where("feed_id = ?", feedID).
+		Where(readstate.UnreadCondition, userID)
+	if keep > 0 {
+		newest := r.db.Model(&models.Article{}).
+			Select("id").
+			Where("feed_id = ?", feedID).
+			Order("published_at DESC, id DESC").
+			Limit(keep)
+		query = query.Where("id NOT IN (?)", newest)
+	}
+	var articleIDs []uint
+	if err := query.Order("id").Pluck("id", &articleIDs).Error; err != nil {
+		return 0, err
+	}
+
+	store := readstate.NewStore(r.db)
+	var marked int64
+	for start := 0; start < len(articleIDs); start += readstate.MaxChanges {
+		end := min(start+readstate.MaxChanges, len(articleIDs))
+		result, err := store.SetRead(ctx, userID, true, articleIDs[start:end]...)
+		if err != nil {
+			return marked, err
+		}
+		marked += int64(result.Applied)
+	}
+	return marked, nil
+}
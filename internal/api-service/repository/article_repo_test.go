@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+func setupArticleRepo(t *testing.T) (*ArticleRepository, *gorm.DB) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_fk=1", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Feed{}, &models.Article{}, &models.Subscription{},
+		&models.ArticleStateEvent{}, &models.UserArticleState{}, &models.Tag{}, &models.ArticleTag{}))
+	return NewArticleRepository(db), db
+}
+
+// markRead stores the articles as read by the user
+func markRead(t *testing.T, db *gorm.DB, userID uint, articleIDs ...uint) {
+	t.Helper()
+	for _, articleID := range articleIDs {
+		require.NoError(t, db.Create(&models.UserArticleState{UserID: userID, ArticleID: articleID, Read: true}).Error)
+	}
+}
+
+func titles(articles []*models.Article) []string {
+	out := make([]string, len(articles))
+	for i, article := range articles {
+		out[i] = article.Title
+	}
+	return out
+}
+
+func TestParseArticleSort(t *testing.T) {
+	for value, want := range map[string]ArticleSort{"": SortRecent, "recent": SortRecent, " Smart ": SortSmart} {
+		got, err := ParseArticleSort(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+	_, err := ParseArticleSort("oldest")
+	assert.Error(t, err)
+}
+
+func TestArticleRepository_SmartSort(t *testing.T) {
+	repo, db := setupArticleRepo(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+
+	feed := &models.Feed{Title: "Feed", URL: "https://example.com/feed.xml"}
+	require.NoError(t, db.Create(feed).Error)
+	for _, article := range []*models.Article{
+		{Title: "fresh read", PublishedAt: now.Add(-time.Hour)},
+		{Title: "day old unread", PublishedAt: now.Add(-24 * time.Hour)},
+		{Title: "week old starred", PublishedAt: now.Add(-7 * 24 * time.Hour)},
+		{Title: "month old read", PublishedAt: now.Add(-30 * 24 * time.Hour)},
+	} {
+		article.FeedID = feed.ID
+		article.URL = "https://example.com/" + article.Title
+		require.NoError(t, db.Create(article).Error)
+		switch article.Title {
+		case "day old unread":
+		case "week old starred":
+			require.NoError(t, db.Create(&models.UserArticleState{UserID: 1, ArticleID: article.ID, Read: true, Starred: true}).Error)
+		default:
+			markRead(t, db, 1, article.ID)
+		}
+	}
+
+	recent, total, err := repo.ListByFeedIDRange(ctx, 1, feed.ID, SortRecent, 0, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, total)
+	assert.Equal(t, []string{"fresh read", "day old unread", "week old starred", "month old read"}, titles(recent))
+	assert.True(t, recent[0].Read)
+	assert.False(t, recent[1].Read)
+	assert.True(t, recent[2].Starred)
+
+	repo.SetSmartSortWeights(SmartSortWeights{Recency: 1, Unread: 1, Starred: 0.5, RecencyHalfLife: 24 * time.Hour})
+	smart, _, err := repo.ListByFeedIDRange(ctx, 1, feed.ID, SortSmart, 0, 10)
+	require.NoError(t, err)
+	// day old unread: 0.5 + 1, fresh read: 0.96, week old starred: 0.125 + 0.5
+	assert.Equal(t, []string{"day old unread", "fresh read", "week old starred", "month old read"}, titles(smart))
+
+	page, _, err := repo.ListByFeedIDRange(ctx, 1, feed.ID, SortSmart, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fresh read", "week old starred"}, titles(page))
+
+	// another user has read and starred nothing: fresh read: 0.96 + 1, day old unread:
+	// 0.5 + 1, week old starred: 0.125 + 1
+	other, _, err := repo.ListByFeedIDRange(ctx, 2, feed.ID, SortSmart, 0, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fresh read", "day old unread", "week old starred"}, titles(other))
+	assert.False(t, other[0].Read)
+	assert.False(t, other[2].Starred)
+}
+
+func TestArticleRepository_SmartSortFeedAffinity(t *testing.T) {
+	repo, db := setupArticleRepo(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+	repo.SetSmartSortWeights(SmartSortWeights{Recency: 1, Affinity: 1, RecencyHalfLife: 24 * time.Hour})
+
+	favourite := &models.Feed{Title: "Favourite", URL: "https://example.com/favourite.xml"}
+	ignored := &models.Feed{Title: "Ignored", URL: "https://example.com/ignored.xml"}
+	require.NoError(t, db.Create(favourite).Error)
+	require.NoError(t, db.Create(ignored).Error)
+	for i, article := range []*models.Article{
+		{FeedID: favourite.ID, Title: "favourite older", PublishedAt: now.Add(-12 * time.Hour)},
+		{FeedID: favourite.ID, Title: "favourite read", PublishedAt: now.Add(-96 * time.Hour)},
+		{FeedID: favourite.ID, Title: "favourite read too", PublishedAt: now.Add(-120 * time.Hour)},
+		{FeedID: ignored.ID, Title: "ignored newer", PublishedAt: now.Add(-time.Hour)},
+		{FeedID: ignored.ID, Title: "ignored other", PublishedAt: now.Add(-96 * time.Hour)},
+	} {
+		article.URL = fmt.Sprintf("https://example.com/%d", i)
+		require.NoError(t, db.Create(article).Error)
+		if i == 1 || i == 2 {
+			markRead(t, db, 1, article.ID)
+		}
+	}
+
+	var articles []*models.Article
+	require.NoError(t, repo.order(db.Model(&models.Article{}), SortSmart, 1).Limit(2).Find(&articles).Error)
+	// favourite older: 0.67 + 2/3 affinity beats ignored newer: 0.96 + 0
+	assert.Equal(t, []string{"favourite older", "ignored newer"}, titles(articles))
+}
+
+func TestArticleRepository_ReadStateHints(t *testing.T) {
+	repo, db := setupArticleRepo(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	solo := &models.Feed{Title: "Solo", URL: "https://example.com/solo.xml"}
+	shared := &models.Feed{Title: "Shared", URL: "https://example.com/shared.xml"}
+	require.NoError(t, db.Create([]*models.Feed{solo, shared}).Error)
+	require.NoError(t, db.Create([]*models.Subscription{
+		{UserID: 1, FeedID: solo.ID}, {UserID: 1, FeedID: shared.ID}, {UserID: 2, FeedID: shared.ID},
+	}).Error)
+	for i := 0; i < 4; i++ {
+		for _, feed := range []*models.Feed{solo, shared} {
+			article := &models.Article{
+				FeedID:      feed.ID,
+				Title:       fmt.Sprintf("%s %d", feed.Title, i),
+				URL:         fmt.Sprintf("%s/%d", feed.URL, i),
+				PublishedAt: now.Add(time.Duration(i) * time.Hour),
+			}
+			require.NoError(t, db.Create(article).Error)
+			if i == 0 {
+				markRead(t, db, 1, article.ID)
+			}
+		}
+	}
+
+	counts, err := repo.CountReadStateByFeeds(ctx, 1, []uint{solo.ID, shared.ID, 99})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []FeedReadCounts{
+		{FeedID: solo.ID, Unread: 3, Total: 4},
+		{FeedID: shared.ID, Unread: 3, Total: 4},
+	}, counts)
+
+	marked, err := repo.KeepNewestUnread(ctx, 1, solo.ID, 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, marked)
+	var unread []*models.Article
+	require.NoError(t, db.Where("feed_id = ?", solo.ID).Where("NOT EXISTS (SELECT 1 FROM user_article_states uas WHERE uas.user_id = 1 AND uas.article_id = articles.id AND uas.read)").Find(&unread).Error)
+	assert.Equal(t, []string{"Solo 3"}, titles(unread))
+
+	marked, err = repo.KeepNewestUnread(ctx, 1, shared.ID, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, marked, "read state is the user's own, even in a shared feed")
+	counts, err = repo.CountReadStateByFeeds(ctx, 2, []uint{shared.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []FeedReadCounts{{FeedID: shared.ID, Unread: 4, Total: 4}}, counts, "the other subscriber still has everything unread")
+
+	marked, err = repo.KeepNewestUnread(ctx, 2, solo.ID, 0)
+	require.NoError(t, err)
+	assert.Zero(t, marked, "not subscribed")
+}
+
+func TestArticleRepository_Detail(t *testing.T) {
+	repo, db := setupArticleRepo(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+
+	feed := &models.Feed{Title: "Feed", URL: "https://example.com/feed.xml"}
+	other := &models.Feed{Title: "Other", URL: "https://other.example/rss"}
+	require.NoError(t, db.Create(feed).Error)
+	require.NoError(t, db.Create(other).Error)
+	customTitle := "My feed"
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feed.ID, CustomTitle: &customTitle}).Error)
+
+	ids := map[string]uint{}
+	for _, article := range []*models.Article{
+		{Title: "newest", FeedID: feed.ID, PublishedAt: now.Add(-time.Hour)},
+		{Title: "deleted", FeedID: feed.ID, PublishedAt: now.Add(-2 * time.Hour)},
+		{Title: "middle", FeedID: feed.ID, PublishedAt: now.Add(-3 * time.Hour)},
+		{Title: "other feed", FeedID: other.ID, PublishedAt: now.Add(-4 * time.Hour)},
+		{Title: "oldest", FeedID: feed.ID, PublishedAt: now.Add(-30 * 24 * time.Hour)},
+	} {
+		article.URL = "https://example.com/" + article.Title
+		require.NoError(t, db.Create(article).Error)
+		ids[article.Title] = article.ID
+	}
+	require.NoError(t, db.Delete(&models.Article{}, ids["deleted"]).Error)
+	markRead(t, db, 1, ids["newest"])
+	require.NoError(t, db.Create(&models.UserArticleState{UserID: 1, ArticleID: ids["middle"], Starred: true}).Error)
+	tag := &models.Tag{UserID: 1, Name: "later"}
+	require.NoError(t, db.Create(tag).Error)
+	require.NoError(t, db.Create(&models.ArticleTag{TagID: tag.ID, ArticleID: ids["middle"]}).Error)
+
+	detail, err := repo.Detail(ctx, 1, ids["middle"], SortRecent)
+	require.NoError(t, err)
+	assert.True(t, detail.Subscribed)
+	assert.Equal(t, "middle", detail.Article.Title)
+	assert.Equal(t, "https://example.com/middle", detail.Article.URL)
+	assert.False(t, detail.Article.Read)
+	assert.True(t, detail.Article.Starred)
+	assert.Equal(t, []string{"later"}, detail.Article.Tags)
+	nav := detail.Navigation
+	assert.Equal(t, models.ArticleFeedInfo{ID: feed.ID, Title: "My feed", URL: feed.URL, IconURL: "https://example.com/favicon.ico"}, nav.Feed)
+	require.NotNil(t, nav.PreviousArticleID)
+	require.NotNil(t, nav.NextArticleID)
+	assert.Equal(t, ids["newest"], *nav.PreviousArticleID, "deleted articles are skipped")
+	assert.Equal(t, ids["oldest"], *nav.NextArticleID, "other feeds are left out")
+
+	detail, err = repo.Detail(ctx, 1, ids["newest"], SortRecent)
+	require.NoError(t, err)
+	assert.True(t, detail.Article.Read)
+	assert.Nil(t, detail.Navigation.PreviousArticleID)
+	assert.Equal(t, ids["middle"], *detail.Navigation.NextArticleID)
+
+	// the unread middle article ranks above the read newest one
+	detail, err = repo.Detail(ctx, 1, ids["middle"], SortSmart)
+	require.NoError(t, err)
+	assert.Nil(t, detail.Navigation.PreviousArticleID)
+	assert.Equal(t, ids["newest"], *detail.Navigation.NextArticleID)
+
+	detail, err = repo.Detail(ctx, 2, ids["other feed"], SortRecent)
+	require.NoError(t, err)
+	assert.False(t, detail.Subscribed)
+	assert.Equal(t, "Other", detail.Navigation.Feed.Title, "without a custom title")
+	assert.Nil(t, detail.Navigation.PreviousArticleID)
+	assert.Nil(t, detail.Navigation.NextArticleID)
+
+	_, err = repo.Detail(ctx, 1, ids["deleted"], SortRecent)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = repo.Detail(ctx, 1, 9999, SortRecent)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
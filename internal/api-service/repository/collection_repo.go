@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// CollectionRepository stores the curated feed collections operators offer to users
+type CollectionRepository struct {
+	db *gorm.DB
+}
+
+func NewCollectionRepository(db *gorm.DB) *CollectionRepository {
+	return &CollectionRepository{db: db}
+}
+
+func orderedCollectionFeeds(db *gorm.DB) *gorm.DB {
+	return db.Order("position")
+}
+
+// List returns every collection with its feeds, by name
+func (r *CollectionRepository) List(ctx context.Context) ([]*models.Collection, error) {
+	collections := make([]*models.Collection, 0)
+	err := r.db.WithContext(ctx).
+		Preload("Feeds", orderedCollectionFeeds).
+		Order("name, id").
+		Find(&collections).Error
+	return collections, err
+}
+
+// ListForNewUsers returns the collections whose feeds new users are subscribed to
+func (r *CollectionRepository) ListForNewUsers(ctx context.Context) ([]*models.Collection, error) {
+	collections := make([]*models.Collection, 0)
+	err := r.db.WithContext(ctx).
+		Preload("Feeds", orderedCollectionFeeds).
+		Where("subscribe_new_users = ?", true).
+		Order("name, id").
+		Find(&collections).Error
+	return collections, err
+}
+
+// Get returns a collection with its feeds, or nil when it does not exist
+func (r *CollectionRepository) Get(ctx context.Context, id uint) (*models.Collection, error) {
+	var collection models.Collection
+	err := r.db.WithContext(ctx).Preload("Feeds", orderedCollectionFeeds).First(&collection, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// SlugTaken reports whether another collection than exceptID uses the slug
+func (r *CollectionRepository) SlugTaken(ctx context.Context, slug string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Collection{}).
+		Where("slug = ? AND id <> ?", slug, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Create stores a new collection with its feeds, numbering the feeds in their order
+func (r *CollectionRepository) Create(ctx context.Context, collection *models.Collection) error {
+	numberCollectionFeeds(collection)
+	return r.db.WithContext(ctx).Create(collection).Error
+}
+
+// Update replaces a collection's details and feeds in one transaction, returning false
+// when it does not exist
+func (r *CollectionRepository) Update(ctx context.Context, collection *models.Collection) (bool, error) {
+	numberCollectionFeeds(collection)
+	found := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Collection{ID: collection.ID}).
+			Select("slug", "name", "description", "subscribe_new_users", "updated_at").
+			Updates(collection)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		found = true
+
+		if err := tx.Where("collection_id = ?", collection.ID).Delete(&models.CollectionFeed{}).Error; err != nil {
+			return err
+		}
+		if len(collection.Feeds) == 0 {
+			return nil
+		}
+		return tx.Create(&collection.Feeds).Error
+	})
+	return found, err
+}
+
+// Delete removes a collection and its feeds, returning false when it does not exist
+func (r *CollectionRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	deleted := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("collection_id = ?", id).Delete(&models.CollectionFeed{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Collection{}, id)
+		deleted = result.RowsAffected > 0
+		return result.Error
+	})
+	return deleted, err
+}
+
+// MarkSubscribed sets Subscribed on the feeds of the collections the user follows, and
+// SubscriberCount on those the instance knows
+func (r *CollectionRepository) MarkSubscribed(ctx context.Context, userID uint, collections ...*models.Collection) error {
+	var urls []string
+	for _, collection := range collections {
+		urls = append(urls, collection.URLs()...)
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	var known []struct {
+		URL             string
+		SubscriberCount int64
+		Subscribed      bool
+	}
+	err := r.db.WithContext(ctx).Model(&models.Feed{}).
+		Select("feeds.url, feeds.subscriber_count, EXISTS (SELECT 1 FROM subscriptions WHERE subscriptions.feed_id = feeds.id AND subscriptions.user_id = ?) AS subscribed", userID).
+		Where("feeds.url IN ?", urls).
+		Scan(&known).Error
+	if err != nil {
+		return err
+	}
+
+	byURL := make(map[string]int, len(known))
+	for i, feed := range known {
+		byURL[feed.URL] = i
+	}
+	for _, collection := range collections {
+		for i := range collection.Feeds {
+			feed := &collection.Feeds[i]
+			feed.Subscribed, feed.SubscriberCount = false, 0
+			if j, ok := byURL[feed.URL]; ok {
+				feed.Subscribed, feed.SubscriberCount = known[j].Subscribed, known[j].SubscriberCount
+			}
+		}
+	}
+	return nil
+}
+
+func numberCollectionFeeds(collection *models.Collection) {
+	for i := range collection.Feeds {
+		collection.Feeds[i].CollectionID = collection.ID
+		collection.Feeds[i].Position = i
+	}
+}
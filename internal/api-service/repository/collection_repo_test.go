@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+func TestCollectionRepository(t *testing.T) {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_fk=1", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Feed{}, &models.Subscription{}, &models.Collection{}, &models.CollectionFeed{}))
+	repo := NewCollectionRepository(db)
+	ctx := context.Background()
+
+	news := &models.Collection{Slug: "news", Name: "News", SubscribeNewUsers: true, Feeds: []models.CollectionFeed{
+		{URL: "https://b.example.com"},
+		{URL: "https://a.example.com"},
+	}}
+	require.NoError(t, repo.Create(ctx, news))
+	require.NoError(t, repo.Create(ctx, &models.Collection{Slug: "art", Name: "Art", Feeds: []models.CollectionFeed{{URL: "https://c.example.com"}}}))
+
+	stored, err := repo.Get(ctx, news.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://b.example.com", "https://a.example.com"}, stored.URLs(), "feeds keep their order")
+
+	all, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "art", all[0].Slug)
+	defaults, err := repo.ListForNewUsers(ctx)
+	require.NoError(t, err)
+	require.Len(t, defaults, 1)
+	assert.Equal(t, "news", defaults[0].Slug)
+
+	taken, err := repo.SlugTaken(ctx, "news", 0)
+	require.NoError(t, err)
+	assert.True(t, taken)
+	taken, err = repo.SlugTaken(ctx, "news", news.ID)
+	require.NoError(t, err)
+	assert.False(t, taken, "a collection keeps its own slug")
+
+	// the update replaces the feeds
+	news.Name = "Daily news"
+	news.Feeds = []models.CollectionFeed{{URL: "https://a.example.com", Title: "A"}}
+	updated, err := repo.Update(ctx, news)
+	require.NoError(t, err)
+	assert.True(t, updated)
+	stored, err = repo.Get(ctx, news.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Daily news", stored.Name)
+	assert.Equal(t, []string{"https://a.example.com"}, stored.URLs())
+
+	updated, err = repo.Update(ctx, &models.Collection{ID: 999, Slug: "gone", Name: "Gone"})
+	require.NoError(t, err)
+	assert.False(t, updated)
+
+	feed := &models.Feed{Title: "A", URL: "https://a.example.com"}
+	require.NoError(t, db.Create(feed).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feed.ID}).Error)
+	// kept by a trigger in Postgres
+	require.NoError(t, db.Exec("UPDATE feeds SET subscriber_count = 4 WHERE id = ?", feed.ID).Error)
+	all, err = repo.List(ctx)
+	require.NoError(t, err)
+	require.NoError(t, repo.MarkSubscribed(ctx, 1, all...))
+	assert.False(t, all[0].Feeds[0].Subscribed)
+	assert.Zero(t, all[0].Feeds[0].SubscriberCount, "the instance does not know the feed")
+	assert.True(t, all[1].Feeds[0].Subscribed)
+	assert.EqualValues(t, 4, all[1].Feeds[0].SubscriberCount)
+	require.NoError(t, repo.MarkSubscribed(ctx, 2, all...))
+	assert.False(t, all[1].Feeds[0].Subscribed)
+	assert.EqualValues(t, 4, all[1].Feeds[0].SubscriberCount)
+
+	deleted, err := repo.Delete(ctx, news.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	stored, err = repo.Get(ctx, news.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+	var orphans int64
+	require.NoError(t, db.Model(&models.CollectionFeed{}).Where("collection_id = ?", news.ID).Count(&orphans).Error)
+	assert.Zero(t, orphans)
+}
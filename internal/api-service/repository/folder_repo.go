@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// FolderRepository stores the folders users file their subscriptions in
+type FolderRepository struct {
+	db *gorm.DB
+}
+
+func NewFolderRepository(db *gorm.DB) *FolderRepository {
+	return &FolderRepository{db: db}
+}
+
+// List returns the user's folders by name
+func (r *FolderRepository) List(ctx context.Context, userID uint) ([]*models.Folder, error) {
+	folders := make([]*models.Folder, 0)
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("name, id").
+		Find(&folders).Error
+	return folders, err
+}
+
+// Get returns a folder of the user, or nil when the user has no such folder
+func (r *FolderRepository) Get(ctx context.Context, userID, id uint) (*models.Folder, error) {
+	var folder models.Folder
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&folder).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// NameTaken reports whether another folder than exceptID under the same parent uses the name
+func (r *FolderRepository) NameTaken(ctx context.Context, userID uint, parentID *uint, name string, exceptID uint) (bool, error) {
+	var count int64
+	err := siblings(r.db.WithContext(ctx).Model(&models.Folder{}), userID, parentID).
+		Where("name = ? AND id <> ?", name, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Create stores a new folder
+func (r *FolderRepository) Create(ctx context.Context, folder *models.Folder) error {
+	return r.db.WithContext(ctx).Create(folder).Error
+}
+
+// Update renames a folder and moves it under its ParentID
+func (r *FolderRepository) Update(ctx context.Context, folder *models.Folder) error {
+	return r.db.WithContext(ctx).Model(folder).
+		Select("name", "parent_id", "updated_at").
+		Updates(folder).Error
+}
+
+// Delete removes a folder and the folders below it in one transaction. The subscriptions
+// filed in them are left unfiled. It returns false when the user has no such folder.
+func (r *FolderRepository) Delete(ctx context.Context, userID, id uint) (bool, error) {
+	folders, err := r.List(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	ids := models.FolderSubtree(folders, id)
+	if len(ids) == 0 {
+		return false, nil
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Subscription{}).
+			Where("user_id = ? AND folder_id IN ?", userID, ids).
+			Update("folder_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND id IN ?", userID, ids).Delete(&models.Folder{}).Error
+	})
+	return err == nil, err
+}
+
+// GetDigest returns the digest of a folder of the user, or nil when it has none
+func (r *FolderRepository) GetDigest(ctx context.Context, userID, folderID uint) (*models.FolderDigest, error) {
+	var digest models.FolderDigest
+	err := r.db.WithContext(ctx).Where("folder_id = ? AND user_id = ?", folderID, userID).First(&digest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &digest, nil
+}
+
+// SaveDigest creates or replaces the digest of a folder
+func (r *FolderRepository) SaveDigest(ctx context.Context, digest *models.FolderDigest) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "folder_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"frequency", "hour", "weekday", "timezone", "next_run_at", "updated_at"}),
+	}).Create(digest).Error
+}
+
+// DeleteDigest stops the digest of a folder, returning false when it had none
+func (r *FolderRepository) DeleteDigest(ctx context.Context, userID, folderID uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("folder_id = ? AND user_id = ?", folderID, userID).Delete(&models.FolderDigest{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetShared returns the folder shared under the slug, or nil when no folder is
+func (r *FolderRepository) GetShared(ctx context.Context, slug string) (*models.Folder, error) {
+	var folder models.Folder
+	err := r.db.WithContext(ctx).Where("share_slug = ?", slug).First(&folder).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// ShareSlugTaken reports whether another folder than exceptID is shared under the slug
+func (r *FolderRepository) ShareSlugTaken(ctx context.Context, slug string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Folder{}).
+		Where("share_slug = ? AND id <> ?", slug, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// SetShareSlug shares a folder under the slug, or makes it private again when slug is nil
+func (r *FolderRepository) SetShareSlug(ctx context.Context, folder *models.Folder, slug *string) error {
+	err := r.db.WithContext(ctx).Model(folder).
+		Where("user_id = ?", folder.UserID).
+		Update("share_slug", slug).Error
+	if err != nil {
+		return err
+	}
+	folder.ShareSlug = slug
+	return nil
+}
+
+// SharedArticles returns the most recent articles of the feeds filed in a shared folder
+// and the folders below it, newest first, with excerpts of their descriptions
+func (r *FolderRepository) SharedArticles(ctx context.Context, folder *models.Folder, limit int) ([]models.SharedArticle, error) {
+	folders, err := r.List(ctx, folder.UserID)
+	if err != nil {
+		return nil, err
+	}
+	ids := models.FolderSubtree(folders, folder.ID)
+	articles := make([]models.SharedArticle, 0)
+	if len(ids) == 0 {
+		return articles, nil
+	}
+
+	err = r.db.WithContext(ctx).Model(&models.Article{}).
+		Select("articles.title, articles.url, articles.description AS excerpt, COALESCE(subscriptions.custom_title, feeds.title) AS feed_title, articles.published_at").
+		Joins("JOIN subscriptions ON subscriptions.feed_id = articles.feed_id AND subscriptions.user_id = ?", folder.UserID).
+		Joins("JOIN feeds ON feeds.id = articles.feed_id").
+		Where("subscriptions.folder_id IN ?", ids).
+		Order("articles.published_at DESC, articles.id DESC").
+		Limit(limit).
+		Scan(&articles).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range articles {
+		articles[i].Excerpt = models.SharedExcerpt(articles[i].Excerpt)
+	}
+	return articles, nil
+}
+
+// EnsurePath returns the folder at path, names from the top level down, creating the
+// folders missing along it
+func (r *FolderRepository) EnsurePath(ctx context.Context, userID uint, path []string) (uint, error) {
+	var parentID *uint
+	for _, name := range path {
+		var folder models.Folder
+		err := siblings(r.db.WithContext(ctx), userID, parentID).
+			Where("name = ?", name).
+			First(&folder).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			folder = models.Folder{UserID: userID, ParentID: parentID, Name: name}
+			err = r.Create(ctx, &folder)
+		}
+		if err != nil {
+			return 0, err
+		}
+		parentID = &folder.ID
+	}
+	if parentID == nil {
+		return 0, nil
+	}
+	return *parentID, nil
+}
+
+// siblings scopes a query to the user's folders directly under parentID
+func siblings(db *gorm.DB, userID uint, parentID *uint) *gorm.DB {
+	db = db.Where("user_id = ?", userID)
+	if parentID == nil {
+		return db.Where("parent_id IS NULL")
+	}
+	return db.Where("parent_id = ?", *parentID)
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+func TestFolderRepository_EnsurePathAndDelete(t *testing.T) {
+	subscriptionRepo, db, feeds := setupSubscriptionRepo(t)
+	repo := NewFolderRepository(db)
+	ctx := context.Background()
+
+	goID, err := repo.EnsurePath(ctx, 1, []string{"Tech", "Go"})
+	require.NoError(t, err)
+	again, err := repo.EnsurePath(ctx, 1, []string{"Tech", "Go"})
+	require.NoError(t, err)
+	assert.Equal(t, goID, again, "existing folders are reused")
+	techID, err := repo.EnsurePath(ctx, 1, []string{"Tech"})
+	require.NoError(t, err)
+	otherUser, err := repo.EnsurePath(ctx, 2, []string{"Tech"})
+	require.NoError(t, err)
+	assert.NotEqual(t, techID, otherUser)
+
+	taken, err := repo.NameTaken(ctx, 1, &techID, "Go", 0)
+	require.NoError(t, err)
+	assert.True(t, taken)
+	taken, err = repo.NameTaken(ctx, 1, nil, "Go", 0)
+	require.NoError(t, err)
+	assert.False(t, taken, "names are unique per parent only")
+
+	require.NoError(t, db.Create([]models.Subscription{
+		{UserID: 1, FeedID: feeds[0].ID},
+		{UserID: 1, FeedID: feeds[1].ID},
+	}).Error)
+	require.NoError(t, subscriptionRepo.Update(ctx, 1, feeds[0].ID, models.SubscriptionUpdate{FolderID: &goID}))
+
+	userFeeds, err := subscriptionRepo.ListUserFeeds(ctx, 1)
+	require.NoError(t, err)
+	paths := make(map[uint][]string)
+	for _, feed := range userFeeds {
+		paths[feed.ID] = feed.FolderPath
+	}
+	assert.Equal(t, []string{"Tech", "Go"}, paths[feeds[0].ID])
+	assert.Nil(t, paths[feeds[1].ID])
+
+	deleted, err := repo.Delete(ctx, 2, techID)
+	require.NoError(t, err)
+	assert.False(t, deleted, "users only delete their own folders")
+
+	deleted, err = repo.Delete(ctx, 1, techID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	folders, err := repo.List(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, folders, "the folders below go too")
+
+	sub, err := subscriptionRepo.GetWithFeed(ctx, 1, feeds[0].ID)
+	require.NoError(t, err)
+	assert.Nil(t, sub.FolderID, "subscriptions are kept, unfiled")
+}
+
+func TestFolderRepository_Sharing(t *testing.T) {
+	_, db, feeds := setupSubscriptionRepo(t)
+	require.NoError(t, db.AutoMigrate(&models.Article{}))
+	repo := NewFolderRepository(db)
+	ctx := context.Background()
+
+	newsID, err := repo.EnsurePath(ctx, 1, []string{"News"})
+	require.NoError(t, err)
+	scienceID, err := repo.EnsurePath(ctx, 1, []string{"News", "Science"})
+	require.NoError(t, err)
+	otherID, err := repo.EnsurePath(ctx, 2, []string{"News"})
+	require.NoError(t, err)
+
+	// feed A is filed below News, B outside it; user 2 files C in their own News
+	custom := "My B"
+	require.NoError(t, db.Create([]models.Subscription{
+		{UserID: 1, FeedID: feeds[0].ID, FolderID: &scienceID},
+		{UserID: 1, FeedID: feeds[1].ID, CustomTitle: &custom},
+		{UserID: 2, FeedID: feeds[2].ID, FolderID: &otherID},
+	}).Error)
+	published := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	articles := []*models.Article{
+		{FeedID: feeds[0].ID, Title: "Older", URL: "https://a.example.com/1", Description: "Short\n  and   plain", Content: "<p>secret</p>", PublishedAt: published},
+		{FeedID: feeds[0].ID, Title: "Newer", URL: "https://a.example.com/2", Description: strings.Repeat("word ", 100), PublishedAt: published.Add(time.Hour)},
+		{FeedID: feeds[0].ID, Title: "Trashed", URL: "https://a.example.com/3", PublishedAt: published.Add(2 * time.Hour)},
+		{FeedID: feeds[1].ID, Title: "Unfiled", URL: "https://b.example.com/1", PublishedAt: published},
+		{FeedID: feeds[2].ID, Title: "Someone else's", URL: "https://c.example.com/1", PublishedAt: published},
+	}
+	require.NoError(t, db.Create(articles).Error)
+	require.NoError(t, db.Delete(articles[2]).Error)
+
+	news, err := repo.Get(ctx, 1, newsID)
+	require.NoError(t, err)
+	slug := "news-picks"
+	require.NoError(t, repo.SetShareSlug(ctx, news, &slug))
+
+	taken, err := repo.ShareSlugTaken(ctx, slug, newsID)
+	require.NoError(t, err)
+	assert.False(t, taken, "a folder keeps its own slug")
+	taken, err = repo.ShareSlugTaken(ctx, slug, otherID)
+	require.NoError(t, err)
+	assert.True(t, taken)
+
+	shared, err := repo.GetShared(ctx, slug)
+	require.NoError(t, err)
+	require.NotNil(t, shared)
+	assert.Equal(t, newsID, shared.ID)
+
+	page, err := repo.SharedArticles(ctx, shared, 10)
+	require.NoError(t, err)
+	require.Len(t, page, 2, "only the feeds filed in the folder and below it, without the trash")
+	assert.Equal(t, "Newer", page[0].Title)
+	assert.Equal(t, "A", page[0].FeedTitle)
+	assert.True(t, strings.HasSuffix(page[0].Excerpt, "word…"))
+	assert.Len(t, []rune(page[0].Excerpt), models.MaxSharedExcerptRunes)
+	assert.Equal(t, models.SharedArticle{Title: "Older", URL: "https://a.example.com/1", Excerpt: "Short and plain", FeedTitle: "A", PublishedAt: published}, page[1])
+
+	page, err = repo.SharedArticles(ctx, shared, 1)
+	require.NoError(t, err)
+	assert.Len(t, page, 1)
+
+	require.NoError(t, repo.SetShareSlug(ctx, news, nil))
+	shared, err = repo.GetShared(ctx, slug)
+	require.NoError(t, err)
+	assert.Nil(t, shared, "the folder is private again")
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+)
+
+// LLMUsageRepository reads the daily LLM usage the ai-service aggregates from its ledger
+type LLMUsageRepository struct {
+	db *gorm.DB
+}
+
+func NewLLMUsageRepository(db *gorm.DB) *LLMUsageRepository {
+	return &LLMUsageRepository{db: db}
+}
+
+// ListDaily returns the usage of the UTC days since the day of since, newest first, the
+// instance key before bring-your-own-key within a day
+func (r *LLMUsageRepository) ListDaily(ctx context.Context, since time.Time) ([]models.LLMUsageDaily, error) {
+	days := make([]models.LLMUsageDaily, 0)
+	err := r.db.WithContext(ctx).
+		Where("day >= ?", models.UsageDay(since)).
+		Order("day DESC, byok ASC, cost_usd DESC, model ASC").
+		Find(&days).Error
+	return days, err
+}
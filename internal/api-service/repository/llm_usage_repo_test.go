@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+	airepository "github.com/Fancu1/phoenix-rss/internal/ai-service/repository"
+)
+
+func TestLLMUsageRepository_ListDaily(t *testing.T) {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.LLMUsage{}, &models.LLMUsageDaily{}))
+	ctx := context.Background()
+
+	// the ai-service adds every ledger entry to its day's row
+	ledger := airepository.NewCredentialRepository(db)
+	today := models.UsageDay(time.Now())
+	userID := uint(4)
+	for _, usage := range []*models.LLMUsage{
+		{Model: "gpt-4o-mini", TotalTokens: 100, PromptTokens: 80, CompletionTokens: 20, CostUSD: 0.5, CreatedAt: today.Add(time.Hour)},
+		{Model: "gpt-4o-mini", TotalTokens: 50, PromptTokens: 40, CompletionTokens: 10, CostUSD: 0.25, CreatedAt: today.Add(2 * time.Hour)},
+		{Model: "own-model", UserID: &userID, BYOK: true, TotalTokens: 30, CostUSD: 1, CreatedAt: today.Add(3 * time.Hour)},
+		{Model: "gpt-4o-mini", TotalTokens: 10, CostUSD: 0.1, CreatedAt: today.Add(-time.Hour)},
+		{Model: "gpt-4o-mini", TotalTokens: 10, CostUSD: 0.1, CreatedAt: today.AddDate(0, 0, -10)},
+	} {
+		require.NoError(t, ledger.RecordUsage(ctx, usage))
+	}
+
+	cost, tokens, err := ledger.InstanceUsageOn(ctx, time.Now())
+	require.NoError(t, err)
+	assert.InDelta(t, 0.75, cost, 1e-9, "bring-your-own-key usage does not count")
+	assert.Equal(t, int64(150), tokens)
+
+	days, err := NewLLMUsageRepository(db).ListDaily(ctx, today.AddDate(0, 0, -1))
+	require.NoError(t, err)
+	require.Len(t, days, 3)
+	assert.True(t, days[0].Day.Equal(today))
+	assert.False(t, days[0].BYOK)
+	assert.Equal(t, int64(2), days[0].Requests)
+	assert.Equal(t, int64(120), days[0].PromptTokens)
+	assert.True(t, days[1].BYOK)
+	assert.True(t, days[2].Day.Equal(today.AddDate(0, 0, -1)))
+}
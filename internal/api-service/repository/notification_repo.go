@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+const MaxNotifications = 100
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// ListByUser returns the user's most recent notifications, newest first
+func (r *NotificationRepository) ListByUser(ctx context.Context, userID uint, unreadOnly bool, limit int) ([]*models.Notification, error) {
+	if limit <= 0 || limit > MaxNotifications {
+		limit = MaxNotifications
+	}
+
+	notifications := make([]*models.Notification, 0)
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+// ListByUserRange returns one page of the user's notifications, newest first, with the
+// number of matching notifications
+func (r *NotificationRepository) ListByUserRange(ctx context.Context, userID uint, unreadOnly bool, offset, limit int) ([]*models.Notification, int64, error) {
+	if limit <= 0 || limit > MaxNotifications {
+		limit = MaxNotifications
+	}
+
+	query := r.db.WithContext(ctx).Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	notifications := make([]*models.Notification, 0)
+	err := query.Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&notifications).Error
+	return notifications, total, err
+}
+
+// MarkRead marks a notification as read, returning false if it does not belong to the user
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID, notificationID uint) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", notificationID, userID).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", time.Now().UTC()))
+	return result.RowsAffected > 0, result.Error
+}
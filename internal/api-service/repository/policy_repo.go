@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// PolicyRepository loads the users and feeds policies are evaluated for
+type PolicyRepository struct {
+	db *gorm.DB
+}
+
+func NewPolicyRepository(db *gorm.DB) *PolicyRepository {
+	return &PolicyRepository{db: db}
+}
+
+// Feed returns the feed, or nil when it does not exist
+func (r *PolicyRepository) Feed(ctx context.Context, feedID uint) (*models.Feed, error) {
+	var feed models.Feed
+	err := r.db.WithContext(ctx).First(&feed, feedID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// CountSubscriptions counts the feeds the user subscribes to
+func (r *PolicyRepository) CountSubscriptions(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Subscription{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
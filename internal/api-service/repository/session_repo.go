@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+)
+
+// sessionTouchInterval throttles last_seen_at updates to one per session per interval
+const sessionTouchInterval = 5 * time.Minute
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// CheckSession tells whether the user's session is neither revoked nor expired, and
+// records that it was seen. A failure to record it is returned along with active = true.
+func (r *SessionRepository) CheckSession(ctx context.Context, userID uint, sessionID string) (active bool, err error) {
+	now := time.Now().UTC()
+	var session models.Session
+	err = r.db.WithContext(ctx).
+		Select("id", "last_seen_at").
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, now).
+		First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		err = r.db.WithContext(ctx).Model(&models.Session{}).
+			Where("id = ?", sessionID).
+			Update("last_seen_at", now).Error
+	}
+	return true, err
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+)
+
+func TestSessionRepository_CheckSession(t *testing.T) {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_fk=1", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Session{}))
+	repo := NewSessionRepository(db)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	revokedAt := now.Add(-time.Minute)
+	require.NoError(t, db.Create([]models.Session{
+		{ID: "stale", UserID: 1, CreatedAt: now.Add(-time.Hour), LastSeenAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},
+		{ID: "fresh", UserID: 1, CreatedAt: now.Add(-time.Hour), LastSeenAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)},
+		{ID: "revoked", UserID: 1, CreatedAt: now.Add(-time.Hour), LastSeenAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt},
+		{ID: "expired", UserID: 1, CreatedAt: now.Add(-2 * time.Hour), LastSeenAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)},
+	}).Error)
+
+	for id, want := range map[string]bool{"stale": true, "fresh": true, "revoked": false, "expired": false, "unknown": false} {
+		active, err := repo.CheckSession(ctx, 1, id)
+		require.NoError(t, err, id)
+		assert.Equal(t, want, active, id)
+	}
+
+	// another user's session does not authenticate the token
+	active, err := repo.CheckSession(ctx, 2, "fresh")
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	// last_seen_at moves only once the touch interval passed
+	var stale, fresh models.Session
+	require.NoError(t, db.First(&stale, "id = ?", "stale").Error)
+	require.NoError(t, db.First(&fresh, "id = ?", "fresh").Error)
+	assert.WithinDuration(t, now, stale.LastSeenAt, 5*time.Second)
+	assert.WithinDuration(t, now.Add(-time.Minute), fresh.LastSeenAt, time.Second)
+}
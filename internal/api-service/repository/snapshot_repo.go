@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// SnapshotRepository reads the raw feed responses the feed service keeps for debugging
+type SnapshotRepository struct {
+	db *gorm.DB
+}
+
+func NewSnapshotRepository(db *gorm.DB) *SnapshotRepository {
+	return &SnapshotRepository{db: db}
+}
+
+// ListByFeed returns the feed's snapshots, newest first, without their bodies
+func (r *SnapshotRepository) ListByFeed(ctx context.Context, feedID uint) ([]*models.FeedSnapshot, error) {
+	snapshots := make([]*models.FeedSnapshot, 0)
+	err := r.db.WithContext(ctx).
+		Omit("body").
+		Where("feed_id = ?", feedID).
+		Order("fetched_at DESC, id DESC").
+		Find(&snapshots).Error
+	return snapshots, err
+}
+
+// Get returns one of the feed's snapshots, or nil when it does not exist
+func (r *SnapshotRepository) Get(ctx context.Context, feedID, snapshotID uint) (*models.FeedSnapshot, error) {
+	var snapshot models.FeedSnapshot
+	err := r.db.WithContext(ctx).Where("feed_id = ? AND id = ?", feedID, snapshotID).First(&snapshot).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
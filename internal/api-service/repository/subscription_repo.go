@@ -37,19 +37,42 @@ func (r *SubscriptionRepository) ListUserFeeds(ctx context.Context, userID uint)
 
 	result := make([]*models.UserFeed, len(subscriptions))
 	for i, sub := range subscriptions {
-		result[i] = &models.UserFeed{
-			Feed:        sub.Feed,
-			CustomTitle: sub.CustomTitle,
-		}
+		result[i] = sub.UserFeed()
+	}
+	if err := r.fillFolderPaths(ctx, userID, result); err != nil {
+		return nil, err
 	}
 	return result, nil
 }
 
-func (r *SubscriptionRepository) UpdateCustomTitle(ctx context.Context, userID, feedID uint, title *string) error {
+// fillFolderPaths sets the FolderPath of the feeds filed in a folder
+func (r *SubscriptionRepository) fillFolderPaths(ctx context.Context, userID uint, feeds []*models.UserFeed) error {
+	filed := false
+	for _, feed := range feeds {
+		filed = filed || feed.FolderID != nil
+	}
+	if !filed {
+		return nil
+	}
+
+	var folders []*models.Folder
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&folders).Error; err != nil {
+		return err
+	}
+	paths := models.FolderPaths(folders)
+	for _, feed := range feeds {
+		if feed.FolderID != nil {
+			feed.FolderPath = paths[*feed.FolderID]
+		}
+	}
+	return nil
+}
+
+func (r *SubscriptionRepository) Update(ctx context.Context, userID, feedID uint, update models.SubscriptionUpdate) error {
 	return r.db.WithContext(ctx).
 		Model(&models.Subscription{}).
 		Where("user_id = ? AND feed_id = ?", userID, feedID).
-		Update("custom_title", title).Error
+		Updates(update.Columns()).Error
 }
 
 func (r *SubscriptionRepository) Delete(ctx context.Context, userID, feedID uint) error {
@@ -70,4 +93,60 @@ func (r *SubscriptionRepository) GetWithFeed(ctx context.Context, userID, feedID
 	return &sub, nil
 }
 
+// DeleteMany unsubscribes the user from the given feeds in one transaction and returns
+// the feeds the user was subscribed to
+func (r *SubscriptionRepository) DeleteMany(ctx context.Context, userID uint, feedIDs []uint) ([]uint, error) {
+	var deleted []uint
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Subscription{}).
+			Where("user_id = ? AND feed_id IN ?", userID, feedIDs).
+			Order("feed_id").
+			Pluck("feed_id", &deleted).Error; err != nil {
+			return err
+		}
+		if len(deleted) == 0 {
+			return nil
+		}
+		return tx.Where("user_id = ? AND feed_id IN ?", userID, deleted).
+			Delete(&models.Subscription{}).Error
+	})
+	return deleted, err
+}
+
+// ListCleanupSuggestions returns the user's subscriptions that delivered at least
+// minDelivered articles, none of which were read, as of the last engagement run
+func (r *SubscriptionRepository) ListCleanupSuggestions(ctx context.Context, userID uint, minDelivered int64) ([]models.CleanupSuggestion, error) {
+	var engagement []models.SubscriptionEngagement
+	err := r.db.WithContext(ctx).
+		Joins("JOIN subscriptions ON subscriptions.user_id = subscription_engagement.user_id AND subscriptions.feed_id = subscription_engagement.feed_id").
+		Where("subscription_engagement.user_id = ? AND subscription_engagement.read_count = 0 AND subscription_engagement.delivered >= ?", userID, minDelivered).
+		Order("subscription_engagement.delivered DESC, subscription_engagement.feed_id").
+		Find(&engagement).Error
+	if err != nil || len(engagement) == 0 {
+		return nil, err
+	}
+
+	feedIDs := make([]uint, len(engagement))
+	for i, e := range engagement {
+		feedIDs[i] = e.FeedID
+	}
+	var subscriptions []models.Subscription
+	if err := r.db.WithContext(ctx).
+		Preload("Feed").
+		Where("user_id = ? AND feed_id IN ?", userID, feedIDs).
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	feeds := make(map[uint]*models.UserFeed, len(subscriptions))
+	for i := range subscriptions {
+		feeds[subscriptions[i].FeedID] = subscriptions[i].UserFeed()
+	}
 
+	suggestions := make([]models.CleanupSuggestion, 0, len(engagement))
+	for _, e := range engagement {
+		if feed, ok := feeds[e.FeedID]; ok {
+			suggestions = append(suggestions, models.CleanupSuggestion{Feed: feed, SubscriptionEngagement: e})
+		}
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+func setupSubscriptionRepo(t *testing.T) (*SubscriptionRepository, *gorm.DB, []*models.Feed) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_fk=1", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Feed{}, &models.Subscription{}, &models.SubscriptionEngagement{}, &models.Folder{}))
+
+	feeds := []*models.Feed{
+		{Title: "A", URL: "https://a.example.com"},
+		{Title: "B", URL: "https://b.example.com"},
+		{Title: "C", URL: "https://c.example.com"},
+	}
+	require.NoError(t, db.Create(feeds).Error)
+	return NewSubscriptionRepository(db), db, feeds
+}
+
+func TestSubscriptionRepository_DeleteMany(t *testing.T) {
+	repo, db, feeds := setupSubscriptionRepo(t)
+	ctx := context.Background()
+	require.NoError(t, db.Create([]models.Subscription{
+		{UserID: 1, FeedID: feeds[0].ID},
+		{UserID: 1, FeedID: feeds[1].ID},
+		{UserID: 2, FeedID: feeds[2].ID},
+	}).Error)
+
+	deleted, err := repo.DeleteMany(ctx, 1, []uint{feeds[1].ID, feeds[0].ID, feeds[2].ID, 999})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{feeds[0].ID, feeds[1].ID}, deleted)
+
+	var remaining []models.Subscription
+	require.NoError(t, db.Find(&remaining).Error)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, uint(2), remaining[0].UserID)
+
+	deleted, err = repo.DeleteMany(ctx, 1, []uint{feeds[0].ID})
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+}
+
+func TestSubscriptionRepository_ListCleanupSuggestions(t *testing.T) {
+	repo, db, feeds := setupSubscriptionRepo(t)
+	ctx := context.Background()
+	require.NoError(t, db.Create([]models.Subscription{
+		{UserID: 1, FeedID: feeds[0].ID},
+		{UserID: 1, FeedID: feeds[1].ID},
+		{UserID: 1, FeedID: feeds[2].ID},
+	}).Error)
+
+	computed := time.Date(2026, 6, 1, 3, 30, 0, 0, time.UTC)
+	require.NoError(t, db.Create([]models.SubscriptionEngagement{
+		{UserID: 1, FeedID: feeds[0].ID, Delivered: 12, ReadCount: 0, ComputedAt: computed},
+		{UserID: 1, FeedID: feeds[1].ID, Delivered: 40, ReadCount: 0, ComputedAt: computed},
+		// read now and then
+		{UserID: 1, FeedID: feeds[2].ID, Delivered: 40, ReadCount: 1, ComputedAt: computed},
+		// another user's unread feed
+		{UserID: 2, FeedID: feeds[2].ID, Delivered: 40, ReadCount: 0, ComputedAt: computed},
+	}).Error)
+
+	suggestions, err := repo.ListCleanupSuggestions(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, "B", suggestions[0].Feed.Title)
+	assert.Equal(t, int64(40), suggestions[0].Delivered)
+	assert.Equal(t, "A", suggestions[1].Feed.Title)
+
+	// too few deliveries to tell
+	suggestions, err = repo.ListCleanupSuggestions(ctx, 1, 20)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+
+	// engagement of a subscription removed since the last run is ignored
+	_, err = repo.DeleteMany(ctx, 1, []uint{feeds[1].ID})
+	require.NoError(t, err)
+	suggestions, err = repo.ListCleanupSuggestions(ctx, 1, 20)
+	require.NoError(t, err)
+	assert.Empty(t, suggestions)
+}
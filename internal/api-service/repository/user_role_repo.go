@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+)
+
+type UserRoleRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRoleRepository(db *gorm.DB) *UserRoleRepository {
+	return &UserRoleRepository{db: db}
+}
+
+// UserRole returns the stored role of the user, or an empty role when there is no such
+// user
+func (r *UserRoleRepository) UserRole(ctx context.Context, userID uint) (string, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Select("role").First(&user, userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return user.Role, err
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+)
+
+func TestUserRoleRepository_UserRole(t *testing.T) {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_fk=1", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}))
+	repo := NewUserRoleRepository(db)
+	ctx := context.Background()
+
+	require.NoError(t, db.Create([]models.User{
+		{ID: 1, Username: "reader", PasswordHash: "x", Role: models.RoleUser},
+		{ID: 2, Username: "operator", PasswordHash: "x", Role: models.RoleAdmin},
+	}).Error)
+
+	for userID, want := range map[uint]string{1: models.RoleUser, 2: models.RoleAdmin, 3: ""} {
+		role, err := repo.UserRole(ctx, userID)
+		require.NoError(t, err, userID)
+		assert.Equal(t, want, role, userID)
+	}
+}
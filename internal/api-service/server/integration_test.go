@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -21,8 +22,9 @@ const (
 )
 
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	User         struct {
 		ID       uint   `json:"id"`
 		Username string `json:"username"`
 	} `json:"user"`
@@ -344,6 +346,281 @@ func TestFeedManagementE2E(t *testing.T) {
 	})
 }
 
+func TestProfileManagement(t *testing.T) {
+	_ = Ctx(t)
+
+	type profile struct {
+		Username    string  `json:"username"`
+		Email       *string `json:"email"`
+		DisplayName string  `json:"display_name"`
+	}
+	token := registerUser(t, "profile_user", TestPassword)
+	otherToken := registerUser(t, "profile_other", TestPassword)
+
+	t.Run("Update and read the profile", func(t *testing.T) {
+		resp := makeAuthenticatedRequest(t, http.MethodPatch, app.Server.URL+"/api/v1/users/me",
+			`{"email": " Profile@Example.com ", "display_name": "Profile User"}`, token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		resp = makeAuthenticatedRequest(t, http.MethodGet, app.Server.URL+"/api/v1/users/me", "", token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var got profile
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		require.Equal(t, "profile_user", got.Username)
+		require.NotNil(t, got.Email)
+		require.Equal(t, "profile@example.com", *got.Email)
+		require.Equal(t, "Profile User", got.DisplayName)
+	})
+
+	t.Run("Email used by another user", func(t *testing.T) {
+		resp := makeAuthenticatedRequest(t, http.MethodPatch, app.Server.URL+"/api/v1/users/me",
+			`{"email": "PROFILE@example.com"}`, otherToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("Rename", func(t *testing.T) {
+		resp := makeAuthenticatedRequest(t, http.MethodPatch, app.Server.URL+"/api/v1/users/me",
+			`{"username": "profile_user"}`, otherToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusConflict, resp.StatusCode, "username taken")
+
+		resp = makeAuthenticatedRequest(t, http.MethodPatch, app.Server.URL+"/api/v1/users/me",
+			`{"username": "x"}`, otherToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, "username too short")
+
+		resp = makeAuthenticatedRequest(t, http.MethodPatch, app.Server.URL+"/api/v1/users/me",
+			`{"username": " profile_renamed "}`, otherToken)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var got profile
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		require.Equal(t, "profile_renamed", got.Username)
+
+		loginUser(t, "profile_renamed", TestPassword)
+	})
+
+	t.Run("Change password", func(t *testing.T) {
+		resp := makeAuthenticatedRequest(t, http.MethodPut, app.Server.URL+"/api/v1/users/me/password",
+			`{"current_password": "wrong-password", "new_password": "new-password"}`, token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = makeAuthenticatedRequest(t, http.MethodPut, app.Server.URL+"/api/v1/users/me/password",
+			fmt.Sprintf(`{"current_password": "%s", "new_password": "new-password"}`, TestPassword), token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		loginUser(t, "profile_user", "new-password")
+	})
+}
+
+func TestPreferences(t *testing.T) {
+	_ = Ctx(t)
+
+	type preferences struct {
+		DefaultSort     string `json:"default_sort"`
+		DefaultView     string `json:"default_view"`
+		ItemsPerPage    int    `json:"items_per_page"`
+		SummaryLanguage string `json:"summary_language"`
+	}
+	token := registerUser(t, "preferences_user", TestPassword)
+	read := func(resp *http.Response) preferences {
+		t.Helper()
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var got preferences
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		return got
+	}
+
+	got := read(makeAuthenticatedRequest(t, http.MethodGet, app.Server.URL+"/api/v1/users/me/preferences", "", token))
+	require.Equal(t, preferences{DefaultSort: "recent", DefaultView: "all", ItemsPerPage: 50}, got)
+
+	got = read(makeAuthenticatedRequest(t, http.MethodPatch, app.Server.URL+"/api/v1/users/me/preferences",
+		`{"default_view": "Unread", "items_per_page": 100, "summary_language": "EN"}`, token))
+	require.Equal(t, preferences{DefaultSort: "recent", DefaultView: "unread", ItemsPerPage: 100, SummaryLanguage: "en"}, got)
+
+	for _, body := range []string{`{"default_view": "later"}`, `{"items_per_page": 5}`, `{"items_per_page": 4294967346}`, `{"summary_language": "en us"}`} {
+		resp := makeAuthenticatedRequest(t, http.MethodPatch, app.Server.URL+"/api/v1/users/me/preferences", body, token)
+		resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+	}
+
+	got = read(makeAuthenticatedRequest(t, http.MethodGet, app.Server.URL+"/api/v1/users/me/preferences", "", token))
+	require.Equal(t, 100, got.ItemsPerPage, "refused updates change nothing")
+}
+
+func TestAdminRole(t *testing.T) {
+	_ = Ctx(t)
+
+	adminToken := registerUser(t, "admin_user", TestPassword)
+	readerToken := registerUser(t, "reader_user", TestPassword)
+	status := func(method, path, body, token string) int {
+		t.Helper()
+		resp := makeAuthenticatedRequest(t, method, app.Server.URL+path, body, token)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	require.Equal(t, http.StatusForbidden, status(http.MethodGet, "/api/v1/admin/users", "", adminToken), "users start without the admin role")
+	require.NoError(t, app.DB.Exec("UPDATE users SET role = 'admin' WHERE username = 'admin_user'").Error)
+
+	resp := makeAuthenticatedRequest(t, http.MethodGet, app.Server.URL+"/api/v1/admin/users", "", adminToken)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, "the role applies to the token already issued")
+	var page struct {
+		Items []struct {
+			ID       uint   `json:"id"`
+			Username string `json:"username"`
+			Role     string `json:"role"`
+		} `json:"items"`
+		Total int64 `json:"total"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	require.Equal(t, int64(2), page.Total)
+	require.Len(t, page.Items, 2)
+	require.Equal(t, "admin", page.Items[0].Role)
+	readerID := page.Items[1].ID
+
+	rolePath := fmt.Sprintf("/api/v1/admin/users/%d/role", readerID)
+	require.Equal(t, http.StatusForbidden, status(http.MethodPatch, rolePath, `{"role": "admin"}`, readerToken))
+	require.Equal(t, http.StatusBadRequest, status(http.MethodPatch, rolePath, `{"role": "owner"}`, adminToken))
+	require.Equal(t, http.StatusOK, status(http.MethodPatch, rolePath, `{"role": "admin"}`, adminToken))
+	require.Equal(t, http.StatusOK, status(http.MethodGet, "/api/v1/admin/users", "", readerToken))
+	require.Equal(t, http.StatusNotFound, status(http.MethodPatch, "/api/v1/admin/users/999999/role", `{"role": "user"}`, adminToken))
+	require.Equal(t, http.StatusNotFound, status(http.MethodPost, "/api/v1/admin/feeds/999999/fetch", "", adminToken))
+}
+
+// refreshTokens exchanges a refresh token, returning the response status and, on
+// success, the new tokens
+func refreshTokens(t *testing.T, refreshToken string) (int, AuthResponse) {
+	t.Helper()
+
+	reqBody := fmt.Sprintf(`{"refresh_token": "%s"}`, refreshToken)
+	resp, err := http.Post(app.Server.URL+"/api/v1/users/refresh", "application/json", bytes.NewBufferString(reqBody))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var authResp AuthResponse
+	if resp.StatusCode == http.StatusOK {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&authResp))
+	}
+	return resp.StatusCode, authResp
+}
+
+func TestTokenRefresh(t *testing.T) {
+	_ = Ctx(t)
+
+	registerUser(t, "refresh_user", TestPassword)
+	login := func() AuthResponse {
+		reqBody := fmt.Sprintf(`{"username": "refresh_user", "password": "%s"}`, TestPassword)
+		resp, err := http.Post(app.Server.URL+"/api/v1/users/login", "application/json", bytes.NewBufferString(reqBody))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var authResp AuthResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&authResp))
+		require.NotEmpty(t, authResp.RefreshToken)
+		return authResp
+	}
+
+	t.Run("Refresh rotates the refresh token", func(t *testing.T) {
+		first := login()
+
+		status, refreshed := refreshTokens(t, first.RefreshToken)
+		require.Equal(t, http.StatusOK, status)
+		require.NotEmpty(t, refreshed.Token)
+		require.NotEqual(t, first.RefreshToken, refreshed.RefreshToken)
+		require.Equal(t, "refresh_user", refreshed.User.Username)
+
+		resp := makeAuthenticatedRequest(t, http.MethodGet, app.Server.URL+"/api/v1/users/me", "", refreshed.Token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// replaying the exchanged token signs the session out
+		status, _ = refreshTokens(t, first.RefreshToken)
+		require.Equal(t, http.StatusUnauthorized, status)
+		status, _ = refreshTokens(t, refreshed.RefreshToken)
+		require.Equal(t, http.StatusUnauthorized, status)
+
+		resp = makeAuthenticatedRequest(t, http.MethodGet, app.Server.URL+"/api/v1/users/me", "", refreshed.Token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Logout ends the session", func(t *testing.T) {
+		session := login()
+
+		resp := makeAuthenticatedRequest(t, http.MethodPost, app.Server.URL+"/api/v1/users/logout", "", session.Token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+		resp = makeAuthenticatedRequest(t, http.MethodGet, app.Server.URL+"/api/v1/users/me", "", session.Token)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		status, _ := refreshTokens(t, session.RefreshToken)
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("Unknown refresh token", func(t *testing.T) {
+		status, _ := refreshTokens(t, "not-a-refresh-token")
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+}
+
+func TestPasswordReset(t *testing.T) {
+	_ = Ctx(t)
+
+	token := registerUser(t, "reset_user", TestPassword)
+	resp := makeAuthenticatedRequest(t, http.MethodPatch, app.Server.URL+"/api/v1/users/me", `{"email": "reset@example.com"}`, token)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	postJSON := func(path, body string) int {
+		resp, err := http.Post(app.Server.URL+path, "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// unknown addresses get the same answer and no email
+	require.Equal(t, http.StatusAccepted, postJSON("/api/v1/users/password-reset", `{"email": "nobody@example.com"}`))
+	require.Equal(t, http.StatusAccepted, postJSON("/api/v1/users/password-reset", `{"email": "Reset@Example.com"}`))
+
+	var resetToken string
+	require.Eventually(t, func() bool {
+		sent := mail.sentTo("reset@example.com")
+		if len(sent) == 0 {
+			return false
+		}
+		_, link, found := strings.Cut(sent[0].Body, "https://phoenix.example.com/reset-password?token=")
+		if found {
+			resetToken = strings.Fields(link)[0]
+		}
+		return true
+	}, 5*time.Second, 20*time.Millisecond)
+	require.NotEmpty(t, resetToken, "email links to the reset page")
+	require.Empty(t, mail.sentTo("nobody@example.com"))
+
+	require.Equal(t, http.StatusBadRequest, postJSON("/api/v1/users/password-reset/confirm", `{"token": "wrong", "new_password": "reset-password"}`))
+	require.Equal(t, http.StatusOK, postJSON("/api/v1/users/password-reset/confirm",
+		fmt.Sprintf(`{"token": "%s", "new_password": "reset-password"}`, resetToken)))
+	// the token works once
+	require.Equal(t, http.StatusBadRequest, postJSON("/api/v1/users/password-reset/confirm",
+		fmt.Sprintf(`{"token": "%s", "new_password": "another-password"}`, resetToken)))
+
+	// sessions opened with the old password are signed out
+	resp = makeAuthenticatedRequest(t, http.MethodGet, app.Server.URL+"/api/v1/users/me", "", token)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	loginUser(t, "reset_user", "reset-password")
+}
+
 func TestUserIsolation(t *testing.T) {
 	_ = Ctx(t)
 
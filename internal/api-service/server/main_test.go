@@ -6,9 +6,9 @@ import (
 	"io/fs"
 	"log"
 	"log/slog"
-	"net"
 	"net/http/httptest"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -28,7 +28,10 @@ import (
 	"github.com/Fancu1/phoenix-rss/internal/user-service/handler"
 	userModels "github.com/Fancu1/phoenix-rss/internal/user-service/models"
 	userRepo "github.com/Fancu1/phoenix-rss/internal/user-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/dbtx"
 	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/mailer"
+	"github.com/Fancu1/phoenix-rss/pkg/secrets"
 	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
 	feedpb "github.com/Fancu1/phoenix-rss/protos/gen/go/feed"
 	userpb "github.com/Fancu1/phoenix-rss/protos/gen/go/user"
@@ -46,7 +49,38 @@ func (m *MockArticleEventProducer) Close() error {
 	return nil
 }
 
-var app *TestApp
+// testMailer keeps the messages sent, such as password reset emails
+type testMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (m *testMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// sentTo returns the messages sent to the address so far
+func (m *testMailer) sentTo(address string) []mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sent []mailer.Message
+	for _, msg := range m.messages {
+		for _, to := range msg.To {
+			if to == address {
+				sent = append(sent, msg)
+			}
+		}
+	}
+	return sent
+}
+
+var (
+	app  *TestApp
+	mail = &testMailer{}
+)
 
 //go:embed testdata/dist/**
 var testStaticFS embed.FS
@@ -72,16 +106,9 @@ func TestMain(m *testing.M) {
 	// Run database migrations (ensure tables exist)
 	runMigrations(db)
 
-	// Start a test gRPC User Service
-	userGRPCAddr := "127.0.0.1:50052" // Use different port for testing
-	userGRPCStop := startTestUserService(db, cfg.Auth.JWTSecret, userGRPCAddr)
-
-	// Start a test gRPC Feed Service
-	feedGRPCAddr := "127.0.0.1:50054" // Use different port for testing
-	feedGRPCStop := startTestFeedService(db, feedGRPCAddr)
-
-	// Wait a moment for the gRPC servers to start
-	time.Sleep(200 * time.Millisecond)
+	// Start the test gRPC services on free ports so parallel test runs don't collide
+	userGRPCAddr, userGRPCStop := startTestUserService(db, cfg.Auth.JWTSecret)
+	feedGRPCAddr, feedGRPCStop := startTestFeedService(db)
 
 	// Create gRPC clients
 	userService, err := core.NewUserServiceClient(userGRPCAddr)
@@ -132,23 +159,34 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
-// startTestUserService starts a gRPC user service for testing
-func startTestUserService(db *gorm.DB, jwtSecret, address string) func() {
+// startTestUserService starts a gRPC user service for testing and returns its address
+func startTestUserService(db *gorm.DB, jwtSecret string) (string, func()) {
 	// Initialize user repository and service for the gRPC service
 	userRepository := userRepo.NewUserRepository(db)
-	userSvc := userCore.NewUserService(userRepository, jwtSecret)
+	userSvc := userCore.NewUserService(userRepository, userRepo.NewSessionRepository(db), jwtSecret)
+	userSvc.SetUnitOfWork(dbtx.NewUnitOfWork(db))
+	userSvc.SetPasswordResets(userRepo.NewPasswordResetRepository(db), mail, userCore.PasswordResetOptions{
+		TTL: time.Hour,
+		URL: "https://phoenix.example.com/reset-password",
+	})
+	credentialCipher, err := secrets.NewCipher("test-credentials-key")
+	if err != nil {
+		log.Fatalf("Failed to create credentials cipher: %v", err)
+	}
+	credentialSvc := userCore.NewCredentialService(userRepo.NewCredentialRepository(db), credentialCipher)
 
 	// Create gRPC handler
-	grpcHandler := handler.NewUserServiceHandler(userSvc)
+	grpcHandler := handler.NewUserServiceHandler(userSvc, credentialSvc)
+	grpcHandler.SetPreferenceService(userCore.NewPreferenceService(userRepo.NewPreferenceRepository(db)))
 
 	// Create gRPC server
 	grpcServer := grpc.NewServer()
 	userpb.RegisterUserServiceServer(grpcServer, grpcHandler)
 
 	// Start listening
-	lis, err := net.Listen("tcp", address)
+	lis, err := config.ListenEphemeral()
 	if err != nil {
-		log.Fatalf("Failed to listen on %s: %v", address, err)
+		log.Fatalf("Failed to listen: %v", err)
 	}
 
 	// Start server in background
@@ -158,14 +196,15 @@ func startTestUserService(db *gorm.DB, jwtSecret, address string) func() {
 		}
 	}()
 
-	// Return stop function
-	return func() {
+	// Return address and stop function
+	return lis.Addr().String(), func() {
 		grpcServer.GracefulStop()
 	}
 }
 
-// startTestFeedService starts a gRPC feed service for testing with in-memory Kafka
-func startTestFeedService(db *gorm.DB, address string) func() {
+// startTestFeedService starts a gRPC feed service for testing with in-memory Kafka and
+// returns its address
+func startTestFeedService(db *gorm.DB) (string, func()) {
 	// Use the same database instance for consistency in tests
 	feedDB := db
 
@@ -178,6 +217,7 @@ func startTestFeedService(db *gorm.DB, address string) func() {
 
 	// Initialize services (pass nil for producer in tests - will use memBus later)
 	feedService := feedCore.NewFeedService(feedRepository, logger.New(slog.LevelDebug), nil)
+	feedService.SetUnitOfWork(dbtx.NewUnitOfWork(db))
 	articleService := feedCore.NewArticleService(feedRepository, articleRepository, mockEventProducer, logger.New(slog.LevelDebug))
 
 	// Create event handler for processing
@@ -207,9 +247,9 @@ func startTestFeedService(db *gorm.DB, address string) func() {
 	feedpb.RegisterFeedServiceServer(grpcServer, grpcHandler)
 
 	// Start listening
-	lis, err := net.Listen("tcp", address)
+	lis, err := config.ListenEphemeral()
 	if err != nil {
-		log.Fatalf("Failed to listen on %s: %v", address, err)
+		log.Fatalf("Failed to listen: %v", err)
 	}
 
 	// Start server in background
@@ -219,8 +259,8 @@ func startTestFeedService(db *gorm.DB, address string) func() {
 		}
 	}()
 
-	// Return stop function
-	return func() {
+	// Return address and stop function
+	return lis.Addr().String(), func() {
 		grpcServer.GracefulStop()
 	}
 }
@@ -229,9 +269,19 @@ func startTestFeedService(db *gorm.DB, address string) func() {
 func runMigrations(db *gorm.DB) {
 	err := db.AutoMigrate(
 		&userModels.User{},
+		&userModels.LLMCredential{},
+		&userModels.Session{},
+		&userModels.UserPreferences{},
+		&userModels.AuditEvent{},
+		&userModels.PasswordReset{},
 		&feedModels.Feed{},
 		&feedModels.Article{},
+		&feedModels.ArticleSummary{},
 		&feedModels.Subscription{},
+		&feedModels.Folder{},
+		&feedModels.FolderDigest{},
+		&feedModels.Tag{},
+		&feedModels.ArticleTag{},
 	)
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
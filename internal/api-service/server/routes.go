@@ -1,7 +1,10 @@
 package server
 
 import (
+	"net/http"
+
 	"github.com/gin-contrib/gzip"
+	"github.com/gin-gonic/gin"
 
 	"github.com/Fancu1/phoenix-rss/internal/api-service/handler"
 	"github.com/Fancu1/phoenix-rss/pkg/ierr"
@@ -11,15 +14,37 @@ import (
 func (s *Server) setupRoutes() {
 	// Apply global middleware
 	s.engine.Use(handler.RequestIDMiddleware())
-	s.engine.Use(logger.GinLoggingMiddleware())
+	s.engine.Use(handler.TracingMiddleware())
+	s.engine.Use(logger.AccessLogMiddleware(logger.AccessLogOptions{
+		SlowThreshold: s.slowRequest,
+		Metrics:       s.routeMetrics,
+	}))
 	s.engine.Use(gzip.Gzip(gzip.DefaultCompression))
 	s.engine.Use(ierr.ErrorHandlerMiddleware())
+	if len(s.config.Server.CORSAllowedOrigins) > 0 {
+		s.engine.Use(handler.CORSMiddleware(s.config.Server.CORSAllowedOrigins))
+	}
 
-	// Register frontend routes
-	s.frontendHandler.RegisterRoutes(s.engine)
+	// Register frontend routes, on the API listener or on their own
+	switch {
+	case s.frontendEngine != nil:
+		s.frontendEngine.Use(gzip.Gzip(gzip.DefaultCompression))
+		s.frontendHandler.RegisterRoutes(s.frontendEngine)
+		s.engine.NoRoute(notFound)
+	case s.frontendHandler != nil:
+		s.frontendHandler.RegisterRoutes(s.engine)
+	default:
+		s.engine.NoRoute(notFound)
+	}
 
 	// Register API v1 routes
 	apiV1 := s.engine.Group("/api/v1")
+	apiV1.Use(handler.APIHeadersMiddleware())
+	if s.config.Server.Demo.Enabled {
+		// Signing in and out stays open so visitors can use the demo user
+		apiV1.Use(handler.DemoModeMiddleware(s.demoCacheTTL,
+			"POST /api/v1/users/login", "POST /api/v1/users/refresh", "POST /api/v1/users/logout"))
+	}
 	{
 		// Public routes (no authentication required)
 		apiV1.GET("/health", handler.HealthCheck)
@@ -27,6 +52,16 @@ func (s *Server) setupRoutes() {
 		// Authentication routes
 		apiV1.POST("/users/register", s.userHandler.Register)
 		apiV1.POST("/users/login", s.userHandler.Login)
+		apiV1.POST("/users/refresh", s.userHandler.Refresh)
+		apiV1.POST("/users/password-reset", s.userHandler.RequestPasswordReset)
+		apiV1.POST("/users/password-reset/confirm", s.userHandler.ResetPassword)
+
+		// Public pages of the folders users share
+		shared := apiV1.Group("/shared")
+		if s.sharedLimit != nil {
+			shared.Use(s.sharedLimit)
+		}
+		shared.GET("/:slug", s.folders.GetSharedFolder)
 
 		// Protected routes (authentication required)
 		protected := apiV1.Group("")
@@ -40,15 +75,126 @@ func (s *Server) setupRoutes() {
 			protected.GET("/feeds/export", s.opmlHandler.ExportOPML)
 			protected.POST("/feeds/import/preview", s.opmlHandler.PreviewOPML)
 			protected.POST("/feeds/import", s.opmlHandler.ImportOPML)
+			protected.GET("/feeds/settings/export", s.opmlHandler.ExportSettings)
+			protected.POST("/feeds/settings/import", s.opmlHandler.ImportSettings)
+			protected.POST("/feeds/unsubscribe", s.feedHandler.UnsubscribeFeeds)
+			protected.GET("/feeds/suggestions/cleanup", s.feedHandler.CleanupSuggestions)
 
 			// Feed-specific routes (with :feed_id parameter)
 			protected.DELETE("/feeds/:feed_id", s.feedHandler.UnsubscribeFeed)
 			protected.PATCH("/feeds/:feed_id", s.feedHandler.UpdateFeed)
+			protected.GET("/feeds/:feed_id/health", s.feedHandler.GetFeedHealth)
 			protected.POST("/feeds/:feed_id/fetch", s.articleHandler.TriggerFetch)
+			protected.POST("/feeds/:feed_id/read", s.articleHandler.MarkFeedRead)
 			protected.GET("/feeds/:feed_id/articles", s.articleHandler.ListArticles)
+			protected.POST("/feeds/:feed_id/exports", s.exports.CreateExport)
+			protected.GET("/exports/:export_id", s.exports.GetExport)
+			protected.GET("/exports/:export_id/download", s.exports.DownloadExport)
+
+			// Article trash and keyboard navigation (must be before :article_id routes)
+			protected.GET("/articles", s.articleHandler.ListTimeline)
+			protected.GET("/articles/trash", s.articleHandler.ListTrash)
+			protected.GET("/articles/next-unread", s.articleHandler.NextUnread)
+			protected.GET("/articles/search", s.articleHandler.SearchArticles)
+			protected.GET("/articles/starred", s.articleHandler.ListStarred)
 
 			// Article access (user-specific)
 			protected.GET("/articles/:article_id", s.articleHandler.GetArticle)
+			protected.DELETE("/articles/:article_id", s.articleHandler.DeleteArticle)
+			protected.GET("/articles/:article_id/export", s.articleHandler.ExportArticle)
+			protected.GET("/articles/:article_id/plain", s.articleHandler.PlainArticle)
+			protected.POST("/articles/:article_id/restore", s.articleHandler.RestoreArticle)
+			protected.POST("/articles/:article_id/read", s.articleHandler.MarkArticleRead)
+			protected.DELETE("/articles/:article_id/read", s.articleHandler.MarkArticleUnread)
+			protected.POST("/articles/:article_id/star", s.articleHandler.StarArticle)
+			protected.DELETE("/articles/:article_id/star", s.articleHandler.UnstarArticle)
+			protected.POST("/articles/:article_id/tags", s.articleHandler.AddArticleTags)
+			protected.DELETE("/articles/:article_id/tags/:tag", s.articleHandler.RemoveArticleTag)
+			protected.POST("/articles/:article_id/summary/regenerate", s.articleHandler.RegenerateSummary)
+			protected.PUT("/articles/:article_id/summary/feedback", s.summaryFeedback.RateSummary)
+
+			// Tags users put on articles
+			protected.GET("/tags", s.articleHandler.ListTags)
+
+			// Folders subscriptions are filed in
+			protected.GET("/folders", s.folders.ListFolders)
+			protected.POST("/folders", s.folders.CreateFolder)
+			protected.PATCH("/folders/:folder_id", s.folders.UpdateFolder)
+			protected.DELETE("/folders/:folder_id", s.folders.DeleteFolder)
+			protected.GET("/folders/:folder_id/digest", s.folders.GetFolderDigest)
+			protected.PUT("/folders/:folder_id/digest", s.folders.SetFolderDigest)
+			protected.DELETE("/folders/:folder_id/digest", s.folders.DeleteFolderDigest)
+			protected.PUT("/folders/:folder_id/share", s.folders.ShareFolder)
+			protected.DELETE("/folders/:folder_id/share", s.folders.UnshareFolder)
+
+			// Curated feed collections
+			protected.GET("/collections", s.collections.ListCollections)
+			protected.GET("/collections/:collection_id", s.collections.GetCollection)
+			protected.POST("/collections/:collection_id/subscribe", s.collections.SubscribeToCollection)
+
+			// Notifications (e.g. archived dead feeds)
+			protected.GET("/notifications", s.notifHandler.ListNotifications)
+			protected.POST("/notifications/:notification_id/read", s.notifHandler.MarkNotificationRead)
+
+			// Bring-your-own-key LLM credentials and usage
+			protected.GET("/users/me/llm-credential", s.userHandler.GetLLMCredential)
+			protected.PUT("/users/me/llm-credential", s.userHandler.SetLLMCredential)
+			protected.DELETE("/users/me/llm-credential", s.userHandler.DeleteLLMCredential)
+			protected.GET("/users/me/llm-usage", s.userHandler.GetLLMUsage)
+
+			// Login sessions
+			protected.GET("/users/me/sessions", s.userHandler.ListSessions)
+			protected.DELETE("/users/me/sessions", s.userHandler.RevokeOtherSessions)
+			protected.DELETE("/users/me/sessions/:session_id", s.userHandler.RevokeSession)
+			protected.POST("/users/logout", s.userHandler.Logout)
+
+			// Profile and password
+			protected.GET("/users/me", s.userHandler.GetProfile)
+			protected.PATCH("/users/me", s.userHandler.UpdateProfile)
+			protected.PUT("/users/me/password", s.userHandler.ChangePassword)
+
+			// Reading preferences
+			protected.GET("/users/me/preferences", s.userHandler.GetPreferences)
+			protected.PATCH("/users/me/preferences", s.userHandler.UpdatePreferences)
+
+			// Per-user read and starred state sync for offline clients
+			protected.GET("/sync/article-states", s.syncHandler.PullArticleStates)
+			protected.POST("/sync/article-states", s.syncHandler.PushArticleStates)
+
+			// "Catch me up" briefing of the unread headlines since a time
+			protected.POST("/briefing", s.briefings.CreateBriefing)
+		}
+
+		// Operator routes, for users with the admin role or requests carrying the admin
+		// token when one is configured
+		admin := apiV1.Group("/admin")
+		admin.Use(s.authMiddleware.RequireAdmin(s.config.Server.AdminToken))
+		{
+			admin.GET("/feeds/:feed_id/snapshots", s.adminHandler.ListFeedSnapshots)
+			admin.GET("/feeds/:feed_id/snapshots/:snapshot_id", s.adminHandler.GetFeedSnapshot)
+			admin.GET("/feeds", s.adminHandler.ListFeeds)
+			admin.POST("/feeds/bulk", s.adminHandler.BulkUpdateFeeds)
+			admin.POST("/feeds/:feed_id/fetch", s.adminHandler.FetchFeed)
+			admin.DELETE("/feeds/:feed_id", s.adminHandler.DeleteFeed)
+			admin.POST("/feeds/:feed_id/exports", s.exports.AdminCreateExport)
+			admin.GET("/exports/:export_id", s.exports.AdminGetExport)
+			admin.GET("/exports/:export_id/download", s.exports.AdminDownloadExport)
+			admin.GET("/users", s.userHandler.ListUsers)
+			admin.PATCH("/users/:user_id/role", s.userHandler.SetUserRole)
+			admin.POST("/users/:user_id/impersonate", s.userHandler.ImpersonateUser)
+			admin.GET("/metrics/routes", s.adminHandler.ListRouteMetrics)
+			admin.GET("/metrics/grpc-clients", s.adminHandler.ListGRPCClientMetrics)
+			admin.GET("/llm-usage", s.adminHandler.GetLLMUsage)
+			admin.GET("/policies", s.adminHandler.ListPolicies)
+			admin.POST("/policies/evaluate", s.adminHandler.EvaluatePolicy)
+			admin.GET("/collections", s.collections.AdminListCollections)
+			admin.POST("/collections", s.collections.CreateCollection)
+			admin.PUT("/collections/:collection_id", s.collections.UpdateCollection)
+			admin.DELETE("/collections/:collection_id", s.collections.DeleteCollection)
 		}
 	}
 }
+
+func notFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{"error": "resource not found"})
+}
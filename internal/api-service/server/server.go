@@ -3,15 +3,29 @@ package server
 import (
 	"fmt"
 	"io/fs"
+	"log/slog"
+	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/client"
+	aicore "github.com/Fancu1/phoenix-rss/internal/ai-service/core"
+	aimodels "github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+	airepository "github.com/Fancu1/phoenix-rss/internal/ai-service/repository"
 	"github.com/Fancu1/phoenix-rss/internal/api-service/core"
 	"github.com/Fancu1/phoenix-rss/internal/api-service/handler"
 	"github.com/Fancu1/phoenix-rss/internal/api-service/repository"
+	"github.com/Fancu1/phoenix-rss/internal/archive"
+	"github.com/Fancu1/phoenix-rss/internal/briefing"
 	"github.com/Fancu1/phoenix-rss/internal/config"
+	"github.com/Fancu1/phoenix-rss/internal/policy"
+	"github.com/Fancu1/phoenix-rss/internal/readstate"
+	"github.com/Fancu1/phoenix-rss/internal/summaryquality"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/secrets"
 )
 
 type Server struct {
@@ -21,42 +35,254 @@ type Server struct {
 	articleHandler  *handler.ArticleHandler
 	userHandler     *handler.UserHandler
 	opmlHandler     *handler.OPMLHandler
+	notifHandler    *handler.NotificationHandler
+	adminHandler    *handler.AdminHandler
+	syncHandler     *handler.SyncHandler
+	summaryFeedback *handler.SummaryFeedbackHandler
+	collections     *handler.CollectionHandler
+	folders         *handler.FolderHandler
+	briefings       *handler.BriefingHandler
+	exports         *handler.ExportHandler
 	authMiddleware  *handler.AuthMiddleware
-	frontendHandler *handler.StaticFrontendHandler
+	routeMetrics    *handler.RouteMetrics
+	slowRequest     time.Duration
+	demoCacheTTL    time.Duration                  // only used in demo mode
+	sharedLimit     gin.HandlerFunc                // nil when shared folders are not rate limited
+	frontendHandler *handler.StaticFrontendHandler // nil when the frontend is disabled
+	frontendEngine  *gin.Engine                    // own listener in separate mode
+	digests         *briefing.Digests              // nil when digests are off
 }
 
 func New(cfg *config.Config, db *gorm.DB, feedService core.FeedServiceInterface, articleService core.ArticleServiceInterface, userService core.UserServiceInterface, redisClient *redis.Client, staticFS fs.FS) (*Server, error) {
 	subscriptionRepo := repository.NewSubscriptionRepository(db)
 	articleRepo := repository.NewArticleRepository(db)
+	folderRepo := repository.NewFolderRepository(db)
 
-	feedHandler := handler.NewFeedHandler(feedService, subscriptionRepo, redisClient)
-	articleHandler := handler.NewArticleHandler(articleService, subscriptionRepo, articleRepo)
+	policies, err := policy.Load(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policies: %w", err)
+	}
+	trashGrace := policies.Defaults().TrashRetention
+
+	slowRequest, err := time.ParseDuration(cfg.Server.SlowRequestThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("invalid slow request threshold: %w", err)
+	}
+
+	smartSort := cfg.Server.SmartSort
+	halfLife, err := time.ParseDuration(smartSort.RecencyHalfLife)
+	if err != nil || halfLife <= 0 {
+		return nil, fmt.Errorf("invalid smart sort recency half-life %q", smartSort.RecencyHalfLife)
+	}
+	articleRepo.SetSmartSortWeights(repository.SmartSortWeights{
+		Recency:         smartSort.RecencyWeight,
+		Unread:          smartSort.UnreadWeight,
+		Affinity:        smartSort.AffinityWeight,
+		Starred:         smartSort.StarredWeight,
+		RecencyHalfLife: halfLife,
+	})
+
+	llmTimeout, err := time.ParseDuration(cfg.AIService.RequestTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LLM request timeout: %w", err)
+	}
+
+	var demoCacheTTL time.Duration
+	if cfg.Server.Demo.Enabled {
+		demoCacheTTL, err = time.ParseDuration(cfg.Server.Demo.CacheTTL)
+		if err != nil || demoCacheTTL < 0 {
+			return nil, fmt.Errorf("invalid demo cache ttl %q", cfg.Server.Demo.CacheTTL)
+		}
+	}
+
+	feedHandler := handler.NewFeedHandler(feedService, subscriptionRepo, folderRepo, redisClient)
+	if demoCacheTTL > 0 {
+		feedHandler.SetCacheTTL(demoCacheTTL)
+	}
+	articleHandler := handler.NewArticleHandler(articleService, subscriptionRepo, articleRepo, trashGrace)
+	articleHandler.SetCache(redisClient)
+	collectionRepo := repository.NewCollectionRepository(db)
 	userHandler := handler.NewUserHandler(userService)
-	opmlHandler := handler.NewOPMLHandler(feedService, subscriptionRepo, redisClient)
+	userHandler.SetAuditLog(repository.NewAuditRepository(db))
+	userHandler.SetDefaultFeeds(collectionRepo, feedService)
+	if login := cfg.Server.LoginProtection; login.Enabled {
+		guard, err := newLoginGuard(login, redisClient)
+		if err != nil {
+			return nil, err
+		}
+		userHandler.SetLoginGuard(guard)
+	}
+	opmlHandler := handler.NewOPMLHandler(feedService, subscriptionRepo, articleRepo, folderRepo, redisClient)
+	notifHandler := handler.NewNotificationHandler(repository.NewNotificationRepository(db))
+	adminHandler := handler.NewAdminHandler(repository.NewSnapshotRepository(db), feedService, redisClient)
+	routeMetrics := handler.NewRouteMetrics()
+	adminHandler.SetRouteMetrics(routeMetrics)
+	adminHandler.SetPolicies(policies, repository.NewPolicyRepository(db))
+	adminHandler.SetLLMUsage(repository.NewLLMUsageRepository(db), aimodels.LLMBudget{
+		DailyCostUSD: cfg.AIService.DailyBudgetUSD,
+		DailyTokens:  cfg.AIService.DailyTokenBudget,
+	})
+	syncHandler := handler.NewSyncHandler(readstate.NewStore(db))
+	summaryFeedbackHandler := handler.NewSummaryFeedbackHandler(summaryquality.NewStore(db))
+	collectionHandler := handler.NewCollectionHandler(collectionRepo, feedService, redisClient)
+	folderHandler := handler.NewFolderHandler(folderRepo, redisClient)
+	sharedCacheTTL, err := time.ParseDuration(cfg.Server.SharedFolders.CacheTTL)
+	if err != nil || sharedCacheTTL < 0 {
+		return nil, fmt.Errorf("invalid shared folders cache ttl %q", cfg.Server.SharedFolders.CacheTTL)
+	}
+	folderHandler.SetSharedCacheTTL(sharedCacheTTL)
+	var sharedLimit gin.HandlerFunc
+	if limit := cfg.Server.SharedFolders.RateLimit; limit > 0 {
+		// the login attempt counters serve any fixed window
+		sharedLimit = handler.RateLimitMiddleware(core.NewRedisLoginAttemptStore(redisClient), "shared_folders", limit, time.Minute)
+	}
+	llmClient := client.NewLLMClient(cfg.AIService.LLMBaseURL, cfg.AIService.LLMAPIKey, cfg.AIService.LLMModel, llmTimeout, logger.New(slog.LevelInfo))
+	briefings := briefing.NewService(briefing.NewStore(db), llmClient, briefing.Options{
+		MaxHeadlines:    cfg.AIService.BriefingMaxHeadlines,
+		MaxTokens:       cfg.AIService.BriefingMaxTokens,
+		DefaultLanguage: cfg.Summaries.DefaultLanguage(),
+	})
+	if cfg.AIService.BriefingCacheEnabled && redisClient != nil {
+		briefings.SetCache(redisClient)
+	}
+	// a reader's own LLM key only ever writes their own briefing
+	credentialCipher, err := secrets.NewCipher(cfg.Auth.CredentialsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize credentials cipher: %w", err)
+	}
+	briefings.UseUserKeys(credentialCipher, func(creds client.Credentials) briefing.Completer {
+		return llmClient.WithCredentials(creds)
+	})
+	// briefings count in the reader's LLM usage, and in the instance budget unless written
+	// with the reader's key
+	modelPrices, err := cfg.AIService.ParsedModelPrices()
+	if err != nil {
+		return nil, fmt.Errorf("invalid model prices: %w", err)
+	}
+	briefings.UseUsageLedger(airepository.NewCredentialRepository(db), func(model string, usage client.Usage) float64 {
+		price := modelPrices[model]
+		return aicore.ModelPrice{PromptPerMillion: price.Prompt, CompletionPerMillion: price.Completion}.Cost(usage)
+	})
+	briefingHandler := handler.NewBriefingHandler(briefings)
+	digestInterval, err := time.ParseDuration(cfg.AIService.DigestInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest interval: %w", err)
+	}
+	exportObjects, err := archive.OpenStorage(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export storage: %w", err)
+	}
+	exportHandler := handler.NewExportHandler(archive.NewStore(db), exportObjects, subscriptionRepo)
 	authMiddleware := handler.NewAuthMiddleware(cfg.Auth.JWTSecret)
-	frontendHandler, err := handler.NewStaticFrontendHandler(staticFS)
+	authMiddleware.SetSessionChecker(repository.NewSessionRepository(db))
+	authMiddleware.SetRoleChecker(repository.NewUserRoleRepository(db))
+
+	var frontendHandler *handler.StaticFrontendHandler
+	if cfg.Server.Frontend.Mode != config.FrontendModeDisabled {
+		csp := cfg.Server.Frontend.ContentSecurityPolicy
+		if csp == "" {
+			csp = handler.FrontendContentSecurityPolicy(cfg.Server.Frontend.APIOrigin)
+		}
+		frontendHandler, err = handler.NewStaticFrontendHandler(staticFS, csp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create frontend handler: %w", err)
+		}
+	}
+
+	engine, err := handler.NewEngine(cfg.Server.TrustedProxies)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create frontend handler: %w", err)
+		return nil, err
 	}
 
 	s := &Server{
 		config:          cfg,
-		engine:          gin.Default(),
+		engine:          engine,
 		feedHandler:     feedHandler,
 		articleHandler:  articleHandler,
 		userHandler:     userHandler,
 		opmlHandler:     opmlHandler,
+		notifHandler:    notifHandler,
+		adminHandler:    adminHandler,
+		syncHandler:     syncHandler,
+		summaryFeedback: summaryFeedbackHandler,
+		collections:     collectionHandler,
+		folders:         folderHandler,
+		briefings:       briefingHandler,
+		exports:         exportHandler,
 		authMiddleware:  authMiddleware,
+		routeMetrics:    routeMetrics,
+		slowRequest:     slowRequest,
+		demoCacheTTL:    demoCacheTTL,
+		sharedLimit:     sharedLimit,
 		frontendHandler: frontendHandler,
 	}
+	if digestInterval > 0 {
+		s.digests = briefing.NewDigests(briefings, logger.New(slog.LevelInfo), digestInterval)
+	}
+
+	if cfg.Server.Frontend.Mode == config.FrontendModeSeparate {
+		if s.frontendEngine, err = handler.NewEngine(cfg.Server.TrustedProxies); err != nil {
+			return nil, err
+		}
+	}
 
 	s.setupRoutes()
 
 	return s, nil
 }
 
-func (s *Server) Start() error {
-	addr := fmt.Sprintf(":%d", s.config.Server.Port)
-	fmt.Printf("Server listening on %s\n", addr)
-	return s.engine.Run(addr)
+// Start serves the API, and in separate mode the frontend on its own port, until one
+// of the listeners fails
+// SetGRPCClients serves the breaker state of the feed and user service clients to
+// administrators
+func (s *Server) SetGRPCClients(clients ...*core.Resilience) {
+	s.adminHandler.SetGRPCClients(clients...)
+}
+
+// SetReadRechecker queues update checks of older articles as users open them
+func (s *Server) SetReadRechecker(opener handler.ArticleOpener) {
+	s.articleHandler.SetReadRechecker(opener)
+}
+
+// Digests returns the writer of the folder digests that are due, nil when digests are off
+func (s *Server) Digests() *briefing.Digests {
+	return s.digests
+}
+
+// HTTPServers returns the API server, and the frontend's when it listens on its own port
+func (s *Server) HTTPServers() []*http.Server {
+	servers := []*http.Server{{
+		Addr:    fmt.Sprintf(":%d", s.config.Server.Port),
+		Handler: s.engine.Handler(),
+	}}
+	if s.frontendEngine != nil {
+		servers = append(servers, &http.Server{
+			Addr:    fmt.Sprintf(":%d", s.config.Server.Frontend.Port),
+			Handler: s.frontendEngine.Handler(),
+		})
+	}
+	return servers
+}
+
+func newLoginGuard(cfg config.ServerLoginProtectionConfig, redisClient *redis.Client) (*core.LoginGuard, error) {
+	durations := make([]time.Duration, 3)
+	for i, value := range []string{cfg.FailureWindow, cfg.BaseLockout, cfg.MaxLockout} {
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid login protection duration %q", value)
+		}
+		durations[i] = duration
+	}
+	guard := core.NewLoginGuard(core.NewRedisLoginAttemptStore(redisClient), core.LoginGuardConfig{
+		MaxAccountFailures: cfg.MaxAccountFailures,
+		MaxIPFailures:      cfg.MaxIPFailures,
+		FailureWindow:      durations[0],
+		ChallengeAfter:     cfg.ChallengeAfter,
+		BaseLockout:        durations[1],
+		MaxLockout:         durations[2],
+	})
+	if cfg.ChallengeVerifyURL != "" {
+		guard.SetChallenge(core.NewSiteVerifyChallenge(cfg.ChallengeVerifyURL, cfg.ChallengeSecret))
+	}
+	return guard, nil
 }
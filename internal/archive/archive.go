@@ -0,0 +1,239 @@
+// Package archive exports every article of a feed to a single file, for research use and
+// to take a large feed's history elsewhere. Exports are jobs: the api-service queues them
+// in article_exports, a feed-service worker pages through the articles by ID and writes
+// the archive to object storage, and the api-service serves it from there once done.
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/config"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/objectstore"
+)
+
+// Format is the file format of an export
+type Format string
+
+const (
+	// FormatJSONL writes one JSON object per article
+	FormatJSONL Format = "jsonl"
+	// FormatWARC writes a WARC/1.1 file: a resource record with the content of each
+	// article and a metadata record with its other fields, every record gzipped on its own
+	// as web archive tools expect
+	FormatWARC Format = "warc"
+)
+
+// ParseFormat parses a format name; empty means FormatJSONL
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(value))); format {
+	case "":
+		return FormatJSONL, nil
+	case FormatJSONL, FormatWARC:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q: must be %s or %s", value, FormatJSONL, FormatWARC)
+	}
+}
+
+// Status is where an export is in its life
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// maxErrorLength caps the error stored with a failed export
+const maxErrorLength = 500
+
+// Export is an archive of a feed's articles, requested by UserID or, when nil, with the
+// admin token
+type Export struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	FeedID     uint       `json:"feed_id"`
+	UserID     *uint      `json:"-"`
+	Format     Format     `json:"format"`
+	Status     Status     `json:"status" gorm:"default:queued"`
+	ObjectKey  string     `json:"-"`
+	Articles   int64      `json:"articles"`
+	Bytes      int64      `json:"bytes"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func (Export) TableName() string {
+	return "article_exports"
+}
+
+// FileName is the name the archive is downloaded under
+func (e *Export) FileName() string {
+	return fmt.Sprintf("feed-%d-export-%d.%s.gz", e.FeedID, e.ID, e.Format)
+}
+
+// objectKey is where the archive of the export is stored
+func (e *Export) objectKey() string {
+	return fmt.Sprintf("exports/feed-%d/%d.%s.gz", e.FeedID, e.ID, e.Format)
+}
+
+// OpenStorage opens the object storage archives are written to
+func OpenStorage(cfg *config.Config) (objectstore.Store, error) {
+	switch cfg.Exports.Storage {
+	case config.ExportStorageS3:
+		s3 := cfg.Exports.S3
+		return objectstore.NewS3(objectstore.S3Config{
+			Endpoint:        s3.Endpoint,
+			Region:          s3.Region,
+			Bucket:          s3.Bucket,
+			AccessKeyID:     s3.AccessKeyID,
+			SecretAccessKey: s3.SecretAccessKey,
+		})
+	default:
+		return objectstore.NewDir(cfg.Exports.Dir), nil
+	}
+}
+
+// Store keeps the export jobs and reads the articles they archive
+type Store struct {
+	db *gorm.DB
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// Create queues an export of the feed. When the same requester already has one of the
+// feed in that format queued or running, that export is returned instead and created
+// is false.
+func (s *Store) Create(ctx context.Context, feedID uint, userID *uint, format Format) (export *Export, created bool, err error) {
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("feed_id = ? AND format = ? AND status IN ?", feedID, format, []Status{StatusQueued, StatusRunning})
+		if userID != nil {
+			query = query.Where("user_id = ?", *userID)
+		} else {
+			query = query.Where("user_id IS NULL")
+		}
+		var existing Export
+		err := query.Order("id").First(&existing).Error
+		if err == nil {
+			export = &existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		export = &Export{FeedID: feedID, UserID: userID, Format: format, Status: StatusQueued}
+		created = true
+		return tx.Create(export).Error
+	})
+	return export, created, err
+}
+
+// Get returns the export with the ID, gorm.ErrRecordNotFound when there is none
+func (s *Store) Get(ctx context.Context, id uint) (*Export, error) {
+	var export Export
+	if err := s.db.WithContext(ctx).First(&export, id).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// FeedExists reports whether there is a feed with the ID to export
+func (s *Store) FeedExists(ctx context.Context, feedID uint) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.Feed{}).Where("id = ?", feedID).Count(&count).Error
+	return count > 0, err
+}
+
+// ClaimNext marks the oldest queued export running and returns it, nil when none is
+// queued. Replicas racing for the same export each claim it at most once.
+func (s *Store) ClaimNext(ctx context.Context, now time.Time) (*Export, error) {
+	db := s.db.WithContext(ctx)
+	for {
+		var export Export
+		err := db.Where("status = ?", StatusQueued).Order("id").First(&export).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result := db.Model(&Export{}).
+			Where("id = ? AND status = ?", export.ID, StatusQueued).
+			Updates(map[string]any{"status": StatusRunning, "started_at": now})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			export.Status = StatusRunning
+			export.StartedAt = &now
+			return &export, nil
+		}
+		// another replica claimed it first, try the next one
+	}
+}
+
+// Requeue puts running exports started before the time back in the queue, returning how
+// many there were
+func (s *Store) Requeue(ctx context.Context, startedBefore time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Model(&Export{}).
+		Where("status = ? AND started_at < ?", StatusRunning, startedBefore).
+		Updates(map[string]any{"status": StatusQueued, "started_at": nil})
+	return result.RowsAffected, result.Error
+}
+
+// requeue puts one running export back in the queue
+func (s *Store) requeue(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Model(&Export{}).
+		Where("id = ? AND status = ?", id, StatusRunning).
+		Updates(map[string]any{"status": StatusQueued, "started_at": nil}).Error
+}
+
+func (s *Store) succeed(ctx context.Context, export *Export, now time.Time) error {
+	return s.db.WithContext(ctx).Model(&Export{}).Where("id = ?", export.ID).Updates(map[string]any{
+		"status":      StatusSucceeded,
+		"object_key":  export.ObjectKey,
+		"articles":    export.Articles,
+		"bytes":       export.Bytes,
+		"error":       "",
+		"finished_at": now,
+	}).Error
+}
+
+func (s *Store) fail(ctx context.Context, id uint, cause error, now time.Time) error {
+	message := cause.Error()
+	if len(message) > maxErrorLength {
+		message = message[:maxErrorLength]
+	}
+	return s.db.WithContext(ctx).Model(&Export{}).Where("id = ?", id).Updates(map[string]any{
+		"status":      StatusFailed,
+		"error":       message,
+		"finished_at": now,
+	}).Error
+}
+
+// ArticlePage returns up to limit articles of the feed with IDs above afterID, in ID
+// order. Paging by ID rather than offset keeps each page an index range scan however far
+// into a large feed the export is, and articles saved meanwhile are not skipped or
+// repeated. Articles in the trash are left out.
+func (s *Store) ArticlePage(ctx context.Context, feedID, afterID uint, limit int) ([]models.Article, error) {
+	var articles []models.Article
+	err := s.db.WithContext(ctx).
+		Where("feed_id = ? AND id > ?", feedID, afterID).
+		Order("id").
+		Limit(limit).
+		Find(&articles).Error
+	return articles, err
+}
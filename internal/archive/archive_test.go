@@ -0,0 +1,178 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/objectstore"
+)
+
+func setupExporter(t *testing.T, articles int) (*Exporter, *Store, *objectstore.Dir, uint) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Feed{}, &models.Article{}, &Export{}))
+
+	feed := &models.Feed{Title: "Big Feed", URL: "https://big.example.com/feed"}
+	require.NoError(t, db.Create(feed).Error)
+	other := &models.Feed{Title: "Other", URL: "https://other.example.com/feed"}
+	require.NoError(t, db.Create(other).Error)
+	published := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	for i := 1; i <= articles; i++ {
+		summary := fmt.Sprintf("Summary %d", i)
+		require.NoError(t, db.Create(&models.Article{
+			FeedID:      feed.ID,
+			Title:       fmt.Sprintf("Article %d", i),
+			URL:         fmt.Sprintf("https://big.example.com/%d", i),
+			Content:     fmt.Sprintf("<p>Content %d</p>", i),
+			Summary:     &summary,
+			PublishedAt: published.Add(time.Duration(i) * time.Hour),
+		}).Error)
+	}
+	require.NoError(t, db.Create(&models.Article{FeedID: other.ID, Title: "Elsewhere", URL: "https://other.example.com/1"}).Error)
+
+	store := NewStore(db)
+	objects := objectstore.NewDir(t.TempDir())
+	exporter := NewExporter(store, objects, 2, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return exporter, store, objects, feed.ID
+}
+
+func readObject(t *testing.T, objects objectstore.Store, key string) string {
+	t.Helper()
+	reader, err := objects.Get(context.Background(), key)
+	require.NoError(t, err)
+	defer reader.Close()
+	gz, err := gzip.NewReader(reader)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestStore_CreateReturnsActiveExport(t *testing.T) {
+	_, store, _, feedID := setupExporter(t, 0)
+	ctx := context.Background()
+	userID := uint(7)
+
+	first, created, err := store.Create(ctx, feedID, &userID, FormatJSONL)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, StatusQueued, first.Status)
+
+	again, created, err := store.Create(ctx, feedID, &userID, FormatJSONL)
+	require.NoError(t, err)
+	assert.False(t, created, "the queued export is reused")
+	assert.Equal(t, first.ID, again.ID)
+
+	warc, created, err := store.Create(ctx, feedID, &userID, FormatWARC)
+	require.NoError(t, err)
+	assert.True(t, created, "another format is another export")
+	assert.NotEqual(t, first.ID, warc.ID)
+
+	admin, created, err := store.Create(ctx, feedID, nil, FormatJSONL)
+	require.NoError(t, err)
+	assert.True(t, created, "another requester is another export")
+	assert.Nil(t, admin.UserID)
+}
+
+func TestExporter_JSONL(t *testing.T) {
+	exporter, store, objects, feedID := setupExporter(t, 5)
+	ctx := context.Background()
+	export, _, err := store.Create(ctx, feedID, nil, FormatJSONL)
+	require.NoError(t, err)
+
+	ran, err := exporter.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.True(t, ran)
+
+	export, err = store.Get(ctx, export.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, export.Status)
+	assert.Equal(t, int64(5), export.Articles, "every page is exported")
+	assert.NotNil(t, export.FinishedAt)
+	assert.Equal(t, fmt.Sprintf("exports/feed-%d/%d.jsonl.gz", feedID, export.ID), export.ObjectKey)
+
+	lines := strings.Split(strings.TrimSpace(readObject(t, objects, export.ObjectKey)), "\n")
+	require.Len(t, lines, 5)
+	for i, line := range lines {
+		var record Record
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		assert.Equal(t, fmt.Sprintf("Article %d", i+1), record.Title, "articles are in ID order")
+		assert.Equal(t, feedID, record.FeedID)
+		require.NotNil(t, record.Summary)
+		assert.Equal(t, fmt.Sprintf("Summary %d", i+1), *record.Summary)
+	}
+
+	ran, err = exporter.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.False(t, ran, "nothing is left in the queue")
+}
+
+func TestExporter_WARC(t *testing.T) {
+	exporter, store, objects, feedID := setupExporter(t, 2)
+	ctx := context.Background()
+	export, _, err := store.Create(ctx, feedID, nil, FormatWARC)
+	require.NoError(t, err)
+
+	_, err = exporter.RunOnce(ctx)
+	require.NoError(t, err)
+	export, err = store.Get(ctx, export.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatusSucceeded, export.Status)
+
+	var types []string
+	scanner := bufio.NewScanner(strings.NewReader(readObject(t, objects, export.ObjectKey)))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "WARC-Type: "); ok {
+			types = append(types, strings.TrimSpace(value))
+		}
+	}
+	assert.Equal(t, []string{"warcinfo", "resource", "metadata", "resource", "metadata"}, types)
+	archive := readObject(t, objects, export.ObjectKey)
+	assert.Contains(t, archive, "WARC-Target-URI: https://big.example.com/2\r\n")
+	assert.Contains(t, archive, "Content-Length: 16\r\n\r\n<p>Content 1</p>\r\n\r\n")
+}
+
+func TestExporter_Failures(t *testing.T) {
+	exporter, store, _, feedID := setupExporter(t, 1)
+	ctx := context.Background()
+
+	missing, _, err := store.Create(ctx, feedID+100, nil, FormatJSONL)
+	require.NoError(t, err)
+	ran, err := exporter.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.True(t, ran)
+	missing, err = store.Get(ctx, missing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, missing.Status)
+	assert.Contains(t, missing.Error, "no longer exists")
+
+	stale, _, err := store.Create(ctx, feedID, nil, FormatJSONL)
+	require.NoError(t, err)
+	claimed, err := store.ClaimNext(ctx, time.Now().UTC().Add(-2*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, stale.ID, claimed.ID)
+
+	ran, err = exporter.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.True(t, ran, "the export left running is picked up again")
+	stale, err = store.Get(ctx, stale.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, stale.Status)
+}
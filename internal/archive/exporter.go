@@ -0,0 +1,143 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/objectstore"
+)
+
+// Exporter runs queued exports. An archive is written to a temporary file one page of
+// articles at a time, so memory stays flat however large the feed, then uploaded.
+type Exporter struct {
+	store      *Store
+	objects    objectstore.Store
+	pageSize   int
+	staleAfter time.Duration
+	logger     *slog.Logger
+	now        func() time.Time
+}
+
+func NewExporter(store *Store, objects objectstore.Store, pageSize int, staleAfter time.Duration, logger *slog.Logger) *Exporter {
+	return &Exporter{
+		store:      store,
+		objects:    objects,
+		pageSize:   pageSize,
+		staleAfter: staleAfter,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// RunOnce requeues stale exports, then runs the oldest queued one. It reports whether
+// there was one to run; a failed export is recorded as failed rather than returned.
+func (e *Exporter) RunOnce(ctx context.Context) (bool, error) {
+	requeued, err := e.store.Requeue(ctx, e.now().UTC().Add(-e.staleAfter))
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue stale exports: %w", err)
+	}
+	if requeued > 0 {
+		e.logger.Warn("requeued stale article exports", "count", requeued, "stale_after", e.staleAfter)
+	}
+
+	export, err := e.store.ClaimNext(ctx, e.now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim export: %w", err)
+	}
+	if export == nil {
+		return false, nil
+	}
+
+	log := e.logger.With("export_id", export.ID, "feed_id", export.FeedID, "format", export.Format)
+	started := e.now()
+	if err := e.run(ctx, export); err != nil {
+		if ctx.Err() != nil {
+			// shutting down: leave the export for the next start rather than failing it
+			if err := e.store.requeue(context.Background(), export.ID); err != nil {
+				log.Error("failed to requeue interrupted export", "error", err)
+			}
+			return true, ctx.Err()
+		}
+		log.Error("article export failed", "error", err)
+		if err := e.store.fail(ctx, export.ID, err, e.now().UTC()); err != nil {
+			return true, fmt.Errorf("failed to record failed export %d: %w", export.ID, err)
+		}
+		return true, nil
+	}
+
+	if err := e.store.succeed(ctx, export, e.now().UTC()); err != nil {
+		return true, fmt.Errorf("failed to record export %d: %w", export.ID, err)
+	}
+	log.Info("article export completed", "articles", export.Articles, "bytes", export.Bytes, "duration", e.now().Sub(started))
+	return true, nil
+}
+
+// run writes the archive of the export and uploads it, filling in its object key, article
+// count and size
+func (e *Exporter) run(ctx context.Context, export *Export) error {
+	var feed models.Feed
+	if err := e.store.db.WithContext(ctx).First(&feed, export.FeedID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("feed %d no longer exists", export.FeedID)
+		}
+		return err
+	}
+
+	file, err := os.CreateTemp("", "phoenix-export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	writer, err := newRecordWriter(export.Format, file, &feed, e.now())
+	if err != nil {
+		return err
+	}
+	var afterID uint
+	var count int64
+	for {
+		articles, err := e.store.ArticlePage(ctx, export.FeedID, afterID, e.pageSize)
+		if err != nil {
+			return fmt.Errorf("failed to read articles after %d: %w", afterID, err)
+		}
+		for i := range articles {
+			if err := writer.Write(&articles[i]); err != nil {
+				return fmt.Errorf("failed to write article %d: %w", articles[i].ID, err)
+			}
+		}
+		count += int64(len(articles))
+		if len(articles) < e.pageSize {
+			break
+		}
+		afterID = articles[len(articles)-1].ID
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	key := export.objectKey()
+	if err := e.objects.Put(ctx, key, file, size, "application/gzip"); err != nil {
+		return fmt.Errorf("failed to upload archive: %w", err)
+	}
+
+	export.ObjectKey = key
+	export.Articles = count
+	export.Bytes = size
+	return nil
+}
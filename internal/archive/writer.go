@@ -0,0 +1,172 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// Record is an article as exported: everything but the fetch bookkeeping and per-user
+// state
+type Record struct {
+	ID               uint          `json:"id"`
+	FeedID           uint          `json:"feed_id"`
+	Title            string        `json:"title"`
+	URL              string        `json:"url"`
+	Description      string        `json:"description,omitempty"`
+	Content          string        `json:"content,omitempty"`
+	Direction        string        `json:"direction,omitempty"`
+	Summary          *string       `json:"summary,omitempty"`
+	SummaryModel     *string       `json:"summary_model,omitempty"`
+	Topics           models.Topics `json:"topics,omitempty"`
+	ProcessingStatus string        `json:"processing_status,omitempty"`
+	PublishedAt      time.Time     `json:"published_at"`
+	CreatedAt        time.Time     `json:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at"`
+}
+
+func newRecord(article *models.Article) Record {
+	return Record{
+		ID:               article.ID,
+		FeedID:           article.FeedID,
+		Title:            article.Title,
+		URL:              article.URL,
+		Description:      article.Description,
+		Content:          article.Content,
+		Direction:        article.Direction,
+		Summary:          article.Summary,
+		SummaryModel:     article.ProcessingModel,
+		Topics:           article.Topics,
+		ProcessingStatus: string(article.ProcessingStatus),
+		PublishedAt:      article.PublishedAt,
+		CreatedAt:        article.CreatedAt,
+		UpdatedAt:        article.UpdatedAt,
+	}
+}
+
+// recordWriter writes the articles of an export in its format
+type recordWriter interface {
+	Write(article *models.Article) error
+	// Close flushes what is buffered; it does not close the underlying writer
+	Close() error
+}
+
+func newRecordWriter(format Format, w io.Writer, feed *models.Feed, now time.Time) (recordWriter, error) {
+	switch format {
+	case FormatWARC:
+		return newWARCWriter(w, feed, now)
+	default:
+		return newJSONLWriter(w), nil
+	}
+}
+
+// jsonlWriter writes gzipped JSON Lines
+type jsonlWriter struct {
+	gz      *gzip.Writer
+	encoder *json.Encoder
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	gz := gzip.NewWriter(w)
+	return &jsonlWriter{gz: gz, encoder: json.NewEncoder(gz)}
+}
+
+func (w *jsonlWriter) Write(article *models.Article) error {
+	return w.encoder.Encode(newRecord(article))
+}
+
+func (w *jsonlWriter) Close() error {
+	return w.gz.Close()
+}
+
+// warcWriter writes WARC/1.1 records, each as its own gzip member
+type warcWriter struct {
+	w io.Writer
+}
+
+func newWARCWriter(w io.Writer, feed *models.Feed, now time.Time) (*warcWriter, error) {
+	writer := &warcWriter{w: w}
+	info := fmt.Sprintf("software: phoenix-rss\r\nformat: WARC File Format 1.1\r\ndescription: Articles of feed %d\r\nisPartOf: %s\r\n", feed.ID, feed.URL)
+	err := writer.record([]string{
+		"WARC-Type: warcinfo",
+		"WARC-Record-ID: " + recordID(),
+		"WARC-Date: " + warcDate(now),
+		"Content-Type: application/warc-fields",
+	}, []byte(info))
+	return writer, err
+}
+
+// Write stores the content of the article as a resource record of its URL and the rest of
+// its fields, as JSON, in a metadata record concurrent to it
+func (w *warcWriter) Write(article *models.Article) error {
+	content := article.Content
+	if content == "" {
+		content = article.Description
+	}
+	resourceID := recordID()
+	date := article.CreatedAt
+	if date.IsZero() {
+		date = article.PublishedAt
+	}
+	err := w.record([]string{
+		"WARC-Type: resource",
+		"WARC-Record-ID: " + resourceID,
+		"WARC-Date: " + warcDate(date),
+		"WARC-Target-URI: " + article.URL,
+		"Content-Type: text/html; charset=utf-8",
+	}, []byte(content))
+	if err != nil {
+		return err
+	}
+
+	record := newRecord(article)
+	record.Content = ""
+	metadata, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return w.record([]string{
+		"WARC-Type: metadata",
+		"WARC-Record-ID: " + recordID(),
+		"WARC-Date: " + warcDate(date),
+		"WARC-Target-URI: " + article.URL,
+		"WARC-Concurrent-To: " + resourceID,
+		"Content-Type: application/json",
+	}, metadata)
+}
+
+func (w *warcWriter) Close() error {
+	return nil
+}
+
+func (w *warcWriter) record(headers []string, block []byte) error {
+	var buf bytes.Buffer
+	buf.WriteString("WARC/1.1\r\n")
+	for _, header := range headers {
+		buf.WriteString(header + "\r\n")
+	}
+	fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n", len(block))
+	buf.Write(block)
+	buf.WriteString("\r\n\r\n")
+
+	gz := gzip.NewWriter(w.w)
+	if _, err := gz.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func recordID() string {
+	return "<urn:uuid:" + uuid.NewString() + ">"
+}
+
+func warcDate(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
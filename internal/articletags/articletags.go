@@ -0,0 +1,134 @@
+// Package articletags keeps the tags users put on articles. Tags belong to a user and are
+// created with the first article they are put on; a tag taken off its last article is
+// removed.
+package articletags
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// TaggedCondition is a WHERE condition on the articles table that keeps the articles a
+// user put a tag on. It takes the user ID and the tag name as its parameters.
+const TaggedCondition = "EXISTS (SELECT 1 FROM article_tags atg JOIN tags tg ON tg.id = atg.tag_id" +
+	" WHERE tg.user_id = ? AND tg.name = ? AND atg.article_id = articles.id)"
+
+// Store reads and writes the tags of users
+type Store struct {
+	db *gorm.DB
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// Add puts the named tags on the article for the user, creating the tags the user does
+// not have yet. Names must be normalized with models.NormalizeTagNames.
+func (s *Store) Add(ctx context.Context, userID, articleID uint, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tags := make([]models.Tag, len(names))
+		for i, name := range names {
+			tags[i] = models.Tag{UserID: userID, Name: name}
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error; err != nil {
+			return fmt.Errorf("create tags: %w", err)
+		}
+
+		var tagIDs []uint
+		if err := tx.Model(&models.Tag{}).
+			Where("user_id = ? AND name IN ?", userID, names).
+			Pluck("id", &tagIDs).Error; err != nil {
+			return fmt.Errorf("load tags: %w", err)
+		}
+		links := make([]models.ArticleTag, len(tagIDs))
+		for i, tagID := range tagIDs {
+			links[i] = models.ArticleTag{TagID: tagID, ArticleID: articleID}
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error; err != nil {
+			return fmt.Errorf("tag article: %w", err)
+		}
+		return nil
+	})
+}
+
+// Remove takes the named tags of the user off the article and removes those of them left
+// on no article. Names the article is not tagged with are ignored.
+func (s *Store) Remove(ctx context.Context, userID, articleID uint, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tagIDs := tx.Model(&models.Tag{}).Select("id").Where("user_id = ? AND name IN ?", userID, names)
+		if err := tx.Where("article_id = ? AND tag_id IN (?)", articleID, tagIDs).
+			Delete(&models.ArticleTag{}).Error; err != nil {
+			return fmt.Errorf("untag article: %w", err)
+		}
+		err := tx.Where("user_id = ? AND name IN ?", userID, names).
+			Where("NOT EXISTS (SELECT 1 FROM article_tags atg WHERE atg.tag_id = tags.id)").
+			Delete(&models.Tag{}).Error
+		if err != nil {
+			return fmt.Errorf("remove unused tags: %w", err)
+		}
+		return nil
+	})
+}
+
+// List returns the user's tags by name, each with the number of articles of the user's
+// subscribed feeds it is on. Tags on no such article are left out.
+func (s *Store) List(ctx context.Context, userID uint) ([]models.TagCount, error) {
+	counts := make([]models.TagCount, 0)
+	err := s.db.WithContext(ctx).Table("tags tg").
+		Select("tg.name, COUNT(*) AS article_count").
+		Joins("JOIN article_tags atg ON atg.tag_id = tg.id").
+		Joins("JOIN articles a ON a.id = atg.article_id AND a.deleted_at IS NULL").
+		Joins("JOIN subscriptions s ON s.feed_id = a.feed_id AND s.user_id = tg.user_id").
+		Where("tg.user_id = ?", userID).
+		Group("tg.name").
+		Order("tg.name").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	return counts, nil
+}
+
+// Apply sets Tags on the articles to the names of the user's tags on them
+func (s *Store) Apply(ctx context.Context, userID uint, articles []*models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+	articleIDs := make([]uint, len(articles))
+	for i, article := range articles {
+		articleIDs[i] = article.ID
+	}
+
+	var rows []struct {
+		ArticleID uint
+		Name      string
+	}
+	err := s.db.WithContext(ctx).Table("article_tags atg").
+		Select("atg.article_id, tg.name").
+		Joins("JOIN tags tg ON tg.id = atg.tag_id").
+		Where("tg.user_id = ? AND atg.article_id IN ?", userID, articleIDs).
+		Order("tg.name").
+		Scan(&rows).Error
+	if err != nil {
+		return fmt.Errorf("load tags: %w", err)
+	}
+	byArticle := make(map[uint][]string, len(rows))
+	for _, row := range rows {
+		byArticle[row.ArticleID] = append(byArticle[row.ArticleID], row.Name)
+	}
+	for _, article := range articles {
+		article.Tags = byArticle[article.ID]
+	}
+	return nil
+}
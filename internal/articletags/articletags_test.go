@@ -0,0 +1,75 @@
+package articletags
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+func setupStore(t *testing.T) (*Store, *gorm.DB) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Article{}, &models.Subscription{}, &models.Tag{}, &models.ArticleTag{}))
+	return NewStore(db), db
+}
+
+func TestStore_AddApplyRemove(t *testing.T) {
+	store, db := setupStore(t)
+	ctx := context.Background()
+	first := &models.Article{FeedID: 1, URL: "https://example.com/1"}
+	second := &models.Article{FeedID: 1, URL: "https://example.com/2"}
+	require.NoError(t, db.Create(first).Error)
+	require.NoError(t, db.Create(second).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: 1}).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 2, FeedID: 1}).Error)
+
+	require.NoError(t, store.Add(ctx, 1, first.ID, []string{"golang", "db"}))
+	require.NoError(t, store.Add(ctx, 1, first.ID, []string{"golang"}), "adding a tag twice is a no-op")
+	require.NoError(t, store.Add(ctx, 1, second.ID, []string{"golang"}))
+	require.NoError(t, store.Add(ctx, 2, first.ID, []string{"later"}))
+
+	articles := []*models.Article{first, second}
+	require.NoError(t, store.Apply(ctx, 1, articles))
+	assert.Equal(t, []string{"db", "golang"}, first.Tags)
+	assert.Equal(t, []string{"golang"}, second.Tags)
+
+	counts, err := store.List(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []models.TagCount{{Name: "db", ArticleCount: 1}, {Name: "golang", ArticleCount: 2}}, counts)
+
+	var matched []uint
+	require.NoError(t, db.Model(&models.Article{}).Where(TaggedCondition, 1, "golang").Order("id").Pluck("id", &matched).Error)
+	assert.Equal(t, []uint{first.ID, second.ID}, matched)
+
+	require.NoError(t, store.Remove(ctx, 1, first.ID, []string{"db", "golang", "missing"}))
+	require.NoError(t, store.Apply(ctx, 1, articles))
+	assert.Nil(t, first.Tags)
+	assert.Equal(t, []string{"golang"}, second.Tags)
+
+	var names []string
+	require.NoError(t, db.Model(&models.Tag{}).Where("user_id = ?", 1).Pluck("name", &names).Error)
+	assert.Equal(t, []string{"golang"}, names, "a tag on no article is removed")
+
+	require.NoError(t, store.Apply(ctx, 2, articles))
+	assert.Equal(t, []string{"later"}, first.Tags, "tags are per user")
+}
+
+func TestNormalizeTagNames(t *testing.T) {
+	names, err := models.NormalizeTagNames([]string{" Golang ", "#golang", "db"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"golang", "db"}, names)
+
+	for _, invalid := range []string{"", "#", "two words", "a,b"} {
+		_, err := models.NormalizeTagNames([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,449 @@
+// Package backup dumps the data phoenix-rss owns into a single archive and restores it,
+// so self-hosters can move or recover an instance without pg_dump.
+//
+// An archive is a gzipped tar holding one JSON Lines file per table under tables/, the
+// Redis keys worth keeping in redis.jsonl and a manifest.json written last. The manifest
+// records the format version, the schema version the data was taken at and the row
+// count and SHA-256 of every file, which restore checks before it touches the database.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	// Format identifies phoenix-rss archives in their manifest
+	Format = "phoenix-rss-backup"
+	// FormatVersion is the archive layout written by this version; restore accepts it and
+	// anything older
+	FormatVersion = 1
+
+	manifestName  = "manifest.json"
+	redisFileName = "redis.jsonl"
+	tablesDir     = "tables/"
+)
+
+// Tables lists the tables a backup covers, parents before the tables referencing them so
+// they can be restored in order. Tables missing from the database are skipped.
+var Tables = []string{
+	"users",
+	"feeds",
+	"feed_collections",
+	"feed_collection_feeds",
+	"articles",
+	"folders",
+	"subscriptions",
+	"folder_digests",
+	"user_llm_credentials",
+	"llm_usage",
+	"llm_usage_daily",
+	"notifications",
+	"feed_snapshots",
+	"audit_events",
+	"user_sessions",
+	"subscription_engagement",
+	"article_state_events",
+	"user_article_states",
+	"summary_feedback",
+	"user_preferences",
+	"article_summaries",
+	"tags",
+	"article_tags",
+	"ai_summary_cache",
+	"operator_reports",
+	"article_exports",
+	"outbox_events",
+}
+
+// DefaultRedisPatterns match the Redis keys that are state rather than cache: login
+// lockouts, host circuit breakers and crawl budgets. Caches are rebuilt on demand.
+var DefaultRedisPatterns = []string{"login:*", "host_breaker:*", "crawl_budget:*"}
+
+// ErrInvalidArchive is returned for archives that are damaged, tampered with or not
+// phoenix-rss backups
+var ErrInvalidArchive = errors.New("invalid backup archive")
+
+// Manifest describes the contents of an archive
+type Manifest struct {
+	Format           string       `json:"format"`
+	Version          int          `json:"version"`
+	CreatedAt        time.Time    `json:"created_at"`
+	SchemaVersion    uint         `json:"schema_version"`
+	OnlineMigrations []string     `json:"online_migrations"`
+	Tables           []TableEntry `json:"tables"`
+	Redis            *FileEntry   `json:"redis,omitempty"`
+}
+
+// FileEntry is a file of the archive with the number of records it holds and its checksum
+type FileEntry struct {
+	File   string `json:"file"`
+	Count  int64  `json:"count"`
+	SHA256 string `json:"sha256"`
+}
+
+// TableEntry is the dump of one table
+type TableEntry struct {
+	Name    string   `json:"name"`
+	Columns []Column `json:"columns"`
+	FileEntry
+}
+
+// Column is a dumped column with its database type, which tells restore how to decode
+// values JSON cannot represent directly
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// redisRecord is a line of redis.jsonl: the DUMP payload of a key and its remaining TTL
+type redisRecord struct {
+	Key   string `json:"key"`
+	TTLMs int64  `json:"ttl_ms"`
+	Dump  string `json:"dump"`
+}
+
+// Backup writes and restores archives of a phoenix-rss database and, when set, Redis
+type Backup struct {
+	db            *gorm.DB
+	redis         redis.Cmdable
+	redisPatterns []string
+	now           func() time.Time
+}
+
+func New(db *gorm.DB) *Backup {
+	return &Backup{db: db, now: time.Now}
+}
+
+// SetRedis includes the Redis keys matching patterns in backups and restores them
+func (b *Backup) SetRedis(client redis.Cmdable, patterns []string) {
+	b.redis = client
+	b.redisPatterns = patterns
+}
+
+// Write dumps the database and Redis keys into an archive written to w
+func (b *Backup) Write(ctx context.Context, w io.Writer) (*Manifest, error) {
+	db := b.db.WithContext(ctx)
+
+	schemaVersion, err := schemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	onlineMigrations, err := onlineMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{
+		Format:           Format,
+		Version:          FormatVersion,
+		CreatedAt:        b.now().UTC(),
+		SchemaVersion:    schemaVersion,
+		OnlineMigrations: onlineMigrations,
+		Tables:           make([]TableEntry, 0, len(Tables)),
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	// a snapshot transaction keeps the tables consistent with each other while they are read
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY").Error; err != nil {
+				return err
+			}
+		}
+		for _, table := range Tables {
+			if !tx.Migrator().HasTable(table) {
+				continue
+			}
+			entry, err := b.dumpTable(tx, tw, table)
+			if err != nil {
+				return fmt.Errorf("dump %s: %w", table, err)
+			}
+			manifest.Tables = append(manifest.Tables, *entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if b.redis != nil {
+		entry, err := b.dumpRedis(ctx, tw)
+		if err != nil {
+			return nil, fmt.Errorf("dump redis: %w", err)
+		}
+		manifest.Redis = entry
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeEntry(tw, manifestName, data, manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func (b *Backup) dumpTable(tx *gorm.DB, tw *tar.Writer, table string) (*TableEntry, error) {
+	generated, err := generatedColumns(tx, table)
+	if err != nil {
+		return nil, err
+	}
+	columnTypes, err := tx.Migrator().ColumnTypes(table)
+	if err != nil {
+		return nil, err
+	}
+	entry := &TableEntry{Name: table, FileEntry: FileEntry{File: tablesDir + table + ".jsonl"}}
+	names := make([]string, 0, len(columnTypes))
+	for _, column := range columnTypes {
+		if generated[column.Name()] {
+			continue
+		}
+		entry.Columns = append(entry.Columns, Column{Name: column.Name(), Type: strings.ToUpper(column.DatabaseTypeName())})
+		names = append(names, column.Name())
+	}
+
+	query := tx.Table(table).Select(names)
+	if hasColumn(entry.Columns, "id") {
+		query = query.Order("id")
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	spool, err := newSpool()
+	if err != nil {
+		return nil, err
+	}
+	defer spool.Close()
+
+	values := make([]any, len(entry.Columns))
+	pointers := make([]any, len(values))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		record := make(map[string]any, len(values))
+		for i, column := range entry.Columns {
+			record[column.Name] = encodeValue(column.Type, values[i])
+		}
+		if err := spool.encode(record); err != nil {
+			return nil, err
+		}
+		entry.Count++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	entry.SHA256, err = spool.copyTo(tw, entry.File, b.now())
+	return entry, err
+}
+
+func (b *Backup) dumpRedis(ctx context.Context, tw *tar.Writer) (*FileEntry, error) {
+	spool, err := newSpool()
+	if err != nil {
+		return nil, err
+	}
+	defer spool.Close()
+
+	entry := &FileEntry{File: redisFileName}
+	for _, pattern := range b.redisPatterns {
+		iter := b.redis.Scan(ctx, 0, pattern, 500).Iterator()
+		for iter.Next(ctx) {
+			key := iter.Val()
+			dump, err := b.redis.Dump(ctx, key).Result()
+			if errors.Is(err, redis.Nil) {
+				continue // expired since the scan
+			}
+			if err != nil {
+				return nil, err
+			}
+			ttl, err := b.redis.PTTL(ctx, key).Result()
+			if err != nil {
+				return nil, err
+			}
+			record := redisRecord{Key: key, Dump: base64.StdEncoding.EncodeToString([]byte(dump))}
+			if ttl > 0 {
+				record.TTLMs = ttl.Milliseconds()
+			}
+			if err := spool.encode(record); err != nil {
+				return nil, err
+			}
+			entry.Count++
+		}
+		if err := iter.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	entry.SHA256, err = spool.copyTo(tw, entry.File, b.now())
+	return entry, err
+}
+
+// encodeValue turns a scanned value into one JSON can carry: binary columns become
+// base64, JSON columns are embedded as they are and times keep their full precision
+func encodeValue(columnType string, value any) any {
+	switch v := value.(type) {
+	case []byte:
+		switch {
+		case isBinary(columnType):
+			return base64.StdEncoding.EncodeToString(v)
+		case isJSON(columnType) && json.Valid(v):
+			return json.RawMessage(v)
+		}
+		return string(v)
+	case string:
+		if isJSON(columnType) && json.Valid([]byte(v)) {
+			return json.RawMessage(v)
+		}
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return value
+}
+
+func isBinary(columnType string) bool {
+	return columnType == "BYTEA" || columnType == "BLOB"
+}
+
+func isJSON(columnType string) bool {
+	return columnType == "JSON" || columnType == "JSONB"
+}
+
+func hasColumn(columns []Column, name string) bool {
+	for _, column := range columns {
+		if column.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaVersion returns the version golang-migrate recorded, 0 for databases it never ran on
+func schemaVersion(db *gorm.DB) (uint, error) {
+	if !db.Migrator().HasTable("schema_migrations") {
+		return 0, nil
+	}
+	var row struct {
+		Version uint
+		Dirty   bool
+	}
+	if err := db.Raw("SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&row).Error; err != nil {
+		return 0, err
+	}
+	if row.Dirty {
+		return 0, fmt.Errorf("schema version %d is dirty, finish or fix the migration first", row.Version)
+	}
+	return row.Version, nil
+}
+
+// onlineMigrations returns the IDs of the completed Go migrations (see internal/migrations)
+func onlineMigrations(db *gorm.DB) ([]string, error) {
+	ids := make([]string, 0)
+	if !db.Migrator().HasTable("online_migrations") {
+		return ids, nil
+	}
+	err := db.Table("online_migrations").Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// generatedColumns returns the columns PostgreSQL computes itself, which cannot be inserted
+func generatedColumns(db *gorm.DB, table string) (map[string]bool, error) {
+	generated := make(map[string]bool)
+	if db.Dialector.Name() != "postgres" {
+		return generated, nil
+	}
+	var names []string
+	err := db.Raw(`SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ? AND is_generated = 'ALWAYS'`, table).
+		Scan(&names).Error
+	for _, name := range names {
+		generated[name] = true
+	}
+	return generated, err
+}
+
+// spool buffers an archive file on disk while it is hashed, since tar needs the size of
+// an entry before its content
+type spool struct {
+	file *os.File
+	hash hash.Hash
+	enc  *json.Encoder
+}
+
+func newSpool() (*spool, error) {
+	file, err := os.CreateTemp("", "phoenix-backup-*.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	s := &spool{file: file, hash: sha256.New()}
+	s.enc = json.NewEncoder(io.MultiWriter(file, s.hash))
+	return s, nil
+}
+
+func (s *spool) encode(v any) error {
+	return s.enc.Encode(v)
+}
+
+// copyTo adds the spooled content to the archive and returns its checksum
+func (s *spool) copyTo(tw *tar.Writer, name string, modTime time.Time) (string, error) {
+	size, err := s.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	header := &tar.Header{Name: name, Mode: 0o600, Size: size, ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(tw, s.file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(s.hash.Sum(nil)), nil
+}
+
+func (s *spool) Close() error {
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
@@ -0,0 +1,179 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	usermodels "github.com/Fancu1/phoenix-rss/internal/user-service/models"
+)
+
+func setupBackupDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s_%s?mode=memory&cache=shared&_fk=1", t.Name(), name)
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&usermodels.User{}, &models.Feed{}, &models.Article{}, &models.Folder{},
+		&models.Subscription{}, &models.FeedSnapshot{},
+	))
+	return db
+}
+
+func seed(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	now := time.Date(2026, 10, 1, 12, 30, 0, 123456000, time.UTC)
+	require.NoError(t, db.Create(&usermodels.User{ID: 1, Username: "alice", PasswordHash: "x"}).Error)
+	require.NoError(t, db.Create(&models.Feed{ID: 1, Title: "Feed", URL: "https://example.com/feed"}).Error)
+	require.NoError(t, db.Create(&models.Article{
+		ID: 1, FeedID: 1, Title: "Hello", URL: "https://example.com/a", PublishedAt: now,
+		Topics: models.Topics{"go", "databases"},
+	}).Error)
+	// the child folder gets the lower ID so restore has to insert it before its parent exists
+	parentID := uint(2)
+	require.NoError(t, db.Create(&models.Folder{ID: 2, UserID: 1, Name: "Tech"}).Error)
+	require.NoError(t, db.Create(&models.Folder{ID: 1, UserID: 1, ParentID: &parentID, Name: "Go"}).Error)
+	folderID := uint(1)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: 1, FolderID: &folderID}).Error)
+	require.NoError(t, db.Create(&models.FeedSnapshot{
+		FeedID: 1, StatusCode: 200, Body: []byte{0x1f, 0x8b, 0x00, 0xff}, FetchedAt: now,
+	}).Error)
+}
+
+func writeArchive(t *testing.T, db *gorm.DB) (string, *Manifest) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "backup.tar.gz")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+	manifest, err := New(db).Write(context.Background(), file)
+	require.NoError(t, err)
+	return path, manifest
+}
+
+func TestBackup_RoundTrip(t *testing.T) {
+	source := setupBackupDB(t, "source")
+	seed(t, source)
+	path, manifest := writeArchive(t, source)
+
+	assert.Equal(t, Format, manifest.Format)
+	counts := make(map[string]int64)
+	for _, table := range manifest.Tables {
+		counts[table.Name] = table.Count
+	}
+	assert.Equal(t, map[string]int64{
+		"users": 1, "feeds": 1, "articles": 1, "folders": 2, "subscriptions": 1, "feed_snapshots": 1,
+	}, counts)
+
+	target := setupBackupDB(t, "target")
+	_, err := New(target).Restore(context.Background(), path, RestoreOptions{})
+	require.NoError(t, err)
+
+	var article models.Article
+	require.NoError(t, target.First(&article, 1).Error)
+	assert.Equal(t, "Hello", article.Title)
+	assert.Equal(t, models.Topics{"go", "databases"}, article.Topics)
+	assert.True(t, article.PublishedAt.Equal(time.Date(2026, 10, 1, 12, 30, 0, 123456000, time.UTC)))
+
+	var folder models.Folder
+	require.NoError(t, target.First(&folder, 1).Error)
+	require.NotNil(t, folder.ParentID)
+	assert.Equal(t, uint(2), *folder.ParentID)
+
+	var subscription models.Subscription
+	require.NoError(t, target.First(&subscription).Error)
+	require.NotNil(t, subscription.FolderID)
+	assert.Equal(t, uint(1), *subscription.FolderID)
+
+	var snapshot models.FeedSnapshot
+	require.NoError(t, target.First(&snapshot).Error)
+	assert.Equal(t, []byte{0x1f, 0x8b, 0x00, 0xff}, snapshot.Body)
+}
+
+func TestBackup_RestoreRefusesNonEmptyDatabase(t *testing.T) {
+	source := setupBackupDB(t, "source")
+	seed(t, source)
+	path, _ := writeArchive(t, source)
+
+	target := setupBackupDB(t, "target")
+	require.NoError(t, target.Create(&usermodels.User{ID: 5, Username: "bob", PasswordHash: "x"}).Error)
+
+	_, err := New(target).Restore(context.Background(), path, RestoreOptions{})
+	require.ErrorIs(t, err, ErrNotEmpty)
+	assert.Contains(t, err.Error(), "users")
+
+	_, err = New(target).Restore(context.Background(), path, RestoreOptions{Replace: true})
+	require.NoError(t, err)
+	var usernames []string
+	require.NoError(t, target.Model(&usermodels.User{}).Pluck("username", &usernames).Error)
+	assert.Equal(t, []string{"alice"}, usernames)
+}
+
+func TestBackup_RestoreChecksSchemaVersion(t *testing.T) {
+	source := setupBackupDB(t, "source")
+	require.NoError(t, source.Exec("CREATE TABLE schema_migrations (version BIGINT, dirty BOOLEAN)").Error)
+	require.NoError(t, source.Exec("INSERT INTO schema_migrations VALUES (33, false)").Error)
+	path, manifest := writeArchive(t, source)
+	assert.Equal(t, uint(33), manifest.SchemaVersion)
+
+	target := setupBackupDB(t, "target")
+	require.NoError(t, target.Exec("CREATE TABLE schema_migrations (version BIGINT, dirty BOOLEAN)").Error)
+	require.NoError(t, target.Exec("INSERT INTO schema_migrations VALUES (32, false)").Error)
+
+	_, err := New(target).Restore(context.Background(), path, RestoreOptions{})
+	require.ErrorIs(t, err, ErrSchemaMismatch)
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	source := setupBackupDB(t, "source")
+	seed(t, source)
+	path, _ := writeArchive(t, source)
+
+	// rewrite the archive with one row of the users table altered
+	tampered := filepath.Join(t.TempDir(), "tampered.tar.gz")
+	in, err := os.Open(path)
+	require.NoError(t, err)
+	defer in.Close()
+	var out bytes.Buffer
+	gzw := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gzw)
+	require.NoError(t, walkArchive(in, func(name string, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if name == "tables/users.jsonl" {
+			data = bytes.Replace(data, []byte("alice"), []byte("mallory"), 1)
+		}
+		return writeEntry(tw, name, data, time.Now())
+	}))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+	require.NoError(t, os.WriteFile(tampered, out.Bytes(), 0o600))
+
+	_, err = Verify(path)
+	require.NoError(t, err)
+	_, err = Verify(tampered)
+	require.ErrorIs(t, err, ErrInvalidArchive)
+	assert.Contains(t, err.Error(), "tables/users.jsonl")
+
+	target := setupBackupDB(t, "target")
+	_, err = New(target).Restore(context.Background(), tampered, RestoreOptions{})
+	require.ErrorIs(t, err, ErrInvalidArchive)
+	var count int64
+	require.NoError(t, target.Model(&usermodels.User{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
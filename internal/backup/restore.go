@@ -0,0 +1,426 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// insertBatchSize bounds the rows of one INSERT statement during restore
+const insertBatchSize = 500
+
+var (
+	// ErrSchemaMismatch is returned when the target database is at another schema version
+	// than the archive was taken at
+	ErrSchemaMismatch = errors.New("backup schema does not match the database")
+	// ErrNotEmpty is returned when restoring into tables that already hold rows
+	ErrNotEmpty = errors.New("database is not empty")
+)
+
+// selfReferences names the columns pointing at rows of their own table. They are restored
+// as NULL and filled in once every row of the table exists.
+var selfReferences = map[string]string{
+	"folders": "parent_id",
+}
+
+// RestoreOptions control how an archive is restored
+type RestoreOptions struct {
+	// Replace deletes the rows of the restored tables first; without it restore refuses
+	// to write into tables that are not empty
+	Replace bool
+}
+
+// Verify checks that the archive at path is a phoenix-rss backup this version can read and
+// that every file matches the row count and checksum in its manifest
+func Verify(path string) (*Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	type digest struct {
+		sha256 string
+		lines  int64
+	}
+	digests := make(map[string]digest)
+	var manifest *Manifest
+
+	err = walkArchive(file, func(name string, r io.Reader) error {
+		if name == manifestName {
+			manifest = &Manifest{}
+			if err := json.NewDecoder(r).Decode(manifest); err != nil {
+				return fmt.Errorf("%w: read manifest: %v", ErrInvalidArchive, err)
+			}
+			return nil
+		}
+		h := sha256.New()
+		lines, err := countLines(io.TeeReader(r, h))
+		if err != nil {
+			return err
+		}
+		digests[name] = digest{sha256: hex.EncodeToString(h.Sum(nil)), lines: lines}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: no %s", ErrInvalidArchive, manifestName)
+	}
+	if manifest.Format != Format {
+		return nil, fmt.Errorf("%w: format %q", ErrInvalidArchive, manifest.Format)
+	}
+	if manifest.Version < 1 || manifest.Version > FormatVersion {
+		return nil, fmt.Errorf("%w: format version %d, this version reads up to %d", ErrInvalidArchive, manifest.Version, FormatVersion)
+	}
+
+	entries := make([]FileEntry, 0, len(manifest.Tables)+1)
+	for _, table := range manifest.Tables {
+		if !slices.Contains(Tables, table.Name) || table.File != tablesDir+table.Name+".jsonl" {
+			return nil, fmt.Errorf("%w: unknown table %q", ErrInvalidArchive, table.Name)
+		}
+		entries = append(entries, table.FileEntry)
+	}
+	if manifest.Redis != nil {
+		entries = append(entries, *manifest.Redis)
+	}
+	for _, entry := range entries {
+		got, ok := digests[entry.File]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is missing", ErrInvalidArchive, entry.File)
+		}
+		if got.sha256 != entry.SHA256 || got.lines != entry.Count {
+			return nil, fmt.Errorf("%w: %s does not match its checksum", ErrInvalidArchive, entry.File)
+		}
+		delete(digests, entry.File)
+	}
+	for name := range digests {
+		return nil, fmt.Errorf("%w: unexpected file %s", ErrInvalidArchive, name)
+	}
+	return manifest, nil
+}
+
+// Restore verifies the archive at path and loads it in one transaction. Redis keys are
+// restored after the database commits, replacing keys of the same name.
+func (b *Backup) Restore(ctx context.Context, path string, opts RestoreOptions) (*Manifest, error) {
+	manifest, err := Verify(path)
+	if err != nil {
+		return nil, err
+	}
+	db := b.db.WithContext(ctx)
+	if err := checkSchema(db, manifest); err != nil {
+		return nil, err
+	}
+
+	tables := make(map[string]*TableEntry, len(manifest.Tables))
+	for i := range manifest.Tables {
+		tables[manifest.Tables[i].File] = &manifest.Tables[i]
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var redisRecords []redisRecord
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := prepareTables(tx, manifest, opts.Replace); err != nil {
+			return err
+		}
+		return walkArchive(file, func(name string, r io.Reader) error {
+			if table, ok := tables[name]; ok {
+				if err := restoreTable(tx, table, r); err != nil {
+					return fmt.Errorf("restore %s: %w", table.Name, err)
+				}
+				return nil
+			}
+			if name == redisFileName && b.redis != nil {
+				return decodeLines(r, func(line []byte) error {
+					var record redisRecord
+					if err := json.Unmarshal(line, &record); err != nil {
+						return err
+					}
+					redisRecords = append(redisRecords, record)
+					return nil
+				})
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range redisRecords {
+		dump, err := base64.StdEncoding.DecodeString(record.Dump)
+		if err != nil {
+			return nil, fmt.Errorf("restore redis key %s: %w", record.Key, err)
+		}
+		ttl := time.Duration(record.TTLMs) * time.Millisecond
+		if err := b.redis.RestoreReplace(ctx, record.Key, ttl, string(dump)).Err(); err != nil {
+			return nil, fmt.Errorf("restore redis key %s: %w", record.Key, err)
+		}
+	}
+	return manifest, nil
+}
+
+// checkSchema makes sure the database was migrated as far as the archive: the same SQL
+// schema version and every online migration the archive had completed
+func checkSchema(db *gorm.DB, manifest *Manifest) error {
+	version, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version != manifest.SchemaVersion {
+		return fmt.Errorf("%w: archive is at schema version %d, database at %d; run the migrator for that version first",
+			ErrSchemaMismatch, manifest.SchemaVersion, version)
+	}
+	completed, err := onlineMigrations(db)
+	if err != nil {
+		return err
+	}
+	for _, id := range manifest.OnlineMigrations {
+		if !slices.Contains(completed, id) {
+			return fmt.Errorf("%w: online migration %s has not run on the database", ErrSchemaMismatch, id)
+		}
+	}
+	return nil
+}
+
+// prepareTables checks the restored tables exist and are empty, or empties them when
+// replace is set, children first
+func prepareTables(tx *gorm.DB, manifest *Manifest, replace bool) error {
+	var occupied []string
+	for i := len(manifest.Tables) - 1; i >= 0; i-- {
+		table := manifest.Tables[i].Name
+		if !tx.Migrator().HasTable(table) {
+			return fmt.Errorf("%w: table %s does not exist", ErrSchemaMismatch, table)
+		}
+		if replace {
+			if err := tx.Exec("DELETE FROM " + tx.Statement.Quote(table)).Error; err != nil {
+				return fmt.Errorf("empty %s: %w", table, err)
+			}
+			continue
+		}
+		var count int64
+		if err := tx.Table(table).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			occupied = append(occupied, table)
+		}
+	}
+	if len(occupied) > 0 {
+		slices.Reverse(occupied)
+		return fmt.Errorf("%w: %s already hold rows, restore with replace to overwrite them",
+			ErrNotEmpty, strings.Join(occupied, ", "))
+	}
+	return nil
+}
+
+func restoreTable(tx *gorm.DB, table *TableEntry, r io.Reader) error {
+	if err := checkColumns(tx, table); err != nil {
+		return err
+	}
+
+	selfRef := selfReferences[table.Name]
+	type reference struct{ id, parent any }
+	var references []reference
+
+	batch := make([]map[string]any, 0, insertBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := tx.Table(table.Name).Create(&batch).Error
+		batch = batch[:0]
+		return err
+	}
+
+	err := decodeLines(r, func(line []byte) error {
+		record, err := decodeRecord(table.Columns, line)
+		if err != nil {
+			return err
+		}
+		if selfRef != "" && record[selfRef] != nil {
+			references = append(references, reference{id: record["id"], parent: record[selfRef]})
+			record[selfRef] = nil
+		}
+		batch = append(batch, record)
+		if len(batch) == insertBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	for _, ref := range references {
+		if err := tx.Table(table.Name).Where("id = ?", ref.id).Update(selfRef, ref.parent).Error; err != nil {
+			return err
+		}
+	}
+	return resetSequence(tx, table)
+}
+
+// checkColumns refuses archives with columns the table does not have, so a schema drift
+// the version check missed fails loudly instead of dropping data
+func checkColumns(tx *gorm.DB, table *TableEntry) error {
+	columnTypes, err := tx.Migrator().ColumnTypes(table.Name)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(columnTypes))
+	for _, column := range columnTypes {
+		existing[column.Name()] = true
+	}
+	for _, column := range table.Columns {
+		if !existing[column.Name] {
+			return fmt.Errorf("%w: column %s.%s does not exist", ErrSchemaMismatch, table.Name, column.Name)
+		}
+	}
+	return nil
+}
+
+// decodeRecord reverses encodeValue for one row
+func decodeRecord(columns []Column, line []byte) (map[string]any, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return nil, err
+	}
+
+	record := make(map[string]any, len(columns))
+	for _, column := range columns {
+		value, ok := raw[column.Name]
+		if !ok || bytes.Equal(value, []byte("null")) {
+			record[column.Name] = nil
+			continue
+		}
+		if isJSON(column.Type) {
+			record[column.Name] = string(value)
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(value))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("column %s: %w", column.Name, err)
+		}
+		switch v := v.(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				record[column.Name] = n
+			} else if f, err := v.Float64(); err == nil {
+				record[column.Name] = f
+			} else {
+				return nil, fmt.Errorf("column %s: %w", column.Name, err)
+			}
+		case string:
+			if !isBinary(column.Type) {
+				record[column.Name] = v
+				continue
+			}
+			data, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", column.Name, err)
+			}
+			record[column.Name] = data
+		default:
+			record[column.Name] = v
+		}
+	}
+	return record, nil
+}
+
+// resetSequence moves the id sequence of a PostgreSQL table past the restored rows
+func resetSequence(tx *gorm.DB, table *TableEntry) error {
+	if tx.Dialector.Name() != "postgres" || !hasColumn(table.Columns, "id") {
+		return nil
+	}
+	quoted := tx.Statement.Quote(table.Name)
+	return tx.Exec(fmt.Sprintf(
+		"SELECT setval(pg_get_serial_sequence(?, 'id'), COALESCE(MAX(id), 1), MAX(id) IS NOT NULL) FROM %s", quoted),
+		table.Name).Error
+}
+
+// walkArchive calls fn with the name and content of every regular file in a gzipped tar
+func walkArchive(r io.Reader, fn func(name string, r io.Reader) error) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(header.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeLines calls fn with every line of a JSON Lines file
+func decodeLines(r io.Reader, fn func(line []byte) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if fnErr := fn(line); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func countLines(r io.Reader) (int64, error) {
+	var lines int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		lines += int64(bytes.Count(buf[:n], []byte{'\n'}))
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+	}
+}
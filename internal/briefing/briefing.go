@@ -0,0 +1,408 @@
+// Package briefing writes "catch me up" briefings: the headlines a reader has not read
+// since a given time, grouped by the LLM into topics with their key stories. The
+// api-service generates them on demand with the instance's LLM settings, or with the
+// reader's own key when they brought one.
+package briefing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/client"
+	aimodels "github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/readstate"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// cacheKeyPattern is the key of a cached briefing by user, since time, language and hour
+const cacheKeyPattern = "briefing:%d:%d:%s:%d"
+
+// Headline is an unread article that goes into a briefing
+type Headline struct {
+	ArticleID   uint
+	FeedTitle   string
+	Title       string
+	URL         string
+	PublishedAt time.Time
+}
+
+// Story is a headline the LLM picked as a key story of a topic
+type Story struct {
+	ArticleID   uint      `json:"article_id"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	FeedTitle   string    `json:"feed_title"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Topic groups the key stories on one subject
+type Topic struct {
+	Name    string  `json:"name"`
+	Summary string  `json:"summary"`
+	Stories []Story `json:"stories"`
+}
+
+// Briefing is what a reader missed since a time, most important topic first
+type Briefing struct {
+	Since       time.Time `json:"since"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Headlines counts the unread articles published since Since. Only the newest are
+	// briefed; Omitted counts the older ones left out.
+	Headlines int64   `json:"headlines"`
+	Omitted   int64   `json:"omitted"`
+	Topics    []Topic `json:"topics"`
+	Model     string  `json:"model,omitempty"`
+	Language  string  `json:"language,omitempty"`
+	// Cached is set when the briefing was generated earlier in the hour
+	Cached bool `json:"cached"`
+}
+
+// Store reads the headlines of a briefing
+type Store struct {
+	db *gorm.DB
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// UnreadHeadlines returns the newest limit articles of the user's subscribed feeds
+// published since the time that the user has not read, and how many there are in all.
+// With folderIDs, only the feeds filed in those folders count. Feeds are named by the
+// user's custom title when they set one.
+func (s *Store) UnreadHeadlines(ctx context.Context, userID uint, since time.Time, folderIDs []uint, limit int) ([]Headline, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.Article{}).
+		Joins("JOIN subscriptions ON subscriptions.feed_id = articles.feed_id AND subscriptions.user_id = ?", userID).
+		Joins("JOIN feeds ON feeds.id = articles.feed_id").
+		Where("articles.published_at >= ?", since).
+		Where(readstate.UnreadCondition, userID)
+	if len(folderIDs) > 0 {
+		query = query.Where("subscriptions.folder_id IN ?", folderIDs)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	var headlines []Headline
+	err := query.
+		Select("articles.id AS article_id, COALESCE(subscriptions.custom_title, feeds.title) AS feed_title, articles.title, articles.url, articles.published_at").
+		Order("articles.published_at DESC, articles.id DESC").
+		Limit(limit).
+		Scan(&headlines).Error
+	return headlines, total, err
+}
+
+// UserCredential returns the LLM key the user brought, nil when they have none
+func (s *Store) UserCredential(ctx context.Context, userID uint) (*aimodels.UserLLMCredential, error) {
+	var credential aimodels.UserLLMCredential
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Take(&credential).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// Completer is the LLM client a briefing is written with
+type Completer interface {
+	Complete(ctx context.Context, prompt string, maxTokens int) (*client.Completion, error)
+	GetModel() string
+}
+
+// Options tune the briefings of a Service
+type Options struct {
+	// MaxHeadlines caps the headlines sent to the LLM, the newest first
+	MaxHeadlines int
+	// MaxTokens caps the LLM's answer
+	MaxTokens int
+	// DefaultLanguage is the language code briefings are written in when the reader asks
+	// for none; empty leaves it to the LLM
+	DefaultLanguage string
+}
+
+// Request asks for the briefing of a user since a time
+type Request struct {
+	UserID   uint
+	Since    time.Time
+	Language string // language code; empty for the default language
+	// Refresh skips the cache and replaces the cached briefing
+	Refresh bool
+	// FolderIDs limits the briefing to the feeds filed in these folders. Such briefings
+	// are written for digests, once per send time, and are not cached.
+	FolderIDs []uint
+}
+
+// Decrypter decrypts the API keys users brought, which are stored encrypted
+type Decrypter interface {
+	Decrypt(ciphertext string) (string, error)
+}
+
+// Service writes briefings
+type Service struct {
+	store *Store
+	llm   Completer
+	opts  Options
+	cache redis.Cmdable
+	now   func() time.Time
+	// decrypter and withKey, when set, write briefings with the reader's own key
+	decrypter Decrypter
+	withKey   func(client.Credentials) Completer
+	// usage and cost, when set, record each briefing's LLM request
+	usage UsageLedger
+	cost  func(model string, usage client.Usage) float64
+}
+
+// UsageLedger records the LLM requests made for accounting, such as the ai-service's
+// repository.CredentialRepository
+type UsageLedger interface {
+	RecordUsage(ctx context.Context, usage *aimodels.LLMUsage) error
+}
+
+func NewService(store *Store, llm Completer, opts Options) *Service {
+	return &Service{store: store, llm: llm, opts: opts, now: time.Now}
+}
+
+// SetCache keeps each briefing until the end of the hour, so asking again for the same
+// time within the hour costs no LLM call
+func (s *Service) SetCache(cache redis.Cmdable) {
+	s.cache = cache
+}
+
+// UseUserKeys writes the briefing of a reader who brought their own LLM key with that
+// key, through the client withKey returns. Only the reader sees their briefing, unlike
+// article summaries, which every subscriber shares and the instance key always writes.
+func (s *Service) UseUserKeys(decrypter Decrypter, withKey func(client.Credentials) Completer) {
+	s.decrypter = decrypter
+	s.withKey = withKey
+}
+
+// UseUsageLedger records the LLM request of every briefing in the ledger for the reader
+// it was written for, marked byok when written with their own key; cost estimates its
+// cost in USD
+func (s *Service) UseUsageLedger(ledger UsageLedger, cost func(model string, usage client.Usage) float64) {
+	s.usage = ledger
+	s.cost = cost
+}
+
+// completerFor returns the LLM client the user's briefing is written with, the instance
+// one when they brought no key or theirs cannot be read, and whether it is their own
+func (s *Service) completerFor(ctx context.Context, userID uint) (Completer, bool) {
+	if s.decrypter == nil || s.withKey == nil {
+		return s.llm, false
+	}
+	log := logger.FromContext(ctx)
+	credential, err := s.store.UserCredential(ctx, userID)
+	if err != nil {
+		log.Warn("failed to read the user's LLM key, using the instance key", "user_id", userID, "error", err)
+		return s.llm, false
+	}
+	if credential == nil {
+		return s.llm, false
+	}
+	apiKey, err := s.decrypter.Decrypt(credential.APIKeyCiphertext)
+	if err != nil {
+		log.Warn("failed to decrypt the user's LLM key, using the instance key", "user_id", userID, "error", err)
+		return s.llm, false
+	}
+	return s.withKey(client.Credentials{BaseURL: credential.BaseURL, APIKey: apiKey, Model: credential.Model}), true
+}
+
+// recordUsage writes a usage ledger entry for the user; failures are logged and never
+// fail the briefing
+func (s *Service) recordUsage(ctx context.Context, userID uint, model string, byok bool, usage client.Usage) {
+	if s.usage == nil {
+		return
+	}
+	entry := &aimodels.LLMUsage{
+		UserID:           &userID,
+		Model:            model,
+		BYOK:             byok,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+	if s.cost != nil {
+		entry.CostUSD = s.cost(model, usage)
+	}
+	if err := s.usage.RecordUsage(ctx, entry); err != nil {
+		logger.FromContext(ctx).Warn("failed to record LLM usage", "user_id", userID, "error", err)
+	}
+}
+
+// Generate writes the briefing of the headlines the user has not read since req.Since.
+// Without any the briefing has no topics and the LLM is not asked.
+func (s *Service) Generate(ctx context.Context, req Request) (*Briefing, error) {
+	log := logger.FromContext(ctx)
+	now := s.now().UTC()
+	language := req.Language
+	if language == "" {
+		language = s.opts.DefaultLanguage
+	}
+
+	key := fmt.Sprintf(cacheKeyPattern, req.UserID, req.Since.Unix(), language, now.Truncate(time.Hour).Unix())
+	cacheable := len(req.FolderIDs) == 0
+	if cacheable && !req.Refresh {
+		if cached := s.cached(ctx, key); cached != nil {
+			return cached, nil
+		}
+	}
+
+	headlines, total, err := s.store.UnreadHeadlines(ctx, req.UserID, req.Since, req.FolderIDs, s.opts.MaxHeadlines)
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to list unread headlines of user %d: %w", req.UserID, err))
+	}
+	briefing := &Briefing{
+		Since:       req.Since.UTC(),
+		GeneratedAt: now,
+		Headlines:   total,
+		Omitted:     total - int64(len(headlines)),
+		Topics:      []Topic{},
+		Language:    language,
+	}
+	if len(headlines) == 0 {
+		return briefing, nil
+	}
+
+	llm, byok := s.completerFor(ctx, req.UserID)
+	completion, err := llm.Complete(ctx, renderPrompt(headlines, language), s.opts.MaxTokens)
+	if err != nil {
+		return nil, ierr.ErrBriefingFailed.WithCause(fmt.Errorf("briefing of user %d: %w", req.UserID, err))
+	}
+	s.recordUsage(ctx, req.UserID, llm.GetModel(), byok, completion.Usage)
+	if completion.Truncated {
+		log.Warn("briefing was truncated at the token limit", "user_id", req.UserID, "max_tokens", s.opts.MaxTokens)
+	}
+	if briefing.Topics, err = parseTopics(completion.Text, headlines); err != nil {
+		return nil, ierr.ErrBriefingFailed.WithCause(fmt.Errorf("briefing of user %d: %w", req.UserID, err))
+	}
+	briefing.Model = llm.GetModel()
+
+	log.Info("generated briefing",
+		"headlines", len(headlines),
+		"topics", len(briefing.Topics),
+		"prompt_tokens", completion.Usage.PromptTokens,
+		"completion_tokens", completion.Usage.CompletionTokens,
+	)
+	if cacheable {
+		s.save(ctx, key, briefing, now.Truncate(time.Hour).Add(time.Hour).Sub(now))
+	}
+	return briefing, nil
+}
+
+// cached returns the cached briefing under key, nil when there is none
+func (s *Service) cached(ctx context.Context, key string) *Briefing {
+	if s.cache == nil {
+		return nil
+	}
+	data, err := s.cache.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			logger.FromContext(ctx).Warn("failed to read cached briefing", "error", err.Error())
+		}
+		return nil
+	}
+	var briefing Briefing
+	if err := json.Unmarshal(data, &briefing); err != nil {
+		return nil
+	}
+	briefing.Cached = true
+	return &briefing
+}
+
+func (s *Service) save(ctx context.Context, key string, briefing *Briefing, ttl time.Duration) {
+	if s.cache == nil {
+		return
+	}
+	data, err := json.Marshal(briefing)
+	if err != nil {
+		return
+	}
+	if err := s.cache.Set(ctx, key, data, ttl).Err(); err != nil {
+		logger.FromContext(ctx).Warn("failed to cache briefing", "error", err.Error())
+	}
+}
+
+// renderPrompt asks for the topics of the numbered headlines as JSON
+func renderPrompt(headlines []Headline, language string) string {
+	var b strings.Builder
+	b.WriteString("You are preparing a short news briefing for a reader catching up on their feeds.\n")
+	b.WriteString("Group the numbered headlines below into a few topics, the most important first. ")
+	b.WriteString("For each topic write a one or two sentence summary and list the numbers of its key stories. ")
+	b.WriteString("Leave out headlines that matter little.")
+	if language != "" {
+		fmt.Fprintf(&b, " Write the topic names and summaries in the language with the code %q.", language)
+	}
+	b.WriteString("\n\nAnswer with JSON only, in this form:\n")
+	b.WriteString(`{"topics": [{"name": "...", "summary": "...", "stories": [1, 4]}]}`)
+	b.WriteString("\n\nHeadlines:\n")
+	for i, headline := range headlines {
+		fmt.Fprintf(&b, "%d. [%s] %s\n", i+1, headline.FeedTitle, headline.Title)
+	}
+	return b.String()
+}
+
+// parseTopics reads the topics of the LLM's answer. Stories refer to the headlines by
+// number, so links always come from the articles rather than the model; numbers that
+// match no headline are dropped, and so are topics left without stories.
+func parseTopics(text string, headlines []Headline) ([]Topic, error) {
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in LLM answer")
+	}
+	var answer struct {
+		Topics []struct {
+			Name    string            `json:"name"`
+			Summary string            `json:"summary"`
+			Stories []json.RawMessage `json:"stories"`
+		} `json:"topics"`
+	}
+	if err := json.Unmarshal([]byte(text[start:end+1]), &answer); err != nil {
+		return nil, fmt.Errorf("failed to parse LLM answer: %w", err)
+	}
+
+	topics := make([]Topic, 0, len(answer.Topics))
+	for _, t := range answer.Topics {
+		topic := Topic{Name: strings.TrimSpace(t.Name), Summary: strings.TrimSpace(t.Summary)}
+		seen := make(map[int]bool, len(t.Stories))
+		for _, raw := range t.Stories {
+			n, err := strconv.Atoi(strings.Trim(string(raw), `"`))
+			if err != nil || n < 1 || n > len(headlines) || seen[n] {
+				continue
+			}
+			seen[n] = true
+			h := headlines[n-1]
+			topic.Stories = append(topic.Stories, Story{
+				ArticleID:   h.ArticleID,
+				Title:       h.Title,
+				URL:         h.URL,
+				FeedTitle:   h.FeedTitle,
+				PublishedAt: h.PublishedAt,
+			})
+		}
+		if topic.Name != "" && len(topic.Stories) > 0 {
+			topics = append(topics, topic)
+		}
+	}
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("no topics in LLM answer")
+	}
+	return topics, nil
+}
@@ -0,0 +1,235 @@
+package briefing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/ai-service/client"
+	aimodels "github.com/Fancu1/phoenix-rss/internal/ai-service/models"
+	airepository "github.com/Fancu1/phoenix-rss/internal/ai-service/repository"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	usercore "github.com/Fancu1/phoenix-rss/internal/user-service/core"
+	usermodels "github.com/Fancu1/phoenix-rss/internal/user-service/models"
+	userrepository "github.com/Fancu1/phoenix-rss/internal/user-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+type fakeCompleter struct {
+	text    string
+	usage   client.Usage
+	err     error
+	prompts []string
+}
+
+func (f *fakeCompleter) Complete(_ context.Context, prompt string, _ int) (*client.Completion, error) {
+	f.prompts = append(f.prompts, prompt)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &client.Completion{Text: f.text, Usage: f.usage}, nil
+}
+
+func (f *fakeCompleter) GetModel() string {
+	return "gpt-test"
+}
+
+func setupStore(t *testing.T) (*Store, time.Time) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Feed{}, &models.Article{}, &models.Subscription{}, &models.UserArticleState{}))
+
+	feeds := []*models.Feed{
+		{Title: "Tech Daily", URL: "https://tech.example.com"},
+		{Title: "Renamed", URL: "https://renamed.example.com"},
+		{Title: "Other", URL: "https://other.example.com"},
+	}
+	require.NoError(t, db.Create(feeds).Error)
+	customTitle := "My Science"
+	require.NoError(t, db.Create([]*models.Subscription{
+		{UserID: 1, FeedID: feeds[0].ID},
+		{UserID: 1, FeedID: feeds[1].ID, CustomTitle: &customTitle},
+	}).Error)
+
+	since := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	articles := []*models.Article{
+		{FeedID: feeds[0].ID, Title: "Chip launch", URL: "https://tech.example.com/1", PublishedAt: since.Add(3 * time.Hour)},
+		{FeedID: feeds[1].ID, Title: "Comet sighted", URL: "https://renamed.example.com/1", PublishedAt: since.Add(2 * time.Hour)},
+		{FeedID: feeds[0].ID, Title: "Already read", URL: "https://tech.example.com/2", PublishedAt: since.Add(time.Hour)},
+		{FeedID: feeds[0].ID, Title: "Too old", URL: "https://tech.example.com/3", PublishedAt: since.Add(-time.Hour)},
+		{FeedID: feeds[2].ID, Title: "Not subscribed", URL: "https://other.example.com/1", PublishedAt: since.Add(time.Hour)},
+	}
+	require.NoError(t, db.Create(articles).Error)
+	require.NoError(t, db.Create(&models.UserArticleState{UserID: 1, ArticleID: articles[2].ID, Read: true}).Error)
+	return NewStore(db), since
+}
+
+func TestStore_UnreadHeadlines(t *testing.T) {
+	store, since := setupStore(t)
+	ctx := context.Background()
+
+	headlines, total, err := store.UnreadHeadlines(ctx, 1, since, nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, headlines, 2)
+	assert.Equal(t, "Chip launch", headlines[0].Title)
+	assert.Equal(t, "Tech Daily", headlines[0].FeedTitle)
+	assert.Equal(t, "Comet sighted", headlines[1].Title)
+	assert.Equal(t, "My Science", headlines[1].FeedTitle, "the custom title names the feed")
+
+	headlines, total, err = store.UnreadHeadlines(ctx, 1, since, nil, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total, "the total counts headlines past the limit")
+	require.Len(t, headlines, 1)
+	assert.Equal(t, "Chip launch", headlines[0].Title, "the newest are kept")
+}
+
+func TestService_Generate(t *testing.T) {
+	store, since := setupStore(t)
+	ctx := context.Background()
+
+	llm := &fakeCompleter{text: "```json\n" + `{"topics": [
+		{"name": "Space", "summary": "A comet is visible.", "stories": [2, 2, 9]},
+		{"name": "Empty", "summary": "Nothing.", "stories": [7]},
+		{"name": "Hardware", "summary": "A new chip.", "stories": ["1"]}
+	]}` + "\n```"}
+	service := NewService(store, llm, Options{MaxHeadlines: 10, DefaultLanguage: "en"})
+
+	briefing, err := service.Generate(ctx, Request{UserID: 1, Since: since})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), briefing.Headlines)
+	assert.Zero(t, briefing.Omitted)
+	assert.Equal(t, "gpt-test", briefing.Model)
+	assert.Equal(t, "en", briefing.Language)
+	require.Len(t, briefing.Topics, 2, "topics without known stories are dropped")
+	assert.Equal(t, "Space", briefing.Topics[0].Name)
+	require.Len(t, briefing.Topics[0].Stories, 1, "unknown and repeated numbers are dropped")
+	assert.Equal(t, "https://renamed.example.com/1", briefing.Topics[0].Stories[0].URL)
+	assert.Equal(t, "Chip launch", briefing.Topics[1].Stories[0].Title)
+
+	require.Len(t, llm.prompts, 1)
+	assert.Contains(t, llm.prompts[0], "1. [Tech Daily] Chip launch\n2. [My Science] Comet sighted\n")
+	assert.Contains(t, llm.prompts[0], `language with the code "en"`)
+}
+
+type prefixDecrypter struct{}
+
+func (prefixDecrypter) Decrypt(ciphertext string) (string, error) {
+	if ciphertext == "corrupt" {
+		return "", errors.New("bad ciphertext")
+	}
+	return "plain-" + ciphertext, nil
+}
+
+func TestService_GenerateWithUserKey(t *testing.T) {
+	store, since := setupStore(t)
+	ctx := context.Background()
+	require.NoError(t, store.db.AutoMigrate(&aimodels.UserLLMCredential{}))
+
+	answer := `{"topics": [{"name": "Space", "summary": "A comet.", "stories": [2]}]}`
+	instance := &fakeCompleter{text: answer}
+	own := &fakeCompleter{text: answer}
+	var used []client.Credentials
+	service := NewService(store, instance, Options{MaxHeadlines: 10})
+	service.UseUserKeys(prefixDecrypter{}, func(creds client.Credentials) Completer {
+		used = append(used, creds)
+		return own
+	})
+
+	_, err := service.Generate(ctx, Request{UserID: 1, Since: since})
+	require.NoError(t, err)
+	assert.Len(t, instance.prompts, 1, "a reader without a key gets the instance key")
+	assert.Empty(t, used)
+
+	require.NoError(t, store.db.Create(&aimodels.UserLLMCredential{UserID: 1, BaseURL: "https://llm.example.com/v1", Model: "own-model", APIKeyCiphertext: "abc"}).Error)
+	_, err = service.Generate(ctx, Request{UserID: 1, Since: since, Refresh: true})
+	require.NoError(t, err)
+	assert.Len(t, own.prompts, 1)
+	assert.Equal(t, []client.Credentials{{BaseURL: "https://llm.example.com/v1", APIKey: "plain-abc", Model: "own-model"}}, used)
+
+	require.NoError(t, store.db.Model(&aimodels.UserLLMCredential{}).Where("user_id = ?", 1).Update("api_key_ciphertext", "corrupt").Error)
+	_, err = service.Generate(ctx, Request{UserID: 1, Since: since, Refresh: true})
+	require.NoError(t, err)
+	assert.Len(t, instance.prompts, 2, "an unreadable key falls back to the instance key")
+}
+
+// TestService_GenerateRecordsUsage checks that briefings show in the LLM usage users get
+// from GET /users/me/llm-usage, which the user-service sums from the ledger
+func TestService_GenerateRecordsUsage(t *testing.T) {
+	store, since := setupStore(t)
+	ctx := context.Background()
+	require.NoError(t, store.db.AutoMigrate(&aimodels.UserLLMCredential{}, &aimodels.LLMUsage{}, &aimodels.LLMUsageDaily{}))
+
+	answer := `{"topics": [{"name": "Space", "summary": "A comet.", "stories": [2]}]}`
+	instance := &fakeCompleter{text: answer, usage: client.Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120}}
+	own := &fakeCompleter{text: answer, usage: client.Usage{PromptTokens: 300, CompletionTokens: 40, TotalTokens: 340}}
+	service := NewService(store, instance, Options{MaxHeadlines: 10})
+	service.UseUserKeys(prefixDecrypter{}, func(client.Credentials) Completer { return own })
+	service.UseUsageLedger(airepository.NewCredentialRepository(store.db), func(_ string, usage client.Usage) float64 {
+		return float64(usage.TotalTokens) / 1000
+	})
+
+	_, err := service.Generate(ctx, Request{UserID: 1, Since: since})
+	require.NoError(t, err)
+	require.NoError(t, store.db.Create(&aimodels.UserLLMCredential{UserID: 1, Model: "gpt-test", APIKeyCiphertext: "abc"}).Error)
+	_, err = service.Generate(ctx, Request{UserID: 1, Since: since, Refresh: true})
+	require.NoError(t, err)
+
+	credentials := usercore.NewCredentialService(userrepository.NewCredentialRepository(store.db), nil)
+	usage, err := credentials.GetLLMUsage(1, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []usermodels.LLMUsageSummary{
+		{Model: "gpt-test", BYOK: true, Requests: 1, PromptTokens: 300, CompletionTokens: 40, TotalTokens: 340},
+		{Model: "gpt-test", BYOK: false, Requests: 1, PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120},
+	}, usage)
+
+	// only the instance key's briefing counts against the instance budget
+	cost, tokens, err := airepository.NewCredentialRepository(store.db).InstanceUsageOn(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(120), tokens)
+	assert.InDelta(t, 0.12, cost, 1e-9)
+
+	usage, err = credentials.GetLLMUsage(2, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, usage, "other users are not billed")
+}
+
+func TestService_GenerateWithoutHeadlines(t *testing.T) {
+	store, since := setupStore(t)
+	llm := &fakeCompleter{}
+	service := NewService(store, llm, Options{MaxHeadlines: 10})
+
+	briefing, err := service.Generate(context.Background(), Request{UserID: 1, Since: since.Add(24 * time.Hour)})
+	require.NoError(t, err)
+	assert.Zero(t, briefing.Headlines)
+	assert.Empty(t, briefing.Topics)
+	assert.NotNil(t, briefing.Topics)
+	assert.Empty(t, llm.prompts, "the LLM is not asked")
+}
+
+func TestService_GenerateFailures(t *testing.T) {
+	store, since := setupStore(t)
+
+	for name, llm := range map[string]*fakeCompleter{
+		"LLM error":      {err: errors.New("rate limited")},
+		"not JSON":       {text: "Here is your briefing: lots happened."},
+		"unknown topics": {text: `{"topics": [{"name": "Space", "stories": [5]}]}`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			service := NewService(store, llm, Options{MaxHeadlines: 10})
+			_, err := service.Generate(context.Background(), Request{UserID: 1, Since: since})
+			var appErr *ierr.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, ierr.ErrBriefingFailed.Code, appErr.Code)
+		})
+	}
+}
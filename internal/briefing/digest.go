@@ -0,0 +1,198 @@
+package briefing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// digestBatchSize is how many due digests one round writes at most
+const digestBatchSize = 50
+
+// DueDigests returns the digests due by now, the longest overdue first
+func (s *Store) DueDigests(ctx context.Context, now time.Time, limit int) ([]models.FolderDigest, error) {
+	var digests []models.FolderDigest
+	err := s.db.WithContext(ctx).
+		Where("next_run_at <= ?", now).
+		Order("next_run_at, folder_id").
+		Limit(limit).
+		Find(&digests).Error
+	return digests, err
+}
+
+// ClaimDigest moves the digest on to its next send time, unless another instance already
+// did. Only the instance that claims a send time writes the digest.
+func (s *Store) ClaimDigest(ctx context.Context, digest *models.FolderDigest, next time.Time) (bool, error) {
+	result := s.db.WithContext(ctx).Model(&models.FolderDigest{}).
+		Where("folder_id = ? AND next_run_at = ?", digest.FolderID, digest.NextRunAt).
+		Update("next_run_at", next)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UserFolders returns all the folders of the user
+func (s *Store) UserFolders(ctx context.Context, userID uint) ([]*models.Folder, error) {
+	var folders []*models.Folder
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&folders).Error
+	return folders, err
+}
+
+// SummaryLanguage returns the summary language the user prefers, empty when they chose
+// none
+func (s *Store) SummaryLanguage(ctx context.Context, userID uint) (string, error) {
+	var languages []string
+	err := s.db.WithContext(ctx).Table("user_preferences").
+		Where("user_id = ?", userID).
+		Pluck("summary_language", &languages).Error
+	if err != nil || len(languages) == 0 {
+		return "", err
+	}
+	return languages[0], nil
+}
+
+// SendDigest delivers a digest as a notification and records when it was sent
+func (s *Store) SendDigest(ctx context.Context, digest *models.FolderDigest, notification *models.Notification) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(notification).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.FolderDigest{}).
+			Where("folder_id = ?", digest.FolderID).
+			Update("last_sent_at", notification.CreatedAt).Error
+	})
+}
+
+// Digests writes the folder digests that are due. Several api-service instances may run
+// it at once: each send time is claimed by one of them.
+type Digests struct {
+	service  *Service
+	logger   *slog.Logger
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewDigests(service *Service, logger *slog.Logger, interval time.Duration) *Digests {
+	return &Digests{service: service, logger: logger, interval: interval, now: time.Now}
+}
+
+// Start writes the due digests every interval until the context is cancelled
+func (d *Digests) Start(ctx context.Context) error {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Error("failed to write folder digests", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce writes the digests due now and returns how many were sent. A digest is sent
+// only when its folder has unread articles; one that fails is skipped until its next
+// send time.
+func (d *Digests) RunOnce(ctx context.Context) (int, error) {
+	now := d.now().UTC()
+	due, err := d.service.store.DueDigests(ctx, now, digestBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list due digests: %w", err)
+	}
+
+	sent := 0
+	for i := range due {
+		digest := &due[i]
+		claimed, err := d.service.store.ClaimDigest(ctx, digest, digest.NextRun(now))
+		if err != nil {
+			return sent, fmt.Errorf("failed to claim digest of folder %d: %w", digest.FolderID, err)
+		}
+		if !claimed {
+			continue
+		}
+
+		log := d.logger.With("user_id", digest.UserID, "folder_id", digest.FolderID)
+		ok, err := d.send(ctx, digest)
+		if err != nil {
+			if ctx.Err() != nil {
+				return sent, ctx.Err()
+			}
+			log.Error("failed to write folder digest", "error", err)
+			continue
+		}
+		if ok {
+			sent++
+			log.Info("sent folder digest", "frequency", digest.Frequency)
+		}
+	}
+	return sent, nil
+}
+
+// send writes the digest of the unread articles published since its previous send time
+// and reports whether there were any
+func (d *Digests) send(ctx context.Context, digest *models.FolderDigest) (bool, error) {
+	store := d.service.store
+	folders, err := store.UserFolders(ctx, digest.UserID)
+	if err != nil {
+		return false, fmt.Errorf("list folders: %w", err)
+	}
+	folderIDs := models.FolderSubtree(folders, digest.FolderID)
+	if len(folderIDs) == 0 {
+		return false, nil
+	}
+	language, err := store.SummaryLanguage(ctx, digest.UserID)
+	if err != nil {
+		return false, fmt.Errorf("read summary language: %w", err)
+	}
+
+	result, err := d.service.Generate(ctx, Request{
+		UserID:    digest.UserID,
+		Since:     digest.PreviousRun(digest.NextRunAt),
+		Language:  language,
+		FolderIDs: folderIDs,
+	})
+	if err != nil {
+		return false, err
+	}
+	if len(result.Topics) == 0 {
+		return false, nil
+	}
+
+	name := ""
+	for _, folder := range folders {
+		if folder.ID == digest.FolderID {
+			name = folder.Name
+		}
+	}
+	err = store.SendDigest(ctx, digest, &models.Notification{
+		UserID:    digest.UserID,
+		Type:      models.NotificationFolderDigest,
+		Message:   renderDigest(name, result),
+		CreatedAt: d.now().UTC(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("send: %w", err)
+	}
+	return true, nil
+}
+
+// renderDigest writes a briefing out as the text of a notification
+func renderDigest(folder string, briefing *Briefing) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s digest: %d unread articles", folder, briefing.Headlines)
+	for _, topic := range briefing.Topics {
+		fmt.Fprintf(&b, "\n\n%s: %s", topic.Name, topic.Summary)
+		for _, story := range topic.Stories {
+			fmt.Fprintf(&b, "\n- %s (%s) %s", story.Title, story.FeedTitle, story.URL)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package briefing
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	usermodels "github.com/Fancu1/phoenix-rss/internal/user-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+func TestDigests_RunOnce(t *testing.T) {
+	store, since := setupStore(t)
+	ctx := context.Background()
+	db := store.db
+	require.NoError(t, db.AutoMigrate(&models.Folder{}, &models.FolderDigest{}, &models.Notification{}, &usermodels.UserPreferences{}))
+
+	// the comet's feed is filed in a folder below News, the chip's in none
+	news := &models.Folder{UserID: 1, Name: "News"}
+	require.NoError(t, db.Create(news).Error)
+	science := &models.Folder{UserID: 1, ParentID: &news.ID, Name: "Science"}
+	empty := &models.Folder{UserID: 1, Name: "Empty"}
+	require.NoError(t, db.Create([]*models.Folder{science, empty}).Error)
+	require.NoError(t, db.Model(&models.Subscription{}).Where("user_id = ? AND custom_title IS NOT NULL", 1).Update("folder_id", science.ID).Error)
+	require.NoError(t, db.Create(&usermodels.UserPreferences{UserID: 1, SummaryLanguage: "de"}).Error)
+
+	// both digests were due at 08:00 the day after the articles came in
+	due := since.Add(24 * time.Hour)
+	for _, folder := range []*models.Folder{news, empty} {
+		require.NoError(t, db.Create(&models.FolderDigest{FolderID: folder.ID, UserID: 1, Frequency: models.DigestDaily, Hour: 8, Timezone: "UTC", NextRunAt: due}).Error)
+	}
+
+	llm := &fakeCompleter{text: `{"topics": [{"name": "Space", "summary": "A comet.", "stories": [1]}]}`}
+	digests := NewDigests(NewService(store, llm, Options{MaxHeadlines: 10}), logger.New(slog.LevelDebug), time.Minute)
+	digests.now = func() time.Time { return due.Add(time.Hour) }
+
+	sent, err := digests.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent, "a folder without unread articles gets no digest")
+
+	require.Len(t, llm.prompts, 1)
+	assert.Contains(t, llm.prompts[0], "1. [My Science] Comet sighted\n")
+	assert.NotContains(t, llm.prompts[0], "Chip launch", "feeds outside the folder are left out")
+	assert.Contains(t, llm.prompts[0], `language with the code "de"`)
+
+	var notifications []models.Notification
+	require.NoError(t, db.Find(&notifications).Error)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, models.NotificationFolderDigest, notifications[0].Type)
+	assert.Equal(t, uint(1), notifications[0].UserID)
+	assert.Equal(t, "News digest: 1 unread articles\n\nSpace: A comet.\n- Comet sighted (My Science) https://renamed.example.com/1", notifications[0].Message)
+
+	var stored []models.FolderDigest
+	require.NoError(t, db.Order("folder_id").Find(&stored).Error)
+	require.Len(t, stored, 2)
+	for _, digest := range stored {
+		assert.True(t, digest.NextRunAt.Equal(due.Add(24*time.Hour)), "folder %d moves on to the next day", digest.FolderID)
+	}
+	require.NotNil(t, stored[0].LastSentAt)
+	assert.Nil(t, stored[1].LastSentAt)
+
+	// the send time was claimed, so running again sends nothing
+	sent, err = digests.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+	assert.Len(t, llm.prompts, 1)
+}
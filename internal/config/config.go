@@ -2,9 +2,17 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+
+	"github.com/Fancu1/phoenix-rss/pkg/grpctls"
+	"github.com/Fancu1/phoenix-rss/pkg/password"
+	"github.com/Fancu1/phoenix-rss/pkg/tracing"
 )
 
 // Config is the main config for the application
@@ -18,11 +26,238 @@ type Config struct {
 	FeedService      FeedServiceConfig      `mapstructure:"feed_service"`
 	SchedulerService SchedulerServiceConfig `mapstructure:"scheduler_service"`
 	AIService        AIServiceConfig        `mapstructure:"ai_service"`
+	Email            EmailConfig            `mapstructure:"email"`
+	Fetch            FetchConfig            `mapstructure:"fetch"`
+	Tracing          TracingConfig          `mapstructure:"tracing"`
+	Summaries        SummariesConfig        `mapstructure:"summaries"`
+	Policy           PolicyConfig           `mapstructure:"policy"`
+	Exports          ExportsConfig          `mapstructure:"exports"`
+}
+
+// TracingConfig exports OpenTelemetry traces of requests and the events they cause
+type TracingConfig struct {
+	// OTLPEndpoint is the host:port, or URL, of the OTLP/HTTP collector; empty disables
+	// exporting, though trace context received from callers is still passed on
+	OTLPEndpoint string `mapstructure:"otlp_endpoint"`
+	// OTLPInsecure sends the traces over plain HTTP instead of HTTPS
+	OTLPInsecure bool `mapstructure:"otlp_insecure"`
+	// SampleRatio is the share of traces started here that are recorded, from 0 to 1;
+	// traces started by a caller follow the caller's decision
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// Params converts the settings for tracing
+func (c TracingConfig) Params() tracing.Config {
+	return tracing.Config{
+		Endpoint:    c.OTLPEndpoint,
+		Insecure:    c.OTLPInsecure,
+		SampleRatio: c.SampleRatio,
+	}
+}
+
+// SummariesConfig decides which languages articles are summarized in
+type SummariesConfig struct {
+	// Languages are the language codes of the summaries made of every article, e.g. zh
+	// or en. The first is the default variant, the one kept in the article's summary
+	// column and shown when a reader asks for no language or one without a summary.
+	Languages []string `mapstructure:"languages"`
+	// MaxReaderLanguages caps the other languages an article is summarized in because
+	// subscribers of its feed chose them in their preferences; 0 only uses Languages
+	MaxReaderLanguages int `mapstructure:"max_reader_languages"`
+}
+
+// DefaultLanguage is the language of the default summary variant
+func (c SummariesConfig) DefaultLanguage() string {
+	if len(c.Languages) == 0 {
+		return ""
+	}
+	return c.Languages[0]
+}
+
+// PolicyConfig points to the policy document overriding the configured retention, quota,
+// crawl budget and check window limits, see internal/policy
+type PolicyConfig struct {
+	// File is the path of the JSON policy document; empty applies the configured limits
+	File string `mapstructure:"file"`
+	// MaxSubscriptions caps the feeds each user subscribes to; 0 is unlimited
+	MaxSubscriptions int `mapstructure:"max_subscriptions"`
+}
+
+// Export storage backends
+const (
+	ExportStorageDir = "dir" // files under exports.dir, shared by the api-service and feed-service
+	ExportStorageS3  = "s3"  // an S3-compatible bucket
+)
+
+// ExportsConfig controls the feed article archives exported in the background, see
+// internal/archive
+type ExportsConfig struct {
+	// Storage is where archives are written: "dir" or "s3"
+	Storage string `mapstructure:"storage"`
+	// Dir is the directory of the dir storage
+	Dir string               `mapstructure:"dir"`
+	S3  ExportsS3StoreConfig `mapstructure:"s3"`
+	// PageSize is how many articles are read from the database at a time
+	PageSize int `mapstructure:"page_size"`
+	// PollInterval is how often the feed-service looks for queued exports
+	PollInterval string `mapstructure:"poll_interval"`
+	// StaleAfter requeues exports left running this long, e.g. by a replica that stopped
+	StaleAfter string `mapstructure:"stale_after"`
+}
+
+// ExportsS3StoreConfig locates the bucket of the s3 storage, addressed path-style
+type ExportsS3StoreConfig struct {
+	Endpoint        string `mapstructure:"endpoint"`
+	Region          string `mapstructure:"region"`
+	Bucket          string `mapstructure:"bucket"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+}
+
+// FetchConfig is the identity every outbound fetch (feeds, robots.txt, article pages)
+// presents to the sites it crawls
+type FetchConfig struct {
+	// UserAgent defaults to feed_service.article_update.http_user_agent for older deployments
+	UserAgent string `mapstructure:"user_agent"`
+	// From is a contact address for site operators, sent in the From header
+	From string `mapstructure:"from"`
+	// InfoURL points to a page describing the crawler and is appended to the User-Agent
+	InfoURL string `mapstructure:"info_url"`
+	// BlockPrivateNetworks refuses fetches of loopback, private and link-local addresses
+	BlockPrivateNetworks bool `mapstructure:"block_private_networks"`
+	// HostRate paces the requests each feed-service replica sends to one host
+	HostRate FetchHostRateConfig `mapstructure:"host_rate"`
+}
+
+// FetchHostRateConfig is the token bucket every host gets: Burst requests at once, then
+// RequestsPerSecond. Feed fetches, robots.txt and article checks share it.
+type FetchHostRateConfig struct {
+	// RequestsPerSecond is the steady rate per host; 0 disables the limit
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"`
+	Burst             int     `mapstructure:"burst"`
 }
 
 // ServerConfig is the config for the server
 type ServerConfig struct {
-	Port int `mapstructure:"port"`
+	Port     int                  `mapstructure:"port"`
+	Frontend ServerFrontendConfig `mapstructure:"frontend"`
+	// CORSAllowedOrigins lists the origins allowed to call the API from a browser, needed
+	// when the frontend is served from another origin (separate listener or CDN)
+	CORSAllowedOrigins []string `mapstructure:"cors_allowed_origins"`
+	// TrustedProxies lists the IPs and CIDRs of the reverse proxies in front of the API,
+	// whose X-Forwarded-For and X-Real-IP headers give the client IP; none by default, so
+	// the client IP is the address of the connection
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// AdminToken grants access to the /api/v1/admin endpoints; when empty only users with
+	// the admin role may use them
+	AdminToken string `mapstructure:"admin_token"`
+	// SlowRequestThreshold flags requests taking longer in the access log; 0 disables it
+	SlowRequestThreshold string `mapstructure:"slow_request_threshold"`
+	// SmartSort tunes the score of sort=smart article listings
+	SmartSort ServerSmartSortConfig `mapstructure:"smart_sort"`
+	// LoginProtection limits failed logins per username and per client IP
+	LoginProtection ServerLoginProtectionConfig `mapstructure:"login_protection"`
+	// Demo serves a read-only public instance
+	Demo ServerDemoConfig `mapstructure:"demo"`
+	// SharedFolders tunes the public pages of the folders users share
+	SharedFolders ServerSharedFoldersConfig `mapstructure:"shared_folders"`
+	// GRPCClients makes the calls to the feed and user services resilient to restarts
+	GRPCClients ServerGRPCClientsConfig `mapstructure:"grpc_clients"`
+}
+
+// ServerGRPCClientsConfig retries idempotent calls to the feed and user services that fail
+// with Unavailable, backing off exponentially, and opens a circuit breaker per service
+// after BreakerFailureThreshold consecutive failures: calls then fail fast until one probe
+// succeeds after BreakerOpenTimeout
+type ServerGRPCClientsConfig struct {
+	// RetryMaxAttempts counts the first attempt; 1 disables retries
+	RetryMaxAttempts    int    `mapstructure:"retry_max_attempts"`
+	RetryBackoffInitial string `mapstructure:"retry_backoff_initial"`
+	RetryBackoffMax     string `mapstructure:"retry_backoff_max"`
+	// BreakerFailureThreshold of 0 disables the circuit breaker
+	BreakerFailureThreshold int    `mapstructure:"breaker_failure_threshold"`
+	BreakerOpenTimeout      string `mapstructure:"breaker_open_timeout"`
+}
+
+// Durations parses the backoff and breaker durations
+func (c ServerGRPCClientsConfig) Durations() (backoffInitial, backoffMax, openTimeout time.Duration, err error) {
+	values := []*time.Duration{&backoffInitial, &backoffMax, &openTimeout}
+	for i, value := range []string{c.RetryBackoffInitial, c.RetryBackoffMax, c.BreakerOpenTimeout} {
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			return 0, 0, 0, fmt.Errorf("invalid gRPC client duration %q", value)
+		}
+		*values[i] = duration
+	}
+	if backoffMax < backoffInitial {
+		return 0, 0, 0, fmt.Errorf("gRPC client retry_backoff_max cannot be below retry_backoff_initial")
+	}
+	return backoffInitial, backoffMax, openTimeout, nil
+}
+
+// ServerDemoConfig turns the instance into a read-only public demo: registration and
+// every write endpoint are refused, and a demo user is kept subscribed to Feeds
+type ServerDemoConfig struct {
+	Enabled  bool     `mapstructure:"enabled"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+	Feeds    []string `mapstructure:"feeds"`
+	// CacheTTL is how long clients may reuse API responses and the feed list stays cached
+	CacheTTL string `mapstructure:"cache_ttl"`
+}
+
+// ServerSharedFoldersConfig caches the public pages of shared folders in Redis and limits
+// how often each client IP may request them
+type ServerSharedFoldersConfig struct {
+	// CacheTTL is how long a page is served from the cache, and may be kept by clients and
+	// proxies; 0 disables caching
+	CacheTTL string `mapstructure:"cache_ttl"`
+	// RateLimit is the requests a client IP may make per minute; 0 disables the limit
+	RateLimit int `mapstructure:"rate_limit"`
+}
+
+// ServerLoginProtectionConfig locks usernames and client IPs out after repeated failed
+// logins, for BaseLockout at first and twice as long for each lockout within a day
+type ServerLoginProtectionConfig struct {
+	Enabled            bool   `mapstructure:"enabled"`
+	MaxAccountFailures int    `mapstructure:"max_account_failures"`
+	MaxIPFailures      int    `mapstructure:"max_ip_failures"`
+	FailureWindow      string `mapstructure:"failure_window"`
+	BaseLockout        string `mapstructure:"base_lockout"`
+	MaxLockout         string `mapstructure:"max_lockout"`
+	// ChallengeAfter failures a CAPTCHA must be solved, checked at ChallengeVerifyURL (an
+	// hCaptcha, reCAPTCHA or Turnstile siteverify endpoint); disabled when the URL is empty
+	ChallengeAfter     int    `mapstructure:"challenge_after"`
+	ChallengeVerifyURL string `mapstructure:"challenge_verify_url"`
+	ChallengeSecret    string `mapstructure:"challenge_secret"`
+}
+
+// ServerSmartSortConfig weighs the parts of the score behind sort=smart article listings
+type ServerSmartSortConfig struct {
+	RecencyWeight  float64 `mapstructure:"recency_weight"`
+	UnreadWeight   float64 `mapstructure:"unread_weight"`
+	AffinityWeight float64 `mapstructure:"affinity_weight"`
+	StarredWeight  float64 `mapstructure:"starred_weight"`
+	// RecencyHalfLife is the age at which an article keeps half of its recency score
+	RecencyHalfLife string `mapstructure:"recency_half_life"`
+}
+
+// Frontend serving modes
+const (
+	FrontendModeEmbedded = "embedded" // frontend and API share server.port
+	FrontendModeSeparate = "separate" // frontend on its own listener at server.frontend.port
+	FrontendModeDisabled = "disabled" // API only, e.g. when the frontend is hosted on a CDN
+)
+
+// ServerFrontendConfig controls how the api-service serves the embedded frontend
+type ServerFrontendConfig struct {
+	Mode string `mapstructure:"mode"`
+	Port int    `mapstructure:"port"`
+	// APIOrigin is where the frontend reaches the API when it is not on the same origin;
+	// it is added to the connect-src of the frontend's Content-Security-Policy
+	APIOrigin string `mapstructure:"api_origin"`
+	// ContentSecurityPolicy replaces the policy sent with frontend pages when set
+	ContentSecurityPolicy string `mapstructure:"content_security_policy"`
 }
 
 // DatabaseConfig is the config for the database
@@ -41,6 +276,81 @@ type RedisConfig struct {
 
 type AuthConfig struct {
 	JWTSecret string `mapstructure:"jwt_secret"`
+	// CredentialsKey encrypts per-user secrets (BYOK LLM API keys, custom fetch headers) at rest
+	CredentialsKey  string                    `mapstructure:"credentials_key"`
+	PasswordHashing AuthPasswordHashingConfig `mapstructure:"password_hashing"`
+	// AccessTokenTTL is how long an access token works; clients then exchange their
+	// refresh token for a new one
+	AccessTokenTTL string `mapstructure:"access_token_ttl"`
+	// SessionTTL is how long a session, and its refresh token, lasts since its last refresh
+	SessionTTL    string                  `mapstructure:"session_ttl"`
+	PasswordReset AuthPasswordResetConfig `mapstructure:"password_reset"`
+	// ServiceToken is the secret every service signs its gRPC calls to the others with,
+	// see pkg/serviceauth. Empty leaves the calls unauthenticated.
+	ServiceToken string `mapstructure:"service_token"`
+	// ServiceTokenMaxSkew is how far a call's signing time may be from the server's clock
+	ServiceTokenMaxSkew string `mapstructure:"service_token_max_skew"`
+	// ImpersonationTTL is how long the read-only token an administrator impersonates a
+	// user with works; it cannot be refreshed
+	ImpersonationTTL string `mapstructure:"impersonation_ttl"`
+}
+
+// AuthPasswordResetConfig configures the password reset emails, sent with the Email settings
+type AuthPasswordResetConfig struct {
+	// TTL is how long an emailed reset token works
+	TTL string `mapstructure:"ttl"`
+	// URL is the page the emailed link opens, with the token as its token query parameter;
+	// empty mails the bare token
+	URL string `mapstructure:"url"`
+}
+
+// maxImpersonationTTL keeps impersonation tokens short-lived
+const maxImpersonationTTL = time.Hour
+
+// minServiceTokenLength keeps the shared service secret from being guessable
+const minServiceTokenLength = 32
+
+// ServiceTokenSkew parses ServiceTokenMaxSkew
+func (c AuthConfig) ServiceTokenSkew() (time.Duration, error) {
+	skew, err := time.ParseDuration(c.ServiceTokenMaxSkew)
+	if err != nil {
+		return 0, fmt.Errorf("invalid service token max skew: %w", err)
+	}
+	return skew, nil
+}
+
+// TokenTTLs parses AccessTokenTTL and SessionTTL
+func (c AuthConfig) TokenTTLs() (accessTTL, sessionTTL time.Duration, err error) {
+	if accessTTL, err = time.ParseDuration(c.AccessTokenTTL); err != nil {
+		return 0, 0, fmt.Errorf("invalid access token ttl: %w", err)
+	}
+	if sessionTTL, err = time.ParseDuration(c.SessionTTL); err != nil {
+		return 0, 0, fmt.Errorf("invalid session ttl: %w", err)
+	}
+	return accessTTL, sessionTTL, nil
+}
+
+// AuthPasswordHashingConfig selects how new password hashes are made. Existing hashes of
+// another algorithm or with other parameters are upgraded when their user next logs in.
+type AuthPasswordHashingConfig struct {
+	// Algorithm is "argon2id" or "bcrypt"
+	Algorithm string `mapstructure:"algorithm"`
+	// Argon2Memory is in KiB
+	Argon2Memory      uint32 `mapstructure:"argon2_memory"`
+	Argon2Iterations  uint32 `mapstructure:"argon2_iterations"`
+	Argon2Parallelism uint8  `mapstructure:"argon2_parallelism"`
+	BcryptCost        int    `mapstructure:"bcrypt_cost"`
+}
+
+// Params converts the settings for password.NewHasher; salt and key lengths keep their defaults
+func (c AuthPasswordHashingConfig) Params() password.Params {
+	params := password.DefaultParams
+	params.Algorithm = c.Algorithm
+	params.Argon2.Memory = c.Argon2Memory
+	params.Argon2.Iterations = c.Argon2Iterations
+	params.Argon2.Parallelism = c.Argon2Parallelism
+	params.BcryptCost = c.BcryptCost
+	return params
 }
 
 // KafkaConfig hold Kafka connectivity and topic configurations
@@ -49,6 +359,40 @@ type KafkaConfig struct {
 	FeedFetch    FeedFetchKafkaConfig    `mapstructure:"feed_fetch"`
 	AIProcessing AIProcessingKafkaConfig `mapstructure:"ai_processing"`
 	ArticleCheck ArticleCheckKafkaConfig `mapstructure:"article_check"`
+	Routing      KafkaRoutingConfig      `mapstructure:"routing"`
+	// SchemaRegistry checks the protobuf events against a schema registry
+	SchemaRegistry KafkaSchemaRegistryConfig `mapstructure:"schema_registry"`
+	// Compression is the codec of every Kafka writer: none, gzip, snappy, lz4 or zstd.
+	// Consumers decompress any of them.
+	Compression string `mapstructure:"compression"`
+	// SizeReportInterval is how often the services log the size of the messages they
+	// published per topic, e.g. "5m"; empty or "0" disables the report
+	SizeReportInterval string `mapstructure:"size_report_interval"`
+}
+
+// KafkaSchemaRegistryConfig connects the protobuf events (article persisted and processed)
+// to a Confluent-compatible schema registry. Producers register their schema and prefix
+// messages with its ID; consumers refuse messages written with a schema registered for
+// another event. Disabled while URL is empty.
+type KafkaSchemaRegistryConfig struct {
+	URL string `mapstructure:"url"`
+	// Topics limits the registry to these topics; empty means every protobuf event topic
+	Topics []string `mapstructure:"topics"`
+	// RequiredTopics lists topics whose consumers reject messages without a schema ID,
+	// once all their producers use the registry
+	RequiredTopics []string `mapstructure:"required_topics"`
+}
+
+// KafkaRoutingConfig multiplexes several event types onto one shared topic. Consumers
+// dispatch each message by its event_type header.
+type KafkaRoutingConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Topic   string `mapstructure:"topic"`
+	// EventTypes lists the event types moved to the shared topic; the rest keep their own topics
+	EventTypes []string `mapstructure:"event_types"`
+	// Strict rejects (and counts) messages with a missing or unknown event_type header
+	Strict             bool   `mapstructure:"strict"`
+	FeedServiceGroupID string `mapstructure:"feed_service_group_id"`
 }
 
 // FeedFetchKafkaConfig config for feed fetching workflow (scheduler -> feed service)
@@ -68,16 +412,176 @@ type AIProcessingKafkaConfig struct {
 	ArticlesProcessedTopic string `mapstructure:"articles_processed_topic"`
 	AIServiceGroupID       string `mapstructure:"ai_service_group_id"`
 	FeedServiceAIGroupID   string `mapstructure:"feed_service_ai_group_id"`
+	// ArticlesRetryTopic holds the new articles whose summary failed with a temporary LLM
+	// error until they are due again; empty reports such failures at once
+	ArticlesRetryTopic    string `mapstructure:"articles_retry_topic"`
+	AIServiceRetryGroupID string `mapstructure:"ai_service_retry_group_id"`
 }
 
 type UserServiceConfig struct {
-	Address string `mapstructure:"address"`
+	Address string        `mapstructure:"address"`
+	TLS     GRPCTLSConfig `mapstructure:"tls"`
+}
+
+// GRPCTLSConfig secures the gRPC connections to a service. The service itself reads the
+// server certificate and client CA; its callers read the CA, server name and client
+// certificate.
+type GRPCTLSConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+	// ClientCAFile makes the server require client certificates signed by these CAs
+	ClientCAFile string `mapstructure:"client_ca_file"`
+	// CAFile is what callers verify the server with; empty uses the system roots
+	CAFile string `mapstructure:"ca_file"`
+	// ServerName overrides the name callers expect in the server's certificate
+	ServerName     string `mapstructure:"server_name"`
+	ClientCertFile string `mapstructure:"client_cert_file"`
+	ClientKeyFile  string `mapstructure:"client_key_file"`
+}
+
+// Params converts the settings for grpctls
+func (c GRPCTLSConfig) Params() grpctls.Config {
+	return grpctls.Config{
+		Enabled:        c.Enabled,
+		CertFile:       c.CertFile,
+		KeyFile:        c.KeyFile,
+		ClientCAFile:   c.ClientCAFile,
+		CAFile:         c.CAFile,
+		ServerName:     c.ServerName,
+		ClientCertFile: c.ClientCertFile,
+		ClientKeyFile:  c.ClientKeyFile,
+	}
+}
+
+func (c GRPCTLSConfig) validate(service string) error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("%s TLS cert file and key file must be set together", service)
+	}
+	if (c.ClientCertFile == "") != (c.ClientKeyFile == "") {
+		return fmt.Errorf("%s TLS client cert file and client key file must be set together", service)
+	}
+	return nil
 }
 
 type FeedServiceConfig struct {
 	Port          int                     `mapstructure:"port"`
 	Address       string                  `mapstructure:"address"`
+	TLS           GRPCTLSConfig           `mapstructure:"tls"`
 	ArticleUpdate FeedArticleUpdateConfig `mapstructure:"article_update"`
+	DeadFeed      FeedDeadFeedConfig      `mapstructure:"dead_feed"`
+	ArticleTrash  FeedArticleTrashConfig  `mapstructure:"article_trash"`
+	Snapshots     FeedSnapshotConfig      `mapstructure:"snapshots"`
+	AIResults     FeedAIResultsConfig     `mapstructure:"ai_results"`
+	Outbox        FeedOutboxConfig        `mapstructure:"outbox"`
+	// DeletedFeedRetention is what happens to the articles of a feed an administrator
+	// deletes without choosing: "archive" keeps them with the archived feed, "purge" drops them
+	DeletedFeedRetention string                `mapstructure:"deleted_feed_retention"`
+	Alerts               FeedAlertsConfig      `mapstructure:"alerts"`
+	CrawlBudget          FeedCrawlBudgetConfig `mapstructure:"crawl_budget"`
+	Health               FeedHealthConfig      `mapstructure:"health"`
+	Import               FeedImportConfig      `mapstructure:"import"`
+	// DrainPeriod is how long the gRPC server may take at shutdown to finish the calls in
+	// flight, after the service reported not serving and stopped consuming Kafka events
+	DrainPeriod string `mapstructure:"drain_period"`
+}
+
+// FeedHealthConfig controls when a failing feed is taken off the fetch schedule
+type FeedHealthConfig struct {
+	// FailureThreshold is how many fetches in a row must fail before the feed is set to
+	// error and no longer scheduled
+	FailureThreshold int `mapstructure:"failure_threshold"`
+}
+
+// FeedImportConfig limits how many articles a fetch saves, so the first fetch of a feed
+// with a long archive does not flood the database and the AI topic. Policies may set
+// other limits per feed, and the articles left out are imported with phoenix-admin feeds
+// backfill.
+type FeedImportConfig struct {
+	// MaxItemsPerFetch is the most new articles one fetch of a feed saves; 0 is unlimited
+	MaxItemsPerFetch int `mapstructure:"max_items_per_fetch"`
+	// InitialCutoff is how far back the first fetch of a feed imports articles, as a
+	// duration; empty imports them all
+	InitialCutoff string `mapstructure:"initial_cutoff"`
+}
+
+// FeedCrawlBudgetConfig caps the outbound requests made for each feed per day, counted in
+// Redis across replicas
+type FeedCrawlBudgetConfig struct {
+	// DailyRequests is the feed fetches and article page requests allowed per feed and
+	// UTC day; 0 disables the budget
+	DailyRequests int `mapstructure:"daily_requests"`
+}
+
+// FeedAlertsConfig controls the webhook operators are alerted through when popular feeds
+// start failing or the fetch failure rate spikes; alerting is off without a webhook URL
+type FeedAlertsConfig struct {
+	WebhookURL string `mapstructure:"webhook_url"`
+	// Format is the payload posted: "slack" for Slack-compatible incoming webhooks, "json"
+	// for the alert itself
+	Format string `mapstructure:"format"`
+	// PopularFeedSubscribers is how many subscribers a feed needs for its failure to be alerted
+	PopularFeedSubscribers int `mapstructure:"popular_feed_subscribers"`
+	// FailureRate is the share of failed fetches within FailureRateWindow that is alerted,
+	// once at least FailureRateMinFetches were made; 0 disables the failure rate alert
+	FailureRate           float64 `mapstructure:"failure_rate"`
+	FailureRateWindow     string  `mapstructure:"failure_rate_window"`
+	FailureRateMinFetches int     `mapstructure:"failure_rate_min_fetches"`
+	// Cooldown is the least time between repeats of the same alert
+	Cooldown string `mapstructure:"cooldown"`
+}
+
+// FeedDeadFeedConfig controls archiving of feeds whose source keeps returning 404/410
+type FeedDeadFeedConfig struct {
+	Threshold     string `mapstructure:"threshold"`
+	CheckInterval string `mapstructure:"check_interval"`
+}
+
+// FeedArticleTrashConfig controls how long deleted articles stay restorable before they are purged
+type FeedArticleTrashConfig struct {
+	GracePeriod   string `mapstructure:"grace_period"`
+	PurgeInterval string `mapstructure:"purge_interval"`
+}
+
+// FeedAIResultsConfig controls how AI processing results are written back to articles
+type FeedAIResultsConfig struct {
+	// BatchSize is how many results are applied in one transaction; 1 applies them one by one
+	BatchSize int `mapstructure:"batch_size"`
+	// BatchWait is how long a batch waits to fill after its first result arrived
+	BatchWait string `mapstructure:"batch_wait"`
+}
+
+// FeedOutboxConfig controls the relay that publishes the events stored in the outbox to
+// Kafka
+type FeedOutboxConfig struct {
+	// PollInterval is how often the relay looks for events to publish
+	PollInterval string `mapstructure:"poll_interval"`
+	// BatchSize is how many events a relay run claims
+	BatchSize int `mapstructure:"batch_size"`
+	// Retention is how long published events are kept
+	Retention string `mapstructure:"retention"`
+}
+
+// Durations parses the poll interval and retention
+func (c FeedOutboxConfig) Durations() (pollInterval, retention time.Duration, err error) {
+	if pollInterval, err = time.ParseDuration(c.PollInterval); err != nil {
+		return 0, 0, fmt.Errorf("invalid outbox poll interval %q: %w", c.PollInterval, err)
+	}
+	if retention, err = time.ParseDuration(c.Retention); err != nil {
+		return 0, 0, fmt.Errorf("invalid outbox retention %q: %w", c.Retention, err)
+	}
+	if pollInterval <= 0 || retention <= 0 {
+		return 0, 0, fmt.Errorf("outbox poll interval and retention must be positive")
+	}
+	return pollInterval, retention, nil
+}
+
+// FeedSnapshotConfig controls the raw feed responses kept for debugging
+type FeedSnapshotConfig struct {
+	// Keep is how many of each feed's latest responses are stored; 0 disables snapshots
+	Keep int `mapstructure:"keep"`
+	// MaxBytes caps the stored body of each response, before compression
+	MaxBytes int64 `mapstructure:"max_bytes"`
 }
 
 type FeedArticleUpdateConfig struct {
@@ -90,6 +594,50 @@ type FeedArticleUpdateConfig struct {
 	RobotsCacheTTL          string `mapstructure:"robots_cache_ttl"`
 	RespectRobots           bool   `mapstructure:"respect_robots"`
 	MaxContentBytes         int64  `mapstructure:"max_content_bytes"`
+
+	HostPause FeedHostPauseConfig     `mapstructure:"host_pause"`
+	OnRead    FeedOnReadRecheckConfig `mapstructure:"on_read"`
+}
+
+// FeedOnReadRecheckConfig queues an update check of an article a user opens, so content
+// that is read stays fresh without checking every article more often
+type FeedOnReadRecheckConfig struct {
+	// MinAge is how old an article must be, by its publication time, to be rechecked on
+	// read; empty or "0" disables the rechecks
+	MinAge string `mapstructure:"min_age"`
+	// CheckedWithin skips articles checked more recently than this
+	CheckedWithin string `mapstructure:"checked_within"`
+	// Cooldown is the least time between two rechecks of the same article, counted in
+	// Redis across replicas
+	Cooldown string `mapstructure:"cooldown"`
+}
+
+// Durations parses the on-read check settings; minAge is 0 when the checks are disabled
+func (c FeedOnReadRecheckConfig) Durations() (minAge, checkedWithin, cooldown time.Duration, err error) {
+	if c.MinAge != "" {
+		if minAge, err = time.ParseDuration(c.MinAge); err != nil {
+			return 0, 0, 0, fmt.Errorf("invalid on-read article check min age %q: %w", c.MinAge, err)
+		}
+	}
+	if minAge <= 0 {
+		return 0, 0, 0, nil
+	}
+	if checkedWithin, err = time.ParseDuration(c.CheckedWithin); err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid on-read article check window %q: %w", c.CheckedWithin, err)
+	}
+	if cooldown, err = time.ParseDuration(c.Cooldown); err != nil || cooldown <= 0 {
+		return 0, 0, 0, fmt.Errorf("invalid on-read article check cooldown %q", c.Cooldown)
+	}
+	return minAge, checkedWithin, cooldown, nil
+}
+
+// FeedHostPauseConfig pauses the article checks against a host after repeated failures,
+// tracked in Redis across replicas
+type FeedHostPauseConfig struct {
+	// FailureThreshold is how many requests in a row must fail (transport errors, 429,
+	// 5xx) to pause the host; 0 disables pausing
+	FailureThreshold int    `mapstructure:"failure_threshold"`
+	Pause            string `mapstructure:"pause"`
 }
 
 type SchedulerServiceConfig struct {
@@ -98,6 +646,29 @@ type SchedulerServiceConfig struct {
 	BatchDelay    string                      `mapstructure:"batch_delay"`
 	MaxConcurrent int                         `mapstructure:"max_concurrent"`
 	ArticleCheck  SchedulerArticleCheckConfig `mapstructure:"article_check"`
+	// FeedPageSize is how many feeds are loaded and dispatched at a time
+	FeedPageSize int `mapstructure:"feed_page_size"`
+	// MinFetchInterval skips feeds fetched more recently than this; 0 fetches every feed each run
+	MinFetchInterval string `mapstructure:"min_fetch_interval"`
+	// LowTierFetchInterval is how often feeds in the low fetch tier are fetched at most;
+	// 0 fetches them like normal tier feeds
+	LowTierFetchInterval string `mapstructure:"low_tier_fetch_interval"`
+	// OperatorReport emails weekly instance statistics to the operators
+	OperatorReport SchedulerOperatorReportConfig `mapstructure:"operator_report"`
+	// Engagement computes how much of each subscription its user reads
+	Engagement SchedulerEngagementConfig `mapstructure:"engagement"`
+	// StateCompaction prunes the synced read and starred change events
+	StateCompaction SchedulerStateCompactionConfig `mapstructure:"state_compaction"`
+	// CatchUp spreads the backlog after downtime instead of dispatching it at once
+	CatchUp SchedulerCatchUpConfig `mapstructure:"catch_up"`
+}
+
+// SchedulerCatchUpConfig controls catch-up mode: when no feed was fetched for Gap, the
+// due feeds are dispatched evenly over Window
+type SchedulerCatchUpConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Gap     string `mapstructure:"gap"`
+	Window  string `mapstructure:"window"`
 }
 
 type SchedulerArticleCheckConfig struct {
@@ -105,6 +676,30 @@ type SchedulerArticleCheckConfig struct {
 	WindowDays       int    `mapstructure:"window_days"`
 	MinCheckInterval string `mapstructure:"min_check_interval"`
 	PageSize         int    `mapstructure:"page_size"`
+	// MaxPageSize bounds the pages the scheduler grows to while the backlog is large;
+	// at or below PageSize every page has PageSize
+	MaxPageSize int `mapstructure:"max_page_size"`
+}
+
+type SchedulerOperatorReportConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Cron    string `mapstructure:"cron"`
+	// Recipients receive the email; without any the report is only stored
+	Recipients []string `mapstructure:"recipients"`
+	// TokenPricePerMillion estimates AI spend on the instance key, in USD
+	TokenPricePerMillion float64 `mapstructure:"token_price_per_million"`
+}
+
+type SchedulerEngagementConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Cron    string `mapstructure:"cron"`
+}
+
+type SchedulerStateCompactionConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Cron    string `mapstructure:"cron"`
+	// Retention keeps the events of this long, e.g. "720h"; the merged states are kept for good
+	Retention string `mapstructure:"retention"`
 }
 
 type AIServiceConfig struct {
@@ -112,39 +707,180 @@ type AIServiceConfig struct {
 	LLMAPIKey      string `mapstructure:"llm_api_key"`
 	LLMModel       string `mapstructure:"llm_model"`
 	RequestTimeout string `mapstructure:"request_timeout"`
+	// SummaryMaxTokens caps each summary; summaries that hit it are flagged as truncated
+	// and can be regenerated with ExpandedMaxTokens
+	SummaryMaxTokens  int `mapstructure:"summary_max_tokens"`
+	ExpandedMaxTokens int `mapstructure:"expanded_max_tokens"`
+	// SummaryCacheEnabled reuses the summary of an article with the same content, e.g. one
+	// syndicated through several feeds, instead of asking the LLM again
+	SummaryCacheEnabled bool `mapstructure:"summary_cache_enabled"`
+	// SummaryPrompts are the prompt variants to summarize with. With several, most summaries
+	// use the variant readers rated best for the model, and a PromptExploration share tries
+	// a random one; a variant's ratings count once it has PromptMinRatings of them.
+	SummaryPrompts    []string `mapstructure:"summary_prompts"`
+	PromptExploration float64  `mapstructure:"prompt_exploration"`
+	PromptMinRatings  int      `mapstructure:"prompt_min_ratings"`
+	// PromptDir holds prompt templates, one <name>.tmpl file per variant, that can be
+	// listed in SummaryPrompts next to the built-in ones; a file named after a built-in
+	// variant replaces it. Empty only offers the built-in variants.
+	PromptDir string `mapstructure:"prompt_dir"`
+	// SummaryMaxLength is the length in characters prompts ask summaries to stay within,
+	// given to templates as .MaxLength; 0 asks for none
+	SummaryMaxLength int `mapstructure:"summary_max_length"`
+	// BriefingMaxHeadlines caps the unread headlines a briefing covers, the newest first,
+	// and BriefingMaxTokens the LLM's answer
+	BriefingMaxHeadlines int `mapstructure:"briefing_max_headlines"`
+	BriefingMaxTokens    int `mapstructure:"briefing_max_tokens"`
+	// BriefingCacheEnabled keeps each briefing in Redis until the end of the hour
+	BriefingCacheEnabled bool `mapstructure:"briefing_cache_enabled"`
+	// DigestInterval is how often the api-service looks for folder digests that are due;
+	// 0 sends none
+	DigestInterval string `mapstructure:"digest_interval"`
+	// BatchSize articles read within BatchWindow of the first are processed together,
+	// BatchConcurrency at a time; a BatchSize of 1 processes them one by one
+	BatchSize        int    `mapstructure:"batch_size"`
+	BatchWindow      string `mapstructure:"batch_window"`
+	BatchConcurrency int    `mapstructure:"batch_concurrency"`
+	// ProcessedEventRetention is how long the ai-service remembers the article persisted
+	// events it processed, so one delivered again is not summarized twice; empty or 0
+	// processes every delivery
+	ProcessedEventRetention string `mapstructure:"processed_event_retention"`
+	// ModelPrices are the USD prices per million prompt and completion tokens the cost of
+	// each LLM request is estimated from, as "model=prompt/completion", e.g.
+	// "gpt-4o-mini=0.15/0.60"; requests to other models are recorded at no cost
+	ModelPrices []string `mapstructure:"model_prices"`
+	// DailyBudgetUSD and DailyTokenBudget cap the estimated cost and the tokens the
+	// instance key may use in a UTC day; processing pauses until the next day once either
+	// is reached. 0 is no limit.
+	DailyBudgetUSD   float64 `mapstructure:"daily_budget_usd"`
+	DailyTokenBudget int64   `mapstructure:"daily_token_budget"`
+	// LLMMaxConcurrency caps the LLM requests in flight across the service; 0 is no cap
+	LLMMaxConcurrency int `mapstructure:"llm_max_concurrency"`
+	// LLMRetryMaxAttempts counts the first attempt at a request the LLM API answered with
+	// 429 or 5xx; the backoff doubles from LLMRetryBackoffInitial up to LLMRetryBackoffMax
+	// and a shorter Retry-After replaces it. 1 disables retries.
+	LLMRetryMaxAttempts    int    `mapstructure:"llm_retry_max_attempts"`
+	LLMRetryBackoffInitial string `mapstructure:"llm_retry_backoff_initial"`
+	LLMRetryBackoffMax     string `mapstructure:"llm_retry_backoff_max"`
+	// MaxRetries is how many times an article that still failed with a temporary LLM error
+	// goes through the retry topic, after RetryDelay doubled for each retry up to
+	// RetryMaxDelay, before it is reported failed; 0 reports it at once
+	MaxRetries    int    `mapstructure:"max_retries"`
+	RetryDelay    string `mapstructure:"retry_delay"`
+	RetryMaxDelay string `mapstructure:"retry_max_delay"`
+}
+
+// RetryDurations parses the LLM request backoff and the article retry delays
+func (c AIServiceConfig) RetryDurations() (backoffInitial, backoffMax, retryDelay, retryMaxDelay time.Duration, err error) {
+	values := []*time.Duration{&backoffInitial, &backoffMax, &retryDelay, &retryMaxDelay}
+	for i, value := range []string{c.LLMRetryBackoffInitial, c.LLMRetryBackoffMax, c.RetryDelay, c.RetryMaxDelay} {
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			return 0, 0, 0, 0, fmt.Errorf("invalid AI service retry duration %q", value)
+		}
+		*values[i] = duration
+	}
+	if backoffMax < backoffInitial {
+		return 0, 0, 0, 0, fmt.Errorf("AI service llm_retry_backoff_max cannot be below llm_retry_backoff_initial")
+	}
+	if retryMaxDelay < retryDelay {
+		return 0, 0, 0, 0, fmt.Errorf("AI service retry_max_delay cannot be below retry_delay")
+	}
+	return backoffInitial, backoffMax, retryDelay, retryMaxDelay, nil
+}
+
+// ModelPrice is what a model costs in USD per million prompt and completion tokens
+type ModelPrice struct {
+	Prompt     float64
+	Completion float64
+}
+
+// ParsedModelPrices parses ModelPrices by model
+func (c AIServiceConfig) ParsedModelPrices() (map[string]ModelPrice, error) {
+	prices := make(map[string]ModelPrice, len(c.ModelPrices))
+	for _, entry := range c.ModelPrices {
+		model, price, ok := strings.Cut(entry, "=")
+		promptStr, completionStr, okPrice := strings.Cut(price, "/")
+		model = strings.TrimSpace(model)
+		if !ok || !okPrice || model == "" {
+			return nil, fmt.Errorf("invalid AI service model price %q, expected model=prompt/completion", entry)
+		}
+		prompt, err := strconv.ParseFloat(strings.TrimSpace(promptStr), 64)
+		if err != nil || prompt < 0 {
+			return nil, fmt.Errorf("invalid AI service model price %q: prompt price must be a non-negative number", entry)
+		}
+		completion, err := strconv.ParseFloat(strings.TrimSpace(completionStr), 64)
+		if err != nil || completion < 0 {
+			return nil, fmt.Errorf("invalid AI service model price %q: completion price must be a non-negative number", entry)
+		}
+		if _, ok := prices[model]; ok {
+			return nil, fmt.Errorf("duplicate AI service model price for %q", model)
+		}
+		prices[model] = ModelPrice{Prompt: prompt, Completion: completion}
+	}
+	return prices, nil
+}
+
+// ProcessedEventRetentionDuration parses ProcessedEventRetention; 0 turns deduplication off
+func (c AIServiceConfig) ProcessedEventRetentionDuration() (time.Duration, error) {
+	if c.ProcessedEventRetention == "" {
+		return 0, nil
+	}
+	retention, err := time.ParseDuration(c.ProcessedEventRetention)
+	if err != nil || retention < 0 {
+		return 0, fmt.Errorf("invalid AI service processed event retention %q", c.ProcessedEventRetention)
+	}
+	return retention, nil
+}
+
+// EmailConfig is the SMTP config for outgoing email; without a host messages are dropped
+type EmailConfig struct {
+	SMTPHost     string `mapstructure:"smtp_host"`
+	SMTPPort     int    `mapstructure:"smtp_port"`
+	SMTPUsername string `mapstructure:"smtp_username"`
+	SMTPPassword string `mapstructure:"smtp_password"`
+	From         string `mapstructure:"from"`
 }
 
 // LoadConfig loads the configuration with the following priority:
 // 1. Environment variables (e.g., from .env file or system)
 // 2. Default values set in the code.
+// Use Load to add overrides or skip the environment.
 func LoadConfig() (*Config, error) {
+	return Load()
+}
+
+// Load loads the configuration like LoadConfig, applying opts on top
+func Load(opts ...Option) (*Config, error) {
+	options := loadOptions{overrides: map[string]any{}}
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			return nil, fmt.Errorf("config option: %w", err)
+		}
+	}
+
 	v := viper.New()
 
 	// Step 1: Set default values. This is the lowest priority.
 	setDefaults(v)
 
-	// Step 2 (Optional): Load .env file. This will override defaults.
-	// We look in the current directory for the .env file.
-	v.SetConfigName(".env")
-	v.SetConfigType("env")
-	v.AddConfigPath(".")
-	if err := v.MergeInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			// Only return an error if the file was found but couldn't be read.
-			// If the file is not found, we can proceed with defaults/env vars.
-			return nil, fmt.Errorf("error reading .env file: %w", err)
+	// Config files override the defaults; see profiles.go for the whole precedence order.
+	if dir, profile, ok := options.configFiles(); ok {
+		if err := mergeConfigFiles(v, dir, profile); err != nil {
+			return nil, err
 		}
 	}
 
-	// Step 3: Enable reading from environment variables.
-	// This has the highest priority and will override .env and defaults.
-	// e.g., DATABASE_HOST will override the value in .env.
-	v.AutomaticEnv()
-	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	if !options.skipEnvironment {
+		if err := mergeEnvironment(v); err != nil {
+			return nil, err
+		}
+	}
 
-	// Bind specific environment variables to their corresponding config keys.
-	// This ensures that `v.Unmarshal` works correctly with AutomaticEnv.
-	bindEnvironmentVariables(v)
+	// Step 4: Overrides from options win over everything else.
+	for key, value := range options.overrides {
+		v.Set(key, value)
+	}
 
 	var config Config
 	if err := v.Unmarshal(&config); err != nil {
@@ -164,10 +900,66 @@ func LoadConfig() (*Config, error) {
 	return &config, nil
 }
 
+// mergeEnvironment layers the .env file and environment variables over the defaults
+func mergeEnvironment(v *viper.Viper) error {
+	// Step 2 (Optional): Load .env file. This will override defaults.
+	// We look in the current directory for the .env file.
+	v.SetConfigName(".env")
+	v.SetConfigType("env")
+	v.AddConfigPath(".")
+	if err := v.MergeInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			// Only return an error if the file was found but couldn't be read.
+			// If the file is not found, we can proceed with defaults/env vars.
+			return fmt.Errorf("error reading .env file: %w", err)
+		}
+	}
+
+	// Step 3: Enable reading from environment variables.
+	// This has the highest priority and will override .env and defaults.
+	// e.g., DATABASE_HOST will override the value in .env.
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+
+	// Bind specific environment variables to their corresponding config keys.
+	// This ensures that `v.Unmarshal` works correctly with AutomaticEnv.
+	bindEnvironmentVariables(v)
+	return nil
+}
+
 // setDefaults configures default values for the application
 func setDefaults(v *viper.Viper) {
 	// Server defaults
 	v.SetDefault("server.port", 8080)
+	v.SetDefault("server.frontend.mode", FrontendModeEmbedded)
+	v.SetDefault("server.frontend.port", 8081)
+	v.SetDefault("server.slow_request_threshold", "1s")
+	v.SetDefault("server.smart_sort.recency_weight", 1.0)
+	v.SetDefault("server.smart_sort.unread_weight", 0.5)
+	v.SetDefault("server.smart_sort.affinity_weight", 0.3)
+	v.SetDefault("server.smart_sort.starred_weight", 0.4)
+	v.SetDefault("server.smart_sort.recency_half_life", "24h")
+	v.SetDefault("server.login_protection.enabled", true)
+	v.SetDefault("server.login_protection.max_account_failures", 5)
+	v.SetDefault("server.login_protection.max_ip_failures", 20)
+	v.SetDefault("server.login_protection.failure_window", "15m")
+	v.SetDefault("server.login_protection.base_lockout", "1m")
+	v.SetDefault("server.login_protection.max_lockout", "1h")
+	v.SetDefault("server.login_protection.challenge_after", 3)
+	v.SetDefault("server.login_protection.challenge_verify_url", "")
+	v.SetDefault("server.login_protection.challenge_secret", "")
+	v.SetDefault("server.demo.enabled", false)
+	v.SetDefault("server.demo.username", "demo")
+	v.SetDefault("server.demo.password", "")
+	v.SetDefault("server.demo.feeds", []string{})
+	v.SetDefault("server.demo.cache_ttl", "1h")
+	v.SetDefault("server.shared_folders.cache_ttl", "5m")
+	v.SetDefault("server.shared_folders.rate_limit", 60)
+	v.SetDefault("server.grpc_clients.retry_max_attempts", 3)
+	v.SetDefault("server.grpc_clients.retry_backoff_initial", "100ms")
+	v.SetDefault("server.grpc_clients.retry_backoff_max", "2s")
+	v.SetDefault("server.grpc_clients.breaker_failure_threshold", 5)
+	v.SetDefault("server.grpc_clients.breaker_open_timeout", "10s")
 
 	// Database defaults
 	v.SetDefault("database.host", "127.0.0.1")
@@ -182,6 +974,19 @@ func setDefaults(v *viper.Viper) {
 
 	// Auth defaults
 	v.SetDefault("auth.jwt_secret", "phoenix-rss-default-secret-please-change-in-production")
+	v.SetDefault("auth.credentials_key", "phoenix-rss-default-credentials-key-please-change-in-production")
+	v.SetDefault("auth.password_hashing.algorithm", "argon2id")
+	v.SetDefault("auth.password_hashing.argon2_memory", 19456)
+	v.SetDefault("auth.password_hashing.argon2_iterations", 2)
+	v.SetDefault("auth.password_hashing.argon2_parallelism", 1)
+	v.SetDefault("auth.password_hashing.bcrypt_cost", 10)
+	v.SetDefault("auth.access_token_ttl", "15m")
+	v.SetDefault("auth.session_ttl", "168h")
+	v.SetDefault("auth.impersonation_ttl", "15m")
+	v.SetDefault("auth.password_reset.ttl", "1h")
+	v.SetDefault("auth.password_reset.url", "")
+	v.SetDefault("auth.service_token", "")
+	v.SetDefault("auth.service_token_max_skew", "5m")
 
 	// Kafka defaults
 	v.SetDefault("kafka.brokers", []string{"127.0.0.1:19092"})
@@ -199,13 +1004,75 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("kafka.ai_processing.articles_processed_topic", "articles.processed")
 	v.SetDefault("kafka.ai_processing.ai_service_group_id", "ai-service-group")
 	v.SetDefault("kafka.ai_processing.feed_service_ai_group_id", "feed-service-ai-group")
+	v.SetDefault("kafka.ai_processing.articles_retry_topic", "articles.retry")
+	v.SetDefault("kafka.ai_processing.ai_service_retry_group_id", "ai-service-retry-group")
+
+	// Shared topic routing defaults (disabled: one topic per event type)
+	v.SetDefault("kafka.routing.enabled", false)
+	v.SetDefault("kafka.routing.topic", "phoenix.events")
+	v.SetDefault("kafka.routing.event_types", []string{})
+	v.SetDefault("kafka.routing.strict", true)
+	v.SetDefault("kafka.routing.feed_service_group_id", "feed-service-router")
+
+	// Schema registry defaults (disabled)
+	v.SetDefault("kafka.schema_registry.url", "")
+	v.SetDefault("kafka.schema_registry.topics", []string{})
+	v.SetDefault("kafka.schema_registry.required_topics", []string{})
+
+	// Message compression and size report defaults
+	v.SetDefault("kafka.compression", "none")
+	v.SetDefault("kafka.size_report_interval", "5m")
+
+	// Tracing defaults (not exported until a collector is configured)
+	v.SetDefault("tracing.otlp_endpoint", "")
+	v.SetDefault("tracing.otlp_insecure", true)
+	v.SetDefault("tracing.sample_ratio", 1.0)
+
+	// Summary languages default (Chinese only, as before variants)
+	v.SetDefault("summaries.languages", []string{"zh"})
+	v.SetDefault("summaries.max_reader_languages", 3)
+
+	// Policy defaults (configured limits only, no subscription quota)
+	v.SetDefault("policy.file", "")
+	v.SetDefault("policy.max_subscriptions", 0)
+
+	// Feed archive export defaults (files on a shared volume)
+	v.SetDefault("exports.storage", ExportStorageDir)
+	v.SetDefault("exports.dir", "data/exports")
+	v.SetDefault("exports.s3.endpoint", "")
+	v.SetDefault("exports.s3.region", "us-east-1")
+	v.SetDefault("exports.s3.bucket", "")
+	v.SetDefault("exports.s3.access_key_id", "")
+	v.SetDefault("exports.s3.secret_access_key", "")
+	v.SetDefault("exports.page_size", 500)
+	v.SetDefault("exports.poll_interval", "10s")
+	v.SetDefault("exports.stale_after", "1h")
 
 	// User Service defaults
 	v.SetDefault("user_service.address", "127.0.0.1:50051")
+	// gRPC TLS defaults (plain text inside the private network)
+	v.SetDefault("user_service.tls.enabled", false)
+	v.SetDefault("user_service.tls.cert_file", "")
+	v.SetDefault("user_service.tls.key_file", "")
+	v.SetDefault("user_service.tls.client_ca_file", "")
+	v.SetDefault("user_service.tls.ca_file", "")
+	v.SetDefault("user_service.tls.server_name", "")
+	v.SetDefault("user_service.tls.client_cert_file", "")
+	v.SetDefault("user_service.tls.client_key_file", "")
 
 	// Feed Service defaults
 	v.SetDefault("feed_service.port", 50053)
+	v.SetDefault("fetch.host_rate.requests_per_second", 1)
+	v.SetDefault("fetch.host_rate.burst", 5)
 	v.SetDefault("feed_service.address", "127.0.0.1:50053")
+	v.SetDefault("feed_service.tls.enabled", false)
+	v.SetDefault("feed_service.tls.cert_file", "")
+	v.SetDefault("feed_service.tls.key_file", "")
+	v.SetDefault("feed_service.tls.client_ca_file", "")
+	v.SetDefault("feed_service.tls.ca_file", "")
+	v.SetDefault("feed_service.tls.server_name", "")
+	v.SetDefault("feed_service.tls.client_cert_file", "")
+	v.SetDefault("feed_service.tls.client_key_file", "")
 	v.SetDefault("feed_service.article_update.http_timeout", "10s")
 	v.SetDefault("feed_service.article_update.http_user_agent", "PhoenixRSS/1.0 (+https://github.com/Fancu1/phoenix-rss)")
 	v.SetDefault("feed_service.article_update.http_retry_max_attempts", 3)
@@ -215,22 +1082,100 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("feed_service.article_update.robots_cache_ttl", "12h")
 	v.SetDefault("feed_service.article_update.respect_robots", true)
 	v.SetDefault("feed_service.article_update.max_content_bytes", 2097152)
+	v.SetDefault("feed_service.article_update.host_pause.failure_threshold", 5)
+	v.SetDefault("feed_service.article_update.host_pause.pause", "10m")
+	v.SetDefault("feed_service.article_update.on_read.min_age", "48h")
+	v.SetDefault("feed_service.article_update.on_read.checked_within", "12h")
+	v.SetDefault("feed_service.article_update.on_read.cooldown", "1h")
+	v.SetDefault("feed_service.dead_feed.threshold", "720h")
+	v.SetDefault("feed_service.dead_feed.check_interval", "6h")
+	v.SetDefault("feed_service.article_trash.grace_period", "720h")
+	v.SetDefault("feed_service.article_trash.purge_interval", "1h")
+	v.SetDefault("feed_service.snapshots.keep", 0)
+	v.SetDefault("feed_service.crawl_budget.daily_requests", 1000)
+	v.SetDefault("feed_service.health.failure_threshold", 5)
+	v.SetDefault("feed_service.import.max_items_per_fetch", 200)
+	v.SetDefault("feed_service.import.initial_cutoff", "720h")
+	v.SetDefault("feed_service.snapshots.max_bytes", 1048576)
+	v.SetDefault("feed_service.ai_results.batch_size", 50)
+	v.SetDefault("feed_service.ai_results.batch_wait", "200ms")
+	v.SetDefault("feed_service.drain_period", "20s")
+	v.SetDefault("feed_service.outbox.poll_interval", "1s")
+	v.SetDefault("feed_service.outbox.batch_size", 100)
+	v.SetDefault("feed_service.outbox.retention", "24h")
+	v.SetDefault("feed_service.deleted_feed_retention", "archive")
+	v.SetDefault("feed_service.alerts.webhook_url", "")
+	v.SetDefault("feed_service.alerts.format", "slack")
+	v.SetDefault("feed_service.alerts.popular_feed_subscribers", 10)
+	v.SetDefault("feed_service.alerts.failure_rate", 0.5)
+	v.SetDefault("feed_service.alerts.failure_rate_window", "15m")
+	v.SetDefault("feed_service.alerts.failure_rate_min_fetches", 50)
+	v.SetDefault("feed_service.alerts.cooldown", "1h")
 
 	// Scheduler Service defaults
 	v.SetDefault("scheduler_service.schedule", "@every 30m")
 	v.SetDefault("scheduler_service.batch_size", 20)
 	v.SetDefault("scheduler_service.batch_delay", "5s")
 	v.SetDefault("scheduler_service.max_concurrent", 5)
+	v.SetDefault("scheduler_service.feed_page_size", 1000)
+	v.SetDefault("scheduler_service.min_fetch_interval", "0s")
+	v.SetDefault("scheduler_service.low_tier_fetch_interval", "6h")
+	v.SetDefault("scheduler_service.catch_up.enabled", true)
+	v.SetDefault("scheduler_service.catch_up.gap", "6h")
+	v.SetDefault("scheduler_service.catch_up.window", "2h")
 	v.SetDefault("scheduler_service.article_check.cron", "0 0 */4 * * *")
 	v.SetDefault("scheduler_service.article_check.window_days", 7)
 	v.SetDefault("scheduler_service.article_check.min_check_interval", "4h")
 	v.SetDefault("scheduler_service.article_check.page_size", 500)
+	v.SetDefault("scheduler_service.article_check.max_page_size", 2000)
+	v.SetDefault("scheduler_service.operator_report.enabled", true)
+	v.SetDefault("scheduler_service.operator_report.cron", "0 0 8 * * MON")
+	v.SetDefault("scheduler_service.operator_report.recipients", []string{})
+	v.SetDefault("scheduler_service.operator_report.token_price_per_million", 0)
+	v.SetDefault("scheduler_service.engagement.enabled", true)
+	v.SetDefault("scheduler_service.engagement.cron", "0 30 3 * * *")
+	v.SetDefault("scheduler_service.state_compaction.enabled", true)
+	v.SetDefault("scheduler_service.state_compaction.cron", "0 45 3 * * *")
+	v.SetDefault("scheduler_service.state_compaction.retention", "720h")
 
 	// AI Service defaults
 	v.SetDefault("ai_service.llm_base_url", "https://api.openai.com")
 	v.SetDefault("ai_service.llm_api_key", "sk-proj-1234567890")
 	v.SetDefault("ai_service.llm_model", "gpt-4o-mini")
 	v.SetDefault("ai_service.request_timeout", "30s")
+	v.SetDefault("ai_service.summary_max_tokens", 512)
+	v.SetDefault("ai_service.expanded_max_tokens", 2048)
+	v.SetDefault("ai_service.summary_cache_enabled", true)
+	v.SetDefault("ai_service.summary_prompts", []string{"default"})
+	v.SetDefault("ai_service.prompt_exploration", 0.1)
+	v.SetDefault("ai_service.prompt_min_ratings", 20)
+	v.SetDefault("ai_service.prompt_dir", "")
+	v.SetDefault("ai_service.summary_max_length", 0)
+	v.SetDefault("ai_service.briefing_max_headlines", 100)
+	v.SetDefault("ai_service.briefing_max_tokens", 1024)
+	v.SetDefault("ai_service.briefing_cache_enabled", true)
+	v.SetDefault("ai_service.digest_interval", "1m")
+	v.SetDefault("ai_service.batch_size", 8)
+	v.SetDefault("ai_service.batch_window", "250ms")
+	v.SetDefault("ai_service.batch_concurrency", 4)
+	v.SetDefault("ai_service.processed_event_retention", "168h")
+	v.SetDefault("ai_service.model_prices", []string{"gpt-4o-mini=0.15/0.60"})
+	v.SetDefault("ai_service.daily_budget_usd", 0)
+	v.SetDefault("ai_service.daily_token_budget", 0)
+	v.SetDefault("ai_service.llm_max_concurrency", 4)
+	v.SetDefault("ai_service.llm_retry_max_attempts", 3)
+	v.SetDefault("ai_service.llm_retry_backoff_initial", "1s")
+	v.SetDefault("ai_service.llm_retry_backoff_max", "30s")
+	v.SetDefault("ai_service.max_retries", 5)
+	v.SetDefault("ai_service.retry_delay", "1m")
+	v.SetDefault("ai_service.retry_max_delay", "30m")
+
+	// Email defaults
+	v.SetDefault("email.smtp_host", "")
+	v.SetDefault("email.smtp_port", 587)
+	v.SetDefault("email.smtp_username", "")
+	v.SetDefault("email.smtp_password", "")
+	v.SetDefault("email.from", "phoenix-rss@localhost")
 }
 
 // validate performs basic validation on the loaded configuration
@@ -239,6 +1184,114 @@ func (c *Config) validate() error {
 		return fmt.Errorf("invalid server port: %d", c.Server.Port)
 	}
 
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("tracing sample ratio must be between 0 and 1: %g", c.Tracing.SampleRatio)
+	}
+
+	if len(c.Summaries.Languages) == 0 {
+		return fmt.Errorf("summary languages cannot be empty")
+	}
+	seenLanguages := make(map[string]bool, len(c.Summaries.Languages))
+	for _, language := range c.Summaries.Languages {
+		if len(language) > 16 {
+			return fmt.Errorf("summary language code is too long: %q", language)
+		}
+		if seenLanguages[language] {
+			return fmt.Errorf("duplicate summary language: %q", language)
+		}
+		seenLanguages[language] = true
+	}
+	if c.Summaries.MaxReaderLanguages < 0 {
+		return fmt.Errorf("summary max reader languages cannot be negative: %d", c.Summaries.MaxReaderLanguages)
+	}
+
+	if c.Policy.MaxSubscriptions < 0 {
+		return fmt.Errorf("policy max subscriptions cannot be negative: %d", c.Policy.MaxSubscriptions)
+	}
+
+	switch c.Exports.Storage {
+	case ExportStorageDir:
+		if c.Exports.Dir == "" {
+			return fmt.Errorf("exports dir cannot be empty with %s storage", ExportStorageDir)
+		}
+	case ExportStorageS3:
+		if c.Exports.S3.Endpoint == "" || c.Exports.S3.Bucket == "" {
+			return fmt.Errorf("exports s3 endpoint and bucket are required with %s storage", ExportStorageS3)
+		}
+	default:
+		return fmt.Errorf("invalid exports storage %q: must be %s or %s", c.Exports.Storage, ExportStorageDir, ExportStorageS3)
+	}
+	if c.Exports.PageSize <= 0 {
+		return fmt.Errorf("exports page size must be positive")
+	}
+	for name, value := range map[string]string{"poll interval": c.Exports.PollInterval, "stale after": c.Exports.StaleAfter} {
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid exports %s: %q", name, value)
+		}
+	}
+
+	switch c.Server.Frontend.Mode {
+	case FrontendModeEmbedded, FrontendModeDisabled:
+	case FrontendModeSeparate:
+		if c.Server.Frontend.Port <= 0 || c.Server.Frontend.Port > 65535 {
+			return fmt.Errorf("invalid frontend port: %d", c.Server.Frontend.Port)
+		}
+		if c.Server.Frontend.Port == c.Server.Port {
+			return fmt.Errorf("frontend port must differ from the server port in %s mode", FrontendModeSeparate)
+		}
+	default:
+		return fmt.Errorf("invalid frontend mode %q: must be %s, %s or %s", c.Server.Frontend.Mode, FrontendModeEmbedded, FrontendModeSeparate, FrontendModeDisabled)
+	}
+
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, err := netip.ParsePrefix(proxy); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(proxy); err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: must be an IP or a CIDR", proxy)
+		}
+	}
+
+	smart := c.Server.SmartSort
+	if smart.RecencyWeight < 0 || smart.UnreadWeight < 0 || smart.AffinityWeight < 0 || smart.StarredWeight < 0 {
+		return fmt.Errorf("smart sort weights cannot be negative")
+	}
+
+	if login := c.Server.LoginProtection; login.Enabled {
+		if login.MaxAccountFailures < 1 || login.MaxIPFailures < 1 {
+			return fmt.Errorf("login protection failure limits must be at least 1")
+		}
+		if login.ChallengeAfter < 0 {
+			return fmt.Errorf("login protection challenge_after cannot be negative")
+		}
+		if login.ChallengeVerifyURL != "" && login.ChallengeSecret == "" {
+			return fmt.Errorf("login protection challenge_secret is required with challenge_verify_url")
+		}
+	}
+
+	if clients := c.Server.GRPCClients; clients.RetryMaxAttempts < 1 || clients.BreakerFailureThreshold < 0 {
+		return fmt.Errorf("gRPC client retry_max_attempts must be at least 1 and breaker_failure_threshold cannot be negative")
+	}
+	if _, _, _, err := c.Server.GRPCClients.Durations(); err != nil {
+		return err
+	}
+
+	if demo := c.Server.Demo; demo.Enabled {
+		if len(strings.TrimSpace(demo.Username)) < 3 || len(demo.Password) < 6 {
+			return fmt.Errorf("demo mode needs a username of at least 3 and a password of at least 6 characters")
+		}
+		if demo.CacheTTL == "" {
+			return fmt.Errorf("demo cache ttl cannot be empty")
+		}
+	}
+
+	if ttl, err := time.ParseDuration(c.Server.SharedFolders.CacheTTL); err != nil || ttl < 0 {
+		return fmt.Errorf("invalid shared folders cache ttl %q", c.Server.SharedFolders.CacheTTL)
+	}
+	if c.Server.SharedFolders.RateLimit < 0 {
+		return fmt.Errorf("shared folders rate limit cannot be negative")
+	}
+
 	if c.Database.Host == "" {
 		return fmt.Errorf("database host cannot be empty")
 	}
@@ -255,10 +1308,71 @@ func (c *Config) validate() error {
 		return fmt.Errorf("JWT secret cannot be empty")
 	}
 
+	if c.Auth.CredentialsKey == "" {
+		return fmt.Errorf("credentials encryption key cannot be empty")
+	}
+
+	if err := c.UserService.TLS.validate("user service"); err != nil {
+		return err
+	}
+	if err := c.FeedService.TLS.validate("feed service"); err != nil {
+		return err
+	}
+
+	if c.Auth.ServiceToken != "" && len(c.Auth.ServiceToken) < minServiceTokenLength {
+		return fmt.Errorf("service token must be at least %d characters", minServiceTokenLength)
+	}
+	if skew, err := c.Auth.ServiceTokenSkew(); err != nil {
+		return err
+	} else if skew <= 0 {
+		return fmt.Errorf("service token max skew must be positive")
+	}
+
+	switch hashing := c.Auth.PasswordHashing; hashing.Algorithm {
+	case "argon2id":
+		if hashing.Argon2Iterations < 1 || hashing.Argon2Parallelism < 1 || hashing.Argon2Memory < 8*uint32(hashing.Argon2Parallelism) {
+			return fmt.Errorf("argon2id needs at least 1 iteration, 1 lane and 8 KiB of memory per lane")
+		}
+	case "bcrypt":
+		if hashing.BcryptCost < 4 || hashing.BcryptCost > 31 {
+			return fmt.Errorf("bcrypt cost must be between 4 and 31, got %d", hashing.BcryptCost)
+		}
+	default:
+		return fmt.Errorf("unknown password hashing algorithm %q (expected argon2id or bcrypt)", hashing.Algorithm)
+	}
+
+	accessTTL, sessionTTL, err := c.Auth.TokenTTLs()
+	if err != nil {
+		return fmt.Errorf("auth: %w", err)
+	}
+	if accessTTL <= 0 || sessionTTL <= 0 {
+		return fmt.Errorf("auth access token and session ttls must be positive")
+	}
+	if accessTTL > sessionTTL {
+		return fmt.Errorf("auth access token ttl (%s) cannot exceed the session ttl (%s)", accessTTL, sessionTTL)
+	}
+	if impTTL, err := time.ParseDuration(c.Auth.ImpersonationTTL); err != nil || impTTL <= 0 || impTTL > maxImpersonationTTL {
+		return fmt.Errorf("auth impersonation ttl must be a duration between 0 and %s, got %q", maxImpersonationTTL, c.Auth.ImpersonationTTL)
+	}
+	if resetTTL, err := time.ParseDuration(c.Auth.PasswordReset.TTL); err != nil || resetTTL <= 0 {
+		return fmt.Errorf("auth password reset ttl must be a positive duration, got %q", c.Auth.PasswordReset.TTL)
+	}
+	if resetURL := c.Auth.PasswordReset.URL; resetURL != "" {
+		if parsed, err := url.Parse(resetURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("auth password reset url must be an absolute http(s) URL, got %q", resetURL)
+		}
+	}
+
 	if len(c.Kafka.Brokers) == 0 {
 		return fmt.Errorf("kafka brokers cannot be empty")
 	}
 
+	switch strings.ToLower(c.Kafka.Compression) {
+	case "", "none", "gzip", "snappy", "lz4", "zstd":
+	default:
+		return fmt.Errorf("unknown kafka compression %q (expected none, gzip, snappy, lz4 or zstd)", c.Kafka.Compression)
+	}
+
 	// Validate feed fetch kafka config
 	if c.Kafka.FeedFetch.Topic == "" {
 		return fmt.Errorf("kafka feed fetch topic cannot be empty")
@@ -288,6 +1402,25 @@ func (c *Config) validate() error {
 	if c.Kafka.AIProcessing.FeedServiceAIGroupID == "" {
 		return fmt.Errorf("kafka feed service AI group ID cannot be empty")
 	}
+	if c.Kafka.AIProcessing.ArticlesRetryTopic != "" && c.Kafka.AIProcessing.AIServiceRetryGroupID == "" {
+		return fmt.Errorf("kafka AI service retry group ID cannot be empty with a retry topic")
+	}
+
+	// Validate shared topic routing config
+	if c.Kafka.Routing.Enabled {
+		if c.Kafka.Routing.Topic == "" {
+			return fmt.Errorf("kafka routing topic cannot be empty")
+		}
+		if len(c.Kafka.Routing.EventTypes) == 0 {
+			return fmt.Errorf("kafka routing event types cannot be empty")
+		}
+		if c.Kafka.Routing.FeedServiceGroupID == "" {
+			return fmt.Errorf("kafka routing feed service group ID cannot be empty")
+		}
+	}
+	if c.Kafka.SchemaRegistry.URL == "" && len(c.Kafka.SchemaRegistry.RequiredTopics) > 0 {
+		return fmt.Errorf("kafka schema registry required topics need a schema registry URL")
+	}
 
 	if c.UserService.Address == "" {
 		return fmt.Errorf("user service address cannot be empty")
@@ -320,6 +1453,72 @@ func (c *Config) validate() error {
 		return fmt.Errorf("feed service article update max content bytes must be positive")
 	}
 
+	if c.FeedService.DeadFeed.Threshold == "" {
+		return fmt.Errorf("feed service dead feed threshold cannot be empty")
+	}
+	if c.FeedService.DeadFeed.CheckInterval == "" {
+		return fmt.Errorf("feed service dead feed check interval cannot be empty")
+	}
+	if c.FeedService.ArticleTrash.GracePeriod == "" {
+		return fmt.Errorf("feed service article trash grace period cannot be empty")
+	}
+	if c.FeedService.ArticleTrash.PurgeInterval == "" {
+		return fmt.Errorf("feed service article trash purge interval cannot be empty")
+	}
+	if c.FeedService.Snapshots.Keep < 0 {
+		return fmt.Errorf("feed service snapshots keep must not be negative")
+	}
+	if c.FeedService.Snapshots.Keep > 0 && c.FeedService.Snapshots.MaxBytes <= 0 {
+		return fmt.Errorf("feed service snapshots max bytes must be positive when snapshots are enabled")
+	}
+	if hostRate := c.Fetch.HostRate; hostRate.RequestsPerSecond < 0 {
+		return fmt.Errorf("fetch host rate requests per second must not be negative")
+	} else if hostRate.RequestsPerSecond > 0 && hostRate.Burst < 1 {
+		return fmt.Errorf("fetch host rate burst must be at least 1")
+	}
+	if hostPause := c.FeedService.ArticleUpdate.HostPause; hostPause.FailureThreshold < 0 {
+		return fmt.Errorf("feed service article update host pause failure threshold must not be negative")
+	} else if hostPause.FailureThreshold > 0 && hostPause.Pause == "" {
+		return fmt.Errorf("feed service article update host pause cannot be empty")
+	}
+	if c.FeedService.CrawlBudget.DailyRequests < 0 {
+		return fmt.Errorf("feed service crawl budget daily requests must not be negative")
+	}
+	if c.FeedService.Import.MaxItemsPerFetch < 0 {
+		return fmt.Errorf("feed service import max items per fetch must not be negative")
+	}
+	if cutoff := c.FeedService.Import.InitialCutoff; cutoff != "" {
+		if d, err := time.ParseDuration(cutoff); err != nil || d < 0 {
+			return fmt.Errorf("feed service import initial cutoff must be a non-negative duration, got %q", cutoff)
+		}
+	}
+	if c.FeedService.Health.FailureThreshold < 1 {
+		return fmt.Errorf("feed service health failure threshold must be at least 1")
+	}
+	if c.FeedService.AIResults.BatchSize < 1 {
+		return fmt.Errorf("feed service AI results batch size must be at least 1")
+	}
+	if period, err := time.ParseDuration(c.FeedService.DrainPeriod); err != nil || period <= 0 {
+		return fmt.Errorf("feed service drain period must be a positive duration, got %q", c.FeedService.DrainPeriod)
+	}
+	if c.FeedService.Outbox.BatchSize < 1 {
+		return fmt.Errorf("feed service outbox batch size must be at least 1")
+	}
+	if _, _, err := c.FeedService.Outbox.Durations(); err != nil {
+		return fmt.Errorf("feed service %w", err)
+	}
+	if r := c.FeedService.DeletedFeedRetention; r != "archive" && r != "purge" {
+		return fmt.Errorf("feed service deleted feed retention must be archive or purge, got %q", r)
+	}
+	if c.FeedService.Alerts.WebhookURL != "" {
+		if f := c.FeedService.Alerts.Format; f != "slack" && f != "json" {
+			return fmt.Errorf("feed service alerts format must be slack or json, got %q", f)
+		}
+		if r := c.FeedService.Alerts.FailureRate; r < 0 || r > 1 {
+			return fmt.Errorf("feed service alerts failure rate must be between 0 and 1")
+		}
+	}
+
 	if c.SchedulerService.Schedule == "" {
 		return fmt.Errorf("scheduler service schedule cannot be empty")
 	}
@@ -335,6 +1534,9 @@ func (c *Config) validate() error {
 	if c.SchedulerService.BatchDelay == "" {
 		return fmt.Errorf("scheduler service batch delay cannot be empty")
 	}
+	if c.SchedulerService.FeedPageSize <= 0 {
+		return fmt.Errorf("scheduler service feed page size must be positive")
+	}
 	if c.SchedulerService.ArticleCheck.Cron == "" {
 		return fmt.Errorf("scheduler article check cron cannot be empty")
 	}
@@ -347,6 +1549,21 @@ func (c *Config) validate() error {
 	if c.SchedulerService.ArticleCheck.PageSize <= 0 {
 		return fmt.Errorf("scheduler article check page size must be positive")
 	}
+	if c.SchedulerService.ArticleCheck.MaxPageSize < 0 || c.SchedulerService.ArticleCheck.MaxPageSize > 2000 {
+		return fmt.Errorf("scheduler article check max page size must be between 0 and 2000")
+	}
+	if c.SchedulerService.OperatorReport.Enabled && c.SchedulerService.OperatorReport.Cron == "" {
+		return fmt.Errorf("scheduler operator report cron cannot be empty")
+	}
+	if c.SchedulerService.OperatorReport.TokenPricePerMillion < 0 {
+		return fmt.Errorf("scheduler operator report token price cannot be negative")
+	}
+	if c.SchedulerService.Engagement.Enabled && c.SchedulerService.Engagement.Cron == "" {
+		return fmt.Errorf("scheduler engagement cron cannot be empty")
+	}
+	if c.SchedulerService.StateCompaction.Enabled && c.SchedulerService.StateCompaction.Cron == "" {
+		return fmt.Errorf("scheduler state compaction cron cannot be empty")
+	}
 
 	if c.AIService.LLMBaseURL == "" {
 		return fmt.Errorf("AI service LLM base URL cannot be empty")
@@ -364,6 +1581,75 @@ func (c *Config) validate() error {
 		return fmt.Errorf("AI service request timeout cannot be empty")
 	}
 
+	if c.AIService.SummaryMaxTokens <= 0 {
+		return fmt.Errorf("AI service summary max tokens must be positive")
+	}
+
+	if c.AIService.ExpandedMaxTokens <= c.AIService.SummaryMaxTokens {
+		return fmt.Errorf("AI service expanded max tokens must be greater than summary max tokens")
+	}
+
+	if len(c.AIService.SummaryPrompts) == 0 {
+		return fmt.Errorf("AI service summary prompts cannot be empty")
+	}
+
+	if c.AIService.PromptExploration < 0 || c.AIService.PromptExploration > 1 {
+		return fmt.Errorf("AI service prompt exploration must be between 0 and 1")
+	}
+
+	if c.AIService.SummaryMaxLength < 0 {
+		return fmt.Errorf("AI service summary max length cannot be negative")
+	}
+	if c.AIService.PromptMinRatings < 0 {
+		return fmt.Errorf("AI service prompt min ratings cannot be negative")
+	}
+
+	if c.AIService.BriefingMaxHeadlines <= 0 {
+		return fmt.Errorf("AI service briefing max headlines must be positive")
+	}
+
+	if c.AIService.BriefingMaxTokens <= 0 {
+		return fmt.Errorf("AI service briefing max tokens must be positive")
+	}
+	if interval, err := time.ParseDuration(c.AIService.DigestInterval); err != nil || interval < 0 {
+		return fmt.Errorf("invalid AI service digest interval %q", c.AIService.DigestInterval)
+	}
+	if c.AIService.BatchSize <= 0 || c.AIService.BatchConcurrency <= 0 {
+		return fmt.Errorf("AI service batch size and batch concurrency must be positive")
+	}
+	if window, err := time.ParseDuration(c.AIService.BatchWindow); err != nil || window < 0 {
+		return fmt.Errorf("invalid AI service batch window %q", c.AIService.BatchWindow)
+	}
+	if retention, err := c.AIService.ProcessedEventRetentionDuration(); err != nil {
+		return err
+	} else if retention > 0 && retention < time.Hour {
+		return fmt.Errorf("AI service processed event retention must be at least 1h, or 0 to turn it off")
+	}
+	if _, err := c.AIService.ParsedModelPrices(); err != nil {
+		return err
+	}
+	if c.AIService.DailyBudgetUSD < 0 || c.AIService.DailyTokenBudget < 0 {
+		return fmt.Errorf("AI service daily budgets cannot be negative")
+	}
+	if c.AIService.LLMMaxConcurrency < 0 || c.AIService.MaxRetries < 0 {
+		return fmt.Errorf("AI service LLM max concurrency and max retries cannot be negative")
+	}
+	if c.AIService.LLMRetryMaxAttempts < 1 {
+		return fmt.Errorf("AI service LLM retry max attempts must be at least 1")
+	}
+	if _, _, _, _, err := c.AIService.RetryDurations(); err != nil {
+		return err
+	}
+
+	if c.Email.SMTPHost != "" {
+		if c.Email.SMTPPort <= 0 {
+			return fmt.Errorf("email SMTP port must be positive")
+		}
+		if c.Email.From == "" {
+			return fmt.Errorf("email from address cannot be empty")
+		}
+	}
+
 	// Warn about default JWT secret in a production environment
 	if c.Auth.JWTSecret == "phoenix-rss-default-secret-please-change-in-production" {
 		// Note: In a real application, you might want to use a logger here
@@ -378,6 +1664,63 @@ func bindEnvironmentVariables(v *viper.Viper) {
 	// Bind all the key environment variables
 	envBindings := []string{
 		"server.port",
+		"server.frontend.mode",
+		"server.frontend.port",
+		"server.frontend.api_origin",
+		"server.frontend.content_security_policy",
+		"server.cors_allowed_origins",
+		"server.trusted_proxies",
+		"server.admin_token",
+		"server.slow_request_threshold",
+		"server.smart_sort.recency_weight",
+		"server.smart_sort.unread_weight",
+		"server.smart_sort.affinity_weight",
+		"server.smart_sort.starred_weight",
+		"server.smart_sort.recency_half_life",
+		"server.login_protection.enabled",
+		"server.login_protection.max_account_failures",
+		"server.login_protection.max_ip_failures",
+		"server.login_protection.failure_window",
+		"server.login_protection.base_lockout",
+		"server.login_protection.max_lockout",
+		"server.login_protection.challenge_after",
+		"server.login_protection.challenge_verify_url",
+		"server.login_protection.challenge_secret",
+		"server.demo.enabled",
+		"server.demo.username",
+		"server.demo.password",
+		"server.demo.feeds",
+		"server.demo.cache_ttl",
+		"server.shared_folders.cache_ttl",
+		"server.shared_folders.rate_limit",
+		"server.grpc_clients.retry_max_attempts",
+		"server.grpc_clients.retry_backoff_initial",
+		"server.grpc_clients.retry_backoff_max",
+		"server.grpc_clients.breaker_failure_threshold",
+		"server.grpc_clients.breaker_open_timeout",
+		"fetch.user_agent",
+		"fetch.from",
+		"fetch.info_url",
+		"fetch.block_private_networks",
+		"fetch.host_rate.requests_per_second",
+		"fetch.host_rate.burst",
+		"tracing.otlp_endpoint",
+		"tracing.otlp_insecure",
+		"tracing.sample_ratio",
+		"summaries.languages",
+		"summaries.max_reader_languages",
+		"policy.file",
+		"policy.max_subscriptions",
+		"exports.storage",
+		"exports.dir",
+		"exports.s3.endpoint",
+		"exports.s3.region",
+		"exports.s3.bucket",
+		"exports.s3.access_key_id",
+		"exports.s3.secret_access_key",
+		"exports.page_size",
+		"exports.poll_interval",
+		"exports.stale_after",
 		"database.host",
 		"database.port",
 		"database.user",
@@ -386,6 +1729,19 @@ func bindEnvironmentVariables(v *viper.Viper) {
 		"database.sslmode",
 		"redis.address",
 		"auth.jwt_secret",
+		"auth.credentials_key",
+		"auth.password_hashing.algorithm",
+		"auth.password_hashing.argon2_memory",
+		"auth.password_hashing.argon2_iterations",
+		"auth.password_hashing.argon2_parallelism",
+		"auth.password_hashing.bcrypt_cost",
+		"auth.access_token_ttl",
+		"auth.session_ttl",
+		"auth.impersonation_ttl",
+		"auth.password_reset.ttl",
+		"auth.password_reset.url",
+		"auth.service_token",
+		"auth.service_token_max_skew",
 		"kafka.brokers",
 		"kafka.feed_fetch.topic",
 		"kafka.feed_fetch.feed_service_group_id",
@@ -393,11 +1749,39 @@ func bindEnvironmentVariables(v *viper.Viper) {
 		"kafka.article_check.feed_service_group_id",
 		"kafka.ai_processing.articles_new_topic",
 		"kafka.ai_processing.articles_processed_topic",
+		"kafka.ai_processing.articles_retry_topic",
+		"kafka.ai_processing.ai_service_retry_group_id",
 		"kafka.ai_processing.ai_service_group_id",
 		"kafka.ai_processing.feed_service_ai_group_id",
+		"kafka.routing.enabled",
+		"kafka.routing.topic",
+		"kafka.routing.event_types",
+		"kafka.routing.strict",
+		"kafka.routing.feed_service_group_id",
+		"kafka.schema_registry.url",
+		"kafka.schema_registry.topics",
+		"kafka.schema_registry.required_topics",
+		"kafka.compression",
+		"kafka.size_report_interval",
 		"user_service.address",
+		"user_service.tls.enabled",
+		"user_service.tls.cert_file",
+		"user_service.tls.key_file",
+		"user_service.tls.client_ca_file",
+		"user_service.tls.ca_file",
+		"user_service.tls.server_name",
+		"user_service.tls.client_cert_file",
+		"user_service.tls.client_key_file",
 		"feed_service.port",
 		"feed_service.address",
+		"feed_service.tls.enabled",
+		"feed_service.tls.cert_file",
+		"feed_service.tls.key_file",
+		"feed_service.tls.client_ca_file",
+		"feed_service.tls.ca_file",
+		"feed_service.tls.server_name",
+		"feed_service.tls.client_cert_file",
+		"feed_service.tls.client_key_file",
 		"feed_service.article_update.http_timeout",
 		"feed_service.article_update.http_user_agent",
 		"feed_service.article_update.http_retry_max_attempts",
@@ -407,18 +1791,94 @@ func bindEnvironmentVariables(v *viper.Viper) {
 		"feed_service.article_update.robots_cache_ttl",
 		"feed_service.article_update.respect_robots",
 		"feed_service.article_update.max_content_bytes",
+		"feed_service.article_update.host_pause.failure_threshold",
+		"feed_service.article_update.host_pause.pause",
+		"feed_service.article_update.on_read.min_age",
+		"feed_service.article_update.on_read.checked_within",
+		"feed_service.article_update.on_read.cooldown",
+		"feed_service.dead_feed.threshold",
+		"feed_service.dead_feed.check_interval",
+		"feed_service.article_trash.grace_period",
+		"feed_service.article_trash.purge_interval",
+		"feed_service.snapshots.keep",
+		"feed_service.snapshots.max_bytes",
+		"feed_service.crawl_budget.daily_requests",
+		"feed_service.health.failure_threshold",
+		"feed_service.import.max_items_per_fetch",
+		"feed_service.import.initial_cutoff",
+		"feed_service.ai_results.batch_size",
+		"feed_service.ai_results.batch_wait",
+		"feed_service.drain_period",
+		"feed_service.outbox.poll_interval",
+		"feed_service.outbox.batch_size",
+		"feed_service.outbox.retention",
+		"feed_service.deleted_feed_retention",
+		"feed_service.alerts.webhook_url",
+		"feed_service.alerts.format",
+		"feed_service.alerts.popular_feed_subscribers",
+		"feed_service.alerts.failure_rate",
+		"feed_service.alerts.failure_rate_window",
+		"feed_service.alerts.failure_rate_min_fetches",
+		"feed_service.alerts.cooldown",
 		"scheduler_service.schedule",
 		"scheduler_service.batch_size",
 		"scheduler_service.batch_delay",
 		"scheduler_service.max_concurrent",
+		"scheduler_service.feed_page_size",
+		"scheduler_service.min_fetch_interval",
+		"scheduler_service.low_tier_fetch_interval",
+		"scheduler_service.catch_up.enabled",
+		"scheduler_service.catch_up.gap",
+		"scheduler_service.catch_up.window",
 		"scheduler_service.article_check.cron",
 		"scheduler_service.article_check.window_days",
 		"scheduler_service.article_check.min_check_interval",
 		"scheduler_service.article_check.page_size",
+		"scheduler_service.article_check.max_page_size",
+		"scheduler_service.operator_report.enabled",
+		"scheduler_service.operator_report.cron",
+		"scheduler_service.operator_report.recipients",
+		"scheduler_service.operator_report.token_price_per_million",
+		"scheduler_service.engagement.enabled",
+		"scheduler_service.engagement.cron",
+		"scheduler_service.state_compaction.enabled",
+		"scheduler_service.state_compaction.cron",
+		"scheduler_service.state_compaction.retention",
 		"ai_service.llm_base_url",
 		"ai_service.llm_api_key",
 		"ai_service.llm_model",
 		"ai_service.request_timeout",
+		"ai_service.summary_max_tokens",
+		"ai_service.expanded_max_tokens",
+		"ai_service.summary_cache_enabled",
+		"ai_service.summary_prompts",
+		"ai_service.prompt_exploration",
+		"ai_service.prompt_min_ratings",
+		"ai_service.prompt_dir",
+		"ai_service.summary_max_length",
+		"ai_service.briefing_max_headlines",
+		"ai_service.briefing_max_tokens",
+		"ai_service.briefing_cache_enabled",
+		"ai_service.digest_interval",
+		"ai_service.batch_size",
+		"ai_service.batch_window",
+		"ai_service.batch_concurrency",
+		"ai_service.processed_event_retention",
+		"ai_service.model_prices",
+		"ai_service.daily_budget_usd",
+		"ai_service.daily_token_budget",
+		"ai_service.llm_max_concurrency",
+		"ai_service.llm_retry_max_attempts",
+		"ai_service.llm_retry_backoff_initial",
+		"ai_service.llm_retry_backoff_max",
+		"ai_service.max_retries",
+		"ai_service.retry_delay",
+		"ai_service.retry_max_delay",
+		"email.smtp_host",
+		"email.smtp_port",
+		"email.smtp_username",
+		"email.smtp_password",
+		"email.from",
 	}
 
 	for _, key := range envBindings {
@@ -441,5 +1901,108 @@ func (c *Config) postProcess(v *viper.Viper) error {
 		}
 	}
 
+	// Routed event types - comma-separated string when set from the environment
+	if typesStr := v.GetString("kafka.routing.event_types"); typesStr != "" {
+		c.Kafka.Routing.EventTypes = nil
+		for _, eventType := range strings.Split(typesStr, ",") {
+			if eventType = strings.TrimSpace(eventType); eventType != "" {
+				c.Kafka.Routing.EventTypes = append(c.Kafka.Routing.EventTypes, eventType)
+			}
+		}
+	}
+
+	// Schema registry topics - comma-separated strings when set from the environment
+	if topicsStr := v.GetString("kafka.schema_registry.topics"); topicsStr != "" {
+		c.Kafka.SchemaRegistry.Topics = nil
+		for _, topic := range strings.Split(topicsStr, ",") {
+			if topic = strings.TrimSpace(topic); topic != "" {
+				c.Kafka.SchemaRegistry.Topics = append(c.Kafka.SchemaRegistry.Topics, topic)
+			}
+		}
+	}
+	if topicsStr := v.GetString("kafka.schema_registry.required_topics"); topicsStr != "" {
+		c.Kafka.SchemaRegistry.RequiredTopics = nil
+		for _, topic := range strings.Split(topicsStr, ",") {
+			if topic = strings.TrimSpace(topic); topic != "" {
+				c.Kafka.SchemaRegistry.RequiredTopics = append(c.Kafka.SchemaRegistry.RequiredTopics, topic)
+			}
+		}
+	}
+
+	// The article update User-Agent predates the global fetch identity
+	if c.Fetch.UserAgent == "" {
+		c.Fetch.UserAgent = c.FeedService.ArticleUpdate.HTTPUserAgent
+	}
+
+	// CORS origins - comma-separated string when set from the environment
+	if originsStr := v.GetString("server.cors_allowed_origins"); originsStr != "" {
+		c.Server.CORSAllowedOrigins = nil
+		for _, origin := range strings.Split(originsStr, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				c.Server.CORSAllowedOrigins = append(c.Server.CORSAllowedOrigins, origin)
+			}
+		}
+	}
+
+	// Trusted proxies - comma-separated string when set from the environment
+	if proxiesStr := v.GetString("server.trusted_proxies"); proxiesStr != "" {
+		c.Server.TrustedProxies = nil
+		for _, proxy := range strings.Split(proxiesStr, ",") {
+			if proxy = strings.TrimSpace(proxy); proxy != "" {
+				c.Server.TrustedProxies = append(c.Server.TrustedProxies, proxy)
+			}
+		}
+	}
+
+	// Demo feeds - comma-separated string when set from the environment
+	if feedsStr := v.GetString("server.demo.feeds"); feedsStr != "" {
+		c.Server.Demo.Feeds = nil
+		for _, feed := range strings.Split(feedsStr, ",") {
+			if feed = strings.TrimSpace(feed); feed != "" {
+				c.Server.Demo.Feeds = append(c.Server.Demo.Feeds, feed)
+			}
+		}
+	}
+
+	// Summary prompt variants - comma-separated string when set from the environment
+	if promptsStr := v.GetString("ai_service.summary_prompts"); promptsStr != "" {
+		c.AIService.SummaryPrompts = nil
+		for _, prompt := range strings.Split(promptsStr, ",") {
+			if prompt = strings.TrimSpace(prompt); prompt != "" {
+				c.AIService.SummaryPrompts = append(c.AIService.SummaryPrompts, prompt)
+			}
+		}
+	}
+
+	// Model prices - comma-separated string when set from the environment
+	if pricesStr := v.GetString("ai_service.model_prices"); pricesStr != "" {
+		c.AIService.ModelPrices = nil
+		for _, price := range strings.Split(pricesStr, ",") {
+			if price = strings.TrimSpace(price); price != "" {
+				c.AIService.ModelPrices = append(c.AIService.ModelPrices, price)
+			}
+		}
+	}
+
+	// Summary languages - comma-separated string when set from the environment
+	if languagesStr := v.GetString("summaries.languages"); languagesStr != "" {
+		c.Summaries.Languages = nil
+		for _, language := range strings.Split(languagesStr, ",") {
+			if language = strings.ToLower(strings.TrimSpace(language)); language != "" {
+				c.Summaries.Languages = append(c.Summaries.Languages, language)
+			}
+		}
+	}
+
+	// Operator report recipients - comma-separated string when set from the environment
+	if recipientsStr := v.GetString("scheduler_service.operator_report.recipients"); recipientsStr != "" {
+		c.SchedulerService.OperatorReport.Recipients = nil
+		for _, recipient := range strings.Split(recipientsStr, ",") {
+			if recipient = strings.TrimSpace(recipient); recipient != "" {
+				c.SchedulerService.OperatorReport.Recipients = append(c.SchedulerService.OperatorReport.Recipients, recipient)
+			}
+		}
+	}
+
 	return nil
 }
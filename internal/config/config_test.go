@@ -0,0 +1,273 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoad_WithOverrides(t *testing.T) {
+	t.Setenv("FEED_SERVICE_PORT", "1111")
+	t.Setenv("AI_SERVICE_LLM_MODEL", "from-env")
+
+	cfg, err := Load(WithOverrides(map[string]any{
+		"feed_service.port": 2222,
+		"kafka.brokers":     []string{"broker-a:9092", "broker-b:9092"},
+		"scheduler_service.operator_report.enabled": false,
+	}))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if cfg.FeedService.Port != 2222 {
+		t.Errorf("override should win over the environment, got port %d", cfg.FeedService.Port)
+	}
+	if cfg.AIService.LLMModel != "from-env" {
+		t.Errorf("environment should still apply to other keys, got model %q", cfg.AIService.LLMModel)
+	}
+	if len(cfg.Kafka.Brokers) != 2 || cfg.Kafka.Brokers[1] != "broker-b:9092" {
+		t.Errorf("unexpected brokers %v", cfg.Kafka.Brokers)
+	}
+	if cfg.SchedulerService.OperatorReport.Enabled {
+		t.Error("expected operator report to be disabled")
+	}
+}
+
+func TestLoad_WithoutEnvironment(t *testing.T) {
+	t.Setenv("AI_SERVICE_LLM_MODEL", "from-env")
+
+	cfg, err := Load(WithoutEnvironment())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.AIService.LLMModel != "gpt-4o-mini" {
+		t.Errorf("expected the default model, got %q", cfg.AIService.LLMModel)
+	}
+}
+
+func TestLoad_InvalidOverride(t *testing.T) {
+	if _, err := Load(WithoutEnvironment(), WithOverrides(map[string]any{"scheduler_service.batch_size": 0})); err == nil {
+		t.Fatal("expected validation error")
+	}
+}
+
+func TestLoad_PasswordHashing(t *testing.T) {
+	cfg, err := Load(WithoutEnvironment(), WithOverrides(map[string]any{"auth.password_hashing.argon2_iterations": 4}))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	params := cfg.Auth.PasswordHashing.Params()
+	if params.Algorithm != "argon2id" || params.Argon2.Iterations != 4 || params.Argon2.Memory != 19456 {
+		t.Errorf("unexpected password hashing params %+v", params)
+	}
+
+	if _, err := Load(WithoutEnvironment(), WithOverrides(map[string]any{"auth.password_hashing.algorithm": "md5"})); err == nil {
+		t.Fatal("expected validation error for an unknown algorithm")
+	}
+}
+
+func TestLoad_ParallelEphemeralPorts(t *testing.T) {
+	const loads = 8
+	keys := []string{"server.port", "feed_service.port"}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, loads)
+	for i := 0; i < loads; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cfg, err := Load(WithoutEnvironment(), WithEphemeralPorts(keys...))
+			if err != nil {
+				errs <- err
+				return
+			}
+			if cfg.Server.Port <= 0 || cfg.Server.Port == cfg.FeedService.Port {
+				errs <- fmt.Errorf("load %d got ports %d and %d", i, cfg.Server.Port, cfg.FeedService.Port)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestLoad_FrontendMode(t *testing.T) {
+	t.Setenv("SERVER_CORS_ALLOWED_ORIGINS", "https://app.example.com, https://cdn.example.com")
+
+	cfg, err := Load(WithOverrides(map[string]any{"server.frontend.mode": FrontendModeSeparate}))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server.Frontend.Port != 8081 {
+		t.Errorf("expected the default frontend port, got %d", cfg.Server.Frontend.Port)
+	}
+	if len(cfg.Server.CORSAllowedOrigins) != 2 || cfg.Server.CORSAllowedOrigins[1] != "https://cdn.example.com" {
+		t.Errorf("unexpected CORS origins %v", cfg.Server.CORSAllowedOrigins)
+	}
+
+	if _, err := Load(WithOverrides(map[string]any{"server.frontend.mode": "cdn"})); err == nil {
+		t.Error("expected an unknown mode to be rejected")
+	}
+	if _, err := Load(WithOverrides(map[string]any{
+		"server.frontend.mode": FrontendModeSeparate,
+		"server.frontend.port": 8080,
+	})); err == nil {
+		t.Error("expected the frontend port to clash with the server port")
+	}
+}
+
+func TestLoad_TrustedProxies(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Server.TrustedProxies) != 0 {
+		t.Errorf("expected no trusted proxies by default, got %v", cfg.Server.TrustedProxies)
+	}
+
+	t.Setenv("SERVER_TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.10")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Server.TrustedProxies) != 2 || cfg.Server.TrustedProxies[1] != "192.168.1.10" {
+		t.Errorf("unexpected trusted proxies %v", cfg.Server.TrustedProxies)
+	}
+
+	t.Setenv("SERVER_TRUSTED_PROXIES", "proxy.internal")
+	if _, err := Load(); err == nil {
+		t.Error("expected a host name to be rejected")
+	}
+}
+
+func TestLoad_FetchUserAgentFallback(t *testing.T) {
+	t.Setenv("FEED_SERVICE_ARTICLE_UPDATE_HTTP_USER_AGENT", "LegacyBot/1.0")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Fetch.UserAgent != "LegacyBot/1.0" {
+		t.Errorf("expected the article update User-Agent as fallback, got %q", cfg.Fetch.UserAgent)
+	}
+
+	t.Setenv("FETCH_USER_AGENT", "NewBot/2.0")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Fetch.UserAgent != "NewBot/2.0" {
+		t.Errorf("expected FETCH_USER_AGENT to win, got %q", cfg.Fetch.UserAgent)
+	}
+}
+
+func TestLoad_SummaryLanguages(t *testing.T) {
+	t.Setenv("SUMMARIES_LANGUAGES", " EN, zh ")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Summaries.Languages) != 2 || cfg.Summaries.DefaultLanguage() != "en" || cfg.Summaries.Languages[1] != "zh" {
+		t.Errorf("unexpected summary languages %v", cfg.Summaries.Languages)
+	}
+
+	t.Setenv("SUMMARIES_LANGUAGES", "en,en")
+	if _, err := Load(); err == nil {
+		t.Error("expected a duplicate summary language to be rejected")
+	}
+}
+
+func TestLoad_ModelPrices(t *testing.T) {
+	t.Setenv("AI_SERVICE_MODEL_PRICES", "gpt-4o-mini=0.15/0.60, gpt-4o = 2.5 / 10")
+	t.Setenv("AI_SERVICE_DAILY_BUDGET_USD", "5.5")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	prices, err := cfg.AIService.ParsedModelPrices()
+	if err != nil || len(prices) != 2 || prices["gpt-4o"] != (ModelPrice{Prompt: 2.5, Completion: 10}) {
+		t.Errorf("unexpected model prices %v (err %v)", prices, err)
+	}
+	if cfg.AIService.DailyBudgetUSD != 5.5 || cfg.AIService.DailyTokenBudget != 0 {
+		t.Errorf("unexpected budget %v usd, %d tokens", cfg.AIService.DailyBudgetUSD, cfg.AIService.DailyTokenBudget)
+	}
+
+	for _, invalid := range []string{"gpt-4o-mini", "gpt-4o-mini=0.15", "=1/2", "gpt-4o-mini=-1/2", "a=1/2,a=3/4"} {
+		t.Setenv("AI_SERVICE_MODEL_PRICES", invalid)
+		if _, err := Load(); err == nil {
+			t.Errorf("expected model prices %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestLoad_AIRetry(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	backoffInitial, backoffMax, retryDelay, retryMaxDelay, err := cfg.AIService.RetryDurations()
+	if err != nil || backoffInitial != time.Second || backoffMax != 30*time.Second || retryDelay != time.Minute || retryMaxDelay != 30*time.Minute {
+		t.Errorf("unexpected retry durations %v %v %v %v (err %v)", backoffInitial, backoffMax, retryDelay, retryMaxDelay, err)
+	}
+	if cfg.Kafka.AIProcessing.ArticlesRetryTopic != "articles.retry" || cfg.AIService.MaxRetries != 5 || cfg.AIService.LLMMaxConcurrency != 4 {
+		t.Errorf("unexpected retry defaults %+v", cfg.AIService)
+	}
+
+	t.Setenv("AI_SERVICE_RETRY_MAX_DELAY", "30s")
+	if _, err := Load(); err == nil {
+		t.Error("expected a max retry delay below the retry delay to be rejected")
+	}
+	t.Setenv("AI_SERVICE_RETRY_MAX_DELAY", "30m")
+	t.Setenv("AI_SERVICE_LLM_RETRY_MAX_ATTEMPTS", "0")
+	if _, err := Load(); err == nil {
+		t.Error("expected 0 LLM retry attempts to be rejected")
+	}
+}
+
+func TestLoad_Policy(t *testing.T) {
+	t.Setenv("POLICY_FILE", "/etc/phoenix/policy.json")
+	t.Setenv("POLICY_MAX_SUBSCRIPTIONS", "200")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Policy.File != "/etc/phoenix/policy.json" || cfg.Policy.MaxSubscriptions != 200 {
+		t.Errorf("unexpected policy config %+v", cfg.Policy)
+	}
+
+	t.Setenv("POLICY_MAX_SUBSCRIPTIONS", "-1")
+	if _, err := Load(); err == nil {
+		t.Error("expected a negative subscription quota to be rejected")
+	}
+}
+
+func TestLoad_TokenTTLs(t *testing.T) {
+	cfg, err := Load(WithoutEnvironment())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	accessTTL, sessionTTL, err := cfg.Auth.TokenTTLs()
+	if err != nil || accessTTL != 15*time.Minute || sessionTTL != 7*24*time.Hour {
+		t.Errorf("unexpected token ttls %s, %s (err %v)", accessTTL, sessionTTL, err)
+	}
+
+	if _, err := Load(WithoutEnvironment(), WithOverrides(map[string]any{"auth.access_token_ttl": "200h"})); err == nil {
+		t.Fatal("expected validation error for an access token outliving its session")
+	}
+}
+
+func TestLoad_PasswordResetURL(t *testing.T) {
+	if _, err := Load(WithoutEnvironment(), WithOverrides(map[string]any{"auth.password_reset.url": "https://rss.example.com/reset"})); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, err := Load(WithoutEnvironment(), WithOverrides(map[string]any{"auth.password_reset.url": "/reset"})); err == nil {
+		t.Fatal("expected validation error for a relative reset URL")
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// Option customizes Load. Every Load builds its own viper instance, so options never
+// leak between callers and configs can be loaded concurrently.
+type Option func(*loadOptions) error
+
+type loadOptions struct {
+	overrides       map[string]any
+	skipEnvironment bool
+	configDir       string
+	profile         *string
+}
+
+// configFiles returns the directory and profile of the config files to read. Without the
+// environment, files are only read from a directory given with WithConfigDir.
+func (o loadOptions) configFiles() (dir, profile string, ok bool) {
+	dir = o.configDir
+	if dir == "" {
+		if o.skipEnvironment {
+			return "", "", false
+		}
+		dir = DefaultConfigDir
+		if fromEnv := os.Getenv(ConfigDirEnvVar); fromEnv != "" {
+			dir = fromEnv
+		}
+	}
+	switch {
+	case o.profile != nil:
+		profile = *o.profile
+	case !o.skipEnvironment:
+		profile = os.Getenv(ProfileEnvVar)
+	}
+	return dir, profile, true
+}
+
+// WithOverrides sets config keys (e.g. "feed_service.port") above every other source
+func WithOverrides(overrides map[string]any) Option {
+	return func(o *loadOptions) error {
+		for key, value := range overrides {
+			o.overrides[key] = value
+		}
+		return nil
+	}
+}
+
+// WithoutEnvironment ignores the .env file and process environment, leaving defaults and
+// overrides only. Tests use it to stay independent of the developer's environment and of
+// tests that call t.Setenv.
+func WithoutEnvironment() Option {
+	return func(o *loadOptions) error {
+		o.skipEnvironment = true
+		return nil
+	}
+}
+
+// WithConfigDir reads config.yaml and the profile's overlay from dir, instead of
+// PHOENIX_CONFIG_DIR or ./config
+func WithConfigDir(dir string) Option {
+	return func(o *loadOptions) error {
+		o.configDir = dir
+		return nil
+	}
+}
+
+// WithProfile selects the profile whose overlay is applied, instead of PHOENIX_ENV. An
+// empty profile applies no overlay.
+func WithProfile(profile string) Option {
+	return func(o *loadOptions) error {
+		o.profile = &profile
+		return nil
+	}
+}
+
+// WithEphemeralPorts overrides the given port keys (e.g. "server.port") with free
+// loopback ports
+func WithEphemeralPorts(keys ...string) Option {
+	return func(o *loadOptions) error {
+		// keep every listener open until all ports are picked so no two keys share one
+		listeners := make([]net.Listener, 0, len(keys))
+		defer func() {
+			for _, lis := range listeners {
+				lis.Close()
+			}
+		}()
+
+		for _, key := range keys {
+			lis, err := ListenEphemeral()
+			if err != nil {
+				return fmt.Errorf("allocate port for %s: %w", key, err)
+			}
+			listeners = append(listeners, lis)
+			o.overrides[key] = lis.Addr().(*net.TCPAddr).Port
+		}
+		return nil
+	}
+}
+
+// ListenEphemeral listens on a free loopback port. Prefer it over EphemeralPort when the
+// listener can be handed to the server, since the port stays reserved.
+func ListenEphemeral() (net.Listener, error) {
+	return net.Listen("tcp", "127.0.0.1:0")
+}
+
+// EphemeralPort returns a loopback port that was free when checked. Another process
+// may still take it before it is bound.
+func EphemeralPort() (int, error) {
+	lis, err := ListenEphemeral()
+	if err != nil {
+		return 0, err
+	}
+	defer lis.Close()
+	return lis.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Settings are read from these sources, each overriding the ones before it:
+//
+//  1. the defaults in setDefaults
+//  2. config.yaml in the config directory
+//  3. config.<profile>.yaml in the config directory, for the profile named by PHOENIX_ENV
+//  4. environment variables, which docker compose fills from .env
+//  5. Load options such as WithOverrides
+//
+// Both files are YAML with the nesting of the setting keys (feed_service.port is port
+// under feed_service). A file setting a key Config does not have fails the load.
+const (
+	// ProfileEnvVar names the profile whose overlay is applied over config.yaml
+	ProfileEnvVar = "PHOENIX_ENV"
+	// ConfigDirEnvVar overrides the directory the config files are read from
+	ConfigDirEnvVar = "PHOENIX_CONFIG_DIR"
+	// DefaultConfigDir is the config directory, relative to the working directory
+	DefaultConfigDir = "config"
+
+	baseConfigFile = "config.yaml"
+)
+
+var profilePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ProfileFile is the name of a profile's overlay file
+func ProfileFile(profile string) string {
+	return "config." + profile + ".yaml"
+}
+
+// mergeConfigFiles layers config.yaml and the profile's overlay over the defaults. The
+// base file is optional, but a profile's overlay must exist so a mistyped PHOENIX_ENV
+// does not silently fall back to the base settings.
+func mergeConfigFiles(v *viper.Viper, dir, profile string) error {
+	if err := mergeConfigFile(v, filepath.Join(dir, baseConfigFile), false); err != nil {
+		return err
+	}
+	if profile == "" {
+		return nil
+	}
+	if !profilePattern.MatchString(profile) {
+		return fmt.Errorf("invalid %s %q: use lowercase letters, digits, - and _", ProfileEnvVar, profile)
+	}
+	return mergeConfigFile(v, filepath.Join(dir, ProfileFile(profile)), true)
+}
+
+func mergeConfigFile(v *viper.Viper, path string, required bool) error {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) && !required {
+			return nil
+		}
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	file := viper.New()
+	file.SetConfigFile(path)
+	file.SetConfigType("yaml")
+	if err := file.ReadInConfig(); err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	if unknown := unknownKeys(file.AllKeys()); len(unknown) > 0 {
+		return fmt.Errorf("%s: unknown settings %s", path, strings.Join(unknown, ", "))
+	}
+	if err := v.MergeConfigMap(file.AllSettings()); err != nil {
+		return fmt.Errorf("error merging %s: %w", path, err)
+	}
+	return nil
+}
+
+// unknownKeys returns the keys, sorted, that do not name a setting of Config
+func unknownKeys(keys []string) []string {
+	known := settingKeys()
+	var unknown []string
+	for _, key := range keys {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// settingKeys lists the key of every setting of Config, from its mapstructure tags
+func settingKeys() map[string]bool {
+	keys := make(map[string]bool)
+	var walk func(t reflect.Type, prefix string)
+	walk = func(t reflect.Type, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("mapstructure")
+			if tag == "" || tag == "-" {
+				continue
+			}
+			if field.Type.Kind() == reflect.Struct {
+				walk(field.Type, prefix+tag+".")
+				continue
+			}
+			keys[prefix+tag] = true
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "")
+	return keys
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func TestLoad_ConfigProfiles(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, "config.yaml", `
+feed_service:
+  port: 6000
+  address: feed-service:6000
+ai_service:
+  llm_model: base-model
+kafka:
+  brokers: [kafka-a:9092, kafka-b:9092]
+`)
+	writeConfigFile(t, dir, ProfileFile("production"), `
+feed_service:
+  port: 7000
+`)
+	t.Setenv(ProfileEnvVar, "production")
+	t.Setenv("AI_SERVICE_LLM_MODEL", "env-model")
+
+	cfg, err := Load(WithConfigDir(dir))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.FeedService.Port != 7000 {
+		t.Errorf("the overlay should win over config.yaml, got port %d", cfg.FeedService.Port)
+	}
+	if cfg.FeedService.Address != "feed-service:6000" {
+		t.Errorf("config.yaml should apply to keys the overlay leaves out, got address %q", cfg.FeedService.Address)
+	}
+	if cfg.AIService.LLMModel != "env-model" {
+		t.Errorf("the environment should win over the files, got model %q", cfg.AIService.LLMModel)
+	}
+	if len(cfg.Kafka.Brokers) != 2 || cfg.Kafka.Brokers[1] != "kafka-b:9092" {
+		t.Errorf("unexpected brokers %v", cfg.Kafka.Brokers)
+	}
+
+	cfg, err = Load(WithConfigDir(dir), WithProfile(""), WithOverrides(map[string]any{"ai_service.llm_model": "override"}))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.FeedService.Port != 6000 || cfg.AIService.LLMModel != "override" {
+		t.Errorf("expected the base file and the override, got port %d and model %q", cfg.FeedService.Port, cfg.AIService.LLMModel)
+	}
+}
+
+func TestLoad_ConfigProfileErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, ProfileFile("typo"), `
+feed_service:
+  prot: 7000
+server:
+  demo:
+    enabled: true
+    usernam: demo
+`)
+	writeConfigFile(t, dir, ProfileFile("badtype"), `
+feed_service:
+  port: seventy
+`)
+
+	tests := []struct {
+		profile string
+		want    string
+	}{
+		{"staging", "config.staging.yaml"},
+		{"Production", "invalid PHOENIX_ENV"},
+		{"typo", "unknown settings feed_service.prot, server.demo.usernam"},
+		{"badtype", "unable to decode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			_, err := Load(WithoutEnvironment(), WithConfigDir(dir), WithProfile(tt.profile))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoad_ConfigFilesOptional(t *testing.T) {
+	if _, err := Load(WithoutEnvironment(), WithConfigDir(t.TempDir())); err != nil {
+		t.Fatalf("a missing config.yaml should fall back to the defaults: %v", err)
+	}
+}
+
+func TestSettingKeys(t *testing.T) {
+	keys := settingKeys()
+	for _, key := range []string{"server.port", "feed_service.tls.cert_file", "auth.password_hashing.argon2_memory", "exports.s3.bucket"} {
+		if !keys[key] {
+			t.Errorf("expected setting %s", key)
+		}
+	}
+	if keys["feed_service"] || keys["feed_service.tls"] {
+		t.Error("sections are not settings")
+	}
+}
+
+// TestLoad_ExampleConfigFiles keeps the examples under config/ in step with the settings
+func TestLoad_ExampleConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	for example, name := range map[string]string{
+		"config.example.yaml":            "config.yaml",
+		"config.production.example.yaml": ProfileFile("production"),
+	} {
+		content, err := os.ReadFile(filepath.Join("..", "..", DefaultConfigDir, example))
+		if err != nil {
+			t.Fatalf("read %s: %v", example, err)
+		}
+		writeConfigFile(t, dir, name, string(content))
+	}
+
+	cfg, err := Load(WithoutEnvironment(), WithConfigDir(dir), WithProfile("production"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !cfg.FeedService.TLS.Enabled || cfg.FeedService.Address != "feed-service:50053" {
+		t.Errorf("expected the overlay over the base file, got %+v", cfg.FeedService)
+	}
+}
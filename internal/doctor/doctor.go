@@ -0,0 +1,248 @@
+// Package doctor finds and repairs data inconsistencies that the foreign keys do not rule
+// out: rows left behind by hand edits or partial restores, feeds stored twice under
+// equivalent URLs, articles whose AI processing never reported back and subscriber counts
+// that no longer match the subscriptions.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// DefaultStuckAfter is how long an article may stay in processing before it counts as stuck
+const DefaultStuckAfter = 24 * time.Hour
+
+// maxSamples caps the example rows reported with each finding
+const maxSamples = 5
+
+// Finding is what one check found, and repaired when fixing was asked for
+type Finding struct {
+	Check       string   `json:"check"`
+	Description string   `json:"description"`
+	Repair      string   `json:"repair"`
+	Count       int64    `json:"count"`
+	Samples     []string `json:"samples,omitempty"`
+	Fixed       int64    `json:"fixed"`
+}
+
+// check is one kind of inconsistency: the rows of table matching where have it
+type check struct {
+	name        string
+	description string
+	repair      string
+	table       string
+	where       string
+	args        []any
+	// label names a row in the samples
+	label string
+	fix   func(tx *gorm.DB, where string, args []any) (int64, error)
+}
+
+// Doctor runs the integrity checks against the shared database
+type Doctor struct {
+	db         *gorm.DB
+	stuckAfter time.Duration
+	now        func() time.Time
+}
+
+func New(db *gorm.DB, stuckAfter time.Duration) *Doctor {
+	if stuckAfter <= 0 {
+		stuckAfter = DefaultStuckAfter
+	}
+	return &Doctor{db: db, stuckAfter: stuckAfter, now: time.Now}
+}
+
+// sameFeedURL matches a feed with a lower ID whose URL only differs in case or a
+// trailing slash; the feed with the lowest ID of such a group is the one kept
+const sameFeedURL = "LOWER(RTRIM(keeper.url, '/')) = LOWER(RTRIM(feeds.url, '/')) AND keeper.id < feeds.id"
+
+func (d *Doctor) checks() []check {
+	return []check{
+		{
+			name:        "subscriptions_missing_feed",
+			description: "subscriptions to feeds that no longer exist",
+			repair:      "delete the subscriptions",
+			table:       "subscriptions",
+			where:       "NOT EXISTS (SELECT 1 FROM feeds WHERE feeds.id = subscriptions.feed_id)",
+			label:       "CAST(subscriptions.user_id AS TEXT) || ':' || CAST(subscriptions.feed_id AS TEXT)",
+			fix:         deleteRows("subscriptions"),
+		},
+		{
+			name:        "subscriptions_missing_user",
+			description: "subscriptions of users that no longer exist",
+			repair:      "delete the subscriptions",
+			table:       "subscriptions",
+			where:       "NOT EXISTS (SELECT 1 FROM users WHERE users.id = subscriptions.user_id)",
+			label:       "CAST(subscriptions.user_id AS TEXT) || ':' || CAST(subscriptions.feed_id AS TEXT)",
+			fix:         deleteRows("subscriptions"),
+		},
+		{
+			name:        "articles_missing_feed",
+			description: "articles, trashed ones included, of feeds that no longer exist",
+			repair:      "delete the articles",
+			table:       "articles",
+			where:       "NOT EXISTS (SELECT 1 FROM feeds WHERE feeds.id = articles.feed_id)",
+			label:       "CAST(articles.id AS TEXT)",
+			fix:         deleteRows("articles"),
+		},
+		{
+			name:        "snapshots_missing_feed",
+			description: "raw feed snapshots of feeds that no longer exist",
+			repair:      "delete the snapshots",
+			table:       "feed_snapshots",
+			where:       "NOT EXISTS (SELECT 1 FROM feeds WHERE feeds.id = feed_snapshots.feed_id)",
+			label:       "CAST(feed_snapshots.id AS TEXT)",
+			fix:         deleteRows("feed_snapshots"),
+		},
+		{
+			name:        "notifications_orphaned",
+			description: "notifications of users or feeds that no longer exist",
+			repair:      "delete the notifications",
+			table:       "notifications",
+			where: "NOT EXISTS (SELECT 1 FROM users WHERE users.id = notifications.user_id)" +
+				" OR (notifications.feed_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM feeds WHERE feeds.id = notifications.feed_id))",
+			label: "CAST(notifications.id AS TEXT)",
+			fix:   deleteRows("notifications"),
+		},
+		{
+			name:        "llm_credentials_missing_user",
+			description: "LLM credentials of users that no longer exist",
+			repair:      "delete the credentials",
+			table:       "user_llm_credentials",
+			where:       "NOT EXISTS (SELECT 1 FROM users WHERE users.id = user_llm_credentials.user_id)",
+			label:       "CAST(user_llm_credentials.user_id AS TEXT)",
+			fix:         deleteRows("user_llm_credentials"),
+		},
+		{
+			name:        "duplicate_feed_urls",
+			description: "feeds whose URL only differs from an older feed's in case or a trailing slash",
+			repair:      "merge them into the oldest feed: move subscriptions, articles and notifications, then delete the duplicate",
+			table:       "feeds",
+			where:       "EXISTS (SELECT 1 FROM feeds keeper WHERE " + sameFeedURL + ")",
+			label:       "CAST(feeds.id AS TEXT) || ' ' || feeds.url",
+			fix:         mergeDuplicateFeeds,
+		},
+		{
+			name:        "stuck_processing",
+			description: fmt.Sprintf("articles in AI processing for more than %s without a result", d.stuckAfter),
+			repair:      "reset them to pending so they can be queued again with `phoenix-admin ai process`",
+			table:       "articles",
+			where:       "articles.processing_status = ? AND articles.updated_at < ? AND articles.deleted_at IS NULL",
+			args:        []any{models.ProcessingInProgress, d.now().Add(-d.stuckAfter)},
+			label:       "CAST(articles.id AS TEXT)",
+			fix:         resetStuckProcessing,
+		},
+		{
+			// last, as the repairs above delete and move subscriptions
+			name:        "feed_subscriber_counts",
+			description: "feeds whose subscriber count does not match their subscriptions",
+			repair:      "recount their subscribers",
+			table:       "feeds",
+			where:       "feeds.subscriber_count <> " + countSubscribers,
+			label:       "CAST(feeds.id AS TEXT) || ' ' || feeds.url",
+			fix:         recountSubscribers,
+		},
+	}
+}
+
+// Run runs every check and reports what it found. With fix set each check's findings are
+// repaired in a transaction of their own; a failing repair stops the run and is rolled
+// back, while the repairs before it stay applied.
+func (d *Doctor) Run(ctx context.Context, fix bool) ([]Finding, error) {
+	var findings []Finding
+	for _, c := range d.checks() {
+		finding := Finding{Check: c.name, Description: c.description, Repair: c.repair}
+
+		query := d.db.WithContext(ctx).Table(c.table).Where(c.where, c.args...)
+		if err := query.Session(&gorm.Session{}).Count(&finding.Count).Error; err != nil {
+			return findings, fmt.Errorf("check %s: %w", c.name, err)
+		}
+		if finding.Count > 0 {
+			if err := query.Session(&gorm.Session{}).Order(c.label).Limit(maxSamples).Pluck(c.label, &finding.Samples).Error; err != nil {
+				return findings, fmt.Errorf("check %s: %w", c.name, err)
+			}
+		}
+
+		if fix && finding.Count > 0 {
+			err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				fixed, err := c.fix(tx, c.where, c.args)
+				finding.Fixed = fixed
+				return err
+			})
+			if err != nil {
+				finding.Fixed = 0
+				findings = append(findings, finding)
+				return findings, fmt.Errorf("repair %s: %w", c.name, err)
+			}
+		}
+		findings = append(findings, finding)
+	}
+	return findings, nil
+}
+
+// deleteRows repairs by deleting the matching rows of table
+func deleteRows(table string) func(tx *gorm.DB, where string, args []any) (int64, error) {
+	return func(tx *gorm.DB, where string, args []any) (int64, error) {
+		result := tx.Exec("DELETE FROM "+table+" WHERE "+where, args...)
+		return result.RowsAffected, result.Error
+	}
+}
+
+// countSubscribers counts the subscriptions of the feed of the outer query
+const countSubscribers = "(SELECT COUNT(*) FROM subscriptions WHERE subscriptions.feed_id = feeds.id)"
+
+func recountSubscribers(tx *gorm.DB, where string, args []any) (int64, error) {
+	result := tx.Exec("UPDATE feeds SET subscriber_count = "+countSubscribers+" WHERE "+where, args...)
+	return result.RowsAffected, result.Error
+}
+
+func resetStuckProcessing(tx *gorm.DB, where string, args []any) (int64, error) {
+	result := tx.Table("articles").Where(where, args...).Updates(map[string]interface{}{
+		"processing_status": models.ProcessingPending,
+		"processing_error":  nil,
+		"updated_at":        time.Now(),
+	})
+	return result.RowsAffected, result.Error
+}
+
+// mergeDuplicateFeeds folds every duplicate feed into the oldest feed with the same URL.
+// A subscriber of both keeps the subscription, and its settings, on the kept feed.
+func mergeDuplicateFeeds(tx *gorm.DB, where string, args []any) (int64, error) {
+	var pairs []struct {
+		DuplicateID uint
+		KeeperID    uint
+	}
+	if err := tx.Table("feeds").
+		Select("feeds.id AS duplicate_id, (SELECT MIN(keeper.id) FROM feeds keeper WHERE "+sameFeedURL+") AS keeper_id").
+		Where(where, args...).
+		Order("feeds.id").
+		Scan(&pairs).Error; err != nil {
+		return 0, err
+	}
+
+	for _, pair := range pairs {
+		steps := []struct {
+			sql  string
+			args []any
+		}{
+			{"UPDATE subscriptions SET feed_id = ? WHERE feed_id = ? AND user_id NOT IN (SELECT user_id FROM subscriptions WHERE feed_id = ?)",
+				[]any{pair.KeeperID, pair.DuplicateID, pair.KeeperID}},
+			{"DELETE FROM subscriptions WHERE feed_id = ?", []any{pair.DuplicateID}},
+			{"UPDATE articles SET feed_id = ? WHERE feed_id = ?", []any{pair.KeeperID, pair.DuplicateID}},
+			{"UPDATE notifications SET feed_id = ? WHERE feed_id = ?", []any{pair.KeeperID, pair.DuplicateID}},
+			{"DELETE FROM feed_snapshots WHERE feed_id = ?", []any{pair.DuplicateID}},
+			{"DELETE FROM feeds WHERE id = ?", []any{pair.DuplicateID}},
+		}
+		for _, step := range steps {
+			if err := tx.Exec(step.sql, step.args...).Error; err != nil {
+				return 0, fmt.Errorf("merge feed %d into %d: %w", pair.DuplicateID, pair.KeeperID, err)
+			}
+		}
+	}
+	return int64(len(pairs)), nil
+}
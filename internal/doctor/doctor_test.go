@@ -0,0 +1,141 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	usermodels "github.com/Fancu1/phoenix-rss/internal/user-service/models"
+)
+
+// setupDoctorDB leaves foreign keys off so the inconsistencies can be seeded
+func setupDoctorDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&usermodels.User{}, &usermodels.LLMCredential{},
+		&models.Feed{}, &models.Article{}, &models.Subscription{}, &models.Notification{}, &models.FeedSnapshot{},
+	))
+	return db
+}
+
+func findingsByCheck(findings []Finding) map[string]Finding {
+	out := make(map[string]Finding, len(findings))
+	for _, finding := range findings {
+		out[finding.Check] = finding
+	}
+	return out
+}
+
+func TestDoctor_FindsAndFixes(t *testing.T) {
+	db := setupDoctorDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	require.NoError(t, db.Create(&usermodels.User{ID: 1, Username: "alice", PasswordHash: "x"}).Error)
+	require.NoError(t, db.Create(&usermodels.User{ID: 2, Username: "bob", PasswordHash: "x"}).Error)
+	feeds := []*models.Feed{
+		{ID: 1, Title: "Original", URL: "https://example.com/feed"},
+		{ID: 2, Title: "Duplicate", URL: "https://Example.com/feed/"},
+		{ID: 3, Title: "Other", URL: "https://other.example.com/feed"},
+	}
+	require.NoError(t, db.Create(feeds).Error)
+
+	notes := "kept"
+	require.NoError(t, db.Create([]*models.Subscription{
+		{UserID: 1, FeedID: 1, Notes: &notes},
+		{UserID: 1, FeedID: 2},
+		{UserID: 2, FeedID: 2},
+		{UserID: 1, FeedID: 99}, // missing feed
+		{UserID: 7, FeedID: 3},  // missing user
+	}).Error)
+
+	stuckSince := now.Add(-48 * time.Hour)
+	require.NoError(t, db.Create([]*models.Article{
+		{ID: 1, FeedID: 2, URL: "https://example.com/a1", PublishedAt: now},
+		{ID: 2, FeedID: 99, URL: "https://example.com/a2", PublishedAt: now},
+		{ID: 3, FeedID: 3, URL: "https://example.com/a3", PublishedAt: now},
+		{ID: 4, FeedID: 3, URL: "https://example.com/a4", PublishedAt: now},
+	}).Error)
+	require.NoError(t, db.Model(&models.Article{}).Where("id = ?", 3).UpdateColumns(map[string]any{
+		"processing_status": models.ProcessingInProgress, "updated_at": stuckSince,
+	}).Error)
+	require.NoError(t, db.Model(&models.Article{}).Where("id = ?", 4).UpdateColumns(map[string]any{
+		"processing_status": models.ProcessingInProgress, "updated_at": now,
+	}).Error)
+
+	missingFeed := uint(99)
+	require.NoError(t, db.Create([]*models.Notification{
+		{UserID: 1, Message: "fine"},
+		{UserID: 7, Message: "missing user"},
+		{UserID: 1, FeedID: &missingFeed, Message: "missing feed"},
+	}).Error)
+	require.NoError(t, db.Create(&usermodels.LLMCredential{UserID: 7, APIKeyCiphertext: "x"}).Error)
+	require.NoError(t, db.Create(&models.FeedSnapshot{FeedID: 99, FetchedAt: now}).Error)
+
+	doctor := New(db, 24*time.Hour)
+	findings, err := doctor.Run(ctx, false)
+	require.NoError(t, err)
+	byCheck := findingsByCheck(findings)
+	for check, want := range map[string]int64{
+		"subscriptions_missing_feed":   1,
+		"subscriptions_missing_user":   1,
+		"articles_missing_feed":        1,
+		"snapshots_missing_feed":       1,
+		"notifications_orphaned":       2,
+		"llm_credentials_missing_user": 1,
+		"duplicate_feed_urls":          1,
+		"stuck_processing":             1,
+		"feed_subscriber_counts":       3, // the seeded subscriptions bypassed the trigger
+	} {
+		assert.Equal(t, want, byCheck[check].Count, check)
+		assert.Zero(t, byCheck[check].Fixed, check)
+	}
+	assert.Equal(t, []string{"1:99"}, byCheck["subscriptions_missing_feed"].Samples)
+	assert.Equal(t, []string{"2 https://Example.com/feed/"}, byCheck["duplicate_feed_urls"].Samples)
+
+	findings, err = doctor.Run(ctx, true)
+	require.NoError(t, err)
+	for _, finding := range findings {
+		assert.Equal(t, finding.Count, finding.Fixed, finding.Check)
+	}
+
+	// the duplicate feed is folded into the original
+	var feedCount int64
+	require.NoError(t, db.Model(&models.Feed{}).Where("id = ?", 2).Count(&feedCount).Error)
+	assert.Zero(t, feedCount)
+	var subs []models.Subscription
+	require.NoError(t, db.Order("user_id, feed_id").Find(&subs).Error)
+	require.Len(t, subs, 2)
+	assert.Equal(t, uint(1), subs[0].FeedID)
+	assert.Equal(t, "kept", *subs[0].Notes, "the subscription to the kept feed wins")
+	assert.Equal(t, uint(2), subs[1].UserID)
+	assert.Equal(t, uint(1), subs[1].FeedID)
+	var kept models.Feed
+	require.NoError(t, db.First(&kept, 1).Error)
+	assert.EqualValues(t, 2, kept.SubscriberCount, "the kept feed counts the merged subscribers")
+	var moved models.Article
+	require.NoError(t, db.First(&moved, 1).Error)
+	assert.Equal(t, uint(1), moved.FeedID)
+
+	var stuck, recent models.Article
+	require.NoError(t, db.First(&stuck, 3).Error)
+	require.NoError(t, db.First(&recent, 4).Error)
+	assert.Equal(t, models.ProcessingPending, stuck.ProcessingStatus)
+	assert.Equal(t, models.ProcessingInProgress, recent.ProcessingStatus)
+
+	findings, err = doctor.Run(ctx, false)
+	require.NoError(t, err)
+	for _, finding := range findings {
+		assert.Zero(t, finding.Count, finding.Check)
+	}
+}
@@ -0,0 +1,83 @@
+// Package engagement measures how much of each subscription its user reads: the articles
+// read out of those delivered since the user subscribed, over the last 90 days. Feeds a
+// user never reads are offered for cleanup.
+package engagement
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// Window is the span of articles counted for each subscription
+const Window = 90 * 24 * time.Hour
+
+// upsertBatchSize caps the rows written per statement
+const upsertBatchSize = 500
+
+// Job recomputes the engagement of every subscription
+type Job struct {
+	db     *gorm.DB
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+func NewJob(db *gorm.DB, logger *slog.Logger) *Job {
+	return &Job{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Run replaces the stored engagement with a fresh count and drops the rows of
+// subscriptions that no longer exist. It returns the number of subscriptions counted.
+func (j *Job) Run(ctx context.Context) (int, error) {
+	now := j.now().UTC()
+	since := now.Add(-Window)
+
+	var rows []models.SubscriptionEngagement
+	err := j.db.WithContext(ctx).Raw(`
+		SELECT s.user_id, s.feed_id,
+			COUNT(a.id) AS delivered,
+			COALESCE(SUM(CASE WHEN uas.read THEN 1 ELSE 0 END), 0) AS read_count
+		FROM subscriptions s
+		LEFT JOIN articles a ON a.feed_id = s.feed_id AND a.created_at >= ? AND a.created_at >= s.created_at
+		LEFT JOIN user_article_states uas ON uas.article_id = a.id AND uas.user_id = s.user_id
+		GROUP BY s.user_id, s.feed_id`, since).
+		Scan(&rows).Error
+	if err != nil {
+		return 0, fmt.Errorf("count subscription engagement: %w", err)
+	}
+	for i := range rows {
+		rows[i].ComputedAt = now
+	}
+
+	err = j.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(rows) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "feed_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"delivered", "read_count", "computed_at"}),
+			}).CreateInBatches(rows, upsertBatchSize).Error
+			if err != nil {
+				return fmt.Errorf("store subscription engagement: %w", err)
+			}
+		}
+		if err := tx.Where("computed_at < ?", now).Delete(&models.SubscriptionEngagement{}).Error; err != nil {
+			return fmt.Errorf("drop stale subscription engagement: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	j.logger.Info("subscription engagement computed", "subscriptions", len(rows), "since", since)
+	return len(rows), nil
+}
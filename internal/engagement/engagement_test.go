@@ -0,0 +1,88 @@
+package engagement
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+func TestJob_Run(t *testing.T) {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Feed{}, &models.Article{}, &models.Subscription{}, &models.SubscriptionEngagement{}, &models.UserArticleState{}))
+
+	now := time.Date(2026, 6, 1, 3, 30, 0, 0, time.UTC)
+	job := NewJob(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	job.now = func() time.Time { return now }
+
+	feeds := []*models.Feed{
+		{Title: "Read", URL: "https://read.example.com"},
+		{Title: "Ignored", URL: "https://ignored.example.com"},
+	}
+	require.NoError(t, db.Create(feeds).Error)
+
+	subscribed := now.Add(-100 * 24 * time.Hour)
+	require.NoError(t, db.Create([]models.Subscription{
+		{UserID: 1, FeedID: feeds[0].ID, CreatedAt: subscribed},
+		{UserID: 1, FeedID: feeds[1].ID, CreatedAt: subscribed},
+		// subscribed yesterday: only the newest article was delivered to user 2
+		{UserID: 2, FeedID: feeds[1].ID, CreatedAt: now.Add(-24 * time.Hour)},
+	}).Error)
+
+	articles := []*models.Article{
+		{FeedID: feeds[0].ID, URL: "https://read.example.com/1", CreatedAt: now.Add(-10 * 24 * time.Hour)},
+		{FeedID: feeds[0].ID, URL: "https://read.example.com/2", CreatedAt: now.Add(-5 * 24 * time.Hour)},
+		// outside the window
+		{FeedID: feeds[0].ID, URL: "https://read.example.com/old", CreatedAt: now.Add(-95 * 24 * time.Hour)},
+		{FeedID: feeds[1].ID, URL: "https://ignored.example.com/1", CreatedAt: now.Add(-20 * 24 * time.Hour)},
+		{FeedID: feeds[1].ID, URL: "https://ignored.example.com/2", CreatedAt: now.Add(-time.Hour)},
+	}
+	require.NoError(t, db.Create(articles).Error)
+	require.NoError(t, db.Create([]models.UserArticleState{
+		{UserID: 1, ArticleID: articles[0].ID, Read: true},
+		{UserID: 1, ArticleID: articles[2].ID, Read: true},
+		// read by someone who is not subscribed to the feed
+		{UserID: 2, ArticleID: articles[1].ID, Read: true},
+	}).Error)
+
+	// a row of a subscription that is gone
+	require.NoError(t, db.Create(&models.SubscriptionEngagement{UserID: 3, FeedID: feeds[0].ID, Delivered: 9, ComputedAt: now.Add(-24 * time.Hour)}).Error)
+
+	counted, err := job.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, counted)
+
+	var rows []models.SubscriptionEngagement
+	require.NoError(t, db.Order("user_id, feed_id").Find(&rows).Error)
+	require.Len(t, rows, 3)
+	got := make(map[[2]uint][2]int64)
+	for _, row := range rows {
+		got[[2]uint{row.UserID, row.FeedID}] = [2]int64{row.Delivered, row.ReadCount}
+	}
+	assert.Equal(t, map[[2]uint][2]int64{
+		{1, feeds[0].ID}: {2, 1},
+		{1, feeds[1].ID}: {2, 0},
+		{2, feeds[1].ID}: {1, 0},
+	}, got)
+
+	// a second run updates the rows in place
+	require.NoError(t, db.Create(&models.UserArticleState{UserID: 1, ArticleID: articles[3].ID, Read: true}).Error)
+	now = now.Add(24 * time.Hour)
+	_, err = job.Run(context.Background())
+	require.NoError(t, err)
+	var row models.SubscriptionEngagement
+	require.NoError(t, db.Where("user_id = ? AND feed_id = ?", 1, feeds[1].ID).First(&row).Error)
+	assert.Equal(t, int64(1), row.ReadCount)
+	assert.True(t, row.ComputedAt.Equal(now))
+}
@@ -8,6 +8,9 @@ import (
 	"time"
 
 	"github.com/segmentio/kafka-go"
+
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/tracing"
 )
 
 type ArticleCheckEvent struct {
@@ -35,6 +38,7 @@ type ArticleCheckConsumer interface {
 type KafkaArticleCheckProducer struct {
 	logger *slog.Logger
 	writer *kafka.Writer
+	sizes  *MessageSizes
 }
 
 func NewKafkaArticleCheckProducer(logger *slog.Logger, cfg KafkaConfig) *KafkaArticleCheckProducer {
@@ -46,10 +50,22 @@ func NewKafkaArticleCheckProducer(logger *slog.Logger, cfg KafkaConfig) *KafkaAr
 	return &KafkaArticleCheckProducer{logger: logger, writer: writer}
 }
 
-func (p *KafkaArticleCheckProducer) PublishArticleCheck(ctx context.Context, event ArticleCheckEvent) error {
+// SetProducerOptions compresses the messages and counts their sizes as configured
+func (p *KafkaArticleCheckProducer) SetProducerOptions(opts ProducerOptions) {
+	p.writer.Compression = opts.Compression
+	p.sizes = opts.Sizes
+}
+
+func (p *KafkaArticleCheckProducer) PublishArticleCheck(ctx context.Context, event ArticleCheckEvent) (err error) {
+	ctx, span := StartPublishSpan(ctx, p.writer.Topic, EventArticleCheck)
+	defer func() { tracing.End(span, err) }()
+
 	if event.Attempt <= 0 {
 		event.Attempt = 1
 	}
+	if event.RequestID == "" {
+		event.RequestID, _ = logger.GetRequestID(ctx)
+	}
 
 	payload, err := json.Marshal(event)
 	if err != nil {
@@ -57,11 +73,16 @@ func (p *KafkaArticleCheckProducer) PublishArticleCheck(ctx context.Context, eve
 	}
 
 	key := fmt.Sprintf("%d", event.ArticleID)
-	message := kafka.Message{Key: []byte(key), Value: payload}
+	message := kafka.Message{
+		Key:     []byte(key),
+		Value:   payload,
+		Headers: WithContextHeaders(ctx, event.RequestID, []kafka.Header{{Key: EventTypeHeader, Value: []byte(EventArticleCheck)}}),
+	}
 
 	if err := p.writer.WriteMessages(ctx, message); err != nil {
 		return fmt.Errorf("failed to write article check message: %w", err)
 	}
+	p.sizes.Observe(p.writer.Topic, message)
 
 	p.logger.Info("published article check event", "article_id", event.ArticleID, "topic", p.writer.Topic, "request_id", event.RequestID)
 	return nil
@@ -113,7 +134,10 @@ func (c *KafkaArticleCheckConsumer) Start(ctx context.Context) error {
 			continue
 		}
 
-		if err := c.handler(ctx, event); err != nil {
+		handlerCtx, span := StartConsumeSpan(ContextWithRequestID(ctx, msg, event.RequestID), msg)
+		err = c.handler(handlerCtx, event)
+		tracing.End(span, err)
+		if err != nil {
 			c.logger.Error("article check handler failed", "error", err, "article_id", event.ArticleID, "request_id", event.RequestID)
 			if commitErr := c.reader.CommitMessages(ctx, msg); commitErr != nil {
 				c.logger.Error("failed to commit message after handler error", "error", commitErr)
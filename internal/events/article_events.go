@@ -2,14 +2,16 @@ package events
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"time"
 
 	"github.com/segmentio/kafka-go"
-	"google.golang.org/protobuf/proto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/tracing"
 	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
 )
 
@@ -22,14 +24,24 @@ type ArticleEventProducer interface {
 // ArticleEventConsumer handle article-related event consumption
 type ArticleEventConsumer interface {
 	StartProcessedEventConsumer(ctx context.Context, handler func(ctx context.Context, event *article_eventspb.ArticleProcessedEvent) error) error
+	StartProcessedEventBatchConsumer(ctx context.Context, opts BatchOptions, handler func(ctx context.Context, events []*article_eventspb.ArticleProcessedEvent) error) error
 	Stop(ctx context.Context) error
 }
 
+// BatchOptions bounds the batches handed to a batch consumer: a batch is handled once it
+// holds MaxSize events or MaxWait has passed since its first event arrived
+type BatchOptions struct {
+	MaxSize int
+	MaxWait time.Duration
+}
+
 // KafkaArticleEventProducer implement ArticleEventProducer using Kafka
 type KafkaArticleEventProducer struct {
 	logger           *slog.Logger
 	articleNewWriter *kafka.Writer
 	articleNewTopic  string
+	codec            *ProtoCodec
+	sizes            *MessageSizes
 }
 
 // NewKafkaArticleEventProducer create a new Kafka-based article event producer
@@ -49,47 +61,90 @@ func NewKafkaArticleEventProducer(logger *slog.Logger, brokers []string, article
 	}
 }
 
+// SetSchemaCodec registers the event schema and frames messages with its ID
+func (p *KafkaArticleEventProducer) SetSchemaCodec(codec *ProtoCodec) {
+	p.codec = codec
+}
+
+// SetProducerOptions compresses the messages and counts their sizes as configured; full
+// article content makes this topic the largest by far
+func (p *KafkaArticleEventProducer) SetProducerOptions(opts ProducerOptions) {
+	p.articleNewWriter.Compression = opts.Compression
+	p.sizes = opts.Sizes
+}
+
 // PublishArticlePersisted publishe an ArticlePersistedEvent to Kafka
-func (p *KafkaArticleEventProducer) PublishArticlePersisted(ctx context.Context, event *article_eventspb.ArticlePersistedEvent) error {
-	data, err := proto.Marshal(event)
-	if err != nil {
-		data, err = json.Marshal(event)
-		if err != nil {
-			return fmt.Errorf("failed to marshal article persisted event: %w", err)
-		}
-	}
+func (p *KafkaArticleEventProducer) PublishArticlePersisted(ctx context.Context, event *article_eventspb.ArticlePersistedEvent) (err error) {
+	ctx, span := StartPublishSpan(ctx, p.articleNewTopic, EventArticlePersisted)
+	defer func() { tracing.End(span, err) }()
 
-	// Create Kafka message
-	message := kafka.Message{
-		Key:   []byte(fmt.Sprintf("article_%d", event.ArticleId)),
-		Value: data,
-		Headers: []kafka.Header{
-			{
-				Key:   "event_type",
-				Value: []byte("article_persisted"),
-			},
-			{
-				Key:   "source",
-				Value: []byte("feed-service"),
-			},
-		},
-		Time: time.Now(),
+	if event.RequestId == "" {
+		event.RequestId, _ = logger.GetRequestID(ctx)
+	}
+	message, err := p.articlePersistedMessage(ctx, event)
+	if err != nil {
+		return err
 	}
 
 	// Send message
 	if err := p.articleNewWriter.WriteMessages(ctx, message); err != nil {
 		return fmt.Errorf("failed to write article persisted event to Kafka: %w", err)
 	}
+	p.sizes.Observe(p.articleNewTopic, message)
 
 	p.logger.Debug("published article persisted event",
 		"article_id", event.ArticleId,
 		"feed_id", event.FeedId,
 		"topic", p.articleNewTopic,
+		"request_id", event.RequestId,
 	)
 
 	return nil
 }
 
+// PublishArticlesPersisted publishes events in one write, for the outbox relay. Unlike
+// PublishArticlePersisted it leaves each event's request ID as it is.
+func (p *KafkaArticleEventProducer) PublishArticlesPersisted(ctx context.Context, events []*article_eventspb.ArticlePersistedEvent) (err error) {
+	ctx, span := StartPublishSpan(ctx, p.articleNewTopic, EventArticlePersisted)
+	defer func() { tracing.End(span, err) }()
+
+	messages := make([]kafka.Message, len(events))
+	for i, event := range events {
+		if messages[i], err = p.articlePersistedMessage(ctx, event); err != nil {
+			return err
+		}
+	}
+	if err := p.articleNewWriter.WriteMessages(ctx, messages...); err != nil {
+		return fmt.Errorf("failed to write article persisted events to Kafka: %w", err)
+	}
+	p.sizes.Observe(p.articleNewTopic, messages...)
+
+	p.logger.Debug("published article persisted events", "count", len(events), "topic", p.articleNewTopic)
+	return nil
+}
+
+func (p *KafkaArticleEventProducer) articlePersistedMessage(ctx context.Context, event *article_eventspb.ArticlePersistedEvent) (kafka.Message, error) {
+	data, err := p.codec.Encode(ctx, event)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to marshal article persisted event: %w", err)
+	}
+	return kafka.Message{
+		Key:   []byte(fmt.Sprintf("article_%d", event.ArticleId)),
+		Value: data,
+		Headers: WithContextHeaders(ctx, event.RequestId, []kafka.Header{
+			{
+				Key:   EventTypeHeader,
+				Value: []byte(EventArticlePersisted),
+			},
+			{
+				Key:   "source",
+				Value: []byte("feed-service"),
+			},
+		}),
+		Time: time.Now(),
+	}, nil
+}
+
 // Close closes the producer
 func (p *KafkaArticleEventProducer) Close() error {
 	p.logger.Info("closing kafka article event producer")
@@ -106,6 +161,7 @@ type KafkaArticleEventConsumer struct {
 	groupID               string
 	articleProcessedTopic string
 	processedEventReader  *kafka.Reader
+	codec                 *ProtoCodec
 }
 
 // NewKafkaArticleEventConsumer create a new Kafka-based article event consumer
@@ -118,8 +174,17 @@ func NewKafkaArticleEventConsumer(logger *slog.Logger, brokers []string, groupID
 	}
 }
 
+// SetSchemaCodec makes the consumer check messages against the schema registry
+func (c *KafkaArticleEventConsumer) SetSchemaCodec(codec *ProtoCodec) {
+	c.codec = codec
+}
+
 // StartProcessedEventConsumer start consuming ArticleProcessedEvent messages
 func (c *KafkaArticleEventConsumer) StartProcessedEventConsumer(ctx context.Context, handler func(ctx context.Context, event *article_eventspb.ArticleProcessedEvent) error) error {
+	if err := c.codec.Check(ctx); err != nil {
+		return err
+	}
+
 	c.processedEventReader = kafka.NewReader(kafka.ReaderConfig{
 		Brokers:        c.brokers,
 		Topic:          c.articleProcessedTopic,
@@ -167,6 +232,112 @@ func (c *KafkaArticleEventConsumer) StartProcessedEventConsumer(ctx context.Cont
 	}
 }
 
+// StartProcessedEventBatchConsumer consumes ArticleProcessedEvent messages in batches. The
+// messages of a batch are committed together after the handler returned, so a crash
+// before that replays the whole batch.
+func (c *KafkaArticleEventConsumer) StartProcessedEventBatchConsumer(ctx context.Context, opts BatchOptions, handler func(ctx context.Context, events []*article_eventspb.ArticleProcessedEvent) error) error {
+	if opts.MaxSize < 1 {
+		opts.MaxSize = 1
+	}
+	if err := c.codec.Check(ctx); err != nil {
+		return err
+	}
+
+	c.processedEventReader = kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        c.brokers,
+		Topic:          c.articleProcessedTopic,
+		GroupID:        c.groupID,
+		MinBytes:       1,
+		MaxBytes:       10e6, // 10MB
+		CommitInterval: time.Second,
+	})
+
+	c.logger.Info("starting article processed event batch consumer",
+		"topic", c.articleProcessedTopic,
+		"group_id", c.groupID,
+		"brokers", c.brokers,
+		"batch_size", opts.MaxSize,
+		"batch_wait", opts.MaxWait,
+	)
+
+	for {
+		messages, err := c.fetchProcessedBatch(ctx, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				c.logger.Info("stopping article processed event batch consumer due to context cancellation")
+				return ctx.Err()
+			}
+			c.logger.Error("failed to fetch processed event message", "error", err)
+			continue
+		}
+
+		batch := make([]*article_eventspb.ArticleProcessedEvent, 0, len(messages))
+		links := make([]trace.Link, 0, len(messages))
+		for _, message := range messages {
+			var event article_eventspb.ArticleProcessedEvent
+			if err := c.codec.Decode(ctx, message.Value, &event); err != nil {
+				c.logger.Error("failed to unmarshal processed event",
+					"error", err,
+					"offset", message.Offset,
+					"partition", message.Partition,
+				)
+				continue
+			}
+			// a batch mixes requests, so each event carries its own ID to the handler
+			if requestID := RequestIDOf(message); requestID != "" {
+				event.RequestId = requestID
+			}
+			batch = append(batch, &event)
+			links = append(links, messageLink(ctx, message))
+		}
+
+		if len(batch) > 0 {
+			batchCtx, span := tracing.Start(ctx, "consume "+string(EventArticleProcessed)+" batch",
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithLinks(links...),
+				trace.WithAttributes(attribute.Int("messaging.batch.message_count", len(batch))))
+			err := handler(batchCtx, batch)
+			tracing.End(span, err)
+			if err != nil {
+				if ctx.Err() != nil {
+					// leave the batch uncommitted so it is replayed after the restart
+					return ctx.Err()
+				}
+				c.logger.Error("failed to process processed event batch", "error", err, "size", len(batch))
+			}
+		}
+
+		if err := c.processedEventReader.CommitMessages(ctx, messages...); err != nil {
+			c.logger.Error("failed to commit processed event messages", "error", err)
+		}
+	}
+}
+
+// fetchProcessedBatch blocks for the first message, then collects more until the batch
+// is full or opts.MaxWait has passed
+func (c *KafkaArticleEventConsumer) fetchProcessedBatch(ctx context.Context, opts BatchOptions) ([]kafka.Message, error) {
+	first, err := c.processedEventReader.FetchMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	messages := []kafka.Message{first}
+	if opts.MaxSize == 1 || opts.MaxWait <= 0 {
+		return messages, nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, opts.MaxWait)
+	defer cancel()
+	for len(messages) < opts.MaxSize {
+		message, err := c.processedEventReader.FetchMessage(waitCtx)
+		if err != nil {
+			// the wait ran out or the consumer is stopping; handle what arrived so far
+			break
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
 // processProcessedEventMessage process a single ArticleProcessedEvent message
 func (c *KafkaArticleEventConsumer) processProcessedEventMessage(ctx context.Context, message kafka.Message, handler func(ctx context.Context, event *article_eventspb.ArticleProcessedEvent) error) error {
 	c.logger.Debug("processing article processed event message",
@@ -176,36 +347,28 @@ func (c *KafkaArticleEventConsumer) processProcessedEventMessage(ctx context.Con
 	)
 
 	var event article_eventspb.ArticleProcessedEvent
-	if err := c.unmarshalProcessedEvent(message.Value, &event); err != nil {
+	if err := c.codec.Decode(ctx, message.Value, &event); err != nil {
 		return fmt.Errorf("failed to unmarshal processed event: %w", err)
 	}
 
+	ctx, span := StartConsumeSpan(ContextWithRequestID(ctx, message, event.RequestId), message)
+	event.RequestId, _ = logger.GetRequestID(ctx)
+
 	c.logger.Info("received article processed event",
 		"article_id", event.ArticleId,
 		"summary_length", len(event.Summary),
+		"request_id", event.RequestId,
 	)
 
-	if err := handler(ctx, &event); err != nil {
+	err := handler(ctx, &event)
+	tracing.End(span, err)
+	if err != nil {
 		return fmt.Errorf("handler failed for article processed event: %w", err)
 	}
 
 	return nil
 }
 
-// unmarshalProcessedEvent unmarshal the processed event
-func (c *KafkaArticleEventConsumer) unmarshalProcessedEvent(data []byte, event *article_eventspb.ArticleProcessedEvent) error {
-	if err := proto.Unmarshal(data, event); err == nil {
-		return nil
-	}
-
-	// Fallback to JSON
-	if err := json.Unmarshal(data, event); err != nil {
-		return fmt.Errorf("failed to unmarshal as both protobuf and JSON: %w", err)
-	}
-
-	return nil
-}
-
 // Stop gracefully stop the consumer
 func (c *KafkaArticleEventConsumer) Stop(ctx context.Context) error {
 	c.logger.Info("stopping kafka article event consumer")
@@ -0,0 +1,12 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/Fancu1/phoenix-rss/pkg/prototest"
+	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
+)
+
+func TestArticleEvents_WireRoundTrip(t *testing.T) {
+	prototest.RequireRoundTrip(t, prototest.MessageTypes(article_eventspb.File_article_events_proto), 50)
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// RequestIDHeader carries the ID of the request that caused an event, so a user action can
+// be followed through the asynchronous work it starts
+const RequestIDHeader = "request_id"
+
+// requestIDHeader returns the request ID header for an event: its own ID when the payload
+// has one, otherwise the ID of the publishing context. It reports false without either.
+func requestIDHeader(ctx context.Context, payloadID string) (kafka.Header, bool) {
+	requestID := payloadID
+	if requestID == "" {
+		requestID, _ = logger.GetRequestID(ctx)
+	}
+	if requestID == "" {
+		return kafka.Header{}, false
+	}
+	return kafka.Header{Key: RequestIDHeader, Value: []byte(requestID)}, true
+}
+
+// WithContextHeaders appends the request ID header, when there is an ID, and the trace
+// context of ctx to headers, for services that write their own messages
+func WithContextHeaders(ctx context.Context, payloadID string, headers []kafka.Header) []kafka.Header {
+	if header, ok := requestIDHeader(ctx, payloadID); ok {
+		headers = append(headers, header)
+	}
+	return withTraceContext(ctx, headers)
+}
+
+// RequestIDOf returns the request ID header of a message, empty when it has none
+func RequestIDOf(msg kafka.Message) string {
+	for _, header := range msg.Headers {
+		if header.Key == RequestIDHeader {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+// ContextWithRequestID restores the request ID an event was published under into the
+// context its handler runs with. The header wins over the payload's own ID; events with
+// neither, published before IDs were propagated, get a fresh one so the work they cause
+// can still be followed.
+func ContextWithRequestID(ctx context.Context, msg kafka.Message, payloadID string) context.Context {
+	requestID := RequestIDOf(msg)
+	if requestID == "" {
+		requestID = payloadID
+	}
+	if requestID == "" {
+		requestID = NewRequestID()
+	}
+	return logger.WithRequestID(ctx, requestID)
+}
+
+// NewRequestID returns an ID for work that does not start with a user request, such as
+// scheduled fetches. It has the form of the IDs the API gateway generates.
+func NewRequestID() string {
+	return uuid.New().String()[:8]
+}
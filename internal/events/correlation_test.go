@@ -0,0 +1,60 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+func TestFeedFetchMessage_CarriesRequestID(t *testing.T) {
+	ctx := logger.WithRequestID(context.Background(), "req-1")
+
+	msg, err := feedFetchMessage(ctx, FeedFetchEvent{FeedID: 7})
+	require.NoError(t, err)
+	assert.Equal(t, "req-1", RequestIDOf(msg))
+
+	var evt FeedFetchEvent
+	require.NoError(t, json.Unmarshal(msg.Value, &evt))
+	assert.Equal(t, FeedFetchEvent{FeedID: 7, RequestID: "req-1"}, evt)
+
+	msg, err = feedFetchMessage(context.Background(), FeedFetchEvent{FeedID: 7})
+	require.NoError(t, err)
+	assert.Empty(t, RequestIDOf(msg))
+	assert.Len(t, msg.Headers, 1)
+}
+
+func TestContextWithRequestID(t *testing.T) {
+	withHeader := kafka.Message{Headers: []kafka.Header{{Key: RequestIDHeader, Value: []byte("from-header")}}}
+
+	requestID, _ := logger.GetRequestID(ContextWithRequestID(context.Background(), withHeader, "from-payload"))
+	assert.Equal(t, "from-header", requestID)
+
+	requestID, _ = logger.GetRequestID(ContextWithRequestID(context.Background(), kafka.Message{}, "from-payload"))
+	assert.Equal(t, "from-payload", requestID)
+
+	// events published before IDs were propagated still get one
+	requestID, ok := logger.GetRequestID(ContextWithRequestID(context.Background(), kafka.Message{}, ""))
+	assert.True(t, ok)
+	assert.Len(t, requestID, 8)
+}
+
+func TestDispatcher_RestoresRequestID(t *testing.T) {
+	d := newTestDispatcher(true)
+
+	var got string
+	d.Register(EventFeedFetch, FeedFetchHandler(func(ctx context.Context, evt FeedFetchEvent) error {
+		got, _ = logger.GetRequestID(ctx)
+		return nil
+	}))
+
+	msg, err := feedFetchMessage(logger.WithRequestID(context.Background(), "req-2"), FeedFetchEvent{FeedID: 1})
+	require.NoError(t, err)
+	require.NoError(t, d.Dispatch(context.Background(), msg))
+	assert.Equal(t, "req-2", got)
+}
@@ -24,14 +24,30 @@ type ArticleCheckEventConsumer interface {
 	Stop(ctx context.Context) error
 }
 
-// EventType define supported event types
+// EventType define supported event types, carried in the EventTypeHeader of every message
 type EventType string
 
 const (
-	EventFeedFetch EventType = "feed:fetch"
+	EventFeedFetch        EventType = "feed_fetch"
+	EventArticleCheck     EventType = "article_check"
+	EventArticlePersisted EventType = "article_persisted"
+	EventArticleProcessed EventType = "article_processed"
 )
 
+// EventTypeHeader is the Kafka header used to tell event types apart on a shared topic
+const EventTypeHeader = "event_type"
+
+// knownEventTypes is the set of event types any service may publish
+var knownEventTypes = map[EventType]bool{
+	EventFeedFetch:        true,
+	EventArticleCheck:     true,
+	EventArticlePersisted: true,
+	EventArticleProcessed: true,
+}
+
 // FeedFetchEvent is the payload for feed fetch requests
 type FeedFetchEvent struct {
 	FeedID uint `json:"feed_id"`
+	// RequestID is the request that asked for the fetch, or the scheduler run's ID
+	RequestID string `json:"request_id,omitempty"`
 }
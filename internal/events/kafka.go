@@ -7,6 +7,9 @@ import (
 	"log/slog"
 
 	"github.com/segmentio/kafka-go"
+
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/tracing"
 )
 
 // KafkaConfig contains producer/consumer configuration
@@ -20,6 +23,7 @@ type KafkaConfig struct {
 type KafkaProducer struct {
 	logger *slog.Logger
 	writer *kafka.Writer
+	sizes  *MessageSizes
 }
 
 func NewKafkaProducer(logger *slog.Logger, cfg KafkaConfig) *KafkaProducer {
@@ -30,20 +34,75 @@ func NewKafkaProducer(logger *slog.Logger, cfg KafkaConfig) *KafkaProducer {
 	return &KafkaProducer{logger: logger, writer: w}
 }
 
-func (p *KafkaProducer) PublishFeedFetch(ctx context.Context, feedID uint) error {
-	payload := FeedFetchEvent{FeedID: feedID}
-	data, err := json.Marshal(payload)
+// SetProducerOptions compresses the messages and counts their sizes as configured
+func (p *KafkaProducer) SetProducerOptions(opts ProducerOptions) {
+	p.writer.Compression = opts.Compression
+	p.sizes = opts.Sizes
+}
+
+func (p *KafkaProducer) PublishFeedFetch(ctx context.Context, feedID uint) (err error) {
+	ctx, span := StartPublishSpan(ctx, p.writer.Topic, EventFeedFetch)
+	defer func() { tracing.End(span, err) }()
+
+	msg, err := feedFetchMessage(ctx, FeedFetchEvent{FeedID: feedID})
 	if err != nil {
-		return fmt.Errorf("failed to marshal feed fetch event: %w", err)
+		return err
 	}
-	msg := kafka.Message{Key: []byte("feed_id"), Value: data}
 	if err := p.writer.WriteMessages(ctx, msg); err != nil {
 		return fmt.Errorf("failed to write kafka message: %w", err)
 	}
+	p.sizes.Observe(p.writer.Topic, msg)
 	p.logger.Info("published feed fetch event", "topic", p.writer.Topic, "feed_id", feedID)
 	return nil
 }
 
+// PublishFeedFetches publishes a fetch event for every feed in one write, for bulk actions
+// that would otherwise wait out the writer's batch timeout once per feed
+func (p *KafkaProducer) PublishFeedFetches(ctx context.Context, feedIDs []uint) error {
+	evts := make([]FeedFetchEvent, len(feedIDs))
+	for i, feedID := range feedIDs {
+		evts[i] = FeedFetchEvent{FeedID: feedID}
+	}
+	return p.PublishFeedFetchEvents(ctx, evts)
+}
+
+// PublishFeedFetchEvents publishes events in one write. Events without a request ID get
+// the one of ctx, so the outbox relay can publish events stored by many requests.
+func (p *KafkaProducer) PublishFeedFetchEvents(ctx context.Context, evts []FeedFetchEvent) (err error) {
+	ctx, span := StartPublishSpan(ctx, p.writer.Topic, EventFeedFetch)
+	defer func() { tracing.End(span, err) }()
+
+	msgs := make([]kafka.Message, len(evts))
+	for i, evt := range evts {
+		msg, err := feedFetchMessage(ctx, evt)
+		if err != nil {
+			return err
+		}
+		msgs[i] = msg
+	}
+	if err := p.writer.WriteMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("failed to write kafka messages: %w", err)
+	}
+	p.sizes.Observe(p.writer.Topic, msgs...)
+	p.logger.Info("published feed fetch events", "topic", p.writer.Topic, "count", len(evts))
+	return nil
+}
+
+func feedFetchMessage(ctx context.Context, evt FeedFetchEvent) (kafka.Message, error) {
+	if evt.RequestID == "" {
+		evt.RequestID, _ = logger.GetRequestID(ctx)
+	}
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to marshal feed fetch event: %w", err)
+	}
+	return kafka.Message{
+		Key:     []byte("feed_id"),
+		Value:   data,
+		Headers: WithContextHeaders(ctx, evt.RequestID, []kafka.Header{{Key: EventTypeHeader, Value: []byte(EventFeedFetch)}}),
+	}, nil
+}
+
 // Close the producer
 func (p *KafkaProducer) Close() error {
 	p.logger.Info("closing kafka producer")
@@ -86,8 +145,11 @@ func (c *KafkaConsumer) Start(ctx context.Context) error {
 			c.logger.Error("failed to unmarshal event", "error", err)
 			continue
 		}
-		if err := c.handler(ctx, evt); err != nil {
-			c.logger.Error("handler failed", "error", err, "feed_id", evt.FeedID)
+		handlerCtx, span := StartConsumeSpan(ContextWithRequestID(ctx, m, evt.RequestID), m)
+		err = c.handler(handlerCtx, evt)
+		tracing.End(span, err)
+		if err != nil {
+			c.logger.Error("handler failed", "error", err, "feed_id", evt.FeedID, "request_id", RequestIDOf(m))
 			continue
 		}
 		if err := c.reader.CommitMessages(ctx, m); err != nil {
@@ -3,6 +3,8 @@ package events
 import (
 	"context"
 	"log/slog"
+
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
 )
 
 // MemoryBus is a simple in-process implementation for tests
@@ -21,7 +23,8 @@ func NewMemoryBus(logger *slog.Logger, handler func(ctx context.Context, evt Fee
 }
 
 func (b *MemoryBus) PublishFeedFetch(ctx context.Context, feedID uint) error {
-	b.ch <- FeedFetchEvent{FeedID: feedID}
+	requestID, _ := logger.GetRequestID(ctx)
+	b.ch <- FeedFetchEvent{FeedID: feedID, RequestID: requestID}
 	return nil
 }
 
@@ -33,7 +36,11 @@ func (b *MemoryBus) Start(ctx context.Context) error {
 			return nil
 		case evt := <-b.ch:
 			if b.handler != nil {
-				if err := b.handler(ctx, evt); err != nil {
+				handlerCtx := ctx
+				if evt.RequestID != "" {
+					handlerCtx = logger.WithRequestID(ctx, evt.RequestID)
+				}
+				if err := b.handler(handlerCtx, evt); err != nil {
 					b.logger.Error("memory handler error", "error", err)
 				}
 			}
@@ -0,0 +1,117 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ProducerOptions are the settings every Kafka writer of a service shares
+type ProducerOptions struct {
+	// Compression compresses message batches. Readers decompress them whatever the codec,
+	// so it can be changed without touching consumers.
+	Compression kafka.Compression
+	// Sizes, when set, counts the size of the published messages per topic
+	Sizes *MessageSizes
+}
+
+// ParseCompression returns the codec named none, gzip, snappy, lz4 or zstd; empty is none
+func ParseCompression(name string) (kafka.Compression, error) {
+	var compression kafka.Compression
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return compression, nil
+	}
+	err := compression.UnmarshalText([]byte(name))
+	return compression, err
+}
+
+// TopicSize is how many messages were published to a topic and how large they were,
+// keys, values and headers together, before compression
+type TopicSize struct {
+	Topic    string
+	Messages int64
+	Bytes    int64
+	MaxBytes int64
+}
+
+// MessageSizes counts the messages published per topic and their sizes, so operators can
+// see which topics the broker storage and network go to
+type MessageSizes struct {
+	mu     sync.Mutex
+	topics map[string]*TopicSize
+}
+
+func NewMessageSizes() *MessageSizes {
+	return &MessageSizes{topics: make(map[string]*TopicSize)}
+}
+
+// Observe counts messages published to topic. A nil MessageSizes ignores them.
+func (s *MessageSizes) Observe(topic string, messages ...kafka.Message) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	size, ok := s.topics[topic]
+	if !ok {
+		size = &TopicSize{Topic: topic}
+		s.topics[topic] = size
+	}
+	for _, message := range messages {
+		n := int64(messageSize(message))
+		size.Messages++
+		size.Bytes += n
+		size.MaxBytes = max(size.MaxBytes, n)
+	}
+}
+
+// Take returns the sizes counted since the last Take, ordered by topic, and starts over
+func (s *MessageSizes) Take() []TopicSize {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]TopicSize, 0, len(s.topics))
+	for _, size := range s.topics {
+		out = append(out, *size)
+	}
+	clear(s.topics)
+	slices.SortFunc(out, func(a, b TopicSize) int { return strings.Compare(a.Topic, b.Topic) })
+	return out
+}
+
+// Run logs the sizes counted for each topic every interval until ctx is done
+func (s *MessageSizes) Run(ctx context.Context, logger *slog.Logger, interval time.Duration, compression kafka.Compression) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, size := range s.Take() {
+			logger.Info("kafka messages published",
+				"topic", size.Topic,
+				"messages", size.Messages,
+				"bytes", size.Bytes,
+				"avg_bytes", size.Bytes/size.Messages,
+				"max_bytes", size.MaxBytes,
+				"compression", compression.String(),
+				"interval", interval.String(),
+			)
+		}
+	}
+}
+
+func messageSize(message kafka.Message) int {
+	n := len(message.Key) + len(message.Value)
+	for _, header := range message.Headers {
+		n += len(header.Key) + len(header.Value)
+	}
+	return n
+}
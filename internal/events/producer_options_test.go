@@ -0,0 +1,45 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCompression(t *testing.T) {
+	for name, want := range map[string]kafka.Compression{
+		"":       0,
+		"none":   0,
+		"zstd":   kafka.Zstd,
+		"Snappy": kafka.Snappy,
+		"lz4":    kafka.Lz4,
+		" gzip ": kafka.Gzip,
+	} {
+		got, err := ParseCompression(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+
+	_, err := ParseCompression("brotli")
+	assert.Error(t, err)
+}
+
+func TestMessageSizes(t *testing.T) {
+	sizes := NewMessageSizes()
+	sizes.Observe("articles.new",
+		kafka.Message{Key: []byte("article_1"), Value: make([]byte, 100)},
+		kafka.Message{Value: make([]byte, 40), Headers: []kafka.Header{{Key: "event_type", Value: []byte("x")}}},
+	)
+	sizes.Observe("feed.fetch", kafka.Message{Value: make([]byte, 10)})
+
+	assert.Equal(t, []TopicSize{
+		{Topic: "articles.new", Messages: 2, Bytes: 160, MaxBytes: 109},
+		{Topic: "feed.fetch", Messages: 1, Bytes: 10, MaxBytes: 10},
+	}, sizes.Take())
+	assert.Empty(t, sizes.Take(), "taking the sizes starts over")
+
+	var disabled *MessageSizes
+	disabled.Observe("articles.new", kafka.Message{Value: []byte("x")})
+}
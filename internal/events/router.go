@@ -0,0 +1,316 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/tracing"
+	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
+)
+
+var (
+	// ErrMissingEventType is returned for messages without exactly one event_type header
+	ErrMissingEventType = errors.New("message must carry exactly one event_type header")
+	// ErrUnknownEventType is returned for messages whose event_type is not a known EventType
+	ErrUnknownEventType = errors.New("unknown event type")
+)
+
+// routerStatsInterval controls how often a routed consumer logs its per-type counters
+const routerStatsInterval = time.Minute
+
+// Routing describes which event types are multiplexed onto a shared topic. A nil
+// *Routing means every event type keeps its dedicated topic.
+type Routing struct {
+	Topic  string
+	Strict bool
+	types  map[EventType]bool
+}
+
+// NewRouting validates the configured event types and returns the routing table
+func NewRouting(topic string, strict bool, eventTypes []string) (*Routing, error) {
+	if topic == "" {
+		return nil, fmt.Errorf("routing topic cannot be empty")
+	}
+	if len(eventTypes) == 0 {
+		return nil, fmt.Errorf("routing needs at least one event type")
+	}
+
+	types := make(map[EventType]bool, len(eventTypes))
+	for _, raw := range eventTypes {
+		eventType := EventType(raw)
+		if !knownEventTypes[eventType] {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownEventType, raw)
+		}
+		types[eventType] = true
+	}
+
+	return &Routing{Topic: topic, Strict: strict, types: types}, nil
+}
+
+// Routes reports whether the event type is published on the shared topic
+func (r *Routing) Routes(eventType EventType) bool {
+	return r != nil && r.types[eventType]
+}
+
+// TopicFor returns the topic an event type is published to and consumed from
+func (r *Routing) TopicFor(eventType EventType, dedicated string) string {
+	if r.Routes(eventType) {
+		return r.Topic
+	}
+	return dedicated
+}
+
+// EventTypeOf returns the event type header of a message. It reports false when the
+// header is missing or repeated.
+func EventTypeOf(msg kafka.Message) (EventType, bool) {
+	var value string
+	found := 0
+	for _, header := range msg.Headers {
+		if header.Key == EventTypeHeader {
+			value = string(header.Value)
+			found++
+		}
+	}
+	if found != 1 || value == "" {
+		return "", false
+	}
+	return EventType(value), true
+}
+
+// MessageHandler handles a raw message that was dispatched by its event type
+type MessageHandler func(ctx context.Context, msg kafka.Message) error
+
+// EventTypeStats counts what happened to messages of one event type
+type EventTypeStats struct {
+	Handled  uint64 `json:"handled"`
+	Failed   uint64 `json:"failed"`
+	Skipped  uint64 `json:"skipped"`
+	Rejected uint64 `json:"rejected"`
+}
+
+// invalidEventTypeKey groups stats for messages without a usable event_type header
+const invalidEventTypeKey EventType = "invalid"
+
+// Dispatcher routes messages from a shared topic to the handler registered for their
+// event_type header. Event types known to the system but without a local handler are
+// skipped, since another consumer group owns them.
+type Dispatcher struct {
+	logger   *slog.Logger
+	strict   bool
+	handlers map[EventType]MessageHandler
+
+	mu    sync.Mutex
+	stats map[EventType]*EventTypeStats
+}
+
+func NewDispatcher(logger *slog.Logger, strict bool) *Dispatcher {
+	return &Dispatcher{
+		logger:   logger,
+		strict:   strict,
+		handlers: make(map[EventType]MessageHandler),
+		stats:    make(map[EventType]*EventTypeStats),
+	}
+}
+
+// Register sets the handler for an event type. It panics on unknown or duplicate
+// registrations since both are programming errors.
+func (d *Dispatcher) Register(eventType EventType, handler MessageHandler) {
+	if !knownEventTypes[eventType] {
+		panic(fmt.Sprintf("events: register unknown event type %q", eventType))
+	}
+	if _, exists := d.handlers[eventType]; exists {
+		panic(fmt.Sprintf("events: duplicate handler for event type %q", eventType))
+	}
+	d.handlers[eventType] = handler
+}
+
+// HasHandlers reports whether any event type was registered
+func (d *Dispatcher) HasHandlers() bool {
+	return len(d.handlers) > 0
+}
+
+// Dispatch hands the message to its handler. Messages with a missing or unknown
+// event type are rejected in strict mode and skipped otherwise.
+func (d *Dispatcher) Dispatch(ctx context.Context, msg kafka.Message) error {
+	eventType, ok := EventTypeOf(msg)
+	if !ok {
+		return d.invalid(invalidEventTypeKey, ErrMissingEventType)
+	}
+	if !knownEventTypes[eventType] {
+		return d.invalid(invalidEventTypeKey, fmt.Errorf("%w: %q", ErrUnknownEventType, eventType))
+	}
+
+	handler, registered := d.handlers[eventType]
+	if !registered {
+		d.record(eventType, func(s *EventTypeStats) { s.Skipped++ })
+		return nil
+	}
+
+	if err := handler(ctx, msg); err != nil {
+		d.record(eventType, func(s *EventTypeStats) { s.Failed++ })
+		return fmt.Errorf("%s handler failed: %w", eventType, err)
+	}
+
+	d.record(eventType, func(s *EventTypeStats) { s.Handled++ })
+	return nil
+}
+
+func (d *Dispatcher) invalid(key EventType, err error) error {
+	if !d.strict {
+		d.record(key, func(s *EventTypeStats) { s.Skipped++ })
+		return nil
+	}
+	d.record(key, func(s *EventTypeStats) { s.Rejected++ })
+	return err
+}
+
+func (d *Dispatcher) record(eventType EventType, update func(*EventTypeStats)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats, ok := d.stats[eventType]
+	if !ok {
+		stats = &EventTypeStats{}
+		d.stats[eventType] = stats
+	}
+	update(stats)
+}
+
+// Stats returns a snapshot of the per-event-type counters
+func (d *Dispatcher) Stats() map[EventType]EventTypeStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	snapshot := make(map[EventType]EventTypeStats, len(d.stats))
+	for eventType, stats := range d.stats {
+		snapshot[eventType] = *stats
+	}
+	return snapshot
+}
+
+func (d *Dispatcher) logStats(msg string) {
+	stats := d.Stats()
+	eventTypes := make([]string, 0, len(stats))
+	for eventType := range stats {
+		eventTypes = append(eventTypes, string(eventType))
+	}
+	sort.Strings(eventTypes)
+
+	attrs := make([]any, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		s := stats[EventType(eventType)]
+		attrs = append(attrs, slog.Group(eventType,
+			"handled", s.Handled,
+			"failed", s.Failed,
+			"skipped", s.Skipped,
+			"rejected", s.Rejected,
+		))
+	}
+	d.logger.Info(msg, attrs...)
+}
+
+// FeedFetchHandler adapts a typed feed fetch handler for a Dispatcher
+func FeedFetchHandler(handler func(ctx context.Context, evt FeedFetchEvent) error) MessageHandler {
+	return func(ctx context.Context, msg kafka.Message) error {
+		var evt FeedFetchEvent
+		if err := json.Unmarshal(msg.Value, &evt); err != nil {
+			return fmt.Errorf("failed to unmarshal feed fetch event: %w", err)
+		}
+		return handler(ContextWithRequestID(ctx, msg, evt.RequestID), evt)
+	}
+}
+
+// ArticleCheckHandler adapts a typed article check handler for a Dispatcher
+func ArticleCheckHandler(handler func(ctx context.Context, event ArticleCheckEvent) error) MessageHandler {
+	return func(ctx context.Context, msg kafka.Message) error {
+		var event ArticleCheckEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			return fmt.Errorf("failed to unmarshal article check event: %w", err)
+		}
+		return handler(ContextWithRequestID(ctx, msg, event.RequestID), event)
+	}
+}
+
+// ArticleProcessedHandler adapts a typed article processed handler for a Dispatcher. The
+// codec may be nil when the topic does not use the schema registry.
+func ArticleProcessedHandler(codec *ProtoCodec, handler func(ctx context.Context, event *article_eventspb.ArticleProcessedEvent) error) MessageHandler {
+	return func(ctx context.Context, msg kafka.Message) error {
+		var event article_eventspb.ArticleProcessedEvent
+		if err := codec.Decode(ctx, msg.Value, &event); err != nil {
+			return err
+		}
+		ctx = ContextWithRequestID(ctx, msg, event.RequestId)
+		event.RequestId, _ = logger.GetRequestID(ctx)
+		return handler(ctx, &event)
+	}
+}
+
+// KafkaRoutedConsumer reads a shared topic and dispatches messages by event type
+type KafkaRoutedConsumer struct {
+	logger     *slog.Logger
+	reader     *kafka.Reader
+	dispatcher *Dispatcher
+}
+
+func NewKafkaRoutedConsumer(logger *slog.Logger, cfg KafkaConfig, dispatcher *Dispatcher) *KafkaRoutedConsumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        cfg.Brokers,
+		GroupID:        cfg.GroupID,
+		Topic:          cfg.Topic,
+		MinBytes:       1,
+		MaxBytes:       10e6,
+		CommitInterval: 0,
+	})
+
+	return &KafkaRoutedConsumer{logger: logger, reader: reader, dispatcher: dispatcher}
+}
+
+func (c *KafkaRoutedConsumer) Start(ctx context.Context) error {
+	c.logger.Info("starting routed kafka consumer", "topic", c.reader.Config().Topic, "group", c.reader.Config().GroupID)
+
+	lastStats := time.Now()
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.logger.Error("failed to fetch routed message", "error", err)
+			continue
+		}
+
+		dispatchCtx, span := StartConsumeSpan(ctx, msg)
+		err = c.dispatcher.Dispatch(dispatchCtx, msg)
+		tracing.End(span, err)
+		if err != nil {
+			c.logger.Error("failed to dispatch routed message",
+				"error", err,
+				"offset", msg.Offset,
+				"partition", msg.Partition,
+			)
+		}
+
+		// failed and rejected messages are committed too so they cannot block the partition
+		if err := c.reader.CommitMessages(ctx, msg); err != nil {
+			c.logger.Error("failed to commit routed message", "error", err)
+		}
+
+		if time.Since(lastStats) >= routerStatsInterval {
+			c.dispatcher.logStats("routed consumer stats")
+			lastStats = time.Now()
+		}
+	}
+}
+
+func (c *KafkaRoutedConsumer) Stop(ctx context.Context) error {
+	c.logger.Info("stopping routed kafka consumer")
+	c.dispatcher.logStats("routed consumer final stats")
+	return c.reader.Close()
+}
@@ -0,0 +1,126 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func routedMessage(t *testing.T, eventType EventType, payload any) kafka.Message {
+	t.Helper()
+	data, err := json.Marshal(payload)
+	require.NoError(t, err)
+	return kafka.Message{
+		Value:   data,
+		Headers: []kafka.Header{{Key: EventTypeHeader, Value: []byte(eventType)}},
+	}
+}
+
+func newTestDispatcher(strict bool) *Dispatcher {
+	return NewDispatcher(slog.New(slog.NewTextHandler(io.Discard, nil)), strict)
+}
+
+func TestNewRouting(t *testing.T) {
+	routing, err := NewRouting("phoenix.events", true, []string{"feed_fetch", "article_check"})
+	require.NoError(t, err)
+	assert.Equal(t, "phoenix.events", routing.TopicFor(EventFeedFetch, "feed.fetch"))
+	assert.Equal(t, "articles.new", routing.TopicFor(EventArticlePersisted, "articles.new"))
+
+	_, err = NewRouting("phoenix.events", true, []string{"feed:fetch"})
+	assert.True(t, errors.Is(err, ErrUnknownEventType))
+
+	_, err = NewRouting("", true, []string{"feed_fetch"})
+	assert.Error(t, err)
+
+	var disabled *Routing
+	assert.False(t, disabled.Routes(EventFeedFetch))
+	assert.Equal(t, "feed.fetch", disabled.TopicFor(EventFeedFetch, "feed.fetch"))
+}
+
+func TestDispatcher_RoutesByHeader(t *testing.T) {
+	d := newTestDispatcher(true)
+
+	var fetched []uint
+	var checked []uint
+	d.Register(EventFeedFetch, FeedFetchHandler(func(ctx context.Context, evt FeedFetchEvent) error {
+		fetched = append(fetched, evt.FeedID)
+		return nil
+	}))
+	d.Register(EventArticleCheck, ArticleCheckHandler(func(ctx context.Context, event ArticleCheckEvent) error {
+		checked = append(checked, event.ArticleID)
+		return nil
+	}))
+
+	ctx := context.Background()
+	require.NoError(t, d.Dispatch(ctx, routedMessage(t, EventFeedFetch, FeedFetchEvent{FeedID: 7})))
+	require.NoError(t, d.Dispatch(ctx, routedMessage(t, EventArticleCheck, ArticleCheckEvent{ArticleID: 42})))
+	// owned by another consumer group
+	require.NoError(t, d.Dispatch(ctx, routedMessage(t, EventArticlePersisted, map[string]int{"article_id": 1})))
+
+	assert.Equal(t, []uint{7}, fetched)
+	assert.Equal(t, []uint{42}, checked)
+
+	stats := d.Stats()
+	assert.Equal(t, uint64(1), stats[EventFeedFetch].Handled)
+	assert.Equal(t, uint64(1), stats[EventArticleCheck].Handled)
+	assert.Equal(t, uint64(1), stats[EventArticlePersisted].Skipped)
+}
+
+func TestDispatcher_StrictValidation(t *testing.T) {
+	d := newTestDispatcher(true)
+	d.Register(EventFeedFetch, FeedFetchHandler(func(ctx context.Context, evt FeedFetchEvent) error { return nil }))
+	ctx := context.Background()
+
+	err := d.Dispatch(ctx, kafka.Message{Value: []byte(`{"feed_id":1}`)})
+	assert.True(t, errors.Is(err, ErrMissingEventType))
+
+	duplicated := routedMessage(t, EventFeedFetch, FeedFetchEvent{FeedID: 1})
+	duplicated.Headers = append(duplicated.Headers, kafka.Header{Key: EventTypeHeader, Value: []byte(EventArticleCheck)})
+	err = d.Dispatch(ctx, duplicated)
+	assert.True(t, errors.Is(err, ErrMissingEventType))
+
+	err = d.Dispatch(ctx, routedMessage(t, EventType("user_deleted"), map[string]int{}))
+	assert.True(t, errors.Is(err, ErrUnknownEventType))
+
+	assert.Equal(t, uint64(3), d.Stats()[invalidEventTypeKey].Rejected)
+}
+
+func TestDispatcher_LenientSkipsInvalid(t *testing.T) {
+	d := newTestDispatcher(false)
+	ctx := context.Background()
+
+	require.NoError(t, d.Dispatch(ctx, kafka.Message{Value: []byte(`{}`)}))
+	require.NoError(t, d.Dispatch(ctx, routedMessage(t, EventType("user_deleted"), map[string]int{})))
+	assert.Equal(t, uint64(2), d.Stats()[invalidEventTypeKey].Skipped)
+}
+
+func TestDispatcher_HandlerFailure(t *testing.T) {
+	d := newTestDispatcher(true)
+	d.Register(EventFeedFetch, FeedFetchHandler(func(ctx context.Context, evt FeedFetchEvent) error {
+		return errors.New("boom")
+	}))
+	ctx := context.Background()
+
+	require.Error(t, d.Dispatch(ctx, routedMessage(t, EventFeedFetch, FeedFetchEvent{FeedID: 1})))
+	bad := routedMessage(t, EventFeedFetch, FeedFetchEvent{})
+	bad.Value = []byte("not json")
+	require.Error(t, d.Dispatch(ctx, bad))
+
+	assert.Equal(t, uint64(2), d.Stats()[EventFeedFetch].Failed)
+}
+
+func TestDispatcher_RegisterValidates(t *testing.T) {
+	d := newTestDispatcher(true)
+	handler := FeedFetchHandler(func(ctx context.Context, evt FeedFetchEvent) error { return nil })
+
+	assert.Panics(t, func() { d.Register(EventType("nope"), handler) })
+	d.Register(EventFeedFetch, handler)
+	assert.Panics(t, func() { d.Register(EventFeedFetch, handler) })
+}
@@ -0,0 +1,467 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
+)
+
+var (
+	// ErrIncompatibleSchema is returned when a schema is refused by the registry, or a
+	// message was written with a schema that is not registered for the expected event
+	ErrIncompatibleSchema = errors.New("incompatible event schema")
+	// ErrMissingSchemaID is returned for messages without a schema ID on topics that require one
+	ErrMissingSchemaID = errors.New("message carries no schema ID")
+)
+
+// Messages written with a registered schema start with a zero magic byte and the 4-byte
+// big-endian schema ID, followed by the message indexes and the protobuf payload, as in
+// the Confluent wire format. Plain protobuf never starts with a zero byte (field number 0
+// is invalid) and JSON starts with '{', so framed messages are told apart without config.
+const (
+	schemaMagicByte    = 0
+	schemaHeaderLength = 5
+)
+
+const schemaRegistryContentType = "application/vnd.schemaregistry.v1+json"
+
+// SchemaRegistry talks to a Confluent-compatible schema registry (Confluent, Redpanda,
+// Apicurio in compatibility mode)
+type SchemaRegistry struct {
+	url    string
+	client *http.Client
+
+	mu       sync.Mutex
+	subjects map[int][]string
+}
+
+func NewSchemaRegistry(registryURL string) *SchemaRegistry {
+	return &SchemaRegistry{
+		url: strings.TrimRight(registryURL, "/"),
+		client: httpclient.New(httpclient.Options{
+			Name:         "schema-registry",
+			Timeout:      10 * time.Second,
+			MaxBodyBytes: 1 << 20,
+			Retry:        httpclient.RetryPolicy{MaxAttempts: 3},
+		}),
+		subjects: make(map[int][]string),
+	}
+}
+
+// registryError is an error response of the registry
+type registryError struct {
+	Status  int    `json:"-"`
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+func (e *registryError) Error() string {
+	return fmt.Sprintf("schema registry returned HTTP %d: %s (code %d)", e.Status, e.Message, e.Code)
+}
+
+// Register registers the protobuf schema under the subject, or finds the ID it already
+// has. The registry refuses schemas that break the subject's compatibility setting.
+func (r *SchemaRegistry) Register(ctx context.Context, subject, schema string) (int, error) {
+	var resp struct {
+		ID int `json:"id"`
+	}
+	err := r.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", protobufSchema(schema), &resp)
+	var regErr *registryError
+	if errors.As(err, &regErr) && regErr.Status == http.StatusConflict {
+		return 0, fmt.Errorf("%w: subject %s: %s", ErrIncompatibleSchema, subject, regErr.Message)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("register schema for subject %s: %w", subject, err)
+	}
+
+	r.mu.Lock()
+	if !slices.Contains(r.subjects[resp.ID], subject) {
+		r.subjects[resp.ID] = append(r.subjects[resp.ID], subject)
+	}
+	r.mu.Unlock()
+	return resp.ID, nil
+}
+
+// CheckCompatible tells whether the schema can read messages written with the latest
+// version registered under the subject. A subject without versions is compatible.
+func (r *SchemaRegistry) CheckCompatible(ctx context.Context, subject, schema string) error {
+	var resp struct {
+		IsCompatible bool     `json:"is_compatible"`
+		Messages     []string `json:"messages"`
+	}
+	err := r.do(ctx, http.MethodPost, "/compatibility/subjects/"+url.PathEscape(subject)+"/versions/latest?verbose=true", protobufSchema(schema), &resp)
+	var regErr *registryError
+	if errors.As(err, &regErr) && regErr.Status == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("check schema compatibility for subject %s: %w", subject, err)
+	}
+	if !resp.IsCompatible {
+		return fmt.Errorf("%w: subject %s: %s", ErrIncompatibleSchema, subject, strings.Join(resp.Messages, "; "))
+	}
+	return nil
+}
+
+// SubjectsOf returns the subjects a schema ID is registered under
+func (r *SchemaRegistry) SubjectsOf(ctx context.Context, id int) ([]string, error) {
+	r.mu.Lock()
+	subjects, ok := r.subjects[id]
+	r.mu.Unlock()
+	if ok {
+		return subjects, nil
+	}
+
+	var versions []struct {
+		Subject string `json:"subject"`
+	}
+	if err := r.do(ctx, http.MethodGet, fmt.Sprintf("/schemas/ids/%d/versions", id), nil, &versions); err != nil {
+		return nil, fmt.Errorf("look up schema %d: %w", id, err)
+	}
+	for _, version := range versions {
+		if !slices.Contains(subjects, version.Subject) {
+			subjects = append(subjects, version.Subject)
+		}
+	}
+
+	r.mu.Lock()
+	r.subjects[id] = subjects
+	r.mu.Unlock()
+	return subjects, nil
+}
+
+func protobufSchema(schema string) map[string]string {
+	return map[string]string{"schemaType": "PROTOBUF", "schema": schema}
+}
+
+func (r *SchemaRegistry) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.url+path, reader)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Accept", schemaRegistryContentType)
+	if body != nil {
+		req.Header.Set("Content-Type", schemaRegistryContentType)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		regErr := &registryError{Status: resp.StatusCode}
+		_ = json.NewDecoder(resp.Body).Decode(regErr)
+		return regErr
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// SchemaPolicy decides which topics use the schema registry. A nil *SchemaPolicy leaves
+// every topic as plain protobuf.
+type SchemaPolicy struct {
+	registry *SchemaRegistry
+	topics   map[string]bool
+	required map[string]bool
+}
+
+// NewSchemaPolicy enables the registry for topics, or for every topic when topics is
+// empty. Consumers of required topics reject messages without a schema ID; required
+// topics use the registry even when they are not listed in topics.
+func NewSchemaPolicy(registry *SchemaRegistry, topics, required []string) *SchemaPolicy {
+	policy := &SchemaPolicy{registry: registry, required: make(map[string]bool, len(required))}
+	if len(topics) > 0 {
+		policy.topics = make(map[string]bool, len(topics)+len(required))
+		for _, topic := range topics {
+			policy.topics[topic] = true
+		}
+	}
+	for _, topic := range required {
+		policy.required[topic] = true
+		if policy.topics != nil {
+			policy.topics[topic] = true
+		}
+	}
+	return policy
+}
+
+// Codec returns the codec for one event type on a topic
+func (p *SchemaPolicy) Codec(topic string, message proto.Message) *ProtoCodec {
+	if p == nil || (p.topics != nil && !p.topics[topic]) {
+		return nil
+	}
+	desc := message.ProtoReflect().Descriptor()
+	return &ProtoCodec{
+		registry: p.registry,
+		required: p.required[topic],
+		// topic and record name, so several event types can share a topic
+		subject: topic + "-" + string(desc.FullName()),
+		schema:  protoSchema(desc.ParentFile()),
+		indexes: messageIndexes(desc),
+	}
+}
+
+// ProtoCodec encodes one protobuf event type for a topic. Producers register the schema
+// and prefix messages with its ID; consumers check that a message's schema ID belongs to
+// the event before decoding it. A nil *ProtoCodec writes plain protobuf and reads plain
+// protobuf, JSON and framed messages without consulting a registry.
+type ProtoCodec struct {
+	registry *SchemaRegistry
+	required bool
+	subject  string
+	schema   string
+	indexes  []byte
+
+	mu sync.Mutex
+	id int
+}
+
+// Subject returns the registry subject of the event, empty for a nil codec
+func (c *ProtoCodec) Subject() string {
+	if c == nil {
+		return ""
+	}
+	return c.subject
+}
+
+// Check fails when the local schema cannot read what producers registered, so a consumer
+// built against an incompatible schema stops at startup instead of at every message
+func (c *ProtoCodec) Check(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	return c.registry.CheckCompatible(ctx, c.subject, c.schema)
+}
+
+// Encode marshals the message, prefixed with its schema ID. The schema is registered on
+// first use.
+func (c *ProtoCodec) Encode(ctx context.Context, message proto.Message) ([]byte, error) {
+	payload, err := proto.Marshal(message)
+	if err != nil || c == nil {
+		return payload, err
+	}
+
+	id, err := c.schemaID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, schemaHeaderLength, schemaHeaderLength+len(c.indexes)+len(payload))
+	data[0] = schemaMagicByte
+	binary.BigEndian.PutUint32(data[1:], uint32(id))
+	data = append(data, c.indexes...)
+	return append(data, payload...), nil
+}
+
+func (c *ProtoCodec) schemaID(ctx context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.id == 0 {
+		id, err := c.registry.Register(ctx, c.subject, c.schema)
+		if err != nil {
+			return 0, err
+		}
+		c.id = id
+	}
+	return c.id, nil
+}
+
+// Decode unmarshals a message written by Encode, or plain protobuf or JSON unless the
+// topic requires a schema ID
+func (c *ProtoCodec) Decode(ctx context.Context, data []byte, message proto.Message) error {
+	if len(data) == 0 || data[0] != schemaMagicByte {
+		if c != nil && c.required {
+			return fmt.Errorf("%w on subject %s", ErrMissingSchemaID, c.subject)
+		}
+		if err := proto.Unmarshal(data, message); err == nil {
+			return nil
+		}
+		if err := json.Unmarshal(data, message); err != nil {
+			return fmt.Errorf("failed to unmarshal as both protobuf and JSON: %w", err)
+		}
+		return nil
+	}
+
+	if len(data) < schemaHeaderLength {
+		return fmt.Errorf("truncated schema header")
+	}
+	id := int(binary.BigEndian.Uint32(data[1:schemaHeaderLength]))
+	indexes, payload, err := splitMessageIndexes(data[schemaHeaderLength:])
+	if err != nil {
+		return fmt.Errorf("schema %d: %w", id, err)
+	}
+
+	if c != nil {
+		subjects, err := c.registry.SubjectsOf(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(subjects, c.subject) {
+			return fmt.Errorf("%w: message written with schema %d of %v, expected subject %s",
+				ErrIncompatibleSchema, id, subjects, c.subject)
+		}
+		if !bytes.Equal(indexes, c.indexes) {
+			return fmt.Errorf("%w: message written for another message type of schema %d", ErrIncompatibleSchema, id)
+		}
+	}
+
+	if err := proto.Unmarshal(payload, message); err != nil {
+		return fmt.Errorf("failed to unmarshal message with schema %d: %w", id, err)
+	}
+	return nil
+}
+
+// messageIndexes encodes the path to the message within its file as zigzag varints, with
+// the common case of the first message shortened to a single zero
+func messageIndexes(desc protoreflect.MessageDescriptor) []byte {
+	var path []int
+	for d := protoreflect.Descriptor(desc); ; d = d.Parent() {
+		if _, ok := d.(protoreflect.MessageDescriptor); !ok {
+			break
+		}
+		path = append([]int{d.Index()}, path...)
+	}
+	if len(path) == 1 && path[0] == 0 {
+		return []byte{0}
+	}
+	out := protowire.AppendVarint(nil, protowire.EncodeZigZag(int64(len(path))))
+	for _, index := range path {
+		out = protowire.AppendVarint(out, protowire.EncodeZigZag(int64(index)))
+	}
+	return out
+}
+
+// splitMessageIndexes returns the encoded message indexes and the payload after them
+func splitMessageIndexes(data []byte) ([]byte, []byte, error) {
+	count, n := protowire.ConsumeVarint(data)
+	if n < 0 {
+		return nil, nil, fmt.Errorf("invalid message indexes")
+	}
+	end := n
+	for i := int64(0); i < protowire.DecodeZigZag(count); i++ {
+		_, n := protowire.ConsumeVarint(data[end:])
+		if n < 0 {
+			return nil, nil, fmt.Errorf("invalid message indexes")
+		}
+		end += n
+	}
+	return data[:end], data[end:], nil
+}
+
+// protoSchema renders a file descriptor as .proto source for the registry. Options and
+// comments are left out; they do not affect the wire format.
+func protoSchema(file protoreflect.FileDescriptor) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "syntax = %q;\n", file.Syntax().String())
+	if file.Package() != "" {
+		fmt.Fprintf(&b, "package %s;\n", file.Package())
+	}
+	for i := 0; i < file.Imports().Len(); i++ {
+		fmt.Fprintf(&b, "import %q;\n", file.Imports().Get(i).Path())
+	}
+	writeEnums(&b, file.Enums(), "")
+	for i := 0; i < file.Messages().Len(); i++ {
+		writeMessage(&b, file.Messages().Get(i), "")
+	}
+	return b.String()
+}
+
+func writeEnums(b *strings.Builder, enums protoreflect.EnumDescriptors, indent string) {
+	for i := 0; i < enums.Len(); i++ {
+		enum := enums.Get(i)
+		fmt.Fprintf(b, "%senum %s {\n", indent, enum.Name())
+		for j := 0; j < enum.Values().Len(); j++ {
+			value := enum.Values().Get(j)
+			fmt.Fprintf(b, "%s  %s = %d;\n", indent, value.Name(), value.Number())
+		}
+		fmt.Fprintf(b, "%s}\n", indent)
+	}
+}
+
+func writeMessage(b *strings.Builder, message protoreflect.MessageDescriptor, indent string) {
+	inner := indent + "  "
+	fmt.Fprintf(b, "%smessage %s {\n", indent, message.Name())
+	writeEnums(b, message.Enums(), inner)
+	for i := 0; i < message.Messages().Len(); i++ {
+		if nested := message.Messages().Get(i); !nested.IsMapEntry() {
+			writeMessage(b, nested, inner)
+		}
+	}
+
+	proto2 := message.ParentFile().Syntax() == protoreflect.Proto2
+	var oneof protoreflect.OneofDescriptor
+	for i := 0; i < message.Fields().Len(); i++ {
+		field := message.Fields().Get(i)
+		fieldIndent := inner
+		if o := field.ContainingOneof(); o != nil && !o.IsSynthetic() {
+			if o != oneof {
+				if oneof != nil {
+					fmt.Fprintf(b, "%s}\n", inner)
+				}
+				fmt.Fprintf(b, "%soneof %s {\n", inner, o.Name())
+				oneof = o
+			}
+			fieldIndent = inner + "  "
+		} else if oneof != nil {
+			fmt.Fprintf(b, "%s}\n", inner)
+			oneof = nil
+		}
+
+		var label string
+		switch {
+		case field.IsMap():
+		case field.Cardinality() == protoreflect.Repeated:
+			label = "repeated "
+		case field.Cardinality() == protoreflect.Required:
+			label = "required "
+		case field.HasOptionalKeyword() || (proto2 && field.ContainingOneof() == nil):
+			label = "optional "
+		}
+		fmt.Fprintf(b, "%s%s%s %s = %d;\n", fieldIndent, label, fieldType(field), field.Name(), field.Number())
+	}
+	if oneof != nil {
+		fmt.Fprintf(b, "%s}\n", inner)
+	}
+	fmt.Fprintf(b, "%s}\n", indent)
+}
+
+func fieldType(field protoreflect.FieldDescriptor) string {
+	switch {
+	case field.IsMap():
+		return fmt.Sprintf("map<%s, %s>", fieldType(field.MapKey()), fieldType(field.MapValue()))
+	case field.Message() != nil:
+		return "." + string(field.Message().FullName())
+	case field.Enum() != nil:
+		return "." + string(field.Enum().FullName())
+	default:
+		return field.Kind().String()
+	}
+}
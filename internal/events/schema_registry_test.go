@@ -0,0 +1,158 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
+)
+
+// fakeRegistry implements the parts of the schema registry API the codec uses
+type fakeRegistry struct {
+	mu         sync.Mutex
+	ids        map[string]int
+	schemas    map[string]string
+	compatible bool
+	registers  int
+}
+
+func newFakeRegistry(t *testing.T) (*fakeRegistry, *SchemaRegistry) {
+	fake := &fakeRegistry{ids: make(map[string]int), schemas: make(map[string]string), compatible: true}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, NewSchemaRegistry(server.URL + "/")
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", schemaRegistryContentType)
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodPost && parts[0] == "subjects":
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.registers++
+		subject := parts[1]
+		if _, ok := f.ids[subject]; !ok {
+			f.ids[subject] = len(f.ids) + 1
+		}
+		f.schemas[subject] = body["schema"]
+		json.NewEncoder(w).Encode(map[string]int{"id": f.ids[subject]})
+	case r.Method == http.MethodPost && parts[0] == "compatibility":
+		if _, ok := f.ids[parts[2]]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"error_code": 40401, "message": "Subject not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"is_compatible": f.compatible, "messages": []string{"field 2 changed type"}})
+	case r.Method == http.MethodGet && parts[0] == "schemas":
+		var versions []map[string]any
+		for subject, id := range f.ids {
+			if parts[2] == strconv.Itoa(id) {
+				versions = append(versions, map[string]any{"subject": subject, "version": 1})
+			}
+		}
+		if versions == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"error_code": 40403, "message": "Schema not found"})
+			return
+		}
+		json.NewEncoder(w).Encode(versions)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestProtoSchema(t *testing.T) {
+	schema := protoSchema((&article_eventspb.ArticlePersistedEvent{}).ProtoReflect().Descriptor().ParentFile())
+	assert.Contains(t, schema, "syntax = \"proto3\";\npackage article_events.v1;\n")
+	assert.Contains(t, schema, "message ArticlePersistedEvent {\n  uint64 article_id = 1;\n")
+	assert.Contains(t, schema, "  string request_id = 9;\n  repeated string languages = 10;\n}\n")
+	assert.Contains(t, schema, "message ArticleProcessedEvent {\n")
+}
+
+func TestProtoCodec_RoundTrip(t *testing.T) {
+	fake, registry := newFakeRegistry(t)
+	policy := NewSchemaPolicy(registry, nil, nil)
+	ctx := context.Background()
+
+	producer := policy.Codec("articles.processed", &article_eventspb.ArticleProcessedEvent{})
+	assert.Equal(t, "articles.processed-article_events.v1.ArticleProcessedEvent", producer.Subject())
+	require.NoError(t, producer.Check(ctx), "a subject without versions is compatible")
+
+	data, err := producer.Encode(ctx, &article_eventspb.ArticleProcessedEvent{ArticleId: 42, Summary: "short"})
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 0, 0, 0, 1}, data[:schemaHeaderLength])
+	assert.Equal(t, byte(2), data[schemaHeaderLength], "second message of the file")
+	_, err = producer.Encode(ctx, &article_eventspb.ArticleProcessedEvent{ArticleId: 43})
+	require.NoError(t, err)
+	assert.Equal(t, 1, fake.registers, "the schema is registered once")
+
+	// a consumer in another process looks the schema ID up
+	consumer := NewSchemaPolicy(NewSchemaRegistry(registry.url), nil, nil).Codec("articles.processed", &article_eventspb.ArticleProcessedEvent{})
+	var event article_eventspb.ArticleProcessedEvent
+	require.NoError(t, consumer.Decode(ctx, data, &event))
+	assert.Equal(t, uint64(42), event.ArticleId)
+	assert.Equal(t, "short", event.Summary)
+
+	// consumers without a registry still read framed and plain messages
+	var nilCodec *ProtoCodec
+	event.Reset()
+	require.NoError(t, nilCodec.Decode(ctx, data, &event))
+	assert.Equal(t, uint64(42), event.ArticleId)
+	plain, err := nilCodec.Encode(ctx, &event)
+	require.NoError(t, err)
+	event.Reset()
+	require.NoError(t, consumer.Decode(ctx, plain, &event), "plain protobuf is read unless the topic requires a schema ID")
+	assert.Equal(t, uint64(42), event.ArticleId)
+}
+
+func TestProtoCodec_RejectsIncompatible(t *testing.T) {
+	fake, registry := newFakeRegistry(t)
+	ctx := context.Background()
+
+	persisted := NewSchemaPolicy(registry, nil, nil).Codec("articles.new", &article_eventspb.ArticlePersistedEvent{})
+	data, err := persisted.Encode(ctx, &article_eventspb.ArticlePersistedEvent{ArticleId: 1, Title: "wrong topic"})
+	require.NoError(t, err)
+
+	processed := NewSchemaPolicy(registry, nil, []string{"articles.processed"}).Codec("articles.processed", &article_eventspb.ArticleProcessedEvent{})
+	var event article_eventspb.ArticleProcessedEvent
+	err = processed.Decode(ctx, data, &event)
+	require.ErrorIs(t, err, ErrIncompatibleSchema)
+	assert.Contains(t, err.Error(), "articles.new-article_events.v1.ArticlePersistedEvent")
+
+	plain, err := proto.Marshal(&article_eventspb.ArticleProcessedEvent{ArticleId: 1})
+	require.NoError(t, err)
+	require.ErrorIs(t, processed.Decode(ctx, plain, &event), ErrMissingSchemaID)
+
+	_, err = processed.Encode(ctx, &article_eventspb.ArticleProcessedEvent{ArticleId: 1})
+	require.NoError(t, err)
+	fake.compatible = false
+	err = processed.Check(ctx)
+	require.ErrorIs(t, err, ErrIncompatibleSchema)
+	assert.Contains(t, err.Error(), "field 2 changed type")
+}
+
+func TestSchemaPolicy_Topics(t *testing.T) {
+	_, registry := newFakeRegistry(t)
+	policy := NewSchemaPolicy(registry, []string{"articles.new"}, []string{"phoenix.events"})
+
+	assert.NotNil(t, policy.Codec("articles.new", &article_eventspb.ArticlePersistedEvent{}))
+	assert.NotNil(t, policy.Codec("phoenix.events", &article_eventspb.ArticlePersistedEvent{}), "required topics use the registry")
+	assert.Nil(t, policy.Codec("articles.processed", &article_eventspb.ArticleProcessedEvent{}))
+
+	var disabled *SchemaPolicy
+	assert.Nil(t, disabled.Codec("articles.new", &article_eventspb.ArticlePersistedEvent{}))
+}
@@ -0,0 +1,91 @@
+package events
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Fancu1/phoenix-rss/pkg/tracing"
+)
+
+// headerCarrier reads and writes trace context in the headers of a Kafka message
+type headerCarrier struct {
+	headers *[]kafka.Header
+}
+
+func (c headerCarrier) Get(key string) string {
+	for _, header := range *c.headers {
+		if header.Key == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+func (c headerCarrier) Set(key, value string) {
+	for i, header := range *c.headers {
+		if header.Key == key {
+			(*c.headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.headers = append(*c.headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, len(*c.headers))
+	for i, header := range *c.headers {
+		keys[i] = header.Key
+	}
+	return keys
+}
+
+// StartPublishSpan starts the span of publishing an event. Messages written with its
+// context carry it as the parent of the consumer's span.
+func StartPublishSpan(ctx context.Context, topic string, eventType EventType) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "publish "+string(eventType),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", topic),
+			attribute.String("messaging.operation.type", "publish"),
+		))
+}
+
+// StartConsumeSpan starts the span of handling a message, continuing the trace its
+// publisher started. It is named after the message's event type.
+func StartConsumeSpan(ctx context.Context, msg kafka.Message) (context.Context, trace.Span) {
+	headers := msg.Headers
+	ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier{headers: &headers})
+
+	name := "consume " + msg.Topic
+	if eventType, ok := EventTypeOf(msg); ok {
+		name = "consume " + string(eventType)
+	}
+	return tracing.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", msg.Topic),
+			attribute.String("messaging.operation.type", "process"),
+			attribute.Int("messaging.destination.partition.id", msg.Partition),
+			attribute.Int64("messaging.kafka.offset", msg.Offset),
+		))
+}
+
+// messageLink links a batch span to the trace the message was published in, since a
+// batch mixes the traces of many requests
+func messageLink(ctx context.Context, msg kafka.Message) trace.Link {
+	headers := msg.Headers
+	ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier{headers: &headers})
+	return trace.Link{SpanContext: trace.SpanContextFromContext(ctx)}
+}
+
+// withTraceContext appends the trace context of ctx to headers
+func withTraceContext(ctx context.Context, headers []kafka.Header) []kafka.Header {
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier{headers: &headers})
+	return headers
+}
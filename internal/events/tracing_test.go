@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceContextCrossesKafka(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	ctx, publish := StartPublishSpan(context.Background(), "feed.fetch", EventFeedFetch)
+	msg := kafka.Message{
+		Topic:   "feed.fetch",
+		Headers: WithContextHeaders(ctx, "req-1", []kafka.Header{{Key: EventTypeHeader, Value: []byte(EventFeedFetch)}}),
+	}
+	publish.End()
+
+	_, consume := StartConsumeSpan(context.Background(), msg)
+	consume.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "publish "+string(EventFeedFetch), spans[0].Name())
+	assert.Equal(t, trace.SpanKindProducer, spans[0].SpanKind())
+	assert.Equal(t, "consume "+string(EventFeedFetch), spans[1].Name())
+	assert.Equal(t, trace.SpanKindConsumer, spans[1].SpanKind())
+	assert.Equal(t, spans[0].SpanContext().TraceID(), spans[1].SpanContext().TraceID())
+	assert.Equal(t, spans[0].SpanContext().SpanID(), spans[1].Parent().SpanID())
+
+	link := messageLink(context.Background(), msg)
+	assert.Equal(t, spans[0].SpanContext().SpanID(), link.SpanContext.SpanID())
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	htmlstd "html"
 	"log/slog"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -17,6 +19,9 @@ import (
 	"github.com/Fancu1/phoenix-rss/internal/events"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
+	"github.com/Fancu1/phoenix-rss/internal/policy"
+	"github.com/Fancu1/phoenix-rss/pkg/dbtx"
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
 	"github.com/Fancu1/phoenix-rss/pkg/ierr"
 	"github.com/Fancu1/phoenix-rss/pkg/logger"
 	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
@@ -26,25 +31,229 @@ type ArticleServiceInterface interface {
 	FetchAndSaveArticles(ctx context.Context, feedID uint) ([]*models.Article, error)
 	ListArticlesByFeedID(ctx context.Context, userID, feedID uint) ([]*models.Article, error)
 	GetArticleByID(ctx context.Context, userID, articleID uint) (*models.Article, error)
+	OpenArticle(ctx context.Context, userID, articleID uint) (*models.Article, error)
+	GetArticleNavigation(ctx context.Context, userID, articleID uint) (*models.ArticleNavigation, error)
 	HandleArticleProcessed(ctx context.Context, event *article_eventspb.ArticleProcessedEvent) error
-	ListArticlesToCheck(ctx context.Context, publishedSince, lastCheckedBefore time.Time, pageSize int, pageToken string) ([]repository.ArticleCheckCandidate, string, error)
+	HandleArticlesProcessed(ctx context.Context, events []*article_eventspb.ArticleProcessedEvent) error
+	RegenerateSummary(ctx context.Context, userID, articleID uint) error
+	DeleteArticle(ctx context.Context, userID, articleID uint) error
+	RestoreArticle(ctx context.Context, userID, articleID uint) (*models.Article, error)
+	NextUnreadArticle(ctx context.Context, userID uint, opts NextUnreadOptions) (*models.Article, error)
+	SearchArticles(ctx context.Context, userID uint, opts SearchOptions) ([]*models.Article, int64, error)
+	SetArticleRead(ctx context.Context, userID, articleID uint, read bool) (*models.Article, error)
+	MarkFeedRead(ctx context.Context, userID, feedID uint, before time.Time) (int, error)
+	SetArticleStarred(ctx context.Context, userID, articleID uint, starred bool) (*models.Article, error)
+	ListStarredArticles(ctx context.Context, userID uint, offset, limit int) ([]*models.Article, int64, error)
+	ListUserArticles(ctx context.Context, userID uint, filter TimelineFilter, pageSize int, pageToken string) ([]*models.Article, string, int64, error)
+	AddArticleTags(ctx context.Context, userID, articleID uint, names []string) (*models.Article, error)
+	RemoveArticleTags(ctx context.Context, userID, articleID uint, names []string) (*models.Article, error)
+	ListTags(ctx context.Context, userID uint) ([]models.TagCount, error)
+	ListArticlesToCheck(ctx context.Context, publishedSince, lastCheckedBefore time.Time, pageSize int, pageToken string) ([]repository.ArticleCheckCandidate, string, repository.ArticleCheckCounts, error)
 }
 
+// Scopes for NextUnreadArticle
+const (
+	NextUnreadScopeFeed   = "feed"
+	NextUnreadScopeFolder = "folder"
+	NextUnreadScopeAll    = "all"
+)
+
+// NextUnreadOptions selects where NextUnreadArticle looks for the next unread article
+type NextUnreadOptions struct {
+	AfterID  uint   // current article; 0 starts from the newest one
+	Scope    string // one of the NextUnreadScope values
+	FeedID   uint   // feed for the feed scope; defaults to the feed of AfterID
+	FolderID uint   // folder for the folder scope, its subfolders included
+	MarkRead bool   // mark AfterID read in the same transaction
+}
+
+// SearchOptions is a full-text search over the articles of the user's subscribed feeds
+type SearchOptions struct {
+	Query  string // web search syntax: quoted phrases, OR and -excluded words
+	Offset int
+	Limit  int // DefaultSearchLimit when 0, at most MaxSearchLimit
+}
+
+// Bounds of a SearchArticles page and query
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+	MaxSearchQueryLen  = 256
+)
+
+// Bounds of a ListStarredArticles page
+const (
+	DefaultStarredLimit = 20
+	MaxStarredLimit     = 100
+)
+
+// Bounds of a ListUserArticles page
+const (
+	DefaultTimelineLimit = 50
+	MaxTimelineLimit     = 200
+)
+
+// TimelineFilter narrows ListUserArticles; zero fields do not filter
+type TimelineFilter struct {
+	Tag      string // name of a tag of the user
+	Unread   bool   // only articles the user has not read
+	Starred  bool   // only articles the user starred
+	FolderID uint   // only articles of the feeds the user filed in this folder
+}
+
+// DefaultTrashGracePeriod is how long a deleted article can be restored
+const DefaultTrashGracePeriod = 30 * 24 * time.Hour
+
+// DefaultSummaryLanguage is the language of the article summaries when none is configured
+const DefaultSummaryLanguage = "zh"
+
 type ArticleService struct {
 	parser        *gofeed.Parser
 	feedRepo      *repository.FeedRepository
 	articleRepo   *repository.ArticleRepository
 	eventProducer events.ArticleEventProducer
 	logger        *slog.Logger
+	trashGrace    time.Duration
+	secrets       models.SecretDecrypter
+	// summaryLanguage is the language of the summary variant kept on the article itself
+	summaryLanguage string
+
+	snapshots        *repository.SnapshotRepository // nil when snapshots are disabled
+	snapshotKeep     int
+	snapshotMaxBytes int64
+
+	rechecker *ReadRechecker // nil when on-read checks are disabled
+	feedLists *FeedListCache // nil when the api-service's feed lists are not invalidated
+
+	outbox *EventOutbox // nil publishes the events of new articles after saving them
+	uow    *dbtx.UnitOfWork
+
+	imports *policy.Engine // nil imports every new item of a fetch
 }
 
 func NewArticleService(feedRepo *repository.FeedRepository, articleRepo *repository.ArticleRepository, eventProducer events.ArticleEventProducer, logger *slog.Logger) *ArticleService {
 	return &ArticleService{
-		parser:        newFeedParser(),
-		feedRepo:      feedRepo,
-		articleRepo:   articleRepo,
-		eventProducer: eventProducer,
-		logger:        logger,
+		parser:          NewHTTPClientFactory(FetchIdentity{}).FeedParser(),
+		feedRepo:        feedRepo,
+		articleRepo:     articleRepo,
+		eventProducer:   eventProducer,
+		logger:          logger,
+		trashGrace:      DefaultTrashGracePeriod,
+		summaryLanguage: DefaultSummaryLanguage,
+	}
+}
+
+// SetOutbox stores the events of new articles and summary regenerations in the outbox
+// instead of publishing them. New articles are saved, marked processing and their events
+// stored in one transaction of uow.
+func (s *ArticleService) SetOutbox(outbox *EventOutbox, uow *dbtx.UnitOfWork) {
+	s.outbox = outbox
+	s.eventProducer = outbox
+	s.uow = uow
+}
+
+// SetReadRechecker queues update checks of older articles as users open them
+func (s *ArticleService) SetReadRechecker(rechecker *ReadRechecker) {
+	s.rechecker = rechecker
+}
+
+// SetFeedListCache drops the cached feed lists of a feed's subscribers when a fetch
+// changes its title, description or site URL
+func (s *ArticleService) SetFeedListCache(cache *FeedListCache) {
+	s.feedLists = cache
+}
+
+// SetHTTPClientFactory makes feed fetches use the factory's clients and identity
+func (s *ArticleService) SetHTTPClientFactory(factory *HTTPClientFactory) {
+	s.parser = factory.FeedParser()
+}
+
+// SetSecretDecrypter lets feed fetches send the custom headers subscribers configured
+func (s *ArticleService) SetSecretDecrypter(decrypter models.SecretDecrypter) {
+	s.secrets = decrypter
+}
+
+// FetchContext returns ctx carrying the feed's custom fetch headers, taken from the
+// longest-standing subscriber who set some. Failures are logged and the feed is fetched
+// without them.
+func (s *ArticleService) FetchContext(ctx context.Context, feedID uint) context.Context {
+	if s.secrets == nil {
+		return ctx
+	}
+	log := logger.FromContext(ctx)
+
+	ciphertext, err := s.feedRepo.FetchHeadersCiphertext(ctx, feedID)
+	if err != nil {
+		log.Warn("failed to load custom fetch headers", "feed_id", feedID, "error", err.Error())
+		return ctx
+	}
+	if ciphertext == "" {
+		return ctx
+	}
+
+	headers, err := models.OpenFetchHeaders(ciphertext, s.secrets)
+	if err != nil {
+		log.Warn("failed to open custom fetch headers", "feed_id", feedID, "error", err.Error())
+		return ctx
+	}
+	return httpclient.WithHeaders(ctx, headers)
+}
+
+// SetSnapshots keeps the last keep raw responses of every feed, each body capped at maxBytes
+func (s *ArticleService) SetSnapshots(snapshots *repository.SnapshotRepository, keep int, maxBytes int64) {
+	s.snapshots = snapshots
+	s.snapshotKeep = keep
+	s.snapshotMaxBytes = maxBytes
+}
+
+// saveSnapshot stores the recorded response of a fetch along with the parse error, if any.
+// Failures are logged; a snapshot never fails the fetch.
+func (s *ArticleService) saveSnapshot(ctx context.Context, feedID uint, rec *httpclient.Recording, parseErr error) {
+	if rec == nil || rec.StatusCode() == 0 {
+		return // disabled, or no response arrived
+	}
+	log := logger.FromContext(ctx)
+
+	raw, truncated := rec.Body()
+	snapshot, err := models.NewFeedSnapshot(feedID, rec.StatusCode(), rec.Header().Get("Content-Type"), raw, truncated, parseErr)
+	if err == nil {
+		err = s.snapshots.Save(ctx, snapshot, s.snapshotKeep)
+	}
+	if err != nil {
+		log.Warn("failed to save feed snapshot", "feed_id", feedID, "error", err.Error())
+	}
+}
+
+// SetTrashGracePeriod sets how long deleted articles stay restorable
+func (s *ArticleService) SetTrashGracePeriod(grace time.Duration) {
+	s.trashGrace = grace
+}
+
+// SetSummaryLanguage sets the language of the summary variant written to the article's
+// own summary, the one clients see unless they ask for another
+func (s *ArticleService) SetSummaryLanguage(language string) {
+	s.summaryLanguage = language
+}
+
+// aiResult is the repository form of a processed event. Events without a language come
+// from AI services that only summarized in the default language.
+func (s *ArticleService) aiResult(event *article_eventspb.ArticleProcessedEvent) repository.AIResult {
+	language := event.Language
+	if language == "" {
+		language = s.summaryLanguage
+	}
+	return repository.AIResult{
+		ArticleID:        uint(event.ArticleId),
+		Summary:          event.Summary,
+		ProcessingModel:  event.ProcessingModel,
+		ProcessingPrompt: event.ProcessingPrompt,
+		SummaryTruncated: event.SummaryTruncated,
+		Language:         language,
+		Default:          language == s.summaryLanguage,
+		Failed:           event.Failed,
+		ErrorClass:       event.ErrorClass,
+		RequestID:        event.RequestId,
+		Topics:           event.Topics,
 	}
 }
 
@@ -66,18 +275,43 @@ func (s *ArticleService) FetchAndSaveArticles(ctx context.Context, feedID uint)
 
 	log.Info("parsing feed from URL", "feed_id", feedID, "url", feed.URL)
 
-	parsedFeed, err := s.parser.ParseURLWithContext(feed.URL, ctx)
+	fetchCtx := s.FetchContext(ctx, feedID)
+	var rec *httpclient.Recording
+	if s.snapshots != nil && s.snapshotKeep > 0 {
+		rec = httpclient.NewRecording(s.snapshotMaxBytes)
+		fetchCtx = httpclient.WithRecording(fetchCtx, rec)
+	}
+
+	resp, err := fetchFeed(fetchCtx, s.parser, feed)
+	if err != nil || !resp.notModified {
+		s.saveSnapshot(ctx, feedID, rec, err)
+	}
 	if err != nil {
-		log.Error("failed to parse feed", "feed_id", feedID, "url", feed.URL, "error", err.Error())
-		return nil, fmt.Errorf("failed to parse feed %d (%s) from URL '%s': %w", feedID, feed.Title, feed.URL, ierr.ErrFeedFetchFailed.WithCause(err))
+		fetchErr := fetchError(err)
+		log.Error("failed to parse feed", "feed_id", feedID, "url", feed.URL, "error_code", fetchErr.Code, "error", err.Error())
+		return nil, fmt.Errorf("failed to parse feed %d (%s) from URL '%s': %w", feedID, feed.Title, feed.URL, fetchErr)
 	}
+	if resp.notModified {
+		log.Info("feed not modified", "feed_id", feedID)
+		return nil, nil
+	}
+	parsedFeed := resp.feed
 
 	log.Info("parsed feed successfully", "feed_id", feedID, "article_count", len(parsedFeed.Items))
+	s.refreshFeedMetadata(ctx, feed, parsedFeed)
 
 	var articles []*models.Article
 	var newArticles []*models.Article
+	requestID, _ := logger.GetRequestID(ctx)
+	publishedDates, undated := itemDates(parsedFeed.Items, time.Now())
+	s.recordDateReliability(ctx, feed, len(parsedFeed.Items), undated)
 
-	for _, item := range parsedFeed.Items {
+	held := 0
+	for i, item := range parsedFeed.Items {
+		if feed.ImportedSince != nil && publishedDates[i].Before(*feed.ImportedSince) {
+			held++ // left out by the import limits until a backfill
+			continue
+		}
 		exists, err := s.articleRepo.ExistsByURL(ctx, item.Link)
 		if err != nil {
 			log.Warn("failed to check if article exists", "url", item.Link, "error", err.Error())
@@ -90,69 +324,102 @@ func (s *ArticleService) FetchAndSaveArticles(ctx context.Context, feedID uint)
 			continue
 		}
 
-		publishedAt := time.Now()
-		if item.PublishedParsed != nil {
-			publishedAt = *item.PublishedParsed
-		}
-
-		baseURL := firstNonEmpty(item.Link, parsedFeed.Link, feed.URL)
-		content, description, sanitizeErr := sanitizeFeedItem(item, baseURL)
-		if sanitizeErr != nil {
-			log.Warn("failed to sanitize article content", "url", item.Link, "error", sanitizeErr.Error())
-			fallback := firstNonEmpty(item.Content, item.Description)
-			safeText := sanitizePlainText(fallback)
-			if safeText != "" {
-				content = "<pre>" + htmlstd.EscapeString(safeText) + "</pre>"
-				if description == "" {
-					description = safeText
-				}
-			}
-		}
-
-		article := &models.Article{
-			Title:       item.Title,
-			URL:         item.Link,
-			Description: description,
-			Content:     content,
-			FeedID:      feedID,
-			PublishedAt: publishedAt,
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-		}
-
+		article := newItemArticle(ctx, feed, parsedFeed, item, publishedDates[i], requestID)
 		articles = append(articles, article)
 		newArticles = append(newArticles, article)
 
 		log.Debug("prepared new article", "title", item.Title, "url", item.Link)
 	}
+	if held > 0 {
+		log.Debug("skipped items held back by the import limits", "feed_id", feedID, "held_items", held, "imported_since", *feed.ImportedSince)
+	}
+	newArticles = s.limitImport(ctx, feed, newArticles)
+	articles = newArticles
 
 	if len(newArticles) == 0 {
 		log.Info("no new articles to save", "feed_id", feedID)
+		s.saveHTTPValidators(ctx, feed, resp)
 		return articles, nil
 	}
 
 	log.Info("saving new articles", "feed_id", feedID, "new_article_count", len(newArticles))
+	if err := s.saveNewArticles(ctx, feed, newArticles, requestID); err != nil {
+		return nil, err
+	}
+	s.saveHTTPValidators(ctx, feed, resp)
+	return articles, nil
+}
+
+// newItemArticle is the article of a feed item that is not stored yet, its content
+// sanitized
+func newItemArticle(ctx context.Context, feed *models.Feed, parsedFeed *gofeed.Feed, item *gofeed.Item, publishedAt time.Time, requestID string) *models.Article {
+	baseURL := firstNonEmpty(item.Link, parsedFeed.Link, feed.URL)
+	content, description, sanitizeErr := sanitizeFeedItem(item, baseURL)
+	if sanitizeErr != nil {
+		logger.FromContext(ctx).Warn("failed to sanitize article content", "url", item.Link, "error", sanitizeErr.Error())
+		fallback := firstNonEmpty(item.Content, item.Description)
+		safeText := sanitizePlainText(fallback)
+		if safeText != "" {
+			content = "<pre>" + htmlstd.EscapeString(safeText) + "</pre>"
+			if description == "" {
+				description = safeText
+			}
+		}
+	}
+
+	return &models.Article{
+		Title:       item.Title,
+		URL:         item.Link,
+		Description: description,
+		Content:     content,
+		Direction:   articleDirection(item.Title, content, parsedFeed.Language),
+		FeedID:      feed.ID,
+		PublishedAt: publishedAt,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		RequestID:   optionalString(requestID),
+	}
+}
 
-	err = s.articleRepo.CreateBatch(ctx, newArticles)
+// saveNewArticles stores the new articles of the feed and queues them for the AI service,
+// through the outbox in the same transaction when there is one
+func (s *ArticleService) saveNewArticles(ctx context.Context, feed *models.Feed, newArticles []*models.Article, requestID string) error {
+	log := logger.FromContext(ctx)
+	feedID := feed.ID
+	languages := s.readerLanguages(ctx, feedID)
+
+	var err error
+	if s.outbox != nil {
+		err = s.uow.Do(ctx, func(tx *gorm.DB) error {
+			if err := s.articleRepo.WithTx(tx).CreateBatch(ctx, newArticles); err != nil {
+				return err
+			}
+			persisted := make([]*article_eventspb.ArticlePersistedEvent, len(newArticles))
+			ids := make([]uint, len(newArticles))
+			for i, article := range newArticles {
+				persisted[i] = articlePersistedEvent(article, requestID, languages)
+				ids[i] = article.ID
+			}
+			if err := s.outbox.WithTx(tx).PublishArticlesPersisted(ctx, persisted); err != nil {
+				return err
+			}
+			return s.articleRepo.WithTx(tx).MarkProcessing(ctx, ids...)
+		})
+	} else {
+		err = s.articleRepo.CreateBatch(ctx, newArticles)
+	}
 	if err != nil {
 		log.Error("failed to save articles", "feed_id", feedID, "error", err.Error())
-		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to save %d articles for feed %d (%s): %w", len(newArticles), feedID, feed.Title, err))
+		return ierr.NewDatabaseError(fmt.Errorf("failed to save %d articles for feed %d (%s): %w", len(newArticles), feedID, feed.Title, err))
 	}
 
 	log.Info("successfully saved articles", "feed_id", feedID, "saved_count", len(newArticles))
 
-	// Publish ArticlePersistedEvent for each new article
-	if s.eventProducer != nil {
+	// Publish ArticlePersistedEvent for each new article, unless the outbox already has them
+	if s.eventProducer != nil && s.outbox == nil {
+		queued := make([]uint, 0, len(newArticles))
 		for _, article := range newArticles {
-			event := &article_eventspb.ArticlePersistedEvent{
-				ArticleId:   uint64(article.ID),
-				FeedId:      uint64(article.FeedID),
-				Title:       article.Title,
-				Content:     article.Content,
-				Url:         article.URL,
-				Description: article.Description,
-				PublishedAt: article.PublishedAt.Unix(),
-			}
+			event := articlePersistedEvent(article, requestID, languages)
 
 			if err := s.eventProducer.PublishArticlePersisted(ctx, event); err != nil {
 				log.Error("failed to publish article persisted event",
@@ -160,28 +427,160 @@ func (s *ArticleService) FetchAndSaveArticles(ctx context.Context, feedID uint)
 					"feed_id", feedID,
 					"error", err.Error())
 			} else {
+				queued = append(queued, article.ID)
 				log.Debug("published article persisted event",
 					"article_id", article.ID,
 					"feed_id", feedID)
 			}
 		}
+		if err := s.articleRepo.MarkProcessing(ctx, queued...); err != nil {
+			log.Error("failed to mark articles as processing", "feed_id", feedID, "error", err.Error())
+		}
 	}
+	return nil
+}
 
-	return articles, nil
+func articlePersistedEvent(article *models.Article, requestID string, languages []string) *article_eventspb.ArticlePersistedEvent {
+	return &article_eventspb.ArticlePersistedEvent{
+		ArticleId:   uint64(article.ID),
+		FeedId:      uint64(article.FeedID),
+		Title:       article.Title,
+		Content:     article.Content,
+		Url:         article.URL,
+		Description: article.Description,
+		PublishedAt: article.PublishedAt.Unix(),
+		RequestId:   requestID,
+		Languages:   languages,
+	}
+}
+
+// readerLanguages are the summary languages the subscribers of the feed prefer, which the
+// AI service summarizes new articles in besides the instance's languages. Failures are
+// logged; the articles are then only summarized in the instance's languages.
+func (s *ArticleService) readerLanguages(ctx context.Context, feedID uint) []string {
+	languages, err := s.feedRepo.SubscriberSummaryLanguages(ctx, feedID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("failed to load subscribers' summary languages", "feed_id", feedID, "error", err.Error())
+		return nil
+	}
+	return languages
+}
+
+// refreshFeedMetadata stores the title, description and site URL the parsed feed declares
+// when they differ from the stored ones, which for a new feed replaces the URL it was
+// titled with until its first fetch. A feed without a title keeps the one it has.
+// Failures are logged; the next fetch tries again.
+func (s *ArticleService) refreshFeedMetadata(ctx context.Context, feed *models.Feed, parsed *gofeed.Feed) {
+	meta := parsedFeedMetadata(feed, parsed)
+	if meta == (models.FeedMetadata{Title: feed.Title, Description: feed.Description, SiteURL: feed.SiteURL}) {
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	if err := s.feedRepo.UpdateFeedMetadata(ctx, feed.ID, meta); err != nil {
+		log.Warn("failed to update feed metadata", "feed_id", feed.ID, "error", err.Error())
+		return
+	}
+	log.Info("updated feed metadata", "feed_id", feed.ID, "title", meta.Title, "previous_title", feed.Title)
+	feed.Title, feed.Description, feed.SiteURL = meta.Title, meta.Description, meta.SiteURL
+
+	if s.feedLists == nil {
+		return
+	}
+	userIDs, err := s.feedRepo.SubscriberIDs(ctx, feed.ID)
+	if err == nil {
+		err = s.feedLists.Invalidate(ctx, userIDs)
+	}
+	if err != nil {
+		log.Warn("failed to invalidate subscribers' feed lists", "feed_id", feed.ID, "error", err.Error())
+	}
+}
+
+// recordDateReliability flags the feed's dates as unreliable when a fetch found items
+// without a usable date, which are then dated from their place in the feed, and clears
+// the flag once every item has one
+func (s *ArticleService) recordDateReliability(ctx context.Context, feed *models.Feed, items, undated int) {
+	unreliable := undated > 0
+	if items == 0 || unreliable == feed.DatesUnreliable {
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	if err := s.feedRepo.SetDatesUnreliable(ctx, feed.ID, unreliable); err != nil {
+		log.Warn("failed to record feed date reliability", "feed_id", feed.ID, "error", err.Error())
+		return
+	}
+	feed.DatesUnreliable = unreliable
+	if unreliable {
+		log.Warn("feed has items without usable dates, dating them from their order", "feed_id", feed.ID, "undated_items", undated, "items", items)
+	} else {
+		log.Info("feed dates are reliable again", "feed_id", feed.ID)
+	}
+}
+
+// parsedFeedMetadata cleans up what a parsed feed says about itself: markup is stripped
+// from the title and description, and the site URL must be an http(s) URL, resolved
+// against the feed's own URL
+func parsedFeedMetadata(feed *models.Feed, parsed *gofeed.Feed) models.FeedMetadata {
+	meta := models.FeedMetadata{
+		Title:       feedText(parsed.Title),
+		Description: feedText(parsed.Description),
+	}
+	if meta.Title == "" {
+		meta.Title = feed.Title
+	}
+	if link := strings.TrimSpace(parsed.Link); link != "" {
+		base, err := url.Parse(feed.URL)
+		if ref, refErr := url.Parse(link); err == nil && refErr == nil {
+			if site := base.ResolveReference(ref); (site.Scheme == "http" || site.Scheme == "https") && site.Host != "" {
+				meta.SiteURL = site.String()
+			}
+		}
+	}
+	return meta
+}
+
+// feedText is value as plain text on a single line
+func feedText(value string) string {
+	return strings.Join(strings.Fields(htmlstd.UnescapeString(sanitizePlainText(value))), " ")
+}
+
+// saveHTTPValidators stores the validators of a feed response once its articles are saved;
+// storing them earlier would make the next fetch skip articles that failed to save.
+// Failures are logged, the next fetch just downloads the feed again.
+func (s *ArticleService) saveHTTPValidators(ctx context.Context, feed *models.Feed, resp *feedResponse) {
+	if equalOptional(feed.HTTPETag, resp.etag) && equalOptional(feed.HTTPLastModified, resp.lastModified) {
+		return
+	}
+	if err := s.feedRepo.UpdateHTTPValidators(ctx, feed.ID, resp.etag, resp.lastModified); err != nil {
+		logger.FromContext(ctx).Warn("failed to store feed validators", "feed_id", feed.ID, "error", err.Error())
+	}
+}
+
+func equalOptional(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
 
-func (s *ArticleService) ListArticlesToCheck(ctx context.Context, publishedSince, lastCheckedBefore time.Time, pageSize int, pageToken string) ([]repository.ArticleCheckCandidate, string, error) {
+// articleCheckTopFeeds is how many feeds the counts of ListArticlesToCheck break down
+const articleCheckTopFeeds = 10
+
+// ListArticlesToCheck lists a page of the articles due for an update check, and counts
+// the candidates after it, so the scheduler can tell how far along a pass is
+func (s *ArticleService) ListArticlesToCheck(ctx context.Context, publishedSince, lastCheckedBefore time.Time, pageSize int, pageToken string) ([]repository.ArticleCheckCandidate, string, repository.ArticleCheckCounts, error) {
 	log := logger.FromContext(ctx)
 
 	if pageSize <= 0 {
-		return nil, "", fmt.Errorf("pageSize must be greater than zero")
+		return nil, "", repository.ArticleCheckCounts{}, fmt.Errorf("pageSize must be greater than zero")
 	}
 
 	var cursor *repository.ArticleCheckCursor
 	if strings.TrimSpace(pageToken) != "" {
 		parsed, err := decodeArticleCursor(pageToken)
 		if err != nil {
-			return nil, "", fmt.Errorf("invalid page token: %w", err)
+			return nil, "", repository.ArticleCheckCounts{}, fmt.Errorf("invalid page token: %w", err)
 		}
 		cursor = parsed
 	}
@@ -193,14 +592,24 @@ func (s *ArticleService) ListArticlesToCheck(ctx context.Context, publishedSince
 			"published_since", publishedSince,
 			"last_checked_before", lastCheckedBefore,
 		)
-		return nil, "", ierr.NewDatabaseError(fmt.Errorf("list articles to check failed: %w", err))
+		return nil, "", repository.ArticleCheckCounts{}, ierr.NewDatabaseError(fmt.Errorf("list articles to check failed: %w", err))
 	}
 
 	if nextCursor == nil {
-		return items, "", nil
+		return items, "", repository.ArticleCheckCounts{}, nil
+	}
+
+	counts, err := s.articleRepo.CountArticlesToCheck(ctx, publishedSince, lastCheckedBefore, nextCursor, articleCheckTopFeeds)
+	if err != nil {
+		log.Error("failed to count articles to check", "error", err)
+		return nil, "", repository.ArticleCheckCounts{}, ierr.NewDatabaseError(fmt.Errorf("count articles to check failed: %w", err))
+	}
+	if counts.Remaining == 0 {
+		// the page was the last one
+		return items, "", counts, nil
 	}
 
-	return items, encodeArticleCursor(*nextCursor), nil
+	return items, encodeArticleCursor(*nextCursor), counts, nil
 }
 
 func encodeArticleCursor(cursor repository.ArticleCheckCursor) string {
@@ -249,6 +658,10 @@ func (s *ArticleService) ListArticlesByFeedID(ctx context.Context, userID, feedI
 		log.Error("failed to list articles", "feed_id", feedID, "error", err.Error())
 		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to list articles for feed %d: %w", feedID, err))
 	}
+	if err := s.articleRepo.ApplyReadState(ctx, userID, articles...); err != nil {
+		log.Error("failed to load read state", "user_id", userID, "feed_id", feedID, "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to load read state of feed %d for user %d: %w", feedID, userID, err))
+	}
 
 	log.Info("successfully listed articles", "user_id", userID, "feed_id", feedID, "count", len(articles))
 	return articles, nil
@@ -281,10 +694,248 @@ func (s *ArticleService) GetArticleByID(ctx context.Context, userID, articleID u
 		return nil, ierr.ErrNotSubscribed
 	}
 
+	if err := s.articleRepo.ApplyReadState(ctx, userID, article); err != nil {
+		log.Error("failed to load read state", "user_id", userID, "article_id", articleID, "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to load read state of article %d for user %d: %w", articleID, userID, err))
+	}
+
+	return article, nil
+}
+
+// SetArticleRead marks an article read or unread for the user alone and returns it with
+// the new state
+func (s *ArticleService) SetArticleRead(ctx context.Context, userID, articleID uint, read bool) (*models.Article, error) {
+	article, err := s.GetArticleByID(ctx, userID, articleID)
+	if err != nil {
+		return nil, err
+	}
+	if article.Read == read {
+		return article, nil
+	}
+
+	if err := s.articleRepo.SetRead(ctx, userID, articleID, read); err != nil {
+		logger.FromContext(ctx).Error("failed to set read state", "user_id", userID, "article_id", articleID, "read", read, "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to set read state of article %d for user %d: %w", articleID, userID, err))
+	}
+	article.Read = read
+	return article, nil
+}
+
+// MarkFeedRead marks every article of a subscribed feed read for the user, only those
+// published at or before a non-zero before, so articles that arrived while the user was
+// reading stay unread. It returns how many articles it marked.
+func (s *ArticleService) MarkFeedRead(ctx context.Context, userID, feedID uint, before time.Time) (int, error) {
+	log := logger.FromContext(ctx)
+
+	isSubscribed, err := s.feedRepo.IsUserSubscribed(ctx, userID, feedID)
+	if err != nil {
+		log.Error("failed to verify subscription", "user_id", userID, "feed_id", feedID, "error", err.Error())
+		return 0, ierr.NewDatabaseError(fmt.Errorf("failed to verify subscription for user %d and feed %d: %w", userID, feedID, err))
+	}
+	if !isSubscribed {
+		return 0, ierr.ErrNotSubscribed
+	}
+
+	marked, err := s.articleRepo.MarkFeedRead(ctx, userID, feedID, before)
+	if err != nil {
+		log.Error("failed to mark feed read", "user_id", userID, "feed_id", feedID, "error", err.Error())
+		return marked, ierr.NewDatabaseError(fmt.Errorf("failed to mark feed %d read for user %d: %w", feedID, userID, err))
+	}
+	log.Info("marked feed read", "user_id", userID, "feed_id", feedID, "marked", marked)
+	return marked, nil
+}
+
+// SetArticleStarred stars or unstars an article for the user alone and returns it with
+// the new state
+func (s *ArticleService) SetArticleStarred(ctx context.Context, userID, articleID uint, starred bool) (*models.Article, error) {
+	article, err := s.GetArticleByID(ctx, userID, articleID)
+	if err != nil {
+		return nil, err
+	}
+	if article.Starred == starred {
+		return article, nil
+	}
+
+	if err := s.articleRepo.SetStarred(ctx, userID, articleID, starred); err != nil {
+		logger.FromContext(ctx).Error("failed to set starred state", "user_id", userID, "article_id", articleID, "starred", starred, "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to set starred state of article %d for user %d: %w", articleID, userID, err))
+	}
+	article.Starred = starred
+	return article, nil
+}
+
+// ListStarredArticles returns a page of the articles the user starred across their
+// subscribed feeds, most recently starred first, and the number of starred articles
+func (s *ArticleService) ListStarredArticles(ctx context.Context, userID uint, offset, limit int) ([]*models.Article, int64, error) {
+	log := logger.FromContext(ctx)
+
+	if offset < 0 {
+		return nil, 0, ierr.NewValidationError("offset must not be negative")
+	}
+	if limit <= 0 {
+		limit = DefaultStarredLimit
+	}
+	limit = min(limit, MaxStarredLimit)
+
+	articles, total, err := s.articleRepo.ListStarred(ctx, userID, offset, limit)
+	if err != nil {
+		log.Error("failed to list starred articles", "user_id", userID, "error", err.Error())
+		return nil, 0, ierr.NewDatabaseError(fmt.Errorf("failed to list starred articles for user %d: %w", userID, err))
+	}
+	if err := s.articleRepo.ApplyReadState(ctx, userID, articles...); err != nil {
+		log.Error("failed to load read state", "user_id", userID, "error", err.Error())
+		return nil, 0, ierr.NewDatabaseError(fmt.Errorf("failed to load read state for user %d: %w", userID, err))
+	}
+	return articles, total, nil
+}
+
+// ListUserArticles returns a page of the timeline of the user's subscribed feeds matching
+// filter, newest published first, the token of the next page, empty on the last one, and
+// the number of articles in the timeline. Articles published while a client pages through
+// do not shift its pages.
+func (s *ArticleService) ListUserArticles(ctx context.Context, userID uint, filter TimelineFilter, pageSize int, pageToken string) ([]*models.Article, string, int64, error) {
+	log := logger.FromContext(ctx)
+
+	if pageSize <= 0 {
+		pageSize = DefaultTimelineLimit
+	}
+	pageSize = min(pageSize, MaxTimelineLimit)
+
+	repoFilter := repository.TimelineFilter{Unread: filter.Unread, Starred: filter.Starred, FolderID: filter.FolderID}
+	if filter.Tag != "" {
+		tag, err := models.NormalizeTagName(filter.Tag)
+		if err != nil {
+			return nil, "", 0, ierr.NewValidationError(err.Error())
+		}
+		repoFilter.Tag = tag
+	}
+
+	var cursor *repository.ArticleCheckCursor
+	if strings.TrimSpace(pageToken) != "" {
+		parsed, err := decodeArticleCursor(pageToken)
+		if err != nil {
+			return nil, "", 0, ierr.NewValidationError("invalid page token")
+		}
+		cursor = parsed
+	}
+
+	articles, next, total, err := s.articleRepo.ListUserArticles(ctx, userID, repoFilter, pageSize, cursor)
+	if err != nil {
+		log.Error("failed to list user articles", "user_id", userID, "error", err.Error())
+		return nil, "", 0, ierr.NewDatabaseError(fmt.Errorf("failed to list articles for user %d: %w", userID, err))
+	}
+	if err := s.articleRepo.ApplyReadState(ctx, userID, articles...); err != nil {
+		log.Error("failed to load read state", "user_id", userID, "error", err.Error())
+		return nil, "", 0, ierr.NewDatabaseError(fmt.Errorf("failed to load read state for user %d: %w", userID, err))
+	}
+
+	var nextToken string
+	if next != nil {
+		nextToken = encodeArticleCursor(*next)
+	}
+	return articles, nextToken, total, nil
+}
+
+// AddArticleTags puts the user's named tags on an article of their subscribed feeds,
+// creating the tags they do not have yet, and returns the article with its tags
+func (s *ArticleService) AddArticleTags(ctx context.Context, userID, articleID uint, names []string) (*models.Article, error) {
+	names, err := normalizeTagNames(names)
+	if err != nil {
+		return nil, err
+	}
+	article, err := s.GetArticleByID(ctx, userID, articleID)
+	if err != nil {
+		return nil, err
+	}
+
+	tagged := len(article.Tags)
+	for _, name := range names {
+		if !slices.Contains(article.Tags, name) {
+			tagged++
+		}
+	}
+	if tagged > models.MaxTagsPerArticle {
+		return nil, ierr.NewValidationError(fmt.Sprintf("an article can have at most %d tags", models.MaxTagsPerArticle))
+	}
+
+	if err := s.articleRepo.AddTags(ctx, userID, articleID, names); err != nil {
+		logger.FromContext(ctx).Error("failed to tag article", "user_id", userID, "article_id", articleID, "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to tag article %d for user %d: %w", articleID, userID, err))
+	}
+	return s.GetArticleByID(ctx, userID, articleID)
+}
+
+// RemoveArticleTags takes the user's named tags off an article of their subscribed feeds
+// and returns the article with the tags left
+func (s *ArticleService) RemoveArticleTags(ctx context.Context, userID, articleID uint, names []string) (*models.Article, error) {
+	names, err := normalizeTagNames(names)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.GetArticleByID(ctx, userID, articleID); err != nil {
+		return nil, err
+	}
+
+	if err := s.articleRepo.RemoveTags(ctx, userID, articleID, names); err != nil {
+		logger.FromContext(ctx).Error("failed to untag article", "user_id", userID, "article_id", articleID, "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to untag article %d for user %d: %w", articleID, userID, err))
+	}
+	return s.GetArticleByID(ctx, userID, articleID)
+}
+
+// ListTags returns the user's tags by name with the number of articles of their
+// subscribed feeds each is on
+func (s *ArticleService) ListTags(ctx context.Context, userID uint) ([]models.TagCount, error) {
+	tags, err := s.articleRepo.ListTags(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to list tags", "user_id", userID, "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to list tags for user %d: %w", userID, err))
+	}
+	return tags, nil
+}
+
+// normalizeTagNames normalizes the tag names of a request, of which there must be some
+func normalizeTagNames(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, ierr.NewValidationError("no tags given")
+	}
+	if len(names) > models.MaxTagsPerArticle {
+		return nil, ierr.NewValidationError(fmt.Sprintf("an article can have at most %d tags", models.MaxTagsPerArticle))
+	}
+	names, err := models.NormalizeTagNames(names)
+	if err != nil {
+		return nil, ierr.NewValidationError(err.Error())
+	}
+	return names, nil
+}
+
+// GetArticleNavigation returns the feed of an article the user may read and its
+// neighbours in the feed's newest-first list
+func (s *ArticleService) GetArticleNavigation(ctx context.Context, userID, articleID uint) (*models.ArticleNavigation, error) {
+	navigation, err := s.articleRepo.Navigation(ctx, userID, articleID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ierr.ErrArticleNotFound
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to load article navigation", "article_id", articleID, "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to get navigation of article %d: %w", articleID, err))
+	}
+	return navigation, nil
+}
+
+// OpenArticle returns an article a user opens to read it, and queues an update check of
+// it when it is due one
+func (s *ArticleService) OpenArticle(ctx context.Context, userID, articleID uint) (*models.Article, error) {
+	article, err := s.GetArticleByID(ctx, userID, articleID)
+	if err != nil {
+		return nil, err
+	}
+	s.rechecker.ArticleOpened(ctx, article)
 	return article, nil
 }
 
-// HandleArticleProcessed handles an ArticleProcessedEvent by updating the article with AI data
+// HandleArticleProcessed handles an ArticleProcessedEvent by updating the article with AI data,
+// or by recording the failure when processing gave up
 func (s *ArticleService) HandleArticleProcessed(ctx context.Context, event *article_eventspb.ArticleProcessedEvent) error {
 	log := logger.FromContext(ctx)
 
@@ -292,6 +943,9 @@ func (s *ArticleService) HandleArticleProcessed(ctx context.Context, event *arti
 		"article_id", event.ArticleId,
 		"summary_length", len(event.Summary),
 		"processing_model", event.ProcessingModel,
+		"processing_prompt", event.ProcessingPrompt,
+		"summary_truncated", event.SummaryTruncated,
+		"language", event.Language,
 	)
 
 	// Validate the event
@@ -299,13 +953,16 @@ func (s *ArticleService) HandleArticleProcessed(ctx context.Context, event *arti
 		return fmt.Errorf("invalid article ID in processed event: %d", event.ArticleId)
 	}
 
-	// Update the article with AI data
-	err := s.articleRepo.UpdateWithAIData(
-		ctx,
-		uint(event.ArticleId),
-		event.Summary,
-		event.ProcessingModel,
-	)
+	if event.Failed {
+		if err := s.articleRepo.MarkProcessingFailed(ctx, uint(event.ArticleId), event.ErrorClass, event.RequestId); err != nil {
+			return ierr.NewDatabaseError(fmt.Errorf("failed to mark processing of article %d as failed: %w", event.ArticleId, err))
+		}
+		log.Warn("AI processing failed permanently", "article_id", event.ArticleId, "error_class", event.ErrorClass)
+		return nil
+	}
+
+	// Store the summary variant, and make it the article's summary in the default language
+	err := s.articleRepo.ApplyAIResults(ctx, []repository.AIResult{s.aiResult(event)})
 	if err != nil {
 		log.Error("failed to update article with AI data",
 			"article_id", event.ArticleId,
@@ -320,3 +977,237 @@ func (s *ArticleService) HandleArticleProcessed(ctx context.Context, event *arti
 
 	return nil
 }
+
+// HandleArticlesProcessed applies a batch of ArticleProcessedEvents in one transaction. When
+// the batch cannot be applied as a whole, each event is retried on its own so one bad event
+// does not hold back the others; the returned error joins the events that still failed.
+func (s *ArticleService) HandleArticlesProcessed(ctx context.Context, events []*article_eventspb.ArticleProcessedEvent) error {
+	log := logger.FromContext(ctx)
+
+	var errs []error
+	results := make([]repository.AIResult, 0, len(events))
+	for _, event := range events {
+		if event.ArticleId == 0 {
+			errs = append(errs, fmt.Errorf("invalid article ID in processed event: %d", event.ArticleId))
+			continue
+		}
+		results = append(results, s.aiResult(event))
+	}
+	if len(results) == 0 {
+		return errors.Join(errs...)
+	}
+
+	err := s.articleRepo.ApplyAIResults(ctx, results)
+	if err == nil {
+		log.Info("applied AI results", "count", len(results))
+		return errors.Join(errs...)
+	}
+
+	log.Warn("failed to apply AI results as a batch, applying them one by one", "count", len(results), "error", err.Error())
+	for _, event := range events {
+		if event.ArticleId == 0 {
+			continue
+		}
+		if err := s.HandleArticleProcessed(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RegenerateSummary requeues an article whose summary was cut off at the LLM token limit.
+// The AI service processes it again with its expanded limit.
+func (s *ArticleService) RegenerateSummary(ctx context.Context, userID, articleID uint) error {
+	log := logger.FromContext(ctx)
+
+	article, err := s.GetArticleByID(ctx, userID, articleID)
+	if err != nil {
+		return err
+	}
+
+	if !article.SummaryTruncated {
+		return ierr.NewValidationError("article summary is not truncated")
+	}
+
+	if s.eventProducer == nil {
+		return ierr.NewTaskQueueError(fmt.Errorf("no event producer configured to regenerate article %d", articleID))
+	}
+
+	event := &article_eventspb.ArticlePersistedEvent{
+		ArticleId:   uint64(article.ID),
+		FeedId:      uint64(article.FeedID),
+		Title:       article.Title,
+		Content:     article.Content,
+		Url:         article.URL,
+		Description: article.Description,
+		PublishedAt: article.PublishedAt.Unix(),
+		Expand:      true,
+		Languages:   s.readerLanguages(ctx, article.FeedID),
+	}
+	if err := s.eventProducer.PublishArticlePersisted(ctx, event); err != nil {
+		log.Error("failed to queue summary regeneration", "article_id", articleID, "error", err.Error())
+		return ierr.NewTaskQueueError(fmt.Errorf("failed to queue summary regeneration for article %d: %w", articleID, err))
+	}
+	if err := s.articleRepo.MarkProcessing(ctx, article.ID); err != nil {
+		log.Error("failed to mark article as processing", "article_id", articleID, "error", err.Error())
+	}
+
+	log.Info("queued summary regeneration", "user_id", userID, "article_id", articleID)
+	return nil
+}
+
+// DeleteArticle moves an article to the trash. Articles are shared, so it disappears for
+// every subscriber of the feed until restored.
+func (s *ArticleService) DeleteArticle(ctx context.Context, userID, articleID uint) error {
+	log := logger.FromContext(ctx)
+
+	article, err := s.GetArticleByID(ctx, userID, articleID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.articleRepo.Delete(ctx, article.ID); err != nil {
+		log.Error("failed to delete article", "article_id", articleID, "error", err.Error())
+		return ierr.NewDatabaseError(fmt.Errorf("failed to delete article %d: %w", articleID, err))
+	}
+
+	log.Info("moved article to trash", "user_id", userID, "article_id", articleID, "feed_id", article.FeedID)
+	return nil
+}
+
+// RestoreArticle takes an article out of the trash while its grace period lasts
+func (s *ArticleService) RestoreArticle(ctx context.Context, userID, articleID uint) (*models.Article, error) {
+	log := logger.FromContext(ctx)
+
+	article, err := s.articleRepo.GetDeletedByID(ctx, articleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ierr.ErrArticleNotFound
+		}
+		log.Error("failed to load deleted article", "article_id", articleID, "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to get deleted article %d: %w", articleID, err))
+	}
+
+	isSubscribed, err := s.feedRepo.IsUserSubscribed(ctx, userID, article.FeedID)
+	if err != nil {
+		log.Error("failed to verify subscription", "user_id", userID, "feed_id", article.FeedID, "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to verify subscription for user %d and feed %d: %w", userID, article.FeedID, err))
+	}
+	if !isSubscribed {
+		return nil, ierr.ErrNotSubscribed
+	}
+
+	restored, err := s.articleRepo.Restore(ctx, articleID, time.Now().UTC().Add(-s.trashGrace))
+	if err != nil {
+		log.Error("failed to restore article", "article_id", articleID, "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to restore article %d: %w", articleID, err))
+	}
+	if !restored {
+		return nil, ierr.NewValidationError("article grace period has ended and it can no longer be restored")
+	}
+
+	log.Info("restored article from trash", "user_id", userID, "article_id", articleID)
+	return s.GetArticleByID(ctx, userID, articleID)
+}
+
+// NextUnreadArticle returns the unread article that follows opts.AfterID in reading order
+// (newest first), or nil when none is left in the scope. With MarkRead the current article
+// is marked read atomically, saving keyboard-driven readers a round trip.
+func (s *ArticleService) NextUnreadArticle(ctx context.Context, userID uint, opts NextUnreadOptions) (*models.Article, error) {
+	log := logger.FromContext(ctx)
+
+	switch opts.Scope {
+	case NextUnreadScopeFeed, NextUnreadScopeAll:
+	case NextUnreadScopeFolder:
+		if opts.FolderID == 0 {
+			return nil, ierr.NewValidationError("folder scope requires a folder")
+		}
+	default:
+		return nil, ierr.NewValidationError("scope must be one of feed, folder or all")
+	}
+	if opts.MarkRead && opts.AfterID == 0 {
+		return nil, ierr.NewValidationError("mark_read requires the current article")
+	}
+
+	query := repository.NextUnreadQuery{UserID: userID, MarkRead: opts.MarkRead}
+	if opts.AfterID != 0 {
+		after, err := s.GetArticleByID(ctx, userID, opts.AfterID)
+		if err != nil {
+			return nil, err
+		}
+		query.After = after
+	}
+
+	if opts.Scope == NextUnreadScopeFeed {
+		query.FeedID = opts.FeedID
+		if query.FeedID == 0 && query.After != nil {
+			query.FeedID = query.After.FeedID
+		}
+		if query.FeedID == 0 {
+			return nil, ierr.NewValidationError("feed scope requires a feed or the current article")
+		}
+		if query.After == nil || query.After.FeedID != query.FeedID {
+			isSubscribed, err := s.feedRepo.IsUserSubscribed(ctx, userID, query.FeedID)
+			if err != nil {
+				log.Error("failed to verify subscription", "user_id", userID, "feed_id", query.FeedID, "error", err.Error())
+				return nil, ierr.NewDatabaseError(fmt.Errorf("failed to verify subscription for user %d and feed %d: %w", userID, query.FeedID, err))
+			}
+			if !isSubscribed {
+				return nil, ierr.ErrNotSubscribed
+			}
+		}
+	}
+
+	if opts.Scope == NextUnreadScopeFolder {
+		query.FolderID = opts.FolderID
+	}
+
+	next, err := s.articleRepo.NextUnread(ctx, query)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ierr.ErrFolderNotFound
+	}
+	if err != nil {
+		log.Error("failed to find next unread article", "user_id", userID, "after_id", opts.AfterID, "scope", opts.Scope, "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to find next unread article for user %d: %w", userID, err))
+	}
+	return next, nil
+}
+
+// SearchArticles returns a page of the articles of the user's subscribed feeds whose title,
+// summary, description or content match the query, best match first, and the number of
+// matches
+func (s *ArticleService) SearchArticles(ctx context.Context, userID uint, opts SearchOptions) ([]*models.Article, int64, error) {
+	log := logger.FromContext(ctx)
+
+	query := strings.TrimSpace(opts.Query)
+	if query == "" {
+		return nil, 0, ierr.NewValidationError("search query is required")
+	}
+	if len(query) > MaxSearchQueryLen {
+		return nil, 0, ierr.NewValidationError(fmt.Sprintf("search query must be at most %d characters", MaxSearchQueryLen))
+	}
+	if opts.Offset < 0 {
+		return nil, 0, ierr.NewValidationError("offset must not be negative")
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	limit = min(limit, MaxSearchLimit)
+
+	articles, total, err := s.articleRepo.Search(ctx, repository.ArticleSearchQuery{
+		UserID: userID,
+		Query:  query,
+		Offset: opts.Offset,
+		Limit:  limit,
+	})
+	if err != nil {
+		log.Error("failed to search articles", "user_id", userID, "error", err.Error())
+		return nil, 0, ierr.NewDatabaseError(fmt.Errorf("failed to search articles for user %d: %w", userID, err))
+	}
+	if err := s.articleRepo.ApplyReadState(ctx, userID, articles...); err != nil {
+		log.Error("failed to load read state", "user_id", userID, "error", err.Error())
+		return nil, 0, ierr.NewDatabaseError(fmt.Errorf("failed to load read state for user %d: %w", userID, err))
+	}
+	return articles, total, nil
+}
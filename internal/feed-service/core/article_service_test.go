@@ -15,8 +15,11 @@ import (
 
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
 	"github.com/Fancu1/phoenix-rss/pkg/ierr"
 	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/secrets"
+	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
 )
 
 func setupArticleService(t *testing.T) (*ArticleService, *repository.FeedRepository, *repository.ArticleRepository, *gorm.DB) {
@@ -24,7 +27,9 @@ func setupArticleService(t *testing.T) (*ArticleService, *repository.FeedReposit
 	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 	require.NoError(t, err)
 
-	require.NoError(t, db.AutoMigrate(&models.Feed{}, &models.Article{}, &models.Subscription{}))
+	require.NoError(t, db.AutoMigrate(&models.Feed{}, &models.Article{}, &models.Subscription{},
+		&models.ArticleStateEvent{}, &models.UserArticleState{}, &models.ArticleSummary{}, &models.Tag{}, &models.ArticleTag{},
+		&models.Folder{}))
 
 	feedRepo := repository.NewFeedRepository(db)
 	articleRepo := repository.NewArticleRepository(db)
@@ -124,9 +129,876 @@ func TestFetchAndSaveArticles_FeedTooLarge(t *testing.T) {
 
 	_, err := service.FetchAndSaveArticles(context.Background(), feed.ID)
 	require.Error(t, err)
-	require.ErrorIs(t, err, errFeedBodyTooLarge)
+	require.ErrorIs(t, err, httpclient.ErrBodyTooLarge)
 
 	var count int64
 	require.NoError(t, db.Model(&models.Article{}).Where("feed_id = ?", feed.ID).Count(&count).Error)
 	require.Zero(t, count)
 }
+
+func TestFetchAndSaveArticles_GoneFeed(t *testing.T) {
+	service, _, _, db := setupArticleService(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	feed := &models.Feed{Title: "Gone Feed", URL: server.URL, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+
+	_, err := service.FetchAndSaveArticles(context.Background(), feed.ID)
+	require.Error(t, err)
+	var appErr *ierr.AppError
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, ierr.ErrFeedFetchFailed.Code, appErr.Code)
+	require.True(t, IsFeedGoneError(err))
+
+	require.False(t, IsFeedGoneError(fmt.Errorf("timeout")))
+}
+
+func TestFetchAndSaveArticles_ClassifiesUpstreamFailures(t *testing.T) {
+	service, _, _, db := setupArticleService(t)
+
+	// a zero status never answers, so the fetch runs into its deadline
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   *ierr.AppError
+	}{
+		{"forbidden", http.StatusForbidden, "", ierr.ErrUpstreamForbidden},
+		{"rate limited", http.StatusTooManyRequests, "", ierr.ErrTooManyRequests},
+		{"gateway timeout", http.StatusGatewayTimeout, "", ierr.ErrUpstreamTimeout},
+		{"server error", http.StatusInternalServerError, "", ierr.ErrFeedFetchFailed},
+		{"html page", http.StatusOK, "<html><body>hi</body></html>", ierr.ErrUnsupportedFormat},
+		{"broken xml", http.StatusOK, `<rss version="2.0"><channel><title>a</ti`, ierr.ErrUnsupportedFormat},
+		{"slow server", 0, "", ierr.ErrUpstreamTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status == 0 {
+					<-r.Context().Done()
+					return
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			feed := &models.Feed{Title: tt.name, URL: server.URL, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+			require.NoError(t, db.Create(feed).Error)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			_, err := service.FetchAndSaveArticles(ctx, feed.ID)
+			var appErr *ierr.AppError
+			require.ErrorAs(t, err, &appErr)
+			require.Equal(t, tt.want.Code, appErr.Code)
+			require.True(t, ierr.IsUpstreamError(err))
+		})
+	}
+}
+
+func TestFetchAndSaveArticles_StoresSnapshotOfUnparsableFeed(t *testing.T) {
+	service, _, _, db := setupArticleService(t)
+	require.NoError(t, db.AutoMigrate(&models.FeedSnapshot{}))
+	snapshots := repository.NewSnapshotRepository(db)
+	service.SetSnapshots(snapshots, 1, 16)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>Not a feed at all</body></html>")
+	}))
+	defer server.Close()
+
+	feed := &models.Feed{Title: "Weird Feed", URL: server.URL, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+
+	_, err := service.FetchAndSaveArticles(context.Background(), feed.ID)
+	require.Error(t, err)
+	_, err = service.FetchAndSaveArticles(context.Background(), feed.ID)
+	require.Error(t, err)
+
+	stored, err := snapshots.ListByFeed(context.Background(), feed.ID)
+	require.NoError(t, err)
+	require.Len(t, stored, 1, "older snapshots are pruned")
+	require.Equal(t, http.StatusOK, stored[0].StatusCode)
+	require.Equal(t, "text/html", stored[0].ContentType)
+	require.NotNil(t, stored[0].ParseError)
+
+	snapshot, err := snapshots.Get(context.Background(), feed.ID, stored[0].ID)
+	require.NoError(t, err)
+	raw, err := snapshot.Raw()
+	require.NoError(t, err)
+	require.Equal(t, "<html><body>Not ", string(raw))
+	require.True(t, snapshot.Truncated)
+}
+
+func TestFetchAndSaveArticles_SendsSubscriptionHeaders(t *testing.T) {
+	service, feedRepo, _, db := setupArticleService(t)
+
+	var gotKey, gotUA string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey, gotUA = r.Header.Get("X-Api-Key"), r.UserAgent()
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>Private</title></channel></rss>`))
+	}))
+	defer server.Close()
+
+	cipher, err := secrets.NewCipher("test-key")
+	require.NoError(t, err)
+	service.SetSecretDecrypter(cipher)
+
+	feed := &models.Feed{Title: "Private", URL: server.URL, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feed.ID, CreatedAt: time.Now().Add(-time.Hour)}).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 2, FeedID: feed.ID}).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 3, FeedID: feed.ID, CreatedAt: time.Now().Add(time.Hour)}).Error)
+
+	for userID, key := range map[uint]string{2: "second", 3: "third"} {
+		update := models.SubscriptionUpdate{FetchHeaders: map[string]string{"x-api-key": key}}
+		require.NoError(t, update.SealFetchHeaders(cipher))
+		require.NoError(t, feedRepo.UpdateSubscription(context.Background(), userID, feed.ID, update))
+	}
+
+	_, err = service.FetchAndSaveArticles(context.Background(), feed.ID)
+	require.NoError(t, err)
+	require.Equal(t, "second", gotKey, "headers of the longest-standing subscriber that set some")
+	require.Equal(t, DefaultUserAgent, gotUA)
+}
+
+func TestFetchAndSaveArticles_ConditionalGet(t *testing.T) {
+	service, feedRepo, _, db := setupArticleService(t)
+
+	var requests int
+	var gotETag, gotSince string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		gotETag, gotSince = r.Header.Get("If-None-Match"), r.Header.Get("If-Modified-Since")
+		if gotETag == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2026 07:28:00 GMT")
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>Cached</title>` +
+			`<item><title>One</title><link>https://example.com/one</link></item></channel></rss>`))
+	}))
+	defer server.Close()
+
+	feed := &models.Feed{Title: "Cached", URL: server.URL, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+
+	articles, err := service.FetchAndSaveArticles(context.Background(), feed.ID)
+	require.NoError(t, err)
+	require.Len(t, articles, 1)
+	require.Empty(t, gotETag, "the first fetch is unconditional")
+
+	stored, err := feedRepo.GetByID(context.Background(), feed.ID)
+	require.NoError(t, err)
+	require.Equal(t, `"v1"`, *stored.HTTPETag)
+	require.Equal(t, "2026-10-21T07:28:00Z", *stored.HTTPLastModified)
+
+	articles, err = service.FetchAndSaveArticles(context.Background(), feed.ID)
+	require.NoError(t, err)
+	require.Empty(t, articles)
+	require.Equal(t, 2, requests)
+	require.Equal(t, `"v1"`, gotETag)
+	require.Equal(t, "Wed, 21 Oct 2026 07:28:00 GMT", gotSince)
+}
+
+func TestFetchAndSaveArticles_RefreshesFeedMetadata(t *testing.T) {
+	service, feedRepo, _, db := setupArticleService(t)
+
+	title := "  The Go\n Blog "
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>%s</title>`+
+			`<link>/blog</link><description>&lt;p&gt;News &amp;amp; notes&lt;/p&gt;</description></channel></rss>`, title)
+	}))
+	defer server.Close()
+
+	// subscribing titles a feed with its URL until the first fetch
+	feed := &models.Feed{Title: server.URL, URL: server.URL, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+
+	_, err := service.FetchAndSaveArticles(context.Background(), feed.ID)
+	require.NoError(t, err)
+	stored, err := feedRepo.GetByID(context.Background(), feed.ID)
+	require.NoError(t, err)
+	require.Equal(t, "The Go Blog", stored.Title)
+	require.Equal(t, "News & notes", stored.Description)
+	require.Equal(t, server.URL+"/blog", stored.SiteURL)
+
+	title = "Go Blog"
+	_, err = service.FetchAndSaveArticles(context.Background(), feed.ID)
+	require.NoError(t, err)
+	stored, err = feedRepo.GetByID(context.Background(), feed.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Go Blog", stored.Title, "later fetches pick up a renamed feed")
+
+	// a feed dropping its title keeps the one it has
+	title = ""
+	_, err = service.FetchAndSaveArticles(context.Background(), feed.ID)
+	require.NoError(t, err)
+	stored, err = feedRepo.GetByID(context.Background(), feed.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Go Blog", stored.Title)
+}
+
+type recordingArticleProducer struct {
+	events []*article_eventspb.ArticlePersistedEvent
+}
+
+func (p *recordingArticleProducer) PublishArticlePersisted(ctx context.Context, event *article_eventspb.ArticlePersistedEvent) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *recordingArticleProducer) Close() error { return nil }
+
+func TestRegenerateSummary_QueuesTruncatedArticle(t *testing.T) {
+	service, _, articleRepo, db := setupArticleService(t)
+	producer := &recordingArticleProducer{}
+	service.eventProducer = producer
+	ctx := context.Background()
+
+	feed := &models.Feed{Title: "Feed", URL: "https://example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feed.ID}).Error)
+
+	article := &models.Article{FeedID: feed.ID, Title: "Article", URL: "https://example.com/article", PublishedAt: time.Now()}
+	_, err := articleRepo.Create(ctx, article)
+	require.NoError(t, err)
+
+	require.NoError(t, service.HandleArticleProcessed(ctx, &article_eventspb.ArticleProcessedEvent{
+		ArticleId:        uint64(article.ID),
+		Summary:          "The first half of",
+		ProcessingModel:  "test-model",
+		SummaryTruncated: true,
+	}))
+
+	stored, err := articleRepo.GetByID(ctx, article.ID)
+	require.NoError(t, err)
+	require.True(t, stored.SummaryTruncated)
+
+	require.NoError(t, service.RegenerateSummary(ctx, 1, article.ID))
+	require.Len(t, producer.events, 1)
+	require.True(t, producer.events[0].Expand)
+	require.Equal(t, uint64(article.ID), producer.events[0].ArticleId)
+
+	// a complete summary clears the flag and cannot be regenerated again
+	require.NoError(t, service.HandleArticleProcessed(ctx, &article_eventspb.ArticleProcessedEvent{
+		ArticleId:       uint64(article.ID),
+		Summary:         "The whole summary.",
+		ProcessingModel: "test-model",
+	}))
+	err = service.RegenerateSummary(ctx, 1, article.ID)
+	require.True(t, ierr.IsValidationError(err))
+
+	require.ErrorIs(t, service.RegenerateSummary(ctx, 2, article.ID), ierr.ErrNotSubscribed)
+}
+
+func TestFetchAndSaveArticles_CarriesReaderLanguages(t *testing.T) {
+	service, _, _, db := setupArticleService(t)
+	producer := &recordingArticleProducer{}
+	service.eventProducer = producer
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>Languages</title>` +
+			`<item><title>Item</title><link>https://languages.example.com/item</link></item></channel></rss>`))
+	}))
+	defer server.Close()
+
+	feed := &models.Feed{Title: "Languages", URL: server.URL, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+
+	// the preferences belong to the user-service, which shares the database
+	require.NoError(t, db.Exec(`CREATE TABLE user_preferences (user_id INTEGER PRIMARY KEY, summary_language TEXT NOT NULL DEFAULT '')`).Error)
+	for userID, language := range map[uint]string{1: "en", 2: "de", 3: "en", 4: "", 5: "fr"} {
+		require.NoError(t, db.Exec(`INSERT INTO user_preferences (user_id, summary_language) VALUES (?, ?)`, userID, language).Error)
+		if userID != 5 {
+			require.NoError(t, db.Create(&models.Subscription{UserID: userID, FeedID: feed.ID}).Error)
+		}
+	}
+
+	_, err := service.FetchAndSaveArticles(ctx, feed.ID)
+	require.NoError(t, err)
+	require.Len(t, producer.events, 1)
+	require.Equal(t, []string{"en", "de"}, producer.events[0].Languages, "the subscribers' languages, most chosen first")
+}
+
+func TestHandleArticleProcessed_TracksProcessingStatus(t *testing.T) {
+	service, _, articleRepo, db := setupArticleService(t)
+	producer := &recordingArticleProducer{}
+	service.eventProducer = producer
+	ctx := context.Background()
+
+	feed := &models.Feed{Title: "Feed", URL: "https://example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feed.ID}).Error)
+
+	article := &models.Article{FeedID: feed.ID, Title: "Article", URL: "https://example.com/article", PublishedAt: time.Now()}
+	_, err := articleRepo.Create(ctx, article)
+	require.NoError(t, err)
+
+	stored, err := articleRepo.GetByID(ctx, article.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ProcessingPending, stored.ProcessingStatus)
+
+	require.NoError(t, service.HandleArticleProcessed(ctx, &article_eventspb.ArticleProcessedEvent{
+		ArticleId:  uint64(article.ID),
+		Failed:     true,
+		ErrorClass: "rate_limited",
+	}))
+	stored, err = articleRepo.GetByID(ctx, article.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ProcessingFailed, stored.ProcessingStatus)
+	require.NotNil(t, stored.ProcessingError)
+	require.Equal(t, "rate_limited", *stored.ProcessingError)
+	require.Nil(t, stored.Summary)
+
+	require.NoError(t, service.HandleArticleProcessed(ctx, &article_eventspb.ArticleProcessedEvent{
+		ArticleId:        uint64(article.ID),
+		Summary:          "The first half of",
+		ProcessingModel:  "test-model",
+		SummaryTruncated: true,
+	}))
+	stored, err = articleRepo.GetByID(ctx, article.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ProcessingSucceeded, stored.ProcessingStatus)
+	require.Nil(t, stored.ProcessingError)
+
+	require.NoError(t, service.RegenerateSummary(ctx, 1, article.ID))
+	stored, err = articleRepo.GetByID(ctx, article.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.ProcessingInProgress, stored.ProcessingStatus)
+}
+
+func TestDeleteAndRestoreArticle(t *testing.T) {
+	service, _, articleRepo, db := setupArticleService(t)
+	ctx := context.Background()
+
+	feed := &models.Feed{Title: "Feed", URL: "https://example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feed.ID}).Error)
+
+	article := &models.Article{FeedID: feed.ID, Title: "Article", URL: "https://example.com/article", PublishedAt: time.Now()}
+	_, err := articleRepo.Create(ctx, article)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, service.DeleteArticle(ctx, 2, article.ID), ierr.ErrNotSubscribed)
+	require.NoError(t, service.DeleteArticle(ctx, 1, article.ID))
+
+	_, err = service.GetArticleByID(ctx, 1, article.ID)
+	require.ErrorIs(t, err, ierr.ErrArticleNotFound)
+	articles, err := service.ListArticlesByFeedID(ctx, 1, feed.ID)
+	require.NoError(t, err)
+	require.Empty(t, articles)
+
+	_, err = service.RestoreArticle(ctx, 2, article.ID)
+	require.ErrorIs(t, err, ierr.ErrNotSubscribed)
+
+	restored, err := service.RestoreArticle(ctx, 1, article.ID)
+	require.NoError(t, err)
+	require.Equal(t, article.ID, restored.ID)
+
+	_, err = service.RestoreArticle(ctx, 1, article.ID)
+	require.ErrorIs(t, err, ierr.ErrArticleNotFound, "article is no longer in the trash")
+}
+
+func TestRestoreArticle_AfterGracePeriod(t *testing.T) {
+	service, _, articleRepo, db := setupArticleService(t)
+	ctx := context.Background()
+	service.SetTrashGracePeriod(time.Hour)
+
+	feed := &models.Feed{Title: "Feed", URL: "https://example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feed.ID}).Error)
+
+	article := &models.Article{FeedID: feed.ID, Title: "Article", URL: "https://example.com/article", PublishedAt: time.Now()}
+	_, err := articleRepo.Create(ctx, article)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&models.Article{}).Where("id = ?", article.ID).Update("deleted_at", time.Now().UTC().Add(-2*time.Hour)).Error)
+
+	_, err = service.RestoreArticle(ctx, 1, article.ID)
+	require.True(t, ierr.IsValidationError(err))
+}
+
+func TestNextUnreadArticle(t *testing.T) {
+	service, _, articleRepo, db := setupArticleService(t)
+	ctx := context.Background()
+
+	feedA := &models.Feed{Title: "A", URL: "https://a.example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	feedB := &models.Feed{Title: "B", URL: "https://b.example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feedA).Error)
+	require.NoError(t, db.Create(feedB).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feedA.ID}).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feedB.ID}).Error)
+
+	now := time.Now().UTC()
+	newest := &models.Article{FeedID: feedA.ID, Title: "A newest", URL: "https://a.example.com/3", PublishedAt: now}
+	read := &models.Article{FeedID: feedA.ID, Title: "A read", URL: "https://a.example.com/2", PublishedAt: now.Add(-time.Hour)}
+	otherFeed := &models.Article{FeedID: feedB.ID, Title: "B", URL: "https://b.example.com/1", PublishedAt: now.Add(-2 * time.Hour)}
+	oldest := &models.Article{FeedID: feedA.ID, Title: "A oldest", URL: "https://a.example.com/1", PublishedAt: now.Add(-3 * time.Hour)}
+	for _, article := range []*models.Article{newest, read, otherFeed, oldest} {
+		_, err := articleRepo.Create(ctx, article)
+		require.NoError(t, err)
+	}
+	require.NoError(t, articleRepo.SetRead(ctx, 1, read.ID, true))
+
+	next, err := service.NextUnreadArticle(ctx, 1, NextUnreadOptions{Scope: NextUnreadScopeAll})
+	require.NoError(t, err)
+	require.Equal(t, newest.ID, next.ID)
+
+	next, err = service.NextUnreadArticle(ctx, 1, NextUnreadOptions{AfterID: newest.ID, Scope: NextUnreadScopeFeed, MarkRead: true})
+	require.NoError(t, err)
+	require.Equal(t, oldest.ID, next.ID, "skips read articles and other feeds")
+
+	marked, err := service.GetArticleByID(ctx, 1, newest.ID)
+	require.NoError(t, err)
+	require.True(t, marked.Read)
+
+	// read state is the user's own
+	require.NoError(t, db.Create(&models.Subscription{UserID: 3, FeedID: feedA.ID}).Error)
+	next, err = service.NextUnreadArticle(ctx, 3, NextUnreadOptions{Scope: NextUnreadScopeAll})
+	require.NoError(t, err)
+	require.Equal(t, newest.ID, next.ID)
+
+	next, err = service.NextUnreadArticle(ctx, 1, NextUnreadOptions{AfterID: newest.ID, Scope: NextUnreadScopeAll})
+	require.NoError(t, err)
+	require.Equal(t, otherFeed.ID, next.ID)
+
+	next, err = service.NextUnreadArticle(ctx, 1, NextUnreadOptions{AfterID: oldest.ID, Scope: NextUnreadScopeAll, MarkRead: true})
+	require.NoError(t, err)
+	require.Nil(t, next)
+
+	_, err = service.NextUnreadArticle(ctx, 2, NextUnreadOptions{Scope: NextUnreadScopeFeed, FeedID: feedA.ID})
+	require.ErrorIs(t, err, ierr.ErrNotSubscribed)
+	_, err = service.NextUnreadArticle(ctx, 1, NextUnreadOptions{Scope: NextUnreadScopeFolder})
+	require.True(t, ierr.IsValidationError(err))
+	_, err = service.NextUnreadArticle(ctx, 1, NextUnreadOptions{Scope: NextUnreadScopeAll, MarkRead: true})
+	require.True(t, ierr.IsValidationError(err))
+}
+
+func TestNextUnreadArticle_FolderScope(t *testing.T) {
+	service, _, articleRepo, db := setupArticleService(t)
+	ctx := context.Background()
+
+	news := &models.Folder{UserID: 1, Name: "News"}
+	require.NoError(t, db.Create(news).Error)
+	local := &models.Folder{UserID: 1, ParentID: &news.ID, Name: "Local"}
+	other := &models.Folder{UserID: 1, Name: "Other"}
+	strangers := &models.Folder{UserID: 2, Name: "News"}
+	require.NoError(t, db.Create([]*models.Folder{local, other, strangers}).Error)
+
+	// the unfiled feed has the newest article, which only the folder scope leaves out
+	var articles []*models.Article
+	now := time.Now().UTC()
+	for i, folder := range []*models.Folder{news, local, other, nil} {
+		feed := &models.Feed{Title: fmt.Sprintf("Feed %d", i), URL: fmt.Sprintf("https://%d.example.com", i), CreatedAt: now, UpdatedAt: now}
+		require.NoError(t, db.Create(feed).Error)
+		subscription := &models.Subscription{UserID: 1, FeedID: feed.ID}
+		if folder != nil {
+			subscription.FolderID = &folder.ID
+		}
+		require.NoError(t, db.Create(subscription).Error)
+		published := now.Add(-time.Duration(i) * time.Hour)
+		if folder == nil {
+			published = now.Add(time.Hour)
+		}
+		article := &models.Article{FeedID: feed.ID, Title: feed.Title, URL: feed.URL + "/1", PublishedAt: published}
+		_, err := articleRepo.Create(ctx, article)
+		require.NoError(t, err)
+		articles = append(articles, article)
+	}
+
+	next, err := service.NextUnreadArticle(ctx, 1, NextUnreadOptions{Scope: NextUnreadScopeFolder, FolderID: news.ID})
+	require.NoError(t, err)
+	require.Equal(t, articles[0].ID, next.ID)
+
+	next, err = service.NextUnreadArticle(ctx, 1, NextUnreadOptions{AfterID: articles[0].ID, Scope: NextUnreadScopeFolder, FolderID: news.ID, MarkRead: true})
+	require.NoError(t, err)
+	require.Equal(t, articles[1].ID, next.ID, "the subfolder's feeds are included")
+
+	next, err = service.NextUnreadArticle(ctx, 1, NextUnreadOptions{AfterID: articles[1].ID, Scope: NextUnreadScopeFolder, FolderID: news.ID})
+	require.NoError(t, err)
+	require.Nil(t, next, "other folders and unfiled feeds are left out")
+
+	next, err = service.NextUnreadArticle(ctx, 1, NextUnreadOptions{Scope: NextUnreadScopeFolder, FolderID: local.ID})
+	require.NoError(t, err)
+	require.Equal(t, articles[1].ID, next.ID, "a subfolder does not reach up to its parent")
+
+	_, err = service.NextUnreadArticle(ctx, 1, NextUnreadOptions{Scope: NextUnreadScopeFolder, FolderID: strangers.ID})
+	require.ErrorIs(t, err, ierr.ErrFolderNotFound)
+	_, err = service.NextUnreadArticle(ctx, 1, NextUnreadOptions{AfterID: articles[1].ID, Scope: NextUnreadScopeFolder, FolderID: strangers.ID, MarkRead: true})
+	require.ErrorIs(t, err, ierr.ErrFolderNotFound)
+	unread, err := service.GetArticleByID(ctx, 1, articles[1].ID)
+	require.NoError(t, err)
+	require.False(t, unread.Read, "nothing is marked read for a missing folder")
+}
+
+func TestSearchArticles(t *testing.T) {
+	service, _, articleRepo, db := setupArticleService(t)
+	ctx := context.Background()
+
+	subscribed := &models.Feed{Title: "A", URL: "https://a.example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	other := &models.Feed{Title: "B", URL: "https://b.example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(subscribed).Error)
+	require.NoError(t, db.Create(other).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: subscribed.ID}).Error)
+
+	now := time.Now().UTC()
+	summary := "Tuning Kafka consumers for throughput"
+	byTitle := &models.Article{FeedID: subscribed.ID, Title: "Kafka in production", URL: "https://a.example.com/1", PublishedAt: now}
+	bySummary := &models.Article{FeedID: subscribed.ID, Title: "Throughput notes", URL: "https://a.example.com/2", PublishedAt: now.Add(-time.Hour), Summary: &summary}
+	byContent := &models.Article{FeedID: subscribed.ID, Title: "Queues", URL: "https://a.example.com/3", Content: "<p>Why we moved to kafka</p>", PublishedAt: now.Add(-2 * time.Hour)}
+	unrelated := &models.Article{FeedID: subscribed.ID, Title: "Postgres", URL: "https://a.example.com/4", PublishedAt: now.Add(-3 * time.Hour)}
+	notSubscribed := &models.Article{FeedID: other.ID, Title: "Kafka elsewhere", URL: "https://b.example.com/1", PublishedAt: now}
+	for _, article := range []*models.Article{byTitle, bySummary, byContent, unrelated, notSubscribed} {
+		_, err := articleRepo.Create(ctx, article)
+		require.NoError(t, err)
+	}
+
+	articles, total, err := service.SearchArticles(ctx, 1, SearchOptions{Query: "  KAFKA "})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+	require.Len(t, articles, 3)
+	require.Equal(t, []uint{byTitle.ID, bySummary.ID, byContent.ID}, []uint{articles[0].ID, articles[1].ID, articles[2].ID})
+
+	articles, total, err = service.SearchArticles(ctx, 1, SearchOptions{Query: "kafka", Offset: 1, Limit: 1})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total, "the total ignores paging")
+	require.Len(t, articles, 1)
+	require.Equal(t, bySummary.ID, articles[0].ID)
+
+	require.NoError(t, articleRepo.Delete(ctx, byTitle.ID))
+	_, total, err = service.SearchArticles(ctx, 1, SearchOptions{Query: "kafka"})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total, "trashed articles are left out")
+
+	_, _, err = service.SearchArticles(ctx, 1, SearchOptions{Query: "   "})
+	require.True(t, ierr.IsValidationError(err))
+	_, _, err = service.SearchArticles(ctx, 1, SearchOptions{Query: strings.Repeat("a", MaxSearchQueryLen+1)})
+	require.True(t, ierr.IsValidationError(err))
+}
+
+func TestHandleArticlesProcessed_AppliesBatch(t *testing.T) {
+	service, _, articleRepo, db := setupArticleService(t)
+	ctx := context.Background()
+
+	feed := &models.Feed{Title: "Feed", URL: "https://example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+
+	var ids []uint
+	for i := range 3 {
+		article := &models.Article{FeedID: feed.ID, Title: "Article", URL: fmt.Sprintf("https://example.com/article/%d", i), PublishedAt: time.Now()}
+		_, err := articleRepo.Create(ctx, article)
+		require.NoError(t, err)
+		ids = append(ids, article.ID)
+	}
+
+	err := service.HandleArticlesProcessed(ctx, []*article_eventspb.ArticleProcessedEvent{
+		{ArticleId: uint64(ids[0]), Summary: "one", ProcessingModel: "test-model", Topics: []string{"go", "databases"}},
+		{ArticleId: uint64(ids[0]), Summary: "one in English", ProcessingModel: "test-model", Language: "en", Topics: []string{"english"}},
+		{ArticleId: 0, Summary: "no article"},
+		{ArticleId: uint64(ids[1]), Failed: true, ErrorClass: "timeout"},
+		{ArticleId: uint64(ids[2]), Summary: "three", ProcessingModel: "test-model"},
+	})
+	require.Error(t, err, "the invalid event is reported")
+
+	for id, want := range map[uint]models.ProcessingStatus{ids[0]: models.ProcessingSucceeded, ids[1]: models.ProcessingFailed, ids[2]: models.ProcessingSucceeded} {
+		stored, err := articleRepo.GetByID(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, want, stored.ProcessingStatus, "article %d", id)
+	}
+
+	// the English variant is kept beside the default summary without replacing it
+	first, err := articleRepo.GetByID(ctx, ids[0])
+	require.NoError(t, err)
+	require.Equal(t, "one", *first.Summary)
+	require.Equal(t, models.Topics{"go", "databases"}, first.Topics, "topics come with the default summary")
+	var languages []string
+	require.NoError(t, db.Model(&models.ArticleSummary{}).Where("article_id = ?", ids[0]).Order("language").Pluck("language", &languages).Error)
+	require.Equal(t, []string{"en", DefaultSummaryLanguage}, languages)
+}
+
+func TestSetArticleReadAndMarkFeedRead(t *testing.T) {
+	service, _, articleRepo, db := setupArticleService(t)
+	ctx := context.Background()
+
+	feed := &models.Feed{Title: "A", URL: "https://a.example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feed.ID}).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 2, FeedID: feed.ID}).Error)
+
+	now := time.Now().UTC()
+	articles := []*models.Article{
+		{FeedID: feed.ID, URL: "https://a.example.com/1", PublishedAt: now.Add(-2 * time.Hour)},
+		{FeedID: feed.ID, URL: "https://a.example.com/2", PublishedAt: now.Add(-time.Hour)},
+		{FeedID: feed.ID, URL: "https://a.example.com/3", PublishedAt: now},
+	}
+	for _, article := range articles {
+		_, err := articleRepo.Create(ctx, article)
+		require.NoError(t, err)
+	}
+
+	article, err := service.SetArticleRead(ctx, 1, articles[0].ID, true)
+	require.NoError(t, err)
+	require.True(t, article.Read)
+	other, err := service.GetArticleByID(ctx, 2, articles[0].ID)
+	require.NoError(t, err)
+	require.False(t, other.Read, "another subscriber's state is untouched")
+
+	article, err = service.SetArticleRead(ctx, 1, articles[0].ID, false)
+	require.NoError(t, err)
+	require.False(t, article.Read)
+
+	marked, err := service.MarkFeedRead(ctx, 1, feed.ID, now.Add(-30*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 2, marked, "articles published after before stay unread")
+	listed, err := service.ListArticlesByFeedID(ctx, 1, feed.ID)
+	require.NoError(t, err)
+	read := map[uint]bool{}
+	for _, a := range listed {
+		read[a.ID] = a.Read
+	}
+	require.Equal(t, map[uint]bool{articles[0].ID: true, articles[1].ID: true, articles[2].ID: false}, read)
+
+	marked, err = service.MarkFeedRead(ctx, 1, feed.ID, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, 1, marked)
+
+	_, err = service.MarkFeedRead(ctx, 3, feed.ID, time.Time{})
+	require.ErrorIs(t, err, ierr.ErrNotSubscribed)
+	_, err = service.SetArticleRead(ctx, 3, articles[0].ID, true)
+	require.ErrorIs(t, err, ierr.ErrNotSubscribed)
+}
+
+func TestSetArticleStarredAndListStarred(t *testing.T) {
+	service, _, articleRepo, db := setupArticleService(t)
+	ctx := context.Background()
+
+	feeds := []*models.Feed{
+		{Title: "A", URL: "https://a.example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{Title: "B", URL: "https://b.example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}
+	var articles []*models.Article
+	for _, feed := range feeds {
+		require.NoError(t, db.Create(feed).Error)
+		require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feed.ID}).Error)
+		for i := 0; i < 2; i++ {
+			article, err := articleRepo.Create(ctx, &models.Article{FeedID: feed.ID, URL: fmt.Sprintf("%s/%d", feed.URL, i), PublishedAt: time.Now()})
+			require.NoError(t, err)
+			articles = append(articles, article)
+		}
+	}
+	require.NoError(t, db.Create(&models.Subscription{UserID: 2, FeedID: feeds[0].ID}).Error)
+
+	for _, article := range []*models.Article{articles[0], articles[3], articles[1]} {
+		starred, err := service.SetArticleStarred(ctx, 1, article.ID, true)
+		require.NoError(t, err)
+		require.True(t, starred.Starred)
+	}
+	unstarred, err := service.SetArticleStarred(ctx, 1, articles[1].ID, false)
+	require.NoError(t, err)
+	require.False(t, unstarred.Starred)
+
+	listed, total, err := service.ListStarredArticles(ctx, 1, 0, 0)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Len(t, listed, 2)
+	require.Equal(t, articles[3].ID, listed[0].ID, "most recently starred first, across feeds")
+	require.Equal(t, articles[0].ID, listed[1].ID)
+	require.True(t, listed[0].Starred)
+
+	page, total, err := service.ListStarredArticles(ctx, 1, 1, 1)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, total)
+	require.Len(t, page, 1)
+	require.Equal(t, articles[0].ID, page[0].ID)
+
+	other, total, err := service.ListStarredArticles(ctx, 2, 0, 0)
+	require.NoError(t, err)
+	require.Zero(t, total, "another subscriber's stars are separate")
+	require.Empty(t, other)
+	article, err := service.GetArticleByID(ctx, 2, articles[0].ID)
+	require.NoError(t, err)
+	require.False(t, article.Starred)
+
+	_, err = service.SetArticleStarred(ctx, 2, articles[3].ID, true)
+	require.ErrorIs(t, err, ierr.ErrNotSubscribed)
+	_, _, err = service.ListStarredArticles(ctx, 1, -1, 0)
+	require.Error(t, err)
+}
+
+func TestListUserArticles(t *testing.T) {
+	service, _, articleRepo, db := setupArticleService(t)
+	ctx := context.Background()
+
+	feeds := []*models.Feed{
+		{Title: "A", URL: "https://a.example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{Title: "B", URL: "https://b.example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{Title: "Unsubscribed", URL: "https://c.example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}
+	for _, feed := range feeds {
+		require.NoError(t, db.Create(feed).Error)
+	}
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feeds[0].ID}).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feeds[1].ID}).Error)
+
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	create := func(feed *models.Feed, name string, publishedAt time.Time) *models.Article {
+		article, err := articleRepo.Create(ctx, &models.Article{FeedID: feed.ID, Title: name, URL: feed.URL + "/" + name, PublishedAt: publishedAt})
+		require.NoError(t, err)
+		return article
+	}
+	oldest := create(feeds[0], "oldest", base)
+	tieFirst := create(feeds[1], "tie-1", base.Add(time.Hour))
+	tieSecond := create(feeds[0], "tie-2", base.Add(time.Hour))
+	newest := create(feeds[1], "newest", base.Add(2*time.Hour))
+	create(feeds[2], "unsubscribed", base.Add(3*time.Hour))
+	_, err := service.SetArticleRead(ctx, 1, newest.ID, true)
+	require.NoError(t, err)
+
+	first, next, total, err := service.ListUserArticles(ctx, 1, TimelineFilter{}, 2, "")
+	require.NoError(t, err)
+	require.EqualValues(t, 4, total)
+	require.Equal(t, []uint{newest.ID, tieSecond.ID}, []uint{first[0].ID, first[1].ID}, "newest first across feeds, ties by id")
+	require.True(t, first[0].Read)
+	require.NotEmpty(t, next)
+
+	create(feeds[0], "published meanwhile", base.Add(4*time.Hour))
+	second, next, _, err := service.ListUserArticles(ctx, 1, TimelineFilter{}, 2, next)
+	require.NoError(t, err)
+	require.Equal(t, []uint{tieFirst.ID, oldest.ID}, []uint{second[0].ID, second[1].ID}, "new articles do not shift the pages")
+	require.Empty(t, next)
+
+	_, _, _, err = service.ListUserArticles(ctx, 1, TimelineFilter{}, 0, "not a token")
+	require.Error(t, err)
+
+	none, next, total, err := service.ListUserArticles(ctx, 2, TimelineFilter{}, 0, "")
+	require.NoError(t, err)
+	require.Empty(t, none)
+	require.Empty(t, next)
+	require.Zero(t, total)
+}
+
+func TestListUserArticles_Filters(t *testing.T) {
+	service, _, articleRepo, db := setupArticleService(t)
+	ctx := context.Background()
+
+	folderID := uint(7)
+	filed := &models.Feed{Title: "Filed", URL: "https://filed.example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	unfiled := &models.Feed{Title: "Unfiled", URL: "https://unfiled.example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(filed).Error)
+	require.NoError(t, db.Create(unfiled).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: filed.ID, FolderID: &folderID}).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: unfiled.ID}).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 2, FeedID: unfiled.ID}).Error)
+
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	create := func(feed *models.Feed, name string, publishedAt time.Time) *models.Article {
+		article, err := articleRepo.Create(ctx, &models.Article{FeedID: feed.ID, Title: name, URL: feed.URL + "/" + name, PublishedAt: publishedAt})
+		require.NoError(t, err)
+		return article
+	}
+	filedRead := create(filed, "filed-read", base)
+	filedStarred := create(filed, "filed-starred", base.Add(time.Hour))
+	unfiledRead := create(unfiled, "unfiled-read", base.Add(2*time.Hour))
+	unfiledNew := create(unfiled, "unfiled-new", base.Add(3*time.Hour))
+	for _, article := range []*models.Article{filedRead, unfiledRead} {
+		_, err := service.SetArticleRead(ctx, 1, article.ID, true)
+		require.NoError(t, err)
+	}
+	_, err := service.SetArticleStarred(ctx, 1, filedStarred.ID, true)
+	require.NoError(t, err)
+	_, err = service.SetArticleStarred(ctx, 2, unfiledNew.ID, true)
+	require.NoError(t, err)
+
+	ids := func(filter TimelineFilter) ([]uint, int64) {
+		t.Helper()
+		articles, _, total, err := service.ListUserArticles(ctx, 1, filter, 0, "")
+		require.NoError(t, err)
+		ids := make([]uint, len(articles))
+		for i, article := range articles {
+			ids[i] = article.ID
+		}
+		return ids, total
+	}
+
+	got, total := ids(TimelineFilter{Unread: true})
+	require.Equal(t, []uint{unfiledNew.ID, filedStarred.ID}, got)
+	require.EqualValues(t, 2, total)
+
+	got, total = ids(TimelineFilter{Starred: true})
+	require.Equal(t, []uint{filedStarred.ID}, got, "another user's stars do not count")
+	require.EqualValues(t, 1, total)
+
+	got, _ = ids(TimelineFilter{FolderID: folderID})
+	require.Equal(t, []uint{filedStarred.ID, filedRead.ID}, got)
+
+	got, _ = ids(TimelineFilter{FolderID: folderID, Unread: true, Starred: true})
+	require.Equal(t, []uint{filedStarred.ID}, got, "filters combine")
+
+	got, total = ids(TimelineFilter{FolderID: folderID + 1})
+	require.Empty(t, got)
+	require.Zero(t, total)
+}
+
+func TestArticleTags(t *testing.T) {
+	service, _, articleRepo, db := setupArticleService(t)
+	ctx := context.Background()
+
+	feed := &models.Feed{Title: "A", URL: "https://a.example.com", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feed.ID}).Error)
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	older, err := articleRepo.Create(ctx, &models.Article{FeedID: feed.ID, Title: "older", URL: feed.URL + "/older", PublishedAt: base})
+	require.NoError(t, err)
+	newer, err := articleRepo.Create(ctx, &models.Article{FeedID: feed.ID, Title: "newer", URL: feed.URL + "/newer", PublishedAt: base.Add(time.Hour)})
+	require.NoError(t, err)
+
+	tagged, err := service.AddArticleTags(ctx, 1, older.ID, []string{"#GoLang", "db"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"db", "golang"}, tagged.Tags)
+	_, err = service.AddArticleTags(ctx, 1, newer.ID, []string{"db"})
+	require.NoError(t, err)
+
+	golang, next, total, err := service.ListUserArticles(ctx, 1, TimelineFilter{Tag: "golang"}, 0, "")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, total)
+	require.Empty(t, next)
+	require.Equal(t, older.ID, golang[0].ID)
+	require.Equal(t, []string{"db", "golang"}, golang[0].Tags)
+
+	tags, err := service.ListTags(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []models.TagCount{{Name: "db", ArticleCount: 2}, {Name: "golang", ArticleCount: 1}}, tags)
+
+	untagged, err := service.RemoveArticleTags(ctx, 1, older.ID, []string{"golang"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"db"}, untagged.Tags)
+	_, _, total, err = service.ListUserArticles(ctx, 1, TimelineFilter{Tag: "golang"}, 0, "")
+	require.NoError(t, err)
+	require.Zero(t, total)
+
+	_, err = service.AddArticleTags(ctx, 2, older.ID, []string{"mine"})
+	require.ErrorIs(t, err, ierr.ErrNotSubscribed)
+	_, err = service.AddArticleTags(ctx, 1, older.ID, []string{"two words"})
+	require.Error(t, err)
+	_, err = service.AddArticleTags(ctx, 1, older.ID, nil)
+	require.Error(t, err)
+	many := make([]string, models.MaxTagsPerArticle)
+	for i := range many {
+		many[i] = fmt.Sprintf("tag%d", i)
+	}
+	_, err = service.AddArticleTags(ctx, 1, older.ID, many)
+	require.Error(t, err, "the article already has a tag, so the limit is exceeded")
+	_, _, _, err = service.ListUserArticles(ctx, 1, TimelineFilter{Tag: "a,b"}, 0, "")
+	require.Error(t, err)
+}
@@ -4,24 +4,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"log/slog"
-	"math/rand"
+	"mime"
 	"net/http"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Fancu1/phoenix-rss/internal/events"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
 	"github.com/Fancu1/phoenix-rss/pkg/logger"
 )
 
+// ArticleUpdateConfig configures the checker. Retries, timeouts and the identity are
+// properties of the HTTP client it is given.
 type ArticleUpdateConfig struct {
-	UserAgent       string
-	MaxAttempts     int
-	BackoffInitial  time.Duration
-	BackoffMax      time.Duration
-	Jitter          bool
+	UserAgent       string // matched against robots.txt groups
 	MaxContentBytes int64
 	RespectRobots   bool
 }
@@ -32,21 +33,14 @@ type ArticleUpdateChecker struct {
 	httpClient *http.Client
 	robots     *RobotsClient
 	cfg        ArticleUpdateConfig
-	randSource *rand.Rand
+	budget     *CrawlBudget
+	breaker    *HostBreaker
+	delays     *crawlDelays
 }
 
 func NewArticleUpdateChecker(repo *repository.ArticleRepository, logger *slog.Logger, httpClient *http.Client, robots *RobotsClient, cfg ArticleUpdateConfig) *ArticleUpdateChecker {
 	if cfg.UserAgent == "" {
-		cfg.UserAgent = "PhoenixRSS/1.0 (+https://github.com/Fancu1/phoenix-rss)"
-	}
-	if cfg.MaxAttempts <= 0 {
-		cfg.MaxAttempts = 3
-	}
-	if cfg.BackoffInitial <= 0 {
-		cfg.BackoffInitial = 500 * time.Millisecond
-	}
-	if cfg.BackoffMax < cfg.BackoffInitial {
-		cfg.BackoffMax = 10 * time.Second
+		cfg.UserAgent = DefaultUserAgent
 	}
 	if cfg.MaxContentBytes <= 0 {
 		cfg.MaxContentBytes = 2 << 20 // 2 MiB
@@ -58,10 +52,20 @@ func NewArticleUpdateChecker(repo *repository.ArticleRepository, logger *slog.Lo
 		httpClient: httpClient,
 		robots:     robots,
 		cfg:        cfg,
-		randSource: rand.New(rand.NewSource(time.Now().UnixNano())),
+		delays:     newCrawlDelays(),
 	}
 }
 
+// SetCrawlBudget charges every article page request to its feed's crawl budget
+func (c *ArticleUpdateChecker) SetCrawlBudget(budget *CrawlBudget) {
+	c.budget = budget
+}
+
+// SetHostBreaker pauses the checks against hosts that keep failing
+func (c *ArticleUpdateChecker) SetHostBreaker(breaker *HostBreaker) {
+	c.breaker = breaker
+}
+
 func (c *ArticleUpdateChecker) HandleEvent(ctx context.Context, event events.ArticleCheckEvent) error {
 	taskCtx := logger.WithValue(ctx, "article_id", event.ArticleID)
 	taskCtx = logger.WithValue(taskCtx, "request_id", event.RequestID)
@@ -71,6 +75,7 @@ func (c *ArticleUpdateChecker) HandleEvent(ctx context.Context, event events.Art
 		return fmt.Errorf("event url cannot be empty")
 	}
 
+	var crawlDelay time.Duration
 	if c.cfg.RespectRobots && c.robots != nil {
 		allowed, err := c.robots.IsAllowed(taskCtx, event.URL, c.cfg.UserAgent)
 		if err != nil {
@@ -79,35 +84,75 @@ func (c *ArticleUpdateChecker) HandleEvent(ctx context.Context, event events.Art
 			log.Info("robots disallow article fetch", "url", event.URL)
 			return c.repo.MarkLastChecked(taskCtx, event.ArticleID, time.Now().UTC())
 		}
+		// the rules are cached by IsAllowed, so this does not fetch robots.txt again
+		crawlDelay, _ = c.robots.CrawlDelay(taskCtx, event.URL, c.cfg.UserAgent)
 	}
 
-	headResp, err := c.performRequest(taskCtx, http.MethodHead, event.URL, event)
-	if err != nil {
-		log.Error("head request failed", "error", err)
-		return err
-	}
-	defer headResp.Body.Close()
+	// a host asking for a Crawl-delay gets a single conditional GET instead of HEAD and GET
+	headHeader := http.Header{}
+	if crawlDelay <= 0 {
+		// a paused host or a feed out of budget has its articles checked again on a later run
+		if !c.breaker.Allow(taskCtx, event.URL) {
+			return nil
+		}
+		if !c.budget.Spend(taskCtx, event.FeedID, CrawlArticleCheck) {
+			return nil
+		}
+		headResp, err := c.performRequest(taskCtx, http.MethodHead, event.URL, event)
+		c.breaker.Record(taskCtx, event.URL, headResp, err)
+		if err != nil {
+			fetchErr := fetchError(err)
+			log.Error("head request failed", "error_code", fetchErr.Code, "error", err)
+			return fetchErr
+		}
+		defer headResp.Body.Close()
+		headHeader = headResp.Header
 
-	switch headResp.StatusCode {
-	case http.StatusNotModified:
-		log.Info("article not modified", "status", headResp.StatusCode)
-		return c.repo.MarkLastChecked(taskCtx, event.ArticleID, time.Now().UTC())
-	case http.StatusOK:
-		// continue to GET
-	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
-		log.Info("head not supported, falling back to GET", "status", headResp.StatusCode)
-	default:
-		if isRetryableStatus(headResp.StatusCode) {
-			return fmt.Errorf("head request returned retryable status %d", headResp.StatusCode)
+		switch headResp.StatusCode {
+		case http.StatusNotModified:
+			log.Info("article not modified", "status", headResp.StatusCode)
+			return c.repo.MarkLastChecked(taskCtx, event.ArticleID, time.Now().UTC())
+		case http.StatusOK:
+			// continue to GET
+		case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+			log.Info("head not supported, falling back to GET", "status", headResp.StatusCode)
+		default:
+			if httpclient.IsRetryableStatus(headResp.StatusCode) {
+				return fmt.Errorf("head request returned retryable status %d: %w", headResp.StatusCode, upstreamStatusError(headResp.StatusCode))
+			}
+			log.Warn("head request returned non-retryable status", "status", headResp.StatusCode)
+			return c.repo.MarkLastChecked(taskCtx, event.ArticleID, time.Now().UTC())
+		}
+
+		// a page HEAD already declares as neither HTML nor text is not downloaded
+		if headResp.StatusCode == http.StatusOK {
+			if mediaType := sniffMediaType(headResp.Header.Get("Content-Type"), nil); mediaType != "" && classifyPage(mediaType) == pageOther {
+				return c.recordNonHTML(taskCtx, event.ArticleID, mediaType, headResp.Header, headResp.Header)
+			}
 		}
-		log.Warn("head request returned non-retryable status", "status", headResp.StatusCode)
-		return c.repo.MarkLastChecked(taskCtx, event.ArticleID, time.Now().UTC())
 	}
 
+	// the GET also waits out the host's Crawl-delay; when that leaves no turn soon the
+	// article is checked again on a later run
+	if !c.breaker.Allow(taskCtx, event.URL) {
+		return nil
+	}
+	if !c.awaitCrawlDelay(taskCtx, event.URL, crawlDelay) {
+		return nil
+	}
+	if !c.budget.Spend(taskCtx, event.FeedID, CrawlArticleCheck) {
+		return nil
+	}
 	getResp, err := c.performRequest(taskCtx, http.MethodGet, event.URL, event)
+	c.breaker.Record(taskCtx, event.URL, getResp, err)
+	if errors.Is(err, httpclient.ErrBodyTooLarge) {
+		log.Warn("article page is too large, keeping feed content", "limit", c.cfg.MaxContentBytes)
+		return c.repo.MarkLastChecked(taskCtx, event.ArticleID, time.Now().UTC())
+	}
 	if err != nil {
-		log.Error("get request failed", "error", err)
-		return err
+		fetchErr := fetchError(err)
+		log.Error("get request failed", "error_code", fetchErr.Code, "error", err)
+		return fetchErr
 	}
 	defer getResp.Body.Close()
 
@@ -118,22 +163,50 @@ func (c *ArticleUpdateChecker) HandleEvent(ctx context.Context, event events.Art
 		log.Info("article unchanged on GET", "status", getResp.StatusCode)
 		return c.repo.MarkLastChecked(taskCtx, event.ArticleID, time.Now().UTC())
 	default:
-		if isRetryableStatus(getResp.StatusCode) {
-			return fmt.Errorf("get request returned retryable status %d", getResp.StatusCode)
+		if httpclient.IsRetryableStatus(getResp.StatusCode) {
+			return fmt.Errorf("get request returned retryable status %d: %w", getResp.StatusCode, upstreamStatusError(getResp.StatusCode))
 		}
 		log.Warn("get request returned non-retryable status", "status", getResp.StatusCode)
 		return c.repo.MarkLastChecked(taskCtx, event.ArticleID, time.Now().UTC())
 	}
 
-	body, err := readLimited(getResp.Body, c.cfg.MaxContentBytes)
-	if err != nil {
+	// the start of the body decides what the page is when its type is not declared
+	peek := make([]byte, sniffLen)
+	n, err := io.ReadFull(getResp.Body, peek)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
 		return fmt.Errorf("failed to read article body: %w", err)
 	}
+	peek = peek[:n]
+	mediaType := sniffMediaType(getResp.Header.Get("Content-Type"), peek)
+	kind := classifyPage(mediaType)
+	if kind == pageOther {
+		return c.recordNonHTML(taskCtx, event.ArticleID, mediaType, getResp.Header, headHeader)
+	}
 
-	content, description := c.sanitizeContent(taskCtx, string(body), event.URL)
+	rest, err := io.ReadAll(getResp.Body)
+	if errors.Is(err, httpclient.ErrBodyTooLarge) {
+		log.Warn("article page is too large, keeping feed content", "limit", c.cfg.MaxContentBytes)
+		return c.repo.MarkLastChecked(taskCtx, event.ArticleID, time.Now().UTC())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read article body: %w", err)
+	}
+	body := append(peek, rest...)
+
+	var content, description string
+	switch {
+	case kind == pageText && !utf8.Valid(body):
+		// binary served as text
+		return c.recordNonHTML(taskCtx, event.ArticleID, "application/octet-stream", getResp.Header, headHeader)
+	case kind == pageText:
+		text := strings.TrimSpace(string(body))
+		content, description = "<pre>"+html.EscapeString(text)+"</pre>", text
+	default:
+		content, description = c.sanitizeContent(taskCtx, string(body), event.URL)
+	}
 
-	newEtag := preferHeader(getResp.Header.Get("ETag"), headResp.Header.Get("ETag"))
-	newLastModified := normalizeHTTPDate(preferHeader(getResp.Header.Get("Last-Modified"), headResp.Header.Get("Last-Modified")))
+	newEtag := preferHeader(getResp.Header.Get("ETag"), headHeader.Get("ETag"))
+	newLastModified := normalizeHTTPDate(preferHeader(getResp.Header.Get("Last-Modified"), headHeader.Get("Last-Modified")))
 
 	now := time.Now().UTC()
 	updated, updateErr := c.repo.UpdateArticleOnChange(
@@ -141,6 +214,8 @@ func (c *ArticleUpdateChecker) HandleEvent(ctx context.Context, event events.Art
 		event.ArticleID,
 		content,
 		description,
+		articleDirection("", content, ""),
+		optionalString(mediaType),
 		optionalString(newEtag),
 		optionalString(newLastModified),
 		now,
@@ -160,64 +235,86 @@ func (c *ArticleUpdateChecker) HandleEvent(ctx context.Context, event events.Art
 	return nil
 }
 
+// recordNonHTML stores the media type of a page that is neither HTML nor text, and keeps
+// the article's content from the feed
+func (c *ArticleUpdateChecker) recordNonHTML(ctx context.Context, articleID uint, mediaType string, header, fallback http.Header) error {
+	etag := preferHeader(header.Get("ETag"), fallback.Get("ETag"))
+	lastModified := normalizeHTTPDate(preferHeader(header.Get("Last-Modified"), fallback.Get("Last-Modified")))
+	logger.FromContext(ctx).Info("article page is not html, keeping feed content", "content_type", mediaType)
+	return c.repo.RecordNonHTMLContent(ctx, articleID, mediaType, optionalString(etag), optionalString(lastModified), time.Now().UTC())
+}
+
+// awaitCrawlDelay waits for the host's Crawl-delay to pass since this replica's last
+// request to it; false means the check should be left for a later run
+func (c *ArticleUpdateChecker) awaitCrawlDelay(ctx context.Context, rawURL string, delay time.Duration) bool {
+	if c.delays.wait(ctx, rawURL, delay) {
+		return true
+	}
+	logger.FromContext(ctx).Info("crawl-delay leaves no turn soon, deferring article check", "url", rawURL, "crawl_delay", delay)
+	return false
+}
+
+// performRequest sends a conditional request; the HTTP client retries it as configured
 func (c *ArticleUpdateChecker) performRequest(ctx context.Context, method, rawURL string, event events.ArticleCheckEvent) (*http.Response, error) {
-	headers := make(http.Header)
-	headers.Set("User-Agent", c.cfg.UserAgent)
-	headers.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-	headers.Set("Accept-Language", "en-US,en;q=0.9")
-
-	if method == http.MethodHead || method == http.MethodGet {
-		if etag := trim(event.PrevETag); etag != "" {
-			headers.Set("If-None-Match", etag)
-		}
-		if lm := trim(event.PrevLastModified); lm != "" {
-			if httpDate := toHTTPDate(lm); httpDate != "" {
-				headers.Set("If-Modified-Since", httpDate)
-			}
-		}
+	limit := c.cfg.MaxContentBytes
+	if method == http.MethodHead {
+		limit = 0 // a HEAD declaring a large body is how large pages are recognized without a download
+	}
+	req, err := http.NewRequestWithContext(httpclient.WithMaxBodyBytes(ctx, limit), method, rawURL, nil)
+	if err != nil {
+		return nil, err
 	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 
-	backoff := c.cfg.BackoffInitial
-	for attempt := 1; attempt <= c.cfg.MaxAttempts; attempt++ {
-		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
-		if err != nil {
-			return nil, err
+	if etag := trim(event.PrevETag); etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lm := trim(event.PrevLastModified); lm != "" {
+		if httpDate := toHTTPDate(lm); httpDate != "" {
+			req.Header.Set("If-Modified-Since", httpDate)
 		}
-		req.Header = headers.Clone()
+	}
 
-		resp, err := c.httpClient.Do(req)
-		if err == nil && !isRetryableStatus(resp.StatusCode) {
-			return resp, nil
-		}
-		if err == nil && attempt == c.cfg.MaxAttempts {
-			return resp, nil
-		}
-		if err != nil && attempt == c.cfg.MaxAttempts {
-			return nil, err
-		}
-		if err == nil {
-			drain(resp.Body)
-			resp.Body.Close()
-		}
+	return c.httpClient.Do(req)
+}
 
-		wait := backoff
-		if c.cfg.Jitter {
-			wait = time.Duration(c.randSource.Int63n(int64(backoff))) + backoff/2
-		}
+// sniffLen is how much of a body is read to sniff its type, as http.DetectContentType considers
+const sniffLen = 512
 
-		select {
-		case <-time.After(wait):
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		}
+// pageKind is how the update checker treats a fetched article page
+type pageKind int
 
-		backoff = backoff * 2
-		if backoff > c.cfg.BackoffMax {
-			backoff = c.cfg.BackoffMax
-		}
+const (
+	pageHTML  pageKind = iota // sanitized and stored
+	pageText                  // stored preformatted, without sanitizing
+	pageOther                 // PDFs, JSON, images...: only the type is recorded
+)
+
+// classifyPage tells how a page of the media type is handled; an unknown type is taken
+// for HTML, as feeds link to web pages
+func classifyPage(mediaType string) pageKind {
+	switch mediaType {
+	case "", "text/html", "application/xhtml+xml":
+		return pageHTML
+	case "text/plain", "text/markdown":
+		return pageText
+	default:
+		return pageOther
 	}
+}
 
-	return nil, errors.New("request attempts exhausted")
+// sniffMediaType returns the media type a response declares or, when it declares none or
+// only application/octet-stream, the one sniffed from the start of its body
+func sniffMediaType(header string, start []byte) string {
+	if mediaType, _, err := mime.ParseMediaType(header); err == nil && mediaType != "application/octet-stream" {
+		return mediaType
+	}
+	if len(start) == 0 {
+		return ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(start))
+	return mediaType
 }
 
 func (c *ArticleUpdateChecker) sanitizeContent(ctx context.Context, raw, base string) (string, string) {
@@ -237,29 +334,6 @@ func (c *ArticleUpdateChecker) sanitizeContent(ctx context.Context, raw, base st
 	return sanitized, description
 }
 
-func isRetryableStatus(code int) bool {
-	if code == http.StatusTooManyRequests || code == http.StatusRequestTimeout {
-		return true
-	}
-	return code >= 500
-}
-
-func drain(body io.Reader) {
-	_, _ = io.Copy(io.Discard, io.LimitReader(body, 512))
-}
-
-func readLimited(r io.Reader, limit int64) (string, error) {
-	reader := io.LimitReader(r, limit+1)
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return "", err
-	}
-	if int64(len(data)) > limit {
-		return "", fmt.Errorf("response exceeds limit of %d bytes", limit)
-	}
-	return string(data), nil
-}
-
 func optionalString(value string) *string {
 	if strings.TrimSpace(value) == "" {
 		return nil
@@ -17,6 +17,7 @@ import (
 	"github.com/Fancu1/phoenix-rss/internal/events"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
 )
 
 func setupCheckerRepo(t *testing.T) (*repository.ArticleRepository, *gorm.DB) {
@@ -79,16 +80,11 @@ func TestArticleUpdateChecker_UpdatesArticleOnChange(t *testing.T) {
 	_, err = repo.Update(context.Background(), article)
 	require.NoError(t, err)
 
-	httpClient := srv.Client()
-	httpClient.Timeout = time.Second
+	httpClient := httpclient.New(httpclient.Options{Timeout: time.Second, UserAgent: "testrunner"})
 
 	robots := NewRobotsClient(httpClient, time.Hour, logger)
 	checker := NewArticleUpdateChecker(repo, logger, httpClient, robots, ArticleUpdateConfig{
 		UserAgent:       "testrunner",
-		MaxAttempts:     1,
-		BackoffInitial:  10 * time.Millisecond,
-		BackoffMax:      10 * time.Millisecond,
-		Jitter:          false,
 		MaxContentBytes: 1024,
 		RespectRobots:   false,
 	})
@@ -147,16 +143,11 @@ func TestArticleUpdateChecker_RespectsRobots(t *testing.T) {
 	_, err = repo.Update(context.Background(), article)
 	require.NoError(t, err)
 
-	httpClient := srv.Client()
-	httpClient.Timeout = time.Second
+	httpClient := httpclient.New(httpclient.Options{Timeout: time.Second, UserAgent: "testrunner"})
 
 	robots := NewRobotsClient(httpClient, time.Hour, logger)
 	checker := NewArticleUpdateChecker(repo, logger, httpClient, robots, ArticleUpdateConfig{
 		UserAgent:       "testrunner",
-		MaxAttempts:     1,
-		BackoffInitial:  10 * time.Millisecond,
-		BackoffMax:      10 * time.Millisecond,
-		Jitter:          false,
 		MaxContentBytes: 1024,
 		RespectRobots:   true,
 	})
@@ -215,16 +206,11 @@ func TestArticleUpdateChecker_FallbackOnHeadNotAllowed(t *testing.T) {
 	_, err = repo.Update(context.Background(), article)
 	require.NoError(t, err)
 
-	httpClient := srv.Client()
-	httpClient.Timeout = time.Second
+	httpClient := httpclient.New(httpclient.Options{Timeout: time.Second, UserAgent: "testrunner"})
 
 	robots := NewRobotsClient(httpClient, time.Hour, logger)
 	checker := NewArticleUpdateChecker(repo, logger, httpClient, robots, ArticleUpdateConfig{
 		UserAgent:       "testrunner",
-		MaxAttempts:     1,
-		BackoffInitial:  10 * time.Millisecond,
-		BackoffMax:      10 * time.Millisecond,
-		Jitter:          false,
 		MaxContentBytes: 1024,
 		RespectRobots:   false,
 	})
@@ -247,3 +233,66 @@ func TestArticleUpdateChecker_FallbackOnHeadNotAllowed(t *testing.T) {
 	require.NotNil(t, stored.HTTPETag)
 	assert.Equal(t, "new", *stored.HTTPETag)
 }
+
+func TestArticleUpdateChecker_NonHTMLContent(t *testing.T) {
+	pdf := append([]byte("%PDF-1.7\n"), make([]byte, 2048)...)
+
+	for _, tc := range []struct {
+		name        string
+		headType    string
+		getType     string
+		body        []byte
+		wantGet     bool
+		wantType    string
+		wantContent string
+	}{
+		{name: "pdf declared on head", headType: "application/pdf", getType: "application/pdf", body: pdf, wantType: "application/pdf", wantContent: "from feed"},
+		{name: "json on get", getType: "application/json; charset=utf-8", body: []byte(`{"id": 1}`), wantGet: true, wantType: "application/json", wantContent: "from feed"},
+		{name: "undeclared pdf", body: pdf, wantGet: true, wantType: "application/pdf", wantContent: "from feed"},
+		{name: "plain text", getType: "text/plain; charset=utf-8", body: []byte("a <b>plain</b> note\n"), wantGet: true, wantType: "text/plain", wantContent: "<pre>a &lt;b&gt;plain&lt;/b&gt; note</pre>"},
+		{name: "binary as text", getType: "text/plain", body: []byte{0xff, 0xfe, 0x00, 0x81}, wantGet: true, wantType: "application/octet-stream", wantContent: "from feed"},
+		{name: "html", getType: "text/html", body: []byte("<p>page</p>"), wantGet: true, wantType: "text/html", wantContent: "<p>page</p>"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, db := setupCheckerRepo(t)
+			now := time.Now().UTC()
+			getHits := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodHead {
+					if tc.headType != "" {
+						w.Header().Set("Content-Type", tc.headType)
+					}
+					w.Header().Set("ETag", `"v2"`)
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				getHits++
+				if tc.getType != "" {
+					w.Header().Set("Content-Type", tc.getType)
+				} else {
+					w.Header()["Content-Type"] = nil // keep net/http from sniffing
+				}
+				_, _ = w.Write(tc.body)
+			}))
+			defer srv.Close()
+
+			article := &models.Article{FeedID: 1, Title: "Test", URL: srv.URL + "/" + tc.name, Content: "from feed", PublishedAt: now}
+			_, err := repo.Create(context.Background(), article)
+			require.NoError(t, err)
+			defer db.Unscoped().Delete(article)
+
+			httpClient := httpclient.New(httpclient.Options{Timeout: time.Second})
+			checker := NewArticleUpdateChecker(repo, newTestLogger(), httpClient, nil, ArticleUpdateConfig{MaxContentBytes: 1024})
+			require.NoError(t, checker.HandleEvent(context.Background(), events.ArticleCheckEvent{ArticleID: article.ID, URL: article.URL}))
+
+			assert.Equal(t, tc.wantGet, getHits > 0)
+			stored, err := repo.GetByID(context.Background(), article.ID)
+			require.NoError(t, err)
+			require.NotNil(t, stored.ContentType)
+			assert.Equal(t, tc.wantType, *stored.ContentType)
+			assert.Equal(t, tc.wantContent, stored.Content)
+			require.NotNil(t, stored.HTTPETag)
+			assert.Equal(t, `"v2"`, *stored.HTTPETag)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	htmlnode "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// DirectionLTR is the text direction of most scripts
+	DirectionLTR = "ltr"
+	// DirectionRTL is the text direction of Arabic, Hebrew, Persian, Urdu and the like
+	DirectionRTL = "rtl"
+)
+
+// directionPattern and languagePattern are the dir and lang values kept by the sanitizer
+var (
+	directionPattern     = regexp.MustCompile(`(?i)^(rtl|ltr|auto)$`)
+	autoDirectionPattern = regexp.MustCompile(`(?i)^auto$`)
+	languagePattern      = regexp.MustCompile(`^[a-zA-Z]{2,8}(-[a-zA-Z0-9]{1,8})*$`)
+)
+
+// rtlLanguages are the primary language subtags written right to left
+var rtlLanguages = map[string]bool{
+	"ar": true, "arc": true, "ckb": true, "dv": true, "fa": true, "he": true, "iw": true,
+	"ks": true, "ku": true, "ps": true, "sd": true, "syr": true, "ug": true, "ur": true, "yi": true,
+}
+
+// directionSampleLetters is how many letters of an article are looked at to detect its
+// direction
+const directionSampleLetters = 2000
+
+// rootDirection returns the dir and lang of the <html> or <body> of a full page. The
+// sanitizer drops both elements, so their attributes are moved onto a wrapping <div>.
+func rootDirection(markup string) (dir, lang string) {
+	tokenizer := htmlnode.NewTokenizer(strings.NewReader(markup))
+	for {
+		switch tokenizer.Next() {
+		case htmlnode.ErrorToken:
+			return dir, lang
+		case htmlnode.StartTagToken, htmlnode.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.DataAtom != atom.Html && token.DataAtom != atom.Body {
+				if token.DataAtom == atom.Head || token.DataAtom == atom.Meta || token.DataAtom == atom.Title ||
+					token.DataAtom == atom.Link || token.DataAtom == atom.Base {
+					continue
+				}
+				return dir, lang
+			}
+			for _, attr := range token.Attr {
+				value := strings.TrimSpace(attr.Val)
+				switch {
+				case attr.Key == "dir" && directionPattern.MatchString(value):
+					dir = strings.ToLower(value)
+				case attr.Key == "lang" && languagePattern.MatchString(value):
+					lang = value
+				}
+			}
+		}
+	}
+}
+
+// wrapDirection puts sanitized markup in a <div> carrying the dir and lang of its page
+func wrapDirection(sanitized, dir, lang string) string {
+	if (dir == "" && lang == "") || strings.TrimSpace(sanitized) == "" {
+		return sanitized
+	}
+	var attrs strings.Builder
+	if dir != "" {
+		attrs.WriteString(` dir="` + html.EscapeString(dir) + `"`)
+	}
+	if lang != "" {
+		attrs.WriteString(` lang="` + html.EscapeString(lang) + `"`)
+	}
+	return "<div" + attrs.String() + ">" + sanitized + "</div>"
+}
+
+// articleDirection is the direction clients should render sanitized content in. The dir
+// of its outermost element wins, then a right-to-left lang there or in the feed, then
+// the script most of the title and text is written in.
+func articleDirection(title, content, feedLanguage string) string {
+	dir, lang := outerDirection(content)
+	switch dir {
+	case DirectionLTR, DirectionRTL:
+		return dir
+	}
+	if isRTLLanguage(lang) || (lang == "" && isRTLLanguage(feedLanguage)) {
+		return DirectionRTL
+	}
+	return detectDirection(title + "\n" + sanitizePlainText(content))
+}
+
+// outerDirection returns the dir and lang of the first element of the content when no
+// text comes before it
+func outerDirection(content string) (dir, lang string) {
+	tokenizer := htmlnode.NewTokenizer(strings.NewReader(content))
+	for {
+		switch tokenizer.Next() {
+		case htmlnode.ErrorToken:
+			return "", ""
+		case htmlnode.TextToken:
+			if strings.TrimSpace(string(tokenizer.Text())) != "" {
+				return "", ""
+			}
+		case htmlnode.StartTagToken:
+			for _, attr := range tokenizer.Token().Attr {
+				switch attr.Key {
+				case "dir":
+					dir = strings.ToLower(strings.TrimSpace(attr.Val))
+				case "lang":
+					lang = strings.TrimSpace(attr.Val)
+				}
+			}
+			return dir, lang
+		}
+	}
+}
+
+func isRTLLanguage(tag string) bool {
+	primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	primary, _, _ = strings.Cut(primary, "_")
+	return rtlLanguages[primary]
+}
+
+// detectDirection returns rtl when most letters of text are from right-to-left scripts,
+// ltr otherwise
+func detectDirection(text string) string {
+	var rtl, ltr, letters int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		if unicode.In(r, unicode.Arabic, unicode.Hebrew, unicode.Syriac, unicode.Thaana, unicode.Nko, unicode.Samaritan, unicode.Mandaic) {
+			rtl++
+		} else {
+			ltr++
+		}
+		if letters++; letters >= directionSampleLetters {
+			break
+		}
+	}
+	if rtl > ltr {
+		return DirectionRTL
+	}
+	return DirectionLTR
+}
@@ -54,7 +54,8 @@ func sanitizeHTML(raw, baseURL string) (string, error) {
 	policy := bluemonday.UGCPolicy()
 	allowRichContent(policy)
 
-	return policy.Sanitize(absoluteMarkup), nil
+	dir, lang := rootDirection(markup)
+	return wrapDirection(policy.Sanitize(absoluteMarkup), dir, lang), nil
 }
 
 func ensureHTML(raw string) string {
@@ -70,13 +71,10 @@ func ensureHTML(raw string) string {
 	return "<pre>" + htmlstd.EscapeString(trimmed) + "</pre>"
 }
 
+// absolutizeMarkup rewrites relative URLs so content renders correctly outside its
+// origin. A <base href> inside the markup takes precedence over the given base.
 func absolutizeMarkup(input, base string) (string, error) {
-	if strings.TrimSpace(input) == "" || strings.TrimSpace(base) == "" {
-		return input, nil
-	}
-
-	parsedBase, err := url.Parse(base)
-	if err != nil || !parsedBase.IsAbs() {
+	if strings.TrimSpace(input) == "" {
 		return input, nil
 	}
 
@@ -86,6 +84,15 @@ func absolutizeMarkup(input, base string) (string, error) {
 		return input, err
 	}
 
+	var parsedBase *url.URL
+	if parsed, err := url.Parse(strings.TrimSpace(base)); err == nil && parsed.IsAbs() {
+		parsedBase = parsed
+	}
+	parsedBase = documentBase(nodes, parsedBase)
+	if parsedBase == nil {
+		return input, nil
+	}
+
 	for _, n := range nodes {
 		rewriteRelativeURLs(n, parsedBase)
 		container.AppendChild(n)
@@ -101,6 +108,49 @@ func absolutizeMarkup(input, base string) (string, error) {
 	return buf.String(), nil
 }
 
+// documentBase returns the first <base href> in the markup, resolved against the
+// fallback base. Only http(s) bases are honoured.
+func documentBase(nodes []*htmlnode.Node, fallback *url.URL) *url.URL {
+	var href string
+	var find func(*htmlnode.Node) bool
+	find = func(n *htmlnode.Node) bool {
+		if n.Type == htmlnode.ElementNode && n.DataAtom == atom.Base {
+			for _, attr := range n.Attr {
+				if attr.Key == "href" && strings.TrimSpace(attr.Val) != "" {
+					href = strings.TrimSpace(attr.Val)
+					return true
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if find(child) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, n := range nodes {
+		if find(n) {
+			break
+		}
+	}
+	if href == "" {
+		return fallback
+	}
+
+	parsed, err := url.Parse(href)
+	if err != nil {
+		return fallback
+	}
+	if fallback != nil {
+		parsed = fallback.ResolveReference(parsed)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fallback
+	}
+	return parsed
+}
+
 func rewriteRelativeURLs(node *htmlnode.Node, base *url.URL) {
 	if node.Type == htmlnode.ElementNode {
 		for i, attr := range node.Attr {
@@ -110,6 +160,8 @@ func rewriteRelativeURLs(node *htmlnode.Node, base *url.URL) {
 				if resolved != "" {
 					node.Attr[i].Val = resolved
 				}
+			case "srcset":
+				node.Attr[i].Val = absolutizeSrcset(attr.Val, base)
 			}
 		}
 	}
@@ -119,6 +171,27 @@ func rewriteRelativeURLs(node *htmlnode.Node, base *url.URL) {
 	}
 }
 
+// absolutizeSrcset resolves each candidate of a srcset attribute ("url [descriptor], ...")
+func absolutizeSrcset(value string, base *url.URL) string {
+	if strings.Contains(value, "data:") {
+		return value // data URIs contain commas; bluemonday drops them anyway
+	}
+
+	candidates := strings.Split(value, ",")
+	resolved := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		if u := absolutize(fields[0], base); u != "" {
+			fields[0] = u
+		}
+		resolved = append(resolved, strings.Join(fields, " "))
+	}
+	return strings.Join(resolved, ", ")
+}
+
 func absolutize(value string, base *url.URL) string {
 	s := strings.TrimSpace(value)
 	if s == "" {
@@ -134,14 +207,18 @@ func absolutize(value string, base *url.URL) string {
 		return s
 	}
 
+	// protocol-relative ("//cdn.example.com/x.png") inherits the base scheme
 	return base.ResolveReference(parsed).String()
 }
 
 func allowRichContent(policy *bluemonday.Policy) {
 	policy.AllowElements("pre", "code", "img", "figure", "figcaption")
-	policy.AllowAttrs("src", "alt", "title", "width", "height", "loading").OnElements("img")
+	policy.AllowAttrs("src", "srcset", "sizes", "alt", "title", "width", "height", "loading").OnElements("img")
 	policy.AllowURLSchemes("http", "https")
 	policy.AllowAttrs("class").OnElements("code", "pre")
+	// the UGC policy keeps lang and dir="rtl|ltr"; dir="auto" asks clients to detect it.
+	// Every matching global policy writes the attribute, so this one matches auto only.
+	policy.AllowAttrs("dir").Matching(autoDirectionPattern).Globally()
 }
 
 func sanitizePlainText(input string) string {
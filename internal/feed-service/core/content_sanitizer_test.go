@@ -1,6 +1,8 @@
 package core
 
 import (
+	"context"
+	"strings"
 	"testing"
 
 	"github.com/mmcdole/gofeed"
@@ -53,3 +55,105 @@ func TestSanitizeFeedItem_FallbackToDescription(t *testing.T) {
 	require.Contains(t, content, "Description only body")
 	require.Equal(t, "Description only body", description)
 }
+
+func TestSanitizeFeedItem_ResolvesSrcset(t *testing.T) {
+	item := &gofeed.Item{
+		Content: `<img src="a.png" srcset="a-1x.png 1x, /img/a-2x.png 2x,//cdn.example.net/a-3x.png 3x" alt="a">`,
+	}
+
+	content, _, err := sanitizeFeedItem(item, "https://example.com/posts/1")
+	require.NoError(t, err)
+	require.Contains(t, content, `src="https://example.com/posts/a.png"`)
+	require.Contains(t, content, `srcset="https://example.com/posts/a-1x.png 1x, https://example.com/img/a-2x.png 2x, https://cdn.example.net/a-3x.png 3x"`)
+}
+
+func TestSanitizeFeedItem_ProtocolRelativeURLs(t *testing.T) {
+	item := &gofeed.Item{
+		Content: `<a href="//other.example.org/page">x</a><img src="//cdn.example.net/pic.png">`,
+	}
+
+	content, _, err := sanitizeFeedItem(item, "http://example.com/article")
+	require.NoError(t, err)
+	require.Contains(t, content, `href="http://other.example.org/page"`)
+	require.Contains(t, content, `src="http://cdn.example.net/pic.png"`)
+}
+
+func TestSanitizeFeedItem_HonoursBaseHref(t *testing.T) {
+	item := &gofeed.Item{
+		Content: `<base href="/blog/2024/"><p><a href="next.html">next</a><img src="pic.png"></p>`,
+	}
+
+	content, _, err := sanitizeFeedItem(item, "https://example.com/feed.xml")
+	require.NoError(t, err)
+	require.Contains(t, content, `href="https://example.com/blog/2024/next.html"`)
+	require.Contains(t, content, `src="https://example.com/blog/2024/pic.png"`)
+	require.NotContains(t, content, "<base")
+}
+
+func TestSanitizeFeedItem_BaseHrefWithoutItemLink(t *testing.T) {
+	item := &gofeed.Item{
+		Content: `<base href="https://static.example.com/"><img src="pic.png">`,
+	}
+
+	content, _, err := sanitizeFeedItem(item, "")
+	require.NoError(t, err)
+	require.Contains(t, content, `src="https://static.example.com/pic.png"`)
+}
+
+func TestArticleUpdateChecker_SanitizeContentResolvesURLs(t *testing.T) {
+	checker := &ArticleUpdateChecker{}
+	page := `<html><head><base href="https://example.com/docs/"></head><body>
+<p><a href="intro">Intro</a> <a href="//mirror.example.org/x">Mirror</a></p>
+<img src="/logo.png" srcset="logo@2x.png 2x">
+</body></html>`
+
+	content, description := checker.sanitizeContent(context.Background(), page, "https://example.com/articles/42")
+	require.Contains(t, content, `href="https://example.com/docs/intro"`)
+	require.Contains(t, content, `href="https://mirror.example.org/x"`)
+	require.Contains(t, content, `src="https://example.com/logo.png"`)
+	require.Contains(t, content, `srcset="https://example.com/docs/logo@2x.png 2x"`)
+	require.Contains(t, description, "Intro")
+}
+
+func TestSanitizeFeedItem_KeepsDirectionAndLanguage(t *testing.T) {
+	item := &gofeed.Item{
+		Content: `<div dir="rtl" lang="ar-EG"><p dir="auto">مرحبا</p><p dir="sideways">x</p></div>`,
+	}
+
+	content, _, err := sanitizeFeedItem(item, "https://example.com/article")
+	require.NoError(t, err)
+	require.Contains(t, content, `<div dir="rtl" lang="ar-EG">`)
+	require.Contains(t, content, `<p dir="auto">`)
+	require.NotContains(t, content, "sideways")
+}
+
+func TestArticleUpdateChecker_SanitizeContentKeepsPageDirection(t *testing.T) {
+	checker := &ArticleUpdateChecker{}
+	page := `<html dir="rtl" lang="he"><head><title>t</title></head><body><p>שלום עולם</p></body></html>`
+
+	content, _ := checker.sanitizeContent(context.Background(), page, "https://example.com/articles/42")
+	require.Equal(t, `<div dir="rtl" lang="he"><p>שלום עולם</p></div>`, strings.TrimSpace(content))
+
+	content, _ = checker.sanitizeContent(context.Background(), `<html dir="x onload=alert(1)"><body><p>a</p></body></html>`, "")
+	require.NotContains(t, content, "<div")
+}
+
+func TestArticleDirection(t *testing.T) {
+	tests := []struct {
+		name, title, content, language, want string
+	}{
+		{"outermost dir wins", "مرحبا", `<div dir="ltr"><p>مرحبا بالعالم</p></div>`, "", DirectionLTR},
+		{"dir after text is not outermost", "", `Hello <span dir="rtl">שלום</span> world`, "", DirectionLTR},
+		{"rtl lang of the content", "News", `<p lang="fa-IR">News</p>`, "", DirectionRTL},
+		{"rtl feed language", "News", `<p>News</p>`, "he-IL", DirectionRTL},
+		{"content lang overrides the feed", "News", `<p lang="en">News</p>`, "ar", DirectionLTR},
+		{"arabic script", "خبر عاجل", `<p>نص المقال باللغة العربية with a few English words</p>`, "en", DirectionRTL},
+		{"latin script", "Breaking", `<p>Article text with a quote: שלום</p>`, "", DirectionLTR},
+		{"no letters", "", `<p>2024</p>`, "", DirectionLTR},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, articleDirection(tt.title, tt.content, tt.language))
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// CrawlRequestKind is what an outbound request charged to a feed's crawl budget was for
+type CrawlRequestKind string
+
+const (
+	// CrawlFeedFetch downloads the feed itself
+	CrawlFeedFetch CrawlRequestKind = "feed_fetch"
+	// CrawlArticleCheck is a HEAD or GET of an article page by the update checker
+	CrawlArticleCheck CrawlRequestKind = "article_check"
+)
+
+// CrawlRequestKinds lists every kind, in the order usage is reported
+var CrawlRequestKinds = []CrawlRequestKind{CrawlFeedFetch, CrawlArticleCheck}
+
+// crawlBudgetKeyPattern holds one feed's counters of one UTC day
+const crawlBudgetKeyPattern = "crawl_budget:%s:%d"
+
+// crawlBudgetTTL keeps yesterday's counters around for reports
+const crawlBudgetTTL = 48 * time.Hour
+
+// Counter fields of a crawl budget key besides the request kinds
+const (
+	crawlBudgetTotalField   = "total"
+	crawlBudgetRefusedField = "refused"
+)
+
+// CrawlBudgetStore keeps the crawl budget counters
+type CrawlBudgetStore interface {
+	// Take counts one request of kind at key, unless the requests counted there already
+	// reached limit, in which case the refusal is counted instead. A key expires ttl after
+	// its first request.
+	Take(ctx context.Context, key string, kind CrawlRequestKind, limit int64, ttl time.Duration) (bool, error)
+	// Counts returns the counters at key by field, empty once it expired
+	Counts(ctx context.Context, key string) (map[string]int64, error)
+}
+
+// CrawlUsage is how much of its crawl budget a feed used on a day
+type CrawlUsage struct {
+	Day     string                     `json:"day"`
+	Limit   int64                      `json:"limit"`
+	Used    int64                      `json:"used"`
+	Refused int64                      `json:"refused"`
+	ByKind  map[CrawlRequestKind]int64 `json:"by_kind"`
+}
+
+// Exhausted reports whether the feed has no requests left that day; a limit of 0 is
+// unlimited
+func (u CrawlUsage) Exhausted() bool {
+	return u.Limit > 0 && u.Used >= u.Limit
+}
+
+// CrawlLimits decides the daily budget of each feed
+type CrawlLimits interface {
+	// DailyRequests is the feed's budget; 0 is unlimited
+	DailyRequests(ctx context.Context, feedID uint) (int, error)
+}
+
+// CrawlBudget caps the outbound requests made for each feed per UTC day, so that one
+// misbehaving feed, e.g. one publishing hundreds of articles an hour, cannot take up the
+// capacity of the instance. The counters are shared by all feed-service replicas.
+type CrawlBudget struct {
+	store  CrawlBudgetStore
+	daily  int64
+	limits CrawlLimits
+	now    func() time.Time
+}
+
+func NewCrawlBudget(store CrawlBudgetStore, dailyRequests int) *CrawlBudget {
+	return &CrawlBudget{store: store, daily: int64(dailyRequests), now: time.Now}
+}
+
+// SetLimits gives feeds their own budgets; the daily requests passed to NewCrawlBudget
+// remain the fallback when a feed's budget cannot be looked up
+func (b *CrawlBudget) SetLimits(limits CrawlLimits) {
+	b.limits = limits
+}
+
+// limit returns the feed's budget, 0 if it is unlimited
+func (b *CrawlBudget) limit(ctx context.Context, feedID uint) int64 {
+	if b.limits == nil {
+		return b.daily
+	}
+	daily, err := b.limits.DailyRequests(ctx, feedID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("failed to look up crawl budget, using the default", "feed_id", feedID, "error", err.Error())
+		return b.daily
+	}
+	return int64(daily)
+}
+
+// Spend charges one request of kind to the feed's budget for today. It reports false once
+// the budget is used up, and the request must then not be made. Feeds without a budget are
+// not counted. Store failures are logged
+// and let the request through: the budget is a safeguard, not a reason to stop crawling.
+// A nil budget allows everything.
+func (b *CrawlBudget) Spend(ctx context.Context, feedID uint, kind CrawlRequestKind) bool {
+	if b == nil {
+		return true
+	}
+	limit := b.limit(ctx, feedID)
+	if limit <= 0 {
+		return true
+	}
+	allowed, err := b.store.Take(ctx, b.key(feedID, b.now()), kind, limit, crawlBudgetTTL)
+	if err != nil {
+		logger.FromContext(ctx).Warn("failed to charge crawl budget, allowing request", "feed_id", feedID, "kind", kind, "error", err.Error())
+		return true
+	}
+	if !allowed {
+		logger.FromContext(ctx).Info("crawl budget exhausted, skipping request", "feed_id", feedID, "kind", kind, "daily_requests", limit)
+	}
+	return allowed
+}
+
+// Usage returns what the feed used of its budget today
+func (b *CrawlBudget) Usage(ctx context.Context, feedID uint) (CrawlUsage, error) {
+	now := b.now().UTC()
+	counts, err := b.store.Counts(ctx, b.key(feedID, now))
+	if err != nil {
+		return CrawlUsage{}, fmt.Errorf("load crawl budget of feed %d: %w", feedID, err)
+	}
+
+	usage := CrawlUsage{
+		Day:     now.Format(time.DateOnly),
+		Limit:   b.limit(ctx, feedID),
+		Used:    counts[crawlBudgetTotalField],
+		Refused: counts[crawlBudgetRefusedField],
+		ByKind:  make(map[CrawlRequestKind]int64, len(CrawlRequestKinds)),
+	}
+	for _, kind := range CrawlRequestKinds {
+		usage.ByKind[kind] = counts[string(kind)]
+	}
+	return usage, nil
+}
+
+func (b *CrawlBudget) key(feedID uint, at time.Time) string {
+	return fmt.Sprintf(crawlBudgetKeyPattern, at.UTC().Format(time.DateOnly), feedID)
+}
+
+// takeScript checks and charges the budget in one step, so replicas racing for the last
+// requests of a feed cannot overspend it
+var takeScript = redis.NewScript(`
+local total = tonumber(redis.call('HGET', KEYS[1], 'total') or '0')
+if total >= tonumber(ARGV[2]) then
+	redis.call('HINCRBY', KEYS[1], 'refused', 1)
+	redis.call('PEXPIRE', KEYS[1], ARGV[3], 'NX')
+	return 0
+end
+redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+redis.call('HINCRBY', KEYS[1], 'total', 1)
+redis.call('PEXPIRE', KEYS[1], ARGV[3], 'NX')
+return 1
+`)
+
+// RedisCrawlBudgetStore keeps the crawl budget counters in a Redis hash per feed and day
+type RedisCrawlBudgetStore struct {
+	client redis.Cmdable
+}
+
+func NewRedisCrawlBudgetStore(client redis.Cmdable) *RedisCrawlBudgetStore {
+	return &RedisCrawlBudgetStore{client: client}
+}
+
+func (s *RedisCrawlBudgetStore) Take(ctx context.Context, key string, kind CrawlRequestKind, limit int64, ttl time.Duration) (bool, error) {
+	allowed, err := takeScript.Run(ctx, s.client, []string{key}, string(kind), limit, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return allowed == 1, nil
+}
+
+func (s *RedisCrawlBudgetStore) Counts(ctx context.Context, key string) (map[string]int64, error) {
+	values, err := s.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(values))
+	for field, value := range values {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("crawl budget counter %s of %s: %w", field, key, err)
+		}
+		counts[field] = n
+	}
+	return counts, nil
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/internal/events"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// memoryCrawlBudgetStore is a CrawlBudgetStore in a map; expiry is left out
+type memoryCrawlBudgetStore struct {
+	counts map[string]map[string]int64
+	err    error
+}
+
+func newMemoryCrawlBudgetStore() *memoryCrawlBudgetStore {
+	return &memoryCrawlBudgetStore{counts: map[string]map[string]int64{}}
+}
+
+func (s *memoryCrawlBudgetStore) Take(_ context.Context, key string, kind CrawlRequestKind, limit int64, _ time.Duration) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	counts := s.counts[key]
+	if counts == nil {
+		counts = map[string]int64{}
+		s.counts[key] = counts
+	}
+	if counts[crawlBudgetTotalField] >= limit {
+		counts[crawlBudgetRefusedField]++
+		return false, nil
+	}
+	counts[string(kind)]++
+	counts[crawlBudgetTotalField]++
+	return true, nil
+}
+
+func (s *memoryCrawlBudgetStore) Counts(_ context.Context, key string) (map[string]int64, error) {
+	return s.counts[key], s.err
+}
+
+func TestCrawlBudget_Spend(t *testing.T) {
+	store := newMemoryCrawlBudgetStore()
+	budget := NewCrawlBudget(store, 3)
+	day := time.Date(2026, 10, 17, 23, 0, 0, 0, time.UTC)
+	budget.now = func() time.Time { return day }
+	ctx := context.Background()
+
+	assert.True(t, budget.Spend(ctx, 1, CrawlFeedFetch))
+	assert.True(t, budget.Spend(ctx, 1, CrawlArticleCheck))
+	assert.True(t, budget.Spend(ctx, 1, CrawlArticleCheck))
+	assert.False(t, budget.Spend(ctx, 1, CrawlFeedFetch), "the budget is used up")
+	assert.True(t, budget.Spend(ctx, 2, CrawlFeedFetch), "every feed has a budget of its own")
+
+	usage, err := budget.Usage(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, CrawlUsage{
+		Day:     "2026-10-17",
+		Limit:   3,
+		Used:    3,
+		Refused: 1,
+		ByKind:  map[CrawlRequestKind]int64{CrawlFeedFetch: 1, CrawlArticleCheck: 2},
+	}, usage)
+	assert.True(t, usage.Exhausted())
+
+	day = day.Add(2 * time.Hour)
+	assert.True(t, budget.Spend(ctx, 1, CrawlFeedFetch), "a new day brings a new budget")
+
+	store.err = errors.New("connection refused")
+	assert.True(t, budget.Spend(ctx, 1, CrawlFeedFetch), "store failures let requests through")
+	_, err = budget.Usage(ctx, 1)
+	require.Error(t, err)
+
+	var disabled *CrawlBudget
+	assert.True(t, disabled.Spend(ctx, 1, CrawlFeedFetch))
+}
+
+// fixedCrawlLimits gives the feeds listed their own budget
+type fixedCrawlLimits struct {
+	daily map[uint]int
+	err   error
+}
+
+func (l fixedCrawlLimits) DailyRequests(_ context.Context, feedID uint) (int, error) {
+	return l.daily[feedID], l.err
+}
+
+func TestCrawlBudget_Limits(t *testing.T) {
+	store := newMemoryCrawlBudgetStore()
+	budget := NewCrawlBudget(store, 1)
+	limits := &fixedCrawlLimits{daily: map[uint]int{1: 2, 2: 0}}
+	budget.SetLimits(limits)
+	ctx := context.Background()
+
+	assert.True(t, budget.Spend(ctx, 1, CrawlFeedFetch))
+	assert.True(t, budget.Spend(ctx, 1, CrawlFeedFetch))
+	assert.False(t, budget.Spend(ctx, 1, CrawlFeedFetch), "the feed's own budget is used up")
+	for range 5 {
+		assert.True(t, budget.Spend(ctx, 2, CrawlFeedFetch), "a budget of 0 is unlimited")
+	}
+
+	usage, err := budget.Usage(ctx, 2)
+	require.NoError(t, err)
+	assert.Zero(t, usage.Limit)
+	assert.Zero(t, usage.Used, "unlimited feeds are not counted")
+	assert.False(t, usage.Exhausted())
+
+	limits.err = errors.New("database down")
+	assert.True(t, budget.Spend(ctx, 3, CrawlFeedFetch))
+	assert.False(t, budget.Spend(ctx, 3, CrawlFeedFetch), "a failed lookup falls back to the default budget")
+}
+
+func TestArticleUpdateChecker_SkipsFeedOutOfBudget(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer server.Close()
+
+	checker := NewArticleUpdateChecker(nil, logger.New(0), server.Client(), nil, ArticleUpdateConfig{})
+	budget := NewCrawlBudget(newMemoryCrawlBudgetStore(), 1)
+	require.True(t, budget.Spend(context.Background(), 7, CrawlFeedFetch))
+	checker.SetCrawlBudget(budget)
+
+	err := checker.HandleEvent(context.Background(), events.ArticleCheckEvent{ArticleID: 1, FeedID: 7, URL: server.URL})
+	require.NoError(t, err)
+	assert.Zero(t, requests)
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCrawlDelayWait is the longest an article check waits for its turn at a host that
+// asks for a Crawl-delay; checks that would wait longer are left to a later run
+const maxCrawlDelayWait = 30 * time.Second
+
+// crawlDelays spaces out this replica's requests to hosts whose robots.txt sets a
+// Crawl-delay. Each host has the time of its next free turn; a request takes that turn
+// and moves it delay further on.
+type crawlDelays struct {
+	mu   sync.Mutex
+	next map[string]time.Time
+	now  func() time.Time
+}
+
+func newCrawlDelays() *crawlDelays {
+	return &crawlDelays{
+		next: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+// wait blocks until rawURL's host may get a request spaced delay after the previous one.
+// It returns false without waiting when the turn is more than maxCrawlDelayWait away,
+// and false when ctx is done first.
+func (d *crawlDelays) wait(ctx context.Context, rawURL string, delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return true
+	}
+
+	wait, ok := d.reserve(strings.ToLower(parsed.Host), delay, maxCrawlDelayWait)
+	if !ok {
+		return false
+	}
+	if wait <= 0 {
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// reserve takes the next turn at host and returns how long until it comes. A turn more
+// than maxWait away is not taken.
+func (d *crawlDelays) reserve(host string, delay, maxWait time.Duration) (time.Duration, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	if len(d.next) >= hostBucketSweepSize {
+		d.sweep(now)
+	}
+	turn := now
+	if next, ok := d.next[host]; ok && next.After(now) {
+		turn = next
+	}
+	wait := turn.Sub(now)
+	if wait > maxWait {
+		return 0, false
+	}
+	d.next[host] = turn.Add(delay)
+	return wait, true
+}
+
+// sweep forgets the hosts whose next turn has passed
+func (d *crawlDelays) sweep(now time.Time) {
+	for host, next := range d.next {
+		if !next.After(now) {
+			delete(d.next, host)
+		}
+	}
+}
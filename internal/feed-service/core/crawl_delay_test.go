@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/internal/events"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
+)
+
+func TestParseRobots_CrawlDelay(t *testing.T) {
+	content := "User-agent: *\nCrawl-delay: 10\nDisallow: /private\n\n" +
+		"User-agent: testrunner\nCrawl-delay: 2.5\n"
+
+	// a group with nothing but a Crawl-delay still applies to its agent
+	assert.Equal(t, 2500*time.Millisecond, parseRobots(content, "testrunner").crawlDelay)
+	assert.Equal(t, 10*time.Second, parseRobots(content, "otherbot").crawlDelay)
+
+	assert.Zero(t, parseRobots("User-agent: *\nCrawl-delay: soon\n", "testrunner").crawlDelay)
+	assert.Zero(t, parseRobots("User-agent: *\nCrawl-delay: -1\n", "testrunner").crawlDelay)
+	assert.Equal(t, maxCrawlDelay, parseRobots("User-agent: *\nCrawl-delay: 1e12\n", "testrunner").crawlDelay)
+}
+
+func TestCrawlDelays_SpacesRequestsPerHost(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	delays := newCrawlDelays()
+	delays.now = func() time.Time { return now }
+
+	wait, ok := delays.reserve("example.com", 10*time.Second, time.Minute)
+	require.True(t, ok)
+	assert.Zero(t, wait)
+	wait, ok = delays.reserve("example.com", 10*time.Second, time.Minute)
+	require.True(t, ok)
+	assert.Equal(t, 10*time.Second, wait)
+	wait, ok = delays.reserve("other.example.com", 10*time.Second, time.Minute)
+	require.True(t, ok)
+	assert.Zero(t, wait, "hosts are spaced independently")
+
+	// the next turn is 20s away, past the longest wait, and is left for a later request
+	_, ok = delays.reserve("example.com", 10*time.Second, 15*time.Second)
+	assert.False(t, ok)
+
+	now = now.Add(time.Minute)
+	wait, ok = delays.reserve("example.com", 10*time.Second, 15*time.Second)
+	require.True(t, ok)
+	assert.Zero(t, wait)
+}
+
+func TestArticleUpdateChecker_HonorsCrawlDelay(t *testing.T) {
+	repo, _ := setupCheckerRepo(t)
+	logger := newTestLogger()
+	now := time.Now().UTC()
+
+	var mu sync.Mutex
+	var gets []time.Time
+	headHits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			_, _ = w.Write([]byte("User-agent: *\nCrawl-delay: 0.1\n"))
+		default:
+			mu.Lock()
+			defer mu.Unlock()
+			if r.Method == http.MethodHead {
+				headHits++
+				return
+			}
+			gets = append(gets, time.Now())
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<p>updated</p>"))
+		}
+	}))
+	defer srv.Close()
+
+	httpClient := httpclient.New(httpclient.Options{Timeout: time.Second, UserAgent: "testrunner"})
+	checker := NewArticleUpdateChecker(repo, logger, httpClient, NewRobotsClient(httpClient, time.Hour, logger), ArticleUpdateConfig{
+		UserAgent:       "testrunner",
+		MaxContentBytes: 1024,
+		RespectRobots:   true,
+	})
+
+	for _, path := range []string{"/a", "/b"} {
+		article := &models.Article{FeedID: 1, Title: path, URL: srv.URL + path, PublishedAt: now}
+		_, err := repo.Create(context.Background(), article)
+		require.NoError(t, err)
+		require.NoError(t, checker.HandleEvent(context.Background(), events.ArticleCheckEvent{
+			ArticleID: article.ID,
+			FeedID:    article.FeedID,
+			URL:       article.URL,
+			RequestID: "test",
+		}))
+	}
+
+	assert.Zero(t, headHits, "a host with a Crawl-delay gets a single GET per check")
+	require.Len(t, gets, 2)
+	assert.GreaterOrEqual(t, gets[1].Sub(gets[0]), 90*time.Millisecond)
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/policy"
+)
+
+// crawlPolicyTTL is how long a feed's evaluated budget is reused, so charging a request
+// does not look the feed up every time
+const crawlPolicyTTL = 5 * time.Minute
+
+// PolicyFeedLookup loads the feeds whose policy is evaluated
+type PolicyFeedLookup interface {
+	GetByID(ctx context.Context, id uint) (*models.Feed, error)
+}
+
+// PolicyCrawlLimits takes each feed's crawl budget from the policy engine
+type PolicyCrawlLimits struct {
+	engine *policy.Engine
+	feeds  PolicyFeedLookup
+	now    func() time.Time
+
+	mu     sync.Mutex
+	cached map[uint]cachedCrawlLimit
+}
+
+type cachedCrawlLimit struct {
+	daily   int
+	expires time.Time
+}
+
+func NewPolicyCrawlLimits(engine *policy.Engine, feeds PolicyFeedLookup) *PolicyCrawlLimits {
+	return &PolicyCrawlLimits{engine: engine, feeds: feeds, now: time.Now, cached: make(map[uint]cachedCrawlLimit)}
+}
+
+// DailyRequests evaluates the policy of the feed. Without rules on crawl budgets every
+// feed gets the default and no feed is looked up.
+func (l *PolicyCrawlLimits) DailyRequests(ctx context.Context, feedID uint) (int, error) {
+	if !l.engine.Overrides(policy.SettingCrawlDailyRequests) {
+		return l.engine.Defaults().CrawlDailyRequests, nil
+	}
+
+	now := l.now()
+	l.mu.Lock()
+	cached, ok := l.cached[feedID]
+	l.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.daily, nil
+	}
+
+	feed, err := l.feeds.GetByID(ctx, feedID)
+	if err != nil {
+		return 0, err
+	}
+	daily := l.engine.Evaluate(policy.FeedSubject(feed)).Policy.CrawlDailyRequests
+
+	l.mu.Lock()
+	for id, entry := range l.cached {
+		if !now.Before(entry.expires) {
+			delete(l.cached, id)
+		}
+	}
+	l.cached[feedID] = cachedCrawlLimit{daily: daily, expires: now.Add(crawlPolicyTTL)}
+	l.mu.Unlock()
+	return daily, nil
+}
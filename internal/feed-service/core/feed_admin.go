@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// bulkBatchSize is how many feeds a bulk action loads, updates and queues at a time
+const bulkBatchSize = 500
+
+// feedFetchBatchPublisher is implemented by producers that publish many fetch events in
+// one write, such as events.KafkaProducer
+type feedFetchBatchPublisher interface {
+	PublishFeedFetches(ctx context.Context, feedIDs []uint) error
+}
+
+// CountFeeds counts the feeds matching filter
+func (s *FeedService) CountFeeds(ctx context.Context, filter repository.FeedListFilter) (int64, error) {
+	count, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to count feeds", "error", err.Error())
+		return 0, ierr.NewDatabaseError(fmt.Errorf("failed to count feeds: %w", err))
+	}
+	return count, nil
+}
+
+// BulkUpdateFeeds applies an administrator's action to every feed matching filter. The
+// feeds are paged through by ID and updated one batch at a time, so the action costs a
+// few statements per batch however many feeds match. Refetched feeds are queued for a
+// fetch, and so are reactivated ones when a producer is configured. The filter must select something: an action on every feed of an
+// instance is refused.
+func (s *FeedService) BulkUpdateFeeds(ctx context.Context, filter repository.FeedListFilter, update models.FeedBulkUpdate) (*models.FeedBulkResult, error) {
+	log := logger.FromContext(ctx)
+
+	if _, err := models.ParseFeedBulkAction(string(update.Action)); err != nil {
+		return nil, ierr.NewValidationError(err.Error())
+	}
+	if !filter.Selective() {
+		return nil, ierr.NewValidationError("a bulk action needs at least one filter")
+	}
+	if update.Action == models.FeedBulkSetTier {
+		tier, err := models.ParseFeedTier(string(update.Tier))
+		if err != nil {
+			return nil, ierr.NewValidationError(err.Error())
+		}
+		update.Tier = tier
+	}
+	if update.Action == models.FeedBulkRefetch && s.producer == nil && !update.DryRun {
+		return nil, ierr.NewTaskQueueError(errors.New("no feed fetch producer configured"))
+	}
+
+	result := &models.FeedBulkResult{Action: update.Action, DryRun: update.DryRun}
+	var afterID uint
+	for {
+		feeds, err := s.repo.ListPage(ctx, filter, afterID, bulkBatchSize)
+		if err != nil {
+			log.Error("failed to list feeds for bulk action", "action", update.Action, "error", err.Error())
+			return result, ierr.NewDatabaseError(fmt.Errorf("failed to list feeds: %w", err))
+		}
+		if len(feeds) == 0 {
+			break
+		}
+		result.Matched += int64(len(feeds))
+		afterID = feeds[len(feeds)-1].ID
+
+		if !update.DryRun {
+			if err := s.applyBulkAction(ctx, feeds, update, result); err != nil {
+				log.Error("bulk action failed", "action", update.Action, "after_feed_id", feeds[0].ID, "error", err.Error())
+				return result, err
+			}
+		}
+		if len(feeds) < bulkBatchSize {
+			break
+		}
+	}
+
+	log.Info("applied bulk feed action",
+		"action", update.Action,
+		"tier", update.Tier,
+		"dry_run", update.DryRun,
+		"matched", result.Matched,
+		"updated", result.Updated,
+		"fetches_queued", result.FetchesQueued,
+	)
+	return result, nil
+}
+
+// applyBulkAction applies the action to one batch of feeds and adds up what it did
+func (s *FeedService) applyBulkAction(ctx context.Context, feeds []*models.Feed, update models.FeedBulkUpdate, result *models.FeedBulkResult) error {
+	ids := make([]uint, 0, len(feeds))
+	for _, feed := range feeds {
+		// a suspended feed is only fetched again once reactivated
+		if update.Action == models.FeedBulkRefetch && feed.Status == models.FeedStatusSuspended {
+			continue
+		}
+		ids = append(ids, feed.ID)
+	}
+
+	var (
+		updated int64
+		err     error
+	)
+	switch update.Action {
+	case models.FeedBulkReactivate:
+		updated, err = s.repo.ReactivateFeeds(ctx, ids)
+	case models.FeedBulkSuspend:
+		updated, err = s.repo.SuspendFeeds(ctx, ids)
+	case models.FeedBulkSetTier:
+		updated, err = s.repo.SetFetchTier(ctx, ids, update.Tier)
+	}
+	if err != nil {
+		return ierr.NewDatabaseError(fmt.Errorf("failed to %s feeds: %w", update.Action, err))
+	}
+	result.Updated += updated
+
+	if s.producer != nil && (update.Action == models.FeedBulkReactivate || update.Action == models.FeedBulkRefetch) {
+		queued, err := s.publishFeedFetches(ctx, ids)
+		result.FetchesQueued += queued
+		if err != nil {
+			return ierr.NewTaskQueueError(fmt.Errorf("failed to queue feed fetches: %w", err))
+		}
+	}
+	return nil
+}
+
+// publishFeedFetches queues a fetch of every feed, in one write when the producer
+// supports it. It returns how many fetches were queued.
+func (s *FeedService) publishFeedFetches(ctx context.Context, feedIDs []uint) (int64, error) {
+	if len(feedIDs) == 0 {
+		return 0, nil
+	}
+	if batch, ok := s.producer.(feedFetchBatchPublisher); ok {
+		if err := batch.PublishFeedFetches(ctx, feedIDs); err != nil {
+			return 0, err
+		}
+		return int64(len(feedIDs)), nil
+	}
+	for i, feedID := range feedIDs {
+		if err := s.producer.PublishFeedFetch(ctx, feedID); err != nil {
+			return int64(i), err
+		}
+	}
+	return int64(len(feedIDs)), nil
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+// batchProducer records the feed fetches queued in one write
+type batchProducer struct {
+	writes  int
+	fetched []uint
+}
+
+func (p *batchProducer) PublishFeedFetch(ctx context.Context, feedID uint) error {
+	return p.PublishFeedFetches(ctx, []uint{feedID})
+}
+
+func (p *batchProducer) PublishFeedFetches(ctx context.Context, feedIDs []uint) error {
+	p.writes++
+	p.fetched = append(p.fetched, feedIDs...)
+	return nil
+}
+
+func TestBulkUpdateFeeds(t *testing.T) {
+	service, db := setupFeedService(t)
+	require.NoError(t, db.AutoMigrate(&models.Notification{}))
+	producer := &batchProducer{}
+	service.producer = producer
+	ctx := context.Background()
+
+	unavailable := "http error: 503 Service Unavailable"
+	var ids []uint
+	for _, feed := range []*models.Feed{
+		{URL: "https://example.com/a.xml", Status: models.FeedStatusError, LastFetchError: &unavailable},
+		{URL: "https://example.com/b.xml", Status: models.FeedStatusError, LastFetchError: &unavailable},
+		{URL: "https://example.com/c.xml", Status: models.FeedStatusActive},
+	} {
+		require.NoError(t, db.Create(feed).Error)
+		ids = append(ids, feed.ID)
+	}
+	failing := repository.FeedListFilter{ErrorContains: "503"}
+
+	_, err := service.BulkUpdateFeeds(ctx, repository.FeedListFilter{}, models.FeedBulkUpdate{Action: models.FeedBulkSuspend})
+	assert.True(t, ierr.IsValidationError(err), "an action on every feed is refused")
+	_, err = service.BulkUpdateFeeds(ctx, failing, models.FeedBulkUpdate{Action: models.FeedBulkSetTier, Tier: "urgent"})
+	assert.True(t, ierr.IsValidationError(err))
+
+	result, err := service.BulkUpdateFeeds(ctx, failing, models.FeedBulkUpdate{Action: models.FeedBulkSuspend, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, &models.FeedBulkResult{Action: models.FeedBulkSuspend, DryRun: true, Matched: 2}, result)
+
+	result, err = service.BulkUpdateFeeds(ctx, failing, models.FeedBulkUpdate{Action: models.FeedBulkSuspend})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Updated)
+
+	result, err = service.BulkUpdateFeeds(ctx, repository.FeedListFilter{IDs: ids}, models.FeedBulkUpdate{Action: models.FeedBulkRefetch})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), result.Matched)
+	assert.Equal(t, int64(1), result.FetchesQueued, "suspended feeds are not refetched")
+	assert.Equal(t, []uint{ids[2]}, producer.fetched)
+
+	producer.fetched = nil
+	result, err = service.BulkUpdateFeeds(ctx, repository.FeedListFilter{Status: models.FeedStatusSuspended}, models.FeedBulkUpdate{Action: models.FeedBulkReactivate})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Updated)
+	assert.Equal(t, int64(2), result.FetchesQueued)
+	assert.Equal(t, []uint{ids[0], ids[1]}, producer.fetched)
+	assert.Equal(t, 2, producer.writes, "each batch is queued in one write")
+
+	result, err = service.BulkUpdateFeeds(ctx, repository.FeedListFilter{IDs: ids[:1]}, models.FeedBulkUpdate{Action: models.FeedBulkSetTier, Tier: models.FeedTierLow})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Updated)
+	var feed models.Feed
+	require.NoError(t, db.First(&feed, ids[0]).Error)
+	assert.Equal(t, models.FeedTierLow, feed.FetchTier)
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// DefaultFeedFailureThreshold is how many fetches in a row must fail before a feed is set
+// to error, unless configured otherwise
+const DefaultFeedFailureThreshold = 5
+
+// SetFailureThreshold sets the streak of failed fetches at which feeds are set to error,
+// as reported by GetFeedHealth; the feed fetcher applies it
+func (s *FeedService) SetFailureThreshold(threshold int) {
+	s.failureThreshold = threshold
+}
+
+// GetFeedHealth reports how fetching a feed the user subscribes to is going
+func (s *FeedService) GetFeedHealth(ctx context.Context, userID, feedID uint) (*models.FeedHealth, error) {
+	log := logger.FromContext(ctx)
+
+	isSubscribed, err := s.repo.IsUserSubscribed(ctx, userID, feedID)
+	if err != nil {
+		log.Error("failed to check subscription status", "user_id", userID, "feed_id", feedID, "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to check subscription status for user %d and feed %d: %w", userID, feedID, err))
+	}
+	if !isSubscribed {
+		return nil, fmt.Errorf("user %d not subscribed to feed %d: %w", userID, feedID, ierr.ErrNotSubscribed)
+	}
+
+	feed, err := s.repo.GetByID(ctx, feedID)
+	if err != nil {
+		log.Error("failed to get feed", "feed_id", feedID, "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to get feed %d: %w", feedID, err))
+	}
+
+	return &models.FeedHealth{
+		FeedID:              feed.ID,
+		Status:              feed.Status,
+		LastFetchedAt:       feed.LastFetchedAt,
+		LastError:           feed.LastFetchError,
+		LastErrorAt:         feed.LastFetchErrorAt,
+		ConsecutiveFailures: feed.ConsecutiveFailures,
+		FailureThreshold:    s.failureThreshold,
+		DatesUnreliable:     feed.DatesUnreliable,
+	}, nil
+}
@@ -0,0 +1,32 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// FeedListCache drops the feed lists the api-service caches per user, so subscribers see
+// a feed's new title without waiting for their cached list to expire
+type FeedListCache struct {
+	client redis.Cmdable
+}
+
+func NewFeedListCache(client redis.Cmdable) *FeedListCache {
+	return &FeedListCache{client: client}
+}
+
+// Invalidate deletes the cached feed lists of the users
+func (c *FeedListCache) Invalidate(ctx context.Context, userIDs []uint) error {
+	if c == nil || len(userIDs) == 0 {
+		return nil
+	}
+	keys := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		keys[i] = fmt.Sprintf(models.UserFeedsCacheKeyPattern, userID)
+	}
+	return c.client.Del(ctx, keys...).Err()
+}
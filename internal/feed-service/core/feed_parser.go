@@ -1,13 +1,18 @@
 package core
 
 import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
-	"fmt"
-	"io"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/mmcdole/gofeed"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
 )
 
 const (
@@ -15,85 +20,130 @@ const (
 	defaultFeedHTTPTimeout = 15 * time.Second
 )
 
-var errFeedBodyTooLarge = errors.New("feed body exceeds configured limit")
-
-func newFeedParser() *gofeed.Parser {
-	parser := gofeed.NewParser()
-	parser.Client = &http.Client{
-		Timeout:   defaultFeedHTTPTimeout,
-		Transport: &limitedBodyTransport{limit: maxFeedDownloadBytes},
+// IsFeedGoneError reports whether a fetch failed because the source no longer exists (HTTP 404/410)
+func IsFeedGoneError(err error) bool {
+	var httpErr gofeed.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusNotFound || httpErr.StatusCode == http.StatusGone
 	}
-	return parser
+	return false
 }
 
-type limitedBodyTransport struct {
-	base  http.RoundTripper
-	limit int64
+// fetchError wraps a failed fetch or parse in the ierr error naming what went wrong at
+// the feed's server, so callers and logs can tell its outages apart from our own bugs
+func fetchError(err error) *ierr.AppError {
+	var httpErr gofeed.HTTPError
+	var netErr net.Error
+	var xmlErr *xml.SyntaxError
+	var jsonErr *json.SyntaxError
+	switch {
+	case errors.As(err, &httpErr):
+		return upstreamStatusError(httpErr.StatusCode).WithCause(err)
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ierr.ErrUpstreamTimeout.WithCause(err)
+	case errors.Is(err, gofeed.ErrFeedTypeNotDetected), errors.As(err, &xmlErr), errors.As(err, &jsonErr):
+		return ierr.ErrUnsupportedFormat.WithCause(err)
+	default:
+		return ierr.ErrFeedFetchFailed.WithCause(err)
+	}
 }
 
-func (t *limitedBodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	transport := t.base
-	if transport == nil {
-		transport = http.DefaultTransport
+// upstreamStatusError names the failure behind an unsuccessful HTTP status
+func upstreamStatusError(status int) *ierr.AppError {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ierr.ErrUpstreamForbidden
+	case http.StatusTooManyRequests:
+		return ierr.ErrTooManyRequests
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return ierr.ErrUpstreamTimeout
+	default:
+		return ierr.ErrFeedFetchFailed
 	}
+}
 
-	resp, err := transport.RoundTrip(req)
-	if err != nil {
-		return nil, err
+// maxFetchErrorLength bounds the error text stored on a feed
+const maxFetchErrorLength = 500
+
+// FetchErrorSummary reduces a fetch error to its root cause (e.g. "http error: 503 Service
+// Unavailable" or "connection refused") so failures of different feeds can be grouped.
+func FetchErrorSummary(err error) string {
+	if err == nil {
+		return ""
 	}
 
-	if t.limit <= 0 {
-		return resp, nil
+	var httpErr gofeed.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Error()
 	}
 
-	if resp.ContentLength > 0 && resp.ContentLength > t.limit {
-		_ = resp.Body.Close()
-		return nil, fmt.Errorf("%w: content-length %d exceeds %d", errFeedBodyTooLarge, resp.ContentLength, t.limit)
+	root := err
+	for {
+		next := errors.Unwrap(root)
+		if next == nil {
+			break
+		}
+		root = next
 	}
 
-	resp.Body = newLimitedReadCloser(resp.Body, t.limit)
-	return resp, nil
+	message := root.Error()
+	if len(message) > maxFetchErrorLength {
+		message = message[:maxFetchErrorLength]
+	}
+	return message
 }
 
-type limitedReadCloser struct {
-	reader    io.ReadCloser
-	remaining int64
-	err       error
+// feedResponse is the outcome of a conditional feed fetch
+type feedResponse struct {
+	feed        *gofeed.Feed // nil when notModified
+	notModified bool
+	// etag and lastModified are the validators to send on the next fetch
+	etag         *string
+	lastModified *string
 }
 
-func newLimitedReadCloser(rc io.ReadCloser, limit int64) *limitedReadCloser {
-	return &limitedReadCloser{
-		reader:    rc,
-		remaining: limit,
+// fetchFeed downloads and parses a feed like gofeed's ParseURLWithContext, sending the
+// validators stored with the feed so an unchanged feed costs the origin a 304 and no body.
+// Statuses other than 2xx and 304 come back as gofeed.HTTPError.
+func fetchFeed(ctx context.Context, parser *gofeed.Parser, feed *models.Feed) (*feedResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed.URL, nil)
+	if err != nil {
+		return nil, err
 	}
-}
-
-func (l *limitedReadCloser) Read(p []byte) (int, error) {
-	if l.err != nil {
-		return 0, l.err
+	req.Header.Set("User-Agent", parser.UserAgent)
+	if feed.HTTPETag != nil && trim(*feed.HTTPETag) != "" {
+		req.Header.Set("If-None-Match", trim(*feed.HTTPETag))
 	}
-	if l.remaining <= 0 {
-		l.err = errFeedBodyTooLarge
-		return 0, l.err
+	if feed.HTTPLastModified != nil {
+		if httpDate := toHTTPDate(trim(*feed.HTTPLastModified)); httpDate != "" {
+			req.Header.Set("If-Modified-Since", httpDate)
+		}
 	}
 
-	if int64(len(p)) > l.remaining+1 {
-		p = p[:l.remaining+1]
+	client := parser.Client
+	if client == nil {
+		client = http.DefaultClient
 	}
-
-	n, err := l.reader.Read(p)
-	if int64(n) <= l.remaining {
-		l.remaining -= int64(n)
-		l.err = err
-		return n, err
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	n = int(l.remaining)
-	l.remaining = 0
-	l.err = errFeedBodyTooLarge
-	return n, l.err
-}
+	if resp.StatusCode == http.StatusNotModified {
+		return &feedResponse{notModified: true, etag: feed.HTTPETag, lastModified: feed.HTTPLastModified}, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, gofeed.HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
 
-func (l *limitedReadCloser) Close() error {
-	return l.reader.Close()
+	parsed, err := parser.Parse(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &feedResponse{
+		feed:         parsed,
+		etag:         optionalString(trim(resp.Header.Get("ETag"))),
+		lastModified: optionalString(normalizeHTTPDate(trim(resp.Header.Get("Last-Modified")))),
+	}, nil
 }
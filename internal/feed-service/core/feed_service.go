@@ -2,15 +2,22 @@ package core
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mmcdole/gofeed"
+	"gorm.io/gorm"
 
 	"github.com/Fancu1/phoenix-rss/internal/events"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
+	"github.com/Fancu1/phoenix-rss/internal/policy"
+	"github.com/Fancu1/phoenix-rss/pkg/dbtx"
 	"github.com/Fancu1/phoenix-rss/pkg/ierr"
 	"github.com/Fancu1/phoenix-rss/pkg/logger"
 )
@@ -26,12 +33,20 @@ type BatchSubscribeResult struct {
 type FeedServiceInterface interface {
 	AddFeedByURL(ctx context.Context, url string) (*models.Feed, error)
 	ListAllFeeds(ctx context.Context) ([]*models.Feed, error)
+	ListSchedulableFeeds(ctx context.Context) ([]*SchedulableFeed, error)
+	ListFeedsPage(ctx context.Context, filter repository.FeedListFilter, pageSize int, pageToken string) ([]*SchedulableFeed, string, error)
+	FetchBacklog(ctx context.Context, dueBefore, lowTierDueBefore *time.Time) (*repository.FetchBacklog, error)
+	CountFeeds(ctx context.Context, filter repository.FeedListFilter) (int64, error)
+	BulkUpdateFeeds(ctx context.Context, filter repository.FeedListFilter, update models.FeedBulkUpdate) (*models.FeedBulkResult, error)
 	SubscribeToFeed(ctx context.Context, userID uint, url string) (*models.Feed, error)
 	BatchSubscribeToFeeds(ctx context.Context, userID uint, urls []string) ([]BatchSubscribeResult, error)
+	ExistsByURLs(ctx context.Context, urls []string) (map[string]bool, error)
 	ListUserFeeds(ctx context.Context, userID uint) ([]*models.UserFeed, error)
 	UnsubscribeFromFeed(ctx context.Context, userID, feedID uint) error
 	IsUserSubscribed(ctx context.Context, userID, feedID uint) (bool, error)
-	UpdateFeedCustomTitle(ctx context.Context, userID, feedID uint, customTitle *string) (*models.UserFeed, error)
+	UpdateSubscription(ctx context.Context, userID, feedID uint, update models.SubscriptionUpdate) (*models.UserFeed, error)
+	GetFeedHealth(ctx context.Context, userID, feedID uint) (*models.FeedHealth, error)
+	DeleteFeed(ctx context.Context, feedID uint, retention models.FeedRetention) (*models.FeedDeletion, error)
 }
 
 type FeedService struct {
@@ -39,18 +54,80 @@ type FeedService struct {
 	repo     *repository.FeedRepository
 	producer events.Producer
 	logger   *slog.Logger
+	secrets  models.SecretEncrypter
+	uow      *dbtx.UnitOfWork
+	// deletedFeedRetention applies to DeleteFeed calls that do not name a policy
+	deletedFeedRetention models.FeedRetention
+	// failureThreshold is reported with a feed's health
+	failureThreshold int
+	// policy decides each user's subscription quota; nil is unlimited
+	policy *policy.Engine
+	// outbox stores the first fetch of a new feed along with it; nil publishes it after
+	outbox *EventOutbox
 }
 
 // NewFeedService creates a FeedService. Producer can be nil (sync mode).
 func NewFeedService(repo *repository.FeedRepository, logger *slog.Logger, producer events.Producer) *FeedService {
 	return &FeedService{
-		parser:   gofeed.NewParser(),
-		repo:     repo,
-		producer: producer,
-		logger:   logger,
+		parser:           NewHTTPClientFactory(FetchIdentity{}).FeedParser(),
+		repo:             repo,
+		producer:         producer,
+		logger:           logger,
+		failureThreshold: DefaultFeedFailureThreshold,
 	}
 }
 
+// SetHTTPClientFactory makes feed fetches use the factory's clients and identity
+func (s *FeedService) SetHTTPClientFactory(factory *HTTPClientFactory) {
+	s.parser = factory.FeedParser()
+}
+
+// SetDeletedFeedRetention sets the policy for deleted feeds' articles when the caller
+// does not choose one; the default keeps them archived
+func (s *FeedService) SetDeletedFeedRetention(retention models.FeedRetention) {
+	s.deletedFeedRetention = retention
+}
+
+// SetUnitOfWork makes writes that span several steps, such as creating a feed along with
+// its first subscription, run in one transaction
+func (s *FeedService) SetUnitOfWork(uow *dbtx.UnitOfWork) {
+	s.uow = uow
+}
+
+// SetOutbox stores feed fetch events in the outbox instead of publishing them. The first
+// fetch of a new feed is stored in the transaction that creates it.
+func (s *FeedService) SetOutbox(outbox *EventOutbox) {
+	s.outbox = outbox
+	s.producer = outbox
+}
+
+// SetPolicy makes subscribing respect the subscription quota the policy gives each user
+func (s *FeedService) SetPolicy(engine *policy.Engine) {
+	s.policy = engine
+}
+
+// subscriptionsLeft returns how many more feeds the user may subscribe to, or -1 without a
+// quota
+func (s *FeedService) subscriptionsLeft(ctx context.Context, repo *repository.FeedRepository, userID uint) (int64, error) {
+	if s.policy == nil {
+		return -1, nil
+	}
+	limit := int64(s.policy.Evaluate(policy.Subject{UserID: userID}).Policy.MaxSubscriptions)
+	if limit == 0 {
+		return -1, nil
+	}
+	count, err := repo.CountSubscriptions(ctx, userID)
+	if err != nil {
+		return 0, ierr.NewDatabaseError(fmt.Errorf("failed to count subscriptions of user %d: %w", userID, err))
+	}
+	return max(limit-count, 0), nil
+}
+
+// SetSecretEncrypter enables custom fetch headers on subscriptions, stored encrypted
+func (s *FeedService) SetSecretEncrypter(encrypter models.SecretEncrypter) {
+	s.secrets = encrypter
+}
+
 func (s *FeedService) AddFeedByURL(ctx context.Context, url string) (*models.Feed, error) {
 	log := logger.FromContext(ctx)
 
@@ -58,8 +135,9 @@ func (s *FeedService) AddFeedByURL(ctx context.Context, url string) (*models.Fee
 
 	feed, err := s.parser.ParseURLWithContext(url, ctx)
 	if err != nil {
-		log.Error("failed to parse feed", "url", url, "error", err.Error())
-		return nil, fmt.Errorf("failed to parse feed from URL '%s': %w", url, ierr.ErrFeedFetchFailed.WithCause(err))
+		fetchErr := fetchError(err)
+		log.Error("failed to parse feed", "url", url, "error_code", fetchErr.Code, "error", err.Error())
+		return nil, fmt.Errorf("failed to parse feed from URL '%s': %w", url, fetchErr)
 	}
 
 	newFeed := &models.Feed{
@@ -97,58 +175,190 @@ func (s *FeedService) ListAllFeeds(ctx context.Context) ([]*models.Feed, error)
 	return feeds, nil
 }
 
-func (s *FeedService) SubscribeToFeed(ctx context.Context, userID uint, url string) (*models.Feed, error) {
+// SchedulableFeed is a feed due for periodic refresh, with the owner the scheduler uses
+// to share each refresh cycle fairly between users
+type SchedulableFeed struct {
+	*models.Feed
+	OwnerUserID uint // longest-standing subscriber, 0 for none
+}
+
+// ListSchedulableFeeds returns feeds that should still be refreshed, excluding archived
+// and suspended ones
+func (s *FeedService) ListSchedulableFeeds(ctx context.Context) ([]*SchedulableFeed, error) {
 	log := logger.FromContext(ctx)
 
-	log.Info("attempting to subscribe user to feed", "user_id", userID, "url", url)
+	feeds, err := s.repo.ListSchedulable(ctx)
+	if err != nil {
+		log.Error("failed to list schedulable feeds", "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to list schedulable feeds: %w", err))
+	}
 
-	existingFeed, err := s.repo.GetByURL(ctx, url)
-	if err != nil && err.Error() != "record not found" {
-		log.Error("failed to check for existing feed", "url", url, "error", err.Error())
-		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to check existing feed for URL '%s': %w", url, err))
+	owners, err := s.repo.Owners(ctx)
+	if err != nil {
+		log.Error("failed to load feed owners", "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to load feed owners: %w", err))
 	}
 
-	var feed *models.Feed
-	var needFetch bool
+	result := make([]*SchedulableFeed, len(feeds))
+	for i, feed := range feeds {
+		result[i] = &SchedulableFeed{Feed: feed, OwnerUserID: owners[feed.ID]}
+	}
 
-	if existingFeed != nil {
-		log.Info("found existing feed", "feed_id", existingFeed.ID, "url", url)
-		feed = existingFeed
-	} else {
-		log.Info("feed does not exist, creating new feed record", "url", url)
-		feed, err = s.createFeed(ctx, url)
+	log.Info("successfully listed schedulable feeds", "count", len(result))
+	return result, nil
+}
+
+// FetchBacklog counts the feeds due for a fetch and finds the latest fetch of any feed
+func (s *FeedService) FetchBacklog(ctx context.Context, dueBefore, lowTierDueBefore *time.Time) (*repository.FetchBacklog, error) {
+	backlog, err := s.repo.FetchBacklog(ctx, dueBefore, lowTierDueBefore)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to get fetch backlog", "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to get fetch backlog: %w", err))
+	}
+	return backlog, nil
+}
+
+// ListFeedsPage returns one page of the feeds matching filter, in ID order, with their
+// owners and the token of the next page ("" on the last page)
+func (s *FeedService) ListFeedsPage(ctx context.Context, filter repository.FeedListFilter, pageSize int, pageToken string) ([]*SchedulableFeed, string, error) {
+	log := logger.FromContext(ctx)
+
+	if pageSize <= 0 {
+		return nil, "", fmt.Errorf("pageSize must be greater than zero")
+	}
+
+	var afterID uint
+	if strings.TrimSpace(pageToken) != "" {
+		parsed, err := decodeFeedCursor(pageToken)
 		if err != nil {
-			log.Error("failed to create feed", "url", url, "error", err.Error())
-			return nil, err
+			return nil, "", ierr.NewValidationError(fmt.Sprintf("invalid page token: %v", err))
 		}
-		needFetch = true
+		afterID = parsed
 	}
 
-	isSubscribed, err := s.repo.IsUserSubscribed(ctx, userID, feed.ID)
+	feeds, err := s.repo.ListPage(ctx, filter, afterID, pageSize)
 	if err != nil {
-		log.Error("failed to check subscription status", "user_id", userID, "feed_id", feed.ID, "error", err.Error())
-		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to check subscription status for user %d and feed %d: %w", userID, feed.ID, err))
+		log.Error("failed to list feeds page", "error", err.Error())
+		return nil, "", ierr.NewDatabaseError(fmt.Errorf("failed to list feeds page: %w", err))
+	}
+	if len(feeds) == 0 {
+		return []*SchedulableFeed{}, "", nil
 	}
 
-	if isSubscribed {
-		log.Info("user already subscribed to feed", "user_id", userID, "feed_id", feed.ID)
-		return nil, fmt.Errorf("user %d already subscribed to feed %d (%s): %w", userID, feed.ID, feed.Title, ierr.ErrAlreadySubscribed)
+	feedIDs := make([]uint, len(feeds))
+	for i, feed := range feeds {
+		feedIDs[i] = feed.ID
+	}
+	owners, err := s.repo.Owners(ctx, feedIDs...)
+	if err != nil {
+		log.Error("failed to load feed owners", "error", err.Error())
+		return nil, "", ierr.NewDatabaseError(fmt.Errorf("failed to load feed owners: %w", err))
 	}
 
-	subscription := &models.Subscription{
-		UserID: userID,
-		FeedID: feed.ID,
+	result := make([]*SchedulableFeed, len(feeds))
+	for i, feed := range feeds {
+		result[i] = &SchedulableFeed{Feed: feed, OwnerUserID: owners[feed.ID]}
 	}
 
-	log.Info("creating subscription", "user_id", userID, "feed_id", feed.ID)
+	// a short page is the last one
+	if len(feeds) < pageSize {
+		return result, "", nil
+	}
+	return result, encodeFeedCursor(feeds[len(feeds)-1].ID), nil
+}
 
-	err = s.repo.CreateSubscription(ctx, subscription)
+func encodeFeedCursor(feedID uint) string {
+	return base64.StdEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(feedID), 10)))
+}
+
+func decodeFeedCursor(token string) (uint, error) {
+	decoded, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+	feedID, err := strconv.ParseUint(string(decoded), 10, 64)
 	if err != nil {
-		log.Error("failed to create subscription", "user_id", userID, "feed_id", feed.ID, "error", err.Error())
-		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to create subscription for user %d to feed %d (%s): %w", userID, feed.ID, feed.Title, err))
+		return 0, fmt.Errorf("invalid feed id in token: %w", err)
 	}
+	return uint(feedID), nil
+}
+
+func (s *FeedService) SubscribeToFeed(ctx context.Context, userID uint, url string) (*models.Feed, error) {
+	log := logger.FromContext(ctx)
+
+	log.Info("attempting to subscribe user to feed", "user_id", userID, "url", url)
 
-	if needFetch && s.producer != nil {
+	var feed *models.Feed
+	var needFetch bool
+
+	// A new feed record is only kept along with the subscription it was created for
+	err := s.uow.Do(ctx, func(tx *gorm.DB) error {
+		repo := s.repo.WithTx(tx)
+
+		existingFeed, err := repo.GetByURL(ctx, url)
+		if err != nil && err.Error() != "record not found" {
+			log.Error("failed to check for existing feed", "url", url, "error", err.Error())
+			return ierr.NewDatabaseError(fmt.Errorf("failed to check existing feed for URL '%s': %w", url, err))
+		}
+
+		if existingFeed != nil {
+			log.Info("found existing feed", "feed_id", existingFeed.ID, "url", url)
+			feed = existingFeed
+		} else {
+			log.Info("feed does not exist, creating new feed record", "url", url)
+			feed, err = s.createFeed(ctx, repo, url)
+			if err != nil {
+				log.Error("failed to create feed", "url", url, "error", err.Error())
+				return err
+			}
+			needFetch = true
+		}
+
+		isSubscribed, err := repo.IsUserSubscribed(ctx, userID, feed.ID)
+		if err != nil {
+			log.Error("failed to check subscription status", "user_id", userID, "feed_id", feed.ID, "error", err.Error())
+			return ierr.NewDatabaseError(fmt.Errorf("failed to check subscription status for user %d and feed %d: %w", userID, feed.ID, err))
+		}
+
+		if isSubscribed {
+			log.Info("user already subscribed to feed", "user_id", userID, "feed_id", feed.ID)
+			return fmt.Errorf("user %d already subscribed to feed %d (%s): %w", userID, feed.ID, feed.Title, ierr.ErrAlreadySubscribed)
+		}
+
+		left, err := s.subscriptionsLeft(ctx, repo, userID)
+		if err != nil {
+			log.Error("failed to check subscription quota", "user_id", userID, "error", err.Error())
+			return err
+		}
+		if left == 0 {
+			log.Info("user reached subscription limit", "user_id", userID, "feed_id", feed.ID)
+			return fmt.Errorf("user %d cannot subscribe to feed %d: %w", userID, feed.ID, ierr.ErrSubscriptionLimit)
+		}
+
+		subscription := &models.Subscription{
+			UserID: userID,
+			FeedID: feed.ID,
+		}
+
+		log.Info("creating subscription", "user_id", userID, "feed_id", feed.ID)
+
+		if err := repo.CreateSubscription(ctx, subscription); err != nil {
+			log.Error("failed to create subscription", "user_id", userID, "feed_id", feed.ID, "error", err.Error())
+			return ierr.NewDatabaseError(fmt.Errorf("failed to create subscription for user %d to feed %d (%s): %w", userID, feed.ID, feed.Title, err))
+		}
+		if needFetch && s.outbox != nil {
+			if err := s.outbox.WithTx(tx).PublishFeedFetch(ctx, feed.ID); err != nil {
+				log.Error("failed to store feed fetch event", "feed_id", feed.ID, "error", err.Error())
+				return ierr.NewDatabaseError(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if needFetch && s.producer != nil && s.outbox == nil {
 		if err := s.producer.PublishFeedFetch(ctx, feed.ID); err != nil {
 			log.Warn("failed to publish feed fetch event, scheduler will retry", "feed_id", feed.ID, "error", err.Error())
 		} else {
@@ -160,7 +370,7 @@ func (s *FeedService) SubscribeToFeed(ctx context.Context, userID uint, url stri
 	return feed, nil
 }
 
-func (s *FeedService) createFeed(ctx context.Context, url string) (*models.Feed, error) {
+func (s *FeedService) createFeed(ctx context.Context, repo *repository.FeedRepository, url string) (*models.Feed, error) {
 	log := logger.FromContext(ctx)
 
 	newFeed := &models.Feed{
@@ -173,7 +383,7 @@ func (s *FeedService) createFeed(ctx context.Context, url string) (*models.Feed,
 
 	log.Info("creating feed record", "url", url)
 
-	createdFeed, err := s.repo.Create(ctx, newFeed)
+	createdFeed, err := repo.Create(ctx, newFeed)
 	if err != nil {
 		log.Error("failed to create feed in database", "url", url, "error", err.Error())
 		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to create feed for URL '%s': %w", url, err))
@@ -198,9 +408,15 @@ func (s *FeedService) ListUserFeeds(ctx context.Context, userID uint) ([]*models
 	return feeds, nil
 }
 
-func (s *FeedService) UpdateFeedCustomTitle(ctx context.Context, userID, feedID uint, customTitle *string) (*models.UserFeed, error) {
+// UpdateSubscription changes the user's settings for a subscription (custom title, notes,
+// fetch headers)
+func (s *FeedService) UpdateSubscription(ctx context.Context, userID, feedID uint, update models.SubscriptionUpdate) (*models.UserFeed, error) {
 	log := logger.FromContext(ctx)
-	log.Info("updating feed custom title", "user_id", userID, "feed_id", feedID)
+	log.Info("updating subscription", "user_id", userID, "feed_id", feedID)
+
+	if err := update.Validate(); err != nil {
+		return nil, ierr.NewValidationError(err.Error())
+	}
 
 	isSubscribed, err := s.repo.IsUserSubscribed(ctx, userID, feedID)
 	if err != nil {
@@ -213,10 +429,21 @@ func (s *FeedService) UpdateFeedCustomTitle(ctx context.Context, userID, feedID
 		return nil, fmt.Errorf("user %d not subscribed to feed %d: %w", userID, feedID, ierr.ErrNotSubscribed)
 	}
 
-	err = s.repo.UpdateSubscriptionCustomTitle(ctx, userID, feedID, customTitle)
-	if err != nil {
-		log.Error("failed to update custom title", "user_id", userID, "feed_id", feedID, "error", err.Error())
-		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to update custom title for user %d and feed %d: %w", userID, feedID, err))
+	if update.FetchHeaders != nil {
+		if s.secrets == nil {
+			return nil, ierr.NewInternalError(fmt.Errorf("custom fetch headers are not configured"))
+		}
+		if err := update.SealFetchHeaders(s.secrets); err != nil {
+			log.Error("failed to seal fetch headers", "user_id", userID, "feed_id", feedID, "error", err.Error())
+			return nil, ierr.NewInternalError(err)
+		}
+	}
+
+	if !update.IsEmpty() {
+		if err := s.repo.UpdateSubscription(ctx, userID, feedID, update); err != nil {
+			log.Error("failed to update subscription", "user_id", userID, "feed_id", feedID, "error", err.Error())
+			return nil, ierr.NewDatabaseError(fmt.Errorf("failed to update subscription for user %d and feed %d: %w", userID, feedID, err))
+		}
 	}
 
 	subscription, err := s.repo.GetSubscription(ctx, userID, feedID)
@@ -225,11 +452,8 @@ func (s *FeedService) UpdateFeedCustomTitle(ctx context.Context, userID, feedID
 		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to get subscription for user %d and feed %d: %w", userID, feedID, err))
 	}
 
-	log.Info("successfully updated feed custom title", "user_id", userID, "feed_id", feedID)
-	return &models.UserFeed{
-		Feed:        subscription.Feed,
-		CustomTitle: subscription.CustomTitle,
-	}, nil
+	log.Info("successfully updated subscription", "user_id", userID, "feed_id", feedID)
+	return subscription.UserFeed(), nil
 }
 
 func (s *FeedService) UnsubscribeFromFeed(ctx context.Context, userID, feedID uint) error {
@@ -258,6 +482,49 @@ func (s *FeedService) UnsubscribeFromFeed(ctx context.Context, userID, feedID ui
 	return nil
 }
 
+// DeleteFeed removes a feed on an administrator's behalf: every subscriber is notified and
+// unsubscribed, and the feed is archived with its articles or purged along with them,
+// all in one transaction. An empty retention uses the configured default.
+func (s *FeedService) DeleteFeed(ctx context.Context, feedID uint, retention models.FeedRetention) (*models.FeedDeletion, error) {
+	log := logger.FromContext(ctx)
+
+	if retention == "" {
+		retention = s.deletedFeedRetention
+	}
+	if retention == "" {
+		retention = models.FeedRetentionArchive
+	}
+	if _, err := models.ParseFeedRetention(string(retention)); err != nil {
+		return nil, ierr.NewValidationError(err.Error())
+	}
+
+	feed, err := s.repo.GetByID(ctx, feedID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ierr.ErrFeedNotFound
+	}
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to get feed %d: %w", feedID, err))
+	}
+
+	message := fmt.Sprintf("Feed %q was removed by an administrator and you have been unsubscribed.", feed.Title)
+	deletion, err := s.repo.DeleteFeed(ctx, feedID, retention, message, time.Now().UTC())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ierr.ErrFeedNotFound
+	}
+	if err != nil {
+		log.Error("failed to delete feed", "feed_id", feedID, "retention", retention, "error", err.Error())
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to delete feed %d: %w", feedID, err))
+	}
+
+	log.Info("deleted feed",
+		"feed_id", feedID,
+		"retention", retention,
+		"unsubscribed_users", len(deletion.UserIDs),
+		"articles_purged", deletion.ArticlesPurged,
+	)
+	return deletion, nil
+}
+
 // IsUserSubscribed check if a user is subscribed to a feed
 func (s *FeedService) IsUserSubscribed(ctx context.Context, userID, feedID uint) (bool, error) {
 	log := logger.FromContext(ctx)
@@ -274,6 +541,20 @@ func (s *FeedService) IsUserSubscribed(ctx context.Context, userID, feedID uint)
 	return isSubscribed, nil
 }
 
+// ExistsByURLs tells which of the URLs a feed already exists for. Subscribing to those
+// needs no fetch before their articles show.
+func (s *FeedService) ExistsByURLs(ctx context.Context, urls []string) (map[string]bool, error) {
+	existing, err := s.repo.ExistingURLs(ctx, urls)
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to look up feed urls: %w", err))
+	}
+	exists := make(map[string]bool, len(existing))
+	for _, url := range existing {
+		exists[url] = true
+	}
+	return exists, nil
+}
+
 // BatchSubscribeToFeeds subscribes a user to multiple feeds in a single batch operation.
 func (s *FeedService) BatchSubscribeToFeeds(ctx context.Context, userID uint, urls []string) ([]BatchSubscribeResult, error) {
 	log := logger.FromContext(ctx)
@@ -353,6 +634,12 @@ func (s *FeedService) BatchSubscribeToFeeds(ctx context.Context, userID uint, ur
 		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to query subscriptions: %w", err))
 	}
 
+	left, err := s.subscriptionsLeft(ctx, s.repo, userID)
+	if err != nil {
+		log.Error("failed to check subscription quota", "user_id", userID, "error", err.Error())
+		return nil, err
+	}
+
 	// Create subscriptions and build results
 	newSubscriptions := make([]*models.Subscription, 0)
 	feedsNeedingFetch := make([]uint, 0)
@@ -373,6 +660,14 @@ func (s *FeedService) BatchSubscribeToFeeds(ctx context.Context, userID uint, ur
 			continue
 		}
 
+		// feeds past the quota are reported, the ones before them still subscribed
+		if left >= 0 && int64(len(newSubscriptions)) >= left {
+			for _, idx := range urlToIndex[url] {
+				results[idx] = BatchSubscribeResult{URL: url, Success: false, Error: "subscription limit reached", Feed: feed}
+			}
+			continue
+		}
+
 		newSubscriptions = append(newSubscriptions, &models.Subscription{
 			UserID: userID,
 			FeedID: feed.ID,
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
+	"github.com/Fancu1/phoenix-rss/internal/policy"
+	"github.com/Fancu1/phoenix-rss/pkg/dbtx"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+func setupFeedService(t *testing.T) (*FeedService, *gorm.DB) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Feed{}, &models.Subscription{}))
+
+	service := NewFeedService(repository.NewFeedRepository(db), logger.New(slog.LevelError), nil)
+	service.SetUnitOfWork(dbtx.NewUnitOfWork(db))
+	return service, db
+}
+
+func TestSubscribeToFeed(t *testing.T) {
+	service, db := setupFeedService(t)
+	ctx := context.Background()
+
+	feed, err := service.SubscribeToFeed(ctx, 1, "https://example.com/feed.xml")
+	require.NoError(t, err)
+	require.NotZero(t, feed.ID)
+
+	again, err := service.SubscribeToFeed(ctx, 2, "https://example.com/feed.xml")
+	require.NoError(t, err)
+	require.Equal(t, feed.ID, again.ID, "a second subscriber shares the feed")
+
+	_, err = service.SubscribeToFeed(ctx, 1, "https://example.com/feed.xml")
+	require.ErrorIs(t, err, ierr.ErrAlreadySubscribed)
+
+	var subscriptions int64
+	require.NoError(t, db.Model(&models.Subscription{}).Count(&subscriptions).Error)
+	require.Equal(t, int64(2), subscriptions)
+}
+
+func TestSubscribeToFeed_RollsBackNewFeed(t *testing.T) {
+	service, db := setupFeedService(t)
+	ctx := context.Background()
+	require.NoError(t, db.Migrator().DropTable(&models.Subscription{}))
+
+	_, err := service.SubscribeToFeed(ctx, 1, "https://example.com/feed.xml")
+	require.Error(t, err)
+
+	var feeds int64
+	require.NoError(t, db.Model(&models.Feed{}).Count(&feeds).Error)
+	require.Zero(t, feeds, "the feed created for a failed subscription must not be kept")
+}
+
+func TestSubscribeToFeed_Quota(t *testing.T) {
+	service, _ := setupFeedService(t)
+	ctx := context.Background()
+	two, unlimited := 2, 0
+	engine, err := policy.NewEngine(policy.Policy{}, policy.Document{
+		Defaults: policy.Settings{MaxSubscriptions: &two},
+		Rules: []policy.Rule{
+			{Name: "staff", Match: policy.Match{UserIDs: []uint{9}}, Set: policy.Settings{MaxSubscriptions: &unlimited}},
+		},
+	})
+	require.NoError(t, err)
+	service.SetPolicy(engine)
+
+	for _, url := range []string{"https://example.com/a.xml", "https://example.com/b.xml"} {
+		_, err := service.SubscribeToFeed(ctx, 1, url)
+		require.NoError(t, err)
+	}
+	_, err = service.SubscribeToFeed(ctx, 1, "https://example.com/c.xml")
+	require.ErrorIs(t, err, ierr.ErrSubscriptionLimit)
+	_, err = service.SubscribeToFeed(ctx, 1, "https://example.com/a.xml")
+	require.ErrorIs(t, err, ierr.ErrAlreadySubscribed, "a duplicate is reported as such, not as over the quota")
+
+	results, err := service.BatchSubscribeToFeeds(ctx, 2, []string{"https://example.com/a.xml", "https://example.com/b.xml", "https://example.com/c.xml"})
+	require.NoError(t, err)
+	require.True(t, results[0].Success)
+	require.True(t, results[1].Success)
+	require.False(t, results[2].Success)
+	require.Equal(t, "subscription limit reached", results[2].Error)
+
+	for _, url := range []string{"https://example.com/a.xml", "https://example.com/b.xml", "https://example.com/c.xml"} {
+		_, err := service.SubscribeToFeed(ctx, 9, url)
+		require.NoError(t, err, "the staff rule lifts the quota")
+	}
+}
+
+func TestGetFeedHealth(t *testing.T) {
+	service, db := setupFeedService(t)
+	service.SetFailureThreshold(3)
+	ctx := context.Background()
+
+	repo := repository.NewFeedRepository(db)
+	feed, err := repo.Create(ctx, &models.Feed{Title: "Flaky", URL: "https://example.com/flaky.xml", Status: models.FeedStatusActive})
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 1, FeedID: feed.ID}))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, repo.MarkFetched(ctx, feed.ID, now, ""))
+	_, err = repo.RecordFetchError(ctx, feed.ID, "HTTP 503", now)
+	require.NoError(t, err)
+	_, err = repo.RecordFetchError(ctx, feed.ID, "HTTP 503", now)
+	require.NoError(t, err)
+
+	health, err := service.GetFeedHealth(ctx, 1, feed.ID)
+	require.NoError(t, err)
+	require.Equal(t, feed.ID, health.FeedID)
+	require.Equal(t, models.FeedStatusActive, health.Status)
+	require.Equal(t, 2, health.ConsecutiveFailures)
+	require.Equal(t, 3, health.FailureThreshold)
+	require.Equal(t, "HTTP 503", *health.LastError)
+	require.True(t, now.Equal(*health.LastFetchedAt))
+
+	_, err = service.GetFeedHealth(ctx, 2, feed.ID)
+	require.ErrorIs(t, err, ierr.ErrNotSubscribed)
+}
@@ -0,0 +1,200 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// hostBreakerKeyPattern holds the failure state of one host
+const hostBreakerKeyPattern = "host_breaker:%s"
+
+// hostProbeTimeout is how long the probe let through after a pause holds the host, so a
+// replica that dies mid-probe does not keep it paused
+const hostProbeTimeout = time.Minute
+
+// HostBreakerStore keeps the failure state of hosts
+type HostBreakerStore interface {
+	// Allow reports whether a request to host may be made at now, and whether it is the
+	// one probe let through once a pause ended. The probe holds the host for probeTTL.
+	Allow(ctx context.Context, host string, now time.Time, probeTTL time.Duration) (allowed, probe bool, err error)
+	// Failure counts a failed request to host. The threshold-th failure in a row, or a
+	// failed probe, pauses the host until now + pause; it reports whether it did.
+	// Failures more than twice the pause apart are not added up.
+	Failure(ctx context.Context, host string, now time.Time, threshold int, pause time.Duration) (paused bool, err error)
+	// Success forgets the failures of host, unless it is paused at now, and reports
+	// whether it ended a pause
+	Success(ctx context.Context, host string, now time.Time) (recovered bool, err error)
+}
+
+// HostBreaker pauses the article checks against a host that keeps failing, e.g. answering
+// 429 or 503, for a while instead of retrying every scheduled article there. Once the
+// pause is over a single check probes the host: its success resumes the checks, its
+// failure pauses the host again. The state is shared by all feed-service replicas.
+type HostBreaker struct {
+	store     HostBreakerStore
+	threshold int
+	pause     time.Duration
+	now       func() time.Time
+}
+
+func NewHostBreaker(store HostBreakerStore, threshold int, pause time.Duration) *HostBreaker {
+	return &HostBreaker{store: store, threshold: threshold, pause: pause, now: time.Now}
+}
+
+// Allow reports whether a request to the host of rawURL may be made. Store failures are
+// logged and let the request through. A nil breaker allows everything.
+func (b *HostBreaker) Allow(ctx context.Context, rawURL string) bool {
+	if b == nil {
+		return true
+	}
+	host := requestHost(rawURL)
+	if host == "" {
+		return true
+	}
+	allowed, probe, err := b.store.Allow(ctx, host, b.now(), hostProbeTimeout)
+	if err != nil {
+		logger.FromContext(ctx).Warn("failed to check host pause, allowing request", "host", host, "error", err.Error())
+		return true
+	}
+	if probe {
+		logger.FromContext(ctx).Info("host pause over, probing host", "host", host)
+	}
+	return allowed
+}
+
+// Record counts the outcome of a request to the host of rawURL: transport errors and
+// retryable statuses (429, 5xx) are failures, any other response a success
+func (b *HostBreaker) Record(ctx context.Context, rawURL string, resp *http.Response, err error) {
+	if b == nil || ctx.Err() != nil {
+		return
+	}
+	host := requestHost(rawURL)
+	if host == "" {
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	failed := resp != nil && httpclient.IsRetryableStatus(resp.StatusCode)
+	if err != nil && !errors.Is(err, httpclient.ErrBodyTooLarge) {
+		failed = true
+	}
+	if !failed {
+		recovered, err := b.store.Success(ctx, host, b.now())
+		if err != nil {
+			log.Warn("failed to record host success", "host", host, "error", err.Error())
+		} else if recovered {
+			log.Info("host recovered, resuming article checks", "host", host)
+		}
+		return
+	}
+
+	paused, err := b.store.Failure(ctx, host, b.now(), b.threshold, b.pause)
+	if err != nil {
+		log.Warn("failed to record host failure", "host", host, "error", err.Error())
+	} else if paused {
+		log.Warn("host keeps failing, pausing article checks", "host", host, "pause", b.pause.String())
+	}
+}
+
+// requestHost is the lower-cased host, with any port, that a request to rawURL goes to
+func requestHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// allowScript lets a request through unless the host is paused, or its pause is over and
+// another request already probes it
+var allowScript = redis.NewScript(`
+local open_until = tonumber(redis.call('HGET', KEYS[1], 'open_until') or '0')
+if open_until == 0 then
+	return 1
+end
+local now = tonumber(ARGV[1])
+if now < open_until then
+	return 0
+end
+if now < tonumber(redis.call('HGET', KEYS[1], 'probe_until') or '0') then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'probe_until', now + tonumber(ARGV[2]))
+return 2
+`)
+
+// failureScript counts a failure and pauses the host on the threshold-th one or when the
+// probe failed; failures of requests made before an ongoing pause do not extend it
+var failureScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local failures = redis.call('HINCRBY', KEYS[1], 'failures', 1)
+local open_until = tonumber(redis.call('HGET', KEYS[1], 'open_until') or '0')
+local paused = 0
+if (open_until > 0 and now >= open_until) or (open_until == 0 and failures >= tonumber(ARGV[2])) then
+	redis.call('HSET', KEYS[1], 'open_until', now + tonumber(ARGV[3]))
+	redis.call('HDEL', KEYS[1], 'probe_until')
+	paused = 1
+end
+redis.call('PEXPIRE', KEYS[1], 2 * tonumber(ARGV[3]))
+return paused
+`)
+
+// successScript forgets the host's failures unless it is paused
+var successScript = redis.NewScript(`
+local open_until = tonumber(redis.call('HGET', KEYS[1], 'open_until') or '0')
+if open_until > tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('DEL', KEYS[1])
+if open_until > 0 then
+	return 1
+end
+return 0
+`)
+
+// RedisHostBreakerStore keeps the failure state of each host in a Redis hash
+type RedisHostBreakerStore struct {
+	client redis.Cmdable
+}
+
+func NewRedisHostBreakerStore(client redis.Cmdable) *RedisHostBreakerStore {
+	return &RedisHostBreakerStore{client: client}
+}
+
+func (s *RedisHostBreakerStore) Allow(ctx context.Context, host string, now time.Time, probeTTL time.Duration) (bool, bool, error) {
+	result, err := allowScript.Run(ctx, s.client, []string{hostBreakerKey(host)}, now.UnixMilli(), probeTTL.Milliseconds()).Int()
+	if err != nil {
+		return false, false, err
+	}
+	return result > 0, result == 2, nil
+}
+
+func (s *RedisHostBreakerStore) Failure(ctx context.Context, host string, now time.Time, threshold int, pause time.Duration) (bool, error) {
+	paused, err := failureScript.Run(ctx, s.client, []string{hostBreakerKey(host)}, now.UnixMilli(), threshold, pause.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return paused == 1, nil
+}
+
+func (s *RedisHostBreakerStore) Success(ctx context.Context, host string, now time.Time) (bool, error) {
+	recovered, err := successScript.Run(ctx, s.client, []string{hostBreakerKey(host)}, now.UnixMilli()).Int()
+	if err != nil {
+		return false, err
+	}
+	return recovered == 1, nil
+}
+
+func hostBreakerKey(host string) string {
+	return fmt.Sprintf(hostBreakerKeyPattern, host)
+}
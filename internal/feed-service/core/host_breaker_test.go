@@ -0,0 +1,169 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/internal/events"
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// memoryHostState is the failure state of one host in a memoryHostBreakerStore
+type memoryHostState struct {
+	failures   int
+	openUntil  time.Time
+	probeUntil time.Time
+}
+
+// memoryHostBreakerStore is a HostBreakerStore in a map; expiry is left out
+type memoryHostBreakerStore struct {
+	hosts map[string]*memoryHostState
+	err   error
+}
+
+func newMemoryHostBreakerStore() *memoryHostBreakerStore {
+	return &memoryHostBreakerStore{hosts: map[string]*memoryHostState{}}
+}
+
+func (s *memoryHostBreakerStore) Allow(_ context.Context, host string, now time.Time, probeTTL time.Duration) (bool, bool, error) {
+	if s.err != nil {
+		return false, false, s.err
+	}
+	state := s.hosts[host]
+	if state == nil || state.openUntil.IsZero() {
+		return true, false, nil
+	}
+	if now.Before(state.openUntil) || now.Before(state.probeUntil) {
+		return false, false, nil
+	}
+	state.probeUntil = now.Add(probeTTL)
+	return true, true, nil
+}
+
+func (s *memoryHostBreakerStore) Failure(_ context.Context, host string, now time.Time, threshold int, pause time.Duration) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	state := s.hosts[host]
+	if state == nil {
+		state = &memoryHostState{}
+		s.hosts[host] = state
+	}
+	state.failures++
+	open := !state.openUntil.IsZero()
+	if (open && !now.Before(state.openUntil)) || (!open && state.failures >= threshold) {
+		state.openUntil = now.Add(pause)
+		state.probeUntil = time.Time{}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *memoryHostBreakerStore) Success(_ context.Context, host string, now time.Time) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	state := s.hosts[host]
+	if state == nil {
+		return false, nil
+	}
+	if state.openUntil.After(now) {
+		return false, nil
+	}
+	delete(s.hosts, host)
+	return !state.openUntil.IsZero(), nil
+}
+
+func TestHostBreaker(t *testing.T) {
+	store := newMemoryHostBreakerStore()
+	breaker := NewHostBreaker(store, 3, 10*time.Minute)
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	breaker.now = func() time.Time { return now }
+	ctx := context.Background()
+	const page = "https://Example.com/posts/1"
+	unavailable := &http.Response{StatusCode: http.StatusServiceUnavailable}
+	ok := &http.Response{StatusCode: http.StatusOK}
+
+	breaker.Record(ctx, page, unavailable, nil)
+	breaker.Record(ctx, page, nil, errors.New("connection reset"))
+	breaker.Record(ctx, page, &http.Response{StatusCode: http.StatusNotFound}, nil)
+	breaker.Record(ctx, page, &http.Response{StatusCode: http.StatusTooManyRequests}, nil)
+	assert.True(t, breaker.Allow(ctx, page), "a success in between resets the failures")
+
+	breaker.Record(ctx, page, unavailable, nil)
+	breaker.Record(ctx, page, nil, httpclient.ErrBodyTooLarge)
+	assert.True(t, breaker.Allow(ctx, page), "a page too large is not a failure of the host")
+
+	for i := 0; i < 3; i++ {
+		breaker.Record(ctx, page, unavailable, nil)
+	}
+	assert.False(t, breaker.Allow(ctx, "https://example.com/posts/2"), "the pause covers the whole host")
+	assert.True(t, breaker.Allow(ctx, "https://other.example.com/posts/1"), "other hosts are not paused")
+
+	breaker.Record(ctx, page, ok, nil)
+	assert.False(t, breaker.Allow(ctx, page), "a request made before the pause does not end it")
+
+	now = now.Add(10 * time.Minute)
+	assert.True(t, breaker.Allow(ctx, page), "one probe once the pause is over")
+	assert.False(t, breaker.Allow(ctx, page), "only one probe at a time")
+	breaker.Record(ctx, page, unavailable, nil)
+	assert.False(t, breaker.Allow(ctx, page), "a failed probe pauses the host again")
+
+	now = now.Add(10 * time.Minute)
+	assert.True(t, breaker.Allow(ctx, page))
+	breaker.Record(ctx, page, ok, nil)
+	assert.True(t, breaker.Allow(ctx, page), "a successful probe resumes the checks")
+	assert.Empty(t, store.hosts)
+
+	store.err = errors.New("connection refused")
+	assert.True(t, breaker.Allow(ctx, page), "store failures let requests through")
+
+	var disabled *HostBreaker
+	assert.True(t, disabled.Allow(ctx, page))
+	disabled.Record(ctx, page, unavailable, nil)
+}
+
+func TestArticleUpdateChecker_PausesFailingHost(t *testing.T) {
+	var requests atomic.Int32
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	repo, _ := setupCheckerRepo(t)
+	checker := NewArticleUpdateChecker(repo, logger.New(0), server.Client(), nil, ArticleUpdateConfig{})
+	breaker := NewHostBreaker(newMemoryHostBreakerStore(), 2, time.Minute)
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	checker.SetHostBreaker(breaker)
+	ctx := context.Background()
+
+	for i := uint(1); i <= 2; i++ {
+		err := checker.HandleEvent(ctx, events.ArticleCheckEvent{ArticleID: i, FeedID: 7, URL: server.URL + "/posts"})
+		require.Error(t, err, "a retryable status is retried later")
+	}
+	require.EqualValues(t, 2, requests.Load())
+
+	err := checker.HandleEvent(ctx, events.ArticleCheckEvent{ArticleID: 3, FeedID: 7, URL: server.URL + "/other"})
+	require.NoError(t, err, "a paused host has its articles checked on a later run")
+	assert.EqualValues(t, 2, requests.Load())
+
+	now = now.Add(time.Minute)
+	status.Store(http.StatusNotModified)
+	err = checker.HandleEvent(ctx, events.ArticleCheckEvent{ArticleID: 3, FeedID: 7, URL: server.URL + "/other"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, requests.Load(), "the probe reaches the host")
+	assert.True(t, breaker.Allow(ctx, server.URL))
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostBucketSweepSize is how many hosts the limiter tracks before it forgets idle ones
+const hostBucketSweepSize = 1024
+
+// HostRateLimiter spaces out the requests of this replica to each host with a token
+// bucket: a host gets burst requests at once and then requestsPerSecond, however many
+// feed fetches and article checks run concurrently. Requests over the rate wait for
+// their turn instead of failing.
+type HostRateLimiter struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*hostBucket
+	now     func() time.Time
+}
+
+type hostBucket struct {
+	// tokens goes negative while requests wait for their turn
+	tokens  float64
+	updated time.Time
+}
+
+// NewHostRateLimiter returns a limiter allowing each host requestsPerSecond with bursts
+// of burst requests; a burst below 1 is raised to 1
+func NewHostRateLimiter(requestsPerSecond float64, burst int) *HostRateLimiter {
+	return &HostRateLimiter{
+		rate:    requestsPerSecond,
+		burst:   float64(max(burst, 1)),
+		buckets: make(map[string]*hostBucket),
+		now:     time.Now,
+	}
+}
+
+// Wait blocks until a request to host may be sent. When ctx is done first the turn is
+// given back and ctx's error returned.
+func (l *HostRateLimiter) Wait(ctx context.Context, host string) error {
+	if l == nil || l.rate <= 0 {
+		return nil
+	}
+	host = strings.ToLower(host)
+
+	delay := l.reserve(host)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.release(host)
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token of host and returns how long until it is due
+func (l *HostRateLimiter) reserve(host string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if len(l.buckets) >= hostBucketSweepSize {
+		l.sweep(now)
+	}
+	bucket, ok := l.buckets[host]
+	if !ok {
+		bucket = &hostBucket{tokens: l.burst, updated: now}
+		l.buckets[host] = bucket
+	}
+	l.refill(bucket, now)
+
+	bucket.tokens--
+	if bucket.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-bucket.tokens / l.rate * float64(time.Second))
+}
+
+// release returns a token taken by a request that gave up waiting
+func (l *HostRateLimiter) release(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if bucket, ok := l.buckets[host]; ok {
+		l.refill(bucket, l.now())
+		bucket.tokens = min(bucket.tokens+1, l.burst)
+	}
+}
+
+func (l *HostRateLimiter) refill(bucket *hostBucket, now time.Time) {
+	if elapsed := now.Sub(bucket.updated); elapsed > 0 {
+		bucket.tokens = min(bucket.tokens+elapsed.Seconds()*l.rate, l.burst)
+		bucket.updated = now
+	}
+}
+
+// sweep forgets the hosts whose bucket filled up again, which a new bucket would start as
+func (l *HostRateLimiter) sweep(now time.Time) {
+	for host, bucket := range l.buckets {
+		l.refill(bucket, now)
+		if bucket.tokens >= l.burst {
+			delete(l.buckets, host)
+		}
+	}
+}
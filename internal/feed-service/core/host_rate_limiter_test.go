@@ -0,0 +1,74 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
+)
+
+func TestHostRateLimiter_TokenBucket(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	limiter := NewHostRateLimiter(2, 3)
+	limiter.now = func() time.Time { return now }
+
+	// the burst goes out at once, then requests are spaced half a second apart
+	for i := 0; i < 3; i++ {
+		assert.Zero(t, limiter.reserve("example.com"))
+	}
+	assert.Equal(t, 500*time.Millisecond, limiter.reserve("example.com"))
+	assert.Equal(t, time.Second, limiter.reserve("example.com"))
+	assert.Zero(t, limiter.reserve("other.example.com"), "hosts have buckets of their own")
+
+	// a request giving up hands its turn to the next one
+	limiter.release("example.com")
+	assert.Equal(t, time.Second, limiter.reserve("example.com"))
+
+	now = now.Add(10 * time.Second)
+	for i := 0; i < 3; i++ {
+		assert.Zero(t, limiter.reserve("example.com"), "the bucket refills up to the burst")
+	}
+	assert.Equal(t, 500*time.Millisecond, limiter.reserve("example.com"))
+}
+
+func TestHostRateLimiter_WaitGivesUpWithContext(t *testing.T) {
+	limiter := NewHostRateLimiter(0.1, 1)
+	require.NoError(t, limiter.Wait(context.Background(), "Example.com"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, limiter.Wait(ctx, "example.com"), context.DeadlineExceeded)
+
+	var disabled *HostRateLimiter
+	require.NoError(t, disabled.Wait(context.Background(), "example.com"))
+	require.NoError(t, NewHostRateLimiter(0, 1).Wait(context.Background(), "example.com"))
+}
+
+func TestHTTPClientFactory_SharesHostRateLimit(t *testing.T) {
+	var hits []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits = append(hits, time.Now())
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>t</title></channel></rss>`))
+	}))
+	defer server.Close()
+
+	factory := NewHTTPClientFactory(FetchIdentity{})
+	factory.SetHostRateLimiter(NewHostRateLimiter(20, 1))
+
+	// the feed parser and an article client draw from the same bucket
+	_, err := factory.FeedParser().ParseURLWithContext(server.URL, context.Background())
+	require.NoError(t, err)
+	resp, err := factory.Client(httpclient.Options{Name: "article_update"}).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.Len(t, hits, 2)
+	assert.GreaterOrEqual(t, hits[1].Sub(hits[0]), 40*time.Millisecond)
+}
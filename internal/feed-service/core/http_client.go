@@ -0,0 +1,93 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
+)
+
+// DefaultUserAgent identifies the feed service when no User-Agent is configured
+const DefaultUserAgent = "PhoenixRSS/1.0 (+https://github.com/Fancu1/phoenix-rss)"
+
+// FetchIdentity is how the feed service introduces itself to the sites it fetches
+type FetchIdentity struct {
+	UserAgent string
+	From      string // contact address sent in the From header
+	InfoURL   string // page describing the crawler, appended to the User-Agent
+}
+
+// userAgent returns the User-Agent header value, with the info URL appended when it
+// is not already part of it
+func (id FetchIdentity) userAgent() string {
+	ua := strings.TrimSpace(id.UserAgent)
+	if ua == "" {
+		ua = DefaultUserAgent
+	}
+	if id.InfoURL != "" && !strings.Contains(ua, id.InfoURL) {
+		ua += " (+" + id.InfoURL + ")"
+	}
+	return ua
+}
+
+// HTTPClientFactory builds the HTTP clients for every outbound fetch (feed parsing, robots,
+// article update checks) so they all carry the same identity, guard and metrics
+type HTTPClientFactory struct {
+	identity FetchIdentity
+	guard    httpclient.Guard
+	metrics  httpclient.Metrics
+	limiter  *HostRateLimiter
+}
+
+func NewHTTPClientFactory(identity FetchIdentity) *HTTPClientFactory {
+	return &HTTPClientFactory{identity: identity}
+}
+
+// SetGuard vets every address the clients connect to, e.g. httpclient.BlockPrivateNetworks
+func (f *HTTPClientFactory) SetGuard(guard httpclient.Guard) {
+	f.guard = guard
+}
+
+// SetMetrics reports every request attempt of the clients
+func (f *HTTPClientFactory) SetMetrics(metrics httpclient.Metrics) {
+	f.metrics = metrics
+}
+
+// SetHostRateLimiter paces the requests of every client to each host, so feed fetches and
+// article checks together stay within one rate per site. Clients built before are not affected.
+func (f *HTTPClientFactory) SetHostRateLimiter(limiter *HostRateLimiter) {
+	f.limiter = limiter
+}
+
+// UserAgent returns the User-Agent sent with every request, also used to match robots.txt groups
+func (f *HTTPClientFactory) UserAgent() string {
+	return f.identity.userAgent()
+}
+
+// Client returns a client built from opts that sends the fetch identity
+func (f *HTTPClientFactory) Client(opts httpclient.Options) *http.Client {
+	opts.UserAgent = f.UserAgent()
+	if f.identity.From != "" {
+		opts.Header = http.Header{"From": {f.identity.From}}
+	}
+	opts.Guard = f.guard
+	opts.Metrics = f.metrics
+	if f.limiter != nil {
+		opts.Limiter = f.limiter
+	}
+	return httpclient.New(opts)
+}
+
+// FeedParser returns a feed parser that sends the fetch identity and refuses oversized feeds
+func (f *HTTPClientFactory) FeedParser() *gofeed.Parser {
+	parser := gofeed.NewParser()
+	parser.UserAgent = f.UserAgent()
+	parser.Client = f.Client(httpclient.Options{
+		Name:         "feed",
+		Timeout:      defaultFeedHTTPTimeout,
+		MaxBodyBytes: maxFeedDownloadBytes,
+	})
+	return parser
+}
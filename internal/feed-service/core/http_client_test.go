@@ -0,0 +1,46 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
+)
+
+func TestHTTPClientFactory_SendsIdentity(t *testing.T) {
+	var gotUA, gotFrom []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA = append(gotUA, r.UserAgent())
+		gotFrom = append(gotFrom, r.Header.Get("From"))
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>t</title></channel></rss>`))
+	}))
+	defer server.Close()
+
+	factory := NewHTTPClientFactory(FetchIdentity{
+		UserAgent: "ExampleBot/2.0",
+		From:      "ops@example.com",
+		InfoURL:   "https://example.com/bot",
+	})
+	require.Equal(t, "ExampleBot/2.0 (+https://example.com/bot)", factory.UserAgent())
+
+	resp, err := factory.Client(httpclient.Options{Timeout: time.Second}).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	_, err = factory.FeedParser().ParseURLWithContext(server.URL, context.Background())
+	require.NoError(t, err)
+
+	require.Equal(t, []string{factory.UserAgent(), factory.UserAgent()}, gotUA, "gofeed's own User-Agent must be replaced")
+	require.Equal(t, []string{"ops@example.com", "ops@example.com"}, gotFrom)
+}
+
+func TestFetchIdentity_Defaults(t *testing.T) {
+	require.Equal(t, DefaultUserAgent, FetchIdentity{}.userAgent())
+	require.Equal(t, DefaultUserAgent, FetchIdentity{InfoURL: "https://github.com/Fancu1/phoenix-rss"}.userAgent(), "info URL already present")
+}
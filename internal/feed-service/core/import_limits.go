@@ -0,0 +1,197 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/policy"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// SetImportPolicy limits the new articles a fetch saves to the max_items_per_fetch and
+// initial_import_cutoff of each feed's policy. The items left out are held back, and
+// skipped by later fetches, until BackfillArticles imports them.
+func (s *ArticleService) SetImportPolicy(engine *policy.Engine) {
+	s.imports = engine
+}
+
+// importLimits are the limits of the feed's policy, none without an import policy
+func (s *ArticleService) importLimits(feed *models.Feed) (maxItems int, initialCutoff time.Duration) {
+	if s.imports == nil {
+		return 0, 0
+	}
+	p := s.imports.Defaults()
+	if s.imports.Overrides(policy.SettingMaxItemsPerFetch) || s.imports.Overrides(policy.SettingInitialImportCutoff) {
+		p = s.imports.Evaluate(policy.FeedSubject(feed)).Policy
+	}
+	return p.MaxItemsPerFetch, p.InitialImportCutoff
+}
+
+// limitImport drops the new articles of a fetch that go past the feed's import limits:
+// on the first import, those published before the initial cutoff, and then the oldest
+// ones past the per-fetch cap. The feed's ImportedSince is moved up to hold back what was
+// dropped. Failing to record it is logged; the dropped articles then come in with a later
+// fetch.
+func (s *ArticleService) limitImport(ctx context.Context, feed *models.Feed, articles []*models.Article) []*models.Article {
+	maxItems, initialCutoff := s.importLimits(feed)
+	if len(articles) == 0 || (maxItems <= 0 && initialCutoff <= 0) {
+		return articles
+	}
+	log := logger.FromContext(ctx)
+
+	var since *time.Time
+	dropped := 0
+	if initialCutoff > 0 {
+		imported, err := s.articleRepo.FeedHasArticles(ctx, feed.ID)
+		if err != nil {
+			log.Warn("failed to check for an initial import", "feed_id", feed.ID, "error", err.Error())
+		} else if !imported {
+			cutoff := time.Now().Add(-initialCutoff)
+			kept := make([]*models.Article, 0, len(articles))
+			for _, article := range articles {
+				if article.PublishedAt.Before(cutoff) {
+					continue
+				}
+				kept = append(kept, article)
+			}
+			if dropped = len(articles) - len(kept); dropped > 0 {
+				articles, since = kept, &cutoff
+			}
+		}
+	}
+
+	if maxItems > 0 && len(articles) > maxItems {
+		newest := newestArticles(articles, maxItems)
+		kept := make([]*models.Article, 0, len(articles))
+		for _, article := range articles {
+			if newest[article] {
+				kept = append(kept, article)
+			}
+		}
+		dropped += len(articles) - len(kept)
+		articles = kept
+
+		oldest := oldestPublished(articles)
+		since = &oldest
+	}
+
+	if since == nil {
+		return articles
+	}
+	if err := s.feedRepo.SetImportedSince(ctx, feed.ID, since); err != nil {
+		log.Warn("failed to record the items held back from import", "feed_id", feed.ID, "error", err.Error())
+	} else {
+		feed.ImportedSince = since
+	}
+	log.Info("import limits held back feed items; phoenix-admin feeds backfill imports them",
+		"feed_id", feed.ID, "held_items", dropped, "imported_items", len(articles),
+		"max_items_per_fetch", maxItems, "initial_import_cutoff", initialCutoff.String(), "imported_since", *since)
+	return articles
+}
+
+// newestArticles picks the n most recently published articles
+func newestArticles(articles []*models.Article, n int) map[*models.Article]bool {
+	sorted := append([]*models.Article(nil), articles...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].PublishedAt.After(sorted[j].PublishedAt)
+	})
+	newest := make(map[*models.Article]bool, n)
+	for _, article := range sorted[:min(n, len(sorted))] {
+		newest[article] = true
+	}
+	return newest
+}
+
+func oldestPublished(articles []*models.Article) time.Time {
+	oldest := articles[0].PublishedAt
+	for _, article := range articles[1:] {
+		if article.PublishedAt.Before(oldest) {
+			oldest = article.PublishedAt
+		}
+	}
+	return oldest
+}
+
+// BackfillArticles imports the items of the feed the import limits held back, newest
+// first: those published after since, or all of them when since is zero, and at most
+// limit when limit is above 0. The feed is fetched again, without its HTTP validators so
+// an unchanged feed is not answered with 304. Items that are no longer in the feed cannot
+// be imported. It returns the imported articles.
+func (s *ArticleService) BackfillArticles(ctx context.Context, feedID uint, since time.Time, limit int) ([]*models.Article, error) {
+	log := logger.FromContext(ctx)
+
+	feed, err := s.feedRepo.GetByID(ctx, feedID)
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to get feed %d for backfill: %w", feedID, err))
+	}
+	if feed == nil {
+		return nil, fmt.Errorf("feed %d not found: %w", feedID, ierr.ErrFeedNotFound)
+	}
+	if feed.ImportedSince == nil {
+		log.Info("feed has no items held back from import", "feed_id", feedID)
+		return nil, nil
+	}
+
+	unconditional := *feed
+	unconditional.HTTPETag, unconditional.HTTPLastModified = nil, nil
+	resp, err := fetchFeed(s.FetchContext(ctx, feedID), s.parser, &unconditional)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse feed %d (%s) from URL '%s': %w", feedID, feed.Title, feed.URL, fetchError(err))
+	}
+	parsedFeed := resp.feed
+	requestID, _ := logger.GetRequestID(ctx)
+	publishedDates, _ := itemDates(parsedFeed.Items, time.Now())
+
+	var candidates []*models.Article
+	olderThanSince := false
+	for i, item := range parsedFeed.Items {
+		publishedAt := publishedDates[i]
+		if !publishedAt.Before(*feed.ImportedSince) {
+			continue // imported by the fetches
+		}
+		if !since.IsZero() && publishedAt.Before(since) {
+			olderThanSince = true
+			continue
+		}
+		exists, err := s.articleRepo.ExistsByURL(ctx, item.Link)
+		if err != nil {
+			return nil, ierr.NewDatabaseError(fmt.Errorf("failed to check if article %s exists: %w", item.Link, err))
+		}
+		if exists {
+			continue
+		}
+		candidates = append(candidates, newItemArticle(ctx, feed, parsedFeed, item, publishedAt, requestID))
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].PublishedAt.After(candidates[j].PublishedAt)
+	})
+	articles := candidates
+	if limit > 0 && len(articles) > limit {
+		articles = articles[:limit]
+	}
+	if len(articles) > 0 {
+		if err := s.saveNewArticles(ctx, feed, articles, requestID); err != nil {
+			return nil, err
+		}
+	}
+
+	// what is still held back: the rest past the limit, else what is older than since
+	var importedSince *time.Time
+	switch {
+	case len(articles) < len(candidates):
+		oldest := oldestPublished(articles)
+		importedSince = &oldest
+	case olderThanSince:
+		importedSince = &since
+	}
+	if err := s.feedRepo.SetImportedSince(ctx, feedID, importedSince); err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to update the import state of feed %d: %w", feedID, err))
+	}
+	log.Info("backfilled feed items", "feed_id", feedID, "imported_items", len(articles), "remaining_items", len(candidates)-len(articles))
+	return articles, nil
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/policy"
+)
+
+func TestFetchAndSaveArticles_ImportLimitsAndBackfill(t *testing.T) {
+	service, feedRepo, _, db := setupArticleService(t)
+	ctx := context.Background()
+
+	engine, err := policy.NewEngine(policy.Policy{MaxItemsPerFetch: 3, InitialImportCutoff: 30 * 24 * time.Hour}, policy.Document{})
+	require.NoError(t, err)
+	service.SetImportPolicy(engine)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	ages := []time.Duration{1 * time.Hour, 2 * time.Hour, 3 * time.Hour, 4 * time.Hour, 5 * time.Hour, 40 * 24 * time.Hour, 50 * 24 * time.Hour}
+	var items strings.Builder
+	for i, age := range ages {
+		fmt.Fprintf(&items, `<item><title>Item %d</title><link>https://import.example.com/%d</link><pubDate>%s</pubDate></item>`,
+			i, i, now.Add(-age).Format(time.RFC1123Z))
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Import</title>%s</channel></rss>`, items.String())
+	}))
+	defer server.Close()
+
+	feed := &models.Feed{Title: "Import", URL: server.URL, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+	urls := func(articles []*models.Article) []string {
+		var urls []string
+		for _, article := range articles {
+			urls = append(urls, article.URL)
+		}
+		return urls
+	}
+
+	// the first fetch leaves out what is past the cutoff, then keeps the newest three
+	articles, err := service.FetchAndSaveArticles(ctx, feed.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://import.example.com/0", "https://import.example.com/1", "https://import.example.com/2"}, urls(articles))
+	stored, err := feedRepo.GetByID(ctx, feed.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.ImportedSince)
+	require.True(t, now.Add(-3*time.Hour).Equal(*stored.ImportedSince), "held back before the oldest imported item")
+
+	// later fetches skip the held back items
+	require.NoError(t, db.Model(&models.Feed{}).Where("id = ?", feed.ID).Update("http_etag", nil).Error)
+	articles, err = service.FetchAndSaveArticles(ctx, feed.ID)
+	require.NoError(t, err)
+	require.Empty(t, articles)
+
+	// the backfill ignores the ETag, imports newest first and keeps holding back the rest
+	articles, err = service.BackfillArticles(ctx, feed.ID, time.Time{}, 3)
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://import.example.com/3", "https://import.example.com/4", "https://import.example.com/5"}, urls(articles))
+	stored, err = feedRepo.GetByID(ctx, feed.ID)
+	require.NoError(t, err)
+	require.True(t, now.Add(-40*24*time.Hour).Equal(*stored.ImportedSince))
+
+	articles, err = service.BackfillArticles(ctx, feed.ID, now.Add(-45*24*time.Hour), 0)
+	require.NoError(t, err)
+	require.Empty(t, articles, "nothing held back is newer than since")
+	stored, err = feedRepo.GetByID(ctx, feed.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.ImportedSince)
+
+	articles, err = service.BackfillArticles(ctx, feed.ID, time.Time{}, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://import.example.com/6"}, urls(articles))
+	stored, err = feedRepo.GetByID(ctx, feed.ID)
+	require.NoError(t, err)
+	require.Nil(t, stored.ImportedSince, "everything is imported")
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// maxFutureItemDate is how far past the fetch an item may be dated before its date is
+// taken for a broken one
+const maxFutureItemDate = 24 * time.Hour
+
+// itemDateStep separates the items dated from their place in the feed, so they keep its
+// order
+const itemDateStep = time.Second
+
+// itemDates dates the items of a fetched feed. Dates gofeed could not parse go through
+// parseItemDate; items still without a usable date, or dated far into the future, are
+// dated from their place in the feed: a second older than the item listed before them in
+// a newest-first feed, the fetch time for the items opening it. It returns how many items
+// had no usable date.
+func itemDates(items []*gofeed.Item, now time.Time) ([]time.Time, int) {
+	dates := make([]time.Time, len(items))
+	parsed := make([]bool, len(items))
+	var first, last *time.Time
+	for i, item := range items {
+		date, ok := itemDate(item)
+		if !ok || date.After(now.Add(maxFutureItemDate)) {
+			continue
+		}
+		dates[i], parsed[i] = date, true
+		if first == nil {
+			first = &dates[i]
+		}
+		last = &dates[i]
+	}
+
+	// most feeds list the newest items first; some list them oldest first
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	if first != nil && first.Before(*last) {
+		for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+			order[i], order[j] = order[j], order[i]
+		}
+	}
+
+	unparsed := 0
+	newer := now.Add(itemDateStep)
+	for _, i := range order {
+		if !parsed[i] {
+			dates[i] = newer.Add(-itemDateStep)
+			unparsed++
+		}
+		newer = dates[i]
+	}
+	return dates, unparsed
+}
+
+// itemDate is when the item was published, or else last updated
+func itemDate(item *gofeed.Item) (time.Time, bool) {
+	if item.PublishedParsed != nil {
+		return *item.PublishedParsed, true
+	}
+	if date, ok := parseItemDate(item.Published); ok {
+		return date, true
+	}
+	if item.UpdatedParsed != nil {
+		return *item.UpdatedParsed, true
+	}
+	return parseItemDate(item.Updated)
+}
+
+var (
+	dateWordPattern      = regexp.MustCompile(`\p{L}+`)
+	dateOrdinalPattern   = regexp.MustCompile(`(?i)\b(\d{1,2})(st|nd|rd|th|er|e)\b`)
+	dateTimeSepPattern   = regexp.MustCompile(`(\d)T(\d)`)
+	dateZoneGluedPattern = regexp.MustCompile(`(\d)([+-]\d{2}:?\d{2})$`)
+)
+
+// dateWords maps the month names, weekdays, zone abbreviations and filler words of the
+// dates feeds write in English, French, German, Spanish, Italian, Portuguese and Dutch to
+// what the itemDateLayouts expect. Weekdays and filler words are dropped.
+var dateWords = func() map[string]string {
+	words := map[string]string{
+		// zones; others are read as UTC
+		"ut": "+0000", "utc": "+0000", "gmt": "+0000", "z": "+0000",
+		"est": "-0500", "edt": "-0400", "cst": "-0600", "cdt": "-0500",
+		"mst": "-0700", "mdt": "-0600", "pst": "-0800", "pdt": "-0700",
+		"bst": "+0100", "cet": "+0100", "cest": "+0200", "mez": "+0100", "mesz": "+0200",
+		"eet": "+0200", "eest": "+0300", "jst": "+0900",
+	}
+	months := map[string][]string{
+		"Jan": {"january", "jan", "janvier", "janv", "januar", "jän", "jänner", "enero", "ene", "gennaio", "gen", "janeiro", "januari"},
+		"Feb": {"february", "feb", "février", "fevrier", "févr", "fevr", "fév", "februar", "febrero", "febbraio", "fevereiro", "fev", "februari"},
+		"Mar": {"march", "mar", "mars", "märz", "maerz", "mär", "marzo", "março", "marco", "maart", "mrt"},
+		"Apr": {"april", "apr", "avril", "avr", "abril", "abr", "aprile"},
+		"May": {"may", "mai", "mayo", "maggio", "mag", "maio", "mei"},
+		"Jun": {"june", "jun", "juin", "juni", "junio", "giugno", "giu", "junho"},
+		"Jul": {"july", "jul", "juillet", "juil", "juli", "julio", "luglio", "lug", "julho"},
+		"Aug": {"august", "aug", "août", "aout", "agosto", "ago", "augustus"},
+		"Sep": {"september", "sep", "sept", "septembre", "septiembre", "setiembre", "settembre", "set", "setembro"},
+		"Oct": {"october", "oct", "octobre", "oktober", "okt", "octubre", "ottobre", "ott", "outubro", "out"},
+		"Nov": {"november", "nov", "novembre", "noviembre", "novembro"},
+		"Dec": {"december", "dec", "décembre", "decembre", "déc", "dezember", "dez", "diciembre", "dic", "dicembre", "dezembro"},
+	}
+	for month, names := range months {
+		for _, name := range names {
+			words[name] = month
+		}
+	}
+	for _, word := range []string{
+		"monday", "mon", "tuesday", "tue", "tues", "wednesday", "wed", "thursday", "thu", "thur", "thurs",
+		"friday", "fri", "saturday", "sat", "sunday", "sun",
+		"lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi", "dimanche",
+		"montag", "dienstag", "mittwoch", "donnerstag", "freitag", "samstag", "sonntag",
+		"mo", "di", "mi", "do", "fr", "sa", "so",
+		"lunes", "martes", "miércoles", "miercoles", "jueves", "viernes", "sábado", "sabado", "domingo",
+		"lunedì", "lunedi", "martedì", "martedi", "mercoledì", "mercoledi", "giovedì", "giovedi", "venerdì", "venerdi", "sabato", "domenica",
+		"segunda", "terça", "terca", "quarta", "quinta", "sexta", "feira",
+		"maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag", "zondag",
+		"de", "del", "à", "a", "um", "at", "le", "den", "el", "op", "om", "uhr",
+	} {
+		words[word] = ""
+	}
+	return words
+}()
+
+// itemDateLayouts are tried in turn by parseItemDate, on dates normalized to a day, an
+// English month abbreviation, a year, a time and a numeric zone separated by spaces
+var itemDateLayouts = func() []string {
+	var layouts []string
+	for _, date := range []string{"2 Jan 2006", "Jan 2 2006", "2006-01-02", "02.01.2006", "2006/01/02", "2 Jan 06"} {
+		for _, clock := range []string{" 15:04:05.999999999", " 15:04:05", " 15:04", ""} {
+			for _, zone := range []string{" -0700", " -07:00", " MST", ""} {
+				if clock == "" && zone != "" {
+					continue
+				}
+				layouts = append(layouts, date+clock+zone)
+			}
+		}
+	}
+	return layouts
+}()
+
+// parseItemDate reads the dates gofeed gives up on: zones like "UT", localized month and
+// weekday names, ordinals and the like. Dates without a zone are taken as UTC.
+func parseItemDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	value = dateTimeSepPattern.ReplaceAllString(value, "$1 $2")
+	value = dateWordPattern.ReplaceAllStringFunc(value, func(word string) string {
+		if replacement, ok := dateWords[strings.ToLower(word)]; ok {
+			return replacement
+		}
+		return word
+	})
+	value = dateOrdinalPattern.ReplaceAllString(value, "$1")
+
+	// "Mo., 2. Jan. 2006" reads "2 Jan 2006"; what is left of "segunda-feira" goes too
+	fields := strings.Fields(strings.ReplaceAll(value, ",", " "))
+	kept := fields[:0]
+	for _, field := range fields {
+		if field = strings.Trim(field, "."); field != "" && field != "-" {
+			kept = append(kept, field)
+		}
+	}
+	value = dateZoneGluedPattern.ReplaceAllString(strings.Join(kept, " "), "$1 $2")
+
+	for _, layout := range itemDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
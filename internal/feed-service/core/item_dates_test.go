@@ -0,0 +1,121 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+func TestParseItemDate(t *testing.T) {
+	want := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"Mon, 02 Jan 2006 15:04:05 UT", want},
+		{"Mon, 02 Jan 2006 10:04:05 EST", want},
+		{"Monday, January 2nd, 2006 15:04:05 GMT", want},
+		{"lundi 2 janvier 2006 16:04:05 +0100", want},
+		{"1er janvier 2006", time.Date(2006, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"Mo., 2. Januar 2006 16:04:05 MEZ", want},
+		{"lunes, 2 de enero de 2006 15:04:05", want},
+		{"segunda-feira, 2 de janeiro de 2006 15:04", want.Add(-5 * time.Second)},
+		{"2 gennaio 2006 15:04:05 +00:00", want},
+		{"maandag 2 maart 2006", time.Date(2006, time.March, 2, 0, 0, 0, 0, time.UTC)},
+		{"02.01.2006 15:04:05", want},
+		{"2006-01-02T17:04:05+02:00", want},
+		{"2006/01/02 15:04:05", want},
+	}
+	for _, tc := range tests {
+		got, ok := parseItemDate(tc.value)
+		require.True(t, ok, tc.value)
+		require.True(t, tc.want.Equal(got), "%s: got %s", tc.value, got)
+	}
+
+	for _, value := range []string{"", "yesterday", "sometime in 2006", "32 Jan 2006"} {
+		_, ok := parseItemDate(value)
+		require.False(t, ok, value)
+	}
+}
+
+func TestItemDates_DatesUndatedItemsFromTheirOrder(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Time) *time.Time { return &d }
+	older := time.Date(2026, time.February, 20, 8, 0, 0, 0, time.UTC)
+	oldest := time.Date(2026, time.February, 10, 8, 0, 0, 0, time.UTC)
+
+	// newest first: an undated item sorts right below the item listed before it
+	dates, undated := itemDates([]*gofeed.Item{
+		{Published: "not a date"},
+		{PublishedParsed: at(older)},
+		{Published: "soon"},
+		{Published: "Tue, 10 Feb 2026 08:00:00 UT"},
+		{PublishedParsed: at(now.Add(30 * 24 * time.Hour))},
+	}, now)
+	require.Equal(t, 3, undated)
+	require.Equal(t, now, dates[0])
+	require.Equal(t, older, dates[1])
+	require.Equal(t, older.Add(-time.Second), dates[2])
+	require.True(t, oldest.Equal(dates[3]))
+	require.True(t, oldest.Add(-time.Second).Equal(dates[4]), "dates far in the future are not trusted")
+
+	// oldest first
+	dates, undated = itemDates([]*gofeed.Item{
+		{PublishedParsed: at(oldest)},
+		{Published: "?"},
+		{PublishedParsed: at(older)},
+		{Published: "?"},
+	}, now)
+	require.Equal(t, 2, undated)
+	require.Equal(t, now, dates[3])
+	require.Equal(t, older.Add(-time.Second), dates[1])
+
+	// the update date stands in for a missing publication date
+	dates, undated = itemDates([]*gofeed.Item{{Updated: "2 février 2026"}}, now)
+	require.Zero(t, undated)
+	require.Equal(t, time.Date(2026, time.February, 2, 0, 0, 0, 0, time.UTC), dates[0])
+}
+
+func TestFetchAndSaveArticles_FlagsUnreliableDates(t *testing.T) {
+	service, feedRepo, _, db := setupArticleService(t)
+	ctx := context.Background()
+
+	undated := `<item><title>Undated</title><link>https://dates.example.com/undated</link><pubDate>whenever</pubDate></item>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Dates</title>`+
+			`<item><title>New</title><link>https://dates.example.com/new</link><pubDate>Sat, 28 Feb 2026 09:00:00 UT</pubDate></item>`+
+			`<item><title>Old</title><link>https://dates.example.com/old</link><pubDate>le 1er février 2026</pubDate></item>`+
+			`%s</channel></rss>`, undated)
+	}))
+	defer server.Close()
+
+	feed := &models.Feed{Title: "Dates", URL: server.URL, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+
+	articles, err := service.FetchAndSaveArticles(ctx, feed.ID)
+	require.NoError(t, err)
+	require.Len(t, articles, 3)
+	require.True(t, time.Date(2026, time.February, 28, 9, 0, 0, 0, time.UTC).Equal(articles[0].PublishedAt))
+	require.True(t, time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC).Equal(articles[1].PublishedAt))
+	require.True(t, articles[1].PublishedAt.Add(-time.Second).Equal(articles[2].PublishedAt), "the undated item keeps its place")
+	stored, err := feedRepo.GetByID(ctx, feed.ID)
+	require.NoError(t, err)
+	require.True(t, stored.DatesUnreliable)
+
+	// a fetch where every item is dated clears the flag
+	undated = ""
+	_, err = service.FetchAndSaveArticles(ctx, feed.ID)
+	require.NoError(t, err)
+	stored, err = feedRepo.GetByID(ctx, feed.ID)
+	require.NoError(t, err)
+	require.False(t, stored.DatesUnreliable)
+}
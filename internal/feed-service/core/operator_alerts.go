@@ -0,0 +1,227 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
+)
+
+// Operator alert kinds
+const (
+	AlertFeedError        = "feed_error"
+	AlertFetchFailureRate = "fetch_failure_rate"
+)
+
+// Webhook payload formats
+const (
+	AlertFormatSlack = "slack" // {"text": ...}, understood by Slack and compatible incoming webhooks
+	AlertFormatJSON  = "json"  // the OperatorAlert itself
+)
+
+// failureRateBuckets is how many slices the failure rate window is counted in
+const failureRateBuckets = 10
+
+// OperatorAlert is a message for the operators of the instance
+type OperatorAlert struct {
+	// Key identifies repeats of the same alert, which are held back during the cooldown
+	Key    string    `json:"key"`
+	Kind   string    `json:"kind"`
+	Title  string    `json:"title"`
+	Text   string    `json:"text"`
+	FeedID uint      `json:"feed_id,omitempty"`
+	At     time.Time `json:"at"`
+	// Suppressed counts the repeats held back since the alert was last sent
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// AlertSink delivers operator alerts
+type AlertSink interface {
+	Send(ctx context.Context, alert OperatorAlert) error
+}
+
+// OperatorAlertConfig sets when fetch results raise an alert
+type OperatorAlertConfig struct {
+	// PopularFeedSubscribers is the number of subscribers from which a feed turning to
+	// error status is alerted
+	PopularFeedSubscribers int
+	// FailureRate alerts when at least FailureRateMinFetches fetches were made within
+	// FailureRateWindow and at least that share of them failed
+	FailureRate           float64
+	FailureRateWindow     time.Duration
+	FailureRateMinFetches int
+	// Cooldown is the least time between two alerts with the same key
+	Cooldown time.Duration
+}
+
+type fetchBucket struct {
+	start   time.Time
+	fetches int
+	failed  int
+}
+
+// OperatorAlerter turns fetch results into operator alerts: popular feeds that start
+// failing and spikes of the fetch failure rate. Repeats of an alert are held back for
+// the cooldown. The failure rate and the cooldowns are counted per feed-service instance.
+type OperatorAlerter struct {
+	sink   AlertSink
+	config OperatorAlertConfig
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu         sync.Mutex
+	lastSent   map[string]time.Time
+	suppressed map[string]int
+	buckets    []fetchBucket
+}
+
+func NewOperatorAlerter(sink AlertSink, config OperatorAlertConfig, logger *slog.Logger) *OperatorAlerter {
+	return &OperatorAlerter{
+		sink:       sink,
+		config:     config,
+		logger:     logger,
+		now:        time.Now,
+		lastSent:   make(map[string]time.Time),
+		suppressed: make(map[string]int),
+	}
+}
+
+// FeedFailed alerts that a feed with at least PopularFeedSubscribers subscribers turned
+// to error status
+func (a *OperatorAlerter) FeedFailed(ctx context.Context, feed *models.Feed, subscribers int64, cause string) {
+	if a.config.PopularFeedSubscribers <= 0 || subscribers < int64(a.config.PopularFeedSubscribers) {
+		return
+	}
+	a.raise(ctx, OperatorAlert{
+		Key:    fmt.Sprintf("%s:%d", AlertFeedError, feed.ID),
+		Kind:   AlertFeedError,
+		Title:  fmt.Sprintf("Feed %q is failing", feed.Title),
+		Text:   fmt.Sprintf("Feed %d (%s) with %d subscribers turned to error status: %s", feed.ID, feed.URL, subscribers, cause),
+		FeedID: feed.ID,
+	})
+}
+
+// RecordFetch counts a fetch result and alerts when the failure rate over the window
+// reaches the threshold
+func (a *OperatorAlerter) RecordFetch(ctx context.Context, failed bool) {
+	if a.config.FailureRate <= 0 || a.config.FailureRateWindow <= 0 {
+		return
+	}
+	now := a.now()
+	width := a.config.FailureRateWindow / failureRateBuckets
+
+	a.mu.Lock()
+	cutoff := now.Add(-a.config.FailureRateWindow)
+	kept := a.buckets[:0]
+	for _, bucket := range a.buckets {
+		if bucket.start.After(cutoff) {
+			kept = append(kept, bucket)
+		}
+	}
+	a.buckets = kept
+	if n := len(a.buckets); n == 0 || now.Sub(a.buckets[n-1].start) >= width {
+		a.buckets = append(a.buckets, fetchBucket{start: now})
+	}
+	current := &a.buckets[len(a.buckets)-1]
+	current.fetches++
+	if failed {
+		current.failed++
+	}
+
+	var fetches, failures int
+	for _, bucket := range a.buckets {
+		fetches += bucket.fetches
+		failures += bucket.failed
+	}
+	a.mu.Unlock()
+
+	if !failed || fetches < a.config.FailureRateMinFetches {
+		return
+	}
+	rate := float64(failures) / float64(fetches)
+	if rate < a.config.FailureRate {
+		return
+	}
+	a.raise(ctx, OperatorAlert{
+		Key:   AlertFetchFailureRate,
+		Kind:  AlertFetchFailureRate,
+		Title: "Feed fetch failures spiking",
+		Text: fmt.Sprintf("%d of the last %d feed fetches failed (%.0f%%) within %s",
+			failures, fetches, rate*100, a.config.FailureRateWindow),
+	})
+}
+
+// raise sends the alert unless one with the same key was sent within the cooldown
+func (a *OperatorAlerter) raise(ctx context.Context, alert OperatorAlert) {
+	now := a.now()
+	a.mu.Lock()
+	if last, ok := a.lastSent[alert.Key]; ok && now.Sub(last) < a.config.Cooldown {
+		a.suppressed[alert.Key]++
+		a.mu.Unlock()
+		return
+	}
+	a.lastSent[alert.Key] = now
+	alert.Suppressed = a.suppressed[alert.Key]
+	delete(a.suppressed, alert.Key)
+	a.mu.Unlock()
+
+	alert.At = now.UTC()
+	if err := a.sink.Send(ctx, alert); err != nil {
+		a.logger.Error("failed to send operator alert", "key", alert.Key, "error", err)
+		return
+	}
+	a.logger.Info("operator alert sent", "key", alert.Key, "suppressed", alert.Suppressed)
+}
+
+// WebhookAlertSink posts alerts to a webhook, such as a Slack incoming webhook
+type WebhookAlertSink struct {
+	url    string
+	format string
+	client *http.Client
+}
+
+func NewWebhookAlertSink(url, format string) *WebhookAlertSink {
+	return &WebhookAlertSink{
+		url:    url,
+		format: format,
+		client: httpclient.New(httpclient.Options{Name: "operator-alerts", Timeout: 10 * time.Second, MaxBodyBytes: 64 << 10}),
+	}
+}
+
+// Send implements AlertSink
+func (s *WebhookAlertSink) Send(ctx context.Context, alert OperatorAlert) error {
+	var payload any = alert
+	if s.format == AlertFormatSlack {
+		text := fmt.Sprintf("*%s*\n%s", alert.Title, alert.Text)
+		if alert.Suppressed > 0 {
+			text += fmt.Sprintf("\n_%d similar alerts held back since the last one_", alert.Suppressed)
+		}
+		payload = map[string]string{"text": text}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post alert: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+type recordingAlertSink struct {
+	alerts []OperatorAlert
+}
+
+func (s *recordingAlertSink) Send(_ context.Context, alert OperatorAlert) error {
+	s.alerts = append(s.alerts, alert)
+	return nil
+}
+
+func newTestAlerter(config OperatorAlertConfig) (*OperatorAlerter, *recordingAlertSink, *time.Time) {
+	sink := &recordingAlertSink{}
+	alerter := NewOperatorAlerter(sink, config, logger.New(0))
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	alerter.now = func() time.Time { return now }
+	return alerter, sink, &now
+}
+
+func TestOperatorAlerter_FeedFailedCooldown(t *testing.T) {
+	ctx := context.Background()
+	alerter, sink, now := newTestAlerter(OperatorAlertConfig{PopularFeedSubscribers: 10, Cooldown: time.Hour})
+	feed := &models.Feed{ID: 7, Title: "Popular", URL: "https://example.com/feed"}
+
+	alerter.FeedFailed(ctx, feed, 3, "connection refused")
+	require.Empty(t, sink.alerts, "feeds with few subscribers are not alerted")
+
+	alerter.FeedFailed(ctx, feed, 12, "connection refused")
+	require.Len(t, sink.alerts, 1)
+	require.Equal(t, "feed_error:7", sink.alerts[0].Key)
+	require.Equal(t, uint(7), sink.alerts[0].FeedID)
+	require.Contains(t, sink.alerts[0].Text, "connection refused")
+
+	*now = now.Add(30 * time.Minute)
+	alerter.FeedFailed(ctx, feed, 12, "connection refused")
+	alerter.FeedFailed(ctx, feed, 12, "connection refused")
+	require.Len(t, sink.alerts, 1, "repeats are held back during the cooldown")
+
+	alerter.FeedFailed(ctx, &models.Feed{ID: 8, Title: "Other"}, 20, "http error: 503")
+	require.Len(t, sink.alerts, 2, "other feeds have their own cooldown")
+
+	*now = now.Add(31 * time.Minute)
+	alerter.FeedFailed(ctx, feed, 12, "connection refused")
+	require.Len(t, sink.alerts, 3)
+	require.Equal(t, 2, sink.alerts[2].Suppressed)
+}
+
+func TestOperatorAlerter_FailureRate(t *testing.T) {
+	ctx := context.Background()
+	alerter, sink, now := newTestAlerter(OperatorAlertConfig{
+		FailureRate:           0.5,
+		FailureRateWindow:     10 * time.Minute,
+		FailureRateMinFetches: 10,
+		Cooldown:              time.Hour,
+	})
+
+	for i := 0; i < 6; i++ {
+		alerter.RecordFetch(ctx, false)
+	}
+	for i := 0; i < 3; i++ {
+		alerter.RecordFetch(ctx, true)
+	}
+	require.Empty(t, sink.alerts, "too few fetches to judge")
+
+	alerter.RecordFetch(ctx, true)
+	require.Empty(t, sink.alerts, "4 of 10 is below the threshold")
+
+	*now = now.Add(2 * time.Minute)
+	alerter.RecordFetch(ctx, true)
+	alerter.RecordFetch(ctx, true)
+	require.Len(t, sink.alerts, 1, "6 of 12 reaches the threshold")
+	require.Equal(t, AlertFetchFailureRate, sink.alerts[0].Key)
+
+	// the first fetches leave the window, so only the recent failures count
+	*now = now.Add(9 * time.Minute)
+	for i := 0; i < 5; i++ {
+		alerter.RecordFetch(ctx, false)
+	}
+	alerter.RecordFetch(ctx, true)
+	require.Len(t, sink.alerts, 1, "3 of 8 and within the cooldown")
+}
+
+func TestWebhookAlertSink_Slack(t *testing.T) {
+	var payload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookAlertSink(server.URL, AlertFormatSlack)
+	err := sink.Send(context.Background(), OperatorAlert{Title: "Feed fetch failures spiking", Text: "6 of 12 failed", Suppressed: 2})
+	require.NoError(t, err)
+	require.Contains(t, payload["text"], "*Feed fetch failures spiking*\n6 of 12 failed")
+	require.Contains(t, payload["text"], "2 similar alerts")
+}
+
+func TestWebhookAlertSink_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	err := NewWebhookAlertSink(server.URL, AlertFormatJSON).Send(context.Background(), OperatorAlert{Key: "k"})
+	require.Error(t, err)
+}
@@ -0,0 +1,244 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/events"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
+)
+
+// EventOutbox stands in for the Kafka producers of the feed-service. It stores the feed
+// fetch and article persisted events in outbox_events, in the transaction of the rows
+// they describe when it is given one with WithTx, and the OutboxRelay publishes them. A
+// broker outage then delays the events instead of losing them.
+type EventOutbox struct {
+	repo *repository.OutboxRepository
+}
+
+func NewEventOutbox(repo *repository.OutboxRepository) *EventOutbox {
+	return &EventOutbox{repo: repo}
+}
+
+// WithTx returns an outbox storing events in the transaction of a dbtx.UnitOfWork; a nil
+// tx stores them on their own
+func (o *EventOutbox) WithTx(tx *gorm.DB) *EventOutbox {
+	return &EventOutbox{repo: o.repo.WithTx(tx)}
+}
+
+// PublishFeedFetch stores a fetch event for the feed
+func (o *EventOutbox) PublishFeedFetch(ctx context.Context, feedID uint) error {
+	return o.PublishFeedFetches(ctx, []uint{feedID})
+}
+
+// PublishFeedFetches stores a fetch event for every feed
+func (o *EventOutbox) PublishFeedFetches(ctx context.Context, feedIDs []uint) error {
+	requestID, _ := logger.GetRequestID(ctx)
+	rows := make([]*models.OutboxEvent, len(feedIDs))
+	for i, feedID := range feedIDs {
+		payload, err := json.Marshal(events.FeedFetchEvent{FeedID: feedID, RequestID: requestID})
+		if err != nil {
+			return fmt.Errorf("failed to marshal feed fetch event: %w", err)
+		}
+		rows[i] = &models.OutboxEvent{EventType: string(events.EventFeedFetch), Payload: payload, RequestID: optionalString(requestID)}
+	}
+	if err := o.repo.Add(ctx, rows...); err != nil {
+		return fmt.Errorf("failed to store feed fetch events: %w", err)
+	}
+	return nil
+}
+
+// PublishArticlePersisted stores the event
+func (o *EventOutbox) PublishArticlePersisted(ctx context.Context, event *article_eventspb.ArticlePersistedEvent) error {
+	return o.PublishArticlesPersisted(ctx, []*article_eventspb.ArticlePersistedEvent{event})
+}
+
+// PublishArticlesPersisted stores the events. Events without a request ID get the one of
+// ctx.
+func (o *EventOutbox) PublishArticlesPersisted(ctx context.Context, evts []*article_eventspb.ArticlePersistedEvent) error {
+	requestID, _ := logger.GetRequestID(ctx)
+	rows := make([]*models.OutboxEvent, len(evts))
+	for i, event := range evts {
+		if event.RequestId == "" {
+			event.RequestId = requestID
+		}
+		payload, err := proto.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal article persisted event: %w", err)
+		}
+		rows[i] = &models.OutboxEvent{EventType: string(events.EventArticlePersisted), Payload: payload, RequestID: optionalString(event.RequestId)}
+	}
+	if err := o.repo.Add(ctx, rows...); err != nil {
+		return fmt.Errorf("failed to store article persisted events: %w", err)
+	}
+	return nil
+}
+
+// Close lets the outbox stand in for an events.ArticleEventProducer; the relay owns the
+// Kafka producers
+func (o *EventOutbox) Close() error {
+	return nil
+}
+
+// FeedFetchEventPublisher publishes stored feed fetch events, such as events.KafkaProducer
+type FeedFetchEventPublisher interface {
+	PublishFeedFetchEvents(ctx context.Context, evts []events.FeedFetchEvent) error
+}
+
+// ArticlesPersistedPublisher publishes stored article persisted events, such as
+// events.KafkaArticleEventProducer
+type ArticlesPersistedPublisher interface {
+	PublishArticlesPersisted(ctx context.Context, evts []*article_eventspb.ArticlePersistedEvent) error
+}
+
+// Defaults of the OutboxRelay
+const (
+	DefaultOutboxBatchSize = 100
+	// defaultOutboxLease is how long a relay holds the events it claimed; a relay that
+	// stops while publishing leaves them to the others once it passes
+	defaultOutboxLease = time.Minute
+)
+
+// OutboxRelay publishes the events of the EventOutbox to Kafka in the order they were
+// stored. Each run claims a batch and publishes each run of events of the same type in
+// one write. Events can be published twice when a relay stops between the write and
+// recording it, so consumers must tolerate duplicates.
+type OutboxRelay struct {
+	repo        *repository.OutboxRepository
+	feedFetches FeedFetchEventPublisher
+	articles    ArticlesPersistedPublisher
+	logger      *slog.Logger
+	owner       string
+	batchSize   int
+	lease       time.Duration
+	now         func() time.Time
+}
+
+func NewOutboxRelay(repo *repository.OutboxRepository, feedFetches FeedFetchEventPublisher, articles ArticlesPersistedPublisher, batchSize int, logger *slog.Logger) *OutboxRelay {
+	if batchSize <= 0 {
+		batchSize = DefaultOutboxBatchSize
+	}
+	return &OutboxRelay{
+		repo:        repo,
+		feedFetches: feedFetches,
+		articles:    articles,
+		logger:      logger,
+		owner:       newRelayOwner(),
+		batchSize:   batchSize,
+		lease:       defaultOutboxLease,
+		now:         func() time.Time { return time.Now().UTC() },
+	}
+}
+
+func newRelayOwner() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "relay-" + hex.EncodeToString(b)
+}
+
+// RunOnce publishes a batch of events and returns how many it published. It stops at the
+// first write Kafka refuses; the events of that write and those after it stay in the
+// outbox for the next run.
+func (r *OutboxRelay) RunOnce(ctx context.Context) (int, error) {
+	batch, err := r.repo.Claim(ctx, r.owner, r.now(), r.lease, r.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+
+	published := 0
+	for start := 0; start < len(batch); {
+		end := start + 1
+		for end < len(batch) && batch[end].EventType == batch[start].EventType {
+			end++
+		}
+		run := batch[start:end]
+		ids := make([]uint64, len(run))
+		for i, row := range run {
+			ids[i] = row.ID
+		}
+
+		if err := r.publish(ctx, run); err != nil {
+			// the context may be done, so record the failure regardless
+			releaseCtx := context.WithoutCancel(ctx)
+			if markErr := r.repo.MarkFailed(releaseCtx, ids, err.Error()); markErr != nil {
+				r.logger.Error("failed to record outbox publish failure", "error", markErr)
+			}
+			if releaseErr := r.repo.Release(releaseCtx, r.owner); releaseErr != nil {
+				r.logger.Error("failed to release outbox events", "error", releaseErr)
+			}
+			return published, fmt.Errorf("failed to publish %d %s events: %w", len(run), run[0].EventType, err)
+		}
+		if err := r.repo.MarkPublished(ctx, ids, r.now()); err != nil {
+			return published, fmt.Errorf("failed to mark outbox events published: %w", err)
+		}
+		published += len(run)
+		start = end
+	}
+	return published, nil
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, run []*models.OutboxEvent) error {
+	switch events.EventType(run[0].EventType) {
+	case events.EventFeedFetch:
+		evts := make([]events.FeedFetchEvent, 0, len(run))
+		for _, row := range run {
+			var evt events.FeedFetchEvent
+			if err := json.Unmarshal(row.Payload, &evt); err != nil {
+				r.logger.Error("dropping undecodable outbox event", "id", row.ID, "event_type", row.EventType, "error", err)
+				continue
+			}
+			evts = append(evts, evt)
+		}
+		if len(evts) == 0 {
+			return nil
+		}
+		return r.feedFetches.PublishFeedFetchEvents(ctx, evts)
+	case events.EventArticlePersisted:
+		evts := make([]*article_eventspb.ArticlePersistedEvent, 0, len(run))
+		for _, row := range run {
+			evt := &article_eventspb.ArticlePersistedEvent{}
+			if err := proto.Unmarshal(row.Payload, evt); err != nil {
+				r.logger.Error("dropping undecodable outbox event", "id", row.ID, "event_type", row.EventType, "error", err)
+				continue
+			}
+			evts = append(evts, evt)
+		}
+		if len(evts) == 0 {
+			return nil
+		}
+		return r.articles.PublishArticlesPersisted(ctx, evts)
+	default:
+		r.logger.Error("dropping outbox events of unknown type", "event_type", run[0].EventType, "count", len(run))
+		return nil
+	}
+}
+
+// Backlog counts the events waiting to be published and returns when the oldest was
+// stored, nil when none is waiting
+func (r *OutboxRelay) Backlog(ctx context.Context) (int64, *time.Time, error) {
+	return r.repo.Backlog(ctx)
+}
+
+// Purge deletes the events published before the time, batchSize at a time, and returns
+// how many it deleted
+func (r *OutboxRelay) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for {
+		deleted, err := r.repo.DeletePublishedBefore(ctx, before, r.batchSize)
+		total += deleted
+		if err != nil || deleted < int64(r.batchSize) {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/internal/events"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/dbtx"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
+)
+
+// recordingKafka stands in for the Kafka producers of the relay, refusing every write
+// while down
+type recordingKafka struct {
+	down        bool
+	writes      []events.EventType
+	feedFetches []events.FeedFetchEvent
+	articles    []*article_eventspb.ArticlePersistedEvent
+}
+
+func (k *recordingKafka) PublishFeedFetchEvents(ctx context.Context, evts []events.FeedFetchEvent) error {
+	if k.down {
+		return errors.New("kafka: broker unreachable")
+	}
+	k.writes = append(k.writes, events.EventFeedFetch)
+	k.feedFetches = append(k.feedFetches, evts...)
+	return nil
+}
+
+func (k *recordingKafka) PublishArticlesPersisted(ctx context.Context, evts []*article_eventspb.ArticlePersistedEvent) error {
+	if k.down {
+		return errors.New("kafka: broker unreachable")
+	}
+	k.writes = append(k.writes, events.EventArticlePersisted)
+	k.articles = append(k.articles, evts...)
+	return nil
+}
+
+func TestFetchAndSaveArticles_StoresEventsInOutbox(t *testing.T) {
+	service, _, articleRepo, db := setupArticleService(t)
+	require.NoError(t, db.AutoMigrate(&models.OutboxEvent{}))
+	outboxRepo := repository.NewOutboxRepository(db)
+	service.SetOutbox(NewEventOutbox(outboxRepo), dbtx.NewUnitOfWork(db))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>Outbox</title>` +
+			`<item><title>One</title><link>https://example.com/one</link></item>` +
+			`<item><title>Two</title><link>https://example.com/two</link></item></channel></rss>`))
+	}))
+	defer server.Close()
+
+	feed := &models.Feed{Title: "Outbox", URL: server.URL, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.Create(feed).Error)
+
+	ctx := logger.WithRequestID(context.Background(), "req-fetch")
+	articles, err := service.FetchAndSaveArticles(ctx, feed.ID)
+	require.NoError(t, err)
+	require.Len(t, articles, 2)
+	for _, article := range articles {
+		stored, err := articleRepo.GetByID(ctx, article.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.ProcessingInProgress, stored.ProcessingStatus)
+	}
+
+	kafka := &recordingKafka{}
+	relay := NewOutboxRelay(outboxRepo, kafka, kafka, 10, logger.New(0))
+	published, err := relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+	require.Equal(t, []events.EventType{events.EventArticlePersisted}, kafka.writes, "a run of events of one type is one write")
+	assert.Equal(t, uint64(articles[0].ID), kafka.articles[0].ArticleId)
+	assert.Equal(t, "One", kafka.articles[0].Title)
+	assert.Equal(t, "req-fetch", kafka.articles[1].RequestId)
+
+	published, err = relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, published, "published events are not published again")
+}
+
+func TestOutboxRelay_KeepsEventsWhileKafkaIsDown(t *testing.T) {
+	_, _, _, db := setupArticleService(t)
+	require.NoError(t, db.AutoMigrate(&models.OutboxEvent{}))
+	outboxRepo := repository.NewOutboxRepository(db)
+	outbox := NewEventOutbox(outboxRepo)
+	ctx := logger.WithRequestID(context.Background(), "req-subscribe")
+
+	require.NoError(t, outbox.PublishFeedFetches(ctx, []uint{1, 2}))
+	require.NoError(t, outbox.PublishArticlePersisted(ctx, &article_eventspb.ArticlePersistedEvent{ArticleId: 10, FeedId: 1}))
+	require.NoError(t, outbox.PublishFeedFetch(ctx, 3))
+
+	kafka := &recordingKafka{down: true}
+	relay := NewOutboxRelay(outboxRepo, kafka, kafka, 10, logger.New(0))
+	published, err := relay.RunOnce(context.Background())
+	require.Error(t, err)
+	assert.Zero(t, published)
+
+	pending, oldest, err := relay.Backlog(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, pending)
+	assert.NotNil(t, oldest)
+
+	var failed models.OutboxEvent
+	require.NoError(t, db.Order("id").First(&failed).Error)
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Contains(t, *failed.LastError, "broker unreachable")
+	assert.Nil(t, failed.LockedBy, "failed events are released for the next run")
+
+	kafka.down = false
+	published, err = relay.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 4, published)
+	assert.Equal(t, []events.EventType{events.EventFeedFetch, events.EventArticlePersisted, events.EventFeedFetch}, kafka.writes)
+	assert.Equal(t, []events.FeedFetchEvent{
+		{FeedID: 1, RequestID: "req-subscribe"},
+		{FeedID: 2, RequestID: "req-subscribe"},
+		{FeedID: 3, RequestID: "req-subscribe"},
+	}, kafka.feedFetches)
+	assert.Equal(t, "req-subscribe", kafka.articles[0].RequestId)
+
+	pending, oldest, err = relay.Backlog(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+	assert.Nil(t, oldest)
+
+	deleted, err := relay.Purge(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, deleted)
+}
+
+func TestOutboxRepository_ClaimSkipsLockedEvents(t *testing.T) {
+	_, _, _, db := setupArticleService(t)
+	require.NoError(t, db.AutoMigrate(&models.OutboxEvent{}))
+	repo := repository.NewOutboxRepository(db)
+	ctx := context.Background()
+	require.NoError(t, NewEventOutbox(repo).PublishFeedFetches(ctx, []uint{1, 2, 3}))
+
+	now := time.Now().UTC()
+	first, err := repo.Claim(ctx, "relay-a", now, time.Minute, 2)
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+
+	second, err := repo.Claim(ctx, "relay-b", now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	assert.Greater(t, second[0].ID, first[1].ID)
+
+	// a relay that stopped while publishing leaves its events once the lease passes
+	later, err := repo.Claim(ctx, "relay-b", now.Add(2*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Len(t, later, 3)
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Fancu1/phoenix-rss/internal/events"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// ReasonOnRead marks article checks queued because a user opened the article
+const ReasonOnRead = "on_read"
+
+// readRecheckKeyPattern holds the cooldown of one article's on-read check
+const readRecheckKeyPattern = "read_recheck:%d"
+
+// readRecheckPublishTimeout bounds the publish, which runs after the article was returned
+const readRecheckPublishTimeout = 10 * time.Second
+
+// ReadRecheckStore rate-limits the on-read checks of each article
+type ReadRecheckStore interface {
+	// Claim reports whether a check of the article may be queued, and if so holds off
+	// further ones for cooldown
+	Claim(ctx context.Context, articleID uint, cooldown time.Duration) (bool, error)
+}
+
+// ReadRecheckConfig decides which opened articles are checked again
+type ReadRecheckConfig struct {
+	// MinAge is how long ago an article must have been published
+	MinAge time.Duration
+	// CheckedWithin skips articles checked more recently than this
+	CheckedWithin time.Duration
+	// Cooldown is the least time between two on-read checks of an article
+	Cooldown time.Duration
+}
+
+// ReadRechecker queues an update check of an older article when a user opens it, so
+// articles people read stay fresh without checking every article more often. The
+// scheduled checks only cover recent articles.
+type ReadRechecker struct {
+	producer events.ArticleCheckEventProducer
+	store    ReadRecheckStore
+	cfg      ReadRecheckConfig
+	now      func() time.Time
+	// publish runs the publish; it defaults to a goroutine so the reader does not wait
+	// for Kafka
+	publish func(func())
+}
+
+func NewReadRechecker(producer events.ArticleCheckEventProducer, store ReadRecheckStore, cfg ReadRecheckConfig) *ReadRechecker {
+	return &ReadRechecker{
+		producer: producer,
+		store:    store,
+		cfg:      cfg,
+		now:      time.Now,
+		publish:  func(f func()) { go f() },
+	}
+}
+
+// ArticleOpened queues a check of the article when it is old enough, was not checked
+// recently and no other on-read check of it is cooling down. It never fails the read:
+// store and publish errors are logged. A nil rechecker does nothing.
+func (r *ReadRechecker) ArticleOpened(ctx context.Context, article *models.Article) {
+	if r == nil || !r.due(article) {
+		return
+	}
+	log := logger.FromContext(ctx)
+
+	claimed, err := r.store.Claim(ctx, article.ID, r.cfg.Cooldown)
+	if err != nil {
+		log.Warn("failed to claim on-read article check", "article_id", article.ID, "error", err.Error())
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	event := events.ArticleCheckEvent{
+		ArticleID:   article.ID,
+		FeedID:      article.FeedID,
+		URL:         article.URL,
+		Attempt:     1,
+		ScheduledAt: r.now().UTC(),
+		Reason:      ReasonOnRead,
+	}
+	if article.HTTPETag != nil {
+		event.PrevETag = *article.HTTPETag
+	}
+	if article.HTTPLastModified != nil {
+		event.PrevLastModified = *article.HTTPLastModified
+	}
+	event.RequestID, _ = logger.GetRequestID(ctx)
+
+	// the read's context ends with the response, the publish must outlive it
+	publishCtx := context.WithoutCancel(ctx)
+	r.publish(func() {
+		publishCtx, cancel := context.WithTimeout(publishCtx, readRecheckPublishTimeout)
+		defer cancel()
+		if err := r.producer.PublishArticleCheck(publishCtx, event); err != nil {
+			log.Error("failed to queue on-read article check", "article_id", article.ID, "error", err.Error())
+			return
+		}
+		log.Info("queued on-read article check", "article_id", article.ID, "feed_id", article.FeedID)
+	})
+}
+
+// due reports whether the article is old enough and was not checked recently
+func (r *ReadRechecker) due(article *models.Article) bool {
+	if article == nil || article.URL == "" {
+		return false
+	}
+	now := r.now()
+	if article.PublishedAt.After(now.Add(-r.cfg.MinAge)) {
+		return false
+	}
+	return article.LastCheckedAt == nil || article.LastCheckedAt.Before(now.Add(-r.cfg.CheckedWithin))
+}
+
+// RedisReadRecheckStore keeps the cooldown of each article as a Redis key, shared by all
+// feed-service replicas
+type RedisReadRecheckStore struct {
+	client redis.Cmdable
+}
+
+func NewRedisReadRecheckStore(client redis.Cmdable) *RedisReadRecheckStore {
+	return &RedisReadRecheckStore{client: client}
+}
+
+func (s *RedisReadRecheckStore) Claim(ctx context.Context, articleID uint, cooldown time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, fmt.Sprintf(readRecheckKeyPattern, articleID), 1, cooldown).Result()
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/internal/events"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+)
+
+// memoryReadRecheckStore is a ReadRecheckStore in a map
+type memoryReadRecheckStore struct {
+	until map[uint]time.Time
+	now   func() time.Time
+	err   error
+}
+
+func (s *memoryReadRecheckStore) Claim(_ context.Context, articleID uint, cooldown time.Duration) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	if s.now().Before(s.until[articleID]) {
+		return false, nil
+	}
+	s.until[articleID] = s.now().Add(cooldown)
+	return true, nil
+}
+
+type recordingCheckProducer struct {
+	events []events.ArticleCheckEvent
+}
+
+func (p *recordingCheckProducer) PublishArticleCheck(_ context.Context, event events.ArticleCheckEvent) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestReadRechecker_ArticleOpened(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	store := &memoryReadRecheckStore{until: map[uint]time.Time{}, now: clock}
+	producer := &recordingCheckProducer{}
+	rechecker := NewReadRechecker(producer, store, ReadRecheckConfig{
+		MinAge:        48 * time.Hour,
+		CheckedWithin: 12 * time.Hour,
+		Cooldown:      time.Hour,
+	})
+	rechecker.now = clock
+	rechecker.publish = func(f func()) { f() }
+
+	etag := `"v1"`
+	checkedRecently := now.Add(-time.Hour)
+	checkedLongAgo := now.Add(-24 * time.Hour)
+	old := now.Add(-72 * time.Hour)
+	articles := map[string]*models.Article{
+		"never checked":    {ID: 1, FeedID: 9, URL: "https://example.com/1", PublishedAt: old, HTTPETag: &etag},
+		"checked long ago": {ID: 2, FeedID: 9, URL: "https://example.com/2", PublishedAt: old, LastCheckedAt: &checkedLongAgo},
+		"checked recently": {ID: 3, FeedID: 9, URL: "https://example.com/3", PublishedAt: old, LastCheckedAt: &checkedRecently},
+		"too new":          {ID: 4, FeedID: 9, URL: "https://example.com/4", PublishedAt: now.Add(-time.Hour)},
+	}
+
+	ctx := logger.WithRequestID(context.Background(), "req-1")
+	for _, name := range []string{"never checked", "checked long ago", "checked recently", "too new"} {
+		rechecker.ArticleOpened(ctx, articles[name])
+	}
+	require.Len(t, producer.events, 2)
+	assert.Equal(t, events.ArticleCheckEvent{
+		ArticleID:   1,
+		FeedID:      9,
+		URL:         "https://example.com/1",
+		PrevETag:    `"v1"`,
+		RequestID:   "req-1",
+		Attempt:     1,
+		ScheduledAt: now,
+		Reason:      ReasonOnRead,
+	}, producer.events[0])
+	assert.Equal(t, uint(2), producer.events[1].ArticleID)
+
+	// opening it again within the cooldown does not queue another check
+	rechecker.ArticleOpened(ctx, articles["never checked"])
+	assert.Len(t, producer.events, 2)
+
+	now = now.Add(time.Hour)
+	rechecker.ArticleOpened(ctx, articles["never checked"])
+	assert.Len(t, producer.events, 3)
+
+	// a failing store skips the check instead of failing the read
+	store.err = errors.New("redis down")
+	now = now.Add(time.Hour)
+	rechecker.ArticleOpened(ctx, articles["never checked"])
+	assert.Len(t, producer.events, 3)
+
+	var disabled *ReadRechecker
+	disabled.ArticleOpened(ctx, articles["never checked"])
+}
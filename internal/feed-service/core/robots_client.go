@@ -6,13 +6,23 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
 )
 
+// maxRobotsBytes caps robots.txt downloads
+const maxRobotsBytes = 1 << 20
+
+// maxCrawlDelay bounds the Crawl-delay taken from a robots.txt
+const maxCrawlDelay = 24 * time.Hour
+
 type RobotsClient struct {
 	httpClient *http.Client
 	logger     *slog.Logger
@@ -29,8 +39,13 @@ type robotsCacheEntry struct {
 type robotsRules struct {
 	allows    []string
 	disallows []string
+	// crawlDelay is how long the site asks crawlers to wait between requests, 0 when it
+	// does not say
+	crawlDelay time.Duration
 }
 
+// NewRobotsClient creates a robots.txt checker. The client should come from the
+// HTTPClientFactory so robots.txt is requested with the same User-Agent it is matched against.
 func NewRobotsClient(httpClient *http.Client, ttl time.Duration, logger *slog.Logger) *RobotsClient {
 	if ttl <= 0 {
 		ttl = 12 * time.Hour
@@ -44,12 +59,32 @@ func NewRobotsClient(httpClient *http.Client, ttl time.Duration, logger *slog.Lo
 }
 
 func (c *RobotsClient) IsAllowed(ctx context.Context, rawURL, userAgent string) (bool, error) {
+	parsed, rules, err := c.rules(ctx, rawURL, userAgent)
+	if err != nil || rules == nil {
+		return true, err
+	}
+	return rules.allowsPath(parsed.EscapedPath()), nil
+}
+
+// CrawlDelay returns the Crawl-delay the host of rawURL asks of userAgent, 0 when its
+// robots.txt sets none or cannot be fetched
+func (c *RobotsClient) CrawlDelay(ctx context.Context, rawURL, userAgent string) (time.Duration, error) {
+	_, rules, err := c.rules(ctx, rawURL, userAgent)
+	if err != nil || rules == nil {
+		return 0, err
+	}
+	return rules.crawlDelay, nil
+}
+
+// rules returns the parsed URL and the robots.txt rules of its host, from the cache while
+// they are fresh. Hosts that are not http(s) have no rules.
+func (c *RobotsClient) rules(ctx context.Context, rawURL, userAgent string) (*url.URL, *robotsRules, error) {
 	parsed, err := url.Parse(rawURL)
 	if err != nil {
-		return false, fmt.Errorf("invalid url for robots check: %w", err)
+		return nil, nil, fmt.Errorf("invalid url for robots check: %w", err)
 	}
 	if parsed.Scheme != "http" && parsed.Scheme != "https" {
-		return true, nil
+		return parsed, nil, nil
 	}
 
 	hostKey := parsed.Scheme + "://" + parsed.Host
@@ -58,30 +93,29 @@ func (c *RobotsClient) IsAllowed(ctx context.Context, rawURL, userAgent string)
 	entry, ok := c.cache[hostKey]
 	if ok && time.Since(entry.fetchedAt) < c.ttl {
 		c.cacheMu.RUnlock()
-		return entry.rules.allowsPath(parsed.EscapedPath()), nil
+		return parsed, &entry.rules, nil
 	}
 	c.cacheMu.RUnlock()
 
 	rules, err := c.fetchRules(ctx, hostKey, userAgent)
 	if err != nil {
-		return true, err
+		return parsed, nil, err
 	}
 
 	c.cacheMu.Lock()
 	c.cache[hostKey] = robotsCacheEntry{fetchedAt: time.Now(), rules: rules}
 	c.cacheMu.Unlock()
 
-	return rules.allowsPath(parsed.EscapedPath()), nil
+	return parsed, &rules, nil
 }
 
 func (c *RobotsClient) fetchRules(ctx context.Context, base, userAgent string) (robotsRules, error) {
 	robotsURL := base + "/robots.txt"
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	req, err := http.NewRequestWithContext(httpclient.WithMaxBodyBytes(ctx, maxRobotsBytes), http.MethodGet, robotsURL, nil)
 	if err != nil {
 		return robotsRules{}, err
 	}
-	req.Header.Set("User-Agent", userAgent)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -98,7 +132,7 @@ func (c *RobotsClient) fetchRules(ctx context.Context, base, userAgent string) (
 		return robotsRules{}, fmt.Errorf("robots fetch status %d", resp.StatusCode)
 	}
 
-	body, err := readWithLimit(resp.Body, 1<<20)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return robotsRules{}, err
 	}
@@ -107,18 +141,6 @@ func (c *RobotsClient) fetchRules(ctx context.Context, base, userAgent string) (
 	return rules, nil
 }
 
-func readWithLimit(r io.Reader, limit int64) ([]byte, error) {
-	reader := io.LimitReader(r, limit+1)
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, err
-	}
-	if int64(len(data)) > limit {
-		return nil, fmt.Errorf("robots.txt exceeds limit of %d bytes", limit)
-	}
-	return data, nil
-}
-
 func parseRobots(content, userAgent string) robotsRules {
 	groups := make(map[string]*robotsRules)
 	groups["*"] = &robotsRules{}
@@ -186,12 +208,24 @@ func parseRobots(content, userAgent string) robotsRules {
 				grp.allows = append(grp.allows, value)
 			}
 			lastWasUserAgent = false
+		case "crawl-delay":
+			if delay, ok := parseCrawlDelay(value); ok {
+				for _, agent := range currentAgents {
+					grp := groups[agent]
+					if grp == nil {
+						grp = &robotsRules{}
+						groups[agent] = grp
+					}
+					grp.crawlDelay = delay
+				}
+			}
+			lastWasUserAgent = false
 		default:
 			lastWasUserAgent = false
 		}
 	}
 
-	if rules, ok := groups[userAgent]; ok && (len(rules.allows) > 0 || len(rules.disallows) > 0) {
+	if rules, ok := groups[userAgent]; ok && (len(rules.allows) > 0 || len(rules.disallows) > 0 || rules.crawlDelay > 0) {
 		return *rules
 	}
 	if rules, ok := groups["*"]; ok {
@@ -200,6 +234,18 @@ func parseRobots(content, userAgent string) robotsRules {
 	return robotsRules{}
 }
 
+// parseCrawlDelay reads a Crawl-delay value in seconds, fractions allowed
+func parseCrawlDelay(value string) (time.Duration, bool) {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(seconds) || seconds <= 0 {
+		return 0, false
+	}
+	if seconds >= maxCrawlDelay.Seconds() {
+		return maxCrawlDelay, true
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
 func (r robotsRules) allowsPath(path string) bool {
 	if path == "" {
 		path = "/"
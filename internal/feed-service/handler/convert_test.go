@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/prototest"
+	feedpb "github.com/Fancu1/phoenix-rss/protos/gen/go/feed"
+)
+
+// The golden files under testdata change whenever a field is added to feed.proto or a
+// conversion maps it differently; review the diff and rerun with
+// PROTOTEST_UPDATE=1.
+
+func TestFeedProto_WireRoundTrip(t *testing.T) {
+	prototest.RequireRoundTrip(t, prototest.MessageTypes(feedpb.File_feed_proto), 20)
+}
+
+func TestToProtoArticle_MapsEveryField(t *testing.T) {
+	var article models.Article
+	prototest.FillStruct(&article)
+
+	pb := toProtoArticle(&article)
+	prototest.RequireAllSet(t, pb)
+	prototest.RequireGolden(t, "testdata/article.golden.json", pb)
+}
+
+func TestToProtoUserFeed_MapsEveryField(t *testing.T) {
+	var feed models.UserFeed
+	prototest.FillStruct(&feed)
+
+	pb := toProtoUserFeed(&feed)
+	// the owner and subscriber count only come with ListAllFeeds, which sets them itself
+	prototest.RequireAllSet(t, pb, "owner_user_id", "subscriber_count")
+	prototest.RequireGolden(t, "testdata/user_feed.golden.json", pb)
+}
+
+func TestToProtoFeedHealth_MapsEveryField(t *testing.T) {
+	var health models.FeedHealth
+	prototest.FillStruct(&health)
+
+	pb := toProtoFeedHealth(&health)
+	prototest.RequireAllSet(t, pb)
+	prototest.RequireGolden(t, "testdata/feed_health.golden.json", pb)
+}
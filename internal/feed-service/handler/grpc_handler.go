@@ -2,7 +2,9 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"strings"
 	"time"
 
 	"google.golang.org/grpc/codes"
@@ -11,6 +13,7 @@ import (
 	"github.com/Fancu1/phoenix-rss/internal/events"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/core"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
 	"github.com/Fancu1/phoenix-rss/pkg/ierr"
 	"github.com/Fancu1/phoenix-rss/pkg/logger"
 	feedpb "github.com/Fancu1/phoenix-rss/protos/gen/go/feed"
@@ -123,10 +126,35 @@ func (h *FeedServiceHandler) BatchSubscribeToFeeds(ctx context.Context, req *fee
 	}, nil
 }
 
+// ExistsByURLs tells which of the requested feed URLs already exist, in request order
+func (h *FeedServiceHandler) ExistsByURLs(ctx context.Context, req *feedpb.ExistsByURLsRequest) (*feedpb.ExistsByURLsResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: ExistsByURLs", "url_count", len(req.Urls))
+
+	if len(req.Urls) == 0 {
+		return &feedpb.ExistsByURLsResponse{}, nil
+	}
+
+	exists, err := h.feedService.ExistsByURLs(ctx, req.Urls)
+	if err != nil {
+		log.Error("failed to look up feed urls", "url_count", len(req.Urls), "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+
+	existing := make([]string, 0, len(exists))
+	for _, url := range req.Urls {
+		if exists[url] {
+			existing = append(existing, url)
+			delete(exists, url)
+		}
+	}
+	return &feedpb.ExistsByURLsResponse{ExistingUrls: existing}, nil
+}
+
 // ListUserFeeds return active feeds subscribed by a specific user (pending feeds are hidden)
 func (h *FeedServiceHandler) ListUserFeeds(ctx context.Context, req *feedpb.ListUserFeedsRequest) (*feedpb.ListUserFeedsResponse, error) {
 	log := logger.FromContext(ctx)
-	log.Info("gRPC: ListUserFeeds", "user_id", req.UserId)
+	log.Info("gRPC: ListUserFeeds", "user_id", req.UserId, "query", req.Query)
 
 	if req.UserId == 0 {
 		return nil, status.Error(codes.InvalidArgument, "user_id is required")
@@ -140,23 +168,15 @@ func (h *FeedServiceHandler) ListUserFeeds(ctx context.Context, req *feedpb.List
 	}
 
 	// Convert to protobuf
-	pbFeeds := make([]*feedpb.Feed, len(feeds))
-	for i, feed := range feeds {
-		pbFeeds[i] = &feedpb.Feed{
-			Id:          uint64(feed.ID),
-			Title:       feed.Title,
-			Url:         feed.URL,
-			Description: feed.Description,
-			Status:      string(feed.Status),
-			CreatedAt:   feed.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:   feed.UpdatedAt.Format(time.RFC3339),
-		}
-		if feed.CustomTitle != nil {
-			pbFeeds[i].CustomTitle = feed.CustomTitle
+	pbFeeds := make([]*feedpb.Feed, 0, len(feeds))
+	for _, feed := range feeds {
+		if !feed.Matches(req.Query) {
+			continue
 		}
+		pbFeeds = append(pbFeeds, toProtoUserFeed(feed))
 	}
 
-	log.Info("successfully listed user feeds", "user_id", req.UserId, "count", len(feeds))
+	log.Info("successfully listed user feeds", "user_id", req.UserId, "count", len(pbFeeds))
 	return &feedpb.ListUserFeedsResponse{Feeds: pbFeeds}, nil
 }
 
@@ -185,6 +205,88 @@ func (h *FeedServiceHandler) UnsubscribeFromFeed(ctx context.Context, req *feedp
 	}, nil
 }
 
+// DeleteFeed removes a feed for every subscriber. It is an administrator operation; the
+// API service only exposes it behind the admin token.
+func (h *FeedServiceHandler) DeleteFeed(ctx context.Context, req *feedpb.DeleteFeedRequest) (*feedpb.DeleteFeedResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: DeleteFeed", "feed_id", req.FeedId, "retention", req.Retention)
+
+	if req.FeedId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "feed_id is required")
+	}
+
+	var retention models.FeedRetention
+	if req.Retention != "" {
+		parsed, err := models.ParseFeedRetention(req.Retention)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		retention = parsed
+	}
+
+	deletion, err := h.feedService.DeleteFeed(ctx, uint(req.FeedId), retention)
+	if err != nil {
+		log.Error("failed to delete feed", "feed_id", req.FeedId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+
+	userIDs := make([]uint64, len(deletion.UserIDs))
+	for i, userID := range deletion.UserIDs {
+		userIDs[i] = uint64(userID)
+	}
+	return &feedpb.DeleteFeedResponse{
+		UnsubscribedUserIds: userIDs,
+		ArticlesPurged:      deletion.ArticlesPurged,
+		Retention:           string(deletion.Retention),
+	}, nil
+}
+
+// BulkUpdateFeeds applies an administrator's action to every feed matching the filters
+func (h *FeedServiceHandler) BulkUpdateFeeds(ctx context.Context, req *feedpb.BulkUpdateFeedsRequest) (*feedpb.BulkUpdateFeedsResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: BulkUpdateFeeds",
+		"action", req.Action,
+		"status", req.Status,
+		"error_contains", req.ErrorContains,
+		"not_fetched_since", req.NotFetchedSince,
+		"fetch_tier", req.FetchTier,
+		"feed_ids", len(req.FeedIds),
+		"target_tier", req.TargetTier,
+		"dry_run", req.DryRun,
+	)
+
+	action, err := models.ParseFeedBulkAction(req.Action)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	filter, err := feedListFilter(req.Status, req.ErrorContains, req.NotFetchedSince, req.FetchTier, req.FeedIds)
+	if err != nil {
+		return nil, err
+	}
+	var tier models.FeedTier
+	if req.TargetTier != "" {
+		if tier, err = models.ParseFeedTier(req.TargetTier); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	result, err := h.feedService.BulkUpdateFeeds(ctx, filter, models.FeedBulkUpdate{
+		Action: action,
+		Tier:   tier,
+		DryRun: req.DryRun,
+	})
+	if err != nil {
+		log.Error("failed to apply bulk feed action", "action", action, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+
+	return &feedpb.BulkUpdateFeedsResponse{
+		Matched:       uint64(result.Matched),
+		Updated:       uint64(result.Updated),
+		FetchesQueued: uint64(result.FetchesQueued),
+	}, nil
+}
+
 // ListArticles return articles for a specific feed (user must be subscribed)
 func (h *FeedServiceHandler) ListArticles(ctx context.Context, req *feedpb.ListArticlesRequest) (*feedpb.ListArticlesResponse, error) {
 	log := logger.FromContext(ctx)
@@ -223,14 +325,332 @@ func (h *FeedServiceHandler) GetArticle(ctx context.Context, req *feedpb.GetArti
 		return nil, status.Error(codes.InvalidArgument, "article_id is required")
 	}
 
-	article, err := h.articleService.GetArticleByID(ctx, uint(req.UserId), uint(req.ArticleId))
+	article, err := h.articleService.OpenArticle(ctx, uint(req.UserId), uint(req.ArticleId))
 	if err != nil {
 		log.Error("failed to get article", "user_id", req.UserId, "article_id", req.ArticleId, "error", err.Error())
 		return nil, h.mapErrorToGRPC(err)
 	}
 
+	navigation, err := h.articleService.GetArticleNavigation(ctx, uint(req.UserId), article.ID)
+	if err != nil {
+		log.Error("failed to get article navigation", "user_id", req.UserId, "article_id", req.ArticleId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+
 	log.Info("successfully retrieved article", "user_id", req.UserId, "article_id", req.ArticleId)
-	return &feedpb.GetArticleResponse{Article: toProtoArticle(article)}, nil
+	resp := &feedpb.GetArticleResponse{
+		Article: toProtoArticle(article),
+		Feed: &feedpb.ArticleFeedInfo{
+			Id:      uint64(navigation.Feed.ID),
+			Title:   navigation.Feed.Title,
+			Url:     navigation.Feed.URL,
+			IconUrl: navigation.Feed.IconURL,
+		},
+	}
+	if navigation.PreviousArticleID != nil {
+		resp.PreviousArticleId = uint64(*navigation.PreviousArticleID)
+	}
+	if navigation.NextArticleID != nil {
+		resp.NextArticleId = uint64(*navigation.NextArticleID)
+	}
+	return resp, nil
+}
+
+// RegenerateSummary queues a truncated summary for regeneration with the expanded token limit
+func (h *FeedServiceHandler) RegenerateSummary(ctx context.Context, req *feedpb.RegenerateSummaryRequest) (*feedpb.RegenerateSummaryResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: RegenerateSummary", "user_id", req.UserId, "article_id", req.ArticleId)
+
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if req.ArticleId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "article_id is required")
+	}
+
+	if err := h.articleService.RegenerateSummary(ctx, uint(req.UserId), uint(req.ArticleId)); err != nil {
+		log.Error("failed to regenerate summary", "user_id", req.UserId, "article_id", req.ArticleId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+
+	return &feedpb.RegenerateSummaryResponse{
+		Success: true,
+		Message: "Summary regeneration queued",
+	}, nil
+}
+
+// DeleteArticle moves an article to the trash
+func (h *FeedServiceHandler) DeleteArticle(ctx context.Context, req *feedpb.DeleteArticleRequest) (*feedpb.DeleteArticleResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: DeleteArticle", "user_id", req.UserId, "article_id", req.ArticleId)
+
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if req.ArticleId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "article_id is required")
+	}
+
+	if err := h.articleService.DeleteArticle(ctx, uint(req.UserId), uint(req.ArticleId)); err != nil {
+		log.Error("failed to delete article", "user_id", req.UserId, "article_id", req.ArticleId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+
+	return &feedpb.DeleteArticleResponse{
+		Success: true,
+		Message: "Article moved to trash",
+	}, nil
+}
+
+// RestoreArticle takes an article out of the trash
+func (h *FeedServiceHandler) RestoreArticle(ctx context.Context, req *feedpb.RestoreArticleRequest) (*feedpb.RestoreArticleResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: RestoreArticle", "user_id", req.UserId, "article_id", req.ArticleId)
+
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if req.ArticleId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "article_id is required")
+	}
+
+	article, err := h.articleService.RestoreArticle(ctx, uint(req.UserId), uint(req.ArticleId))
+	if err != nil {
+		log.Error("failed to restore article", "user_id", req.UserId, "article_id", req.ArticleId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+
+	return &feedpb.RestoreArticleResponse{Article: toProtoArticle(article)}, nil
+}
+
+// NextUnreadArticle returns the next unread article, optionally marking the current one read
+func (h *FeedServiceHandler) NextUnreadArticle(ctx context.Context, req *feedpb.NextUnreadArticleRequest) (*feedpb.NextUnreadArticleResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: NextUnreadArticle", "user_id", req.UserId, "after_article_id", req.AfterArticleId, "scope", req.Scope, "folder_id", req.FolderId, "mark_read", req.MarkRead)
+
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	article, err := h.articleService.NextUnreadArticle(ctx, uint(req.UserId), core.NextUnreadOptions{
+		AfterID:  uint(req.AfterArticleId),
+		Scope:    req.Scope,
+		FeedID:   uint(req.FeedId),
+		FolderID: uint(req.FolderId),
+		MarkRead: req.MarkRead,
+	})
+	if err != nil {
+		log.Error("failed to find next unread article", "user_id", req.UserId, "after_article_id", req.AfterArticleId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+
+	resp := &feedpb.NextUnreadArticleResponse{}
+	if article != nil {
+		resp.Article = toProtoArticle(article)
+	}
+	return resp, nil
+}
+
+// SetArticleRead marks an article read or unread for one user
+func (h *FeedServiceHandler) SetArticleRead(ctx context.Context, req *feedpb.SetArticleReadRequest) (*feedpb.SetArticleReadResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: SetArticleRead", "user_id", req.UserId, "article_id", req.ArticleId, "read", req.Read)
+
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if req.ArticleId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "article_id is required")
+	}
+
+	article, err := h.articleService.SetArticleRead(ctx, uint(req.UserId), uint(req.ArticleId), req.Read)
+	if err != nil {
+		log.Error("failed to set article read state", "user_id", req.UserId, "article_id", req.ArticleId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+	return &feedpb.SetArticleReadResponse{Article: toProtoArticle(article)}, nil
+}
+
+// MarkFeedRead marks the articles of a subscribed feed read for one user
+func (h *FeedServiceHandler) MarkFeedRead(ctx context.Context, req *feedpb.MarkFeedReadRequest) (*feedpb.MarkFeedReadResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: MarkFeedRead", "user_id", req.UserId, "feed_id", req.FeedId, "before", req.Before)
+
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if req.FeedId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "feed_id is required")
+	}
+	var before time.Time
+	if req.Before != "" {
+		parsed, err := time.Parse(time.RFC3339, req.Before)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid before timestamp")
+		}
+		before = parsed
+	}
+
+	marked, err := h.articleService.MarkFeedRead(ctx, uint(req.UserId), uint(req.FeedId), before)
+	if err != nil {
+		log.Error("failed to mark feed read", "user_id", req.UserId, "feed_id", req.FeedId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+	return &feedpb.MarkFeedReadResponse{Marked: int64(marked)}, nil
+}
+
+// SetArticleStarred stars or unstars an article for one user
+func (h *FeedServiceHandler) SetArticleStarred(ctx context.Context, req *feedpb.SetArticleStarredRequest) (*feedpb.SetArticleStarredResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: SetArticleStarred", "user_id", req.UserId, "article_id", req.ArticleId, "starred", req.Starred)
+
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if req.ArticleId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "article_id is required")
+	}
+
+	article, err := h.articleService.SetArticleStarred(ctx, uint(req.UserId), uint(req.ArticleId), req.Starred)
+	if err != nil {
+		log.Error("failed to set article starred state", "user_id", req.UserId, "article_id", req.ArticleId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+	return &feedpb.SetArticleStarredResponse{Article: toProtoArticle(article)}, nil
+}
+
+// ListStarredArticles returns a page of the articles one user starred
+func (h *FeedServiceHandler) ListStarredArticles(ctx context.Context, req *feedpb.ListStarredArticlesRequest) (*feedpb.ListStarredArticlesResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: ListStarredArticles", "user_id", req.UserId, "offset", req.Offset, "limit", req.Limit)
+
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	articles, total, err := h.articleService.ListStarredArticles(ctx, uint(req.UserId), int(req.Offset), int(req.Limit))
+	if err != nil {
+		log.Error("failed to list starred articles", "user_id", req.UserId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+
+	pbArticles := make([]*feedpb.Article, len(articles))
+	for i, article := range articles {
+		pbArticles[i] = toProtoArticle(article)
+	}
+	return &feedpb.ListStarredArticlesResponse{Articles: pbArticles, Total: total}, nil
+}
+
+// ListUserArticles returns the timeline of the user's subscribed feeds
+func (h *FeedServiceHandler) ListUserArticles(ctx context.Context, req *feedpb.ListUserArticlesRequest) (*feedpb.ListUserArticlesResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: ListUserArticles", "user_id", req.UserId, "page_size", req.PageSize, "has_page_token", req.PageToken != "", "tag", req.Tag,
+		"unread", req.Unread, "starred", req.Starred, "folder_id", req.FolderId)
+
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	filter := core.TimelineFilter{Tag: req.Tag, Unread: req.Unread, Starred: req.Starred, FolderID: uint(req.FolderId)}
+	articles, next, total, err := h.articleService.ListUserArticles(ctx, uint(req.UserId), filter, int(req.PageSize), req.PageToken)
+	if err != nil {
+		log.Error("failed to list user articles", "user_id", req.UserId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+
+	pbArticles := make([]*feedpb.Article, len(articles))
+	for i, article := range articles {
+		pbArticles[i] = toProtoArticle(article)
+	}
+	return &feedpb.ListUserArticlesResponse{Articles: pbArticles, NextPageToken: next, Total: total}, nil
+}
+
+// AddArticleTags puts tags on an article for one user
+func (h *FeedServiceHandler) AddArticleTags(ctx context.Context, req *feedpb.ArticleTagsRequest) (*feedpb.ArticleTagsResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: AddArticleTags", "user_id", req.UserId, "article_id", req.ArticleId, "tags", len(req.Tags))
+
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if req.ArticleId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "article_id is required")
+	}
+
+	article, err := h.articleService.AddArticleTags(ctx, uint(req.UserId), uint(req.ArticleId), req.Tags)
+	if err != nil {
+		log.Error("failed to tag article", "user_id", req.UserId, "article_id", req.ArticleId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+	return &feedpb.ArticleTagsResponse{Article: toProtoArticle(article)}, nil
+}
+
+// RemoveArticleTags takes tags off an article for one user
+func (h *FeedServiceHandler) RemoveArticleTags(ctx context.Context, req *feedpb.ArticleTagsRequest) (*feedpb.ArticleTagsResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: RemoveArticleTags", "user_id", req.UserId, "article_id", req.ArticleId, "tags", len(req.Tags))
+
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if req.ArticleId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "article_id is required")
+	}
+
+	article, err := h.articleService.RemoveArticleTags(ctx, uint(req.UserId), uint(req.ArticleId), req.Tags)
+	if err != nil {
+		log.Error("failed to untag article", "user_id", req.UserId, "article_id", req.ArticleId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+	return &feedpb.ArticleTagsResponse{Article: toProtoArticle(article)}, nil
+}
+
+// ListTags returns the tags of one user with their article counts
+func (h *FeedServiceHandler) ListTags(ctx context.Context, req *feedpb.ListTagsRequest) (*feedpb.ListTagsResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: ListTags", "user_id", req.UserId)
+
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	tags, err := h.articleService.ListTags(ctx, uint(req.UserId))
+	if err != nil {
+		log.Error("failed to list tags", "user_id", req.UserId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+
+	pbTags := make([]*feedpb.TagCount, len(tags))
+	for i, tag := range tags {
+		pbTags[i] = &feedpb.TagCount{Name: tag.Name, ArticleCount: tag.ArticleCount}
+	}
+	return &feedpb.ListTagsResponse{Tags: pbTags}, nil
+}
+
+// SearchArticles runs a full-text search over the user's subscribed feeds
+func (h *FeedServiceHandler) SearchArticles(ctx context.Context, req *feedpb.SearchArticlesRequest) (*feedpb.SearchArticlesResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: SearchArticles", "user_id", req.UserId, "offset", req.Offset, "limit", req.Limit)
+
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	articles, total, err := h.articleService.SearchArticles(ctx, uint(req.UserId), core.SearchOptions{
+		Query:  req.Query,
+		Offset: int(req.Offset),
+		Limit:  int(req.Limit),
+	})
+	if err != nil {
+		log.Error("failed to search articles", "user_id", req.UserId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+
+	pbArticles := make([]*feedpb.Article, len(articles))
+	for i, article := range articles {
+		pbArticles[i] = toProtoArticle(article)
+	}
+	return &feedpb.SearchArticlesResponse{Articles: pbArticles, Total: total}, nil
 }
 
 // TriggerFetch publishe a Kafka event for manual feed fetch
@@ -269,32 +689,145 @@ func (h *FeedServiceHandler) TriggerFetch(ctx context.Context, req *feedpb.Trigg
 	}, nil
 }
 
-// ListAllFeeds return all feeds in the system
-func (h *FeedServiceHandler) ListAllFeeds(ctx context.Context, req *feedpb.ListAllFeedsRequest) (*feedpb.ListAllFeedsResponse, error) {
+// ListAllFeeds return all feeds in the system, or one page of them when paging or filtering
+// GetFetchBacklog tells the scheduler how far behind feed fetching is
+func (h *FeedServiceHandler) GetFetchBacklog(ctx context.Context, req *feedpb.GetFetchBacklogRequest) (*feedpb.GetFetchBacklogResponse, error) {
 	log := logger.FromContext(ctx)
-	log.Info("gRPC: ListAllFeeds")
+	log.Info("gRPC: GetFetchBacklog", "due_before", req.DueBefore, "low_tier_due_before", req.LowTierDueBefore)
 
-	feeds, err := h.feedService.ListAllFeeds(ctx)
+	dueBefore, err := parseOptionalTime(req.DueBefore)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid due_before timestamp")
+	}
+	lowTierDueBefore, err := parseOptionalTime(req.LowTierDueBefore)
 	if err != nil {
-		log.Error("failed to list all feeds", "error", err.Error())
+		return nil, status.Error(codes.InvalidArgument, "invalid low_tier_due_before timestamp")
+	}
+
+	backlog, err := h.feedService.FetchBacklog(ctx, dueBefore, lowTierDueBefore)
+	if err != nil {
+		log.Error("failed to get fetch backlog", "error", err.Error())
 		return nil, h.mapErrorToGRPC(err)
 	}
 
+	resp := &feedpb.GetFetchBacklogResponse{DueFeeds: uint64(backlog.DueFeeds)}
+	if backlog.LastFetchedAt != nil {
+		resp.LastFetchedAt = backlog.LastFetchedAt.UTC().Format(time.RFC3339)
+	}
+	return resp, nil
+}
+
+func (h *FeedServiceHandler) ListAllFeeds(ctx context.Context, req *feedpb.ListAllFeedsRequest) (*feedpb.ListAllFeedsResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: ListAllFeeds",
+		"exclude_archived", req.ExcludeArchived,
+		"page_size", req.PageSize,
+		"status", req.Status,
+		"due_before", req.DueBefore,
+		"error_contains", req.ErrorContains,
+		"not_fetched_since", req.NotFetchedSince,
+		"fetch_tier", req.FetchTier,
+	)
+
+	var feeds []*core.SchedulableFeed
+	var nextPageToken string
+	var total int64
+	if req.PageSize > 0 || req.PageToken != "" || req.Status != "" || req.DueBefore != "" || req.LowTierDueBefore != "" ||
+		req.ErrorContains != "" || req.NotFetchedSince != "" || req.FetchTier != "" || len(req.FeedIds) > 0 || req.IncludeTotal {
+		filter, err := feedListFilter(req.Status, req.ErrorContains, req.NotFetchedSince, req.FetchTier, req.FeedIds)
+		if err != nil {
+			return nil, err
+		}
+		filter.ExcludeArchived = req.ExcludeArchived
+		if filter.DueBefore, err = parseOptionalTime(req.DueBefore); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid due_before timestamp")
+		}
+		if filter.LowTierDueBefore, err = parseOptionalTime(req.LowTierDueBefore); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid low_tier_due_before timestamp")
+		}
+
+		pageSize := int(req.PageSize)
+		if pageSize <= 0 {
+			pageSize = 500
+		} else if pageSize > 2000 {
+			pageSize = 2000
+		}
+
+		page, next, err := h.feedService.ListFeedsPage(ctx, filter, pageSize, req.PageToken)
+		if err != nil {
+			log.Error("failed to list feeds page", "error", err.Error())
+			return nil, h.mapErrorToGRPC(err)
+		}
+		feeds, nextPageToken = page, next
+
+		if req.IncludeTotal {
+			if total, err = h.feedService.CountFeeds(ctx, filter); err != nil {
+				return nil, h.mapErrorToGRPC(err)
+			}
+		}
+	} else if req.ExcludeArchived {
+		schedulable, err := h.feedService.ListSchedulableFeeds(ctx)
+		if err != nil {
+			log.Error("failed to list schedulable feeds", "error", err.Error())
+			return nil, h.mapErrorToGRPC(err)
+		}
+		feeds = schedulable
+	} else {
+		all, err := h.feedService.ListAllFeeds(ctx)
+		if err != nil {
+			log.Error("failed to list all feeds", "error", err.Error())
+			return nil, h.mapErrorToGRPC(err)
+		}
+		feeds = make([]*core.SchedulableFeed, len(all))
+		for i, feed := range all {
+			feeds[i] = &core.SchedulableFeed{Feed: feed}
+		}
+	}
+
 	pbFeeds := make([]*feedpb.Feed, len(feeds))
 	for i, feed := range feeds {
-		pbFeeds[i] = &feedpb.Feed{
-			Id:          uint64(feed.ID),
-			Title:       feed.Title,
-			Url:         feed.URL,
-			Description: feed.Description,
-			Status:      string(feed.Status),
-			CreatedAt:   feed.CreatedAt.Format(time.RFC3339),
-			UpdatedAt:   feed.UpdatedAt.Format(time.RFC3339),
+		pbFeeds[i] = toProtoFeed(feed.Feed)
+		pbFeeds[i].OwnerUserId = uint64(feed.OwnerUserID)
+	}
+
+	log.Info("successfully listed all feeds", "count", len(feeds), "has_next", nextPageToken != "")
+	return &feedpb.ListAllFeedsResponse{Feeds: pbFeeds, NextPageToken: nextPageToken, Total: uint64(total)}, nil
+}
+
+// feedListFilter builds the filter selecting feeds for administration
+func feedListFilter(feedStatus, errorContains, notFetchedSince, tier string, feedIDs []uint64) (repository.FeedListFilter, error) {
+	var filter repository.FeedListFilter
+	var err error
+	if feedStatus != "" {
+		if filter.Status, err = models.ParseFeedStatus(feedStatus); err != nil {
+			return filter, status.Error(codes.InvalidArgument, "invalid status filter")
+		}
+	}
+	if tier != "" {
+		if filter.Tier, err = models.ParseFeedTier(tier); err != nil {
+			return filter, status.Error(codes.InvalidArgument, err.Error())
 		}
 	}
+	if filter.NotFetchedSince, err = parseOptionalTime(notFetchedSince); err != nil {
+		return filter, status.Error(codes.InvalidArgument, "invalid not_fetched_since timestamp")
+	}
+	filter.ErrorContains = strings.TrimSpace(errorContains)
+	for _, feedID := range feedIDs {
+		filter.IDs = append(filter.IDs, uint(feedID))
+	}
+	return filter, nil
+}
 
-	log.Info("successfully listed all feeds", "count", len(feeds))
-	return &feedpb.ListAllFeedsResponse{Feeds: pbFeeds}, nil
+// parseOptionalTime parses an RFC3339 timestamp; empty is nil
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
 }
 
 // CheckSubscription check if user is subscribed to a feed
@@ -322,7 +855,7 @@ func (h *FeedServiceHandler) CheckSubscription(ctx context.Context, req *feedpb.
 	}, nil
 }
 
-// UpdateSubscription updates subscription settings (e.g., custom title)
+// UpdateSubscription updates subscription settings (e.g., custom title, fetch headers)
 func (h *FeedServiceHandler) UpdateSubscription(ctx context.Context, req *feedpb.UpdateSubscriptionRequest) (*feedpb.UpdateSubscriptionResponse, error) {
 	log := logger.FromContext(ctx)
 	log.Info("gRPC: UpdateSubscription", "user_id", req.UserId, "feed_id", req.FeedId)
@@ -334,27 +867,44 @@ func (h *FeedServiceHandler) UpdateSubscription(ctx context.Context, req *feedpb
 		return nil, status.Error(codes.InvalidArgument, "feed_id is required")
 	}
 
-	userFeed, err := h.feedService.UpdateFeedCustomTitle(ctx, uint(req.UserId), uint(req.FeedId), req.CustomTitle)
+	update := models.SubscriptionUpdate{
+		CustomTitle: req.CustomTitle,
+		Notes:       req.Notes,
+	}
+	if req.FetchHeaders != nil {
+		update.FetchHeaders = req.FetchHeaders.Headers
+		if update.FetchHeaders == nil {
+			update.FetchHeaders = map[string]string{}
+		}
+	}
+	userFeed, err := h.feedService.UpdateSubscription(ctx, uint(req.UserId), uint(req.FeedId), update)
 	if err != nil {
 		log.Error("failed to update subscription", "user_id", req.UserId, "feed_id", req.FeedId, "error", err.Error())
 		return nil, h.mapErrorToGRPC(err)
 	}
 
-	pbFeed := &feedpb.Feed{
-		Id:          uint64(userFeed.ID),
-		Title:       userFeed.Title,
-		Url:         userFeed.URL,
-		Description: userFeed.Description,
-		Status:      string(userFeed.Status),
-		CreatedAt:   userFeed.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:   userFeed.UpdatedAt.Format(time.RFC3339),
+	log.Info("successfully updated subscription", "user_id", req.UserId, "feed_id", req.FeedId)
+	return &feedpb.UpdateSubscriptionResponse{Feed: toProtoUserFeed(userFeed)}, nil
+}
+
+// GetFeedHealth reports the fetch status of a feed the user subscribes to
+func (h *FeedServiceHandler) GetFeedHealth(ctx context.Context, req *feedpb.GetFeedHealthRequest) (*feedpb.GetFeedHealthResponse, error) {
+	log := logger.FromContext(ctx)
+	log.Info("gRPC: GetFeedHealth", "user_id", req.UserId, "feed_id", req.FeedId)
+
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
 	}
-	if userFeed.CustomTitle != nil {
-		pbFeed.CustomTitle = userFeed.CustomTitle
+	if req.FeedId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "feed_id is required")
 	}
 
-	log.Info("successfully updated subscription", "user_id", req.UserId, "feed_id", req.FeedId)
-	return &feedpb.UpdateSubscriptionResponse{Feed: pbFeed}, nil
+	health, err := h.feedService.GetFeedHealth(ctx, uint(req.UserId), uint(req.FeedId))
+	if err != nil {
+		log.Error("failed to get feed health", "user_id", req.UserId, "feed_id", req.FeedId, "error", err.Error())
+		return nil, h.mapErrorToGRPC(err)
+	}
+	return &feedpb.GetFeedHealthResponse{Health: toProtoFeedHealth(health)}, nil
 }
 
 func (h *FeedServiceHandler) ListArticlesToCheck(ctx context.Context, req *feedpb.ListArticlesToCheckRequest) (*feedpb.ListArticlesToCheckResponse, error) {
@@ -388,7 +938,7 @@ func (h *FeedServiceHandler) ListArticlesToCheck(ctx context.Context, req *feedp
 		pageSize = 2000
 	}
 
-	items, nextToken, svcErr := h.articleService.ListArticlesToCheck(ctx, publishedSince, lastCheckedBefore, pageSize, req.PageToken)
+	items, nextToken, counts, svcErr := h.articleService.ListArticlesToCheck(ctx, publishedSince, lastCheckedBefore, pageSize, req.PageToken)
 	if svcErr != nil {
 		log.Error("failed to list articles to check", "error", svcErr)
 		return nil, h.mapErrorToGRPC(svcErr)
@@ -409,14 +959,22 @@ func (h *FeedServiceHandler) ListArticlesToCheck(ctx context.Context, req *feedp
 		}
 	}
 
+	feedCounts := make([]*feedpb.FeedCheckCount, len(counts.Feeds))
+	for i, feed := range counts.Feeds {
+		feedCounts[i] = &feedpb.FeedCheckCount{FeedId: uint64(feed.FeedID), Count: uint64(feed.Count)}
+	}
+
 	log.Info("successfully listed articles to check",
 		"count", len(pbItems),
+		"remaining", counts.Remaining,
 		"has_next", nextToken != "",
 	)
 
 	return &feedpb.ListArticlesToCheckResponse{
 		Items:         pbItems,
 		NextPageToken: nextToken,
+		Remaining:     uint64(counts.Remaining),
+		FeedCounts:    feedCounts,
 	}, nil
 }
 
@@ -425,6 +983,15 @@ func (h *FeedServiceHandler) mapErrorToGRPC(err error) error {
 	if err == ierr.ErrNotSubscribed {
 		return status.Error(codes.PermissionDenied, "Not subscribed to this feed")
 	}
+	if errors.Is(err, ierr.ErrSubscriptionLimit) {
+		return status.Error(codes.ResourceExhausted, ierr.ErrSubscriptionLimit.Message)
+	}
+	// a feed's server failing is not this service failing: Unavailable or DeadlineExceeded
+	// would be retried and trip the api-service's circuit breaker
+	var appErr *ierr.AppError
+	if ierr.IsUpstreamError(err) && errors.As(err, &appErr) {
+		return status.Error(codes.FailedPrecondition, appErr.Message)
+	}
 
 	switch {
 	case ierr.IsValidationError(err):
@@ -443,19 +1010,76 @@ func (h *FeedServiceHandler) mapErrorToGRPC(err error) error {
 	}
 }
 
+func toProtoFeed(feed *models.Feed) *feedpb.Feed {
+	pb := &feedpb.Feed{
+		Id:                  uint64(feed.ID),
+		Title:               feed.Title,
+		Url:                 feed.URL,
+		Description:         feed.Description,
+		Status:              string(feed.Status),
+		CreatedAt:           feed.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:           feed.UpdatedAt.Format(time.RFC3339),
+		FetchTier:           string(feed.FetchTier),
+		ConsecutiveFailures: uint32(feed.ConsecutiveFailures),
+		SiteUrl:             feed.SiteURL,
+		DatesUnreliable:     feed.DatesUnreliable,
+		SubscriberCount:     uint32(feed.SubscriberCount),
+	}
+	if feed.LastFetchError != nil {
+		pb.LastFetchError = *feed.LastFetchError
+	}
+	if feed.LastFetchedAt != nil {
+		pb.LastFetchedAt = feed.LastFetchedAt.UTC().Format(time.RFC3339)
+	}
+	return pb
+}
+
+func toProtoFeedHealth(health *models.FeedHealth) *feedpb.FeedHealth {
+	pb := &feedpb.FeedHealth{
+		FeedId:              uint64(health.FeedID),
+		Status:              string(health.Status),
+		ConsecutiveFailures: uint32(health.ConsecutiveFailures),
+		FailureThreshold:    uint32(health.FailureThreshold),
+		DatesUnreliable:     health.DatesUnreliable,
+	}
+	if health.LastFetchedAt != nil {
+		pb.LastFetchedAt = health.LastFetchedAt.UTC().Format(time.RFC3339)
+	}
+	if health.LastError != nil {
+		pb.LastFetchError = *health.LastError
+	}
+	if health.LastErrorAt != nil {
+		pb.LastFetchErrorAt = health.LastErrorAt.UTC().Format(time.RFC3339)
+	}
+	return pb
+}
+
+func toProtoUserFeed(feed *models.UserFeed) *feedpb.Feed {
+	pb := toProtoFeed(&feed.Feed)
+	pb.CustomTitle = feed.CustomTitle
+	pb.Notes = feed.Notes
+	pb.HasFetchHeaders = feed.HasFetchHeaders
+	return pb
+}
+
 func toProtoArticle(article *models.Article) *feedpb.Article {
 	pb := &feedpb.Article{
-		Id:          uint64(article.ID),
-		FeedId:      uint64(article.FeedID),
-		Title:       article.Title,
-		Url:         article.URL,
-		Description: article.Description,
-		Content:     article.Content,
-		CreatedAt:   article.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:   article.UpdatedAt.Format(time.RFC3339),
-		Read:        article.Read,
-		Starred:     article.Starred,
-		PublishedAt: article.PublishedAt.Format(time.RFC3339),
+		Id:               uint64(article.ID),
+		FeedId:           uint64(article.FeedID),
+		Title:            article.Title,
+		Url:              article.URL,
+		Description:      article.Description,
+		Content:          article.Content,
+		CreatedAt:        article.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:        article.UpdatedAt.Format(time.RFC3339),
+		Read:             article.Read,
+		Starred:          article.Starred,
+		PublishedAt:      article.PublishedAt.Format(time.RFC3339),
+		SummaryTruncated: article.SummaryTruncated,
+		ProcessingStatus: string(article.ProcessingStatus),
+		Direction:        article.Direction,
+		Tags:             article.Tags,
+		Topics:           article.Topics,
 	}
 
 	if article.Summary != nil {
@@ -464,9 +1088,15 @@ func toProtoArticle(article *models.Article) *feedpb.Article {
 	if article.ProcessingModel != nil {
 		pb.ProcessingModel = *article.ProcessingModel
 	}
+	if article.ProcessingPrompt != nil {
+		pb.ProcessingPrompt = *article.ProcessingPrompt
+	}
 	if article.ProcessedAt != nil {
 		pb.ProcessedAt = article.ProcessedAt.Format(time.RFC3339)
 	}
+	if article.ProcessingError != nil {
+		pb.ProcessingError = *article.ProcessingError
+	}
 	if article.LastCheckedAt != nil {
 		pb.LastCheckedAt = article.LastCheckedAt.Format(time.RFC3339)
 	}
@@ -476,6 +1106,9 @@ func toProtoArticle(article *models.Article) *feedpb.Article {
 	if article.HTTPLastModified != nil {
 		pb.HttpLastModified = *article.HTTPLastModified
 	}
+	if article.ContentType != nil {
+		pb.ContentType = *article.ContentType
+	}
 
 	return pb
 }
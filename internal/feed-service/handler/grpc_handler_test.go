@@ -2,14 +2,18 @@ package handler
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/Fancu1/phoenix-rss/internal/events"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/core"
@@ -48,21 +52,147 @@ func (m *mockArticleService) GetArticleByID(ctx context.Context, userID, article
 	return nil, args.Error(1)
 }
 
+func (m *mockArticleService) OpenArticle(ctx context.Context, userID, articleID uint) (*models.Article, error) {
+	args := m.Called(ctx, userID, articleID)
+	if v := args.Get(0); v != nil {
+		return v.(*models.Article), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockArticleService) GetArticleNavigation(ctx context.Context, userID, articleID uint) (*models.ArticleNavigation, error) {
+	args := m.Called(ctx, userID, articleID)
+	if v := args.Get(0); v != nil {
+		return v.(*models.ArticleNavigation), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func (m *mockArticleService) HandleArticleProcessed(ctx context.Context, event *article_eventspb.ArticleProcessedEvent) error {
 	args := m.Called(ctx, event)
 	return args.Error(0)
 }
 
-func (m *mockArticleService) ListArticlesToCheck(ctx context.Context, publishedSince, lastCheckedBefore time.Time, pageSize int, pageToken string) ([]repository.ArticleCheckCandidate, string, error) {
+func (m *mockArticleService) HandleArticlesProcessed(ctx context.Context, events []*article_eventspb.ArticleProcessedEvent) error {
+	args := m.Called(ctx, events)
+	return args.Error(0)
+}
+
+func (m *mockArticleService) RegenerateSummary(ctx context.Context, userID, articleID uint) error {
+	args := m.Called(ctx, userID, articleID)
+	return args.Error(0)
+}
+
+func (m *mockArticleService) DeleteArticle(ctx context.Context, userID, articleID uint) error {
+	args := m.Called(ctx, userID, articleID)
+	return args.Error(0)
+}
+
+func (m *mockArticleService) RestoreArticle(ctx context.Context, userID, articleID uint) (*models.Article, error) {
+	args := m.Called(ctx, userID, articleID)
+	var article *models.Article
+	if v := args.Get(0); v != nil {
+		article = v.(*models.Article)
+	}
+	return article, args.Error(1)
+}
+
+func (m *mockArticleService) NextUnreadArticle(ctx context.Context, userID uint, opts core.NextUnreadOptions) (*models.Article, error) {
+	args := m.Called(ctx, userID, opts)
+	var article *models.Article
+	if v := args.Get(0); v != nil {
+		article = v.(*models.Article)
+	}
+	return article, args.Error(1)
+}
+
+func (m *mockArticleService) SearchArticles(ctx context.Context, userID uint, opts core.SearchOptions) ([]*models.Article, int64, error) {
+	args := m.Called(ctx, userID, opts)
+	var articles []*models.Article
+	if v := args.Get(0); v != nil {
+		articles = v.([]*models.Article)
+	}
+	return articles, args.Get(1).(int64), args.Error(2)
+}
+
+func (m *mockArticleService) SetArticleRead(ctx context.Context, userID, articleID uint, read bool) (*models.Article, error) {
+	args := m.Called(ctx, userID, articleID, read)
+	if v := args.Get(0); v != nil {
+		return v.(*models.Article), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockArticleService) MarkFeedRead(ctx context.Context, userID, feedID uint, before time.Time) (int, error) {
+	args := m.Called(ctx, userID, feedID, before)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockArticleService) SetArticleStarred(ctx context.Context, userID, articleID uint, starred bool) (*models.Article, error) {
+	args := m.Called(ctx, userID, articleID, starred)
+	if v := args.Get(0); v != nil {
+		return v.(*models.Article), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *mockArticleService) ListStarredArticles(ctx context.Context, userID uint, offset, limit int) ([]*models.Article, int64, error) {
+	args := m.Called(ctx, userID, offset, limit)
+	var articles []*models.Article
+	if v := args.Get(0); v != nil {
+		articles = v.([]*models.Article)
+	}
+	return articles, args.Get(1).(int64), args.Error(2)
+}
+
+func (m *mockArticleService) ListUserArticles(ctx context.Context, userID uint, filter core.TimelineFilter, pageSize int, pageToken string) ([]*models.Article, string, int64, error) {
+	args := m.Called(ctx, userID, filter, pageSize, pageToken)
+	var articles []*models.Article
+	if v := args.Get(0); v != nil {
+		articles = v.([]*models.Article)
+	}
+	return articles, args.String(1), args.Get(2).(int64), args.Error(3)
+}
+
+func (m *mockArticleService) AddArticleTags(ctx context.Context, userID, articleID uint, names []string) (*models.Article, error) {
+	args := m.Called(ctx, userID, articleID, names)
+	var article *models.Article
+	if v := args.Get(0); v != nil {
+		article = v.(*models.Article)
+	}
+	return article, args.Error(1)
+}
+
+func (m *mockArticleService) RemoveArticleTags(ctx context.Context, userID, articleID uint, names []string) (*models.Article, error) {
+	args := m.Called(ctx, userID, articleID, names)
+	var article *models.Article
+	if v := args.Get(0); v != nil {
+		article = v.(*models.Article)
+	}
+	return article, args.Error(1)
+}
+
+func (m *mockArticleService) ListTags(ctx context.Context, userID uint) ([]models.TagCount, error) {
+	args := m.Called(ctx, userID)
+	var tags []models.TagCount
+	if v := args.Get(0); v != nil {
+		tags = v.([]models.TagCount)
+	}
+	return tags, args.Error(1)
+}
+
+func (m *mockArticleService) ListArticlesToCheck(ctx context.Context, publishedSince, lastCheckedBefore time.Time, pageSize int, pageToken string) ([]repository.ArticleCheckCandidate, string, repository.ArticleCheckCounts, error) {
 	args := m.Called(ctx, publishedSince, lastCheckedBefore, pageSize, pageToken)
 	var result []repository.ArticleCheckCandidate
 	if v := args.Get(0); v != nil {
 		result = v.([]repository.ArticleCheckCandidate)
 	}
-	return result, args.String(1), args.Error(2)
+	return result, args.String(1), args.Get(2).(repository.ArticleCheckCounts), args.Error(3)
 }
 
-type noopFeedService struct{}
+type noopFeedService struct {
+	core.FeedServiceInterface
+}
 
 func (noopFeedService) AddFeedByURL(ctx context.Context, url string) (*models.Feed, error) {
 	return nil, nil
@@ -71,7 +201,7 @@ func (noopFeedService) ListAllFeeds(ctx context.Context) ([]*models.Feed, error)
 func (noopFeedService) SubscribeToFeed(ctx context.Context, userID uint, url string) (*models.Feed, error) {
 	return nil, nil
 }
-func (noopFeedService) ListUserFeeds(ctx context.Context, userID uint) ([]*models.Feed, error) {
+func (noopFeedService) ListUserFeeds(ctx context.Context, userID uint) ([]*models.UserFeed, error) {
 	return nil, nil
 }
 func (noopFeedService) UnsubscribeFromFeed(ctx context.Context, userID, feedID uint) error {
@@ -84,6 +214,193 @@ func (noopFeedService) BatchSubscribeToFeeds(ctx context.Context, userID uint, u
 	return nil, nil
 }
 
+// pagingFeedService records the feed page requested through ListAllFeeds
+type pagingFeedService struct {
+	noopFeedService
+	filter    repository.FeedListFilter
+	pageSize  int
+	pageToken string
+}
+
+func (p *pagingFeedService) ListFeedsPage(ctx context.Context, filter repository.FeedListFilter, pageSize int, pageToken string) ([]*core.SchedulableFeed, string, error) {
+	p.filter, p.pageSize, p.pageToken = filter, pageSize, pageToken
+	feed := &models.Feed{ID: 7, Title: "Paged", Status: models.FeedStatusActive, SubscriberCount: 2}
+	return []*core.SchedulableFeed{{Feed: feed, OwnerUserID: 3}}, "next", nil
+}
+
+func TestListAllFeeds_Paged(t *testing.T) {
+	feeds := &pagingFeedService{}
+	h := NewFeedServiceHandler(slogDiscard(), feeds, new(mockArticleService), events.Producer(nil))
+
+	resp, err := h.ListAllFeeds(context.Background(), &feedpb.ListAllFeedsRequest{
+		ExcludeArchived: true,
+		PageSize:        5000,
+		PageToken:       "token",
+		Status:          "active",
+		DueBefore:       "2024-01-01T00:00:00Z",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "next", resp.NextPageToken)
+	require.Len(t, resp.Feeds, 1)
+	assert.Equal(t, uint64(3), resp.Feeds[0].OwnerUserId)
+	assert.Equal(t, uint32(2), resp.Feeds[0].SubscriberCount)
+
+	assert.Equal(t, 2000, feeds.pageSize, "page size is capped")
+	assert.Equal(t, "token", feeds.pageToken)
+	assert.True(t, feeds.filter.ExcludeArchived)
+	assert.Equal(t, models.FeedStatusActive, feeds.filter.Status)
+	require.NotNil(t, feeds.filter.DueBefore)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), feeds.filter.DueBefore.UTC())
+
+	_, err = h.ListAllFeeds(context.Background(), &feedpb.ListAllFeedsRequest{PageSize: 10, Status: "bogus"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = h.ListAllFeeds(context.Background(), &feedpb.ListAllFeedsRequest{PageSize: 10, DueBefore: "yesterday"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// existingFeedService knows the feeds at its URLs
+type existingFeedService struct {
+	noopFeedService
+	urls []string
+}
+
+func (e existingFeedService) ExistsByURLs(ctx context.Context, urls []string) (map[string]bool, error) {
+	exists := make(map[string]bool)
+	for _, url := range urls {
+		exists[url] = slices.Contains(e.urls, url)
+	}
+	return exists, nil
+}
+
+func TestExistsByURLs(t *testing.T) {
+	feeds := existingFeedService{urls: []string{"https://a.example.com/feed", "https://c.example.com/feed"}}
+	h := NewFeedServiceHandler(slogDiscard(), feeds, new(mockArticleService), events.Producer(nil))
+
+	resp, err := h.ExistsByURLs(context.Background(), &feedpb.ExistsByURLsRequest{Urls: []string{
+		"https://c.example.com/feed",
+		"https://b.example.com/feed",
+		"https://a.example.com/feed",
+		"https://c.example.com/feed",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://c.example.com/feed", "https://a.example.com/feed"}, resp.ExistingUrls, "request order, without repeats")
+
+	resp, err = h.ExistsByURLs(context.Background(), &feedpb.ExistsByURLsRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, resp.ExistingUrls)
+}
+
+// deletingFeedService records the retention DeleteFeed was called with
+type deletingFeedService struct {
+	noopFeedService
+	retention models.FeedRetention
+}
+
+func (d *deletingFeedService) DeleteFeed(ctx context.Context, feedID uint, retention models.FeedRetention) (*models.FeedDeletion, error) {
+	if feedID == 404 {
+		return nil, ierr.ErrFeedNotFound
+	}
+	d.retention = retention
+	applied := retention
+	if applied == "" {
+		applied = models.FeedRetentionArchive
+	}
+	return &models.FeedDeletion{FeedID: feedID, Retention: applied, UserIDs: []uint{1, 2}, ArticlesPurged: 3}, nil
+}
+
+func TestDeleteFeed(t *testing.T) {
+	feeds := &deletingFeedService{}
+	h := NewFeedServiceHandler(slogDiscard(), feeds, new(mockArticleService), events.Producer(nil))
+	ctx := context.Background()
+
+	resp, err := h.DeleteFeed(ctx, &feedpb.DeleteFeedRequest{FeedId: 7, Retention: "PURGE"})
+	require.NoError(t, err)
+	assert.Equal(t, models.FeedRetentionPurge, feeds.retention)
+	assert.Equal(t, []uint64{1, 2}, resp.UnsubscribedUserIds)
+	assert.Equal(t, int64(3), resp.ArticlesPurged)
+
+	resp, err = h.DeleteFeed(ctx, &feedpb.DeleteFeedRequest{FeedId: 7})
+	require.NoError(t, err)
+	assert.Empty(t, feeds.retention, "an empty retention leaves the choice to the service default")
+	assert.Equal(t, "archive", resp.Retention)
+
+	_, err = h.DeleteFeed(ctx, &feedpb.DeleteFeedRequest{FeedId: 7, Retention: "shred"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = h.DeleteFeed(ctx, &feedpb.DeleteFeedRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = h.DeleteFeed(ctx, &feedpb.DeleteFeedRequest{FeedId: 404})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// failingFeedService fails every subscription with err
+type failingFeedService struct {
+	noopFeedService
+	err error
+}
+
+func (f failingFeedService) SubscribeToFeed(ctx context.Context, userID uint, url string) (*models.Feed, error) {
+	return nil, f.err
+}
+
+func TestSubscribeToFeed_UpstreamErrors(t *testing.T) {
+	ctx := context.Background()
+	req := &feedpb.SubscribeToFeedRequest{UserId: 1, FeedUrl: "https://example.com/feed"}
+
+	wrapped := fmt.Errorf("failed to parse feed from URL: %w", ierr.ErrTooManyRequests.WithCause(assert.AnError))
+	h := NewFeedServiceHandler(slogDiscard(), failingFeedService{err: wrapped}, new(mockArticleService), events.Producer(nil))
+	_, err := h.SubscribeToFeed(ctx, req)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "the feed's server failing must not look like this service being unavailable")
+	assert.Equal(t, ierr.ErrTooManyRequests.Message, status.Convert(err).Message())
+
+	h = NewFeedServiceHandler(slogDiscard(), failingFeedService{err: assert.AnError}, new(mockArticleService), events.Producer(nil))
+	_, err = h.SubscribeToFeed(ctx, req)
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+// bulkFeedService records the filter and action BulkUpdateFeeds was called with
+type bulkFeedService struct {
+	noopFeedService
+	filter repository.FeedListFilter
+	update models.FeedBulkUpdate
+}
+
+func (b *bulkFeedService) BulkUpdateFeeds(ctx context.Context, filter repository.FeedListFilter, update models.FeedBulkUpdate) (*models.FeedBulkResult, error) {
+	b.filter, b.update = filter, update
+	return &models.FeedBulkResult{Action: update.Action, Matched: 4, Updated: 3}, nil
+}
+
+func TestBulkUpdateFeeds(t *testing.T) {
+	feeds := &bulkFeedService{}
+	h := NewFeedServiceHandler(slogDiscard(), feeds, new(mockArticleService), events.Producer(nil))
+	ctx := context.Background()
+
+	resp, err := h.BulkUpdateFeeds(ctx, &feedpb.BulkUpdateFeedsRequest{
+		Action:          "set_tier",
+		Status:          "error",
+		ErrorContains:   " 503 ",
+		NotFetchedSince: "2024-01-01T00:00:00Z",
+		FeedIds:         []uint64{4, 5},
+		TargetTier:      "low",
+		DryRun:          true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), resp.Matched)
+	assert.Equal(t, uint64(3), resp.Updated)
+	assert.Equal(t, models.FeedBulkUpdate{Action: models.FeedBulkSetTier, Tier: models.FeedTierLow, DryRun: true}, feeds.update)
+	assert.Equal(t, models.FeedStatusError, feeds.filter.Status)
+	assert.Equal(t, "503", feeds.filter.ErrorContains)
+	assert.Equal(t, []uint{4, 5}, feeds.filter.IDs)
+	require.NotNil(t, feeds.filter.NotFetchedSince)
+	assert.False(t, feeds.filter.ExcludeArchived)
+
+	_, err = h.BulkUpdateFeeds(ctx, &feedpb.BulkUpdateFeedsRequest{Action: "delete", Status: "error"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = h.BulkUpdateFeeds(ctx, &feedpb.BulkUpdateFeedsRequest{Action: "suspend", FetchTier: "urgent"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = h.BulkUpdateFeeds(ctx, &feedpb.BulkUpdateFeedsRequest{Action: "suspend", NotFetchedSince: "last week"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
 func TestListArticlesToCheck_Success(t *testing.T) {
 	mockArticles := new(mockArticleService)
 	h := NewFeedServiceHandler(slogDiscard(), noopFeedService{}, mockArticles, events.Producer(nil))
@@ -95,7 +412,8 @@ func TestListArticlesToCheck_Success(t *testing.T) {
 		{ID: 1, FeedID: 2, URL: "https://example.com", HTTPETag: strPtr("etag"), HTTPLastModified: strPtr("2024-01-01T00:00:00Z")},
 	}
 
-	mockArticles.On("ListArticlesToCheck", mock.Anything, publishedSince, lastCheckedBefore, 25, "").Return(candidates, "next", nil)
+	mockArticles.On("ListArticlesToCheck", mock.Anything, publishedSince, lastCheckedBefore, 25, "").
+		Return(candidates, "next", repository.ArticleCheckCounts{Remaining: 40, Feeds: []repository.FeedCheckCount{{FeedID: 2, Count: 30}, {FeedID: 5, Count: 10}}}, nil)
 
 	req := &feedpb.ListArticlesToCheckRequest{
 		PublishedSince:    publishedSince.Format(time.RFC3339),
@@ -110,6 +428,10 @@ func TestListArticlesToCheck_Success(t *testing.T) {
 	require.Len(t, resp.Items, 1)
 	assert.Equal(t, uint64(1), resp.Items[0].ArticleId)
 	assert.Equal(t, "etag", resp.Items[0].PrevEtag)
+	assert.Equal(t, uint64(40), resp.Remaining)
+	require.Len(t, resp.FeedCounts, 2)
+	assert.Equal(t, uint64(2), resp.FeedCounts[0].FeedId)
+	assert.Equal(t, uint64(30), resp.FeedCounts[0].Count)
 
 	mockArticles.AssertExpectations(t)
 }
@@ -130,7 +452,7 @@ func TestListArticlesToCheck_ServiceError(t *testing.T) {
 	publishedSince := time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Second)
 	lastCheckedBefore := time.Now().Add(-4 * time.Hour).UTC().Truncate(time.Second)
 
-	mockArticles.On("ListArticlesToCheck", mock.Anything, publishedSince, lastCheckedBefore, 500, "").Return(nil, "", ierr.NewDatabaseError(assert.AnError))
+	mockArticles.On("ListArticlesToCheck", mock.Anything, publishedSince, lastCheckedBefore, 500, "").Return(nil, "", repository.ArticleCheckCounts{}, ierr.NewDatabaseError(assert.AnError))
 
 	req := &feedpb.ListArticlesToCheckRequest{
 		PublishedSince:    publishedSince.Format(time.RFC3339),
@@ -148,3 +470,112 @@ func slogDiscard() *slog.Logger {
 }
 
 func strPtr(s string) *string { return &s }
+
+func TestRegenerateSummary(t *testing.T) {
+	mockArticles := new(mockArticleService)
+	h := NewFeedServiceHandler(slogDiscard(), noopFeedService{}, mockArticles, events.Producer(nil))
+
+	mockArticles.On("RegenerateSummary", mock.Anything, uint(1), uint(7)).Return(nil)
+	mockArticles.On("RegenerateSummary", mock.Anything, uint(1), uint(8)).Return(ierr.NewValidationError("article summary is not truncated"))
+
+	resp, err := h.RegenerateSummary(context.Background(), &feedpb.RegenerateSummaryRequest{UserId: 1, ArticleId: 7})
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+
+	_, err = h.RegenerateSummary(context.Background(), &feedpb.RegenerateSummaryRequest{UserId: 1, ArticleId: 8})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = h.RegenerateSummary(context.Background(), &feedpb.RegenerateSummaryRequest{UserId: 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mockArticles.AssertExpectations(t)
+}
+
+func TestNextUnreadArticle(t *testing.T) {
+	mockArticles := new(mockArticleService)
+	h := NewFeedServiceHandler(slogDiscard(), noopFeedService{}, mockArticles, events.Producer(nil))
+
+	opts := core.NextUnreadOptions{AfterID: 7, Scope: core.NextUnreadScopeFeed, MarkRead: true}
+	mockArticles.On("NextUnreadArticle", mock.Anything, uint(1), opts).Return(&models.Article{ID: 6, FeedID: 2}, nil)
+	mockArticles.On("NextUnreadArticle", mock.Anything, uint(1), core.NextUnreadOptions{AfterID: 6, Scope: core.NextUnreadScopeAll}).Return(nil, nil)
+
+	resp, err := h.NextUnreadArticle(context.Background(), &feedpb.NextUnreadArticleRequest{UserId: 1, AfterArticleId: 7, Scope: "feed", MarkRead: true})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Article)
+	assert.Equal(t, uint64(6), resp.Article.Id)
+
+	resp, err = h.NextUnreadArticle(context.Background(), &feedpb.NextUnreadArticleRequest{UserId: 1, AfterArticleId: 6, Scope: "all"})
+	require.NoError(t, err)
+	assert.Nil(t, resp.Article, "nothing left to read")
+
+	_, err = h.NextUnreadArticle(context.Background(), &feedpb.NextUnreadArticleRequest{AfterArticleId: 6})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mockArticles.AssertExpectations(t)
+}
+
+func TestSearchArticles(t *testing.T) {
+	mockArticles := new(mockArticleService)
+	h := NewFeedServiceHandler(slogDiscard(), noopFeedService{}, mockArticles, events.Producer(nil))
+
+	opts := core.SearchOptions{Query: "kafka -zookeeper", Offset: 20, Limit: 10}
+	mockArticles.On("SearchArticles", mock.Anything, uint(1), opts).Return([]*models.Article{{ID: 9, FeedID: 2}}, int64(21), nil)
+	mockArticles.On("SearchArticles", mock.Anything, uint(1), core.SearchOptions{}).Return(nil, int64(0), ierr.NewValidationError("search query is required"))
+
+	resp, err := h.SearchArticles(context.Background(), &feedpb.SearchArticlesRequest{UserId: 1, Query: "kafka -zookeeper", Offset: 20, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, resp.Articles, 1)
+	assert.Equal(t, uint64(9), resp.Articles[0].Id)
+	assert.Equal(t, int64(21), resp.Total)
+
+	_, err = h.SearchArticles(context.Background(), &feedpb.SearchArticlesRequest{UserId: 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = h.SearchArticles(context.Background(), &feedpb.SearchArticlesRequest{Query: "kafka"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mockArticles.AssertExpectations(t)
+}
+
+func TestMarkFeedRead(t *testing.T) {
+	mockArticles := new(mockArticleService)
+	h := NewFeedServiceHandler(slogDiscard(), noopFeedService{}, mockArticles, events.Producer(nil))
+
+	before := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	mockArticles.On("MarkFeedRead", mock.Anything, uint(1), uint(2), before).Return(12, nil)
+	mockArticles.On("MarkFeedRead", mock.Anything, uint(1), uint(3), time.Time{}).Return(0, ierr.ErrNotSubscribed)
+
+	resp, err := h.MarkFeedRead(context.Background(), &feedpb.MarkFeedReadRequest{UserId: 1, FeedId: 2, Before: before.Format(time.RFC3339)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), resp.Marked)
+
+	_, err = h.MarkFeedRead(context.Background(), &feedpb.MarkFeedReadRequest{UserId: 1, FeedId: 3})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = h.MarkFeedRead(context.Background(), &feedpb.MarkFeedReadRequest{UserId: 1, FeedId: 2, Before: "yesterday"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mockArticles.AssertExpectations(t)
+}
+
+func TestGetArticle_Navigation(t *testing.T) {
+	mockArticles := new(mockArticleService)
+	h := NewFeedServiceHandler(slogDiscard(), noopFeedService{}, mockArticles, events.Producer(nil))
+
+	next := uint(6)
+	mockArticles.On("OpenArticle", mock.Anything, uint(1), uint(7)).Return(&models.Article{ID: 7, FeedID: 2}, nil)
+	mockArticles.On("GetArticleNavigation", mock.Anything, uint(1), uint(7)).Return(&models.ArticleNavigation{
+		Feed:          models.ArticleFeedInfo{ID: 2, Title: "My feed", URL: "https://example.com/feed.xml", IconURL: "https://example.com/favicon.ico"},
+		NextArticleID: &next,
+	}, nil)
+
+	resp, err := h.GetArticle(context.Background(), &feedpb.GetArticleRequest{UserId: 1, ArticleId: 7})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), resp.Article.Id)
+	assert.Equal(t, "My feed", resp.Feed.Title)
+	assert.Equal(t, "https://example.com/favicon.ico", resp.Feed.IconUrl)
+	assert.Equal(t, uint64(0), resp.PreviousArticleId, "first in the feed")
+	assert.Equal(t, uint64(6), resp.NextArticleId)
+
+	mockArticles.AssertExpectations(t)
+}
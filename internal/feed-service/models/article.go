@@ -1,7 +1,17 @@
 package models
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
 
+	"gorm.io/gorm"
+)
+
+// Article is an entry of a feed. Read and Starred are the requesting user's own state,
+// filled in from user_article_states; the shared read and starred columns are no longer
+// written.
 type Article struct {
 	ID               uint       `json:"id"`
 	FeedID           uint       `json:"feed_id"`
@@ -17,9 +27,101 @@ type Article struct {
 	LastCheckedAt    *time.Time `json:"last_checked_at,omitempty" gorm:"column:last_checked_at"`
 	HTTPETag         *string    `json:"http_etag,omitempty" gorm:"column:http_etag"`
 	HTTPLastModified *string    `json:"http_last_modified,omitempty" gorm:"column:http_last_modified"`
+	// ContentType is the media type of the article page as last fetched by the update
+	// checker, e.g. text/html or application/pdf; only HTML and plain text pages are stored
+	// as content
+	ContentType *string `json:"content_type,omitempty" gorm:"size:100"`
+	// Direction is the text direction to render the content in, ltr or rtl, taken from
+	// its markup or language or detected from its script
+	Direction string `json:"direction" gorm:"size:3;default:ltr"`
 
 	// AI processing fields
-	Summary         *string    `json:"summary,omitempty"`
-	ProcessingModel *string    `json:"processing_model,omitempty"`
-	ProcessedAt     *time.Time `json:"processed_at,omitempty"`
+	Summary          *string    `json:"summary,omitempty"`
+	SummaryTruncated bool       `json:"summary_truncated" gorm:"default:false"`
+	ProcessingModel  *string    `json:"processing_model,omitempty"`
+	ProcessedAt      *time.Time `json:"processed_at,omitempty"`
+	// ProcessingPrompt names the prompt variant the summary was made with
+	ProcessingPrompt *string `json:"processing_prompt,omitempty" gorm:"size:64"`
+	// ProcessingStatus tracks the article through AI processing; ProcessingError holds the
+	// error class once it has failed for good
+	ProcessingStatus ProcessingStatus `json:"processing_status" gorm:"default:pending"`
+	ProcessingError  *string          `json:"processing_error,omitempty"`
+	// Topics are the article's topics the AI listed along with the default summary
+	Topics Topics `json:"topics,omitempty" gorm:"type:jsonb"`
+
+	// DeletedAt soft-deletes the article: GORM leaves it out of every query unless
+	// Unscoped is used, and it stays restorable until the trash grace period ends
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// RequestID is the request whose fetch saved the article and ProcessingRequestID the
+	// one whose AI result was last recorded, to trace them in the logs
+	RequestID           *string `json:"-" gorm:"size:64"`
+	ProcessingRequestID *string `json:"-" gorm:"size:64"`
+
+	// Summaries are the article's summary variants, loaded when a reader asks for them.
+	// Summary and its fields above then hold the variant the reader prefers.
+	Summaries []ArticleSummary `json:"summaries,omitempty" gorm:"-"`
+	// Tags are the names of the requesting user's tags on the article, by name
+	Tags []string `json:"tags,omitempty" gorm:"-"`
+}
+
+// Topics is a list of topics stored as a JSON array; an empty list is stored as NULL
+type Topics []string
+
+// Value implements driver.Valuer
+func (t Topics) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+	raw, err := json.Marshal([]string(t))
+	return string(raw), err
+}
+
+// Scan implements sql.Scanner
+func (t *Topics) Scan(value any) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return fmt.Errorf("cannot scan %T into Topics", value)
+	}
+	return json.Unmarshal(raw, (*[]string)(t))
+}
+
+// ProcessingStatus is where an article is in AI processing
+type ProcessingStatus string
+
+const (
+	// ProcessingPending articles are saved but not yet queued for processing
+	ProcessingPending ProcessingStatus = "pending"
+	// ProcessingInProgress articles were sent to the AI service and await a result
+	ProcessingInProgress ProcessingStatus = "processing"
+	// ProcessingSucceeded articles have a summary
+	ProcessingSucceeded ProcessingStatus = "succeeded"
+	// ProcessingFailed articles could not be summarized; ProcessingError holds the class
+	ProcessingFailed ProcessingStatus = "failed"
+)
+
+// ArticleFeedInfo is the feed shown along with one of its articles
+type ArticleFeedInfo struct {
+	ID uint `json:"id"`
+	// Title is the subscriber's custom title of the feed when set
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+// ArticleNavigation places an article within its feed's article list
+type ArticleNavigation struct {
+	Feed ArticleFeedInfo `json:"feed"`
+	// PreviousArticleID and NextArticleID are the articles listed right before and after,
+	// nil at either end of the list
+	PreviousArticleID *uint `json:"previous_article_id"`
+	NextArticleID     *uint `json:"next_article_id"`
 }
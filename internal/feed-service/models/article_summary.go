@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// ArticleSummary is one summary variant of an article, made by a model in a language.
+// The variant in the default language is also kept in Article.Summary for clients that
+// know only the one summary.
+type ArticleSummary struct {
+	ID        uint   `json:"-"`
+	ArticleID uint   `json:"-" gorm:"not null;uniqueIndex:idx_article_summaries_variant"`
+	Model     string `json:"model" gorm:"not null;default:'';uniqueIndex:idx_article_summaries_variant"`
+	// Language is the code of the language the summary is written in, e.g. zh or en
+	Language  string  `json:"language" gorm:"size:16;not null;uniqueIndex:idx_article_summaries_variant"`
+	Summary   string  `json:"summary" gorm:"not null"`
+	Truncated bool    `json:"truncated" gorm:"not null;default:false"`
+	Prompt    *string `json:"prompt,omitempty" gorm:"size:64"`
+	// RequestID is the request whose processing made the summary
+	RequestID *string   `json:"-" gorm:"size:64"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (ArticleSummary) TableName() string {
+	return "article_summaries"
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Collection is an operator-curated, ordered list of feeds that users subscribe to in one
+// go. Collections with SubscribeNewUsers set are the instance's default feeds, subscribed
+// for every user on registration.
+type Collection struct {
+	ID                uint             `json:"id"`
+	Slug              string           `json:"slug" gorm:"size:100;uniqueIndex"`
+	Name              string           `json:"name"`
+	Description       string           `json:"description"`
+	SubscribeNewUsers bool             `json:"subscribe_new_users"`
+	Feeds             []CollectionFeed `json:"feeds" gorm:"foreignKey:CollectionID;constraint:OnDelete:CASCADE"`
+	CreatedAt         time.Time        `json:"created_at"`
+	UpdatedAt         time.Time        `json:"updated_at"`
+}
+
+func (Collection) TableName() string {
+	return "feed_collections"
+}
+
+// URLs returns the URLs of the collection's feeds in order
+func (c *Collection) URLs() []string {
+	urls := make([]string, len(c.Feeds))
+	for i, feed := range c.Feeds {
+		urls[i] = feed.URL
+	}
+	return urls
+}
+
+// CollectionFeed is a feed of a collection, by URL since the instance may not know it yet
+type CollectionFeed struct {
+	CollectionID uint   `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	Position     int    `json:"position" gorm:"primaryKey;autoIncrement:false"`
+	URL          string `json:"url"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	// Subscribed reports whether the requesting user already follows the feed
+	Subscribed bool `json:"subscribed" gorm:"-"`
+	// SubscriberCount is how many users of the instance follow the feed, 0 while it is unknown
+	SubscriberCount int64 `json:"subscriber_count" gorm:"-"`
+}
+
+func (CollectionFeed) TableName() string {
+	return "feed_collection_feeds"
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// SubscriptionEngagement counts how many of a subscription's recent articles its user
+// read. It is recomputed periodically by the scheduler.
+type SubscriptionEngagement struct {
+	UserID uint `json:"-" gorm:"primaryKey"`
+	FeedID uint `json:"-" gorm:"primaryKey"`
+	// Delivered counts the articles that arrived since the user subscribed, within the window
+	Delivered  int64     `json:"delivered"`
+	ReadCount  int64     `json:"read"`
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+func (SubscriptionEngagement) TableName() string {
+	return "subscription_engagement"
+}
+
+// CleanupSuggestion is a subscribed feed its user does not read
+type CleanupSuggestion struct {
+	Feed *UserFeed `json:"feed"`
+	SubscriptionEngagement
+}
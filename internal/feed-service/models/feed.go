@@ -1,26 +1,170 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
 
 type FeedStatus string
 
 const (
-	FeedStatusActive FeedStatus = "active"
-	FeedStatusError  FeedStatus = "error"
+	FeedStatusActive   FeedStatus = "active"
+	FeedStatusError    FeedStatus = "error"
+	FeedStatusArchived FeedStatus = "archived" // source returned 404/410 for too long, no longer scheduled
+	// FeedStatusSuspended is set by an administrator; the feed is not fetched until reactivated
+	FeedStatusSuspended FeedStatus = "suspended"
 )
 
+// ParseFeedStatus validates a feed status name
+func ParseFeedStatus(value string) (FeedStatus, error) {
+	switch status := FeedStatus(strings.ToLower(strings.TrimSpace(value))); status {
+	case FeedStatusActive, FeedStatusError, FeedStatusArchived, FeedStatusSuspended:
+		return status, nil
+	}
+	return "", fmt.Errorf("unknown feed status %q", value)
+}
+
 type Feed struct {
 	ID          uint       `json:"id"`
 	Title       string     `json:"title"`
 	URL         string     `json:"url"`
 	Description string     `json:"description"`
 	Status      FeedStatus `json:"status"`
+	GoneSince   *time.Time `json:"gone_since,omitempty"`  // first 404/410 of the current failure streak
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"` // set when the feed was archived as dead
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// SiteURL is the website the feed belongs to, as the feed's own link declares
+	SiteURL string `json:"site_url,omitempty"`
+
+	// LastFetchError is the root cause of the most recent failed fetch
+	LastFetchError   *string    `json:"last_fetch_error,omitempty"`
+	LastFetchErrorAt *time.Time `json:"last_fetch_error_at,omitempty"`
+	// LastFetchedAt is when the scheduler's last fetch attempt finished, successful or not
+	LastFetchedAt *time.Time `json:"last_fetched_at,omitempty"`
+	// HTTPETag and HTTPLastModified are the validators of the last feed response, sent
+	// back on the next fetch so an unchanged feed is answered with 304 Not Modified
+	HTTPETag         *string `json:"-" gorm:"column:http_etag"`
+	HTTPLastModified *string `json:"-" gorm:"column:http_last_modified"`
+	// FetchTier decides how often the scheduler fetches the feed
+	FetchTier FeedTier `json:"fetch_tier" gorm:"size:16;not null;default:normal"`
+	// LastFetchRequestID is the request that caused the last fetch, to trace it in the logs
+	LastFetchRequestID *string `json:"-" gorm:"size:64"`
+	// ConsecutiveFailures counts the fetches that failed since the last successful one
+	ConsecutiveFailures int `json:"consecutive_failures" gorm:"not null;default:0"`
+	// DatesUnreliable is set while the feed has items without a usable date, which are
+	// dated from their place in the feed instead
+	DatesUnreliable bool `json:"dates_unreliable" gorm:"not null;default:false"`
+	// ImportedSince holds back the items published before it, which the import limits
+	// left out, until phoenix-admin feeds backfill imports them
+	ImportedSince *time.Time `json:"imported_since,omitempty"`
+	// SubscriberCount is kept by a trigger on subscriptions, so it is read-only here and
+	// saving a feed never overwrites it
+	SubscriberCount int64 `json:"subscriber_count" gorm:"->;not null;default:0"`
+}
+
+// FeedMetadata is what a feed says about itself, refreshed on every fetch
+type FeedMetadata struct {
+	Title       string
+	Description string
+	SiteURL     string
+}
+
+// UserFeedsCacheKeyPattern is the Redis key, formatted with a user ID, under which the
+// api-service caches the user's feed list. Whatever changes what the list shows deletes
+// the key of every user concerned.
+const UserFeedsCacheKeyPattern = "user:%d:feeds"
+
+// FeedHealth tells a subscriber how well fetching a feed is going
+type FeedHealth struct {
+	FeedID        uint       `json:"feed_id"`
+	Status        FeedStatus `json:"status"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+	// LastError is the root cause of the most recent failed fetch, kept after the feed
+	// recovers; ConsecutiveFailures tells whether it still fails
+	LastError           *string    `json:"last_error"`
+	LastErrorAt         *time.Time `json:"last_error_at"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	// FailureThreshold is the streak of failures at which the feed is set to error
+	FailureThreshold int `json:"failure_threshold"`
+	// DatesUnreliable tells that the last fetch found items without a usable date
+	DatesUnreliable bool `json:"dates_unreliable"`
+}
+
+// FeedIconURL is the favicon of the site serving a feed, or "" for an unparsable URL.
+// Feeds rarely declare an icon of their own, so clients fall back to the site's favicon.
+func FeedIconURL(feedURL string) string {
+	u, err := url.Parse(feedURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + "/favicon.ico"
 }
 
-// UserFeed represents a feed from the user's perspective, including custom title
+// FeedRetention decides what happens to the articles of a feed an administrator deletes
+type FeedRetention string
+
+const (
+	// FeedRetentionArchive keeps the feed archived, unscheduled, with its articles, so a
+	// later subscriber gets the history back
+	FeedRetentionArchive FeedRetention = "archive"
+	// FeedRetentionPurge deletes the feed with its articles and snapshots for good
+	FeedRetentionPurge FeedRetention = "purge"
+)
+
+// ParseFeedRetention validates a retention policy name
+func ParseFeedRetention(value string) (FeedRetention, error) {
+	switch retention := FeedRetention(strings.ToLower(strings.TrimSpace(value))); retention {
+	case FeedRetentionArchive, FeedRetentionPurge:
+		return retention, nil
+	}
+	return "", fmt.Errorf("unknown feed retention %q, want %q or %q", value, FeedRetentionArchive, FeedRetentionPurge)
+}
+
+// FeedDeletion reports what deleting a feed did
+type FeedDeletion struct {
+	FeedID    uint          `json:"feed_id"`
+	Retention FeedRetention `json:"retention"`
+	// UserIDs were subscribed to the feed and have been unsubscribed
+	UserIDs []uint `json:"unsubscribed_user_ids"`
+	// ArticlesPurged counts the articles deleted; archived feeds keep theirs
+	ArticlesPurged int64 `json:"articles_purged"`
+}
+
+// UserFeed represents a feed from the user's perspective, including custom title and notes
 type UserFeed struct {
 	Feed
 	CustomTitle *string `json:"custom_title,omitempty"`
+	Notes       *string `json:"notes,omitempty"`
+	FolderID    *uint   `json:"folder_id,omitempty"`
+	// FolderPath names the folder the feed is filed in and its parents, top-level first
+	FolderPath []string `json:"folder_path,omitempty"`
+	// HasFetchHeaders tells whether custom fetch headers are set; their values are never returned
+	HasFetchHeaders bool `json:"has_fetch_headers"`
+}
+
+// Matches reports whether the feed's title, custom title, URL or notes contain the
+// query, ignoring case. An empty query matches every feed.
+func (f *UserFeed) Matches(query string) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return true
+	}
+
+	fields := []string{f.Title, f.URL}
+	if f.CustomTitle != nil {
+		fields = append(fields, *f.CustomTitle)
+	}
+	if f.Notes != nil {
+		fields = append(fields, *f.Notes)
+	}
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FeedTier sets how often the scheduler fetches a feed
+type FeedTier string
+
+const (
+	// FeedTierHigh feeds are fetched on every scheduler run, ignoring the minimum fetch interval
+	FeedTierHigh FeedTier = "high"
+	// FeedTierNormal feeds are fetched once the minimum fetch interval has passed
+	FeedTierNormal FeedTier = "normal"
+	// FeedTierLow feeds are fetched once the low tier fetch interval has passed
+	FeedTierLow FeedTier = "low"
+)
+
+// ParseFeedTier validates a fetch tier name
+func ParseFeedTier(value string) (FeedTier, error) {
+	switch tier := FeedTier(strings.ToLower(strings.TrimSpace(value))); tier {
+	case FeedTierHigh, FeedTierNormal, FeedTierLow:
+		return tier, nil
+	}
+	return "", fmt.Errorf("unknown fetch tier %q, want %q, %q or %q", value, FeedTierHigh, FeedTierNormal, FeedTierLow)
+}
+
+// FeedBulkAction is what an administrator applies to every feed matching a filter
+type FeedBulkAction string
+
+const (
+	// FeedBulkReactivate sets feeds back to active, unarchives them and queues a fetch
+	FeedBulkReactivate FeedBulkAction = "reactivate"
+	// FeedBulkSuspend stops fetching feeds until they are reactivated
+	FeedBulkSuspend FeedBulkAction = "suspend"
+	// FeedBulkRefetch queues a fetch of every feed right away; suspended feeds are skipped
+	FeedBulkRefetch FeedBulkAction = "refetch"
+	// FeedBulkSetTier moves feeds to another fetch tier
+	FeedBulkSetTier FeedBulkAction = "set_tier"
+)
+
+// ParseFeedBulkAction validates a bulk action name
+func ParseFeedBulkAction(value string) (FeedBulkAction, error) {
+	switch action := FeedBulkAction(strings.ToLower(strings.TrimSpace(value))); action {
+	case FeedBulkReactivate, FeedBulkSuspend, FeedBulkRefetch, FeedBulkSetTier:
+		return action, nil
+	}
+	return "", fmt.Errorf("unknown bulk action %q, want %q, %q, %q or %q",
+		value, FeedBulkReactivate, FeedBulkSuspend, FeedBulkRefetch, FeedBulkSetTier)
+}
+
+// FeedBulkUpdate is a bulk action with its settings
+type FeedBulkUpdate struct {
+	Action FeedBulkAction
+	Tier   FeedTier // target tier of FeedBulkSetTier
+	// DryRun only counts the matching feeds
+	DryRun bool
+}
+
+// FeedBulkResult reports what a bulk action did
+type FeedBulkResult struct {
+	Action  FeedBulkAction `json:"action"`
+	DryRun  bool           `json:"dry_run"`
+	Matched int64          `json:"matched"`
+	// Updated counts the feeds whose status or tier changed
+	Updated       int64 `json:"updated"`
+	FetchesQueued int64 `json:"fetches_queued"`
+}
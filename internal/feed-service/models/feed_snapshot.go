@@ -0,0 +1,65 @@
+package models
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"time"
+)
+
+// FeedSnapshot is a raw feed response kept for debugging feeds that parse weirdly.
+// Body holds the gzip-compressed response body, cut at the configured size cap.
+type FeedSnapshot struct {
+	ID          uint   `json:"id"`
+	FeedID      uint   `json:"feed_id"`
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"-"`
+	Size        int64  `json:"size"`      // uncompressed length of the kept body
+	Truncated   bool   `json:"truncated"` // the response was longer than the size cap
+	// ParseError is why the response could not be parsed, if it could not
+	ParseError *string   `json:"parse_error,omitempty"`
+	FetchedAt  time.Time `json:"fetched_at"`
+}
+
+// NewFeedSnapshot compresses raw into a snapshot of the feed's response
+func NewFeedSnapshot(feedID uint, statusCode int, contentType string, raw []byte, truncated bool, parseErr error) (*FeedSnapshot, error) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(raw); err != nil {
+		return nil, fmt.Errorf("failed to compress feed snapshot: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress feed snapshot: %w", err)
+	}
+
+	snapshot := &FeedSnapshot{
+		FeedID:      feedID,
+		StatusCode:  statusCode,
+		ContentType: contentType,
+		Body:        compressed.Bytes(),
+		Size:        int64(len(raw)),
+		Truncated:   truncated,
+		FetchedAt:   time.Now().UTC(),
+	}
+	if parseErr != nil {
+		message := parseErr.Error()
+		snapshot.ParseError = &message
+	}
+	return snapshot, nil
+}
+
+// Raw returns the decompressed response body
+func (s *FeedSnapshot) Raw() ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(s.Body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress feed snapshot %d: %w", s.ID, err)
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress feed snapshot %d: %w", s.ID, err)
+	}
+	return raw, nil
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits for the folders a user files subscriptions in
+const (
+	MaxFolderNameLength = 100
+	MaxFolderDepth      = 10
+	MaxShareSlugLength  = 100
+)
+
+// Limits of the public page of a shared folder
+const (
+	MaxSharedArticles     = 50
+	MaxSharedExcerptRunes = 280
+)
+
+var shareSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Folder groups a user's subscriptions. Folders nest through ParentID; top-level folders
+// have none. The names of a folder's children are unique.
+type Folder struct {
+	ID       uint   `json:"id"`
+	UserID   uint   `json:"-" gorm:"not null;index"`
+	ParentID *uint  `json:"parent_id"`
+	Name     string `json:"name" gorm:"size:100;not null"`
+	// ShareSlug publishes the recent articles of the folder and the folders below it on a
+	// public read-only page at /api/v1/shared/:slug; nil while the folder is private
+	ShareSlug *string   `json:"share_slug" gorm:"size:100;uniqueIndex"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Folder) TableName() string {
+	return "folders"
+}
+
+// NormalizeFolderName trims a folder name and checks it fits the column
+func NormalizeFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("folder name must not be empty")
+	}
+	if utf8.RuneCountInString(name) > MaxFolderNameLength {
+		return "", fmt.Errorf("folder name must be at most %d characters", MaxFolderNameLength)
+	}
+	return name, nil
+}
+
+// NormalizeShareSlug trims a share slug and checks it is lowercase letters and digits in
+// words joined by single hyphens
+func NormalizeShareSlug(slug string) (string, error) {
+	slug = strings.TrimSpace(slug)
+	if len(slug) > MaxShareSlugLength || !shareSlugPattern.MatchString(slug) {
+		return "", fmt.Errorf("slug must be at most %d lowercase letters, digits and single hyphens", MaxShareSlugLength)
+	}
+	return slug, nil
+}
+
+// SharedFolder is the public page of a shared folder. It carries none of the owner's
+// details or reading state.
+type SharedFolder struct {
+	Slug     string          `json:"slug"`
+	Name     string          `json:"name"`
+	Articles []SharedArticle `json:"articles"`
+}
+
+// SharedArticle is an article on the page of a shared folder: its title, an excerpt of
+// its description and its link, without the content
+type SharedArticle struct {
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Excerpt     string    `json:"excerpt"`
+	FeedTitle   string    `json:"feed_title"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// SharedExcerpt shortens an article description to at most MaxSharedExcerptRunes, on a
+// single line
+func SharedExcerpt(description string) string {
+	excerpt := strings.Join(strings.Fields(description), " ")
+	if utf8.RuneCountInString(excerpt) <= MaxSharedExcerptRunes {
+		return excerpt
+	}
+	runes := []rune(excerpt)[:MaxSharedExcerptRunes-1]
+	return strings.TrimRight(string(runes), " ") + "…"
+}
+
+// FolderPaths returns the names from the top level down to each folder, by folder ID.
+// Folders whose parent is missing from folders are treated as top-level.
+func FolderPaths(folders []*Folder) map[uint][]string {
+	byID := make(map[uint]*Folder, len(folders))
+	for _, folder := range folders {
+		byID[folder.ID] = folder
+	}
+
+	paths := make(map[uint][]string, len(folders))
+	var pathOf func(folder *Folder, depth int) []string
+	pathOf = func(folder *Folder, depth int) []string {
+		if path, ok := paths[folder.ID]; ok {
+			return path
+		}
+		var path []string
+		if folder.ParentID != nil && depth < MaxFolderDepth {
+			if parent, ok := byID[*folder.ParentID]; ok {
+				path = append(path, pathOf(parent, depth+1)...)
+			}
+		}
+		path = append(path, folder.Name)
+		paths[folder.ID] = path
+		return path
+	}
+	for _, folder := range folders {
+		pathOf(folder, 0)
+	}
+	return paths
+}
+
+// FolderSubtree returns the ID of the folder and of every folder below it, nil when the
+// folder is not in folders
+func FolderSubtree(folders []*Folder, id uint) []uint {
+	children := make(map[uint][]uint, len(folders))
+	found := false
+	for _, folder := range folders {
+		if folder.ID == id {
+			found = true
+		}
+		if folder.ParentID != nil {
+			children[*folder.ParentID] = append(children[*folder.ParentID], folder.ID)
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids
+}
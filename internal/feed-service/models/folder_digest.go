@@ -0,0 +1,102 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// How often a folder digest is sent
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// FolderDigest schedules a digest of the unread articles of a folder and the folders
+// below it, written by the LLM and delivered as a notification. It is sent daily, or
+// weekly on Weekday, at Hour in Timezone, and covers the articles published since the
+// previous send time.
+type FolderDigest struct {
+	FolderID  uint   `json:"folder_id" gorm:"primaryKey;autoIncrement:false"`
+	UserID    uint   `json:"-" gorm:"not null;index"`
+	Frequency string `json:"frequency" gorm:"size:16;not null"`
+	Hour      int    `json:"hour" gorm:"not null"`
+	// Weekday is set for weekly digests only, 0 being Sunday
+	Weekday *int `json:"weekday,omitempty"`
+	// Timezone is an IANA time zone name, such as Europe/Berlin
+	Timezone   string     `json:"timezone" gorm:"size:64;not null;default:UTC"`
+	NextRunAt  time.Time  `json:"next_run_at" gorm:"not null;index"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (FolderDigest) TableName() string {
+	return "folder_digests"
+}
+
+// Validate checks the schedule of a digest
+func (d *FolderDigest) Validate() error {
+	switch d.Frequency {
+	case DigestDaily:
+		if d.Weekday != nil {
+			return fmt.Errorf("weekday is only set for %s digests", DigestWeekly)
+		}
+	case DigestWeekly:
+		if d.Weekday == nil || *d.Weekday < 0 || *d.Weekday > 6 {
+			return fmt.Errorf("weekly digests need a weekday from 0 (Sunday) to 6")
+		}
+	default:
+		return fmt.Errorf("frequency must be %s or %s", DigestDaily, DigestWeekly)
+	}
+	if d.Hour < 0 || d.Hour > 23 {
+		return fmt.Errorf("hour must be from 0 to 23")
+	}
+	if _, err := d.location(); err != nil {
+		return fmt.Errorf("unknown timezone %q", d.Timezone)
+	}
+	return nil
+}
+
+// NextRun returns the first send time of the digest after t. Send times follow the wall
+// clock of the digest's timezone, so they keep their hour across daylight saving changes.
+func (d *FolderDigest) NextRun(t time.Time) time.Time {
+	loc, err := d.location()
+	if err != nil {
+		loc = time.UTC
+	}
+	local := t.In(loc)
+	year, month, day := local.Date()
+	if d.Frequency == DigestWeekly && d.Weekday != nil {
+		day += (*d.Weekday - int(local.Weekday()) + 7) % 7
+	}
+	next := time.Date(year, month, day, d.Hour, 0, 0, 0, loc)
+	if !next.After(t) {
+		next = time.Date(year, month, day+d.periodDays(), d.Hour, 0, 0, 0, loc)
+	}
+	return next.UTC()
+}
+
+// PreviousRun returns the send time a period before the one at t
+func (d *FolderDigest) PreviousRun(t time.Time) time.Time {
+	loc, err := d.location()
+	if err != nil {
+		loc = time.UTC
+	}
+	local := t.In(loc)
+	year, month, day := local.Date()
+	return time.Date(year, month, day-d.periodDays(), d.Hour, 0, 0, 0, loc).UTC()
+}
+
+func (d *FolderDigest) periodDays() int {
+	if d.Frequency == DigestWeekly {
+		return 7
+	}
+	return 1
+}
+
+func (d *FolderDigest) location() (*time.Location, error) {
+	if d.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(d.Timezone)
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFolderDigest_Validate(t *testing.T) {
+	monday, sunday, eighth := 1, 0, 7
+	tests := []struct {
+		name    string
+		digest  FolderDigest
+		wantErr string
+	}{
+		{name: "daily", digest: FolderDigest{Frequency: DigestDaily, Hour: 8, Timezone: "Europe/Berlin"}},
+		{name: "weekly on sunday", digest: FolderDigest{Frequency: DigestWeekly, Hour: 0, Weekday: &sunday, Timezone: "UTC"}},
+		{name: "monthly", digest: FolderDigest{Frequency: "monthly", Hour: 8}, wantErr: "frequency"},
+		{name: "daily with weekday", digest: FolderDigest{Frequency: DigestDaily, Hour: 8, Weekday: &monday}, wantErr: "weekday"},
+		{name: "weekly without weekday", digest: FolderDigest{Frequency: DigestWeekly, Hour: 8}, wantErr: "weekday"},
+		{name: "weekday out of range", digest: FolderDigest{Frequency: DigestWeekly, Hour: 8, Weekday: &eighth}, wantErr: "weekday"},
+		{name: "hour out of range", digest: FolderDigest{Frequency: DigestDaily, Hour: 24}, wantErr: "hour"},
+		{name: "unknown timezone", digest: FolderDigest{Frequency: DigestDaily, Hour: 8, Timezone: "Mars/Olympus"}, wantErr: "timezone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.digest.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestFolderDigest_NextRun(t *testing.T) {
+	monday := 1
+	daily := FolderDigest{Frequency: DigestDaily, Hour: 8, Timezone: "Europe/Berlin"}
+	weekly := FolderDigest{Frequency: DigestWeekly, Hour: 8, Weekday: &monday, Timezone: "America/New_York"}
+
+	tests := []struct {
+		name   string
+		digest FolderDigest
+		after  time.Time
+		want   time.Time
+	}{
+		// 08:00 in Berlin is 06:00 UTC in summer
+		{name: "later today", digest: daily, after: time.Date(2026, 7, 1, 5, 0, 0, 0, time.UTC), want: time.Date(2026, 7, 1, 6, 0, 0, 0, time.UTC)},
+		{name: "at the send time", digest: daily, after: time.Date(2026, 7, 1, 6, 0, 0, 0, time.UTC), want: time.Date(2026, 7, 2, 6, 0, 0, 0, time.UTC)},
+		// the clocks go forward on 29 March, and the digest keeps to 08:00 local time
+		{name: "across daylight saving", digest: daily, after: time.Date(2026, 3, 28, 12, 0, 0, 0, time.UTC), want: time.Date(2026, 3, 29, 6, 0, 0, 0, time.UTC)},
+		// Saturday 17 October 2026; 08:00 in New York is 12:00 UTC
+		{name: "next monday", digest: weekly, after: time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC), want: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)},
+		{name: "monday after the send time", digest: weekly, after: time.Date(2026, 10, 19, 13, 0, 0, 0, time.UTC), want: time.Date(2026, 10, 26, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.digest.NextRun(tt.after))
+		})
+	}
+
+	assert.Equal(t, time.Date(2026, 10, 12, 12, 0, 0, 0, time.UTC), weekly.PreviousRun(time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)))
+}
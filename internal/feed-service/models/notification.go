@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type NotificationType string
+
+const (
+	NotificationFeedArchived NotificationType = "feed_archived"
+	NotificationFeedRestored NotificationType = "feed_restored"
+	NotificationFeedRemoved  NotificationType = "feed_removed"
+	NotificationFolderDigest NotificationType = "folder_digest"
+)
+
+// Notification is a user-facing message about something that happened to their subscriptions
+type Notification struct {
+	ID        uint             `json:"id"`
+	UserID    uint             `json:"user_id"`
+	FeedID    *uint            `json:"feed_id,omitempty"`
+	Type      NotificationType `json:"type"`
+	Message   string           `json:"message"`
+	ReadAt    *time.Time       `json:"read_at,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
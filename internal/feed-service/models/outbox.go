@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// OutboxEvent is an event waiting in the database to be published to Kafka. It is
+// written in the transaction of the rows it describes, so the event is kept exactly when
+// they are, and the outbox relay publishes it once Kafka accepts it.
+type OutboxEvent struct {
+	ID uint64 `json:"id"`
+	// EventType is one of the events.EventType values and tells how Payload is encoded
+	EventType string  `json:"event_type"`
+	Payload   []byte  `json:"-"`
+	RequestID *string `json:"request_id,omitempty"`
+	// Attempts counts the failed publications; LastError is the latest failure
+	Attempts  int     `json:"attempts"`
+	LastError *string `json:"last_error,omitempty"`
+	// LockedBy names the relay publishing the event until LockedUntil
+	LockedBy    *string    `json:"-"`
+	LockedUntil *time.Time `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Article state fields a sync client can change
+const (
+	StateFieldRead    = "read"
+	StateFieldStarred = "starred"
+)
+
+// ArticleStateEvent is one change to a user's read or starred state of an article, as
+// the client made it. Events are only appended; the scheduler prunes old ones.
+type ArticleStateEvent struct {
+	ID        int64  `json:"id"`
+	UserID    uint   `json:"-"`
+	ArticleID uint   `json:"article_id"`
+	Field     string `json:"field"`
+	Value     bool   `json:"value"`
+	// ChangedAt is the client's clock when the change was made, possibly offline
+	ChangedAt  time.Time `json:"changed_at"`
+	ReceivedAt time.Time `json:"received_at"`
+	DeviceID   string    `json:"device_id,omitempty"`
+}
+
+func (ArticleStateEvent) TableName() string {
+	return "article_state_events"
+}
+
+// UserArticleState is the merged read and starred state of an article for a user. Each
+// field keeps the timestamp of the change that set it, so it can be merged last writer wins.
+type UserArticleState struct {
+	UserID           uint       `json:"-" gorm:"primaryKey"`
+	ArticleID        uint       `json:"article_id" gorm:"primaryKey"`
+	Read             bool       `json:"read"`
+	ReadChangedAt    *time.Time `json:"read_changed_at,omitempty"`
+	Starred          bool       `json:"starred"`
+	StarredChangedAt *time.Time `json:"starred_changed_at,omitempty"`
+	// Seq is the ID of the last event that changed the state
+	Seq int64 `json:"seq"`
+}
+
+func (UserArticleState) TableName() string {
+	return "user_article_states"
+}
@@ -1,14 +1,209 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// MaxSubscriptionNotesLength caps the free-form note a user keeps on a subscription
+const MaxSubscriptionNotesLength = 2000
+
+// Limits for the custom headers sent when fetching a subscribed feed
+const (
+	MaxFetchHeaders           = 20
+	MaxFetchHeaderValueLength = 4096
+)
+
+// deniedFetchHeaders are managed by the HTTP client or the fetch identity and cannot
+// be overridden by a subscription
+var deniedFetchHeaders = map[string]bool{
+	"Host":                true,
+	"Content-Length":      true,
+	"Transfer-Encoding":   true,
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Upgrade":             true,
+	"Proxy-Connection":    true,
+	"Proxy-Authorization": true,
+	"Accept-Encoding":     true,
+	"User-Agent":          true,
+	"From":                true,
+}
+
+// SecretEncrypter seals subscription secrets for storage at rest
+type SecretEncrypter interface {
+	Encrypt(plaintext string) (string, error)
+}
+
+// SecretDecrypter opens subscription secrets sealed by a SecretEncrypter
+type SecretDecrypter interface {
+	Decrypt(ciphertext string) (string, error)
+}
 
 type Subscription struct {
 	UserID      uint      `gorm:"primaryKey"`
 	FeedID      uint      `gorm:"primaryKey"`
 	CustomTitle *string   `json:"custom_title,omitempty" gorm:"size:255"`
+	Notes       *string   `json:"notes,omitempty" gorm:"type:text"`
+	FolderID    *uint     `json:"folder_id,omitempty" gorm:"index"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 
+	// FetchHeadersCiphertext holds the encrypted custom fetch headers (a JSON object)
+	FetchHeadersCiphertext *string `json:"-" gorm:"type:text"`
+
 	// Associations
 	Feed Feed `gorm:"foreignKey:FeedID"`
 }
+
+// SubscriptionUpdate lists the subscription settings to change. Nil fields are left
+// as they are; an empty string clears the setting.
+type SubscriptionUpdate struct {
+	CustomTitle *string
+	Notes       *string
+	// FolderID files the subscription in a folder of the user; 0 takes it out of its folder
+	FolderID *uint
+	// FetchHeaders replaces the custom fetch headers; an empty map clears them. They are
+	// only written once sealed with SealFetchHeaders.
+	FetchHeaders map[string]string
+
+	fetchHeadersCiphertext *string
+}
+
+// IsEmpty reports whether the update changes nothing
+func (u SubscriptionUpdate) IsEmpty() bool {
+	return u.CustomTitle == nil && u.Notes == nil && u.FolderID == nil && u.FetchHeaders == nil
+}
+
+// Validate checks the new values against the column limits
+func (u SubscriptionUpdate) Validate() error {
+	if u.CustomTitle != nil && utf8.RuneCountInString(*u.CustomTitle) > 255 {
+		return fmt.Errorf("custom_title must be at most 255 characters")
+	}
+	if u.Notes != nil && utf8.RuneCountInString(*u.Notes) > MaxSubscriptionNotesLength {
+		return fmt.Errorf("notes must be at most %d characters", MaxSubscriptionNotesLength)
+	}
+	if u.FetchHeaders != nil {
+		return ValidateFetchHeaders(u.FetchHeaders)
+	}
+	return nil
+}
+
+// ValidateFetchHeaders checks custom fetch headers: valid names and values, none the
+// fetcher manages itself, and no name given twice in different case
+func ValidateFetchHeaders(headers map[string]string) error {
+	if len(headers) > MaxFetchHeaders {
+		return fmt.Errorf("at most %d fetch headers are allowed", MaxFetchHeaders)
+	}
+
+	seen := make(map[string]bool, len(headers))
+	for name, value := range headers {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return fmt.Errorf("invalid fetch header name %q", name)
+		}
+		canonical := http.CanonicalHeaderKey(name)
+		if deniedFetchHeaders[canonical] {
+			return fmt.Errorf("fetch header %q cannot be overridden", canonical)
+		}
+		if seen[canonical] {
+			return fmt.Errorf("fetch header %q is given more than once", canonical)
+		}
+		seen[canonical] = true
+
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return fmt.Errorf("invalid value for fetch header %q", canonical)
+		}
+		if len(value) > MaxFetchHeaderValueLength {
+			return fmt.Errorf("fetch header %q must be at most %d bytes", canonical, MaxFetchHeaderValueLength)
+		}
+	}
+	return nil
+}
+
+// SealFetchHeaders encrypts the new fetch headers so Columns can store them. Clearing
+// the headers needs no encryption.
+func (u *SubscriptionUpdate) SealFetchHeaders(encrypter SecretEncrypter) error {
+	if u.FetchHeaders == nil {
+		return nil
+	}
+	if len(u.FetchHeaders) == 0 {
+		cleared := ""
+		u.fetchHeadersCiphertext = &cleared
+		return nil
+	}
+
+	canonical := make(map[string]string, len(u.FetchHeaders))
+	for name, value := range u.FetchHeaders {
+		canonical[http.CanonicalHeaderKey(name)] = value
+	}
+	plaintext, err := json.Marshal(canonical)
+	if err != nil {
+		return fmt.Errorf("encode fetch headers: %w", err)
+	}
+	ciphertext, err := encrypter.Encrypt(string(plaintext))
+	if err != nil {
+		return fmt.Errorf("encrypt fetch headers: %w", err)
+	}
+	u.fetchHeadersCiphertext = &ciphertext
+	return nil
+}
+
+// OpenFetchHeaders decrypts headers stored by SealFetchHeaders
+func OpenFetchHeaders(ciphertext string, decrypter SecretDecrypter) (map[string]string, error) {
+	plaintext, err := decrypter.Decrypt(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt fetch headers: %w", err)
+	}
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(plaintext), &headers); err != nil {
+		return nil, fmt.Errorf("decode fetch headers: %w", err)
+	}
+	return headers, nil
+}
+
+// Columns returns the column updates, storing cleared settings as NULL
+func (u SubscriptionUpdate) Columns() map[string]interface{} {
+	columns := make(map[string]interface{}, 4)
+	if u.CustomTitle != nil {
+		columns["custom_title"] = nullIfEmpty(*u.CustomTitle)
+	}
+	if u.Notes != nil {
+		columns["notes"] = nullIfEmpty(*u.Notes)
+	}
+	if u.FolderID != nil {
+		if *u.FolderID == 0 {
+			columns["folder_id"] = nil
+		} else {
+			columns["folder_id"] = *u.FolderID
+		}
+	}
+	if u.fetchHeadersCiphertext != nil {
+		columns["fetch_headers_ciphertext"] = nullIfEmpty(*u.fetchHeadersCiphertext)
+	}
+	return columns
+}
+
+func nullIfEmpty(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// UserFeed returns the subscribed feed as the user sees it
+func (s *Subscription) UserFeed() *UserFeed {
+	return &UserFeed{
+		Feed:            s.Feed,
+		CustomTitle:     s.CustomTitle,
+		Notes:           s.Notes,
+		FolderID:        s.FolderID,
+		HasFetchHeaders: s.FetchHeadersCiphertext != nil,
+	}
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/pkg/secrets"
+)
+
+func TestValidateFetchHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		wantErr string
+	}{
+		{name: "api key and cookie", headers: map[string]string{"X-Api-Key": "k", "cookie": "session=1"}},
+		{name: "empty clears", headers: map[string]string{}},
+		{name: "host", headers: map[string]string{"host": "internal"}, wantErr: `"Host" cannot be overridden`},
+		{name: "content length", headers: map[string]string{"Content-Length": "0"}, wantErr: "cannot be overridden"},
+		{name: "user agent", headers: map[string]string{"User-Agent": "curl"}, wantErr: "cannot be overridden"},
+		{name: "invalid name", headers: map[string]string{"X Api Key": "k"}, wantErr: "invalid fetch header name"},
+		{name: "header injection", headers: map[string]string{"X-Api-Key": "k\r\nHost: evil"}, wantErr: "invalid value"},
+		{name: "duplicate", headers: map[string]string{"x-api-key": "a", "X-API-KEY": "b"}, wantErr: "more than once"},
+		{name: "value too long", headers: map[string]string{"Cookie": strings.Repeat("a", MaxFetchHeaderValueLength+1)}, wantErr: "at most"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFetchHeaders(tt.headers)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestSubscriptionUpdate_SealFetchHeaders(t *testing.T) {
+	cipher, err := secrets.NewCipher("test-key")
+	require.NoError(t, err)
+
+	update := SubscriptionUpdate{FetchHeaders: map[string]string{"x-api-key": "secret"}}
+	require.NoError(t, update.SealFetchHeaders(cipher))
+
+	stored, ok := update.Columns()["fetch_headers_ciphertext"].(*string)
+	require.True(t, ok)
+	require.NotNil(t, stored)
+	assert.NotContains(t, *stored, "secret")
+
+	headers, err := OpenFetchHeaders(*stored, cipher)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"X-Api-Key": "secret"}, headers)
+
+	cleared := SubscriptionUpdate{FetchHeaders: map[string]string{}}
+	require.NoError(t, cleared.SealFetchHeaders(cipher))
+	assert.Nil(t, cleared.Columns()["fetch_headers_ciphertext"], "empty headers are stored as NULL")
+
+	unsealed := SubscriptionUpdate{FetchHeaders: map[string]string{"X-Api-Key": "secret"}}
+	assert.NotContains(t, unsealed.Columns(), "fetch_headers_ciphertext", "headers are never stored in plain text")
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// SummaryFeedback is a reader's rating of an article's AI summary. ProcessingModel and
+// ProcessingPrompt are those of the summary that was rated.
+type SummaryFeedback struct {
+	UserID           uint   `json:"-" gorm:"primaryKey"`
+	ArticleID        uint   `json:"article_id" gorm:"primaryKey"`
+	ProcessingModel  string `json:"processing_model"`
+	ProcessingPrompt string `json:"processing_prompt" gorm:"size:64"`
+	Useful           bool   `json:"useful"`
+	// Hallucination flags a summary stating something the article does not say
+	Hallucination bool      `json:"hallucination"`
+	Comment       string    `json:"comment,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (SummaryFeedback) TableName() string {
+	return "summary_feedback"
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits for the tags users put on articles
+const (
+	MaxTagNameLength  = 50
+	MaxTagsPerArticle = 20
+)
+
+// Tag is a label a user puts on articles. Tag names are lowercase and unique per user.
+type Tag struct {
+	ID        uint      `json:"-"`
+	UserID    uint      `json:"-" gorm:"not null;uniqueIndex:idx_tags_user_name"`
+	Name      string    `json:"name" gorm:"size:50;not null;uniqueIndex:idx_tags_user_name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (Tag) TableName() string {
+	return "tags"
+}
+
+// ArticleTag puts a tag on an article
+type ArticleTag struct {
+	TagID     uint `gorm:"primaryKey;autoIncrement:false"`
+	ArticleID uint `gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt time.Time
+}
+
+func (ArticleTag) TableName() string {
+	return "article_tags"
+}
+
+// TagCount is a tag of a user with the number of articles it is on
+type TagCount struct {
+	Name         string `json:"name"`
+	ArticleCount int64  `json:"article_count"`
+}
+
+// NormalizeTagName lowercases and trims a tag name, dropping a leading #, and checks it
+// is a single word that fits the column
+func NormalizeTagName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if name == "" {
+		return "", fmt.Errorf("tag name must not be empty")
+	}
+	if utf8.RuneCountInString(name) > MaxTagNameLength {
+		return "", fmt.Errorf("tag name must be at most %d characters", MaxTagNameLength)
+	}
+	if strings.ContainsFunc(name, func(r rune) bool { return unicode.IsSpace(r) || r == ',' }) {
+		return "", fmt.Errorf("tag name %q must not contain spaces or commas", name)
+	}
+	return name, nil
+}
+
+// NormalizeTagNames normalizes each name, dropping duplicates
+func NormalizeTagNames(names []string) ([]string, error) {
+	normalized := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name, err := NormalizeTagName(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized, nil
+}
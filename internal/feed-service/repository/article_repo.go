@@ -3,17 +3,26 @@ package repository
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/Fancu1/phoenix-rss/internal/articletags"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/readstate"
+	"github.com/Fancu1/phoenix-rss/internal/summaries"
+	"github.com/Fancu1/phoenix-rss/pkg/dbtx"
 )
 
 type ArticleRepository struct {
 	db *gorm.DB
 }
 
+// ArticleCheckCursor is the position of an article in a list ordered by published_at, for
+// the articles to check and the user timeline
 type ArticleCheckCursor struct {
 	PublishedAt time.Time
 	ArticleID   uint
@@ -26,6 +35,19 @@ type ArticleCheckCandidate struct {
 	HTTPETag         *string `gorm:"column:http_etag"`
 	HTTPLastModified *string `gorm:"column:http_last_modified"`
 	PublishedAt      time.Time
+	SubscriberCount  int64 // of the article's feed
+}
+
+// ArticleCheckCounts describes the update check candidates after a page
+type ArticleCheckCounts struct {
+	Remaining int64
+	// Feeds lists the feeds with the most remaining candidates, most first
+	Feeds []FeedCheckCount
+}
+
+type FeedCheckCount struct {
+	FeedID uint
+	Count  int64
 }
 
 func NewArticleRepository(db *gorm.DB) *ArticleRepository {
@@ -34,6 +56,12 @@ func NewArticleRepository(db *gorm.DB) *ArticleRepository {
 	}
 }
 
+// WithTx returns a copy of the repository working in the transaction of a
+// dbtx.UnitOfWork; a nil tx keeps the repository's own DB
+func (r *ArticleRepository) WithTx(tx *gorm.DB) *ArticleRepository {
+	return &ArticleRepository{db: dbtx.Bind(r.db, tx)}
+}
+
 func (r *ArticleRepository) Create(ctx context.Context, article *models.Article) (*models.Article, error) {
 	result := r.db.WithContext(ctx).Create(article)
 	return article, result.Error
@@ -59,6 +87,48 @@ func (r *ArticleRepository) GetByFeedID(ctx context.Context, feedID uint) ([]*mo
 	return articles, result.Error
 }
 
+// Navigation returns the feed of an article, titled as the user named it, and the
+// articles listed right before and after it in its feed, newest first, in one query. It
+// returns gorm.ErrRecordNotFound for a missing or deleted article.
+func (r *ArticleRepository) Navigation(ctx context.Context, userID, articleID uint) (*models.ArticleNavigation, error) {
+	var row struct {
+		PrevID          *uint
+		NextID          *uint
+		FeedID          uint
+		FeedTitle       string
+		FeedURL         string
+		FeedCustomTitle *string
+	}
+	result := r.db.WithContext(ctx).Raw(`SELECT nav.prev_id, nav.next_id, feeds.id AS feed_id, feeds.title AS feed_title,
+		feeds.url AS feed_url, subscriptions.custom_title AS feed_custom_title
+	FROM (
+		SELECT articles.id, articles.feed_id,
+			LAG(articles.id) OVER w AS prev_id, LEAD(articles.id) OVER w AS next_id
+		FROM articles
+		WHERE articles.feed_id = (SELECT feed_id FROM articles WHERE id = ?) AND articles.deleted_at IS NULL
+		WINDOW w AS (ORDER BY articles.published_at DESC, articles.id DESC)
+	) nav
+	JOIN feeds ON feeds.id = nav.feed_id
+	LEFT JOIN subscriptions ON subscriptions.feed_id = nav.feed_id AND subscriptions.user_id = ?
+	WHERE nav.id = ?`, articleID, userID, articleID).Scan(&row)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	title := row.FeedTitle
+	if row.FeedCustomTitle != nil && *row.FeedCustomTitle != "" {
+		title = *row.FeedCustomTitle
+	}
+	return &models.ArticleNavigation{
+		Feed:              models.ArticleFeedInfo{ID: row.FeedID, Title: title, URL: row.FeedURL, IconURL: models.FeedIconURL(row.FeedURL)},
+		PreviousArticleID: row.PrevID,
+		NextArticleID:     row.NextID,
+	}, nil
+}
+
 func (r *ArticleRepository) GetByURL(ctx context.Context, url string) (*models.Article, error) {
 	article := &models.Article{}
 	result := r.db.WithContext(ctx).Where("url = ?", url).First(article)
@@ -70,27 +140,424 @@ func (r *ArticleRepository) Update(ctx context.Context, article *models.Article)
 	return article, result.Error
 }
 
+// Delete moves the article to the trash
 func (r *ArticleRepository) Delete(ctx context.Context, id uint) error {
 	result := r.db.WithContext(ctx).Delete(&models.Article{}, id)
 	return result.Error
 }
 
+// GetDeletedByID returns a trashed article
+func (r *ArticleRepository) GetDeletedByID(ctx context.Context, id uint) (*models.Article, error) {
+	article := &models.Article{}
+	result := r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(article, id)
+	return article, result.Error
+}
+
+// Restore takes an article out of the trash if it was deleted at or after deletedSince.
+// It returns false when there is no such article.
+func (r *ArticleRepository) Restore(ctx context.Context, id uint, deletedSince time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Unscoped().
+		Model(&models.Article{}).
+		Where("id = ? AND deleted_at IS NOT NULL AND deleted_at >= ?", id, deletedSince).
+		Update("deleted_at", nil)
+	return result.RowsAffected > 0, result.Error
+}
+
+// PurgeDeletedBefore permanently removes articles trashed before cutoff
+func (r *ArticleRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Delete(&models.Article{})
+	return result.RowsAffected, result.Error
+}
+
+// ExistsByURL includes trashed articles, so a fetch does not bring back a deleted article
 func (r *ArticleRepository) ExistsByURL(ctx context.Context, url string) (bool, error) {
 	var count int64
-	result := r.db.WithContext(ctx).Model(&models.Article{}).Where("url = ?", url).Count(&count)
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.Article{}).Where("url = ?", url).Count(&count)
 	return count > 0, result.Error
 }
 
-func (r *ArticleRepository) UpdateWithAIData(ctx context.Context, articleID uint, summary string, processingModel string) error {
+// FeedHasArticles tells whether the feed has any article, trashed ones included
+func (r *ArticleRepository) FeedHasArticles(ctx context.Context, feedID uint) (bool, error) {
+	var ids []uint
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.Article{}).
+		Where("feed_id = ?", feedID).
+		Limit(1).
+		Pluck("id", &ids)
+	return len(ids) > 0, result.Error
+}
+
+// NextUnreadQuery describes where to look for the next unread article
+type NextUnreadQuery struct {
+	UserID   uint
+	FeedID   uint            // 0 searches every feed the user subscribes to
+	FolderID uint            // folder of the user whose feeds, and its subfolders', are searched
+	After    *models.Article // nil starts from the newest article
+	MarkRead bool            // mark After read in the same transaction
+}
+
+// NextUnread returns the first unread article that follows q.After in reading order
+// (newest first), or nil when there is none. It returns gorm.ErrRecordNotFound when
+// q.FolderID is not a folder of the user.
+func (r *ArticleRepository) NextUnread(ctx context.Context, q NextUnreadQuery) (*models.Article, error) {
+	var next *models.Article
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var folderIDs []uint
+		if q.FolderID != 0 {
+			var folders []*models.Folder
+			if err := tx.Where("user_id = ?", q.UserID).Find(&folders).Error; err != nil {
+				return err
+			}
+			if folderIDs = models.FolderSubtree(folders, q.FolderID); len(folderIDs) == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+		if q.MarkRead && q.After != nil {
+			if _, err := readstate.NewStore(tx).SetRead(ctx, q.UserID, true, q.After.ID); err != nil {
+				return err
+			}
+		}
+
+		query := tx.Where(readstate.UnreadCondition, q.UserID)
+		switch {
+		case q.FeedID != 0:
+			query = query.Where("feed_id = ?", q.FeedID)
+		case len(folderIDs) > 0:
+			query = query.Where("feed_id IN (?)", tx.Table("subscriptions").Select("feed_id").Where("user_id = ? AND folder_id IN ?", q.UserID, folderIDs))
+		default:
+			query = query.Where("feed_id IN (?)", tx.Table("subscriptions").Select("feed_id").Where("user_id = ?", q.UserID))
+		}
+		if q.After != nil {
+			query = query.Where("(published_at < ?) OR (published_at = ? AND id < ?)", q.After.PublishedAt, q.After.PublishedAt, q.After.ID)
+		}
+
+		articles := make([]*models.Article, 0, 1)
+		if err := query.Order("published_at DESC, id DESC").Limit(1).Find(&articles).Error; err != nil {
+			return err
+		}
+		if len(articles) > 0 {
+			next = articles[0]
+		}
+		return nil
+	})
+	return next, err
+}
+
+// SetRead marks an article read or unread for the user alone
+func (r *ArticleRepository) SetRead(ctx context.Context, userID, articleID uint, read bool) error {
+	_, err := readstate.NewStore(r.db).SetRead(ctx, userID, read, articleID)
+	return err
+}
+
+// MarkFeedRead marks the user's unread articles of the feed read, only those published at
+// or before a non-zero before, and returns how many it marked
+func (r *ArticleRepository) MarkFeedRead(ctx context.Context, userID, feedID uint, before time.Time) (int, error) {
+	return readstate.NewStore(r.db).MarkFeedRead(ctx, userID, feedID, before)
+}
+
+// SetStarred stars or unstars an article for the user alone
+func (r *ArticleRepository) SetStarred(ctx context.Context, userID, articleID uint, starred bool) error {
+	_, err := readstate.NewStore(r.db).SetStarred(ctx, userID, starred, articleID)
+	return err
+}
+
+// ListStarred returns a page of the articles the user starred in their subscribed feeds,
+// most recently starred first, and the number of such articles
+func (r *ArticleRepository) ListStarred(ctx context.Context, userID uint, offset, limit int) ([]*models.Article, int64, error) {
+	db := r.db.WithContext(ctx)
+	query := db.Model(&models.Article{}).
+		Joins("JOIN user_article_states uas ON uas.article_id = articles.id AND uas.user_id = ? AND uas.starred", userID).
+		Where("articles.feed_id IN (?)", db.Table("subscriptions").Select("feed_id").Where("user_id = ?", userID)).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var articles []*models.Article
+	err := query.Select("articles.*").
+		Order("uas.starred_changed_at DESC, articles.id DESC").
+		Offset(offset).Limit(limit).
+		Find(&articles).Error
+	return articles, total, err
+}
+
+// TimelineFilter narrows the user timeline; zero fields do not filter
+type TimelineFilter struct {
+	Tag      string // normalized name of a tag of the user
+	Unread   bool
+	Starred  bool
+	FolderID uint // folder the user filed the feeds in
+}
+
+// ListUserArticles returns up to limit articles of the user's subscribed feeds matching
+// filter, newest published first, after cursor when set, the cursor of the page that
+// follows, nil on the last page, and the number of matching articles
+func (r *ArticleRepository) ListUserArticles(ctx context.Context, userID uint, filter TimelineFilter, limit int, cursor *ArticleCheckCursor) ([]*models.Article, *ArticleCheckCursor, int64, error) {
+	db := r.db.WithContext(ctx)
+	subscribed := db.Table("subscriptions").Select("feed_id").Where("user_id = ?", userID)
+	if filter.FolderID != 0 {
+		subscribed = subscribed.Where("folder_id = ?", filter.FolderID)
+	}
+	query := db.Model(&models.Article{}).Where("feed_id IN (?)", subscribed)
+	if filter.Tag != "" {
+		query = query.Where(articletags.TaggedCondition, userID, filter.Tag)
+	}
+	if filter.Unread {
+		query = query.Where(readstate.UnreadCondition, userID)
+	}
+	if filter.Starred {
+		query = query.Where(readstate.StarredCondition, userID)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, nil, 0, err
+	}
+
+	page := query
+	if cursor != nil {
+		page = page.Where("(published_at < ?) OR (published_at = ? AND id < ?)", cursor.PublishedAt, cursor.PublishedAt, cursor.ArticleID)
+	}
+
+	var articles []*models.Article
+	if err := page.Order("published_at DESC, id DESC").Limit(limit + 1).Find(&articles).Error; err != nil {
+		return nil, nil, 0, err
+	}
+	if len(articles) <= limit {
+		return articles, nil, total, nil
+	}
+
+	articles = articles[:limit]
+	last := articles[limit-1]
+	return articles, &ArticleCheckCursor{PublishedAt: last.PublishedAt, ArticleID: last.ID}, total, nil
+}
+
+// ApplyReadState sets Read, Starred and Tags on the articles to the user's own state
+func (r *ArticleRepository) ApplyReadState(ctx context.Context, userID uint, articles ...*models.Article) error {
+	if err := readstate.NewStore(r.db).ApplyReadState(ctx, userID, articles); err != nil {
+		return err
+	}
+	return articletags.NewStore(r.db).Apply(ctx, userID, articles)
+}
+
+// AddTags puts the user's tags with the normalized names on the article
+func (r *ArticleRepository) AddTags(ctx context.Context, userID, articleID uint, names []string) error {
+	return articletags.NewStore(r.db).Add(ctx, userID, articleID, names)
+}
+
+// RemoveTags takes the user's tags with the normalized names off the article
+func (r *ArticleRepository) RemoveTags(ctx context.Context, userID, articleID uint, names []string) error {
+	return articletags.NewStore(r.db).Remove(ctx, userID, articleID, names)
+}
+
+// ListTags returns the user's tags by name with their article counts
+func (r *ArticleRepository) ListTags(ctx context.Context, userID uint) ([]models.TagCount, error) {
+	return articletags.NewStore(r.db).List(ctx, userID)
+}
+
+// ArticleSearchQuery is a full-text search over the articles of a user's subscribed feeds
+type ArticleSearchQuery struct {
+	UserID uint
+	Query  string // web search syntax: quoted phrases, OR and -excluded words
+	Offset int
+	Limit  int
+}
+
+// Search returns a page of the articles matching q, best match first, and the number of
+// matches. On Postgres it uses the search_vector column; other databases, i.e. SQLite in
+// tests, fall back to matching every word of the query as a substring.
+func (r *ArticleRepository) Search(ctx context.Context, q ArticleSearchQuery) ([]*models.Article, int64, error) {
+	db := r.db.WithContext(ctx)
+	query := db.Model(&models.Article{}).
+		Where("feed_id IN (?)", db.Table("subscriptions").Select("feed_id").Where("user_id = ?", q.UserID))
+
+	postgres := r.db.Dialector.Name() == "postgres"
+	if postgres {
+		query = query.Where("search_vector @@ websearch_to_tsquery('english', ?)", q.Query)
+	} else {
+		for _, word := range strings.Fields(strings.ToLower(q.Query)) {
+			pattern := "%" + word + "%"
+			query = query.Where("LOWER(title) LIKE ? OR LOWER(description) LIKE ? OR LOWER(content) LIKE ? OR LOWER(COALESCE(summary, '')) LIKE ?",
+				pattern, pattern, pattern, pattern)
+		}
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if postgres {
+		query = query.Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "ts_rank_cd(search_vector, websearch_to_tsquery('english', ?)) DESC, published_at DESC, id DESC",
+			Vars: []any{q.Query},
+		}})
+	} else {
+		query = query.Order("published_at DESC, id DESC")
+	}
+
+	var articles []*models.Article
+	err := query.Offset(q.Offset).Limit(q.Limit).Find(&articles).Error
+	return articles, total, err
+}
+
+// AIResult is the outcome of AI processing for one article, as applied by ApplyAIResults
+type AIResult struct {
+	ArticleID        uint
+	Summary          string
+	ProcessingModel  string
+	ProcessingPrompt string
+	SummaryTruncated bool
+	// Language is the code of the summary's language. Every summary is kept as a variant
+	// of the article; Default ones, in the default language, also become the article's
+	// own summary and settle its processing status.
+	Language string
+	Default  bool
+	// Failed results only record ErrorClass and keep any earlier summary
+	Failed     bool
+	ErrorClass string
+	// RequestID is the request that caused the processing
+	RequestID string
+	// Topics are kept on the article along with a Default summary
+	Topics []string
+}
+
+// optionalString stores NULL for an empty value, such as the prompt of summaries made
+// before prompt variants were recorded or a request ID of events published before IDs were
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// ApplyAIResults records a batch of AI results in one transaction: the summary variants in
+// one upsert, one UPDATE per default summary, and one per error class for the failures.
+// When a batch holds several results for the same variant of an article the last one
+// wins, and so does the last default summary or failure for the article's status.
+// Results delivered again are harmless: a default summary of the request the article
+// already has its summary from leaves the article alone, and a failure never replaces a
+// summary that succeeded since the article was last queued.
+func (r *ArticleRepository) ApplyAIResults(ctx context.Context, results []AIResult) error {
+	type variantKey struct {
+		articleID       uint
+		language, model string
+	}
+	variants := make(map[variantKey]models.ArticleSummary, len(results))
+	variantOrder := make([]variantKey, 0, len(results))
+	outcomes := make(map[uint]AIResult, len(results))
+	outcomeOrder := make([]uint, 0, len(results))
+	for _, result := range results {
+		if !result.Failed {
+			key := variantKey{result.ArticleID, result.Language, result.ProcessingModel}
+			if _, seen := variants[key]; !seen {
+				variantOrder = append(variantOrder, key)
+			}
+			variants[key] = models.ArticleSummary{
+				ArticleID: result.ArticleID,
+				Model:     result.ProcessingModel,
+				Language:  result.Language,
+				Summary:   result.Summary,
+				Truncated: result.SummaryTruncated,
+				Prompt:    optionalString(result.ProcessingPrompt),
+				RequestID: optionalString(result.RequestID),
+			}
+		}
+		if result.Failed || result.Default {
+			if _, seen := outcomes[result.ArticleID]; !seen {
+				outcomeOrder = append(outcomeOrder, result.ArticleID)
+			}
+			outcomes[result.ArticleID] = result
+		}
+	}
+
 	now := time.Now()
-	result := r.db.WithContext(ctx).Model(&models.Article{}).Where("id = ?", articleID).Updates(map[string]interface{}{
-		"summary":          summary,
-		"processing_model": processingModel,
-		"processed_at":     now,
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		saved := make([]models.ArticleSummary, len(variantOrder))
+		for i, key := range variantOrder {
+			saved[i] = variants[key]
+		}
+		if err := summaries.NewStore(tx).Save(ctx, saved...); err != nil {
+			return fmt.Errorf("summary variants: %w", err)
+		}
+
+		type failure struct{ errorClass, requestID string }
+		failed := make(map[failure][]uint)
+		for _, id := range outcomeOrder {
+			result := outcomes[id]
+			if result.Failed {
+				key := failure{result.ErrorClass, result.RequestID}
+				failed[key] = append(failed[key], id)
+				continue
+			}
+			update := tx.Model(&models.Article{}).Where("id = ?", id)
+			if result.RequestID != "" {
+				update = update.Where("processing_status <> ? OR processing_request_id IS NULL OR processing_request_id <> ?", models.ProcessingSucceeded, result.RequestID)
+			}
+			if err := update.Updates(map[string]interface{}{
+				"summary":               result.Summary,
+				"summary_truncated":     result.SummaryTruncated,
+				"processing_model":      result.ProcessingModel,
+				"processing_prompt":     optionalString(result.ProcessingPrompt),
+				"processed_at":          now,
+				"processing_status":     models.ProcessingSucceeded,
+				"processing_error":      nil,
+				"processing_request_id": optionalString(result.RequestID),
+				"topics":                models.Topics(result.Topics),
+			}).Error; err != nil {
+				return fmt.Errorf("article %d: %w", id, err)
+			}
+		}
+		for key, ids := range failed {
+			if err := tx.Model(&models.Article{}).Where("id IN ? AND processing_status <> ?", ids, models.ProcessingSucceeded).UpdateColumns(map[string]interface{}{
+				"processing_status":     models.ProcessingFailed,
+				"processing_error":      key.errorClass,
+				"processing_request_id": optionalString(key.requestID),
+			}).Error; err != nil {
+				return fmt.Errorf("failed articles %v: %w", ids, err)
+			}
+		}
+		return nil
 	})
-	return result.Error
 }
 
+// MarkProcessing records that the articles were queued for AI processing. updated_at is
+// bumped so articles that never get a result can be told apart by how long they waited.
+func (r *ArticleRepository) MarkProcessing(ctx context.Context, articleIDs ...uint) error {
+	if len(articleIDs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&models.Article{}).Where("id IN ?", articleIDs).
+		UpdateColumns(map[string]interface{}{
+			"processing_status": models.ProcessingInProgress,
+			"processing_error":  nil,
+			"updated_at":        time.Now(),
+		}).Error
+}
+
+// MarkProcessingFailed records that AI processing gave up on the article. A summary from an
+// earlier run is kept, so only the status and error class change. An article whose
+// processing succeeded since it was last queued is left alone, as the failure was
+// delivered again or overtaken.
+func (r *ArticleRepository) MarkProcessingFailed(ctx context.Context, articleID uint, errorClass, requestID string) error {
+	return r.db.WithContext(ctx).Model(&models.Article{}).Where("id = ? AND processing_status <> ?", articleID, models.ProcessingSucceeded).
+		UpdateColumns(map[string]interface{}{
+			"processing_status":     models.ProcessingFailed,
+			"processing_error":      errorClass,
+			"processing_request_id": optionalString(requestID),
+		}).Error
+}
+
+// ListArticlesToCheck lists up to limit articles due for an update check after the cursor,
+// newest first, and returns the cursor after them. Within the page the articles of the
+// feeds with the most subscribers come first.
 func (r *ArticleRepository) ListArticlesToCheck(
 	ctx context.Context,
 	publishedSince, lastCheckedBefore time.Time,
@@ -101,18 +568,13 @@ func (r *ArticleRepository) ListArticlesToCheck(
 		return nil, nil, fmt.Errorf("limit must be greater than zero")
 	}
 
-	query := r.db.WithContext(ctx).
-		Model(&models.Article{}).
-		Select("id, feed_id, url, http_etag, http_last_modified, published_at").
-		Where("published_at >= ?", publishedSince).
-		Where("last_checked_at IS NULL OR last_checked_at <= ?", lastCheckedBefore)
-
-	if cursor != nil {
-		query = query.Where("(published_at < ?) OR (published_at = ? AND id > ?)", cursor.PublishedAt, cursor.PublishedAt, cursor.ArticleID)
-	}
+	query := r.articlesToCheck(ctx, publishedSince, lastCheckedBefore, cursor).
+		Joins("LEFT JOIN feeds ON feeds.id = articles.feed_id").
+		Select("articles.id, articles.feed_id, articles.url, articles.http_etag, articles.http_last_modified, articles.published_at, " +
+			"COALESCE(feeds.subscriber_count, 0) AS subscriber_count")
 
 	var records []ArticleCheckCandidate
-	if err := query.Order("published_at DESC, id ASC").Limit(limit).Find(&records).Error; err != nil {
+	if err := query.Order("articles.published_at DESC, articles.id ASC").Limit(limit).Find(&records).Error; err != nil {
 		return nil, nil, err
 	}
 
@@ -121,7 +583,53 @@ func (r *ArticleRepository) ListArticlesToCheck(
 	}
 
 	last := records[len(records)-1]
-	return records, &ArticleCheckCursor{PublishedAt: last.PublishedAt, ArticleID: last.ID}, nil
+	cursor = &ArticleCheckCursor{PublishedAt: last.PublishedAt, ArticleID: last.ID}
+	// the cursor follows publication order, only the order within the page changes
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].SubscriberCount > records[j].SubscriberCount
+	})
+	return records, cursor, nil
+}
+
+// CountArticlesToCheck counts the candidates ListArticlesToCheck would list after the
+// cursor, all of them when it is nil, along with the topFeeds feeds having the most
+func (r *ArticleRepository) CountArticlesToCheck(
+	ctx context.Context,
+	publishedSince, lastCheckedBefore time.Time,
+	cursor *ArticleCheckCursor,
+	topFeeds int,
+) (ArticleCheckCounts, error) {
+	var counts ArticleCheckCounts
+	if err := r.articlesToCheck(ctx, publishedSince, lastCheckedBefore, cursor).Count(&counts.Remaining).Error; err != nil {
+		return ArticleCheckCounts{}, err
+	}
+	if counts.Remaining == 0 || topFeeds <= 0 {
+		return counts, nil
+	}
+
+	err := r.articlesToCheck(ctx, publishedSince, lastCheckedBefore, cursor).
+		Select("feed_id, COUNT(*) AS count").
+		Group("feed_id").
+		Order("count DESC, feed_id ASC").
+		Limit(topFeeds).
+		Scan(&counts.Feeds).Error
+	if err != nil {
+		return ArticleCheckCounts{}, err
+	}
+	return counts, nil
+}
+
+// articlesToCheck selects the articles published since publishedSince and not checked
+// after lastCheckedBefore, after the cursor when there is one
+func (r *ArticleRepository) articlesToCheck(ctx context.Context, publishedSince, lastCheckedBefore time.Time, cursor *ArticleCheckCursor) *gorm.DB {
+	query := r.db.WithContext(ctx).
+		Model(&models.Article{}).
+		Where("articles.published_at >= ?", publishedSince).
+		Where("articles.last_checked_at IS NULL OR articles.last_checked_at <= ?", lastCheckedBefore)
+	if cursor != nil {
+		query = query.Where("(articles.published_at < ?) OR (articles.published_at = ? AND articles.id > ?)", cursor.PublishedAt, cursor.PublishedAt, cursor.ArticleID)
+	}
+	return query
 }
 
 func (r *ArticleRepository) MarkLastChecked(ctx context.Context, articleID uint, checkedAt time.Time) error {
@@ -138,10 +646,33 @@ func (r *ArticleRepository) MarkLastChecked(ctx context.Context, articleID uint,
 	return nil
 }
 
+// RecordNonHTMLContent records that an article page is not HTML or text, such as a PDF,
+// keeping the content from the feed. The validators are stored so the next check is a
+// cheap conditional request.
+func (r *ArticleRepository) RecordNonHTMLContent(ctx context.Context, articleID uint, contentType string, newETag, newLastModified *string, checkedAt time.Time) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.Article{}).
+		Where("id = ?", articleID).
+		Updates(map[string]interface{}{
+			"content_type":       contentType,
+			"last_checked_at":    checkedAt,
+			"http_etag":          newETag,
+			"http_last_modified": newLastModified,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("article %d not found: %w", articleID, gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
 func (r *ArticleRepository) UpdateArticleOnChange(
 	ctx context.Context,
 	articleID uint,
-	content, description string,
+	content, description, direction string,
+	contentType *string,
 	newETag, newLastModified *string,
 	checkedAt time.Time,
 	prevETag, prevLastModified *string,
@@ -149,6 +680,8 @@ func (r *ArticleRepository) UpdateArticleOnChange(
 	updates := map[string]interface{}{
 		"content":            content,
 		"description":        description,
+		"direction":          direction,
+		"content_type":       contentType,
 		"last_checked_at":    checkedAt,
 		"updated_at":         checkedAt,
 		"http_etag":          newETag,
@@ -19,7 +19,7 @@ func setupArticleRepo(t *testing.T) *ArticleRepository {
 	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_fk=1", t.Name())
 	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
 	require.NoError(t, err)
-	require.NoError(t, db.AutoMigrate(&models.Article{}))
+	require.NoError(t, db.AutoMigrate(&models.Article{}, &models.ArticleSummary{}, &models.Feed{}))
 	return NewArticleRepository(db)
 }
 
@@ -55,6 +55,69 @@ func TestArticleRepository_ListArticlesToCheck(t *testing.T) {
 	assert.Nil(t, nextCursor)
 }
 
+func TestArticleRepository_ListArticlesToCheck_PopularFeedsFirst(t *testing.T) {
+	repo := setupArticleRepo(t)
+	ctx := context.Background()
+
+	for id, subscribers := range map[uint]int{1: 1, 2: 12} {
+		require.NoError(t, repo.db.Create(&models.Feed{ID: id, Title: "F", URL: fmt.Sprintf("https://example.com/%d.xml", id)}).Error)
+		require.NoError(t, repo.db.Exec("UPDATE feeds SET subscriber_count = ? WHERE id = ?", subscribers, id).Error)
+	}
+
+	now := time.Now().UTC()
+	var articles []*models.Article
+	for i, feedID := range []uint{1, 3, 2, 1, 2} {
+		articles = append(articles, &models.Article{FeedID: feedID, Title: "A", URL: fmt.Sprintf("https://example.com/%d", i),
+			PublishedAt: now.Add(-time.Duration(i+1) * time.Hour), CreatedAt: now, UpdatedAt: now})
+	}
+	require.NoError(t, repo.CreateBatch(ctx, articles))
+
+	publishedSince := now.Add(-24 * time.Hour)
+	records, cursor, err := repo.ListArticlesToCheck(ctx, publishedSince, now, 4, nil)
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	ids := make([]uint, len(records))
+	for i, record := range records {
+		ids[i] = record.ID
+	}
+	assert.Equal(t, []uint{articles[2].ID, articles[0].ID, articles[3].ID, articles[1].ID}, ids,
+		"most followed feeds first, newest first among equals, feeds without a row last")
+	assert.EqualValues(t, 12, records[0].SubscriberCount)
+	require.NotNil(t, cursor)
+	assert.Equal(t, articles[3].ID, cursor.ArticleID, "the cursor still follows publication order")
+}
+
+func TestArticleRepository_CountArticlesToCheck(t *testing.T) {
+	repo := setupArticleRepo(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	var articles []*models.Article
+	for i, feedID := range []uint{1, 2, 2, 3, 2, 3} {
+		articles = append(articles, &models.Article{FeedID: feedID, Title: "A", URL: "https://example.com/" + string(rune('a'+i)),
+			PublishedAt: now.Add(-time.Duration(i+1) * time.Hour), CreatedAt: now, UpdatedAt: now})
+	}
+	// checked recently, so not a candidate
+	articles = append(articles, &models.Article{FeedID: 1, Title: "A", URL: "https://example.com/checked",
+		PublishedAt: now.Add(-time.Hour), CreatedAt: now, UpdatedAt: now, LastCheckedAt: ptrTime(now)})
+	require.NoError(t, repo.CreateBatch(ctx, articles))
+
+	publishedSince := now.Add(-24 * time.Hour)
+	lastCheckedBefore := now.Add(-time.Hour)
+
+	counts, err := repo.CountArticlesToCheck(ctx, publishedSince, lastCheckedBefore, nil, 2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 6, counts.Remaining)
+	assert.Equal(t, []FeedCheckCount{{FeedID: 2, Count: 3}, {FeedID: 3, Count: 2}}, counts.Feeds)
+
+	_, cursor, err := repo.ListArticlesToCheck(ctx, publishedSince, lastCheckedBefore, 4, nil)
+	require.NoError(t, err)
+	counts, err = repo.CountArticlesToCheck(ctx, publishedSince, lastCheckedBefore, cursor, 2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, counts.Remaining)
+	assert.Equal(t, []FeedCheckCount{{FeedID: 2, Count: 1}, {FeedID: 3, Count: 1}}, counts.Feeds)
+}
+
 func TestArticleRepository_MarkLastChecked(t *testing.T) {
 	repo := setupArticleRepo(t)
 	ctx := context.Background()
@@ -82,7 +145,7 @@ func TestArticleRepository_UpdateArticleOnChange(t *testing.T) {
 	require.NoError(t, err)
 
 	checkedAt := now.Add(time.Minute)
-	updated, err := repo.UpdateArticleOnChange(ctx, article.ID, "content", "desc", optional("etag"), optional("2024-01-01T00:00:00Z"), checkedAt, nil, nil)
+	updated, err := repo.UpdateArticleOnChange(ctx, article.ID, "content", "desc", "ltr", optional("text/html"), optional("etag"), optional("2024-01-01T00:00:00Z"), checkedAt, nil, nil)
 	require.NoError(t, err)
 	assert.True(t, updated)
 
@@ -93,7 +156,7 @@ func TestArticleRepository_UpdateArticleOnChange(t *testing.T) {
 	require.NotNil(t, stored.HTTPETag)
 	assert.Equal(t, "etag", *stored.HTTPETag)
 
-	updated, err = repo.UpdateArticleOnChange(ctx, article.ID, "new", "desc", optional("etag2"), nil, checkedAt, optional("missing"), nil)
+	updated, err = repo.UpdateArticleOnChange(ctx, article.ID, "new", "desc", "ltr", nil, optional("etag2"), nil, checkedAt, optional("missing"), nil)
 	require.NoError(t, err)
 	assert.False(t, updated)
 }
@@ -105,3 +168,137 @@ func ptrTime(t time.Time) *time.Time {
 func optional(s string) *string {
 	return &s
 }
+
+func TestArticleRepository_SoftDelete(t *testing.T) {
+	repo := setupArticleRepo(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	articles := []*models.Article{
+		{FeedID: 1, Title: "Kept", URL: "https://example.com/kept", PublishedAt: now},
+		{FeedID: 1, Title: "Recent", URL: "https://example.com/recent", PublishedAt: now},
+		{FeedID: 1, Title: "Old", URL: "https://example.com/old", PublishedAt: now},
+	}
+	require.NoError(t, repo.CreateBatch(ctx, articles))
+
+	require.NoError(t, repo.Delete(ctx, articles[1].ID))
+	require.NoError(t, repo.db.Model(&models.Article{}).Where("id = ?", articles[2].ID).Update("deleted_at", now.Add(-48*time.Hour)).Error)
+
+	listed, err := repo.GetByFeedID(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, listed, 1, "trashed articles are excluded by default")
+	_, err = repo.GetByID(ctx, articles[1].ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	exists, err := repo.ExistsByURL(ctx, "https://example.com/recent")
+	require.NoError(t, err)
+	assert.True(t, exists, "a fetch must not recreate a trashed article")
+
+	cutoff := now.Add(-24 * time.Hour)
+	restored, err := repo.Restore(ctx, articles[2].ID, cutoff)
+	require.NoError(t, err)
+	assert.False(t, restored, "past the grace period")
+
+	purged, err := repo.PurgeDeletedBefore(ctx, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	_, err = repo.GetDeletedByID(ctx, articles[2].ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	restored, err = repo.Restore(ctx, articles[1].ID, cutoff)
+	require.NoError(t, err)
+	assert.True(t, restored)
+	listed, err = repo.GetByFeedID(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, listed, 2)
+}
+
+func TestArticleRepository_ApplyAIResults(t *testing.T) {
+	repo := setupArticleRepo(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	articles := []*models.Article{
+		{FeedID: 1, Title: "A1", URL: "https://example.com/1", PublishedAt: now},
+		{FeedID: 1, Title: "A2", URL: "https://example.com/2", PublishedAt: now, Summary: optional("earlier summary")},
+		{FeedID: 1, Title: "A3", URL: "https://example.com/3", PublishedAt: now},
+		{FeedID: 1, Title: "A4", URL: "https://example.com/4", PublishedAt: now},
+	}
+	require.NoError(t, repo.CreateBatch(ctx, articles))
+
+	require.NoError(t, repo.ApplyAIResults(ctx, []AIResult{
+		{ArticleID: articles[0].ID, Summary: "first", ProcessingModel: "model", Language: "zh", Default: true, RequestID: "req-1"},
+		{ArticleID: articles[0].ID, Summary: "in English", ProcessingModel: "model", Language: "en", RequestID: "req-1"},
+		{ArticleID: articles[1].ID, Failed: true, ErrorClass: "rate_limited", RequestID: "req-2"},
+		{ArticleID: articles[2].ID, Failed: true, ErrorClass: "timeout"},
+		{ArticleID: articles[2].ID, Summary: "retried", ProcessingModel: "model", Language: "zh", Default: true, SummaryTruncated: true},
+		{ArticleID: articles[3].ID, Failed: true, ErrorClass: "rate_limited"},
+	}))
+
+	first, err := repo.GetByID(ctx, articles[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProcessingSucceeded, first.ProcessingStatus)
+	assert.Equal(t, "first", *first.Summary)
+	assert.NotNil(t, first.ProcessedAt)
+	assert.Equal(t, "req-1", *first.ProcessingRequestID)
+
+	var variants []models.ArticleSummary
+	require.NoError(t, repo.db.Where("article_id = ?", articles[0].ID).Order("language").Find(&variants).Error)
+	require.Len(t, variants, 2)
+	assert.Equal(t, "in English", variants[0].Summary)
+	assert.Equal(t, "first", variants[1].Summary)
+
+	failed, err := repo.GetByID(ctx, articles[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProcessingFailed, failed.ProcessingStatus)
+	assert.Equal(t, "rate_limited", *failed.ProcessingError)
+	assert.Equal(t, "earlier summary", *failed.Summary, "a failure keeps the earlier summary")
+	assert.Equal(t, "req-2", *failed.ProcessingRequestID)
+
+	retried, err := repo.GetByID(ctx, articles[2].ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProcessingSucceeded, retried.ProcessingStatus, "the last result for an article wins")
+	assert.Nil(t, retried.ProcessingError)
+	assert.True(t, retried.SummaryTruncated)
+
+	alsoFailed, err := repo.GetByID(ctx, articles[3].ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProcessingFailed, alsoFailed.ProcessingStatus)
+	assert.Nil(t, alsoFailed.ProcessingRequestID)
+}
+
+func TestArticleRepository_ApplyAIResultsDeliveredAgain(t *testing.T) {
+	repo := setupArticleRepo(t)
+	ctx := context.Background()
+
+	article := &models.Article{FeedID: 1, Title: "A1", URL: "https://example.com/1", PublishedAt: time.Now()}
+	require.NoError(t, repo.CreateBatch(ctx, []*models.Article{article}))
+	summary := AIResult{ArticleID: article.ID, Summary: "first", ProcessingModel: "model", Language: "zh", Default: true, RequestID: "req-1"}
+	require.NoError(t, repo.ApplyAIResults(ctx, []AIResult{summary}))
+	applied, err := repo.GetByID(ctx, article.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.ApplyAIResults(ctx, []AIResult{summary}))
+	require.NoError(t, repo.ApplyAIResults(ctx, []AIResult{{ArticleID: article.ID, Failed: true, ErrorClass: "timeout", RequestID: "req-1"}}))
+	require.NoError(t, repo.MarkProcessingFailed(ctx, article.ID, "timeout", "req-1"))
+
+	again, err := repo.GetByID(ctx, article.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProcessingSucceeded, again.ProcessingStatus, "a failure delivered late does not replace the summary")
+	assert.Nil(t, again.ProcessingError)
+	assert.True(t, applied.ProcessedAt.Equal(*again.ProcessedAt), "the summary delivered again is not applied again")
+
+	// once the article is queued again its failures count
+	require.NoError(t, repo.MarkProcessing(ctx, article.ID))
+	require.NoError(t, repo.MarkProcessingFailed(ctx, article.ID, "rate_limited", "req-2"))
+	requeued, err := repo.GetByID(ctx, article.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProcessingFailed, requeued.ProcessingStatus)
+	assert.Equal(t, "first", *requeued.Summary)
+
+	require.NoError(t, repo.ApplyAIResults(ctx, []AIResult{{ArticleID: article.ID, Summary: "second", ProcessingModel: "model", Language: "zh", Default: true, RequestID: "req-2"}}))
+	regenerated, err := repo.GetByID(ctx, article.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "second", *regenerated.Summary)
+	assert.Equal(t, models.ProcessingSucceeded, regenerated.ProcessingStatus)
+}
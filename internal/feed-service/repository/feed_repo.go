@@ -2,10 +2,14 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/dbtx"
 )
 
 type FeedRepository struct {
@@ -18,6 +22,12 @@ func NewFeedRepository(db *gorm.DB) *FeedRepository {
 	}
 }
 
+// WithTx returns a copy of the repository working in the transaction of a
+// dbtx.UnitOfWork; a nil tx keeps the repository's own DB
+func (r *FeedRepository) WithTx(tx *gorm.DB) *FeedRepository {
+	return &FeedRepository{db: dbtx.Bind(r.db, tx)}
+}
+
 func (r *FeedRepository) Create(ctx context.Context, feed *models.Feed) (*models.Feed, error) {
 	result := r.db.WithContext(ctx).Create(feed)
 	return feed, result.Error
@@ -34,6 +44,171 @@ func (r *FeedRepository) ListAll(ctx context.Context) ([]*models.Feed, error) {
 	return feeds, result.Error
 }
 
+// unscheduledStatuses are never fetched by the scheduler
+var unscheduledStatuses = []models.FeedStatus{models.FeedStatusArchived, models.FeedStatusSuspended, models.FeedStatusError}
+
+// ListSchedulable returns every feed that should still be fetched periodically (i.e. not
+// archived, suspended or failing)
+func (r *FeedRepository) ListSchedulable(ctx context.Context) ([]*models.Feed, error) {
+	feeds := make([]*models.Feed, 0)
+	result := r.db.WithContext(ctx).Where("status NOT IN ?", unscheduledStatuses).Find(&feeds)
+	return feeds, result.Error
+}
+
+// FeedListFilter narrows ListPage; the zero value matches every feed
+type FeedListFilter struct {
+	ExcludeArchived bool              // skip the feeds that are not scheduled: archived, suspended or in error
+	Status          models.FeedStatus // only feeds with this status when set
+	// DueBefore keeps the feeds due for a fetch: never fetched or last fetched before this
+	// time. High tier feeds are always due, low tier feeds and the other feeds nobody
+	// subscribes to follow LowTierDueBefore when set.
+	DueBefore        *time.Time
+	LowTierDueBefore *time.Time
+
+	// The fields below select feeds for administration
+	IDs []uint
+	// ErrorContains keeps feeds whose last fetch error contains it, ignoring case, e.g.
+	// "503" or "no such host"
+	ErrorContains string
+	// NotFetchedSince keeps feeds never fetched or last fetched before this time
+	NotFetchedSince *time.Time
+	Tier            models.FeedTier
+}
+
+// Selective reports whether the filter narrows the feeds by more than the scheduling fields
+func (f FeedListFilter) Selective() bool {
+	return f.Status != "" || len(f.IDs) > 0 || f.ErrorContains != "" || f.NotFetchedSince != nil || f.Tier != ""
+}
+
+func (f FeedListFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.ExcludeArchived {
+		query = query.Where("status NOT IN ?", unscheduledStatuses)
+	}
+	if f.Status != "" {
+		query = query.Where("status = ?", f.Status)
+	}
+	query = scopeDue(query, f.DueBefore, f.LowTierDueBefore)
+	if len(f.IDs) > 0 {
+		query = query.Where("id IN ?", f.IDs)
+	}
+	if f.ErrorContains != "" {
+		query = query.Where("LOWER(last_fetch_error) LIKE ?", "%"+strings.ToLower(f.ErrorContains)+"%")
+	}
+	if f.NotFetchedSince != nil {
+		query = query.Where("(last_fetched_at IS NULL OR last_fetched_at < ?)", *f.NotFetchedSince)
+	}
+	if f.Tier != "" {
+		query = query.Where("fetch_tier = ?", f.Tier)
+	}
+	return query
+}
+
+// scopeDue keeps the feeds due for a fetch: high tier feeds always are, low tier feeds and
+// the other feeds nobody subscribes to when never fetched or last fetched before
+// lowTierDueBefore (dueBefore when nil), and the other feeds when never fetched or last
+// fetched before dueBefore. Nil cutoffs keep every feed.
+func scopeDue(query *gorm.DB, dueBefore, lowTierDueBefore *time.Time) *gorm.DB {
+	if lowTierDueBefore == nil {
+		lowTierDueBefore = dueBefore
+	}
+	if dueBefore == nil && lowTierDueBefore == nil {
+		return query
+	}
+
+	tiers := []models.FeedTier{models.FeedTierHigh, models.FeedTierLow}
+	conditions := []string{"fetch_tier = ?"}
+	args := []any{models.FeedTierHigh}
+	if lowTierDueBefore != nil {
+		conditions = append(conditions, "((fetch_tier = ? OR (fetch_tier NOT IN ? AND subscriber_count = 0)) AND (last_fetched_at IS NULL OR last_fetched_at < ?))")
+		args = append(args, models.FeedTierLow, tiers, *lowTierDueBefore)
+	} else {
+		conditions = append(conditions, "(fetch_tier = ? OR (fetch_tier NOT IN ? AND subscriber_count = 0))")
+		args = append(args, models.FeedTierLow, tiers)
+	}
+	if dueBefore != nil {
+		conditions = append(conditions, "(fetch_tier NOT IN ? AND subscriber_count > 0 AND (last_fetched_at IS NULL OR last_fetched_at < ?))")
+		args = append(args, tiers, *dueBefore)
+	} else {
+		conditions = append(conditions, "(fetch_tier NOT IN ? AND subscriber_count > 0)")
+		args = append(args, tiers)
+	}
+	return query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+}
+
+// ListPage returns up to limit feeds matching the filter with IDs above afterID, in ID order
+func (r *FeedRepository) ListPage(ctx context.Context, filter FeedListFilter, afterID uint, limit int) ([]*models.Feed, error) {
+	query := filter.apply(r.db.WithContext(ctx).Where("id > ?", afterID))
+
+	feeds := make([]*models.Feed, 0, limit)
+	result := query.Order("id ASC").Limit(limit).Find(&feeds)
+	return feeds, result.Error
+}
+
+// Count counts the feeds matching the filter
+func (r *FeedRepository) Count(ctx context.Context, filter FeedListFilter) (int64, error) {
+	var count int64
+	result := filter.apply(r.db.WithContext(ctx).Model(&models.Feed{})).Count(&count)
+	return count, result.Error
+}
+
+// FetchBacklog is how far behind feed fetching is
+type FetchBacklog struct {
+	DueFeeds      int64      // scheduled feeds due for a fetch
+	LastFetchedAt *time.Time // latest fetch of any feed, nil if none was ever fetched
+}
+
+// FetchBacklog counts the scheduled feeds due for a fetch under dueBefore and
+// lowTierDueBefore (every scheduled feed when both are nil), and finds the latest fetch
+// of any feed
+func (r *FeedRepository) FetchBacklog(ctx context.Context, dueBefore, lowTierDueBefore *time.Time) (*FetchBacklog, error) {
+	backlog := &FetchBacklog{}
+	filter := FeedListFilter{ExcludeArchived: true, DueBefore: dueBefore, LowTierDueBefore: lowTierDueBefore}
+	if err := filter.apply(r.db.WithContext(ctx).Model(&models.Feed{})).Count(&backlog.DueFeeds).Error; err != nil {
+		return nil, err
+	}
+
+	var latest models.Feed
+	err := r.db.WithContext(ctx).
+		Select("last_fetched_at").
+		Where("last_fetched_at IS NOT NULL").
+		Order("last_fetched_at DESC").
+		Take(&latest).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	backlog.LastFetchedAt = latest.LastFetchedAt
+	return backlog, nil
+}
+
+// Owners returns the longest-standing subscriber of the given feeds, or of every feed
+// when none are given. Feeds without subscribers are left out.
+func (r *FeedRepository) Owners(ctx context.Context, feedIDs ...uint) (map[uint]uint, error) {
+	query := r.db.WithContext(ctx).
+		Model(&models.Subscription{}).
+		Select("feed_id, user_id").
+		Where(`NOT EXISTS (SELECT 1 FROM subscriptions earlier WHERE earlier.feed_id = subscriptions.feed_id
+			AND (earlier.created_at < subscriptions.created_at
+				OR (earlier.created_at = subscriptions.created_at AND earlier.user_id < subscriptions.user_id)))`)
+	if len(feedIDs) > 0 {
+		query = query.Where("feed_id IN ?", feedIDs)
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	owners := make(map[uint]uint)
+	for rows.Next() {
+		var feedID, userID uint
+		if err := rows.Scan(&feedID, &userID); err != nil {
+			return nil, err
+		}
+		owners[feedID] = userID
+	}
+	return owners, rows.Err()
+}
+
 func (r *FeedRepository) GetByID(ctx context.Context, id uint) (*models.Feed, error) {
 	feed := &models.Feed{}
 	result := r.db.WithContext(ctx).First(feed, id)
@@ -70,10 +245,7 @@ func (r *FeedRepository) ListUserFeeds(ctx context.Context, userID uint) ([]*mod
 
 	userFeeds := make([]*models.UserFeed, 0, len(subscriptions))
 	for _, sub := range subscriptions {
-		userFeeds = append(userFeeds, &models.UserFeed{
-			Feed:        sub.Feed,
-			CustomTitle: sub.CustomTitle,
-		})
+		userFeeds = append(userFeeds, sub.UserFeed())
 	}
 	return userFeeds, nil
 }
@@ -90,13 +262,28 @@ func (r *FeedRepository) GetSubscription(ctx context.Context, userID, feedID uin
 	return &subscription, nil
 }
 
-func (r *FeedRepository) UpdateSubscriptionCustomTitle(ctx context.Context, userID, feedID uint, customTitle *string) error {
+func (r *FeedRepository) UpdateSubscription(ctx context.Context, userID, feedID uint, update models.SubscriptionUpdate) error {
 	result := r.db.WithContext(ctx).Model(&models.Subscription{}).
 		Where("user_id = ? AND feed_id = ?", userID, feedID).
-		Update("custom_title", customTitle)
+		Updates(update.Columns())
 	return result.Error
 }
 
+// FetchHeadersCiphertext returns the encrypted custom fetch headers of the longest-standing
+// subscriber who set some, or "" when nobody did
+func (r *FeedRepository) FetchHeadersCiphertext(ctx context.Context, feedID uint) (string, error) {
+	var ciphertexts []string
+	result := r.db.WithContext(ctx).Model(&models.Subscription{}).
+		Where("feed_id = ? AND fetch_headers_ciphertext IS NOT NULL", feedID).
+		Order("created_at, user_id").
+		Limit(1).
+		Pluck("fetch_headers_ciphertext", &ciphertexts)
+	if result.Error != nil || len(ciphertexts) == 0 {
+		return "", result.Error
+	}
+	return ciphertexts[0], nil
+}
+
 func (r *FeedRepository) UpdateStatus(ctx context.Context, feedID uint, status models.FeedStatus) error {
 	result := r.db.WithContext(ctx).Model(&models.Feed{}).
 		Where("id = ?", feedID).
@@ -104,17 +291,59 @@ func (r *FeedRepository) UpdateStatus(ctx context.Context, feedID uint, status m
 	return result.Error
 }
 
-func (r *FeedRepository) UpdateFeedMetadata(ctx context.Context, feedID uint, title, description string, status models.FeedStatus) error {
+// UpdateFeedMetadata stores the title, description and site URL a fetch found
+func (r *FeedRepository) UpdateFeedMetadata(ctx context.Context, feedID uint, meta models.FeedMetadata) error {
 	result := r.db.WithContext(ctx).Model(&models.Feed{}).
 		Where("id = ?", feedID).
 		Updates(map[string]interface{}{
-			"title":       title,
-			"description": description,
-			"status":      status,
+			"title":       meta.Title,
+			"description": meta.Description,
+			"site_url":    meta.SiteURL,
 		})
 	return result.Error
 }
 
+// SetDatesUnreliable flags whether the feed has items without usable dates
+func (r *FeedRepository) SetDatesUnreliable(ctx context.Context, feedID uint, unreliable bool) error {
+	result := r.db.WithContext(ctx).Model(&models.Feed{}).
+		Where("id = ?", feedID).
+		UpdateColumn("dates_unreliable", unreliable)
+	return result.Error
+}
+
+// SetImportedSince sets the publication date before which fetches skip the items of the
+// feed; nil imports them all again
+func (r *FeedRepository) SetImportedSince(ctx context.Context, feedID uint, since *time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.Feed{}).
+		Where("id = ?", feedID).
+		UpdateColumn("imported_since", since)
+	return result.Error
+}
+
+// SubscriberSummaryLanguages lists the summary languages the feed's subscribers chose in
+// their preferences (a user-service table), the most chosen first
+func (r *FeedRepository) SubscriberSummaryLanguages(ctx context.Context, feedID uint) ([]string, error) {
+	var languages []string
+	err := r.db.WithContext(ctx).
+		Table("user_preferences AS p").
+		Joins("JOIN subscriptions s ON s.user_id = p.user_id").
+		Where("s.feed_id = ? AND p.summary_language <> ''", feedID).
+		Group("p.summary_language").
+		Order("COUNT(*) DESC, p.summary_language").
+		Pluck("p.summary_language", &languages).Error
+	return languages, err
+}
+
+// SubscriberIDs lists the users subscribed to the feed
+func (r *FeedRepository) SubscriberIDs(ctx context.Context, feedID uint) ([]uint, error) {
+	var userIDs []uint
+	err := r.db.WithContext(ctx).Model(&models.Subscription{}).
+		Where("feed_id = ?", feedID).
+		Order("user_id").
+		Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
 func (r *FeedRepository) CreateSubscription(ctx context.Context, subscription *models.Subscription) error {
 	result := r.db.WithContext(ctx).Create(subscription)
 	return result.Error
@@ -134,6 +363,13 @@ func (r *FeedRepository) IsUserSubscribed(ctx context.Context, userID, feedID ui
 	return count > 0, result.Error
 }
 
+// CountSubscriptions counts the feeds a user subscribes to
+func (r *FeedRepository) CountSubscriptions(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	result := r.db.WithContext(ctx).Model(&models.Subscription{}).Where("user_id = ?", userID).Count(&count)
+	return count, result.Error
+}
+
 func (r *FeedRepository) GetByURLs(ctx context.Context, urls []string) ([]*models.Feed, error) {
 	if len(urls) == 0 {
 		return []*models.Feed{}, nil
@@ -143,6 +379,25 @@ func (r *FeedRepository) GetByURLs(ctx context.Context, urls []string) ([]*model
 	return feeds, result.Error
 }
 
+// existingURLsBatch bounds the URLs looked up per query, well below the bind parameter limit
+const existingURLsBatch = 1000
+
+// ExistingURLs returns the given URLs a feed exists for, compared exactly
+func (r *FeedRepository) ExistingURLs(ctx context.Context, urls []string) ([]string, error) {
+	existing := make([]string, 0)
+	for start := 0; start < len(urls); start += existingURLsBatch {
+		var batch []string
+		err := r.db.WithContext(ctx).Model(&models.Feed{}).
+			Where("url IN ?", urls[start:min(start+existingURLsBatch, len(urls))]).
+			Pluck("url", &batch).Error
+		if err != nil {
+			return nil, err
+		}
+		existing = append(existing, batch...)
+	}
+	return existing, nil
+}
+
 func (r *FeedRepository) BatchCreateFeeds(ctx context.Context, feeds []*models.Feed) error {
 	if len(feeds) == 0 {
 		return nil
@@ -176,3 +431,294 @@ func (r *FeedRepository) BatchCreateSubscriptions(ctx context.Context, subscript
 	}
 	return r.db.WithContext(ctx).CreateInBatches(subscriptions, 100).Error
 }
+
+// RecordFetchError stores the root cause of a failed fetch and extends the feed's failure
+// streak. It returns the length of the streak.
+func (r *FeedRepository) RecordFetchError(ctx context.Context, feedID uint, message string, at time.Time) (int, error) {
+	var failures int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Feed{}).
+			Where("id = ?", feedID).
+			Updates(map[string]interface{}{
+				"last_fetch_error":     message,
+				"last_fetch_error_at":  at,
+				"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Model(&models.Feed{}).
+			Where("id = ?", feedID).
+			Pluck("consecutive_failures", &failures).Error
+	})
+	return failures, err
+}
+
+// RecordFetchSuccess ends the feed's failure streak and sets a feed in error back to
+// active. It reports whether the feed was in error.
+func (r *FeedRepository) RecordFetchSuccess(ctx context.Context, feedID uint) (bool, error) {
+	recovered := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Feed{}).
+			Where("id = ? AND status = ?", feedID, models.FeedStatusError).
+			Updates(map[string]interface{}{
+				"status":               models.FeedStatusActive,
+				"consecutive_failures": 0,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		recovered = result.RowsAffected > 0
+
+		return tx.Model(&models.Feed{}).
+			Where("id = ? AND consecutive_failures <> 0", feedID).
+			UpdateColumn("consecutive_failures", 0).Error
+	})
+	return recovered, err
+}
+
+// MarkFetched records when a fetch attempt of the feed finished and the request that
+// caused it, leaving updated_at alone
+func (r *FeedRepository) MarkFetched(ctx context.Context, feedID uint, at time.Time, requestID string) error {
+	result := r.db.WithContext(ctx).Model(&models.Feed{}).
+		Where("id = ?", feedID).
+		UpdateColumns(map[string]any{
+			"last_fetched_at":       at,
+			"last_fetch_request_id": optionalString(requestID),
+		})
+	return result.Error
+}
+
+// UpdateHTTPValidators stores the ETag and Last-Modified of the last feed response; nil
+// clears a validator the server stopped sending
+func (r *FeedRepository) UpdateHTTPValidators(ctx context.Context, feedID uint, etag, lastModified *string) error {
+	result := r.db.WithContext(ctx).Model(&models.Feed{}).
+		Where("id = ?", feedID).
+		UpdateColumns(map[string]any{
+			"http_etag":          etag,
+			"http_last_modified": lastModified,
+		})
+	return result.Error
+}
+
+// MarkGone records that the feed source returned 404/410. The first occurrence of a
+// failure streak is kept so the detector can measure how long the feed has been gone.
+func (r *FeedRepository) MarkGone(ctx context.Context, feedID uint, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.Feed{}).
+		Where("id = ?", feedID).
+		Update("gone_since", gorm.Expr("COALESCE(gone_since, ?)", at))
+	return result.Error
+}
+
+// ListGoneSince returns feeds that have been gone since before the cutoff, leaving out
+// archived ones and suspended ones, which stay as an administrator left them
+func (r *FeedRepository) ListGoneSince(ctx context.Context, cutoff time.Time) ([]*models.Feed, error) {
+	feeds := make([]*models.Feed, 0)
+	result := r.db.WithContext(ctx).
+		Where("archived_at IS NULL AND gone_since IS NOT NULL AND gone_since <= ?", cutoff).
+		Where("status <> ?", models.FeedStatusSuspended).
+		Order("id ASC").
+		Find(&feeds)
+	return feeds, result.Error
+}
+
+// ArchiveFeed flags the feed as archived and notifies every subscriber in one transaction
+func (r *FeedRepository) ArchiveFeed(ctx context.Context, feedID uint, at time.Time, message string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Feed{}).
+			Where("id = ? AND archived_at IS NULL", feedID).
+			Updates(map[string]interface{}{
+				"status":      models.FeedStatusArchived,
+				"archived_at": at,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil // already archived
+		}
+		return notifySubscribers(tx, feedID, models.NotificationFeedArchived, message)
+	})
+}
+
+// DeleteFeed removes a feed on an administrator's behalf in one transaction: subscribers
+// are notified and unsubscribed, then the feed is either archived with its articles or
+// purged together with them. It returns gorm.ErrRecordNotFound for an unknown feed.
+func (r *FeedRepository) DeleteFeed(ctx context.Context, feedID uint, retention models.FeedRetention, message string, at time.Time) (*models.FeedDeletion, error) {
+	deletion := &models.FeedDeletion{FeedID: feedID, Retention: retention, UserIDs: []uint{}}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var feed models.Feed
+		if err := tx.First(&feed, feedID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Subscription{}).Where("feed_id = ?", feedID).
+			Order("user_id").Pluck("user_id", &deletion.UserIDs).Error; err != nil {
+			return err
+		}
+		// the notification outlives a purged feed, so it does not reference it
+		if err := tx.Exec(`INSERT INTO notifications (user_id, feed_id, type, message, created_at)
+			SELECT user_id, NULL, ?, ?, ? FROM subscriptions WHERE feed_id = ?`,
+			models.NotificationFeedRemoved, message, at, feedID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("feed_id = ?", feedID).Delete(&models.Subscription{}).Error; err != nil {
+			return err
+		}
+
+		if retention == models.FeedRetentionArchive {
+			return tx.Model(&models.Feed{}).Where("id = ?", feedID).
+				Updates(map[string]interface{}{
+					"status":      models.FeedStatusArchived,
+					"archived_at": gorm.Expr("COALESCE(archived_at, ?)", at),
+				}).Error
+		}
+
+		// the foreign keys cascade, but are not relied on so rows orphaned by an
+		// earlier manual delete cannot survive either
+		result := tx.Unscoped().Where("feed_id = ?", feedID).Delete(&models.Article{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deletion.ArticlesPurged = result.RowsAffected
+		if err := tx.Where("feed_id = ?", feedID).Delete(&models.FeedSnapshot{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("feed_id = ?", feedID).Delete(&models.Notification{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Feed{}, feedID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deletion, nil
+}
+
+// OrphanPurge counts the rows PurgeOrphans removed
+type OrphanPurge struct {
+	Subscriptions int64
+	Articles      int64
+	Snapshots     int64
+}
+
+// PurgeOrphans deletes subscriptions, articles and snapshots whose feed no longer exists.
+// They are left behind when a feed row is deleted by hand where the foreign keys are
+// missing or disabled; DeleteFeed never leaves any.
+func (r *FeedRepository) PurgeOrphans(ctx context.Context) (*OrphanPurge, error) {
+	purge := &OrphanPurge{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("NOT EXISTS (SELECT 1 FROM feeds WHERE feeds.id = subscriptions.feed_id)").
+			Delete(&models.Subscription{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purge.Subscriptions = result.RowsAffected
+
+		result = tx.Unscoped().Where("NOT EXISTS (SELECT 1 FROM feeds WHERE feeds.id = articles.feed_id)").
+			Delete(&models.Article{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purge.Articles = result.RowsAffected
+
+		result = tx.Where("NOT EXISTS (SELECT 1 FROM feeds WHERE feeds.id = feed_snapshots.feed_id)").
+			Delete(&models.FeedSnapshot{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purge.Snapshots = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return purge, nil
+}
+
+// RestoreFeed clears the gone/archived markers after a successful fetch. Subscribers are
+// notified only when the feed had actually been archived. It reports whether it was.
+func (r *FeedRepository) RestoreFeed(ctx context.Context, feedID uint, message string) (bool, error) {
+	restored := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Feed{}).
+			Where("id = ? AND archived_at IS NOT NULL", feedID).
+			Updates(map[string]interface{}{
+				"status":      models.FeedStatusActive,
+				"archived_at": nil,
+				"gone_since":  nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			restored = true
+			return notifySubscribers(tx, feedID, models.NotificationFeedRestored, message)
+		}
+
+		// not archived: just reset a pending failure streak
+		return tx.Model(&models.Feed{}).
+			Where("id = ? AND gone_since IS NOT NULL", feedID).
+			Update("gone_since", nil).Error
+	})
+	return restored, err
+}
+
+// ReactivateFeeds sets the given feeds back to active and clears their gone/archived
+// markers and failure streaks. Subscribers of the feeds that had been archived are notified, in the same
+// transaction. It returns how many feeds changed.
+func (r *FeedRepository) ReactivateFeeds(ctx context.Context, feedIDs []uint) (int64, error) {
+	if len(feedIDs) == 0 {
+		return 0, nil
+	}
+	var updated int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`INSERT INTO notifications (user_id, feed_id, type, message, created_at)
+			SELECT subscriptions.user_id, subscriptions.feed_id, ?, 'Feed "' || feeds.title || '" has been reactivated by an administrator.', ?
+			FROM subscriptions JOIN feeds ON feeds.id = subscriptions.feed_id
+			WHERE feeds.id IN ? AND feeds.archived_at IS NOT NULL`,
+			models.NotificationFeedRestored, time.Now().UTC(), feedIDs).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.Feed{}).
+			Where("id IN ? AND (status <> ? OR archived_at IS NOT NULL OR gone_since IS NOT NULL OR consecutive_failures <> 0)", feedIDs, models.FeedStatusActive).
+			Updates(map[string]interface{}{
+				"status":               models.FeedStatusActive,
+				"archived_at":          nil,
+				"gone_since":           nil,
+				"consecutive_failures": 0,
+			})
+		updated = result.RowsAffected
+		return result.Error
+	})
+	return updated, err
+}
+
+// SuspendFeeds stops the scheduler from fetching the given feeds. It returns how many
+// feeds changed.
+func (r *FeedRepository) SuspendFeeds(ctx context.Context, feedIDs []uint) (int64, error) {
+	if len(feedIDs) == 0 {
+		return 0, nil
+	}
+	result := r.db.WithContext(ctx).Model(&models.Feed{}).
+		Where("id IN ? AND status <> ?", feedIDs, models.FeedStatusSuspended).
+		Update("status", models.FeedStatusSuspended)
+	return result.RowsAffected, result.Error
+}
+
+// SetFetchTier moves the given feeds to tier. It returns how many feeds changed.
+func (r *FeedRepository) SetFetchTier(ctx context.Context, feedIDs []uint, tier models.FeedTier) (int64, error) {
+	if len(feedIDs) == 0 {
+		return 0, nil
+	}
+	result := r.db.WithContext(ctx).Model(&models.Feed{}).
+		Where("id IN ? AND fetch_tier <> ?", feedIDs, tier).
+		Update("fetch_tier", tier)
+	return result.RowsAffected, result.Error
+}
+
+func notifySubscribers(tx *gorm.DB, feedID uint, notificationType models.NotificationType, message string) error {
+	return tx.Exec(`INSERT INTO notifications (user_id, feed_id, type, message, created_at)
+		SELECT user_id, feed_id, ?, ?, ? FROM subscriptions WHERE feed_id = ?`,
+		notificationType, message, time.Now().UTC(), feedID).Error
+}
@@ -0,0 +1,563 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+func setupFeedRepo(t *testing.T) (*FeedRepository, *gorm.DB) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_fk=1", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Feed{}, &models.Subscription{}, &models.Notification{}))
+	createSubscriberCountTriggers(t, db)
+	return NewFeedRepository(db), db
+}
+
+// createSubscriberCountTriggers keeps feeds.subscriber_count like the trigger of
+// migration 000046 does in Postgres
+func createSubscriberCountTriggers(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	for _, statement := range []string{
+		`CREATE TRIGGER subscriptions_count_insert AFTER INSERT ON subscriptions BEGIN
+			UPDATE feeds SET subscriber_count = subscriber_count + 1 WHERE id = NEW.feed_id;
+		END`,
+		`CREATE TRIGGER subscriptions_count_delete AFTER DELETE ON subscriptions BEGIN
+			UPDATE feeds SET subscriber_count = subscriber_count - 1 WHERE id = OLD.feed_id;
+		END`,
+		`CREATE TRIGGER subscriptions_count_update AFTER UPDATE OF feed_id ON subscriptions BEGIN
+			UPDATE feeds SET subscriber_count = subscriber_count - 1 WHERE id = OLD.feed_id;
+			UPDATE feeds SET subscriber_count = subscriber_count + 1 WHERE id = NEW.feed_id;
+		END`,
+	} {
+		require.NoError(t, db.Exec(statement).Error)
+	}
+}
+
+func TestFeedRepository_ArchiveLifecycle(t *testing.T) {
+	repo, db := setupFeedRepo(t)
+	ctx := context.Background()
+
+	feed, err := repo.Create(ctx, &models.Feed{Title: "Dead", URL: "https://example.com/dead.xml", Status: models.FeedStatusActive})
+	require.NoError(t, err)
+	live, err := repo.Create(ctx, &models.Feed{Title: "Live", URL: "https://example.com/live.xml", Status: models.FeedStatusActive})
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 1, FeedID: feed.ID}))
+	require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 2, FeedID: feed.ID}))
+
+	now := time.Now().UTC()
+	first := now.Add(-40 * 24 * time.Hour)
+	require.NoError(t, repo.MarkGone(ctx, feed.ID, first))
+	require.NoError(t, repo.MarkGone(ctx, feed.ID, now), "later failures must keep the original timestamp")
+
+	gone, err := repo.ListGoneSince(ctx, now.Add(-30*24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, gone, 1)
+	assert.Equal(t, feed.ID, gone[0].ID)
+	assert.WithinDuration(t, first, *gone[0].GoneSince, time.Second)
+
+	require.NoError(t, repo.ArchiveFeed(ctx, feed.ID, now, "archived"))
+	require.NoError(t, repo.ArchiveFeed(ctx, feed.ID, now, "archived"), "archiving twice is a no-op")
+
+	var notifications []models.Notification
+	require.NoError(t, db.Where("type = ?", models.NotificationFeedArchived).Find(&notifications).Error)
+	assert.Len(t, notifications, 2)
+
+	schedulable, err := repo.ListSchedulable(ctx)
+	require.NoError(t, err)
+	require.Len(t, schedulable, 1)
+	assert.Equal(t, live.ID, schedulable[0].ID)
+
+	gone, err = repo.ListGoneSince(ctx, now)
+	require.NoError(t, err)
+	assert.Empty(t, gone, "archived feeds are not candidates again")
+
+	restored, err := repo.RestoreFeed(ctx, feed.ID, "restored")
+	require.NoError(t, err)
+	assert.True(t, restored)
+
+	got, err := repo.GetByID(ctx, feed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.FeedStatusActive, got.Status)
+	assert.Nil(t, got.ArchivedAt)
+	assert.Nil(t, got.GoneSince)
+
+	var restoredCount int64
+	require.NoError(t, db.Model(&models.Notification{}).Where("type = ?", models.NotificationFeedRestored).Count(&restoredCount).Error)
+	assert.Equal(t, int64(2), restoredCount)
+}
+
+func TestFeedRepository_RestoreFeed_NotArchived(t *testing.T) {
+	repo, db := setupFeedRepo(t)
+	ctx := context.Background()
+
+	feed, err := repo.Create(ctx, &models.Feed{Title: "Flaky", URL: "https://example.com/flaky.xml", Status: models.FeedStatusActive})
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 1, FeedID: feed.ID}))
+	require.NoError(t, repo.MarkGone(ctx, feed.ID, time.Now().UTC()))
+
+	restored, err := repo.RestoreFeed(ctx, feed.ID, "restored")
+	require.NoError(t, err)
+	assert.False(t, restored)
+
+	got, err := repo.GetByID(ctx, feed.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.GoneSince, "a successful fetch resets the failure streak")
+
+	var count int64
+	require.NoError(t, db.Model(&models.Notification{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestFeedRepository_UpdateSubscriptionNotes(t *testing.T) {
+	repo, _ := setupFeedRepo(t)
+	ctx := context.Background()
+
+	feed, err := repo.Create(ctx, &models.Feed{Title: "Go Blog", URL: "https://go.dev/blog/feed.atom", Status: models.FeedStatusActive})
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 1, FeedID: feed.ID}))
+
+	title, notes := "Go", "Release notes for the toolchain upgrade"
+	require.NoError(t, repo.UpdateSubscription(ctx, 1, feed.ID, models.SubscriptionUpdate{CustomTitle: &title}))
+	require.NoError(t, repo.UpdateSubscription(ctx, 1, feed.ID, models.SubscriptionUpdate{Notes: &notes}))
+
+	feeds, err := repo.ListUserFeeds(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, feeds, 1)
+	require.NotNil(t, feeds[0].CustomTitle, "updating notes leaves the custom title alone")
+	assert.Equal(t, title, *feeds[0].CustomTitle)
+	require.NotNil(t, feeds[0].Notes)
+	assert.Equal(t, notes, *feeds[0].Notes)
+	assert.True(t, feeds[0].Matches("TOOLCHAIN"))
+	assert.False(t, feeds[0].Matches("rust"))
+
+	cleared := ""
+	require.NoError(t, repo.UpdateSubscription(ctx, 1, feed.ID, models.SubscriptionUpdate{Notes: &cleared}))
+	subscription, err := repo.GetSubscription(ctx, 1, feed.ID)
+	require.NoError(t, err)
+	assert.Nil(t, subscription.Notes)
+	assert.NotNil(t, subscription.CustomTitle)
+}
+
+func TestFeedRepository_Owners(t *testing.T) {
+	repo, _ := setupFeedRepo(t)
+	ctx := context.Background()
+
+	popular, err := repo.Create(ctx, &models.Feed{Title: "Popular", URL: "https://example.com/popular.xml", Status: models.FeedStatusActive})
+	require.NoError(t, err)
+	niche, err := repo.Create(ctx, &models.Feed{Title: "Niche", URL: "https://example.com/niche.xml", Status: models.FeedStatusActive})
+	require.NoError(t, err)
+	unsubscribed, err := repo.Create(ctx, &models.Feed{Title: "Orphan", URL: "https://example.com/orphan.xml", Status: models.FeedStatusActive})
+	require.NoError(t, err)
+
+	start := time.Now().Add(-time.Hour)
+	require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 3, FeedID: popular.ID, CreatedAt: start.Add(time.Minute)}))
+	require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 5, FeedID: popular.ID, CreatedAt: start}))
+	require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 1, FeedID: popular.ID, CreatedAt: start.Add(2 * time.Minute)}))
+	require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 3, FeedID: niche.ID, CreatedAt: start}))
+
+	owners, err := repo.Owners(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint(5), owners[popular.ID], "the earliest subscriber owns the feed")
+	assert.Equal(t, uint(3), owners[niche.ID])
+	assert.NotContains(t, owners, unsubscribed.ID)
+
+	owners, err = repo.Owners(ctx, niche.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[uint]uint{niche.ID: 3}, owners, "owners can be limited to some feeds")
+}
+
+func TestFeedRepository_SubscriberCount(t *testing.T) {
+	repo, _ := setupFeedRepo(t)
+	ctx := context.Background()
+
+	feed, err := repo.Create(ctx, &models.Feed{Title: "Counted", URL: "https://example.com/counted.xml", Status: models.FeedStatusActive})
+	require.NoError(t, err)
+	assert.Zero(t, feed.SubscriberCount)
+
+	require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 1, FeedID: feed.ID}))
+	require.NoError(t, repo.BatchCreateSubscriptions(ctx, []*models.Subscription{{UserID: 2, FeedID: feed.ID}, {UserID: 3, FeedID: feed.ID}}))
+	stored, err := repo.GetByID(ctx, feed.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, stored.SubscriberCount)
+
+	require.NoError(t, repo.DeleteSubscription(ctx, 2, feed.ID))
+	stored.Title = "Renamed"
+	_, err = repo.Update(ctx, stored)
+	require.NoError(t, err)
+	stored, err = repo.GetByID(ctx, feed.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, stored.SubscriberCount, "saving a feed keeps the count")
+}
+
+func TestFeedRepository_ListPage(t *testing.T) {
+	repo, _ := setupFeedRepo(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	recent := now.Add(-5 * time.Minute)
+	stale := now.Add(-2 * time.Hour)
+
+	var ids []uint
+	for i, feed := range []*models.Feed{
+		{Status: models.FeedStatusActive},
+		{Status: models.FeedStatusActive, LastFetchedAt: &recent},
+		{Status: models.FeedStatusError, LastFetchedAt: &stale},
+		{Status: models.FeedStatusArchived},
+		{Status: models.FeedStatusActive, LastFetchedAt: &stale},
+	} {
+		feed.Title = fmt.Sprintf("Feed %d", i)
+		feed.URL = fmt.Sprintf("https://example.com/%d.xml", i)
+		created, err := repo.Create(ctx, feed)
+		require.NoError(t, err)
+		ids = append(ids, created.ID)
+	}
+
+	feedIDs := func(feeds []*models.Feed) []uint {
+		result := make([]uint, len(feeds))
+		for i, feed := range feeds {
+			result[i] = feed.ID
+		}
+		return result
+	}
+
+	first, err := repo.ListPage(ctx, FeedListFilter{ExcludeArchived: true}, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{ids[0], ids[1]}, feedIDs(first))
+	rest, err := repo.ListPage(ctx, FeedListFilter{ExcludeArchived: true}, ids[1], 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{ids[4]}, feedIDs(rest), "archived feeds and feeds in error are skipped")
+
+	active, err := repo.ListPage(ctx, FeedListFilter{Status: models.FeedStatusActive}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{ids[0], ids[1], ids[4]}, feedIDs(active))
+
+	dueBefore := now.Add(-time.Hour)
+	due, err := repo.ListPage(ctx, FeedListFilter{ExcludeArchived: true, DueBefore: &dueBefore}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{ids[0], ids[4]}, feedIDs(due), "never fetched and stale feeds are due")
+
+	require.NoError(t, repo.MarkFetched(ctx, ids[0], now, ""))
+	due, err = repo.ListPage(ctx, FeedListFilter{ExcludeArchived: true, DueBefore: &dueBefore}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{ids[4]}, feedIDs(due))
+}
+
+func TestFeedRepository_ListPageTiers(t *testing.T) {
+	repo, _ := setupFeedRepo(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	recent := now.Add(-5 * time.Minute)
+	stale := now.Add(-2 * time.Hour)
+	old := now.Add(-48 * time.Hour)
+
+	var ids []uint
+	for i, feed := range []*models.Feed{
+		{Status: models.FeedStatusActive, FetchTier: models.FeedTierHigh, LastFetchedAt: &recent},
+		{Status: models.FeedStatusActive, FetchTier: models.FeedTierNormal, LastFetchedAt: &stale},
+		{Status: models.FeedStatusActive, FetchTier: models.FeedTierLow, LastFetchedAt: &stale},
+		{Status: models.FeedStatusActive, FetchTier: models.FeedTierLow, LastFetchedAt: &old},
+		{Status: models.FeedStatusSuspended, LastFetchedAt: &old},
+		{Status: models.FeedStatusActive, FetchTier: models.FeedTierNormal, LastFetchedAt: &stale},
+	} {
+		feed.Title = fmt.Sprintf("Feed %d", i)
+		feed.URL = fmt.Sprintf("https://example.com/%d.xml", i)
+		created, err := repo.Create(ctx, feed)
+		require.NoError(t, err)
+		ids = append(ids, created.ID)
+	}
+	// the last normal feed has no subscribers
+	require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 1, FeedID: ids[1]}))
+
+	feedIDs := func(feeds []*models.Feed) []uint {
+		result := make([]uint, len(feeds))
+		for i, feed := range feeds {
+			result[i] = feed.ID
+		}
+		return result
+	}
+
+	dueBefore := now.Add(-time.Hour)
+	due, err := repo.ListPage(ctx, FeedListFilter{ExcludeArchived: true, DueBefore: &dueBefore}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{ids[0], ids[1], ids[2], ids[3], ids[5]}, feedIDs(due), "without a low tier cutoff low feeds follow the normal one")
+
+	lowTierDueBefore := now.Add(-24 * time.Hour)
+	due, err = repo.ListPage(ctx, FeedListFilter{ExcludeArchived: true, DueBefore: &dueBefore, LowTierDueBefore: &lowTierDueBefore}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{ids[0], ids[1], ids[3]}, feedIDs(due), "high tier feeds are always due, suspended ones never and unsubscribed ones follow the low tier")
+
+	backlog, err := repo.FetchBacklog(ctx, &dueBefore, &lowTierDueBefore)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), backlog.DueFeeds)
+}
+
+func TestFeedRepository_AdminFilters(t *testing.T) {
+	repo, _ := setupFeedRepo(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	stale := now.Add(-48 * time.Hour)
+	unavailable := "http error: 503 Service Unavailable"
+	notFound := "http error: 404 Not Found"
+
+	var ids []uint
+	for i, feed := range []*models.Feed{
+		{Status: models.FeedStatusError, LastFetchError: &unavailable, LastFetchedAt: &stale},
+		{Status: models.FeedStatusError, LastFetchError: &notFound, LastFetchedAt: &now},
+		{Status: models.FeedStatusActive, FetchTier: models.FeedTierLow},
+	} {
+		feed.Title = fmt.Sprintf("Feed %d", i)
+		feed.URL = fmt.Sprintf("https://example.com/%d.xml", i)
+		created, err := repo.Create(ctx, feed)
+		require.NoError(t, err)
+		ids = append(ids, created.ID)
+	}
+
+	count := func(filter FeedListFilter) int64 {
+		t.Helper()
+		n, err := repo.Count(ctx, filter)
+		require.NoError(t, err)
+		return n
+	}
+	notFetchedSince := now.Add(-24 * time.Hour)
+	assert.Equal(t, int64(1), count(FeedListFilter{ErrorContains: "SERVICE unavailable"}), "error matching ignores case")
+	assert.Equal(t, int64(2), count(FeedListFilter{NotFetchedSince: &notFetchedSince}), "never fetched feeds are stale too")
+	assert.Equal(t, int64(1), count(FeedListFilter{Status: models.FeedStatusError, NotFetchedSince: &notFetchedSince}))
+	assert.Equal(t, int64(1), count(FeedListFilter{Tier: models.FeedTierLow}))
+	assert.Equal(t, int64(2), count(FeedListFilter{IDs: []uint{ids[0], ids[2]}}))
+}
+
+func TestFeedRepository_BulkUpdates(t *testing.T) {
+	repo, db := setupFeedRepo(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	archived, err := repo.Create(ctx, &models.Feed{Title: "Dead", URL: "https://example.com/dead.xml", Status: models.FeedStatusArchived, ArchivedAt: &now, GoneSince: &now})
+	require.NoError(t, err)
+	failing, err := repo.Create(ctx, &models.Feed{Title: "Failing", URL: "https://example.com/failing.xml", Status: models.FeedStatusError})
+	require.NoError(t, err)
+	active, err := repo.Create(ctx, &models.Feed{Title: "Fine", URL: "https://example.com/fine.xml", Status: models.FeedStatusActive})
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 1, FeedID: archived.ID}))
+	require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 1, FeedID: failing.ID}))
+
+	updated, err := repo.SuspendFeeds(ctx, []uint{failing.ID, active.ID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), updated)
+	updated, err = repo.SuspendFeeds(ctx, []uint{failing.ID})
+	require.NoError(t, err)
+	assert.Zero(t, updated, "suspending twice changes nothing")
+
+	updated, err = repo.ReactivateFeeds(ctx, []uint{archived.ID, failing.ID, active.ID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), updated)
+	for _, id := range []uint{archived.ID, failing.ID, active.ID} {
+		feed, err := repo.GetByID(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, models.FeedStatusActive, feed.Status)
+		assert.Nil(t, feed.ArchivedAt)
+		assert.Nil(t, feed.GoneSince)
+	}
+
+	var notifications []models.Notification
+	require.NoError(t, db.Find(&notifications).Error)
+	require.Len(t, notifications, 1, "only subscribers of archived feeds are told")
+	assert.Equal(t, archived.ID, *notifications[0].FeedID)
+	assert.Equal(t, `Feed "Dead" has been reactivated by an administrator.`, notifications[0].Message)
+
+	updated, err = repo.SetFetchTier(ctx, []uint{archived.ID, active.ID}, models.FeedTierHigh)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), updated)
+	n, err := repo.Count(ctx, FeedListFilter{Tier: models.FeedTierNormal})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestFeedRepository_FetchBacklog(t *testing.T) {
+	repo, _ := setupFeedRepo(t)
+	ctx := context.Background()
+
+	backlog, err := repo.FetchBacklog(ctx, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), backlog.DueFeeds)
+	assert.Nil(t, backlog.LastFetchedAt, "nothing fetched yet")
+
+	now := time.Now().UTC().Truncate(time.Second)
+	recent := now.Add(-5 * time.Minute)
+	stale := now.Add(-26 * time.Hour)
+	for i, feed := range []*models.Feed{
+		{Status: models.FeedStatusActive},
+		{Status: models.FeedStatusActive, LastFetchedAt: &stale},
+		{Status: models.FeedStatusError, LastFetchedAt: &recent},
+		{Status: models.FeedStatusArchived, LastFetchedAt: &stale},
+	} {
+		feed.Title = fmt.Sprintf("Feed %d", i)
+		feed.URL = fmt.Sprintf("https://example.com/%d.xml", i)
+		_, err := repo.Create(ctx, feed)
+		require.NoError(t, err)
+	}
+
+	backlog, err = repo.FetchBacklog(ctx, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), backlog.DueFeeds, "archived feeds and feeds in error are not due")
+	require.NotNil(t, backlog.LastFetchedAt)
+	assert.True(t, recent.Equal(*backlog.LastFetchedAt))
+
+	dueBefore := now.Add(-time.Hour)
+	backlog, err = repo.FetchBacklog(ctx, &dueBefore, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), backlog.DueFeeds, "never fetched and stale feeds are due")
+}
+
+func TestFeedRepository_DeleteFeed(t *testing.T) {
+	repo, db := setupFeedRepo(t)
+	require.NoError(t, db.AutoMigrate(&models.Article{}, &models.FeedSnapshot{}))
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	seed := func(url string) *models.Feed {
+		feed, err := repo.Create(ctx, &models.Feed{Title: url, URL: url, Status: models.FeedStatusActive})
+		require.NoError(t, err)
+		require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 2, FeedID: feed.ID}))
+		require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 1, FeedID: feed.ID}))
+		for i := 0; i < 2; i++ {
+			require.NoError(t, db.Create(&models.Article{FeedID: feed.ID, Title: "a", URL: fmt.Sprintf("%s/%d", url, i), PublishedAt: now}).Error)
+		}
+		require.NoError(t, db.Create(&models.FeedSnapshot{FeedID: feed.ID, StatusCode: 200, FetchedAt: now}).Error)
+		return feed
+	}
+
+	archived := seed("https://example.com/archive.xml")
+	deletion, err := repo.DeleteFeed(ctx, archived.ID, models.FeedRetentionArchive, "removed", now)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 2}, deletion.UserIDs)
+	assert.Zero(t, deletion.ArticlesPurged)
+
+	got, err := repo.GetByID(ctx, archived.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.FeedStatusArchived, got.Status)
+	assert.NotNil(t, got.ArchivedAt)
+	var count int64
+	require.NoError(t, db.Model(&models.Article{}).Where("feed_id = ?", archived.ID).Count(&count).Error)
+	assert.Equal(t, int64(2), count, "archived feeds keep their articles")
+	require.NoError(t, db.Model(&models.Subscription{}).Where("feed_id = ?", archived.ID).Count(&count).Error)
+	assert.Zero(t, count)
+
+	purged := seed("https://example.com/purge.xml")
+	deletion, err = repo.DeleteFeed(ctx, purged.ID, models.FeedRetentionPurge, "removed", now)
+	require.NoError(t, err)
+	assert.Len(t, deletion.UserIDs, 2)
+	assert.Equal(t, int64(2), deletion.ArticlesPurged)
+
+	_, err = repo.GetByID(ctx, purged.ID)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	require.NoError(t, db.Unscoped().Model(&models.Article{}).Where("feed_id = ?", purged.ID).Count(&count).Error)
+	assert.Zero(t, count)
+	require.NoError(t, db.Model(&models.FeedSnapshot{}).Where("feed_id = ?", purged.ID).Count(&count).Error)
+	assert.Zero(t, count)
+
+	var notifications []models.Notification
+	require.NoError(t, db.Where("type = ?", models.NotificationFeedRemoved).Find(&notifications).Error)
+	assert.Len(t, notifications, 4, "every subscriber of both feeds is told, even after a purge")
+
+	_, err = repo.DeleteFeed(ctx, purged.ID, models.FeedRetentionPurge, "removed", now)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestFeedRepository_PurgeOrphans(t *testing.T) {
+	repo, db := setupFeedRepo(t)
+	require.NoError(t, db.AutoMigrate(&models.Article{}, &models.FeedSnapshot{}))
+	ctx := context.Background()
+
+	kept, err := repo.Create(ctx, &models.Feed{Title: "Kept", URL: "https://example.com/kept.xml"})
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateSubscription(ctx, &models.Subscription{UserID: 1, FeedID: kept.ID}))
+	require.NoError(t, db.Create(&models.Article{FeedID: kept.ID, Title: "kept", URL: "https://example.com/kept"}).Error)
+
+	// rows left behind by a manual delete with the foreign keys off
+	require.NoError(t, db.Exec("PRAGMA foreign_keys = OFF").Error)
+	defer db.Exec("PRAGMA foreign_keys = ON")
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: 999}).Error)
+	require.NoError(t, db.Create(&models.Article{FeedID: 999, Title: "orphan", URL: "https://example.com/orphan"}).Error)
+	require.NoError(t, db.Create(&models.FeedSnapshot{FeedID: 999, StatusCode: 200}).Error)
+
+	purge, err := repo.PurgeOrphans(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &OrphanPurge{Subscriptions: 1, Articles: 1, Snapshots: 1}, purge)
+
+	var count int64
+	require.NoError(t, db.Model(&models.Article{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestFeedRepository_FailureStreak(t *testing.T) {
+	repo, _ := setupFeedRepo(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	feed, err := repo.Create(ctx, &models.Feed{Title: "Flaky", URL: "https://example.com/flaky.xml", Status: models.FeedStatusActive})
+	require.NoError(t, err)
+
+	for want := 1; want <= 3; want++ {
+		failures, err := repo.RecordFetchError(ctx, feed.ID, "HTTP 503", now)
+		require.NoError(t, err)
+		assert.Equal(t, want, failures)
+	}
+	require.NoError(t, repo.UpdateStatus(ctx, feed.ID, models.FeedStatusError))
+
+	schedulable, err := repo.ListSchedulable(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, schedulable, "feeds in error are not scheduled")
+
+	recovered, err := repo.RecordFetchSuccess(ctx, feed.ID)
+	require.NoError(t, err)
+	assert.True(t, recovered)
+	got, err := repo.GetByID(ctx, feed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.FeedStatusActive, got.Status)
+	assert.Zero(t, got.ConsecutiveFailures)
+	assert.Equal(t, "HTTP 503", *got.LastFetchError, "the last error is kept for the health report")
+
+	_, err = repo.RecordFetchError(ctx, feed.ID, "HTTP 503", now)
+	require.NoError(t, err)
+	recovered, err = repo.RecordFetchSuccess(ctx, feed.ID)
+	require.NoError(t, err)
+	assert.False(t, recovered, "a feed still active did not need to recover")
+	got, err = repo.GetByID(ctx, feed.ID)
+	require.NoError(t, err)
+	assert.Zero(t, got.ConsecutiveFailures)
+}
+
+func TestFeedRepository_ExistingURLs(t *testing.T) {
+	repo, _ := setupFeedRepo(t)
+	ctx := context.Background()
+
+	_, err := repo.Create(ctx, &models.Feed{Title: "Known", URL: "https://example.com/feed.xml", Status: models.FeedStatusActive})
+	require.NoError(t, err)
+
+	urls := make([]string, 0, existingURLsBatch+2)
+	for i := range existingURLsBatch + 1 {
+		urls = append(urls, fmt.Sprintf("https://unknown.example.com/%d.xml", i))
+	}
+	urls = append(urls, "https://example.com/feed.xml")
+
+	existing, err := repo.ExistingURLs(ctx, urls)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/feed.xml"}, existing, "URLs past the first batch are looked up too")
+
+	existing, err = repo.ExistingURLs(ctx, []string{"https://EXAMPLE.com/feed.xml"})
+	require.NoError(t, err)
+	assert.Empty(t, existing, "URLs are compared exactly, as subscribing does")
+}
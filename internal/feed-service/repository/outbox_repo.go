@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/dbtx"
+)
+
+// OutboxRepository stores the events waiting to be published to Kafka
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// WithTx returns a copy of the repository working in the transaction of a
+// dbtx.UnitOfWork; a nil tx keeps the repository's own DB
+func (r *OutboxRepository) WithTx(tx *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{db: dbtx.Bind(r.db, tx)}
+}
+
+// Add stores events to publish
+func (r *OutboxRepository) Add(ctx context.Context, events ...*models.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(events).Error
+}
+
+// Claim locks up to limit unpublished events for the relay named owner until lease has
+// passed, and returns them oldest first. Events another relay holds are skipped, so
+// replicas publish different events.
+func (r *OutboxRepository) Claim(ctx context.Context, owner string, now time.Time, lease time.Duration, limit int) ([]*models.OutboxEvent, error) {
+	db := r.db.WithContext(ctx)
+	unlocked := "published_at IS NULL AND (locked_until IS NULL OR locked_until < ?)"
+
+	var ids []uint64
+	if err := db.Model(&models.OutboxEvent{}).Where(unlocked, now).Order("id").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// a relay that read the same IDs first keeps them
+	if err := db.Model(&models.OutboxEvent{}).Where("id IN ?", ids).Where(unlocked, now).
+		Updates(map[string]any{"locked_by": owner, "locked_until": now.Add(lease)}).Error; err != nil {
+		return nil, err
+	}
+
+	var events []*models.OutboxEvent
+	err := db.Where("id IN ? AND locked_by = ? AND published_at IS NULL", ids, owner).Order("id").Find(&events).Error
+	return events, err
+}
+
+// MarkPublished records that the events were published
+func (r *OutboxRepository) MarkPublished(ctx context.Context, ids []uint64, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&models.OutboxEvent{}).Where("id IN ?", ids).
+		Updates(map[string]any{"published_at": at, "locked_by": nil, "locked_until": nil}).Error
+}
+
+// MarkFailed records a failed publication of the events and releases them, so the next
+// claim picks them up again
+func (r *OutboxRepository) MarkFailed(ctx context.Context, ids []uint64, message string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&models.OutboxEvent{}).Where("id IN ?", ids).
+		Updates(map[string]any{
+			"attempts":     gorm.Expr("attempts + 1"),
+			"last_error":   message,
+			"locked_by":    nil,
+			"locked_until": nil,
+		}).Error
+}
+
+// Release unlocks the unpublished events owner holds
+func (r *OutboxRepository) Release(ctx context.Context, owner string) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxEvent{}).
+		Where("locked_by = ? AND published_at IS NULL", owner).
+		Updates(map[string]any{"locked_by": nil, "locked_until": nil}).Error
+}
+
+// Backlog counts the unpublished events and returns when the oldest of them was stored,
+// nil when there are none
+func (r *OutboxRepository) Backlog(ctx context.Context) (int64, *time.Time, error) {
+	db := r.db.WithContext(ctx)
+	var count int64
+	if err := db.Model(&models.OutboxEvent{}).Where("published_at IS NULL").Count(&count).Error; err != nil {
+		return 0, nil, err
+	}
+	if count == 0 {
+		return 0, nil, nil
+	}
+
+	var oldest models.OutboxEvent
+	if err := db.Select("created_at").Where("published_at IS NULL").Order("id").First(&oldest).Error; err != nil {
+		return 0, nil, err
+	}
+	return count, &oldest.CreatedAt, nil
+}
+
+// DeletePublishedBefore deletes up to limit events published before the time and returns
+// how many it deleted
+func (r *OutboxRepository) DeletePublishedBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	db := r.db.WithContext(ctx)
+	ids := db.Model(&models.OutboxEvent{}).Select("id").Where("published_at < ?", before).Order("id").Limit(limit)
+	result := db.Where("id IN (?)", ids).Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
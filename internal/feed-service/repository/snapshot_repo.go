@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// SnapshotRepository stores the raw feed responses kept for debugging
+type SnapshotRepository struct {
+	db *gorm.DB
+}
+
+func NewSnapshotRepository(db *gorm.DB) *SnapshotRepository {
+	return &SnapshotRepository{db: db}
+}
+
+// Save stores the snapshot and prunes the feed's older snapshots down to the newest keep,
+// in one transaction
+func (r *SnapshotRepository) Save(ctx context.Context, snapshot *models.FeedSnapshot, keep int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(snapshot).Error; err != nil {
+			return err
+		}
+		newest := tx.Model(&models.FeedSnapshot{}).
+			Select("id").
+			Where("feed_id = ?", snapshot.FeedID).
+			Order("fetched_at DESC, id DESC").
+			Limit(keep)
+		return tx.Where("feed_id = ? AND id NOT IN (?)", snapshot.FeedID, newest).
+			Delete(&models.FeedSnapshot{}).Error
+	})
+}
+
+// ListByFeed returns the feed's snapshots, newest first, without their bodies
+func (r *SnapshotRepository) ListByFeed(ctx context.Context, feedID uint) ([]*models.FeedSnapshot, error) {
+	snapshots := make([]*models.FeedSnapshot, 0)
+	err := r.db.WithContext(ctx).
+		Omit("body").
+		Where("feed_id = ?", feedID).
+		Order("fetched_at DESC, id DESC").
+		Find(&snapshots).Error
+	return snapshots, err
+}
+
+// Get returns one of the feed's snapshots, or nil when it does not exist
+func (r *SnapshotRepository) Get(ctx context.Context, feedID, snapshotID uint) (*models.FeedSnapshot, error) {
+	var snapshot models.FeedSnapshot
+	err := r.db.WithContext(ctx).Where("feed_id = ? AND id = ?", feedID, snapshotID).First(&snapshot).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+func TestSnapshotRepository_SavePrunesToKeep(t *testing.T) {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_fk=1", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Feed{}, &models.FeedSnapshot{}))
+	repo := NewSnapshotRepository(db)
+	ctx := context.Background()
+
+	feed := &models.Feed{Title: "Feed", URL: "https://example.com/feed.xml", Status: models.FeedStatusActive}
+	require.NoError(t, db.Create(feed).Error)
+	other := &models.Feed{Title: "Other", URL: "https://example.com/other.xml", Status: models.FeedStatusActive}
+	require.NoError(t, db.Create(other).Error)
+
+	otherSnapshot, err := models.NewFeedSnapshot(other.ID, 200, "application/rss+xml", []byte("<rss/>"), false, nil)
+	require.NoError(t, err)
+	require.NoError(t, repo.Save(ctx, otherSnapshot, 2))
+
+	base := time.Now().UTC()
+	for i := 0; i < 4; i++ {
+		snapshot, err := models.NewFeedSnapshot(feed.ID, 200, "application/rss+xml", []byte(fmt.Sprintf("<rss>%d</rss>", i)), false, nil)
+		require.NoError(t, err)
+		snapshot.FetchedAt = base.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, repo.Save(ctx, snapshot, 2))
+	}
+
+	snapshots, err := repo.ListByFeed(ctx, feed.ID)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Empty(t, snapshots[0].Body, "listing leaves bodies out")
+	assert.True(t, snapshots[0].FetchedAt.After(snapshots[1].FetchedAt))
+
+	newest, err := repo.Get(ctx, feed.ID, snapshots[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, newest)
+	raw, err := newest.Raw()
+	require.NoError(t, err)
+	assert.Equal(t, "<rss>3</rss>", string(raw))
+
+	missing, err := repo.Get(ctx, feed.ID, otherSnapshot.ID)
+	require.NoError(t, err)
+	assert.Nil(t, missing, "snapshots are scoped to their feed")
+
+	kept, err := repo.ListByFeed(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Len(t, kept, 1, "pruning leaves other feeds alone")
+}
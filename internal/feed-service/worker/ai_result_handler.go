@@ -14,6 +14,7 @@ type AIResultHandler struct {
 	logger         *slog.Logger
 	articleService core.ArticleServiceInterface
 	eventConsumer  events.ArticleEventConsumer
+	batch          events.BatchOptions
 }
 
 // NewAIResultHandler creates a new AI result handler instance
@@ -26,15 +27,26 @@ func NewAIResultHandler(
 		logger:         logger,
 		articleService: articleService,
 		eventConsumer:  eventConsumer,
+		batch:          events.BatchOptions{MaxSize: 1},
 	}
 }
 
+// SetBatching applies up to opts.MaxSize results in one transaction, waiting at most
+// opts.MaxWait for a batch to fill. This cuts write amplification when the AI service
+// works through a backlog.
+func (h *AIResultHandler) SetBatching(opts events.BatchOptions) {
+	h.batch = opts
+}
+
 // Start begins processing AI results
 func (h *AIResultHandler) Start(ctx context.Context) error {
 	h.logger.Info("starting AI result handler for feed service")
 
 	// start consuming ArticleProcessedEvent messages
-	return h.eventConsumer.StartProcessedEventConsumer(ctx, h.handleArticleProcessed)
+	if h.batch.MaxSize > 1 {
+		return h.eventConsumer.StartProcessedEventBatchConsumer(ctx, h.batch, h.HandleArticlesProcessed)
+	}
+	return h.eventConsumer.StartProcessedEventConsumer(ctx, h.HandleArticleProcessed)
 }
 
 // Stop gracefully stops the AI result handler
@@ -43,8 +55,8 @@ func (h *AIResultHandler) Stop(ctx context.Context) error {
 	return h.eventConsumer.Stop(ctx)
 }
 
-// handleArticleProcessed handles an ArticleProcessedEvent
-func (h *AIResultHandler) handleArticleProcessed(ctx context.Context, event *article_eventspb.ArticleProcessedEvent) error {
+// HandleArticleProcessed handles an ArticleProcessedEvent
+func (h *AIResultHandler) HandleArticleProcessed(ctx context.Context, event *article_eventspb.ArticleProcessedEvent) error {
 	h.logger.Debug("received AI processed article event",
 		"article_id", event.ArticleId,
 		"summary_length", len(event.Summary),
@@ -65,3 +77,19 @@ func (h *AIResultHandler) handleArticleProcessed(ctx context.Context, event *art
 
 	return nil
 }
+
+// HandleArticlesProcessed handles a batch of ArticleProcessedEvents
+func (h *AIResultHandler) HandleArticlesProcessed(ctx context.Context, batch []*article_eventspb.ArticleProcessedEvent) error {
+	h.logger.Debug("received AI processed article event batch", "size", len(batch))
+
+	if err := h.articleService.HandleArticlesProcessed(ctx, batch); err != nil {
+		h.logger.Error("failed to handle AI processed article event batch",
+			"size", len(batch),
+			"error", err,
+		)
+		return err
+	}
+
+	h.logger.Info("successfully handled AI processed article event batch", "size", len(batch))
+	return nil
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/archive"
+)
+
+// ArticleExportWorker runs the queued feed archive exports, one at a time
+type ArticleExportWorker struct {
+	logger   *slog.Logger
+	exporter *archive.Exporter
+	interval time.Duration
+}
+
+func NewArticleExportWorker(logger *slog.Logger, exporter *archive.Exporter, interval time.Duration) *ArticleExportWorker {
+	return &ArticleExportWorker{
+		logger:   logger,
+		exporter: exporter,
+		interval: interval,
+	}
+}
+
+// Start runs the exports queued every interval until the context is cancelled
+func (w *ArticleExportWorker) Start(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			ran, err := w.exporter.RunOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				w.logger.Error("article export run failed", "error", err)
+			}
+			if !ran || err != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
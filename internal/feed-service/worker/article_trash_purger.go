@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
+)
+
+// ArticleTrashPurger periodically removes articles that have been in the trash for
+// longer than the grace period. Until then they can be restored.
+type ArticleTrashPurger struct {
+	logger      *slog.Logger
+	articleRepo *repository.ArticleRepository
+	grace       time.Duration
+	interval    time.Duration
+}
+
+func NewArticleTrashPurger(logger *slog.Logger, articleRepo *repository.ArticleRepository, grace, interval time.Duration) *ArticleTrashPurger {
+	return &ArticleTrashPurger{
+		logger:      logger,
+		articleRepo: articleRepo,
+		grace:       grace,
+		interval:    interval,
+	}
+}
+
+// Start runs the purger until the context is cancelled
+func (p *ArticleTrashPurger) Start(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.RunOnce(ctx); err != nil {
+			p.logger.Error("article trash purge failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce permanently deletes expired trash and returns how many articles were removed
+func (p *ArticleTrashPurger) RunOnce(ctx context.Context) (int64, error) {
+	purged, err := p.articleRepo.PurgeDeletedBefore(ctx, time.Now().UTC().Add(-p.grace))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted articles: %w", err)
+	}
+	if purged > 0 {
+		p.logger.Info("purged articles from trash", "count", purged, "grace_period", p.grace)
+	}
+	return purged, nil
+}
@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/repository"
+)
+
+// DeadFeedDetector periodically archives feeds whose source has returned 404/410
+// for longer than the configured threshold, so they stop consuming fetch budget.
+type DeadFeedDetector struct {
+	logger    *slog.Logger
+	feedRepo  *repository.FeedRepository
+	threshold time.Duration
+	interval  time.Duration
+}
+
+func NewDeadFeedDetector(logger *slog.Logger, feedRepo *repository.FeedRepository, threshold, interval time.Duration) *DeadFeedDetector {
+	return &DeadFeedDetector{
+		logger:    logger,
+		feedRepo:  feedRepo,
+		threshold: threshold,
+		interval:  interval,
+	}
+}
+
+// Start runs the detector until the context is cancelled
+func (d *DeadFeedDetector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.RunOnce(ctx); err != nil {
+			d.logger.Error("dead feed detection failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce archives every feed gone for longer than the threshold and returns how many were archived
+func (d *DeadFeedDetector) RunOnce(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	feeds, err := d.feedRepo.ListGoneSince(ctx, now.Add(-d.threshold))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list gone feeds: %w", err)
+	}
+
+	archived := 0
+	for _, feed := range feeds {
+		message := fmt.Sprintf("Feed %q has been unreachable (HTTP 404/410) since %s and was archived. It will no longer be refreshed automatically; refresh it manually to unarchive it if it comes back.",
+			feed.Title, feed.GoneSince.Format("2006-01-02"))
+		if err := d.feedRepo.ArchiveFeed(ctx, feed.ID, now, message); err != nil {
+			d.logger.Error("failed to archive dead feed", "feed_id", feed.ID, "error", err)
+			continue
+		}
+		archived++
+		d.logger.Info("archived dead feed", "feed_id", feed.ID, "url", feed.URL, "gone_since", feed.GoneSince)
+	}
+
+	if archived > 0 {
+		d.logger.Info("dead feed detection completed", "archived", archived, "candidates", len(feeds))
+	}
+	return archived, nil
+}
@@ -2,9 +2,12 @@ package worker
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"time"
 
-	"github.com/mmcdole/gofeed"
+	"gorm.io/gorm"
 
 	"github.com/Fancu1/phoenix-rss/internal/events"
 	"github.com/Fancu1/phoenix-rss/internal/feed-service/core"
@@ -18,44 +21,118 @@ type FeedFetcher struct {
 	logger         *slog.Logger
 	articleService *core.ArticleService
 	feedRepo       *repository.FeedRepository
-	parser         *gofeed.Parser
+	alerter        *core.OperatorAlerter
+	budget         *core.CrawlBudget
+	// failureThreshold is the streak of failed fetches at which a feed is set to error
+	failureThreshold int
 }
 
 func NewFeedFetcher(logger *slog.Logger, articleService *core.ArticleService, feedRepo *repository.FeedRepository) *FeedFetcher {
 	return &FeedFetcher{
-		logger:         logger,
-		articleService: articleService,
-		feedRepo:       feedRepo,
-		parser:         gofeed.NewParser(),
+		logger:           logger,
+		articleService:   articleService,
+		feedRepo:         feedRepo,
+		failureThreshold: core.DefaultFeedFailureThreshold,
 	}
 }
 
-// HandleFeedFetch fetches articles and updates feed metadata if needed.
+// SetAlerter reports fetch results to the operators' alerting
+func (f *FeedFetcher) SetAlerter(alerter *core.OperatorAlerter) {
+	f.alerter = alerter
+}
+
+// SetCrawlBudget charges feed fetches to the feed's crawl budget
+func (f *FeedFetcher) SetCrawlBudget(budget *core.CrawlBudget) {
+	f.budget = budget
+}
+
+// SetFailureThreshold sets how many fetches in a row must fail before a feed is set to
+// error, which takes it off the schedule
+func (f *FeedFetcher) SetFailureThreshold(threshold int) {
+	f.failureThreshold = threshold
+}
+
+// HandleFeedFetch fetches articles, which also refreshes the feed's metadata, and
+// records how the fetch went
 func (f *FeedFetcher) HandleFeedFetch(ctx context.Context, evt events.FeedFetchEvent) error {
 	taskCtx := logger.WithValue(ctx, "feed_id", evt.FeedID)
 	log := logger.FromContext(taskCtx)
 	log.Info("starting feed fetch", "feed_id", evt.FeedID)
 
 	feed, err := f.feedRepo.GetByID(ctx, evt.FeedID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// purged by an administrator after the fetch was scheduled
+		log.Info("feed no longer exists, dropping fetch", "feed_id", evt.FeedID)
+		return nil
+	}
 	if err != nil {
 		log.Error("failed to get feed", "feed_id", evt.FeedID, "error", err.Error())
 		return err
 	}
 
-	needsMetadataUpdate := feed.Title == feed.URL // title == URL means first fetch
+	// queued before the feed was suspended, or by a subscriber's manual fetch
+	if feed.Status == models.FeedStatusSuspended {
+		log.Info("feed is suspended, dropping fetch", "feed_id", evt.FeedID)
+		return nil
+	}
+
+	// A successful fetch unarchives a feed, which must not bring back one an
+	// administrator deleted with the archive policy
+	if feed.Status == models.FeedStatusArchived && feed.SubscriberCount == 0 {
+		log.Info("archived feed has no subscribers, dropping fetch", "feed_id", evt.FeedID)
+		return nil
+	}
+
+	// a feed out of budget is fetched again once the day is over; the skip is not a failure
+	if !f.budget.Spend(taskCtx, evt.FeedID, core.CrawlFeedFetch) {
+		return nil
+	}
 
 	articles, err := f.articleService.FetchAndSaveArticles(taskCtx, evt.FeedID)
+	requestID, _ := logger.GetRequestID(ctx)
+	if markErr := f.feedRepo.MarkFetched(ctx, evt.FeedID, time.Now().UTC(), requestID); markErr != nil {
+		log.Error("failed to record fetch time", "feed_id", evt.FeedID, "error", markErr.Error())
+	}
+	if f.alerter != nil {
+		f.alerter.RecordFetch(ctx, err != nil)
+	}
 	if err != nil {
 		log.Error("failed to fetch and save articles for feed", "feed_id", evt.FeedID, "error", err.Error())
-		if updateErr := f.feedRepo.UpdateStatus(ctx, evt.FeedID, models.FeedStatusError); updateErr != nil {
-			log.Error("failed to update feed status to error", "feed_id", evt.FeedID, "error", updateErr.Error())
+		failures, recordErr := f.feedRepo.RecordFetchError(ctx, evt.FeedID, core.FetchErrorSummary(err), time.Now().UTC())
+		if recordErr != nil {
+			log.Error("failed to record fetch error", "feed_id", evt.FeedID, "error", recordErr.Error())
+		}
+		if core.IsFeedGoneError(err) {
+			if markErr := f.feedRepo.MarkGone(ctx, evt.FeedID, time.Now().UTC()); markErr != nil {
+				log.Error("failed to mark feed as gone", "feed_id", evt.FeedID, "error", markErr.Error())
+			}
+		}
+		// a feed is only taken off the schedule once it kept failing; archived feeds keep
+		// their status until a fetch succeeds again
+		if feed.Status != models.FeedStatusArchived && failures >= f.failureThreshold {
+			if updateErr := f.feedRepo.UpdateStatus(ctx, evt.FeedID, models.FeedStatusError); updateErr != nil {
+				log.Error("failed to update feed status to error", "feed_id", evt.FeedID, "error", updateErr.Error())
+			} else if feed.Status != models.FeedStatusError {
+				log.Warn("feed keeps failing, no longer scheduled", "feed_id", evt.FeedID, "consecutive_failures", failures)
+				f.alertFeedFailed(ctx, feed, err)
+			}
 		}
 		return err
 	}
 
-	if needsMetadataUpdate {
-		if err := f.updateFeedMetadata(ctx, feed); err != nil {
-			log.Error("failed to update feed metadata", "feed_id", evt.FeedID, "error", err.Error())
+	recovered, err := f.feedRepo.RecordFetchSuccess(ctx, evt.FeedID)
+	if err != nil {
+		log.Error("failed to reset failure streak", "feed_id", evt.FeedID, "error", err.Error())
+	} else if recovered {
+		log.Info("feed in error fetched successfully, scheduled again", "feed_id", evt.FeedID)
+	}
+
+	if feed.GoneSince != nil || feed.ArchivedAt != nil {
+		restored, err := f.feedRepo.RestoreFeed(ctx, evt.FeedID, fmt.Sprintf("Feed %q is reachable again and has been unarchived.", feed.Title))
+		if err != nil {
+			log.Error("failed to restore feed", "feed_id", evt.FeedID, "error", err.Error())
+		} else if restored {
+			log.Info("archived feed is reachable again, unarchived", "feed_id", evt.FeedID)
 		}
 	}
 
@@ -63,27 +140,11 @@ func (f *FeedFetcher) HandleFeedFetch(ctx context.Context, evt events.FeedFetchE
 	return nil
 }
 
-func (f *FeedFetcher) updateFeedMetadata(ctx context.Context, feed *models.Feed) error {
-	log := logger.FromContext(ctx)
-	log.Info("updating feed metadata", "feed_id", feed.ID, "url", feed.URL)
-
-	parsedFeed, err := f.parser.ParseURLWithContext(feed.URL, ctx)
-	if err != nil {
-		log.Error("failed to parse feed for metadata update", "feed_id", feed.ID, "error", err.Error())
-		return nil // articles already saved, skip metadata
-	}
-
-	title := parsedFeed.Title
-	if title == "" {
-		title = feed.URL
+// alertFeedFailed tells the operators about a feed that just turned to error status, if
+// it has enough subscribers to matter
+func (f *FeedFetcher) alertFeedFailed(ctx context.Context, feed *models.Feed, fetchErr error) {
+	if f.alerter == nil {
+		return
 	}
-
-	err = f.feedRepo.UpdateFeedMetadata(ctx, feed.ID, title, parsedFeed.Description, models.FeedStatusActive)
-	if err != nil {
-		log.Error("failed to save feed metadata", "feed_id", feed.ID, "error", err.Error())
-		return err
-	}
-
-	log.Info("successfully updated feed metadata", "feed_id", feed.ID, "title", title)
-	return nil
+	f.alerter.FeedFailed(ctx, feed, feed.SubscriberCount, core.FetchErrorSummary(fetchErr))
 }
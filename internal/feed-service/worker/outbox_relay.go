@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/core"
+)
+
+// outboxMaxBackoff caps the wait between runs while Kafka keeps refusing events
+const outboxMaxBackoff = time.Minute
+
+// OutboxRelayWorker publishes the events of the outbox every interval, and right away
+// again after a run that published some. While Kafka is down it waits twice as long after
+// every failed run, up to a minute, and warns how many events are waiting. Events
+// published longer ago than the retention are deleted once an hour.
+type OutboxRelayWorker struct {
+	logger    *slog.Logger
+	relay     *core.OutboxRelay
+	interval  time.Duration
+	retention time.Duration
+}
+
+func NewOutboxRelayWorker(logger *slog.Logger, relay *core.OutboxRelay, interval, retention time.Duration) *OutboxRelayWorker {
+	return &OutboxRelayWorker{
+		logger:    logger,
+		relay:     relay,
+		interval:  interval,
+		retention: retention,
+	}
+}
+
+// Start runs the relay until the context is cancelled
+func (w *OutboxRelayWorker) Start(ctx context.Context) error {
+	wait := w.interval
+	var lastPurge time.Time
+	for {
+		published, err := w.relay.RunOnce(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			wait = min(max(wait*2, w.interval), outboxMaxBackoff)
+			w.warnBacklog(ctx, err, wait)
+		case err == nil:
+			if wait > w.interval {
+				w.logger.Info("outbox relay recovered", "published", published)
+			}
+			wait = w.interval
+			if published > 0 {
+				// there may be more waiting
+				wait = 0
+			}
+		}
+
+		if time.Since(lastPurge) >= time.Hour {
+			lastPurge = time.Now()
+			if deleted, err := w.relay.Purge(ctx, time.Now().Add(-w.retention)); err != nil {
+				w.logger.Error("failed to purge published outbox events", "error", err)
+			} else if deleted > 0 {
+				w.logger.Info("purged published outbox events", "deleted", deleted)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (w *OutboxRelayWorker) warnBacklog(ctx context.Context, err error, retryIn time.Duration) {
+	pending, oldest, backlogErr := w.relay.Backlog(ctx)
+	if backlogErr != nil || oldest == nil {
+		w.logger.Warn("outbox relay failed, events wait in the outbox", "error", err, "retry_in", retryIn.String())
+		return
+	}
+	w.logger.Warn("outbox relay failed, events wait in the outbox", "error", err, "retry_in", retryIn.String(),
+		"pending", pending, "oldest_age", time.Since(*oldest).Round(time.Second).String())
+}
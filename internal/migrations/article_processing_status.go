@@ -0,0 +1,27 @@
+package migrations
+
+import "context"
+
+// articleProcessingStatus adds articles.processing_status and processing_error so permanent
+// AI failures are visible. The constant default keeps ADD COLUMN a catalog-only change;
+// articles that already have a summary are backfilled to succeeded in batches.
+var articleProcessingStatus = Migration{
+	ID:          "0002_article_processing_status",
+	Description: "add articles.processing_status and processing_error",
+	Up: func(ctx context.Context, r *Runner) error {
+		if err := r.AddColumn(ctx, "articles", "processing_status", "TEXT NOT NULL DEFAULT 'pending'"); err != nil {
+			return err
+		}
+		if err := r.AddColumn(ctx, "articles", "processing_error", "TEXT"); err != nil {
+			return err
+		}
+		if _, err := r.Backfill(ctx, BackfillSpec{
+			Table: "articles",
+			Set:   "processing_status = 'succeeded'",
+			Where: "processed_at IS NOT NULL AND processing_status = 'pending'",
+		}); err != nil {
+			return err
+		}
+		return r.CreateIndex(ctx, "idx_articles_processing_status", "articles", "processing_status", false)
+	},
+}
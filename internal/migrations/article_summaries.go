@@ -0,0 +1,25 @@
+package migrations
+
+import "context"
+
+// articleSummaries copies the single summary of each article into article_summaries as
+// its first variant. Every summary made before variants existed was written in Chinese,
+// so they are all recorded as zh, whichever language is configured as the default now.
+var articleSummaries = Migration{
+	ID:          "0004_article_summaries",
+	Description: "copy article summaries into article_summaries as zh variants",
+	Up: func(ctx context.Context, r *Runner) error {
+		_, err := r.CopyRows(ctx, CopySpec{
+			Source:  "articles",
+			Target:  "article_summaries",
+			Columns: []string{"article_id", "model", "language", "summary", "truncated", "prompt", "request_id", "created_at", "updated_at"},
+			Select: []string{
+				"articles.id", "COALESCE(articles.processing_model, '')", "'zh'", "articles.summary",
+				"articles.summary_truncated", "articles.processing_prompt", "articles.processing_request_id",
+				"COALESCE(articles.processed_at, articles.updated_at)", "COALESCE(articles.processed_at, articles.updated_at)",
+			},
+			Where: "articles.summary IS NOT NULL AND articles.summary <> ''",
+		})
+		return err
+	},
+}
@@ -0,0 +1,191 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBatchSize = 1000
+	// progressLogEvery controls how often progress is logged when no callback is set
+	progressLogEvery = 10
+)
+
+// Progress reports how far a batched backfill has come
+type Progress struct {
+	Done    int64
+	Total   int64
+	LastKey int64
+}
+
+// BackfillSpec updates existing rows in place, one key range at a time
+type BackfillSpec struct {
+	Table string
+	// Key is an integer, indexed column used to walk the table (default "id")
+	Key string
+	// Set is the SET clause, e.g. "read_at = updated_at"
+	Set string
+	// Where selects rows that still need the backfill. It is required so an
+	// interrupted backfill can simply be run again.
+	Where     string
+	BatchSize int
+	// Pause between batches gives replicas and autovacuum room to keep up
+	Pause    time.Duration
+	Progress func(Progress)
+}
+
+// CopySpec copies rows from a legacy table into its replacement, one key range at a time.
+// Rows already present in the target are left alone (ON CONFLICT DO NOTHING).
+type CopySpec struct {
+	Source string
+	Target string
+	// Key is an integer, indexed column of the source table (default "id")
+	Key string
+	// Columns of the target and the matching Select expressions over the source
+	Columns []string
+	Select  []string
+	// Join optionally joins more tables to the source, so one source row can become
+	// several target rows, e.g. "JOIN subscriptions ON subscriptions.feed_id = articles.feed_id"
+	Join string
+	// Where optionally restricts the source rows. It also bounds the key range over the
+	// source alone, so it may only refer to the source's columns.
+	Where     string
+	BatchSize int
+	Pause     time.Duration
+	Progress  func(Progress)
+}
+
+// Backfill runs the update in batches of BatchSize keys so no statement holds row
+// locks for long. It returns the number of rows updated.
+func (r *Runner) Backfill(ctx context.Context, spec BackfillSpec) (int64, error) {
+	spec.Key = orDefault(spec.Key, "id")
+	if err := checkIdentifiers(spec.Table, spec.Key); err != nil {
+		return 0, err
+	}
+	if strings.TrimSpace(spec.Set) == "" || strings.TrimSpace(spec.Where) == "" {
+		return 0, fmt.Errorf("backfill %s: Set and Where are required", spec.Table)
+	}
+
+	stmt := fmt.Sprintf("UPDATE %s SET %s WHERE %s >= ? AND %s < ? AND (%s)",
+		spec.Table, spec.Set, spec.Key, spec.Key, spec.Where)
+
+	return r.walk(ctx, walkSpec{
+		name:      "backfill " + spec.Table,
+		table:     spec.Table,
+		key:       spec.Key,
+		where:     spec.Where,
+		batchSize: spec.BatchSize,
+		pause:     spec.Pause,
+		progress:  spec.Progress,
+		statement: stmt,
+	})
+}
+
+// CopyRows copies source rows into the target in batches. It returns the number of
+// rows inserted.
+func (r *Runner) CopyRows(ctx context.Context, spec CopySpec) (int64, error) {
+	spec.Key = orDefault(spec.Key, "id")
+	if err := checkIdentifiers(append([]string{spec.Source, spec.Target, spec.Key}, spec.Columns...)...); err != nil {
+		return 0, err
+	}
+	if len(spec.Columns) == 0 || len(spec.Columns) != len(spec.Select) {
+		return 0, fmt.Errorf("copy %s to %s: Columns and Select must have the same, non-zero length", spec.Source, spec.Target)
+	}
+
+	where := orDefault(strings.TrimSpace(spec.Where), "1 = 1")
+	stmt := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s %s WHERE %s.%s >= ? AND %s.%s < ? AND (%s) ON CONFLICT DO NOTHING",
+		spec.Target, strings.Join(spec.Columns, ", "), strings.Join(spec.Select, ", "),
+		spec.Source, spec.Join, spec.Source, spec.Key, spec.Source, spec.Key, where)
+
+	return r.walk(ctx, walkSpec{
+		name:      "copy " + spec.Source + " to " + spec.Target,
+		table:     spec.Source,
+		key:       spec.Key,
+		where:     where,
+		batchSize: spec.BatchSize,
+		pause:     spec.Pause,
+		progress:  spec.Progress,
+		statement: stmt,
+	})
+}
+
+type walkSpec struct {
+	name      string
+	table     string
+	key       string
+	where     string
+	batchSize int
+	pause     time.Duration
+	progress  func(Progress)
+	// statement takes the key range [lo, hi) as its two arguments
+	statement string
+}
+
+func (r *Runner) walk(ctx context.Context, spec walkSpec) (int64, error) {
+	batchSize := int64(spec.batchSize)
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	var bounds struct {
+		MinKey sql.NullInt64
+		MaxKey sql.NullInt64
+		Total  int64
+	}
+	query := fmt.Sprintf("SELECT MIN(%s) AS min_key, MAX(%s) AS max_key, COUNT(*) AS total FROM %s WHERE %s",
+		spec.key, spec.key, spec.table, spec.where)
+	if err := r.db.WithContext(ctx).Raw(query).Scan(&bounds).Error; err != nil {
+		return 0, fmt.Errorf("%s: read key range: %w", spec.name, err)
+	}
+	if !bounds.MinKey.Valid {
+		r.logger.Info("nothing to do", "step", spec.name)
+		return 0, nil
+	}
+
+	r.logger.Info("starting batched migration", "step", spec.name, "rows", bounds.Total, "batch_size", batchSize)
+	start := time.Now()
+
+	var done int64
+	batch := 0
+	for lo := bounds.MinKey.Int64; lo <= bounds.MaxKey.Int64; lo += batchSize {
+		if err := ctx.Err(); err != nil {
+			return done, err
+		}
+
+		hi := lo + batchSize
+		result := r.db.WithContext(ctx).Exec(spec.statement, lo, hi)
+		if result.Error != nil {
+			return done, fmt.Errorf("%s: batch [%d, %d): %w", spec.name, lo, hi, result.Error)
+		}
+		done += result.RowsAffected
+
+		batch++
+		progress := Progress{Done: done, Total: bounds.Total, LastKey: hi - 1}
+		if spec.progress != nil {
+			spec.progress(progress)
+		} else if batch%progressLogEvery == 0 {
+			r.logger.Info("batched migration progress", "step", spec.name, "done", progress.Done, "total", progress.Total, "last_key", progress.LastKey)
+		}
+
+		if spec.pause > 0 {
+			select {
+			case <-time.After(spec.pause):
+			case <-ctx.Done():
+				return done, ctx.Err()
+			}
+		}
+	}
+
+	r.logger.Info("batched migration finished", "step", spec.name, "rows", done, "duration", time.Since(start))
+	return done, nil
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,152 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DualWriteSpec mirrors inserts and updates on a legacy table into its replacement
+// with a database trigger. While old and new service versions run side by side,
+// writes through the old code path keep the new table current; once every service
+// writes the new table directly the shim is removed with RemoveDualWrite.
+type DualWriteSpec struct {
+	// Name of the trigger (and, on PostgreSQL, of its function)
+	Name   string
+	Source string
+	Target string
+	// Columns of the target and the matching Values expressions over NEW
+	Columns []string
+	Values  []string
+	// ConflictKey is the target's unique key; other columns are updated on conflict
+	ConflictKey []string
+	// When optionally restricts the mirrored rows, e.g. "NEW.read_at IS NOT NULL"
+	When string
+}
+
+func (s DualWriteSpec) validate() error {
+	if err := checkIdentifiers(append([]string{s.Name, s.Source, s.Target}, append(s.Columns, s.ConflictKey...)...)...); err != nil {
+		return err
+	}
+	if len(s.Columns) == 0 || len(s.Columns) != len(s.Values) {
+		return fmt.Errorf("dual write %s: Columns and Values must have the same, non-zero length", s.Name)
+	}
+	if len(s.ConflictKey) == 0 {
+		return fmt.Errorf("dual write %s: ConflictKey is required", s.Name)
+	}
+	return nil
+}
+
+// upsert renders the mirrored INSERT ... ON CONFLICT statement
+func (s DualWriteSpec) upsert() string {
+	conflict := make(map[string]bool, len(s.ConflictKey))
+	for _, column := range s.ConflictKey {
+		conflict[column] = true
+	}
+
+	var updates []string
+	for _, column := range s.Columns {
+		if !conflict[column] {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", column, column))
+		}
+	}
+
+	action := "DO NOTHING"
+	if len(updates) > 0 {
+		action = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) %s",
+		s.Target, strings.Join(s.Columns, ", "), strings.Join(s.Values, ", "),
+		strings.Join(s.ConflictKey, ", "), action)
+}
+
+func (s DualWriteSpec) installStatements(dialect string) ([]string, error) {
+	switch dialect {
+	case "postgres":
+		when := ""
+		if s.When != "" {
+			when = fmt.Sprintf(" WHEN (%s)", s.When)
+		}
+		return []string{
+			fmt.Sprintf(`CREATE OR REPLACE FUNCTION %s_fn() RETURNS trigger AS $$
+BEGIN
+    %s;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql`, s.Name, s.upsert()),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", s.Name, s.Source),
+			fmt.Sprintf("CREATE TRIGGER %s AFTER INSERT OR UPDATE ON %s FOR EACH ROW%s EXECUTE FUNCTION %s_fn()",
+				s.Name, s.Source, when, s.Name),
+		}, nil
+	case "sqlite":
+		// SQLite has no INSERT OR UPDATE triggers; install one per event
+		when := ""
+		if s.When != "" {
+			when = fmt.Sprintf(" WHEN %s", s.When)
+		}
+		var statements []string
+		for _, event := range []string{"insert", "update"} {
+			name := fmt.Sprintf("%s_%s", s.Name, event)
+			statements = append(statements,
+				fmt.Sprintf("DROP TRIGGER IF EXISTS %s", name),
+				fmt.Sprintf("CREATE TRIGGER %s AFTER %s ON %s FOR EACH ROW%s BEGIN %s; END",
+					name, strings.ToUpper(event), s.Source, when, s.upsert()),
+			)
+		}
+		return statements, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDialect, dialect)
+	}
+}
+
+func (s DualWriteSpec) removeStatements(dialect string) ([]string, error) {
+	switch dialect {
+	case "postgres":
+		return []string{
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", s.Name, s.Source),
+			fmt.Sprintf("DROP FUNCTION IF EXISTS %s_fn()", s.Name),
+		}, nil
+	case "sqlite":
+		return []string{
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s_insert", s.Name),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s_update", s.Name),
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDialect, dialect)
+	}
+}
+
+// InstallDualWrite creates (or replaces) the mirroring trigger
+func (r *Runner) InstallDualWrite(ctx context.Context, spec DualWriteSpec) error {
+	if err := spec.validate(); err != nil {
+		return err
+	}
+	statements, err := spec.installStatements(r.db.Dialector.Name())
+	if err != nil {
+		return err
+	}
+	if err := r.execWithLockTimeout(ctx, statements...); err != nil {
+		return fmt.Errorf("install dual write %s: %w", spec.Name, err)
+	}
+
+	r.logger.Info("dual write installed", "name", spec.Name, "source", spec.Source, "target", spec.Target)
+	return nil
+}
+
+// RemoveDualWrite drops the mirroring trigger once it is no longer needed
+func (r *Runner) RemoveDualWrite(ctx context.Context, spec DualWriteSpec) error {
+	if err := checkIdentifiers(spec.Name, spec.Source); err != nil {
+		return err
+	}
+	statements, err := spec.removeStatements(r.db.Dialector.Name())
+	if err != nil {
+		return err
+	}
+	if err := r.execWithLockTimeout(ctx, statements...); err != nil {
+		return fmt.Errorf("remove dual write %s: %w", spec.Name, err)
+	}
+
+	r.logger.Info("dual write removed", "name", spec.Name, "source", spec.Source)
+	return nil
+}
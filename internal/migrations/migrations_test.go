@@ -0,0 +1,237 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupRunner(t *testing.T) (*Runner, *gorm.DB) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL, read INTEGER NOT NULL DEFAULT 0)`).Error)
+	return NewRunner(db, slog.New(slog.NewTextHandler(io.Discard, nil))), db
+}
+
+func seedItems(t *testing.T, db *gorm.DB, n int) {
+	t.Helper()
+	for i := 1; i <= n; i++ {
+		require.NoError(t, db.Exec(`INSERT INTO items (id, name, read) VALUES (?, ?, ?)`, i, fmt.Sprintf("item-%d", i), i%2).Error)
+	}
+}
+
+func TestCheckColumnDefinition(t *testing.T) {
+	assert.NoError(t, checkColumnDefinition("TIMESTAMPTZ"))
+	assert.NoError(t, checkColumnDefinition("BOOLEAN NOT NULL DEFAULT false"))
+	assert.NoError(t, checkColumnDefinition("TIMESTAMPTZ NOT NULL DEFAULT NOW()"))
+
+	for _, definition := range []string{
+		"TEXT NOT NULL",
+		"UUID DEFAULT gen_random_uuid()",
+		"TIMESTAMPTZ DEFAULT clock_timestamp()",
+		"TEXT UNIQUE",
+	} {
+		err := checkColumnDefinition(definition)
+		assert.True(t, errors.Is(err, ErrUnsafeChange), definition)
+	}
+}
+
+func TestRunner_AddColumnIsIdempotent(t *testing.T) {
+	r, db := setupRunner(t)
+	ctx := context.Background()
+
+	require.NoError(t, r.AddColumn(ctx, "items", "archived_at", "TIMESTAMP"))
+	require.NoError(t, r.AddColumn(ctx, "items", "archived_at", "TIMESTAMP"))
+	assert.True(t, db.Migrator().HasColumn("items", "archived_at"))
+
+	err := r.AddColumn(ctx, "items", "slug", "TEXT NOT NULL")
+	assert.True(t, errors.Is(err, ErrUnsafeChange))
+	assert.Error(t, r.AddColumn(ctx, "items; DROP TABLE items", "x", "TEXT"))
+}
+
+func TestRunner_CreateIndex(t *testing.T) {
+	r, db := setupRunner(t)
+	ctx := context.Background()
+
+	require.NoError(t, r.CreateIndex(ctx, "idx_items_name", "items", "name", false))
+	require.NoError(t, r.CreateIndex(ctx, "idx_items_name", "items", "name", false))
+	assert.True(t, db.Migrator().HasIndex("items", "idx_items_name"))
+}
+
+func TestRunner_BackfillInBatches(t *testing.T) {
+	r, db := setupRunner(t)
+	ctx := context.Background()
+	seedItems(t, db, 25)
+	require.NoError(t, r.AddColumn(ctx, "items", "title", "TEXT"))
+
+	var reports []Progress
+	updated, err := r.Backfill(ctx, BackfillSpec{
+		Table:     "items",
+		Set:       "title = UPPER(name)",
+		Where:     "title IS NULL",
+		BatchSize: 10,
+		Progress:  func(p Progress) { reports = append(reports, p) },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(25), updated)
+	require.Len(t, reports, 3)
+	assert.Equal(t, int64(25), reports[2].Done)
+	assert.Equal(t, int64(25), reports[2].Total)
+
+	var title string
+	require.NoError(t, db.Raw(`SELECT title FROM items WHERE id = 7`).Scan(&title).Error)
+	assert.Equal(t, "ITEM-7", title)
+
+	// nothing left: a rerun is a no-op
+	updated, err = r.Backfill(ctx, BackfillSpec{Table: "items", Set: "title = UPPER(name)", Where: "title IS NULL"})
+	require.NoError(t, err)
+	assert.Zero(t, updated)
+
+	_, err = r.Backfill(ctx, BackfillSpec{Table: "items", Set: "title = name"})
+	assert.Error(t, err, "Where is required")
+}
+
+func TestRunner_CopyRowsResumes(t *testing.T) {
+	r, db := setupRunner(t)
+	ctx := context.Background()
+	seedItems(t, db, 12)
+	require.NoError(t, db.Exec(`CREATE TABLE item_reads (item_id INTEGER PRIMARY KEY, read INTEGER NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO item_reads (item_id, read) VALUES (1, 1)`).Error)
+
+	spec := CopySpec{
+		Source:    "items",
+		Target:    "item_reads",
+		Columns:   []string{"item_id", "read"},
+		Select:    []string{"id", "read"},
+		Where:     "read = 1",
+		BatchSize: 5,
+	}
+	copied, err := r.CopyRows(ctx, spec)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), copied, "odd ids are read; id 1 was already copied")
+
+	copied, err = r.CopyRows(ctx, spec)
+	require.NoError(t, err)
+	assert.Zero(t, copied)
+}
+
+func TestRunner_CopyRowsWithJoin(t *testing.T) {
+	r, db := setupRunner(t)
+	ctx := context.Background()
+	seedItems(t, db, 4)
+	require.NoError(t, db.Exec(`CREATE TABLE owners (owner_id INTEGER NOT NULL, item_id INTEGER NOT NULL)`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO owners (owner_id, item_id) VALUES (1, 1), (2, 1), (1, 2), (2, 3)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE owner_reads (owner_id INTEGER NOT NULL, item_id INTEGER NOT NULL, PRIMARY KEY (owner_id, item_id))`).Error)
+
+	spec := CopySpec{
+		Source:    "items",
+		Target:    "owner_reads",
+		Columns:   []string{"owner_id", "item_id"},
+		Select:    []string{"owners.owner_id", "items.id"},
+		Join:      "JOIN owners ON owners.item_id = items.id",
+		Where:     "items.read = 1",
+		BatchSize: 2,
+	}
+	copied, err := r.CopyRows(ctx, spec)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), copied, "items 1 and 3 are read; item 1 has two owners")
+
+	copied, err = r.CopyRows(ctx, spec)
+	require.NoError(t, err)
+	assert.Zero(t, copied)
+}
+
+func TestRunner_DualWrite(t *testing.T) {
+	r, db := setupRunner(t)
+	ctx := context.Background()
+	require.NoError(t, db.Exec(`CREATE TABLE item_reads (item_id INTEGER PRIMARY KEY, read INTEGER NOT NULL)`).Error)
+
+	spec := DualWriteSpec{
+		Name:        "items_dual_write",
+		Source:      "items",
+		Target:      "item_reads",
+		Columns:     []string{"item_id", "read"},
+		Values:      []string{"NEW.id", "NEW.read"},
+		ConflictKey: []string{"item_id"},
+	}
+	require.NoError(t, r.InstallDualWrite(ctx, spec))
+	require.NoError(t, r.InstallDualWrite(ctx, spec), "reinstalling replaces the trigger")
+
+	require.NoError(t, db.Exec(`INSERT INTO items (id, name, read) VALUES (1, 'a', 0)`).Error)
+	require.NoError(t, db.Exec(`UPDATE items SET read = 1 WHERE id = 1`).Error)
+
+	var read int
+	require.NoError(t, db.Raw(`SELECT read FROM item_reads WHERE item_id = 1`).Scan(&read).Error)
+	assert.Equal(t, 1, read)
+
+	require.NoError(t, r.RemoveDualWrite(ctx, spec))
+	require.NoError(t, db.Exec(`UPDATE items SET read = 0 WHERE id = 1`).Error)
+	require.NoError(t, db.Raw(`SELECT read FROM item_reads WHERE item_id = 1`).Scan(&read).Error)
+	assert.Equal(t, 1, read, "writes are no longer mirrored")
+}
+
+func TestDualWriteSpec_PostgresStatements(t *testing.T) {
+	spec := DualWriteSpec{
+		Name:        "items_dual_write",
+		Source:      "items",
+		Target:      "item_reads",
+		Columns:     []string{"item_id", "read"},
+		Values:      []string{"NEW.id", "NEW.read"},
+		ConflictKey: []string{"item_id"},
+		When:        "NEW.read = 1",
+	}
+	statements, err := spec.installStatements("postgres")
+	require.NoError(t, err)
+	require.Len(t, statements, 3)
+	assert.Contains(t, statements[0], "ON CONFLICT (item_id) DO UPDATE SET read = excluded.read")
+	assert.True(t, strings.HasPrefix(statements[2], "CREATE TRIGGER items_dual_write AFTER INSERT OR UPDATE ON items FOR EACH ROW WHEN (NEW.read = 1)"))
+
+	_, err = spec.installStatements("mysql")
+	assert.True(t, errors.Is(err, ErrUnsupportedDialect))
+}
+
+func TestRunner_ApplyRecordsCompletion(t *testing.T) {
+	r, _ := setupRunner(t)
+	ctx := context.Background()
+
+	runs := map[string]int{}
+	failNext := true
+	migrations := []Migration{
+		{ID: "0002_second", Up: func(ctx context.Context, r *Runner) error {
+			runs["0002"]++
+			if failNext {
+				failNext = false
+				return errors.New("interrupted")
+			}
+			return nil
+		}},
+		{ID: "0001_first", Up: func(ctx context.Context, r *Runner) error { runs["0001"]++; return nil }},
+	}
+
+	applied, err := r.Apply(ctx, migrations)
+	require.Error(t, err)
+	assert.Equal(t, 1, applied, "0001 runs first and is recorded")
+
+	applied, err = r.Apply(ctx, migrations)
+	require.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	assert.Equal(t, map[string]int{"0001": 1, "0002": 2}, runs)
+
+	statuses, err := r.Status(ctx, migrations)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "0001_first", statuses[0].ID)
+	assert.NotNil(t, statuses[0].CompletedAt)
+	assert.NotNil(t, statuses[1].CompletedAt)
+}
@@ -0,0 +1,15 @@
+package migrations
+
+import "context"
+
+// outboxEvents indexes outbox_events for the relay, which reads the unpublished events in
+// id order, and for the purge of events published long ago. Online migrations may run
+// once the feed-service is back up and writing events, so the index is built without
+// blocking those writes.
+var outboxEvents = Migration{
+	ID:          "0007_outbox_events_index",
+	Description: "index outbox_events by publication time and id",
+	Up: func(ctx context.Context, r *Runner) error {
+		return r.CreateIndex(ctx, "idx_outbox_events_published_id", "outbox_events", "published_at, id", false)
+	},
+}
@@ -0,0 +1,127 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// trackingTable records which Go migrations have completed
+const trackingTable = "online_migrations"
+
+type migrationRecord struct {
+	ID          string `gorm:"primaryKey;size:255"`
+	Description string `gorm:"not null;default:''"`
+	CompletedAt time.Time
+}
+
+func (migrationRecord) TableName() string {
+	return trackingTable
+}
+
+// Migration is a resumable, Go-coded step that runs after the SQL migrations. Up must be
+// safe to run again after an interruption (the helpers in this package are).
+type Migration struct {
+	// ID orders migrations and is recorded once Up succeeds, e.g. "0001_backfill_read_state"
+	ID          string
+	Description string
+	Up          func(ctx context.Context, r *Runner) error
+}
+
+// All lists the registered Go migrations in the order they were added
+var All = []Migration{
+	softDeleteArticles,
+	articleProcessingStatus,
+	userArticleStates,
+	articleSummaries,
+	subscriptionFolders,
+	timelineIndex,
+	outboxEvents,
+}
+
+// MigrationStatus tells whether a migration has completed
+type MigrationStatus struct {
+	ID          string
+	Description string
+	CompletedAt *time.Time
+}
+
+func (r *Runner) ensureTrackingTable(ctx context.Context) error {
+	return r.db.WithContext(ctx).AutoMigrate(&migrationRecord{})
+}
+
+func (r *Runner) completed(ctx context.Context) (map[string]time.Time, error) {
+	var records []migrationRecord
+	if err := r.db.WithContext(ctx).Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	done := make(map[string]time.Time, len(records))
+	for _, record := range records {
+		done[record.ID] = record.CompletedAt
+	}
+	return done, nil
+}
+
+// Apply runs every migration that has not completed yet, in ID order. It returns the
+// number of migrations applied.
+func (r *Runner) Apply(ctx context.Context, migrations []Migration) (int, error) {
+	if err := r.ensureTrackingTable(ctx); err != nil {
+		return 0, fmt.Errorf("create %s: %w", trackingTable, err)
+	}
+	done, err := r.completed(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("read %s: %w", trackingTable, err)
+	}
+
+	applied := 0
+	for _, m := range sorted(migrations) {
+		if _, ok := done[m.ID]; ok {
+			continue
+		}
+
+		r.logger.Info("applying migration", "id", m.ID, "description", m.Description)
+		start := time.Now()
+		if err := m.Up(ctx, r); err != nil {
+			return applied, fmt.Errorf("migration %s: %w", m.ID, err)
+		}
+
+		record := &migrationRecord{ID: m.ID, Description: m.Description, CompletedAt: time.Now().UTC()}
+		if err := r.db.WithContext(ctx).Create(record).Error; err != nil {
+			return applied, fmt.Errorf("record migration %s: %w", m.ID, err)
+		}
+
+		r.logger.Info("migration applied", "id", m.ID, "duration", time.Since(start))
+		applied++
+	}
+
+	return applied, nil
+}
+
+// Status reports the state of each migration in ID order
+func (r *Runner) Status(ctx context.Context, migrations []Migration) ([]MigrationStatus, error) {
+	if err := r.ensureTrackingTable(ctx); err != nil {
+		return nil, fmt.Errorf("create %s: %w", trackingTable, err)
+	}
+	done, err := r.completed(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", trackingTable, err)
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range sorted(migrations) {
+		status := MigrationStatus{ID: m.ID, Description: m.Description}
+		if completedAt, ok := done[m.ID]; ok {
+			status.CompletedAt = &completedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func sorted(migrations []Migration) []Migration {
+	ordered := append([]Migration(nil), migrations...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].ID < ordered[j].ID })
+	return ordered
+}
@@ -0,0 +1,205 @@
+// Package migrations holds helpers for schema changes that must not block a running
+// deployment: DDL runs under a short lock_timeout and is retried, indexes are built
+// concurrently, data is backfilled in small batches and legacy tables can be kept in
+// sync with a dual-write trigger while services roll over.
+//
+// The SQL files in db/migrations remain the source of truth for the schema; Go
+// migrations registered in All run after them (see cmd/migrator).
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnsafeChange is returned for schema changes that would lock or rewrite a table
+	ErrUnsafeChange = errors.New("unsafe schema change")
+	// ErrUnsupportedDialect is returned by helpers that only exist for some databases
+	ErrUnsupportedDialect = errors.New("unsupported database dialect")
+)
+
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// volatileDefaultPattern matches defaults that force PostgreSQL to rewrite the table
+var volatileDefaultPattern = regexp.MustCompile(`(?i)\b(random|clock_timestamp|gen_random_uuid|uuid_generate_v[1-4]|timeofday|nextval)\s*\(`)
+
+// lockNotAvailable is the PostgreSQL SQLSTATE raised when lock_timeout expires
+const lockNotAvailable = "55P03"
+
+// Runner applies schema changes and backfills against a database
+type Runner struct {
+	db     *gorm.DB
+	logger *slog.Logger
+
+	// LockTimeout bounds how long DDL waits for its table lock before giving up and retrying,
+	// so a long-running query never queues every other writer behind the ALTER.
+	LockTimeout time.Duration
+	LockRetries int
+	RetryDelay  time.Duration
+}
+
+func NewRunner(db *gorm.DB, logger *slog.Logger) *Runner {
+	return &Runner{
+		db:          db,
+		logger:      logger,
+		LockTimeout: 3 * time.Second,
+		LockRetries: 5,
+		RetryDelay:  2 * time.Second,
+	}
+}
+
+// DB exposes the underlying connection for migration steps that need plain queries
+func (r *Runner) DB() *gorm.DB {
+	return r.db
+}
+
+func (r *Runner) isPostgres() bool {
+	return r.db.Dialector.Name() == "postgres"
+}
+
+// AddColumn adds a column if it does not exist yet. Definitions that would rewrite or
+// fully scan the table (NOT NULL without a default, volatile defaults, inline
+// constraints that build an index) are rejected.
+func (r *Runner) AddColumn(ctx context.Context, table, column, definition string) error {
+	if err := checkIdentifiers(table, column); err != nil {
+		return err
+	}
+	if err := checkColumnDefinition(definition); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
+
+	if r.db.WithContext(ctx).Migrator().HasColumn(table, column) {
+		r.logger.Info("column already exists, skipping", "table", table, "column", column)
+		return nil
+	}
+
+	stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	if err := r.execWithLockTimeout(ctx, stmt); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
+
+	r.logger.Info("column added", "table", table, "column", column)
+	return nil
+}
+
+// CreateIndex builds an index without blocking writes. On PostgreSQL it uses
+// CREATE INDEX CONCURRENTLY and drops an INVALID leftover from an interrupted run first.
+func (r *Runner) CreateIndex(ctx context.Context, name, table, columns string, unique bool) error {
+	if err := checkIdentifiers(name, table); err != nil {
+		return err
+	}
+
+	kind := "INDEX"
+	if unique {
+		kind = "UNIQUE INDEX"
+	}
+
+	if !r.isPostgres() {
+		stmt := fmt.Sprintf("CREATE %s IF NOT EXISTS %s ON %s (%s)", kind, name, table, columns)
+		return r.db.WithContext(ctx).Exec(stmt).Error
+	}
+
+	var valid []bool
+	if err := r.db.WithContext(ctx).Raw(
+		`SELECT i.indisvalid FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid WHERE c.relname = ?`, name,
+	).Scan(&valid).Error; err != nil {
+		return fmt.Errorf("inspect index %s: %w", name, err)
+	}
+	if len(valid) > 0 {
+		if valid[0] {
+			r.logger.Info("index already exists, skipping", "index", name)
+			return nil
+		}
+		r.logger.Warn("dropping invalid index left by an interrupted build", "index", name)
+		if err := r.db.WithContext(ctx).Exec(fmt.Sprintf("DROP INDEX CONCURRENTLY IF EXISTS %s", name)).Error; err != nil {
+			return fmt.Errorf("drop invalid index %s: %w", name, err)
+		}
+	}
+
+	start := time.Now()
+	// CONCURRENTLY cannot run inside a transaction block
+	stmt := fmt.Sprintf("CREATE %s CONCURRENTLY IF NOT EXISTS %s ON %s (%s)", kind, name, table, columns)
+	if err := r.db.WithContext(ctx).Exec(stmt).Error; err != nil {
+		return fmt.Errorf("create index %s: %w", name, err)
+	}
+
+	r.logger.Info("index created", "index", name, "table", table, "duration", time.Since(start))
+	return nil
+}
+
+// execWithLockTimeout runs DDL with a short lock_timeout, retrying when the lock could
+// not be acquired in time
+func (r *Runner) execWithLockTimeout(ctx context.Context, statements ...string) error {
+	if !r.isPostgres() {
+		return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			for _, stmt := range statements {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	var err error
+	for attempt := 1; attempt <= r.LockRetries; attempt++ {
+		err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(fmt.Sprintf("SET LOCAL lock_timeout = '%dms'", r.LockTimeout.Milliseconds())).Error; err != nil {
+				return err
+			}
+			for _, stmt := range statements {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err == nil || !isLockTimeout(err) {
+			return err
+		}
+
+		r.logger.Warn("lock not acquired, retrying", "attempt", attempt, "max_attempts", r.LockRetries, "lock_timeout", r.LockTimeout)
+		select {
+		case <-time.After(r.RetryDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return fmt.Errorf("lock not acquired after %d attempts: %w", r.LockRetries, err)
+}
+
+func isLockTimeout(err error) bool {
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && pgErr.SQLState() == lockNotAvailable
+}
+
+func checkIdentifiers(names ...string) error {
+	for _, name := range names {
+		if !identifierPattern.MatchString(name) {
+			return fmt.Errorf("invalid identifier %q", name)
+		}
+	}
+	return nil
+}
+
+func checkColumnDefinition(definition string) error {
+	upper := strings.ToUpper(definition)
+	if strings.Contains(upper, "NOT NULL") && !strings.Contains(upper, "DEFAULT") {
+		return fmt.Errorf("%w: NOT NULL needs a DEFAULT; add the column nullable, backfill, then add the constraint", ErrUnsafeChange)
+	}
+	if volatileDefaultPattern.MatchString(definition) {
+		return fmt.Errorf("%w: a volatile DEFAULT rewrites the whole table", ErrUnsafeChange)
+	}
+	if strings.Contains(upper, "UNIQUE") || strings.Contains(upper, "PRIMARY KEY") {
+		return fmt.Errorf("%w: inline constraints build an index under lock; use CreateIndex", ErrUnsafeChange)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package migrations
+
+import "context"
+
+// softDeleteArticles adds articles.deleted_at for the article trash. The articles table
+// is the largest in the schema, so the column is added under lock_timeout and the index
+// is built concurrently.
+var softDeleteArticles = Migration{
+	ID:          "0001_soft_delete_articles",
+	Description: "add articles.deleted_at for soft deletion",
+	Up: func(ctx context.Context, r *Runner) error {
+		if err := r.AddColumn(ctx, "articles", "deleted_at", "TIMESTAMPTZ"); err != nil {
+			return err
+		}
+		return r.CreateIndex(ctx, "idx_articles_deleted_at", "articles", "deleted_at", false)
+	},
+}
@@ -0,0 +1,17 @@
+package migrations
+
+import "context"
+
+// subscriptionFolders adds subscriptions.folder_id for the folders subscriptions are filed
+// in. Every subscription starts out unfiled, so the nullable column needs no backfill and
+// the foreign key has no rows to look up.
+var subscriptionFolders = Migration{
+	ID:          "0005_subscription_folders",
+	Description: "add subscriptions.folder_id",
+	Up: func(ctx context.Context, r *Runner) error {
+		if err := r.AddColumn(ctx, "subscriptions", "folder_id", "INTEGER REFERENCES folders(id) ON DELETE SET NULL"); err != nil {
+			return err
+		}
+		return r.CreateIndex(ctx, "idx_subscriptions_folder_id", "subscriptions", "folder_id", false)
+	},
+}
@@ -0,0 +1,15 @@
+package migrations
+
+import "context"
+
+// timelineIndex backs the user timeline, which pages through the articles of every
+// subscribed feed newest published first with id breaking ties. The index covers that
+// order per feed, so each page is read from the index without sorting the feeds'
+// articles.
+var timelineIndex = Migration{
+	ID:          "0006_timeline_index",
+	Description: "index articles by feed, publication time and id for the timeline",
+	Up: func(ctx context.Context, r *Runner) error {
+		return r.CreateIndex(ctx, "idx_articles_feed_published_id", "articles", "feed_id, published_at DESC, id DESC", false)
+	},
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import "context"
+
+// userArticleStates seeds the per-user article states of sync clients from the read and
+// starred flags on articles, which every subscriber of a feed shares. Each subscriber
+// gets a copy with the article's updated_at as the change time and seq 0, so the first
+// sync of any device picks them up and later changes win over them.
+var userArticleStates = Migration{
+	ID:          "0003_user_article_states",
+	Description: "copy shared read and starred flags into user_article_states",
+	Up: func(ctx context.Context, r *Runner) error {
+		_, err := r.CopyRows(ctx, CopySpec{
+			Source:  "articles",
+			Target:  "user_article_states",
+			Columns: []string{"user_id", "article_id", "read", "read_changed_at", "starred", "starred_changed_at", "seq"},
+			Select: []string{
+				"subscriptions.user_id", "articles.id",
+				"articles.read", "CASE WHEN articles.read THEN articles.updated_at END",
+				"articles.starred", "CASE WHEN articles.starred THEN articles.updated_at END",
+				"0",
+			},
+			Join:  "JOIN subscriptions ON subscriptions.feed_id = articles.feed_id",
+			Where: "(articles.read OR articles.starred) AND articles.deleted_at IS NULL",
+		})
+		return err
+	},
+}
@@ -0,0 +1,71 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/config"
+)
+
+// Load builds the engine of cfg: the limits configured in the environment, with the
+// policy document at cfg.Policy.File, if any, applied over them
+func Load(cfg *config.Config) (*Engine, error) {
+	base, err := Base(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var doc Document
+	if cfg.Policy.File != "" {
+		doc, err = ReadDocument(cfg.Policy.File)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return NewEngine(base, doc)
+}
+
+// Base is the policy configured in the environment
+func Base(cfg *config.Config) (Policy, error) {
+	trashRetention, err := time.ParseDuration(cfg.FeedService.ArticleTrash.GracePeriod)
+	if err != nil {
+		return Policy{}, fmt.Errorf("invalid article trash grace period %q: %w", cfg.FeedService.ArticleTrash.GracePeriod, err)
+	}
+	checkInterval, err := time.ParseDuration(cfg.SchedulerService.ArticleCheck.MinCheckInterval)
+	if err != nil {
+		return Policy{}, fmt.Errorf("invalid article check min interval %q: %w", cfg.SchedulerService.ArticleCheck.MinCheckInterval, err)
+	}
+	var importCutoff time.Duration
+	if cutoff := cfg.FeedService.Import.InitialCutoff; cutoff != "" {
+		if importCutoff, err = time.ParseDuration(cutoff); err != nil {
+			return Policy{}, fmt.Errorf("invalid initial import cutoff %q: %w", cutoff, err)
+		}
+	}
+	return Policy{
+		TrashRetention:       trashRetention,
+		MaxSubscriptions:     cfg.Policy.MaxSubscriptions,
+		CrawlDailyRequests:   cfg.FeedService.CrawlBudget.DailyRequests,
+		ArticleCheckWindow:   time.Duration(cfg.SchedulerService.ArticleCheck.WindowDays) * 24 * time.Hour,
+		ArticleCheckInterval: checkInterval,
+		MaxItemsPerFetch:     cfg.FeedService.Import.MaxItemsPerFetch,
+		InitialImportCutoff:  importCutoff,
+	}, nil
+}
+
+// ReadDocument parses the JSON policy document at path. Unknown fields are rejected, so
+// a misspelt setting does not go unnoticed.
+func ReadDocument(path string) (Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Document{}, fmt.Errorf("failed to read policy document: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var doc Document
+	if err := decoder.Decode(&doc); err != nil {
+		return Document{}, fmt.Errorf("failed to parse policy document %s: %w", path, err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,364 @@
+// Package policy evaluates the retention, quota, crawl budget, import and check window
+// limits of the instance. The limits configured in the environment are the base; a declarative
+// policy document can change the defaults and override them for matching users or feeds.
+// Each service evaluates the document for the subject it works on: the subscribe path for
+// a user, the crawl budget for a feed, the trash purger and scheduler for the instance.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// Names of the settings, as written in policy documents
+const (
+	SettingTrashRetention       = "trash_retention"
+	SettingMaxSubscriptions     = "max_subscriptions"
+	SettingCrawlDailyRequests   = "crawl_daily_requests"
+	SettingArticleCheckWindow   = "article_check_window"
+	SettingArticleCheckInterval = "article_check_interval"
+	SettingMaxItemsPerFetch     = "max_items_per_fetch"
+	SettingInitialImportCutoff  = "initial_import_cutoff"
+)
+
+// Policy is the limits in effect for a subject
+type Policy struct {
+	// TrashRetention is how long deleted articles stay restorable before they are purged
+	TrashRetention time.Duration
+	// MaxSubscriptions caps the feeds a user subscribes to; 0 is unlimited
+	MaxSubscriptions int
+	// CrawlDailyRequests caps the outbound requests made for a feed per UTC day; 0 is
+	// unlimited
+	CrawlDailyRequests int
+	// ArticleCheckWindow is how far back articles are checked for updates
+	ArticleCheckWindow time.Duration
+	// ArticleCheckInterval is the least time between two checks of an article
+	ArticleCheckInterval time.Duration
+	// MaxItemsPerFetch caps the new articles a fetch of a feed saves; 0 is unlimited
+	MaxItemsPerFetch int
+	// InitialImportCutoff is how far back the first fetch of a feed imports articles; 0
+	// imports them all
+	InitialImportCutoff time.Duration
+}
+
+// MarshalJSON writes the durations as Go duration strings, e.g. 720h0m0s
+func (p Policy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		TrashRetention       string `json:"trash_retention"`
+		MaxSubscriptions     int    `json:"max_subscriptions"`
+		CrawlDailyRequests   int    `json:"crawl_daily_requests"`
+		ArticleCheckWindow   string `json:"article_check_window"`
+		ArticleCheckInterval string `json:"article_check_interval"`
+		MaxItemsPerFetch     int    `json:"max_items_per_fetch"`
+		InitialImportCutoff  string `json:"initial_import_cutoff"`
+	}{
+		TrashRetention:       p.TrashRetention.String(),
+		MaxSubscriptions:     p.MaxSubscriptions,
+		CrawlDailyRequests:   p.CrawlDailyRequests,
+		ArticleCheckWindow:   p.ArticleCheckWindow.String(),
+		ArticleCheckInterval: p.ArticleCheckInterval.String(),
+		MaxItemsPerFetch:     p.MaxItemsPerFetch,
+		InitialImportCutoff:  p.InitialImportCutoff.String(),
+	})
+}
+
+// Duration is a time.Duration written as a Go duration string in policy documents
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string such as \"720h\": %w", err)
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Settings are the limits a document sets; nil ones are left as they were
+type Settings struct {
+	TrashRetention       *Duration `json:"trash_retention,omitempty"`
+	MaxSubscriptions     *int      `json:"max_subscriptions,omitempty"`
+	CrawlDailyRequests   *int      `json:"crawl_daily_requests,omitempty"`
+	ArticleCheckWindow   *Duration `json:"article_check_window,omitempty"`
+	ArticleCheckInterval *Duration `json:"article_check_interval,omitempty"`
+	MaxItemsPerFetch     *int      `json:"max_items_per_fetch,omitempty"`
+	InitialImportCutoff  *Duration `json:"initial_import_cutoff,omitempty"`
+}
+
+// names lists the settings that are set
+func (s Settings) names() []string {
+	var names []string
+	if s.TrashRetention != nil {
+		names = append(names, SettingTrashRetention)
+	}
+	if s.MaxSubscriptions != nil {
+		names = append(names, SettingMaxSubscriptions)
+	}
+	if s.CrawlDailyRequests != nil {
+		names = append(names, SettingCrawlDailyRequests)
+	}
+	if s.ArticleCheckWindow != nil {
+		names = append(names, SettingArticleCheckWindow)
+	}
+	if s.ArticleCheckInterval != nil {
+		names = append(names, SettingArticleCheckInterval)
+	}
+	if s.MaxItemsPerFetch != nil {
+		names = append(names, SettingMaxItemsPerFetch)
+	}
+	if s.InitialImportCutoff != nil {
+		names = append(names, SettingInitialImportCutoff)
+	}
+	return names
+}
+
+func (s Settings) validate() error {
+	switch {
+	case s.TrashRetention != nil && *s.TrashRetention <= 0:
+		return fmt.Errorf("%s must be positive", SettingTrashRetention)
+	case s.MaxSubscriptions != nil && *s.MaxSubscriptions < 0:
+		return fmt.Errorf("%s must not be negative", SettingMaxSubscriptions)
+	case s.CrawlDailyRequests != nil && *s.CrawlDailyRequests < 0:
+		return fmt.Errorf("%s must not be negative", SettingCrawlDailyRequests)
+	case s.ArticleCheckWindow != nil && *s.ArticleCheckWindow <= 0:
+		return fmt.Errorf("%s must be positive", SettingArticleCheckWindow)
+	case s.ArticleCheckInterval != nil && *s.ArticleCheckInterval < 0:
+		return fmt.Errorf("%s must not be negative", SettingArticleCheckInterval)
+	case s.MaxItemsPerFetch != nil && *s.MaxItemsPerFetch < 0:
+		return fmt.Errorf("%s must not be negative", SettingMaxItemsPerFetch)
+	case s.InitialImportCutoff != nil && *s.InitialImportCutoff < 0:
+		return fmt.Errorf("%s must not be negative", SettingInitialImportCutoff)
+	}
+	return nil
+}
+
+// apply overwrites the limits of p that s sets
+func (s Settings) apply(p *Policy) {
+	if s.TrashRetention != nil {
+		p.TrashRetention = time.Duration(*s.TrashRetention)
+	}
+	if s.MaxSubscriptions != nil {
+		p.MaxSubscriptions = *s.MaxSubscriptions
+	}
+	if s.CrawlDailyRequests != nil {
+		p.CrawlDailyRequests = *s.CrawlDailyRequests
+	}
+	if s.ArticleCheckWindow != nil {
+		p.ArticleCheckWindow = time.Duration(*s.ArticleCheckWindow)
+	}
+	if s.ArticleCheckInterval != nil {
+		p.ArticleCheckInterval = time.Duration(*s.ArticleCheckInterval)
+	}
+	if s.MaxItemsPerFetch != nil {
+		p.MaxItemsPerFetch = *s.MaxItemsPerFetch
+	}
+	if s.InitialImportCutoff != nil {
+		p.InitialImportCutoff = time.Duration(*s.InitialImportCutoff)
+	}
+}
+
+// Match picks the subjects a rule applies to. A subject matches when it meets every
+// criterion given, and a criterion when it equals any of its values.
+type Match struct {
+	UserIDs   []uint            `json:"user_ids,omitempty"`
+	FeedIDs   []uint            `json:"feed_ids,omitempty"`
+	FeedTiers []models.FeedTier `json:"feed_tiers,omitempty"`
+	// FeedHosts match the host of the feed URL and its subdomains
+	FeedHosts []string `json:"feed_hosts,omitempty"`
+}
+
+func (m Match) byUser() bool {
+	return len(m.UserIDs) > 0
+}
+
+func (m Match) byFeed() bool {
+	return len(m.FeedIDs) > 0 || len(m.FeedTiers) > 0 || len(m.FeedHosts) > 0
+}
+
+func (m Match) matches(subject Subject) bool {
+	if len(m.UserIDs) > 0 && !slices.Contains(m.UserIDs, subject.UserID) {
+		return false
+	}
+	if len(m.FeedIDs) > 0 && !slices.Contains(m.FeedIDs, subject.FeedID) {
+		return false
+	}
+	if len(m.FeedTiers) > 0 && !slices.Contains(m.FeedTiers, subject.FeedTier) {
+		return false
+	}
+	if len(m.FeedHosts) > 0 && !slices.ContainsFunc(m.FeedHosts, func(host string) bool {
+		return subject.FeedHost != "" && (subject.FeedHost == host || strings.HasSuffix(subject.FeedHost, "."+host))
+	}) {
+		return false
+	}
+	return true
+}
+
+// Rule overrides settings for the subjects it matches
+type Rule struct {
+	Name  string   `json:"name"`
+	Match Match    `json:"match"`
+	Set   Settings `json:"set"`
+}
+
+// Document is a declarative policy: defaults replacing the configured limits, and rules
+// applied on top of them in order, a later match overriding an earlier one
+type Document struct {
+	Defaults Settings `json:"defaults"`
+	Rules    []Rule   `json:"rules"`
+}
+
+// Validate checks the settings and that each rule only sets limits its match can decide.
+// Trash retention and the article check window and interval apply to the whole instance
+// and are only set in the defaults; a subscription quota is decided per user, a crawl
+// budget and the import limits per feed.
+func (d Document) Validate() error {
+	if err := d.Defaults.validate(); err != nil {
+		return fmt.Errorf("defaults: %w", err)
+	}
+
+	seen := make(map[string]bool, len(d.Rules))
+	for i, rule := range d.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d has no name", i+1)
+		}
+		if seen[rule.Name] {
+			return fmt.Errorf("rule %q is defined twice", rule.Name)
+		}
+		seen[rule.Name] = true
+
+		if !rule.Match.byUser() && !rule.Match.byFeed() {
+			return fmt.Errorf("rule %q matches everything, set its limits in the defaults", rule.Name)
+		}
+		for _, tier := range rule.Match.FeedTiers {
+			if _, err := models.ParseFeedTier(string(tier)); err != nil {
+				return fmt.Errorf("rule %q: %w", rule.Name, err)
+			}
+		}
+		for _, host := range rule.Match.FeedHosts {
+			if host == "" || host != strings.ToLower(host) || strings.ContainsAny(host, "/:") {
+				return fmt.Errorf("rule %q: feed host %q must be a lowercase host name", rule.Name, host)
+			}
+		}
+
+		names := rule.Set.names()
+		if len(names) == 0 {
+			return fmt.Errorf("rule %q sets nothing", rule.Name)
+		}
+		if err := rule.Set.validate(); err != nil {
+			return fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		for _, name := range names {
+			switch name {
+			case SettingMaxSubscriptions:
+				if rule.Match.byFeed() {
+					return fmt.Errorf("rule %q: %s is decided per user, match on user_ids only", rule.Name, name)
+				}
+			case SettingCrawlDailyRequests, SettingMaxItemsPerFetch, SettingInitialImportCutoff:
+				if rule.Match.byUser() {
+					return fmt.Errorf("rule %q: %s is decided per feed, do not match on user_ids", rule.Name, name)
+				}
+			default:
+				return fmt.Errorf("rule %q: %s applies to the whole instance, set it in the defaults", rule.Name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Subject is who or what a policy is evaluated for. Zero fields are unknown and do not
+// match rules on them.
+type Subject struct {
+	UserID   uint            `json:"user_id,omitempty"`
+	FeedID   uint            `json:"feed_id,omitempty"`
+	FeedTier models.FeedTier `json:"feed_tier,omitempty"`
+	FeedHost string          `json:"feed_host,omitempty"`
+}
+
+// FeedSubject is the subject of a feed, with the host taken from its URL
+func FeedSubject(feed *models.Feed) Subject {
+	subject := Subject{FeedID: feed.ID, FeedTier: feed.FetchTier}
+	if parsed, err := url.Parse(feed.URL); err == nil {
+		subject.FeedHost = strings.ToLower(parsed.Hostname())
+	}
+	return subject
+}
+
+// Decision is the policy of a subject and the rules that made it
+type Decision struct {
+	Subject Subject  `json:"subject"`
+	Policy  Policy   `json:"policy"`
+	Rules   []string `json:"matched_rules"`
+}
+
+// Engine evaluates a policy document over the configured limits
+type Engine struct {
+	base     Policy
+	defaults Policy
+	doc      Document
+}
+
+// NewEngine validates doc and applies it over base
+func NewEngine(base Policy, doc Document) (*Engine, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy document: %w", err)
+	}
+	if doc.Rules == nil {
+		doc.Rules = []Rule{}
+	}
+	defaults := base
+	doc.Defaults.apply(&defaults)
+	return &Engine{base: base, defaults: defaults, doc: doc}, nil
+}
+
+// Base is the policy configured in the environment, before the document
+func (e *Engine) Base() Policy {
+	return e.base
+}
+
+// Defaults is the policy of subjects no rule matches, and of the instance as a whole
+func (e *Engine) Defaults() Policy {
+	return e.defaults
+}
+
+// Document is the policy document the engine evaluates
+func (e *Engine) Document() Document {
+	return e.doc
+}
+
+// Overrides reports whether any rule sets the named setting, i.e. whether it can differ
+// from the default between subjects
+func (e *Engine) Overrides(setting string) bool {
+	for _, rule := range e.doc.Rules {
+		if slices.Contains(rule.Set.names(), setting) {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate applies the rules matching subject over the defaults
+func (e *Engine) Evaluate(subject Subject) Decision {
+	decision := Decision{Subject: subject, Policy: e.defaults, Rules: []string{}}
+	for _, rule := range e.doc.Rules {
+		if !rule.Match.matches(subject) {
+			continue
+		}
+		rule.Set.apply(&decision.Policy)
+		decision.Rules = append(decision.Rules, rule.Name)
+	}
+	return decision
+}
@@ -0,0 +1,137 @@
+package policy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Fancu1/phoenix-rss/internal/config"
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+const testDocument = `{
+	"defaults": {"max_subscriptions": 100, "trash_retention": "168h"},
+	"rules": [
+		{"name": "staff", "match": {"user_ids": [1, 2]}, "set": {"max_subscriptions": 0}},
+		{"name": "low tier", "match": {"feed_tiers": ["low"]}, "set": {"crawl_daily_requests": 50}},
+		{"name": "busy host", "match": {"feed_hosts": ["example.com"]}, "set": {"crawl_daily_requests": 5000}}
+	]
+}`
+
+func testBase() Policy {
+	return Policy{
+		TrashRetention:       720 * time.Hour,
+		CrawlDailyRequests:   1000,
+		ArticleCheckWindow:   7 * 24 * time.Hour,
+		ArticleCheckInterval: 6 * time.Hour,
+		MaxItemsPerFetch:     200,
+		InitialImportCutoff:  720 * time.Hour,
+	}
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	var doc Document
+	require.NoError(t, json.Unmarshal([]byte(testDocument), &doc))
+	engine, err := NewEngine(testBase(), doc)
+	require.NoError(t, err)
+
+	defaults := engine.Defaults()
+	assert.Equal(t, 168*time.Hour, defaults.TrashRetention, "the document's defaults replace the configured ones")
+	assert.Equal(t, 100, defaults.MaxSubscriptions)
+	assert.Equal(t, 1000, defaults.CrawlDailyRequests, "settings the document leaves out keep their configured value")
+	assert.True(t, engine.Overrides(SettingCrawlDailyRequests))
+	assert.False(t, engine.Overrides(SettingTrashRetention))
+
+	decision := engine.Evaluate(Subject{UserID: 2})
+	assert.Equal(t, []string{"staff"}, decision.Rules)
+	assert.Zero(t, decision.Policy.MaxSubscriptions)
+
+	decision = engine.Evaluate(Subject{UserID: 3})
+	assert.Empty(t, decision.Rules)
+	assert.Equal(t, 100, decision.Policy.MaxSubscriptions)
+
+	feed := &models.Feed{ID: 7, URL: "https://blog.Example.com/feed.xml", FetchTier: models.FeedTierLow}
+	decision = engine.Evaluate(FeedSubject(feed))
+	assert.Equal(t, "blog.example.com", decision.Subject.FeedHost)
+	assert.Equal(t, []string{"low tier", "busy host"}, decision.Rules)
+	assert.Equal(t, 5000, decision.Policy.CrawlDailyRequests, "a later rule overrides an earlier one")
+
+	decision = engine.Evaluate(FeedSubject(&models.Feed{ID: 8, URL: "https://notexample.com/rss", FetchTier: models.FeedTierNormal}))
+	assert.Empty(t, decision.Rules, "hosts match whole labels only")
+
+	decision = engine.Evaluate(Subject{})
+	assert.Equal(t, defaults, decision.Policy)
+}
+
+func TestDocument_Validate(t *testing.T) {
+	hundred, negative := 100, -1
+	week := Duration(7 * 24 * time.Hour)
+	tests := map[string]Document{
+		"negative default": {Defaults: Settings{MaxSubscriptions: &negative}},
+		"unnamed rule":     {Rules: []Rule{{Match: Match{UserIDs: []uint{1}}, Set: Settings{MaxSubscriptions: &hundred}}}},
+		"duplicate rule": {Rules: []Rule{
+			{Name: "a", Match: Match{UserIDs: []uint{1}}, Set: Settings{MaxSubscriptions: &hundred}},
+			{Name: "a", Match: Match{UserIDs: []uint{2}}, Set: Settings{MaxSubscriptions: &hundred}},
+		}},
+		"matches everything":    {Rules: []Rule{{Name: "all", Set: Settings{MaxSubscriptions: &hundred}}}},
+		"sets nothing":          {Rules: []Rule{{Name: "noop", Match: Match{UserIDs: []uint{1}}}}},
+		"unknown tier":          {Rules: []Rule{{Name: "tier", Match: Match{FeedTiers: []models.FeedTier{"urgent"}}, Set: Settings{CrawlDailyRequests: &hundred}}}},
+		"host with scheme":      {Rules: []Rule{{Name: "host", Match: Match{FeedHosts: []string{"https://example.com"}}, Set: Settings{CrawlDailyRequests: &hundred}}}},
+		"quota per feed":        {Rules: []Rule{{Name: "quota", Match: Match{FeedIDs: []uint{1}}, Set: Settings{MaxSubscriptions: &hundred}}}},
+		"budget per user":       {Rules: []Rule{{Name: "budget", Match: Match{UserIDs: []uint{1}}, Set: Settings{CrawlDailyRequests: &hundred}}}},
+		"import cap per user":   {Rules: []Rule{{Name: "import", Match: Match{UserIDs: []uint{1}}, Set: Settings{MaxItemsPerFetch: &hundred}}}},
+		"instance-wide in rule": {Rules: []Rule{{Name: "trash", Match: Match{FeedIDs: []uint{1}}, Set: Settings{TrashRetention: &week}}}},
+	}
+	for name, doc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewEngine(testBase(), doc)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(testDocument), 0o600))
+
+	cfg := &config.Config{}
+	cfg.FeedService.ArticleTrash.GracePeriod = "720h"
+	cfg.FeedService.CrawlBudget.DailyRequests = 1000
+	cfg.SchedulerService.ArticleCheck.WindowDays = 7
+	cfg.SchedulerService.ArticleCheck.MinCheckInterval = "6h"
+	cfg.FeedService.Import.MaxItemsPerFetch = 200
+	cfg.FeedService.Import.InitialCutoff = "720h"
+
+	engine, err := Load(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, testBase(), engine.Defaults(), "without a document the configured limits apply")
+
+	cfg.Policy.File = path
+	engine, err = Load(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, testBase(), engine.Base())
+	assert.Len(t, engine.Document().Rules, 3)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"defaults": {"max_subscription": 10}}`), 0o600))
+	_, err = Load(cfg)
+	assert.Error(t, err, "a misspelt setting is rejected")
+}
+
+func TestPolicy_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(testBase())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"trash_retention": "720h0m0s",
+		"max_subscriptions": 0,
+		"crawl_daily_requests": 1000,
+		"article_check_window": "168h0m0s",
+		"article_check_interval": "6h0m0s",
+		"max_items_per_fetch": 200,
+		"initial_import_cutoff": "720h0m0s"
+	}`, string(data))
+}
@@ -0,0 +1,396 @@
+// Package readstate keeps each user's read and starred state of articles for sync clients.
+// Every change is appended to an event stream and folded into one state row per article.
+// Clients push the changes they made offline with their own timestamps, and each field is
+// merged last writer wins, so the order in which devices sync does not change the outcome.
+package readstate
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// MaxChanges caps the changes of one push
+const MaxChanges = 1000
+
+// maxClockSkew is how far ahead of the server a client's clock may be. Later change times
+// are clamped to now, so a device with a wrong clock cannot pin a state for good.
+const maxClockSkew = 5 * time.Minute
+
+// cursorPrefix versions the opaque cursor encoding
+const cursorPrefix = "s:"
+
+// Change is a client's change of one field of an article's state
+type Change struct {
+	ArticleID uint
+	Field     string
+	Value     bool
+	// ChangedAt is when the client made the change; zero means now
+	ChangedAt time.Time
+}
+
+// PushResult tells what became of a push
+type PushResult struct {
+	// Applied counts the changes that won their merge
+	Applied int
+	// Rejected lists the articles that are not in the user's subscriptions
+	Rejected []uint
+}
+
+// Cursor marks how far a client has synced: states are ordered by the event that last
+// changed them, then by article
+type Cursor struct {
+	Seq       int64
+	ArticleID uint
+}
+
+// String encodes the cursor for clients
+func (c Cursor) String() string {
+	raw := cursorPrefix + strconv.FormatInt(c.Seq, 10) + ":" + strconv.FormatUint(uint64(c.ArticleID), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a cursor from Cursor.String; the empty string is the start
+func ParseCursor(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, err
+	}
+	value, ok := strings.CutPrefix(string(raw), cursorPrefix)
+	if !ok {
+		return Cursor{}, errors.New("unknown cursor format")
+	}
+	seq, article, ok := strings.Cut(value, ":")
+	if !ok {
+		return Cursor{}, errors.New("unknown cursor format")
+	}
+	var c Cursor
+	if c.Seq, err = strconv.ParseInt(seq, 10, 64); err != nil {
+		return Cursor{}, err
+	}
+	articleID, err := strconv.ParseUint(article, 10, 32)
+	if err != nil {
+		return Cursor{}, err
+	}
+	c.ArticleID = uint(articleID)
+	return c, nil
+}
+
+// UnreadCondition is a WHERE condition on the articles table that keeps the articles a
+// user has not read. It takes the user ID as its only parameter.
+const UnreadCondition = "NOT EXISTS (SELECT 1 FROM user_article_states uas WHERE uas.user_id = ? AND uas.article_id = articles.id AND uas.read)"
+
+// StarredCondition is a WHERE condition on the articles table that keeps the articles a
+// user starred. It takes the user ID as its only parameter.
+const StarredCondition = "EXISTS (SELECT 1 FROM user_article_states uas WHERE uas.user_id = ? AND uas.article_id = articles.id AND uas.starred)"
+
+// Store reads and writes the article state of users
+type Store struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db, now: time.Now}
+}
+
+// ValidField tells whether field is an article state field clients can change
+func ValidField(field string) bool {
+	return field == models.StateFieldRead || field == models.StateFieldStarred
+}
+
+// Push appends the user's changes to the event stream and merges them into the states.
+// Changes to articles outside the user's subscriptions are skipped and reported.
+func (s *Store) Push(ctx context.Context, userID uint, deviceID string, changes []Change) (PushResult, error) {
+	var result PushResult
+	if len(changes) == 0 {
+		return result, nil
+	}
+	if len(changes) > MaxChanges {
+		return result, fmt.Errorf("push of %d changes exceeds the limit of %d", len(changes), MaxChanges)
+	}
+
+	now := s.now().UTC()
+	articleIDs := make([]uint, 0, len(changes))
+	for _, change := range changes {
+		if !ValidField(change.Field) {
+			return result, fmt.Errorf("unknown article state field %q", change.Field)
+		}
+		articleIDs = append(articleIDs, change.ArticleID)
+	}
+
+	var allowed []uint
+	err := s.db.WithContext(ctx).Model(&models.Article{}).
+		Joins("JOIN subscriptions ON subscriptions.feed_id = articles.feed_id").
+		Where("subscriptions.user_id = ? AND articles.id IN ?", userID, articleIDs).
+		Distinct().
+		Pluck("articles.id", &allowed).Error
+	if err != nil {
+		return result, fmt.Errorf("check articles: %w", err)
+	}
+	isAllowed := make(map[uint]bool, len(allowed))
+	for _, id := range allowed {
+		isAllowed[id] = true
+	}
+
+	events := make([]models.ArticleStateEvent, 0, len(changes))
+	rejected := make(map[uint]bool)
+	for _, change := range changes {
+		if !isAllowed[change.ArticleID] {
+			if !rejected[change.ArticleID] {
+				rejected[change.ArticleID] = true
+				result.Rejected = append(result.Rejected, change.ArticleID)
+			}
+			continue
+		}
+		events = append(events, models.ArticleStateEvent{
+			UserID:     userID,
+			ArticleID:  change.ArticleID,
+			Field:      change.Field,
+			Value:      change.Value,
+			ChangedAt:  clampChangeTime(change.ChangedAt, now),
+			ReceivedAt: now,
+			DeviceID:   deviceID,
+		})
+	}
+	if len(events) == 0 {
+		return result, nil
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Pushes of one user take turns, so the event IDs that become the states' seq are
+		// committed in order and no client cursor can pass a change still in flight
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('user_article_states'), ?)", userID).Error; err != nil {
+				return fmt.Errorf("lock article states: %w", err)
+			}
+		}
+		if err := tx.Create(&events).Error; err != nil {
+			return fmt.Errorf("append article state events: %w", err)
+		}
+
+		var existing []models.UserArticleState
+		if err := tx.Where("user_id = ? AND article_id IN ?", userID, allowed).Find(&existing).Error; err != nil {
+			return fmt.Errorf("load article states: %w", err)
+		}
+		states := make(map[uint]*models.UserArticleState, len(existing))
+		for i := range existing {
+			states[existing[i].ArticleID] = &existing[i]
+		}
+
+		var changed []*models.UserArticleState
+		isChanged := make(map[uint]bool)
+		for _, event := range events {
+			state, ok := states[event.ArticleID]
+			if !ok {
+				state = &models.UserArticleState{UserID: userID, ArticleID: event.ArticleID}
+				states[event.ArticleID] = state
+			}
+			if !Merge(state, event) {
+				continue
+			}
+			result.Applied++
+			state.Seq = event.ID
+			if !isChanged[event.ArticleID] {
+				isChanged[event.ArticleID] = true
+				changed = append(changed, state)
+			}
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "article_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"read", "read_changed_at", "starred", "starred_changed_at", "seq"}),
+		}).Create(&changed).Error
+		if err != nil {
+			return fmt.Errorf("store article states: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return PushResult{}, err
+	}
+	return result, nil
+}
+
+// SetRead marks the articles read or unread for the user, as a change made now
+func (s *Store) SetRead(ctx context.Context, userID uint, read bool, articleIDs ...uint) (PushResult, error) {
+	changes := make([]Change, len(articleIDs))
+	for i, articleID := range articleIDs {
+		changes[i] = Change{ArticleID: articleID, Field: models.StateFieldRead, Value: read}
+	}
+	return s.Push(ctx, userID, "", changes)
+}
+
+// SetStarred stars or unstars the articles for the user, as a change made now
+func (s *Store) SetStarred(ctx context.Context, userID uint, starred bool, articleIDs ...uint) (PushResult, error) {
+	changes := make([]Change, len(articleIDs))
+	for i, articleID := range articleIDs {
+		changes[i] = Change{ArticleID: articleID, Field: models.StateFieldStarred, Value: starred}
+	}
+	return s.Push(ctx, userID, "", changes)
+}
+
+// MarkFeedRead marks every live article of the feed the user has not read yet as read,
+// only those published at or before a non-zero before. It pushes the changes MaxChanges
+// at a time and returns how many articles it marked.
+func (s *Store) MarkFeedRead(ctx context.Context, userID, feedID uint, before time.Time) (int, error) {
+	marked := 0
+	var afterID uint
+	for {
+		query := s.db.WithContext(ctx).Model(&models.Article{}).
+			Where("feed_id = ? AND id > ?", feedID, afterID).
+			Where(UnreadCondition, userID)
+		if !before.IsZero() {
+			query = query.Where("published_at <= ?", before)
+		}
+		var articleIDs []uint
+		if err := query.Order("id").Limit(MaxChanges).Pluck("id", &articleIDs).Error; err != nil {
+			return marked, fmt.Errorf("list unread articles of feed %d: %w", feedID, err)
+		}
+		if len(articleIDs) == 0 {
+			return marked, nil
+		}
+
+		result, err := s.SetRead(ctx, userID, true, articleIDs...)
+		if err != nil {
+			return marked, err
+		}
+		marked += result.Applied
+		if len(articleIDs) < MaxChanges {
+			return marked, nil
+		}
+		afterID = articleIDs[len(articleIDs)-1]
+	}
+}
+
+// ApplyReadState sets Read and Starred on the articles to the user's own state of them.
+// Articles the user never changed are unread and not starred.
+func (s *Store) ApplyReadState(ctx context.Context, userID uint, articles []*models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+	articleIDs := make([]uint, len(articles))
+	for i, article := range articles {
+		articleIDs[i] = article.ID
+	}
+
+	var states []models.UserArticleState
+	err := s.db.WithContext(ctx).
+		Select("article_id", "read", "starred").
+		Where("user_id = ? AND article_id IN ? AND (read OR starred)", userID, articleIDs).
+		Find(&states).Error
+	if err != nil {
+		return fmt.Errorf("load read state: %w", err)
+	}
+	byArticle := make(map[uint]models.UserArticleState, len(states))
+	for _, state := range states {
+		byArticle[state.ArticleID] = state
+	}
+	for _, article := range articles {
+		state := byArticle[article.ID]
+		article.Read = state.Read
+		article.Starred = state.Starred
+	}
+	return nil
+}
+
+// Merge applies the event to the state if it is the last write of its field: it changed
+// the field later than the state's change, or at the same time and set it to true, so
+// merges agree whatever order they see events in. It reports whether the state changed.
+func Merge(state *models.UserArticleState, event models.ArticleStateEvent) bool {
+	value, changedAt := &state.Read, &state.ReadChangedAt
+	if event.Field == models.StateFieldStarred {
+		value, changedAt = &state.Starred, &state.StarredChangedAt
+	}
+
+	if current := *changedAt; current != nil {
+		if event.ChangedAt.Before(*current) {
+			return false
+		}
+		if event.ChangedAt.Equal(*current) && (*value || !event.Value) {
+			return false
+		}
+	}
+	at := event.ChangedAt
+	*value, *changedAt = event.Value, &at
+	return true
+}
+
+// Changes returns up to limit states of the user changed after the cursor, and the cursor
+// to continue from. more tells whether further states are waiting.
+func (s *Store) Changes(ctx context.Context, userID uint, after Cursor, limit int) (states []models.UserArticleState, next Cursor, more bool, err error) {
+	err = s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("seq > ? OR (seq = ? AND article_id > ?)", after.Seq, after.Seq, after.ArticleID).
+		Order("seq ASC, article_id ASC").
+		Limit(limit + 1).
+		Find(&states).Error
+	if err != nil {
+		return nil, after, false, fmt.Errorf("list article state changes: %w", err)
+	}
+	if len(states) > limit {
+		states, more = states[:limit], true
+	}
+	next = after
+	if len(states) > 0 {
+		last := states[len(states)-1]
+		next = Cursor{Seq: last.Seq, ArticleID: last.ArticleID}
+	}
+	return states, next, more, nil
+}
+
+// Compact deletes the events received before cutoff. The states already hold everything
+// the events add up to, so only the history of older changes is lost.
+func (s *Store) Compact(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Where("received_at < ?", cutoff).Delete(&models.ArticleStateEvent{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("compact article state events: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// clampChangeTime rounds a client's change time to what the database keeps, and takes
+// now for a missing time or one too far in the future
+func clampChangeTime(changedAt, now time.Time) time.Time {
+	if changedAt.IsZero() || changedAt.After(now.Add(maxClockSkew)) {
+		changedAt = now
+	}
+	return changedAt.UTC().Truncate(time.Microsecond)
+}
+
+// CompactionJob prunes old state events on the scheduler
+type CompactionJob struct {
+	store     *Store
+	retention time.Duration
+	logger    *slog.Logger
+}
+
+func NewCompactionJob(store *Store, retention time.Duration, logger *slog.Logger) *CompactionJob {
+	return &CompactionJob{store: store, retention: retention, logger: logger}
+}
+
+// Run deletes the events older than the retention and returns how many it deleted
+func (j *CompactionJob) Run(ctx context.Context) (int64, error) {
+	cutoff := j.store.now().UTC().Add(-j.retention)
+	deleted, err := j.store.Compact(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	j.logger.Info("article state events compacted", "deleted", deleted, "cutoff", cutoff)
+	return deleted, nil
+}
@@ -0,0 +1,152 @@
+package readstate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+func setupStore(t *testing.T) (*Store, []*models.Article) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Feed{}, &models.Article{}, &models.Subscription{},
+		&models.ArticleStateEvent{}, &models.UserArticleState{}))
+
+	feeds := []*models.Feed{
+		{Title: "Subscribed", URL: "https://subscribed.example.com"},
+		{Title: "Other", URL: "https://other.example.com"},
+	}
+	require.NoError(t, db.Create(feeds).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feeds[0].ID}).Error)
+	articles := []*models.Article{
+		{FeedID: feeds[0].ID, URL: "https://subscribed.example.com/1"},
+		{FeedID: feeds[0].ID, URL: "https://subscribed.example.com/2"},
+		{FeedID: feeds[1].ID, URL: "https://other.example.com/1"},
+	}
+	require.NoError(t, db.Create(articles).Error)
+	return NewStore(db), articles
+}
+
+func TestStore_OfflineMerge(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+
+	// the phone read article 1 at minute 1 and starred it at minute 3; the laptop marked it
+	// unread at minute 2. The outcome must not depend on which device syncs first.
+	phone := []Change{{Field: models.StateFieldRead, Value: true, ChangedAt: at(1)}, {Field: models.StateFieldStarred, Value: true, ChangedAt: at(3)}}
+	laptop := []Change{{Field: models.StateFieldRead, Value: false, ChangedAt: at(2)}}
+
+	for name, order := range map[string][2]string{"phone first": {"phone", "laptop"}, "laptop first": {"laptop", "phone"}} {
+		t.Run(name, func(t *testing.T) {
+			store, articles := setupStore(t)
+			store.now = func() time.Time { return at(10) }
+			pushes := map[string][]Change{"phone": phone, "laptop": laptop}
+			for _, device := range order {
+				changes := append([]Change(nil), pushes[device]...)
+				for i := range changes {
+					changes[i].ArticleID = articles[0].ID
+				}
+				_, err := store.Push(ctx, 1, device, changes)
+				require.NoError(t, err)
+			}
+
+			states, _, more, err := store.Changes(ctx, 1, Cursor{}, 10)
+			require.NoError(t, err)
+			assert.False(t, more)
+			require.Len(t, states, 1)
+			assert.False(t, states[0].Read, "the later unread wins")
+			assert.True(t, states[0].Starred)
+			require.NotNil(t, states[0].ReadChangedAt)
+			assert.True(t, states[0].ReadChangedAt.Equal(at(2)))
+		})
+	}
+}
+
+func TestStore_PushRejectsAndClamps(t *testing.T) {
+	ctx := context.Background()
+	store, articles := setupStore(t)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	result, err := store.Push(ctx, 1, "", []Change{
+		{ArticleID: articles[0].ID, Field: models.StateFieldRead, Value: true, ChangedAt: now.Add(24 * time.Hour)},
+		{ArticleID: articles[2].ID, Field: models.StateFieldRead, Value: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Applied)
+	assert.Equal(t, []uint{articles[2].ID}, result.Rejected, "article of a feed user 1 is not subscribed to")
+
+	states, _, _, err := store.Changes(ctx, 1, Cursor{}, 10)
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+	assert.True(t, states[0].ReadChangedAt.Equal(now), "a change time from the future is clamped to now")
+
+	// an unread made a minute ago still beats the clamped read
+	result, err = store.Push(ctx, 1, "", []Change{{ArticleID: articles[0].ID, Field: models.StateFieldRead, ChangedAt: now.Add(-time.Minute)}})
+	require.NoError(t, err)
+	assert.Zero(t, result.Applied)
+
+	_, err = store.Push(ctx, 1, "", []Change{{ArticleID: articles[0].ID, Field: "hidden", Value: true}})
+	assert.Error(t, err)
+}
+
+func TestStore_ChangesPagesAndCompaction(t *testing.T) {
+	ctx := context.Background()
+	store, articles := setupStore(t)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	_, err := store.Push(ctx, 1, "", []Change{
+		{ArticleID: articles[0].ID, Field: models.StateFieldRead, Value: true},
+		{ArticleID: articles[1].ID, Field: models.StateFieldRead, Value: true},
+	})
+	require.NoError(t, err)
+
+	page, cursor, more, err := store.Changes(ctx, 1, Cursor{}, 1)
+	require.NoError(t, err)
+	assert.True(t, more)
+	require.Len(t, page, 1)
+
+	parsed, err := ParseCursor(cursor.String())
+	require.NoError(t, err)
+	assert.Equal(t, cursor, parsed)
+
+	page, cursor, more, err = store.Changes(ctx, 1, parsed, 1)
+	require.NoError(t, err)
+	assert.False(t, more)
+	require.Len(t, page, 1)
+	assert.Equal(t, articles[1].ID, page[0].ArticleID)
+
+	// nothing new since the last cursor until the next change
+	page, _, _, err = store.Changes(ctx, 1, cursor, 10)
+	require.NoError(t, err)
+	assert.Empty(t, page)
+	_, err = store.Push(ctx, 1, "", []Change{{ArticleID: articles[0].ID, Field: models.StateFieldStarred, Value: true}})
+	require.NoError(t, err)
+	page, _, _, err = store.Changes(ctx, 1, cursor, 10)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.True(t, page[0].Read && page[0].Starred)
+
+	// compaction drops the events but keeps the merged states
+	deleted, err := store.Compact(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	states, _, _, err := store.Changes(ctx, 1, Cursor{}, 10)
+	require.NoError(t, err)
+	assert.Len(t, states, 2)
+
+	_, err = ParseCursor("not a cursor")
+	assert.Error(t, err)
+}
@@ -0,0 +1,112 @@
+package reports
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/pkg/mailer"
+)
+
+// reportRecord is a stored report artifact
+type reportRecord struct {
+	ID          uint64 `gorm:"primaryKey"`
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Report      string `gorm:"type:jsonb"`
+	Recipients  string
+	EmailedAt   *time.Time
+	CreatedAt   time.Time
+}
+
+func (reportRecord) TableName() string {
+	return "operator_reports"
+}
+
+// Job compiles the weekly report, stores it and emails it to the operators
+type Job struct {
+	db         *gorm.DB
+	collector  *Collector
+	mailer     mailer.Mailer
+	recipients []string
+	logger     *slog.Logger
+	now        func() time.Time
+}
+
+func NewJob(db *gorm.DB, collector *Collector, m mailer.Mailer, recipients []string, logger *slog.Logger) *Job {
+	return &Job{
+		db:         db,
+		collector:  collector,
+		mailer:     m,
+		recipients: recipients,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Run reports on the seven days up to now. The artifact is stored even when the email
+// cannot be sent; without recipients nothing is emailed.
+func (j *Job) Run(ctx context.Context) (*WeeklyReport, error) {
+	end := j.now().UTC().Truncate(time.Hour)
+	start := end.Add(-ReportPeriod)
+
+	report, err := j.collector.Collect(ctx, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("collect operator report: %w", err)
+	}
+
+	var emailedAt *time.Time
+	var sendErr error
+	if len(j.recipients) > 0 {
+		sendErr = j.mailer.Send(ctx, mailer.Message{
+			To:      j.recipients,
+			Subject: report.Subject(),
+			Body:    report.Text(),
+		})
+		if sendErr == nil {
+			sentAt := j.now().UTC()
+			emailedAt = &sentAt
+		}
+	} else {
+		j.logger.Info("no operator report recipients configured, storing report only")
+	}
+
+	if err := j.store(ctx, report, emailedAt); err != nil {
+		return report, err
+	}
+	if sendErr != nil {
+		return report, fmt.Errorf("email operator report: %w", sendErr)
+	}
+
+	j.logger.Info("operator report completed",
+		"period_start", start,
+		"period_end", end,
+		"recipients", len(j.recipients),
+		"emailed", emailedAt != nil,
+	)
+	return report, nil
+}
+
+func (j *Job) store(ctx context.Context, report *WeeklyReport, emailedAt *time.Time) error {
+	artifact, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("encode operator report: %w", err)
+	}
+
+	record := &reportRecord{
+		PeriodStart: report.PeriodStart,
+		PeriodEnd:   report.PeriodEnd,
+		Report:      string(artifact),
+		Recipients:  strings.Join(j.recipients, ","),
+		EmailedAt:   emailedAt,
+	}
+	if err := j.db.WithContext(ctx).Create(record).Error; err != nil {
+		return fmt.Errorf("store operator report: %w", err)
+	}
+	return nil
+}
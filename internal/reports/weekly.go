@@ -0,0 +1,224 @@
+// Package reports compiles the weekly operator report: instance growth, feed health,
+// AI usage and the most common fetch errors over the last seven days.
+package reports
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// ReportPeriod is the span covered by one report
+const ReportPeriod = 7 * 24 * time.Hour
+
+// topErrorLimit caps the error list in a report
+const topErrorLimit = 5
+
+// WeeklyReport is the JSON artifact stored for every run
+type WeeklyReport struct {
+	PeriodStart time.Time    `json:"period_start"`
+	PeriodEnd   time.Time    `json:"period_end"`
+	Users       CountStats   `json:"users"`
+	Feeds       FeedStats    `json:"feeds"`
+	Articles    ArticleStats `json:"articles"`
+	AI          AIStats      `json:"ai"`
+	TopErrors   []ErrorCount `json:"top_errors"`
+}
+
+// CountStats counts rows created during the period and in total
+type CountStats struct {
+	New   int64 `json:"new"`
+	Total int64 `json:"total"`
+}
+
+type FeedStats struct {
+	New   int64 `json:"new"`
+	Total int64 `json:"total"`
+	// Failing counts feeds with at least one failed fetch during the period
+	Failing int64 `json:"failing"`
+	// InError counts feeds whose latest fetch failed
+	InError  int64 `json:"in_error"`
+	Archived int64 `json:"archived"`
+}
+
+type ArticleStats struct {
+	New       int64 `json:"new"`
+	Processed int64 `json:"processed"`
+}
+
+type AIStats struct {
+	Requests         int64 `json:"requests"`
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+	// BYOKTokens were billed to users' own keys rather than the instance key
+	BYOKTokens int64 `json:"byok_tokens"`
+	// EstimatedCostUSD covers instance-key tokens; zero when no price is configured
+	EstimatedCostUSD float64      `json:"estimated_cost_usd"`
+	Models           []ModelUsage `json:"models"`
+}
+
+type ModelUsage struct {
+	Model       string `json:"model"`
+	Requests    int64  `json:"requests"`
+	TotalTokens int64  `json:"total_tokens"`
+}
+
+// ErrorCount groups feeds whose last failed fetch during the period had the same root cause
+type ErrorCount struct {
+	Error string `json:"error"`
+	Feeds int64  `json:"feeds"`
+}
+
+// Collector queries the statistics for a report
+type Collector struct {
+	db *gorm.DB
+	// pricePerMillionTokens estimates AI spend on the instance key
+	pricePerMillionTokens float64
+}
+
+func NewCollector(db *gorm.DB, pricePerMillionTokens float64) *Collector {
+	return &Collector{db: db, pricePerMillionTokens: pricePerMillionTokens}
+}
+
+// Collect compiles the report for [start, end)
+func (c *Collector) Collect(ctx context.Context, start, end time.Time) (*WeeklyReport, error) {
+	db := c.db.WithContext(ctx)
+	report := &WeeklyReport{PeriodStart: start, PeriodEnd: end, TopErrors: []ErrorCount{}}
+	inPeriod := func(column string) string {
+		return column + " >= ? AND " + column + " < ?"
+	}
+
+	counts := []struct {
+		name  string
+		query *gorm.DB
+		dest  *int64
+	}{
+		{"new users", db.Table("users").Where(inPeriod("created_at"), start, end), &report.Users.New},
+		{"users", db.Table("users"), &report.Users.Total},
+		{"new feeds", db.Model(&models.Feed{}).Where(inPeriod("created_at"), start, end), &report.Feeds.New},
+		{"feeds", db.Model(&models.Feed{}), &report.Feeds.Total},
+		{"failing feeds", db.Model(&models.Feed{}).Where(inPeriod("last_fetch_error_at"), start, end), &report.Feeds.Failing},
+		{"feeds in error", db.Model(&models.Feed{}).Where("status = ?", models.FeedStatusError), &report.Feeds.InError},
+		{"archived feeds", db.Model(&models.Feed{}).Where(inPeriod("archived_at"), start, end), &report.Feeds.Archived},
+		{"new articles", db.Model(&models.Article{}).Where(inPeriod("created_at"), start, end), &report.Articles.New},
+		{"processed articles", db.Model(&models.Article{}).Where(inPeriod("processed_at"), start, end), &report.Articles.Processed},
+	}
+	for _, count := range counts {
+		if err := count.query.Count(count.dest).Error; err != nil {
+			return nil, fmt.Errorf("count %s: %w", count.name, err)
+		}
+	}
+
+	var usage []struct {
+		Model            string
+		BYOK             bool `gorm:"column:byok"`
+		Requests         int64
+		PromptTokens     int64
+		CompletionTokens int64
+		TotalTokens      int64
+	}
+	if err := db.Table("llm_usage").
+		Select("model, byok, COUNT(*) AS requests, "+
+			"COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, "+
+			"COALESCE(SUM(completion_tokens), 0) AS completion_tokens, "+
+			"COALESCE(SUM(total_tokens), 0) AS total_tokens").
+		Where(inPeriod("created_at"), start, end).
+		Group("model, byok").
+		Order("model ASC").
+		Scan(&usage).Error; err != nil {
+		return nil, fmt.Errorf("summarize llm usage: %w", err)
+	}
+
+	report.AI.Models = []ModelUsage{}
+	byModel := map[string]int{}
+	var instanceTokens int64
+	for _, row := range usage {
+		report.AI.Requests += row.Requests
+		report.AI.PromptTokens += row.PromptTokens
+		report.AI.CompletionTokens += row.CompletionTokens
+		report.AI.TotalTokens += row.TotalTokens
+		if row.BYOK {
+			report.AI.BYOKTokens += row.TotalTokens
+		} else {
+			instanceTokens += row.TotalTokens
+		}
+
+		i, ok := byModel[row.Model]
+		if !ok {
+			i = len(report.AI.Models)
+			byModel[row.Model] = i
+			report.AI.Models = append(report.AI.Models, ModelUsage{Model: row.Model})
+		}
+		report.AI.Models[i].Requests += row.Requests
+		report.AI.Models[i].TotalTokens += row.TotalTokens
+	}
+	report.AI.EstimatedCostUSD = float64(instanceTokens) / 1e6 * c.pricePerMillionTokens
+
+	if err := db.Model(&models.Feed{}).
+		Select("last_fetch_error AS error, COUNT(*) AS feeds").
+		Where(inPeriod("last_fetch_error_at"), start, end).
+		Where("last_fetch_error IS NOT NULL AND last_fetch_error <> ''").
+		Group("last_fetch_error").
+		Order("feeds DESC, error ASC").
+		Limit(topErrorLimit).
+		Scan(&report.TopErrors).Error; err != nil {
+		return nil, fmt.Errorf("group fetch errors: %w", err)
+	}
+
+	return report, nil
+}
+
+// Subject is the email subject line
+func (r *WeeklyReport) Subject() string {
+	return fmt.Sprintf("Phoenix RSS weekly report: %s to %s",
+		r.PeriodStart.Format("2006-01-02"), r.PeriodEnd.Add(-time.Second).Format("2006-01-02"))
+}
+
+// Text renders the plain-text email body
+func (r *WeeklyReport) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "What changed between %s and %s (UTC)\n\n",
+		r.PeriodStart.UTC().Format("Mon 2006-01-02 15:04"), r.PeriodEnd.UTC().Format("Mon 2006-01-02 15:04"))
+
+	fmt.Fprintf(&b, "Users\n")
+	fmt.Fprintf(&b, "  New:            %d\n", r.Users.New)
+	fmt.Fprintf(&b, "  Total:          %d\n\n", r.Users.Total)
+
+	fmt.Fprintf(&b, "Feeds\n")
+	fmt.Fprintf(&b, "  New:            %d\n", r.Feeds.New)
+	fmt.Fprintf(&b, "  Total:          %d\n", r.Feeds.Total)
+	fmt.Fprintf(&b, "  Fetch failures: %d feeds\n", r.Feeds.Failing)
+	fmt.Fprintf(&b, "  In error now:   %d\n", r.Feeds.InError)
+	fmt.Fprintf(&b, "  Archived:       %d\n\n", r.Feeds.Archived)
+
+	fmt.Fprintf(&b, "Articles\n")
+	fmt.Fprintf(&b, "  New:            %d\n", r.Articles.New)
+	fmt.Fprintf(&b, "  Summarized:     %d\n\n", r.Articles.Processed)
+
+	fmt.Fprintf(&b, "AI usage\n")
+	fmt.Fprintf(&b, "  Requests:       %d\n", r.AI.Requests)
+	fmt.Fprintf(&b, "  Tokens:         %d (%d on user keys)\n", r.AI.TotalTokens, r.AI.BYOKTokens)
+	if r.AI.EstimatedCostUSD > 0 {
+		fmt.Fprintf(&b, "  Estimated cost: $%.2f\n", r.AI.EstimatedCostUSD)
+	}
+	for _, m := range r.AI.Models {
+		fmt.Fprintf(&b, "    %-20s %d requests, %d tokens\n", m.Model, m.Requests, m.TotalTokens)
+	}
+	b.WriteString("\n")
+
+	b.WriteString("Top fetch errors\n")
+	if len(r.TopErrors) == 0 {
+		b.WriteString("  None\n")
+	}
+	for _, e := range r.TopErrors {
+		fmt.Fprintf(&b, "  %4d feeds  %s\n", e.Feeds, e.Error)
+	}
+
+	return b.String()
+}
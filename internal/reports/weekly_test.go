@@ -0,0 +1,127 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/mailer"
+)
+
+func setupReportDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Feed{}, &models.Article{}, &reportRecord{}))
+	require.NoError(t, db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, username TEXT, created_at DATETIME)`).Error)
+	require.NoError(t, db.Exec(`CREATE TABLE llm_usage (id INTEGER PRIMARY KEY, model TEXT, byok BOOLEAN, prompt_tokens INTEGER, completion_tokens INTEGER, total_tokens INTEGER, created_at DATETIME)`).Error)
+	return db
+}
+
+func seedReportData(t *testing.T, db *gorm.DB, end time.Time) {
+	t.Helper()
+	inPeriod := end.Add(-2 * 24 * time.Hour)
+	before := end.Add(-30 * 24 * time.Hour)
+
+	require.NoError(t, db.Exec(`INSERT INTO users (username, created_at) VALUES ('old', ?), ('new', ?)`, before, inPeriod).Error)
+
+	timeout, refused := "http error: 503 Service Unavailable", "connection refused"
+	feeds := []*models.Feed{
+		{Title: "Old", URL: "https://old.example.com", Status: models.FeedStatusActive, CreatedAt: before},
+		{Title: "A", URL: "https://a.example.com", Status: models.FeedStatusError, CreatedAt: inPeriod, LastFetchError: &timeout, LastFetchErrorAt: &inPeriod},
+		{Title: "B", URL: "https://b.example.com", Status: models.FeedStatusError, CreatedAt: inPeriod, LastFetchError: &timeout, LastFetchErrorAt: &inPeriod},
+		{Title: "C", URL: "https://c.example.com", Status: models.FeedStatusActive, CreatedAt: before, LastFetchError: &refused, LastFetchErrorAt: &inPeriod},
+		{Title: "D", URL: "https://d.example.com", Status: models.FeedStatusArchived, CreatedAt: before, ArchivedAt: &inPeriod, LastFetchError: &refused, LastFetchErrorAt: &before},
+	}
+	require.NoError(t, db.Create(feeds).Error)
+
+	require.NoError(t, db.Create(&models.Article{FeedID: feeds[0].ID, URL: "https://old.example.com/1", CreatedAt: inPeriod, ProcessedAt: &inPeriod}).Error)
+	require.NoError(t, db.Create(&models.Article{FeedID: feeds[0].ID, URL: "https://old.example.com/2", CreatedAt: before}).Error)
+
+	require.NoError(t, db.Exec(`INSERT INTO llm_usage (model, byok, prompt_tokens, completion_tokens, total_tokens, created_at) VALUES
+		('gpt-4o-mini', false, 800000, 200000, 1000000, ?),
+		('gpt-4o-mini', true, 400, 100, 500, ?),
+		('claude', true, 1000, 500, 1500, ?),
+		('gpt-4o-mini', false, 9, 9, 18, ?)`, inPeriod, inPeriod, inPeriod, before).Error)
+}
+
+func TestCollector_Collect(t *testing.T) {
+	db := setupReportDB(t)
+	end := time.Date(2024, 3, 11, 8, 0, 0, 0, time.UTC)
+	seedReportData(t, db, end)
+
+	report, err := NewCollector(db, 0.6).Collect(context.Background(), end.Add(-ReportPeriod), end)
+	require.NoError(t, err)
+
+	assert.Equal(t, CountStats{New: 1, Total: 2}, report.Users)
+	assert.Equal(t, FeedStats{New: 2, Total: 5, Failing: 3, InError: 2, Archived: 1}, report.Feeds)
+	assert.Equal(t, ArticleStats{New: 1, Processed: 1}, report.Articles)
+
+	assert.Equal(t, int64(3), report.AI.Requests)
+	assert.Equal(t, int64(1002000), report.AI.TotalTokens)
+	assert.Equal(t, int64(2000), report.AI.BYOKTokens)
+	assert.InDelta(t, 0.6, report.AI.EstimatedCostUSD, 1e-9, "only instance-key tokens are priced")
+	require.Len(t, report.AI.Models, 2)
+	assert.Equal(t, ModelUsage{Model: "gpt-4o-mini", Requests: 2, TotalTokens: 1000500}, report.AI.Models[1])
+
+	assert.Equal(t, []ErrorCount{
+		{Error: "http error: 503 Service Unavailable", Feeds: 2},
+		{Error: "connection refused", Feeds: 1},
+	}, report.TopErrors)
+
+	text := report.Text()
+	assert.Contains(t, text, "Fetch failures: 3 feeds")
+	assert.Contains(t, text, "Estimated cost: $0.60")
+	assert.Equal(t, "Phoenix RSS weekly report: 2024-03-04 to 2024-03-11", report.Subject())
+}
+
+type recordingMailer struct {
+	sent []mailer.Message
+	err  error
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return m.err
+}
+
+func TestJob_RunStoresArtifact(t *testing.T) {
+	db := setupReportDB(t)
+	now := time.Date(2024, 3, 11, 8, 30, 0, 0, time.UTC)
+	seedReportData(t, db, now)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	m := &recordingMailer{}
+	job := NewJob(db, NewCollector(db, 0), m, []string{"ops@example.com"}, logger)
+	job.now = func() time.Time { return now }
+
+	report, err := job.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC), report.PeriodStart)
+	require.Len(t, m.sent, 1)
+	assert.Equal(t, []string{"ops@example.com"}, m.sent[0].To)
+
+	// a failed send still keeps the artifact, without emailed_at
+	m.err = errors.New("smtp down")
+	_, err = job.Run(context.Background())
+	require.Error(t, err)
+
+	var records []reportRecord
+	require.NoError(t, db.Order("id ASC").Find(&records).Error)
+	require.Len(t, records, 2)
+	assert.NotNil(t, records[0].EmailedAt)
+	assert.Nil(t, records[1].EmailedAt)
+	assert.Contains(t, records[0].Report, `"top_errors":[{"error":"http error: 503 Service Unavailable","feeds":2}`)
+	assert.Equal(t, "ops@example.com", records[0].Recipients)
+}
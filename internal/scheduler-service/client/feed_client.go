@@ -25,31 +25,81 @@ func NewFeedServiceClient(conn *grpc.ClientConn, logger *slog.Logger) *FeedServi
 	}
 }
 
-// GetAllFeeds retrieve all feeds from the feed service
-func (c *FeedServiceClient) GetAllFeeds(ctx context.Context) ([]*models.Feed, error) {
+// ListFeeds retrieve one page of the schedulable feeds from the feed service
+func (c *FeedServiceClient) ListFeeds(ctx context.Context, filter models.FeedFilter, pageSize int, pageToken string) (*models.FeedPage, error) {
 	log := logger.FromContext(ctx)
-	log.Debug("fetching all feeds from feed service")
+	log.Debug("fetching feeds from feed service", "page_size", pageSize, "status", filter.Status)
 
-	req := &feedpb.ListAllFeedsRequest{}
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+
+	// archived (dead) and suspended feeds are never scheduled
+	req := &feedpb.ListAllFeedsRequest{
+		ExcludeArchived: true,
+		PageSize:        uint32(pageSize),
+		PageToken:       pageToken,
+		Status:          filter.Status,
+	}
+	if filter.DueBefore != nil {
+		req.DueBefore = filter.DueBefore.UTC().Format(time.RFC3339)
+	}
+	if filter.LowTierDueBefore != nil {
+		req.LowTierDueBefore = filter.LowTierDueBefore.UTC().Format(time.RFC3339)
+	}
 
 	resp, err := c.client.ListAllFeeds(ctx, req)
 	if err != nil {
-		log.Error("failed to list all feeds", "error", err.Error())
-		return nil, fmt.Errorf("failed to list all feeds: %w", err)
+		log.Error("failed to list feeds", "error", err.Error())
+		return nil, fmt.Errorf("failed to list feeds: %w", err)
 	}
 
 	feeds := make([]*models.Feed, len(resp.Feeds))
 	for i, pbFeed := range resp.Feeds {
 		feeds[i] = &models.Feed{
-			ID:          uint(pbFeed.Id),
-			Title:       pbFeed.Title,
-			URL:         pbFeed.Url,
-			Description: pbFeed.Description,
+			ID:              uint(pbFeed.Id),
+			Title:           pbFeed.Title,
+			URL:             pbFeed.Url,
+			Description:     pbFeed.Description,
+			OwnerUserID:     uint(pbFeed.OwnerUserId),
+			SubscriberCount: int(pbFeed.SubscriberCount),
 		}
 	}
 
-	log.Debug("successfully fetched feeds", "count", len(feeds))
-	return feeds, nil
+	log.Debug("received feeds", "count", len(feeds), "has_next", resp.NextPageToken != "")
+
+	return &models.FeedPage{
+		Items:         feeds,
+		NextPageToken: resp.NextPageToken,
+	}, nil
+}
+
+// FetchBacklog counts the feeds due under the filter's DueBefore and LowTierDueBefore and
+// returns the latest fetch of any feed
+func (c *FeedServiceClient) FetchBacklog(ctx context.Context, filter models.FeedFilter) (*models.FetchBacklog, error) {
+	req := &feedpb.GetFetchBacklogRequest{}
+	if filter.DueBefore != nil {
+		req.DueBefore = filter.DueBefore.UTC().Format(time.RFC3339)
+	}
+	if filter.LowTierDueBefore != nil {
+		req.LowTierDueBefore = filter.LowTierDueBefore.UTC().Format(time.RFC3339)
+	}
+
+	resp, err := c.client.GetFetchBacklog(ctx, req)
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to get fetch backlog", "error", err.Error())
+		return nil, fmt.Errorf("failed to get fetch backlog: %w", err)
+	}
+
+	backlog := &models.FetchBacklog{DueFeeds: int(resp.DueFeeds)}
+	if resp.LastFetchedAt != "" {
+		lastFetchedAt, err := time.Parse(time.RFC3339, resp.LastFetchedAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid last fetched time %q: %w", resp.LastFetchedAt, err)
+		}
+		backlog.LastFetchedAt = &lastFetchedAt
+	}
+	return backlog, nil
 }
 
 func (c *FeedServiceClient) ListArticlesToCheck(ctx context.Context, timeRange models.ArticleCheckWindow, pageSize int, pageToken string) (*models.ArticleCheckPage, error) {
@@ -88,10 +138,17 @@ func (c *FeedServiceClient) ListArticlesToCheck(ctx context.Context, timeRange m
 		}
 	}
 
-	log.Debug("received articles to check", "count", len(items), "has_next", resp.NextPageToken != "")
+	feedCounts := make([]models.FeedCheckCount, len(resp.FeedCounts))
+	for i, feed := range resp.FeedCounts {
+		feedCounts[i] = models.FeedCheckCount{FeedID: uint(feed.FeedId), Count: int(feed.Count)}
+	}
+
+	log.Debug("received articles to check", "count", len(items), "remaining", resp.Remaining, "has_next", resp.NextPageToken != "")
 
 	return &models.ArticleCheckPage{
 		Items:         items,
 		NextPageToken: resp.NextPageToken,
+		Remaining:     int(resp.Remaining),
+		FeedCounts:    feedCounts,
 	}, nil
 }
@@ -19,24 +19,45 @@ import (
 
 // MockFeedServiceClient implements a mock gRPC client with correct signatures
 type MockFeedServiceClient struct {
+	feedpb.FeedServiceClient
+
 	feeds     []*feedpb.Feed
 	articles  []*feedpb.ArticleToCheck
+	remaining uint64
 	nextToken string
 	err       error
+
+	lastFeedsRequest *feedpb.ListAllFeedsRequest
+	backlog          *feedpb.GetFetchBacklogResponse
+	lastBacklogDue   string
+}
+
+func (m *MockFeedServiceClient) GetFetchBacklog(ctx context.Context, req *feedpb.GetFetchBacklogRequest, opts ...grpc.CallOption) (*feedpb.GetFetchBacklogResponse, error) {
+	m.lastBacklogDue = req.DueBefore
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.backlog, nil
 }
 
 func (m *MockFeedServiceClient) ListAllFeeds(ctx context.Context, req *feedpb.ListAllFeedsRequest, opts ...grpc.CallOption) (*feedpb.ListAllFeedsResponse, error) {
+	m.lastFeedsRequest = req
 	if m.err != nil {
 		return nil, m.err
 	}
-	return &feedpb.ListAllFeedsResponse{Feeds: m.feeds}, nil
+	return &feedpb.ListAllFeedsResponse{Feeds: m.feeds, NextPageToken: m.nextToken}, nil
 }
 
 func (m *MockFeedServiceClient) ListArticlesToCheck(ctx context.Context, req *feedpb.ListArticlesToCheckRequest, opts ...grpc.CallOption) (*feedpb.ListArticlesToCheckResponse, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
-	return &feedpb.ListArticlesToCheckResponse{Items: m.articles, NextPageToken: m.nextToken}, nil
+	return &feedpb.ListArticlesToCheckResponse{
+		Items:         m.articles,
+		NextPageToken: m.nextToken,
+		Remaining:     m.remaining,
+		FeedCounts:    []*feedpb.FeedCheckCount{{FeedId: 10, Count: m.remaining}},
+	}, nil
 }
 
 func (m *MockFeedServiceClient) SubscribeToFeed(ctx context.Context, req *feedpb.SubscribeToFeedRequest, opts ...grpc.CallOption) (*feedpb.SubscribeToFeedResponse, error) {
@@ -71,7 +92,7 @@ func (m *MockFeedServiceClient) BatchSubscribeToFeeds(ctx context.Context, req *
 	return nil, status.Error(codes.Unimplemented, "not implemented")
 }
 
-func TestFeedServiceClient_GetAllFeeds_Success(t *testing.T) {
+func TestFeedServiceClient_ListFeeds_Success(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
 	// Setup mock client with test data
@@ -94,7 +115,7 @@ func TestFeedServiceClient_GetAllFeeds_Success(t *testing.T) {
 		},
 	}
 
-	mockClient := &MockFeedServiceClient{feeds: pbFeeds}
+	mockClient := &MockFeedServiceClient{feeds: pbFeeds, nextToken: "next"}
 
 	// Create client with mock
 	client := &FeedServiceClient{
@@ -102,13 +123,24 @@ func TestFeedServiceClient_GetAllFeeds_Success(t *testing.T) {
 		logger: logger,
 	}
 
-	// Test GetAllFeeds
+	// Test ListFeeds
 	ctx := context.Background()
-	feeds, err := client.GetAllFeeds(ctx)
+	dueBefore := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	page, err := client.ListFeeds(ctx, models.FeedFilter{Status: "active", DueBefore: &dueBefore}, 100, "token")
 
 	// Assertions
 	require.NoError(t, err)
+	feeds := page.Items
 	assert.Len(t, feeds, 2)
+	assert.Equal(t, "next", page.NextPageToken)
+
+	// Verify the request carries the paging and filters
+	req := mockClient.lastFeedsRequest
+	assert.True(t, req.ExcludeArchived)
+	assert.Equal(t, uint32(100), req.PageSize)
+	assert.Equal(t, "token", req.PageToken)
+	assert.Equal(t, "active", req.Status)
+	assert.Equal(t, "2024-01-01T12:00:00Z", req.DueBefore)
 
 	// Verify conversion from protobuf to model
 	assert.Equal(t, uint(1), feeds[0].ID)
@@ -122,7 +154,7 @@ func TestFeedServiceClient_GetAllFeeds_Success(t *testing.T) {
 	assert.Equal(t, "Description 2", feeds[1].Description)
 }
 
-func TestFeedServiceClient_GetAllFeeds_Empty(t *testing.T) {
+func TestFeedServiceClient_ListFeeds_Empty(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
 	// Setup mock client with no feeds
@@ -133,16 +165,17 @@ func TestFeedServiceClient_GetAllFeeds_Empty(t *testing.T) {
 		logger: logger,
 	}
 
-	// Test GetAllFeeds
+	// Test ListFeeds
 	ctx := context.Background()
-	feeds, err := client.GetAllFeeds(ctx)
+	page, err := client.ListFeeds(ctx, models.FeedFilter{}, 100, "")
 
 	// Assertions
 	require.NoError(t, err)
-	assert.Len(t, feeds, 0)
+	assert.Len(t, page.Items, 0)
+	assert.Empty(t, page.NextPageToken)
 }
 
-func TestFeedServiceClient_GetAllFeeds_Error(t *testing.T) {
+func TestFeedServiceClient_ListFeeds_Error(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
 	// Setup mock client with error
@@ -155,14 +188,14 @@ func TestFeedServiceClient_GetAllFeeds_Error(t *testing.T) {
 		logger: logger,
 	}
 
-	// Test GetAllFeeds
+	// Test ListFeeds
 	ctx := context.Background()
-	feeds, err := client.GetAllFeeds(ctx)
+	page, err := client.ListFeeds(ctx, models.FeedFilter{}, 100, "")
 
 	// Assertions
 	require.Error(t, err)
-	assert.Nil(t, feeds)
-	assert.Contains(t, err.Error(), "failed to list all feeds")
+	assert.Nil(t, page)
+	assert.Contains(t, err.Error(), "failed to list feeds")
 }
 
 func TestFeedServiceClient_ListArticlesToCheck_Success(t *testing.T) {
@@ -183,7 +216,7 @@ func TestFeedServiceClient_ListArticlesToCheck_Success(t *testing.T) {
 		},
 	}
 
-	mockClient := &MockFeedServiceClient{articles: articles, nextToken: "next"}
+	mockClient := &MockFeedServiceClient{articles: articles, remaining: 120, nextToken: "next"}
 	client := &FeedServiceClient{client: mockClient, logger: logger}
 
 	ctx := context.Background()
@@ -200,6 +233,8 @@ func TestFeedServiceClient_ListArticlesToCheck_Success(t *testing.T) {
 	assert.Equal(t, "next", page.NextPageToken)
 	assert.Equal(t, uint(1), page.Items[0].ArticleID)
 	assert.Equal(t, "etag-1", page.Items[0].PrevETag)
+	assert.Equal(t, 120, page.Remaining)
+	assert.Equal(t, []models.FeedCheckCount{{FeedID: 10, Count: 120}}, page.FeedCounts)
 }
 
 func TestFeedServiceClient_ListArticlesToCheck_Error(t *testing.T) {
@@ -234,3 +269,23 @@ func TestFeedServiceClient_ListArticlesToCheck_InvalidPageSize(t *testing.T) {
 	assert.Nil(t, page)
 	assert.Contains(t, err.Error(), "page size must be positive")
 }
+
+func TestFeedServiceClient_FetchBacklog(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	mockClient := &MockFeedServiceClient{backlog: &feedpb.GetFetchBacklogResponse{DueFeeds: 42, LastFetchedAt: "2026-03-01T12:00:00Z"}}
+	client := &FeedServiceClient{client: mockClient, logger: logger}
+
+	dueBefore := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	backlog, err := client.FetchBacklog(context.Background(), models.FeedFilter{DueBefore: &dueBefore})
+	require.NoError(t, err)
+	assert.Equal(t, "2026-03-02T08:00:00Z", mockClient.lastBacklogDue)
+	assert.Equal(t, 42, backlog.DueFeeds)
+	require.NotNil(t, backlog.LastFetchedAt)
+	assert.True(t, backlog.LastFetchedAt.Equal(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)))
+
+	mockClient.backlog = &feedpb.GetFetchBacklogResponse{DueFeeds: 3}
+	backlog, err = client.FetchBacklog(context.Background(), models.FeedFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, mockClient.lastBacklogDue)
+	assert.Nil(t, backlog.LastFetchedAt, "no feed fetched yet")
+}
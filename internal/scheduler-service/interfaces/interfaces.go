@@ -9,7 +9,8 @@ import (
 
 // FeedServiceClientInterface define the interface for feed service communication
 type FeedServiceClientInterface interface {
-	GetAllFeeds(ctx context.Context) ([]*models.Feed, error)
+	ListFeeds(ctx context.Context, filter models.FeedFilter, pageSize int, pageToken string) (*models.FeedPage, error)
+	FetchBacklog(ctx context.Context, filter models.FeedFilter) (*models.FetchBacklog, error)
 	ListArticlesToCheck(ctx context.Context, timeRange models.ArticleCheckWindow, pageSize int, pageToken string) (*models.ArticleCheckPage, error)
 }
 
@@ -13,6 +13,15 @@ type ArticleToCheck struct {
 type ArticleCheckPage struct {
 	Items         []*ArticleToCheck
 	NextPageToken string
+	// Remaining counts the candidates after this page
+	Remaining int
+	// FeedCounts lists the feeds with the most remaining candidates, most first
+	FeedCounts []FeedCheckCount
+}
+
+type FeedCheckCount struct {
+	FeedID uint
+	Count  int
 }
 
 type ArticleCheckWindow struct {
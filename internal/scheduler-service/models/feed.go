@@ -1,9 +1,35 @@
 package models
 
+import "time"
+
 // Feed represent a simplified feed model for the scheduler service
 type Feed struct {
 	ID          uint   `json:"id"`
 	Title       string `json:"title"`
 	URL         string `json:"url"`
 	Description string `json:"description"`
+	// OwnerUserID is the feed's longest-standing subscriber, 0 when nobody subscribes
+	OwnerUserID     uint `json:"owner_user_id"`
+	SubscriberCount int  `json:"subscriber_count"`
+}
+
+// FeedFilter narrows the feeds the scheduler pages through; archived and suspended feeds
+// are always skipped
+type FeedFilter struct {
+	Status string // only feeds with this status when set, e.g. "active"
+	// DueBefore keeps feeds never fetched or last fetched before this time; high tier feeds
+	// are always kept and low tier feeds follow LowTierDueBefore when set
+	DueBefore        *time.Time
+	LowTierDueBefore *time.Time
+}
+
+type FeedPage struct {
+	Items         []*Feed
+	NextPageToken string
+}
+
+// FetchBacklog is how far behind feed fetching is
+type FetchBacklog struct {
+	DueFeeds      int        // feeds due for a fetch under the filter
+	LastFetchedAt *time.Time // latest fetch of any feed, nil if none was ever fetched
 }
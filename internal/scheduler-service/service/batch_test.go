@@ -133,7 +133,7 @@ func TestScheduler_BatchProcessing_Integration(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	mockClient.On("GetAllFeeds", mock.AnythingOfType("*context.valueCtx")).Return(feeds, nil)
+	mockClient.On("ListFeeds", mock.AnythingOfType("*context.valueCtx"), models.FeedFilter{}, defaultFeedPageSize, "").Return(&models.FeedPage{Items: feeds}, nil)
 
 	// Expect all feeds to be processed
 	for _, feed := range feeds {
@@ -154,3 +154,33 @@ func TestScheduler_BatchProcessing_Integration(t *testing.T) {
 	mockClient.AssertExpectations(t)
 	mockProducer.AssertExpectations(t)
 }
+
+func TestFairOrder_InterleavesOwners(t *testing.T) {
+	feeds := []*models.Feed{
+		{ID: 1, OwnerUserID: 7, SubscriberCount: 1},
+		{ID: 2, OwnerUserID: 7, SubscriberCount: 1},
+		{ID: 3, OwnerUserID: 7, SubscriberCount: 4},
+		{ID: 4, OwnerUserID: 7, SubscriberCount: 1},
+		{ID: 5, OwnerUserID: 2, SubscriberCount: 1},
+		{ID: 6, OwnerUserID: 0},
+		{ID: 7, OwnerUserID: 2, SubscriberCount: 2},
+	}
+
+	ordered := fairOrder(feeds)
+
+	var ids []uint
+	for _, feed := range ordered {
+		ids = append(ids, feed.ID)
+	}
+	// rounds over owners 0, 2, 7; busier feeds first within an owner
+	assert.Equal(t, []uint{6, 7, 3, 5, 1, 2, 4}, ids)
+}
+
+func TestFairOrder_SingleOwnerKeepsOrder(t *testing.T) {
+	feeds := []*models.Feed{
+		{ID: 3, OwnerUserID: 1},
+		{ID: 1, OwnerUserID: 1},
+	}
+
+	assert.Equal(t, feeds, fairOrder(feeds))
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -30,7 +32,16 @@ type Scheduler struct {
 	articleWindow time.Duration
 	articleMinGap time.Duration
 	articlePage   int
+	articleMax    int // 0 keeps every page at articlePage
+	articleStats  ArticleCheckStats
+	feedPage      int
+	minFetchGap   time.Duration // 0 schedules every feed on every run
+	lowTierGap    time.Duration // 0 schedules low tier feeds like the others
+	catchUpGap    time.Duration // 0 disables catch-up mode
+	catchUpWindow time.Duration
+	catchingUp    atomic.Bool
 	cron          *cron.Cron
+	jobs          []job
 	running       bool
 	mu            sync.RWMutex
 }
@@ -62,10 +73,98 @@ func NewScheduler(
 		articleWindow: articleWindow,
 		articleMinGap: articleMinGap,
 		articlePage:   articlePage,
+		feedPage:      defaultFeedPageSize,
 		cron:          cron.New(cron.WithSeconds()),
 	}
 }
 
+const defaultFeedPageSize = 1000
+
+// SetFeedPaging sets how many feeds are loaded and dispatched at a time, and skips feeds
+// fetched less than minFetchInterval ago (0 fetches every feed on every run)
+func (s *Scheduler) SetFeedPaging(pageSize int, minFetchInterval time.Duration) {
+	if pageSize > 0 {
+		s.feedPage = pageSize
+	}
+	s.minFetchGap = minFetchInterval
+}
+
+// SetLowTierInterval fetches feeds an administrator moved to the low tier at most once
+// per interval; 0 fetches them like normal tier feeds
+func (s *Scheduler) SetLowTierInterval(interval time.Duration) {
+	s.lowTierGap = interval
+}
+
+// SetCatchUp turns on catch-up mode: when no feed was fetched for at least gap, as after
+// downtime, a run spreads the backlog of due feeds over window instead of dispatching it
+// all at once, and the runs scheduled meanwhile are skipped
+func (s *Scheduler) SetCatchUp(gap, window time.Duration) {
+	s.catchUpGap = gap
+	s.catchUpWindow = window
+}
+
+// SetArticleCheckMaxPageSize lets the article check pages grow up to maxPageSize while
+// the backlog is large, so a pass takes about articleCheckTargetPages pages; they shrink
+// back to the configured page size as it drains
+func (s *Scheduler) SetArticleCheckMaxPageSize(maxPageSize int) {
+	s.articleMax = maxPageSize
+}
+
+// articleCheckTargetPages is how many pages the adaptive page size aims to list a pass in
+const articleCheckTargetPages = 20
+
+// ArticleCheckStats describes the progress of the last article check pass
+type ArticleCheckStats struct {
+	StartedAt  time.Time
+	FinishedAt time.Time // zero while the pass runs
+	// Backlog counts the candidates not listed yet
+	Backlog   int
+	Listed    int
+	Published int
+	Failed    int
+	PageSize  int
+	// TopFeeds lists the feeds with the most candidates not listed yet, most first
+	TopFeeds []models.FeedCheckCount
+}
+
+// ArticleCheckStats returns the progress of the running article check pass, or the
+// outcome of the last one
+func (s *Scheduler) ArticleCheckStats() ArticleCheckStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.articleStats
+}
+
+func (s *Scheduler) updateArticleStats(update func(stats *ArticleCheckStats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update(&s.articleStats)
+}
+
+// nextArticlePageSize sizes the next page after the backlog left, between the configured
+// page size and the maximum
+func (s *Scheduler) nextArticlePageSize(minPageSize, backlog int) int {
+	if s.articleMax <= minPageSize {
+		return minPageSize
+	}
+	size := (backlog + articleCheckTargetPages - 1) / articleCheckTargetPages
+	return min(max(size, minPageSize), s.articleMax)
+}
+
+// job is an additional cron job registered with AddJob
+type job struct {
+	name     string
+	schedule string
+	run      func(ctx context.Context)
+}
+
+// AddJob registers an additional cron job; it is scheduled when the scheduler starts
+func (s *Scheduler) AddJob(name, schedule string, run func(ctx context.Context)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, job{name: name, schedule: schedule, run: run})
+}
+
 // Start the scheduler
 func (s *Scheduler) Start(ctx context.Context) error {
 	s.mu.Lock()
@@ -93,6 +192,16 @@ func (s *Scheduler) Start(ctx context.Context) error {
 		}
 	}
 
+	for _, j := range s.jobs {
+		j := j
+		s.logger.Info("adding cron job", "job", j.name, "schedule", j.schedule)
+		if _, err := s.cron.AddFunc(j.schedule, func() {
+			j.run(ctx)
+		}); err != nil {
+			return fmt.Errorf("failed to add %s cron job: %w", j.name, err)
+		}
+	}
+
 	// Start the cron scheduler
 	s.cron.Start()
 	s.running = true
@@ -127,39 +236,141 @@ func (s *Scheduler) Stop(ctx context.Context) error {
 	return nil
 }
 
-// triggerFeedFetches fetch all feeds and publish fetch events with batch processing
+// triggerFeedFetches pages through the due feeds and publishes their fetch events with
+// batch processing, one page at a time so memory stays flat however many feeds there are
 func (s *Scheduler) triggerFeedFetches(ctx context.Context) {
 	taskCtx := logger.WithValue(ctx, "task", "feed_fetch_scheduler")
 	log := logger.FromContext(taskCtx)
 
+	if s.catchingUp.Load() {
+		log.Info("catch-up in progress, skipping scheduled feed fetch task")
+		return
+	}
+
+	var filter models.FeedFilter
+	now := time.Now().UTC()
+	if s.minFetchGap > 0 {
+		dueBefore := now.Add(-s.minFetchGap)
+		filter.DueBefore = &dueBefore
+	}
+	if s.lowTierGap > 0 {
+		lowTierDueBefore := now.Add(-s.lowTierGap)
+		filter.LowTierDueBefore = &lowTierDueBefore
+	}
+
+	batchDelay := s.batchDelay
+	catchUp := false
+	if delay := s.catchUpDelay(taskCtx, filter); delay > 0 {
+		if !s.catchingUp.CompareAndSwap(false, true) {
+			return
+		}
+		defer s.catchingUp.Store(false)
+		batchDelay, catchUp = delay, true
+	}
+
 	log.Info("starting scheduled feed fetch task with batch processing",
 		"batch_size", s.batchSize,
-		"batch_delay", s.batchDelay,
+		"batch_delay", batchDelay,
 		"max_concurrent", s.maxConcurrent,
+		"page_size", s.feedPage,
+		"catch_up", catchUp,
 	)
 
-	// Get all feeds from feed service
-	feeds, err := s.feedClient.GetAllFeeds(taskCtx)
-	if err != nil {
-		log.Error("failed to get feeds from feed service", "error", err.Error())
-		return
+	var (
+		pageToken  string
+		totalFeeds int
+		pageNumber int
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("feed fetch scheduler cancelled")
+			return
+		default:
+		}
+
+		pageNumber++
+		pageCtx := logger.WithValue(taskCtx, "page", pageNumber)
+		pageLog := logger.FromContext(pageCtx)
+
+		page, err := s.feedClient.ListFeeds(pageCtx, filter, s.feedPage, pageToken)
+		if err != nil {
+			pageLog.Error("failed to get feeds from feed service", "error", err.Error())
+			break
+		}
+
+		if len(page.Items) > 0 {
+			totalFeeds += len(page.Items)
+
+			// Interleave users before batching so no single user's feeds fill the page
+			feeds := fairOrder(page.Items)
+
+			batches := s.createBatches(feeds)
+			pageLog.Info("created batches", "batch_count", len(batches), "page_feeds", len(feeds))
+
+			// Process batches with concurrency control and rate limiting
+			s.processBatchesConcurrently(pageCtx, batches, batchDelay)
+		}
+
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+
+		// while catching up the pace holds across pages too
+		if catchUp {
+			select {
+			case <-time.After(batchDelay):
+			case <-ctx.Done():
+				log.Info("feed fetch scheduler cancelled")
+				return
+			}
+		}
 	}
 
-	if len(feeds) == 0 {
+	if totalFeeds == 0 {
 		log.Info("no feeds found to schedule")
 		return
 	}
 
-	log.Info("processing feeds in batches", "total_feeds", len(feeds))
+	log.Info("completed scheduled feed fetch task", "total_feeds", totalFeeds, "pages", pageNumber)
+}
 
-	// Create batches
-	batches := s.createBatches(feeds)
-	log.Info("created batches", "batch_count", len(batches), "total_feeds", len(feeds))
+// catchUpDelay returns the delay between batches that spreads the due feeds over the
+// catch-up window when no feed was fetched for the catch-up gap, or 0 to schedule normally
+func (s *Scheduler) catchUpDelay(ctx context.Context, filter models.FeedFilter) time.Duration {
+	if s.catchUpGap <= 0 || s.catchUpWindow <= 0 {
+		return 0
+	}
+	log := logger.FromContext(ctx)
 
-	// Process batches with concurrency control and rate limiting
-	s.processBatchesConcurrently(taskCtx, batches)
+	backlog, err := s.feedClient.FetchBacklog(ctx, filter)
+	if err != nil {
+		log.Warn("failed to get fetch backlog, scheduling normally", "error", err.Error())
+		return 0
+	}
+	// a new instance that never fetched anything has no downtime to recover from
+	if backlog.LastFetchedAt == nil || backlog.DueFeeds == 0 {
+		return 0
+	}
+	gap := time.Since(*backlog.LastFetchedAt)
+	if gap < s.catchUpGap {
+		return 0
+	}
 
-	log.Info("completed scheduled feed fetch task", "total_feeds", len(feeds))
+	batches := (backlog.DueFeeds + s.batchSize - 1) / s.batchSize
+	delay := s.catchUpWindow / time.Duration(batches)
+	if delay <= s.batchDelay {
+		return 0
+	}
+	log.Warn("no feed fetched for a long time, spreading the backlog",
+		"gap", gap.Round(time.Second),
+		"due_feeds", backlog.DueFeeds,
+		"window", s.catchUpWindow,
+		"batch_delay", delay,
+	)
+	return delay
 }
 
 func (s *Scheduler) triggerArticleChecks(ctx context.Context) {
@@ -176,10 +387,11 @@ func (s *Scheduler) triggerArticleChecks(ctx context.Context) {
 		LastCheckedBefore: now.Add(-s.articleMinGap),
 	}
 
-	pageSize := s.articlePage
-	if pageSize <= 0 {
-		pageSize = 500
+	minPageSize := s.articlePage
+	if minPageSize <= 0 {
+		minPageSize = 500
 	}
+	pageSize := minPageSize
 
 	var (
 		pageToken         string
@@ -194,7 +406,11 @@ func (s *Scheduler) triggerArticleChecks(ctx context.Context) {
 		"last_checked_before", window.LastCheckedBefore,
 		"page_size", pageSize,
 	)
+	s.updateArticleStats(func(stats *ArticleCheckStats) {
+		*stats = ArticleCheckStats{StartedAt: now, PageSize: pageSize}
+	})
 
+	backlog := 0
 	for {
 		select {
 		case <-ctx.Done():
@@ -215,10 +431,12 @@ func (s *Scheduler) triggerArticleChecks(ctx context.Context) {
 
 		if len(page.Items) == 0 {
 			pageLog.Info("no articles to check in page")
+			backlog = 0
 			break
 		}
 
 		totalCandidates += len(page.Items)
+		backlog = page.Remaining
 
 		for _, item := range page.Items {
 			articleCtx := logger.WithValue(pageCtx, "article_id", item.ArticleID)
@@ -243,19 +461,90 @@ func (s *Scheduler) triggerArticleChecks(ctx context.Context) {
 			successfulPublish++
 		}
 
+		nextPageSize := s.nextArticlePageSize(minPageSize, backlog)
+		s.updateArticleStats(func(stats *ArticleCheckStats) {
+			stats.Backlog = backlog
+			stats.Listed = totalCandidates
+			stats.Published = successfulPublish
+			stats.Failed = failedPublish
+			stats.PageSize = nextPageSize
+			stats.TopFeeds = page.FeedCounts
+		})
+		pageLog.Info("article update check progress",
+			"listed", totalCandidates,
+			"remaining", backlog,
+			"progress_pct", totalCandidates*100/(totalCandidates+backlog),
+			"top_feeds", formatFeedCounts(page.FeedCounts),
+			"next_page_size", nextPageSize,
+		)
+
 		if page.NextPageToken == "" {
 			break
 		}
 		pageToken = page.NextPageToken
+		pageSize = nextPageSize
 	}
 
+	s.updateArticleStats(func(stats *ArticleCheckStats) {
+		stats.Backlog = backlog
+		stats.FinishedAt = time.Now().UTC()
+	})
 	log.Info("completed scheduled article update check",
 		"candidates", totalCandidates,
 		"published", successfulPublish,
 		"failed", failedPublish,
+		"backlog", backlog,
+		"pages", pageNumber,
 	)
 }
 
+// formatFeedCounts renders per-feed candidate counts for the logs, as "feed_id:count"
+func formatFeedCounts(counts []models.FeedCheckCount) []string {
+	out := make([]string, len(counts))
+	for i, count := range counts {
+		out[i] = fmt.Sprintf("%d:%d", count.FeedID, count.Count)
+	}
+	return out
+}
+
+// fairOrder interleaves feeds round-robin across their owning users, so a user with thousands
+// of feeds cannot push everyone else's to the end of the page. Within one user, feeds with
+// more subscribers go first since a refresh serves more readers. Unowned feeds form their own
+// group; ordering is deterministic so consecutive cycles behave the same.
+func fairOrder(feeds []*models.Feed) []*models.Feed {
+	groups := make(map[uint][]*models.Feed)
+	var owners []uint
+	for _, feed := range feeds {
+		if _, ok := groups[feed.OwnerUserID]; !ok {
+			owners = append(owners, feed.OwnerUserID)
+		}
+		groups[feed.OwnerUserID] = append(groups[feed.OwnerUserID], feed)
+	}
+	if len(owners) <= 1 {
+		return feeds
+	}
+
+	sort.Slice(owners, func(i, j int) bool { return owners[i] < owners[j] })
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			if group[i].SubscriberCount != group[j].SubscriberCount {
+				return group[i].SubscriberCount > group[j].SubscriberCount
+			}
+			return group[i].ID < group[j].ID
+		})
+	}
+
+	ordered := make([]*models.Feed, 0, len(feeds))
+	for round := 0; len(ordered) < len(feeds); round++ {
+		for _, owner := range owners {
+			if group := groups[owner]; round < len(group) {
+				ordered = append(ordered, group[round])
+			}
+		}
+	}
+	return ordered
+}
+
 // createBatches split feeds into smaller batches
 func (s *Scheduler) createBatches(feeds []*models.Feed) [][]*models.Feed {
 	var batches [][]*models.Feed
@@ -272,7 +561,7 @@ func (s *Scheduler) createBatches(feeds []*models.Feed) [][]*models.Feed {
 }
 
 // processBatchesConcurrently process batches with concurrency control and rate limiting
-func (s *Scheduler) processBatchesConcurrently(ctx context.Context, batches [][]*models.Feed) {
+func (s *Scheduler) processBatchesConcurrently(ctx context.Context, batches [][]*models.Feed, batchDelay time.Duration) {
 	log := logger.FromContext(ctx)
 
 	// Create semaphore for concurrency control
@@ -321,7 +610,7 @@ func (s *Scheduler) processBatchesConcurrently(ctx context.Context, batches [][]
 		// Add delay between batch starts (except for the last batch)
 		if batchIndex < len(batches)-1 {
 			select {
-			case <-time.After(s.batchDelay):
+			case <-time.After(batchDelay):
 				// Continue to next batch
 			case <-ctx.Done():
 				log.Info("context cancelled, stopping batch processing")
@@ -344,7 +633,9 @@ func (s *Scheduler) processBatch(ctx context.Context, feeds []*models.Feed) (suc
 	log := logger.FromContext(ctx)
 
 	for _, feed := range feeds {
+		// each scheduled fetch gets its own ID to follow it through fetching and AI processing
 		feedCtx := logger.WithValue(ctx, "feed_id", feed.ID)
+		feedCtx = logger.WithRequestID(feedCtx, events.NewRequestID())
 		feedLog := logger.FromContext(feedCtx)
 
 		err := s.producer.PublishFeedFetch(feedCtx, feed.ID)
@@ -19,9 +19,13 @@ type MockFeedClient struct {
 	mock.Mock
 }
 
-func (m *MockFeedClient) GetAllFeeds(ctx context.Context) ([]*models.Feed, error) {
-	args := m.Called(ctx)
-	return args.Get(0).([]*models.Feed), args.Error(1)
+func (m *MockFeedClient) ListFeeds(ctx context.Context, filter models.FeedFilter, pageSize int, pageToken string) (*models.FeedPage, error) {
+	args := m.Called(ctx, filter, pageSize, pageToken)
+	var page *models.FeedPage
+	if v := args.Get(0); v != nil {
+		page = v.(*models.FeedPage)
+	}
+	return page, args.Error(1)
 }
 
 func (m *MockFeedClient) ListArticlesToCheck(ctx context.Context, timeRange models.ArticleCheckWindow, pageSize int, pageToken string) (*models.ArticleCheckPage, error) {
@@ -33,6 +37,15 @@ func (m *MockFeedClient) ListArticlesToCheck(ctx context.Context, timeRange mode
 	return page, args.Error(1)
 }
 
+func (m *MockFeedClient) FetchBacklog(ctx context.Context, filter models.FeedFilter) (*models.FetchBacklog, error) {
+	args := m.Called(ctx, filter)
+	var backlog *models.FetchBacklog
+	if v := args.Get(0); v != nil {
+		backlog = v.(*models.FetchBacklog)
+	}
+	return backlog, args.Error(1)
+}
+
 // MockProducer implements a mock Kafka producer
 type MockProducer struct {
 	mock.Mock
@@ -86,6 +99,37 @@ func TestScheduler_StartStop(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestScheduler_AddJob(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	scheduler := NewScheduler(logger, new(MockFeedClient), new(MockProducer), nil, "@every 1h", 10, 1*time.Second, 2, "", 24*time.Hour, 4*time.Hour, 100)
+
+	ran := make(chan struct{}, 1)
+	scheduler.AddJob("test", "@every 1s", func(ctx context.Context) {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+	})
+	assert.NoError(t, scheduler.Start(context.Background()))
+	defer scheduler.Stop(context.Background())
+
+	select {
+	case <-ran:
+	case <-time.After(3 * time.Second):
+		t.Fatal("added job did not run")
+	}
+}
+
+func TestScheduler_AddJobInvalidSchedule(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	scheduler := NewScheduler(logger, new(MockFeedClient), new(MockProducer), nil, "@every 1h", 10, 1*time.Second, 2, "", 24*time.Hour, 4*time.Hour, 100)
+
+	scheduler.AddJob("broken", "not a schedule", func(ctx context.Context) {})
+	err := scheduler.Start(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "broken")
+}
+
 func TestScheduler_TriggerFeedFetches_Success(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 	mockClient := new(MockFeedClient)
@@ -100,7 +144,7 @@ func TestScheduler_TriggerFeedFetches_Success(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	mockClient.On("GetAllFeeds", mock.AnythingOfType("*context.valueCtx")).Return(feeds, nil)
+	mockClient.On("ListFeeds", mock.AnythingOfType("*context.valueCtx"), models.FeedFilter{}, defaultFeedPageSize, "").Return(&models.FeedPage{Items: feeds}, nil)
 	mockProducer.On("PublishFeedFetch", mock.AnythingOfType("*context.valueCtx"), uint(1)).Return(nil)
 	mockProducer.On("PublishFeedFetch", mock.AnythingOfType("*context.valueCtx"), uint(2)).Return(nil)
 
@@ -123,7 +167,7 @@ func TestScheduler_TriggerFeedFetches_NoFeeds(t *testing.T) {
 	feeds := []*models.Feed{}
 
 	ctx := context.Background()
-	mockClient.On("GetAllFeeds", mock.AnythingOfType("*context.valueCtx")).Return(feeds, nil)
+	mockClient.On("ListFeeds", mock.AnythingOfType("*context.valueCtx"), models.FeedFilter{}, defaultFeedPageSize, "").Return(&models.FeedPage{Items: feeds}, nil)
 
 	// Test the trigger function
 	scheduler.triggerFeedFetches(ctx)
@@ -143,14 +187,14 @@ func TestScheduler_TriggerFeedFetches_GetFeedsError(t *testing.T) {
 
 	// Setup mock expectations
 	ctx := context.Background()
-	mockClient.On("GetAllFeeds", mock.AnythingOfType("*context.valueCtx")).Return(([]*models.Feed)(nil), assert.AnError)
+	mockClient.On("ListFeeds", mock.AnythingOfType("*context.valueCtx"), models.FeedFilter{}, defaultFeedPageSize, "").Return(nil, assert.AnError)
 
 	// Test the trigger function
 	scheduler.triggerFeedFetches(ctx)
 
 	// Verify expectations
 	mockClient.AssertExpectations(t)
-	// Producer should not be called when GetAllFeeds fails
+	// Producer should not be called when ListFeeds fails
 	mockProducer.AssertNotCalled(t, "PublishFeedFetch")
 }
 
@@ -168,7 +212,7 @@ func TestScheduler_TriggerFeedFetches_PartialFailure(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	mockClient.On("GetAllFeeds", mock.AnythingOfType("*context.valueCtx")).Return(feeds, nil)
+	mockClient.On("ListFeeds", mock.AnythingOfType("*context.valueCtx"), models.FeedFilter{}, defaultFeedPageSize, "").Return(&models.FeedPage{Items: feeds}, nil)
 	mockProducer.On("PublishFeedFetch", mock.AnythingOfType("*context.valueCtx"), uint(1)).Return(nil)
 	mockProducer.On("PublishFeedFetch", mock.AnythingOfType("*context.valueCtx"), uint(2)).Return(assert.AnError)
 
@@ -217,6 +261,45 @@ func TestScheduler_TriggerArticleChecks_Success(t *testing.T) {
 	mockArticleProducer.AssertExpectations(t)
 }
 
+func TestScheduler_TriggerArticleChecks_AdaptsPageSizeToBacklog(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	mockClient := new(MockFeedClient)
+	mockArticleProducer := new(MockArticleCheckProducer)
+
+	scheduler := NewScheduler(logger, mockClient, new(MockProducer), mockArticleProducer, "@every 1h", 10, time.Second, 2, "", 7*24*time.Hour, 4*time.Hour, 50)
+	scheduler.SetArticleCheckMaxPageSize(300)
+
+	window := mock.AnythingOfType("models.ArticleCheckWindow")
+	ctx := context.Background()
+	mockClient.On("ListArticlesToCheck", mock.AnythingOfType("*context.valueCtx"), window, 50, "").
+		Return(&models.ArticleCheckPage{
+			Items:         []*models.ArticleToCheck{{ArticleID: 1, FeedID: 2}},
+			NextPageToken: "page-2",
+			Remaining:     10000,
+			FeedCounts:    []models.FeedCheckCount{{FeedID: 2, Count: 9000}},
+		}, nil)
+	// 10000 left over 20 pages would be 500 a page, capped at 300
+	mockClient.On("ListArticlesToCheck", mock.AnythingOfType("*context.valueCtx"), window, 300, "page-2").
+		Return(&models.ArticleCheckPage{
+			Items:         []*models.ArticleToCheck{{ArticleID: 2, FeedID: 2}},
+			NextPageToken: "page-3",
+			Remaining:     1200,
+		}, nil)
+	mockClient.On("ListArticlesToCheck", mock.AnythingOfType("*context.valueCtx"), window, 60, "page-3").
+		Return(&models.ArticleCheckPage{Items: []*models.ArticleToCheck{{ArticleID: 3, FeedID: 4}}}, nil)
+	mockArticleProducer.On("PublishArticleCheck", mock.Anything, mock.Anything).Return(nil)
+
+	scheduler.triggerArticleChecks(ctx)
+
+	mockClient.AssertExpectations(t)
+	stats := scheduler.ArticleCheckStats()
+	assert.Equal(t, 3, stats.Listed)
+	assert.Equal(t, 3, stats.Published)
+	assert.Zero(t, stats.Backlog)
+	assert.Equal(t, 50, stats.PageSize, "an empty backlog shrinks the page back")
+	assert.False(t, stats.FinishedAt.IsZero())
+}
+
 func TestScheduler_TriggerArticleChecks_Error(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 	mockClient := new(MockFeedClient)
@@ -235,3 +318,98 @@ func TestScheduler_TriggerArticleChecks_Error(t *testing.T) {
 	mockClient.AssertExpectations(t)
 	mockArticleProducer.AssertNotCalled(t, "PublishArticleCheck", mock.Anything, mock.Anything)
 }
+
+func TestScheduler_TriggerFeedFetches_PagesThroughDueFeeds(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	mockClient := new(MockFeedClient)
+	mockProducer := new(MockProducer)
+
+	scheduler := NewScheduler(logger, mockClient, mockProducer, nil, "@every 1h", 10, time.Millisecond, 2, "", 24*time.Hour, 4*time.Hour, 100)
+	scheduler.SetFeedPaging(2, 30*time.Minute)
+	scheduler.SetLowTierInterval(6 * time.Hour)
+
+	dueFilter := mock.MatchedBy(func(filter models.FeedFilter) bool {
+		return filter.DueBefore != nil && time.Since(*filter.DueBefore) >= 30*time.Minute &&
+			filter.LowTierDueBefore != nil && time.Since(*filter.LowTierDueBefore) >= 6*time.Hour
+	})
+
+	ctx := context.Background()
+	mockClient.On("ListFeeds", mock.AnythingOfType("*context.valueCtx"), dueFilter, 2, "").
+		Return(&models.FeedPage{Items: []*models.Feed{{ID: 1}, {ID: 2}}, NextPageToken: "page-2"}, nil)
+	mockClient.On("ListFeeds", mock.AnythingOfType("*context.valueCtx"), dueFilter, 2, "page-2").
+		Return(&models.FeedPage{Items: []*models.Feed{{ID: 3}}}, nil)
+	for _, id := range []uint{1, 2, 3} {
+		mockProducer.On("PublishFeedFetch", mock.AnythingOfType("*context.valueCtx"), id).Return(nil).Once()
+	}
+
+	scheduler.triggerFeedFetches(ctx)
+
+	mockClient.AssertExpectations(t)
+	mockProducer.AssertExpectations(t)
+}
+
+func TestScheduler_CatchUpDelay(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	ctx := context.Background()
+	longAgo := time.Now().Add(-26 * time.Hour)
+	recently := time.Now().Add(-10 * time.Minute)
+
+	for _, tc := range []struct {
+		name    string
+		backlog *models.FetchBacklog
+		err     error
+		want    time.Duration
+	}{
+		{name: "after downtime", backlog: &models.FetchBacklog{DueFeeds: 95, LastFetchedAt: &longAgo}, want: 12 * time.Minute},
+		{name: "no gap", backlog: &models.FetchBacklog{DueFeeds: 95, LastFetchedAt: &recently}},
+		{name: "never fetched", backlog: &models.FetchBacklog{DueFeeds: 95}},
+		{name: "batch delay already slower", backlog: &models.FetchBacklog{DueFeeds: 50000, LastFetchedAt: &longAgo}},
+		{name: "backlog unknown", err: assert.AnError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockClient := new(MockFeedClient)
+			scheduler := NewScheduler(logger, mockClient, new(MockProducer), nil, "@every 1h", 10, 5*time.Second, 2, "", 24*time.Hour, 4*time.Hour, 100)
+			scheduler.SetCatchUp(6*time.Hour, 2*time.Hour)
+			mockClient.On("FetchBacklog", ctx, models.FeedFilter{}).Return(tc.backlog, tc.err)
+
+			assert.Equal(t, tc.want, scheduler.catchUpDelay(ctx, models.FeedFilter{}))
+		})
+	}
+}
+
+func TestScheduler_TriggerFeedFetches_CatchUp(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	mockClient := new(MockFeedClient)
+	mockProducer := new(MockProducer)
+
+	scheduler := NewScheduler(logger, mockClient, mockProducer, nil, "@every 1h", 1, time.Millisecond, 4, "", 24*time.Hour, 4*time.Hour, 100)
+	scheduler.SetFeedPaging(2, 0)
+	scheduler.SetCatchUp(time.Hour, 120*time.Millisecond)
+
+	longAgo := time.Now().Add(-24 * time.Hour)
+	mockClient.On("FetchBacklog", mock.AnythingOfType("*context.valueCtx"), models.FeedFilter{}).
+		Return(&models.FetchBacklog{DueFeeds: 3, LastFetchedAt: &longAgo}, nil).Once()
+	mockClient.On("ListFeeds", mock.AnythingOfType("*context.valueCtx"), models.FeedFilter{}, 2, "").
+		Return(&models.FeedPage{Items: []*models.Feed{{ID: 1}, {ID: 2}}, NextPageToken: "page-2"}, nil).Once()
+	mockClient.On("ListFeeds", mock.AnythingOfType("*context.valueCtx"), models.FeedFilter{}, 2, "page-2").
+		Return(&models.FeedPage{Items: []*models.Feed{{ID: 3}}}, nil).Once()
+	var published []time.Time
+	mockProducer.On("PublishFeedFetch", mock.AnythingOfType("*context.valueCtx"), mock.Anything).
+		Run(func(mock.Arguments) { published = append(published, time.Now()) }).Return(nil).Times(3)
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.triggerFeedFetches(context.Background())
+		close(done)
+	}()
+
+	// runs scheduled during the catch-up are skipped
+	time.Sleep(20 * time.Millisecond)
+	scheduler.triggerFeedFetches(context.Background())
+	<-done
+
+	mockClient.AssertExpectations(t)
+	mockProducer.AssertExpectations(t)
+	assert.GreaterOrEqual(t, published[2].Sub(published[0]), 70*time.Millisecond, "the backlog is spread over the window")
+	assert.False(t, scheduler.catchingUp.Load())
+}
@@ -0,0 +1,116 @@
+// Package summaries keeps the summary variants of articles, one per model and language,
+// and picks the variant a reader prefers. The variant in the default language is also
+// written to the article's own summary columns, which older clients read.
+package summaries
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+// Preference is the summary variant a reader asks for. Empty fields match any variant.
+type Preference struct {
+	Language string
+	Model    string
+}
+
+// IsZero reports whether the reader has no preference, so the default variant is shown
+func (p Preference) IsZero() bool {
+	return p.Language == "" && p.Model == ""
+}
+
+// matches reports whether the variant is one the reader asked for
+func (p Preference) matches(summary models.ArticleSummary) bool {
+	return (p.Language == "" || strings.EqualFold(p.Language, summary.Language)) &&
+		(p.Model == "" || p.Model == summary.Model)
+}
+
+// Store reads and writes article_summaries
+type Store struct {
+	db *gorm.DB
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+// Save stores the variants, replacing the summary an article already has from the same
+// model in the same language
+func (s *Store) Save(ctx context.Context, variants ...models.ArticleSummary) error {
+	if len(variants) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "article_id"}, {Name: "model"}, {Name: "language"}},
+		DoUpdates: clause.AssignmentColumns([]string{"summary", "truncated", "prompt", "request_id", "updated_at"}),
+	}).Create(&variants).Error
+}
+
+// Apply loads the summary variants of the articles into Summaries. When a variant matches
+// the preference, the most recent one replaces the article's summary; otherwise the
+// article keeps its default variant.
+func (s *Store) Apply(ctx context.Context, pref Preference, articles []*models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+	articleIDs := make([]uint, len(articles))
+	for i, article := range articles {
+		articleIDs[i] = article.ID
+	}
+
+	var variants []models.ArticleSummary
+	err := s.db.WithContext(ctx).
+		Where("article_id IN ?", articleIDs).
+		Order("article_id, language, model").
+		Find(&variants).Error
+	if err != nil {
+		return fmt.Errorf("load article summaries: %w", err)
+	}
+	byArticle := make(map[uint][]models.ArticleSummary, len(articles))
+	for _, variant := range variants {
+		byArticle[variant.ArticleID] = append(byArticle[variant.ArticleID], variant)
+	}
+
+	for _, article := range articles {
+		article.Summaries = byArticle[article.ID]
+		if pref.IsZero() {
+			continue
+		}
+		if preferred := preferred(pref, article.Summaries); preferred != nil {
+			show(article, *preferred)
+		}
+	}
+	return nil
+}
+
+// preferred returns the most recently made variant matching the preference, or nil
+func preferred(pref Preference, variants []models.ArticleSummary) *models.ArticleSummary {
+	var best *models.ArticleSummary
+	for i := range variants {
+		if !pref.matches(variants[i]) {
+			continue
+		}
+		if best == nil || variants[i].UpdatedAt.After(best.UpdatedAt) {
+			best = &variants[i]
+		}
+	}
+	return best
+}
+
+// show puts the variant in the article's summary fields
+func show(article *models.Article, variant models.ArticleSummary) {
+	summary := variant.Summary
+	model := variant.Model
+	updatedAt := variant.UpdatedAt
+	article.Summary = &summary
+	article.SummaryTruncated = variant.Truncated
+	article.ProcessingModel = &model
+	article.ProcessingPrompt = variant.Prompt
+	article.ProcessedAt = &updatedAt
+}
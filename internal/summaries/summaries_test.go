@@ -0,0 +1,87 @@
+package summaries
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+)
+
+func setupStore(t *testing.T) (*Store, *gorm.DB) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Article{}, &models.ArticleSummary{}))
+	return NewStore(db), db
+}
+
+func TestStore_SaveReplacesVariant(t *testing.T) {
+	store, db := setupStore(t)
+	ctx := context.Background()
+	article := &models.Article{FeedID: 1, URL: "https://example.com/1"}
+	require.NoError(t, db.Create(article).Error)
+
+	require.NoError(t, store.Save(ctx,
+		models.ArticleSummary{ArticleID: article.ID, Model: "m", Language: "zh", Summary: "first", Truncated: true},
+		models.ArticleSummary{ArticleID: article.ID, Model: "m", Language: "en", Summary: "english"},
+	))
+	require.NoError(t, store.Save(ctx, models.ArticleSummary{ArticleID: article.ID, Model: "m", Language: "zh", Summary: "regenerated"}))
+
+	var variants []models.ArticleSummary
+	require.NoError(t, db.Order("language").Find(&variants).Error)
+	require.Len(t, variants, 2)
+	assert.Equal(t, "english", variants[0].Summary)
+	assert.Equal(t, "regenerated", variants[1].Summary)
+	assert.False(t, variants[1].Truncated)
+}
+
+func TestStore_ApplyPrefersVariant(t *testing.T) {
+	store, db := setupStore(t)
+	ctx := context.Background()
+	legacy := "默认摘要"
+	legacyModel := "m1"
+	articles := []*models.Article{
+		{FeedID: 1, URL: "https://example.com/1", Summary: &legacy, ProcessingModel: &legacyModel},
+		{FeedID: 1, URL: "https://example.com/2", Summary: &legacy, ProcessingModel: &legacyModel},
+	}
+	require.NoError(t, db.Create(articles).Error)
+
+	older := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	require.NoError(t, db.Create([]models.ArticleSummary{
+		{ArticleID: articles[0].ID, Model: "m1", Language: "zh", Summary: legacy, UpdatedAt: older},
+		{ArticleID: articles[0].ID, Model: "m1", Language: "en", Summary: "English by m1", UpdatedAt: older},
+		{ArticleID: articles[0].ID, Model: "m2", Language: "en", Summary: "English by m2", UpdatedAt: newer},
+	}).Error)
+
+	load := func(pref Preference) []*models.Article {
+		var loaded []*models.Article
+		require.NoError(t, db.Order("id").Find(&loaded).Error)
+		require.NoError(t, store.Apply(ctx, pref, loaded))
+		return loaded
+	}
+
+	loaded := load(Preference{})
+	assert.Equal(t, legacy, *loaded[0].Summary, "no preference keeps the default variant")
+	assert.Len(t, loaded[0].Summaries, 3)
+	assert.Empty(t, loaded[1].Summaries)
+
+	loaded = load(Preference{Language: "EN"})
+	assert.Equal(t, "English by m2", *loaded[0].Summary, "the newest matching variant wins")
+	assert.Equal(t, "m2", *loaded[0].ProcessingModel)
+	assert.Equal(t, legacy, *loaded[1].Summary, "articles without the language keep the default")
+
+	loaded = load(Preference{Language: "en", Model: "m1"})
+	assert.Equal(t, "English by m1", *loaded[0].Summary)
+
+	loaded = load(Preference{Language: "fr"})
+	assert.Equal(t, legacy, *loaded[0].Summary)
+}
@@ -0,0 +1,167 @@
+// Package summaryquality collects readers' ratings of AI summaries and scores each pair
+// of model and prompt variant by them. The ai-service uses the scores to choose between
+// the prompt variants an operator enabled, and phoenix-admin reports them.
+package summaryquality
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+// MaxCommentLength caps the free-text comment of a rating
+const MaxCommentLength = 1000
+
+// Rating is a reader's verdict on a summary
+type Rating struct {
+	Useful bool
+	// Hallucination flags a summary stating something the article does not say
+	Hallucination bool
+	Comment       string
+}
+
+// VariantStats are the ratings of the summaries made by one model with one prompt variant
+type VariantStats struct {
+	Model          string `json:"model"`
+	Prompt         string `json:"prompt"`
+	Ratings        int64  `json:"ratings"`
+	Useful         int64  `json:"useful"`
+	Hallucinations int64  `json:"hallucinations"`
+}
+
+// UsefulRate is the share of ratings that found the summaries useful
+func (v VariantStats) UsefulRate() float64 {
+	if v.Ratings == 0 {
+		return 0
+	}
+	return float64(v.Useful) / float64(v.Ratings)
+}
+
+// HallucinationRate is the share of ratings that flagged a hallucination
+func (v VariantStats) HallucinationRate() float64 {
+	if v.Ratings == 0 {
+		return 0
+	}
+	return float64(v.Hallucinations) / float64(v.Ratings)
+}
+
+// Evaluator scores a variant from its ratings, higher is better. Scores are only
+// compared with each other, so an evaluator may use any scale.
+type Evaluator interface {
+	Score(stats VariantStats) float64
+}
+
+// WilsonEvaluator scores a variant by the lower bound of the 95% Wilson interval of its
+// useful rate, so a variant with few ratings does not win on luck, minus its
+// hallucination rate times HallucinationPenalty
+type WilsonEvaluator struct {
+	HallucinationPenalty float64
+}
+
+// DefaultEvaluator weighs a hallucination as heavily as a useful rating
+var DefaultEvaluator Evaluator = WilsonEvaluator{HallucinationPenalty: 1}
+
+func (e WilsonEvaluator) Score(stats VariantStats) float64 {
+	if stats.Ratings == 0 {
+		return 0
+	}
+	const z = 1.96
+	n := float64(stats.Ratings)
+	p := stats.UsefulRate()
+	center := p + z*z/(2*n)
+	margin := z * math.Sqrt(p*(1-p)/n+z*z/(4*n*n))
+	lower := (center - margin) / (1 + z*z/n)
+	return lower - e.HallucinationPenalty*stats.HallucinationRate()
+}
+
+// Store keeps the ratings in the summary_feedback table
+type Store struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{db: db, now: time.Now}
+}
+
+// Rate records the user's rating of an article's current summary, replacing an earlier
+// rating of the same article. The article must be in one of the user's subscribed feeds
+// and have a summary.
+func (s *Store) Rate(ctx context.Context, userID, articleID uint, rating Rating) (*models.SummaryFeedback, error) {
+	if len(rating.Comment) > MaxCommentLength {
+		return nil, ierr.NewValidationError(fmt.Sprintf("comment must be at most %d characters", MaxCommentLength))
+	}
+
+	db := s.db.WithContext(ctx)
+	var article models.Article
+	err := db.Select("id", "feed_id", "summary", "processing_model", "processing_prompt").Take(&article, articleID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ierr.ErrArticleNotFound
+	}
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("load article %d: %w", articleID, err))
+	}
+
+	var subscribed int64
+	if err := db.Table("subscriptions").Where("user_id = ? AND feed_id = ?", userID, article.FeedID).Count(&subscribed).Error; err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("check subscription of user %d to feed %d: %w", userID, article.FeedID, err))
+	}
+	if subscribed == 0 {
+		return nil, ierr.ErrNotSubscribed
+	}
+	if article.Summary == nil || *article.Summary == "" {
+		return nil, ierr.NewValidationError("article has no summary to rate")
+	}
+
+	now := s.now().UTC()
+	feedback := &models.SummaryFeedback{
+		UserID:        userID,
+		ArticleID:     articleID,
+		Useful:        rating.Useful,
+		Hallucination: rating.Hallucination,
+		Comment:       rating.Comment,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if article.ProcessingModel != nil {
+		feedback.ProcessingModel = *article.ProcessingModel
+	}
+	if article.ProcessingPrompt != nil {
+		feedback.ProcessingPrompt = *article.ProcessingPrompt
+	}
+
+	err = db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "article_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"processing_model", "processing_prompt", "useful", "hallucination", "comment", "updated_at"}),
+	}).Create(feedback).Error
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("save rating of article %d by user %d: %w", articleID, userID, err))
+	}
+	return feedback, nil
+}
+
+// Stats aggregates the ratings given or changed since the given time by model and prompt
+// variant. The zero time counts every rating.
+func (s *Store) Stats(ctx context.Context, since time.Time) ([]VariantStats, error) {
+	var stats []VariantStats
+	err := s.db.WithContext(ctx).Model(&models.SummaryFeedback{}).
+		Select("processing_model AS model, processing_prompt AS prompt, COUNT(*) AS ratings, "+
+			"COALESCE(SUM(CASE WHEN useful THEN 1 ELSE 0 END), 0) AS useful, "+
+			"COALESCE(SUM(CASE WHEN hallucination THEN 1 ELSE 0 END), 0) AS hallucinations").
+		Where("updated_at >= ?", since).
+		Group("processing_model, processing_prompt").
+		Order("processing_model, processing_prompt").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, fmt.Errorf("aggregate summary ratings: %w", err)
+	}
+	return stats, nil
+}
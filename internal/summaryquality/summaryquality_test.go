@@ -0,0 +1,110 @@
+package summaryquality
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/feed-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+func setupStore(t *testing.T) (*Store, *gorm.DB, []*models.Article) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Feed{}, &models.Article{}, &models.Subscription{}, &models.SummaryFeedback{}))
+
+	feeds := []*models.Feed{
+		{Title: "Subscribed", URL: "https://subscribed.example.com"},
+		{Title: "Other", URL: "https://other.example.com"},
+	}
+	require.NoError(t, db.Create(feeds).Error)
+	require.NoError(t, db.Create(&models.Subscription{UserID: 1, FeedID: feeds[0].ID}).Error)
+
+	summary, model, prompt := "A summary", "gpt-test", "default"
+	articles := []*models.Article{
+		{FeedID: feeds[0].ID, URL: "https://subscribed.example.com/1", Summary: &summary, ProcessingModel: &model, ProcessingPrompt: &prompt},
+		{FeedID: feeds[0].ID, URL: "https://subscribed.example.com/2"},
+		{FeedID: feeds[1].ID, URL: "https://other.example.com/1", Summary: &summary},
+	}
+	require.NoError(t, db.Create(articles).Error)
+	return NewStore(db), db, articles
+}
+
+func TestStore_Rate(t *testing.T) {
+	store, db, articles := setupStore(t)
+	ctx := context.Background()
+
+	feedback, err := store.Rate(ctx, 1, articles[0].ID, Rating{Useful: true})
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-test", feedback.ProcessingModel)
+	assert.Equal(t, "default", feedback.ProcessingPrompt)
+
+	// the summary was regenerated with another prompt and the reader changed their mind
+	require.NoError(t, db.Model(articles[0]).Update("processing_prompt", "key_points").Error)
+	_, err = store.Rate(ctx, 1, articles[0].ID, Rating{Useful: false, Hallucination: true, Comment: "made up a date"})
+	require.NoError(t, err)
+
+	var stored []models.SummaryFeedback
+	require.NoError(t, db.Find(&stored).Error)
+	require.Len(t, stored, 1, "one rating per user and article")
+	assert.Equal(t, "key_points", stored[0].ProcessingPrompt)
+	assert.True(t, stored[0].Hallucination)
+	assert.Equal(t, "made up a date", stored[0].Comment)
+
+	_, err = store.Rate(ctx, 1, articles[1].ID, Rating{Useful: true})
+	assert.True(t, ierr.IsValidationError(err), "no summary to rate")
+	_, err = store.Rate(ctx, 1, articles[2].ID, Rating{Useful: true})
+	assert.ErrorIs(t, err, ierr.ErrNotSubscribed)
+	_, err = store.Rate(ctx, 1, 999, Rating{Useful: true})
+	assert.ErrorIs(t, err, ierr.ErrArticleNotFound)
+	_, err = store.Rate(ctx, 1, articles[0].ID, Rating{Comment: strings.Repeat("x", MaxCommentLength+1)})
+	assert.True(t, ierr.IsValidationError(err))
+}
+
+func TestStore_Stats(t *testing.T) {
+	store, db, _ := setupStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	ratings := []models.SummaryFeedback{
+		{UserID: 1, ArticleID: 1, ProcessingModel: "m", ProcessingPrompt: "default", Useful: true, UpdatedAt: now},
+		{UserID: 2, ArticleID: 1, ProcessingModel: "m", ProcessingPrompt: "default", Useful: false, Hallucination: true, UpdatedAt: now},
+		{UserID: 1, ArticleID: 2, ProcessingModel: "m", ProcessingPrompt: "key_points", Useful: true, UpdatedAt: now},
+		{UserID: 2, ArticleID: 2, ProcessingModel: "m", ProcessingPrompt: "key_points", Useful: false, UpdatedAt: now.Add(-48 * time.Hour)},
+	}
+	require.NoError(t, db.Create(&ratings).Error)
+
+	stats, err := store.Stats(ctx, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []VariantStats{
+		{Model: "m", Prompt: "default", Ratings: 2, Useful: 1, Hallucinations: 1},
+		{Model: "m", Prompt: "key_points", Ratings: 1, Useful: 1},
+	}, stats)
+
+	all, err := store.Stats(ctx, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), all[1].Ratings)
+}
+
+func TestWilsonEvaluator(t *testing.T) {
+	evaluator := WilsonEvaluator{HallucinationPenalty: 1}
+
+	assert.Zero(t, evaluator.Score(VariantStats{}))
+	lucky := evaluator.Score(VariantStats{Ratings: 2, Useful: 2})
+	proven := evaluator.Score(VariantStats{Ratings: 200, Useful: 180})
+	assert.Greater(t, proven, lucky, "many ratings beat a lucky few")
+
+	clean := evaluator.Score(VariantStats{Ratings: 100, Useful: 80})
+	flagged := evaluator.Score(VariantStats{Ratings: 100, Useful: 80, Hallucinations: 10})
+	assert.InDelta(t, 0.1, clean-flagged, 1e-9)
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/user-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/httpclient"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/secrets"
+)
+
+const apiKeyHintLength = 4
+
+type CredentialServiceInterface interface {
+	SetLLMCredential(userID uint, baseURL, model, apiKey string) (*models.LLMCredential, error)
+	GetLLMCredential(userID uint) (*models.LLMCredential, error)
+	DeleteLLMCredential(userID uint) error
+	GetLLMUsage(userID uint, since time.Time) ([]models.LLMUsageSummary, error)
+}
+
+// CredentialService manages per-user LLM credentials (bring-your-own-key)
+type CredentialService struct {
+	credentialRepo *repository.CredentialRepository
+	cipher         *secrets.Cipher
+}
+
+func NewCredentialService(credentialRepo *repository.CredentialRepository, cipher *secrets.Cipher) *CredentialService {
+	return &CredentialService{
+		credentialRepo: credentialRepo,
+		cipher:         cipher,
+	}
+}
+
+func (s *CredentialService) SetLLMCredential(userID uint, baseURL, model, apiKey string) (*models.LLMCredential, error) {
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return nil, ierr.NewValidationError("api_key is required")
+	}
+
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if baseURL != "" {
+		parsed, err := url.Parse(baseURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, ierr.NewValidationError("base_url must be an absolute http(s) URL")
+		}
+		if privateHost(parsed.Hostname()) {
+			return nil, ierr.NewValidationError("base_url must be a public host")
+		}
+	}
+
+	ciphertext, err := s.cipher.Encrypt(apiKey)
+	if err != nil {
+		return nil, ierr.NewInternalError(fmt.Errorf("failed to encrypt LLM credential for user %d: %w", userID, err))
+	}
+
+	credential := &models.LLMCredential{
+		UserID:           userID,
+		BaseURL:          baseURL,
+		Model:            strings.TrimSpace(model),
+		APIKeyCiphertext: ciphertext,
+		APIKeyHint:       keyHint(apiKey),
+	}
+
+	saved, err := s.credentialRepo.Upsert(credential)
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to save LLM credential for user %d: %w", userID, err))
+	}
+
+	return saved, nil
+}
+
+// privateHost reports whether host names this machine or is an address on a private,
+// loopback or link-local network. Names that resolve to such addresses are refused when
+// the LLM client connects.
+func privateHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	addr, err := netip.ParseAddr(host)
+	return err == nil && httpclient.BlockPrivateNetworks(addr) != nil
+}
+
+func (s *CredentialService) GetLLMCredential(userID uint) (*models.LLMCredential, error) {
+	credential, err := s.credentialRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to get LLM credential for user %d: %w", userID, err))
+	}
+	if credential == nil {
+		return nil, fmt.Errorf("no LLM credential for user %d: %w", userID, ierr.ErrCredentialNotFound)
+	}
+	return credential, nil
+}
+
+func (s *CredentialService) DeleteLLMCredential(userID uint) error {
+	deleted, err := s.credentialRepo.Delete(userID)
+	if err != nil {
+		return ierr.NewDatabaseError(fmt.Errorf("failed to delete LLM credential for user %d: %w", userID, err))
+	}
+	if !deleted {
+		return fmt.Errorf("no LLM credential for user %d: %w", userID, ierr.ErrCredentialNotFound)
+	}
+	return nil
+}
+
+func (s *CredentialService) GetLLMUsage(userID uint, since time.Time) ([]models.LLMUsageSummary, error) {
+	summaries, err := s.credentialRepo.SummarizeUsage(userID, since)
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to summarize LLM usage for user %d: %w", userID, err))
+	}
+	return summaries, nil
+}
+
+// keyHint keeps the trailing characters of a key so users can tell keys apart
+func keyHint(apiKey string) string {
+	if len(apiKey) <= apiKeyHintLength {
+		return ""
+	}
+	return "..." + apiKey[len(apiKey)-apiKeyHintLength:]
+}
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/user-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/mailer"
+)
+
+const (
+	// passwordResetInterval is how soon after a reset email another one can be sent to
+	// the same user; requests in between are dropped
+	passwordResetInterval = time.Minute
+	// passwordResetMailTimeout bounds the delivery of a reset email
+	passwordResetMailTimeout = 30 * time.Second
+)
+
+// PasswordResetOptions configures password resets
+type PasswordResetOptions struct {
+	// TTL is how long a reset token works
+	TTL time.Duration
+	// URL is the page the emailed link opens, with the token added as its token query
+	// parameter. Without it the email carries the bare token.
+	URL string
+}
+
+// SetPasswordResets enables password resets, mailing their tokens with mail
+func (s *UserService) SetPasswordResets(resets *repository.PasswordResetRepository, mail mailer.Mailer, options PasswordResetOptions) {
+	s.resets = resets
+	s.mail = mail
+	s.resetOpts = options
+}
+
+// RequestPasswordReset mails a reset token to the user with the email. So that the
+// answer tells nothing about which addresses have accounts, it succeeds without sending
+// anything for unknown addresses and the email is sent in the background.
+func (s *UserService) RequestPasswordReset(email, ip string) error {
+	log := logger.FromContext(context.Background())
+	if s.resets == nil {
+		return ierr.NewInternalError(fmt.Errorf("password resets are not enabled"))
+	}
+
+	normalized, err := models.NormalizeEmail(email)
+	if err != nil {
+		return ierr.NewValidationError(err.Error())
+	}
+	if normalized == nil {
+		return ierr.NewValidationError("email is required")
+	}
+
+	user, err := s.userRepo.GetByEmail(*normalized)
+	if err != nil {
+		return ierr.NewDatabaseError(fmt.Errorf("failed to look up user by email: %w", err))
+	}
+	if user == nil {
+		log.Info("password reset requested for unknown email", "ip", ip)
+		return nil
+	}
+
+	now := time.Now().UTC()
+	latest, err := s.resets.LatestCreatedAt(user.ID)
+	if err != nil {
+		return ierr.NewDatabaseError(fmt.Errorf("failed to get last password reset of user %d: %w", user.ID, err))
+	}
+	if now.Sub(latest) < passwordResetInterval {
+		log.Info("password reset requested again too soon", "user_id", user.ID, "ip", ip)
+		return nil
+	}
+
+	token, hash, err := newSecretToken()
+	if err != nil {
+		return ierr.NewInternalError(fmt.Errorf("failed to generate password reset token for user %d: %w", user.ID, err))
+	}
+	reset := &models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hash,
+		IP:        ip,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.resetOpts.TTL),
+	}
+	if err := s.resets.Replace(reset); err != nil {
+		return ierr.NewDatabaseError(fmt.Errorf("failed to store password reset of user %d: %w", user.ID, err))
+	}
+
+	go s.sendPasswordReset(user, token, reset.ExpiresAt)
+	log.Info("password reset requested", "user_id", user.ID, "ip", ip)
+	return nil
+}
+
+// sendPasswordReset mails the reset token to the user. A failure is logged; the user can
+// ask again.
+func (s *UserService) sendPasswordReset(user *models.User, token string, expiresAt time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordResetMailTimeout)
+	defer cancel()
+
+	err := s.mail.Send(ctx, mailer.Message{
+		To:      []string{*user.Email},
+		Subject: "Reset your Phoenix RSS password",
+		Body:    s.passwordResetText(user, token, expiresAt),
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("failed to send password reset email", "user_id", user.ID, "error", err.Error())
+	}
+}
+
+// passwordResetText renders the body of a reset email
+func (s *UserService) passwordResetText(user *models.User, token string, expiresAt time.Time) string {
+	name := user.DisplayName
+	if name == "" {
+		name = user.Username
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hi %s,\n\n", name)
+	fmt.Fprintf(&b, "Someone, hopefully you, asked to reset the password of the Phoenix RSS account %s.\n", user.Username)
+	if link, err := url.Parse(s.resetOpts.URL); err == nil && s.resetOpts.URL != "" {
+		query := link.Query()
+		query.Set("token", token)
+		link.RawQuery = query.Encode()
+		fmt.Fprintf(&b, "To choose a new password, open:\n\n  %s\n\n", link)
+	} else {
+		fmt.Fprintf(&b, "To choose a new password, use this reset token:\n\n  %s\n\n", token)
+	}
+	fmt.Fprintf(&b, "It works once, until %s UTC.\n\n", expiresAt.UTC().Format("2006-01-02 15:04"))
+	b.WriteString("If you did not ask for this, ignore this email; your password stays as it is.\n")
+	return b.String()
+}
+
+// ResetPassword sets a new password with a reset token and signs out every session of
+// the user. It returns the user and how many sessions were signed out.
+func (s *UserService) ResetPassword(token, newPassword string) (*models.User, int64, error) {
+	if s.resets == nil {
+		return nil, 0, ierr.NewInternalError(fmt.Errorf("password resets are not enabled"))
+	}
+	if len(newPassword) < models.MinPasswordLength {
+		return nil, 0, ierr.NewValidationError(fmt.Sprintf("new password must be at least %d characters", models.MinPasswordLength))
+	}
+
+	now := time.Now().UTC()
+	reset, err := s.resets.GetByTokenHash(hashSecretToken(token))
+	if err != nil {
+		return nil, 0, ierr.NewDatabaseError(fmt.Errorf("failed to look up password reset: %w", err))
+	}
+	if reset == nil || reset.UsedAt != nil || !reset.ExpiresAt.After(now) {
+		return nil, 0, fmt.Errorf("unusable password reset token: %w", ierr.ErrInvalidResetToken)
+	}
+
+	user, err := s.userRepo.GetByID(reset.UserID)
+	if err != nil {
+		return nil, 0, ierr.NewDatabaseError(fmt.Errorf("failed to get user %d: %w", reset.UserID, err))
+	}
+	if user == nil {
+		return nil, 0, fmt.Errorf("password reset of deleted user %d: %w", reset.UserID, ierr.ErrInvalidResetToken)
+	}
+
+	hash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return nil, 0, ierr.NewInternalError(fmt.Errorf("failed to hash password for user %d: %w", user.ID, err))
+	}
+
+	var revoked int64
+	err = s.uow.Do(context.Background(), func(tx *gorm.DB) error {
+		used, err := s.resets.WithTx(tx).MarkUsed(reset.ID, now)
+		if err != nil {
+			return ierr.NewDatabaseError(fmt.Errorf("failed to use password reset %d: %w", reset.ID, err))
+		}
+		if !used {
+			// used by a concurrent request since it was looked up
+			return fmt.Errorf("password reset %d already used: %w", reset.ID, ierr.ErrInvalidResetToken)
+		}
+
+		replaced, err := s.userRepo.WithTx(tx).UpdatePasswordHash(user.ID, user.PasswordHash, hash)
+		if err != nil {
+			return ierr.NewDatabaseError(fmt.Errorf("failed to reset password of user %d: %w", user.ID, err))
+		}
+		if !replaced {
+			// the password changed since the user was loaded; the token stays usable
+			return fmt.Errorf("password of user %d changed concurrently: %w", user.ID, ierr.ErrInvalidResetToken)
+		}
+
+		// whoever knew the old password must not stay signed in
+		revoked, err = s.sessionRepo.WithTx(tx).RevokeOthers(user.ID, "", now)
+		if err != nil {
+			return ierr.NewDatabaseError(fmt.Errorf("failed to revoke sessions of user %d: %w", user.ID, err))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	logger.FromContext(context.Background()).Info("password reset", "user_id", user.ID, "revoked_sessions", revoked)
+	return user, revoked, nil
+}
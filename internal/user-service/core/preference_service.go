@@ -0,0 +1,111 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+	"github.com/Fancu1/phoenix-rss/internal/user-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+)
+
+type PreferenceServiceInterface interface {
+	GetPreferences(userID uint) (*models.UserPreferences, error)
+	UpdatePreferences(userID uint, update models.PreferencesUpdate) (*models.UserPreferences, error)
+}
+
+// PreferenceService keeps per-user reading preferences
+type PreferenceService struct {
+	preferenceRepo *repository.PreferenceRepository
+}
+
+func NewPreferenceService(preferenceRepo *repository.PreferenceRepository) *PreferenceService {
+	return &PreferenceService{
+		preferenceRepo: preferenceRepo,
+	}
+}
+
+// GetPreferences returns the user's preferences, the defaults when they never changed them
+func (s *PreferenceService) GetPreferences(userID uint) (*models.UserPreferences, error) {
+	preferences, err := s.preferenceRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to get preferences for user %d: %w", userID, err))
+	}
+	if preferences == nil {
+		return models.DefaultUserPreferences(userID), nil
+	}
+	return preferences, nil
+}
+
+// UpdatePreferences changes the preferences set in update and keeps the others
+func (s *PreferenceService) UpdatePreferences(userID uint, update models.PreferencesUpdate) (*models.UserPreferences, error) {
+	preferences, err := s.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.DefaultSort != nil {
+		if preferences.DefaultSort, err = oneOf("default_sort", *update.DefaultSort, models.SortRecent, models.SortSmart); err != nil {
+			return nil, err
+		}
+	}
+	if update.ShowRead != nil {
+		preferences.ShowRead = *update.ShowRead
+	}
+	if update.ListContent != nil {
+		if preferences.ListContent, err = oneOf("list_content", *update.ListContent, models.ListContentExcerpt, models.ListContentFull); err != nil {
+			return nil, err
+		}
+	}
+	if update.Theme != nil {
+		if preferences.Theme, err = oneOf("theme", *update.Theme, models.ThemeSystem, models.ThemeLight, models.ThemeDark); err != nil {
+			return nil, err
+		}
+	}
+	if update.DefaultView != nil {
+		if preferences.DefaultView, err = oneOf("default_view", *update.DefaultView, models.ViewAll, models.ViewUnread, models.ViewStarred); err != nil {
+			return nil, err
+		}
+	}
+	if update.ItemsPerPage != nil {
+		if *update.ItemsPerPage < models.MinItemsPerPage || *update.ItemsPerPage > models.MaxItemsPerPage {
+			return nil, ierr.NewValidationError(fmt.Sprintf("items_per_page must be between %d and %d", models.MinItemsPerPage, models.MaxItemsPerPage))
+		}
+		preferences.ItemsPerPage = *update.ItemsPerPage
+	}
+	if update.SummaryLanguage != nil {
+		if preferences.SummaryLanguage, err = languageCode(*update.SummaryLanguage); err != nil {
+			return nil, err
+		}
+	}
+
+	saved, err := s.preferenceRepo.Upsert(preferences)
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to save preferences for user %d: %w", userID, err))
+	}
+	return saved, nil
+}
+
+// languageCode returns a lower-cased language code such as zh or pt-br, empty for none
+func languageCode(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if len(value) > models.MaxSummaryLanguageLength {
+		return "", ierr.NewValidationError(fmt.Sprintf("summary_language must be at most %d characters", models.MaxSummaryLanguageLength))
+	}
+	for _, r := range value {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return "", ierr.NewValidationError("summary_language must be a language code such as zh or pt-br")
+		}
+	}
+	return value, nil
+}
+
+// oneOf returns value, lower-cased, when it is one of allowed
+func oneOf(field, value string, allowed ...string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if !slices.Contains(allowed, value) {
+		return "", ierr.NewValidationError(fmt.Sprintf("%s must be one of %s", field, strings.Join(allowed, ", ")))
+	}
+	return value, nil
+}
@@ -1,96 +1,547 @@
 package core
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 
 	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
 	"github.com/Fancu1/phoenix-rss/internal/user-service/repository"
+	"github.com/Fancu1/phoenix-rss/pkg/dbtx"
 	"github.com/Fancu1/phoenix-rss/pkg/ierr"
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/mailer"
+	"github.com/Fancu1/phoenix-rss/pkg/password"
 )
 
 type UserServiceInterface interface {
 	Register(username, password string) (*models.User, error)
-	Login(username, password string) (string, error)
+	Login(username, password string, client models.SessionClient) (*models.AuthTokens, error)
+	RefreshToken(refreshToken string, client models.SessionClient) (*models.AuthTokens, error)
 	ValidateToken(tokenString string) (*jwt.Token, error)
 	GetUserFromToken(tokenString string) (*models.User, error)
+	ListSessions(userID uint) ([]models.Session, error)
+	RevokeSession(userID uint, sessionID string) error
+	RevokeOtherSessions(userID uint, currentSessionID string) (int64, error)
+	GetProfile(userID uint) (*models.User, error)
+	UpdateProfile(userID uint, update models.ProfileUpdate) (*models.User, error)
+	ChangePassword(userID uint, currentPassword, newPassword, keepSessionID string) (int64, error)
+	RequestPasswordReset(email, ip string) error
+	ResetPassword(token, newPassword string) (*models.User, int64, error)
+	ListUsers(afterID uint, limit int) ([]models.User, int64, error)
+	SetUserRole(userID uint, role string) (*models.User, error)
+	Impersonate(userID, impersonatorID uint, impersonator string) (*models.User, *models.AuthTokens, error)
 }
 
+const (
+	// defaultAccessTokenTTL is how long an access token lasts before it must be refreshed
+	defaultAccessTokenTTL = 15 * time.Minute
+	// defaultSessionTTL is how long a session lasts since its last refresh
+	defaultSessionTTL = 7 * 24 * time.Hour
+	// defaultImpersonationTTL is how long an impersonation token lasts
+	defaultImpersonationTTL = 15 * time.Minute
+	// maxUserAgentLength matches the user_sessions table
+	maxUserAgentLength = 512
+)
+
 type UserService struct {
-	userRepo  *repository.UserRepository
-	jwtSecret []byte
+	userRepo    *repository.UserRepository
+	sessionRepo *repository.SessionRepository
+	jwtSecret   []byte
+	hasher      *password.Hasher
+	uow         *dbtx.UnitOfWork
+	accessTTL   time.Duration
+	sessionTTL  time.Duration
+	resets      *repository.PasswordResetRepository
+	mail        mailer.Mailer
+	resetOpts   PasswordResetOptions
+	impTTL      time.Duration
 }
 
-func NewUserService(userRepo *repository.UserRepository, jwtSecret string) *UserService {
+func NewUserService(userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository, jwtSecret string) *UserService {
+	hasher, _ := password.NewHasher(password.DefaultParams)
 	return &UserService{
-		userRepo:  userRepo,
-		jwtSecret: []byte(jwtSecret),
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		jwtSecret:   []byte(jwtSecret),
+		hasher:      hasher,
+		accessTTL:   defaultAccessTokenTTL,
+		sessionTTL:  defaultSessionTTL,
+		impTTL:      defaultImpersonationTTL,
 	}
 }
 
-func (s *UserService) Register(username, password string) (*models.User, error) {
-	// check if user already exists
-	existingUser, err := s.userRepo.GetByUsername(username)
-	if err != nil {
-		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to check existing user '%s': %w", username, err))
-	}
-	if existingUser != nil {
-		return nil, fmt.Errorf("user '%s' already exists: %w", username, ierr.ErrUserExists)
-	}
+// SetTokenTTLs sets how long access tokens last and how long an unused session, and so
+// its refresh token, stays open; the defaults are 15 minutes and 7 days
+func (s *UserService) SetTokenTTLs(accessTTL, sessionTTL time.Duration) {
+	s.accessTTL = accessTTL
+	s.sessionTTL = sessionTTL
+}
+
+// SetImpersonationTTL sets how long the tokens administrators impersonate users with
+// last; the default is 15 minutes
+func (s *UserService) SetImpersonationTTL(ttl time.Duration) {
+	s.impTTL = ttl
+}
+
+// SetPasswordHasher replaces how passwords are hashed; the default is argon2id with
+// password.DefaultParams
+func (s *UserService) SetPasswordHasher(hasher *password.Hasher) {
+	s.hasher = hasher
+}
 
+// SetUnitOfWork makes writes that span several steps, such as registering a user, run in
+// one transaction
+func (s *UserService) SetUnitOfWork(uow *dbtx.UnitOfWork) {
+	s.uow = uow
+}
+
+func (s *UserService) Register(username, plaintext string) (*models.User, error) {
 	// hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := s.hasher.Hash(plaintext)
 	if err != nil {
 		return nil, ierr.NewInternalError(fmt.Errorf("failed to hash password for user '%s': %w", username, err))
 	}
 
-	// create user
-	user := &models.User{
-		Username:     username,
-		PasswordHash: string(hashedPassword),
-	}
+	var createdUser *models.User
+	err = s.uow.Do(context.Background(), func(tx *gorm.DB) error {
+		users := s.userRepo.WithTx(tx)
+
+		// check if user already exists
+		existingUser, err := users.GetByUsername(username)
+		if err != nil {
+			return ierr.NewDatabaseError(fmt.Errorf("failed to check existing user '%s': %w", username, err))
+		}
+		if existingUser != nil {
+			return fmt.Errorf("user '%s' already exists: %w", username, ierr.ErrUserExists)
+		}
+
+		// create user
+		user := &models.User{
+			Username:     username,
+			PasswordHash: hashedPassword,
+			Role:         models.RoleUser,
+		}
 
-	createdUser, err := s.userRepo.Create(user)
+		createdUser, err = users.Create(user)
+		if err != nil {
+			return ierr.NewDatabaseError(fmt.Errorf("failed to create user '%s': %w", username, err))
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to create user '%s': %w", username, err))
+		return nil, err
 	}
 
 	return createdUser, nil
 }
 
-func (s *UserService) Login(username, password string) (string, error) {
+// Login opens a session for the client and returns an access token bound to it, along
+// with the session's refresh token
+func (s *UserService) Login(username, plaintext string, client models.SessionClient) (*models.AuthTokens, error) {
 	// get user
 	user, err := s.userRepo.GetByUsername(username)
 	if err != nil {
-		return "", ierr.NewDatabaseError(fmt.Errorf("failed to get user '%s': %w", username, err))
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to get user '%s': %w", username, err))
 	}
 	if user == nil {
-		return "", fmt.Errorf("login failed for user '%s': %w", username, ierr.ErrInvalidCredentials)
+		return nil, fmt.Errorf("login failed for user '%s': %w", username, ierr.ErrInvalidCredentials)
 	}
 
 	// verify password
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+	rehash, err := s.hasher.Verify(plaintext, user.PasswordHash)
+	if errors.Is(err, password.ErrMismatch) {
+		return nil, fmt.Errorf("password verification failed for user '%s': %w", username, ierr.ErrInvalidCredentials)
+	}
+	if err != nil {
+		return nil, ierr.NewInternalError(fmt.Errorf("failed to verify password of user '%s' (ID: %d): %w", username, user.ID, err))
+	}
+	if rehash {
+		s.rehashPassword(user, plaintext)
+	}
+
+	refreshToken, refreshHash, err := newSecretToken()
+	if err != nil {
+		return nil, ierr.NewInternalError(fmt.Errorf("failed to generate refresh token for user %d: %w", user.ID, err))
+	}
+	session, err := s.openSession(user.ID, client, refreshHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, session, refreshToken)
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a new refresh token,
+// extending the session. Each refresh token works once: presenting one that was already
+// exchanged means it leaked, so the session is revoked.
+func (s *UserService) RefreshToken(refreshToken string, client models.SessionClient) (*models.AuthTokens, error) {
+	log := logger.FromContext(context.Background())
+	hash := hashSecretToken(refreshToken)
+
+	session, err := s.sessionRepo.GetByRefreshHash(hash)
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to look up refresh token: %w", err))
+	}
+	if session == nil {
+		return nil, fmt.Errorf("unknown refresh token: %w", ierr.ErrInvalidToken)
+	}
+
+	now := time.Now().UTC()
+	if session.RevokedAt != nil || !session.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("refresh token of ended session %s: %w", session.ID, ierr.ErrInvalidToken)
+	}
+	if session.RefreshTokenHash == nil || *session.RefreshTokenHash != hash {
+		return nil, s.revokeReusedSession(session, client)
+	}
+
+	user, err := s.userRepo.GetByID(session.UserID)
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to get user %d: %w", session.UserID, err))
+	}
+	if user == nil {
+		return nil, fmt.Errorf("refresh token of deleted user %d: %w", session.UserID, ierr.ErrInvalidToken)
+	}
+
+	newToken, newHash, err := newSecretToken()
+	if err != nil {
+		return nil, ierr.NewInternalError(fmt.Errorf("failed to generate refresh token for user %d: %w", user.ID, err))
+	}
+	expiresAt := now.Add(s.sessionTTL)
+	rotated, err := s.sessionRepo.RotateRefreshToken(session.ID, hash, newHash, now, expiresAt)
 	if err != nil {
-		return "", fmt.Errorf("password verification failed for user '%s': %w", username, ierr.ErrInvalidCredentials)
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to rotate refresh token of session %s: %w", session.ID, err))
+	}
+	if !rotated {
+		// a concurrent refresh with the same token got there first
+		return nil, s.revokeReusedSession(session, client)
+	}
+	session.LastSeenAt = now
+	session.ExpiresAt = expiresAt
+
+	log.Debug("refreshed session", "user_id", user.ID, "session_id", session.ID)
+	return s.issueTokens(user, session, newToken)
+}
+
+// revokeReusedSession ends the session of a refresh token that was presented again after
+// being exchanged, and returns the error to answer with
+func (s *UserService) revokeReusedSession(session *models.Session, client models.SessionClient) error {
+	log := logger.FromContext(context.Background())
+	log.Warn("refresh token reused, revoking session", "user_id", session.UserID, "session_id", session.ID, "ip", client.IP)
+	if _, err := s.sessionRepo.Revoke(session.UserID, session.ID, time.Now().UTC()); err != nil {
+		log.Error("failed to revoke session of reused refresh token", "session_id", session.ID, "error", err.Error())
+	}
+	return fmt.Errorf("refresh token of session %s was already used: %w", session.ID, ierr.ErrInvalidToken)
+}
+
+// issueTokens signs an access token for the session. It expires after the access token
+// TTL, or with the session if that comes first.
+func (s *UserService) issueTokens(user *models.User, session *models.Session, refreshToken string) (*models.AuthTokens, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.accessTTL)
+	if session.ExpiresAt.Before(expiresAt) {
+		expiresAt = session.ExpiresAt
 	}
 
-	// generate JWT token
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id":  user.ID,
 		"username": user.Username,
-		"exp":      time.Now().Add(time.Hour * 24 * 7).Unix(), // 7 days
-		"iat":      time.Now().Unix(),
+		"role":     user.Role,
+		"sid":      session.ID,
+		"exp":      expiresAt.Unix(),
+		"iat":      now.Unix(),
+	})
+
+	tokenString, err := token.SignedString(s.jwtSecret)
+	if err != nil {
+		return nil, ierr.NewInternalError(fmt.Errorf("failed to generate token for user '%s' (ID: %d): %w", user.Username, user.ID, err))
+	}
+
+	return &models.AuthTokens{
+		AccessToken:  tokenString,
+		ExpiresAt:    expiresAt,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// newSecretToken returns a random token, such as a refresh token, and the hash it is
+// stored as
+func newSecretToken() (token, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(raw)
+	return token, hashSecretToken(token), nil
+}
+
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// rehashPassword upgrades a legacy hash while the password is known. A failure only
+// postpones the upgrade to the next login.
+func (s *UserService) rehashPassword(user *models.User, plaintext string) {
+	log := logger.FromContext(context.Background())
+	hash, err := s.hasher.Hash(plaintext)
+	if err == nil {
+		_, err = s.userRepo.UpdatePasswordHash(user.ID, user.PasswordHash, hash)
+	}
+	if err != nil {
+		log.Warn("failed to rehash password", "user_id", user.ID, "error", err.Error())
+		return
+	}
+	log.Info("rehashed password", "user_id", user.ID, "from", password.Describe(user.PasswordHash), "to", password.Describe(hash))
+	user.PasswordHash = hash
+}
+
+func (s *UserService) openSession(userID uint, client models.SessionClient, refreshHash string) (*models.Session, error) {
+	return s.createSession(userID, client, s.sessionTTL, &refreshHash)
+}
+
+// createSession stores a session lasting ttl; one without a refresh hash cannot be
+// refreshed
+func (s *UserService) createSession(userID uint, client models.SessionClient, ttl time.Duration, refreshHash *string) (*models.Session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, ierr.NewInternalError(fmt.Errorf("failed to generate session ID for user %d: %w", userID, err))
+	}
+	userAgent := client.UserAgent
+	if runes := []rune(userAgent); len(runes) > maxUserAgentLength {
+		userAgent = string(runes[:maxUserAgentLength])
+	}
+
+	now := time.Now().UTC()
+	session := &models.Session{
+		ID:         hex.EncodeToString(id),
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         client.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(ttl),
+		// the hash is stored, never the token
+		RefreshTokenHash: refreshHash,
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to create session for user %d: %w", userID, err))
+	}
+	return session, nil
+}
+
+func (s *UserService) ListSessions(userID uint) ([]models.Session, error) {
+	sessions, err := s.sessionRepo.ListActive(userID, time.Now().UTC())
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to list sessions of user %d: %w", userID, err))
+	}
+	return sessions, nil
+}
+
+func (s *UserService) RevokeSession(userID uint, sessionID string) error {
+	revoked, err := s.sessionRepo.Revoke(userID, sessionID, time.Now().UTC())
+	if err != nil {
+		return ierr.NewDatabaseError(fmt.Errorf("failed to revoke session of user %d: %w", userID, err))
+	}
+	if !revoked {
+		return fmt.Errorf("no active session %s for user %d: %w", sessionID, userID, ierr.ErrSessionNotFound)
+	}
+	return nil
+}
+
+// RevokeOtherSessions ends every active session of the user except currentSessionID
+func (s *UserService) RevokeOtherSessions(userID uint, currentSessionID string) (int64, error) {
+	revoked, err := s.sessionRepo.RevokeOthers(userID, currentSessionID, time.Now().UTC())
+	if err != nil {
+		return 0, ierr.NewDatabaseError(fmt.Errorf("failed to revoke other sessions of user %d: %w", userID, err))
+	}
+	return revoked, nil
+}
+
+// GetProfile returns the user's account with its profile fields
+func (s *UserService) GetProfile(userID uint) (*models.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to get user %d: %w", userID, err))
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %d: %w", userID, ierr.ErrUserNotFound)
+	}
+	return user, nil
+}
+
+// UpdateProfile changes the profile fields set in update. An email can belong to one
+// user only.
+func (s *UserService) UpdateProfile(userID uint, update models.ProfileUpdate) (*models.User, error) {
+	var user *models.User
+	err := s.uow.Do(context.Background(), func(tx *gorm.DB) error {
+		users := s.userRepo.WithTx(tx)
+
+		var err error
+		if user, err = users.GetByID(userID); err != nil {
+			return ierr.NewDatabaseError(fmt.Errorf("failed to get user %d: %w", userID, err))
+		}
+		if user == nil {
+			return fmt.Errorf("user %d: %w", userID, ierr.ErrUserNotFound)
+		}
+
+		if update.Username != nil {
+			if user.Username, err = models.NormalizeUsername(*update.Username); err != nil {
+				return ierr.NewValidationError(err.Error())
+			}
+			taken, err := users.UsernameTaken(user.Username, userID)
+			if err != nil {
+				return ierr.NewDatabaseError(fmt.Errorf("failed to check username of user %d: %w", userID, err))
+			}
+			if taken {
+				return fmt.Errorf("user %d renaming to '%s': %w", userID, user.Username, ierr.ErrUserExists)
+			}
+		}
+		if update.DisplayName != nil {
+			if user.DisplayName, err = models.NormalizeDisplayName(*update.DisplayName); err != nil {
+				return ierr.NewValidationError(err.Error())
+			}
+		}
+		if update.Email != nil {
+			if user.Email, err = models.NormalizeEmail(*update.Email); err != nil {
+				return ierr.NewValidationError(err.Error())
+			}
+			if user.Email != nil {
+				taken, err := users.EmailTaken(*user.Email, userID)
+				if err != nil {
+					return ierr.NewDatabaseError(fmt.Errorf("failed to check email of user %d: %w", userID, err))
+				}
+				if taken {
+					return fmt.Errorf("user %d: %w", userID, ierr.ErrEmailTaken)
+				}
+			}
+		}
+
+		if err := users.UpdateProfile(user); err != nil {
+			return ierr.NewDatabaseError(fmt.Errorf("failed to update profile of user %d: %w", userID, err))
+		}
+		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ListUsers returns up to limit users with an ID above afterID, in ID order, and how many
+// users there are
+func (s *UserService) ListUsers(afterID uint, limit int) ([]models.User, int64, error) {
+	users, err := s.userRepo.List(afterID, limit)
+	if err != nil {
+		return nil, 0, ierr.NewDatabaseError(fmt.Errorf("failed to list users after %d: %w", afterID, err))
+	}
+	total, err := s.userRepo.Count()
+	if err != nil {
+		return nil, 0, ierr.NewDatabaseError(fmt.Errorf("failed to count users: %w", err))
+	}
+	return users, total, nil
+}
+
+// SetUserRole makes the user a regular user or an administrator. The API checks the
+// stored role on every admin request, so the change applies to tokens already issued.
+func (s *UserService) SetUserRole(userID uint, role string) (*models.User, error) {
+	role, err := models.ParseRole(role)
+	if err != nil {
+		return nil, ierr.NewValidationError(err.Error())
+	}
+	updated, err := s.userRepo.UpdateRole(userID, role)
+	if err != nil {
+		return nil, ierr.NewDatabaseError(fmt.Errorf("failed to set role of user %d: %w", userID, err))
+	}
+	if !updated {
+		return nil, fmt.Errorf("user %d: %w", userID, ierr.ErrUserNotFound)
+	}
+	return s.GetProfile(userID)
+}
+
+// Impersonate signs a token for the administrator to see the API as the user does. It
+// carries the administrator in its imp_by claims, which the API flags and keeps read-only.
+// It belongs to a session of its own that lasts the impersonation TTL and has no refresh
+// token, so it shows in the user's sessions and is ended by revoking it or signing out
+// everywhere. Administrators cannot be impersonated, nor can one impersonate oneself.
+func (s *UserService) Impersonate(userID, impersonatorID uint, impersonator string) (*models.User, *models.AuthTokens, error) {
+	if userID == impersonatorID {
+		return nil, nil, ierr.NewValidationError("cannot impersonate yourself")
+	}
+	user, err := s.GetProfile(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Role == models.RoleAdmin {
+		return nil, nil, fmt.Errorf("user %d is an administrator and cannot be impersonated: %w", userID, ierr.ErrForbidden)
+	}
 
+	session, err := s.createSession(user.ID, models.SessionClient{UserAgent: "impersonation by " + impersonator}, s.impTTL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	expiresAt := session.ExpiresAt
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":   user.ID,
+		"username":  user.Username,
+		"role":      user.Role,
+		"sid":       session.ID,
+		"imp_by":    impersonator,
+		"imp_by_id": impersonatorID,
+		"exp":       expiresAt.Unix(),
+		"iat":       now.Unix(),
+	})
 	tokenString, err := token.SignedString(s.jwtSecret)
 	if err != nil {
-		return "", ierr.NewInternalError(fmt.Errorf("failed to generate token for user '%s' (ID: %d): %w", username, user.ID, err))
+		return nil, nil, ierr.NewInternalError(fmt.Errorf("failed to generate impersonation token for user %d: %w", userID, err))
+	}
+	return user, &models.AuthTokens{AccessToken: tokenString, ExpiresAt: expiresAt}, nil
+}
+
+// ChangePassword replaces the user's password after checking the current one, then signs
+// out every other session, which may have been opened with the old password. It returns
+// how many sessions were signed out.
+func (s *UserService) ChangePassword(userID uint, currentPassword, newPassword, keepSessionID string) (int64, error) {
+	user, err := s.GetProfile(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := s.hasher.Verify(currentPassword, user.PasswordHash); errors.Is(err, password.ErrMismatch) {
+		return 0, fmt.Errorf("password change of user %d: %w", userID, ierr.ErrWrongPassword)
+	} else if err != nil {
+		return 0, ierr.NewInternalError(fmt.Errorf("failed to verify password of user %d: %w", userID, err))
+	}
+	if len(newPassword) < models.MinPasswordLength {
+		return 0, ierr.NewValidationError(fmt.Sprintf("new password must be at least %d characters", models.MinPasswordLength))
+	}
+	if newPassword == currentPassword {
+		return 0, ierr.NewValidationError("new password must differ from the current one")
+	}
+
+	hash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return 0, ierr.NewInternalError(fmt.Errorf("failed to hash password for user %d: %w", userID, err))
+	}
+	replaced, err := s.userRepo.UpdatePasswordHash(userID, user.PasswordHash, hash)
+	if err != nil {
+		return 0, ierr.NewDatabaseError(fmt.Errorf("failed to change password of user %d: %w", userID, err))
+	}
+	if !replaced {
+		// changed by another request since it was verified
+		return 0, fmt.Errorf("password of user %d changed concurrently: %w", userID, ierr.ErrWrongPassword)
 	}
 
-	return tokenString, nil
+	return s.RevokeOtherSessions(userID, keepSessionID)
 }
 
 func (s *UserService) ValidateToken(tokenString string) (*jwt.Token, error) {
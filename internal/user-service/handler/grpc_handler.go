@@ -5,26 +5,37 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"github.com/Fancu1/phoenix-rss/internal/user-service/core"
+	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
 	"github.com/Fancu1/phoenix-rss/pkg/ierr"
 	userpb "github.com/Fancu1/phoenix-rss/protos/gen/go/user"
 )
 
 type UserServiceHandler struct {
 	userpb.UnimplementedUserServiceServer
-	userService core.UserServiceInterface
+	userService       core.UserServiceInterface
+	credentialService core.CredentialServiceInterface
+	preferenceService core.PreferenceServiceInterface
 }
 
-func NewUserServiceHandler(userService core.UserServiceInterface) *UserServiceHandler {
+func NewUserServiceHandler(userService core.UserServiceInterface, credentialService core.CredentialServiceInterface) *UserServiceHandler {
 	return &UserServiceHandler{
-		userService: userService,
+		userService:       userService,
+		credentialService: credentialService,
 	}
 }
 
+// SetPreferenceService serves the reading preferences; without it their RPCs are
+// unimplemented
+func (h *UserServiceHandler) SetPreferenceService(preferenceService core.PreferenceServiceInterface) {
+	h.preferenceService = preferenceService
+}
+
 func (h *UserServiceHandler) Register(ctx context.Context, req *userpb.RegisterRequest) (*userpb.RegisterResponse, error) {
 	// validate input
 	if req.Username == "" {
@@ -59,24 +70,52 @@ func (h *UserServiceHandler) Login(ctx context.Context, req *userpb.LoginRequest
 	}
 
 	// call the business logic
-	token, err := h.userService.Login(req.Username, req.Password)
+	tokens, err := h.userService.Login(req.Username, req.Password, models.SessionClient{UserAgent: req.UserAgent, IP: req.Ip})
 	if err != nil {
 		return nil, h.handleError(err)
 	}
 
 	// get user details for response
-	userFromToken, err := h.userService.GetUserFromToken(token)
+	userFromToken, err := h.userService.GetUserFromToken(tokens.AccessToken)
 	if err != nil {
 		return nil, h.handleError(err)
 	}
 
 	// convert to proto response
 	return &userpb.LoginResponse{
-		Token: token,
+		Token: tokens.AccessToken,
 		User: &userpb.User{
 			Id:       uint64(userFromToken.ID),
 			Username: userFromToken.Username,
 		},
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt.Unix(),
+	}, nil
+}
+
+func (h *UserServiceHandler) RefreshToken(ctx context.Context, req *userpb.RefreshTokenRequest) (*userpb.RefreshTokenResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh_token is required")
+	}
+
+	tokens, err := h.userService.RefreshToken(req.RefreshToken, models.SessionClient{UserAgent: req.UserAgent, IP: req.Ip})
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	user, err := h.userService.GetUserFromToken(tokens.AccessToken)
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	return &userpb.RefreshTokenResponse{
+		Token: tokens.AccessToken,
+		User: &userpb.User{
+			Id:       uint64(user.ID),
+			Username: user.Username,
+		},
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt.Unix(),
 	}, nil
 }
 
@@ -136,7 +175,331 @@ func (h *UserServiceHandler) GetUserFromToken(ctx context.Context, req *userpb.G
 	}, nil
 }
 
+func (h *UserServiceHandler) SetLLMCredential(ctx context.Context, req *userpb.SetLLMCredentialRequest) (*userpb.SetLLMCredentialResponse, error) {
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if req.ApiKey == "" {
+		return nil, status.Error(codes.InvalidArgument, "api_key is required")
+	}
+
+	credential, err := h.credentialService.SetLLMCredential(uint(req.UserId), req.BaseUrl, req.Model, req.ApiKey)
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	return &userpb.SetLLMCredentialResponse{Credential: toProtoLLMCredential(credential)}, nil
+}
+
+func (h *UserServiceHandler) GetLLMCredential(ctx context.Context, req *userpb.GetLLMCredentialRequest) (*userpb.GetLLMCredentialResponse, error) {
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	credential, err := h.credentialService.GetLLMCredential(uint(req.UserId))
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	return &userpb.GetLLMCredentialResponse{Credential: toProtoLLMCredential(credential)}, nil
+}
+
+func (h *UserServiceHandler) DeleteLLMCredential(ctx context.Context, req *userpb.DeleteLLMCredentialRequest) (*userpb.DeleteLLMCredentialResponse, error) {
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	if err := h.credentialService.DeleteLLMCredential(uint(req.UserId)); err != nil {
+		return nil, h.handleError(err)
+	}
+
+	return &userpb.DeleteLLMCredentialResponse{}, nil
+}
+
+func (h *UserServiceHandler) GetLLMUsage(ctx context.Context, req *userpb.GetLLMUsageRequest) (*userpb.GetLLMUsageResponse, error) {
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	since := time.Unix(req.Since, 0)
+	summaries, err := h.credentialService.GetLLMUsage(uint(req.UserId), since)
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	usage := make([]*userpb.LLMUsageSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		usage = append(usage, &userpb.LLMUsageSummary{
+			Model:            summary.Model,
+			Byok:             summary.BYOK,
+			Requests:         summary.Requests,
+			PromptTokens:     summary.PromptTokens,
+			CompletionTokens: summary.CompletionTokens,
+			TotalTokens:      summary.TotalTokens,
+		})
+	}
+
+	return &userpb.GetLLMUsageResponse{Usage: usage}, nil
+}
+
+func (h *UserServiceHandler) ListSessions(ctx context.Context, req *userpb.ListSessionsRequest) (*userpb.ListSessionsResponse, error) {
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	sessions, err := h.userService.ListSessions(uint(req.UserId))
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	pbSessions := make([]*userpb.Session, 0, len(sessions))
+	for _, session := range sessions {
+		pbSessions = append(pbSessions, &userpb.Session{
+			Id:         session.ID,
+			UserAgent:  session.UserAgent,
+			Ip:         session.IP,
+			CreatedAt:  session.CreatedAt.Unix(),
+			LastSeenAt: session.LastSeenAt.Unix(),
+			ExpiresAt:  session.ExpiresAt.Unix(),
+		})
+	}
+
+	return &userpb.ListSessionsResponse{Sessions: pbSessions}, nil
+}
+
+func (h *UserServiceHandler) RevokeSession(ctx context.Context, req *userpb.RevokeSessionRequest) (*userpb.RevokeSessionResponse, error) {
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if req.SessionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "session_id is required")
+	}
+
+	if err := h.userService.RevokeSession(uint(req.UserId), req.SessionId); err != nil {
+		return nil, h.handleError(err)
+	}
+
+	return &userpb.RevokeSessionResponse{}, nil
+}
+
+func (h *UserServiceHandler) RevokeOtherSessions(ctx context.Context, req *userpb.RevokeOtherSessionsRequest) (*userpb.RevokeOtherSessionsResponse, error) {
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	revoked, err := h.userService.RevokeOtherSessions(uint(req.UserId), req.CurrentSessionId)
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	return &userpb.RevokeOtherSessionsResponse{Revoked: revoked}, nil
+}
+
+func toProtoLLMCredential(credential *models.LLMCredential) *userpb.LLMCredential {
+	return &userpb.LLMCredential{
+		BaseUrl:    credential.BaseURL,
+		Model:      credential.Model,
+		ApiKeyHint: credential.APIKeyHint,
+		UpdatedAt:  credential.UpdatedAt.Unix(),
+	}
+}
+
 // handleError converts internal errors to appropriate gRPC status codes
+func (h *UserServiceHandler) GetPreferences(ctx context.Context, req *userpb.GetPreferencesRequest) (*userpb.GetPreferencesResponse, error) {
+	if h.preferenceService == nil {
+		return nil, status.Error(codes.Unimplemented, "preferences are not enabled")
+	}
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	preferences, err := h.preferenceService.GetPreferences(uint(req.UserId))
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	return &userpb.GetPreferencesResponse{Preferences: toProtoPreferences(preferences)}, nil
+}
+
+func (h *UserServiceHandler) UpdatePreferences(ctx context.Context, req *userpb.UpdatePreferencesRequest) (*userpb.UpdatePreferencesResponse, error) {
+	if h.preferenceService == nil {
+		return nil, status.Error(codes.Unimplemented, "preferences are not enabled")
+	}
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	update := models.PreferencesUpdate{
+		DefaultSort:     req.DefaultSort,
+		ShowRead:        req.ShowRead,
+		ListContent:     req.ListContent,
+		Theme:           req.Theme,
+		DefaultView:     req.DefaultView,
+		SummaryLanguage: req.SummaryLanguage,
+	}
+	if req.ItemsPerPage != nil {
+		itemsPerPage := int(*req.ItemsPerPage)
+		update.ItemsPerPage = &itemsPerPage
+	}
+	preferences, err := h.preferenceService.UpdatePreferences(uint(req.UserId), update)
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	return &userpb.UpdatePreferencesResponse{Preferences: toProtoPreferences(preferences)}, nil
+}
+
+func toProtoPreferences(preferences *models.UserPreferences) *userpb.Preferences {
+	pb := &userpb.Preferences{
+		DefaultSort:     preferences.DefaultSort,
+		ShowRead:        preferences.ShowRead,
+		ListContent:     preferences.ListContent,
+		Theme:           preferences.Theme,
+		DefaultView:     preferences.DefaultView,
+		ItemsPerPage:    int32(preferences.ItemsPerPage),
+		SummaryLanguage: preferences.SummaryLanguage,
+	}
+	if !preferences.UpdatedAt.IsZero() {
+		pb.UpdatedAt = preferences.UpdatedAt.Unix()
+	}
+	return pb
+}
+
+func (h *UserServiceHandler) GetProfile(ctx context.Context, req *userpb.GetProfileRequest) (*userpb.GetProfileResponse, error) {
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	user, err := h.userService.GetProfile(uint(req.UserId))
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	return &userpb.GetProfileResponse{User: toProtoProfile(user)}, nil
+}
+
+func (h *UserServiceHandler) UpdateProfile(ctx context.Context, req *userpb.UpdateProfileRequest) (*userpb.UpdateProfileResponse, error) {
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	user, err := h.userService.UpdateProfile(uint(req.UserId), models.ProfileUpdate{
+		Username:    req.Username,
+		Email:       req.Email,
+		DisplayName: req.DisplayName,
+	})
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	return &userpb.UpdateProfileResponse{User: toProtoProfile(user)}, nil
+}
+
+func (h *UserServiceHandler) ChangePassword(ctx context.Context, req *userpb.ChangePasswordRequest) (*userpb.ChangePasswordResponse, error) {
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		return nil, status.Error(codes.InvalidArgument, "current_password and new_password are required")
+	}
+
+	revoked, err := h.userService.ChangePassword(uint(req.UserId), req.CurrentPassword, req.NewPassword, req.CurrentSessionId)
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	return &userpb.ChangePasswordResponse{RevokedSessions: revoked}, nil
+}
+
+func (h *UserServiceHandler) RequestPasswordReset(ctx context.Context, req *userpb.RequestPasswordResetRequest) (*userpb.RequestPasswordResetResponse, error) {
+	if req.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+
+	if err := h.userService.RequestPasswordReset(req.Email, req.Ip); err != nil {
+		return nil, h.handleError(err)
+	}
+
+	return &userpb.RequestPasswordResetResponse{}, nil
+}
+
+func (h *UserServiceHandler) ResetPassword(ctx context.Context, req *userpb.ResetPasswordRequest) (*userpb.ResetPasswordResponse, error) {
+	if req.Token == "" || req.NewPassword == "" {
+		return nil, status.Error(codes.InvalidArgument, "token and new_password are required")
+	}
+
+	user, revoked, err := h.userService.ResetPassword(req.Token, req.NewPassword)
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	return &userpb.ResetPasswordResponse{User: toProtoProfile(user), RevokedSessions: revoked}, nil
+}
+
+// maxListUsersLimit bounds a page of ListUsers
+const maxListUsersLimit = 1000
+
+func (h *UserServiceHandler) ListUsers(ctx context.Context, req *userpb.ListUsersRequest) (*userpb.ListUsersResponse, error) {
+	if req.Limit <= 0 || req.Limit > maxListUsersLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 1 and %d", maxListUsersLimit)
+	}
+
+	users, total, err := h.userService.ListUsers(uint(req.AfterId), int(req.Limit))
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	resp := &userpb.ListUsersResponse{Users: make([]*userpb.User, len(users)), Total: total}
+	for i := range users {
+		resp.Users[i] = toProtoProfile(&users[i])
+	}
+	return resp, nil
+}
+
+func (h *UserServiceHandler) SetUserRole(ctx context.Context, req *userpb.SetUserRoleRequest) (*userpb.SetUserRoleResponse, error) {
+	if req.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	user, err := h.userService.SetUserRole(uint(req.UserId), req.Role)
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	return &userpb.SetUserRoleResponse{User: toProtoProfile(user)}, nil
+}
+
+func (h *UserServiceHandler) Impersonate(ctx context.Context, req *userpb.ImpersonateRequest) (*userpb.ImpersonateResponse, error) {
+	if req.UserId == 0 || req.ImpersonatorId == 0 || req.Impersonator == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id, impersonator_id and impersonator are required")
+	}
+
+	user, tokens, err := h.userService.Impersonate(uint(req.UserId), uint(req.ImpersonatorId), req.Impersonator)
+	if err != nil {
+		return nil, h.handleError(err)
+	}
+
+	return &userpb.ImpersonateResponse{
+		Token:     tokens.AccessToken,
+		User:      toProtoProfile(user),
+		ExpiresAt: tokens.ExpiresAt.Unix(),
+	}, nil
+}
+
+func toProtoProfile(user *models.User) *userpb.User {
+	pb := &userpb.User{
+		Id:          uint64(user.ID),
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		CreatedAt:   user.CreatedAt.Unix(),
+		Role:        user.Role,
+	}
+	if user.Email != nil {
+		pb.Email = *user.Email
+	}
+	return pb
+}
+
 func (h *UserServiceHandler) handleError(err error) error {
 	// check for specific error types
 	var appErr *ierr.AppError
@@ -164,6 +527,9 @@ func (h *UserServiceHandler) handleError(err error) error {
 			if appErr.HTTPStatus == http.StatusUnauthorized {
 				return status.Error(codes.Unauthenticated, appErr.Error())
 			}
+			if appErr.HTTPStatus == http.StatusForbidden {
+				return status.Error(codes.PermissionDenied, appErr.Error())
+			}
 			if appErr.HTTPStatus == http.StatusNotFound {
 				return status.Error(codes.NotFound, appErr.Error())
 			}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Audit events
+const (
+	AuditLoginSucceeded       = "login.succeeded"
+	AuditLoginFailed          = "login.failed"
+	AuditLoginLocked          = "login.locked"  // a failure started a lockout
+	AuditLoginBlocked         = "login.blocked" // an attempt was refused during a lockout
+	AuditLoginChallengeFailed = "login.challenge_failed"
+	AuditLogout               = "logout"
+	AuditSessionRevoked       = "session.revoked"
+	AuditSessionsRevoked      = "session.revoked_others"
+	AuditProfileUpdated       = "profile.updated"
+	AuditPasswordChanged      = "password.changed"
+	AuditPasswordChangeFailed = "password.change_failed" // the current password was wrong
+	AuditPasswordReset        = "password.reset"
+	AuditPasswordResetFailed  = "password.reset_failed" // the reset token was unknown, used or expired
+	AuditRoleChanged          = "user.role_changed"     // by an administrator, for the user in the event
+	AuditImpersonationStarted = "user.impersonated"     // an administrator got a token to act as the user in the event
+)
+
+// AuditEvent records a security-relevant action. UserID is nil when no account could be
+// tied to it, e.g. a login attempt for an unknown username.
+type AuditEvent struct {
+	ID        uint      `json:"id"`
+	Event     string    `json:"event"`
+	UserID    *uint     `json:"user_id,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	IP        string    `json:"ip,omitempty" gorm:"column:ip"`
+	RequestID string    `json:"request_id,omitempty"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
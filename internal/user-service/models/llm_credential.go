@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// LLMCredential is a user's own LLM provider key (bring-your-own-key).
+// The API key is only ever persisted encrypted.
+type LLMCredential struct {
+	UserID           uint      `json:"user_id" gorm:"primaryKey"`
+	BaseURL          string    `json:"base_url" gorm:"not null;default:''"`
+	Model            string    `json:"model" gorm:"not null;default:'';size:255"`
+	APIKeyCiphertext string    `json:"-" gorm:"column:api_key_ciphertext;not null"`
+	APIKeyHint       string    `json:"api_key_hint" gorm:"column:api_key_hint;not null;default:'';size:16"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+func (LLMCredential) TableName() string {
+	return "user_llm_credentials"
+}
+
+// LLMUsageSummary aggregates LLM usage for a user grouped by model and key source
+type LLMUsageSummary struct {
+	Model            string `json:"model"`
+	BYOK             bool   `json:"byok"`
+	Requests         int64  `json:"requests"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+	TotalTokens      int64  `json:"total_tokens"`
+}
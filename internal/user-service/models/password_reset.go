@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// PasswordReset is a token mailed to a user who forgot their password. Only its hash is
+// stored; it works once, until ExpiresAt.
+type PasswordReset struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	TokenHash string `gorm:"not null;size:64;uniqueIndex"`
+	// IP is the client that asked for the reset
+	IP        string `gorm:"column:ip;not null;default:'';size:64"`
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func (PasswordReset) TableName() string {
+	return "password_resets"
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// Values of the reading preferences
+const (
+	SortRecent = "recent"
+	SortSmart  = "smart"
+
+	ListContentExcerpt = "excerpt"
+	ListContentFull    = "full"
+
+	ThemeSystem = "system"
+	ThemeLight  = "light"
+	ThemeDark   = "dark"
+
+	ViewAll     = "all"
+	ViewUnread  = "unread"
+	ViewStarred = "starred"
+)
+
+// Bounds of the preferences clients use as list parameters
+const (
+	DefaultItemsPerPage = 50
+	MinItemsPerPage     = 10
+	MaxItemsPerPage     = 200
+	// MaxSummaryLanguageLength matches the summary language codes the instance accepts
+	MaxSummaryLanguageLength = 16
+)
+
+// UserPreferences are how a user reads by default, stored server-side so they follow the
+// user across devices. Clients apply them; list endpoints still take their own parameters.
+type UserPreferences struct {
+	UserID uint `json:"-" gorm:"primaryKey"`
+	// DefaultSort is the sort of article lists: recent or smart
+	DefaultSort string `json:"default_sort" gorm:"not null;size:16"`
+	// ShowRead lists read articles along with unread ones
+	ShowRead bool `json:"show_read" gorm:"not null"`
+	// ListContent shows an excerpt or the full content of articles in lists
+	ListContent string `json:"list_content" gorm:"not null;size:16"`
+	// Theme is a hint for the client's theme: system, light or dark
+	Theme string `json:"theme" gorm:"not null;size:16"`
+	// DefaultView is the articles lists open on: all, unread or starred
+	DefaultView string `json:"default_view" gorm:"not null;size:16;default:all"`
+	// ItemsPerPage is the page size of article lists
+	ItemsPerPage int `json:"items_per_page" gorm:"not null;default:50"`
+	// SummaryLanguage is the language code of the summaries shown, empty for the
+	// instance's default language
+	SummaryLanguage string    `json:"summary_language" gorm:"not null;size:16;default:''"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func (UserPreferences) TableName() string {
+	return "user_preferences"
+}
+
+// DefaultUserPreferences are the preferences of a user who never changed them
+func DefaultUserPreferences(userID uint) *UserPreferences {
+	return &UserPreferences{
+		UserID:       userID,
+		DefaultSort:  SortRecent,
+		ShowRead:     true,
+		ListContent:  ListContentExcerpt,
+		Theme:        ThemeSystem,
+		DefaultView:  ViewAll,
+		ItemsPerPage: DefaultItemsPerPage,
+	}
+}
+
+// PreferencesUpdate changes the preferences that are set and leaves the others
+type PreferencesUpdate struct {
+	DefaultSort     *string
+	ShowRead        *bool
+	ListContent     *string
+	Theme           *string
+	DefaultView     *string
+	ItemsPerPage    *int
+	SummaryLanguage *string
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Session is a login. Its ID travels in the token's sid claim, so the token stops working
+// once the session is revoked, even before it expires.
+type Session struct {
+	ID         string     `json:"id" gorm:"primaryKey;size:64"`
+	UserID     uint       `json:"-" gorm:"not null;index"`
+	UserAgent  string     `json:"user_agent" gorm:"not null;default:'';size:512"`
+	IP         string     `json:"ip" gorm:"column:ip;not null;default:'';size:64"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+	// RefreshTokenHash is the SHA-256 of the session's refresh token; PreviousRefreshHash
+	// is that of the token it replaced, kept to spot a replayed one
+	RefreshTokenHash    *string `json:"-" gorm:"size:64"`
+	PreviousRefreshHash *string `json:"-" gorm:"size:64"`
+	// Current marks the session of the token that listed the sessions
+	Current bool `json:"current" gorm:"-"`
+}
+
+func (Session) TableName() string {
+	return "user_sessions"
+}
+
+// SessionClient describes the client a session is opened for
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+// AuthTokens is what a login or a refresh hands out: a short-lived access token and the
+// refresh token that gets the next one while the session lasts
+type AuthTokens struct {
+	AccessToken string
+	// ExpiresAt is when the access token expires
+	ExpiresAt    time.Time
+	RefreshToken string
+}
@@ -1,11 +1,89 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits of the profile fields, matching the users table
+const (
+	MinUsernameLength    = 3
+	MaxUsernameLength    = 50
+	MaxEmailLength       = 254
+	MaxDisplayNameLength = 100
+	// MinPasswordLength applies to new passwords, at registration and when changing one
+	MinPasswordLength = 6
+)
+
+// Roles of a user. Administrators may use the /admin endpoints of the API.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// ParseRole checks role is one of the known roles
+func ParseRole(role string) (string, error) {
+	switch role {
+	case RoleUser, RoleAdmin:
+		return role, nil
+	}
+	return "", fmt.Errorf("role must be one of %s, %s", RoleUser, RoleAdmin)
+}
 
 type User struct {
-	ID           uint      `json:"id" gorm:"primaryKey"`
-	Username     string    `json:"username" gorm:"unique;not null;size:50"`
-	PasswordHash string    `json:"-" gorm:"not null;size:255"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           uint   `json:"id" gorm:"primaryKey"`
+	Username     string `json:"username" gorm:"unique;not null;size:50"`
+	PasswordHash string `json:"-" gorm:"not null;size:255"`
+	// Email is stored lower-cased; nil until the user sets one
+	Email       *string   `json:"email" gorm:"size:254;uniqueIndex:idx_users_email"`
+	DisplayName string    `json:"display_name" gorm:"not null;size:100;default:''"`
+	Role        string    `json:"role" gorm:"not null;size:16;default:'user'"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ProfileUpdate changes the profile fields that are set and leaves the others. An empty
+// Email removes the address.
+type ProfileUpdate struct {
+	Username    *string
+	Email       *string
+	DisplayName *string
+}
+
+// NormalizeUsername trims a username and checks its length is what registration accepts
+func NormalizeUsername(username string) (string, error) {
+	username = strings.TrimSpace(username)
+	if n := utf8.RuneCountInString(username); n < MinUsernameLength || n > MaxUsernameLength {
+		return "", fmt.Errorf("username must be between %d and %d characters", MinUsernameLength, MaxUsernameLength)
+	}
+	return username, nil
+}
+
+// NormalizeEmail trims and lower-cases an address and checks it is a bare address, not a
+// name with one. An empty address is returned as nil.
+func NormalizeEmail(email string) (*string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil, nil
+	}
+	if len(email) > MaxEmailLength {
+		return nil, fmt.Errorf("email must be at most %d characters", MaxEmailLength)
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || address.Name != "" {
+		return nil, fmt.Errorf("email is not a valid address")
+	}
+	return &email, nil
+}
+
+// NormalizeDisplayName trims a display name and checks it fits the column
+func NormalizeDisplayName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > MaxDisplayNameLength {
+		return "", fmt.Errorf("display name must be at most %d characters", MaxDisplayNameLength)
+	}
+	return name, nil
 }
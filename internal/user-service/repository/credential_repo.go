@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+)
+
+type CredentialRepository struct {
+	db *gorm.DB
+}
+
+func NewCredentialRepository(db *gorm.DB) *CredentialRepository {
+	return &CredentialRepository{
+		db: db,
+	}
+}
+
+// Upsert stores the credential, replacing any existing one for the user
+func (r *CredentialRepository) Upsert(credential *models.LLMCredential) (*models.LLMCredential, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"base_url", "model", "api_key_ciphertext", "api_key_hint", "updated_at"}),
+	}).Create(credential)
+	return credential, result.Error
+}
+
+func (r *CredentialRepository) GetByUserID(userID uint) (*models.LLMCredential, error) {
+	credential := &models.LLMCredential{}
+	result := r.db.Where("user_id = ?", userID).First(credential)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return credential, result.Error
+}
+
+// Delete removes the user's credential, returning false when none existed
+func (r *CredentialRepository) Delete(userID uint) (bool, error) {
+	result := r.db.Where("user_id = ?", userID).Delete(&models.LLMCredential{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// SummarizeUsage aggregates the llm_usage ledger for a user since the given time
+func (r *CredentialRepository) SummarizeUsage(userID uint, since time.Time) ([]models.LLMUsageSummary, error) {
+	var summaries []models.LLMUsageSummary
+	result := r.db.Table("llm_usage").
+		Select("model, byok, COUNT(*) AS requests, "+
+			"COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, "+
+			"COALESCE(SUM(completion_tokens), 0) AS completion_tokens, "+
+			"COALESCE(SUM(total_tokens), 0) AS total_tokens").
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Group("model, byok").
+		Order("model ASC, byok DESC").
+		Scan(&summaries)
+	return summaries, result.Error
+}
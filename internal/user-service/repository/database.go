@@ -8,6 +8,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/Fancu1/phoenix-rss/internal/config"
+	"github.com/Fancu1/phoenix-rss/pkg/tracing"
 )
 
 func InitDB(cfg *config.DatabaseConfig) *gorm.DB {
@@ -18,6 +19,9 @@ func InitDB(cfg *config.DatabaseConfig) *gorm.DB {
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		log.Fatalf("Failed to trace database statements: %v", err)
+	}
 
 	log.Println("Database connected and migrated successfully")
 	return db
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/dbtx"
+)
+
+type PasswordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repository working in the transaction of a
+// dbtx.UnitOfWork; a nil tx keeps the repository's own DB
+func (r *PasswordResetRepository) WithTx(tx *gorm.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: dbtx.Bind(r.db, tx)}
+}
+
+// Replace voids the user's unused resets and stores the new one
+func (r *PasswordResetRepository) Replace(reset *models.PasswordReset) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.PasswordReset{}).
+			Where("user_id = ? AND used_at IS NULL", reset.UserID).
+			Update("used_at", reset.CreatedAt).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(reset).Error
+	})
+}
+
+// LatestCreatedAt returns when the user's last reset was asked for, zero when never
+func (r *PasswordResetRepository) LatestCreatedAt(userID uint) (time.Time, error) {
+	var reset models.PasswordReset
+	err := r.db.Select("created_at").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		First(&reset).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	return reset.CreatedAt, err
+}
+
+// GetByTokenHash returns the reset whose token has the hash, or nil when there is none
+func (r *PasswordResetRepository) GetByTokenHash(hash string) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	err := r.db.Where("token_hash = ?", hash).First(&reset).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
+
+// MarkUsed uses up the reset, returning false when it was used or expired already
+func (r *PasswordResetRepository) MarkUsed(id uint, now time.Time) (bool, error) {
+	result := r.db.Model(&models.PasswordReset{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", id, now).
+		Update("used_at", now)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+)
+
+type PreferenceRepository struct {
+	db *gorm.DB
+}
+
+func NewPreferenceRepository(db *gorm.DB) *PreferenceRepository {
+	return &PreferenceRepository{
+		db: db,
+	}
+}
+
+// GetByUserID returns the user's stored preferences, nil when they never changed them
+func (r *PreferenceRepository) GetByUserID(userID uint) (*models.UserPreferences, error) {
+	preferences := &models.UserPreferences{}
+	result := r.db.Where("user_id = ?", userID).First(preferences)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return preferences, result.Error
+}
+
+// Upsert stores every preference, replacing the user's previous ones
+func (r *PreferenceRepository) Upsert(preferences *models.UserPreferences) (*models.UserPreferences, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"default_sort", "show_read", "list_content", "theme", "default_view", "items_per_page", "summary_language", "updated_at"}),
+	}).Create(preferences)
+	return preferences, result.Error
+}
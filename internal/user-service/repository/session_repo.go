@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/dbtx"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repository working in the transaction of a
+// dbtx.UnitOfWork; a nil tx keeps the repository's own DB
+func (r *SessionRepository) WithTx(tx *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: dbtx.Bind(r.db, tx)}
+}
+
+func (r *SessionRepository) Create(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+// GetByRefreshHash returns the session whose current or previous refresh token has the
+// hash, revoked and expired ones included, or nil when there is none
+func (r *SessionRepository) GetByRefreshHash(hash string) (*models.Session, error) {
+	var session models.Session
+	err := r.db.Where("refresh_token_hash = ? OR previous_refresh_hash = ?", hash, hash).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RotateRefreshToken replaces the active session's refresh token oldHash with newHash and
+// extends the session to expiresAt. It returns false when the token was already replaced,
+// e.g. by a concurrent refresh, or the session ended.
+func (r *SessionRepository) RotateRefreshToken(sessionID, oldHash, newHash string, now, expiresAt time.Time) (bool, error) {
+	result := r.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, oldHash, now).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":    newHash,
+			"previous_refresh_hash": oldHash,
+			"last_seen_at":          now,
+			"expires_at":            expiresAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ListActive returns the user's sessions that are neither revoked nor expired, most
+// recently seen first
+func (r *SessionRepository) ListActive(userID uint, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	result := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at DESC, created_at DESC").
+		Find(&sessions)
+	return sessions, result.Error
+}
+
+// Revoke ends one of the user's active sessions, returning false when there was none
+func (r *SessionRepository) Revoke(userID uint, sessionID string, now time.Time) (bool, error) {
+	result := r.db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, userID, now).
+		Update("revoked_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RevokeOthers ends all of the user's active sessions but keepID, returning how many
+func (r *SessionRepository) RevokeOthers(userID uint, keepID string, now time.Time) (int64, error) {
+	result := r.db.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL AND expires_at > ?", userID, keepID, now).
+		Update("revoked_at", now)
+	return result.RowsAffected, result.Error
+}
@@ -2,10 +2,12 @@ package repository
 
 import (
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 
 	"github.com/Fancu1/phoenix-rss/internal/user-service/models"
+	"github.com/Fancu1/phoenix-rss/pkg/dbtx"
 )
 
 type UserRepository struct {
@@ -18,6 +20,12 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	}
 }
 
+// WithTx returns a copy of the repository working in the transaction of a
+// dbtx.UnitOfWork; a nil tx keeps the repository's own DB
+func (r *UserRepository) WithTx(tx *gorm.DB) *UserRepository {
+	return &UserRepository{db: dbtx.Bind(r.db, tx)}
+}
+
 func (r *UserRepository) Create(user *models.User) (*models.User, error) {
 	result := r.db.Create(user)
 	return user, result.Error
@@ -41,7 +49,73 @@ func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 	return user, result.Error
 }
 
+// GetByEmail returns the user with the (lower-cased) email, or nil when there is none
+func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
+	user := &models.User{}
+	result := r.db.Where("email = ?", email).First(user)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return user, result.Error
+}
+
 func (r *UserRepository) Update(user *models.User) (*models.User, error) {
 	result := r.db.Save(user)
 	return user, result.Error
 }
+
+// UpdatePasswordHash replaces the user's password hash, unless it changed from oldHash
+// in the meantime. It reports whether the hash was replaced.
+func (r *UserRepository) UpdatePasswordHash(id uint, oldHash, newHash string) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND password_hash = ?", id, oldHash).
+		Update("password_hash", newHash)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateProfile stores the user's username, email and display name
+func (r *UserRepository) UpdateProfile(user *models.User) error {
+	return r.db.Model(user).
+		Select("username", "email", "display_name", "updated_at").
+		Updates(user).Error
+}
+
+// UpdateRole sets the user's role. It reports whether the user exists.
+func (r *UserRepository) UpdateRole(id uint, role string) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"role": role, "updated_at": time.Now().UTC()})
+	return result.RowsAffected > 0, result.Error
+}
+
+// List returns up to limit users with an ID above afterID, in ID order
+func (r *UserRepository) List(afterID uint, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("id > ?", afterID).Order("id").Limit(limit).Find(&users).Error
+	return users, err
+}
+
+// Count returns how many users there are
+func (r *UserRepository) Count() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Count(&count).Error
+	return count, err
+}
+
+// UsernameTaken reports whether another user than exceptID has the username
+func (r *UserRepository) UsernameTaken(username string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).
+		Where("username = ? AND id <> ?", username, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// EmailTaken reports whether another user than exceptID uses the (lower-cased) email
+func (r *UserRepository) EmailTaken(email string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).
+		Where("email = ? AND id <> ?", email, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
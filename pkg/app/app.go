@@ -0,0 +1,290 @@
+// Package app runs a phoenix-rss service. A service registers its components on an App
+// in dependency order: resources to close, components to start before the next, and
+// long-running ones such as servers and consumers. Run starts them in that order, reports
+// the service as serving on the gRPC health service, and waits for SIGINT, SIGTERM or a
+// component failing. It then reports not serving, stops the intake components such as
+// Kafka consumers so no new work arrives, and stops the other components in reverse
+// order: servers finish the calls in flight within the drain period before the producers
+// and databases behind them go away.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/Fancu1/phoenix-rss/pkg/logger"
+	"github.com/Fancu1/phoenix-rss/pkg/tracing"
+)
+
+// DefaultShutdownTimeout bounds the stop of each component
+const DefaultShutdownTimeout = 10 * time.Second
+
+type component struct {
+	name string
+	// start runs in order at startup and returns once the component is up
+	start func(ctx context.Context) error
+	// run runs in the background until its context is cancelled
+	run func(ctx context.Context) error
+	// stop runs in reverse order at shutdown
+	stop func(ctx context.Context) error
+	// intake components stop before all others
+	intake bool
+	// drain components are servers, given the drain period to stop
+	drain bool
+
+	started bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// App holds the components of one service
+type App struct {
+	name            string
+	log             *slog.Logger
+	health          *health.Server
+	shutdownTimeout time.Duration
+	drainPeriod     time.Duration
+
+	mu         sync.Mutex
+	components []*component
+	failed     chan error
+}
+
+// New sets up logging for the service. The logger writes to the file named by LOG_FILE,
+// like every service did before.
+func New(name string) (*App, error) {
+	if err := logger.InitFromEnv(); err != nil {
+		return nil, fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	return &App{
+		name:            name,
+		log:             logger.New(slog.LevelDebug),
+		health:          healthServer,
+		shutdownTimeout: DefaultShutdownTimeout,
+		failed:          make(chan error, 1),
+	}, nil
+}
+
+func (a *App) Name() string {
+	return a.name
+}
+
+func (a *App) Logger() *slog.Logger {
+	return a.log
+}
+
+// Health returns the health service, which reports serving only between startup and
+// shutdown. ServeGRPC registers it on its server.
+func (a *App) Health() *health.Server {
+	return a.health
+}
+
+// SetShutdownTimeout changes how long each component may take to stop
+func (a *App) SetShutdownTimeout(timeout time.Duration) {
+	a.shutdownTimeout = timeout
+}
+
+// SetDrainPeriod changes how long the servers may take at shutdown to finish the calls in
+// flight before they are stopped outright. It defaults to the shutdown timeout.
+func (a *App) SetDrainPeriod(period time.Duration) {
+	a.drainPeriod = period
+}
+
+func (a *App) drainTimeout() time.Duration {
+	if a.drainPeriod > 0 {
+		return a.drainPeriod
+	}
+	return a.shutdownTimeout
+}
+
+// SetupTracing exports the service's traces and flushes them when everything else has
+// stopped
+func (a *App) SetupTracing(cfg tracing.Config) error {
+	shutdown, err := tracing.Setup(context.Background(), a.name, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	a.OnStop("tracing", shutdown)
+	return nil
+}
+
+// Add registers a component started in order at startup and stopped in reverse order at
+// shutdown. Either function may be nil. The context start gets is cancelled as shutdown
+// begins, so work the component keeps running with it winds down before stop is called.
+func (a *App) Add(name string, start, stop func(ctx context.Context) error) {
+	a.register(&component{name: name, start: start, stop: stop})
+}
+
+// Go registers a component that runs in the background until shutdown cancels its
+// context. It returning an error stops the service; returning nil only ends the
+// component.
+func (a *App) Go(name string, run func(ctx context.Context) error) {
+	a.register(&component{name: name, run: run})
+}
+
+// Intake registers a component that takes in work other than calls, such as a Kafka
+// consumer. Run fetches and handles work until its context is cancelled; stop, which may
+// be nil, releases the component once run has returned. Intake components stop first at
+// shutdown, right after the service reports not serving, so no new work arrives while
+// the servers drain.
+func (a *App) Intake(name string, run, stop func(ctx context.Context) error) {
+	a.register(&component{name: name, run: run, stop: stop, intake: true})
+}
+
+// OnStop registers a function run at shutdown, after the components registered later
+// have stopped
+func (a *App) OnStop(name string, stop func(ctx context.Context) error) {
+	a.register(&component{name: name, stop: stop, started: true})
+}
+
+// Closer closes an open resource at shutdown, like OnStop
+func (a *App) Closer(name string, closer io.Closer) {
+	a.OnStop(name, func(context.Context) error {
+		return closer.Close()
+	})
+}
+
+func (a *App) register(c *component) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.components = append(a.components, c)
+}
+
+// Fatal logs a setup failure, stops what was already set up and exits
+func (a *App) Fatal(msg string, args ...any) {
+	a.log.Error(msg, args...)
+	a.stopAll()
+	logger.Close()
+	os.Exit(1)
+}
+
+// Run starts the components and blocks until ctx is cancelled, the process receives
+// SIGINT or SIGTERM, or a component fails. It returns the error that stopped the service,
+// after stopping every component.
+func (a *App) Run(ctx context.Context) error {
+	defer logger.Close()
+
+	ctx, stopSignals := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	err := a.startAll(ctx)
+	if err == nil {
+		a.health.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+		a.log.Info("service started", "service", a.name)
+
+		select {
+		case <-ctx.Done():
+			a.log.Info("received shutdown signal", "service", a.name)
+		case err = <-a.failed:
+		}
+	}
+
+	a.health.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	a.stopAll()
+	if err != nil {
+		a.log.Error("service stopped", "service", a.name, "error", err)
+		return err
+	}
+	a.log.Info("service shutdown completed", "service", a.name)
+	return nil
+}
+
+func (a *App) startAll(ctx context.Context) error {
+	a.mu.Lock()
+	components := append([]*component(nil), a.components...)
+	a.mu.Unlock()
+
+	for _, c := range components {
+		if c.started {
+			continue
+		}
+		if c.start != nil {
+			if err := c.start(ctx); err != nil {
+				return fmt.Errorf("failed to start %s: %w", c.name, err)
+			}
+		}
+		if c.run != nil {
+			a.launch(ctx, c)
+		}
+		c.started = true
+	}
+	return nil
+}
+
+func (a *App) launch(ctx context.Context, c *component) {
+	// shutdown cancels the components one by one, not all at once with the signal
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	c.cancel = cancel
+	c.done = make(chan struct{})
+	a.log.Info("starting component", "component", c.name)
+
+	go func() {
+		defer close(c.done)
+		err := c.run(runCtx)
+		switch {
+		case err != nil && !errors.Is(err, context.Canceled) && runCtx.Err() == nil:
+			select {
+			case a.failed <- fmt.Errorf("%s: %w", c.name, err):
+			default:
+			}
+		case err != nil && !errors.Is(err, context.Canceled):
+			a.log.Error("component failed while stopping", "component", c.name, "error", err)
+		}
+	}()
+}
+
+// stopAll stops the started intake components, then the others, each in reverse order.
+// Servers get the drain period and the shutdown timeout, the others the shutdown timeout.
+func (a *App) stopAll() {
+	a.mu.Lock()
+	components := append([]*component(nil), a.components...)
+	a.mu.Unlock()
+
+	for _, intake := range []bool{true, false} {
+		for i := len(components) - 1; i >= 0; i-- {
+			c := components[i]
+			if !c.started || c.intake != intake {
+				continue
+			}
+			c.started = false
+			a.stopOne(c)
+		}
+	}
+}
+
+func (a *App) stopOne(c *component) {
+	timeout := a.shutdownTimeout
+	if c.drain {
+		// the server forces its stop once the drain period is over
+		timeout += a.drainTimeout()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if c.cancel != nil {
+		c.cancel()
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			a.log.Warn("component did not stop in time", "component", c.name, "timeout", timeout.String())
+		}
+	}
+	if c.stop != nil {
+		if err := c.stop(ctx); err != nil {
+			a.log.Error("failed to stop component", "component", c.name, "error", err)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	a, err := New("test-service")
+	require.NoError(t, err)
+	a.SetShutdownTimeout(time.Second)
+	return a
+}
+
+// runner waits for its context, recording when it starts and stops
+func runner(r *recorder, name string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		r.add("run " + name)
+		<-ctx.Done()
+		r.add("stop " + name)
+		return ctx.Err()
+	}
+}
+
+func TestRun_OrderedStartupAndShutdown(t *testing.T) {
+	a := newTestApp(t)
+	var r recorder
+	a.OnStop("database", func(context.Context) error {
+		r.add("close database")
+		return nil
+	})
+	a.Add("producer", func(context.Context) error {
+		r.add("start producer")
+		return nil
+	}, func(context.Context) error {
+		r.add("stop producer")
+		return nil
+	})
+	a.Go("consumer", runner(&r, "consumer"))
+	a.Go("server", runner(&r, "server"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+
+	require.Eventually(t, func() bool { return len(r.list()) == 3 }, time.Second, 5*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	events := r.list()
+	assert.Equal(t, "start producer", events[0])
+	assert.ElementsMatch(t, []string{"run consumer", "run server"}, events[1:3])
+	assert.Equal(t, []string{"stop server", "stop consumer", "stop producer", "close database"}, events[3:])
+}
+
+func TestRun_ComponentFailureStopsService(t *testing.T) {
+	a := newTestApp(t)
+	var r recorder
+	failure := errors.New("broker unreachable")
+	a.Go("server", runner(&r, "server"))
+	a.Go("consumer", func(context.Context) error { return failure })
+
+	err := a.Run(context.Background())
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, []string{"run server", "stop server"}, r.list())
+}
+
+func TestRun_StartFailureStopsStartedComponents(t *testing.T) {
+	a := newTestApp(t)
+	var r recorder
+	a.Go("consumer", runner(&r, "consumer"))
+	a.Add("listener", func(context.Context) error { return errors.New("address in use") }, nil)
+	a.Go("server", runner(&r, "server"))
+
+	err := a.Run(context.Background())
+	assert.ErrorContains(t, err, "failed to start listener")
+	require.Eventually(t, func() bool { return len(r.list()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"run consumer", "stop consumer"}, r.list())
+}
+
+func TestRun_IntakeStopsFirst(t *testing.T) {
+	a := newTestApp(t)
+	var r recorder
+	a.OnStop("database", func(context.Context) error {
+		r.add("close database")
+		return nil
+	})
+	a.Intake("consumer", runner(&r, "consumer"), func(ctx context.Context) error {
+		resp, err := a.Health().Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		if err == nil && resp.Status == grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+			r.add("close consumer while not serving")
+		}
+		return nil
+	})
+	a.Go("server", runner(&r, "server"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+
+	require.Eventually(t, func() bool { return len(r.list()) == 2 }, time.Second, 5*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, []string{"stop consumer", "close consumer while not serving", "stop server", "close database"}, r.list()[2:])
+}
+
+func TestServeHTTP_DrainsRequestsInFlight(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	started := make(chan struct{})
+	a := newTestApp(t)
+	a.SetShutdownTimeout(50 * time.Millisecond)
+	a.SetDrainPeriod(2 * time.Second)
+	a.ServeHTTP("http", &http.Server{Addr: address, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	})})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", address)
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + address)
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-started
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, http.StatusNoContent, <-status, "a request in flight outlasting the shutdown timeout finishes within the drain period")
+}
+
+func TestServeGRPC_Health(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	a := newTestApp(t)
+	a.ServeGRPC("grpc", grpc.NewServer(), address)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := grpc_health_v1.NewHealthClient(conn)
+	require.Eventually(t, func() bool {
+		resp, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+		return err == nil && resp.Status == grpc_health_v1.HealthCheckResponse_SERVING
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	resp, err := a.Health().Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, resp.Status)
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// ServeGRPC registers the app's health service on the server and serves it on the
+// address. At shutdown the server finishes the calls in flight, and is stopped outright
+// once the drain period passes.
+func (a *App) ServeGRPC(name string, server *grpc.Server, address string) {
+	grpc_health_v1.RegisterHealthServer(server, a.health)
+
+	var lis net.Listener
+	a.Add(name+" listener", func(context.Context) error {
+		var err error
+		lis, err = net.Listen("tcp", address)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", address, err)
+		}
+		return nil
+	}, nil)
+
+	a.serve(name, func(ctx context.Context) error {
+		a.log.Info("starting gRPC server", "server", name, "address", address)
+		serverErr := make(chan error, 1)
+		go func() {
+			serverErr <- server.Serve(lis)
+		}()
+
+		select {
+		case err := <-serverErr:
+			return fmt.Errorf("gRPC server error: %w", err)
+		case <-ctx.Done():
+		}
+
+		a.log.Info("gracefully stopping gRPC server", "server", name, "drain_period", a.drainTimeout().String())
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), a.drainTimeout())
+		defer cancel()
+		select {
+		case <-stopped:
+			a.log.Info("gRPC server stopped gracefully", "server", name)
+		case <-shutdownCtx.Done():
+			a.log.Warn("gRPC server drain period over, forcing stop", "server", name)
+			server.Stop()
+		}
+		return nil
+	})
+}
+
+// ServeHTTP serves the server on its address. At shutdown the server finishes the
+// requests in flight within the drain period.
+func (a *App) ServeHTTP(name string, server *http.Server) {
+	a.serve(name, func(ctx context.Context) error {
+		a.log.Info("starting HTTP server", "server", name, "address", server.Addr)
+		serverErr := make(chan error, 1)
+		go func() {
+			serverErr <- server.ListenAndServe()
+		}()
+
+		select {
+		case err := <-serverErr:
+			return fmt.Errorf("HTTP server error: %w", err)
+		case <-ctx.Done():
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), a.drainTimeout())
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.log.Warn("HTTP server shutdown timeout, closing connections", "server", name, "error", err)
+			return server.Close()
+		}
+		a.log.Info("HTTP server stopped gracefully", "server", name)
+		return nil
+	})
+}
+
+// serve registers a server, which runs like a component of Go but is given the drain
+// period to stop
+func (a *App) serve(name string, run func(ctx context.Context) error) {
+	a.register(&component{name: name, run: run, drain: true})
+}
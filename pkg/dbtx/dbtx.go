@@ -0,0 +1,46 @@
+// Package dbtx lets writes spanning several repositories share one database transaction.
+//
+// Repositories hold their own *gorm.DB; each one that takes part in a unit of work has a
+// WithTx method returning a copy bound to the transaction:
+//
+//	err := uow.Do(ctx, func(tx *gorm.DB) error {
+//		feed, err := feeds.WithTx(tx).Create(ctx, feed)
+//		...
+//		return subscriptions.WithTx(tx).CreateSubscription(ctx, sub)
+//	})
+package dbtx
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfWork runs functions in a database transaction
+type UnitOfWork struct {
+	db *gorm.DB
+}
+
+func NewUnitOfWork(db *gorm.DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+// Do runs fn in a transaction, which is committed when fn returns nil and rolled back
+// when it returns an error or panics. The error of fn is returned as is, so callers can
+// still tell their own errors apart. A nil UnitOfWork runs fn without a transaction on a
+// nil *gorm.DB, which WithTx methods take as "keep the repository's own DB".
+func (u *UnitOfWork) Do(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	if u == nil {
+		return fn(nil)
+	}
+	return u.db.WithContext(ctx).Transaction(fn)
+}
+
+// Bind returns tx, or db when there is no transaction; WithTx methods use it so that
+// code works the same with and without a unit of work
+func Bind(db, tx *gorm.DB) *gorm.DB {
+	if tx == nil {
+		return db
+	}
+	return tx
+}
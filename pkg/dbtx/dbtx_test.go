@@ -0,0 +1,72 @@
+package dbtx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type item struct {
+	ID   uint
+	Name string `gorm:"uniqueIndex"`
+}
+
+func setupDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&item{}))
+	return db
+}
+
+func count(t *testing.T, db *gorm.DB) int64 {
+	t.Helper()
+	var n int64
+	require.NoError(t, db.Model(&item{}).Count(&n).Error)
+	return n
+}
+
+func TestUnitOfWork_Do(t *testing.T) {
+	db := setupDB(t)
+	uow := NewUnitOfWork(db)
+	ctx := context.Background()
+
+	err := uow.Do(ctx, func(tx *gorm.DB) error {
+		require.NoError(t, tx.Create(&item{Name: "a"}).Error)
+		return tx.Create(&item{Name: "b"}).Error
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count(t, db))
+
+	errStop := errors.New("stop")
+	err = uow.Do(ctx, func(tx *gorm.DB) error {
+		require.NoError(t, tx.Create(&item{Name: "c"}).Error)
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop, "the function's error is returned as is")
+	require.Equal(t, int64(2), count(t, db), "writes are rolled back on error")
+
+	err = uow.Do(ctx, func(tx *gorm.DB) error {
+		require.NoError(t, tx.Create(&item{Name: "d"}).Error)
+		return tx.Create(&item{Name: "a"}).Error
+	})
+	require.Error(t, err)
+	require.Equal(t, int64(2), count(t, db), "a failing second write undoes the first")
+}
+
+func TestUnitOfWork_Nil(t *testing.T) {
+	db := setupDB(t)
+	var uow *UnitOfWork
+
+	err := uow.Do(context.Background(), func(tx *gorm.DB) error {
+		require.Nil(t, tx)
+		return Bind(db, tx).Create(&item{Name: "a"}).Error
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count(t, db))
+}
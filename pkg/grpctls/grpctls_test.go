@@ -0,0 +1,154 @@
+package grpctls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type testPKI struct {
+	dir    string
+	ca     *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	serial int64
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "phoenix test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	p := &testPKI{dir: t.TempDir(), ca: ca, caKey: key, serial: 1}
+	p.write(t, "ca.pem", "CERTIFICATE", der)
+	return p
+}
+
+func (p *testPKI) write(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(p.dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return path
+}
+
+// issue signs a certificate for the name and returns its certificate and key files
+func (p *testPKI) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(p.serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, p.ca, &key.PublicKey, p.caKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return p.write(t, name+".pem", "CERTIFICATE", der), p.write(t, name+"-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+// serve starts a health server with the config and returns its address
+func serve(t *testing.T, cfg Config) string {
+	t.Helper()
+	creds, err := ServerCredentials(cfg)
+	require.NoError(t, err)
+	server := grpc.NewServer(grpc.Creds(creds))
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+func check(t *testing.T, address string, cfg Config) error {
+	t.Helper()
+	creds, err := ClientCredentials(cfg)
+	require.NoError(t, err)
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(creds))
+	require.NoError(t, err)
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	return err
+}
+
+func TestCredentials(t *testing.T) {
+	pki := newTestPKI(t)
+	serverCert, serverKey := pki.issue(t, "feed-service", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := pki.issue(t, "api-service", x509.ExtKeyUsageClientAuth)
+	caFile := filepath.Join(pki.dir, "ca.pem")
+
+	t.Run("plain text", func(t *testing.T) {
+		address := serve(t, Config{})
+		assert.NoError(t, check(t, address, Config{}))
+	})
+
+	t.Run("TLS", func(t *testing.T) {
+		address := serve(t, Config{Enabled: true, CertFile: serverCert, KeyFile: serverKey})
+		assert.NoError(t, check(t, address, Config{Enabled: true, CAFile: caFile, ServerName: "feed-service"}))
+		assert.Error(t, check(t, address, Config{Enabled: true, CAFile: caFile, ServerName: "user-service"}), "the server name is verified")
+		assert.Error(t, check(t, address, Config{}), "plain text clients are refused")
+	})
+
+	t.Run("mutual TLS", func(t *testing.T) {
+		address := serve(t, Config{Enabled: true, CertFile: serverCert, KeyFile: serverKey, ClientCAFile: caFile})
+		assert.NoError(t, check(t, address, Config{
+			Enabled:        true,
+			CAFile:         caFile,
+			ServerName:     "feed-service",
+			ClientCertFile: clientCert,
+			ClientKeyFile:  clientKey,
+		}))
+		assert.Error(t, check(t, address, Config{Enabled: true, CAFile: caFile, ServerName: "feed-service"}), "clients without a certificate are refused")
+	})
+}
+
+func TestCredentials_InvalidConfig(t *testing.T) {
+	_, err := ServerCredentials(Config{Enabled: true})
+	assert.Error(t, err)
+	_, err = ServerCredentials(Config{Enabled: true, CertFile: "missing.pem", KeyFile: "missing-key.pem"})
+	assert.Error(t, err)
+	_, err = ClientCredentials(Config{Enabled: true, CAFile: "missing.pem"})
+	assert.Error(t, err)
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(empty, []byte("not a certificate"), 0o600))
+	_, err = ClientCredentials(Config{Enabled: true, CAFile: empty})
+	assert.ErrorContains(t, err, "no PEM certificates")
+}
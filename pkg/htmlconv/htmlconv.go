@@ -0,0 +1,460 @@
+// Package htmlconv turns sanitized article HTML into lightweight markup for note-taking
+// tools, into plain text for terminals, or into bare text for screen readers and speech.
+// It covers the elements that survive the feed
+// content sanitizer; anything else is reduced to its text.
+package htmlconv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ToMarkdown converts HTML to CommonMark-flavoured Markdown
+func ToMarkdown(markup string) (string, error) {
+	return render(markup, markdown)
+}
+
+// ToOrg converts HTML to Org-mode markup
+func ToOrg(markup string) (string, error) {
+	return render(markup, org)
+}
+
+// ToText converts HTML to plain text, keeping link targets and the block structure
+func ToText(markup string) (string, error) {
+	return render(markup, text)
+}
+
+// ToPlainText converts HTML to prose without any markup, for screen readers, speech
+// synthesis and language models: paragraphs stay separated by blank lines and links are
+// numbered like footnotes, their targets listed at the end
+func ToPlainText(markup string) (string, error) {
+	return render(markup, plain)
+}
+
+// syntax holds the markup a target format uses for each construct
+type syntax struct {
+	// footnotes numbers links in the text and lists their targets after it instead of
+	// writing them inline with link
+	footnotes bool
+
+	heading   func(level int, text string) string
+	bold      string
+	italic    string
+	code      string
+	link      func(text, href string) string
+	image     func(alt, src string) string
+	codeBlock func(lang, body string) string
+	quote     func(body string) string
+	rule      string
+	table     func(rows [][]string) string
+}
+
+var markdown = syntax{
+	heading: func(level int, text string) string { return strings.Repeat("#", level) + " " + text },
+	bold:    "**",
+	italic:  "_",
+	code:    "`",
+	link:    func(text, href string) string { return fmt.Sprintf("[%s](%s)", text, href) },
+	image:   func(alt, src string) string { return fmt.Sprintf("![%s](%s)", alt, src) },
+	codeBlock: func(lang, body string) string {
+		return "```" + lang + "\n" + body + "\n```"
+	},
+	quote: func(body string) string {
+		lines := strings.Split(body, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return strings.Join(lines, "\n")
+	},
+	rule: "---",
+	table: func(rows [][]string) string {
+		lines := make([]string, 0, len(rows)+1)
+		for i, row := range rows {
+			lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+			if i == 0 {
+				lines = append(lines, "|"+strings.Repeat(" --- |", len(row)))
+			}
+		}
+		return strings.Join(lines, "\n")
+	},
+}
+
+var org = syntax{
+	heading: func(level int, text string) string { return strings.Repeat("*", level) + " " + text },
+	bold:    "*",
+	italic:  "/",
+	code:    "~",
+	link:    func(text, href string) string { return fmt.Sprintf("[[%s][%s]]", href, text) },
+	image:   func(_, src string) string { return fmt.Sprintf("[[%s]]", src) },
+	codeBlock: func(lang, body string) string {
+		if lang == "" {
+			return "#+BEGIN_EXAMPLE\n" + body + "\n#+END_EXAMPLE"
+		}
+		return "#+BEGIN_SRC " + lang + "\n" + body + "\n#+END_SRC"
+	},
+	quote: func(body string) string { return "#+BEGIN_QUOTE\n" + body + "\n#+END_QUOTE" },
+	rule:  "-----",
+	table: func(rows [][]string) string {
+		lines := make([]string, 0, len(rows)+1)
+		for i, row := range rows {
+			lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+			if i == 0 && len(rows) > 1 {
+				lines = append(lines, "|"+strings.Repeat("---+", len(row)-1)+"---|")
+			}
+		}
+		return strings.Join(lines, "\n")
+	},
+}
+
+var text = syntax{
+	heading: func(level int, text string) string {
+		if level == 1 {
+			return strings.ToUpper(text)
+		}
+		return text
+	},
+	link: func(text, href string) string {
+		if text == href {
+			return text
+		}
+		return fmt.Sprintf("%s <%s>", text, href)
+	},
+	image: func(alt, _ string) string {
+		if alt == "" {
+			return "[image]"
+		}
+		return "[image: " + alt + "]"
+	},
+	codeBlock: func(_, body string) string {
+		lines := strings.Split(body, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("    "+line, " ")
+		}
+		return strings.Join(lines, "\n")
+	},
+	quote: func(body string) string {
+		lines := strings.Split(body, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("  | "+line, " ")
+		}
+		return strings.Join(lines, "\n")
+	},
+	rule: "----",
+	table: func(rows [][]string) string {
+		lines := make([]string, 0, len(rows))
+		for _, row := range rows {
+			lines = append(lines, strings.Join(row, "  "))
+		}
+		return strings.Join(lines, "\n")
+	},
+}
+
+var plain = syntax{
+	footnotes: true,
+	heading:   func(_ int, text string) string { return text },
+	link:      func(text, _ string) string { return text },
+	image: func(alt, _ string) string {
+		if alt == "" {
+			return ""
+		}
+		return "Image: " + alt
+	},
+	codeBlock: func(_, body string) string { return body },
+	quote:     func(body string) string { return body },
+	table: func(rows [][]string) string {
+		lines := make([]string, 0, len(rows))
+		for _, row := range rows {
+			lines = append(lines, strings.Join(row, ", "))
+		}
+		return strings.Join(lines, "\n")
+	},
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+func render(markup string, s syntax) (string, error) {
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(markup), root)
+	if err != nil {
+		return "", fmt.Errorf("parse html: %w", err)
+	}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+
+	r := renderer{s: s}
+	if s.footnotes {
+		r.notes = &footnotes{index: make(map[string]int)}
+	}
+	blocks := r.blocks(root)
+	if r.notes != nil && len(r.notes.hrefs) > 0 {
+		blocks = append(blocks, r.notes.list())
+	}
+	out := strings.Join(blocks, "\n\n")
+	if out == "" {
+		return "", nil
+	}
+	return out + "\n", nil
+}
+
+type renderer struct {
+	s     syntax
+	notes *footnotes // nil unless the syntax footnotes links
+}
+
+// footnotes numbers the link targets of a document in order of appearance, a target
+// linked twice keeping its first number
+type footnotes struct {
+	hrefs []string
+	index map[string]int
+}
+
+func (f *footnotes) add(href string) int {
+	if n, ok := f.index[href]; ok {
+		return n
+	}
+	f.hrefs = append(f.hrefs, href)
+	f.index[href] = len(f.hrefs)
+	return len(f.hrefs)
+}
+
+func (f *footnotes) list() string {
+	lines := make([]string, 0, len(f.hrefs)+1)
+	lines = append(lines, "Links:")
+	for i, href := range f.hrefs {
+		lines = append(lines, fmt.Sprintf("[%d] %s", i+1, href))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// blocks renders the children of n as a list of blocks, grouping runs of inline
+// content into paragraphs
+func (r renderer) blocks(n *html.Node) []string {
+	var out []string
+	var inline strings.Builder
+	flush := func() {
+		if text := tidyLines(inline.String()); text != "" {
+			out = append(out, text)
+		}
+		inline.Reset()
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && isBlock(c.DataAtom) {
+			flush()
+			if block := r.block(c); block != "" {
+				out = append(out, block)
+			}
+			continue
+		}
+		inline.WriteString(r.inline(c))
+	}
+	flush()
+	return out
+}
+
+func (r renderer) block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := strings.Join(strings.Fields(r.inlineChildren(n)), " ")
+		if text == "" {
+			return ""
+		}
+		return r.s.heading(int(n.Data[1]-'0'), text)
+	case atom.Ul, atom.Ol:
+		return r.list(n)
+	case atom.Blockquote:
+		body := strings.Join(r.blocks(n), "\n\n")
+		if body == "" {
+			return ""
+		}
+		return r.s.quote(body)
+	case atom.Pre:
+		return r.s.codeBlock(codeLanguage(n), strings.TrimRight(textContent(n), "\n"))
+	case atom.Hr:
+		return r.s.rule
+	case atom.Table:
+		return r.table(n)
+	default:
+		return strings.Join(r.blocks(n), "\n\n")
+	}
+}
+
+// list renders each item with its marker, indenting continuation lines (and nested
+// lists) under the item text
+func (r renderer) list(n *html.Node) string {
+	var items []string
+	number := 1
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+		body := strings.Join(r.blocks(c), "\n")
+		indent := strings.Repeat(" ", len(marker))
+		items = append(items, marker+strings.ReplaceAll(body, "\n", "\n"+indent))
+	}
+	return strings.Join(items, "\n")
+}
+
+func (r renderer) table(n *html.Node) string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if c.DataAtom != atom.Tr {
+				walk(c)
+				continue
+			}
+			var row []string
+			for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+					text := strings.Join(strings.Fields(r.inlineChildren(cell)), " ")
+					row = append(row, strings.ReplaceAll(text, "|", `\|`))
+				}
+			}
+			if len(row) > 0 {
+				rows = append(rows, row)
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return ""
+	}
+	return r.s.table(rows)
+}
+
+func (r renderer) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return whitespace.ReplaceAllString(n.Data, " ")
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "\n"
+	case atom.Strong, atom.B:
+		return wrap(r.inlineChildren(n), r.s.bold)
+	case atom.Em, atom.I:
+		return wrap(r.inlineChildren(n), r.s.italic)
+	case atom.Code:
+		return wrap(textContent(n), r.s.code)
+	case atom.A:
+		text := strings.TrimSpace(r.inlineChildren(n))
+		href := attr(n, "href")
+		if href == "" {
+			return text
+		}
+		if text == "" {
+			text = href
+		}
+		if r.notes != nil && text != href {
+			return fmt.Sprintf("%s [%d]", text, r.notes.add(href))
+		}
+		return r.s.link(text, href)
+	case atom.Img:
+		src := attr(n, "src")
+		if src == "" {
+			return ""
+		}
+		return r.s.image(attr(n, "alt"), src)
+	case atom.Script, atom.Style:
+		return ""
+	default:
+		return r.inlineChildren(n)
+	}
+}
+
+func (r renderer) inlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(r.inline(c))
+	}
+	return b.String()
+}
+
+// wrap surrounds text with an emphasis marker, keeping the surrounding spaces outside
+// so the markup stays valid
+func wrap(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	start := strings.Index(text, trimmed)
+	return text[:start] + marker + trimmed + marker + text[start+len(trimmed):]
+}
+
+// tidyLines trims each line of a paragraph and drops blank lines
+func tidyLines(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func isBlock(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.Header, atom.Footer, atom.Aside, atom.Nav,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Ul, atom.Ol, atom.Li, atom.Dl, atom.Dt, atom.Dd,
+		atom.Blockquote, atom.Pre, atom.Hr, atom.Table,
+		atom.Figure, atom.Figcaption, atom.Details, atom.Summary:
+		return true
+	}
+	return false
+}
+
+// codeLanguage reads the language from a "language-x" class on the pre or its code element
+func codeLanguage(pre *html.Node) string {
+	candidates := []*html.Node{pre}
+	if c := pre.FirstChild; c != nil && c.DataAtom == atom.Code {
+		candidates = append(candidates, c)
+	}
+	for _, n := range candidates {
+		for _, class := range strings.Fields(attr(n, "class")) {
+			if lang, ok := strings.CutPrefix(class, "language-"); ok {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package htmlconv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sample = `<h2>Release  notes</h2>
+<p>Version <strong>2.0</strong> is <em>out</em>. See <a href="https://example.com/changelog">the changelog</a>.<br>Thanks!</p>
+<ul><li>Faster sync<ul><li>Twice as fast</li></ul></li><li>New <code>--dry-run</code> flag</li></ul>
+<ol><li>Download</li><li>Install</li></ol>
+<blockquote><p>It just works.</p></blockquote>
+<pre><code class="language-go">fmt.Println("hi")
+</code></pre>
+<img src="https://example.com/shot.png" alt="Screenshot">
+<table><tr><th>Plan</th><th>Price</th></tr><tr><td>Pro</td><td>$5</td></tr></table>`
+
+func TestToMarkdown(t *testing.T) {
+	got, err := ToMarkdown(sample)
+	require.NoError(t, err)
+
+	want := "## Release notes\n\n" +
+		"Version **2.0** is _out_. See [the changelog](https://example.com/changelog).\nThanks!\n\n" +
+		"- Faster sync\n  - Twice as fast\n- New `--dry-run` flag\n\n" +
+		"1. Download\n2. Install\n\n" +
+		"> It just works.\n\n" +
+		"```go\nfmt.Println(\"hi\")\n```\n\n" +
+		"![Screenshot](https://example.com/shot.png)\n\n" +
+		"| Plan | Price |\n| --- | --- |\n| Pro | $5 |\n"
+	assert.Equal(t, want, got)
+}
+
+func TestToOrg(t *testing.T) {
+	got, err := ToOrg(sample)
+	require.NoError(t, err)
+
+	want := "** Release notes\n\n" +
+		"Version *2.0* is /out/. See [[https://example.com/changelog][the changelog]].\nThanks!\n\n" +
+		"- Faster sync\n  - Twice as fast\n- New ~--dry-run~ flag\n\n" +
+		"1. Download\n2. Install\n\n" +
+		"#+BEGIN_QUOTE\nIt just works.\n#+END_QUOTE\n\n" +
+		"#+BEGIN_SRC go\nfmt.Println(\"hi\")\n#+END_SRC\n\n" +
+		"[[https://example.com/shot.png]]\n\n" +
+		"| Plan | Price |\n|---+---|\n| Pro | $5 |\n"
+	assert.Equal(t, want, got)
+}
+
+func TestToText(t *testing.T) {
+	got, err := ToText(sample)
+	require.NoError(t, err)
+
+	want := "Release notes\n\n" +
+		"Version 2.0 is out. See the changelog <https://example.com/changelog>.\nThanks!\n\n" +
+		"- Faster sync\n  - Twice as fast\n- New --dry-run flag\n\n" +
+		"1. Download\n2. Install\n\n" +
+		"  | It just works.\n\n" +
+		"    fmt.Println(\"hi\")\n\n" +
+		"[image: Screenshot]\n\n" +
+		"Plan  Price\nPro  $5\n"
+	assert.Equal(t, want, got)
+}
+
+func TestToPlainText(t *testing.T) {
+	got, err := ToPlainText(sample + `<hr><p>Read <a href="https://example.com/changelog">it again</a> at <a href="https://example.com">https://example.com</a>.</p>`)
+	require.NoError(t, err)
+
+	want := "Release notes\n\n" +
+		"Version 2.0 is out. See the changelog [1].\nThanks!\n\n" +
+		"- Faster sync\n  - Twice as fast\n- New --dry-run flag\n\n" +
+		"1. Download\n2. Install\n\n" +
+		"It just works.\n\n" +
+		"fmt.Println(\"hi\")\n\n" +
+		"Image: Screenshot\n\n" +
+		"Plan, Price\nPro, $5\n\n" +
+		"Read it again [1] at https://example.com.\n\n" +
+		"Links:\n[1] https://example.com/changelog\n"
+	assert.Equal(t, want, got)
+}
+
+func TestEmphasisKeepsSpacesOutside(t *testing.T) {
+	got, err := ToMarkdown("<p>a<strong> bold </strong>b</p>")
+	require.NoError(t, err)
+	assert.Equal(t, "a **bold** b\n", got)
+}
+
+func TestEmptyInput(t *testing.T) {
+	got, err := ToOrg("  ")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
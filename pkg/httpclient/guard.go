@@ -0,0 +1,37 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"syscall"
+)
+
+// ErrBlockedAddress is returned when a Guard refuses the address a request would connect to
+var ErrBlockedAddress = errors.New("address blocked by outbound request guard")
+
+// Guard vets an address before the client connects to it. It runs after DNS resolution,
+// so a hostname cannot be re-pointed at a blocked address between check and connect.
+type Guard func(addr netip.Addr) error
+
+func (g Guard) control(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: unparsable address %q", ErrBlockedAddress, address)
+	}
+	return g(addrPort.Addr().Unmap())
+}
+
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// BlockPrivateNetworks refuses loopback, private, link-local (including cloud metadata
+// endpoints), shared and unspecified addresses
+func BlockPrivateNetworks(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsUnspecified() || addr.IsMulticast() ||
+		sharedAddressSpace.Contains(addr) {
+		return fmt.Errorf("%w: %s is not a public address", ErrBlockedAddress, addr)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+// Package httpclient builds the HTTP clients used for every outbound request: retries with
+// backoff, response size limits, per-attempt deadlines, request metrics, a fixed client
+// identity, optional per-host rate limits and an optional guard against requests into
+// private networks.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ErrBodyTooLarge is returned when a response body exceeds the configured limit
+var ErrBodyTooLarge = errors.New("response body exceeds configured limit")
+
+// Options configures a client. The zero value is a plain client without timeout,
+// retries or limits.
+type Options struct {
+	// Name identifies the client in metrics, e.g. "feed" or "robots"
+	Name string
+	// Timeout bounds each attempt, from sending the request to closing the body.
+	// The request context bounds the whole call, retries included.
+	Timeout time.Duration
+	// UserAgent replaces the User-Agent of every request when set
+	UserAgent string
+	// Header is added to every request that does not set the header itself
+	Header http.Header
+	// MaxBodyBytes caps response bodies; WithMaxBodyBytes overrides it per request
+	MaxBodyBytes int64
+	Retry        RetryPolicy
+	// Guard vets every address the client dials, after DNS resolution
+	Guard   Guard
+	Metrics Metrics
+	// Limiter is waited on before every attempt, retries included
+	Limiter RateLimiter
+	// Transport replaces the default transport, mostly for tests. Guard is not applied to it.
+	Transport http.RoundTripper
+}
+
+// New returns a client configured with opts
+func New(opts Options) *http.Client {
+	base := opts.Transport
+	if base == nil {
+		base = defaultTransport(opts.Guard)
+	}
+
+	var transport http.RoundTripper = &limitTransport{base: base, limit: opts.MaxBodyBytes}
+	transport = &headerTransport{base: transport, userAgent: opts.UserAgent, header: opts.Header}
+	transport = &retryTransport{
+		base:    transport,
+		name:    opts.Name,
+		timeout: opts.Timeout,
+		policy:  opts.Retry.normalized(),
+		metrics: opts.Metrics,
+		limiter: opts.Limiter,
+	}
+	transport = &recordTransport{base: transport}
+	return &http.Client{Transport: transport}
+}
+
+func defaultTransport(guard Guard) http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if guard != nil {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   guard.control,
+		}
+		transport.DialContext = dialer.DialContext
+	}
+	return transport
+}
+
+// RateLimiter paces the requests sent to each host
+type RateLimiter interface {
+	// Wait blocks until a request to host may be sent, or fails when ctx is done first
+	Wait(ctx context.Context, host string) error
+}
+
+type headersKey struct{}
+
+// WithHeaders attaches extra headers to the requests made with ctx. They never replace
+// the client's User-Agent.
+func WithHeaders(ctx context.Context, headers map[string]string) context.Context {
+	if len(headers) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, headersKey{}, headers)
+}
+
+type maxBodyKey struct{}
+
+// WithMaxBodyBytes overrides the client's response size limit for requests made with ctx
+func WithMaxBodyBytes(ctx context.Context, limit int64) context.Context {
+	return context.WithValue(ctx, maxBodyKey{}, limit)
+}
+
+// headerTransport stamps the client identity on each request. The User-Agent is always
+// replaced so libraries with their own default (gofeed) cannot leak it.
+type headerTransport struct {
+	base      http.RoundTripper
+	userAgent string
+	header    http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if headers, ok := req.Context().Value(headersKey{}).(map[string]string); ok {
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+	}
+	for name, values := range t.header {
+		if req.Header.Get(name) == "" {
+			req.Header[name] = values
+		}
+	}
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// limitTransport fails responses whose body is larger than the limit, up front when
+// Content-Length gives it away and otherwise once the reader crosses it
+type limitTransport struct {
+	base  http.RoundTripper
+	limit int64
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limit := t.limit
+	if override, ok := req.Context().Value(maxBodyKey{}).(int64); ok {
+		limit = override
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || limit <= 0 {
+		return resp, err
+	}
+
+	if resp.ContentLength > limit {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("%w: content-length %d exceeds %d", ErrBodyTooLarge, resp.ContentLength, limit)
+	}
+
+	resp.Body = &limitedReadCloser{reader: resp.Body, remaining: limit}
+	return resp, nil
+}
+
+type limitedReadCloser struct {
+	reader    io.ReadCloser
+	remaining int64
+	err       error
+}
+
+func (l *limitedReadCloser) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if l.remaining <= 0 {
+		// one more byte tells a body of exactly the limit apart from a larger one
+		var probe [1]byte
+		n, err := l.reader.Read(probe[:])
+		if n > 0 {
+			l.err = ErrBodyTooLarge
+			return 0, l.err
+		}
+		l.err = err
+		return 0, err
+	}
+
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.reader.Read(p)
+	l.remaining -= int64(n)
+	l.err = err
+	return n, err
+}
+
+func (l *limitedReadCloser) Close() error {
+	return l.reader.Close()
+}
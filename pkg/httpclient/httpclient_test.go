@@ -0,0 +1,214 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMetrics struct {
+	mu           sync.Mutex
+	observations []RequestMetrics
+}
+
+func (r *recordingMetrics) ObserveRequest(m RequestMetrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observations = append(r.observations, m)
+}
+
+func fastRetry(attempts int) RetryPolicy {
+	return RetryPolicy{MaxAttempts: attempts, BackoffInitial: time.Millisecond, BackoffMax: time.Millisecond}
+}
+
+func TestClient_RetriesRetryableStatus(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	metrics := &recordingMetrics{}
+	client := New(Options{Name: "test", Retry: fastRetry(3), Metrics: metrics})
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, hits)
+
+	require.Len(t, metrics.observations, 3)
+	assert.Equal(t, http.StatusServiceUnavailable, metrics.observations[0].StatusCode)
+	assert.Equal(t, 3, metrics.observations[2].Attempt)
+	assert.Equal(t, "test", metrics.observations[2].Client)
+}
+
+func TestClient_ReturnsLastResponseWhenAttemptsRunOut(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	resp, err := New(Options{Retry: fastRetry(2)}).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, 2, hits)
+}
+
+func TestClient_DoesNotRetryPost(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	resp, err := New(Options{Retry: fastRetry(3)}).Post(server.URL, "text/plain", strings.NewReader("body"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 1, hits)
+}
+
+func TestClient_LimitsBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/declared" {
+			w.Header().Set("Content-Length", "100")
+		} else {
+			w.(http.Flusher).Flush() // chunked, no Content-Length
+		}
+		_, _ = w.Write([]byte(strings.Repeat("a", 100)))
+	}))
+	defer server.Close()
+
+	client := New(Options{MaxBodyBytes: 10})
+
+	_, err := client.Get(server.URL + "/declared")
+	require.ErrorIs(t, err, ErrBodyTooLarge)
+
+	resp, err := client.Get(server.URL + "/streamed")
+	require.NoError(t, err)
+	_, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.ErrorIs(t, err, ErrBodyTooLarge)
+
+	req, err := http.NewRequestWithContext(WithMaxBodyBytes(context.Background(), 100), http.MethodGet, server.URL+"/streamed", nil)
+	require.NoError(t, err)
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err, "a body of exactly the limit is allowed")
+	assert.Len(t, body, 100)
+}
+
+func TestClient_SendsIdentityAndContextHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	client := New(Options{UserAgent: "ExampleBot/1.0", Header: http.Header{"From": {"ops@example.com"}}})
+
+	ctx := WithHeaders(context.Background(), map[string]string{"X-Api-Key": "secret", "User-Agent": "spoofed"})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, "ExampleBot/1.0", got.Get("User-Agent"))
+	assert.Equal(t, "ops@example.com", got.Get("From"))
+	assert.Equal(t, "secret", got.Get("X-Api-Key"))
+}
+
+func TestClient_TimeoutAppliesPerAttempt(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits == 1 {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	resp, err := New(Options{Timeout: 50 * time.Millisecond, Retry: fastRetry(2)}).Get(server.URL)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+}
+
+func TestClient_GuardBlocksPrivateAddresses(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	_, err := New(Options{Guard: BlockPrivateNetworks, Retry: fastRetry(3)}).Get(server.URL)
+	require.ErrorIs(t, err, ErrBlockedAddress)
+	assert.Zero(t, hits)
+}
+
+func TestBlockPrivateNetworks(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1", "10.1.2.3", "192.168.0.1", "169.254.169.254", "100.64.0.1", "::1", "fd00::1", "::ffff:10.0.0.1", "0.0.0.0"} {
+		assert.ErrorIs(t, BlockPrivateNetworks(netip.MustParseAddr(addr)), ErrBlockedAddress, addr)
+	}
+	for _, addr := range []string{"93.184.216.34", "2606:2800:220:1::1"} {
+		assert.NoError(t, BlockPrivateNetworks(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestClient_RecordsFinalResponse(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte("<rss>0123456789</rss>"))
+	}))
+	defer server.Close()
+
+	rec := NewRecording(10)
+	req, err := http.NewRequestWithContext(WithRecording(context.Background(), rec), http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := New(Options{Retry: fastRetry(2)}).Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+
+	assert.Equal(t, "<rss>0123456789</rss>", string(body), "the caller still gets the whole body")
+	assert.Equal(t, http.StatusOK, rec.StatusCode())
+	assert.Equal(t, "application/rss+xml", rec.Header().Get("Content-Type"))
+	recorded, truncated := rec.Body()
+	assert.Equal(t, "<rss>01234", string(recorded))
+	assert.True(t, truncated)
+}
@@ -0,0 +1,43 @@
+package httpclient
+
+import (
+	"log/slog"
+	"time"
+)
+
+// RequestMetrics describes one attempt of an outbound request
+type RequestMetrics struct {
+	Client     string
+	Method     string
+	Host       string
+	Attempt    int
+	StatusCode int // 0 when the attempt failed in transit
+	Duration   time.Duration
+	Err        error
+}
+
+// Metrics receives an observation for every request attempt
+type Metrics interface {
+	ObserveRequest(m RequestMetrics)
+}
+
+// LogMetrics records each attempt as a debug log line, and failed attempts as warnings
+type LogMetrics struct {
+	Logger *slog.Logger
+}
+
+func (l LogMetrics) ObserveRequest(m RequestMetrics) {
+	attrs := []any{
+		"client", m.Client,
+		"method", m.Method,
+		"host", m.Host,
+		"attempt", m.Attempt,
+		"status", m.StatusCode,
+		"duration_ms", m.Duration.Milliseconds(),
+	}
+	if m.Err != nil {
+		l.Logger.Warn("outbound request failed", append(attrs, "error", m.Err.Error())...)
+		return
+	}
+	l.Logger.Debug("outbound request", attrs...)
+}
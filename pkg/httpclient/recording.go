@@ -0,0 +1,104 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Recording captures the final response of a request made with a context from
+// WithRecording: status, headers and the first MaxBytes of the body as the caller read it
+type Recording struct {
+	MaxBytes int64
+
+	mu         sync.Mutex
+	statusCode int
+	header     http.Header
+	body       bytes.Buffer
+	truncated  bool
+}
+
+// NewRecording returns a recording that keeps at most maxBytes of the response body
+func NewRecording(maxBytes int64) *Recording {
+	return &Recording{MaxBytes: maxBytes}
+}
+
+// StatusCode returns the recorded status, 0 when no response arrived
+func (r *Recording) StatusCode() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.statusCode
+}
+
+// Header returns the recorded response headers
+func (r *Recording) Header() http.Header {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.header.Clone()
+}
+
+// Body returns the recorded body and whether it was cut at MaxBytes
+func (r *Recording) Body() ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return bytes.Clone(r.body.Bytes()), r.truncated
+}
+
+func (r *Recording) start(resp *http.Response) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statusCode = resp.StatusCode
+	r.header = resp.Header.Clone()
+	r.body.Reset()
+	r.truncated = false
+}
+
+func (r *Recording) write(p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	room := r.MaxBytes - int64(r.body.Len())
+	if int64(len(p)) > room {
+		p = p[:max(room, 0)]
+		r.truncated = true
+	}
+	r.body.Write(p)
+}
+
+type recordingKey struct{}
+
+// WithRecording records the final response of the requests made with ctx into rec
+func WithRecording(ctx context.Context, rec *Recording) context.Context {
+	return context.WithValue(ctx, recordingKey{}, rec)
+}
+
+// recordTransport tees the response that reaches the caller, after retries, into the
+// context's Recording
+type recordTransport struct {
+	base http.RoundTripper
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	rec, ok := req.Context().Value(recordingKey{}).(*Recording)
+	if err != nil || !ok || rec == nil {
+		return resp, err
+	}
+	rec.start(resp)
+	resp.Body = &recordingReadCloser{ReadCloser: resp.Body, rec: rec}
+	return resp, nil
+}
+
+type recordingReadCloser struct {
+	io.ReadCloser
+	rec *Recording
+}
+
+func (r *recordingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.rec.write(p[:n])
+	}
+	return n, err
+}
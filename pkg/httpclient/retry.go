@@ -0,0 +1,157 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy retries idempotent requests that failed in transit or got a retryable
+// status (408, 429, 5xx). When attempts run out the last response is returned as is.
+type RetryPolicy struct {
+	MaxAttempts    int // 0 or 1 disables retries
+	BackoffInitial time.Duration
+	BackoffMax     time.Duration
+	Jitter         bool // wait a random 50-150% of the backoff
+}
+
+func (p RetryPolicy) normalized() RetryPolicy {
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = 1
+	}
+	if p.BackoffInitial <= 0 {
+		p.BackoffInitial = 500 * time.Millisecond
+	}
+	if p.BackoffMax < p.BackoffInitial {
+		p.BackoffMax = 10 * time.Second
+	}
+	return p
+}
+
+func (p RetryPolicy) wait(backoff time.Duration) time.Duration {
+	if !p.Jitter {
+		return backoff
+	}
+	return time.Duration(rand.Int63n(int64(backoff))) + backoff/2
+}
+
+// IsRetryableStatus reports whether a response status is worth retrying
+func IsRetryableStatus(code int) bool {
+	if code == http.StatusTooManyRequests || code == http.StatusRequestTimeout {
+		return true
+	}
+	return code >= 500
+}
+
+// retryTransport paces each attempt with the limiter, runs it under its own deadline,
+// reports it to the metrics and retries while the policy allows
+type retryTransport struct {
+	base    http.RoundTripper
+	name    string
+	timeout time.Duration
+	policy  RetryPolicy
+	metrics Metrics
+	limiter RateLimiter
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := t.policy.MaxAttempts
+	if !replayable(req) {
+		attempts = 1
+	}
+
+	backoff := t.policy.BackoffInitial
+	for attempt := 1; ; attempt++ {
+		if t.limiter != nil {
+			if err := t.limiter.Wait(req.Context(), req.URL.Hostname()); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := t.attempt(req, attempt)
+		if attempt >= attempts || !shouldRetry(req.Context(), resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 512))
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(t.policy.wait(backoff)):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		backoff *= 2
+		if backoff > t.policy.BackoffMax {
+			backoff = t.policy.BackoffMax
+		}
+	}
+}
+
+func (t *retryTransport) attempt(req *http.Request, attempt int) (*http.Response, error) {
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if t.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+
+	if t.metrics != nil {
+		observation := RequestMetrics{
+			Client:   t.name,
+			Method:   req.Method,
+			Host:     req.URL.Host,
+			Attempt:  attempt,
+			Duration: time.Since(start),
+			Err:      err,
+		}
+		if resp != nil {
+			observation.StatusCode = resp.StatusCode
+		}
+		t.metrics.ObserveRequest(observation)
+	}
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the deadline keeps running until the caller is done with the body
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, ErrBlockedAddress) && !errors.Is(err, ErrBodyTooLarge)
+	}
+	return IsRetryableStatus(resp.StatusCode)
+}
+
+// replayable reports whether the request can safely be sent again
+func replayable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.Body == http.NoBody
+	default:
+		return false
+	}
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -1,6 +1,7 @@
 package ierr
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 )
@@ -36,21 +37,45 @@ func (e *AppError) WithCause(cause error) *AppError {
 // Predefined application errors
 var (
 	// User-related errors (1000-1099)
-	ErrUserExists         = &AppError{Code: 1001, Message: "Username already exists", HTTPStatus: http.StatusConflict}
-	ErrInvalidCredentials = &AppError{Code: 1002, Message: "Invalid credentials", HTTPStatus: http.StatusUnauthorized}
-	ErrUserNotFound       = &AppError{Code: 1003, Message: "User not found", HTTPStatus: http.StatusNotFound}
-	ErrInvalidToken       = &AppError{Code: 1004, Message: "Invalid or expired token", HTTPStatus: http.StatusUnauthorized}
+	ErrUserExists           = &AppError{Code: 1001, Message: "Username already exists", HTTPStatus: http.StatusConflict}
+	ErrInvalidCredentials   = &AppError{Code: 1002, Message: "Invalid credentials", HTTPStatus: http.StatusUnauthorized}
+	ErrUserNotFound         = &AppError{Code: 1003, Message: "User not found", HTTPStatus: http.StatusNotFound}
+	ErrInvalidToken         = &AppError{Code: 1004, Message: "Invalid or expired token", HTTPStatus: http.StatusUnauthorized}
+	ErrCredentialNotFound   = &AppError{Code: 1005, Message: "LLM credential not found", HTTPStatus: http.StatusNotFound}
+	ErrNotificationNotFound = &AppError{Code: 1006, Message: "Notification not found", HTTPStatus: http.StatusNotFound}
+	ErrLoginLocked          = &AppError{Code: 1007, Message: "Too many failed login attempts, try again later", HTTPStatus: http.StatusTooManyRequests}
+	ErrChallengeRequired    = &AppError{Code: 1008, Message: "Login challenge required", HTTPStatus: http.StatusUnauthorized}
+	ErrSessionNotFound      = &AppError{Code: 1009, Message: "Session not found", HTTPStatus: http.StatusNotFound}
+	ErrEmailTaken           = &AppError{Code: 1010, Message: "Email address already in use", HTTPStatus: http.StatusConflict}
+	ErrWrongPassword        = &AppError{Code: 1011, Message: "Current password is incorrect", HTTPStatus: http.StatusForbidden}
+	ErrInvalidResetToken    = &AppError{Code: 1012, Message: "Invalid or expired password reset token", HTTPStatus: http.StatusBadRequest}
 
 	// Feed-related errors (1100-1199)
-	ErrFeedNotFound      = &AppError{Code: 1101, Message: "Feed not found", HTTPStatus: http.StatusNotFound}
-	ErrFeedAlreadyExists = &AppError{Code: 1102, Message: "Feed already exists", HTTPStatus: http.StatusConflict}
-	ErrInvalidFeedURL    = &AppError{Code: 1103, Message: "Invalid feed URL", HTTPStatus: http.StatusBadRequest}
-	ErrFeedFetchFailed   = &AppError{Code: 1104, Message: "Failed to fetch feed", HTTPStatus: http.StatusBadGateway}
-	ErrNotSubscribed     = &AppError{Code: 1105, Message: "Not subscribed to this feed", HTTPStatus: http.StatusForbidden}
-	ErrAlreadySubscribed = &AppError{Code: 1106, Message: "Already subscribed to this feed", HTTPStatus: http.StatusConflict}
+	ErrFeedNotFound         = &AppError{Code: 1101, Message: "Feed not found", HTTPStatus: http.StatusNotFound}
+	ErrFeedAlreadyExists    = &AppError{Code: 1102, Message: "Feed already exists", HTTPStatus: http.StatusConflict}
+	ErrInvalidFeedURL       = &AppError{Code: 1103, Message: "Invalid feed URL", HTTPStatus: http.StatusBadRequest}
+	ErrFeedFetchFailed      = &AppError{Code: 1104, Message: "Failed to fetch feed", HTTPStatus: http.StatusBadGateway}
+	ErrNotSubscribed        = &AppError{Code: 1105, Message: "Not subscribed to this feed", HTTPStatus: http.StatusForbidden}
+	ErrAlreadySubscribed    = &AppError{Code: 1106, Message: "Already subscribed to this feed", HTTPStatus: http.StatusConflict}
+	ErrSnapshotNotFound     = &AppError{Code: 1107, Message: "Feed snapshot not found", HTTPStatus: http.StatusNotFound}
+	ErrCollectionNotFound   = &AppError{Code: 1108, Message: "Feed collection not found", HTTPStatus: http.StatusNotFound}
+	ErrCollectionExists     = &AppError{Code: 1109, Message: "Feed collection slug already in use", HTTPStatus: http.StatusConflict}
+	ErrSubscriptionLimit    = &AppError{Code: 1110, Message: "Subscription limit reached", HTTPStatus: http.StatusForbidden}
+	ErrFolderNotFound       = &AppError{Code: 1111, Message: "Folder not found", HTTPStatus: http.StatusNotFound}
+	ErrFolderExists         = &AppError{Code: 1112, Message: "A folder with this name already exists here", HTTPStatus: http.StatusConflict}
+	ErrExportNotFound       = &AppError{Code: 1113, Message: "Export not found", HTTPStatus: http.StatusNotFound}
+	ErrExportNotReady       = &AppError{Code: 1114, Message: "Export is not ready", HTTPStatus: http.StatusConflict}
+	ErrUpstreamTimeout      = &AppError{Code: 1115, Message: "Feed server did not respond in time", HTTPStatus: http.StatusGatewayTimeout}
+	ErrUpstreamForbidden    = &AppError{Code: 1116, Message: "Feed server refused access", HTTPStatus: http.StatusBadGateway}
+	ErrTooManyRequests      = &AppError{Code: 1117, Message: "Feed server is rate limiting requests", HTTPStatus: http.StatusServiceUnavailable}
+	ErrUnsupportedFormat    = &AppError{Code: 1118, Message: "Not a supported feed format", HTTPStatus: http.StatusUnprocessableEntity}
+	ErrDigestNotFound       = &AppError{Code: 1119, Message: "Folder has no digest", HTTPStatus: http.StatusNotFound}
+	ErrSharedFolderNotFound = &AppError{Code: 1120, Message: "Shared folder not found", HTTPStatus: http.StatusNotFound}
+	ErrShareSlugTaken       = &AppError{Code: 1121, Message: "Share slug already in use", HTTPStatus: http.StatusConflict}
 
 	// Article-related errors (1200-1299)
 	ErrArticleNotFound = &AppError{Code: 1201, Message: "Article not found", HTTPStatus: http.StatusNotFound}
+	ErrBriefingFailed  = &AppError{Code: 1202, Message: "Briefing could not be generated", HTTPStatus: http.StatusBadGateway}
 
 	// Validation errors (1300-1399)
 	ErrInvalidInput  = &AppError{Code: 1301, Message: "Invalid input", HTTPStatus: http.StatusBadRequest}
@@ -58,13 +83,17 @@ var (
 	ErrInvalidFeedID = &AppError{Code: 1303, Message: "Invalid feed ID", HTTPStatus: http.StatusBadRequest}
 
 	// Authorization errors (1400-1499)
-	ErrUnauthorized = &AppError{Code: 1401, Message: "Authentication required", HTTPStatus: http.StatusUnauthorized}
-	ErrForbidden    = &AppError{Code: 1402, Message: "Access denied", HTTPStatus: http.StatusForbidden}
+	ErrUnauthorized          = &AppError{Code: 1401, Message: "Authentication required", HTTPStatus: http.StatusUnauthorized}
+	ErrForbidden             = &AppError{Code: 1402, Message: "Access denied", HTTPStatus: http.StatusForbidden}
+	ErrReadOnlyDemo          = &AppError{Code: 1403, Message: "This is a read-only demo instance, changes are disabled", HTTPStatus: http.StatusForbidden}
+	ErrImpersonationReadOnly = &AppError{Code: 1404, Message: "Impersonation is read-only, changes are disabled", HTTPStatus: http.StatusForbidden}
+	ErrRateLimited           = &AppError{Code: 1405, Message: "Too many requests, try again later", HTTPStatus: http.StatusTooManyRequests}
 
 	// System errors (9000+)
-	ErrInternalServer = &AppError{Code: 9001, Message: "Internal server error", HTTPStatus: http.StatusInternalServerError}
-	ErrDatabaseError  = &AppError{Code: 9002, Message: "Database error", HTTPStatus: http.StatusInternalServerError}
-	ErrTaskQueueError = &AppError{Code: 9003, Message: "Task queue error", HTTPStatus: http.StatusInternalServerError}
+	ErrInternalServer     = &AppError{Code: 9001, Message: "Internal server error", HTTPStatus: http.StatusInternalServerError}
+	ErrDatabaseError      = &AppError{Code: 9002, Message: "Database error", HTTPStatus: http.StatusInternalServerError}
+	ErrTaskQueueError     = &AppError{Code: 9003, Message: "Task queue error", HTTPStatus: http.StatusInternalServerError}
+	ErrServiceUnavailable = &AppError{Code: 9004, Message: "Service temporarily unavailable", HTTPStatus: http.StatusServiceUnavailable}
 )
 
 // NewAppError create a new AppError with the given parameters
@@ -85,6 +114,16 @@ func NewValidationError(message string) *AppError {
 	}
 }
 
+// NewInvalidQueryError create a validation error for query parameters that are malformed
+// or out of range
+func NewInvalidQueryError(message string) *AppError {
+	return &AppError{
+		Code:       1302,
+		Message:    message,
+		HTTPStatus: http.StatusUnprocessableEntity,
+	}
+}
+
 // IsValidationError check if the error is a validation error
 func IsValidationError(err error) bool {
 	if appErr, ok := err.(*AppError); ok {
@@ -124,3 +163,20 @@ func IsAlreadyExists(err error) bool {
 	}
 	return false
 }
+
+// UpstreamErrors lists the errors that blame the feed's own server rather than this service
+var UpstreamErrors = []*AppError{ErrFeedFetchFailed, ErrUpstreamTimeout, ErrUpstreamForbidden, ErrTooManyRequests, ErrUnsupportedFormat}
+
+// IsUpstreamError check if the error is a failure of the feed's server, anywhere in the chain
+func IsUpstreamError(err error) bool {
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		return false
+	}
+	for _, upstream := range UpstreamErrors {
+		if appErr.Code == upstream.Code {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package ierr
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"testing"
 
@@ -63,14 +64,37 @@ func TestPredefinedErrors(t *testing.T) {
 		{"ErrInvalidCredentials", ErrInvalidCredentials, 1002, http.StatusUnauthorized},
 		{"ErrUserNotFound", ErrUserNotFound, 1003, http.StatusNotFound},
 		{"ErrInvalidToken", ErrInvalidToken, 1004, http.StatusUnauthorized},
+		{"ErrCredentialNotFound", ErrCredentialNotFound, 1005, http.StatusNotFound},
+		{"ErrSessionNotFound", ErrSessionNotFound, 1009, http.StatusNotFound},
+		{"ErrEmailTaken", ErrEmailTaken, 1010, http.StatusConflict},
+		{"ErrWrongPassword", ErrWrongPassword, 1011, http.StatusForbidden},
+		{"ErrInvalidResetToken", ErrInvalidResetToken, 1012, http.StatusBadRequest},
 		{"ErrFeedNotFound", ErrFeedNotFound, 1101, http.StatusNotFound},
 		{"ErrInvalidFeedURL", ErrInvalidFeedURL, 1103, http.StatusBadRequest},
 		{"ErrNotSubscribed", ErrNotSubscribed, 1105, http.StatusForbidden},
+		{"ErrCollectionNotFound", ErrCollectionNotFound, 1108, http.StatusNotFound},
+		{"ErrSubscriptionLimit", ErrSubscriptionLimit, 1110, http.StatusForbidden},
+		{"ErrFolderNotFound", ErrFolderNotFound, 1111, http.StatusNotFound},
+		{"ErrFolderExists", ErrFolderExists, 1112, http.StatusConflict},
+		{"ErrExportNotFound", ErrExportNotFound, 1113, http.StatusNotFound},
+		{"ErrExportNotReady", ErrExportNotReady, 1114, http.StatusConflict},
+		{"ErrUpstreamTimeout", ErrUpstreamTimeout, 1115, http.StatusGatewayTimeout},
+		{"ErrUpstreamForbidden", ErrUpstreamForbidden, 1116, http.StatusBadGateway},
+		{"ErrTooManyRequests", ErrTooManyRequests, 1117, http.StatusServiceUnavailable},
+		{"ErrUnsupportedFormat", ErrUnsupportedFormat, 1118, http.StatusUnprocessableEntity},
+		{"ErrDigestNotFound", ErrDigestNotFound, 1119, http.StatusNotFound},
+		{"ErrSharedFolderNotFound", ErrSharedFolderNotFound, 1120, http.StatusNotFound},
+		{"ErrShareSlugTaken", ErrShareSlugTaken, 1121, http.StatusConflict},
+		{"ErrBriefingFailed", ErrBriefingFailed, 1202, http.StatusBadGateway},
 		{"ErrInvalidInput", ErrInvalidInput, 1301, http.StatusBadRequest},
 		{"ErrUnauthorized", ErrUnauthorized, 1401, http.StatusUnauthorized},
 		{"ErrForbidden", ErrForbidden, 1402, http.StatusForbidden},
+		{"ErrReadOnlyDemo", ErrReadOnlyDemo, 1403, http.StatusForbidden},
+		{"ErrImpersonationReadOnly", ErrImpersonationReadOnly, 1404, http.StatusForbidden},
+		{"ErrRateLimited", ErrRateLimited, 1405, http.StatusTooManyRequests},
 		{"ErrInternalServer", ErrInternalServer, 9001, http.StatusInternalServerError},
 		{"ErrDatabaseError", ErrDatabaseError, 9002, http.StatusInternalServerError},
+		{"ErrServiceUnavailable", ErrServiceUnavailable, 9004, http.StatusServiceUnavailable},
 	}
 
 	for _, tt := range tests {
@@ -82,6 +106,17 @@ func TestPredefinedErrors(t *testing.T) {
 	}
 }
 
+func TestIsUpstreamError(t *testing.T) {
+	cause := errors.New("dial tcp: connection refused")
+
+	assert.True(t, IsUpstreamError(ErrUpstreamTimeout))
+	assert.True(t, IsUpstreamError(fmt.Errorf("fetch feed 7: %w", ErrTooManyRequests.WithCause(cause))))
+	assert.True(t, IsUpstreamError(ErrFeedFetchFailed.WithCause(cause)))
+	assert.False(t, IsUpstreamError(ErrFeedNotFound))
+	assert.False(t, IsUpstreamError(NewInternalError(cause)))
+	assert.False(t, IsUpstreamError(cause))
+}
+
 func TestNewAppError(t *testing.T) {
 	code := 2001
 	message := "Custom error"
@@ -104,6 +139,14 @@ func TestNewValidationError(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, appErr.HTTPStatus)
 }
 
+func TestNewInvalidQueryError(t *testing.T) {
+	appErr := NewInvalidQueryError("invalid query parameters: limit must be between 1 and 100")
+
+	assert.Equal(t, 1302, appErr.Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, appErr.HTTPStatus)
+	assert.True(t, IsValidationError(appErr))
+}
+
 func TestErrorWrapping(t *testing.T) {
 	originalErr := errors.New("original error")
 	appErr := ErrDatabaseError.WithCause(originalErr)
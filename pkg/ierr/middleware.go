@@ -98,6 +98,14 @@ func findAppErrorByIs(err error) *AppError {
 		ErrInvalidCredentials,
 		ErrUserNotFound,
 		ErrInvalidToken,
+		ErrCredentialNotFound,
+		ErrNotificationNotFound,
+		ErrLoginLocked,
+		ErrChallengeRequired,
+		ErrSessionNotFound,
+		ErrEmailTaken,
+		ErrWrongPassword,
+		ErrInvalidResetToken,
 
 		// Feed-related errors
 		ErrFeedNotFound,
@@ -106,9 +114,22 @@ func findAppErrorByIs(err error) *AppError {
 		ErrFeedFetchFailed,
 		ErrNotSubscribed,
 		ErrAlreadySubscribed,
+		ErrSubscriptionLimit,
+		ErrFolderNotFound,
+		ErrFolderExists,
+		ErrExportNotFound,
+		ErrExportNotReady,
+		ErrUpstreamTimeout,
+		ErrUpstreamForbidden,
+		ErrTooManyRequests,
+		ErrUnsupportedFormat,
+		ErrDigestNotFound,
+		ErrSharedFolderNotFound,
+		ErrShareSlugTaken,
 
 		// Article-related errors
 		ErrArticleNotFound,
+		ErrBriefingFailed,
 
 		// Validation errors
 		ErrInvalidInput,
@@ -118,11 +139,15 @@ func findAppErrorByIs(err error) *AppError {
 		// Authorization errors
 		ErrUnauthorized,
 		ErrForbidden,
+		ErrReadOnlyDemo,
+		ErrImpersonationReadOnly,
+		ErrRateLimited,
 
 		// System errors
 		ErrInternalServer,
 		ErrDatabaseError,
 		ErrTaskQueueError,
+		ErrServiceUnavailable,
 	}
 
 	// check each predefined error
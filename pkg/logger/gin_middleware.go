@@ -6,9 +6,38 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// GinLoggingMiddleware returns a Gin middleware that logs HTTP requests.
-// It records method, path, status, latency, client IP, and context values (request_id, user_id).
-func GinLoggingMiddleware() gin.HandlerFunc {
+// AccessRecord describes one request served by gin, as logged by AccessLogMiddleware
+type AccessRecord struct {
+	Method string
+	// Route is the matched route template, e.g. /api/v1/feeds/:feed_id; empty when no route matched
+	Route     string
+	Path      string
+	Status    int
+	Latency   time.Duration
+	UserID    uint // 0 for anonymous requests
+	RequestID string
+	BytesIn   int64 // request body size, -1 when unknown
+	BytesOut  int64
+	// Slow is set when the request took longer than the configured threshold
+	Slow bool
+}
+
+// AccessMetrics receives a record for every request the access log sees
+type AccessMetrics interface {
+	ObserveAccess(r AccessRecord)
+}
+
+// AccessLogOptions configures AccessLogMiddleware
+type AccessLogOptions struct {
+	// SlowThreshold flags requests taking longer; 0 disables flagging
+	SlowThreshold time.Duration
+	Metrics       AccessMetrics
+}
+
+// AccessLogMiddleware logs every request with method, route template, status, latency,
+// sizes and context values (request_id, user_id), and reports it to opts.Metrics. Server
+// errors log at error level, client errors and slow requests at warn level.
+func AccessLogMiddleware(opts AccessLogOptions) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
@@ -17,22 +46,39 @@ func GinLoggingMiddleware() gin.HandlerFunc {
 		// Process request
 		c.Next()
 
-		// Calculate latency and get status
-		latency := time.Since(start)
-		status := c.Writer.Status()
+		// The handlers may have replaced the request context, e.g. with the user ID
+		ctx := c.Request.Context()
+		record := AccessRecord{
+			Method:   c.Request.Method,
+			Route:    c.FullPath(),
+			Path:     path,
+			Status:   c.Writer.Status(),
+			Latency:  time.Since(start),
+			BytesIn:  c.Request.ContentLength,
+			BytesOut: int64(max(c.Writer.Size(), 0)),
+		}
+		record.Slow = opts.SlowThreshold > 0 && record.Latency > opts.SlowThreshold
+		record.RequestID, _ = GetRequestID(ctx)
+		record.UserID, _ = GetUserID(ctx)
+
+		if opts.Metrics != nil {
+			opts.Metrics.ObserveAccess(record)
+		}
 
 		// Get contextual logger with request_id and user_id
-		log := FromContext(c.Request.Context())
+		log := FromContext(ctx)
 
 		// Build log attributes
 		attrs := []any{
-			"method", c.Request.Method,
-			"path", path,
-			"status", status,
-			"latency", latency.String(),
-			"latency_ms", latency.Milliseconds(),
+			"method", record.Method,
+			"route", record.Route,
+			"path", record.Path,
+			"status", record.Status,
+			"latency", record.Latency.String(),
+			"latency_ms", record.Latency.Milliseconds(),
 			"client_ip", c.ClientIP(),
-			"body_size", c.Writer.Size(),
+			"bytes_in", record.BytesIn,
+			"bytes_out", record.BytesOut,
 		}
 
 		if query != "" {
@@ -43,11 +89,15 @@ func GinLoggingMiddleware() gin.HandlerFunc {
 			attrs = append(attrs, "user_agent", c.Request.UserAgent())
 		}
 
+		if record.Slow {
+			attrs = append(attrs, "slow", true, "slow_threshold_ms", opts.SlowThreshold.Milliseconds())
+		}
+
 		// Log at appropriate level based on status code
 		switch {
-		case status >= 500:
+		case record.Status >= 500:
 			log.Error("HTTP request completed", attrs...)
-		case status >= 400:
+		case record.Status >= 400 || record.Slow:
 			log.Warn("HTTP request completed", attrs...)
 		default:
 			log.Info("HTTP request completed", attrs...)
@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDMetadataKey is the gRPC metadata key carrying the request ID between services
+const RequestIDMetadataKey = "x-request-id"
+
+// RequestIDClientInterceptor sends the request ID of the calling context along with every
+// unary call, so the called service logs and publishes events under the same ID
+func RequestIDClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if requestID, ok := GetRequestID(ctx); ok && requestID != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, RequestIDMetadataKey, requestID)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// RequestIDServerInterceptor restores the request ID sent by the caller into the handler's
+// context. Calls without one are handled without a request ID.
+func RequestIDServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(RequestIDMetadataKey); len(values) > 0 && values[0] != "" {
+				ctx = WithRequestID(ctx, values[0])
+			}
+		}
+		return handler(ctx, req)
+	}
+}
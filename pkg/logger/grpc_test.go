@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRequestIDInterceptors(t *testing.T) {
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	if err := RequestIDClientInterceptor()(ctx, "/feed.FeedService/TriggerFetch", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("client interceptor: %v", err)
+	}
+	if got := outgoing.Get(RequestIDMetadataKey); len(got) != 1 || got[0] != "req-123" {
+		t.Fatalf("Expected request ID in outgoing metadata, got %v", got)
+	}
+
+	var restored string
+	handler := func(ctx context.Context, req any) (any, error) {
+		restored, _ = GetRequestID(ctx)
+		return nil, nil
+	}
+	serverCtx := metadata.NewIncomingContext(context.Background(), outgoing)
+	if _, err := RequestIDServerInterceptor()(serverCtx, nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Fatalf("server interceptor: %v", err)
+	}
+	if restored != "req-123" {
+		t.Errorf("Expected restored request ID 'req-123', got '%s'", restored)
+	}
+
+	// calls without a request ID are handled without one
+	restored = ""
+	if _, err := RequestIDServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+		t.Fatalf("server interceptor: %v", err)
+	}
+	if restored != "" {
+		t.Errorf("Expected no request ID, got '%s'", restored)
+	}
+}
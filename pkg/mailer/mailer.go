@@ -0,0 +1,115 @@
+// Package mailer sends plain-text email over SMTP. When no SMTP host is configured
+// messages are dropped and only noted in the log, so development setups need no mail
+// server.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoRecipients is returned when a message has no To addresses
+var ErrNoRecipients = errors.New("message has no recipients")
+
+// Message is a plain-text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config holds the SMTP settings
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// New returns an SMTP mailer, or a log-only mailer when cfg.Host is empty
+func New(cfg Config, logger *slog.Logger) Mailer {
+	if cfg.Host == "" {
+		return &LogMailer{logger: logger}
+	}
+	return &SMTPMailer{cfg: cfg, logger: logger}
+}
+
+// SMTPMailer sends messages through an SMTP server, using STARTTLS when offered
+type SMTPMailer struct {
+	cfg    Config
+	logger *slog.Logger
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return ErrNoRecipients
+	}
+
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	// net/smtp has no context support; run the exchange in the background and stop
+	// waiting once the context is done
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, m.cfg.From, msg.To, compose(m.cfg.From, msg, time.Now()))
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("send mail via %s: %w", addr, err)
+		}
+		m.logger.Info("email sent", "subject", msg.Subject, "recipients", len(msg.To))
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// LogMailer drops messages, logging only their subject and number of recipients. Bodies
+// may carry secrets such as password reset tokens, so they never reach the log.
+type LogMailer struct {
+	logger *slog.Logger
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return ErrNoRecipients
+	}
+	m.logger.Info("email not sent: no SMTP host configured", "subject", msg.Subject, "recipients", len(msg.To))
+	return nil
+}
+
+// compose renders the RFC 5322 message
+func compose(from string, msg Message, date time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes()
+}
@@ -0,0 +1,54 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompose(t *testing.T) {
+	date := time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+	raw := string(compose("phoenix@example.com", Message{
+		To:      []string{"a@example.com", "b@example.com"},
+		Subject: "Wöchentlicher Bericht",
+		Body:    "line one\nline two",
+	}, date))
+
+	for _, want := range []string{
+		"From: phoenix@example.com\r\n",
+		"To: a@example.com, b@example.com\r\n",
+		"Subject: =?utf-8?q?W=C3=B6chentlicher_Bericht?=\r\n",
+		"Date: Mon, 04 Mar 2024 08:00:00 +0000\r\n",
+		"\r\n\r\nline one\r\nline two",
+	} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("composed message missing %q\n%s", want, raw)
+		}
+	}
+}
+
+func TestNew_WithoutHostLogs(t *testing.T) {
+	var logged strings.Builder
+	m := New(Config{}, slog.New(slog.NewTextHandler(&logged, nil)))
+	if _, ok := m.(*LogMailer); !ok {
+		t.Fatalf("expected LogMailer without SMTP host, got %T", m)
+	}
+
+	if err := m.Send(context.Background(), Message{Subject: "x"}); !errors.Is(err, ErrNoRecipients) {
+		t.Errorf("expected ErrNoRecipients, got %v", err)
+	}
+	if err := m.Send(context.Background(), Message{To: []string{"ops@example.com"}, Subject: "x", Body: "reset token abc123"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !strings.Contains(logged.String(), "email not sent") {
+		t.Errorf("expected the dropped message to be logged, got %q", logged.String())
+	}
+	for _, secret := range []string{"abc123", "ops@example.com"} {
+		if strings.Contains(logged.String(), secret) {
+			t.Errorf("log leaks %q: %q", secret, logged.String())
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package objectstore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := store.Get(ctx, "exports/missing.jsonl")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	body := "line one\nline two\n"
+	require.NoError(t, store.Put(ctx, "exports/feed-1/1.jsonl", strings.NewReader(body), int64(len(body)), "application/x-ndjson"))
+	reader, err := store.Get(ctx, "exports/feed-1/1.jsonl")
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	reader.Close()
+	assert.Equal(t, body, string(data))
+
+	require.NoError(t, store.Delete(ctx, "exports/feed-1/1.jsonl"))
+	require.NoError(t, store.Delete(ctx, "exports/feed-1/1.jsonl"), "deleting twice is not an error")
+	_, err = store.Get(ctx, "exports/feed-1/1.jsonl")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDir(t *testing.T) {
+	store := NewDir(t.TempDir())
+	testStore(t, store)
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "exports/../../outside"} {
+		assert.Error(t, store.Put(context.Background(), key, strings.NewReader("x"), 1, ""), key)
+	}
+}
+
+func TestS3(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/20261017/eu-west-1/s3/aws4_request, SignedHeaders=") ||
+			!strings.Contains(auth, "x-amz-content-sha256;x-amz-date") ||
+			r.Header.Get("X-Amz-Date") != "20261017T120000Z" {
+			http.Error(w, "bad signature", http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = string(data)
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				http.Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+			io.WriteString(w, data)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	store, err := NewS3(S3Config{
+		Endpoint:        server.URL,
+		Region:          "eu-west-1",
+		Bucket:          "archives",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+	})
+	require.NoError(t, err)
+	store.now = func() time.Time { return time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC) }
+	testStore(t, store)
+
+	require.NoError(t, store.Put(context.Background(), "a/b.txt", strings.NewReader("x"), 1, ""))
+	assert.Contains(t, objects, "/archives/a/b.txt", "objects are addressed path-style")
+
+	store.cfg.SecretAccessKey = ""
+	store.cfg.AccessKeyID = "other"
+	err = store.Put(context.Background(), "a/b.txt", strings.NewReader("x"), 1, "")
+	assert.ErrorContains(t, err, "403")
+}
+
+func TestNewS3_InvalidConfig(t *testing.T) {
+	_, err := NewS3(S3Config{Endpoint: "minio:9000", Bucket: "archives"})
+	assert.Error(t, err)
+	_, err = NewS3(S3Config{Endpoint: "http://minio:9000"})
+	assert.Error(t, err)
+}
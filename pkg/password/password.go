@@ -0,0 +1,192 @@
+// Package password hashes and verifies user passwords. New hashes use the configured
+// algorithm; hashes made with another algorithm or weaker parameters still verify and are
+// reported as due for a rehash, so they can be upgraded the next time the password is known.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported algorithms
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+	// AlgorithmUnknown names hashes in no format this package can read
+	AlgorithmUnknown = "unknown"
+)
+
+// ErrMismatch is returned when a password does not match its hash
+var ErrMismatch = errors.New("password does not match")
+
+// Argon2Params tunes argon2id. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Params selects the algorithm of new hashes and the parameters of each algorithm
+type Params struct {
+	Algorithm  string
+	Argon2     Argon2Params
+	BcryptCost int
+}
+
+// DefaultParams follow the OWASP recommendation for argon2id (19 MiB, 2 iterations)
+var DefaultParams = Params{
+	Algorithm: AlgorithmArgon2id,
+	Argon2: Argon2Params{
+		Memory:      19 * 1024,
+		Iterations:  2,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	},
+	BcryptCost: bcrypt.DefaultCost,
+}
+
+// Hasher hashes passwords with one set of parameters
+type Hasher struct {
+	params Params
+}
+
+func NewHasher(params Params) (*Hasher, error) {
+	switch params.Algorithm {
+	case AlgorithmArgon2id:
+		a := params.Argon2
+		if a.Memory < 8*uint32(a.Parallelism) || a.Iterations < 1 || a.Parallelism < 1 {
+			return nil, fmt.Errorf("invalid argon2id parameters: memory must be at least 8 KiB per lane, iterations and parallelism at least 1")
+		}
+		if a.SaltLength < 8 || a.KeyLength < 16 {
+			return nil, fmt.Errorf("invalid argon2id parameters: salt must be at least 8 bytes and key at least 16")
+		}
+	case AlgorithmBcrypt:
+		if params.BcryptCost < bcrypt.MinCost || params.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return nil, fmt.Errorf("unknown password hashing algorithm %q", params.Algorithm)
+	}
+	return &Hasher{params: params}, nil
+}
+
+// Hash returns the encoded hash of password. argon2id hashes use the PHC string format:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.params.Algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.params.BcryptCost)
+		return string(hash), err
+	}
+
+	a := h.params.Argon2
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, a.Memory, a.Iterations, a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify checks password against an encoded hash of any supported algorithm. It returns
+// ErrMismatch for a wrong password, and tells whether a matching hash should be replaced
+// by a new one from Hash.
+func (h *Hasher) Verify(password, encoded string) (rehash bool, err error) {
+	switch Algorithm(encoded) {
+	case AlgorithmBcrypt:
+		if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, ErrMismatch
+			}
+			return false, err
+		}
+	case AlgorithmArgon2id:
+		params, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, err
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(computed, key) != 1 {
+			return false, ErrMismatch
+		}
+	default:
+		return false, fmt.Errorf("unrecognized password hash format")
+	}
+	return h.NeedsRehash(encoded), nil
+}
+
+// NeedsRehash reports whether the encoded hash was made with another algorithm or other
+// parameters than the hasher's
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	if Algorithm(encoded) != h.params.Algorithm {
+		return true
+	}
+	if h.params.Algorithm == AlgorithmBcrypt {
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost != h.params.BcryptCost
+	}
+	params, _, _, err := decodeArgon2id(encoded)
+	return err != nil || params != h.params.Argon2
+}
+
+// Algorithm tells which algorithm made an encoded hash
+func Algorithm(encoded string) string {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return AlgorithmArgon2id
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return AlgorithmBcrypt
+	default:
+		return AlgorithmUnknown
+	}
+}
+
+// Describe names the algorithm and parameters of an encoded hash, for reports
+func Describe(encoded string) string {
+	switch Algorithm(encoded) {
+	case AlgorithmBcrypt:
+		if cost, err := bcrypt.Cost([]byte(encoded)); err == nil {
+			return fmt.Sprintf("bcrypt cost=%d", cost)
+		}
+	case AlgorithmArgon2id:
+		if p, _, _, err := decodeArgon2id(encoded); err == nil {
+			return fmt.Sprintf("argon2id m=%d,t=%d,p=%d", p.Memory, p.Iterations, p.Parallelism)
+		}
+	}
+	return AlgorithmUnknown
+}
+
+func decodeArgon2id(encoded string) (params Argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, fmt.Errorf("malformed argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id parameters: %w", err)
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id salt: %w", err)
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id key: %w", err)
+	}
+	if len(key) == 0 || params.Iterations < 1 || params.Parallelism < 1 {
+		return params, nil, nil, fmt.Errorf("malformed argon2id hash")
+	}
+	params.SaltLength, params.KeyLength = uint32(len(salt)), uint32(len(key))
+	return params, salt, key, nil
+}
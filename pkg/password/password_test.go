@@ -0,0 +1,85 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2 keeps the tests fast
+var testArgon2 = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHasher_Argon2idRoundTrip(t *testing.T) {
+	h, err := NewHasher(Params{Algorithm: AlgorithmArgon2id, Argon2: testArgon2})
+	require.NoError(t, err)
+
+	hash, err := h.Hash("correct horse")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$"), hash)
+	assert.Equal(t, "argon2id m=64,t=1,p=1", Describe(hash))
+
+	rehash, err := h.Verify("correct horse", hash)
+	require.NoError(t, err)
+	assert.False(t, rehash)
+
+	_, err = h.Verify("battery staple", hash)
+	assert.ErrorIs(t, err, ErrMismatch)
+
+	other, err := h.Hash("correct horse")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "salts are random")
+}
+
+func TestHasher_LegacyBcryptNeedsRehash(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	h, err := NewHasher(Params{Algorithm: AlgorithmArgon2id, Argon2: testArgon2})
+	require.NoError(t, err)
+	rehash, err := h.Verify("secret", string(legacy))
+	require.NoError(t, err)
+	assert.True(t, rehash)
+	_, err = h.Verify("wrong", string(legacy))
+	assert.ErrorIs(t, err, ErrMismatch)
+
+	sameCost, err := NewHasher(Params{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	require.NoError(t, err)
+	assert.False(t, sameCost.NeedsRehash(string(legacy)))
+	higherCost, err := NewHasher(Params{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost + 1})
+	require.NoError(t, err)
+	assert.True(t, higherCost.NeedsRehash(string(legacy)))
+}
+
+func TestHasher_Argon2idParameterChangeNeedsRehash(t *testing.T) {
+	old, err := NewHasher(Params{Algorithm: AlgorithmArgon2id, Argon2: testArgon2})
+	require.NoError(t, err)
+	hash, err := old.Hash("secret")
+	require.NoError(t, err)
+
+	stronger := testArgon2
+	stronger.Iterations = 2
+	h, err := NewHasher(Params{Algorithm: AlgorithmArgon2id, Argon2: stronger})
+	require.NoError(t, err)
+	rehash, err := h.Verify("secret", hash)
+	require.NoError(t, err, "hashes verify with the parameters they were made with")
+	assert.True(t, rehash)
+}
+
+func TestHasher_RejectsBadInput(t *testing.T) {
+	_, err := NewHasher(Params{Algorithm: "md5"})
+	assert.Error(t, err)
+	_, err = NewHasher(Params{Algorithm: AlgorithmArgon2id, Argon2: Argon2Params{Memory: 64, Iterations: 0, Parallelism: 1, SaltLength: 16, KeyLength: 32}})
+	assert.Error(t, err)
+
+	h, err := NewHasher(DefaultParams)
+	require.NoError(t, err)
+	for _, encoded := range []string{"", "plaintext", "$argon2id$v=19$m=64,t=1,p=1$bad", "$argon2id$v=18$m=64,t=1,p=1$c2FsdA$a2V5"} {
+		_, err := h.Verify("secret", encoded)
+		assert.Error(t, err, encoded)
+		assert.NotErrorIs(t, err, ErrMismatch, encoded)
+		assert.Equal(t, AlgorithmUnknown, Describe(encoded), encoded)
+	}
+}
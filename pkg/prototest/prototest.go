@@ -0,0 +1,469 @@
+// Package prototest checks the conversions between models and protobuf messages, so that a
+// field added to a .proto file, or to a model, cannot be dropped without a failing test.
+//
+// Fill and FillStruct populate every field of a message or a model. A conversion of a
+// filled value must then either set every field of its result (RequireAllSet) or change
+// its result whenever one of its input fields is cleared (RequireAllRead). RequireGolden
+// compares a converted value with a JSON file under testdata, rewritten by running the
+// tests with PROTOTEST_UPDATE=1. RequireRoundTrip checks that random messages survive the binary and
+// JSON wire formats.
+package prototest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// UpdateEnv names the environment variable that makes RequireGolden rewrite the golden
+// files instead of comparing them. A flag would not do: go test ./... passes flags to every
+// test binary, and those not importing this package reject it.
+const UpdateEnv = "PROTOTEST_UPDATE"
+
+// maxDepth bounds how deep nested messages and structs are filled, for recursive types
+const maxDepth = 4
+
+// BaseTime is the first time FillStruct sets; later time fields are a second apart
+var BaseTime = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// Fill sets every field of msg to a value derived from the field, the same on every run:
+// numbers take the field number, strings the field name and number, enums their last
+// value. Lists and maps get one element, and of each oneof only the first field is set.
+func Fill(msg proto.Message) {
+	(&filler{}).message(msg.ProtoReflect(), 0)
+}
+
+// FillRandom sets every field of msg to a random value
+func FillRandom(msg proto.Message, r *rand.Rand) {
+	(&filler{r: r}).message(msg.ProtoReflect(), 0)
+}
+
+type filler struct {
+	r *rand.Rand // nil fills deterministically
+}
+
+func (f *filler) message(m protoreflect.Message, depth int) {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() && oneof.Fields().Get(0) != fd {
+			continue
+		}
+		switch {
+		case fd.IsList():
+			list := m.Mutable(fd).List()
+			if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+				if depth < maxDepth {
+					element := list.NewElement()
+					f.message(element.Message(), depth+1)
+					list.Append(element)
+				}
+				continue
+			}
+			list.Append(f.scalar(fd))
+		case fd.IsMap():
+			mp := m.Mutable(fd).Map()
+			key := f.scalar(fd.MapKey()).MapKey()
+			if value := fd.MapValue(); value.Kind() == protoreflect.MessageKind {
+				if depth < maxDepth {
+					f.message(mp.Mutable(key).Message(), depth+1)
+				}
+				continue
+			}
+			mp.Set(key, f.scalar(fd.MapValue()))
+		case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+			if depth < maxDepth {
+				f.message(m.Mutable(fd).Message(), depth+1)
+			}
+		default:
+			m.Set(fd, f.scalar(fd))
+		}
+	}
+}
+
+// scalar returns a value of the field's kind other than its default
+func (f *filler) scalar(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	n := int64(fd.Number())
+	if f.r != nil {
+		n = 1 + f.r.Int64N(1<<30)
+	}
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(true)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(n))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(n)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(n))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(uint64(n))
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(n) + 0.5)
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(float64(n) + 0.25)
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(f.text(fd, n))
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(f.text(fd, n)))
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		index := values.Len() - 1
+		if f.r != nil {
+			index = f.r.IntN(values.Len())
+		}
+		return protoreflect.ValueOfEnum(values.Get(index).Number())
+	}
+	panic(fmt.Sprintf("prototest: cannot fill field %s of kind %s", fd.FullName(), fd.Kind()))
+}
+
+// text is a string value, with characters outside ASCII when random
+func (f *filler) text(fd protoreflect.FieldDescriptor, n int64) string {
+	if f.r == nil {
+		return fmt.Sprintf("%s-%d", fd.Name(), n)
+	}
+	const alphabet = "abcxyz019 -_/:é✓日本"
+	runes := []rune(alphabet)
+	length := 1 + f.r.IntN(16)
+	var b strings.Builder
+	for range length {
+		b.WriteRune(runes[f.r.IntN(len(runes))])
+	}
+	return b.String()
+}
+
+// UnsetFields returns the paths of the fields of msg left at their default value, such as
+// "summary" or "feed.title"; the fields of unset messages and of oneofs set through
+// another field are not listed
+func UnsetFields(msg proto.Message) []string {
+	var unset []string
+	unsetFields(msg.ProtoReflect(), "", &unset)
+	return unset
+}
+
+func unsetFields(m protoreflect.Message, prefix string, unset *[]string) {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := prefix + string(fd.Name())
+		if oneof := fd.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() && m.WhichOneof(oneof) != nil {
+			if m.WhichOneof(oneof) != fd {
+				continue
+			}
+		}
+		if !m.Has(fd) {
+			*unset = append(*unset, path)
+			continue
+		}
+		if fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap() {
+			unsetFields(m.Get(fd).Message(), path+".", unset)
+		}
+	}
+}
+
+// RequireAllSet fails the test when a field of msg other than the ignored paths is unset,
+// meaning the conversion producing msg does not map it
+func RequireAllSet(t testing.TB, msg proto.Message, ignore ...string) {
+	t.Helper()
+	var missing []string
+	for _, path := range UnsetFields(msg) {
+		if !slices.Contains(ignore, path) {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) > 0 {
+		t.Errorf("%s: fields not mapped by the conversion: %s", msg.ProtoReflect().Descriptor().FullName(), strings.Join(missing, ", "))
+	}
+}
+
+// UnreadFields returns the top-level fields of msg whose value convert ignores: clearing
+// one of them leaves the result of convert unchanged. msg should be filled first.
+func UnreadFields(msg proto.Message, convert func(proto.Message) any) []string {
+	want := convert(msg)
+	var unread []string
+	fields := msg.ProtoReflect().Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !msg.ProtoReflect().Has(fd) {
+			continue
+		}
+		cleared := proto.Clone(msg)
+		cleared.ProtoReflect().Clear(fd)
+		if reflect.DeepEqual(convert(cleared), want) {
+			unread = append(unread, string(fd.Name()))
+		}
+	}
+	return unread
+}
+
+// RequireAllRead fails the test when convert ignores a field of msg other than the
+// ignored ones
+func RequireAllRead(t testing.TB, msg proto.Message, convert func(proto.Message) any, ignore ...string) {
+	t.Helper()
+	var missing []string
+	for _, name := range UnreadFields(msg, convert) {
+		if !slices.Contains(ignore, name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		t.Errorf("%s: fields not read by the conversion: %s", msg.ProtoReflect().Descriptor().FullName(), strings.Join(missing, ", "))
+	}
+}
+
+// RequireRoundTrip fails the test when a random message of each type changes going
+// through the binary or the JSON wire format
+func RequireRoundTrip(t testing.TB, types []protoreflect.MessageType, runs int) {
+	t.Helper()
+	for _, mt := range types {
+		for seed := range uint64(runs) {
+			msg := mt.New().Interface()
+			FillRandom(msg, rand.New(rand.NewPCG(seed, uint64(len(mt.Descriptor().FullName())))))
+
+			data, err := proto.Marshal(msg)
+			if err != nil {
+				t.Fatalf("%s: marshal: %v", mt.Descriptor().FullName(), err)
+			}
+			decoded := mt.New().Interface()
+			if err := proto.Unmarshal(data, decoded); err != nil {
+				t.Fatalf("%s: unmarshal: %v", mt.Descriptor().FullName(), err)
+			}
+			if !proto.Equal(msg, decoded) {
+				t.Errorf("%s (seed %d): binary round trip changed the message", mt.Descriptor().FullName(), seed)
+			}
+
+			jsonData, err := protojson.Marshal(msg)
+			if err != nil {
+				t.Fatalf("%s: marshal JSON: %v", mt.Descriptor().FullName(), err)
+			}
+			decoded = mt.New().Interface()
+			if err := protojson.Unmarshal(jsonData, decoded); err != nil {
+				t.Fatalf("%s: unmarshal JSON: %v", mt.Descriptor().FullName(), err)
+			}
+			if !proto.Equal(msg, decoded) {
+				t.Errorf("%s (seed %d): JSON round trip changed the message", mt.Descriptor().FullName(), seed)
+			}
+		}
+	}
+}
+
+// MessageTypes returns the types of every message declared in the file, nested ones included
+func MessageTypes(file protoreflect.FileDescriptor) []protoreflect.MessageType {
+	var types []protoreflect.MessageType
+	var collect func(messages protoreflect.MessageDescriptors)
+	collect = func(messages protoreflect.MessageDescriptors) {
+		for i := 0; i < messages.Len(); i++ {
+			md := messages.Get(i)
+			if md.IsMapEntry() {
+				continue
+			}
+			if mt, err := protoregistry.GlobalTypes.FindMessageByName(md.FullName()); err == nil {
+				types = append(types, mt)
+			}
+			collect(md.Messages())
+		}
+	}
+	collect(file.Messages())
+	return types
+}
+
+// RequireGolden fails the test when v, as indented JSON, differs from the file at path;
+// messages are written with protojson including unset fields, anything else with
+// encoding/json. With PROTOTEST_UPDATE set the file is rewritten instead.
+func RequireGolden(t testing.TB, path string, v any) {
+	t.Helper()
+	got, err := goldenJSON(v)
+	if err != nil {
+		t.Fatalf("encode %s: %v", path, err)
+	}
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s (run the test with PROTOTEST_UPDATE=1 to create it): %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is out of date; a field was added, removed or mapped differently. Review the change and run the test with PROTOTEST_UPDATE=1.\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
+
+func goldenJSON(v any) ([]byte, error) {
+	var data []byte
+	var err error
+	if msg, ok := v.(proto.Message); ok {
+		data, err = protojson.MarshalOptions{EmitUnpopulated: true, UseProtoNames: true}.Marshal(msg)
+	} else {
+		data, err = json.Marshal(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// protojson varies its whitespace between runs on purpose, so it is indented anew
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// FillStruct sets every exported field of the struct v points to, recursively, to a value
+// other than its zero value: numbers count up from 1, strings are the field name, times
+// start at BaseTime and pointers point to filled values. Fields of types FillStruct cannot
+// make up, such as interfaces and funcs, are left alone.
+func FillStruct(v any) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		panic("prototest: FillStruct needs a pointer to a struct")
+	}
+	counter := 0
+	fillValue(rv.Elem(), "", &counter, 0)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// fillValue fills v; counter numbers the values set, so each one is different
+func fillValue(v reflect.Value, name string, counter *int, depth int) {
+	next := func() int {
+		*counter++
+		return *counter
+	}
+	switch {
+	case v.Type() == timeType:
+		v.Set(reflect.ValueOf(BaseTime.Add(time.Duration(next()) * time.Second)))
+		return
+	case v.Kind() == reflect.Struct:
+		if depth >= maxDepth {
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fieldName := field.Name
+			if !field.Anonymous && name != "" {
+				fieldName = name + "." + field.Name
+			} else if field.Anonymous {
+				fieldName = name
+			}
+			fillValue(v.Field(i), fieldName, counter, depth+1)
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(next()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(next()))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(next()) + 0.5)
+	case reflect.String:
+		v.SetString(fmt.Sprintf("%s-%d", name, next()))
+	case reflect.Pointer:
+		if depth >= maxDepth {
+			return
+		}
+		elem := reflect.New(v.Type().Elem())
+		fillValue(elem.Elem(), name, counter, depth+1)
+		v.Set(elem)
+	case reflect.Slice:
+		if depth >= maxDepth {
+			return
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		fillValue(elem, name, counter, depth+1)
+		v.Set(reflect.Append(reflect.MakeSlice(v.Type(), 0, 1), elem))
+	case reflect.Map:
+		if depth >= maxDepth {
+			return
+		}
+		key := reflect.New(v.Type().Key()).Elem()
+		fillValue(key, name, counter, depth+1)
+		value := reflect.New(v.Type().Elem()).Elem()
+		fillValue(value, name, counter, depth+1)
+		m := reflect.MakeMap(v.Type())
+		m.SetMapIndex(key, value)
+		v.Set(m)
+	}
+}
+
+// ZeroFields returns the exported fields of the struct v points to, in dotted paths such
+// as "Summary", that are still zero, e.g. after a conversion that does not map them
+func ZeroFields(v any) []string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	var zero []string
+	zeroFields(rv, "", &zero, 0)
+	return zero
+}
+
+func zeroFields(v reflect.Value, prefix string, zero *[]string, depth int) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := v.Field(i)
+		path := prefix + field.Name
+		if field.Anonymous {
+			path = strings.TrimSuffix(prefix, ".")
+		}
+		if field.Anonymous && value.Kind() == reflect.Pointer && !value.IsNil() {
+			value = value.Elem()
+		}
+		if value.Kind() == reflect.Struct && value.Type() != timeType && depth < maxDepth {
+			next := path + "."
+			if path == "" {
+				next = ""
+			}
+			zeroFields(value, next, zero, depth+1)
+			continue
+		}
+		if value.IsZero() {
+			*zero = append(*zero, path)
+		}
+	}
+}
+
+// RequireNoZeroFields fails the test when a field of the struct v points to, other than
+// the ignored paths, is zero
+func RequireNoZeroFields(t testing.TB, v any, ignore ...string) {
+	t.Helper()
+	var missing []string
+	for _, path := range ZeroFields(v) {
+		if !slices.Contains(ignore, path) {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) > 0 {
+		t.Errorf("%T: fields not mapped by the conversion: %s", v, strings.Join(missing, ", "))
+	}
+}
@@ -0,0 +1,90 @@
+package prototest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	article_eventspb "github.com/Fancu1/phoenix-rss/proto/gen/article_events"
+)
+
+func TestFill(t *testing.T) {
+	var event article_eventspb.ArticlePersistedEvent
+	assert.Len(t, UnsetFields(&event), 10)
+
+	Fill(&event)
+	assert.Empty(t, UnsetFields(&event))
+	assert.Equal(t, uint64(1), event.ArticleId)
+	assert.Equal(t, "title-3", event.Title)
+	assert.True(t, event.Expand)
+
+	var again article_eventspb.ArticlePersistedEvent
+	Fill(&again)
+	assert.True(t, proto.Equal(&event, &again), "filling is deterministic")
+}
+
+func TestUnreadFields(t *testing.T) {
+	var event article_eventspb.ArticleProcessedEvent
+	Fill(&event)
+
+	// a conversion that forgot the error class
+	convert := func(msg proto.Message) any {
+		e := msg.(*article_eventspb.ArticleProcessedEvent)
+		return [...]any{e.ArticleId, e.Summary, e.ProcessingModel, e.SummaryTruncated, e.Failed, e.ProcessingPrompt, e.RequestId, e.Language, e.Topics}
+	}
+	assert.Equal(t, []string{"error_class"}, UnreadFields(&event, convert))
+}
+
+func TestRequireRoundTrip(t *testing.T) {
+	types := MessageTypes(article_eventspb.File_article_events_proto)
+	require.Len(t, types, 2)
+	RequireRoundTrip(t, types, 10)
+}
+
+type sample struct {
+	ID      uint
+	Title   string
+	Summary *string
+	At      time.Time
+	Nested  struct{ Count int }
+	Tags    []string
+	hidden  string
+}
+
+func TestFillStruct(t *testing.T) {
+	var s sample
+	FillStruct(&s)
+	assert.Empty(t, ZeroFields(&s))
+	assert.Equal(t, "Title-2", s.Title)
+	assert.Equal(t, BaseTime.Add(4*time.Second), s.At)
+	assert.Empty(t, s.hidden)
+
+	s.Summary = nil
+	s.Nested.Count = 0
+	assert.Equal(t, []string{"Summary", "Nested.Count"}, ZeroFields(&s))
+}
+
+func TestRequireGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "event.golden.json")
+	event := &article_eventspb.ArticleProcessedEvent{ArticleId: 7}
+	require.NoError(t, os.WriteFile(path, []byte(`{
+  "article_id": "7",
+  "summary": "",
+  "processing_model": "",
+  "summary_truncated": false,
+  "failed": false,
+  "error_class": "",
+  "processing_prompt": "",
+  "request_id": "",
+  "language": "",
+  "topics": []
+}
+`), 0o644))
+
+	RequireGolden(t, path, event)
+}
@@ -0,0 +1,62 @@
+package secrets
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCipher_RoundTrip(t *testing.T) {
+	c, err := NewCipher("test-passphrase")
+	require.NoError(t, err)
+
+	encrypted, err := c.Encrypt("sk-user-key")
+	require.NoError(t, err)
+	assert.NotContains(t, encrypted, "sk-user-key")
+
+	decrypted, err := c.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-user-key", decrypted)
+}
+
+func TestCipher_NonceIsRandom(t *testing.T) {
+	c, err := NewCipher("test-passphrase")
+	require.NoError(t, err)
+
+	first, err := c.Encrypt("same")
+	require.NoError(t, err)
+	second, err := c.Encrypt("same")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, first, second)
+}
+
+func TestCipher_WrongKey(t *testing.T) {
+	c1, err := NewCipher("key-one")
+	require.NoError(t, err)
+	c2, err := NewCipher("key-two")
+	require.NoError(t, err)
+
+	encrypted, err := c1.Encrypt("secret")
+	require.NoError(t, err)
+
+	_, err = c2.Decrypt(encrypted)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalidCiphertext))
+}
+
+func TestCipher_InvalidInput(t *testing.T) {
+	_, err := NewCipher("")
+	require.Error(t, err)
+
+	c, err := NewCipher("key")
+	require.NoError(t, err)
+
+	_, err = c.Decrypt("not base64!")
+	assert.True(t, errors.Is(err, ErrInvalidCiphertext))
+
+	_, err = c.Decrypt("AAAA")
+	assert.True(t, errors.Is(err, ErrInvalidCiphertext))
+}
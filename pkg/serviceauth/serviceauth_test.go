@@ -0,0 +1,87 @@
+package serviceauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const method = "/feed.FeedService/TriggerFetch"
+
+// signedContext returns the server context of a call the signer made to the method
+func signedContext(t *testing.T, signer *Signer, method string) context.Context {
+	t.Helper()
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	require.NoError(t, signer.ClientInterceptor()(context.Background(), method, nil, nil, nil, invoker))
+	return metadata.NewIncomingContext(context.Background(), outgoing)
+}
+
+func call(verifier *Verifier, ctx context.Context, method string) (string, error) {
+	var caller string
+	handler := func(ctx context.Context, req any) (any, error) {
+		caller, _ = CallerFromContext(ctx)
+		return nil, nil
+	}
+	_, err := verifier.ServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	return caller, err
+}
+
+func TestInterceptors(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	signer := NewSigner("api-service", "shared-secret")
+	signer.now = func() time.Time { return now }
+	verifier := NewVerifier("shared-secret", time.Minute)
+	verifier.now = func() time.Time { return now.Add(30 * time.Second) }
+
+	caller, err := call(verifier, signedContext(t, signer, method), method)
+	require.NoError(t, err)
+	assert.Equal(t, "api-service", caller)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"no token", context.Background()},
+		{"malformed token", metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, "secret"))},
+		{"other method", signedContext(t, signer, "/user.UserService/ListUsers")},
+		{"other secret", signedContext(t, NewSigner("api-service", "guessed"), method)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := call(verifier, tt.ctx, method)
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+
+	t.Run("expired token", func(t *testing.T) {
+		verifier.now = func() time.Time { return now.Add(2 * time.Minute) }
+		defer func() { verifier.now = func() time.Time { return now } }()
+		_, err := call(verifier, signedContext(t, signer, method), method)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Contains(t, err.Error(), "expired")
+	})
+
+	t.Run("forged caller", func(t *testing.T) {
+		md, _ := metadata.FromIncomingContext(signedContext(t, signer, method))
+		token := md.Get(MetadataKey)[0]
+		forged := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, "v1:scheduler-service"+token[len("v1:api-service"):]))
+		_, err := call(verifier, forged, method)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("health checks are open", func(t *testing.T) {
+		_, err := call(verifier, context.Background(), "/grpc.health.v1.Health/Check")
+		assert.NoError(t, err)
+	})
+}
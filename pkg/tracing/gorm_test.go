@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type tracedRow struct {
+	ID   uint
+	Name string
+}
+
+// recordSpans installs a tracer provider recording every span for the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func attributesOf(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestGormPlugin(t *testing.T) {
+	recorder := recordSpans(t)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(GormPlugin{}))
+	require.NoError(t, db.AutoMigrate(&tracedRow{}))
+
+	ctx, parent := Start(context.Background(), "request")
+	require.NoError(t, db.WithContext(ctx).Create(&tracedRow{Name: "first"}).Error)
+	var row tracedRow
+	err = db.WithContext(ctx).Where("name = ?", "missing").First(&row).Error
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	err = db.WithContext(ctx).Exec("SELECT * FROM no_such_table").Error
+	require.Error(t, err)
+	parent.End()
+
+	var statements []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == parent.SpanContext().SpanID() {
+			statements = append(statements, span)
+		}
+	}
+	require.Len(t, statements, 3)
+
+	create := statements[0]
+	assert.Equal(t, "db.create", create.Name())
+	attrs := attributesOf(create)
+	assert.Equal(t, "sqlite", attrs["db.system"].AsString())
+	assert.Equal(t, "traced_rows", attrs["db.collection.name"].AsString())
+	assert.Contains(t, attrs["db.query.text"].AsString(), "INSERT INTO `traced_rows`")
+	assert.Equal(t, int64(1), attrs["db.rows_affected"].AsInt64())
+	assert.Equal(t, codes.Unset, create.Status().Code)
+
+	// finding nothing is not an error of the query
+	assert.Equal(t, "db.query", statements[1].Name())
+	assert.Equal(t, codes.Unset, statements[1].Status().Code)
+
+	assert.Equal(t, "db.raw", statements[2].Name())
+	assert.Equal(t, codes.Error, statements[2].Status().Code)
+}
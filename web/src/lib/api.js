@@ -2,8 +2,9 @@
 import { get } from 'svelte/store';
 import { authStore } from './stores.js';
 
-// API base configuration
-const API_BASE = '/api/v1';
+// API base configuration. Builds hosted apart from the API (CDN, separate listener)
+// set VITE_API_ORIGIN, e.g. https://api.example.com
+const API_BASE = `${import.meta.env.VITE_API_ORIGIN ?? ''}/api/v1`;
 const DEFAULT_TIMEOUT = 15000; // 15 seconds
 
 // Custom error class for API errors